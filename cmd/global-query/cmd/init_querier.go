@@ -5,6 +5,7 @@ import (
 
 	"github.com/els0r/goProbe/cmd/global-query/pkg/conf"
 	"github.com/els0r/goProbe/cmd/global-query/pkg/distributed"
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
 	"github.com/els0r/goProbe/plugins"
 	"github.com/spf13/viper"
 
@@ -12,9 +13,23 @@ import (
 	_ "github.com/els0r/goProbe/plugins/querier"
 )
 
-func initQuerier(ctx context.Context) (querier distributed.Querier, err error) {
+// initQuerier initializes the querier plugin. If provided, the pin store is made available to
+// the plugin in order to verify the certificates of the queried hosts
+func initQuerier(ctx context.Context, pins *pinning.Store) (querier distributed.Querier, err error) {
+	if pins != nil {
+		ctx = pinning.WithStore(ctx, pins)
+	}
 	return plugins.InitQuerier(ctx,
 		viper.GetString(conf.QuerierType),
 		viper.GetString(conf.QuerierConfig),
 	)
 }
+
+// initPins creates the store of the certificate fingerprints pinned for the queried hosts (if
+// pinning is enabled)
+func initPins() (*pinning.Store, error) {
+	if !viper.GetBool(conf.PinningEnabled) {
+		return nil, nil
+	}
+	return pinning.New(viper.GetString(conf.PinningStore))
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -64,7 +65,10 @@ func init() {
 
 	pflags.String(conf.LogLevel, conf.DefaultLogLevel, "log level for logger")
 	pflags.String(conf.LogEncoding, conf.DefaultLogEncoding, "message encoding format for logger")
-	pflags.String(conf.HostsResolverType, conf.DefaultHostsResolver, "resolver used for the hosts resolution query")
+	pflags.String(conf.HostsResolverType, conf.DefaultHostsResolver, "resolver used for the hosts resolution query (string, dns_srv or file)")
+	pflags.String(conf.HostsResolverSRVName, "", "name of the DNS SRV records the hosts are discovered from (e.g. _goprobe._tcp.example.com), if using the dns_srv resolver")
+	pflags.String(conf.HostsResolverFile, "", "path to the hosts file (one host per line) the hosts are discovered from, if using the file resolver")
+	pflags.Duration(conf.HostsResolverReloadInterval, hosts.DefaultReloadInterval, "interval in which the hosts file is checked for changes, if using the file resolver")
 	pflags.String(conf.QuerierType, conf.DefaultHostsQuerierType, "querier used to run queries")
 	pflags.String(conf.QuerierConfig, "", "querier config file location")
 	pflags.Int(conf.QuerierMaxConcurrent, 0, "maximum number of concurrent queries to hosts")
@@ -115,11 +119,30 @@ func initConfig() {
 	}
 }
 
-func initHostListResolver() (hosts.Resolver, error) {
+// initHostListResolver initializes the hosts resolver. A hosts file is watched for changes until the
+// context is cancelled
+func initHostListResolver(ctx context.Context) (hosts.Resolver, error) {
 	resolverType := viper.GetString(conf.HostsResolverType)
 	switch resolverType {
 	case string(hosts.StringResolverType):
 		return hosts.NewStringResolver(true), nil
+	case string(hosts.SRVResolverType):
+		name := viper.GetString(conf.HostsResolverSRVName)
+		if name == "" {
+			return nil, fmt.Errorf("hosts resolver type %q requires the name of the SRV records (%s)", resolverType, conf.HostsResolverSRVName)
+		}
+		return hosts.NewSRVResolver(name), nil
+	case string(hosts.FileResolverType):
+		path := viper.GetString(conf.HostsResolverFile)
+		if path == "" {
+			return nil, fmt.Errorf("hosts resolver type %q requires the path to the hosts file (%s)", resolverType, conf.HostsResolverFile)
+		}
+		resolver, err := hosts.NewFileResolver(path, viper.GetDuration(conf.HostsResolverReloadInterval))
+		if err != nil {
+			return nil, err
+		}
+		resolver.Watch(ctx)
+		return resolver, nil
 	default:
 		err := fmt.Errorf("hosts resolver type %q not supported", resolverType)
 		return nil, err
@@ -3,17 +3,28 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
+	"time"
 
 	"github.com/els0r/goProbe/cmd/global-query/pkg/conf"
+	"github.com/els0r/goProbe/cmd/global-query/pkg/distributed"
+	"github.com/els0r/goProbe/pkg/api"
 	gqserver "github.com/els0r/goProbe/pkg/api/globalquery/server"
 	"github.com/els0r/goProbe/pkg/api/server"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/query/report"
+	"github.com/els0r/goProbe/pkg/query/schedule"
 	"github.com/els0r/goProbe/plugins"
 	"github.com/els0r/telemetry/logging"
 	"github.com/els0r/telemetry/tracing"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -35,9 +46,39 @@ func init() {
 
 	pflags.String(conf.ServerAddr, conf.DefaultServerAddr, "address to which the server binds")
 	pflags.Duration(conf.ServerShutdownGracePeriod, conf.DefaultServerShutdownGracePeriod, "duration the server will wait during shutdown before forcing shutdown")
+	pflags.Bool(conf.ServerUI, false, "serve a minimal web UI for running queries on /ui")
+	pflags.Int64(conf.ServerMaxBodySize, api.DefaultMaxBodySize, "maximum size of request bodies (in bytes, a negative value disables the limit)")
+	pflags.Bool(conf.ServerCompression, false, "compress response bodies (zstd / gzip, as negotiated via the Accept-Encoding header)")
+	pflags.StringSlice(conf.ServerKeys, nil, "API keys authorizing access to the routes requiring authentication (e.g. approving certificate changes), presented via the Authorization header")
+
+	// scheduled queries
+	pflags.Bool(conf.SchedulerEnabled, false, "enable the scheduler for recurring queries (jobs can be defined in the config file or registered via the API)")
+	pflags.Int(conf.SchedulerHistorySize, schedule.DefaultHistorySize, "number of runs kept in the history of each scheduled query")
+	pflags.String(conf.SchedulerFileDir, "", "base directory of the file sinks of scheduled queries (file sinks are rejected if empty)")
+	pflags.StringSlice(conf.SchedulerAllowedHosts, nil, "hosts (host or host:port) scheduled queries registered via the API may deliver results / alerts to")
+
+	// daily summary reports
+	pflags.String(conf.ReportsDir, "", "directory daily summary reports are written to (reports are disabled if empty, definitions can be provided in the config file)")
+	pflags.String(conf.ReportsTimeOfDay, report.DefaultTimeOfDay, "local time of day (HH:MM) the reports of the previous day are generated at")
+	pflags.Int(conf.ReportsRetention, report.DefaultRetention, "number of days reports are retained for (0 retains them indefinitely)")
+
+	// caching of per-host results
+	pflags.Bool(conf.CacheEnabled, false, "cache the results of the individual hosts of queries, only querying the hosts whose results are missing or stale when re-running a query")
+	pflags.Duration(conf.CacheTTL, distributed.DefaultResultCacheTTL, "duration for which the cached result of a host is considered fresh")
+	pflags.Int(conf.CacheMaxEntries, distributed.DefaultResultCacheMaxEntries, "maximum number of cached host results")
+
+	// query auditing
+	pflags.Bool(conf.AuditEnabled, false, "record all executed queries in an audit log (sinks can be defined in the config file)")
+	pflags.String(conf.AuditTenantHeader, audit.DefaultTenantHeader, "request header identifying the tenant running a query")
+	pflags.Int(conf.AuditHistorySize, audit.DefaultHistorySize, "number of audit log entries kept in memory (and exposed via the API)")
+
+	// certificate pinning
+	pflags.Bool(conf.PinningEnabled, false, "pin the certificates presented by the queried hosts upon first contact (trust on first use) instead of verifying them against a CA")
+	pflags.String(conf.PinningStore, "", "file the pinned certificate fingerprints are persisted to (if empty, they are pinned anew on restart)")
 
 	// telemetry
 	pflags.Bool(conf.ProfilingEnabled, false, "enable profiling endpoints")
+	pflags.Bool(conf.MetricsEnabled, false, "enable prometheus metrics endpoint (including latency / errors of each API route)")
 
 	_ = viper.BindPFlags(pflags)
 }
@@ -53,7 +94,7 @@ func serverEntrypoint(cmd *cobra.Command, args []string) error {
 		logger.With("error", err).Error("failed to set up tracing")
 	}
 
-	hostListResolver, err := initHostListResolver()
+	hostListResolver, err := initHostListResolver(ctx)
 	if err != nil {
 		logger.Errorf("failed to prepare query: %v", err)
 		return err
@@ -62,22 +103,100 @@ func serverEntrypoint(cmd *cobra.Command, args []string) error {
 	qlogger := logger.With("plugins", plugins.GetInitializer())
 	qlogger.Debug("getting available plugins")
 
+	// set up the pinning of the certificates of the queried hosts (if enabled), shared by the
+	// querier and the API endpoints to review the pins
+	pins, err := initPins()
+	if err != nil {
+		logger.Errorf("failed to set up certificate pinning: %v", err)
+		return err
+	}
+
 	// get the querier
-	querier, err := initQuerier(ctx)
+	querier, err := initQuerier(ctx, pins)
 	if err != nil {
 		qlogger.Errorf("failed to set up queriers: %v", err)
 		return err
 	}
 
+	// set up the cache of per-host results (if enabled), shared by all queries
+	var queryOpts []distributed.QueryOption
+	if viper.GetBool(conf.CacheEnabled) {
+		queryOpts = append(queryOpts, distributed.WithResultCache(
+			distributed.NewResultCache(viper.GetDuration(conf.CacheTTL), viper.GetInt(conf.CacheMaxEntries)),
+		))
+	}
+
+	// set up the audit log (if enabled). All queries, including scheduled ones, are recorded
+	var (
+		auditLog *audit.Log
+		runner   query.Runner = distributed.NewQueryRunner(hostListResolver, querier, queryOpts...)
+	)
+	if viper.GetBool(conf.AuditEnabled) {
+		auditLog, err = initAuditLog(ctx)
+		if err != nil {
+			logger.Errorf("failed to set up audit log: %v", err)
+			return err
+		}
+		runner = audit.NewRunner(runner, auditLog)
+	}
+
+	// set up the scheduler for recurring queries. It also runs the push schedules and the daily reports,
+	// but is only exposed
+	// via the API if enabled
+	scheduler, err := initScheduler(ctx, runner)
+	if err != nil {
+		logger.Errorf("failed to set up scheduler: %v", err)
+		return err
+	}
+	var apiScheduler *schedule.Scheduler
+	if viper.GetBool(conf.SchedulerEnabled) {
+		apiScheduler = scheduler
+	}
+
+	// set up scheduled result delivery (if configured)
+	nPushSchedules, err := registerPushSchedules(scheduler)
+	if err != nil {
+		logger.Errorf("failed to set up push schedules: %v", err)
+		return err
+	}
+
+	// set up the daily summary reports (if a report directory is configured)
+	var reporter *report.Reporter
+	if viper.GetString(conf.ReportsDir) != "" {
+		reporter, err = initReporter(runner)
+		if err != nil {
+			logger.Errorf("failed to set up daily reports: %v", err)
+			return err
+		}
+		if err := reporter.Register(scheduler); err != nil {
+			logger.Errorf("failed to schedule daily reports: %v", err)
+			return err
+		}
+	}
+
 	// set up the API server
 	addr := viper.GetString(conf.ServerAddr)
-	apiServer := gqserver.New(addr, hostListResolver, querier,
+	apiServer := gqserver.New(addr, hostListResolver, querier, apiScheduler, pins, queryOpts,
 		// Set the release mode of GIN depending on the log level
 		server.WithDebugMode(
 			logging.LevelFromString(viper.GetString(conf.LogLevel)) == logging.LevelDebug,
 		),
 		server.WithProfiling(viper.GetBool(conf.ProfilingEnabled)),
+		server.WithMetrics(viper.GetBool(conf.MetricsEnabled)),
 		server.WithTracing(viper.GetBool(tracing.TracingEnabledArg)),
+		server.WithQueryAudit(auditLog, viper.GetString(conf.AuditTenantHeader)),
+		server.WithUI(viper.GetBool(conf.ServerUI)),
+		server.WithMaxBodySize(viper.GetInt64(conf.ServerMaxBodySize)),
+		server.WithCompression(viper.GetBool(conf.ServerCompression)),
+		server.WithKeys(viper.GetStringSlice(conf.ServerKeys)),
+		server.WithFeatures("global-query", map[string]bool{
+			"audit":          auditLog != nil,
+			"cache":          viper.GetBool(conf.CacheEnabled),
+			"pinning":        pins != nil,
+			"push_schedules": nPushSchedules > 0,
+			"reports":        reporter != nil,
+			"scheduler":      apiScheduler != nil,
+		}),
 	)
 
 	// initializing the server in a goroutine so that it won't block the graceful
@@ -107,6 +226,12 @@ func serverEntrypoint(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		logger.With("error", err).Error("forced shut down of API server")
 	}
+	if auditLog != nil {
+		err = auditLog.Close()
+		if err != nil {
+			logger.With("error", err).Error("failed to close audit log")
+		}
+	}
 	err = shutdownTracing(ctx)
 	if err != nil {
 		logger.With("error", err).Error("forced shut down of tracing")
@@ -115,3 +240,115 @@ func serverEntrypoint(cmd *cobra.Command, args []string) error {
 	logger.Info("shut down complete")
 	return nil
 }
+
+// pushSchedule defines a query whose result is pushed to a remote endpoint in fixed intervals, i.e. a
+// shorthand for a scheduled query delivering to a single webhook sink
+type pushSchedule struct {
+	Name     string        `json:"name"`
+	Interval time.Duration `json:"interval"`
+	Target   push.Target   `json:"target"`
+
+	// Args are the query arguments. Relative time specifications (e.g. first: -1h) are
+	// evaluated anew for every run
+	Args *query.Args `json:"query"`
+}
+
+// registerPushSchedules registers the scheduled push definitions from the configuration as jobs of the
+// scheduler, returning their number
+func registerPushSchedules(scheduler *schedule.Scheduler) (int, error) {
+	var schedules []pushSchedule
+	err := viper.UnmarshalKey(conf.PushSchedules, &schedules, withDefaultQueryArgs)
+	if err != nil {
+		return 0, err
+	}
+	for _, ps := range schedules {
+		target := ps.Target
+		err := scheduler.Register(schedule.Job{
+			Name:  ps.Name,
+			Spec:  "@every " + ps.Interval.String(),
+			Args:  ps.Args,
+			Sinks: []schedule.SinkConfig{{Webhook: &target}},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("invalid push schedule %q: %w", ps.Name, err)
+		}
+	}
+	return len(schedules), nil
+}
+
+// initScheduler creates the scheduler and registers all jobs defined in the configuration (if the
+// scheduler is enabled)
+func initScheduler(ctx context.Context, runner query.Runner) (*schedule.Scheduler, error) {
+	scheduler := schedule.New(ctx, runner,
+		schedule.WithHistorySize(viper.GetInt(conf.SchedulerHistorySize)),
+		schedule.WithFileDir(viper.GetString(conf.SchedulerFileDir)),
+		schedule.WithAllowedHosts(viper.GetStringSlice(conf.SchedulerAllowedHosts)),
+	)
+	if !viper.GetBool(conf.SchedulerEnabled) {
+		return scheduler, nil
+	}
+
+	var jobs []schedule.Job
+	err := viper.UnmarshalKey(conf.SchedulerJobs, &jobs, withDefaultQueryArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if err := scheduler.Register(job); err != nil {
+			return nil, fmt.Errorf("invalid scheduled query %q: %w", job.Name, err)
+		}
+	}
+	return scheduler, nil
+}
+
+// withDefaultQueryArgs decodes definitions using their JSON field names. Query arguments are decoded on
+// top of the defaults, as done for queries run via the API
+func withDefaultQueryArgs(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "json"
+	dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(dc.DecodeHook, func(from, to reflect.Value) (interface{}, error) {
+		if to.Type() == reflect.TypeOf((*query.Args)(nil)) && to.IsNil() && to.CanSet() {
+			to.Set(reflect.ValueOf(query.DefaultArgs()))
+		}
+		return from.Interface(), nil
+	})
+}
+
+// initReporter creates the generator of daily summary reports defined in the configuration, falling
+// back to the default reports if none are defined. The report definitions are decoded using their JSON
+// field names
+func initReporter(runner query.Runner) (*report.Reporter, error) {
+	var reports []report.Config
+	err := viper.UnmarshalKey(conf.ReportsDefinitions, &reports, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "json"
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		reports = report.DefaultReports()
+	}
+
+	return report.New(runner, viper.GetString(conf.ReportsDir), reports,
+		report.WithTimeOfDay(viper.GetString(conf.ReportsTimeOfDay)),
+		report.WithRetention(viper.GetInt(conf.ReportsRetention)),
+	)
+}
+
+// initAuditLog creates the audit log of executed queries, forwarding all entries to the sinks
+// defined in the configuration
+func initAuditLog(ctx context.Context) (*audit.Log, error) {
+	var sinks []audit.SinkConfig
+	err := viper.UnmarshalKey(conf.AuditSinks, &sinks, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "json"
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, sink := range sinks {
+		if err := sink.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid audit sink %d: %w", i, err)
+		}
+	}
+	return audit.New(ctx, audit.WithHistorySize(viper.GetInt(conf.AuditHistorySize)), audit.WithSinks(sinks...))
+}
@@ -16,10 +16,16 @@ const (
 	profilingKey     = "profiling"
 	ProfilingEnabled = profilingKey + ".enabled"
 
+	metricsKey     = "metrics"
+	MetricsEnabled = metricsKey + ".enabled"
+
 	hostsKey         = "hosts"
 	hostsResolverKey = hostsKey + ".resolver"
 
-	HostsResolverType = hostsResolverKey + ".type"
+	HostsResolverType           = hostsResolverKey + ".type"
+	HostsResolverSRVName        = hostsResolverKey + ".srv_name"
+	HostsResolverFile           = hostsResolverKey + ".file"
+	HostsResolverReloadInterval = hostsResolverKey + ".reload_interval"
 
 	querierKey = "querier"
 
@@ -27,9 +33,44 @@ const (
 	QuerierConfig        = querierKey + ".config"
 	QuerierMaxConcurrent = querierKey + ".max_concurrent"
 
+	pushKey       = "push"
+	PushSchedules = pushKey + ".schedules"
+
+	schedulerKey          = "scheduler"
+	SchedulerEnabled      = schedulerKey + ".enabled"
+	SchedulerJobs         = schedulerKey + ".jobs"
+	SchedulerHistorySize  = schedulerKey + ".history_size"
+	SchedulerFileDir      = schedulerKey + ".file_dir"
+	SchedulerAllowedHosts = schedulerKey + ".allowed_hosts"
+
+	reportsKey         = "reports"
+	ReportsDir         = reportsKey + ".dir"
+	ReportsTimeOfDay   = reportsKey + ".time_of_day"
+	ReportsRetention   = reportsKey + ".retention"
+	ReportsDefinitions = reportsKey + ".definitions"
+
+	cacheKey        = "cache"
+	CacheEnabled    = cacheKey + ".enabled"
+	CacheTTL        = cacheKey + ".ttl"
+	CacheMaxEntries = cacheKey + ".max_entries"
+
+	auditKey          = "audit"
+	AuditEnabled      = auditKey + ".enabled"
+	AuditTenantHeader = auditKey + ".tenant_header"
+	AuditHistorySize  = auditKey + ".history_size"
+	AuditSinks        = auditKey + ".sinks"
+
+	pinningKey     = "pinning"
+	PinningEnabled = pinningKey + ".enabled"
+	PinningStore   = pinningKey + ".store"
+
 	serverKey                 = "server"
 	ServerAddr                = serverKey + ".addr"
 	ServerShutdownGracePeriod = serverKey + ".shutdowngraceperiod"
+	ServerUI                  = serverKey + ".ui"
+	ServerMaxBodySize         = serverKey + ".max_body_size"
+	ServerCompression         = serverKey + ".compression"
+	ServerKeys                = serverKey + ".keys"
 )
 
 // Global defaults for command line parameters / arguments
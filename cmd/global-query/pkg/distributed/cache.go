@@ -0,0 +1,188 @@
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+)
+
+const (
+	// DefaultResultCacheTTL denotes the default duration for which the sub-result of a host is considered fresh
+	DefaultResultCacheTTL = 5 * time.Minute
+
+	// DefaultResultCacheMaxEntries denotes the default maximum number of sub-results held by the cache
+	DefaultResultCacheMaxEntries = 4096
+)
+
+// ResultCache caches the (successful) sub-results of the individual hosts of distributed queries, keyed
+// by host, canonical query and time range. Re-running a query then only requires querying the hosts whose
+// sub-results are missing or stale, e.g. after an unreachable host has been fixed. The freshness of each
+// host's sub-result is tracked individually
+type ResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	entries map[string]cacheEntry
+	sync.Mutex
+}
+
+type cacheEntry struct {
+	result   *results.Result
+	storedAt time.Time
+}
+
+// NewResultCache instantiates a new cache for per-host sub-results, considering them fresh for ttl and
+// retaining at most maxEntries of them (evicting the oldest ones first). Non-positive values imply the
+// respective defaults
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	if ttl <= 0 {
+		ttl = DefaultResultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultResultCacheMaxEntries
+	}
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// WithResultCache enables caching of per-host sub-results, controlled via the cache mode of each query
+func WithResultCache(cache *ResultCache) QueryOption {
+	return func(qr *QueryRunner) {
+		qr.cache = cache
+	}
+}
+
+// Len returns the number of sub-results currently held by the cache (including stale ones)
+func (c *ResultCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.entries)
+}
+
+// lookup splits the list of hosts into those with a fresh sub-result in the cache (which are returned
+// as copies) and those that have to be queried
+func (c *ResultCache) lookup(hostList hosts.Hosts, key string, t time.Time) (cached []*results.Result, missing hosts.Hosts, stats *results.CacheStats) {
+	c.Lock()
+	defer c.Unlock()
+
+	stats = &results.CacheStats{}
+	for _, host := range hostList {
+		entry, exists := c.entries[entryKey(host, key)]
+		if !exists || t.Sub(entry.storedAt) >= c.ttl {
+			missing = append(missing, host)
+			stats.Misses++
+			continue
+		}
+
+		cached = append(cached, cloneResult(entry.result))
+		if stats.CachedAt == nil {
+			stats.CachedAt = make(map[string]time.Time)
+		}
+		stats.CachedAt[host] = entry.storedAt
+		stats.Hits++
+	}
+	return
+}
+
+// store adds the sub-result of a host to the cache, evicting stale (or, if there are none, the oldest)
+// entries if the cache is full
+func (c *ResultCache) store(host, key string, res *results.Result, t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		c.evict(t)
+	}
+	c.entries[entryKey(host, key)] = cacheEntry{
+		result:   cloneResult(res),
+		storedAt: t,
+	}
+}
+
+func (c *ResultCache) evict(t time.Time) {
+	var (
+		oldestKey string
+		oldest    time.Time
+	)
+	for key, entry := range c.entries {
+		if t.Sub(entry.storedAt) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// serve emits the cached sub-results, followed by the ones received from the queried hosts (if any),
+// storing the successful ones in the cache
+func (c *ResultCache) serve(ctx context.Context, key string, cached []*results.Result, queryResults <-chan *results.Result) <-chan *results.Result {
+	res := make(chan *results.Result)
+	go func() {
+		defer close(res)
+
+		for _, qr := range cached {
+			select {
+			case <-ctx.Done():
+				return
+			case res <- qr:
+			}
+		}
+
+		if queryResults == nil {
+			return
+		}
+		for qr := range queryResults {
+			if qr.Err() == nil && qr.Hostname != "" {
+				c.store(qr.Hostname, key, qr, time.Now())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case res <- qr:
+			}
+		}
+	}()
+	return res
+}
+
+func entryKey(host, key string) string {
+	return host + "|" + key
+}
+
+// cacheKey returns the canonical representation of a query, covering all of its arguments affecting the
+// sub-result of an individual host. The time range is represented by the database blocks it covers, since
+// the sub-result of a host does not change in between two writeouts (except for live data)
+func cacheKey(args *query.Args, stmt *query.Statement) string {
+	canonical := *args
+	canonical.QueryHosts, canonical.Caller, canonical.Cache = "", "", ""
+	canonical.First, canonical.Last = "", ""
+
+	return fmt.Sprintf("%s|%d-%d", canonical.ToJSONString(), stmt.First/goDB.DBWriteInterval, stmt.Last/goDB.DBWriteInterval)
+}
+
+// cloneResult copies a sub-result, since the results received from the hosts are modified while
+// aggregating them
+func cloneResult(res *results.Result) *results.Result {
+	cp := *res
+	cp.Rows = slices.Clone(res.Rows)
+	cp.HostsStatuses = maps.Clone(res.HostsStatuses)
+	cp.Summary.Interfaces = slices.Clone(res.Summary.Interfaces)
+	return &cp
+}
@@ -0,0 +1,130 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/stretchr/testify/require"
+)
+
+// testQuerier returns a single row per host, failing for all hosts marked as unreachable
+type testQuerier struct {
+	unreachable map[string]bool
+	queried     []string
+
+	sync.Mutex
+}
+
+func (q *testQuerier) Query(_ context.Context, hostList hosts.Hosts, _ *query.Args) <-chan *results.Result {
+	res := make(chan *results.Result, len(hostList))
+	for _, host := range hostList {
+		q.Lock()
+		q.queried = append(q.queried, host)
+		q.Unlock()
+
+		qr := results.New()
+		qr.Hostname = host
+		if q.unreachable[host] {
+			qr.SetErr(errors.New("connection refused"))
+		} else {
+			qr.Rows = results.Rows{testRow(host, 1, 2, 3, 4)}
+			qr.Summary.Hits.Total = 1
+			qr.HostsStatuses[host] = results.Status{}
+		}
+		res <- qr
+	}
+	close(res)
+	return res
+}
+
+func (q *testQuerier) reset() []string {
+	q.Lock()
+	defer q.Unlock()
+
+	queried := q.queried
+	sort.Strings(queried)
+	q.queried = nil
+	return queried
+}
+
+func TestResultCache(t *testing.T) {
+	querier := &testQuerier{unreachable: map[string]bool{"hostC": true}}
+	runner := NewQueryRunner(hosts.NewStringResolver(true), querier,
+		WithResultCache(NewResultCache(time.Minute, 0)),
+	)
+
+	args := query.NewArgs("sip,dip,dport,proto", "eth0", query.WithFirst("1704067200"), query.WithLast("1704153600")).AddOutputs(io.Discard)
+	args.QueryHosts = "hostA,hostB,hostC"
+
+	res, err := runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Equal(t, []string{"hostA", "hostB", "hostC"}, querier.reset())
+	require.Equal(t, &results.CacheStats{Misses: 3}, res.Summary.Cache)
+	require.Len(t, res.Rows, 2)
+
+	// once the unreachable host has been fixed, re-running the query only queries that host
+	querier.unreachable = nil
+	res, err = runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Equal(t, []string{"hostC"}, querier.reset())
+	require.Equal(t, 2, res.Summary.Cache.Hits)
+	require.Equal(t, 1, res.Summary.Cache.Misses)
+	require.Contains(t, res.Summary.Cache.CachedAt, "hostA")
+	require.Contains(t, res.Summary.Cache.CachedAt, "hostB")
+	require.Len(t, res.Rows, 3)
+	require.Len(t, res.HostsStatuses, 3)
+
+	// all sub-results are fresh now
+	res, err = runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Empty(t, querier.reset())
+	require.Equal(t, 3, res.Summary.Cache.Hits)
+	require.Len(t, res.Rows, 3)
+
+	// a different query does not match the cached sub-results
+	args.Condition = "dport = 443"
+	_, err = runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Len(t, querier.reset(), 3)
+
+	// the cache can be refreshed or bypassed on a per-query basis
+	args.Cache = query.CacheRefresh
+	res, err = runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Len(t, querier.reset(), 3)
+	require.Zero(t, res.Summary.Cache.Hits)
+
+	args.Cache = query.CacheOff
+	res, err = runner.Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Len(t, querier.reset(), 3)
+	require.Nil(t, res.Summary.Cache)
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	cache := NewResultCache(time.Minute, 2)
+	t0 := time.Now()
+
+	cache.store("hostA", "q", results.New(), t0)
+	cache.store("hostB", "q", results.New(), t0.Add(time.Second))
+	cache.store("hostC", "q", results.New(), t0.Add(2*time.Second))
+	require.Equal(t, 2, cache.Len())
+
+	// the oldest sub-result was evicted
+	cached, missing, stats := cache.lookup(hosts.Hosts{"hostA", "hostB", "hostC"}, "q", t0.Add(3*time.Second))
+	require.Len(t, cached, 2)
+	require.Equal(t, hosts.Hosts{"hostA"}, missing)
+	require.Equal(t, 2, stats.Hits)
+
+	// stale sub-results are not served
+	_, missing, _ = cache.lookup(hosts.Hosts{"hostB", "hostC"}, "q", t0.Add(2*time.Minute))
+	require.Equal(t, hosts.Hosts{"hostB", "hostC"}, missing)
+}
@@ -0,0 +1,163 @@
+package distributed
+
+import (
+	"sort"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// MirrorTolerance denotes the maximum relative deviation between the counters of two rows
+// for them to be considered mirrored. Probes on either side of a link rarely observe exactly
+// the same traffic (e.g. due to drops or capture start / stop times), hence a small deviation
+// is permitted
+const MirrorTolerance = 0.05
+
+// hostRows holds the rows returned by an individual host
+type hostRows struct {
+	host string
+	rows results.Rows
+}
+
+// mirrorKey identifies a flow in a given time bin independent of the host / interface it
+// was observed on
+func mirrorKey(row *results.Row) results.MergeableAttributes {
+	labels := row.Labels
+	labels.Hostname, labels.HostID, labels.Iface = "", "", ""
+
+	return results.MergeableAttributes{
+		Labels:     labels,
+		Attributes: row.Attributes,
+	}
+}
+
+// isMirrored determines if two counters describe the same traffic observed in inverse
+// directions, i.e. what was received by one host was sent by the other and vice versa
+func isMirrored(a, b types.Counters) bool {
+	return withinTolerance(a.BytesRcvd, b.BytesSent) &&
+		withinTolerance(a.BytesSent, b.BytesRcvd) &&
+		withinTolerance(a.PacketsRcvd, b.PacketsSent) &&
+		withinTolerance(a.PacketsSent, b.PacketsRcvd)
+}
+
+func withinTolerance(a, b uint64) bool {
+	if a == b {
+		return true
+	}
+	lower, upper := a, b
+	if lower > upper {
+		lower, upper = upper, lower
+	}
+	return float64(upper-lower) <= MirrorTolerance*float64(upper)
+}
+
+// dedupRows detects mirrored rows, i.e. rows of the same flow and time bin reported by different
+// hosts in inverse directions. Depending on the mode, the row of the host whose name sorts last is
+// removed (query.DedupMerge) or both rows are flagged (query.DedupFlag). Each row is paired at
+// most once.
+//
+// It returns the number of mirrored rows detected and the counters of all removed rows
+func dedupRows(perHost []hostRows, mode string) (mirrored int, removed types.Counters) {
+
+	// ensure a deterministic choice of which row is kept
+	sort.Slice(perHost, func(i, j int) bool {
+		return perHost[i].host < perHost[j].host
+	})
+
+	type rowRef struct {
+		host, row int
+	}
+
+	candidates := make(map[results.MergeableAttributes][]rowRef)
+	for h := range perHost {
+		for r := range perHost[h].rows {
+			key := mirrorKey(&perHost[h].rows[r])
+			candidates[key] = append(candidates[key], rowRef{h, r})
+		}
+	}
+
+	var drop = make(map[rowRef]struct{})
+	for _, refs := range candidates {
+		if len(refs) < 2 {
+			continue
+		}
+
+		paired := make([]bool, len(refs))
+		for i := 0; i < len(refs); i++ {
+			if paired[i] {
+				continue
+			}
+			a := &perHost[refs[i].host].rows[refs[i].row]
+			for j := i + 1; j < len(refs); j++ {
+				if paired[j] || refs[i].host == refs[j].host {
+					continue
+				}
+				b := &perHost[refs[j].host].rows[refs[j].row]
+				if !isMirrored(a.Counters, b.Counters) {
+					continue
+				}
+
+				paired[i], paired[j] = true, true
+				mirrored++
+
+				if mode == query.DedupFlag {
+					a.Mirrored, b.Mirrored = true, true
+				} else {
+					drop[refs[j]] = struct{}{}
+					removed = removed.Add(b.Counters)
+				}
+				break
+			}
+		}
+	}
+
+	if len(drop) == 0 {
+		return
+	}
+
+	for h := range perHost {
+		kept := perHost[h].rows[:0]
+		for r, row := range perHost[h].rows {
+			if _, exists := drop[rowRef{h, r}]; !exists {
+				kept = append(kept, row)
+			}
+		}
+		perHost[h].rows = kept
+	}
+
+	return
+}
+
+// mergeDeduplicated runs the dedup stage on the rows retained per host and merges the remaining
+// rows into rm, updating the summary of the final result accordingly. It returns the set of
+// flagged rows (if any)
+func mergeDeduplicated(finalResult *results.Result, rm results.RowsMap, perHost []hostRows, mode string) map[results.MergeableAttributes]struct{} {
+	var nRows int
+	for _, hr := range perHost {
+		nRows += len(hr.rows)
+	}
+
+	mirrored, removed := dedupRows(perHost, mode)
+	finalResult.Summary.MirroredRows = mirrored
+	finalResult.Summary.Totals = finalResult.Summary.Totals.Sub(removed)
+
+	var (
+		flagged = make(map[results.MergeableAttributes]struct{})
+		merged  int
+	)
+	for _, hr := range perHost {
+		nRows -= len(hr.rows)
+		for _, row := range hr.rows {
+			if row.Mirrored {
+				flagged[results.MergeableAttributes{Labels: row.Labels, Attributes: row.Attributes}] = struct{}{}
+			}
+		}
+		merged += rm.MergeRows(hr.rows)
+	}
+
+	// both merged and removed rows no longer count towards the total number of hits
+	finalResult.Summary.Hits.Total -= merged + nRows
+
+	return flagged
+}
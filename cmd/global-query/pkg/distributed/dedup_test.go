@@ -0,0 +1,123 @@
+package distributed
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testRow(host string, bytesRcvd, bytesSent, packetsRcvd, packetsSent uint64) results.Row {
+	return results.Row{
+		Labels: results.Labels{
+			Hostname: host,
+			Iface:    "eth0",
+		},
+		Attributes: results.Attributes{
+			SrcIP:   netip.MustParseAddr("10.0.0.1"),
+			DstIP:   netip.MustParseAddr("10.0.0.2"),
+			IPProto: 6,
+			DstPort: 443,
+		},
+		Counters: types.Counters{
+			BytesRcvd:   bytesRcvd,
+			BytesSent:   bytesSent,
+			PacketsRcvd: packetsRcvd,
+			PacketsSent: packetsSent,
+		},
+	}
+}
+
+func TestIsMirrored(t *testing.T) {
+	var tests = []struct {
+		name     string
+		a, b     types.Counters
+		expected bool
+	}{
+		{"exact inverse",
+			types.Counters{BytesRcvd: 1000, BytesSent: 200, PacketsRcvd: 10, PacketsSent: 4},
+			types.Counters{BytesRcvd: 200, BytesSent: 1000, PacketsRcvd: 4, PacketsSent: 10},
+			true,
+		},
+		{"inverse within tolerance",
+			types.Counters{BytesRcvd: 1000, BytesSent: 200, PacketsRcvd: 100, PacketsSent: 40},
+			types.Counters{BytesRcvd: 198, BytesSent: 980, PacketsRcvd: 39, PacketsSent: 97},
+			true,
+		},
+		{"same direction",
+			types.Counters{BytesRcvd: 1000, BytesSent: 200, PacketsRcvd: 10, PacketsSent: 4},
+			types.Counters{BytesRcvd: 1000, BytesSent: 200, PacketsRcvd: 10, PacketsSent: 4},
+			false,
+		},
+		{"inverse outside tolerance",
+			types.Counters{BytesRcvd: 1000, BytesSent: 200, PacketsRcvd: 10, PacketsSent: 4},
+			types.Counters{BytesRcvd: 200, BytesSent: 500, PacketsRcvd: 4, PacketsSent: 5},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, isMirrored(test.a, test.b))
+			require.Equal(t, test.expected, isMirrored(test.b, test.a))
+		})
+	}
+}
+
+func TestDedupRows(t *testing.T) {
+	newPerHost := func() []hostRows {
+		return []hostRows{
+			{host: "hostB", rows: results.Rows{testRow("hostB", 200, 1000, 4, 10)}},
+			{host: "hostA", rows: results.Rows{testRow("hostA", 1000, 200, 10, 4)}},
+			{host: "hostC", rows: results.Rows{testRow("hostC", 50, 50, 1, 1)}},
+		}
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		perHost := newPerHost()
+		mirrored, removed := dedupRows(perHost, query.DedupMerge)
+
+		require.Equal(t, 1, mirrored)
+		require.Equal(t, types.Counters{BytesRcvd: 200, BytesSent: 1000, PacketsRcvd: 4, PacketsSent: 10}, removed)
+
+		// the row of the host sorting last is removed
+		require.Equal(t, "hostA", perHost[0].host)
+		require.Len(t, perHost[0].rows, 1)
+		require.Equal(t, "hostB", perHost[1].host)
+		require.Empty(t, perHost[1].rows)
+		require.Len(t, perHost[2].rows, 1)
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		perHost := newPerHost()
+		mirrored, removed := dedupRows(perHost, query.DedupFlag)
+
+		require.Equal(t, 1, mirrored)
+		require.Equal(t, types.Counters{}, removed)
+
+		require.True(t, perHost[0].rows[0].Mirrored)
+		require.True(t, perHost[1].rows[0].Mirrored)
+		require.False(t, perHost[2].rows[0].Mirrored)
+	})
+
+	t.Run("merge into final result", func(t *testing.T) {
+		perHost := newPerHost()
+
+		finalResult := results.New()
+		finalResult.Summary.Hits.Total = 3
+		finalResult.Summary.Totals = types.Counters{BytesRcvd: 1250, BytesSent: 1250, PacketsRcvd: 15, PacketsSent: 15}
+
+		rm := make(results.RowsMap)
+		flagged := mergeDeduplicated(finalResult, rm, perHost, query.DedupMerge)
+
+		require.Empty(t, flagged)
+		require.Len(t, rm, 2)
+		require.Equal(t, 1, finalResult.Summary.MirroredRows)
+		require.Equal(t, 2, finalResult.Summary.Hits.Total)
+		require.Equal(t, types.Counters{BytesRcvd: 1050, BytesSent: 250, PacketsRcvd: 11, PacketsSent: 5}, finalResult.Summary.Totals)
+	})
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net/netip"
+	"time"
 
 	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
+	"github.com/els0r/goProbe/pkg/goDB"
 	"github.com/els0r/goProbe/pkg/query"
 	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/goProbe/pkg/types"
@@ -20,6 +24,7 @@ import (
 type QueryRunner struct {
 	resolver hosts.Resolver
 	querier  Querier
+	cache    *ResultCache
 }
 
 // QueryOption configures the query runner
@@ -56,6 +61,24 @@ func (q *QueryRunner) Run(ctx context.Context, args *query.Args) (*results.Resul
 		return nil, fmt.Errorf("failed to prepare query statement: %w", err)
 	}
 
+	// the time zone is only applied to the final result, keeping the timestamps of the
+	// individual hosts' rows comparable while merging them
+	queryArgs.TimeZone = ""
+
+	// the statistics per time bin cannot be merged across hosts. Hence, the hosts are queried for the
+	// traffic per time bin of all rows instead, from which the statistics are computed once merged
+	numResults, hostStmt := queryArgs.NumResults, stmt
+	if stmt.Stats {
+		queryArgs.Stats = false
+		queryArgs.Query = types.TimeName + types.AttrSep + queryArgs.Query
+		queryArgs.NumResults = query.MaxResults
+		queryArgs.Having, queryArgs.Analysis, queryArgs.Sample = "", "", 0
+		hostStmt, err = queryArgs.Prepare()
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare query statement for the time bins: %w", err)
+		}
+	}
+
 	hostList, err := q.prepareHostList(ctx, args.QueryHosts)
 	if err != nil {
 		return nil, err // prepareHostList() returns formatted error
@@ -66,28 +89,109 @@ func (q *QueryRunner) Run(ctx context.Context, args *query.Args) (*results.Resul
 
 	logger.Info("reading query results from querier")
 
-	finalResult := aggregateResults(ctx, stmt,
-		q.querier.Query(ctx, hostList, &queryArgs),
-	)
+	// progress is reported per host, hence the queried hosts are not asked to report their own
+	queryResults, cacheStats := q.query(ctx, hostStmt, hostList, &queryArgs)
+	finalResult := aggregateResults(ctx, hostStmt, len(hostList), queryResults)
+	if stmt.Stats {
+		binStats(finalResult, stmt)
+	}
 
+	finalResult.Summary.Cache = cacheStats
 	finalResult.End()
 
-	// truncate results based on the limit
-	if queryArgs.NumResults < uint64(len(finalResult.Rows)) {
-		finalResult.Rows = finalResult.Rows[:queryArgs.NumResults]
+	// truncate results based on the limit (unless sampled, in which case the samples of all hosts
+	// are retained to keep them representative)
+	if stmt.Sample == 0 && numResults < uint64(len(finalResult.Rows)) {
+		finalResult.Rows = finalResult.Rows[:numResults]
 	}
 	finalResult.Summary.Hits.Displayed = len(finalResult.Rows)
 
+	// the hostnames recorded by the hosts are restricted to the IP addresses of the remaining rows
+	if hostnames := finalResult.Hostnames; len(hostnames) > 0 {
+		finalResult.Hostnames = nil
+		finalResult.AddHostnames(func(ip netip.Addr) (string, bool) {
+			name, exists := hostnames[ip.String()]
+			return name, exists
+		})
+	}
+
+	if loc := stmt.Location(); loc != nil {
+		finalResult.In(loc)
+	}
+
+	// the hashes of the hosts' rows are lost when merging, hence they are assigned to the final rows
+	if stmt.FlowHash {
+		finalResult.AddFlowHashes()
+	}
+	if stmt.CommunityID {
+		finalResult.AddCommunityIDs(stmt.CommunityIDSeed)
+	}
+
 	return finalResult, nil
 }
 
+// binStats replaces the merged rows of all time bins of the result by their statistics (see Rows.BinStats()),
+// subsequently applying the predicate, the sample and the sort order of the statement
+func binStats(result *results.Result, stmt *query.Statement) {
+	rows := result.Rows.BinStats(result.Summary.First, result.Summary.Last, time.Duration(goDB.DBWriteInterval)*time.Second)
+
+	// all rows of the hosts are available, hence the totals are those of the remaining rows
+	if having := stmt.Predicate(); having != nil {
+		rows, result.Summary.Totals = rows.Filter(having)
+	}
+	result.Summary.Hits.Total = len(rows)
+
+	if stmt.Sample > 0 {
+		rows, result.Summary.Sample = rows.Sample(stmt.Sample, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	results.By(stmt.SortBy, stmt.Direction, stmt.SortAscending).Sort(rows)
+
+	result.Rows = rows
+}
+
+// query runs the query on all hosts, serving the sub-results of hosts that are fresh in the cache (if
+// enabled) instead of querying them (unless requested otherwise via the cache mode of the query)
+func (q *QueryRunner) query(ctx context.Context, stmt *query.Statement, hostList hosts.Hosts, args *query.Args) (<-chan *results.Result, *results.CacheStats) {
+	queryCtx := query.WithProgress(ctx, nil)
+
+	// live data changes continuously, hence it is never cached
+	if q.cache == nil || args.Cache == query.CacheOff || stmt.Live {
+		return q.querier.Query(queryCtx, hostList, args), nil
+	}
+
+	key := cacheKey(args, stmt)
+
+	var (
+		cached     []*results.Result
+		missing    = hostList
+		cacheStats = &results.CacheStats{Misses: len(hostList)}
+	)
+	if args.Cache != query.CacheRefresh {
+		cached, missing, cacheStats = q.cache.lookup(hostList, key, time.Now())
+	}
+
+	logging.FromContext(ctx).With("hits", cacheStats.Hits, "misses", cacheStats.Misses).Debug("looked up cached results")
+
+	// there is nothing to query if all sub-results were served from the cache
+	var queryResults <-chan *results.Result
+	if len(missing) > 0 {
+		queryResults = q.querier.Query(queryCtx, missing, args)
+	}
+
+	return q.cache.serve(ctx, key, cached, queryResults), cacheStats
+}
+
 func (q *QueryRunner) prepareHostList(ctx context.Context, queryHosts string) (hostList hosts.Hosts, err error) {
 	ctx, span := tracing.Start(ctx, "(*distributed.QueryRunner).prepareHostList", trace.WithAttributes(attribute.String("hosts", queryHosts)))
 	defer span.End()
 
-	// Handle ANY (all hosts) case
+	// Handle ANY (all hosts) case, preferring the hosts discovered by the resolver (if supported)
 	if types.IsAnySelector(queryHosts) {
-		if querierAnyable, ok := q.querier.(QuerierAnyable); ok {
+		if discoverer, ok := q.resolver.(hosts.Discoverer); ok {
+			if hostList, err = discoverer.AllHosts(ctx); err != nil {
+				err = fmt.Errorf("failed to discover list of all hosts: %w", err)
+			}
+		} else if querierAnyable, ok := q.querier.(QuerierAnyable); ok {
 			if hostList, err = querierAnyable.AllHosts(); err != nil {
 				err = fmt.Errorf("failed to extract list of all hosts: %w", err)
 			}
@@ -107,8 +211,12 @@ func (q *QueryRunner) prepareHostList(ctx context.Context, queryHosts string) (h
 }
 
 // aggregateResults takes finished query workloads from the workloads channel, aggregates the result by merging the rows and summaries,
-// and returns the final result. The `tracker` variable provides information about potential Run failures for individual hosts
-func aggregateResults(ctx context.Context, stmt *query.Statement, queryResults <-chan *results.Result) (finalResult *results.Result) {
+// and returns the final result. The `tracker` variable provides information about potential Run failures for individual hosts.
+//
+// If a dedup mode is set, the rows of all hosts are retained until all results have been received in order to detect mirrored
+// rows (see dedupRows()) prior to merging them. If requested via the context, the progress is reported each time a host
+// has returned its result
+func aggregateResults(ctx context.Context, stmt *query.Statement, nHosts int, queryResults <-chan *results.Result) (finalResult *results.Result) {
 	ctx, span := tracing.Start(ctx, "aggregateResults")
 	defer span.End()
 
@@ -118,15 +226,67 @@ func aggregateResults(ctx context.Context, stmt *query.Statement, queryResults <
 
 	var rowMap = make(results.RowsMap)
 
+	// the traffic by role isn't covered by the row map and hence merged separately (if requested)
+	var rolesMap = make(results.RolesMap)
+
 	// tracker maps for meta info
 	var ifaceMap = make(map[string]struct{})
 
+	// rows retained per host for the dedup stage (if enabled)
+	var perHost []hostRows
+
 	logger := logging.FromContext(ctx)
 
+	var (
+		progressFn = query.ProgressFromContext(ctx)
+		progress   = query.Progress{HostsTotal: nHosts}
+		start      = time.Now()
+	)
+	reportProgress := func(nRows int) {
+		if progressFn == nil {
+			return
+		}
+		progress.HostsDone++
+		progress.RowsAggregated += uint64(nRows)
+		progress.Elapsed = time.Since(start)
+		progress.EstimateETA()
+		progressFn(progress)
+	}
+
 	defer func() {
+		var mirroredKeys map[results.MergeableAttributes]struct{}
+		if stmt.Dedup != "" {
+			mirroredKeys = mergeDeduplicated(finalResult, rowMap, perHost, stmt.Dedup)
+		}
+
 		if len(rowMap) > 0 {
 			finalResult.Rows = rowMap.ToRowsSorted(results.By(stmt.SortBy, stmt.Direction, stmt.SortAscending))
+
+			// the rows of each host fulfill the predicate (if any), which doesn't necessarily hold for the
+			// rows merged across hosts. Rows joined by role carry the traffic of hosts instead of flows
+			if having := stmt.Predicate(); having != nil && !stmt.Roles {
+				nRows, merged := len(finalResult.Rows), types.Counters{}
+				for _, row := range finalResult.Rows {
+					merged = merged.Add(row.Counters)
+				}
+				var remaining types.Counters
+				finalResult.Rows, remaining = finalResult.Rows.Filter(having)
+
+				// the rows of the hosts may be limited, hence only the traffic of the removed rows is deducted
+				finalResult.Summary.Totals = finalResult.Summary.Totals.Sub(merged.Sub(remaining))
+				finalResult.Summary.Hits.Total -= nRows - len(finalResult.Rows)
+			}
+			if stmt.Roles {
+				rolesMap.Assign(finalResult.Rows)
+			}
+			for i, row := range finalResult.Rows {
+				if _, exists := mirroredKeys[results.MergeableAttributes{Labels: row.Labels, Attributes: row.Attributes}]; exists {
+					finalResult.Rows[i].Mirrored = true
+				}
+			}
 		}
+		finalResult.Summary.Saturated = finalResult.Summary.Saturated ||
+			finalResult.Summary.Totals.IsSaturated() || finalResult.Rows.Saturated()
 		finalResult.End()
 	}()
 
@@ -149,10 +309,12 @@ func aggregateResults(ctx context.Context, stmt *query.Statement, queryResults <
 				finalResult.HostsStatuses.SetErr(qr.Hostname, uerr)
 
 				logger.Error(qr.Err())
+				reportProgress(0)
 				continue
 			}
 
 			res := qr
+			hostname := res.Hostname
 
 			for host, status := range res.HostsStatuses {
 				finalResult.HostsStatuses[host] = status
@@ -163,8 +325,17 @@ func aggregateResults(ctx context.Context, stmt *query.Statement, queryResults <
 				res.Hostname = ""
 			}
 
-			// merges the traffic data
-			merged := rowMap.MergeRows(res.Rows)
+			// merges the traffic data (deferred until all results are available if mirrored rows
+			// have to be detected)
+			var merged int
+			if stmt.Dedup != "" {
+				perHost = append(perHost, hostRows{host: hostname, rows: res.Rows})
+			} else {
+				merged = rowMap.MergeRows(res.Rows)
+				if stmt.Roles {
+					rolesMap.MergeRows(res.Rows)
+				}
+			}
 
 			// merges the metadata
 			for _, iface := range res.Summary.Interfaces {
@@ -180,11 +351,34 @@ func aggregateResults(ctx context.Context, stmt *query.Statement, queryResults <
 			finalResult.Query = res.Query
 			finalResult.Summary.First = res.Summary.First
 			finalResult.Summary.Last = res.Summary.Last
+			if alignment := res.Summary.Alignment; alignment != nil {
+				if finalResult.Summary.Alignment == nil {
+					finalResult.Summary.Alignment = &results.Alignment{
+						Mode:      alignment.Mode,
+						Requested: alignment.Requested,
+					}
+				}
+				finalResult.Summary.Alignment.InterpolatedBlocks += alignment.InterpolatedBlocks
+			}
 			finalResult.Summary.Totals = finalResult.Summary.Totals.Add(res.Summary.Totals)
+			finalResult.Summary.CorruptBlocks += res.Summary.CorruptBlocks
+			finalResult.Summary.Saturated = finalResult.Summary.Saturated || res.Summary.Saturated
+			finalResult.Summary.Sample = finalResult.Summary.Sample.Add(res.Summary.Sample)
+			finalResult.Plan = finalResult.Plan.Add(res.Plan)
+			for ip, name := range res.Hostnames {
+				if _, exists := finalResult.Hostnames[ip]; !exists {
+					if finalResult.Hostnames == nil {
+						finalResult.Hostnames = make(map[string]string)
+					}
+					finalResult.Hostnames[ip] = name
+				}
+			}
 
 			// take the total from the query result. Since there may be overlap between the queries of two
 			// different systems, the overlap has to be deducted from the total
 			finalResult.Summary.Hits.Total += res.Summary.Hits.Total - merged
+
+			reportProgress(len(res.Rows))
 		}
 	}
 }
@@ -0,0 +1,156 @@
+package hosts
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/telemetry/logging"
+)
+
+// DefaultReloadInterval denotes the default interval in which the hosts file is checked for changes
+const DefaultReloadInterval = 30 * time.Second
+
+// FileResolver discovers the hosts via a hosts file, listing one host per line (blank lines and
+// comments starting with # are ignored). Once watched, the file is reloaded whenever it changes,
+// hence hosts can be added / removed without restarting the server. Explicitly listed hosts are
+// resolved in the same way as by the StringResolver
+type FileResolver struct {
+	path           string
+	reloadInterval time.Duration
+
+	hostList Hosts
+	modTime  time.Time
+	size     int64
+
+	*StringResolver
+
+	sync.RWMutex
+}
+
+// NewFileResolver creates a new hosts file based resolver, performing an initial read of the file
+func NewFileResolver(path string, reloadInterval time.Duration) (*FileResolver, error) {
+	if reloadInterval <= 0 {
+		reloadInterval = DefaultReloadInterval
+	}
+	f := &FileResolver{
+		path:           filepath.Clean(path),
+		reloadInterval: reloadInterval,
+		StringResolver: NewStringResolver(true),
+	}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// AllHosts returns all hosts listed in the hosts file (as of its last reload)
+func (f *FileResolver) AllHosts(_ context.Context) (Hosts, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if len(f.hostList) == 0 {
+		return nil, fmt.Errorf("%w in hosts file %s", ErrNoHostsDiscovered, f.path)
+	}
+	return append(Hosts(nil), f.hostList...), nil
+}
+
+// Watch starts reloading the hosts file periodically (until the context is cancelled) whenever
+// it has changed
+func (f *FileResolver) Watch(ctx context.Context) {
+	go f.reloadPeriodically(ctx)
+}
+
+// Reload reads the hosts file if it has changed since the last read, returning if that was the case.
+// If the file cannot be read, the hosts of the last successful read are retained
+func (f *FileResolver) Reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to access hosts file: %w", err)
+	}
+
+	f.RLock()
+	unchanged := info.ModTime().Equal(f.modTime) && info.Size() == f.size
+	f.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	hostList, err := readHostsFile(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	f.Lock()
+	f.hostList, f.modTime, f.size = hostList, info.ModTime(), info.Size()
+	f.Unlock()
+
+	return true, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+
+func (f *FileResolver) reloadPeriodically(ctx context.Context) {
+
+	logger := logging.FromContext(ctx).With("path", f.path)
+	ticker := time.NewTicker(f.reloadInterval)
+	logger.With("interval", f.reloadInterval.Round(time.Second)).Info("watching hosts file")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stopping hosts file watcher")
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			changed, err := f.Reload()
+			if err != nil {
+				logger.Errorf("failed to reload hosts file: %s", err)
+				continue
+			}
+			if changed {
+				f.RLock()
+				logger.With("hosts", len(f.hostList)).Info("hosts file reloaded")
+				f.RUnlock()
+			}
+		}
+	}
+}
+
+func readHostsFile(path string) (hostList Hosts, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	var hostMap = make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if host := strings.TrimSpace(line); host != "" {
+			hostMap[host] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	hostList = make(Hosts, 0, len(hostMap))
+	for host := range hostMap {
+		hostList = append(hostList, host)
+	}
+	sort.Strings(hostList)
+
+	return hostList, nil
+}
@@ -0,0 +1,86 @@
+package hosts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.Nil(t, os.WriteFile(path, []byte(`# probes of site A
+hostB
+hostA  # primary
+
+10.0.0.1:8145
+hostA
+`), 0600))
+
+	resolver, err := NewFileResolver(path, time.Minute)
+	require.Nil(t, err)
+
+	hostList, err := resolver.AllHosts(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, Hosts{"10.0.0.1:8145", "hostA", "hostB"}, hostList)
+
+	// an unchanged file is not read again
+	changed, err := resolver.Reload()
+	require.Nil(t, err)
+	require.False(t, changed)
+
+	// changes are picked up upon reload
+	require.Nil(t, os.WriteFile(path, []byte("hostC\n"), 0600))
+	require.Nil(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	changed, err = resolver.Reload()
+	require.Nil(t, err)
+	require.True(t, changed)
+
+	hostList, err = resolver.AllHosts(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, Hosts{"hostC"}, hostList)
+
+	// the hosts of the last successful read are retained if the file cannot be read
+	require.Nil(t, os.Remove(path))
+	_, err = resolver.Reload()
+	require.NotNil(t, err)
+
+	hostList, err = resolver.AllHosts(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, Hosts{"hostC"}, hostList)
+}
+
+func TestFileResolverWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.Nil(t, os.WriteFile(path, []byte("hostA\n"), 0600))
+
+	resolver, err := NewFileResolver(path, 10*time.Millisecond)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resolver.Watch(ctx)
+
+	require.Nil(t, os.WriteFile(path, []byte("hostA\nhostB\n"), 0600))
+	require.Eventually(t, func() bool {
+		hostList, err := resolver.AllHosts(ctx)
+		return err == nil && len(hostList) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileResolverEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.Nil(t, os.WriteFile(path, []byte("# no hosts yet\n"), 0600))
+
+	resolver, err := NewFileResolver(path, 0)
+	require.Nil(t, err)
+
+	_, err = resolver.AllHosts(context.Background())
+	require.ErrorIs(t, err, ErrNoHostsDiscovered)
+
+	_, err = NewFileResolver(filepath.Join(t.TempDir(), "missing"), 0)
+	require.NotNil(t, err)
+}
@@ -16,6 +16,12 @@ const (
 
 	// StringResolverType denotes a simple string resolver type
 	StringResolverType ResolverType = "string"
+
+	// SRVResolverType denotes a resolver discovering the hosts via DNS SRV records
+	SRVResolverType ResolverType = "dns_srv"
+
+	// FileResolverType denotes a resolver discovering the hosts via a (watched) hosts file
+	FileResolverType ResolverType = "file"
 )
 
 // Resolver returns a list of hosts based on the query string
@@ -23,6 +29,13 @@ type Resolver interface {
 	Resolve(ctx context.Context, query string) (Hosts, error)
 }
 
+// Discoverer extends a Resolver with the support to discover all hosts / targets available for querying,
+// taking precedence over the hosts known to the querier (e.g. when querying "any" host)
+type Discoverer interface {
+	// AllHosts returns a list of all hosts / targets currently discovered
+	AllHosts(ctx context.Context) (Hosts, error)
+}
+
 // StringResolver transforms a comma-separated list of hosts into an array. Sorting is
 // enabled by default
 type StringResolver struct {
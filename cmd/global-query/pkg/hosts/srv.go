@@ -0,0 +1,67 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoHostsDiscovered is returned if discovery yields no hosts at all
+var ErrNoHostsDiscovered = errors.New("no hosts discovered")
+
+// lookupSRVFn denotes a function performing a DNS SRV lookup (c.f. net.Resolver.LookupSRV)
+type lookupSRVFn func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
+// SRVResolver discovers the hosts via the DNS SRV records of a service (e.g. _goprobe._tcp.example.com),
+// addressing each target by host:port. The records are looked up anew for each discovery, hence changes
+// of the fleet take effect as soon as they are visible via DNS. Explicitly listed hosts are resolved in
+// the same way as by the StringResolver
+type SRVResolver struct {
+	name      string
+	lookupSRV lookupSRVFn
+
+	*StringResolver
+}
+
+// NewSRVResolver creates a new DNS SRV based hosts resolver for the (fully qualified) name of the SRV records
+func NewSRVResolver(name string) *SRVResolver {
+	return &SRVResolver{
+		name:           name,
+		lookupSRV:      net.DefaultResolver.LookupSRV,
+		StringResolver: NewStringResolver(true),
+	}
+}
+
+// AllHosts returns the targets of all SRV records (as host:port), sorted and deduplicated
+func (s *SRVResolver) AllHosts(ctx context.Context) (Hosts, error) {
+	_, records, err := s.lookupSRV(ctx, "", "", s.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SRV records of %s: %w", s.name, err)
+	}
+
+	var hostMap = make(map[string]struct{}, len(records))
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+
+		// a target of "." explicitly denotes that the service is not available
+		if target == "" {
+			continue
+		}
+		hostMap[net.JoinHostPort(target, strconv.Itoa(int(record.Port)))] = struct{}{}
+	}
+	if len(hostMap) == 0 {
+		return nil, fmt.Errorf("%w via SRV records of %s", ErrNoHostsDiscovered, s.name)
+	}
+
+	hostList := make(Hosts, 0, len(hostMap))
+	for host := range hostMap {
+		hostList = append(hostList, host)
+	}
+	sort.Strings(hostList)
+
+	return hostList, nil
+}
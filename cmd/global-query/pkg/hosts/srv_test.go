@@ -0,0 +1,73 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRVResolver(t *testing.T) {
+	var tests = []struct {
+		name     string
+		records  []*net.SRV
+		expected Hosts
+		err      error
+	}{
+		{"multiple targets",
+			[]*net.SRV{
+				{Target: "probe-b.example.com.", Port: 8145},
+				{Target: "probe-a.example.com.", Port: 8145},
+				{Target: "probe-a.example.com.", Port: 8146},
+			},
+			Hosts{"probe-a.example.com:8145", "probe-a.example.com:8146", "probe-b.example.com:8145"},
+			nil,
+		},
+		{"duplicate targets",
+			[]*net.SRV{
+				{Target: "probe-a.example.com.", Port: 8145, Priority: 10},
+				{Target: "probe-a.example.com.", Port: 8145, Priority: 20},
+			},
+			Hosts{"probe-a.example.com:8145"},
+			nil,
+		},
+		{"service not available",
+			[]*net.SRV{{Target: ".", Port: 0}},
+			nil,
+			ErrNoHostsDiscovered,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolver := NewSRVResolver("_goprobe._tcp.example.com")
+			resolver.lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				require.Empty(t, service)
+				require.Empty(t, proto)
+				require.Equal(t, "_goprobe._tcp.example.com", name)
+				return name, test.records, nil
+			}
+
+			hostList, err := resolver.AllHosts(context.Background())
+			require.ErrorIs(t, err, test.err)
+			require.Equal(t, test.expected, hostList)
+		})
+	}
+}
+
+func TestSRVResolverLookupError(t *testing.T) {
+	lookupErr := errors.New("no such host")
+
+	resolver := NewSRVResolver("_goprobe._tcp.example.com")
+	resolver.lookupSRV = func(_ context.Context, _, _, _ string) (string, []*net.SRV, error) {
+		return "", nil, lookupErr
+	}
+	_, err := resolver.AllHosts(context.Background())
+	require.ErrorIs(t, err, lookupErr)
+
+	// explicitly listed hosts are resolved without a lookup
+	hostList, err := resolver.Resolve(context.Background(), "hostB,hostA")
+	require.Nil(t, err)
+	require.Equal(t, Hosts{"hostA", "hostB"}, hostList)
+}
@@ -0,0 +1,114 @@
+// Binary for the central collector receiving goDB databases synchronized by goProbe instances
+// (c.f. pkg/goDB/dbsync). The database of each probe is stored below <root>/<probe>, where the
+// probe identity is derived from the common name of its client certificate
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/dbsync"
+	"github.com/els0r/goProbe/pkg/version"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	defaultListenAddr   = ":8147"
+	shutdownGracePeriod = 30 * time.Second
+	readHeaderTimeout   = 10 * time.Second
+)
+
+// Config stores the flags provided to the collector
+type Config struct {
+	ListenAddr string
+	Root       string
+	TLS        dbsync.TLSConfig
+	LogLevel   string
+	Version    bool
+}
+
+func parseCommandLineArgs(cfg *Config) {
+	flag.StringVar(&cfg.ListenAddr, "listen", defaultListenAddr, "Address the collector listens on")
+	flag.StringVar(&cfg.Root, "root", "", "Root directory below which the databases of all probes are stored")
+	flag.StringVar(&cfg.TLS.Cert, "cert", "", "Path to the PEM encoded server certificate")
+	flag.StringVar(&cfg.TLS.Key, "key", "", "Path to the PEM encoded private key of the server certificate")
+	flag.StringVar(&cfg.TLS.CA, "ca", "", "Path to the PEM encoded CA certificate(s) used to verify the probes")
+	flag.StringVar(&cfg.LogLevel, "log-level", "info", "Log level")
+	flag.BoolVar(&cfg.Version, "version", false, "Print version information and exit")
+
+	flag.Parse()
+}
+
+func main() {
+
+	// parse command line arguments
+	var cfg Config
+	parseCommandLineArgs(&cfg)
+	if cfg.Version {
+		fmt.Printf("%s", version.Version())
+		os.Exit(0)
+	}
+	if cfg.Root == "" {
+		fmt.Fprintf(os.Stderr, "no root directory specified\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	err := logging.Init(logging.LevelFromString(cfg.LogLevel), logging.EncodingLogfmt,
+		logging.WithVersion(version.Short()),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	logger := logging.Logger()
+
+	tlsConfig, err := cfg.TLS.ServerConfig()
+	if err != nil {
+		logger.Fatalf("failed to initialize TLS configuration: %v", err)
+	}
+
+	// #nosec G301
+	if err := os.MkdirAll(filepath.Clean(cfg.Root), 0755); err != nil {
+		logger.Fatalf("failed to create root directory: %v", err)
+	}
+
+	// We quit on encountering SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           dbsync.NewServer(cfg.Root),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	logger.With("addr", cfg.ListenAddr, "root", cfg.Root).Info("starting collector")
+	go func() {
+		// certificates are provided via the TLS configuration
+		err := srv.ListenAndServeTLS("", "")
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalf("failed to spawn collector: %s", err)
+		}
+	}()
+
+	// listen for the interrupt signal
+	<-ctx.Done()
+	stop()
+	logger.Info("shutting down gracefully")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("forced shut down of collector: %v", err)
+	}
+}
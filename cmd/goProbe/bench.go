@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	gpconf "github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/cmd/goProbe/flags"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goprobe/bench"
+	"github.com/els0r/telemetry/logging"
+)
+
+// runBench runs the bench subcommand, returning the exit code
+func runBench(args []string) int {
+	benchFlags, cfg, err := flags.ReadBench(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	if err := logging.Init(logging.LevelFromString(benchFlags.LogLevel), logging.EncodingLogfmt); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		return 1
+	}
+
+	// Unless a database path was provided, the benchmark writes to a temporary database
+	if cfg.DBPath == "" {
+		tempDir, err := os.MkdirTemp("", "goprobe_bench")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create temporary database directory: %v\n", err)
+			return 1
+		}
+		defer os.RemoveAll(tempDir)
+		cfg.DBPath = tempDir
+	}
+
+	// The benchmark can be stopped early via SIGTERM or SIGINT (reporting the results so far)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Running benchmark for %v (%d flows, rate: %s)...\n", cfg.Duration, cfg.Flows, benchFlags.Rate)
+	res, err := bench.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchmark failed: %v\n", err)
+		return 1
+	}
+	if err := res.Print(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print results: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runStartupBench benchmarks each interface (one after the other) with its configured ring buffer
+// parameters for the given duration, warning if its ring buffer is predicted insufficient for the link
+// speed and recording the assessment in the status of the capture
+func runStartupBench(ctx context.Context, captureManager *capture.Manager, ifaces gpconf.Ifaces, duration time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	names := make([]string, 0, len(ifaces))
+	for iface := range ifaces {
+		names = append(names, iface)
+	}
+	sort.Strings(names)
+
+	capabilities := captureManager.Capabilities(names...)
+	for _, iface := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		ifaceLogger := logger.With("iface", iface)
+
+		ringBuffer := ifaces[iface].RingBuffer
+		if ringBuffer == nil {
+			ringBuffer = &gpconf.RingBufferConfig{
+				BlockSize: gpconf.DefaultRingBufferBlockSize,
+				NumBlocks: gpconf.DefaultRingBufferNumBlocks,
+			}
+		}
+
+		res, err := startupBench(ctx, ringBuffer, duration)
+		if err != nil {
+			ifaceLogger.Errorf("failed to run startup benchmark: %v", err)
+			continue
+		}
+		assessment := bench.Assess(res, capabilities[iface].Speed, ringBuffer.NumBlocks)
+		captureManager.SetBenchAssessment(iface, assessment)
+
+		ifaceLogger = ifaceLogger.With("rate", bench.FormatRate(float64(assessment.Rate)), "capacity", assessment.Capacity)
+		switch {
+		case assessment.Insufficient:
+			ifaceLogger.With(
+				"line_rate", bench.FormatRate(float64(assessment.LineRate)),
+				"headroom", assessment.Headroom,
+				"recommended_num_blocks", assessment.RecommendedNumBlocks,
+			).Warn("ring buffer predicted insufficient for link speed, consider increasing ring_buffer.num_blocks")
+		case assessment.LineRate == 0:
+			ifaceLogger.Info("completed startup benchmark (link speed unknown)")
+		default:
+			ifaceLogger.Info("completed startup benchmark")
+		}
+	}
+}
+
+func startupBench(ctx context.Context, ringBuffer *gpconf.RingBufferConfig, duration time.Duration) (*bench.Result, error) {
+	tempDir, err := os.MkdirTemp("", "goprobe_startup_bench")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	return bench.Run(ctx, bench.Config{
+		Flows:            bench.DefaultFlows,
+		Duration:         duration,
+		RotationInterval: duration,
+		DBPath:           tempDir,
+		RingBuffer:       ringBuffer,
+	})
+}
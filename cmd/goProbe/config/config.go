@@ -16,12 +16,27 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/els0r/goProbe/pkg/capture/filter"
+	"github.com/els0r/goProbe/pkg/capture/tagging"
 	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/goDB/dbsync"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goprobe/statspush"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/types"
 	jsoniter "github.com/json-iterator/go"
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
@@ -56,21 +71,739 @@ type Config struct {
 	Logging      LogConfig          `json:"logging" yaml:"logging"`
 	API          *APIConfig         `json:"api" yaml:"api"`
 	LocalBuffers *LocalBufferConfig `json:"local_buffers" yaml:"local_buffers"`
+	Alerting     *AlertingConfig    `json:"alerting,omitempty" yaml:"alerting,omitempty"`
+	Sync         *SyncConfig        `json:"sync,omitempty" yaml:"sync,omitempty"`
+
+	// StatsPush: denotes the statsd / graphite endpoint the capture and writeout statistics of
+	// each rotation are pushed to
+	StatsPush *statspush.Config `json:"stats_push,omitempty" yaml:"stats_push,omitempty"`
+
+	// Tagging: denotes the rules assigning tags to flows upon their creation. Rules are evaluated
+	// in order, the first matching rule determines the tag of a flow
+	Tagging []tagging.Rule `json:"tagging,omitempty" yaml:"tagging,omitempty"`
+
+	// ErrorDumps: denotes the (optional) dumping of the raw payloads of packets that could not be
+	// parsed, allowing parser issues to be reproduced offline
+	ErrorDumps *ErrorDumpConfig `json:"error_dumps,omitempty" yaml:"error_dumps,omitempty"`
+
+	// RotationHistory: denotes the (optional) in-memory retention of the most recent rotations of
+	// each interface, allowing near-real-time consumers to fetch them via the API
+	RotationHistory *RotationHistoryConfig `json:"rotation_history,omitempty" yaml:"rotation_history,omitempty"`
+
+	// SFlow: denotes the (optional) ingest of sFlow datagrams exported by switches / routers, whose
+	// flow samples are written to the database under dedicated pseudo-interfaces
+	SFlow *SFlowConfig `json:"sflow,omitempty" yaml:"sflow,omitempty"`
+
+	// FlowExport: denotes the (optional) export of the flows of each rotation as NetFlow v9 / IPFIX
+	// records to a flow collector (for all interfaces enabling it), in parallel to writing the database
+	FlowExport *FlowExportConfig `json:"flow_export,omitempty" yaml:"flow_export,omitempty"`
+}
+
+// ErrorDumpConfig stores the configuration of the dumps of packets that could not be parsed. Sampled
+// payloads are written to per-interface directories (along with an index) and rotated, i.e. only the
+// most recent dumps are retained
+type ErrorDumpConfig struct {
+	// Path: denotes the directory the dumps are written to (one subdirectory per interface)
+	// Example: "/var/lib/goprobe/error_dumps"
+	Path string `json:"path" yaml:"path"`
+
+	// MaxDumps: maximum number of dumps retained per interface. Defaults to 100
+	// Example: 100
+	MaxDumps int `json:"max_dumps,omitempty" yaml:"max_dumps,omitempty"`
+
+	// MaxSize: maximum number of bytes of the payload of a packet that are dumped. Defaults to 512
+	// Example: 512
+	MaxSize int `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+
+	// MinInterval: minimum interval between two dumps of the same interface (limiting the rate of
+	// dumps during bursts of faulty packets). Defaults to 1s
+	// Example: 10s
+	MinInterval time.Duration `json:"min_interval,omitempty" yaml:"min_interval,omitempty"`
+}
+
+// Defaults of the error dump configuration
+const (
+	DefaultErrorDumpMaxDumps    = 100
+	DefaultErrorDumpMaxSize     = 512
+	DefaultErrorDumpMinInterval = time.Second
+)
+
+// RotationHistoryConfig stores the configuration of the in-memory history of recent rotations. The
+// oldest rotations are discarded once either the retention or the memory cap is exceeded
+type RotationHistoryConfig struct {
+	// Retention: maximum number of rotations retained per interface. Defaults to 12
+	// Example: 3
+	Retention int `json:"retention,omitempty" yaml:"retention,omitempty"`
+
+	// MaxMemory: maximum (approximate) amount of memory in bytes occupied by the flows of all retained
+	// rotations. Defaults to 64 MiB
+	// Example: 67108864
+	MaxMemory int64 `json:"max_memory,omitempty" yaml:"max_memory,omitempty"`
+
+	// SummaryOnly: only retain the statistics and totals of each rotation (but not its flows)
+	// Example: false
+	SummaryOnly bool `json:"summary_only,omitempty" yaml:"summary_only,omitempty"`
+}
+
+// Defaults of the rotation history configuration
+const (
+	DefaultRotationHistoryRetention = 12
+	DefaultRotationHistoryMaxMemory = 64 * 1024 * 1024
+)
+
+// SFlowConfig stores the configuration of the sFlow collector. The sampled packets of all flow samples
+// received are scaled by their sampling rate and attributed to the pseudo-interfaces matching their agent
+// (and interface index), which are rotated and written to the database like any captured interface
+type SFlowConfig struct {
+	// Listen: denotes the UDP address the collector listens on for sFlow datagrams. Defaults to ":6343"
+	// Example: "0.0.0.0:6343"
+	Listen string `json:"listen,omitempty" yaml:"listen,omitempty"`
+
+	// Ifaces: denotes the pseudo-interfaces (keyed by their name) the flow samples are attributed to
+	Ifaces map[string]SFlowIfaceConfig `json:"ifaces" yaml:"ifaces"`
+}
+
+// SFlowIfaceConfig stores the configuration of a pseudo-interface fed by the sFlow collector
+type SFlowIfaceConfig struct {
+	// Agent: denotes the IP address of the sFlow agent (as stated in its datagrams) whose flow samples
+	// are attributed to the pseudo-interface
+	// Example: "10.0.0.1"
+	Agent string `json:"agent" yaml:"agent"`
+
+	// IfIndex: restricts the flow samples to packets received (input) / sent (output) on the interface
+	// of the agent with the given SNMP ifIndex. If unset, all flow samples of the agent are attributed
+	// to the pseudo-interface (as received packets)
+	// Example: 12
+	IfIndex uint32 `json:"if_index,omitempty" yaml:"if_index,omitempty"`
+}
+
+// DefaultSFlowListen denotes the default address of the sFlow collector (using the IANA assigned port)
+const DefaultSFlowListen = ":6343"
+
+// FlowExportConfig stores the configuration of the NetFlow v9 / IPFIX exporter. Upon each rotation,
+// the flows of all interfaces enabling the export are sent to the collector as records of two fixed
+// templates (IPv4 / IPv6), which are sent ahead of the first records of each connection and refreshed
+// periodically if exporting via UDP
+type FlowExportConfig struct {
+	// Target: denotes the address (host:port) of the flow collector
+	// Example: "collector.example.com:4739"
+	Target string `json:"target" yaml:"target"`
+
+	// Protocol: denotes the export protocol. Enum: [netflow_v9, ipfix]. Defaults to ipfix
+	// Example: "ipfix"
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+
+	// Transport: denotes the transport protocol. Enum: [udp, tcp]. Defaults to udp (tcp is only supported
+	// by IPFIX, since NetFlow v9 messages do not state their length)
+	// Example: "udp"
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+
+	// ObservationDomain: denotes the source ID (NetFlow v9) / observation domain ID (IPFIX) of the
+	// exported messages, allowing the collector to distinguish several exporters
+	// Example: 1
+	ObservationDomain uint32 `json:"observation_domain,omitempty" yaml:"observation_domain,omitempty"`
+
+	// MaxMessageSize: denotes the maximum size of an exported message in bytes. Defaults to 1400
+	// Example: 1400
+	MaxMessageSize int `json:"max_message_size,omitempty" yaml:"max_message_size,omitempty"`
+
+	// TemplateRefresh: denotes the interval in which the templates are resent via UDP. Defaults to 10m
+	// Example: 10m
+	TemplateRefresh time.Duration `json:"template_refresh,omitempty" yaml:"template_refresh,omitempty"`
+}
+
+// Export protocols / transports of the NetFlow v9 / IPFIX exporter
+const (
+	FlowExportProtocolNetFlowV9 = "netflow_v9"
+	FlowExportProtocolIPFIX     = "ipfix"
+
+	FlowExportTransportUDP = "udp"
+	FlowExportTransportTCP = "tcp"
+)
+
+// Defaults of the NetFlow v9 / IPFIX exporter configuration
+const (
+	DefaultFlowExportProtocol        = FlowExportProtocolIPFIX
+	DefaultFlowExportTransport       = FlowExportTransportUDP
+	DefaultFlowExportMaxMessageSize  = 1400
+	DefaultFlowExportTemplateRefresh = 10 * time.Minute
+)
+
+// AlertingConfig stores the configuration of the targets alerts (e.g. flow cardinality spikes)
+// are delivered to
+type AlertingConfig struct {
+	// Webhook: denotes the endpoint alerts are POSTed to (in JSON format)
+	Webhook *push.Target `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+}
+
+// SyncConfig stores the configuration of the synchronization of the local database to a
+// central collector (c.f. goCollector)
+type SyncConfig struct {
+	// Target: denotes the address of the collector
+	// Example: "https://collector.example.com:8147"
+	Target string `json:"target" yaml:"target"`
+
+	// Interval: denotes the interval in which the database is synchronized. Defaults to the
+	// writeout interval
+	// Example: 5m
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// ChunkSize: denotes the size (in bytes) of the chunks files are uploaded in. Defaults to 4 MiB
+	// Example: 4194304
+	ChunkSize int `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`
+
+	// TLS: denotes the certificates / keys used for mutual TLS authentication with the collector
+	TLS dbsync.TLSConfig `json:"tls" yaml:"tls"`
 }
 
 // DBConfig stores the local on-disk database configuration
 type DBConfig struct {
-	Path        string      `json:"path" yaml:"path"`
-	EncoderType string      `json:"encoder_type" yaml:"encoder_type"`
-	Permissions fs.FileMode `json:"permissions" yaml:"permissions"`
+	Path        string         `json:"path" yaml:"path"`
+	EncoderType string         `json:"encoder_type" yaml:"encoder_type"`
+	Permissions fs.FileMode    `json:"permissions" yaml:"permissions"`
+	Backlog     *BacklogConfig `json:"backlog,omitempty" yaml:"backlog,omitempty"`
+
+	// HandshakeRTT: enables storing a summary of the TCP handshake round trip times of each
+	// rotation alongside the flows
+	// Example: true
+	HandshakeRTT bool `json:"handshake_rtt,omitempty" yaml:"handshake_rtt,omitempty"`
+
+	// SpillBufferSize: maximum number of failed writeouts per interface that are retained in memory
+	// in order to allow for backfilling them later on (e.g. after a disk-full incident). A value of
+	// zero disables the spill buffer
+	// Example: 12
+	SpillBufferSize int `json:"spill_buffer_size,omitempty" yaml:"spill_buffer_size,omitempty"`
+
+	// CoalesceMaxFlows: maximum number of flows captured on an interface during a rotation for its
+	// writeout to be coalesced with the ones of other low-traffic interfaces into a single transaction
+	// (sharing buffers and a single sync barrier per rotation). A value of zero disables write coalescing
+	// Example: 1000
+	CoalesceMaxFlows int `json:"coalesce_max_flows,omitempty" yaml:"coalesce_max_flows,omitempty"`
+
+	// QueryMmap: enables reading the database via memory-mapped IO (with readahead hints) for all
+	// queries served by the API, reducing the syscall overhead of large scans. Otherwise, it can
+	// be enabled per query
+	// Example: true
+	QueryMmap bool `json:"query_mmap,omitempty" yaml:"query_mmap,omitempty"`
+
+	// QueryReadBufferLimit: maximum total size (in bytes) of the buffers used to read the database by all
+	// concurrently running queries served by the API. Once reached, queries wait for buffers held by other
+	// queries instead of allocating additional memory. Does not apply to memory-mapped reads or queries run
+	// in worker processes (c.f. APIConfig.QueryCgroup). A value of zero disables the limit
+	// Example: 536870912
+	QueryReadBufferLimit int64 `json:"query_read_buffer_limit,omitempty" yaml:"query_read_buffer_limit,omitempty"`
+
+	// Encoders: denotes the encoders of individual interfaces, overriding the default one (c.f.
+	// EncoderType). The encoder is recorded per block, hence existing data remains readable after a
+	// change (and can be re-encoded via a migration)
+	// Example: {"eth0": {"type": "lz4"}, "eth1": {"type": "zstd", "level": 19}}
+	Encoders map[string]EncoderConfig `json:"encoders,omitempty" yaml:"encoders,omitempty"`
+
+	// Quota: denotes the (optional) disk usage quotas of the interfaces, enforced at writeout time
+	Quota *QuotaConfig `json:"quota,omitempty" yaml:"quota,omitempty"`
+
+	// Spool: denotes the (optional) local directory each rotation is additionally written to as a
+	// JSON snapshot per interface, consumable by third-party agents watching the filesystem
+	Spool *SpoolConfig `json:"spool,omitempty" yaml:"spool,omitempty"`
+
+	// Hostnames: denotes the (optional) labeling of the observed internal IP addresses with their
+	// hostnames at writeout, allowing queries to show the hostnames as they were at capture time
+	Hostnames *HostnamesConfig `json:"hostnames,omitempty" yaml:"hostnames,omitempty"`
+
+	// Recompress: denotes the (optional) background re-compression of historical data with a denser
+	// encoder, shrinking long-term archives without a manual migration
+	Recompress *RecompressConfig `json:"recompress,omitempty" yaml:"recompress,omitempty"`
+
+	// Stream: denotes the (optional) external gRPC consumer the flows of each interface are streamed
+	// to as soon as they have been rotated (c.f. pkg/api/flowstream)
+	Stream *StreamConfig `json:"stream,omitempty" yaml:"stream,omitempty"`
+
+	// SyncPolicy: denotes when written data is committed to stable storage: "always" syncs every
+	// written file (and its directory), "per-rotation" performs a single sync barrier once all
+	// interfaces of a rotation have been written and "os-default" leaves it to the write-back of the
+	// operating system. Under both of the former, newly created directories are synced as well
+	// Enum: [always, per-rotation, os-default]
+	// Example: per-rotation
+	SyncPolicy string `json:"sync_policy,omitempty" yaml:"sync_policy,omitempty"`
+}
+
+// EncoderConfig stores the encoder / compressor the data of an interface is written with
+type EncoderConfig struct {
+	// Type: denotes the encoder type
+	// Enum: [null, lz4, lz4cust, zstd]
+	// Example: zstd
+	Type string `json:"type" yaml:"type"`
+
+	// Level: denotes the compression level (if supported by the encoder, e.g. 1-19 for zstd). A value
+	// of zero uses the default level of the encoder
+	// Example: 19
+	Level int `json:"level,omitempty" yaml:"level,omitempty"`
+}
+
+// EncoderOf returns the encoder the data of an interface is written with
+func (d *DBConfig) EncoderOf(iface string) EncoderConfig {
+	if enc, exists := d.Encoders[iface]; exists {
+		return enc
+	}
+	return EncoderConfig{Type: d.EncoderType}
+}
+
+// RecompressConfig stores the configuration of the background re-compression. Periodically, all data
+// older than the minimum age is re-encoded with the configured (typically slower, but denser) encoder
+// while the database is idle in between writeouts. The progress is persisted in the database, hence
+// each directory is only re-encoded once (across restarts). Since the encoder is recorded per block,
+// re-compressed data remains readable by all queries
+type RecompressConfig struct {
+	// Encoder: denotes the encoder historical data is re-encoded with
+	// Example: {"type": "zstd", "level": 19}
+	Encoder EncoderConfig `json:"encoder" yaml:"encoder"`
+
+	// MinAge: denotes the minimum age of the data before it is re-encoded. Defaults to 7 days
+	// Example: 720h
+	MinAge time.Duration `json:"min_age,omitempty" yaml:"min_age,omitempty"`
+
+	// Interval: denotes the interval in which the database is checked for data to re-encode.
+	// Defaults to 1h
+	// Example: 6h
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// Pause: denotes the duration to pause after re-encoding a directory, leaving IO capacity to
+	// writeouts and queries. Defaults to 1s
+	// Example: 10s
+	Pause time.Duration `json:"pause,omitempty" yaml:"pause,omitempty"`
+}
+
+const (
+	// DefaultRecompressMinAge denotes the default minimum age of the data before it is re-encoded
+	DefaultRecompressMinAge = 7 * 24 * time.Hour
+
+	// DefaultRecompressInterval denotes the default interval of the background re-compression
+	DefaultRecompressInterval = time.Hour
+
+	// DefaultRecompressPause denotes the default pause after re-encoding a directory
+	DefaultRecompressPause = time.Second
+)
+
+// SpoolConfig stores the configuration of the local JSON spool. Snapshots are written atomically
+// (i.e. they only appear in the directory once complete) and the oldest ones are removed once any
+// of the bounds of the spool is exceeded
+type SpoolConfig struct {
+	// Path: denotes the directory the snapshots are written to (created if it does not exist)
+	// Example: /var/spool/goprobe
+	Path string `json:"path" yaml:"path"`
+
+	// MaxFiles: maximum number of snapshots retained in the directory. Defaults to 288 (i.e. one
+	// day worth of rotations of a single interface)
+	// Example: 1000
+	MaxFiles int `json:"max_files,omitempty" yaml:"max_files,omitempty"`
+
+	// MaxSize: maximum total size (in bytes) of the snapshots retained in the directory. A value of
+	// zero does not limit the size
+	// Example: 1073741824
+	MaxSize int64 `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+}
+
+// DefaultSpoolMaxFiles denotes the default maximum number of snapshots retained in the spool
+const DefaultSpoolMaxFiles = 288
+
+// StreamConfig stores the configuration of the flow stream. The flows of each interface are streamed
+// to an external gRPC consumer (c.f. pkg/api/flowstream) at every rotation. While the consumer is
+// unavailable or lagging behind, batches are buffered (up to the queue size, discarding the oldest ones
+// beyond) and retransmitted once the stream has been reestablished
+type StreamConfig struct {
+	// Target: denotes the address of the consumer
+	// Example: "enrichment.example.com:9090"
+	Target string `json:"target" yaml:"target"`
+
+	// TLS: denotes the certificates / keys used for mutual TLS authentication with the consumer. If
+	// omitted, the flows are streamed unencrypted
+	TLS *dbsync.TLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+
+	// QueueSize: maximum number of batches (i.e. rotated flows of an interface) buffered while the
+	// consumer is unavailable or lagging behind. Defaults to 1024
+	// Example: 4096
+	QueueSize int `json:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+
+	// MaxInFlight: maximum number of batches sent to the consumer without having been acknowledged.
+	// Defaults to 32
+	// Example: 64
+	MaxInFlight int `json:"max_in_flight,omitempty" yaml:"max_in_flight,omitempty"`
+
+	// MaxBackoff: maximum delay between attempts to reestablish the stream. Defaults to 1m
+	// Example: 5m
+	MaxBackoff time.Duration `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+}
+
+const (
+	// DefaultStreamQueueSize denotes the default maximum number of batches buffered for the consumer
+	DefaultStreamQueueSize = 1024
+
+	// DefaultStreamMaxInFlight denotes the default maximum number of unacknowledged batches
+	DefaultStreamMaxInFlight = 32
+
+	// DefaultStreamMaxBackoff denotes the default maximum delay between attempts to reestablish the stream
+	DefaultStreamMaxBackoff = time.Minute
+)
+
+// HostnamesConfig stores the configuration of the hostname labeling at writeout. The hostnames of the
+// internal IP addresses observed are resolved via reverse DNS lookups (asynchronously, i.e. without
+// delaying the writeout) and cached. The hostnames known at writeout are stored alongside the flows
+type HostnamesConfig struct {
+	// Networks: denotes the networks whose addresses are resolved. Defaults to the private address
+	// ranges (RFC 1918 / RFC 4193)
+	// Example: ["10.0.0.0/8", "fd00::/8"]
+	Networks []string `json:"networks,omitempty" yaml:"networks,omitempty"`
+
+	// CacheSize: maximum number of addresses whose hostnames are cached. Defaults to 65536
+	// Example: 10000
+	CacheSize int `json:"cache_size,omitempty" yaml:"cache_size,omitempty"`
+
+	// TTL: denotes how long a hostname (or the lack thereof) is cached before the address is resolved
+	// again. Defaults to 1h
+	// Example: 6h
+	TTL time.Duration `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// MaxPending: maximum number of concurrent lookups. Addresses observed while the limit is reached are
+	// resolved during a subsequent writeout. Defaults to 16
+	// Example: 64
+	MaxPending int `json:"max_pending,omitempty" yaml:"max_pending,omitempty"`
+
+	// Timeout: denotes the timeout of each lookup. Defaults to 2s
+	// Example: 500ms
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+const (
+	// DefaultHostnamesCacheSize denotes the default maximum number of cached hostnames
+	DefaultHostnamesCacheSize = 65536
+
+	// DefaultHostnamesTTL denotes the default duration hostnames are cached for
+	DefaultHostnamesTTL = time.Hour
+
+	// DefaultHostnamesMaxPending denotes the default maximum number of concurrent lookups
+	DefaultHostnamesMaxPending = 16
+
+	// DefaultHostnamesTimeout denotes the default timeout of each lookup
+	DefaultHostnamesTimeout = 2 * time.Second
+)
+
+// QuotaConfig stores the disk usage quotas of the interfaces stored in the database. The disk usage of
+// an interface is determined prior to each of its writeouts and the configured policy is applied if it
+// exceeds its quota
+type QuotaConfig struct {
+	// MaxSize: default maximum disk usage (in bytes) of each interface. A value of zero disables the
+	// quota for all interfaces without an explicit one
+	// Example: 10737418240
+	MaxSize int64 `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+
+	// Ifaces: maximum disk usage (in bytes) of individual interfaces, overriding the default. A value of
+	// zero disables the quota for the respective interface
+	// Example: {"eth0": 53687091200}
+	Ifaces map[string]int64 `json:"ifaces,omitempty" yaml:"ifaces,omitempty"`
+
+	// Policy: denotes the action taken upon writeout if an interface exceeds its quota. Defaults to
+	// drop_oldest
+	// Enum: [drop_oldest, skip, downsample]
+	// Example: drop_oldest
+	Policy string `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+const (
+	// QuotaPolicyDropOldest removes the oldest days of the interface until it complies with its
+	// quota again (the day currently written to is never removed)
+	QuotaPolicyDropOldest = "drop_oldest"
+
+	// QuotaPolicySkip skips the writeout of the interface (raising an alert), i.e. its flows are
+	// discarded until disk space is reclaimed
+	QuotaPolicySkip = "skip"
+
+	// QuotaPolicyDownsample writes the flows of the interface with their destination ports
+	// aggregated, reducing the amount of data written per writeout
+	QuotaPolicyDownsample = "downsample"
+)
+
+// MaxSizeOf returns the maximum disk usage of an interface (zero if there is no quota)
+func (q *QuotaConfig) MaxSizeOf(iface string) int64 {
+	if q == nil {
+		return 0
+	}
+	if maxSize, exists := q.Ifaces[iface]; exists {
+		return maxSize
+	}
+	return q.MaxSize
+}
+
+// PolicyOrDefault returns the configured policy, falling back to the default one
+func (q *QuotaConfig) PolicyOrDefault() string {
+	if q == nil || q.Policy == "" {
+		return QuotaPolicyDropOldest
+	}
+	return q.Policy
+}
+
+// BacklogConfig stores the bounds of the writeout backlog beyond which the writeout is
+// reported as degraded. A value of zero disables the respective bound
+type BacklogConfig struct {
+	// MaxQueueDepth: maximum number of rotated flow maps pending writeout
+	// Example: 100
+	MaxQueueDepth int `json:"max_queue_depth,omitempty" yaml:"max_queue_depth,omitempty"`
+
+	// MaxPendingAge: maximum age of the oldest rotation pending writeout. Defaults
+	// to twice the writeout interval
+	// Example: 10m
+	MaxPendingAge time.Duration `json:"max_pending_age,omitempty" yaml:"max_pending_age,omitempty"`
 }
 
 // CaptureConfig stores the capture / buffer related configuration for an individual interface
 type CaptureConfig struct {
-	Promisc    bool              `json:"promisc" yaml:"promisc"`         // Promisc: enables / disables promiscuous capture mode. Example: true
-	RingBuffer *RingBufferConfig `json:"ring_buffer" yaml:"ring_buffer"` // RingBuffer: denotes the kernel ring buffer configuration of this interface
+	Driver     string            `json:"capture_driver,omitempty" yaml:"capture_driver,omitempty"` // Driver: denotes the source of flow data. Enum: [afpacket, ebpf]. Example: afpacket
+	Promisc    bool              `json:"promisc" yaml:"promisc"`                                   // Promisc: enables / disables promiscuous capture mode. Example: true
+	RingBuffer *RingBufferConfig `json:"ring_buffer" yaml:"ring_buffer"`                           // RingBuffer: denotes the kernel ring buffer configuration of this interface
+	EBPF       *EBPFConfig       `json:"ebpf,omitempty" yaml:"ebpf,omitempty"`                     // EBPF: denotes the eBPF flow source configuration of this interface
+	Filter     *FilterConfig     `json:"filter,omitempty" yaml:"filter,omitempty"`                 // Filter: denotes the (optional) IP based capture filter of this interface
+
+	// Cardinality: denotes the (optional) alarm on spikes of the number of unique flows of this interface
+	Cardinality *CardinalityConfig `json:"cardinality,omitempty" yaml:"cardinality,omitempty"`
+
+	// Emergency: denotes the (optional) limit of the number of concurrent flows of this interface, beyond
+	// which flows are collapsed to the prefixes of their IP addresses until the cardinality subsides
+	Emergency *EmergencyConfig `json:"emergency,omitempty" yaml:"emergency,omitempty"`
+
+	// HostAddrs: denotes the (optional) addresses of the capturing host on this interface, used to
+	// definitively classify the direction of flows involving the host itself
+	HostAddrs *HostAddrsConfig `json:"host_addrs,omitempty" yaml:"host_addrs,omitempty"`
+
+	// Netns: denotes the (optional) network namespace the interface resides in, allowing to capture
+	// on interfaces of other namespaces / containers without running goProbe inside of them
+	Netns *NetnsConfig `json:"netns,omitempty" yaml:"netns,omitempty"`
+
+	// RecordTTL: enables recording the range (minimum / maximum) of the TTL (IPv4) / hop limit (IPv6)
+	// observed for each flow. Not supported by the eBPF capture driver. Example: true
+	RecordTTL bool `json:"record_ttl,omitempty" yaml:"record_ttl,omitempty"`
+
+	// VerifyCounters: enables verifying upon each rotation that all packets processed during the
+	// rotation interval are either recorded in the rotated flows or accounted for as ignored / unparsable,
+	// reporting any discrepancy. Not supported by the eBPF capture driver. Example: true
+	VerifyCounters bool `json:"verify_counters,omitempty" yaml:"verify_counters,omitempty"`
+
+	// Standby: denotes the (optional) standby of the interface while idle, releasing its capture (and
+	// hence its ring buffer) if no packets are received for a while
+	Standby *StandbyConfig `json:"standby,omitempty" yaml:"standby,omitempty"`
+
+	// Watchdog: denotes the (optional) watchdog of the capture, reinitializing its capture source if
+	// packet processing stalls (e.g. due to a wedged driver) despite traffic on the interface
+	Watchdog *WatchdogConfig `json:"watchdog,omitempty" yaml:"watchdog,omitempty"`
+
+	// FlowExport: enables exporting the flows of this interface upon each rotation via the (global)
+	// NetFlow v9 / IPFIX exporter. Example: true
+	FlowExport bool `json:"flow_export,omitempty" yaml:"flow_export,omitempty"`
+
+	// Tagging: denotes the (global) tagging rules, populated from the configuration upon parsing
+	Tagging []tagging.Rule `json:"-" yaml:"-"`
 }
 
+const (
+	// CaptureDriverAFPacket denotes the default capture driver, processing each packet from an
+	// AF_PACKET ring buffer in userspace
+	CaptureDriverAFPacket = "afpacket"
+
+	// CaptureDriverEBPF denotes the capture driver consuming flows aggregated in-kernel by
+	// goProbe's eBPF (TC) program
+	CaptureDriverEBPF = "ebpf"
+)
+
+// EBPFConfig stores the configuration of the eBPF flow source of an individual interface
+type EBPFConfig struct {
+	// PinPath: denotes the directory in the BPF file system in which the maps of the TC
+	// program attached to the interface are pinned. Defaults to /sys/fs/bpf/goprobe/<iface>
+	// Example: /sys/fs/bpf/goprobe/eth0
+	PinPath string `json:"pin_path,omitempty" yaml:"pin_path,omitempty"`
+}
+
+// DefaultEBPFPinRoot denotes the default root directory in which the maps of the eBPF program
+// are pinned (one subdirectory per interface)
+const DefaultEBPFPinRoot = "/sys/fs/bpf/goprobe"
+
+// IsEBPF returns if the capture is configured to use the eBPF flow source
+func (c CaptureConfig) IsEBPF() bool {
+	return c.Driver == CaptureDriverEBPF
+}
+
+// FilterConfig stores the IP allow / deny lists evaluated for each packet captured on an
+// individual interface before it is added to the flow log. Packets are discarded if any of
+// their endpoints is contained in the deny list or, if an allow list is provided, if none
+// of their endpoints is contained in the allow list
+type FilterConfig struct {
+	// Allow: list of prefixes (CIDR notation) of which at least one must contain an
+	// endpoint of a packet for it to be recorded
+	// Example: ["10.0.0.0/8", "2001:db8::/32"]
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+
+	// Deny: list of prefixes (CIDR notation) that must not be recorded under any circumstance
+	// Example: ["192.168.1.0/24"]
+	Deny []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// CardinalityConfig stores the configuration of the flow cardinality alarm of an individual
+// interface. The number of unique flows of each rotation is compared against a baseline (the
+// median across the most recent rotations), raising an alert if it exceeds a multiple of it
+type CardinalityConfig struct {
+	// Factor: denotes the multiple of the baseline beyond which an alert is raised
+	// Example: 3
+	Factor float64 `json:"factor" yaml:"factor"`
+
+	// History: denotes the number of rotations the baseline is computed from. Defaults
+	// to 12 (i.e. one hour)
+	// Example: 12
+	History int `json:"history,omitempty" yaml:"history,omitempty"`
+
+	// MinFlows: denotes the minimum number of unique flows required for an alert to be
+	// raised (to avoid alerts on interfaces with very little traffic)
+	// Example: 1000
+	MinFlows int `json:"min_flows,omitempty" yaml:"min_flows,omitempty"`
+}
+
+// EmergencyConfig stores the configuration of the emergency aggregation mode of an individual
+// interface. Once the number of concurrent flows reaches the limit (e.g. during a DDoS attack or a
+// scan), the IP addresses of all flows are collapsed to their prefixes (and only the lower of their
+// ports is retained), bounding the memory consumption while preserving visibility. The mode is left
+// upon the first rotation during which the number of distinct flows stayed below half the limit
+type EmergencyConfig struct {
+	// MaxFlows: denotes the number of concurrent flows beyond which the emergency aggregation mode
+	// is entered
+	// Example: 1000000
+	MaxFlows int `json:"max_flows" yaml:"max_flows"`
+
+	// IPv4Prefix: denotes the length of the prefixes IPv4 addresses are collapsed to. Defaults to 24
+	// Example: 24
+	IPv4Prefix int `json:"ipv4_prefix,omitempty" yaml:"ipv4_prefix,omitempty"`
+
+	// IPv6Prefix: denotes the length of the prefixes IPv6 addresses are collapsed to. Defaults to 64
+	// Example: 64
+	IPv6Prefix int `json:"ipv6_prefix,omitempty" yaml:"ipv6_prefix,omitempty"`
+}
+
+const (
+	// DefaultEmergencyIPv4Prefix denotes the default length of the prefixes IPv4 addresses are
+	// collapsed to in emergency aggregation mode
+	DefaultEmergencyIPv4Prefix = 24
+
+	// DefaultEmergencyIPv6Prefix denotes the default length of the prefixes IPv6 addresses are
+	// collapsed to in emergency aggregation mode
+	DefaultEmergencyIPv6Prefix = 64
+)
+
+// Prefixes returns the lengths of the prefixes IPv4 / IPv6 addresses are collapsed to, resolving
+// the defaults
+func (e *EmergencyConfig) Prefixes() (ipv4Prefix, ipv6Prefix int) {
+	ipv4Prefix, ipv6Prefix = e.IPv4Prefix, e.IPv6Prefix
+	if ipv4Prefix == 0 {
+		ipv4Prefix = DefaultEmergencyIPv4Prefix
+	}
+	if ipv6Prefix == 0 {
+		ipv6Prefix = DefaultEmergencyIPv6Prefix
+	}
+	return
+}
+
+// HostAddrsConfig stores the addresses / prefixes of the capturing host on an individual interface.
+// Flows between the host and a remote endpoint are classified based on the role of the host's
+// endpoint (client or server) instead of the port heuristics and are considered high-confidence
+type HostAddrsConfig struct {
+	// Discover: enables the automatic discovery of the addresses assigned to the interface (via
+	// netlink on Linux) whenever the capture is (re-)initialized
+	// Example: true
+	Discover bool `json:"discover,omitempty" yaml:"discover,omitempty"`
+
+	// Prefixes: list of addresses / prefixes (CIDR notation) belonging to the host, used in addition
+	// to any discovered addresses
+	// Example: ["192.0.2.10", "2001:db8::10/128"]
+	Prefixes []string `json:"prefixes,omitempty" yaml:"prefixes,omitempty"`
+}
+
+// NetnsConfig stores the network namespace an individual interface resides in. The namespace is
+// either provided directly (via a path) or determined from a container (via the API of its runtime)
+// whenever the capture is (re-)initialized. Since interface names are frequently identical across
+// namespaces (e.g. eth0 in each container), the name of the interface inside the namespace can be
+// provided separately, the configuration key serving as unique name of the capture (and in the goDB)
+type NetnsConfig struct {
+	// Path: denotes the path of the network namespace (e.g. created via `ip netns add` or of a
+	// process via /proc/<pid>/ns/net)
+	// Example: "/var/run/netns/blue"
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Container: denotes the ID (or name) of a container whose network namespace is used
+	// Example: "4f66ad9a0b2e"
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+
+	// Runtime: denotes the endpoint (unix socket) of the container runtime API used to look up the
+	// container. Any Docker Engine compatible API is supported (e.g. Docker, Podman). Defaults to
+	// unix:///var/run/docker.sock
+	// Example: "unix:///run/podman/podman.sock"
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+
+	// Device: denotes the name of the interface inside the namespace. Defaults to the configuration key
+	// Example: "eth0"
+	Device string `json:"device,omitempty" yaml:"device,omitempty"`
+}
+
+// StandbyConfig stores the idle standby configuration of an individual interface. If no packets were
+// received on the interface for the configured period, its capture (and hence its ring buffer) is released
+// and the interface is polled for traffic / link activity at low frequency instead, reinitializing the
+// capture as soon as any activity is observed
+type StandbyConfig struct {
+	// IdleTimeout: denotes the period without any received packets after which the interface is put into
+	// standby. Since inactivity is assessed upon rotation, it is effectively rounded up to a multiple of the
+	// writeout interval
+	// Example: "30m"
+	IdleTimeout time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+
+	// PollInterval: denotes the interval in which an interface in standby is polled for activity. Defaults
+	// to 10s
+	// Example: "10s"
+	PollInterval time.Duration `json:"poll_interval,omitempty" yaml:"poll_interval,omitempty"`
+}
+
+// DefaultStandbyPollInterval denotes the default interval in which an interface in standby is polled
+// for activity
+const DefaultStandbyPollInterval = 10 * time.Second
+
+// WatchdogConfig stores the watchdog configuration of an individual interface. The progress of the packet
+// processing routine (i.e. the capture source returning packets / events) is checked periodically. If it has
+// not progressed for the configured period although the packet counters of the link kept increasing, the
+// capture source is closed and reinitialized (reported as automatic recovery in the status of the interface)
+type WatchdogConfig struct {
+	// Timeout: denotes the period without any progress of the packet processing routine (despite traffic
+	// on the interface) after which the capture source is reinitialized
+	// Example: "1m"
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	// CheckInterval: denotes the interval in which the progress of the packet processing routine is
+	// checked. Defaults to 5s
+	// Example: "5s"
+	CheckInterval time.Duration `json:"check_interval,omitempty" yaml:"check_interval,omitempty"`
+}
+
+// DefaultWatchdogCheckInterval denotes the default interval in which the progress of the packet processing
+// routine of an interface is checked
+const DefaultWatchdogCheckInterval = 5 * time.Second
+
+// CheckIntervalOrDefault returns the interval in which the progress of the packet processing routine
+// is checked
+func (w *WatchdogConfig) CheckIntervalOrDefault() time.Duration {
+	if w.CheckInterval > 0 {
+		return w.CheckInterval
+	}
+	return DefaultWatchdogCheckInterval
+}
+
+// DefaultNetnsRuntime denotes the default endpoint of the container runtime API
+const DefaultNetnsRuntime = "unix:///var/run/docker.sock"
+
+// DefaultCardinalityHistory denotes the default number of rotations the flow cardinality
+// baseline is computed from
+const DefaultCardinalityHistory = 12
+
 // LocalBufferConfig stores the shared local in-memory buffer configuration
 type LocalBufferConfig struct {
 
@@ -126,6 +859,104 @@ type APIConfig struct {
 	Timeout        int                  `json:"request_timeout" yaml:"request_timeout"`
 	Keys           []string             `json:"keys" yaml:"keys"`
 	QueryRateLimit QueryRateLimitConfig `json:"query_rate_limit" yaml:"query_rate_limit"`
+
+	// UI: serves a minimal web UI (interface status and a basic query form) on /ui
+	UI bool `json:"ui,omitempty" yaml:"ui,omitempty"`
+
+	// QueryAudit: enables the audit log of all queries executed via the API
+	QueryAudit *QueryAuditConfig `json:"query_audit,omitempty" yaml:"query_audit,omitempty"`
+
+	// MaxBodySize: denotes the maximum request body sizes of the API routes. Requests exceeding them
+	// are rejected
+	MaxBodySize *MaxBodySizeConfig `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+
+	// Compression: compresses response bodies (zstd / gzip, as negotiated via the Accept-Encoding
+	// header), reducing the transfer volume of large query results, e.g. over WAN links
+	Compression bool `json:"compression,omitempty" yaml:"compression,omitempty"`
+
+	// QueryCgroup: runs queries in worker processes confined to a (cgroup v2) control group, preventing
+	// heavy queries from starving the capture on single-box deployments. Linux only
+	QueryCgroup *QueryCgroupConfig `json:"query_cgroup,omitempty" yaml:"query_cgroup,omitempty"`
+}
+
+// QueryCgroupConfig stores the configuration of the control group queries are run in. The limits apply to
+// all queries in sum, queries presenting one of the keys of Tokens are additionally subject to its limits
+type QueryCgroupConfig struct {
+	// Path: denotes the path of the control group (created if required). It must be delegated to goProbe
+	// (e.g. via systemd's Delegate=yes), with the cpu and memory controllers enabled
+	// Example: "/sys/fs/cgroup/goprobe.slice/queries"
+	Path string `json:"path" yaml:"path"`
+
+	// CPUWeight: denotes the relative share of CPU time of all queries under contention (1-10000).
+	// Defaults to the kernel default of 100
+	// Example: 20
+	CPUWeight uint64 `json:"cpu_weight,omitempty" yaml:"cpu_weight,omitempty"`
+
+	// MemoryMax: denotes the maximum memory usage (in bytes) of all queries. Queries exceeding it fail.
+	// Defaults to unlimited
+	// Example: 2147483648
+	MemoryMax int64 `json:"memory_max,omitempty" yaml:"memory_max,omitempty"`
+
+	// Tokens: denotes the limits of the queries of individual API tokens (presented via the Authorization
+	// header, c.f. APIConfig.Keys), by name of the child group they are run in
+	Tokens map[string]QueryCgroupTokenConfig `json:"tokens,omitempty" yaml:"tokens,omitempty"`
+}
+
+// QueryCgroupTokenConfig stores the limits of the queries of an API token
+type QueryCgroupTokenConfig struct {
+	// Key: denotes the API token the limits apply to
+	// Example: "<random key of at least 32 characters>"
+	Key string `json:"key" yaml:"key"`
+
+	// CPUWeight: denotes the relative share of CPU time of the queries of the token (1-10000)
+	// Example: 10
+	CPUWeight uint64 `json:"cpu_weight,omitempty" yaml:"cpu_weight,omitempty"`
+
+	// MemoryMax: denotes the maximum memory usage (in bytes) of the queries of the token
+	// Example: 1073741824
+	MemoryMax int64 `json:"memory_max,omitempty" yaml:"memory_max,omitempty"`
+}
+
+// Limits returns the limits of all queries
+func (q *QueryCgroupConfig) Limits() cgroup.Limits {
+	return cgroup.Limits{CPUWeight: q.CPUWeight, MemoryMax: q.MemoryMax}
+}
+
+// Limits returns the limits of the queries of the token
+func (t QueryCgroupTokenConfig) Limits() cgroup.Limits {
+	return cgroup.Limits{CPUWeight: t.CPUWeight, MemoryMax: t.MemoryMax}
+}
+
+// MaxBodySizeConfig stores the maximum request body sizes (in bytes) of the API routes. Unset values
+// retain the defaults, negative values disable the respective limit
+type MaxBodySizeConfig struct {
+	// Default: denotes the maximum body size of all routes without a dedicated limit. Defaults to 1 MiB
+	// Example: 1048576
+	Default int64 `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// Config: denotes the maximum size of a config update. Defaults to 1 MiB
+	// Example: 1048576
+	Config int64 `json:"config,omitempty" yaml:"config,omitempty"`
+
+	// Ingest: denotes the maximum size of an ingest request. Defaults to 64 MiB
+	// Example: 268435456
+	Ingest int64 `json:"ingest,omitempty" yaml:"ingest,omitempty"`
+}
+
+// QueryAuditConfig stores the configuration of the audit log of executed queries
+type QueryAuditConfig struct {
+	// TenantHeader: denotes the request header identifying the tenant running a query. Defaults
+	// to X-GOPROBE-TENANT
+	// Example: "X-Tenant"
+	TenantHeader string `json:"tenant_header,omitempty" yaml:"tenant_header,omitempty"`
+
+	// HistorySize: denotes the number of entries kept in memory (and exposed via the API). Defaults
+	// to 1000
+	// Example: 5000
+	HistorySize int `json:"history_size,omitempty" yaml:"history_size,omitempty"`
+
+	// Sinks: denotes where all entries are forwarded to (file, syslog and / or webhook)
+	Sinks []audit.SinkConfig `json:"sinks,omitempty" yaml:"sinks,omitempty"`
 }
 
 // newDefault creates a new configuration struct with default settings
@@ -151,6 +982,9 @@ var (
 	errorNoAPIAddrSpecified       = errors.New("no API address specified")
 	errorInvalidAPITimeout        = errors.New("the request timeout must be a positive number")
 	errorInvalidAPIQueryRateLimit = errors.New("the query rate limit values must both be positive numbers")
+	errorInvalidAuditHistorySize  = errors.New("the audit history size must not be negative")
+	errorNoQueryCgroupPath        = errors.New("no query cgroup path specified")
+	errorDuplicateQueryCgroupKey  = errors.New("duplicate query cgroup token key")
 )
 
 func (a APIConfig) validate() error {
@@ -171,6 +1005,45 @@ func (a APIConfig) validate() error {
 	if a.Timeout < 0 {
 		return errorInvalidAPITimeout
 	}
+	if a.QueryAudit != nil {
+		if a.QueryAudit.HistorySize < 0 {
+			return errorInvalidAuditHistorySize
+		}
+		for i, sink := range a.QueryAudit.Sinks {
+			if err := sink.Validate(); err != nil {
+				return fmt.Errorf("invalid audit sink %d: %w", i, err)
+			}
+		}
+	}
+	if a.QueryCgroup != nil {
+		return a.QueryCgroup.validate()
+	}
+	return nil
+}
+
+func (q *QueryCgroupConfig) validate() error {
+	if q.Path == "" {
+		return errorNoQueryCgroupPath
+	}
+	if err := q.Limits().Validate(); err != nil {
+		return err
+	}
+	keys := make(map[string]struct{}, len(q.Tokens))
+	for name, token := range q.Tokens {
+		if name == cgroup.DefaultName {
+			return fmt.Errorf("%w: %s is reserved for queries without token", cgroup.ErrInvalidName, name)
+		}
+		if err := checkKeyConstraints(token.Key); err != nil {
+			return fmt.Errorf("invalid key of query cgroup token %s: %w", name, err)
+		}
+		if _, exists := keys[token.Key]; exists {
+			return fmt.Errorf("%w: %s", errorDuplicateQueryCgroupKey, name)
+		}
+		keys[token.Key] = struct{}{}
+		if err := token.Limits().Validate(); err != nil {
+			return fmt.Errorf("invalid limits of query cgroup token %s: %w", name, err)
+		}
+	}
 	return nil
 }
 
@@ -191,15 +1064,192 @@ func (l LocalBufferConfig) validate() error {
 
 var (
 	errorNoRingBufferConfig = errors.New("no ring buffer configuration specified")
+	errorUnknownDriver      = errors.New("unknown capture driver")
+	errorEBPFConfigMismatch = errors.New("eBPF configuration requires capture_driver: ebpf")
+	errorTTLEBPF            = errors.New("recording TTLs is not supported by the eBPF capture driver")
+	errorStandbyEBPF        = errors.New("idle standby is not supported by the eBPF capture driver")
+	errorVerifyCountersEBPF = errors.New("verifying packet counters is not supported by the eBPF capture driver")
+	errorWatchdogEBPF       = errors.New("the capture watchdog is not supported by the eBPF capture driver")
 )
 
 func (c CaptureConfig) validate() error {
+	switch c.Driver {
+	case "", CaptureDriverAFPacket:
+		if c.EBPF != nil {
+			return errorEBPFConfigMismatch
+		}
+	case CaptureDriverEBPF:
+		if c.RecordTTL {
+			return errorTTLEBPF
+		}
+		if c.Standby != nil {
+			return errorStandbyEBPF
+		}
+		if c.VerifyCounters {
+			return errorVerifyCountersEBPF
+		}
+		if c.Watchdog != nil {
+			return errorWatchdogEBPF
+		}
+	default:
+		return fmt.Errorf("%w: %s", errorUnknownDriver, c.Driver)
+	}
+	if c.Filter != nil {
+		if err := c.Filter.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Cardinality != nil {
+		if err := c.Cardinality.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Emergency != nil {
+		if err := c.Emergency.validate(); err != nil {
+			return err
+		}
+	}
+	if c.HostAddrs != nil {
+		if err := c.HostAddrs.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Netns != nil {
+		if err := c.Netns.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Standby != nil {
+		if err := c.Standby.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Watchdog != nil {
+		if err := c.Watchdog.validate(); err != nil {
+			return err
+		}
+	}
+
+	// flows are aggregated in-kernel when using the eBPF driver, hence no ring buffer is
+	// required (it is ignored if present)
+	if c.IsEBPF() {
+		return nil
+	}
 	if c.RingBuffer == nil {
 		return errorNoRingBufferConfig
 	}
 	return c.RingBuffer.validate()
 }
 
+var (
+	errorEmptyFilter = errors.New("capture filter must contain at least one allow or deny prefix")
+)
+
+func (f *FilterConfig) validate() error {
+	if len(f.Allow) == 0 && len(f.Deny) == 0 {
+		return errorEmptyFilter
+	}
+	for _, cidrs := range [][]string{f.Allow, f.Deny} {
+		for _, cidr := range cidrs {
+			if _, err := filter.ParsePrefix(cidr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	errorEmptyHostAddrs = errors.New("host addresses must either be discovered or contain at least one prefix")
+)
+
+func (h *HostAddrsConfig) validate() error {
+	if !h.Discover && len(h.Prefixes) == 0 {
+		return errorEmptyHostAddrs
+	}
+	for _, cidr := range h.Prefixes {
+		if _, err := filter.ParsePrefix(cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	errorNetnsTarget  = errors.New("network namespace requires either a path or a container (but not both)")
+	errorNetnsRuntime = errors.New("container runtime endpoint must be a unix socket (unix://<path>)")
+)
+
+func (n *NetnsConfig) validate() error {
+	if (n.Path == "") == (n.Container == "") {
+		return errorNetnsTarget
+	}
+	if n.Runtime != "" && (n.Container == "" || !strings.HasPrefix(n.Runtime, "unix://")) {
+		return errorNetnsRuntime
+	}
+	return nil
+}
+
+var (
+	errorStandbyIdleTimeout  = errors.New("standby idle timeout must be a positive duration")
+	errorStandbyPollInterval = errors.New("standby poll interval must not be negative")
+)
+
+func (s *StandbyConfig) validate() error {
+	if s.IdleTimeout <= 0 {
+		return errorStandbyIdleTimeout
+	}
+	if s.PollInterval < 0 {
+		return errorStandbyPollInterval
+	}
+	return nil
+}
+
+var (
+	errorWatchdogTimeout       = errors.New("watchdog timeout must be a positive duration")
+	errorWatchdogCheckInterval = errors.New("watchdog check interval must not be negative and must be shorter than the timeout")
+)
+
+func (w *WatchdogConfig) validate() error {
+	if w.Timeout <= 0 {
+		return errorWatchdogTimeout
+	}
+	if w.CheckInterval < 0 || w.CheckIntervalOrDefault() >= w.Timeout {
+		return errorWatchdogCheckInterval
+	}
+	return nil
+}
+
+var (
+	errorCardinalityFactor = errors.New("flow cardinality factor must be greater than one")
+	errorCardinalityLimits = errors.New("flow cardinality history and minimum number of flows must not be negative")
+)
+
+func (cc *CardinalityConfig) validate() error {
+	if cc.Factor <= 1 {
+		return errorCardinalityFactor
+	}
+	if cc.History < 0 || cc.MinFlows < 0 {
+		return errorCardinalityLimits
+	}
+	return nil
+}
+
+var (
+	errorEmergencyMaxFlows = errors.New("emergency aggregation flow limit must be a positive number")
+	errorEmergencyPrefix   = errors.New("emergency aggregation prefix lengths must be within 0-32 (IPv4) / 0-128 (IPv6)")
+)
+
+func (e *EmergencyConfig) validate() error {
+	if e.MaxFlows <= 0 {
+		return errorEmergencyMaxFlows
+	}
+	if e.IPv4Prefix < 0 || e.IPv4Prefix > 32 || e.IPv6Prefix < 0 || e.IPv6Prefix > 128 {
+		return errorEmergencyPrefix
+	}
+	return nil
+}
+
 var (
 	errorRingBufferBlockSize = errors.New("ring buffer block size must be a postive number")
 	errorRingBufferNumBlocks = errors.New("ring buffer num blocks must be a postive number")
@@ -217,14 +1267,99 @@ func (r *RingBufferConfig) validate() error {
 
 // Equals compares c to cfg and returns true if all fields are identical
 func (c CaptureConfig) Equals(cfg CaptureConfig) bool {
-	return c.Promisc == cfg.Promisc &&
-		c.RingBuffer.Equals(cfg.RingBuffer)
+	return c.driver() == cfg.driver() &&
+		c.Promisc == cfg.Promisc &&
+		c.RingBuffer.Equals(cfg.RingBuffer) &&
+		c.EBPF.Equals(cfg.EBPF) &&
+		c.Filter.Equals(cfg.Filter) &&
+		c.Cardinality.Equals(cfg.Cardinality) &&
+		c.Emergency.Equals(cfg.Emergency) &&
+		c.HostAddrs.Equals(cfg.HostAddrs) &&
+		c.Netns.Equals(cfg.Netns) &&
+		c.RecordTTL == cfg.RecordTTL &&
+		c.VerifyCounters == cfg.VerifyCounters &&
+		c.Standby.Equals(cfg.Standby) &&
+		c.Watchdog.Equals(cfg.Watchdog) &&
+		c.FlowExport == cfg.FlowExport &&
+		slices.EqualFunc(c.Tagging, cfg.Tagging, tagging.Rule.Equals)
+}
+
+// driver returns the capture driver, resolving the default
+func (c CaptureConfig) driver() string {
+	if c.Driver == "" {
+		return CaptureDriverAFPacket
+	}
+	return c.Driver
+}
+
+// Equals compares e to cfg and returns true if all fields are identical
+func (e *EBPFConfig) Equals(cfg *EBPFConfig) bool {
+	if e == nil || cfg == nil {
+		return e == cfg
+	}
+	return e.PinPath == cfg.PinPath
+}
+
+// Equals compares f to cfg and returns true if all fields are identical
+func (f *FilterConfig) Equals(cfg *FilterConfig) bool {
+	if f == nil || cfg == nil {
+		return f == cfg
+	}
+	return slices.Equal(f.Allow, cfg.Allow) && slices.Equal(f.Deny, cfg.Deny)
+}
+
+// Equals compares cc to cfg and returns true if all fields are identical
+func (cc *CardinalityConfig) Equals(cfg *CardinalityConfig) bool {
+	if cc == nil || cfg == nil {
+		return cc == cfg
+	}
+	return *cc == *cfg
+}
+
+// Equals compares e to cfg and returns true if all fields are identical
+func (e *EmergencyConfig) Equals(cfg *EmergencyConfig) bool {
+	if e == nil || cfg == nil {
+		return e == cfg
+	}
+	return *e == *cfg
+}
+
+// Equals compares h to cfg and returns true if all fields are identical
+func (h *HostAddrsConfig) Equals(cfg *HostAddrsConfig) bool {
+	if h == nil || cfg == nil {
+		return h == cfg
+	}
+	return h.Discover == cfg.Discover && slices.Equal(h.Prefixes, cfg.Prefixes)
+}
+
+// Equals compares n to cfg and returns true if all fields are identical
+func (n *NetnsConfig) Equals(cfg *NetnsConfig) bool {
+	if n == nil || cfg == nil {
+		return n == cfg
+	}
+	return *n == *cfg
+}
+
+// Equals compares s to cfg and returns true if all fields are identical
+func (s *StandbyConfig) Equals(cfg *StandbyConfig) bool {
+	if s == nil || cfg == nil {
+		return s == cfg
+	}
+	return *s == *cfg
+}
+
+// Equals compares w to cfg and returns true if all fields are identical
+func (w *WatchdogConfig) Equals(cfg *WatchdogConfig) bool {
+	if w == nil || cfg == nil {
+		return w == cfg
+	}
+	return *w == *cfg
 }
 
 // Equals compares r to cfg and returns true if all fields are identical
 func (r *RingBufferConfig) Equals(cfg *RingBufferConfig) bool {
-	if cfg == nil {
-		return false
+	if r == nil || cfg == nil {
+		return r == cfg
 	}
 	return r.BlockSize == cfg.BlockSize && r.NumBlocks == cfg.NumBlocks
 }
@@ -253,7 +1388,20 @@ func (i Ifaces) Validate() error {
 }
 
 var (
-	errorEmptyDBPath = errors.New("database path must not be empty")
+	errorEmptyDBPath          = errors.New("database path must not be empty")
+	errorInvalidBacklogLimits = errors.New("writeout backlog limits must not be negative")
+	errorInvalidSpillBuffer   = errors.New("spill buffer size must not be negative")
+	errorInvalidEncoderLevel  = errors.New("encoder compression level must not be negative")
+	errorInvalidCoalescing    = errors.New("maximum number of flows for write coalescing must not be negative")
+	errorInvalidReadBuffers   = errors.New("query read buffer limit must not be negative")
+	errorInvalidQuota         = errors.New("disk usage quotas must not be negative")
+	errorUnknownQuotaPolicy   = fmt.Errorf("unknown quota policy (must be one of %s, %s, %s)", QuotaPolicyDropOldest, QuotaPolicySkip, QuotaPolicyDownsample)
+	errorEmptySpoolPath       = errors.New("spool path must not be empty")
+	errorInvalidSpoolBounds   = errors.New("spool bounds must not be negative")
+	errorInvalidHostnames     = errors.New("hostname labeling bounds must not be negative")
+	errorInvalidRecompress    = errors.New("re-compression durations must not be negative")
+	errorEmptyStreamTarget    = errors.New("flow stream target must not be empty")
+	errorInvalidStreamBounds  = errors.New("flow stream bounds must not be negative")
 )
 
 func (d DBConfig) validate() error {
@@ -264,6 +1412,273 @@ func (d DBConfig) validate() error {
 	if err != nil {
 		return err
 	}
+	for iface, enc := range d.Encoders {
+		if err := enc.validate(); err != nil {
+			return fmt.Errorf("invalid encoder of interface %s: %w", iface, err)
+		}
+	}
+	if d.SpillBufferSize < 0 {
+		return errorInvalidSpillBuffer
+	}
+	if d.CoalesceMaxFlows < 0 {
+		return errorInvalidCoalescing
+	}
+	if d.QueryReadBufferLimit < 0 {
+		return errorInvalidReadBuffers
+	}
+	if _, err := storage.ParseSyncPolicy(d.SyncPolicy); err != nil {
+		return err
+	}
+	if d.Quota != nil {
+		if err := d.Quota.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Spool != nil {
+		if err := d.Spool.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Hostnames != nil {
+		if err := d.Hostnames.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Recompress != nil {
+		if err := d.Recompress.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Stream != nil {
+		if err := d.Stream.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Backlog != nil {
+		return d.Backlog.validate()
+	}
+	return nil
+}
+
+func (e EncoderConfig) validate() error {
+	if _, err := encoders.GetTypeByString(e.Type); err != nil {
+		return err
+	}
+	if e.Level < 0 {
+		return errorInvalidEncoderLevel
+	}
+	return nil
+}
+
+func (a *AlertingConfig) validate() error {
+	if a.Webhook != nil {
+		if err := a.Webhook.Validate(); err != nil {
+			return fmt.Errorf("invalid alerting webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+var (
+	errorNoSyncTarget     = errors.New("no sync target specified")
+	errorInvalidSyncURL   = errors.New("sync target must be an https:// URL")
+	errorInvalidSyncLimit = errors.New("sync interval and chunk size must not be negative")
+	errorSyncChunkSize    = fmt.Errorf("sync chunk size must not exceed %d bytes", dbsync.MaxChunkSize)
+)
+
+func (s *SyncConfig) validate() error {
+	if s.Target == "" {
+		return errorNoSyncTarget
+	}
+	target, err := url.Parse(s.Target)
+	if err != nil || target.Scheme != "https" || target.Host == "" {
+		return errorInvalidSyncURL
+	}
+	if s.Interval < 0 || s.ChunkSize < 0 {
+		return errorInvalidSyncLimit
+	}
+	if s.ChunkSize > dbsync.MaxChunkSize {
+		return errorSyncChunkSize
+	}
+	return s.TLS.Validate()
+}
+
+var (
+	errorNoErrorDumpPath        = errors.New("no error dump path specified")
+	errorInvalidErrorDumpLimits = errors.New("error dump limits must not be negative")
+)
+
+var errorInvalidRotationHistoryLimits = errors.New("rotation history limits must not be negative")
+
+func (r *RotationHistoryConfig) validate() error {
+	if r.Retention < 0 || r.MaxMemory < 0 {
+		return errorInvalidRotationHistoryLimits
+	}
+	return nil
+}
+
+var (
+	errorNoSFlowIfaces         = errors.New("no sFlow pseudo-interfaces specified")
+	errorInvalidSFlowIfaceName = errors.New("invalid sFlow pseudo-interface name")
+	errorInvalidSFlowAgent     = errors.New("sFlow agent must be a valid IP address")
+	errorSFlowIfaceConflict    = errors.New("sFlow pseudo-interface conflicts with a captured interface")
+)
+
+func (s *SFlowConfig) validate() error {
+	if len(s.Ifaces) == 0 {
+		return errorNoSFlowIfaces
+	}
+	for iface, cfg := range s.Ifaces {
+		if iface == "" || iface == "." || iface == ".." || filepath.Base(iface) != iface {
+			return fmt.Errorf("%w: %q", errorInvalidSFlowIfaceName, iface)
+		}
+		if _, err := netip.ParseAddr(cfg.Agent); err != nil {
+			return fmt.Errorf("%s: %w", iface, errorInvalidSFlowAgent)
+		}
+	}
+	return nil
+}
+
+var (
+	errorInvalidFlowExportTarget    = errors.New("invalid flow collector address (must be host:port)")
+	errorInvalidFlowExportProtocol  = errors.New("invalid flow export protocol (must be netflow_v9 or ipfix)")
+	errorInvalidFlowExportTransport = errors.New("invalid flow export transport (must be udp or tcp, the latter requiring ipfix)")
+	errorInvalidFlowExportMsgSize   = errors.New("flow export maximum message size must be between 512 and 65535")
+	errorInvalidFlowExportRefresh   = errors.New("flow export template refresh interval must not be negative")
+	errorFlowExportNotConfigured    = errors.New("flow export enabled on interface without flow_export section")
+)
+
+func (f *FlowExportConfig) validate() error {
+	if _, _, err := net.SplitHostPort(f.Target); err != nil {
+		return fmt.Errorf("%w: %w", errorInvalidFlowExportTarget, err)
+	}
+
+	protocol, transport := f.Protocol, f.Transport
+	if protocol == "" {
+		protocol = DefaultFlowExportProtocol
+	}
+	if transport == "" {
+		transport = DefaultFlowExportTransport
+	}
+	if protocol != FlowExportProtocolNetFlowV9 && protocol != FlowExportProtocolIPFIX {
+		return errorInvalidFlowExportProtocol
+	}
+	if transport != FlowExportTransportUDP && !(transport == FlowExportTransportTCP && protocol == FlowExportProtocolIPFIX) {
+		return errorInvalidFlowExportTransport
+	}
+
+	if f.MaxMessageSize != 0 && (f.MaxMessageSize < 512 || f.MaxMessageSize > 65535) {
+		return errorInvalidFlowExportMsgSize
+	}
+	if f.TemplateRefresh < 0 {
+		return errorInvalidFlowExportRefresh
+	}
+	return nil
+}
+
+// validateFlowExportIfaces ensures that the flow export is only enabled on interfaces if the exporter
+// is configured
+func (c *Config) validateFlowExportIfaces() error {
+	if c.FlowExport != nil {
+		return nil
+	}
+	for iface, cfg := range c.Interfaces {
+		if cfg.FlowExport {
+			return fmt.Errorf("%s: %w", iface, errorFlowExportNotConfigured)
+		}
+	}
+	return nil
+}
+
+// validateSFlowIfaces ensures that the pseudo-interfaces of the sFlow collector (if any) are distinct
+// from the captured interfaces (since they are stored in the same database)
+func (c *Config) validateSFlowIfaces() error {
+	if c.SFlow == nil {
+		return nil
+	}
+	for iface := range c.SFlow.Ifaces {
+		if _, exists := c.Interfaces[iface]; exists {
+			return fmt.Errorf("%w: %s", errorSFlowIfaceConflict, iface)
+		}
+	}
+	return nil
+}
+
+func (e *ErrorDumpConfig) validate() error {
+	if e.Path == "" {
+		return errorNoErrorDumpPath
+	}
+	if e.MaxDumps < 0 || e.MaxSize < 0 || e.MinInterval < 0 {
+		return errorInvalidErrorDumpLimits
+	}
+	return nil
+}
+
+func (q *QuotaConfig) validate() error {
+	if q.MaxSize < 0 {
+		return errorInvalidQuota
+	}
+	for _, maxSize := range q.Ifaces {
+		if maxSize < 0 {
+			return errorInvalidQuota
+		}
+	}
+	switch q.Policy {
+	case "", QuotaPolicyDropOldest, QuotaPolicySkip, QuotaPolicyDownsample:
+		return nil
+	}
+	return errorUnknownQuotaPolicy
+}
+
+func (s *SpoolConfig) validate() error {
+	if s.Path == "" {
+		return errorEmptySpoolPath
+	}
+	if s.MaxFiles < 0 || s.MaxSize < 0 {
+		return errorInvalidSpoolBounds
+	}
+	return nil
+}
+
+func (r *RecompressConfig) validate() error {
+	if err := r.Encoder.validate(); err != nil {
+		return fmt.Errorf("invalid re-compression encoder: %w", err)
+	}
+	if r.MinAge < 0 || r.Interval < 0 || r.Pause < 0 {
+		return errorInvalidRecompress
+	}
+	return nil
+}
+
+func (s *StreamConfig) validate() error {
+	if s.Target == "" {
+		return errorEmptyStreamTarget
+	}
+	if s.QueueSize < 0 || s.MaxInFlight < 0 || s.MaxBackoff < 0 {
+		return errorInvalidStreamBounds
+	}
+	if s.TLS != nil {
+		return s.TLS.Validate()
+	}
+	return nil
+}
+
+func (h *HostnamesConfig) validate() error {
+	for _, network := range h.Networks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			return fmt.Errorf("invalid hostname labeling network: %w", err)
+		}
+	}
+	if h.CacheSize < 0 || h.TTL < 0 || h.MaxPending < 0 || h.Timeout < 0 {
+		return errorInvalidHostnames
+	}
+	return nil
+}
+
+func (b BacklogConfig) validate() error {
+	if b.MaxQueueDepth < 0 || b.MaxPendingAge < 0 {
+		return errorInvalidBacklogLimits
+	}
 	return nil
 }
 
@@ -289,12 +1704,59 @@ func (c *Config) Validate() error {
 	if c.LocalBuffers != nil {
 		optValidators = append(optValidators, c.LocalBuffers)
 	}
+	if c.Alerting != nil {
+		optValidators = append(optValidators, c.Alerting)
+	}
+	if c.Sync != nil {
+		optValidators = append(optValidators, c.Sync)
+	}
+	if c.ErrorDumps != nil {
+		optValidators = append(optValidators, c.ErrorDumps)
+	}
+	if c.RotationHistory != nil {
+		optValidators = append(optValidators, c.RotationHistory)
+	}
+	if c.SFlow != nil {
+		optValidators = append(optValidators, c.SFlow)
+	}
+	if c.FlowExport != nil {
+		optValidators = append(optValidators, c.FlowExport)
+	}
 	for _, section := range optValidators {
 		err := section.validate()
 		if err != nil {
 			return err
 		}
 	}
+	if err := c.validateSFlowIfaces(); err != nil {
+		return err
+	}
+	if err := c.validateFlowExportIfaces(); err != nil {
+		return err
+	}
+	if c.StatsPush != nil {
+		if err := c.StatsPush.Validate(); err != nil {
+			return fmt.Errorf("invalid stats push configuration: %w", err)
+		}
+	}
+	return c.validateTagging()
+}
+
+var (
+	errorTooManyTags = fmt.Errorf("tagging rules must not use more than %d distinct tags", types.MaxTags)
+)
+
+func (c *Config) validateTagging() error {
+	tags := make(map[string]struct{})
+	for i, rule := range c.Tagging {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid tagging rule %d: %w", i, err)
+		}
+		tags[rule.Tag] = struct{}{}
+	}
+	if len(tags) > types.MaxTags {
+		return errorTooManyTags
+	}
 	return nil
 }
 
@@ -343,6 +1805,13 @@ func Parse(src io.Reader) (*Config, error) {
 		return nil, err
 	}
 
+	// Provide the tagging rules to all interfaces (each capture only evaluates the rules
+	// applying to its interface)
+	for iface, cc := range config.Interfaces {
+		cc.Tagging = config.Tagging
+		config.Interfaces[iface] = cc
+	}
+
 	return config, nil
 }
 
@@ -3,8 +3,13 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/els0r/goProbe/pkg/capture/tagging"
 	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/goDB/dbsync"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -61,6 +66,420 @@ func TestValidate(t *testing.T) {
 			},
 			errorEmptyDBPath,
 		},
+		{"invalid cardinality factor",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer:  &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Cardinality: &CardinalityConfig{Factor: 0.5},
+					},
+				},
+			},
+			errorCardinalityFactor,
+		},
+		{"negative cardinality history",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer:  &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Cardinality: &CardinalityConfig{Factor: 3, History: -1},
+					},
+				},
+			},
+			errorCardinalityLimits,
+		},
+		{"invalid emergency prefix",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Emergency:  &EmergencyConfig{MaxFlows: 1000, IPv4Prefix: 33},
+					},
+				},
+			},
+			errorEmergencyPrefix,
+		},
+		{"negative writeout backlog limit",
+			&Config{
+				DB: DBConfig{
+					Path:    defaults.DBPath,
+					Backlog: &BacklogConfig{MaxQueueDepth: -1},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidBacklogLimits,
+		},
+		{"negative spill buffer size",
+			&Config{
+				DB: DBConfig{
+					Path:            defaults.DBPath,
+					SpillBufferSize: -1,
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidSpillBuffer,
+		},
+		{"negative query read buffer limit",
+			&Config{
+				DB: DBConfig{
+					Path:                 defaults.DBPath,
+					QueryReadBufferLimit: -1,
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidReadBuffers,
+		},
+		{"negative interface encoder level",
+			&Config{
+				DB: DBConfig{
+					Path:     defaults.DBPath,
+					Encoders: map[string]EncoderConfig{"eth0": {Type: "zstd", Level: -1}},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidEncoderLevel,
+		},
+		{"negative interface quota",
+			&Config{
+				DB: DBConfig{
+					Path:  defaults.DBPath,
+					Quota: &QuotaConfig{MaxSize: 1024, Ifaces: map[string]int64{"eth0": -1}},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidQuota,
+		},
+		{"unknown quota policy",
+			&Config{
+				DB: DBConfig{
+					Path:  defaults.DBPath,
+					Quota: &QuotaConfig{MaxSize: 1024, Policy: "truncate"},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorUnknownQuotaPolicy,
+		},
+		{"empty spool path",
+			&Config{
+				DB: DBConfig{
+					Path:  defaults.DBPath,
+					Spool: &SpoolConfig{MaxFiles: 10},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorEmptySpoolPath,
+		},
+		{"negative spool bounds",
+			&Config{
+				DB: DBConfig{
+					Path:  defaults.DBPath,
+					Spool: &SpoolConfig{Path: "/var/spool/goprobe", MaxSize: -1},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidSpoolBounds,
+		},
+		{"empty flow stream target",
+			&Config{
+				DB: DBConfig{
+					Path:   defaults.DBPath,
+					Stream: &StreamConfig{QueueSize: 10},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorEmptyStreamTarget,
+		},
+		{"negative flow stream bounds",
+			&Config{
+				DB: DBConfig{
+					Path:   defaults.DBPath,
+					Stream: &StreamConfig{Target: "localhost:9090", MaxInFlight: -1},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidStreamBounds,
+		},
+		{"negative write coalescing threshold",
+			&Config{
+				DB: DBConfig{
+					Path:             defaults.DBPath,
+					CoalesceMaxFlows: -1,
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidCoalescing,
+		},
+		{"unknown sync policy",
+			&Config{
+				DB: DBConfig{
+					Path:       defaults.DBPath,
+					SyncPolicy: "sometimes",
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			storage.ErrUnknownSyncPolicy,
+		},
+		{"negative hostname cache size",
+			&Config{
+				DB: DBConfig{
+					Path:      defaults.DBPath,
+					Hostnames: &HostnamesConfig{CacheSize: -1},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidHostnames,
+		},
+		{"negative re-compression pause",
+			&Config{
+				DB: DBConfig{
+					Path:       defaults.DBPath,
+					Recompress: &RecompressConfig{Encoder: EncoderConfig{Type: "zstd", Level: 19}, Pause: -time.Second},
+				},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorInvalidRecompress,
+		},
+		{"sync target not using https",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				Sync: &SyncConfig{
+					Target: "http://collector.example.com:8147",
+					TLS:    dbsync.TLSConfig{Cert: "probe.crt", Key: "probe.key", CA: "ca.crt"},
+				},
+			},
+			errorInvalidSyncURL,
+		},
+		{"error dumps without path",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				ErrorDumps: &ErrorDumpConfig{MaxDumps: 10},
+			},
+			errorNoErrorDumpPath,
+		},
+		{"negative error dump limits",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				ErrorDumps: &ErrorDumpConfig{Path: "/tmp/dumps", MaxSize: -1},
+			},
+			errorInvalidErrorDumpLimits,
+		},
+		{"negative rotation history limits",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				RotationHistory: &RotationHistoryConfig{Retention: -1},
+			},
+			errorInvalidRotationHistoryLimits,
+		},
+		{"sflow without pseudo-interfaces",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				SFlow: &SFlowConfig{Listen: ":6343"},
+			},
+			errorNoSFlowIfaces,
+		},
+		{"sflow with invalid pseudo-interface name",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				SFlow: &SFlowConfig{Ifaces: map[string]SFlowIfaceConfig{
+					"sw1/port1": {Agent: "10.0.0.1"},
+				}},
+			},
+			errorInvalidSFlowIfaceName,
+		},
+		{"sflow with invalid agent",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				SFlow: &SFlowConfig{Ifaces: map[string]SFlowIfaceConfig{
+					"sw1": {Agent: "switch.example.com"},
+				}},
+			},
+			errorInvalidSFlowAgent,
+		},
+		{"sflow pseudo-interface conflicting with captured interface",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				SFlow: &SFlowConfig{Ifaces: map[string]SFlowIfaceConfig{
+					"eth0": {Agent: "10.0.0.1", IfIndex: 12},
+				}},
+			},
+			errorSFlowIfaceConflict,
+		},
+		{"flow export",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+				FlowExport: &FlowExportConfig{Target: "127.0.0.1:4739", Protocol: "ipfix", Transport: "tcp"},
+			},
+			nil,
+		},
+		{"flow export with invalid collector address",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+				FlowExport: &FlowExportConfig{Target: "collector.example.com"},
+			},
+			errorInvalidFlowExportTarget,
+		},
+		{"flow export with invalid protocol",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+				FlowExport: &FlowExportConfig{Target: "127.0.0.1:2055", Protocol: "netflow_v5"},
+			},
+			errorInvalidFlowExportProtocol,
+		},
+		{"flow export of netflow v9 via tcp",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+				FlowExport: &FlowExportConfig{Target: "127.0.0.1:2055", Protocol: "netflow_v9", Transport: "tcp"},
+			},
+			errorInvalidFlowExportTransport,
+		},
+		{"flow export with invalid maximum message size",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+				FlowExport: &FlowExportConfig{Target: "127.0.0.1:4739", MaxMessageSize: 128},
+			},
+			errorInvalidFlowExportMsgSize,
+		},
+		{"flow export enabled without exporter",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						FlowExport: true,
+					},
+				},
+			},
+			errorFlowExportNotConfigured,
+		},
 		{"no iface config provided",
 			&Config{
 				DB:         DBConfig{Path: defaults.DBPath},
@@ -101,6 +520,214 @@ func TestValidate(t *testing.T) {
 			},
 			errorRingBufferNumBlocks,
 		},
+		{"empty capture filter",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Filter:     &FilterConfig{},
+					},
+				},
+			},
+			errorEmptyFilter,
+		},
+		{"empty host addresses",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						HostAddrs:  &HostAddrsConfig{},
+					},
+				},
+			},
+			errorEmptyHostAddrs,
+		},
+		{"host addresses",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						HostAddrs:  &HostAddrsConfig{Discover: true, Prefixes: []string{"192.0.2.10", "2001:db8::/64"}},
+					},
+				},
+			},
+			nil,
+		},
+		{"network namespace without target",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"web-eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Netns:      &NetnsConfig{Device: "eth0"},
+					},
+				},
+			},
+			errorNetnsTarget,
+		},
+		{"network namespace with path and container",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"web-eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Netns:      &NetnsConfig{Path: "/var/run/netns/web", Container: "web"},
+					},
+				},
+			},
+			errorNetnsTarget,
+		},
+		{"network namespace with invalid runtime",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"web-eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Netns:      &NetnsConfig{Container: "web", Runtime: "http://localhost:2375"},
+					},
+				},
+			},
+			errorNetnsRuntime,
+		},
+		{"network namespaces",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"blue-eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Netns:      &NetnsConfig{Path: "/var/run/netns/blue", Device: "eth0"},
+					},
+					"web-eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Netns:      &NetnsConfig{Container: "web", Runtime: "unix:///run/podman/podman.sock", Device: "eth0"},
+					},
+				},
+			},
+			nil,
+		},
+		{"eBPF driver without ring buffer",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver: CaptureDriverEBPF,
+						EBPF:   &EBPFConfig{PinPath: "/sys/fs/bpf/goprobe/eth0"},
+					},
+				},
+			},
+			nil,
+		},
+		{"unknown capture driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver:     "xdp",
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+			},
+			errorUnknownDriver,
+		},
+		{"eBPF config without eBPF driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						EBPF:       &EBPFConfig{},
+					},
+				},
+			},
+			errorEBPFConfigMismatch,
+		},
+		{"TTL recording with eBPF driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver:    CaptureDriverEBPF,
+						RecordTTL: true,
+					},
+				},
+			},
+			errorTTLEBPF,
+		},
+		{"counter verification with eBPF driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver:         CaptureDriverEBPF,
+						VerifyCounters: true,
+					},
+				},
+			},
+			errorVerifyCountersEBPF,
+		},
+		{"standby without idle timeout",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Standby:    &StandbyConfig{PollInterval: 10 * time.Second},
+					},
+				},
+			},
+			errorStandbyIdleTimeout,
+		},
+		{"standby with eBPF driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver:  CaptureDriverEBPF,
+						Standby: &StandbyConfig{IdleTimeout: 30 * time.Minute},
+					},
+				},
+			},
+			errorStandbyEBPF,
+		},
+		{"watchdog check interval exceeding timeout",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Watchdog:   &WatchdogConfig{Timeout: 3 * time.Second},
+					},
+				},
+			},
+			errorWatchdogCheckInterval,
+		},
+		{"watchdog with eBPF driver",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						Driver:   CaptureDriverEBPF,
+						Watchdog: &WatchdogConfig{Timeout: time.Minute},
+					},
+				},
+			},
+			errorWatchdogEBPF,
+		},
+		{"standby",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+						Standby:    &StandbyConfig{IdleTimeout: 30 * time.Minute},
+					},
+				},
+			},
+			nil,
+		},
 		{"missing API addr",
 			&Config{
 				DB: DBConfig{Path: defaults.DBPath},
@@ -152,6 +779,89 @@ func TestValidate(t *testing.T) {
 			},
 			errorInvalidAPIQueryRateLimit,
 		},
+		{"invalid audit history size",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				Logging: LogConfig{Level: "debug", Encoding: "logfmt"},
+				API: &APIConfig{
+					Addr: "unix:/var/run/goprobe.sock",
+					QueryAudit: &QueryAuditConfig{
+						HistorySize: -1,
+					},
+				},
+			},
+			errorInvalidAuditHistorySize,
+		},
+		{"query cgroup without path",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				Logging: LogConfig{Level: "debug", Encoding: "logfmt"},
+				API: &APIConfig{
+					Addr: "unix:/var/run/goprobe.sock",
+					QueryCgroup: &QueryCgroupConfig{
+						CPUWeight: 20,
+					},
+				},
+			},
+			errorNoQueryCgroupPath,
+		},
+		{"invalid query cgroup token limits",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				Logging: LogConfig{Level: "debug", Encoding: "logfmt"},
+				API: &APIConfig{
+					Addr: "unix:/var/run/goprobe.sock",
+					QueryCgroup: &QueryCgroupConfig{
+						Path: "/sys/fs/cgroup/goprobe.slice/queries",
+						Tokens: map[string]QueryCgroupTokenConfig{
+							"reporting": {
+								Key:       "c2e9a7f4b1d8036e5a9c7b2f4d1e8a3c",
+								CPUWeight: 20000,
+							},
+						},
+					},
+				},
+			},
+			cgroup.ErrInvalidCPUWeight,
+		},
+		{"query cgroup token with reserved name",
+			&Config{
+				DB: DBConfig{Path: defaults.DBPath},
+				Interfaces: Ifaces{
+					"eth0": CaptureConfig{
+						RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+					},
+				},
+				Logging: LogConfig{Level: "debug", Encoding: "logfmt"},
+				API: &APIConfig{
+					Addr: "unix:/var/run/goprobe.sock",
+					QueryCgroup: &QueryCgroupConfig{
+						Path: "/sys/fs/cgroup/goprobe.slice/queries",
+						Tokens: map[string]QueryCgroupTokenConfig{
+							cgroup.DefaultName: {
+								Key: "c2e9a7f4b1d8036e5a9c7b2f4d1e8a3c",
+							},
+						},
+					},
+				},
+			},
+			cgroup.ErrInvalidName,
+		},
 	}
 
 	// run tests
@@ -204,6 +914,57 @@ interfaces:
 `,
 			nil,
 		},
+		{"valid config YAML with capture filter",
+			`db:
+  path: /var/lib/goprobe/goprobe.db
+interfaces:
+  eth0:
+   ring_buffer:
+      block_size: 1048576
+      num_blocks: 2
+   filter:
+      allow:
+        - 10.0.0.0/8
+        - 2001:db8::/32
+      deny:
+        - 10.0.1.0/24
+`,
+			nil,
+		},
+		{"valid config YAML with tagging rules",
+			`db:
+  path: /var/lib/goprobe/goprobe.db
+interfaces:
+  eth0:
+   ring_buffer:
+      block_size: 1048576
+      num_blocks: 2
+tagging:
+  - tag: voip
+    match:
+      ports: [5060, 5061]
+      protos: [udp]
+  - tag: backup
+    match:
+      ifaces: [eth0]
+      cidrs:
+        - 10.1.0.0/16
+`,
+			nil,
+		},
+		{"invalid tagging rule",
+			`db:
+  path: /var/lib/goprobe/goprobe.db
+interfaces:
+  eth0:
+   ring_buffer:
+      block_size: 1048576
+      num_blocks: 2
+tagging:
+  - tag: voip
+`,
+			tagging.ErrEmptyMatch,
+		},
 		{"malformed",
 			`db`,
 			errorUnmarshalConfig,
@@ -224,3 +985,31 @@ interfaces:
 		})
 	}
 }
+
+func TestParseTagging(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(`db:
+  path: /var/lib/goprobe/goprobe.db
+interfaces:
+  eth0:
+   ring_buffer:
+      block_size: 1048576
+      num_blocks: 2
+  eth1:
+   ring_buffer:
+      block_size: 1048576
+      num_blocks: 2
+tagging:
+  - tag: internet
+    match:
+      ifaces: [eth1]
+`))
+	assert.Nil(t, err)
+
+	// the rules are provided to all interfaces and changes to them must trigger an update
+	for _, iface := range []string{"eth0", "eth1"} {
+		assert.Equal(t, cfg.Tagging, cfg.Interfaces[iface].Tagging)
+	}
+	cc := cfg.Interfaces["eth0"]
+	cc.Tagging = nil
+	assert.False(t, cc.Equals(cfg.Interfaces["eth0"]))
+}
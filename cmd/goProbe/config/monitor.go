@@ -80,6 +80,27 @@ func (m *Monitor) PutIfaceConfig(cfg Ifaces) {
 	m.Unlock()
 }
 
+// Patch applies a JSON merge patch (RFC 7396) to the current configuration. If the resulting
+// configuration is valid, it replaces the current one and the provided callback is executed. The
+// changes caused by the patch are returned (with all secrets redacted)
+func (m *Monitor) Patch(ctx context.Context, patch []byte, fn CallbackFn) (changes Changes, enabled, updated, disabled capturetypes.IfaceChanges, err error) {
+	m.Lock()
+	cfg, changes, err := m.config.Patch(patch)
+	if err != nil {
+		m.Unlock()
+		return nil, nil, nil, nil, err
+	}
+	m.config = cfg
+	m.Unlock()
+
+	logging.FromContext(ctx).With("changes", len(changes)).Debugf("config patched")
+
+	if fn != nil {
+		enabled, updated, disabled, err = m.Apply(ctx, fn)
+	}
+	return
+}
+
 // Start initializaes the config monitor background task(s)
 func (m *Monitor) Start(ctx context.Context, fn CallbackFn) {
 	go m.reloadPeriodically(ctx, fn)
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redactedValue denotes the placeholder replacing secrets when exposing the configuration
+const redactedValue = "<redacted>"
+
+// runtimeSections denotes the (top-level) sections of the configuration that can be modified
+// at runtime. Changes to all other sections require a restart of goProbe
+var runtimeSections = map[string]struct{}{
+	"interfaces": {},
+	"tagging":    {},
+}
+
+var (
+	// ErrInvalidPatch denotes that a merge patch cannot be applied to the configuration (e.g. because
+	// the resulting configuration is invalid)
+	ErrInvalidPatch = errors.New("invalid config patch")
+
+	errorPatchNoObject = errors.New("merge patch must be a JSON object")
+)
+
+// Change denotes a single modification of the configuration
+type Change struct {
+	Path string `json:"path"`          // Path: the JSON pointer (RFC 6901) of the modified value. Example: /interfaces/eth0/promisc
+	Old  any    `json:"old,omitempty"` // Old: the previous value (absent if the value was added). Example: false
+	New  any    `json:"new,omitempty"` // New: the new value (absent if the value was removed). Example: true
+}
+
+// Changes denotes a list of modifications of the configuration (ordered by path)
+type Changes []Change
+
+// Redacted returns a copy of the configuration in which all secrets (API keys, HTTP headers of
+// webhooks) are replaced by a placeholder, allowing it to be exposed (e.g. via the API)
+func (c *Config) Redacted() (*Config, error) {
+	doc, err := c.document()
+	if err != nil {
+		return nil, err
+	}
+	redact(doc)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config document: %w", err)
+	}
+	redacted := new(Config)
+	if err := json.Unmarshal(b, redacted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config document: %w", err)
+	}
+	return redacted, nil
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the configuration and returns the resulting
+// configuration along with the changes it caused (secrets being redacted). The resulting configuration
+// is validated and the patch is rejected if it modifies any section that cannot be changed at
+// runtime. The configuration itself remains unmodified
+func (c *Config) Patch(patch []byte) (*Config, Changes, error) {
+	var patchDoc any
+	if err := unmarshalDocument(patch, &patchDoc); err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to parse merge patch: %w", ErrInvalidPatch, err)
+	}
+	if _, isObject := patchDoc.(map[string]any); !isObject {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPatch, errorPatchNoObject)
+	}
+
+	// The patch is applied in-place, hence a separate copy of the document is retained in order
+	// to determine the changes
+	doc, err := c.document()
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := c.document()
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := json.Marshal(mergePatch(target, patchDoc))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal patched config document: %w", err)
+	}
+
+	// Parse the patched document from scratch, ensuring that it is validated exactly like a
+	// configuration read from disk
+	patched, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	patchedDoc, err := patched.document()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, change := range diff(doc, patchedDoc) {
+		section := strings.SplitN(strings.TrimPrefix(change.Path, "/"), "/", 2)[0]
+		if _, isRuntime := runtimeSections[section]; !isRuntime {
+			return nil, nil, fmt.Errorf("%w: section `%s` cannot be modified at runtime", ErrInvalidPatch, section)
+		}
+	}
+
+	// Determine the changes once more, this time without exposing any secrets
+	redact(doc)
+	redact(patchedDoc)
+
+	return patched, diff(doc, patchedDoc), nil
+}
+
+// IfacesMergePatch generates a JSON merge patch (RFC 7396) replacing the interface configuration
+// current by desired (i.e. removing all interfaces and fields not part of desired)
+func IfacesMergePatch(current, desired Ifaces) ([]byte, error) {
+	var docs [2]any
+	for i, ifaces := range []Ifaces{current, desired} {
+		b, err := json.Marshal(ifaces)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal interface configuration: %w", err)
+		}
+		if err := unmarshalDocument(b, &docs[i]); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal interface configuration: %w", err)
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"interfaces": createMergePatch(docs[0], docs[1]),
+	})
+}
+
+// document returns the configuration as a generic JSON document
+func (c *Config) document() (map[string]any, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var doc map[string]any
+	if err := unmarshalDocument(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config document: %w", err)
+	}
+	return doc, nil
+}
+
+// unmarshalDocument parses a generic JSON document, retaining numbers as is (instead of
+// converting them to float64, which would lose precision for large integers)
+func unmarshalDocument(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// redact replaces all secrets in the document by a placeholder
+func redact(doc map[string]any) {
+	for key, val := range doc {
+		switch v := val.(type) {
+		case map[string]any:
+			if key == "headers" {
+				for header := range v {
+					v[header] = redactedValue
+				}
+				continue
+			}
+			redact(v)
+		case []any:
+			if key == "keys" {
+				for i := range v {
+					v[i] = redactedValue
+				}
+				continue
+			}
+			for _, elem := range v {
+				if m, isObject := elem.(map[string]any); isObject {
+					redact(m)
+				}
+			}
+		}
+	}
+}
+
+// mergePatch applies patch to target as defined in RFC 7396
+func mergePatch(target, patch any) any {
+	patchObj, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+	targetObj, isObject := target.(map[string]any)
+	if !isObject {
+		targetObj = make(map[string]any)
+	}
+	for key, val := range patchObj {
+		if val == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], val)
+	}
+	return targetObj
+}
+
+// createMergePatch generates the merge patch (RFC 7396) transforming document a into document b
+func createMergePatch(a, b any) any {
+	aObj, aIsObject := a.(map[string]any)
+	bObj, bIsObject := b.(map[string]any)
+	if !aIsObject || !bIsObject {
+		return b
+	}
+	patch := make(map[string]any)
+	for key, aVal := range aObj {
+		bVal, exists := bObj[key]
+		if !exists {
+			patch[key] = nil
+			continue
+		}
+		if !reflect.DeepEqual(aVal, bVal) {
+			patch[key] = createMergePatch(aVal, bVal)
+		}
+	}
+	for key, bVal := range bObj {
+		if _, exists := aObj[key]; !exists {
+			patch[key] = bVal
+		}
+	}
+	return patch
+}
+
+// diff determines all changes between document a and document b. Objects are compared
+// recursively, all other values (including arrays) are compared as a whole
+func diff(a, b any) (changes Changes) {
+	diffAt("", a, b, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return
+}
+
+func diffAt(path string, a, b any, changes *Changes) {
+	aObj, aIsObject := a.(map[string]any)
+	bObj, bIsObject := b.(map[string]any)
+	if aIsObject && bIsObject {
+		for key, aVal := range aObj {
+			diffAt(path+"/"+escapePointer(key), aVal, bObj[key], changes)
+		}
+		for key, bVal := range bObj {
+			if _, exists := aObj[key]; !exists {
+				diffAt(path+"/"+escapePointer(key), nil, bVal, changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Old: a, New: b})
+	}
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escapePointer escapes a reference token of a JSON pointer (RFC 6901)
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/stretchr/testify/require"
+)
+
+func testPatchConfig() *Config {
+	cfg := newDefault()
+	cfg.DB.Path = defaults.DBPath
+	cfg.Interfaces = Ifaces{
+		"eth0": CaptureConfig{
+			Promisc:    true,
+			RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 2},
+		},
+	}
+	cfg.API = &APIConfig{
+		Addr: "unix:/var/run/goprobe.sock",
+		Keys: []string{"testtesttesttesttesttesttesttesttesttesttesttesttesttesttesttesttesttesttesttest"},
+	}
+	return cfg
+}
+
+func TestPatch(t *testing.T) {
+	var tests = []struct {
+		name            string
+		patch           string
+		expectedChanges []string
+		expectedErr     error
+	}{
+		{"modify interface",
+			`{"interfaces": {"eth0": {"promisc": false}}}`,
+			[]string{"/interfaces/eth0/promisc"},
+			nil,
+		},
+		{"add and remove interface",
+			`{"interfaces": {"eth0": null, "eth1": {"ring_buffer": {"block_size": 1048576, "num_blocks": 4}}}}`,
+			[]string{"/interfaces/eth0", "/interfaces/eth1"},
+			nil,
+		},
+		{"no-op", `{}`, nil, nil},
+		{"not an object", `[]`, nil, ErrInvalidPatch},
+		{"invalid JSON", `{"interfaces":`, nil, ErrInvalidPatch},
+		{"invalid result", `{"interfaces": {"eth0": {"ring_buffer": {"num_blocks": 0}}}}`, nil, ErrInvalidPatch},
+		{"non-runtime section", `{"db": {"path": "/tmp/db"}}`, nil, ErrInvalidPatch},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg := testPatchConfig()
+
+			patched, changes, err := cfg.Patch([]byte(test.patch))
+			if test.expectedErr != nil {
+				require.True(t, errors.Is(err, test.expectedErr), "unexpected error: %v", err)
+				return
+			}
+			require.Nil(t, err)
+			require.NotNil(t, patched)
+
+			var paths []string
+			for _, change := range changes {
+				paths = append(paths, change.Path)
+			}
+			require.Equal(t, test.expectedChanges, paths)
+
+			// the original configuration must remain untouched
+			require.Equal(t, testPatchConfig(), cfg)
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := testPatchConfig()
+
+	redacted, err := cfg.Redacted()
+	require.Nil(t, err)
+	require.Equal(t, []string{redactedValue}, redacted.API.Keys)
+	require.Equal(t, cfg.Interfaces, redacted.Interfaces)
+
+	// secrets are also redacted in the changes caused by a patch
+	_, changes, err := cfg.Patch([]byte(`{"interfaces": {"eth0": {"promisc": false}}}`))
+	require.Nil(t, err)
+	b, err := json.Marshal(changes)
+	require.Nil(t, err)
+	require.NotContains(t, string(b), cfg.API.Keys[0])
+}
+
+func TestIfacesMergePatch(t *testing.T) {
+	cfg := testPatchConfig()
+	desired := Ifaces{
+		"eth1": CaptureConfig{
+			RingBuffer: &RingBufferConfig{BlockSize: 1024 * 1024, NumBlocks: 4},
+		},
+	}
+
+	patch, err := IfacesMergePatch(cfg.Interfaces, desired)
+	require.Nil(t, err)
+
+	patched, _, err := cfg.Patch(patch)
+	require.Nil(t, err)
+	require.Equal(t, desired, patched.Interfaces)
+}
@@ -0,0 +1,462 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/goDB/dbsync"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goprobe/statspush"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
+)
+
+// SchemaDialect denotes the JSON Schema dialect of the configuration schema
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches durations in the string representation accepted by time.ParseDuration
+const durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$`
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// schemaAnnotations stores the defaults and constraints of the configuration (as applied upon
+// parsing / validation) that cannot be derived from the configuration structs themselves. They
+// are keyed by the path of the respective field, using "*" for map values and "[]" for slice items
+var schemaAnnotations = map[string]map[string]any{
+	"": {
+		"required": []string{"interfaces"},
+	},
+
+	// db
+	"db.path": {
+		"default":   defaults.DBPath,
+		"minLength": 1,
+	},
+	"db.encoder_type": {
+		"default": "lz4",
+		"enum":    []string{"null", "lz4", "lz4cust", "zstd"},
+	},
+	"db.encoders.*": {
+		"required": []string{"type"},
+	},
+	"db.encoders.*.type": {
+		"enum": []string{"null", "lz4", "lz4cust", "zstd"},
+	},
+	"db.encoders.*.level": {"minimum": 0},
+	"db.permissions": {
+		"description": "file mode of the database files / directories (e.g. 0644 in YAML)",
+		"minimum":     0,
+	},
+	"db.spill_buffer_size":       {"minimum": 0},
+	"db.coalesce_max_flows":      {"minimum": 0},
+	"db.query_read_buffer_limit": {"minimum": 0},
+	"db.backlog.max_queue_depth": {"minimum": 0},
+	"db.quota.max_size":          {"minimum": 0},
+	"db.quota.ifaces.*":          {"minimum": 0},
+	"db.quota.policy": {
+		"default": QuotaPolicyDropOldest,
+		"enum":    []string{QuotaPolicyDropOldest, QuotaPolicySkip, QuotaPolicyDownsample},
+	},
+	"db.sync_policy": {
+		"default": string(storage.SyncOSDefault),
+		"enum":    []string{string(storage.SyncAlways), string(storage.SyncPerRotation), string(storage.SyncOSDefault)},
+	},
+	"db.spool": {
+		"required": []string{"path"},
+	},
+	"db.spool.path": {"minLength": 1},
+	"db.spool.max_files": {
+		"default": DefaultSpoolMaxFiles,
+		"minimum": 0,
+	},
+	"db.spool.max_size": {"minimum": 0},
+	"db.hostnames.cache_size": {
+		"default": DefaultHostnamesCacheSize,
+		"minimum": 0,
+	},
+	"db.hostnames.ttl": {
+		"default": DefaultHostnamesTTL.String(),
+	},
+	"db.hostnames.max_pending": {
+		"default": DefaultHostnamesMaxPending,
+		"minimum": 0,
+	},
+	"db.hostnames.timeout": {
+		"default": DefaultHostnamesTimeout.String(),
+	},
+	"db.recompress": {
+		"required": []string{"encoder"},
+	},
+	"db.recompress.encoder": {
+		"required": []string{"type"},
+	},
+	"db.recompress.encoder.type": {
+		"enum": []string{"null", "lz4", "lz4cust", "zstd"},
+	},
+	"db.recompress.encoder.level": {"minimum": 0},
+	"db.recompress.min_age": {
+		"default": DefaultRecompressMinAge.String(),
+	},
+	"db.recompress.interval": {
+		"default": DefaultRecompressInterval.String(),
+	},
+	"db.recompress.pause": {
+		"default": DefaultRecompressPause.String(),
+	},
+	"db.stream": {
+		"required": []string{"target"},
+	},
+	"db.stream.target": {"minLength": 1},
+	"db.stream.queue_size": {
+		"default": DefaultStreamQueueSize,
+		"minimum": 0,
+	},
+	"db.stream.max_in_flight": {
+		"default": DefaultStreamMaxInFlight,
+		"minimum": 0,
+	},
+	"db.stream.max_backoff": {
+		"default": DefaultStreamMaxBackoff.String(),
+	},
+
+	// interfaces
+	"interfaces": {
+		"minProperties": 1,
+	},
+	"interfaces.*.capture_driver": {
+		"default": CaptureDriverAFPacket,
+		"enum":    []string{CaptureDriverAFPacket, CaptureDriverEBPF},
+	},
+	"interfaces.*.ring_buffer": {
+		"description": "required unless the eBPF capture driver is used",
+		"required":    []string{"block_size", "num_blocks"},
+	},
+	"interfaces.*.ring_buffer.block_size": {
+		"default":          DefaultRingBufferBlockSize,
+		"exclusiveMinimum": 0,
+	},
+	"interfaces.*.ring_buffer.num_blocks": {
+		"default":          DefaultRingBufferNumBlocks,
+		"exclusiveMinimum": 0,
+	},
+	"interfaces.*.ebpf.pin_path": {
+		"description": "defaults to " + DefaultEBPFPinRoot + "/<iface>",
+	},
+	"interfaces.*.filter": {
+		"minProperties": 1,
+	},
+	"interfaces.*.cardinality": {
+		"required": []string{"factor"},
+	},
+	"interfaces.*.cardinality.factor": {
+		"exclusiveMinimum": 1,
+	},
+	"interfaces.*.cardinality.history": {
+		"default": DefaultCardinalityHistory,
+		"minimum": 0,
+	},
+	"interfaces.*.cardinality.min_flows": {
+		"minimum": 0,
+	},
+	"interfaces.*.emergency": {
+		"required": []string{"max_flows"},
+	},
+	"interfaces.*.emergency.max_flows": {
+		"minimum": 1,
+	},
+	"interfaces.*.emergency.ipv4_prefix": {
+		"default": DefaultEmergencyIPv4Prefix,
+		"minimum": 0,
+		"maximum": 32,
+	},
+	"interfaces.*.emergency.ipv6_prefix": {
+		"default": DefaultEmergencyIPv6Prefix,
+		"minimum": 0,
+		"maximum": 128,
+	},
+	"interfaces.*.netns": {
+		"description": "requires either a path or a container (but not both)",
+	},
+	"interfaces.*.netns.runtime": {
+		"default": DefaultNetnsRuntime,
+		"pattern": "^unix://",
+	},
+	"interfaces.*.standby": {
+		"description": "not supported by the eBPF capture driver",
+		"required":    []string{"idle_timeout"},
+	},
+	"interfaces.*.standby.poll_interval": {
+		"default": DefaultStandbyPollInterval.String(),
+	},
+	"interfaces.*.watchdog": {
+		"description": "not supported by the eBPF capture driver",
+		"required":    []string{"timeout"},
+	},
+	"interfaces.*.watchdog.check_interval": {
+		"default":     DefaultWatchdogCheckInterval.String(),
+		"description": "must be shorter than the timeout",
+	},
+
+	// logging
+	"logging.level": {
+		"default": "info",
+	},
+	"logging.encoding": {
+		"default": "logfmt",
+	},
+
+	// api
+	"api": {
+		"required": []string{"addr"},
+	},
+	"api.addr": {
+		"minLength": 1,
+	},
+	"api.request_timeout": {
+		"description": "request timeout (in seconds)",
+		"minimum":     0,
+	},
+	"api.keys": {
+		"description": "API keys authorizing access to the routes requiring authentication (e.g. /ingest)",
+	},
+	"api.keys[]": {
+		"minLength": 32,
+	},
+	"api.query_rate_limit.max_req_per_sec": {"minimum": 0},
+	"api.query_rate_limit.max_burst":       {"minimum": 0},
+	"api.query_audit.tenant_header": {
+		"default": audit.DefaultTenantHeader,
+	},
+	"api.query_audit.history_size": {
+		"default": audit.DefaultHistorySize,
+		"minimum": 0,
+	},
+	"api.query_cgroup": {
+		"required": []string{"path"},
+	},
+	"api.query_cgroup.path": {
+		"minLength": 1,
+	},
+	"api.query_cgroup.cpu_weight": {
+		"minimum": cgroup.MinCPUWeight,
+		"maximum": cgroup.MaxCPUWeight,
+	},
+	"api.query_cgroup.memory_max": {"minimum": 0},
+	"api.query_cgroup.tokens.*": {
+		"required": []string{"key"},
+	},
+	"api.query_cgroup.tokens.*.key": {
+		"minLength": 32,
+	},
+	"api.query_cgroup.tokens.*.cpu_weight": {
+		"minimum": cgroup.MinCPUWeight,
+		"maximum": cgroup.MaxCPUWeight,
+	},
+	"api.query_cgroup.tokens.*.memory_max": {"minimum": 0},
+
+	// local_buffers
+	"local_buffers.size_limit": {
+		"default":          DefaultLocalBufferSizeLimit,
+		"exclusiveMinimum": 0,
+	},
+	"local_buffers.num_buffers": {
+		"default":          DefaultLocalBufferNumBuffers,
+		"exclusiveMinimum": 0,
+	},
+
+	// alerting
+	"alerting.webhook": {
+		"required": []string{"url"},
+	},
+
+	// sync
+	"sync": {
+		"required": []string{"target"},
+	},
+	"sync.target": {
+		"pattern": "^https://",
+	},
+	"sync.interval": {
+		"description": "defaults to the writeout interval",
+	},
+	"sync.chunk_size": {
+		"default": dbsync.DefaultChunkSize,
+		"minimum": 0,
+		"maximum": dbsync.MaxChunkSize,
+	},
+
+	// stats_push
+	"stats_push": {
+		"required": []string{"protocol", "addr"},
+	},
+	"stats_push.protocol": {
+		"enum": []string{statspush.ProtocolStatsd, statspush.ProtocolGraphite},
+	},
+	"stats_push.prefix": {
+		"default": statspush.DefaultPrefix,
+	},
+	"stats_push.timeout": {
+		"default": statspush.DefaultTimeout.String(),
+	},
+
+	// tagging
+	"tagging[]": {
+		"required": []string{"tag"},
+	},
+
+	// error_dumps
+	"error_dumps": {
+		"required": []string{"path"},
+	},
+	"error_dumps.max_dumps": {
+		"default": DefaultErrorDumpMaxDumps,
+		"minimum": 0,
+	},
+	"error_dumps.max_size": {
+		"default": DefaultErrorDumpMaxSize,
+		"minimum": 0,
+	},
+	"error_dumps.min_interval": {
+		"default": DefaultErrorDumpMinInterval.String(),
+	},
+
+	// rotation_history
+	"rotation_history.retention": {
+		"default": DefaultRotationHistoryRetention,
+		"minimum": 0,
+	},
+	"rotation_history.max_memory": {
+		"default": DefaultRotationHistoryMaxMemory,
+		"minimum": 0,
+	},
+
+	// sflow
+	"sflow": {
+		"required": []string{"ifaces"},
+	},
+	"sflow.listen": {
+		"default": DefaultSFlowListen,
+	},
+	"sflow.ifaces": {
+		"minProperties": 1,
+	},
+	"sflow.ifaces.*": {
+		"required": []string{"agent"},
+	},
+
+	// flow_export
+	"flow_export": {
+		"required": []string{"target"},
+	},
+	"flow_export.protocol": {
+		"default": DefaultFlowExportProtocol,
+		"enum":    []string{FlowExportProtocolNetFlowV9, FlowExportProtocolIPFIX},
+	},
+	"flow_export.transport": {
+		"default":     DefaultFlowExportTransport,
+		"enum":        []string{FlowExportTransportUDP, FlowExportTransportTCP},
+		"description": "tcp is only supported by ipfix",
+	},
+	"flow_export.max_message_size": {
+		"default": DefaultFlowExportMaxMessageSize,
+		"minimum": 512,
+		"maximum": 65535,
+	},
+	"flow_export.template_refresh": {
+		"default": DefaultFlowExportTemplateRefresh.String(),
+	},
+}
+
+// Schema returns a JSON Schema describing the full goProbe configuration. It is generated from the
+// configuration structs (following their JSON field names) and annotated with the defaults and
+// constraints applied upon parsing / validation, allowing to validate configuration documents prior
+// to deploying them
+func Schema() map[string]any {
+	res := schemaOf(reflect.TypeOf(Config{}), "")
+	res["$schema"] = SchemaDialect
+	res["title"] = "goProbe configuration"
+	return res
+}
+
+func schemaOf(t reflect.Type, path string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var res map[string]any
+	switch {
+	case t == durationType:
+		// durations are provided in their string representation (YAML) or in nanoseconds
+		res = map[string]any{
+			"type":    []string{"string", "integer"},
+			"pattern": durationPattern,
+		}
+	case t.Kind() == reflect.Struct:
+		props := make(map[string]any)
+		addSchemaProperties(t, path, props)
+		res = map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	case t.Kind() == reflect.Map:
+		res = map[string]any{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem(), path+".*"),
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		res = map[string]any{
+			"type":  "array",
+			"items": schemaOf(t.Elem(), path+"[]"),
+		}
+	case t.Kind() == reflect.Bool:
+		res = map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.String:
+		res = map[string]any{"type": "string"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		res = map[string]any{"type": "number"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		res = map[string]any{"type": "integer"}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		res = map[string]any{"type": "integer", "minimum": 0}
+	default:
+		res = make(map[string]any)
+	}
+
+	if annotations, exists := schemaAnnotations[path]; exists {
+		maps.Copy(res, annotations)
+	}
+	return res
+}
+
+// addSchemaProperties adds the schemas of all (JSON) fields of a struct to props, inlining the
+// fields of embedded structs
+func addSchemaProperties(t reflect.Type, path string, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				addSchemaProperties(field.Type, path, props)
+				continue
+			}
+			name = field.Name
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		props[name] = schemaOf(field.Type, fieldPath)
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaPaths collects the paths of all nodes of a (generated) schema
+func schemaPaths(node map[string]any, path string, paths map[string]map[string]any) {
+	paths[path] = node
+	if props, ok := node["properties"].(map[string]any); ok {
+		for name, prop := range props {
+			propPath := name
+			if path != "" {
+				propPath = path + "." + name
+			}
+			schemaPaths(prop.(map[string]any), propPath, paths)
+		}
+	}
+	if values, ok := node["additionalProperties"].(map[string]any); ok {
+		schemaPaths(values, path+".*", paths)
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		schemaPaths(items, path+"[]", paths)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	require.Equal(t, SchemaDialect, schema["$schema"])
+
+	_, err := json.Marshal(schema)
+	require.Nil(t, err)
+
+	paths := make(map[string]map[string]any)
+	schemaPaths(schema, "", paths)
+
+	// all annotations must refer to an existing field (guarding against renamed / removed fields)
+	for path := range schemaAnnotations {
+		require.Contains(t, paths, path, "annotation of non-existent field")
+	}
+
+	var tests = []struct {
+		path     string
+		expected map[string]any
+	}{
+		{"db.path", map[string]any{"type": "string", "default": defaults.DBPath, "minLength": 1}},
+		{"db.permissions", map[string]any{"type": "integer", "minimum": 0}},
+		{"interfaces.*.promisc", map[string]any{"type": "boolean"}},
+		{"interfaces.*.ring_buffer.block_size", map[string]any{"type": "integer", "default": DefaultRingBufferBlockSize, "exclusiveMinimum": 0}},
+		{"interfaces.*.filter.allow[]", map[string]any{"type": "string"}},
+		{"interfaces.*.cardinality.factor", map[string]any{"type": "number", "exclusiveMinimum": 1}},
+		{"api.query_rate_limit.max_req_per_sec", map[string]any{"type": "number", "minimum": 0}},
+		{"error_dumps.min_interval", map[string]any{"type": []string{"string", "integer"}, "default": "1s"}},
+		{"tagging[].match.ports[]", map[string]any{"type": "integer", "minimum": 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			require.Contains(t, paths, test.path)
+			for key, val := range test.expected {
+				require.EqualValues(t, val, paths[test.path][key], key)
+			}
+		})
+	}
+
+	// fields excluded from (or embedded into) the configuration must not show up
+	require.NotContains(t, paths, "Mutex")
+	require.NotContains(t, paths, "interfaces.*.Tagging")
+}
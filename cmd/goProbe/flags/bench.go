@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goprobe/bench"
+)
+
+// BenchCommand denotes the subcommand running a synthetic load benchmark
+const BenchCommand = "bench"
+
+// BenchFlags stores the command line parameters of the bench subcommand
+type BenchFlags struct {
+	Rate             string
+	Flows            string
+	Duration         time.Duration
+	RotationInterval time.Duration
+	DBPath           string
+	LogLevel         string
+}
+
+// ReadBench reads in the command line parameters of the bench subcommand and returns the
+// resulting benchmark configuration
+func ReadBench(args []string) (*BenchFlags, bench.Config, error) {
+	benchFlags := &BenchFlags{}
+
+	fs := flag.NewFlagSet(BenchCommand, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goProbe %s [flags]\n\n", BenchCommand)
+		fmt.Fprintln(fs.Output(), "Drives the capture pipeline with synthetic traffic at the requested load and reports")
+		fmt.Fprintln(fs.Output(), "the achieved throughput, drops and rotation latencies.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&benchFlags.Rate, "rate", "0", "packets per second to generate (e.g. 1Mpps, 250k), 0 for an unlimited rate")
+	fs.StringVar(&benchFlags.Flows, "flows", fmt.Sprint(bench.DefaultFlows), "number of distinct flows to generate (e.g. 100k)")
+	fs.DurationVar(&benchFlags.Duration, "duration", bench.DefaultDuration, "duration of the benchmark")
+	fs.DurationVar(&benchFlags.RotationInterval, "rotation-interval", bench.DefaultRotationInterval, "interval between rotations / writeouts")
+	fs.StringVar(&benchFlags.DBPath, "db-path", "", "path of the database written to (defaults to a temporary directory removed afterwards)")
+	fs.StringVar(&benchFlags.LogLevel, "log-level", "warn", "log level during the benchmark")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, bench.Config{}, err
+	}
+
+	rate, err := bench.ParseRate(benchFlags.Rate)
+	if err != nil {
+		fs.Usage()
+		return nil, bench.Config{}, err
+	}
+	flows, err := bench.ParseCount(benchFlags.Flows)
+	if err != nil {
+		fs.Usage()
+		return nil, bench.Config{}, err
+	}
+
+	return benchFlags, bench.Config{
+		Rate:             rate,
+		Flows:            flows,
+		Duration:         benchFlags.Duration,
+		RotationInterval: benchFlags.RotationInterval,
+		DBPath:           benchFlags.DBPath,
+	}, nil
+}
+
+// IsBench returns if the bench subcommand was invoked
+func IsBench() bool {
+	return len(os.Args) > 1 && os.Args[1] == BenchCommand
+}
@@ -0,0 +1,44 @@
+package flags
+
+import (
+	"flag"
+	"os"
+)
+
+// QueryWorkerCommand denotes the (internal) subcommand running a single query on behalf of the API
+// server in a worker process confined to a control group (c.f. cgroup.Serve)
+const QueryWorkerCommand = "query-worker"
+
+// QueryWorkerFlags stores the command line parameters of the query-worker subcommand
+type QueryWorkerFlags struct {
+	DBPath string
+	Mmap   bool
+}
+
+// ReadQueryWorker reads in the command line parameters of the query-worker subcommand
+func ReadQueryWorker(args []string) (*QueryWorkerFlags, error) {
+	workerFlags := &QueryWorkerFlags{}
+
+	fs := flag.NewFlagSet(QueryWorkerCommand, flag.ContinueOnError)
+	fs.StringVar(&workerFlags.DBPath, "db-path", "", "path of the database queried")
+	fs.BoolVar(&workerFlags.Mmap, "mmap", false, "access the database via memory-mapped files")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return workerFlags, nil
+}
+
+// QueryWorkerArgs returns the arguments invoking the query-worker subcommand for the provided parameters
+func QueryWorkerArgs(dbPath string, mmap bool) []string {
+	args := []string{QueryWorkerCommand, "-db-path", dbPath}
+	if mmap {
+		args = append(args, "-mmap")
+	}
+	return args
+}
+
+// IsQueryWorker returns if the query-worker subcommand was invoked
+func IsQueryWorker() bool {
+	return len(os.Args) > 1 && os.Args[1] == QueryWorkerCommand
+}
@@ -4,12 +4,14 @@ package flags
 import (
 	"errors"
 	"flag"
+	"time"
 )
 
 // Flags stores goProbe's command line parameters
 type Flags struct {
-	Config  string
-	Version bool
+	Config       string
+	Version      bool
+	StartupBench time.Duration
 }
 
 // CmdLine globally exposes the parsed flags
@@ -19,6 +21,7 @@ var CmdLine = &Flags{}
 func Read() error {
 	flag.StringVar(&CmdLine.Config, "config", "", "path to goProbe's configuration file (required)")
 	flag.BoolVar(&CmdLine.Version, "version", false, "print goProbe's version and exit")
+	flag.DurationVar(&CmdLine.StartupBench, "startup-bench", 0, "benchmark each interface for the given duration on startup, warning if its ring buffer is predicted insufficient for the link speed (0 disables)")
 
 	flag.Parse()
 
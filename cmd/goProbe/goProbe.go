@@ -23,9 +23,13 @@ import (
 	"time"
 
 	"github.com/els0r/goProbe/cmd/goProbe/flags"
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
 	gpserver "github.com/els0r/goProbe/pkg/api/goprobe/server"
 	"github.com/els0r/goProbe/pkg/api/server"
 	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/goDB/dbsync"
+	"github.com/els0r/goProbe/pkg/query/audit"
 	"github.com/els0r/goProbe/pkg/version"
 	"github.com/els0r/telemetry/logging"
 
@@ -41,6 +45,16 @@ func main() {
 	// non-zero exit code.
 	// Issues encountered during capture will be logged to syslog by default
 
+	// Run a synthetic load benchmark instead of capturing if requested
+	if flags.IsBench() {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
+	// Run a single query on behalf of the API server if invoked as query worker
+	if flags.IsQueryWorker() {
+		os.Exit(runQueryWorker(os.Args[2:]))
+	}
+
 	// Read / parse command-line flags
 	if err := flags.Read(); err != nil {
 		os.Exit(1)
@@ -113,11 +127,37 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	// Benchmark each interface in the background if requested, predicting if its ring buffer suffices
+	if flags.CmdLine.StartupBench > 0 {
+		go runStartupBench(ctx, captureManager, config.Interfaces, flags.CmdLine.StartupBench)
+	}
+
 	// Initialize constant monitoring / reloading of the config file
 	configMonitor.Start(ctx, captureManager.Update)
 
+	// Periodically synchronize the database to the central collector (if configured)
+	if config.Sync != nil {
+		tlsConfig, err := config.Sync.TLS.ClientConfig()
+		if err != nil {
+			logger.Fatalf("failed to initialize sync TLS configuration: %v", err)
+		}
+		interval := config.Sync.Interval
+		if interval == 0 {
+			interval = time.Duration(goDB.DBWriteInterval) * time.Second
+		}
+
+		syncClient := dbsync.NewClient(config.Sync.Target, config.DB.Path, tlsConfig,
+			dbsync.WithChunkSize(config.Sync.ChunkSize),
+		)
+		logger.With("target", config.Sync.Target, "interval", interval).Info("starting database sync")
+		go syncClient.Run(ctx, interval)
+	}
+
 	// configure api server
-	var apiServer *gpserver.Server
+	var (
+		apiServer *gpserver.Server
+		auditLog  *audit.Log
+	)
 
 	// create server and start listening for requests
 	if config.API != nil {
@@ -135,13 +175,72 @@ func main() {
 
 			// enable global query rate limit if provided
 			server.WithQueryRateLimit(config.API.QueryRateLimit.MaxReqPerSecond, config.API.QueryRateLimit.MaxBurst),
+
+			// serve the embedded web UI if enabled
+			server.WithUI(config.API.UI),
+
+			// expose the optional components in use via the runtime info endpoint
+			server.WithFeatures("goprobe", map[string]bool{
+				"alerting":     config.Alerting != nil,
+				"error_dumps":  config.ErrorDumps != nil,
+				"flow_export":  config.FlowExport != nil,
+				"query_cgroup": config.API.QueryCgroup != nil,
+				"query_mmap":   config.DB.QueryMmap,
+				"quota":        config.DB.Quota != nil,
+				"recompress":   config.DB.Recompress != nil,
+				"stats_push":   config.StatsPush != nil,
+				"stream":       config.DB.Stream != nil,
+				"sync":         config.Sync != nil,
+				"syslog_flows": config.SyslogFlows,
+			}),
+		}
+
+		// record all queries in an audit log if enabled
+		if config.API.QueryAudit != nil {
+			auditLog, err = audit.New(ctx,
+				audit.WithHistorySize(config.API.QueryAudit.HistorySize),
+				audit.WithSinks(config.API.QueryAudit.Sinks...),
+			)
+			if err != nil {
+				logger.Fatalf("failed to initialize query audit log: %v", err)
+			}
+			apiOptions = append(apiOptions, server.WithQueryAudit(auditLog, config.API.QueryAudit.TenantHeader))
+		}
+
+		// limit the request body sizes (beyond the defaults) and compress responses if configured
+		if config.API.MaxBodySize != nil {
+			apiOptions = append(apiOptions,
+				server.WithMaxBodySize(config.API.MaxBodySize.Default),
+				server.WithRouteMaxBodySize(gpapi.ConfigRoute, config.API.MaxBodySize.Config),
+				server.WithRouteMaxBodySize(gpapi.IngestRoute, config.API.MaxBodySize.Ingest),
+			)
+		}
+		apiOptions = append(apiOptions, server.WithCompression(config.API.Compression))
+
+		// API keys authorize access to the routes requiring authentication (e.g. ingesting flows)
+		if len(config.API.Keys) > 0 {
+			apiOptions = append(apiOptions, server.WithKeys(config.API.Keys))
 		}
-		// if len(config.API.Keys) > 0 {
-		// 	apiOptions = append(apiOptions, api.WithKeys(config.API.Keys))
-		// }
 
 		apiServer = gpserver.New(config.API.Addr, captureManager, configMonitor, apiOptions...)
-		apiServer.SetDBPath(config.DB.Path)
+		apiServer.SetDBPath(config.DB.Path).SetQueryMmap(config.DB.QueryMmap)
+
+		// share a limited amount of read buffers between all concurrently running queries if configured
+		goDB.SetReadBufferLimit(config.DB.QueryReadBufferLimit)
+
+		// run queries in worker processes confined to a control group if configured
+		if config.API.QueryCgroup != nil {
+			executable, err := os.Executable()
+			if err != nil {
+				logger.Fatalf("failed to determine query worker executable: %v", err)
+			}
+			err = apiServer.SetQueryCgroup(config.API.QueryCgroup,
+				append([]string{executable}, flags.QueryWorkerArgs(config.DB.Path, config.DB.QueryMmap)...)...,
+			)
+			if err != nil {
+				logger.Fatalf("failed to initialize query control group: %v", err)
+			}
+		}
 
 		logger.With("addr", config.API.Addr).Info("starting API server")
 		go func() {
@@ -171,6 +270,12 @@ func main() {
 			logger.Errorf("forced shut down of goProbe API server: %v", err)
 		}
 	}
+	if auditLog != nil {
+		err = auditLog.Close()
+		if err != nil {
+			logger.Errorf("failed to close query audit log: %v", err)
+		}
+	}
 
 	captureManager.Close(fallbackCtx)
 	logger.Info("graceful shut down completed")
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/els0r/goProbe/cmd/goProbe/flags"
+	"github.com/els0r/goProbe/pkg/goDB/engine"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
+	"github.com/els0r/telemetry/logging"
+)
+
+// runQueryWorker runs the query-worker subcommand, returning the exit code. The query args are read
+// from stdin and the result is written to stdout, hence logs are written to stderr exclusively
+func runQueryWorker(args []string) int {
+	workerFlags, err := flags.ReadQueryWorker(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	if err := logging.Init(logging.LevelWarn, logging.EncodingLogfmt,
+		logging.WithOutput(os.Stderr),
+		logging.WithErrorOutput(os.Stderr),
+	); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	runner := engine.NewQueryRunner(workerFlags.DBPath).WithMmap(workerFlags.Mmap)
+	if err := cgroup.Serve(ctx, runner, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
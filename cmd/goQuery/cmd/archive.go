@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/archive"
+)
+
+// openDB returns the file system and path of the goDB to be queried: the local database
+// directory or, if an archive is provided, the database contained in the archive. The returned
+// function must be called once the goDB is no longer accessed
+func openDB(dbPath, archivePath string) (storage.FS, string, func(), error) {
+	if archivePath == "" {
+		return storage.DefaultFS, dbPath, func() {}, nil
+	}
+
+	afs, err := archive.Open(archivePath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return afs, afs.Root(), func() { _ = afs.Close() }, nil
+}
@@ -33,15 +33,20 @@ const helpBaseLong = helpBase + `
 
       sip   (or src)   source ip
       dip   (or dst)   destination ip
-      dport (or port)  destination port
+      dport (or port)  destination port (ICMP type / code for ICMP flows)
       proto            protocol (e.g. UDP, TCP)
+      tag              tag assigned at capture time (e.g. voip)
+      ttl_min          minimum TTL / hop limit observed (if recorded)
+      ttl_max          maximum TTL / hop limit observed (if recorded)
 
-    Labels which can also be printed as columns:
+    Labels which can be freely combined with the columns above. The traffic is
+    aggregated across all labels which are not part of the query (e.g. "sip,hostname"
+    yields the top talkers of each host across all of its interfaces):
 
-      hostid           unique ID of the host
-      hostname         hostname
-      iface            interface
-      time             timestamp
+      hostid   (or host_id)    unique ID of the host
+      hostname (or host)       hostname
+      iface    (or interface)  interface
+      time                     timestamp
 
   QUERY_TYPE
 
@@ -82,6 +87,10 @@ DEFAULTS
 
   --last will default to the current time if not provided
 
+  Blocks are written once per rotation (every 5 minutes). Unless
+  --time-align is provided, all blocks written between --first and
+  --last are queried.
+
 ALLOWED FORMATS
 
   1357800683                            EPOCH
@@ -208,14 +217,63 @@ ATTRIBUTES:
              "net != 192.168.1.0/24" is equivalent to
              "(snet != 192.168.1.0/24 & dnet != 192.168.1.0/24)"
 
+  Talker by address class:
+
+    Instead of an IP/Hostname, the talker attributes (dip, sip, host, ...)
+    can be compared against one of the following address classes:
+
+    private               RFC1918 and unique local (fc00::/7) addresses
+    linklocal             Link-local addresses (169.254.0.0/16, fe80::/10)
+    bogon                 Addresses that are not globally routable (private,
+                          link-local, loopback, documentation, multicast, ...)
+    public                Globally routable addresses (i.e. no bogon)
+
+    EXAMPLE: "sip is private & dip is public" is equivalent to
+             "sip = private & dip = public"
+             "dip is not bogon" is equivalent to
+             "dip != bogon"
+
   Application:
 
     dport (or port) Destination port
-    proto           IP protocol
+    proto           IP protocol (by number or case-insensitive name,
+                    e.g. tcp, udp, icmp, gre, esp)
 
     EXAMPLE: "dport = 22 & proto = TCP" is equivalent to
              "port = 22 & proto = 6"
 
+    Protocols are printed by name unless --numeric is set.
+
+    ICMP / ICMPv6 flows are grouped by message type and code instead of
+    ports: dport holds the type (with replies grouped under the type of
+    their request, e.g. echo reply under echo) and code of the messages,
+    which is printed as e.g. "echo" or "unreachable/port" (or "3/3" if
+    --numeric is set). In conditions it is provided as "<type>/<code>".
+
+    EXAMPLE: "proto = icmp & dport = 3/3" (ICMP port unreachable)
+
+  Tags:
+
+    tag             Tag assigned by goProbe's tagging rules at capture time
+
+    Besides = and !=, tags can be matched by prefix, suffix or glob pattern
+    (see STRING MATCHING OPERATORS below). Untagged flows never match a
+    pattern (but always match its negation).
+
+    EXAMPLE: "tag = voip" or "tag != backup"
+             "tag ^= voip" or "tag like *-prod"
+
+  TTL / Hop Limit:
+
+    ttl_min         Minimum TTL (IPv4) / hop limit (IPv6) observed for a flow
+    ttl_max         Maximum TTL (IPv4) / hop limit (IPv6) observed for a flow
+
+    Only available for interfaces with record_ttl enabled (0 if not recorded).
+    A range (ttl_min != ttl_max) may indicate path changes, spoofing or
+    asymmetric routing.
+
+    EXAMPLE: "ttl_min < 64" or "ttl_max >= 128 & proto = TCP"
+
   Traffic Direction:
 
     direction (or dir)   Direction filter to match against aggregated results
@@ -239,8 +297,8 @@ COMPARATIVE OPERATORS:
 
   Base    Description            Other representations
 
-     =    equal to               eq, -eq, equals, ==, ===
-    !=    not equal to           neq, -neq, ne, -ne
+     =    equal to               eq, -eq, equals, ==, ===, is
+    !=    not equal to           neq, -neq, ne, -ne, is not
     <=    less or equal to       le, -le, leq, -leq
     >=    greater or equal to    ge, -ge, geq, -geq
      <    less than              less, l, -l, lt, -lt
@@ -252,6 +310,25 @@ All of the items under "Other representations" (except for "===" and
   NOTE: In case the attribute involves an IP address, only "=" and "!="
         are supported.
 
+STRING MATCHING OPERATORS (label attributes such as tag only):
+
+  Base    Description            Other representations
+
+    ^=    starts with            prefix, startswith
+   !^=    does not start with    not prefix, not startswith
+    $=    ends with              suffix, endswith
+   !$=    does not end with      not suffix, not endswith
+    ~=    matches glob pattern   like, glob
+   !~=    does not match glob    not like, not glob
+
+Glob patterns support "*" (any sequence of characters), "?" (any single
+character) and character classes (e.g. "[pt]"). A "*" following one of
+the glob operators is part of the pattern (and not a logical AND). All
+matching is case-insensitive.
+
+    EXAMPLE: "tag like voip-* & dport = 5060"
+             "tag not suffix -test"
+
 Individual conditions can be chained together via logical operators,
 e.g.
 
@@ -312,6 +389,23 @@ and ( dport   le 1024 or dport   ge 443 )
   * { dport -leq 1024 || dport -geq 443 }
 
 and any other combination of the allowed representations.
+
+MACROS
+
+Recurring (complex) conditions can be defined as named macros in the
+configuration file (query.macros) or via --query.macros, e.g.
+
+    corp_nets := snet = 10.0.0.0/8 | snet = 172.16.0.0/12
+    corp_web  := $corp_nets & dport = 443 & proto = TCP
+
+and referenced within conditions via $<name>. Each reference is replaced
+by the condition of the macro, enclosed in braces:
+
+    "$corp_web & ! dip = private" is equivalent to
+    "((snet = 10.0.0.0/8 | snet = 172.16.0.0/12) & dport = 443 & proto = TCP)
+     & ! dip = private"
+
+Macros may reference other macros, but not (indirectly) themselves.
 `,
 
 	"List": `List all interfaces on which data was captured and written
@@ -324,5 +418,43 @@ with --out.
 with --in.
 `,
 	"Sum": `Sum incoming and outgoing data.
+`,
+	"Counters": `Limit the counters aggregated by the query (comma-separated list).
+Counters that are not selected are not read from the database at all,
+significantly reducing I/O for e.g. packet-count-only reports.
+
+  bytes         Received and sent bytes
+  packets       Received and sent packets
+  bytes_in      Received bytes (alias: bytes_rcvd)
+  bytes_out     Sent bytes (alias: bytes_sent)
+  packets_in    Received packets (alias: pkts_rcvd)
+  packets_out   Sent packets (alias: pkts_sent)
+
+If none of the counters used for sorting are selected, the results are
+sorted by the selected ones instead. By default, all counters are aggregated.
+`,
+	"Having": `Predicate on the counters of the aggregated rows. Contrary to the
+condition (which is evaluated on the individual flows), it is evaluated
+after aggregation and can hence select rows by values derived from their
+counters, e.g. by the ratio of received and sent bytes (asymmetry).
+
+A single predicate compares a counter (see --counters for their names)
+or the ratio of two counters with a (non-negative) number:
+
+    packets_out = 0
+    ratio(bytes_in, bytes_out) > 100
+
+A ratio with a denominator of zero is infinite, i.e. one-directional
+traffic exceeds any ratio. Predicates can be chained via & (and) and
+| (or), with & taking precedence, and grouped via parentheses:
+
+    bytes > 1e6 & (ratio(bytes_in, bytes_out) > 100 | packets_out = 0)
+
+The totals of the result only cover the rows fulfilling the predicate.
+For distributed queries, it is evaluated on the rows of each host (and
+once more on the rows merged across hosts).
+
+    EXAMPLE: "--analysis one-way" is equivalent to
+             "--having 'packets_in = 0 | packets_out = 0'"
 `,
 }
@@ -47,14 +47,20 @@ be broken up into IPv4 and IPv6 flows and the drops for that interface will be s
 }
 
 func listInterfacesEntrypoint(_ *cobra.Command, args []string) error {
-	return listInterfaces(viper.GetString(conf.QueryDBPath), args...)
+	return listInterfaces(viper.GetString(conf.QueryDBPath), viper.GetString(conf.QueryDBArchive), args...)
 }
 
 // List interfaces for which data is available and show how many flows and
 // how much traffic was observed for each one.
-func listInterfaces(dbPath string, ifaces ...string) error {
+func listInterfaces(dbPath, archivePath string, ifaces ...string) error {
 	queryArgs := cmdLineParams
 
+	fsys, dbPath, closeDB, err := openDB(dbPath, archivePath)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
 	// TODO: consider making this configurable
 	output := os.Stdout
 
@@ -63,7 +69,7 @@ func listInterfaces(dbPath string, ifaces ...string) error {
 		return err
 	}
 
-	ifaceDirs, err := info.GetInterfaces(dbPath)
+	ifaceDirs, err := info.GetInterfacesFS(fsys, dbPath)
 	if err != nil {
 		return err
 	}
@@ -93,7 +99,7 @@ func listInterfaces(dbPath string, ifaces ...string) error {
 		if err != nil {
 			return fmt.Errorf("failed to set up work manager for %s: %w", iface, err)
 		}
-		dbWorkerManagers = append(dbWorkerManagers, wm)
+		dbWorkerManagers = append(dbWorkerManagers, wm.FS(fsys))
 	}
 
 	var ifacesMetadata = make([]*goDB.InterfaceMetadata, 0, len(dbWorkerManagers))
@@ -9,17 +9,18 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"syscall"
-	"time"
 
 	"github.com/els0r/goProbe/cmd/goQuery/pkg/conf"
 	"github.com/els0r/goProbe/pkg/api/globalquery/client"
 	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/goDB/conditions"
 	"github.com/els0r/goProbe/pkg/goDB/engine"
 	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/push"
 	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
 	"github.com/els0r/goProbe/pkg/version"
 	"github.com/els0r/telemetry/logging"
 	"github.com/els0r/telemetry/tracing"
@@ -96,10 +97,12 @@ func init() {
 	flags.BoolVarP(&cmdLineParams.In, "in", "", query.DefaultIn, helpMap["In"])
 	flags.BoolVarP(&cmdLineParams.Out, "out", "", query.DefaultOut, helpMap["Out"])
 	flags.BoolVarP(&cmdLineParams.Sum, "sum", "", false, helpMap["Sum"])
+	flags.StringVarP(&cmdLineParams.Counters, "counters", "", "", helpMap["Counters"])
 	flags.BoolVarP(&cmdLineParams.Version, "version", "v", false, "Print version information and exit\n")
 
 	flags.StringVarP(&cmdLineParams.Ifaces, "ifaces", "i", "", helpMap["Ifaces"])
 	flags.StringVarP(&cmdLineParams.Condition, "condition", "c", "", helpMap["Condition"])
+	flags.StringVar(&cmdLineParams.Having, conf.Having, "", helpMap["Having"])
 
 	flags.StringVarP(&cmdLineParams.SortBy, conf.SortBy, "s", query.DefaultSortBy,
 		`Sort results by given column name:
@@ -121,6 +124,102 @@ Ignored for queries including the "time" field.
 `,
 	)
 
+	flags.BoolVar(&cmdLineParams.HumanReadable, conf.ResultsHumanReadable, false,
+		`Render byte and packet counters in human-readable units (e.g. KiB / MiB / GiB)
+instead of raw values. Only permitted for the txt and csv output formats.
+`,
+	)
+	flags.BoolVar(&cmdLineParams.DirectionPercentages, conf.ResultsDirectionPercentages, false,
+		`Include percentage-of-total columns for each direction (received / sent)
+in addition to the combined percentage. Only applies if both directions are shown.
+Only permitted for the txt and csv output formats.
+`,
+	)
+	flags.BoolVar(&cmdLineParams.Numeric, conf.Numeric, false,
+		`Print IP protocols as numbers (e.g. 17) instead of their names (e.g. UDP).
+Names and numbers can be used interchangeably in conditions regardless.
+`,
+	)
+	flags.BoolVar(&cmdLineParams.FlowHash, conf.FlowHash, false,
+		`Add the canonical flow hash to each row (64-bit FNV-1a of the sip, dip, dport and
+proto attributes, see pkg/results/flowhash.go for the exact definition). It is
+independent of the probe the flow was recorded on and can be used to join flows
+across probes or with data exported to other systems. Requires the query to
+contain all of these attributes (e.g. "raw" or "sip,dip,dport,proto").
+`,
+	)
+	flags.BoolVar(&cmdLineParams.CommunityID, conf.CommunityID, false,
+		`Add the Community ID (version 1) of each flow, as used by e.g. Zeek and Suricata,
+in order to join flows with IDS alerts and other tooling relying on it. Since
+source ports aren't recorded, it is only set for flows of protocols without
+ports (e.g. ICMP, GRE or ESP) and left empty for TCP, UDP and SCTP flows.
+Requires the query to contain the sip, dip, dport and proto attributes (e.g. "raw").
+`,
+	)
+	flags.Uint16Var(&cmdLineParams.CommunityIDSeed, conf.CommunityIDSeed, 0,
+		`Seed of the Community ID (has to match the one configured in the tools the
+flows are joined with)
+`,
+	)
+	flags.BoolVar(&cmdLineParams.Roles, conf.Roles, false,
+		`Correlate the traffic of each host as source (sip, i.e. as client) and as
+destination (dip, i.e. as server), emitting a single row per host (and all other
+attributes) instead of one per flow, e.g. to compare the data volume a host
+fetched with the data volume it served. Requires the query to contain both the
+sip and dip attributes (e.g. "talk_conv" or "sip,dip,dport").
+`,
+	)
+	flags.BoolVar(&cmdLineParams.Stats, conf.Stats, false,
+		`Summarize the data volume of each row per time bin (5-minute interval) over the
+queried time range by its median (p50), 95th percentile (p95), maximum and
+standard deviation, emitting a single row per set of attributes instead of the
+raw bins. Time bins without any traffic of a row count as zero, hence the
+percentiles reflect the load over the entire time range (e.g. for capacity
+planning). Cannot be combined with the time attribute.
+`,
+	)
+	flags.StringVar(&cmdLineParams.Analysis, conf.Analysis, "",
+		`Evaluate the result according to an analysis mode instead of printing its rows.
+Supported modes:
+  asn-matrix  Traffic between all pairs of source and destination ASNs over the
+              queried time range (e.g. for peering and transit cost analysis).
+              Requires the query to contain the sip and dip attributes, an ASN
+              database (see --`+conf.ASNDB+`) and the json or csv format
+  one-way     Only the one-directional rows (i.e. without any packets in one of
+              the directions), a common indicator of blocked responses,
+              exfiltration or broken mirrors. The rows are printed as usual
+              (shortcut for --`+conf.Having+` 'packets_in = 0 | packets_out = 0')
+`,
+	)
+	flags.StringVar(&cmdLineParams.ASNDB, conf.ASNDB, "",
+		`Path to the database mapping IP prefixes to ASNs (used by the asn-matrix
+analysis), containing one prefix per line, followed by the ASN and (optionally)
+its name, e.g. "1.1.1.0/24 13335 CLOUDFLARENET"
+`,
+	)
+	flags.IntVar(&cmdLineParams.Sample, conf.Sample, 0,
+		`Return a uniform random sample of N of the matching rows instead of sorting and
+limiting them, along with the traffic of all matching rows extrapolated from the
+sample (and its relative standard error). Meant for quick exploratory looks at
+enormous results (0: no sampling)
+`,
+	)
+	flags.StringVar(&cmdLineParams.TimeZone, conf.TimeZone, "",
+		`Time zone timestamps are printed in (IANA name, e.g. Europe/Zurich, "UTC" or
+"Local"). Daylight saving time is taken into account for each timestamp individually.
+JSON output keeps RFC3339 timestamps, carrying the offset of the time zone.
+Defaults to the local time zone.
+`,
+	)
+	flags.StringVar(&cmdLineParams.TimeFormat, conf.TimeFormat, "",
+		`Format timestamps are printed in (csv and txt output). Either one of
+  default   2006-01-02 15:04:05 (txt default)
+  rfc3339   2006-01-02T15:04:05+01:00
+  unix      seconds since the epoch (csv default)
+or a custom layout in Go reference time notation (e.g. "02.01.2006 15:04")
+`,
+	)
+
 	flags.BoolVarP(&cmdLineParams.DNSResolution.Enabled, conf.DNSResolutionEnabled, "r", false,
 		`Resolve top IPs in output using reverse DNS lookups.
 If the reverse DNS lookup for an IP fails, the IP is shown instead.
@@ -148,9 +247,61 @@ a high load on the DNS resolver and network!
 	flags.BoolVar(&cmdLineParams.LowMem, conf.MemoryLowMode, false,
 		`Enable low-memory mode (reduces overall memory use at the expense of higher CPU
 and I/O load)
+`,
+	)
+	flags.IntVar(&cmdLineParams.MaxAggMem, conf.MemoryMaxAgg, 0,
+		`Memory budget (in MiB) for the aggregation of flows. If exceeded, partial
+aggregates are spilled to temporary, compressed files (in $TMPDIR) and merged at
+the end of the query instead of running out of memory (0: no budget)
+`,
+	)
+	flags.BoolVar(&cmdLineParams.Mmap, conf.QueryDBMmap, false,
+		`Read the database via memory-mapped IO (reduces the syscall overhead of large
+scans, e.g. on NVMe-backed archives)
+`,
+	)
+	flags.IntVar(&cmdLineParams.IOWorkers, conf.QueryDBIOWorkers, 0,
+		`Number of workers reading / decompressing the blocks of an interface in parallel
+(0: number of available CPUs)
+`,
+	)
+	flags.IntVar(&cmdLineParams.CPUWorkers, conf.QueryDBCPUWorkers, 0,
+		`Number of workers filtering / aggregating the blocks read by the IO workers in
+parallel (0: number of available CPUs)
+`,
+	)
+	flags.BoolVar(&cmdLineParams.Explain, conf.Explain, false,
+		`Show the execution plan of the query instead of running it: the day partitions
+and interfaces read, column projections, filter pushdowns, parallelism and memory
+estimates (derived from the database metadata only, no flow data is read)
 `,
 	)
 	flags.StringVarP(&cmdLineParams.QueryHosts, conf.QueryHostsResolution, "q", "", "Hosts resolution query\n")
+	flags.StringVar(&cmdLineParams.TimeAlign, conf.TimeAlign, "",
+		`Handle a time range cutting through blocks (each covering one 5 minute rotation).
+By default, all blocks written within the time range are queried:
+  snap          Expand the time range to the rotation boundaries enclosing it (the
+                effective time range is reported in the summary)
+  interpolate   Scale the traffic of blocks only partially overlapping the time range
+                by the fraction of the overlap (assuming evenly distributed traffic)
+`,
+	)
+	flags.StringVar(&cmdLineParams.Dedup, conf.QueryDedup, "",
+		`Detect mirrored rows in distributed queries, i.e. the same flow observed by
+multiple hosts (e.g. both sides of a link) in inverse directions:
+  merge         Count mirrored rows only once
+  flag          Keep mirrored rows, but flag them as such
+Only applies to queries run against a query server.
+`,
+	)
+	flags.StringVar(&cmdLineParams.Cache, conf.QueryCache, "",
+		`Control the use of the per-host results cached by the query server (if enabled):
+  use           Use fresh cached results instead of querying the respective hosts (default)
+  refresh       Query all hosts and cache their results
+  off           Neither read nor populate the cache
+Only applies to queries run against a query server.
+`,
+	)
 
 	// persistent flags to be also passed to children commands
 	pflags.String(conf.ProfilingOutputDir, "", "Enable and set directory to store CPU and memory profiles")
@@ -160,6 +311,10 @@ and I/O load)
   txt           Output in plain text format (default)
   json          Output in JSON format
   csv           Output in comma-separated table format
+  pcapng        Output one synthetic marker packet per flow in pcapng format, carrying the
+                flow counters as packet options (e.g. for correlation with captures in Wireshark)
+  influxdb      Output one point per flow in InfluxDB line protocol (e.g. for --push-to an
+                InfluxDB / VictoriaMetrics write endpoint)
 `,
 	)
 
@@ -180,11 +335,50 @@ If it does not exist, an error will be thrown.
 This also implies that you have to explicitly specify
 the path if you analyze data on a different host without
 goProbe.
+`,
+	)
+	pflags.String(conf.QueryDBArchive, "",
+		`Path to a tar (optionally gzip / zstd compressed) or zip archive containing goDB
+data (e.g. a backup), which is queried instead of the database directory. The
+location of the database within the archive is determined automatically.
+Members are read on demand, hence the archive does not have to be restored
+(compressed tar archives are decompressed to a temporary file in $TMPDIR)
 `,
 	)
 	pflags.String(conf.StoredQuery, "", "Load JSON serialized query arguments from disk and run them\n")
 	pflags.Duration(conf.QueryTimeout, query.DefaultQueryTimeout, "Abort query processing after timeout expires\n")
 	pflags.String(conf.QueryLog, "", "Log query invocations to file\n")
+	pflags.Uint64(conf.QuerySeed, 0,
+		`Seed for the hash maps used during query processing (0: random). Setting a
+fixed seed renders the internal iteration order reproducible across runs
+`,
+	)
+	pflags.Bool(conf.QueryProgress, false,
+		`Continuously print the progress of the query (directories scanned, data processed,
+rows aggregated and an estimated time until completion) to stderr while it is running
+`,
+	)
+
+	pflags.StringArray(conf.QueryMacros, nil,
+		`Define a condition macro, which can be referenced in conditions via $<name>.
+Format: "<name> := <condition>", e.g. "corp_nets := snet = 10.0.0.0/8 | snet = 172.16.0.0/12".
+Macros may reference other macros. Can be provided multiple times (usually, macros are
+defined in the configuration file instead)
+`,
+	)
+
+	pflags.String(conf.PushTo, "",
+		`POST the completed query result to the given URL (http or https). The result
+is serialized according to the output format (json, csv or txt)
+`,
+	)
+	pflags.StringArray(conf.PushHeaders, nil,
+		`Additional header sent with the push request, e.g. for authentication.
+Format: "Key: Value". Can be provided multiple times
+`,
+	)
+	pflags.Int(conf.PushRetries, push.DefaultRetries, "Number of retries (with back-off) if pushing the result fails\n")
+	pflags.Duration(conf.PushTimeout, push.DefaultTimeout, "Timeout for a single push attempt\n")
 
 	pflags.String(conf.LogLevel, logging.LevelWarn.String(), "log level (debug, info, warn, error, fatal, panic)")
 
@@ -228,6 +422,8 @@ const (
 	queryPrepFailureMsg = "failed to prepare query"
 )
 
+var jsonSortedKeys = jsoniter.Config{EscapeHTML: true, SortMapKeys: true}.Froze()
+
 // main program entrypoint
 func entrypoint(cmd *cobra.Command, args []string) (err error) {
 	// assign query args
@@ -240,7 +436,7 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 	// run commands that don't require any argument
 	// handle list flag
 	if cmdLineParams.List {
-		err := listInterfaces(dbPathCfg)
+		err := listInterfaces(dbPathCfg, viper.GetString(conf.QueryDBArchive))
 		if err != nil {
 			return fmt.Errorf("failed to retrieve list of available databases: %w", err)
 		}
@@ -286,7 +482,7 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	// make sure there's protection against unbounded time intervals
-	queryArgs = setDefaultTimeRange(&queryArgs)
+	queryArgs = query.SetDefaultTimeRange(&queryArgs)
 
 	queryCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	defer stop()
@@ -315,6 +511,18 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 
 	queryArgs.Caller = os.Args[0] // take the full path of called binary
 
+	// expand all references to condition macros (resolved locally, even for queries run against a query server)
+	macros, err := conditions.ParseMacros(viper.GetStringSlice(conf.QueryMacros)...)
+	if err != nil {
+		return fmt.Errorf("failed to parse condition macros: %w", err)
+	}
+	if queryArgs.Condition, err = macros.Expand(queryArgs.Condition); err != nil {
+		return types.ShouldPretty(err, queryPrepFailureMsg)
+	}
+
+	// use a fixed hash map seed for processing (if requested)
+	hashmap.SetSeed(viper.GetUint64(conf.QuerySeed))
+
 	// run the query
 	var result *results.Result
 
@@ -327,24 +535,17 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 			return err
 		}
 
-		// store the query type and make sure that aliases are resolved. This
-		// is important so that the hostname/hostid can be appended
-		queryArgs.Query = types.SanitizeQueryType(queryArgs.Query)
-
-		// make sure that the hostname is present in the query type (and therefore output)
-		// The assumption being that a human will have better knowledge
-		// of hostnames than of their ID counterparts
-		if queryArgs.Format == "txt" {
-			if !strings.Contains(queryArgs.Query, types.HostnameName) {
-				queryArgs.Query += types.AttrSep + types.HostnameName
-			}
-		}
-
 		// query using query server
 		querier = client.New(viper.GetString(conf.QueryServerAddr))
 	} else {
-		// query using local goDB
-		querier = engine.NewQueryRunner(dbPathCfg)
+		// query using local goDB (or the one contained in an archive)
+		fsys, dbPath, closeDB, err := openDB(dbPathCfg, viper.GetString(conf.QueryDBArchive))
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		querier = engine.NewQueryRunner(dbPath).WithFS(fsys)
 	}
 
 	// check if the traceparent is set
@@ -392,7 +593,16 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 		}
 	}
 
+	// report the progress of long-running queries on stderr, keeping stdout free for the result
+	progressDone := func() {}
+	if viper.GetBool(conf.QueryProgress) {
+		var progressFn query.ProgressFn
+		progressFn, progressDone = query.NewProgressPrinter(os.Stderr)
+		ctx = query.WithProgress(ctx, progressFn)
+	}
+
 	result, err = querier.Run(ctx, &queryArgs)
+	progressDone()
 	if err != nil {
 		return fmt.Errorf(`failed to execute query
 
@@ -401,9 +611,18 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 %s`, err, types.PrettyIndent(stmt, 4))
 	}
 
-	// serialize raw results array if json is selected
-	if stmt.Format == "json" {
-		err = jsoniter.NewEncoder(stmt.Output).Encode(result)
+	// deliver the result to a remote endpoint if requested
+	if pushURL := viper.GetString(conf.PushTo); pushURL != "" {
+		err = pushResult(ctx, pushURL, stmt, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	// serialize raw results array if json is selected (with sorted map keys for the output to
+	// be deterministic), unless the result is subject to analysis
+	if stmt.Format == "json" && stmt.Analysis == "" {
+		err = jsonSortedKeys.NewEncoder(stmt.Output).Encode(result)
 		if err != nil {
 			return fmt.Errorf("failed to serialize query results: %w", err)
 		}
@@ -429,26 +648,19 @@ func entrypoint(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
-// setDefaultTimeRange handles the defaults for time arguments if they aren't set
-func setDefaultTimeRange(args *query.Args) query.Args {
-	logger := logging.Logger()
-	if args.First == "" {
-		logger.Debug("setting default value for 'first'")
-
-		// protect against queries that are possibly too large and only go back a day if a time attribute
-		// is included. This is only done if first wasn't explicitly set. If it is, it must be assumed that
-		// the caller knows the possible extend of a "time" query
-		if strings.Contains(args.Query, types.TimeName) || strings.Contains(args.Query, types.RawCompoundQuery) {
-			logger.With("query", args.Query).Debug("time attribute detected, limiting time range to one day")
-			args.First = time.Now().AddDate(0, 0, -1).Format(time.ANSIC)
-		} else {
-			// by default, go back one month in time
-			args.First = time.Now().AddDate(0, -1, 0).Format(time.ANSIC)
-		}
+// pushResult POSTs the result to the remote endpoint at pushURL
+func pushResult(ctx context.Context, pushURL string, stmt *query.Statement, result *results.Result) error {
+	headers, err := push.ParseHeaders(viper.GetStringSlice(conf.PushHeaders))
+	if err != nil {
+		return fmt.Errorf("failed to parse push headers: %w", err)
 	}
-	if args.Last == "" {
-		logger.Debug("setting default value for 'last'")
-		args.Last = time.Now().Format(time.ANSIC)
+	pusher, err := push.New(pushURL,
+		push.WithHeaders(headers),
+		push.WithRetries(viper.GetInt(conf.PushRetries)),
+		push.WithTimeout(viper.GetDuration(conf.PushTimeout)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set up result push: %w", err)
 	}
-	return *args
+	return pusher.Push(ctx, stmt, result)
 }
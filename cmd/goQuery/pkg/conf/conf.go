@@ -8,10 +8,21 @@ const (
 	QueryServerAddr      = serverKey + ".addr"
 	QueryTimeout         = queryKey + ".timeout"
 	QueryHostsResolution = queryKey + ".hosts-resolution"
+	QueryDedup           = queryKey + ".dedup"
+	QueryCache           = queryKey + ".cache"
 	QueryLog             = queryKey + ".log"
+	QuerySeed            = queryKey + ".seed"
+	QueryProgress        = queryKey + ".progress"
+	QueryMacros          = queryKey + ".macros"
 
 	dbKey       = "db"
 	QueryDBPath = dbKey + ".path"
+	QueryDBMmap = dbKey + ".mmap"
+
+	QueryDBArchive = dbKey + ".archive"
+
+	QueryDBIOWorkers  = dbKey + ".io-workers"
+	QueryDBCPUWorkers = dbKey + ".cpu-workers"
 
 	StoredQuery = "stored-query"
 
@@ -35,14 +46,39 @@ const (
 	ResultsFormat = resultsKey + ".format"
 	ResultsLimit  = resultsKey + ".limit"
 
+	ResultsHumanReadable        = resultsKey + ".human-readable"
+	ResultsDirectionPercentages = resultsKey + ".direction-percentages"
+	Numeric                     = "numeric"
+	TimeZone                    = "tz"
+	TimeFormat                  = "time-format"
+	Explain                     = "explain"
+	FlowHash                    = "flow-hash"
+	CommunityID                 = "community-id"
+	CommunityIDSeed             = "community-id-seed"
+	Roles                       = "roles"
+	Stats                       = "stats"
+	Analysis                    = "analysis"
+	ASNDB                       = "asn-db"
+	Sample                      = "sample"
+	Having                      = "having"
+
+	// Result delivery
+	PushTo      = "push-to"
+	pushKey     = "push"
+	PushHeaders = pushKey + ".headers"
+	PushRetries = pushKey + ".retries"
+	PushTimeout = pushKey + ".timeout"
+
 	// Memory
 	memoryKey     = "memory"
 	MemoryMaxPct  = memoryKey + ".max-pct"
 	MemoryLowMode = memoryKey + ".low-mode"
+	MemoryMaxAgg  = memoryKey + ".max-agg"
 
 	// Time
-	First = "first"
-	Last  = "last"
+	First     = "first"
+	Last      = "last"
+	TimeAlign = "time-align"
 
 	// Profiling
 	profilingKey       = "profiling"
@@ -172,7 +172,7 @@ func bashCompletion(args []string) {
 		// handled by wrapper bash script
 		return
 	case "-e":
-		printlns(filterPrefix(last(args), "txt", "json", "csv", "influxdb"))
+		printlns(filterPrefix(last(args), "txt", "json", "csv", "pcapng", "influxdb"))
 		return
 	case "-f", "-l", "-h", "--help":
 		return
@@ -14,8 +14,8 @@ import (
 	"strings"
 
 	"github.com/els0r/goProbe/pkg/goDB/conditions"
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
 func openParens(tokens []string) (open int) {
@@ -130,6 +130,9 @@ func nextAll(prevprev, prev string, openParens int) []suggestion {
 			s(types.DportName, false),
 			s("port", false),
 			s(types.ProtoName, false),
+			s(types.TagName, false),
+			s(types.TTLMinName, false),
+			s(types.TTLMaxName, false),
 			s(types.FilterKeywordDirection, false),
 			s(types.FilterKeywordDirectionSugared, false),
 		}
@@ -147,13 +150,27 @@ func nextAll(prevprev, prev string, openParens int) []suggestion {
 			s(types.DportName, false),
 			s("port", false),
 			s(types.ProtoName, false),
+			s(types.TagName, false),
+			s(types.TTLMinName, false),
+			s(types.TTLMaxName, false),
 		}
 	case types.DIPName, types.SIPName, "dnet", "snet", "dst", "src", "host", "net":
 		return []suggestion{
 			s("=", false),
 			s("!=", false),
 		}
-	case types.DportName, "port", types.ProtoName:
+	case types.TagName:
+		return []suggestion{
+			s("=", false),
+			s("!=", false),
+			s("^=", false),
+			s("!^=", false),
+			s("$=", false),
+			s("!$=", false),
+			s("~=", false),
+			s("!~=", false),
+		}
+	case types.DportName, "port", types.ProtoName, types.TTLMinName, types.TTLMaxName:
 		return []suggestion{
 			s("=", false),
 			s("!=", false),
@@ -166,7 +183,7 @@ func nextAll(prevprev, prev string, openParens int) []suggestion {
 		return []suggestion{
 			s("=", false),
 		}
-	case "=", "!=", "<", ">", "<=", ">=":
+	case "=", "!=", "<", ">", "<=", ">=", "^=", "!^=", "$=", "!$=", "~=", "!~=":
 		switch prevprev {
 		case types.ProtoName:
 			var result []suggestion
@@ -180,6 +197,12 @@ func nextAll(prevprev, prev string, openParens int) []suggestion {
 				result = append(result, s(direction, true))
 			}
 			return result
+		case types.DIPName, types.SIPName, "dst", "src", "host":
+			var result []suggestion
+			for _, class := range types.IPClasses {
+				result = append(result, suggestion{class, class + " ...", openParens == 0})
+			}
+			return result
 		default:
 			return nil
 		}
@@ -204,7 +227,7 @@ func nextAll(prevprev, prev string, openParens int) []suggestion {
 		}
 	default:
 		switch prevprev {
-		case "=", "!=", "<", ">", "<=", ">=":
+		case "=", "!=", "<", ">", "<=", ">=", "^=", "!^=", "$=", "!$=", "~=", "!~=":
 			if openParens > 0 {
 				return []suggestion{
 					s(")", openParens == 1),
@@ -388,7 +411,7 @@ func conditionMustTerminate(suggs []suggestion, prevprev, prev, last string, ope
 		// Do not auto terminate on incomplete direction filters.
 		case types.FilterKeywordDirection, types.FilterKeywordDirectionSugared:
 			return false
-		case "=", "!=", "<", ">", "<=", ">=", "&", "|":
+		case "=", "!=", "<", ">", "<=", ">=", "^=", "!^=", "$=", "!$=", "~=", "!~=", "&", "|":
 			if last == "" {
 				return false
 			}
@@ -21,8 +21,8 @@ func testConditionals(t *testing.T, tests []conditionalTest) {
 
 func TestConditionalsBasic(t *testing.T) {
 	var conditionalTestsBasic = []conditionalTest{
-		{[]string{""}, 15},
-		{[]string{"!"}, 12},
+		{[]string{""}, 18},
+		{[]string{"!"}, 15},
 		{[]string{"goquery", "-c", "d"}, 6},
 		{[]string{"goquery", "-c", "di"}, 3},
 		{[]string{"goquery", "-c", "dir"}, 2},
@@ -41,19 +41,19 @@ func TestConditionalsStructure(t *testing.T) {
 		{[]string{"goquery", "-c", "dir = in"}, 2},
 
 		// Complete inbound + only suggest & + don't suggest another dir keyword.
-		{[]string{"goquery", "-c", "dir = inb"}, 14},
+		{[]string{"goquery", "-c", "dir = inb"}, 17},
 		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dir = inb"}, 1},
 		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dir = inb"}, 1},
 
 		// Suggest dir directly after top-level &.
-		{[]string{"goquery", "-c", "sip = 127.0.0.1 & "}, 15},
-		{[]string{"goquery", "-c", "(sip = 127.0.0.1 & dport = 22) & "}, 15},
+		{[]string{"goquery", "-c", "sip = 127.0.0.1 & "}, 18},
+		{[]string{"goquery", "-c", "(sip = 127.0.0.1 & dport = 22) & "}, 18},
 		// Don't suggest dir after non-top-level &.
-		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dport = 22 & "}, 13},
+		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dport = 22 & "}, 16},
 		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dport = 22 & dir = "}, 2},
 
 		// Don't suggest dir after top-level |.
-		{[]string{"goquery", "-c", "sip = 127.0.0.1 | "}, 13},
+		{[]string{"goquery", "-c", "sip = 127.0.0.1 | "}, 16},
 
 		// Don't suggest after invalid condition strings.
 		{[]string{"goquery", "-c", "sip = 127.0.0.1 & dport = 22 & dir = "}, 2},
@@ -75,8 +75,8 @@ func TestConditionalsEdgeCases(t *testing.T) {
 		{[]string{"goquery", "-c", "dir = out & (sip = 127.0.0.1 | dport = 22) "}, 1},
 
 		// Do not terminate condition string.
-		{[]string{"goquery", "-c", "dir = out & (sip = 127.0.0.1 |"}, 13},
-		{[]string{"goquery", "-c", "dir = out "}, 13},
+		{[]string{"goquery", "-c", "dir = out & (sip = 127.0.0.1 |"}, 16},
+		{[]string{"goquery", "-c", "dir = out "}, 16},
 		{[]string{"goquery", "-c", "dir = inbound & ( dst = 127.0.0.1 & src = 127.0.0.1) &"}, 2},
 		{[]string{"goquery", "-c", "dir = inbound & ( dst = 127.0.0.1 & src = 127.0.0.1) |"}, 2},
 		{[]string{"goquery", "-c", "dir = inbound & ( dst = 127.0.0.1 & src = 127.0.0.1) & "}, 2},
@@ -27,12 +27,15 @@ func queryType(args []string) []string {
 
 	unusedAttribs := func(attribs []string) []string {
 		attribUnused := map[string]bool{
-			"time":          true,
-			"iface":         true,
-			types.SIPName:   true,
-			types.DIPName:   true,
-			types.DportName: true,
-			types.ProtoName: true,
+			"time":           true,
+			"iface":          true,
+			types.SIPName:    true,
+			types.DIPName:    true,
+			types.DportName:  true,
+			types.ProtoName:  true,
+			types.TagName:    true,
+			types.TTLMinName: true,
+			types.TTLMaxName: true,
 		}
 
 		for _, attrib := range attribs {
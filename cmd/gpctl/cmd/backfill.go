@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	apiclient "github.com/els0r/goProbe/pkg/api/client"
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagBackfillFirst = "first"
+	flagBackfillLast  = "last"
+	flagBackfillPcap  = "pcap"
+)
+
+var (
+	backfillFirst string
+	backfillLast  string
+	backfillPcap  string
+)
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill IFACE",
+	Short: "Re-write / backfill a time interval of an interface",
+	Long: `Re-write / backfill a time interval of an interface
+
+Writes the flows of the interval [--first, --last] that could not be written to
+the database during regular operation (e.g. after a disk-full incident). By default,
+all writeouts of the interval retained in goprobe's spill buffer are written.
+
+If -p|--pcap is provided, all packets contained in the (optionally gzip compressed)
+pcap file are written as a single block for the end of the interval instead. The
+path refers to the host goprobe is running on.
+
+Existing blocks for the same timestamp are replaced, so a backfill can safely be
+repeated. The provenance of each backfilled block is recorded in the database metadata.
+
+Since all affected database files may have to be rewritten, consider increasing the
+request timeout (-t|--timeout) for larger intervals.
+`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(backfillEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+
+	backfillCmd.Flags().StringVarP(&backfillFirst, flagBackfillFirst, "f", "", "start of the interval to backfill (same formats as for goQuery)")
+	backfillCmd.Flags().StringVarP(&backfillLast, flagBackfillLast, "l", "", "end of the interval to backfill (default: now)")
+	backfillCmd.Flags().StringVarP(&backfillPcap, flagBackfillPcap, "p", "", "pcap file on the goprobe host to backfill the interval from")
+}
+
+func backfillEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr), apiclient.WithAPIKey(viper.GetString(conf.GoProbeServerKey)))
+
+	if backfillFirst == "" {
+		cmd.SilenceUsage = false
+		return errors.New("start of the interval to backfill must be provided via --first")
+	}
+	first, last, err := query.ParseTimeRange(backfillFirst, backfillLast)
+	if err != nil {
+		return err
+	}
+
+	iface := args[0]
+	results, err := client.Backfill(ctx, &gpapi.BackfillRequest{
+		Iface: iface,
+		From:  time.Unix(first, 0),
+		To:    time.Unix(last, 0),
+		Pcap:  backfillPcap,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill interface %s: %w", iface, err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Backfilled Blocks (%s)", iface))
+
+	table.AddRow("timestamp", "source", "action", "flows")
+	table.AddSeparator()
+
+	for _, res := range results {
+		action := "added"
+		if res.Replaced {
+			action = "replaced"
+		}
+		table.AddRow(res.Timestamp.Local().Format(time.RFC3339), res.Source, action, res.NumFlows)
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+	table.SetAlign(tablewriter.AlignLeft, 3)
+	table.SetAlign(tablewriter.AlignRight, 4)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
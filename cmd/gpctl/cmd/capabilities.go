@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+// capabilitiesCmd represents the capabilities command
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities [IFACE]",
+	Short: "Show the NIC capabilities of interfaces and settings distorting flow accounting",
+	Long: `Show the NIC capabilities of interfaces and settings distorting flow accounting
+
+Offloads like GRO / LRO (or TSO / GSO for outbound traffic) merge packets into
+super-packets before they reach the capture, causing the packet counts of the
+affected flows to be underestimated. For each such setting (and e.g. ring buffers
+too small for the link speed), a hint and a suggested remediation is shown.
+
+Without an interface, the capabilities probed upon initialization of all captures
+are shown. If an interface is provided, it is probed on demand (allowing to validate
+an interface before capturing on it).
+`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          wrapCancellationContext(capabilitiesEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(capabilitiesCmd)
+}
+
+func capabilitiesEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	var iface string
+	if len(args) > 0 {
+		iface = args[0]
+	}
+	capabilities, err := client.Capabilities(ctx, iface)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface capabilities: %w", err)
+	}
+
+	ifaces := make([]string, 0, len(capabilities))
+	for iface := range capabilities {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Interface Capabilities"))
+
+	table.AddRow("iface", "mtu", "speed", "offloads")
+	table.AddSeparator()
+
+	var hints []string
+	for _, iface := range ifaces {
+		caps := capabilities[iface]
+
+		speed := "-"
+		if caps.Speed > 0 {
+			speed = fmt.Sprintf("%d Mbit/s", caps.Speed)
+		}
+
+		offloads := make([]string, 0, len(caps.Offloads))
+		for offload, enabled := range caps.Offloads {
+			if enabled {
+				offloads = append(offloads, offload)
+			}
+		}
+		sort.Strings(offloads)
+
+		table.AddRow(iface, caps.MTU, speed, strings.Join(offloads, ","))
+
+		for _, hint := range caps.Hints {
+			hints = append(hints, fmt.Sprintf("%s: %s", iface, hint.Message))
+			if hint.Remediation != "" {
+				hints = append(hints, fmt.Sprintf("  -> %s", hint.Remediation))
+			}
+		}
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignRight, 2)
+	table.SetAlign(tablewriter.AlignRight, 3)
+	table.SetAlign(tablewriter.AlignLeft, 4)
+
+	fmt.Println(table.Render())
+
+	if len(hints) > 0 {
+		fmt.Println(shellformat.Fmt(shellformat.Bold, "Hints"))
+		fmt.Println()
+		fmt.Println(strings.Join(hints, "\n"))
+		fmt.Println()
+	}
+
+	return nil
+}
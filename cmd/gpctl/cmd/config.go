@@ -5,8 +5,11 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
@@ -22,16 +25,20 @@ import (
 
 const (
 	flagFile   = "file"
+	flagPatch  = "patch"
 	flagReload = "reload"
 	flagSilent = "silent"
+	flagSchema = "schema"
 
 	defaultRequestTimeout = 3 * time.Second
 )
 
 var (
-	file   string
-	silent bool
-	reload bool
+	file      string
+	patchFile string
+	silent    bool
+	reload    bool
+	schema    bool
 )
 
 // configCmd represents the config command
@@ -43,9 +50,13 @@ var configCmd = &cobra.Command{
 If the (list of) interface(s) is provided as an argument, it will only
 show the configuration for them. Otherwise, all configurations are printed
 
-The list of interfaces is ignored if -f|--file or -r|--reload is provided (which
-are mutually exclusive and both trigger a change of goprobe's runtime configuration,
-either from the provided file or reloading the on-disk configuration).
+The list of interfaces is ignored if -f|--file, -p|--patch or -r|--reload is provided
+(which are mutually exclusive and all trigger a change of goprobe's runtime configuration,
+either replacing the interface configuration by the one from the provided file, applying
+a JSON merge patch (RFC 7396) to the configuration or reloading the on-disk configuration).
+
+If --schema is provided, the JSON Schema describing goprobe's full configuration is printed
+instead (e.g. for editor validation or config-generation tooling).
 `,
 	RunE:          wrapCancellationContext(configEntrypoint),
 	SilenceUsage:  true,
@@ -56,17 +67,28 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 
 	configCmd.Flags().StringVarP(&file, flagFile, "f", "", "apply config file to goprobe's runtime configuration")
+	configCmd.Flags().StringVarP(&patchFile, flagPatch, "p", "", "apply JSON merge patch (RFC 7396) from file to goprobe's runtime configuration")
 	configCmd.Flags().BoolVarP(&reload, flagReload, "r", false, "reload on-disk config file and apply it to goprobe's runtime configuration")
 	configCmd.Flags().BoolVar(&silent, flagSilent, false, "don't output interface changes after update")
+	configCmd.Flags().BoolVar(&schema, flagSchema, false, "print the JSON Schema of goprobe's configuration")
 }
 
 func configEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) error {
 	client := client.New(viper.GetString(conf.GoProbeServerAddr))
 
-	// If the user specifies both a reload from disk and provided a config, abort (and show usage)
-	if file != "" && reload {
+	// If the user specifies more than one way to change the configuration, abort (and show usage)
+	var numChanges int
+	for _, change := range []bool{file != "", patchFile != "", reload} {
+		if change {
+			numChanges++
+		}
+	}
+	if numChanges > 1 {
 		cmd.SilenceUsage = false
-		return errors.New("cannot perform both config reload from disk and apply external runtime configuration")
+		return errors.New("cannot perform more than one of config reload from disk, applying external runtime configuration or patch")
+	}
+	if schema {
+		return printConfigSchema(ctx)
 	}
 	if reload {
 		return reloadConfig(ctx)
@@ -74,6 +96,9 @@ func configEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 	if file != "" {
 		return updateConfig(ctx, file, silent)
 	}
+	if patchFile != "" {
+		return patchConfig(ctx, patchFile, silent)
+	}
 
 	ifaces := args
 
@@ -129,6 +154,19 @@ func configEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 	return nil
 }
 
+func printConfigSchema(ctx context.Context) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	res, err := client.GetConfigSchema(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch schema of goprobe's configuration: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
 func reloadConfig(ctx context.Context) error {
 	client := client.New(viper.GetString(conf.GoProbeServerAddr))
 
@@ -161,18 +199,50 @@ func updateConfig(ctx context.Context, file string, silent bool) error {
 	}
 
 	// send update call
-	enabled, updated, disabled, err := client.UpdateInterfaceConfigs(ctx, gpConfig.Interfaces)
+	changes, enabled, updated, disabled, err := client.UpdateInterfaceConfigs(ctx, gpConfig.Interfaces)
 	if err != nil {
 		return fmt.Errorf("failed to update goprobe's runtime configuration: %w", err)
 	}
 
 	if !silent {
+		printConfigChanges(changes)
 		printIfaceChanges(enabled, updated, disabled)
 	}
 
 	return nil
 }
 
+func patchConfig(ctx context.Context, file string, silent bool) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	patch, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return fmt.Errorf("failed to read config patch %s: %w", file, err)
+	}
+
+	// send patch call
+	changes, enabled, updated, disabled, err := client.PatchConfig(ctx, patch)
+	if err != nil {
+		return fmt.Errorf("failed to patch goprobe's runtime configuration: %w", err)
+	}
+
+	if !silent {
+		printConfigChanges(changes)
+		printIfaceChanges(enabled, updated, disabled)
+	}
+
+	return nil
+}
+
+func printConfigChanges(changes config.Changes) {
+	fmt.Printf("\n     Changes: %d\n", len(changes))
+	for _, change := range changes {
+		oldVal, _ := json.Marshal(change.Old)
+		newVal, _ := json.Marshal(change.New)
+		fmt.Printf("              %s: %s -> %s\n", change.Path, oldVal, newVal)
+	}
+}
+
 func printIfaceChanges(enabled, updated, disabled capturetypes.IfaceChanges) {
 	fmt.Printf(`
      Enabled: %s
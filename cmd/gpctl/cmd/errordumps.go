@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagOutput = "output"
+)
+
+// errorDumpsCmd represents the errordumps command
+var errorDumpsCmd = &cobra.Command{
+	Use:   "errordumps IFACE [ID]",
+	Short: "List / retrieve dumps of packets that could not be parsed",
+	Long: `List / retrieve dumps of packets that could not be parsed
+
+If error dumps are enabled in the goProbe configuration (error_dumps), the payloads
+of (a rate-limited sample of) packets that could not be parsed are retained on disk,
+allowing parser issues to be reproduced offline.
+
+Without an ID, the metadata of all dumps retained for the interface is listed. If an
+ID is provided, the raw IP layer of the respective dump is printed as hex dump or
+written to a file (--output).
+`,
+	Args:          cobra.RangeArgs(1, 2),
+	RunE:          wrapCancellationContext(errorDumpsEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var errorDumpOutput string
+
+func init() {
+	rootCmd.AddCommand(errorDumpsCmd)
+
+	errorDumpsCmd.Flags().StringVarP(&errorDumpOutput, flagOutput, "o", "", "write the raw IP layer of the dump to a file")
+}
+
+func errorDumpsEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	iface := args[0]
+	if len(args) == 1 {
+		return listErrorDumps(ctx, client, iface)
+	}
+
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid error dump ID %q: %w", args[1], err)
+	}
+	payload, err := client.ErrorDump(ctx, iface, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch error dump %d of interface %s: %w", id, iface, err)
+	}
+
+	if errorDumpOutput != "" {
+		return os.WriteFile(filepath.Clean(errorDumpOutput), payload, 0600)
+	}
+	fmt.Print(hex.Dump(payload))
+
+	return nil
+}
+
+func listErrorDumps(ctx context.Context, client *client.Client, iface string) error {
+	dumps, err := client.ErrorDumps(ctx, iface)
+	if err != nil {
+		return fmt.Errorf("failed to fetch error dumps of interface %s: %w", iface, err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Error Dumps (%s)", iface))
+
+	table.AddRow("id", "timestamp", "error", "pkt type", "pkt size", "dumped")
+	table.AddSeparator()
+
+	for _, dump := range dumps {
+		table.AddRow(dump.ID, dump.Timestamp.Local().Format(time.RFC3339), dump.Error, dump.PktType, dump.PktSize, dump.Size)
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignRight, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+	table.SetAlign(tablewriter.AlignLeft, 3)
+	table.SetAlign(tablewriter.AlignRight, 4)
+	table.SetAlign(tablewriter.AlignRight, 5)
+	table.SetAlign(tablewriter.AlignRight, 6)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+// flushCmd represents the flush command
+var flushCmd = &cobra.Command{
+	Use:   "flush [IFACES]",
+	Short: "Trigger an immediate writeout of all (or the specified) interfaces",
+	Long: `Trigger an immediate writeout of all (or the specified) interfaces
+
+Rotates the flows captured since the last writeout and writes them to the database
+right away (out of the regular writeout cycle), e.g. right before maintenance or
+upgrades, or when an investigation requires the freshest data to be available in
+the database. The regular writeout schedule is not affected.
+
+Flows that cannot be written (e.g. due to a full disk) are retained in the writeout
+backlog, as with any regular writeout.
+`,
+	RunE:          wrapCancellationContext(flushEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(flushCmd)
+}
+
+func flushEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	res, err := client.Writeout(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("failed to flush interfaces: %w", err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Flushed Interfaces (%s)", res.Timestamp.Local().Format(time.RFC3339)))
+
+	table.AddRow("iface", "flows")
+	table.AddSeparator()
+
+	var total int
+	for _, iface := range res.Ifaces {
+		table.AddRow(iface.Iface, iface.NumFlows)
+		total += iface.NumFlows
+	}
+	table.AddSeparator()
+	table.AddRow("Total", total)
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignRight, 2)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
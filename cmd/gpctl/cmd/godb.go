@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	apiclient "github.com/els0r/goProbe/pkg/api/client"
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagVacuumDryRun = "dry-run"
+	flagVacuumMinAge = "min-age"
+
+	flagMigrateDryRun = "dry-run"
+	flagMigrateForce  = "force"
+)
+
+var (
+	vacuumDryRun bool
+	vacuumMinAge time.Duration
+
+	migrateDryRun bool
+	migrateForce  bool
+)
+
+// godbCmd represents the godb command
+var godbCmd = &cobra.Command{
+	Use:   "godb",
+	Short: "Maintain goprobe's database",
+}
+
+var godbVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Reclaim disk space from goprobe's database",
+	Long: `Reclaim disk space from goprobe's database
+
+Removes all data of interfaces that are no longer part of goprobe's configuration,
+as long as it is older than a safety window (--min-age). In addition, all column
+files containing data not referenced by the database metadata (e.g. left behind by
+interrupted writeouts) are compacted.
+
+The reclaimed disk space is reported per interface. Use --dry-run to only report
+the disk space that would be reclaimed, without modifying the database.
+
+Since all database directories have to be inspected, consider increasing the
+request timeout (-t|--timeout) for larger databases.
+`,
+	Args:          cobra.NoArgs,
+	RunE:          wrapCancellationContext(godbVacuumEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var godbMigrateCmd = &cobra.Command{
+	Use:   "migrate [IFACE...]",
+	Short: "Re-encode goprobe's database with the configured encoders",
+	Long: `Re-encode goprobe's database with the configured encoders
+
+Since the encoder is recorded per block, data written prior to changing the encoder
+of an interface (db.encoders) remains readable, but is only stored with the new
+encoder once migrated. All database directories of the provided interfaces (or of
+all interfaces if none are provided) containing data stored with another encoder
+are rewritten.
+
+The compression level is not recorded, hence in order to apply a changed level,
+use --force to re-encode all data regardless of its encoder. Use --dry-run to only
+report the data that would be re-encoded, without modifying the database.
+
+Since all database directories have to be inspected (and possibly rewritten),
+consider increasing the request timeout (-t|--timeout) for larger databases.
+`,
+	RunE:          wrapCancellationContext(godbMigrateEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(godbCmd)
+	godbCmd.AddCommand(godbVacuumCmd)
+	godbCmd.AddCommand(godbMigrateCmd)
+
+	godbVacuumCmd.Flags().BoolVar(&vacuumDryRun, flagVacuumDryRun, false, "only report the disk space that would be reclaimed")
+	godbVacuumCmd.Flags().DurationVar(&vacuumMinAge, flagVacuumMinAge, vacuum.DefaultMinAge, "minimum age of the data of unconfigured interfaces before it is removed")
+
+	godbMigrateCmd.Flags().BoolVar(&migrateDryRun, flagMigrateDryRun, false, "only report the data that would be re-encoded")
+	godbMigrateCmd.Flags().BoolVar(&migrateForce, flagMigrateForce, false, "re-encode all data, even if it is already stored with the configured encoder")
+}
+
+func godbVacuumEntrypoint(ctx context.Context, _ *cobra.Command, _ []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr), apiclient.WithAPIKey(viper.GetString(conf.GoProbeServerKey)))
+
+	res, err := client.Vacuum(ctx, &gpapi.VacuumRequest{
+		DryRun: vacuumDryRun,
+		MinAge: vacuumMinAge.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	fmt.Println()
+
+	title := "Vacuumed Interfaces"
+	if res.DryRun {
+		title += " (dry-run)"
+	}
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "%s", title))
+
+	table.AddRow("iface", "configured", "removed dirs", "compacted dirs", "reclaimed")
+	table.AddSeparator()
+
+	for _, ifaceRes := range res.Ifaces {
+		table.AddRow(ifaceRes.Iface, ifaceRes.Configured, ifaceRes.RemovedDirs, ifaceRes.CompactedDirs, formatting.Size(uint64(ifaceRes.ReclaimedBytes)))
+	}
+	table.AddSeparator()
+	table.AddRow("", "", "", "Total", formatting.Size(uint64(res.Ifaces.ReclaimedBytes())))
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+	table.SetAlign(tablewriter.AlignRight, 3)
+	table.SetAlign(tablewriter.AlignRight, 4)
+	table.SetAlign(tablewriter.AlignRight, 5)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func godbMigrateEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr), apiclient.WithAPIKey(viper.GetString(conf.GoProbeServerKey)))
+
+	res, err := client.Migrate(ctx, &gpapi.MigrateRequest{
+		Ifaces: args,
+		DryRun: migrateDryRun,
+		Force:  migrateForce,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	fmt.Println()
+
+	title := "Migrated Interfaces"
+	if res.DryRun {
+		title += " (dry-run)"
+	}
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "%s", title))
+
+	table.AddRow("iface", "encoder", "migrated dirs", "before", "after")
+	table.AddSeparator()
+
+	for _, ifaceRes := range res.Ifaces {
+		after := formatting.Size(uint64(ifaceRes.BytesAfter))
+		if res.DryRun {
+			after = "-"
+		}
+		table.AddRow(ifaceRes.Iface, ifaceRes.Encoder, ifaceRes.MigratedDirs, formatting.Size(uint64(ifaceRes.BytesBefore)), after)
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+	table.SetAlign(tablewriter.AlignRight, 3)
+	table.SetAlign(tablewriter.AlignRight, 4)
+	table.SetAlign(tablewriter.AlignRight, 5)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
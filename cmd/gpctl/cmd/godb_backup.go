@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/goDB/backup"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagBackupDBPath  = "db-path"
+	flagBackupTo      = "to"
+	flagBackupRetries = "retries"
+)
+
+var (
+	backupDBPath  string
+	backupTo      string
+	backupRetries int
+)
+
+var godbBackupCmd = &cobra.Command{
+	Use:   "backup [IFACE...]",
+	Short: "Back up goprobe's database while it is running",
+	Long: `Back up goprobe's database while it is running
+
+Copies all data of the provided interfaces (or of all interfaces if none are
+provided) to --to, which must either not exist or be empty. In contrast to copying
+the database files verbatim (e.g. via rsync), the backup is consistent even while
+goprobe is writing to the database: of each directory, only the data referenced by
+its metadata is copied and verified (including block checksums, if available). If
+a directory changes while being copied, the copy is retried (up to --retries times).
+
+The backup can be queried like any database and is complete up to the last rotation
+committed prior to copying the respective directory.
+
+The database is accessed directly (--db-path), hence gpctl has to be run on the host
+goprobe is running on.
+`,
+	Example:       `  gpctl godb backup --to /mnt/backup/godb`,
+	Annotations:   map[string]string{annotationLocal: ""},
+	RunE:          wrapSignalContext(godbBackupEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	godbCmd.AddCommand(godbBackupCmd)
+
+	godbBackupCmd.Flags().StringVarP(&backupDBPath, flagBackupDBPath, "d", defaults.DBPath, "path to the goDB")
+	godbBackupCmd.Flags().StringVar(&backupTo, flagBackupTo, "", "path to back up the goDB to (must not exist or be empty)")
+	godbBackupCmd.Flags().IntVar(&backupRetries, flagBackupRetries, backup.DefaultMaxRetries, "number of times the copy of a directory is retried if it changed while being copied")
+	_ = godbBackupCmd.MarkFlagRequired(flagBackupTo)
+}
+
+func godbBackupEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	res, err := backup.New(backupDBPath,
+		backup.WithRetries(backupRetries, backup.DefaultRetryInterval),
+	).Run(ctx, backupTo, args...)
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Backed up to %s", backupTo))
+
+	table.AddRow("iface", "dirs", "blocks", "retries", "size", "last")
+	table.AddSeparator()
+
+	for _, ifaceRes := range res {
+		last := "-"
+		if !ifaceRes.Last.IsZero() {
+			last = ifaceRes.Last.Local().Format(time.RFC3339)
+		}
+		table.AddRow(ifaceRes.Iface, ifaceRes.Dirs, ifaceRes.Blocks, ifaceRes.Retries, formatting.Size(uint64(ifaceRes.Bytes)), last)
+	}
+	table.AddSeparator()
+	table.AddRow("", "", "", "Total", formatting.Size(uint64(res.Bytes())), "")
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	for i := 2; i <= 5; i++ {
+		table.SetAlign(tablewriter.AlignRight, i)
+	}
+	table.SetAlign(tablewriter.AlignLeft, 6)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
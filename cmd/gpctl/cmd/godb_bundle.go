@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/defaults"
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/goDB/bundle"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagBundleDBPath    = "db-path"
+	flagBundleIfaces    = "iface"
+	flagBundleFrom      = "from"
+	flagBundleTo        = "to"
+	flagBundleAnonymize = "anonymize"
+	flagBundleOutput    = "output"
+)
+
+var (
+	bundleDBPath    string
+	bundleIfaces    []string
+	bundleFrom      string
+	bundleTo        string
+	bundleAnonymize bool
+	bundleOutput    string
+)
+
+var godbExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a slice of goprobe's database to a bundle",
+	Long: `Export a slice of goprobe's database to a bundle
+
+Writes all data of the selected interfaces (--iface, default: all interfaces) within
+the interval [--from, --to] to a self-contained bundle (a zstd compressed tar archive),
+e.g. to share a reproducible dataset as part of a support case. The bundle contains a
+manifest describing its origin and content (including the database schema version).
+
+If --anonymize is provided, all IP addresses are replaced by pseudonyms (consistently
+across the bundle, retaining the address family) and the host name is omitted.
+
+The database is accessed directly (--db-path), hence gpctl has to be run on the host
+goprobe is running on. Bundles can be queried directly via goQuery --archive or be
+imported into another database via "gpctl godb import".
+`,
+	Example:       `  gpctl godb export --iface eth0 --from -2d --to -1d -o bundle.tar.zst`,
+	Args:          cobra.NoArgs,
+	Annotations:   map[string]string{annotationLocal: ""},
+	RunE:          wrapSignalContext(godbExportEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var godbImportCmd = &cobra.Command{
+	Use:   "import BUNDLE",
+	Short: "Import a bundle into a database",
+	Long: `Import a bundle into a database
+
+Imports all data contained in a bundle created via "gpctl godb export" into the
+database located at --db-path (which is created if it does not exist). The import
+is rejected if any of the contained directories already exist in the database, so
+existing data is never modified. It is advisable to import bundles into a dedicated
+database (instead of the one goprobe is writing to).
+`,
+	Args:          cobra.ExactArgs(1),
+	Annotations:   map[string]string{annotationLocal: ""},
+	RunE:          wrapSignalContext(godbImportEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	godbCmd.AddCommand(godbExportCmd)
+	godbCmd.AddCommand(godbImportCmd)
+
+	for _, cmd := range []*cobra.Command{godbExportCmd, godbImportCmd} {
+		cmd.Flags().StringVarP(&bundleDBPath, flagBundleDBPath, "d", defaults.DBPath, "path to the goDB")
+	}
+
+	godbExportCmd.Flags().StringSliceVarP(&bundleIfaces, flagBundleIfaces, "i", nil, "interfaces to export (default: all interfaces)")
+	godbExportCmd.Flags().StringVar(&bundleFrom, flagBundleFrom, "", "start of the interval to export (same formats as for goQuery, default: first block)")
+	godbExportCmd.Flags().StringVar(&bundleTo, flagBundleTo, "", "end of the interval to export (default: now)")
+	godbExportCmd.Flags().BoolVar(&bundleAnonymize, flagBundleAnonymize, false, "anonymize all IP addresses")
+	godbExportCmd.Flags().StringVarP(&bundleOutput, flagBundleOutput, "o", "", "path of the bundle to write (e.g. bundle"+bundle.FileSuffix+")")
+	_ = godbExportCmd.MarkFlagRequired(flagBundleOutput)
+}
+
+func godbExportEntrypoint(ctx context.Context, _ *cobra.Command, _ []string) (err error) {
+	first, last, err := query.ParseTimeRange(bundleFrom, bundleTo)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(bundleOutput, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+
+		// Do not leave any partial bundle behind
+		if err != nil {
+			err = errors.Join(err, os.Remove(bundleOutput))
+		}
+	}()
+
+	manifest, err := bundle.Export(ctx, bundleDBPath, f,
+		bundle.WithIfaces(bundleIfaces...),
+		bundle.WithTimeRange(first, last),
+		bundle.WithAnonymization(bundleAnonymize),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to export database: %w", err)
+	}
+
+	printManifest(fmt.Sprintf("Exported to %s", bundleOutput), manifest)
+
+	return nil
+}
+
+func godbImportEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	f, err := os.Open(args[0]) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	manifest, err := bundle.Import(ctx, f, bundleDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+
+	printManifest(fmt.Sprintf("Imported to %s", bundleDBPath), manifest)
+
+	return nil
+}
+
+func printManifest(title string, manifest *bundle.Manifest) {
+	fmt.Println()
+
+	origin := manifest.Version
+	if manifest.Host != "" {
+		origin = fmt.Sprintf("%s@%s", origin, manifest.Host)
+	}
+	if manifest.Anonymized {
+		origin += ", anonymized"
+	}
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "%s (%s)", title, origin))
+
+	table.AddRow("iface", "dirs", "blocks", "flows", "packets", "bytes")
+	table.AddSeparator()
+
+	for _, iface := range manifest.Ifaces {
+		table.AddRow(iface.Iface, iface.Dirs, iface.Blocks,
+			formatting.Count(iface.Flows),
+			formatting.Count(iface.Counts.SumPackets()),
+			formatting.Size(iface.Counts.SumBytes()),
+		)
+	}
+	table.AddSeparator()
+	table.AddRow("", "", "", "", "First", manifest.First.Local().Format(time.RFC3339))
+	table.AddRow("", "", "", "", "Last", manifest.Last.Local().Format(time.RFC3339))
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	for i := 2; i <= 6; i++ {
+		table.SetAlign(tablewriter.AlignRight, i)
+	}
+
+	fmt.Println(table.Render())
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagInfoJSON = "json"
+	flagInfoDeps = "deps"
+
+	defaultGODEBUGSetting = "DefaultGODEBUG"
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show build and runtime diagnostics of goProbe",
+	Long: `Show build and runtime diagnostics of goProbe
+
+Prints the version / commit, Go version, build settings, garbage collector and allocator
+statistics, goroutine counts and the enabled features of the running goProbe instance.
+
+Please include the output (e.g. via --json) when reporting a bug.
+`,
+	RunE:          wrapCancellationContext(infoEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var infoJSON, infoDeps bool
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoJSON, flagInfoJSON, false, "print the diagnostics as JSON")
+	infoCmd.Flags().BoolVar(&infoDeps, flagInfoDeps, false, "list the module dependencies goProbe was built with")
+}
+
+func infoEntrypoint(ctx context.Context, _ *cobra.Command, _ []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	info, err := client.RuntimeInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch runtime info: %w", err)
+	}
+
+	if infoJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Runtime Info (%s)", info.Name))
+
+	addInfoRow(table, "version", info.Version)
+	addInfoRow(table, "commit", info.Commit)
+	if info.BuildTime != nil {
+		addInfoRow(table, "build time", info.BuildTime.Local().Format(types.DefaultTimeOutputFormat))
+	}
+	addInfoRow(table, "running since", fmt.Sprintf("%s (%s ago)",
+		info.Started.Local().Format(types.DefaultTimeOutputFormat),
+		time.Duration(info.UptimeNs).Round(time.Second),
+	))
+
+	addInfoSection(table, "go")
+	addInfoRow(table, "version", info.Go.Version)
+	addInfoRow(table, "platform", info.Go.OS+"/"+info.Go.Arch)
+	addInfoRow(table, "cpus / gomaxprocs", fmt.Sprintf("%d / %d", info.Go.NumCPU, info.Go.GOMAXPROCS))
+	addInfoRow(table, "goroutines", info.Go.Goroutines)
+	addInfoRow(table, "cgo calls", info.Go.CgoCalls)
+	if info.Build != nil {
+		addInfoRow(table, "module", strings.TrimSpace(info.Build.Module.Path+" "+info.Build.Module.Version))
+		for _, key := range sortedKeys(info.Build.Settings) {
+			// the default GODEBUG settings are too verbose for a table (they are included in the JSON output)
+			if key == defaultGODEBUGSetting {
+				continue
+			}
+			addInfoRow(table, key, info.Build.Settings[key])
+		}
+	}
+
+	addInfoSection(table, "garbage collector")
+	addInfoRow(table, "cycles (forced)", fmt.Sprintf("%d (%d)", info.GC.NumGC, info.GC.NumForcedGC))
+	if info.GC.LastGC != nil {
+		addInfoRow(table, "last cycle", fmt.Sprintf("%s ago (paused %s)",
+			time.Since(*info.GC.LastGC).Round(time.Millisecond),
+			time.Duration(info.GC.LastPauseNs), // #nosec G115
+		))
+	}
+	addInfoRow(table, "total pause", time.Duration(info.GC.PauseTotalNs)) // #nosec G115
+	addInfoRow(table, "cpu fraction", fmt.Sprintf("%.4f%%", info.GC.CPUFraction*100))
+	addInfoRow(table, "GOGC", gcPercentString(info.GC.GOGC))
+	addInfoRow(table, "GOMEMLIMIT", memoryLimitString(info.GC.MemoryLimit))
+	addInfoRow(table, "next target heap", formatting.Sizeable(info.GC.NextGC))
+
+	addInfoSection(table, "memory")
+	addInfoRow(table, "obtained from OS", formatting.Sizeable(info.Memory.Sys))
+	addInfoRow(table, "heap allocated", formatting.Sizeable(info.Memory.HeapAlloc))
+	addInfoRow(table, "heap in use / idle", fmt.Sprintf("%s / %s",
+		formatting.Sizeable(info.Memory.HeapInuse), formatting.Sizeable(info.Memory.HeapIdle)),
+	)
+	addInfoRow(table, "heap released", formatting.Sizeable(info.Memory.HeapReleased))
+	addInfoRow(table, "heap objects", formatting.Countable(info.Memory.HeapObjects))
+	addInfoRow(table, "stack in use", formatting.Sizeable(info.Memory.StackInuse))
+	addInfoRow(table, "total allocated", formatting.Sizeable(info.Memory.TotalAlloc))
+	addInfoRow(table, "mallocs / frees", fmt.Sprintf("%s / %s",
+		formatting.Countable(info.Memory.Mallocs), formatting.Countable(info.Memory.Frees)),
+	)
+
+	for _, module := range sortedKeys(info.Features) {
+		addInfoSection(table, "features: "+module)
+		for _, feature := range sortedKeys(info.Features[module]) {
+			enabled := "disabled"
+			if info.Features[module][feature] {
+				enabled = shellformat.Fmt(shellformat.Bold, "enabled")
+			}
+			addInfoRow(table, feature, enabled)
+		}
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+
+	fmt.Println(table.Render())
+
+	if infoDeps && info.Build != nil {
+		printInfoDeps(info.Build.Deps)
+	}
+
+	return nil
+}
+
+func printInfoDeps(deps []api.Module) {
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Dependencies"))
+
+	table.AddRow("module", "version", "replaced by")
+	table.AddSeparator()
+	for _, dep := range deps {
+		table.AddRow(dep.Path, dep.Version, dep.Replace)
+	}
+
+	// set alignment before rendering
+	for i := 1; i <= 3; i++ {
+		table.SetAlign(tablewriter.AlignLeft, i)
+	}
+
+	fmt.Println(table.Render())
+}
+
+func addInfoSection(table *tablewriter.Table, title string) {
+	table.AddSeparator()
+	table.AddRow(shellformat.Fmt(shellformat.Bold, "%s", title), "")
+	table.AddSeparator()
+}
+
+func addInfoRow(table *tablewriter.Table, key string, value any) {
+	if str, ok := value.(string); ok && str == "" {
+		value = "-"
+	}
+	table.AddRow(key, value)
+}
+
+func gcPercentString(percent int) string {
+	if percent < 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d", percent)
+}
+
+func memoryLimitString(limit int64) string {
+	if limit <= 0 || limit == math.MaxInt64 {
+		return "none"
+	}
+	return formatting.Size(uint64(limit))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+// pinsCmd represents the pins command
+var pinsCmd = &cobra.Command{
+	Use:   "pins",
+	Short: "Review the certificates pinned by the query server",
+	Long: `Review the certificates pinned by the query server
+
+If certificate pinning is enabled (pinning.enabled), the query server pins the
+certificate presented by each queried host upon first contact (trust on first use).
+If a host subsequently presents a different certificate (e.g. after a renewal or due
+to a man-in-the-middle), the host isn't queried until the change has been approved.
+
+Lists all pinned certificates and the pending changes thereof.
+
+The query server (global-query) address is configured via --query.server.addr.
+Approving, rejecting or removing pins requires one of the API keys configured for
+the query server (server.keys), provided via --query.server.key.
+`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: verifyPinsArgs,
+	RunE:              wrapCancellationContext(pinsEntrypoint),
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var pinsApproveCmd = &cobra.Command{
+	Use:   "approve HOST",
+	Short: "Approve the pending certificate change of a host",
+	Long: `Approve the pending certificate change of a host
+
+Pins the certificate the host presented most recently, replacing the pinned one.
+Make sure the change is expected (e.g. by comparing the fingerprint with the one
+of the certificate deployed on the host) before approving it.
+`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(pinsApproveEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var pinsRejectCmd = &cobra.Command{
+	Use:           "reject HOST",
+	Short:         "Reject the pending certificate change of a host",
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(pinsRejectEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var pinsRemoveCmd = &cobra.Command{
+	Use:           "rm HOST",
+	Short:         "Remove the pinned certificate of a host (it is pinned anew upon the next query)",
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(pinsRemoveEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(pinsCmd)
+	pinsCmd.AddCommand(pinsApproveCmd, pinsRejectCmd, pinsRemoveCmd)
+
+	pinsCmd.PersistentFlags().String(conf.QueryServerAddr, "", "server address of the query server (global-query) API")
+	pinsCmd.PersistentFlags().String(conf.QueryServerKey, "", "API key of the query server (required to approve, reject or remove pins)")
+}
+
+// verifyPinsArgs binds the query server address / key flags (which are shared with other commands, hence
+// they are only bound once the command is run) and verifies them
+func verifyPinsArgs(cmd *cobra.Command, args []string) error {
+	_ = viper.BindPFlag(conf.QueryServerAddr, cmd.Flag(conf.QueryServerAddr))
+	_ = viper.BindPFlag(conf.QueryServerKey, cmd.Flag(conf.QueryServerKey))
+	return verifyQueryServerArgs(cmd, args)
+}
+
+func pinsEntrypoint(ctx context.Context, _ *cobra.Command, _ []string) error {
+	pins, pending, err := newQueryServerClient().ListPins(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pinned certificates: %w", err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Pinned Certificates"))
+
+	table.AddRow("host", "fingerprint (SHA-256)", "subject", "expires", "since")
+	table.AddSeparator()
+	for _, pin := range pins {
+		table.AddRow(pin.Host, pin.Fingerprint, orDash(pin.Subject), formatTime(pin.NotAfter), formatTime(pin.Since))
+	}
+	for i := 1; i <= 5; i++ {
+		table.SetAlign(tablewriter.AlignLeft, i)
+	}
+
+	fmt.Println(table.Render())
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	table = tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold|shellformat.Red, "Pending Changes (hosts are not queried until approved)"))
+
+	table.AddRow("host", "presented fingerprint (SHA-256)", "subject", "expires", "since")
+	table.AddSeparator()
+	for _, change := range pending {
+		table.AddRow(change.Host, change.Fingerprint, orDash(change.Subject), formatTime(change.NotAfter), formatTime(change.Since))
+	}
+	for i := 1; i <= 5; i++ {
+		table.SetAlign(tablewriter.AlignLeft, i)
+	}
+
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func pinsApproveEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	pin, err := newQueryServerClient().ApprovePin(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to approve certificate change of %s: %w", args[0], err)
+	}
+
+	fmt.Printf("Pinned certificate %s for %s\n", pin.Fingerprint, pin.Host)
+	return nil
+}
+
+func pinsRejectEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	err := newQueryServerClient().RejectPin(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to reject certificate change of %s: %w", args[0], err)
+	}
+
+	fmt.Printf("Rejected certificate change of %s\n", args[0])
+	return nil
+}
+
+func pinsRemoveEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	err := newQueryServerClient().RemovePin(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to remove pinned certificate of %s: %w", args[0], err)
+	}
+
+	fmt.Printf("Removed pinned certificate of %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	qconf "github.com/els0r/goProbe/cmd/goQuery/pkg/conf"
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query -i <interfaces> QUERY TYPE",
+	Short: "Run a query against the running goProbe instance",
+	Long: `Run a query against the running goProbe instance
+
+The query is executed by goProbe's API, i.e. it takes into account both the flows
+written to the local goDB as well as the flows currently held in memory (which have
+not been written out yet). Results are rendered in the same way as by goQuery.
+
+The arguments are the same as the ones used by goQuery (see "goQuery --help" for
+a detailed description of query types, columns and conditions). Since -s and -t are
+reserved for the goProbe API address and request timeout, sorting is only available
+via --sort.by.
+
+Unless set explicitly via -t|--timeout, queries are aborted after the default
+query timeout of goQuery.
+`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          queryEntrypoint,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var queryArgs = &query.Args{}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	flags := queryCmd.Flags()
+
+	flags.BoolVar(&queryArgs.In, "in", query.DefaultIn, "Take into account incoming data (received packets / bytes)\n")
+	flags.BoolVar(&queryArgs.Out, "out", query.DefaultOut, "Take into account outgoing data (sent packets / bytes)\n")
+	flags.BoolVar(&queryArgs.Sum, "sum", false, "Sum incoming and outgoing data\n")
+	flags.StringVar(&queryArgs.Counters, "counters", "", "Limit the aggregated counters (e.g. packets or bytes_in,bytes_out)\n")
+
+	flags.StringVarP(&queryArgs.Ifaces, "ifaces", "i", "", "Interfaces for which the query should be performed (e.g. eth0,eth1 or any)\n")
+	flags.StringVarP(&queryArgs.Condition, "condition", "c", "", "Logical conditions for the query (e.g. \"dport = 443 & dip is public\")\n")
+
+	flags.StringVar(&queryArgs.SortBy, qconf.SortBy, query.DefaultSortBy, "Sort results by given column name (bytes, packets, time)\n")
+	flags.BoolVarP(&queryArgs.SortAscending, qconf.SortAscending, "a", false, "Sort results in ascending instead of descending order\n")
+	flags.Uint64VarP(&queryArgs.NumResults, qconf.ResultsLimit, "n", query.DefaultNumResults, "Maximum number of final entries to show\n")
+
+	flags.BoolVar(&queryArgs.HumanReadable, qconf.ResultsHumanReadable, false, "Render byte and packet counters in human-readable units (txt / csv format only)\n")
+	flags.BoolVar(&queryArgs.DirectionPercentages, qconf.ResultsDirectionPercentages, false, "Include percentage-of-total columns for each direction (txt / csv format only)\n")
+	flags.BoolVar(&queryArgs.Numeric, qconf.Numeric, false, "Print IP protocols as numbers instead of their names\n")
+	flags.BoolVar(&queryArgs.FlowHash, qconf.FlowHash, false, "Add the canonical flow hash (of sip, dip, dport and proto) to each row\n")
+	flags.BoolVar(&queryArgs.CommunityID, qconf.CommunityID, false, "Add the Community ID to each row (only set for flows of protocols without ports, e.g. ICMP)\n")
+	flags.Uint16Var(&queryArgs.CommunityIDSeed, qconf.CommunityIDSeed, 0, "Seed of the Community ID\n")
+	flags.BoolVar(&queryArgs.Roles, qconf.Roles, false, "Correlate the traffic of each host as source and as destination (one row per host)\n")
+	flags.BoolVar(&queryArgs.Stats, qconf.Stats, false, "Summarize the data volume of each row per time bin by percentiles, maximum and standard deviation\n")
+	flags.StringVar(&queryArgs.Having, qconf.Having, "", "Predicate on the counters of the aggregated rows (e.g. \"ratio(bytes_in, bytes_out) > 100\")\n")
+	flags.StringVar(&queryArgs.Analysis, qconf.Analysis, "", "Evaluate the result according to an analysis mode (asn-matrix, one-way)\n")
+	flags.StringVar(&queryArgs.ASNDB, qconf.ASNDB, "", "Path to the database mapping IP prefixes to ASNs (for the asn-matrix analysis)\n")
+	flags.IntVar(&queryArgs.Sample, qconf.Sample, 0, "Return a random sample of N matching rows (and the extrapolated traffic) instead of the top ones\n")
+	flags.StringVar(&queryArgs.TimeZone, qconf.TimeZone, "", "Time zone timestamps are printed in (e.g. Europe/Zurich, UTC)\n")
+	flags.StringVar(&queryArgs.TimeFormat, qconf.TimeFormat, "", "Format timestamps are printed in (default, rfc3339, unix or a Go time layout)\n")
+
+	flags.BoolVarP(&queryArgs.DNSResolution.Enabled, qconf.DNSResolutionEnabled, "r", false, "Resolve top IPs in output using reverse DNS lookups\n")
+	flags.IntVar(&queryArgs.DNSResolution.MaxRows, qconf.DNSResolutionMaxRows, query.DefaultResolveRows, "Maximum number of output rows to perform DNS resolution against\n")
+	flags.DurationVar(&queryArgs.DNSResolution.Timeout, qconf.DNSResolutionTimeout, query.DefaultResolveTimeout, "Timeout for (reverse) DNS lookups\n")
+
+	flags.IntVar(&queryArgs.MaxMemPct, qconf.MemoryMaxPct, query.DefaultMaxMemPct, "Maximum amount of memory that can be used for the query (in % of available memory)\n")
+	flags.BoolVar(&queryArgs.LowMem, qconf.MemoryLowMode, false, "Enable low-memory mode\n")
+	flags.IntVar(&queryArgs.MaxAggMem, qconf.MemoryMaxAgg, 0, "Memory budget (in MiB) for the aggregation, spilling partial aggregates to disk if exceeded\n")
+	flags.BoolVar(&queryArgs.Mmap, qconf.QueryDBMmap, false, "Read the database via memory-mapped IO\n")
+	flags.IntVar(&queryArgs.IOWorkers, qconf.QueryDBIOWorkers, 0, "Number of workers reading / decompressing blocks in parallel (0: number of CPUs)\n")
+	flags.IntVar(&queryArgs.CPUWorkers, qconf.QueryDBCPUWorkers, 0, "Number of workers filtering / aggregating blocks in parallel (0: number of CPUs)\n")
+	flags.BoolVar(&queryArgs.Explain, qconf.Explain, false, "Show the execution plan of the query instead of running it\n")
+
+	flags.StringVarP(&queryArgs.Format, qconf.ResultsFormat, "e", query.DefaultFormat, "Output format (txt, json, csv, pcapng, influxdb)\n")
+	flags.StringVarP(&queryArgs.First, qconf.First, "f", "", "Show flows no earlier than --first\n")
+	flags.StringVarP(&queryArgs.Last, qconf.Last, "l", "", "Show flows no later than --last\n")
+
+	flags.String(qconf.StoredQuery, "", "Load JSON serialized query arguments from disk (or - for STDIN) and run them\n")
+}
+
+func queryEntrypoint(cmd *cobra.Command, args []string) error {
+	var stmtArgs = *queryArgs
+
+	// check if arguments should be loaded from disk. The command line parameters are taken as
+	// the base for this to allow modification of single parameters
+	argsLocation, _ := cmd.Flags().GetString(qconf.StoredQuery)
+	if argsLocation != "" {
+		var argsReader io.Reader = os.Stdin
+		if argsLocation != "-" {
+			f, err := os.Open(filepath.Clean(argsLocation))
+			if err != nil {
+				return fmt.Errorf("failed to open query args from %s: %w", argsLocation, err)
+			}
+			defer f.Close()
+			argsReader = f
+		}
+
+		if err := jsoniter.NewDecoder(argsReader).Decode(&stmtArgs); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON query args: %w", err)
+		}
+	} else {
+		if len(args) == 0 {
+			return errors.New("no query type provided")
+		}
+		stmtArgs.Query = args[0]
+	}
+
+	// make sure there's protection against unbounded time intervals
+	stmtArgs = query.SetDefaultTimeRange(&stmtArgs)
+	stmtArgs.Caller = os.Args[0]
+
+	stmt, err := stmtArgs.Prepare()
+	if err != nil {
+		return types.ShouldPretty(err, "failed to prepare query")
+	}
+
+	// queries usually take longer than the other API calls, hence the query timeout applies
+	// unless the request timeout was set explicitly
+	timeout := query.DefaultQueryTimeout
+	if rootCmd.PersistentFlags().Changed(conf.RequestTimeout) {
+		timeout = viper.GetDuration(conf.RequestTimeout)
+	}
+
+	sdCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(sdCtx, timeout)
+	defer cancel()
+
+	result, err := client.New(viper.GetString(conf.GoProbeServerAddr)).Query(ctx, &stmtArgs)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	// serialize raw results if json is selected (unless the result is subject to analysis)
+	if stmt.Format == "json" && stmt.Analysis == "" {
+		err = jsoniter.NewEncoder(stmt.Output).Encode(result)
+		if err != nil {
+			return fmt.Errorf("failed to serialize query results: %w", err)
+		}
+		return nil
+	}
+
+	if result.Status.Code != types.StatusOK {
+		fmt.Fprintf(stmt.Output, "Status %q: %s\n", result.Status.Code, result.Status.Message)
+		return nil
+	}
+
+	err = stmt.Print(ctx, result)
+	if err != nil {
+		return fmt.Errorf("failed to print query result: %w", err)
+	}
+	return nil
+}
@@ -51,6 +51,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gpctl.yaml)")
 
 	rootCmd.PersistentFlags().StringP(conf.GoProbeServerAddr, "s", "", "server address of goProbe API")
+	rootCmd.PersistentFlags().String(conf.GoProbeServerKey, "", "API key of goProbe API (required to backfill, vacuum or migrate goDB)")
 	rootCmd.PersistentFlags().DurationP(conf.RequestTimeout, "t", defaultRequestTimeout, "request timeout / deadline for goProbe API")
 
 	_ = viper.BindPFlags(rootCmd.PersistentFlags())
@@ -94,9 +95,20 @@ func verifyArgs(cmd *cobra.Command, _ []string) error {
 		return nil
 	}
 
-	serverAddr := viper.GetString(conf.GoProbeServerAddr)
+	// commands operating on local resources only don't talk to the API
+	if _, isLocal := cmd.Annotations[annotationLocal]; isLocal {
+		return nil
+	}
+
+	return verifyServerAddr(conf.GoProbeServerAddr)
+}
+
+// verifyServerAddr checks that the server address stored under key is either a unix socket
+// or of form <host>:<port>
+func verifyServerAddr(key string) error {
+	serverAddr := viper.GetString(key)
 	if serverAddr == "" {
-		return fmt.Errorf("%s: empty", conf.GoProbeServerAddr)
+		return fmt.Errorf("%s: empty", key)
 	}
 
 	unixSocketFile := api.ExtractUnixSocket(serverAddr)
@@ -106,7 +118,7 @@ func verifyArgs(cmd *cobra.Command, _ []string) error {
 
 	_, _, err := net.SplitHostPort(serverAddr)
 	if err != nil {
-		return fmt.Errorf("%s: %w", conf.GoProbeServerAddr, err)
+		return fmt.Errorf("%s: %w", key, err)
 	}
 	return nil
 }
@@ -118,6 +130,21 @@ func rootEntrypoint(_ *cobra.Command, _ []string) error {
 type entrypointE func(ctx context.Context, cmd *cobra.Command, args []string) error
 type runE func(cmd *cobra.Command, args []string) error
 
+// annotationLocal marks commands that operate on local resources only (instead of the goProbe API)
+const annotationLocal = "local"
+
+// wrapSignalContext provides a context that is cancelled upon interruption, but (unlike for API calls)
+// not subject to the request timeout
+func wrapSignalContext(f entrypointE) runE {
+
+	return func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		return f(ctx, cmd, args)
+	}
+}
+
 func wrapCancellationContext(f entrypointE) runE {
 
 	return func(cmd *cobra.Command, args []string) error {
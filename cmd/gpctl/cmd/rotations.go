@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	"github.com/els0r/goProbe/pkg/api/goprobe/client"
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+)
+
+const (
+	flagRotationsLast = "last"
+	flagRotationsJSON = "json"
+)
+
+// rotationsCmd represents the rotations command
+var rotationsCmd = &cobra.Command{
+	Use:   "rotations [IFACES]",
+	Short: "Show the most recent rotations retained in memory",
+	Long: `Show the most recent rotations retained in memory
+
+If the rotation history is enabled in the goProbe configuration (rotation_history),
+the most recent rotations of each interface (statistics, totals and flows) are kept
+in memory and can be fetched without reading the goDB.
+
+By default, a summary of each rotation is printed. Use --json to retrieve the flows
+of the rotations as well (unless goProbe only retains summaries).
+`,
+	RunE:          wrapCancellationContext(rotationsEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	rotationsLast int
+	rotationsJSON bool
+)
+
+func init() {
+	rootCmd.AddCommand(rotationsCmd)
+
+	rotationsCmd.Flags().IntVarP(&rotationsLast, flagRotationsLast, "n", 0, "number of rotations per interface (default: all retained rotations)")
+	rotationsCmd.Flags().BoolVar(&rotationsJSON, flagRotationsJSON, false, "print the rotations (including their flows) as JSON")
+}
+
+func rotationsEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := client.New(viper.GetString(conf.GoProbeServerAddr))
+
+	if rotationsLast < 0 {
+		return fmt.Errorf("invalid number of rotations: %d", rotationsLast)
+	}
+	rotations, err := client.Rotations(ctx, rotationsLast, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch rotations: %w", err)
+	}
+
+	if rotationsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rotations)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Rotations"))
+
+	table.AddRow("timestamp", "iface", "flows", "packets", "bytes", "dropped")
+	table.AddSeparator()
+
+	for _, rotation := range rotations {
+		table.AddRow(
+			rotation.Timestamp.Local().Format(time.DateTime),
+			rotation.Iface,
+			formatting.Countable(rotation.NumFlows),
+			formatting.Count(rotation.Totals.SumPackets()),
+			formatting.Size(rotation.Totals.SumBytes()),
+			formatting.Countable(rotation.Stats.Dropped),
+		)
+	}
+
+	// set alignment before rendering
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignLeft, 2)
+	for i := 3; i <= 6; i++ {
+		table.SetAlign(tablewriter.AlignRight, i)
+	}
+
+	fmt.Println(table.Render())
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
+	apiclient "github.com/els0r/goProbe/pkg/api/client"
+	"github.com/els0r/goProbe/pkg/api/globalquery/client"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/shellformat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xlab/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// schedulesCmd represents the schedules command
+var schedulesCmd = &cobra.Command{
+	Use:   "schedules [NAME]",
+	Short: "Manage scheduled queries of the query server",
+	Long: `Manage scheduled queries of the query server
+
+If the name of a scheduled query is provided as an argument, its configuration and
+run history are shown. Otherwise, all scheduled queries are listed.
+
+The query server (global-query) address is configured via --query.server.addr.
+`,
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRunE: verifyQueryServerArgs,
+	RunE:              wrapCancellationContext(schedulesEntrypoint),
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+var schedulesAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Register a scheduled query",
+	Long: `Register a scheduled query
+
+The job definition is read from the YAML / JSON file provided via -f|--file, e.g.
+
+  name: daily-top-talkers
+  spec: "0 6 * * *"
+  query:
+    query: sip,dip
+    ifaces: any
+    first: -24h
+    format: csv
+  sinks:
+    - webhook:
+        url: https://dashboard.example.com/hooks/goprobe
+
+Jobs registered this way may only deliver to webhook / InfluxDB endpoints on the
+hosts allowed by the query server (scheduler.allowed_hosts).
+`,
+	Args:          cobra.NoArgs,
+	RunE:          wrapCancellationContext(schedulesAddEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var schedulesRemoveCmd = &cobra.Command{
+	Use:           "rm NAME",
+	Short:         "Remove a scheduled query",
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(schedulesRemoveEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var schedulesRunCmd = &cobra.Command{
+	Use:   "run NAME",
+	Short: "Trigger an immediate run of a scheduled query",
+	Long: `Trigger an immediate run of a scheduled query
+
+The run is performed asynchronously by the query server. Its outcome can be
+inspected via "gpctl schedules NAME".
+`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          wrapCancellationContext(schedulesRunEntrypoint),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var jobFile string
+
+func init() {
+	rootCmd.AddCommand(schedulesCmd)
+	schedulesCmd.AddCommand(schedulesAddCmd, schedulesRemoveCmd, schedulesRunCmd)
+
+	schedulesCmd.PersistentFlags().String(conf.QueryServerAddr, "", "server address of the query server (global-query) API")
+	_ = viper.BindPFlags(schedulesCmd.PersistentFlags())
+
+	schedulesAddCmd.Flags().StringVarP(&jobFile, flagFile, "f", "", "file containing the job definition")
+	_ = schedulesAddCmd.MarkFlagRequired(flagFile)
+}
+
+func verifyQueryServerArgs(_ *cobra.Command, _ []string) error {
+	return verifyServerAddr(conf.QueryServerAddr)
+}
+
+func newQueryServerClient() *client.Client {
+	return client.New(viper.GetString(conf.QueryServerAddr), apiclient.WithAPIKey(viper.GetString(conf.QueryServerKey)))
+}
+
+func schedulesEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	client := newQueryServerClient()
+
+	if len(args) == 1 {
+		job, err := client.GetSchedule(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to fetch scheduled query %s: %w", args[0], err)
+		}
+		printSchedule(job)
+		return nil
+	}
+
+	jobs, err := client.ListSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch scheduled queries: %w", err)
+	}
+
+	fmt.Println()
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Scheduled Queries"))
+
+	table.AddRow("name", "spec", "query", "sinks", "next run", "last run", "status", "failures")
+	table.AddSeparator()
+
+	for _, job := range jobs {
+		lastRun, status := "-", "-"
+		if job.LastRun != nil {
+			lastRun = formatTime(job.LastRun.Started)
+			status = formatRunStatus(job.LastRun.Status)
+		}
+		table.AddRow(job.Name, job.Spec, job.Args.Query, formatSinks(job.Sinks),
+			formatTime(job.NextRun), lastRun, status, job.ConsecutiveFailures,
+		)
+	}
+
+	// set alignment before rendering
+	for i := 1; i <= 7; i++ {
+		table.SetAlign(tablewriter.AlignLeft, i)
+	}
+	table.SetAlign(tablewriter.AlignRight, 8)
+
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func schedulesAddEntrypoint(ctx context.Context, _ *cobra.Command, _ []string) error {
+	data, err := os.ReadFile(filepath.Clean(jobFile))
+	if err != nil {
+		return fmt.Errorf("failed to read job definition: %w", err)
+	}
+
+	var job schedule.Job
+	err = yaml.Unmarshal(data, &job)
+	if err != nil {
+		return fmt.Errorf("failed to parse job definition %s: %w", jobFile, err)
+	}
+
+	status, err := newQueryServerClient().RegisterSchedule(ctx, &job)
+	if err != nil {
+		return fmt.Errorf("failed to register scheduled query %s: %w", job.Name, err)
+	}
+
+	fmt.Printf("Registered scheduled query %s (next run: %s)\n", status.Name, formatTime(status.NextRun))
+	return nil
+}
+
+func schedulesRemoveEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	err := newQueryServerClient().RemoveSchedule(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to remove scheduled query %s: %w", args[0], err)
+	}
+
+	fmt.Printf("Removed scheduled query %s\n", args[0])
+	return nil
+}
+
+func schedulesRunEntrypoint(ctx context.Context, _ *cobra.Command, args []string) error {
+	err := newQueryServerClient().TriggerSchedule(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to trigger scheduled query %s: %w", args[0], err)
+	}
+
+	fmt.Printf("Triggered run of scheduled query %s\n", args[0])
+	return nil
+}
+
+func printSchedule(job *schedule.JobStatus) {
+	fmt.Printf(`
+         Name: %s
+         Spec: %s
+        Query: %s (ifaces: %s, first: %s, last: %s, format: %s)
+        Sinks: %s
+     Next run: %s
+     Failures: %d consecutive
+
+`, job.Name, job.Spec,
+		job.Args.Query, job.Args.Ifaces, orDash(job.Args.First), orDash(job.Args.Last), job.Args.Format,
+		formatSinks(job.Sinks),
+		formatTime(job.NextRun),
+		job.ConsecutiveFailures,
+	)
+
+	if len(job.History) == 0 {
+		fmt.Println("No runs recorded yet")
+		fmt.Println()
+		return
+	}
+
+	table := tablewriter.CreateTable()
+	table.UTF8Box()
+	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Run History"))
+
+	table.AddRow("started", "duration", "status", "hits", "error")
+	table.AddSeparator()
+
+	for _, run := range job.History {
+		table.AddRow(formatTime(run.Started), run.Duration.Round(time.Millisecond).String(),
+			formatRunStatus(run.Status), run.Hits, orDash(run.Error),
+		)
+	}
+
+	table.SetAlign(tablewriter.AlignLeft, 1)
+	table.SetAlign(tablewriter.AlignRight, 2)
+	table.SetAlign(tablewriter.AlignLeft, 3)
+	table.SetAlign(tablewriter.AlignRight, 4)
+	table.SetAlign(tablewriter.AlignLeft, 5)
+
+	fmt.Println(table.Render())
+}
+
+func formatSinks(sinks []schedule.SinkConfig) string {
+	descriptions := make([]string, 0, len(sinks))
+	for _, sink := range sinks {
+		descriptions = append(descriptions, sink.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func formatRunStatus(status schedule.RunStatus) string {
+	if status == schedule.RunFailed {
+		return shellformat.Fmt(shellformat.Bold|shellformat.Red, "%s", status)
+	}
+	return string(status)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(types.DefaultTimeOutputFormat)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -7,7 +7,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/els0r/goProbe/cmd/gpctl/pkg/conf"
@@ -22,9 +26,25 @@ import (
 )
 
 const (
-	flagDetailed = "detailed"
+	flagDetailed         = "detailed"
+	flagStatusSortBy     = "sort-by"
+	flagStatusOnlyErrors = "only-errors"
+	flagStatusMinDropped = "min-dropped"
+	flagStatusWatch      = "watch"
 )
 
+const (
+	statusSortIface    = "iface"
+	statusSortDrops    = "drops"
+	statusSortReceived = "received"
+)
+
+// defaultStatusWatchInterval denotes the refresh interval if --watch is provided without an interval
+const defaultStatusWatchInterval = 2 * time.Second
+
+// clearScreen moves the cursor to the top left corner and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
 // statusCmd represents the stats command
 var statusCmd = &cobra.Command{
 	Use:   "status [IFACES]",
@@ -33,18 +53,141 @@ var statusCmd = &cobra.Command{
 
 If the (list of) interface(s) is provided as an argument, it will only
 show the statistics for them. Otherwise, all interfaces are printed
+
+On hosts with many interfaces, the problematic ones can be singled out by
+filtering / sorting the interfaces, e.g. showing the interfaces that dropped
+packets since the last writeout, the ones with the most drops first:
+
+  gpctl status --only-errors --sort-by drops
+
+Using --watch, the status is refreshed continuously (every 2s by default,
+or in the provided interval, e.g. --watch=10s) until interrupted. The totals
+always cover all (requested) interfaces, regardless of any filter.
+
+Interfaces that are configured, but not capturing (since their capture failed
+to initialize or was torn down due to a critical error) are listed separately,
+along with the reason of the error and a hint on how to remedy it.
 `,
 
-	RunE:          wrapCancellationContext(statusEntrypoint),
+	Args:          verifyStatusArgs,
+	RunE:          statusRunE,
 	SilenceErrors: true, // Errors are emitted after command completion, avoid duplicate
 }
 
-var detailed bool
+var (
+	detailed         bool
+	statusSortBy     string
+	statusOnlyErrors bool
+	statusMinDropped uint64
+	statusWatch      time.Duration
+)
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
 
-	statusCmd.Flags().BoolVarP(&detailed, flagDetailed, "v", false, "print extended interface statistics (packet parsing errors)")
+	flags := statusCmd.Flags()
+	flags.BoolVarP(&detailed, flagDetailed, "v", false, "print extended interface statistics (packet parsing errors)")
+	flags.StringVar(&statusSortBy, flagStatusSortBy, statusSortIface, fmt.Sprintf("sort interfaces by %s (descending)", strings.Join([]string{statusSortIface, statusSortDrops, statusSortReceived}, " | ")))
+	flags.BoolVar(&statusOnlyErrors, flagStatusOnlyErrors, false, "only show interfaces that dropped packets or encountered parsing errors since the last writeout")
+	flags.Uint64Var(&statusMinDropped, flagStatusMinDropped, 0, "only show interfaces that dropped at least N packets since the last writeout")
+	flags.DurationVarP(&statusWatch, flagStatusWatch, "w", 0, "refresh the status continuously in the provided interval until interrupted")
+	flags.Lookup(flagStatusWatch).NoOptDefVal = defaultStatusWatchInterval.String()
+}
+
+func verifyStatusArgs(_ *cobra.Command, _ []string) error {
+	switch statusSortBy {
+	case statusSortIface, statusSortDrops, statusSortReceived:
+	default:
+		return fmt.Errorf("invalid sort order %q (must be one of %s, %s, %s)", statusSortBy, statusSortIface, statusSortDrops, statusSortReceived)
+	}
+	if statusWatch < 0 {
+		return fmt.Errorf("invalid watch interval %s", statusWatch)
+	}
+	return nil
+}
+
+// statusRunE prints the status once or, in watch mode, refreshes it in the watch interval (applying the
+// request timeout to each refresh) until interrupted
+func statusRunE(cmd *cobra.Command, args []string) error {
+	if statusWatch == 0 {
+		return wrapCancellationContext(statusEntrypoint)(cmd, args)
+	}
+
+	sdCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(statusWatch)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(sdCtx, viper.GetDuration(conf.RequestTimeout))
+		fmt.Print(clearScreen)
+		fmt.Printf("Every %s: gpctl status (%s)\n", statusWatch, time.Now().Format(types.DefaultTimeOutputFormat))
+		err := statusEntrypoint(ctx, cmd, args)
+		cancel()
+		if err != nil {
+			if sdCtx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-sdCtx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ifaceStatus denotes the status of a single interface
+type ifaceStatus struct {
+	iface  string
+	status capturetypes.CaptureStats
+}
+
+// hasErrors determines if the interface dropped packets or encountered parsing errors since the last writeout
+func (s *ifaceStatus) hasErrors() bool {
+	return s.status.Dropped > 0 || s.status.ParsingErrors.Sum() > 0
+}
+
+// filterStatuses returns the statuses of all interfaces matching the filter flags
+func filterStatuses(statuses []ifaceStatus, onlyErrors bool, minDropped uint64) []ifaceStatus {
+	filtered := make([]ifaceStatus, 0, len(statuses))
+	for _, st := range statuses {
+		if onlyErrors && !st.hasErrors() {
+			continue
+		}
+		if st.status.Dropped < minDropped {
+			continue
+		}
+		filtered = append(filtered, st)
+	}
+	return filtered
+}
+
+// sortStatuses sorts the statuses of the interfaces in the provided order (the interface name breaking any ties)
+func sortStatuses(statuses []ifaceStatus, sortBy string) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := statuses[i].status, statuses[j].status
+		switch sortBy {
+		case statusSortDrops:
+			if a.Dropped != b.Dropped {
+				return a.Dropped > b.Dropped
+			}
+			if a.DroppedTotal != b.DroppedTotal {
+				return a.DroppedTotal > b.DroppedTotal
+			}
+		case statusSortReceived:
+			if a.Received != b.Received {
+				return a.Received > b.Received
+			}
+			if a.ReceivedTotal != b.ReceivedTotal {
+				return a.ReceivedTotal > b.ReceivedTotal
+			}
+		}
+		return statuses[i].iface < statuses[j].iface
+	})
 }
 
 func statusEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) error {
@@ -52,7 +195,7 @@ func statusEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 
 	ifaces := args
 
-	statuses, lastWriteout, startedAt, err := client.GetInterfaceStatus(ctx, ifaces...)
+	status, err := client.Status(ctx, ifaces...)
 	if err != nil {
 
 		// If the error is caused by context timeout / cancellation, skip the usage notification
@@ -64,39 +207,46 @@ func statusEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 	}
 
 	var (
-		runtimeTotalReceived, runtimeTotalProcessed, runtimeTotalDropped int64
-		totalReceived, totalProcessed, totalDropped                      int64
+		runtimeTotalReceived, runtimeTotalProcessed, runtimeTotalDropped, runtimeTotalFiltered int64
+		totalReceived, totalProcessed, totalDropped, totalFiltered                             int64
 	)
 
 	fmt.Println()
 
-	var allStatuses []struct {
-		iface  string
-		status capturetypes.CaptureStats
-	}
-	for iface, status := range statuses {
-		allStatuses = append(allStatuses, struct {
-			iface  string
-			status capturetypes.CaptureStats
-		}{
+	allStatuses := make([]ifaceStatus, 0, len(status.Statuses))
+	for iface, ifaceStats := range status.Statuses {
+		allStatuses = append(allStatuses, ifaceStatus{
 			iface:  iface,
-			status: status,
+			status: ifaceStats,
 		})
 	}
 
-	sort.SliceStable(allStatuses, func(i, j int) bool {
-		return allStatuses[i].iface < allStatuses[j].iface
-	})
+	// The totals cover all interfaces, regardless of any filter
+	for _, st := range allStatuses {
+		runtimeTotalReceived += int64(st.status.ReceivedTotal)
+		runtimeTotalProcessed += int64(st.status.ProcessedTotal)
+		runtimeTotalDropped += int64(st.status.DroppedTotal)
+		runtimeTotalFiltered += int64(st.status.FilteredTotal)
+
+		totalProcessed += int64(st.status.Processed)
+		totalReceived += int64(st.status.Received)
+		totalDropped += int64(st.status.Dropped)
+		totalFiltered += int64(st.status.Filtered)
+	}
+
+	shownStatuses := filterStatuses(allStatuses, statusOnlyErrors, statusMinDropped)
+	sortStatuses(shownStatuses, statusSortBy)
 
 	table := tablewriter.CreateTable()
 	table.UTF8Box()
 	table.AddTitle(shellformat.Fmt(shellformat.Bold, "Interface Statuses"))
 
-	headerRow1 := []interface{}{"", "total", "", "total", "", "total", "", "active"}
+	headerRow1 := []interface{}{"", "total", "", "total", "", "total", "", "total", "", "active"}
 	headerRow2 := []interface{}{"iface",
 		"received", "+ received",
 		"processed", "+ processed",
-		"dropped", "+ dropped", "for"}
+		"dropped", "+ dropped",
+		"cpu time", "+ cpu time", "for"}
 	if detailed {
 		for _, parsingErrnoName := range capturetypes.ParsingErrnoNames {
 			headerRow2 = append(headerRow2, parsingErrnoName)
@@ -111,27 +261,31 @@ func statusEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 	table.AddRow(headerRow2...)
 	table.AddSeparator()
 
-	for _, st := range allStatuses {
+	for _, st := range shownStatuses {
 		ifaceStatus := st.status
 
-		runtimeTotalReceived += int64(ifaceStatus.ReceivedTotal)
-		runtimeTotalProcessed += int64(ifaceStatus.ProcessedTotal)
-		runtimeTotalDropped += int64(ifaceStatus.DroppedTotal)
-
-		totalProcessed += int64(ifaceStatus.Processed)
-		totalReceived += int64(ifaceStatus.Received)
-		totalDropped += int64(ifaceStatus.Dropped)
-
 		dropped := fmt.Sprint(formatting.Countable(ifaceStatus.Dropped))
 		if ifaceStatus.Dropped > 0 {
 			dropped = shellformat.Fmt(shellformat.Bold|shellformat.Red, "%d", ifaceStatus.Dropped)
 		}
 
+		// interfaces released due to inactivity are not actively capturing
+		active := time.Since(ifaceStatus.StartedAt).Round(time.Second).String()
+		if ifaceStatus.Standby {
+			active = shellformat.Fmt(shellformat.Bold, "standby")
+		}
+
+		// flag interfaces whose capture was reinitialized after its packet processing stalled
+		if ifaceStatus.AutoRecovered > 0 {
+			active += shellformat.Fmt(shellformat.Bold|shellformat.Red, " (%d auto-recovered)", ifaceStatus.AutoRecovered)
+		}
+
 		ifaceRow := []interface{}{st.iface,
 			formatting.Countable(ifaceStatus.ReceivedTotal), formatting.Countable(ifaceStatus.Received),
 			formatting.Countable(ifaceStatus.ProcessedTotal), formatting.Countable(ifaceStatus.Processed),
 			formatting.Countable(ifaceStatus.DroppedTotal), dropped,
-			time.Since(ifaceStatus.StartedAt).Round(time.Second).String()}
+			ifaceStatus.CPUTimeTotal.Round(time.Millisecond).String(), ifaceStatus.CPUTime.Round(time.Millisecond).String(),
+			active}
 		if detailed {
 			for _, parsingErrno := range ifaceStatus.ParsingErrors {
 				ifaceRow = append(ifaceRow, tablewriter.CreateCell(formatting.Countable(parsingErrno), &tablewriter.CellStyle{Alignment: tablewriter.AlignRight}))
@@ -143,12 +297,20 @@ func statusEntrypoint(ctx context.Context, cmd *cobra.Command, args []string) er
 
 	// set alignment before rendering
 	table.SetAlign(tablewriter.AlignLeft, 1)
-	for i := 2; i <= 8; i++ {
+	for i := 2; i <= 10; i++ {
 		table.SetAlign(tablewriter.AlignRight, i)
 	}
 
 	fmt.Println(table.Render())
 
+	if len(shownStatuses) < len(allStatuses) {
+		fmt.Printf("Showing %d of %d interfaces (filtered)\n\n", len(shownStatuses), len(allStatuses))
+	}
+
+	printIfaceErrors(status.Errors)
+
+	lastWriteout, startedAt := status.LastWriteout, status.StartedAt
+
 	lastWriteoutStr := "-"
 	ago := "-"
 	if !lastWriteout.IsZero() {
@@ -169,6 +331,7 @@ Totals:
        Received: %s / + %s
       Processed: %s / + %s
         Dropped: %s / + %s
+       Filtered: %s / + %s
 
 `,
 		startedAt.Local().Format(types.DefaultTimeOutputFormat), time.Since(startedAt).Round(time.Second).String(),
@@ -176,7 +339,44 @@ Totals:
 		formatting.Countable(runtimeTotalReceived), formatting.Countable(totalReceived),
 		formatting.Countable(runtimeTotalProcessed), formatting.Countable(totalProcessed),
 		formatting.Countable(runtimeTotalDropped), formatting.Countable(totalDropped),
+		formatting.Countable(runtimeTotalFiltered), formatting.Countable(totalFiltered),
 	)
 
 	return nil
 }
+
+// printIfaceErrors lists all interfaces in an error state, along with a hint on how to remedy the error
+func printIfaceErrors(ifaceErrors map[string]capturetypes.IfaceError) {
+	if len(ifaceErrors) == 0 {
+		return
+	}
+
+	ifaces := make([]string, 0, len(ifaceErrors))
+	for iface := range ifaceErrors {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	fmt.Printf("%s\n\n", shellformat.Fmt(shellformat.Bold, "Interface Errors"))
+	for _, iface := range ifaces {
+		ifaceErr := ifaceErrors[iface]
+
+		// fall back to the hint known to the client in case the server didn't provide one
+		hint := ifaceErr.Hint
+		if hint == "" {
+			hint = ifaceErr.Reason.Hint()
+		}
+
+		fmt.Printf("  %s: %s (%s, %dx since %s, last %s ago)\n",
+			shellformat.Fmt(shellformat.Bold|shellformat.Red, "%s", iface),
+			ifaceErr.Reason, ifaceErr.Class, ifaceErr.Count,
+			ifaceErr.Since.Local().Format(types.DefaultTimeOutputFormat),
+			time.Since(ifaceErr.Timestamp).Round(time.Second).String(),
+		)
+		fmt.Printf("    error: %s\n", ifaceErr.Message)
+		if hint != "" {
+			fmt.Printf("     hint: %s\n", hint)
+		}
+		fmt.Println()
+	}
+}
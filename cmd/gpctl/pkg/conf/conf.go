@@ -4,5 +4,9 @@ const (
 	serverKey = "server"
 
 	GoProbeServerAddr = serverKey + ".addr" // GoProbeServerAddr : The server endpoint / address of form <host>:<port>
+	GoProbeServerKey  = serverKey + ".key"  // GoProbeServerKey : The API key presented to the server (for routes requiring authentication)
 	RequestTimeout    = "timeout"           // RequestTimeout : The request timeout
+
+	QueryServerAddr = "query." + serverKey + ".addr" // QueryServerAddr : The global-query server endpoint / address of form <host>:<port>
+	QueryServerKey  = "query." + serverKey + ".key"  // QueryServerKey : The API key presented to the global-query server
 )
@@ -9,6 +9,11 @@ const (
 	InfoRoute = infoPrefix + "/info"
 	// ReadyRoute denotes the route / URI path to the ready endpoint
 	ReadyRoute = infoPrefix + "/ready"
+	// RuntimeInfoRoute denotes the route / URI path to the runtime / build diagnostics endpoint
+	RuntimeInfoRoute = InfoRoute + "/runtime"
+	// APICapabilitiesRoute denotes the route / URI path to the endpoint describing the supported versions
+	// of the query API and the enabled features
+	APICapabilitiesRoute = infoPrefix + "/capabilities"
 )
 
 const (
@@ -18,3 +23,15 @@ const (
 	// ValidationRoute is the route to validate a goquery query
 	ValidationRoute = QueryRoute + "/validate"
 )
+
+// AuditRoute is the route to query the audit log of executed queries
+const AuditRoute = "/_audit"
+
+// MetricsRoute is the route serving the prometheus metrics (if enabled), supporting the OpenMetrics format
+const MetricsRoute = "/metrics"
+
+// TextMetricsRoute is the route serving the prometheus metrics (if enabled) in the plain text format only
+const TextMetricsRoute = infoPrefix + "/metrics"
+
+// UIRoute is the route serving the embedded web UI (if enabled)
+const UIRoute = "/ui"
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditIdentityMiddleware attaches the identity of the caller (the tenant provided via the
+// tenantHeader and the client's address) to the request context, so it can be recorded in
+// the audit log
+func AuditIdentityMiddleware(tenantHeader string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithIdentity(c.Request.Context(), audit.Identity{
+			Tenant:     c.Request.Header.Get(tenantHeader),
+			RemoteAddr: c.ClientIP(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// AuditHandler returns the handler listing the entries of the audit log matching the filter
+// provided via URL query parameters
+func AuditHandler(log *audit.Log) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter audit.Filter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			LogAndAbort(c.Request.Context(), c, http.StatusBadRequest, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, &AuditResponse{
+			StatusCode: http.StatusOK,
+			Entries:    log.Entries(filter),
+		})
+	}
+}
+
+// AuditResponse stores the response to an audit log query
+type AuditResponse struct {
+	StatusCode int           `json:"status_code"` // StatusCode: stores the HTTP status code of the response. Example: 200
+	Entries    []audit.Entry `json:"entries"`     // Entries: the matching entries of the audit log, most recent first
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/fako1024/httpc"
+)
+
+// Audit retrieves the entries of the query audit log of the service serving the API matching filter
+// (most recent first)
+func (c *DefaultClient) Audit(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var res = new(api.AuditResponse)
+
+	params := httpc.Params{}
+	if filter.Tenant != "" {
+		params["tenant"] = filter.Tenant
+	}
+	if filter.Caller != "" {
+		params["caller"] = filter.Caller
+	}
+	if !filter.Since.IsZero() {
+		params["since"] = filter.Since.Format(time.RFC3339)
+	}
+	if !filter.Until.IsZero() {
+		params["until"] = filter.Until.Format(time.RFC3339)
+	}
+	if filter.Limit > 0 {
+		params["limit"] = strconv.Itoa(filter.Limit)
+	}
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", c.NewURL(api.AuditRoute), c.Client()).
+			QueryParams(params).
+			ParseJSON(res),
+	)
+	if err := req.RunWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return res.Entries, nil
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net"
@@ -33,7 +34,10 @@ type DefaultClient struct {
 
 	name string
 
+	tlsConfig *tls.Config
+
 	requestLogging bool
+	binaryResults  bool
 }
 
 // Option configures the client
@@ -46,6 +50,15 @@ func WithRequestLogging(b bool) Option {
 	}
 }
 
+// WithBinaryResults requests query results in their compact binary representation instead of JSON
+// (c.f. package resultpb), considerably reducing the serialization cost and transfer size of large
+// results. Servers not supporting it continue to respond with JSON
+func WithBinaryResults(b bool) Option {
+	return func(c *DefaultClient) {
+		c.binaryResults = b
+	}
+}
+
 // WithRequestTimeout sets the timeout for every request
 func WithRequestTimeout(timeout time.Duration) Option {
 	return func(c *DefaultClient) {
@@ -82,6 +95,14 @@ func WithName(name string) Option {
 	}
 }
 
+// WithTLSConfig sets the TLS configuration used for connections to the API server (e.g. to verify
+// its certificate against a pinned fingerprint, see package pinning)
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *DefaultClient) {
+		c.tlsConfig = cfg
+	}
+}
+
 const (
 	defaultRequestTimeout = 30 * time.Second
 	defaultClientName     = "default-client"
@@ -108,6 +129,11 @@ func NewDefault(addr string, opts ...Option) *DefaultClient {
 	}
 
 	t := http.DefaultTransport
+	if c.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.tlsConfig
+		t = transport
+	}
 
 	// change transport to dial to the unix socket instead
 	unixSocketFile := api.ExtractUnixSocket(addr)
@@ -204,12 +230,13 @@ func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
 	return resp, err
 }
 
-// Modify activates retry behavior, timeout handling and authorization via the stored key
+// Modify activates retry behavior, timeout handling and authorization via the stored key. Responses
+// with an unexpected status code are reported as *Error
 func (c *DefaultClient) Modify(_ context.Context, req *httpc.Request) *httpc.Request {
 
 	req = req.Headers(httpc.Params{
 		server.RuntimeIDHeaderKey: info.RuntimeID(),
-	})
+	}).ErrorFn(parseError)
 
 	// retry any request that isn't 2xx
 	if c.retry {
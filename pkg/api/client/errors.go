@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxErrorBodySize denotes the maximum number of bytes of a response body considered for an error message
+const maxErrorBodySize = 1024
+
+// Error denotes an error response of an API server, providing access to its HTTP status code (e.g. to
+// distinguish a missing interface from a server failure via errors.As())
+type Error struct {
+	StatusCode int    // StatusCode: the HTTP status code of the response. Example: 404
+	Message    string // Message: the error reported by the server (if any). Example: "interface not found"
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// StatusCode returns the HTTP status code of an error response (or zero if err does not stem from one)
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound returns if err stems from a response indicating that the requested resource (e.g. an
+// interface) does not exist
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// parseError extracts the error from a response with an unexpected status code. The error messages of
+// all APIs are reported in the "error" field of a JSON body, other bodies are used verbatim
+func parseError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return apiErr
+	}
+	body = bytes.TrimSpace(body)
+
+	var res struct {
+		Error string `json:"error"`
+	}
+	if jsoniter.Unmarshal(body, &res) == nil && res.Error != "" {
+		apiErr.Message = res.Error
+	} else if len(body) > 0 && body[0] != '{' {
+		apiErr.Message = string(body)
+	}
+	return apiErr
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/fako1024/httpc"
+)
+
+// RuntimeInfo retrieves the build and runtime environment of the service serving the API
+func (c *DefaultClient) RuntimeInfo(ctx context.Context) (*api.RuntimeInfo, error) {
+	var res = new(api.RuntimeInfo)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", c.NewURL(api.RuntimeInfoRoute), c.Client()).
+			ParseJSON(res),
+	)
+	if err := req.RunWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
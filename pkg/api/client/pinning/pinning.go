@@ -0,0 +1,306 @@
+// Package pinning provides trust-on-first-use (TOFU) pinning of the TLS certificates presented by
+// API servers. Instead of verifying a certificate against a CA, the fingerprint of the certificate
+// presented by a server upon first contact is pinned and each subsequent connection is only accepted
+// if the server presents the same certificate. A different certificate (e.g. due to a renewal or a
+// man-in-the-middle) is recorded as pending change, rejecting all connections to the server until
+// the change has been reviewed and approved.
+//
+// This protects the connections to a fleet of servers using self-signed certificates without
+// requiring an internal CA, provided that the first contact happens over a trusted network (or the
+// pins are reviewed after it).
+package pinning
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	storePermissions = 0600
+	storeTempSuffix  = ".tmp"
+)
+
+var (
+	// ErrFingerprintMismatch denotes that a server presented a certificate differing from the pinned one
+	ErrFingerprintMismatch = errors.New("certificate does not match the pinned fingerprint")
+
+	// ErrNoCertificate denotes that a server did not present any certificate
+	ErrNoCertificate = errors.New("no certificate presented")
+
+	// ErrPinNotFound denotes that no fingerprint is pinned for a host
+	ErrPinNotFound = errors.New("no fingerprint pinned for host")
+
+	// ErrNoPendingChange denotes that there is no pending change of the fingerprint of a host
+	ErrNoPendingChange = errors.New("no pending fingerprint change for host")
+)
+
+// Pin describes the certificate pinned for a host
+type Pin struct {
+	Host        string    `json:"host"`              // Host: the address of the server. Example: "probe-1.example.com:8145"
+	Fingerprint string    `json:"fingerprint"`       // Fingerprint: the SHA-256 fingerprint of the certificate (hex encoded). Example: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	Subject     string    `json:"subject,omitempty"` // Subject: the subject of the certificate. Example: "CN=probe-1.example.com"
+	NotAfter    time.Time `json:"not_after"`         // NotAfter: the end of the validity period of the certificate
+	Since       time.Time `json:"since"`             // Since: the time the certificate was first presented by the host
+}
+
+// Change describes a certificate presented by a host which differs from the pinned one. It replaces
+// the pinned certificate once approved
+type Change struct {
+	Pin
+	Pinned string `json:"pinned"` // Pinned: the fingerprint currently pinned for the host. Example: "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+}
+
+// Fingerprint computes the (hex encoded) SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func newPin(host string, cert *x509.Certificate, now time.Time) Pin {
+	return Pin{
+		Host:        host,
+		Fingerprint: Fingerprint(cert),
+		Subject:     cert.Subject.String(),
+		NotAfter:    cert.NotAfter.UTC(),
+		Since:       now.UTC(),
+	}
+}
+
+// Store manages the pinned fingerprints of all hosts and the pending changes thereof. If a path is
+// provided, the store is persisted to it on each modification
+type Store struct {
+	path string
+
+	pins    map[string]Pin
+	pending map[string]Change
+
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// storeState is the persisted representation of a Store
+type storeState struct {
+	Pins    []Pin    `json:"pins"`
+	Pending []Change `json:"pending,omitempty"`
+}
+
+// New creates a new pin store, loading the pins from path (if it exists). If path is empty, the
+// pins are only kept in memory (and hence pinned anew on restart)
+func New(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		pins:    make(map[string]Pin),
+		pending: make(map[string]Change),
+		now:     time.Now,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read pin store: %w", err)
+	}
+	var state storeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse pin store %s: %w", path, err)
+	}
+	for _, pin := range state.Pins {
+		s.pins[pin.Host] = pin
+	}
+	for _, change := range state.Pending {
+		s.pending[change.Host] = change
+	}
+	return s, nil
+}
+
+// Verify checks the certificate presented by host during the TLS handshake against the pinned one,
+// pinning it if the host is contacted for the first time. A differing certificate is recorded as
+// pending change and rejected with ErrFingerprintMismatch
+func (s *Store) Verify(host string, state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%s: %w", host, ErrNoCertificate)
+	}
+	cert := state.PeerCertificates[0]
+	fingerprint := Fingerprint(cert)
+
+	s.Lock()
+	defer s.Unlock()
+
+	pin, exists := s.pins[host]
+	if !exists {
+		s.pins[host] = newPin(host, cert, s.now())
+		if err := s.save(); err != nil {
+			return fmt.Errorf("failed to pin certificate of %s: %w", host, err)
+		}
+		return nil
+	}
+	if pin.Fingerprint == fingerprint {
+		return nil
+	}
+
+	// the time the change was first seen is retained if the same certificate is presented repeatedly
+	if change, exists := s.pending[host]; !exists || change.Fingerprint != fingerprint {
+		s.pending[host] = Change{Pin: newPin(host, cert, s.now()), Pinned: pin.Fingerprint}
+		if err := s.save(); err != nil {
+			return fmt.Errorf("failed to record certificate change of %s: %w", host, err)
+		}
+	}
+	return fmt.Errorf("%s: %w (pinned %s, presented %s): approve the change if it is expected", host, ErrFingerprintMismatch, pin.Fingerprint, fingerprint)
+}
+
+// ClientTLSConfig returns a TLS configuration for connections to host, verifying the certificate
+// presented by the host against the pinned one (see Verify()) instead of a CA
+func (s *Store) ClientTLSConfig(host string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+
+		// the certificate chain is deliberately not verified against a CA, the certificate
+		// itself is verified against the pinned fingerprint instead
+		InsecureSkipVerify: true, // #nosec G402
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return s.Verify(host, state)
+		},
+	}
+}
+
+// Pins returns the pinned certificates of all hosts, ordered by host
+func (s *Store) Pins() []Pin {
+	s.Lock()
+	defer s.Unlock()
+
+	pins := make([]Pin, 0, len(s.pins))
+	for _, pin := range s.pins {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Host < pins[j].Host
+	})
+	return pins
+}
+
+// Pending returns the pending changes of the pinned certificates, ordered by host
+func (s *Store) Pending() []Change {
+	s.Lock()
+	defer s.Unlock()
+
+	changes := make([]Change, 0, len(s.pending))
+	for _, change := range s.pending {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Host < changes[j].Host
+	})
+	return changes
+}
+
+// Approve pins the certificate of the pending change of host, replacing the pinned one
+func (s *Store) Approve(host string) (Pin, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	change, exists := s.pending[host]
+	if !exists {
+		return Pin{}, fmt.Errorf("%w: %s", ErrNoPendingChange, host)
+	}
+	s.pins[host] = change.Pin
+	delete(s.pending, host)
+
+	return change.Pin, s.save()
+}
+
+// Reject discards the pending change of host, retaining the pinned certificate
+func (s *Store) Reject(host string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.pending[host]; !exists {
+		return fmt.Errorf("%w: %s", ErrNoPendingChange, host)
+	}
+	delete(s.pending, host)
+
+	return s.save()
+}
+
+// Remove removes the pinned certificate of host (and its pending change, if any). The certificate
+// presented upon the next contact is pinned anew
+func (s *Store) Remove(host string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.pins[host]; !exists {
+		return fmt.Errorf("%w: %s", ErrPinNotFound, host)
+	}
+	delete(s.pins, host)
+	delete(s.pending, host)
+
+	return s.save()
+}
+
+// save atomically persists the store (if a path was provided). The caller must hold the lock
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	state := storeState{
+		Pins:    make([]Pin, 0, len(s.pins)),
+		Pending: make([]Change, 0, len(s.pending)),
+	}
+	for _, pin := range s.pins {
+		state.Pins = append(state.Pins, pin)
+	}
+	for _, change := range s.pending {
+		state.Pending = append(state.Pending, change)
+	}
+	sort.Slice(state.Pins, func(i, j int) bool {
+		return state.Pins[i].Host < state.Pins[j].Host
+	})
+	sort.Slice(state.Pending, func(i, j int) bool {
+		return state.Pending[i].Host < state.Pending[j].Host
+	})
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + storeTempSuffix
+	if err := os.WriteFile(tmpPath, data, storePermissions); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+type storeKey struct{}
+
+// WithStore returns a context providing the pin store to API clients created based on it
+func WithStore(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// FromContext returns the pin store provided by the context (or nil if there is none)
+func FromContext(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeKey{}).(*Store)
+	return s
+}
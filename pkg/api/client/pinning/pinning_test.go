@@ -0,0 +1,124 @@
+package pinning
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testHost = "probe-1.example.com:8145"
+
+func testCertificate(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return cert
+}
+
+func presenting(cert *x509.Certificate) tls.ConnectionState {
+	return tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.json")
+	store, err := New(path)
+	require.Nil(t, err)
+
+	cert, renewed := testCertificate(t, "probe-1"), testCertificate(t, "probe-1")
+
+	// the certificate presented upon first contact is pinned
+	require.Nil(t, store.Verify(testHost, presenting(cert)))
+	require.Nil(t, store.Verify(testHost, presenting(cert)))
+	require.Len(t, store.Pins(), 1)
+	require.Equal(t, Fingerprint(cert), store.Pins()[0].Fingerprint)
+	require.Equal(t, "CN=probe-1", store.Pins()[0].Subject)
+
+	// a different certificate is rejected until the change has been approved
+	require.ErrorIs(t, store.Verify(testHost, presenting(renewed)), ErrFingerprintMismatch)
+	require.ErrorIs(t, store.Verify(testHost, presenting(renewed)), ErrFingerprintMismatch)
+	require.ErrorIs(t, store.Verify(testHost, tls.ConnectionState{}), ErrNoCertificate)
+	require.Nil(t, store.Verify(testHost, presenting(cert)))
+
+	pending := store.Pending()
+	require.Len(t, pending, 1)
+	require.Equal(t, Fingerprint(renewed), pending[0].Fingerprint)
+	require.Equal(t, Fingerprint(cert), pending[0].Pinned)
+
+	// the pins and pending changes are persisted
+	reloaded, err := New(path)
+	require.Nil(t, err)
+	require.Equal(t, store.Pins(), reloaded.Pins())
+	require.Equal(t, store.Pending(), reloaded.Pending())
+
+	pin, err := reloaded.Approve(testHost)
+	require.Nil(t, err)
+	require.Equal(t, Fingerprint(renewed), pin.Fingerprint)
+	require.Empty(t, reloaded.Pending())
+	require.Nil(t, reloaded.Verify(testHost, presenting(renewed)))
+	require.ErrorIs(t, reloaded.Verify(testHost, presenting(cert)), ErrFingerprintMismatch)
+
+	_, err = reloaded.Approve("unknown:8145")
+	require.ErrorIs(t, err, ErrNoPendingChange)
+
+	// rejecting a change retains the pinned certificate
+	require.Nil(t, reloaded.Reject(testHost))
+	require.ErrorIs(t, reloaded.Reject(testHost), ErrNoPendingChange)
+	require.Equal(t, Fingerprint(renewed), reloaded.Pins()[0].Fingerprint)
+
+	// once removed, the next certificate presented is pinned anew
+	require.Nil(t, reloaded.Remove(testHost))
+	require.ErrorIs(t, reloaded.Remove(testHost), ErrPinNotFound)
+	require.Nil(t, reloaded.Verify(testHost, presenting(cert)))
+	require.Equal(t, Fingerprint(cert), reloaded.Pins()[0].Fingerprint)
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	store, err := New("")
+	require.Nil(t, err)
+
+	get := func(tlsConfig *tls.Config) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// the self-signed certificate of the server is accepted (and pinned) upon first contact
+	require.Nil(t, get(store.ClientTLSConfig(srv.Listener.Addr().String())))
+	require.Nil(t, get(store.ClientTLSConfig(srv.Listener.Addr().String())))
+	require.Equal(t, Fingerprint(srv.Certificate()), store.Pins()[0].Fingerprint)
+
+	// a server presenting a different certificate under the same address is rejected
+	store.pins[srv.Listener.Addr().String()] = Pin{Host: srv.Listener.Addr().String(), Fingerprint: Fingerprint(testCertificate(t, "other"))}
+	require.ErrorIs(t, get(store.ClientTLSConfig(srv.Listener.Addr().String())), ErrFingerprintMismatch)
+	require.Len(t, store.Pending(), 1)
+}
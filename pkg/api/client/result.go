@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/api/resultpb"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/fako1024/httpc"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// binaryResultsAccept denotes the Accept header of query requests if binary results are enabled. JSON
+// remains acceptable (with lower preference) for servers not supporting the binary representation
+var binaryResultsAccept = api.ProtobufContentType + ", application/json;q=0.9"
+
+// ParseResult configures a query request to decode the result into res, requesting its binary
+// representation if enabled (c.f. WithBinaryResults). The response is decoded according to its
+// content type, hence both representations are handled transparently
+func (c *DefaultClient) ParseResult(req *httpc.Request, res *results.Result) *httpc.Request {
+	if c.binaryResults {
+		req = req.ModifyRequest(func(r *http.Request) error {
+			r.Header.Set("Accept", binaryResultsAccept)
+			return nil
+		})
+	}
+	return req.ParseFn(func(resp *http.Response) error {
+		return decodeResult(resp, res)
+	})
+}
+
+func decodeResult(resp *http.Response, res *results.Result) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != api.ProtobufContentType {
+		return jsoniter.NewDecoder(resp.Body).Decode(res)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read binary result: %w", err)
+	}
+	return resultpb.Unmarshal(data, res)
+}
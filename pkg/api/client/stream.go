@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/api/server"
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/query"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// errorStreamEnded is returned if the event stream ends before the result of the query was received
+var errorStreamEnded = errors.New("event stream ended without result")
+
+// StreamQuery runs a query against the query route of the API, requesting the server to stream the
+// progress of the query (reported to fn) before sending its result, which is decoded into res
+func (c *DefaultClient) StreamQuery(ctx context.Context, args any, res any, fn query.ProgressFn) error {
+	body, err := jsoniter.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode query args: %w", err)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.NewURL(api.QueryRoute), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", api.StreamContentType)
+	req.Header.Set(server.RuntimeIDHeaderKey, info.RuntimeID())
+	if c.key != "" {
+		req.Header.Set("Authorization", "digest "+c.key)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("query failed: %w", parseError(resp))
+	}
+
+	return readEvents(resp.Body, res, fn)
+}
+
+// readEvents consumes the server-sent events of a streamed query until either its result or an
+// error is received
+func readEvents(r io.Reader, res any, fn query.ProgressFn) error {
+	var (
+		br    = bufio.NewReader(r)
+		event string
+		data  []byte
+	)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			if errors.Is(err, io.EOF) {
+				return errorStreamEnded
+			}
+			return err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+
+		// a non-empty line adds to the current event, an empty one dispatches it
+		if len(line) > 0 {
+			field, value, _ := bytes.Cut(line, []byte(":"))
+			value = bytes.TrimPrefix(value, []byte(" "))
+			switch string(field) {
+			case "event":
+				event = string(value)
+			case "data":
+				if data != nil {
+					data = append(data, '\n')
+				}
+				data = append(data, value...)
+			}
+			if err == nil {
+				continue
+			}
+		}
+		if event == "" && data == nil {
+			continue
+		}
+
+		switch event {
+		case api.ProgressEvent:
+			var p query.Progress
+			if err := jsoniter.Unmarshal(data, &p); err != nil {
+				return fmt.Errorf("failed to decode progress: %w", err)
+			}
+			if fn != nil {
+				fn(p)
+			}
+		case api.ResultEvent:
+			if err := jsoniter.Unmarshal(data, res); err != nil {
+				return fmt.Errorf("failed to decode result: %w", err)
+			}
+			return nil
+		case api.ErrorEvent:
+			return errors.New(string(data))
+		}
+		event, data = "", nil
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/els0r/telemetry/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingZstd denotes the zstd content encoding
+	EncodingZstd = "zstd"
+
+	// EncodingGzip denotes the gzip content encoding
+	EncodingGzip = "gzip"
+
+	// DefaultCompressionMinSize denotes the minimum size of a response body (in bytes) to be compressed
+	DefaultCompressionMinSize = 1024
+)
+
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+	zstdWriterPool = sync.Pool{
+		New: func() any {
+			// single-threaded encoding suffices for response bodies and avoids spawning goroutines per encoder
+			enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+			return enc
+		},
+	}
+)
+
+// CompressionMiddleware compresses all response bodies using the encoding preferred by the client (zstd or
+// gzip, as negotiated via the Accept-Encoding header). Bodies smaller than minSize (unless flushed before
+// reaching it, e.g. for streamed responses) and bodies already encoded by the handler are sent as is
+func CompressionMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := NegotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := c.Writer
+		cw := &compressWriter{
+			ResponseWriter: writer,
+			encoding:       encoding,
+			minSize:        minSize,
+		}
+		c.Writer = cw
+		c.Next()
+
+		if err := cw.close(); err != nil {
+			logging.FromContext(c.Request.Context()).With("encoding", encoding, "error", err).Error("failed to compress response")
+		}
+		c.Writer = writer
+	}
+}
+
+// NegotiateEncoding returns the supported content encoding preferred by the client according to the
+// Accept-Encoding header (an empty string if none of them is acceptable). For equal quality values,
+// zstd is preferred over gzip
+func NegotiateEncoding(acceptEncoding string) string {
+	var (
+		best     string
+		bestQVal float64
+	)
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(entry, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		qVal := 1.
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			qVal = parsed
+		}
+		if qVal <= 0 {
+			continue
+		}
+
+		switch coding {
+		case EncodingZstd:
+			if qVal >= bestQVal {
+				best, bestQVal = EncodingZstd, qVal
+			}
+		case EncodingGzip, "*":
+			if qVal > bestQVal {
+				best, bestQVal = EncodingGzip, qVal
+			}
+		}
+	}
+	return best
+}
+
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter buffers the response body until it reaches the minimum size for compression, from then
+// on all data is compressed before being written to the underlying writer
+type compressWriter struct {
+	gin.ResponseWriter
+
+	encoding string
+	minSize  int
+
+	buf         []byte
+	enc         flushWriteCloser
+	passthrough bool
+}
+
+// WriteString implements gin.ResponseWriter (which would otherwise bypass the compression)
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Write implements io.Writer
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	if w.passthrough || w.skipCompression() {
+		w.passthrough = true
+		return w.ResponseWriter.Write(data)
+	}
+	if len(w.buf)+len(data) < w.minSize {
+		w.buf = append(w.buf, data...)
+		return len(data), nil
+	}
+
+	w.startCompression()
+	return w.enc.Write(data)
+}
+
+// Flush implements http.Flusher, starting the compression right away (since the response is
+// apparently streamed)
+func (w *compressWriter) Flush() {
+	if w.enc == nil && !w.passthrough {
+		if w.skipCompression() {
+			w.passthrough = true
+		} else {
+			w.startCompression()
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// skipCompression determines if the response must be sent as is, i.e. if it is already encoded, cannot
+// carry a body or if the headers have already been sent
+func (w *compressWriter) skipCompression() bool {
+	if len(w.buf) > 0 {
+		return false
+	}
+	return w.Header().Get("Content-Encoding") != "" || w.ResponseWriter.Written() || !bodyAllowedForStatus(w.Status())
+}
+
+func (w *compressWriter) startCompression() {
+	header := w.Header()
+
+	// the content type can no longer be sniffed from the (compressed) body
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+
+	switch w.encoding {
+	case EncodingZstd:
+		enc := zstdWriterPool.Get().(*zstd.Encoder)
+		enc.Reset(w.ResponseWriter)
+		w.enc = enc
+	default:
+		enc := gzipWriterPool.Get().(*gzip.Writer)
+		enc.Reset(w.ResponseWriter)
+		w.enc = enc
+	}
+
+	// the buffer is always smaller than the minimum size, hence its contents are compressed along with
+	// the data triggering the compression
+	if len(w.buf) > 0 {
+		_, _ = w.enc.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// close finalizes the response, sending buffered (small) bodies uncompressed
+func (w *compressWriter) close() error {
+	if w.enc == nil {
+		if len(w.buf) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	err := w.enc.Close()
+	switch enc := w.enc.(type) {
+	case *zstd.Encoder:
+		enc.Reset(io.Discard)
+		zstdWriterPool.Put(enc)
+	case *gzip.Writer:
+		enc.Reset(io.Discard)
+		gzipWriterPool.Put(enc)
+	}
+	w.enc = nil
+
+	return err
+}
+
+// bodyAllowedForStatus reports whether a given response status code permits a body (c.f. RFC 7230,
+// section 3.3)
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
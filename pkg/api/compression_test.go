@@ -0,0 +1,102 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for _, c := range []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingGzip},
+		{"gzip, zstd", EncodingZstd},
+		{"zstd;q=0.5, gzip", EncodingGzip},
+		{"zstd;q=0, gzip;q=0", ""},
+		{"ZSTD", EncodingZstd},
+		{"*", EncodingGzip},
+		{"gzip;q=invalid", ""},
+	} {
+		t.Run(c.acceptEncoding, func(t *testing.T) {
+			require.Equal(t, c.expected, NegotiateEncoding(c.acceptEncoding))
+		})
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	largeBody := strings.Repeat("goProbe ", 1024)
+	router := gin.New()
+	router.Use(CompressionMiddleware(DefaultCompressionMinSize))
+	router.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, largeBody)
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "small")
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", EncodingGzip)
+		c.String(http.StatusOK, largeBody)
+	})
+
+	decoders := map[string]func(r io.Reader) (io.Reader, error){
+		"": func(r io.Reader) (io.Reader, error) {
+			return r, nil
+		},
+		EncodingGzip: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		EncodingZstd: func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		},
+	}
+
+	for _, c := range []struct {
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{"/large", "", "", largeBody},
+		{"/large", "gzip", EncodingGzip, largeBody},
+		{"/large", "gzip, zstd", EncodingZstd, largeBody},
+		{"/small", "zstd", "", "small"},
+		{"/encoded", "zstd", EncodingGzip, largeBody},
+	} {
+		t.Run(c.path+"/"+c.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, c.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+			// handler-encoded bodies are passed through as is
+			if c.path == "/encoded" {
+				require.Equal(t, c.expectedBody, rec.Body.String())
+				return
+			}
+
+			r, err := decoders[c.expectedEncoding](rec.Body)
+			require.Nil(t, err)
+			body, err := io.ReadAll(r)
+			require.Nil(t, err)
+			require.Equal(t, c.expectedBody, string(body))
+		})
+	}
+}
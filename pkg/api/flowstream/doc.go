@@ -0,0 +1,6 @@
+// Package flowstream provides the protobuf schema (and the generated gRPC bindings) of the flow stream,
+// via which goProbe streams the flows of each interface to an external consumer as soon as they have
+// been rotated (c.f. the stream writeout sink). Consumers implement the FlowStreamServer interface
+package flowstream
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative flowstream.proto
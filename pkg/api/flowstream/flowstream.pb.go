@@ -0,0 +1,556 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: flowstream.proto
+
+package flowstream
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FlowBatch denotes the flows of a single interface of a single rotation
+type FlowBatch struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Session identifies the goProbe instance the batch originates from (it is regenerated upon each
+	// restart). Together with the sequence number, it uniquely identifies a batch
+	Session string `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	// Sequence denotes the (strictly increasing) sequence number of the batch within the session
+	Sequence uint64 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Hostname denotes the name of the host goProbe is running on
+	Hostname string `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// Iface denotes the interface the flows were captured on
+	Iface string `protobuf:"bytes,4,opt,name=iface,proto3" json:"iface,omitempty"`
+	// Timestamp denotes the time of the rotation (in seconds since the epoch)
+	Timestamp int64 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Stats denotes the capture statistics of the interface for the rotation
+	Stats *CaptureStats `protobuf:"bytes,6,opt,name=stats,proto3" json:"stats,omitempty"`
+	// Flows stores the flows of the rotation
+	Flows []*Flow `protobuf:"bytes,7,rep,name=flows,proto3" json:"flows,omitempty"`
+}
+
+func (x *FlowBatch) Reset() {
+	*x = FlowBatch{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowstream_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FlowBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowBatch) ProtoMessage() {}
+
+func (x *FlowBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_flowstream_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowBatch.ProtoReflect.Descriptor instead.
+func (*FlowBatch) Descriptor() ([]byte, []int) {
+	return file_flowstream_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FlowBatch) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *FlowBatch) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *FlowBatch) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *FlowBatch) GetIface() string {
+	if x != nil {
+		return x.Iface
+	}
+	return ""
+}
+
+func (x *FlowBatch) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *FlowBatch) GetStats() *CaptureStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *FlowBatch) GetFlows() []*Flow {
+	if x != nil {
+		return x.Flows
+	}
+	return nil
+}
+
+// CaptureStats denotes the capture statistics of an interface for a single rotation
+type CaptureStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Received denotes the number of packets received
+	Received uint64 `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"`
+	// Processed denotes the number of packets processed
+	Processed uint64 `protobuf:"varint,2,opt,name=processed,proto3" json:"processed,omitempty"`
+	// Dropped denotes the number of packets dropped
+	Dropped uint64 `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	// Filtered denotes the number of packets discarded by the capture filter
+	Filtered uint64 `protobuf:"varint,4,opt,name=filtered,proto3" json:"filtered,omitempty"`
+}
+
+func (x *CaptureStats) Reset() {
+	*x = CaptureStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowstream_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CaptureStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureStats) ProtoMessage() {}
+
+func (x *CaptureStats) ProtoReflect() protoreflect.Message {
+	mi := &file_flowstream_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureStats.ProtoReflect.Descriptor instead.
+func (*CaptureStats) Descriptor() ([]byte, []int) {
+	return file_flowstream_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CaptureStats) GetReceived() uint64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetProcessed() uint64 {
+	if x != nil {
+		return x.Processed
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetFiltered() uint64 {
+	if x != nil {
+		return x.Filtered
+	}
+	return 0
+}
+
+// Flow denotes a single flow aggregated over a rotation
+type Flow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Sip denotes the source IP address (4 or 16 bytes)
+	Sip []byte `protobuf:"bytes,1,opt,name=sip,proto3" json:"sip,omitempty"`
+	// Dip denotes the destination IP address (4 or 16 bytes)
+	Dip []byte `protobuf:"bytes,2,opt,name=dip,proto3" json:"dip,omitempty"`
+	// Dport denotes the destination port
+	Dport uint32 `protobuf:"varint,3,opt,name=dport,proto3" json:"dport,omitempty"`
+	// Proto denotes the IP protocol number
+	Proto uint32 `protobuf:"varint,4,opt,name=proto,proto3" json:"proto,omitempty"`
+	// Tag denotes the name of the tag assigned to the flow (if any)
+	Tag string `protobuf:"bytes,5,opt,name=tag,proto3" json:"tag,omitempty"`
+	// TtlMin denotes the minimum IP TTL / hop limit observed (if tracked)
+	TtlMin uint32 `protobuf:"varint,6,opt,name=ttl_min,json=ttlMin,proto3" json:"ttl_min,omitempty"`
+	// TtlMax denotes the maximum IP TTL / hop limit observed (if tracked)
+	TtlMax uint32 `protobuf:"varint,7,opt,name=ttl_max,json=ttlMax,proto3" json:"ttl_max,omitempty"`
+	// BytesRcvd denotes the number of bytes received
+	BytesRcvd uint64 `protobuf:"varint,8,opt,name=bytes_rcvd,json=bytesRcvd,proto3" json:"bytes_rcvd,omitempty"`
+	// BytesSent denotes the number of bytes sent
+	BytesSent uint64 `protobuf:"varint,9,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	// PacketsRcvd denotes the number of packets received
+	PacketsRcvd uint64 `protobuf:"varint,10,opt,name=packets_rcvd,json=packetsRcvd,proto3" json:"packets_rcvd,omitempty"`
+	// PacketsSent denotes the number of packets sent
+	PacketsSent uint64 `protobuf:"varint,11,opt,name=packets_sent,json=packetsSent,proto3" json:"packets_sent,omitempty"`
+}
+
+func (x *Flow) Reset() {
+	*x = Flow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowstream_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Flow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Flow) ProtoMessage() {}
+
+func (x *Flow) ProtoReflect() protoreflect.Message {
+	mi := &file_flowstream_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Flow.ProtoReflect.Descriptor instead.
+func (*Flow) Descriptor() ([]byte, []int) {
+	return file_flowstream_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Flow) GetSip() []byte {
+	if x != nil {
+		return x.Sip
+	}
+	return nil
+}
+
+func (x *Flow) GetDip() []byte {
+	if x != nil {
+		return x.Dip
+	}
+	return nil
+}
+
+func (x *Flow) GetDport() uint32 {
+	if x != nil {
+		return x.Dport
+	}
+	return 0
+}
+
+func (x *Flow) GetProto() uint32 {
+	if x != nil {
+		return x.Proto
+	}
+	return 0
+}
+
+func (x *Flow) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *Flow) GetTtlMin() uint32 {
+	if x != nil {
+		return x.TtlMin
+	}
+	return 0
+}
+
+func (x *Flow) GetTtlMax() uint32 {
+	if x != nil {
+		return x.TtlMax
+	}
+	return 0
+}
+
+func (x *Flow) GetBytesRcvd() uint64 {
+	if x != nil {
+		return x.BytesRcvd
+	}
+	return 0
+}
+
+func (x *Flow) GetBytesSent() uint64 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
+}
+
+func (x *Flow) GetPacketsRcvd() uint64 {
+	if x != nil {
+		return x.PacketsRcvd
+	}
+	return 0
+}
+
+func (x *Flow) GetPacketsSent() uint64 {
+	if x != nil {
+		return x.PacketsSent
+	}
+	return 0
+}
+
+// Ack acknowledges all batches of the stream up to and including the sequence number
+type Ack struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Sequence denotes the sequence number of the most recent batch processed by the consumer
+	Sequence uint64 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowstream_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_flowstream_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_flowstream_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Ack) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+var File_flowstream_proto protoreflect.FileDescriptor
+
+var file_flowstream_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x15, 0x67, 0x6f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x2e, 0x66, 0x6c, 0x6f, 0x77,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x22, 0xff, 0x01, 0x0a, 0x09, 0x46, 0x6c,
+	0x6f, 0x77, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x66, 0x61,
+	0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x66, 0x61, 0x63, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x39, 0x0a,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x67,
+	0x6f, 0x70, 0x72, 0x6f, 0x62, 0x65, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x73, 0x12, 0x31, 0x0a, 0x05, 0x66, 0x6c, 0x6f, 0x77,
+	0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x67, 0x6f, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e,
+	0x46, 0x6c, 0x6f, 0x77, 0x52, 0x05, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x22, 0x7e, 0x0a, 0x0c, 0x43,
+	0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72,
+	0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x72,
+	0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x63, 0x65,
+	0x73, 0x73, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x63,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x65, 0x64, 0x22, 0x9e, 0x02, 0x0a, 0x04,
+	0x46, 0x6c, 0x6f, 0x77, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x03, 0x73, 0x69, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x69, 0x70, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x03, 0x64, 0x69, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x70, 0x6f, 0x72,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x64, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x74, 0x61, 0x67, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x74, 0x6c, 0x5f, 0x6d, 0x69,
+	0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x74, 0x74, 0x6c, 0x4d, 0x69, 0x6e, 0x12,
+	0x17, 0x0a, 0x07, 0x74, 0x74, 0x6c, 0x5f, 0x6d, 0x61, 0x78, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x06, 0x74, 0x74, 0x6c, 0x4d, 0x61, 0x78, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x79, 0x74, 0x65,
+	0x73, 0x5f, 0x72, 0x63, 0x76, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x52, 0x63, 0x76, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x79, 0x74, 0x65, 0x73,
+	0x5f, 0x73, 0x65, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x53, 0x65, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x73, 0x5f, 0x72, 0x63, 0x76, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x70, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x73, 0x52, 0x63, 0x76, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x73, 0x5f, 0x73, 0x65, 0x6e, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0b, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x53, 0x65, 0x6e, 0x74, 0x22, 0x21, 0x0a, 0x03,
+	0x41, 0x63, 0x6b, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x32,
+	0x58, 0x0a, 0x0a, 0x46, 0x6c, 0x6f, 0x77, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x4a, 0x0a,
+	0x06, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x20, 0x2e, 0x67, 0x6f, 0x70, 0x72, 0x6f, 0x62,
+	0x65, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e,
+	0x46, 0x6c, 0x6f, 0x77, 0x42, 0x61, 0x74, 0x63, 0x68, 0x1a, 0x1a, 0x2e, 0x67, 0x6f, 0x70, 0x72,
+	0x6f, 0x62, 0x65, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76,
+	0x31, 0x2e, 0x41, 0x63, 0x6b, 0x28, 0x01, 0x30, 0x01, 0x42, 0x2d, 0x5a, 0x2b, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x6c, 0x73, 0x30, 0x72, 0x2f, 0x67, 0x6f,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x66, 0x6c,
+	0x6f, 0x77, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_flowstream_proto_rawDescOnce sync.Once
+	file_flowstream_proto_rawDescData = file_flowstream_proto_rawDesc
+)
+
+func file_flowstream_proto_rawDescGZIP() []byte {
+	file_flowstream_proto_rawDescOnce.Do(func() {
+		file_flowstream_proto_rawDescData = protoimpl.X.CompressGZIP(file_flowstream_proto_rawDescData)
+	})
+	return file_flowstream_proto_rawDescData
+}
+
+var file_flowstream_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_flowstream_proto_goTypes = []interface{}{
+	(*FlowBatch)(nil),    // 0: goprobe.flowstream.v1.FlowBatch
+	(*CaptureStats)(nil), // 1: goprobe.flowstream.v1.CaptureStats
+	(*Flow)(nil),         // 2: goprobe.flowstream.v1.Flow
+	(*Ack)(nil),          // 3: goprobe.flowstream.v1.Ack
+}
+var file_flowstream_proto_depIdxs = []int32{
+	1, // 0: goprobe.flowstream.v1.FlowBatch.stats:type_name -> goprobe.flowstream.v1.CaptureStats
+	2, // 1: goprobe.flowstream.v1.FlowBatch.flows:type_name -> goprobe.flowstream.v1.Flow
+	0, // 2: goprobe.flowstream.v1.FlowStream.Stream:input_type -> goprobe.flowstream.v1.FlowBatch
+	3, // 3: goprobe.flowstream.v1.FlowStream.Stream:output_type -> goprobe.flowstream.v1.Ack
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_flowstream_proto_init() }
+func file_flowstream_proto_init() {
+	if File_flowstream_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_flowstream_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FlowBatch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_flowstream_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CaptureStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_flowstream_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Flow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_flowstream_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Ack); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_flowstream_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_flowstream_proto_goTypes,
+		DependencyIndexes: file_flowstream_proto_depIdxs,
+		MessageInfos:      file_flowstream_proto_msgTypes,
+	}.Build()
+	File_flowstream_proto = out.File
+	file_flowstream_proto_rawDesc = nil
+	file_flowstream_proto_goTypes = nil
+	file_flowstream_proto_depIdxs = nil
+}
@@ -0,0 +1,149 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: flowstream.proto
+
+package flowstream
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FlowStream_Stream_FullMethodName = "/goprobe.flowstream.v1.FlowStream/Stream"
+)
+
+// FlowStreamClient is the client API for FlowStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FlowStreamClient interface {
+	// Stream transmits the flows of each interface and rotation as a single batch. The consumer
+	// acknowledges batches cumulatively (i.e. an acknowledgement covers all batches up to and including
+	// its sequence number). Batches which have not been acknowledged are retransmitted once the stream
+	// is reestablished, hence batches are delivered at least once
+	Stream(ctx context.Context, opts ...grpc.CallOption) (FlowStream_StreamClient, error)
+}
+
+type flowStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFlowStreamClient(cc grpc.ClientConnInterface) FlowStreamClient {
+	return &flowStreamClient{cc}
+}
+
+func (c *flowStreamClient) Stream(ctx context.Context, opts ...grpc.CallOption) (FlowStream_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FlowStream_ServiceDesc.Streams[0], FlowStream_Stream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &flowStreamStreamClient{stream}
+	return x, nil
+}
+
+type FlowStream_StreamClient interface {
+	Send(*FlowBatch) error
+	Recv() (*Ack, error)
+	grpc.ClientStream
+}
+
+type flowStreamStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *flowStreamStreamClient) Send(m *FlowBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *flowStreamStreamClient) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FlowStreamServer is the server API for FlowStream service.
+// All implementations must embed UnimplementedFlowStreamServer
+// for forward compatibility
+type FlowStreamServer interface {
+	// Stream transmits the flows of each interface and rotation as a single batch. The consumer
+	// acknowledges batches cumulatively (i.e. an acknowledgement covers all batches up to and including
+	// its sequence number). Batches which have not been acknowledged are retransmitted once the stream
+	// is reestablished, hence batches are delivered at least once
+	Stream(FlowStream_StreamServer) error
+	mustEmbedUnimplementedFlowStreamServer()
+}
+
+// UnimplementedFlowStreamServer must be embedded to have forward compatible implementations.
+type UnimplementedFlowStreamServer struct {
+}
+
+func (UnimplementedFlowStreamServer) Stream(FlowStream_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedFlowStreamServer) mustEmbedUnimplementedFlowStreamServer() {}
+
+// UnsafeFlowStreamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FlowStreamServer will
+// result in compilation errors.
+type UnsafeFlowStreamServer interface {
+	mustEmbedUnimplementedFlowStreamServer()
+}
+
+func RegisterFlowStreamServer(s grpc.ServiceRegistrar, srv FlowStreamServer) {
+	s.RegisterService(&FlowStream_ServiceDesc, srv)
+}
+
+func _FlowStream_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FlowStreamServer).Stream(&flowStreamStreamServer{stream})
+}
+
+type FlowStream_StreamServer interface {
+	Send(*Ack) error
+	Recv() (*FlowBatch, error)
+	grpc.ServerStream
+}
+
+type flowStreamStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *flowStreamStreamServer) Send(m *Ack) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *flowStreamStreamServer) Recv() (*FlowBatch, error) {
+	m := new(FlowBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FlowStream_ServiceDesc is the grpc.ServiceDesc for FlowStream service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FlowStream_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "goprobe.flowstream.v1.FlowStream",
+	HandlerType: (*FlowStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _FlowStream_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "flowstream.proto",
+}
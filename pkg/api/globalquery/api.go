@@ -0,0 +1,58 @@
+package globalquery
+
+import (
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+)
+
+type response struct {
+	StatusCode int    `json:"status_code"`     // StatusCode: stores the HTTP status code of the response. Example: 200
+	Error      string `json:"error,omitempty"` // Error: stores the error message if the request failed. Example: "job not found"
+}
+
+// SchedulesRoute is the route to list / register scheduled queries
+const SchedulesRoute = "/schedules"
+
+// ScheduleTriggerRoute is the route (relative to a single scheduled query) to trigger an
+// immediate run
+const ScheduleTriggerRoute = "/_run"
+
+// SchedulesResponse is the response to a listing of all scheduled queries
+type SchedulesResponse struct {
+	response
+	Jobs []schedule.JobStatus `json:"jobs"` // Jobs: stores the registered scheduled queries alongside their run history
+}
+
+// ScheduleResponse is the response to a request concerning a single scheduled query
+type ScheduleResponse struct {
+	response
+	Job *schedule.JobStatus `json:"job,omitempty"` // Job: stores the scheduled query alongside its run history
+}
+
+// ScheduleRegisterRequest is the payload to register a new scheduled query
+type ScheduleRegisterRequest schedule.Job
+
+// PinsRoute is the route to list the certificate fingerprints pinned for the queried hosts (and the
+// pending changes thereof)
+const PinsRoute = "/pins"
+
+// PinApproveRoute is the route (relative to the pin of a single host) to approve a pending change of
+// the pinned certificate
+const PinApproveRoute = "/_approve"
+
+// PinRejectRoute is the route (relative to the pin of a single host) to reject a pending change of
+// the pinned certificate
+const PinRejectRoute = "/_reject"
+
+// PinsResponse is the response to a listing of all pinned certificates
+type PinsResponse struct {
+	response
+	Pins    []pinning.Pin    `json:"pins"`              // Pins: stores the certificates pinned for the queried hosts
+	Pending []pinning.Change `json:"pending,omitempty"` // Pending: stores the changes of pinned certificates awaiting approval
+}
+
+// PinResponse is the response to a request concerning the pinned certificate of a single host
+type PinResponse struct {
+	response
+	Pin *pinning.Pin `json:"pin,omitempty"` // Pin: stores the certificate pinned for the host
+}
@@ -39,6 +39,8 @@ func (c *Client) Query(ctx context.Context, args *query.Args) (*results.Result,
 
 	// whatever happens, the results are expected to be returned in json
 	queryArgs.Format = "json"
+	// human-readable units and percentages are rendered locally (and rejected for json)
+	queryArgs.HumanReadable, queryArgs.DirectionPercentages = false, false
 
 	if queryArgs.Caller == "" {
 		queryArgs.Caller = clientName
@@ -46,11 +48,18 @@ func (c *Client) Query(ctx context.Context, args *query.Args) (*results.Result,
 
 	var res = new(results.Result)
 
-	req := c.Modify(ctx,
+	// if progress reporting was requested, have the server stream it alongside the result
+	if fn := query.ProgressFromContext(ctx); fn != nil {
+		if err := c.StreamQuery(ctx, queryArgs, res, fn); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	req := c.ParseResult(c.Modify(ctx,
 		httpc.NewWithClient("POST", c.NewURL(api.QueryRoute), c.Client()).
-			EncodeJSON(queryArgs).
-			ParseJSON(res),
-	)
+			EncodeJSON(queryArgs),
+	), res)
 
 	err := req.RunWithContext(ctx)
 	if err != nil {
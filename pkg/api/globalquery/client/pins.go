@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
+	gqapi "github.com/els0r/goProbe/pkg/api/globalquery"
+	"github.com/fako1024/httpc"
+)
+
+// ListPins returns the certificates pinned for the queried hosts and the pending changes thereof
+func (c *Client) ListPins(ctx context.Context) ([]pinning.Pin, []pinning.Change, error) {
+	var res = new(gqapi.PinsResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", c.NewURL(gqapi.PinsRoute), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, nil, responseError(err, res.StatusCode, res.Error)
+	}
+
+	return res.Pins, res.Pending, nil
+}
+
+// ApprovePin approves the pending change of the certificate pinned for a host, returning the
+// newly pinned certificate
+func (c *Client) ApprovePin(ctx context.Context, host string) (*pinning.Pin, error) {
+	var res = new(gqapi.PinResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", c.NewURL(pinPath(host)+gqapi.PinApproveRoute), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, responseError(err, res.StatusCode, res.Error)
+	}
+
+	return res.Pin, nil
+}
+
+// RejectPin rejects the pending change of the certificate pinned for a host
+func (c *Client) RejectPin(ctx context.Context, host string) error {
+	var res = new(gqapi.PinResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", c.NewURL(pinPath(host)+gqapi.PinRejectRoute), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return responseError(err, res.StatusCode, res.Error)
+	}
+
+	return nil
+}
+
+// RemovePin removes the certificate pinned for a host, which is pinned anew upon the next query
+func (c *Client) RemovePin(ctx context.Context, host string) error {
+	var res = new(gqapi.PinResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("DELETE", c.NewURL(pinPath(host)), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return responseError(err, res.StatusCode, res.Error)
+	}
+
+	return nil
+}
+
+func pinPath(host string) string {
+	return gqapi.PinsRoute + "/" + url.PathEscape(host)
+}
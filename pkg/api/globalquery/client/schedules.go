@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	gqapi "github.com/els0r/goProbe/pkg/api/globalquery"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/fako1024/httpc"
+)
+
+// ListSchedules returns all scheduled queries registered with the query server
+func (c *Client) ListSchedules(ctx context.Context) ([]schedule.JobStatus, error) {
+	var res = new(gqapi.SchedulesResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", c.NewURL(gqapi.SchedulesRoute), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, responseError(err, res.StatusCode, res.Error)
+	}
+
+	return res.Jobs, nil
+}
+
+// GetSchedule returns a single scheduled query alongside its run history
+func (c *Client) GetSchedule(ctx context.Context, name string) (*schedule.JobStatus, error) {
+	var res = new(gqapi.ScheduleResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", c.NewURL(schedulePath(name)), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, responseError(err, res.StatusCode, res.Error)
+	}
+
+	return res.Job, nil
+}
+
+// RegisterSchedule registers a new scheduled query
+func (c *Client) RegisterSchedule(ctx context.Context, job *schedule.Job) (*schedule.JobStatus, error) {
+	var res = new(gqapi.ScheduleResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", c.NewURL(gqapi.SchedulesRoute), c.Client()).
+			EncodeJSON(job).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, responseError(err, res.StatusCode, res.Error)
+	}
+
+	return res.Job, nil
+}
+
+// RemoveSchedule removes a scheduled query
+func (c *Client) RemoveSchedule(ctx context.Context, name string) error {
+	var res = new(gqapi.ScheduleResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("DELETE", c.NewURL(schedulePath(name)), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return responseError(err, res.StatusCode, res.Error)
+	}
+
+	return nil
+}
+
+// TriggerSchedule triggers an immediate run of a scheduled query
+func (c *Client) TriggerSchedule(ctx context.Context, name string) error {
+	var res = new(gqapi.ScheduleResponse)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", c.NewURL(schedulePath(name)+gqapi.ScheduleTriggerRoute), c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return responseError(err, res.StatusCode, res.Error)
+	}
+
+	return nil
+}
+
+func schedulePath(name string) string {
+	return gqapi.SchedulesRoute + "/" + url.PathEscape(name)
+}
+
+func responseError(err error, statusCode int, msg string) error {
+	if msg != "" {
+		return fmt.Errorf("%d: %s", statusCode, msg)
+	}
+	return err
+}
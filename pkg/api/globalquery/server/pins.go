@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
+	gqapi "github.com/els0r/goProbe/pkg/api/globalquery"
+	"github.com/gin-gonic/gin"
+)
+
+const pinHostKey = "host"
+
+// RegisterPinHandlers hooks up the endpoints to review the certificates pinned for the queried hosts
+// to an existing gin engine. Since approving a certificate change decides which hosts are trusted, the
+// endpoints modifying the pins require authorization and are only exposed if authorized is provided
+func RegisterPinHandlers(engine *gin.Engine, route string, pins *pinning.Store, authorized gin.HandlerFunc) {
+	h := &pinHandlers{pins: pins}
+
+	pinRoutes := engine.Group(route)
+	pinRoutes.GET("", h.listPins)
+	if authorized == nil {
+		return
+	}
+	pinRoutes.DELETE("/:"+pinHostKey, authorized, h.removePin)
+	pinRoutes.POST("/:"+pinHostKey+gqapi.PinApproveRoute, authorized, h.approvePin)
+	pinRoutes.POST("/:"+pinHostKey+gqapi.PinRejectRoute, authorized, h.rejectPin)
+}
+
+type pinHandlers struct {
+	pins *pinning.Store
+}
+
+// pinStatusCode maps pin store errors to HTTP status codes
+func pinStatusCode(err error) int {
+	if errors.Is(err, pinning.ErrPinNotFound) || errors.Is(err, pinning.ErrNoPendingChange) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *pinHandlers) listPins(c *gin.Context) {
+	resp := &gqapi.PinsResponse{}
+	resp.StatusCode = http.StatusOK
+	resp.Pins = h.pins.Pins()
+	resp.Pending = h.pins.Pending()
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *pinHandlers) approvePin(c *gin.Context) {
+	resp := &gqapi.PinResponse{}
+	resp.StatusCode = http.StatusOK
+
+	pin, err := h.pins.Approve(c.Param(pinHostKey))
+	if err != nil {
+		resp.StatusCode = pinStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Pin = &pin
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *pinHandlers) rejectPin(c *gin.Context) {
+	resp := &gqapi.PinResponse{}
+	resp.StatusCode = http.StatusOK
+
+	err := h.pins.Reject(c.Param(pinHostKey))
+	if err != nil {
+		resp.StatusCode = pinStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *pinHandlers) removePin(c *gin.Context) {
+	resp := &gqapi.PinResponse{}
+	resp.StatusCode = http.StatusOK
+
+	err := h.pins.Remove(c.Param(pinHostKey))
+	if err != nil {
+		resp.StatusCode = pinStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/els0r/goProbe/cmd/global-query/pkg/distributed"
 	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
 	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/query"
 	"github.com/els0r/goProbe/pkg/version"
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +14,15 @@ import (
 // RegisterQueryHandler hooks up the distributed query endpoint to an existing gin engine. It is meant for third-party
 // APIs as a means to integrate query capabilities
 func RegisterQueryHandler(engine *gin.Engine, route string, resolver hosts.Resolver, querier distributed.Querier) {
+	registerQueryHandler(engine, route, distributed.NewQueryRunner(resolver, querier))
+}
+
+func registerQueryHandler(engine *gin.Engine, route string, runner query.Runner) {
 	handler := func(c *gin.Context) {
 		api.RunQuery(
 			fmt.Sprintf("global-query/%s", version.Short()),
 			"distributed",
-			distributed.NewQueryRunner(resolver, querier),
+			runner,
 			c,
 		)
 	}
@@ -29,4 +34,11 @@ func RegisterQueryHandler(engine *gin.Engine, route string, resolver hosts.Resol
 	// validation
 	queryGroup.GET("/validate", api.ValidationHandler())
 	queryGroup.POST("/validate", api.ValidationHandler())
+
+	// versioned query routes, rendering the result in the schema of the respective version
+	for _, version := range api.APIVersions {
+		versionedGroup := engine.Group(version.Route(route), api.APIVersionMiddleware(version))
+		versionedGroup.GET("/", handler)
+		versionedGroup.POST("/", handler)
+	}
 }
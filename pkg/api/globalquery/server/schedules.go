@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	gqapi "github.com/els0r/goProbe/pkg/api/globalquery"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/gin-gonic/gin"
+)
+
+const jobNameKey = "name"
+
+// RegisterScheduleHandlers hooks up the endpoints to manage scheduled queries to an existing
+// gin engine
+func RegisterScheduleHandlers(engine *gin.Engine, route string, scheduler *schedule.Scheduler) {
+	h := &scheduleHandlers{scheduler: scheduler}
+
+	scheduleRoutes := engine.Group(route)
+	scheduleRoutes.GET("", h.listSchedules)
+	scheduleRoutes.POST("", h.registerSchedule)
+	scheduleRoutes.GET("/:"+jobNameKey, h.getSchedule)
+	scheduleRoutes.DELETE("/:"+jobNameKey, h.removeSchedule)
+	scheduleRoutes.POST("/:"+jobNameKey+gqapi.ScheduleTriggerRoute, h.triggerSchedule)
+}
+
+type scheduleHandlers struct {
+	scheduler *schedule.Scheduler
+}
+
+// statusCode maps scheduler errors to HTTP status codes
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, schedule.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, schedule.ErrJobExists), errors.Is(err, schedule.ErrRunPending):
+		return http.StatusConflict
+	case errors.Is(err, schedule.ErrSinkNotPermitted):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (h *scheduleHandlers) listSchedules(c *gin.Context) {
+	resp := &gqapi.SchedulesResponse{}
+	resp.StatusCode = http.StatusOK
+	resp.Jobs = h.scheduler.List()
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *scheduleHandlers) getSchedule(c *gin.Context) {
+	resp := &gqapi.ScheduleResponse{}
+	resp.StatusCode = http.StatusOK
+
+	status, err := h.scheduler.Get(c.Param(jobNameKey))
+	if err != nil {
+		resp.StatusCode = statusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Job = &status
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *scheduleHandlers) registerSchedule(c *gin.Context) {
+	resp := &gqapi.ScheduleResponse{}
+	resp.StatusCode = http.StatusCreated
+
+	// the query arguments are decoded on top of the defaults (as for regular queries)
+	req := gqapi.ScheduleRegisterRequest{Args: query.DefaultArgs()}
+	err := c.BindJSON(&req)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	// jobs registered via the API may only deliver to the allowed hosts
+	job := schedule.Job(req)
+	err = h.scheduler.RegisterRestricted(job)
+	if err != nil {
+		resp.StatusCode = statusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	status, err := h.scheduler.Get(job.Name)
+	if err == nil {
+		resp.Job = &status
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *scheduleHandlers) removeSchedule(c *gin.Context) {
+	resp := &gqapi.ScheduleResponse{}
+	resp.StatusCode = http.StatusOK
+
+	err := h.scheduler.Remove(c.Param(jobNameKey))
+	if err != nil {
+		resp.StatusCode = statusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (h *scheduleHandlers) triggerSchedule(c *gin.Context) {
+	resp := &gqapi.ScheduleResponse{}
+	resp.StatusCode = http.StatusAccepted
+
+	err := h.scheduler.Trigger(c.Param(jobNameKey))
+	if err != nil {
+		resp.StatusCode = statusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -5,22 +5,38 @@ import (
 	"github.com/els0r/goProbe/cmd/global-query/pkg/distributed"
 	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
 	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
+	gqapi "github.com/els0r/goProbe/pkg/api/globalquery"
 	"github.com/els0r/goProbe/pkg/api/server"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/gin-gonic/gin"
 )
 
 // Server runs a global-query API server
 type Server struct {
 	hostListResolver hosts.Resolver
 	querier          distributed.Querier
+	queryOpts        []distributed.QueryOption
+	scheduler        *schedule.Scheduler
+	pins             *pinning.Store
 
 	*server.DefaultServer
 }
 
-// New creates a new global-query API server
-func New(addr string, resolver hosts.Resolver, querier distributed.Querier, opts ...server.Option) *Server {
+// New creates a new global-query API server. If a scheduler is provided, the endpoints to manage
+// scheduled queries are exposed. If a pin store is provided, the endpoints to review the certificates
+// pinned for the queried hosts are exposed (modifying them requires one of the API keys configured via
+// server.WithKeys()). The query options (e.g. a cache of per-host results) are applied to all queries
+// run via the API
+func New(addr string, resolver hosts.Resolver, querier distributed.Querier, scheduler *schedule.Scheduler, pins *pinning.Store, queryOpts []distributed.QueryOption, opts ...server.Option) *Server {
 	server := &Server{
 		hostListResolver: resolver,
 		querier:          querier,
+		queryOpts:        queryOpts,
+		scheduler:        scheduler,
+		pins:             pins,
 		DefaultServer:    server.NewDefault(conf.ServiceName, addr, opts...),
 	}
 
@@ -30,5 +46,23 @@ func New(addr string, resolver hosts.Resolver, querier distributed.Querier, opts
 }
 
 func (server *Server) registerRoutes() {
-	RegisterQueryHandler(server.Router(), api.QueryRoute, server.hostListResolver, server.querier)
+	var runner query.Runner = distributed.NewQueryRunner(server.hostListResolver, server.querier, server.queryOpts...)
+	if auditLog, hasAuditLog := server.QueryAuditLog(); hasAuditLog {
+		runner = audit.NewRunner(runner, auditLog)
+	}
+	if queryStats, hasQueryStats := server.QueryStats(); hasQueryStats {
+		runner = queryStats.Runner(runner)
+	}
+	registerQueryHandler(server.Router(), api.QueryRoute, runner)
+	if server.scheduler != nil {
+		RegisterScheduleHandlers(server.Router(), gqapi.SchedulesRoute, server.scheduler)
+	}
+	if server.pins != nil {
+		// the pins can only be modified if API keys are configured
+		var authorized gin.HandlerFunc
+		if server.HasKeys() {
+			authorized = server.Authorized()
+		}
+		RegisterPinHandlers(server.Router(), gqapi.PinsRoute, server.pins, authorized)
+	}
 }
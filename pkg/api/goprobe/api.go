@@ -5,6 +5,8 @@ import (
 
 	"github.com/els0r/goProbe/cmd/goProbe/config"
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
 )
 
 const (
@@ -37,28 +39,173 @@ type StatusResponse struct {
 	StartedAt time.Time `json:"started_at"`
 	// Statuses: stores the statistics for each interface
 	Statuses capturetypes.InterfaceStats `json:"statuses"`
+	// Writeout: stores the statistics of the writeout backlog
+	Writeout *capturetypes.WriteoutStats `json:"writeout,omitempty"`
+	// Cardinality: stores the flow cardinality statistics for each interface with a
+	// configured flow cardinality alarm
+	Cardinality map[string]capturetypes.CardinalityStats `json:"cardinality,omitempty"`
+	// Quota: stores the disk usage quota statistics for each interface with a configured quota
+	Quota map[string]capturetypes.QuotaStats `json:"quota,omitempty"`
+	// Capabilities: stores the capabilities of the NIC backing each interface (including hints on
+	// settings distorting flow accounting), as probed upon initialization of the capture
+	Capabilities map[string]capturetypes.IfaceCapabilities `json:"capabilities,omitempty"`
+	// Bench: stores the assessment of the startup self-benchmark of each interface (if performed),
+	// predicting if its ring buffer suffices for the link speed
+	Bench map[string]capturetypes.BenchAssessment `json:"bench,omitempty"`
+	// Errors: stores the error state of each interface which is configured, but not capturing since its
+	// capture failed to initialize or was torn down due to a critical processing error
+	Errors map[string]capturetypes.IfaceError `json:"errors,omitempty"`
 }
 
-// ConfigRoute is the route to query/modify the current configuration
+// ConfigRoute is the route to query / modify the current configuration. Modifications are
+// performed via JSON merge patches (RFC 7396)
 const ConfigRoute = "/config"
 
+// DefaultMaxConfigBodySize denotes the default maximum size of a config merge patch (in bytes)
+const DefaultMaxConfigBodySize = 1 << 20 // 1 MiB
+
 // ConfigReloadRoute is the route to trigger a config reload
 const ConfigReloadRoute = "/_reload"
 
+// ConfigSchemaRoute is the route to query the JSON Schema describing the full configuration
+const ConfigSchemaRoute = "/schema"
+
 // ConfigResponse is the response to a config query
 type ConfigResponse struct {
 	response
 	Ifaces config.Ifaces `json:"ifaces"` // Ifaces: stores the current configuration for each interface
+	// Config: stores the full configuration document (with all secrets redacted). Only provided if
+	// the configuration of all interfaces is queried
+	Config *config.Config `json:"config,omitempty"`
 }
 
 // ConfigUpdateResponse is the response to a config update
 type ConfigUpdateResponse struct {
 	response
-	Enabled  capturetypes.IfaceChanges `json:"enabled"`  // Enabled: stores the interfaces that were enabled. Example: ["eth0", "eth1"]
-	Updated  capturetypes.IfaceChanges `json:"updated"`  // Updated: stores the interfaces that were updated. Example: ["eth2"]
-	Disabled capturetypes.IfaceChanges `json:"disabled"` // Disabled: stores the interfaces that were disabled. Example: ["eth5"]
+	Changes  config.Changes            `json:"changes,omitempty"` // Changes: stores the changes applied to the configuration (with all secrets redacted)
+	Enabled  capturetypes.IfaceChanges `json:"enabled"`           // Enabled: stores the interfaces that were enabled. Example: ["eth0", "eth1"]
+	Updated  capturetypes.IfaceChanges `json:"updated"`           // Updated: stores the interfaces that were updated. Example: ["eth2"]
+	Disabled capturetypes.IfaceChanges `json:"disabled"`          // Disabled: stores the interfaces that were disabled. Example: ["eth5"]
+}
+
+// BackfillRoute is the route to re-write / backfill an interval of an interface
+const BackfillRoute = "/backfill"
+
+// BackfillRequest is the payload to re-write / backfill an interval of an interface
+type BackfillRequest struct {
+	// Iface: denotes the interface to backfill. Example: "eth0"
+	Iface string `json:"iface"`
+	// From: denotes the start of the interval to backfill. Example: "2021-01-01T00:00:00Z"
+	From time.Time `json:"from"`
+	// To: denotes the end of the interval to backfill. Example: "2021-01-01T01:00:00Z"
+	To time.Time `json:"to"`
+	// Pcap: denotes the path of a (optionally gzip compressed) pcap file on the goProbe host whose
+	// packets are written as a single block for the end of the interval. If empty, the writeouts
+	// retained in the spill buffer are used. Example: "/var/tmp/eth0.pcap.gz"
+	Pcap string `json:"pcap,omitempty"`
+}
+
+// BackfillResponse is the response to a backfill request
+type BackfillResponse struct {
+	response
+	// Iface: denotes the interface that was backfilled. Example: "eth0"
+	Iface string `json:"iface"`
+	// Blocks: stores the outcome for each backfilled block
+	Blocks []capturetypes.BackfillResult `json:"blocks"`
+}
+
+// VacuumRoute is the route to reclaim disk space from the goDB
+const VacuumRoute = "/vacuum"
+
+// VacuumRequest is the payload to reclaim disk space from the goDB
+type VacuumRequest struct {
+	// DryRun: denotes whether the disk space that would be reclaimed is only reported, without
+	// modifying the goDB. Example: true
+	DryRun bool `json:"dry_run,omitempty"`
+	// MinAge: denotes the minimum age of the data of an interface no longer part of the configuration
+	// before it is removed. Defaults to 168h (7 days). Example: "720h"
+	MinAge string `json:"min_age,omitempty"`
+}
+
+// VacuumResponse is the response to a vacuum request
+type VacuumResponse struct {
+	response
+	// DryRun: denotes whether the goDB was left untouched. Example: true
+	DryRun bool `json:"dry_run"`
+	// Ifaces: stores the outcome for each interface present in the goDB
+	Ifaces vacuum.Results `json:"ifaces"`
+}
+
+// MigrateRoute is the route to re-encode the data in the goDB with the currently configured encoders
+const MigrateRoute = "/migrate"
+
+// MigrateRequest is the payload to re-encode the data in the goDB
+type MigrateRequest struct {
+	// Ifaces: denotes the interfaces to migrate. If empty, all interfaces present in the goDB are
+	// migrated. Example: ["eth0", "eth1"]
+	Ifaces []string `json:"ifaces,omitempty"`
+	// DryRun: denotes whether the data that would be re-encoded is only reported, without modifying
+	// the goDB. Example: true
+	DryRun bool `json:"dry_run,omitempty"`
+	// Force: denotes whether all data is re-encoded, even if it is already stored with the configured
+	// encoder (e.g. to apply a changed compression level). Example: false
+	Force bool `json:"force,omitempty"`
+}
+
+// MigrateResponse is the response to a migrate request
+type MigrateResponse struct {
+	response
+	// DryRun: denotes whether the goDB was left untouched. Example: true
+	DryRun bool `json:"dry_run"`
+	// Ifaces: stores the outcome for each migrated interface
+	Ifaces migrate.Results `json:"ifaces"`
+}
+
+// WriteoutRoute is the route to trigger an immediate (out-of-cycle) writeout of all (or a set of) interfaces
+const WriteoutRoute = "/writeout"
+
+// WriteoutResponse is the response to a writeout request
+type WriteoutResponse struct {
+	response
+	// Timestamp: denotes the timestamp of the writeout. Example: "2021-01-01T00:03:17Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Ifaces: stores the number of flows written for each interface
+	Ifaces []capturetypes.WriteoutResult `json:"ifaces"`
+}
+
+// ErrorDumpsRoute is the route to retrieve the dumps of packets that could not be parsed (per interface)
+const ErrorDumpsRoute = "/_errordumps"
+
+// ErrorDumpsResponse is the response to an error dump listing
+type ErrorDumpsResponse struct {
+	response
+	// Iface: denotes the interface the dumps were captured on. Example: "eth0"
+	Iface string `json:"iface"`
+	// Dumps: stores the metadata of all retained dumps (oldest first)
+	Dumps []capturetypes.ErrorDump `json:"dumps"`
+}
+
+// CapabilitiesRoute is the route to retrieve the capabilities of the NICs backing all capturing interfaces
+// or to probe / validate an individual interface on demand (even if not capturing on it yet)
+const CapabilitiesRoute = "/capabilities"
+
+// CapabilitiesResponse is the response to a capabilities query
+type CapabilitiesResponse struct {
+	response
+	// Ifaces: stores the capabilities of the NIC backing each interface (including hints on settings
+	// distorting flow accounting)
+	Ifaces map[string]capturetypes.IfaceCapabilities `json:"ifaces"`
 }
 
-// ConfigUpdateRequest is the payload to update the configuration of all
-// interfaces stored in it
-type ConfigUpdateRequest config.Ifaces
+// RotationsRoute is the route to retrieve the most recent rotations retained in memory
+const RotationsRoute = "/rotations"
+
+// LastQueryParam is the query parameter to specify the number of rotations returned per interface
+const LastQueryParam = "last"
+
+// RotationsResponse is the response to a rotations query
+type RotationsResponse struct {
+	response
+	// Rotations: stores the most recent rotations of each interface (oldest first)
+	Rotations []capturetypes.Rotation `json:"rotations"`
+}
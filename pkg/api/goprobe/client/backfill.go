@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/httpc"
+)
+
+// Backfill re-writes / backfills an interval of an interface, either from the writeouts retained in
+// goprobe's spill buffer or from a pcap file located on the goprobe host (c.f. gpapi.BackfillRequest)
+func (c *Client) Backfill(ctx context.Context, backfillReq *gpapi.BackfillRequest) ([]capturetypes.BackfillResult, error) {
+	var res = new(gpapi.BackfillResponse)
+
+	url := c.NewURL(gpapi.BackfillRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", url, c.Client()).
+			EncodeJSON(backfillReq).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Blocks, nil
+}
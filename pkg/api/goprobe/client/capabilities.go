@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/httpc"
+)
+
+// Capabilities returns the capabilities of the NICs backing all interfaces the running goProbe instance
+// captures on. If an interface is provided, it is probed on demand instead (even if goProbe does not
+// capture on it yet)
+func (c *Client) Capabilities(ctx context.Context, iface string) (map[string]capturetypes.IfaceCapabilities, error) {
+	var res = new(gpapi.CapabilitiesResponse)
+
+	route := gpapi.CapabilitiesRoute
+	if iface != "" {
+		route += "/" + iface
+	}
+	url := c.NewURL(route)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Ifaces, nil
+}
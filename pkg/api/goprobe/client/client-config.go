@@ -14,6 +14,9 @@ type Config struct {
 	RequestTimeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 
 	Log bool `json:"log" yaml:"log"`
+
+	// BinaryResults requests query results in their compact binary representation (c.f. client.WithBinaryResults)
+	BinaryResults bool `json:"binary_results,omitempty" yaml:"binary_results,omitempty"`
 }
 
 var (
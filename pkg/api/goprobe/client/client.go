@@ -15,6 +15,10 @@ type Client struct {
 	*client.DefaultClient
 }
 
+// Error denotes an error response of goProbe's API, providing access to its HTTP status code. All
+// calls report responses with an unexpected status code as *Error
+type Error = client.Error
+
 const (
 	clientName = "goprobe-client"
 )
@@ -41,18 +45,20 @@ func New(addr string, opts ...client.Option) *Client {
 	}
 }
 
-// NewFromConfig creates the client based on cfg
-func NewFromConfig(cfg *Config) *Client {
+// NewFromConfig creates the client based on cfg. Additional options are applied on top of
+// the configuration
+func NewFromConfig(cfg *Config, opts ...client.Option) *Client {
 	if cfg == nil {
-		return New(gpapi.DefaultServerAddress)
+		return New(gpapi.DefaultServerAddress, opts...)
 	}
 
-	c := New(cfg.Addr,
+	c := New(cfg.Addr, append([]client.Option{
 		client.WithRequestLogging(cfg.Log),
 		client.WithRequestTimeout(cfg.RequestTimeout),
 		client.WithScheme(cfg.Scheme),
 		client.WithAPIKey(cfg.Key),
-	)
+		client.WithBinaryResults(cfg.BinaryResults),
+	}, opts...)...)
 
 	return c
 }
@@ -2,7 +2,7 @@ package client
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"strings"
 
 	"github.com/els0r/goProbe/cmd/goProbe/config"
@@ -29,33 +29,59 @@ func (c *Client) GetInterfaceConfig(ctx context.Context, ifaces ...string) (ifac
 	}
 	err = req.RunWithContext(ctx)
 	if err != nil {
-		if res.Error != "" {
-			err = fmt.Errorf("%d: %s", res.StatusCode, res.Error)
-		}
 		return nil, err
 	}
 	return res.Ifaces, nil
 }
 
-// UpdateInterfaceConfigs updates goprobe's runtime configuration for the provided interfaces
-func (c *Client) UpdateInterfaceConfigs(ctx context.Context, ifaceConfigs config.Ifaces) (enabled, updated, disabled capturetypes.IfaceChanges, err error) {
+// GetConfig returns goprobe's full runtime configuration (with all secrets redacted)
+func (c *Client) GetConfig(ctx context.Context) (*config.Config, error) {
+	var res = new(gpapi.ConfigResponse)
+
+	url := c.NewURL(gpapi.ConfigRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Config, nil
+}
+
+// PatchConfig applies a JSON merge patch (RFC 7396) to goprobe's runtime configuration and returns
+// the changes it caused
+func (c *Client) PatchConfig(ctx context.Context, patch []byte) (changes config.Changes, enabled, updated, disabled capturetypes.IfaceChanges, err error) {
 	var res = new(gpapi.ConfigUpdateResponse)
 
 	url := c.NewURL(gpapi.ConfigRoute)
 
 	req := c.Modify(ctx,
-		httpc.NewWithClient("PUT", url, c.Client()).
-			EncodeJSON(ifaceConfigs).
+		httpc.NewWithClient("PATCH", url, c.Client()).
+			EncodeJSON(json.RawMessage(patch)).
 			ParseJSON(res),
 	)
 	err = req.RunWithContext(ctx)
 	if err != nil {
-		if res.Error != "" {
-			err = fmt.Errorf("%d: %s", res.StatusCode, res.Error)
-		}
 		return
 	}
-	return res.Enabled, res.Updated, res.Disabled, nil
+	return res.Changes, res.Enabled, res.Updated, res.Disabled, nil
+}
+
+// UpdateInterfaceConfigs replaces goprobe's runtime configuration of all interfaces by the provided one
+// (removing all interfaces not part of it)
+func (c *Client) UpdateInterfaceConfigs(ctx context.Context, ifaceConfigs config.Ifaces) (changes config.Changes, enabled, updated, disabled capturetypes.IfaceChanges, err error) {
+	current, err := c.GetInterfaceConfig(ctx)
+	if err != nil {
+		return
+	}
+	patch, err := config.IfacesMergePatch(current, ifaceConfigs)
+	if err != nil {
+		return
+	}
+	return c.PatchConfig(ctx, patch)
 }
 
 // ReloadConfig reads / updates goprobe's runtime configuration with the one from disk
@@ -70,10 +96,23 @@ func (c *Client) ReloadConfig(ctx context.Context) (enabled, updated, disabled c
 	)
 	err = req.RunWithContext(ctx)
 	if err != nil {
-		if res.Error != "" {
-			err = fmt.Errorf("%d: %s", res.StatusCode, res.Error)
-		}
 		return
 	}
 	return res.Enabled, res.Updated, res.Disabled, nil
 }
+
+// GetConfigSchema returns the JSON Schema describing goprobe's full configuration
+func (c *Client) GetConfigSchema(ctx context.Context) (map[string]any, error) {
+	var res map[string]any
+
+	url := c.NewURL(gpapi.ConfigRoute + gpapi.ConfigSchemaRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			ParseJSON(&res),
+	)
+	if err := req.RunWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/httpc"
+)
+
+// ErrorDumps returns the metadata of all dumps of packets that could not be parsed retained by the
+// running goProbe instance for an interface (oldest first)
+func (c *Client) ErrorDumps(ctx context.Context, iface string) ([]capturetypes.ErrorDump, error) {
+	var res = new(gpapi.ErrorDumpsResponse)
+
+	url := c.NewURL(gpapi.ErrorDumpsRoute + "/" + iface)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Dumps, nil
+}
+
+// ErrorDump returns the raw (truncated) IP layer of a dump of a packet that could not be parsed
+func (c *Client) ErrorDump(ctx context.Context, iface string, id uint64) ([]byte, error) {
+	var payload []byte
+
+	url := c.NewURL(gpapi.ErrorDumpsRoute + "/" + iface + "/" + strconv.FormatUint(id, 10))
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			ParseFn(func(resp *http.Response) (err error) {
+				payload, err = io.ReadAll(resp.Body)
+				return err
+			}),
+	)
+	if err := req.RunWithContext(ctx); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/httpc"
+)
+
+// Ingest writes externally generated flow aggregates of an interface to goprobe's goDB (c.f. gpapi.IngestRequest).
+// The client has to present one of the API keys configured on the goprobe host
+func (c *Client) Ingest(ctx context.Context, ingestReq *gpapi.IngestRequest) (*capturetypes.BackfillResult, error) {
+	var res = new(gpapi.IngestResponse)
+
+	url := c.NewURL(gpapi.IngestRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", url, c.Client()).
+			EncodeJSON(ingestReq).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Block, nil
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/fako1024/httpc"
+)
+
+// Migrate re-encodes the data in goprobe's database with the encoders currently configured for the
+// respective interfaces (c.f. gpapi.MigrateRequest)
+func (c *Client) Migrate(ctx context.Context, migrateReq *gpapi.MigrateRequest) (*gpapi.MigrateResponse, error) {
+	var res = new(gpapi.MigrateResponse)
+
+	url := c.NewURL(gpapi.MigrateRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", url, c.Client()).
+			EncodeJSON(migrateReq).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/httpc"
+)
+
+// Rotations returns the (up to) last n rotations of all (or a set of) interfaces retained in memory by
+// the running goProbe instance, oldest first. If n is zero, all retained rotations are returned
+func (c *Client) Rotations(ctx context.Context, n int, ifaces ...string) ([]capturetypes.Rotation, error) {
+	var res = new(gpapi.RotationsResponse)
+
+	url := c.NewURL(addIfaceToPath(gpapi.RotationsRoute, ifaces...))
+
+	params := httpc.Params{}
+	if n > 0 {
+		params[gpapi.LastQueryParam] = strconv.Itoa(n)
+	}
+	if len(ifaces) > 1 {
+		params[gpapi.IfacesQueryParam] = strings.Join(ifaces, ",")
+	}
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("GET", url, c.Client()).
+			QueryParams(params).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Rotations, nil
+}
+
+// RotationStream iterates over the rotations of the running goProbe instance as they occur (c.f.
+// Client.StreamRotations). It is NOT threadsafe
+type RotationStream struct {
+	client   *Client
+	ifaces   []string
+	interval time.Duration
+
+	polled  bool
+	last    map[string]time.Time // timestamp of the most recent rotation seen per interface
+	pending []capturetypes.Rotation
+	cur     capturetypes.Rotation
+	err     error
+}
+
+// StreamRotations returns an iterator over all rotations of all (or a set of) interfaces performed by
+// the running goProbe instance after the first call to Next(). Since the rotations are retrieved by
+// polling every interval, the interval should be well below the rotation interval times the number of
+// rotations retained in memory (otherwise rotations may be missed). Example:
+//
+//	stream := c.StreamRotations(10*time.Second, "eth0")
+//	for stream.Next(ctx) {
+//		fmt.Println(stream.Rotation().NumFlows)
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+func (c *Client) StreamRotations(interval time.Duration, ifaces ...string) *RotationStream {
+	return &RotationStream{
+		client:   c,
+		ifaces:   ifaces,
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// Next blocks until the next rotation is available, returning false once the stream ended (either
+// because ctx was cancelled or the rotations could not be retrieved, c.f. Err())
+func (s *RotationStream) Next(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+
+	for len(s.pending) == 0 {
+		if s.polled {
+			timer := time.NewTimer(s.interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				s.err = ctx.Err()
+				return false
+			case <-timer.C:
+			}
+		}
+		if s.err = s.poll(ctx); s.err != nil {
+			return false
+		}
+	}
+
+	s.cur, s.pending = s.pending[0], s.pending[1:]
+	return true
+}
+
+// Rotation returns the current rotation of the stream (as advanced by Next())
+func (s *RotationStream) Rotation() capturetypes.Rotation {
+	return s.cur
+}
+
+// Err returns the error that ended the stream (if any)
+func (s *RotationStream) Err() error {
+	return s.err
+}
+
+// poll retrieves all rotations not seen before. The rotations retained upon the first poll are
+// considered to precede the stream and hence skipped
+func (s *RotationStream) poll(ctx context.Context) error {
+	rotations, err := s.client.Rotations(ctx, 0, s.ifaces...)
+	if err != nil {
+		return err
+	}
+
+	for _, rotation := range rotations {
+		if !rotation.Timestamp.After(s.last[rotation.Iface]) {
+			continue
+		}
+		s.last[rotation.Iface] = rotation.Timestamp
+		if s.polled {
+			s.pending = append(s.pending, rotation)
+		}
+	}
+	sort.SliceStable(s.pending, func(i, j int) bool {
+		return s.pending[i].Timestamp.Before(s.pending[j].Timestamp)
+	})
+	s.polled = true
+
+	return nil
+}
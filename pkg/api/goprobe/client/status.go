@@ -2,7 +2,6 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
@@ -13,6 +12,17 @@ import (
 
 // GetInterfaceStatus returns the interface capture stats from the running goProbe instance
 func (c *Client) GetInterfaceStatus(ctx context.Context, ifaces ...string) (statuses map[string]capturetypes.CaptureStats, lastWriteout time.Time, startedAt time.Time, err error) {
+	res, err := c.Status(ctx, ifaces...)
+	if err != nil {
+		return nil, lastWriteout, startedAt, err
+	}
+
+	return res.Statuses, res.LastWriteout, res.StartedAt, nil
+}
+
+// Status returns the full status of the running goProbe instance for all (or a set of) interfaces,
+// including the writeout backlog as well as the cardinality, quota and capabilities of each interface
+func (c *Client) Status(ctx context.Context, ifaces ...string) (*gpapi.StatusResponse, error) {
 	var res = new(gpapi.StatusResponse)
 
 	url := c.NewURL(addIfaceToPath(gpapi.StatusRoute, ifaces...))
@@ -26,13 +36,10 @@ func (c *Client) GetInterfaceStatus(ctx context.Context, ifaces ...string) (stat
 			gpapi.IfacesQueryParam: strings.Join(ifaces, ","),
 		})
 	}
-	err = req.RunWithContext(ctx)
+	err := req.RunWithContext(ctx)
 	if err != nil {
-		if res.Error != "" {
-			err = fmt.Errorf("%d: %s", res.StatusCode, res.Error)
-		}
-		return nil, lastWriteout, startedAt, err
+		return nil, err
 	}
 
-	return res.Statuses, res.LastWriteout, res.StartedAt, nil
+	return res, nil
 }
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/fako1024/httpc"
+)
+
+// Vacuum reclaims disk space from goprobe's database by removing the data of interfaces no longer part
+// of its configuration and compacting all remaining data (c.f. gpapi.VacuumRequest)
+func (c *Client) Vacuum(ctx context.Context, vacuumReq *gpapi.VacuumRequest) (*gpapi.VacuumResponse, error) {
+	var res = new(gpapi.VacuumResponse)
+
+	url := c.NewURL(gpapi.VacuumRoute)
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", url, c.Client()).
+			EncodeJSON(vacuumReq).
+			ParseJSON(res),
+	)
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/fako1024/httpc"
+)
+
+// Writeout triggers an immediate (out-of-cycle) rotation and writeout of all (or a set of) interfaces
+// of the running goProbe instance, returning the number of flows written for each of them
+func (c *Client) Writeout(ctx context.Context, ifaces ...string) (*gpapi.WriteoutResponse, error) {
+	var res = new(gpapi.WriteoutResponse)
+
+	url := c.NewURL(addIfaceToPath(gpapi.WriteoutRoute, ifaces...))
+
+	req := c.Modify(ctx,
+		httpc.NewWithClient("POST", url, c.Client()).
+			ParseJSON(res),
+	)
+	if len(ifaces) > 1 {
+		req = req.QueryParams(httpc.Params{
+			gpapi.IfacesQueryParam: strings.Join(ifaces, ","),
+		})
+	}
+	err := req.RunWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
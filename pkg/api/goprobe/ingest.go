@@ -0,0 +1,110 @@
+package goprobe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+)
+
+// IngestRoute is the route to write externally generated flow aggregates of an interface to the goDB
+const IngestRoute = "/ingest"
+
+// DefaultMaxIngestBodySize denotes the default maximum size of an ingest request (in bytes)
+const DefaultMaxIngestBodySize = 64 << 20 // 64 MiB
+
+var (
+	// ErrNoIngestFlows denotes that an ingest request does not contain any flows
+	ErrNoIngestFlows = errors.New("no flows provided")
+
+	// ErrInvalidIngestFlow denotes that a flow of an ingest request cannot be written to the goDB
+	ErrInvalidIngestFlow = errors.New("invalid flow")
+)
+
+// IngestRequest is the payload to write externally generated flow aggregates of an interface to the goDB.
+// The flows are attributed to the interval [from, to] and written as a single block with the timestamp of
+// its end, replacing any block already present for the same timestamp
+type IngestRequest struct {
+	// Iface: denotes the interface the flows are attributed to. Example: "eth0"
+	Iface string `json:"iface"`
+	// From: denotes the start of the interval the flows were observed in. Example: "2021-01-01T00:00:00Z"
+	From time.Time `json:"from"`
+	// To: denotes the end of the interval the flows were observed in. Example: "2021-01-01T00:05:00Z"
+	To time.Time `json:"to"`
+	// Flows: stores the flow aggregates to write, using the same format as the rows of a query result
+	// (flows with identical attributes are merged)
+	Flows []IngestFlow `json:"flows"`
+}
+
+// IngestFlow denotes a single flow aggregate of an ingest request
+type IngestFlow struct {
+	// Attributes: denotes the attributes of the flow. Both IP addresses are required and must be of
+	// the same family, tags are not supported
+	Attributes results.Attributes `json:"attributes"`
+	// Counters: stores the bytes / packets counters of the flow
+	Counters types.Counters `json:"counters"`
+}
+
+// IngestResponse is the response to an ingest request
+type IngestResponse struct {
+	response
+	// Iface: denotes the interface the flows were written for. Example: "eth0"
+	Iface string `json:"iface"`
+	// Block: stores the outcome for the written block
+	Block *capturetypes.BackfillResult `json:"block,omitempty"`
+}
+
+// FlowMap validates the flows of the request and converts them into a flow map, as if they had been
+// captured on the interface during a single rotation
+func (r *IngestRequest) FlowMap() (*hashmap.AggFlowMap, error) {
+	if len(r.Flows) == 0 {
+		return nil, ErrNoIngestFlows
+	}
+
+	flows := hashmap.NewAggFlowMap()
+	for i, flow := range r.Flows {
+		key, err := flow.key()
+		if err != nil {
+			return nil, fmt.Errorf("%w %d: %w", ErrInvalidIngestFlow, i, err)
+		}
+		flows.SetOrUpdate(key, key.IsIPv4(),
+			flow.Counters.BytesRcvd, flow.Counters.BytesSent,
+			flow.Counters.PacketsRcvd, flow.Counters.PacketsSent,
+		)
+	}
+
+	return flows, nil
+}
+
+func (f IngestFlow) key() (types.Key, error) {
+	attr := f.Attributes
+	if !attr.SrcIP.IsValid() || !attr.DstIP.IsValid() {
+		return nil, errors.New("source and destination IP are required")
+	}
+	sip, dip := attr.SrcIP.Unmap(), attr.DstIP.Unmap()
+	if sip.Is4() != dip.Is4() {
+		return nil, errors.New("source and destination IP must be of the same family")
+	}
+	if attr.Tag != "" {
+		return nil, fmt.Errorf("tags are not supported: %s", attr.Tag)
+	}
+	if attr.TTLMin > attr.TTLMax {
+		return nil, fmt.Errorf("minimum TTL %d exceeds maximum TTL %d", attr.TTLMin, attr.TTLMax)
+	}
+
+	dport := make([]byte, types.DportSizeof)
+	binary.BigEndian.PutUint16(dport, attr.DstPort)
+
+	key := types.NewKey(sip.AsSlice(), dip.AsSlice(), dport, attr.IPProto)
+	if attr.TTLMax > 0 {
+		key.PutTTLMin(attr.TTLMin)
+		key.PutTTLMax(attr.TTLMax)
+	}
+
+	return key, nil
+}
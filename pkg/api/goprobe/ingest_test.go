@@ -0,0 +1,61 @@
+package goprobe
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestFlowMap(t *testing.T) {
+	var req IngestRequest
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"iface": "eth0",
+		"from": "2024-01-01T00:00:00Z",
+		"to": "2024-01-01T00:05:00Z",
+		"flows": [
+			{"attributes": {"sip": "10.0.0.1", "dip": "10.0.0.2", "dport": 443, "proto": 6}, "counters": {"br": 200, "bs": 100, "pr": 2, "ps": 1}},
+			{"attributes": {"sip": "10.0.0.1", "dip": "10.0.0.2", "dport": 443, "proto": 6}, "counters": {"br": 50, "pr": 1}},
+			{"attributes": {"sip": "2001:db8::1", "dip": "2001:db8::2", "dport": 53, "proto": 17, "ttl_min": 58, "ttl_max": 64}, "counters": {"bs": 80, "ps": 1}}
+		]
+	}`), &req))
+
+	flows, err := req.FlowMap()
+	require.Nil(t, err)
+	require.Equal(t, 2, flows.Len())
+
+	// flows with identical attributes are merged
+	val, exists := flows.PrimaryMap.Get(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, []byte{1, 187}, 6))
+	require.True(t, exists)
+	require.Equal(t, types.Counters{BytesRcvd: 250, BytesSent: 100, PacketsRcvd: 3, PacketsSent: 1}, val)
+
+	for i := flows.SecondaryMap.Iter(); i.Next(); {
+		require.Equal(t, byte(58), types.Key(i.Key()).GetTTLMin())
+		require.Equal(t, byte(64), types.Key(i.Key()).GetTTLMax())
+	}
+}
+
+func TestIngestFlowMapInvalid(t *testing.T) {
+	var tests = []struct {
+		name  string
+		flows string
+	}{
+		{"no flows", `[]`},
+		{"missing IP", `[{"attributes": {"sip": "10.0.0.1", "dport": 80, "proto": 6}}]`},
+		{"mixed families", `[{"attributes": {"sip": "10.0.0.1", "dip": "2001:db8::2", "dport": 80, "proto": 6}}]`},
+		{"tag", `[{"attributes": {"sip": "10.0.0.1", "dip": "10.0.0.2", "dport": 80, "proto": 6, "tag": "voip"}}]`},
+		{"ttl range", `[{"attributes": {"sip": "10.0.0.1", "dip": "10.0.0.2", "dport": 80, "proto": 6, "ttl_min": 64, "ttl_max": 58}}]`},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := IngestRequest{Iface: "eth0"}
+			require.Nil(t, json.Unmarshal([]byte(test.flows), &req.Flows))
+
+			_, err := req.FlowMap()
+			require.NotNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/gin-gonic/gin"
+)
+
+var errNoBackfillIface = errors.New("no interface specified")
+
+func (server *Server) postBackfill(c *gin.Context) {
+	resp := &gpapi.BackfillResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var req gpapi.BackfillRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	if req.Iface == "" {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = errNoBackfillIface.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Iface = req.Iface
+
+	resp.Blocks, err = server.captureManager.Backfill(c.Request.Context(), req.Iface, req.From, req.To, req.Pcap)
+	if err != nil {
+		switch {
+		case errors.Is(err, writeout.ErrInvalidInterval):
+			resp.StatusCode = http.StatusBadRequest
+		case errors.Is(err, writeout.ErrNoSpilledWriteouts):
+			resp.StatusCode = http.StatusNotFound
+		case errors.Is(err, capture.ErrBackfillNotSupported):
+			resp.StatusCode = http.StatusNotImplemented
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
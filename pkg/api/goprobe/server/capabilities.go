@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/nicprobe"
+	"github.com/gin-gonic/gin"
+)
+
+func (server *Server) getCapabilities(c *gin.Context) {
+	iface := c.Param(ifaceKey)
+
+	resp := &gpapi.CapabilitiesResponse{}
+	resp.StatusCode = http.StatusOK
+
+	// without an interface, the capabilities probed upon initialization of all captures are provided
+	if iface == "" {
+		resp.Ifaces = server.captureManager.Capabilities()
+		if len(resp.Ifaces) == 0 {
+			resp.StatusCode = http.StatusNoContent
+		}
+
+		c.JSON(resp.StatusCode, resp)
+		return
+	}
+
+	caps, err := server.captureManager.ProbeCapabilities(iface)
+	if err != nil {
+		resp.StatusCode = capabilitiesStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Ifaces = map[string]capturetypes.IfaceCapabilities{
+		iface: caps,
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func capabilitiesStatusCode(err error) int {
+	switch {
+	case errors.Is(err, nicprobe.ErrUnknownIface):
+		return http.StatusNotFound
+	case errors.Is(err, capture.ErrInvalidIfaceName):
+		return http.StatusBadRequest
+	case errors.Is(err, nicprobe.ErrNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
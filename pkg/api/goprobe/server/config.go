@@ -1,11 +1,14 @@
 package server
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/api"
 	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
 	"github.com/gin-gonic/gin"
 )
@@ -34,8 +37,16 @@ func (server *Server) getConfig(c *gin.Context) {
 			// fetch all specified
 			resp.Ifaces = server.captureManager.Config(strings.Split(ifaces, ",")...)
 		} else {
-			// otherwise, fetch all
+			// otherwise, fetch all (including the full configuration document)
 			resp.Ifaces = server.captureManager.Config()
+			resp.Config, err = server.configMonitor.GetConfig().Redacted()
+			if err != nil {
+				resp.StatusCode = http.StatusInternalServerError
+				resp.Error = err.Error()
+
+				c.AbortWithStatusJSON(resp.StatusCode, resp)
+				return
+			}
 		}
 	}
 
@@ -46,36 +57,26 @@ func (server *Server) getConfig(c *gin.Context) {
 	c.JSON(resp.StatusCode, resp)
 }
 
-func (server *Server) putConfig(c *gin.Context) {
+func (server *Server) patchConfig(c *gin.Context) {
 	resp := &gpapi.ConfigUpdateResponse{}
 	resp.StatusCode = http.StatusOK
 
-	// de-serialize the configuration
-	var ifaceConfigs config.Ifaces
-
-	err := c.BindJSON(&ifaceConfigs)
+	// the size of the patch is limited by the body size limit of the route
+	patch, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		resp.StatusCode = http.StatusBadRequest
+		resp.StatusCode = api.RequestBodyErrorStatus(err)
 		resp.Error = err.Error()
 
 		c.AbortWithStatusJSON(resp.StatusCode, resp)
 		return
 	}
 
-	// validate config before processing it
-	err = ifaceConfigs.Validate()
-	if err != nil {
-		resp.StatusCode = http.StatusBadRequest
-		resp.Error = err.Error()
-
-		c.AbortWithStatusJSON(resp.StatusCode, resp)
-		return
-	}
-
-	// update the captures
-	server.configMonitor.PutIfaceConfig(ifaceConfigs)
-	if resp.Enabled, resp.Updated, resp.Disabled, err = server.configMonitor.Apply(c.Request.Context(), server.captureManager.Update); err != nil {
-		resp.StatusCode = http.StatusBadRequest
+	// apply the patch (validating the resulting configuration) and update the captures
+	if resp.Changes, resp.Enabled, resp.Updated, resp.Disabled, err = server.configMonitor.Patch(c.Request.Context(), patch, server.captureManager.Update); err != nil {
+		resp.StatusCode = http.StatusInternalServerError
+		if errors.Is(err, config.ErrInvalidPatch) {
+			resp.StatusCode = http.StatusBadRequest
+		}
 		resp.Error = err.Error()
 
 		c.AbortWithStatusJSON(resp.StatusCode, resp)
@@ -101,3 +102,7 @@ func (server *Server) reloadConfig(c *gin.Context) {
 
 	c.JSON(resp.StatusCode, resp)
 }
+
+func (server *Server) getConfigSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, config.Schema())
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/gin-gonic/gin"
+)
+
+const errorDumpIDKey = "id"
+
+func (server *Server) getErrorDumps(c *gin.Context) {
+	iface := c.Param(ifaceKey)
+
+	resp := &gpapi.ErrorDumpsResponse{
+		Iface: iface,
+	}
+	resp.StatusCode = http.StatusOK
+
+	var err error
+	resp.Dumps, err = server.captureManager.ErrorDumps(iface)
+	if err != nil {
+		resp.StatusCode = errorDumpStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
+
+func (server *Server) getErrorDump(c *gin.Context) {
+	iface := c.Param(ifaceKey)
+
+	resp := &gpapi.ErrorDumpsResponse{
+		Iface: iface,
+	}
+
+	id, err := strconv.ParseUint(c.Param(errorDumpIDKey), 10, 64)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	payload, err := server.captureManager.ErrorDumpPayload(iface, id)
+	if err != nil {
+		resp.StatusCode = errorDumpStatusCode(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", payload)
+}
+
+func errorDumpStatusCode(err error) int {
+	switch {
+	case errors.Is(err, capture.ErrErrorDumpsDisabled), errors.Is(err, capture.ErrErrorDumpNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, capture.ErrInvalidIfaceName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
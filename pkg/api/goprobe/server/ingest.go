@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/api"
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/gin-gonic/gin"
+)
+
+var errNoIngestInterval = errors.New("no interval end specified")
+
+func (server *Server) postIngest(c *gin.Context) {
+	resp := &gpapi.IngestResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var req gpapi.IngestRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		resp.StatusCode = api.RequestBodyErrorStatus(err)
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Iface = req.Iface
+
+	if req.To.IsZero() {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = errNoIngestInterval.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	flows, err := req.FlowMap()
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	block, err := server.captureManager.Ingest(c.Request.Context(), req.Iface, req.From, req.To, flows)
+	if err != nil {
+		switch {
+		case errors.Is(err, writeout.ErrInvalidInterval), errors.Is(err, capture.ErrIngestInFuture),
+			errors.Is(err, capture.ErrInvalidIfaceName):
+			resp.StatusCode = http.StatusBadRequest
+		case errors.Is(err, capture.ErrBackfillNotSupported):
+			resp.StatusCode = http.StatusNotImplemented
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.Block = &block
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/gin-gonic/gin"
+)
+
+func (server *Server) postMigrate(c *gin.Context) {
+	resp := &gpapi.MigrateResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var req gpapi.MigrateRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+	resp.DryRun = req.DryRun
+
+	resp.Ifaces, err = server.captureManager.Migrate(c.Request.Context(), req.DryRun, req.Force, req.Ifaces...)
+	if err != nil {
+		switch {
+		case errors.Is(err, capture.ErrMigrateNotSupported):
+			resp.StatusCode = http.StatusNotImplemented
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -5,15 +5,30 @@ import (
 
 	"github.com/els0r/goProbe/pkg/api"
 	"github.com/els0r/goProbe/pkg/goDB/engine"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/audit"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
 	"github.com/els0r/goProbe/pkg/version"
 	"github.com/gin-gonic/gin"
 )
 
 func (server *Server) postQuery(c *gin.Context) {
+	var runner query.Runner = engine.NewQueryRunnerWithLiveData(server.dbPath, server.captureManager).WithMmap(server.queryMmap)
+	if server.queryCgroups != nil {
+		// live data is only available to the capturing process itself, hence live queries are run in-process
+		runner = cgroup.NewRunner(server.queryCgroups.group(c.Request), server.queryCgroups.command...).WithInProcess(runner)
+	}
+	if auditLog, hasAuditLog := server.QueryAuditLog(); hasAuditLog {
+		runner = audit.NewRunner(runner, auditLog)
+	}
+	if queryStats, hasQueryStats := server.QueryStats(); hasQueryStats {
+		runner = queryStats.Runner(runner)
+	}
+
 	api.RunQuery(
 		fmt.Sprintf("goProbe/%s", version.Short()),
 		"local DB",
-		engine.NewQueryRunnerWithLiveData(server.dbPath, server.captureManager),
+		runner,
 		c,
 	)
 }
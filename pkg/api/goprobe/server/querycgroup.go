@@ -0,0 +1,70 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/query/cgroup"
+)
+
+// queryCgroups denotes the control groups queries are run in (by means of worker processes)
+type queryCgroups struct {
+	command []string
+
+	def    *cgroup.Group
+	tokens []tokenCgroup
+}
+
+type tokenCgroup struct {
+	key   string
+	group *cgroup.Group
+}
+
+// group returns the group the query of a request is run in, depending on the API key it presents
+func (q *queryCgroups) group(req *http.Request) *cgroup.Group {
+	if key := api.APIKey(req); key != "" {
+		for _, token := range q.tokens {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token.key)) == 1 {
+				return token.group
+			}
+		}
+	}
+	return q.def
+}
+
+// SetQueryCgroup runs all queries in worker processes (executed via command) confined to the control
+// group described by cfg, creating it (and a child group per token plus one for all other queries) if
+// required
+func (server *Server) SetQueryCgroup(cfg *config.QueryCgroupConfig, command ...string) error {
+	parent, err := cgroup.Create(cfg.Path, cfg.Limits())
+	if err != nil {
+		return err
+	}
+
+	// since a group with children cannot hold any processes itself, queries without a token are run
+	// in a dedicated child group as well
+	def, err := parent.Child(cgroup.DefaultName, cgroup.Limits{})
+	if err != nil {
+		return err
+	}
+	cgroups := &queryCgroups{
+		command: command,
+		def:     def,
+	}
+	for name, token := range cfg.Tokens {
+		group, err := parent.Child(name, token.Limits())
+		if err != nil {
+			return fmt.Errorf("failed to create control group of token %s: %w", name, err)
+		}
+		cgroups.tokens = append(cgroups.tokens, tokenCgroup{
+			key:   token.Key,
+			group: group,
+		})
+	}
+
+	server.queryCgroups = cgroups
+	return nil
+}
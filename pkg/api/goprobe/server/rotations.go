@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/gin-gonic/gin"
+)
+
+func (server *Server) getRotations(c *gin.Context) {
+	iface := c.Param(ifaceKey)
+	query := c.Request.URL.Query()
+
+	resp := &gpapi.RotationsResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var (
+		last int
+		err  error
+	)
+	if lastParam := query.Get(gpapi.LastQueryParam); lastParam != "" {
+		last, err = strconv.Atoi(lastParam)
+		if err == nil && last <= 0 {
+			err = fmt.Errorf("number of rotations must be positive, got %d", last)
+		}
+		if err != nil {
+			resp.StatusCode = http.StatusBadRequest
+			resp.Error = err.Error()
+
+			c.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+	}
+
+	var ifaceList []string
+	if iface != "" {
+		ifaceList = []string{iface}
+	} else if ifaces := query.Get(gpapi.IfacesQueryParam); ifaces != "" {
+		ifaceList = strings.Split(ifaces, ",")
+	}
+
+	resp.Rotations, err = server.captureManager.Rotations(last, ifaceList...)
+	if err != nil {
+		switch {
+		case errors.Is(err, capture.ErrRotationHistoryDisabled):
+			resp.StatusCode = http.StatusNotFound
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -15,6 +15,8 @@ type Server struct {
 
 	// goprobe specific variables
 	dbPath         string
+	queryMmap      bool
+	queryCgroups   *queryCgroups
 	captureManager *capture.Manager
 	configMonitor  *config.Monitor
 
@@ -27,8 +29,21 @@ func (server *Server) SetDBPath(path string) *Server {
 	return server
 }
 
+// SetQueryMmap enables / disables memory-mapped access to the database for all queries
+func (server *Server) SetQueryMmap(enable bool) *Server {
+	server.queryMmap = enable
+	return server
+}
+
 // New creates a new goprobe API server
 func New(addr string, captureManager *capture.Manager, configMonitor *config.Monitor, opts ...server.Option) *Server {
+
+	// the routes accepting larger request bodies have dedicated limits (which can be overridden via opts)
+	opts = append([]server.Option{
+		server.WithRouteMaxBodySize(gpapi.ConfigRoute, gpapi.DefaultMaxConfigBodySize),
+		server.WithRouteMaxBodySize(gpapi.IngestRoute, gpapi.DefaultMaxIngestBodySize),
+	}, opts...)
+
 	server := &Server{
 		dbPath:         defaults.DBPath,
 		captureManager: captureManager,
@@ -54,6 +69,13 @@ func (server *Server) registerRoutes() {
 	router.GET(api.QueryRoute, queryHandlers...)  // support for URL-encoded form data GET requests
 	router.POST(api.QueryRoute, queryHandlers...) // support for JSON or form-data body POST requests
 
+	// versioned query routes, rendering the result in the schema of the respective version
+	for _, version := range api.APIVersions {
+		versionedHandlers := append(gin.HandlersChain{api.APIVersionMiddleware(version)}, queryHandlers...)
+		router.GET(version.Route(api.QueryRoute), versionedHandlers...)
+		router.POST(version.Route(api.QueryRoute), versionedHandlers...)
+	}
+
 	// stats
 	statsRoutes := router.Group(gpapi.StatusRoute)
 	statsRoutes.GET("", server.getStatus)
@@ -62,7 +84,40 @@ func (server *Server) registerRoutes() {
 	// config
 	configRoutes := router.Group(gpapi.ConfigRoute)
 	configRoutes.GET("", server.getConfig)
+	configRoutes.GET(gpapi.ConfigSchemaRoute, server.getConfigSchema)
 	configRoutes.GET("/:"+ifaceKey, server.getConfig)
-	configRoutes.PUT("", server.putConfig)
+	configRoutes.PATCH("", server.patchConfig)
 	configRoutes.POST(gpapi.ConfigReloadRoute, server.reloadConfig)
+
+	// backfill (writes to the goDB, hence requires authentication)
+	router.POST(gpapi.BackfillRoute, server.Authorized(), server.postBackfill)
+
+	// ingest (writes to the goDB on behalf of external collectors, hence requires authentication)
+	router.POST(gpapi.IngestRoute, server.Authorized(), server.postIngest)
+
+	// vacuum (deletes data from the goDB, hence requires authentication)
+	router.POST(gpapi.VacuumRoute, server.Authorized(), server.postVacuum)
+
+	// migrate (re-encodes data with the configured encoders, hence requires authentication)
+	router.POST(gpapi.MigrateRoute, server.Authorized(), server.postMigrate)
+
+	// writeout
+	writeoutRoutes := router.Group(gpapi.WriteoutRoute)
+	writeoutRoutes.POST("", server.postWriteout)
+	writeoutRoutes.POST("/:"+ifaceKey, server.postWriteout)
+
+	// error dumps
+	errorDumpRoutes := router.Group(gpapi.ErrorDumpsRoute)
+	errorDumpRoutes.GET("/:"+ifaceKey, server.getErrorDumps)
+	errorDumpRoutes.GET("/:"+ifaceKey+"/:"+errorDumpIDKey, server.getErrorDump)
+
+	// interface capabilities
+	capabilitiesRoutes := router.Group(gpapi.CapabilitiesRoute)
+	capabilitiesRoutes.GET("", server.getCapabilities)
+	capabilitiesRoutes.GET("/:"+ifaceKey, server.getCapabilities)
+
+	// rotation history
+	rotationsRoutes := router.Group(gpapi.RotationsRoute)
+	rotationsRoutes.GET("", server.getRotations)
+	rotationsRoutes.GET("/:"+ifaceKey, server.getRotations)
 }
@@ -16,6 +16,9 @@ func (server *Server) getStatus(c *gin.Context) {
 	resp := &gpapi.StatusResponse{}
 	resp.StatusCode = http.StatusOK
 	resp.StartedAt, resp.LastWriteout = server.captureManager.GetTimestamps()
+	if writeoutStats, ok := server.captureManager.WriteoutStats(); ok {
+		resp.Writeout = &writeoutStats
+	}
 
 	var err error
 	ifaces, err = url.QueryUnescape(ifaces)
@@ -31,17 +34,33 @@ func (server *Server) getStatus(c *gin.Context) {
 
 	if iface != "" {
 		resp.Statuses = server.captureManager.Status(ctx, iface)
+		resp.Cardinality = server.captureManager.CardinalityStats(iface)
+		resp.Quota = server.captureManager.QuotaStats(iface)
+		resp.Capabilities = server.captureManager.Capabilities(iface)
+		resp.Bench = server.captureManager.BenchAssessments(iface)
+		resp.Errors = server.captureManager.IfaceErrors(iface)
 	} else {
 		if ifaces != "" {
 			// fetch all specified
 			resp.Statuses = server.captureManager.Status(ctx, strings.Split(ifaces, ",")...)
+			resp.Cardinality = server.captureManager.CardinalityStats(strings.Split(ifaces, ",")...)
+			resp.Quota = server.captureManager.QuotaStats(strings.Split(ifaces, ",")...)
+			resp.Capabilities = server.captureManager.Capabilities(strings.Split(ifaces, ",")...)
+			resp.Bench = server.captureManager.BenchAssessments(strings.Split(ifaces, ",")...)
+			resp.Errors = server.captureManager.IfaceErrors(strings.Split(ifaces, ",")...)
 		} else {
 			// otherwise, fetch all
 			resp.Statuses = server.captureManager.Status(ctx)
+			resp.Cardinality = server.captureManager.CardinalityStats()
+			resp.Quota = server.captureManager.QuotaStats()
+			resp.Capabilities = server.captureManager.Capabilities()
+			resp.Bench = server.captureManager.BenchAssessments()
+			resp.Errors = server.captureManager.IfaceErrors()
 		}
 	}
 
-	if len(resp.Statuses) == 0 {
+	// interfaces in an error state are reported even if no interface is capturing
+	if len(resp.Statuses) == 0 && len(resp.Errors) == 0 {
 		resp.StatusCode = http.StatusNoContent
 	}
 
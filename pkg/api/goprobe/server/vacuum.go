@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
+	"github.com/gin-gonic/gin"
+)
+
+func (server *Server) postVacuum(c *gin.Context) {
+	resp := &gpapi.VacuumResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var req gpapi.VacuumRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	minAge := vacuum.DefaultMinAge
+	if req.MinAge != "" {
+		minAge, err = time.ParseDuration(req.MinAge)
+		if err == nil && minAge < 0 {
+			err = vacuum.ErrNegativeMinAge
+		}
+		if err != nil {
+			resp.StatusCode = http.StatusBadRequest
+			resp.Error = fmt.Sprintf("invalid minimum age: %s", err)
+
+			c.AbortWithStatusJSON(resp.StatusCode, resp)
+			return
+		}
+	}
+	resp.DryRun = req.DryRun
+
+	resp.Ifaces, err = server.captureManager.Vacuum(c.Request.Context(), req.DryRun, minAge)
+	if err != nil {
+		switch {
+		case errors.Is(err, capture.ErrVacuumNotSupported):
+			resp.StatusCode = http.StatusNotImplemented
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	gpapi "github.com/els0r/goProbe/pkg/api/goprobe"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/gin-gonic/gin"
+)
+
+func (server *Server) postWriteout(c *gin.Context) {
+	iface := c.Param(ifaceKey)
+	ifaces := c.Request.URL.Query().Get(gpapi.IfacesQueryParam)
+
+	resp := &gpapi.WriteoutResponse{}
+	resp.StatusCode = http.StatusOK
+
+	var err error
+	ifaces, err = url.QueryUnescape(ifaces)
+	if err != nil {
+		resp.StatusCode = http.StatusBadRequest
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	var ifaceList []string
+	if iface != "" {
+		ifaceList = []string{iface}
+	} else if ifaces != "" {
+		ifaceList = strings.Split(ifaces, ",")
+	}
+
+	resp.Timestamp, resp.Ifaces, err = server.captureManager.Flush(c.Request.Context(), ifaceList...)
+	if err != nil {
+		switch {
+		case errors.Is(err, capture.ErrIfaceNotCapturing):
+			resp.StatusCode = http.StatusNotFound
+		default:
+			resp.StatusCode = http.StatusInternalServerError
+		}
+		resp.Error = err.Error()
+
+		c.AbortWithStatusJSON(resp.StatusCode, resp)
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp)
+}
@@ -15,10 +15,13 @@ type ServiceInfo struct {
 	Version string `json:"version"`          // Version: (semantic) version and commit short.  Example: 4.0.0-824f5847
 	Commit  string `json:"commit,omitempty"` // Commit: full git commit SHA. Example: 824f58479a8f326cb350085b3a0e287645e11bc1
 	Pod     string `json:"pod,omitempty"`    // Pod: name of kubernetes pod, if available. Example: global-query-5987cbf795-dvnsl
+
+	API *APIStats `json:"api,omitempty"` // API: latency and error rate of each API route within the rolling window
 }
 
-// ServiceInfoHandler returns a handler that returns the service name, version, and commit
-func ServiceInfoHandler(serviceName string) gin.HandlerFunc {
+// ServiceInfoHandler returns a handler that returns the service name, version, and commit. If routeStats
+// are provided, the summaries of all API routes are included
+func ServiceInfoHandler(serviceName string, routeStats *RouteStats) gin.HandlerFunc {
 	info := &ServiceInfo{
 		Name:    serviceName,
 		Version: version.Short(),
@@ -35,7 +38,14 @@ func ServiceInfoHandler(serviceName string) gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, info)
+		if routeStats == nil {
+			c.JSON(http.StatusOK, info)
+			return
+		}
+
+		resp := *info
+		resp.API = routeStats.Summary()
+		c.JSON(http.StatusOK, &resp)
 	}
 }
 
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/els0r/telemetry/logging"
@@ -73,6 +76,23 @@ func RequestLoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RouteStatsMiddleware records the latency and status code of all requests received via the including
+// handler chain. Requests not matching any route are ignored
+func RouteStatsMiddleware(stats *RouteStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		duration := time.Since(start)
+
+		// use the route (path template) instead of the actual path to keep the cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		stats.Observe(c.Request.Method, route, c.Writer.Status(), duration)
+	}
+}
+
 // RateLimitMiddleware creates a global rate limit for all requests, using a maximum of
 // r requests per second and a maximum burst rate of b tokens
 func RateLimitMiddleware(limiter *rate.Limiter) gin.HandlerFunc {
@@ -85,6 +105,80 @@ func RateLimitMiddleware(limiter *rate.Limiter) gin.HandlerFunc {
 	}
 }
 
+// DefaultMaxBodySize denotes the default maximum size of a request body (in bytes)
+const DefaultMaxBodySize = 1 << 20 // 1 MiB
+
+// ErrRequestBodyTooLarge denotes that the body of a request exceeds the maximum size permitted for its route
+var ErrRequestBodyTooLarge = errors.New("request body too large")
+
+// BodySizeLimitMiddleware limits the size of all request bodies to the maximum size configured for the route
+// (path template) of a request, or defaultMaxSize for all other routes. Requests declaring a larger body are
+// rejected right away, all others fail upon reading beyond the limit (c.f. RequestBodyErrorStatus). A
+// maximum size <= 0 disables the limit
+func BodySizeLimitMiddleware(defaultMaxSize int64, routeMaxSizes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxSize := defaultMaxSize
+		if routeMaxSize, exists := routeMaxSizes[c.FullPath()]; exists {
+			maxSize = routeMaxSize
+		}
+		if maxSize <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxSize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"status_code": http.StatusRequestEntityTooLarge,
+				"error":       fmt.Sprintf("%s (max. %d bytes)", ErrRequestBodyTooLarge, maxSize),
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+		c.Next()
+	}
+}
+
+// RequestBodyErrorStatus returns the status code for an error encountered while reading / decoding a
+// request body, distinguishing bodies exceeding the size limit from malformed ones
+func RequestBodyErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// ErrUnauthorized denotes that a request does not present a valid API key
+var ErrUnauthorized = errors.New("missing or invalid API key")
+
+// APIKey returns the API key presented by a request via the Authorization header (e.g. "Authorization:
+// digest <key>"), if any
+func APIKey(req *http.Request) string {
+	_, key, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	return strings.TrimSpace(key)
+}
+
+// APIKeyMiddleware only admits requests presenting one of the provided API keys via the Authorization
+// header (e.g. "Authorization: digest <key>"). If no keys are provided, all requests are rejected
+func APIKeyMiddleware(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := APIKey(c.Request)
+		if key != "" {
+			for _, valid := range keys {
+				if subtle.ConstantTimeCompare([]byte(key), []byte(valid)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"status_code": http.StatusUnauthorized,
+			"error":       ErrUnauthorized.Error(),
+		})
+	}
+}
+
 // RecursionDetectorMiddleware provides a means to avoid having a distributed querier query itself
 // into oblivion
 func RecursionDetectorMiddleware(headerKey, match string) gin.HandlerFunc {
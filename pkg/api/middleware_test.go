@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodySizeLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodySizeLimitMiddleware(16, map[string]int64{"/large": 1024, "/unlimited": -1}))
+	for _, route := range []string{"/default", "/large", "/unlimited"} {
+		router.POST(route, func(c *gin.Context) {
+			if _, err := io.ReadAll(c.Request.Body); err != nil {
+				c.AbortWithStatus(RequestBodyErrorStatus(err))
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+	}
+
+	for _, c := range []struct {
+		path     string
+		size     int
+		chunked  bool
+		expected int
+	}{
+		{"/default", 16, false, http.StatusOK},
+		{"/default", 17, false, http.StatusRequestEntityTooLarge},
+		{"/default", 17, true, http.StatusRequestEntityTooLarge},
+		{"/large", 1024, true, http.StatusOK},
+		{"/large", 1025, false, http.StatusRequestEntityTooLarge},
+		{"/unlimited", 1 << 20, false, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, c.path, bytes.NewReader(make([]byte, c.size)))
+		if c.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, c.expected, rec.Code, "%s (%d bytes, chunked: %v)", c.path, c.size, c.chunked)
+	}
+}
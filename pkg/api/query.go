@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
+	"github.com/els0r/goProbe/pkg/api/resultpb"
 	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/telemetry/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -43,22 +47,116 @@ func RunQuery(caller, sourceData string, querier query.Runner, c *gin.Context) {
 
 	logger := logging.FromContext(ctx)
 
+	// determine the schema the result is rendered in
+	version, err := NegotiateAPIVersion(c)
+	if err != nil {
+		LogAndAbort(ctx, c, http.StatusNotAcceptable, err)
+		return
+	}
+	c.Header(APIVersionHeader, string(version))
+
 	// Check if the statement can be created
-	logger.With("args", queryArgs).Info("running query")
-	_, err := queryArgs.Prepare()
+	logger.With("args", queryArgs, "api_version", version).Info("running query")
+	_, err = queryArgs.Prepare()
 	if err != nil {
 		LogAndAbort(ctx, c, http.StatusBadRequest, fmt.Errorf("failed to prepare query statement: %w", err))
 		return
 	}
 
+	// stream the progress of the query (and finally its result) if requested by the client
+	if IsStreamingRequest(c.Request) {
+		runStreamingQuery(ctx, c, sourceData, querier, queryArgs, version)
+		return
+	}
+
 	result, err := querier.Run(ctx, queryArgs)
 	if err != nil {
 		LogAndAbort(ctx, c, http.StatusInternalServerError, fmt.Errorf("%s query failed: %w", sourceData, err))
 		return
 	}
 
+	// serialize the result in its binary representation if requested by the client (which mirrors the
+	// original schema, independent of the version negotiated)
+	if IsProtobufRequest(c.Request) {
+		data, err := resultpb.Marshal(result)
+		if err != nil {
+			LogAndAbort(ctx, c, http.StatusInternalServerError, fmt.Errorf("failed to encode binary result: %w", err))
+			return
+		}
+		c.Header(APIVersionHeader, string(APIVersionV1))
+		c.Data(http.StatusOK, ProtobufContentType, data)
+		return
+	}
+
 	// serialize raw result if json is selected
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, version.Render(result))
+}
+
+const (
+	// StreamContentType denotes the content type requested by clients in order to receive the progress
+	// of a query as server-sent events (followed by the result)
+	StreamContentType = "text/event-stream"
+
+	// ProtobufContentType denotes the content type requested by clients in order to receive the result
+	// of a query in its compact binary representation (c.f. package resultpb) instead of JSON
+	ProtobufContentType = "application/x-protobuf"
+
+	// ProgressEvent denotes the server-sent event carrying the progress of a running query
+	ProgressEvent = "progress"
+	// ResultEvent denotes the server-sent event carrying the result of a query (terminating the stream)
+	ResultEvent = "result"
+	// ErrorEvent denotes the server-sent event carrying the error of a failed query (terminating the stream)
+	ErrorEvent = "error"
+)
+
+// IsStreamingRequest returns if the client requested the progress of the query to be streamed
+func IsStreamingRequest(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), StreamContentType)
+}
+
+// IsProtobufRequest returns if the client requested the result of the query in its binary representation
+func IsProtobufRequest(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), ProtobufContentType)
+}
+
+type queryOutcome struct {
+	result *results.Result
+	err    error
+}
+
+func runStreamingQuery(ctx context.Context, c *gin.Context, sourceData string, querier query.Runner, queryArgs *query.Args, version APIVersion) {
+
+	// progress updates are dropped if the client cannot keep up (the next one supersedes them anyway)
+	progress := make(chan query.Progress, 1)
+	outcome := make(chan queryOutcome, 1)
+	go func() {
+		result, err := querier.Run(query.WithProgress(ctx, func(p query.Progress) {
+			select {
+			case progress <- p:
+			default:
+			}
+		}), queryArgs)
+		outcome <- queryOutcome{result: result, err: err}
+	}()
+
+	c.Stream(func(_ io.Writer) bool {
+		select {
+		case p := <-progress:
+			c.SSEvent(ProgressEvent, p)
+			return true
+		case o := <-outcome:
+			if o.err != nil {
+				err := fmt.Errorf("%s query failed: %w", sourceData, o.err)
+				logging.FromContext(ctx).Error(err)
+				c.SSEvent(ErrorEvent, err.Error())
+				return false
+			}
+			c.SSEvent(ResultEvent, version.Render(o.result))
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	})
 }
 
 // ValidationHandler returns the query args validation handler
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const queryMetricsSubsystem = "query"
+
+// QueryStats tracks the load of the query tier (running queries, pending work, cache use, scanned rows
+// and spill events) as prometheus metrics, allowing its capacity to be monitored separately from the
+// health of the capture tier
+type QueryStats struct {
+	activeQueries prometheus.Gauge
+	queueDepth    prometheus.Gauge
+	queries       *prometheus.CounterVec
+	cacheHits     prometheus.Counter
+	cacheMisses   prometheus.Counter
+	rowsScanned   prometheus.Counter
+	spillEvents   prometheus.Counter
+
+	// the cache use is additionally tracked in order to provide the hit ratio
+	nCacheHits, nCacheMisses atomic.Uint64
+}
+
+// NewQueryStats creates a new query tracker, exposing its metrics within the given namespace
+func NewQueryStats(namespace string) *QueryStats {
+	s := &QueryStats{
+		activeQueries: registerCollector(prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "active_queries",
+			Help:      "Number of queries currently running",
+		})),
+		queueDepth: registerCollector(prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "queue_depth",
+			Help:      "Number of work items of the running queries still pending (hosts for distributed queries, daily directories otherwise)",
+		})),
+		queries: registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "queries_total",
+			Help:      "Number of queries run, by outcome",
+		},
+			[]string{"status"},
+		)),
+		cacheHits: registerCollector(prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "cache_hits_total",
+			Help:      "Number of per-host sub-results served from the result cache",
+		})),
+		cacheMisses: registerCollector(prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "cache_misses_total",
+			Help:      "Number of per-host sub-results that had to be queried despite the result cache being enabled",
+		})),
+		rowsScanned: registerCollector(prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "rows_scanned_total",
+			Help:      "Number of flow rows aggregated by all queries",
+		})),
+		spillEvents: registerCollector(prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: queryMetricsSubsystem,
+			Name:      "spill_events_total",
+			Help:      "Number of times partial aggregates were spilled to disk because the aggregation memory budget was exceeded",
+		})),
+	}
+
+	// the hit rate is also provided directly, since it is the figure capacity planning is based on
+	registerCollector(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: queryMetricsSubsystem,
+		Name:      "cache_hit_ratio",
+		Help:      "Fraction of per-host sub-results served from the result cache since startup",
+	}, s.cacheHitRatio))
+
+	return s
+}
+
+func (s *QueryStats) cacheHitRatio() float64 {
+	hits, misses := s.nCacheHits.Load(), s.nCacheMisses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Runner wraps a query runner, tracking every query run by it
+func (s *QueryStats) Runner(runner query.Runner) query.Runner {
+	return &queryStatsRunner{
+		runner: runner,
+		stats:  s,
+	}
+}
+
+type queryStatsRunner struct {
+	runner query.Runner
+	stats  *QueryStats
+}
+
+// Run executes the query using the underlying runner. The progress of the query is tapped in order to
+// track the rows scanned and the work pending while it is running
+func (r *queryStatsRunner) Run(ctx context.Context, args *query.Args) (*results.Result, error) {
+	s := r.stats
+
+	s.activeQueries.Inc()
+	defer s.activeQueries.Dec()
+
+	tracker := &queryProgressTracker{stats: s, next: query.ProgressFromContext(ctx)}
+	defer tracker.done()
+
+	result, err := r.runner.Run(query.WithProgress(ctx, tracker.report), args)
+
+	status := "ok"
+	if err == nil && result != nil {
+		err = result.Err()
+	}
+	if err != nil {
+		status = "failed"
+	}
+	s.queries.WithLabelValues(status).Inc()
+
+	if result != nil {
+		if cache := result.Summary.Cache; cache != nil {
+			s.cacheHits.Add(float64(cache.Hits))
+			s.cacheMisses.Add(float64(cache.Misses))
+			s.nCacheHits.Add(uint64(cache.Hits))
+			s.nCacheMisses.Add(uint64(cache.Misses))
+		}
+		if spill := result.Summary.Timings.Spill; spill != nil {
+			s.spillEvents.Add(float64(spill.Spills))
+		}
+	}
+
+	return result, err
+}
+
+// queryProgressTracker converts the (cumulative) progress reports of a query into increments of the
+// query metrics, forwarding them to the progress function of the caller (if any)
+type queryProgressTracker struct {
+	stats *QueryStats
+	next  query.ProgressFn
+
+	rowsScanned uint64
+	pending     int
+
+	sync.Mutex
+}
+
+func (t *queryProgressTracker) report(p query.Progress) {
+	t.Lock()
+	if p.RowsAggregated > t.rowsScanned {
+		t.stats.rowsScanned.Add(float64(p.RowsAggregated - t.rowsScanned))
+		t.rowsScanned = p.RowsAggregated
+	}
+
+	pending := p.DirsTotal - p.DirsScanned
+	if p.HostsTotal > 0 {
+		pending = p.HostsTotal - p.HostsDone
+	}
+	t.stats.queueDepth.Add(float64(pending - t.pending))
+	t.pending = pending
+	t.Unlock()
+
+	if t.next != nil {
+		t.next(p)
+	}
+}
+
+// done removes the work still accounted as pending once the query has ended
+func (t *queryProgressTracker) done() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.stats.queueDepth.Sub(float64(t.pending))
+	t.pending = 0
+}
+
+// MetricsHandler serves the metrics of the default prometheus registry. The OpenMetrics format is used
+// if requested by the scraper (via the Accept header), the classic text format otherwise
+func MetricsHandler() gin.HandlerFunc {
+	h := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		}),
+	)
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type runnerFunc func(ctx context.Context, args *query.Args) (*results.Result, error)
+
+func (f runnerFunc) Run(ctx context.Context, args *query.Args) (*results.Result, error) {
+	return f(ctx, args)
+}
+
+func TestQueryStats(t *testing.T) {
+	stats := NewQueryStats("test_query_stats")
+
+	var reported []query.Progress
+	runner := stats.Runner(runnerFunc(func(ctx context.Context, _ *query.Args) (*results.Result, error) {
+		require.Equal(t, 1., testutil.ToFloat64(stats.activeQueries))
+
+		progressFn := query.ProgressFromContext(ctx)
+		require.NotNil(t, progressFn)
+
+		progressFn(query.Progress{HostsTotal: 4, HostsDone: 1, RowsAggregated: 100})
+		require.Equal(t, 3., testutil.ToFloat64(stats.queueDepth))
+		progressFn(query.Progress{HostsTotal: 4, HostsDone: 3, RowsAggregated: 250})
+		require.Equal(t, 1., testutil.ToFloat64(stats.queueDepth))
+
+		result := results.New()
+		result.Summary.Cache = &results.CacheStats{Hits: 3, Misses: 1}
+		result.Summary.Timings.Spill = &results.SpillStats{Spills: 2}
+		return result, nil
+	}))
+
+	ctx := query.WithProgress(context.Background(), func(p query.Progress) {
+		reported = append(reported, p)
+	})
+	_, err := runner.Run(ctx, query.DefaultArgs())
+	require.Nil(t, err)
+
+	// the progress must still be forwarded to the caller
+	require.Len(t, reported, 2)
+
+	require.Zero(t, testutil.ToFloat64(stats.activeQueries))
+	require.Zero(t, testutil.ToFloat64(stats.queueDepth), "pending work must be released once the query ended")
+	require.Equal(t, 250., testutil.ToFloat64(stats.rowsScanned))
+	require.Equal(t, 2., testutil.ToFloat64(stats.spillEvents))
+	require.Equal(t, 3., testutil.ToFloat64(stats.cacheHits))
+	require.Equal(t, 0.75, stats.cacheHitRatio())
+	require.Equal(t, 1., testutil.ToFloat64(stats.queries.WithLabelValues("ok")))
+
+	// failed queries are tracked as such
+	runner = stats.Runner(runnerFunc(func(context.Context, *query.Args) (*results.Result, error) {
+		return nil, errors.New("query failed")
+	}))
+	_, err = runner.Run(context.Background(), query.DefaultArgs())
+	require.NotNil(t, err)
+	require.Equal(t, 1., testutil.ToFloat64(stats.queries.WithLabelValues("failed")))
+}
+
+func TestMetricsHandlerOpenMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	NewQueryStats("test_metrics_handler")
+
+	router := gin.New()
+	router.GET(MetricsRoute, MetricsHandler())
+
+	req := httptest.NewRequest(http.MethodGet, MetricsRoute, nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "application/openmetrics-text"))
+	require.Contains(t, w.Body.String(), "test_metrics_handler_query_active_queries")
+	require.True(t, strings.HasSuffix(w.Body.String(), "# EOF\n"))
+}
@@ -0,0 +1,258 @@
+package resultpb
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidIP denotes that an IP address of a row doesn't have a valid length
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// Marshal encodes a result in its binary representation
+func Marshal(res *results.Result) ([]byte, error) {
+	msg, err := FromResult(res)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal decodes the binary representation of a result into res
+func Unmarshal(data []byte, res *results.Result) error {
+	var msg Result
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to decode binary result: %w", err)
+	}
+
+	decoded, err := msg.ToResult()
+	if err != nil {
+		return err
+	}
+	*res = *decoded
+	return nil
+}
+
+// FromResult converts a result to its protobuf representation
+func FromResult(res *results.Result) (*Result, error) {
+	summary, err := jsoniter.Marshal(res.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	msg := &Result{
+		Hostname: res.Hostname,
+		Status:   fromStatus(res.Status),
+		Summary:  summary,
+		Query: &Query{
+			Attributes: res.Query.Attributes,
+			Condition:  res.Query.Condition,
+		},
+	}
+	if res.Plan != nil {
+		if msg.Plan, err = jsoniter.Marshal(res.Plan); err != nil {
+			return nil, fmt.Errorf("failed to encode plan: %w", err)
+		}
+	}
+
+	// maps are encoded in order of their keys to guarantee a deterministic encoding
+	msg.HostsStatuses = make([]*HostStatus, 0, len(res.HostsStatuses))
+	for host, status := range res.HostsStatuses {
+		msg.HostsStatuses = append(msg.HostsStatuses, &HostStatus{Host: host, Status: fromStatus(status)})
+	}
+	slices.SortFunc(msg.HostsStatuses, func(a, b *HostStatus) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+	if len(res.Hostnames) > 0 {
+		msg.Hostnames = make([]*Hostname, 0, len(res.Hostnames))
+		for ip, name := range res.Hostnames {
+			msg.Hostnames = append(msg.Hostnames, &Hostname{Ip: ip, Name: name})
+		}
+		slices.SortFunc(msg.Hostnames, func(a, b *Hostname) int {
+			return strings.Compare(a.Ip, b.Ip)
+		})
+	}
+
+	// rows (and their counters) are allocated in bulk since they make up the bulk of large results
+	rows := make([]Row, len(res.Rows))
+	counters := make([]Counters, len(res.Rows))
+	msg.Rows = make([]*Row, len(res.Rows))
+	for i, row := range res.Rows {
+		msg.Rows[i] = &rows[i]
+		fromRow(&rows[i], &counters[i], row)
+	}
+
+	return msg, nil
+}
+
+// ToResult converts the protobuf representation of a result back to a result
+func (x *Result) ToResult() (*results.Result, error) {
+	res := &results.Result{
+		Hostname:      x.GetHostname(),
+		Status:        x.GetStatus().toStatus(),
+		HostsStatuses: make(results.HostsStatuses, len(x.GetHostsStatuses())),
+		Query: results.Query{
+			Attributes: x.GetQuery().GetAttributes(),
+			Condition:  x.GetQuery().GetCondition(),
+		},
+		Rows: make(results.Rows, len(x.GetRows())),
+	}
+	if len(x.GetSummary()) > 0 {
+		if err := jsoniter.Unmarshal(x.GetSummary(), &res.Summary); err != nil {
+			return nil, fmt.Errorf("failed to decode summary: %w", err)
+		}
+	}
+	if len(x.GetPlan()) > 0 {
+		res.Plan = new(results.Plan)
+		if err := jsoniter.Unmarshal(x.GetPlan(), res.Plan); err != nil {
+			return nil, fmt.Errorf("failed to decode plan: %w", err)
+		}
+	}
+
+	for _, hs := range x.GetHostsStatuses() {
+		res.HostsStatuses[hs.GetHost()] = hs.GetStatus().toStatus()
+	}
+	if len(x.GetHostnames()) > 0 {
+		res.Hostnames = make(map[string]string, len(x.GetHostnames()))
+		for _, hostname := range x.GetHostnames() {
+			res.Hostnames[hostname.GetIp()] = hostname.GetName()
+		}
+	}
+
+	for i, row := range x.GetRows() {
+		if err := row.toRow(&res.Rows[i]); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	return res, nil
+}
+
+func fromRow(msg *Row, counters *Counters, row results.Row) {
+	if !row.Labels.Timestamp.IsZero() {
+		msg.Timestamp = row.Labels.Timestamp.Unix()
+	}
+	msg.Iface = row.Labels.Iface
+	msg.Host = row.Labels.Hostname
+	msg.HostId = row.Labels.HostID
+
+	if row.Attributes.SrcIP.IsValid() {
+		msg.Sip = row.Attributes.SrcIP.AsSlice()
+	}
+	if row.Attributes.DstIP.IsValid() {
+		msg.Dip = row.Attributes.DstIP.AsSlice()
+	}
+	msg.Proto = uint32(row.Attributes.IPProto)
+	msg.Dport = uint32(row.Attributes.DstPort)
+	msg.Tag = row.Attributes.Tag
+	msg.TtlMin = uint32(row.Attributes.TTLMin)
+	msg.TtlMax = uint32(row.Attributes.TTLMax)
+
+	counters.set(row.Counters)
+	msg.Counters = counters
+	msg.Mirrored = row.Mirrored
+	msg.FlowHash = row.FlowHash
+	msg.CommunityId = row.CommunityID
+
+	if row.Roles != nil {
+		msg.Roles = &RoleCounters{Source: new(Counters), Destination: new(Counters)}
+		msg.Roles.Source.set(row.Roles.Source)
+		msg.Roles.Destination.set(row.Roles.Destination)
+	}
+	if row.Stats != nil {
+		msg.Stats = &BinStats{
+			Bins:   int64(row.Stats.Bins),
+			P50:    row.Stats.P50,
+			P95:    row.Stats.P95,
+			Max:    row.Stats.Max,
+			StdDev: row.Stats.StdDev,
+		}
+	}
+}
+
+func (x *Row) toRow(row *results.Row) (err error) {
+	if x.GetTimestamp() != 0 {
+		row.Labels.Timestamp = time.Unix(x.GetTimestamp(), 0)
+	}
+	row.Labels.Iface = x.GetIface()
+	row.Labels.Hostname = x.GetHost()
+	row.Labels.HostID = x.GetHostId()
+
+	if row.Attributes.SrcIP, err = toIP(x.GetSip()); err != nil {
+		return err
+	}
+	if row.Attributes.DstIP, err = toIP(x.GetDip()); err != nil {
+		return err
+	}
+	row.Attributes.IPProto = uint8(x.GetProto())
+	row.Attributes.DstPort = uint16(x.GetDport())
+	row.Attributes.Tag = x.GetTag()
+	row.Attributes.TTLMin = uint8(x.GetTtlMin())
+	row.Attributes.TTLMax = uint8(x.GetTtlMax())
+
+	row.Counters = x.GetCounters().toCounters()
+	row.Mirrored = x.GetMirrored()
+	row.FlowHash = x.GetFlowHash()
+	row.CommunityID = x.GetCommunityId()
+
+	if x.GetRoles() != nil {
+		row.Roles = &results.RoleCounters{
+			Source:      x.GetRoles().GetSource().toCounters(),
+			Destination: x.GetRoles().GetDestination().toCounters(),
+		}
+	}
+	if stats := x.GetStats(); stats != nil {
+		row.Stats = &results.BinStats{
+			Bins:   int(stats.GetBins()),
+			P50:    stats.GetP50(),
+			P95:    stats.GetP95(),
+			Max:    stats.GetMax(),
+			StdDev: stats.GetStdDev(),
+		}
+	}
+	return nil
+}
+
+// toIP converts the binary representation of an IP address (empty if the address is not set)
+func toIP(b []byte) (netip.Addr, error) {
+	if len(b) == 0 {
+		return netip.Addr{}, nil
+	}
+	ip, ok := netip.AddrFromSlice(b)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("%w: length %d", ErrInvalidIP, len(b))
+	}
+	return ip, nil
+}
+
+func fromStatus(status results.Status) *Status {
+	return &Status{Code: string(status.Code), Message: status.Message}
+}
+
+func (x *Status) toStatus() results.Status {
+	return results.Status{Code: types.Status(x.GetCode()), Message: x.GetMessage()}
+}
+
+func (x *Counters) set(c types.Counters) {
+	x.BytesRcvd = c.BytesRcvd
+	x.BytesSent = c.BytesSent
+	x.PacketsRcvd = c.PacketsRcvd
+	x.PacketsSent = c.PacketsSent
+}
+
+func (x *Counters) toCounters() types.Counters {
+	return types.Counters{
+		BytesRcvd:   x.GetBytesRcvd(),
+		BytesSent:   x.GetBytesSent(),
+		PacketsRcvd: x.GetPacketsRcvd(),
+		PacketsSent: x.GetPacketsSent(),
+	}
+}
@@ -0,0 +1,130 @@
+package resultpb
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func testResult(nRows int) *results.Result {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	res := &results.Result{
+		Hostname: "probe-1",
+		Status:   results.Status{Code: types.StatusOK},
+		HostsStatuses: results.HostsStatuses{
+			"probe-1": {Code: types.StatusOK},
+			"probe-2": {Code: types.StatusError, Message: "connection refused"},
+		},
+		Summary: results.Summary{
+			Interfaces: []string{"eth0", "eth1"},
+			TimeRange:  results.TimeRange{First: start, Last: start.Add(time.Hour)},
+			Totals:     types.Counters{BytesRcvd: 1000, BytesSent: 2000, PacketsRcvd: 10, PacketsSent: 20},
+			Timings:    results.Timings{QueryStart: start, QueryDuration: time.Second},
+			Hits:       results.Hits{Displayed: nRows, Total: nRows},
+		},
+		Query: results.Query{
+			Attributes: []string{"sip", "dip", "dport", "proto"},
+			Condition:  "port=443",
+		},
+		Hostnames: map[string]string{"10.0.0.1": "db.example.com"},
+		Rows:      make(results.Rows, nRows),
+	}
+	for i := range res.Rows {
+		res.Rows[i] = results.Row{
+			Labels: results.Labels{
+				Timestamp: time.Unix(start.Add(time.Duration(i)*5*time.Minute).Unix(), 0),
+				Iface:     "eth0",
+				Hostname:  "probe-1",
+				HostID:    "1234",
+			},
+			Attributes: results.Attributes{
+				SrcIP:   netip.MustParseAddr(fmt.Sprintf("10.0.%d.%d", i/256, i%256)),
+				DstIP:   netip.MustParseAddr("2001:db8::1"),
+				IPProto: 6,
+				DstPort: 443,
+				Tag:     "web",
+				TTLMin:  58,
+				TTLMax:  64,
+			},
+			Counters: types.Counters{BytesRcvd: uint64(i) * 100, BytesSent: 2000, PacketsRcvd: uint64(i), PacketsSent: 20},
+		}
+	}
+	return res
+}
+
+func TestRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name   string
+		result func() *results.Result
+	}{
+		{"empty", func() *results.Result {
+			return &results.Result{HostsStatuses: results.HostsStatuses{}, Rows: results.Rows{}}
+		}},
+		{"rows", func() *results.Result { return testResult(100) }},
+		{"attributes only", func() *results.Result {
+			res := testResult(2)
+			res.Hostnames = nil
+			for i := range res.Rows {
+				res.Rows[i].Labels = results.Labels{}
+				res.Rows[i].Attributes.DstIP = netip.Addr{}
+			}
+			return res
+		}},
+		{"extensions", func() *results.Result {
+			res := testResult(2)
+			res.Plan = &results.Plan{Columns: []string{"sip", "dip"}, Workers: 4, CPUWorkers: 8}
+			res.Rows[0].Mirrored = true
+			res.Rows[0].FlowHash = "3f2a"
+			res.Rows[0].CommunityID = "1:abc="
+			res.Rows[1].Roles = &results.RoleCounters{
+				Source:      types.Counters{BytesRcvd: 1, BytesSent: 2},
+				Destination: types.Counters{PacketsRcvd: 3, PacketsSent: 4},
+			}
+			res.Rows[1].Stats = &results.BinStats{Bins: 12, P50: 100, P95: 200, Max: 300, StdDev: 12.5}
+			return res
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := test.result()
+
+			data, err := Marshal(res)
+			require.Nil(t, err)
+
+			decoded := new(results.Result)
+			require.Nil(t, Unmarshal(data, decoded))
+			require.Equal(t, res, decoded)
+
+			// the encoding is deterministic
+			data2, err := Marshal(decoded)
+			require.Nil(t, err)
+			require.Equal(t, data, data2)
+		})
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	res := testResult(10000)
+
+	data, err := Marshal(res)
+	require.Nil(t, err)
+	jsonData, err := jsoniter.Marshal(res)
+	require.Nil(t, err)
+
+	require.Less(t, 3*len(data), len(jsonData), "binary result should be a fraction of the size of the JSON one")
+}
+
+func TestInvalidIP(t *testing.T) {
+	data, err := proto.Marshal(&Result{Rows: []*Row{{Sip: []byte{10, 0, 0}}}})
+	require.Nil(t, err)
+
+	require.ErrorIs(t, Unmarshal(data, new(results.Result)), ErrInvalidIP)
+}
@@ -0,0 +1,7 @@
+// Package resultpb provides the protobuf schema of the compact binary representation of query results,
+// which clients can request from the query API instead of JSON (c.f. api.ProtobufContentType) in order to
+// cut the serialization cost and transfer size of large results. Use Marshal / Unmarshal to convert
+// between results.Result and its binary representation
+package resultpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative result.proto
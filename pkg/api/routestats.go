@@ -0,0 +1,258 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// RouteStatsWindow denotes the time span covered by the rolling route summaries
+	RouteStatsWindow = 5 * time.Minute
+
+	routeStatsSlots        = 30
+	routeStatsSlotDuration = RouteStatsWindow / routeStatsSlots
+
+	routeMetricsSubsystem = "api"
+)
+
+// APIStats summarizes the requests served by the API within the rolling window
+type APIStats struct {
+	Window time.Duration  `json:"window_ns"` // Window: the time span covered by the summaries in nanoseconds. Example: 300000000000
+	Routes []RouteSummary `json:"routes"`    // Routes: the summaries of all routes that served requests within the window
+}
+
+// RouteSummary summarizes the requests served by an individual API route within the rolling window
+type RouteSummary struct {
+	Method string `json:"method"` // Method: the HTTP method. Example: POST
+	Route  string `json:"route"`  // Route: the route (path template) of the request. Example: /_query
+
+	Requests     uint64  `json:"requests"`      // Requests: the number of requests served. Example: 120
+	ClientErrors uint64  `json:"client_errors"` // ClientErrors: the number of requests answered with a 4xx status code. Example: 3
+	ServerErrors uint64  `json:"server_errors"` // ServerErrors: the number of requests answered with a 5xx status code. Example: 1
+	ErrorRate    float64 `json:"error_rate"`    // ErrorRate: the fraction of requests answered with a 5xx status code. Example: 0.0083
+
+	LatencyMean time.Duration `json:"latency_mean_ns"` // LatencyMean: the mean request latency in nanoseconds. Example: 25000000
+	LatencyP50  time.Duration `json:"latency_p50_ns"`  // LatencyP50: the (estimated) median request latency in nanoseconds. Example: 12000000
+	LatencyP95  time.Duration `json:"latency_p95_ns"`  // LatencyP95: the (estimated) 95th percentile of the request latency in nanoseconds. Example: 80000000
+	LatencyP99  time.Duration `json:"latency_p99_ns"`  // LatencyP99: the (estimated) 99th percentile of the request latency in nanoseconds. Example: 240000000
+	LatencyMax  time.Duration `json:"latency_max_ns"`  // LatencyMax: the maximum request latency in nanoseconds. Example: 310000000
+}
+
+type routeKey struct {
+	method, route string
+}
+
+type routeSlot struct {
+	epoch int64 // index of the time slot the counters belong to
+
+	requests, clientErrors, serverErrors uint64
+	latencySum, latencyMax               time.Duration
+	latencyBuckets                       []uint64 // one counter per bucket, plus one for +Inf
+}
+
+// RouteStats tracks the latency and error rate of each API route, both as prometheus metrics
+// (if enabled) and as summaries over a rolling window
+type RouteStats struct {
+	sync.Mutex
+
+	buckets []float64 // latency bucket upper bounds in seconds
+	routes  map[routeKey]*[routeStatsSlots]routeSlot
+
+	requestDuration *prometheus.HistogramVec
+	errors          *prometheus.CounterVec
+
+	now func() time.Time
+}
+
+// NewRouteStats creates a new route tracker. The latency of requests is estimated based on the provided
+// buckets (upper bounds in seconds). If none are provided, the prometheus default buckets are used
+func NewRouteStats(buckets ...float64) *RouteStats {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &RouteStats{
+		buckets: buckets,
+		routes:  make(map[routeKey]*[routeStatsSlots]routeSlot),
+		now:     time.Now,
+	}
+}
+
+// WithPrometheus additionally exposes the latency and errors of each route as prometheus metrics
+// within the given namespace
+func (s *RouteStats) WithPrometheus(namespace string) *RouteStats {
+	s.requestDuration = registerCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: routeMetricsSubsystem,
+		Name:      "route_request_duration_seconds",
+		Help:      "Time to serve a request, by API route",
+		Buckets:   s.buckets,
+	},
+		[]string{"method", "route"},
+	))
+	s.errors = registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: routeMetricsSubsystem,
+		Name:      "route_errors_total",
+		Help:      "Number of requests answered with a 4xx / 5xx status code, by API route",
+	},
+		[]string{"method", "route", "code"},
+	))
+	return s
+}
+
+// registerCollector registers c with the default registry. If an identical collector is already
+// registered (e.g. by another server instance), the existing one is used instead
+func registerCollector[T prometheus.Collector](c T) T {
+	if err := prometheus.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Observe records a request served by the route
+func (s *RouteStats) Observe(method, route string, statusCode int, duration time.Duration) {
+	if s.requestDuration != nil {
+		s.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+		if statusCode >= 400 {
+			s.errors.WithLabelValues(method, route, strconv.Itoa(statusCode)).Inc()
+		}
+	}
+
+	epoch := s.now().UnixNano() / int64(routeStatsSlotDuration)
+
+	s.Lock()
+	defer s.Unlock()
+
+	key := routeKey{method: method, route: route}
+	slots, exists := s.routes[key]
+	if !exists {
+		slots = new([routeStatsSlots]routeSlot)
+		s.routes[key] = slots
+	}
+
+	// slots are re-used in a round-robin fashion, resetting them once a new time slot begins
+	slot := &slots[epoch%routeStatsSlots]
+	if slot.epoch != epoch {
+		*slot = routeSlot{
+			epoch:          epoch,
+			latencyBuckets: slot.latencyBuckets,
+		}
+		if slot.latencyBuckets == nil {
+			slot.latencyBuckets = make([]uint64, len(s.buckets)+1)
+		}
+		clear(slot.latencyBuckets)
+	}
+
+	slot.requests++
+	switch {
+	case statusCode >= 500:
+		slot.serverErrors++
+	case statusCode >= 400:
+		slot.clientErrors++
+	}
+	slot.latencySum += duration
+	if duration > slot.latencyMax {
+		slot.latencyMax = duration
+	}
+	slot.latencyBuckets[sort.SearchFloat64s(s.buckets, duration.Seconds())]++
+}
+
+// Summary summarizes the requests served by each route within the rolling window. Routes are sorted
+// by route and method
+func (s *RouteStats) Summary() *APIStats {
+	epoch := s.now().UnixNano() / int64(routeStatsSlotDuration)
+
+	s.Lock()
+	defer s.Unlock()
+
+	stats := &APIStats{
+		Window: RouteStatsWindow,
+		Routes: make([]RouteSummary, 0, len(s.routes)),
+	}
+	latencyBuckets := make([]uint64, len(s.buckets)+1)
+	for key, slots := range s.routes {
+		summary := RouteSummary{Method: key.method, Route: key.route}
+		clear(latencyBuckets)
+
+		var latencySum time.Duration
+		for i := range slots {
+			slot := &slots[i]
+			if slot.requests == 0 || epoch-slot.epoch >= routeStatsSlots {
+				continue
+			}
+			summary.Requests += slot.requests
+			summary.ClientErrors += slot.clientErrors
+			summary.ServerErrors += slot.serverErrors
+			latencySum += slot.latencySum
+			if slot.latencyMax > summary.LatencyMax {
+				summary.LatencyMax = slot.latencyMax
+			}
+			for b, count := range slot.latencyBuckets {
+				latencyBuckets[b] += count
+			}
+		}
+		if summary.Requests == 0 {
+			continue
+		}
+
+		summary.ErrorRate = float64(summary.ServerErrors) / float64(summary.Requests)
+		summary.LatencyMean = latencySum / time.Duration(summary.Requests)
+		summary.LatencyP50 = s.quantile(0.5, latencyBuckets, summary.Requests, summary.LatencyMax)
+		summary.LatencyP95 = s.quantile(0.95, latencyBuckets, summary.Requests, summary.LatencyMax)
+		summary.LatencyP99 = s.quantile(0.99, latencyBuckets, summary.Requests, summary.LatencyMax)
+
+		stats.Routes = append(stats.Routes, summary)
+	}
+	sort.Slice(stats.Routes, func(i, j int) bool {
+		if stats.Routes[i].Route != stats.Routes[j].Route {
+			return stats.Routes[i].Route < stats.Routes[j].Route
+		}
+		return stats.Routes[i].Method < stats.Routes[j].Method
+	})
+	return stats
+}
+
+// quantile estimates the q-quantile of the latency by linear interpolation within the bucket it
+// falls into (analogous to prometheus' histogram_quantile). The estimate is capped by the maximum
+// latency observed
+func (s *RouteStats) quantile(q float64, latencyBuckets []uint64, count uint64, latencyMax time.Duration) time.Duration {
+	rank := q * float64(count)
+
+	var cumulative uint64
+	for b, n := range latencyBuckets {
+		if float64(cumulative+n) < rank || n == 0 {
+			cumulative += n
+			continue
+		}
+
+		// the +Inf bucket has no upper bound to interpolate to
+		if b == len(s.buckets) {
+			return latencyMax
+		}
+		lower := 0.
+		if b > 0 {
+			lower = s.buckets[b-1]
+		}
+		upper := s.buckets[b]
+		estimate := time.Duration((lower + (upper-lower)*(rank-float64(cumulative))/float64(n)) * float64(time.Second))
+		if estimate > latencyMax {
+			return latencyMax
+		}
+		return estimate
+	}
+	return latencyMax
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/version"
+	"github.com/gin-gonic/gin"
+)
+
+// Features denotes the features enabled / disabled in a running service, grouped by module
+// (e.g. "api" -> "metrics" -> true)
+type Features map[string]map[string]bool
+
+// RuntimeInfo summarizes the build and runtime environment of the running service, such that bug
+// reports can include consistent diagnostics
+type RuntimeInfo struct {
+	Name      string     `json:"name"`                 // Name: service name. Example: goprobe
+	Version   string     `json:"version"`              // Version: (semantic) version and commit short. Example: 4.0.0-824f5847
+	Commit    string     `json:"commit,omitempty"`     // Commit: full git commit SHA. Example: 824f58479a8f326cb350085b3a0e287645e11bc1
+	BuildTime *time.Time `json:"build_time,omitempty"` // BuildTime: time the binary was built (if provided during release). Example: 2024-01-01T00:00:00Z
+	Started   time.Time  `json:"started"`              // Started: time the service was started. Example: 2024-01-02T00:00:00Z
+	UptimeNs  int64      `json:"uptime_ns"`            // UptimeNs: time since the service was started in nanoseconds. Example: 3600000000000
+
+	Go       GoRuntime  `json:"go"`                 // Go: Go version and runtime properties
+	Build    *BuildInfo `json:"build,omitempty"`    // Build: build information embedded in the binary (if available)
+	GC       GCStats    `json:"gc"`                 // GC: garbage collector statistics
+	Memory   MemStats   `json:"memory"`             // Memory: allocator statistics
+	Features Features   `json:"features,omitempty"` // Features: enabled / disabled features, grouped by module
+}
+
+// GoRuntime summarizes the Go version and the properties of the Go runtime
+type GoRuntime struct {
+	Version    string `json:"version"`    // Version: Go version the binary was built with. Example: go1.22.1
+	OS         string `json:"os"`         // OS: operating system. Example: linux
+	Arch       string `json:"arch"`       // Arch: architecture. Example: amd64
+	NumCPU     int    `json:"num_cpu"`    // NumCPU: number of logical CPUs usable by the process. Example: 8
+	GOMAXPROCS int    `json:"gomaxprocs"` // GOMAXPROCS: maximum number of CPUs executing simultaneously. Example: 8
+	Goroutines int    `json:"goroutines"` // Goroutines: number of currently existing goroutines. Example: 42
+	CgoCalls   int64  `json:"cgo_calls"`  // CgoCalls: number of cgo calls made by the process. Example: 0
+}
+
+// BuildInfo summarizes the build information embedded in the binary
+type BuildInfo struct {
+	Path     string            `json:"path"`               // Path: main package path. Example: github.com/els0r/goProbe/cmd/goProbe
+	Module   Module            `json:"module"`             // Module: main module
+	Settings map[string]string `json:"settings,omitempty"` // Settings: build settings (e.g. GOOS, CGO_ENABLED, -tags, vcs.revision)
+	Deps     []Module          `json:"deps,omitempty"`     // Deps: module dependencies
+}
+
+// Module denotes a Go module the binary was built from
+type Module struct {
+	Path    string `json:"path"`              // Path: module path. Example: github.com/els0r/goProbe
+	Version string `json:"version"`           // Version: module version. Example: v1.2.3
+	Replace string `json:"replace,omitempty"` // Replace: path of the replacement module (if replaced). Example: ../goProbe
+}
+
+// GCStats summarizes the statistics of the garbage collector
+type GCStats struct {
+	NumGC        uint32     `json:"num_gc"`                  // NumGC: number of completed GC cycles. Example: 120
+	NumForcedGC  uint32     `json:"num_forced_gc"`           // NumForcedGC: number of GC cycles forced by the application. Example: 2
+	LastGC       *time.Time `json:"last_gc,omitempty"`       // LastGC: time the last GC cycle finished. Example: 2024-01-02T00:59:58Z
+	PauseTotalNs uint64     `json:"pause_total_ns"`          // PauseTotalNs: cumulative GC stop-the-world pause time in nanoseconds. Example: 5000000
+	LastPauseNs  uint64     `json:"last_pause_ns"`           // LastPauseNs: GC stop-the-world pause time of the last cycle in nanoseconds. Example: 40000
+	CPUFraction  float64    `json:"cpu_fraction"`            // CPUFraction: fraction of the available CPU time used by the GC since the start. Example: 0.0012
+	GOGC         int        `json:"gogc"`                    // GOGC: GC target percentage (negative if the GC is disabled). Example: 100
+	MemoryLimit  int64      `json:"memory_limit_bytes"`      // MemoryLimit: soft memory limit in bytes (math.MaxInt64 if unset). Example: 1073741824
+	NextGC       uint64     `json:"next_gc_bytes,omitempty"` // NextGC: target heap size of the next GC cycle in bytes. Example: 8388608
+}
+
+// MemStats summarizes the statistics of the memory allocator
+type MemStats struct {
+	Sys          uint64 `json:"sys_bytes"`           // Sys: total bytes of memory obtained from the OS. Example: 25165824
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`   // TotalAlloc: cumulative bytes allocated for heap objects. Example: 104857600
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`    // HeapAlloc: bytes of allocated heap objects. Example: 4194304
+	HeapInuse    uint64 `json:"heap_inuse_bytes"`    // HeapInuse: bytes in in-use heap spans. Example: 5242880
+	HeapIdle     uint64 `json:"heap_idle_bytes"`     // HeapIdle: bytes in idle (unused) heap spans. Example: 3145728
+	HeapReleased uint64 `json:"heap_released_bytes"` // HeapReleased: bytes of physical memory returned to the OS. Example: 2097152
+	HeapObjects  uint64 `json:"heap_objects"`        // HeapObjects: number of allocated heap objects. Example: 20000
+	StackInuse   uint64 `json:"stack_inuse_bytes"`   // StackInuse: bytes in stack spans. Example: 524288
+	Mallocs      uint64 `json:"mallocs"`             // Mallocs: cumulative count of allocated heap objects. Example: 1000000
+	Frees        uint64 `json:"frees"`               // Frees: cumulative count of freed heap objects. Example: 980000
+}
+
+// RuntimeInfoHandler returns a handler that returns the build and runtime environment of the service,
+// including the provided features
+func RuntimeInfoHandler(serviceName string, features Features) gin.HandlerFunc {
+	started := time.Now()
+	build := readBuildInfo()
+
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, NewRuntimeInfo(serviceName, started, build, features))
+	}
+}
+
+// NewRuntimeInfo gathers the current build and runtime environment of the service
+func NewRuntimeInfo(serviceName string, started time.Time, build *BuildInfo, features Features) *RuntimeInfo {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gcSettings := []metrics.Sample{
+		{Name: gcPercentMetric},
+		{Name: memoryLimitMetric},
+	}
+	metrics.Read(gcSettings)
+
+	info := &RuntimeInfo{
+		Name:     serviceName,
+		Version:  version.Short(),
+		Commit:   version.GitSHA,
+		Started:  started,
+		UptimeNs: time.Since(started).Nanoseconds(),
+		Go: GoRuntime{
+			Version:    runtime.Version(),
+			OS:         runtime.GOOS,
+			Arch:       runtime.GOARCH,
+			NumCPU:     runtime.NumCPU(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: runtime.NumGoroutine(),
+			CgoCalls:   runtime.NumCgoCall(),
+		},
+		Build: build,
+		GC: GCStats{
+			NumGC:        ms.NumGC,
+			NumForcedGC:  ms.NumForcedGC,
+			PauseTotalNs: ms.PauseTotalNs,
+			CPUFraction:  ms.GCCPUFraction,
+			GOGC:         int(sampleValue(gcSettings[0])),   // #nosec G115
+			MemoryLimit:  int64(sampleValue(gcSettings[1])), // #nosec G115
+			NextGC:       ms.NextGC,
+		},
+		Memory: MemStats{
+			Sys:          ms.Sys,
+			TotalAlloc:   ms.TotalAlloc,
+			HeapAlloc:    ms.HeapAlloc,
+			HeapInuse:    ms.HeapInuse,
+			HeapIdle:     ms.HeapIdle,
+			HeapReleased: ms.HeapReleased,
+			HeapObjects:  ms.HeapObjects,
+			StackInuse:   ms.StackInuse,
+			Mallocs:      ms.Mallocs,
+			Frees:        ms.Frees,
+		},
+		Features: features,
+	}
+	if !version.BuildTime.IsZero() {
+		info.BuildTime = &version.BuildTime
+	}
+	if ms.NumGC > 0 {
+		lastGC := time.Unix(0, int64(ms.LastGC)) // #nosec G115
+		info.GC.LastGC = &lastGC
+		info.GC.LastPauseNs = ms.PauseNs[(ms.NumGC+255)%256]
+	}
+
+	return info
+}
+
+// GC settings are read via runtime/metrics (instead of debug.SetGCPercent() / debug.SetMemoryLimit(),
+// which would require modifying them in order to read them)
+const (
+	gcPercentMetric   = "/gc/gogc:percent"
+	memoryLimitMetric = "/gc/gomemlimit:bytes"
+)
+
+func sampleValue(sample metrics.Sample) uint64 {
+	if sample.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample.Value.Uint64()
+}
+
+func readBuildInfo() *BuildInfo {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	info := &BuildInfo{
+		Path:     bi.Path,
+		Module:   newModule(&bi.Main),
+		Settings: make(map[string]string, len(bi.Settings)),
+	}
+	for _, setting := range bi.Settings {
+		info.Settings[setting.Key] = setting.Value
+	}
+	for _, dep := range bi.Deps {
+		info.Deps = append(info.Deps, newModule(dep))
+	}
+
+	return info
+}
+
+func newModule(m *debug.Module) Module {
+	mod := Module{
+		Path:    m.Path,
+		Version: m.Version,
+	}
+	if m.Replace != nil {
+		mod.Replace = m.Replace.Path
+	}
+	return mod
+}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/els0r/goProbe/pkg/api"
+	"github.com/els0r/goProbe/pkg/api/ui"
 	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/query/audit"
 	"github.com/els0r/telemetry/metrics"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -31,8 +33,7 @@ type Option func(*DefaultServer)
 // DefaultServer is the default API server, allowing middlewares and settings to be
 // re-used across binaries serving an API
 type DefaultServer struct {
-	// api handling
-	// TODO: authorize API access
+	// api handling: keys authorizing access to the routes requiring authentication
 	keys []string
 
 	debug bool
@@ -49,6 +50,27 @@ type DefaultServer struct {
 	// global rate limiting for queries
 	queryRateLimiter *rate.Limiter
 
+	// latency / error rate of each API route
+	routeStats *api.RouteStats
+
+	// load of the query tier (only tracked if metrics are enabled)
+	queryStats *api.QueryStats
+
+	// audit log of executed queries
+	queryAuditLog     *audit.Log
+	queryTenantHeader string
+
+	// embedded web UI
+	ui bool
+
+	// maximum request body sizes (default and per route) and response compression
+	maxBodySize       int64
+	routeMaxBodySizes map[string]int64
+	compression       bool
+
+	// features of the service, exposed via the runtime info endpoint
+	features api.Features
+
 	srv    *http.Server
 	router *gin.Engine
 
@@ -94,10 +116,84 @@ func WithQueryRateLimit(r rate.Limit, b int) Option {
 	}
 }
 
+// WithQueryAudit records all queries in the provided audit log and exposes it via the audit endpoint.
+// The tenant running a query is identified via the tenantHeader
+func WithQueryAudit(log *audit.Log, tenantHeader string) Option {
+	return func(server *DefaultServer) {
+		if log != nil {
+			server.queryAuditLog = log
+			server.queryTenantHeader = tenantHeader
+			if server.queryTenantHeader == "" {
+				server.queryTenantHeader = audit.DefaultTenantHeader
+			}
+		}
+	}
+}
+
+// WithKeys sets the API keys authorizing access to all routes requiring authentication (c.f. Authorized())
+func WithKeys(keys []string) Option {
+	return func(server *DefaultServer) {
+		server.keys = keys
+	}
+}
+
+// WithUI serves the embedded web UI (interface status and a basic query form)
+func WithUI(enabled bool) Option {
+	return func(server *DefaultServer) {
+		server.ui = enabled
+	}
+}
+
+// WithMaxBodySize sets the maximum size (in bytes) of the request bodies of all routes without an explicit
+// limit (c.f. WithRouteMaxBodySize). A value of zero retains the default (api.DefaultMaxBodySize), a
+// negative value disables the limit
+func WithMaxBodySize(maxSize int64) Option {
+	return func(server *DefaultServer) {
+		if maxSize != 0 {
+			server.maxBodySize = maxSize
+		}
+	}
+}
+
+// WithRouteMaxBodySize sets the maximum size (in bytes) of the request bodies of a route (denoted by its
+// path template, e.g. "/status/:interface"). A value of zero retains the current limit, a negative value
+// disables the limit for the route
+func WithRouteMaxBodySize(route string, maxSize int64) Option {
+	return func(server *DefaultServer) {
+		if maxSize == 0 {
+			return
+		}
+		if server.routeMaxBodySizes == nil {
+			server.routeMaxBodySizes = make(map[string]int64)
+		}
+		server.routeMaxBodySizes[route] = maxSize
+	}
+}
+
+// WithCompression enables the compression of response bodies (zstd / gzip, as negotiated via the
+// Accept-Encoding header of a request)
+func WithCompression(enabled bool) Option {
+	return func(server *DefaultServer) {
+		server.compression = enabled
+	}
+}
+
+// WithFeatures adds the features of a module of the service (e.g. which optional components are enabled)
+// to the runtime info endpoint. The features of the API server itself are always included
+func WithFeatures(module string, features map[string]bool) Option {
+	return func(server *DefaultServer) {
+		if server.features == nil {
+			server.features = make(api.Features)
+		}
+		server.features[module] = features
+	}
+}
+
 // NewDefault creates a new API server
 func NewDefault(serviceName, addr string, opts ...Option) *DefaultServer {
 	s := &DefaultServer{
-		addr: addr,
+		addr:        addr,
+		maxBodySize: api.DefaultMaxBodySize,
 		// make sure that serviceName conforms to the prometheus naming convention. Exhaustive would be stripping
 		// the serviceName off any characters that are not permitted
 		serviceName: strings.ToLower(serviceName),
@@ -121,12 +217,29 @@ func NewDefault(serviceName, addr string, opts ...Option) *DefaultServer {
 		opt(s)
 	}
 
+	// route statistics are always tracked (they are exposed via the info endpoint), the
+	// corresponding prometheus metrics only if enabled
+	s.routeStats = api.NewRouteStats(s.requestDurationBuckets...)
+	if s.metrics {
+		s.routeStats.WithPrometheus(s.serviceName)
+		s.queryStats = api.NewQueryStats(s.serviceName)
+	}
+
 	// register info routes before any other middleware so they are exempt from logging
 	// and/or tracing
 	s.registerInfoRoutes()
 
+	// the UI only consists of static files, requesting them is equally exempt from logging / tracing
+	if s.ui {
+		ui.Register(s.router)
+	}
+
 	s.registerMiddlewares()
 
+	if s.queryAuditLog != nil {
+		s.router.GET(api.AuditRoute, api.AuditHandler(s.queryAuditLog))
+	}
+
 	return s
 }
 
@@ -140,11 +253,55 @@ func (server *DefaultServer) QueryRateLimiter() (*rate.Limiter, bool) {
 	return server.queryRateLimiter, server.queryRateLimiter != nil
 }
 
+// Authorized returns a middleware admitting only requests presenting one of the configured API keys. If
+// no keys are configured, all requests are rejected
+func (server *DefaultServer) Authorized() gin.HandlerFunc {
+	return api.APIKeyMiddleware(server.keys)
+}
+
+// HasKeys returns if any API keys are configured (c.f. WithKeys())
+func (server *DefaultServer) HasKeys() bool {
+	return len(server.keys) > 0
+}
+
+// QueryAuditLog returns the audit log of executed queries, if enabled (if not it returns nil and false)
+func (server *DefaultServer) QueryAuditLog() (*audit.Log, bool) {
+	return server.queryAuditLog, server.queryAuditLog != nil
+}
+
+// QueryStats returns the tracker of the query tier load, if metrics are enabled (if not it returns nil and false)
+func (server *DefaultServer) QueryStats() (*api.QueryStats, bool) {
+	return server.queryStats, server.queryStats != nil
+}
+
 func (server *DefaultServer) registerInfoRoutes() {
 	// make sure these endpoints don't interfere with the standard API path
-	server.router.GET(api.InfoRoute, api.ServiceInfoHandler(server.serviceName))
+	server.router.GET(api.InfoRoute, api.ServiceInfoHandler(server.serviceName, server.routeStats))
 	server.router.GET(api.HealthRoute, api.HealthHandler())
 	server.router.GET(api.ReadyRoute, api.ReadyHandler())
+	server.router.GET(api.RuntimeInfoRoute, api.RuntimeInfoHandler(server.serviceName, server.runtimeFeatures()))
+	server.router.GET(api.APICapabilitiesRoute, api.APICapabilitiesHandler(server.runtimeFeatures()))
+}
+
+// apiModule denotes the module the features of the API server are reported for
+const apiModule = "api"
+
+func (server *DefaultServer) runtimeFeatures() api.Features {
+	features := make(api.Features, len(server.features)+1)
+	for module, flags := range server.features {
+		features[module] = flags
+	}
+	features[apiModule] = map[string]bool{
+		"authentication":   len(server.keys) > 0,
+		"compression":      server.compression,
+		"metrics":          server.metrics,
+		"profiling":        server.profiling,
+		"query_audit":      server.queryAuditLog != nil,
+		"query_rate_limit": server.queryRateLimiter != nil,
+		"tracing":          server.tracing,
+		"ui":               server.ui,
+	}
+	return features
 }
 
 func (server *DefaultServer) registerMiddlewares() {
@@ -155,7 +312,7 @@ func (server *DefaultServer) registerMiddlewares() {
 				// make sure the excluded endpoints don't get traced
 				func(req *http.Request) bool {
 					for _, path := range []string{
-						api.InfoRoute, api.HealthRoute, api.ReadyRoute,
+						api.InfoRoute, api.HealthRoute, api.ReadyRoute, api.RuntimeInfoRoute, api.APICapabilitiesRoute,
 					} {
 						// paths prefixed with /- should also be excluded. It's a convention pushed in prometheus projects and quite used in osag
 						if req.URL.Path == path || req.URL.Path == "/-"+path {
@@ -171,8 +328,16 @@ func (server *DefaultServer) registerMiddlewares() {
 	middlewares = append(middlewares,
 		api.TraceIDMiddleware(),
 		api.RequestLoggingMiddleware(),
+		api.RouteStatsMiddleware(server.routeStats),
 		api.RecursionDetectorMiddleware(RuntimeIDHeaderKey, info.RuntimeID()),
+		api.BodySizeLimitMiddleware(server.maxBodySize, server.routeMaxBodySizes),
 	)
+	if server.compression {
+		middlewares = append(middlewares, api.CompressionMiddleware(api.DefaultCompressionMinSize))
+	}
+	if server.queryAuditLog != nil {
+		middlewares = append(middlewares, api.AuditIdentityMiddleware(server.queryTenantHeader))
+	}
 
 	server.router.Use(middlewares...)
 
@@ -181,9 +346,13 @@ func (server *DefaultServer) registerMiddlewares() {
 		if len(server.requestDurationBuckets) > 0 {
 			buckets = server.requestDurationBuckets
 		}
+		// the plain text exposition of the metrics middleware is moved aside in favor of an endpoint
+		// also supporting the OpenMetrics format
 		metrics.NewPrometheus(server.serviceName, "api").
 			WithRequestDurationBuckets(buckets).
+			WithMetricsPath(api.TextMetricsRoute).
 			Register(server.router)
+		server.router.GET(api.MetricsRoute, api.MetricsHandler())
 	}
 	if server.profiling {
 		api.RegisterProfiling(server.router)
@@ -0,0 +1,271 @@
+// Minimal goProbe / global-query web UI. Everything shown is retrieved from the regular API,
+// the UI itself does not keep any server-side state.
+"use strict";
+
+const statusInterval = 5000; // polling interval of the status endpoint (in ms)
+const dropHistory = 60;      // number of drop rate samples kept per interface
+
+const dropRates = {};
+let lastStatus = null;
+
+const fmt = new Intl.NumberFormat();
+
+function humanize(value, base, units) {
+  let i = 0;
+  while (value >= base && i < units.length - 1) {
+    value /= base;
+    i++;
+  }
+  return (i === 0 ? value : value.toFixed(2)) + " " + units[i];
+}
+
+function bytes(value) {
+  return humanize(value, 1024, ["B", "kB", "MB", "GB", "TB", "PB"]);
+}
+
+function packets(value) {
+  return humanize(value, 1000, ["", "k", "M", "G", "T"]).trim();
+}
+
+function cell(row, text, numeric) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (numeric) {
+    td.className = "num";
+  }
+  return td;
+}
+
+function sparkline(values) {
+  const width = 160, height = 24;
+  const svgNS = "http://www.w3.org/2000/svg";
+  const svg = document.createElementNS(svgNS, "svg");
+  svg.setAttribute("class", "sparkline");
+  svg.setAttribute("width", width);
+  svg.setAttribute("height", height);
+
+  const max = Math.max(1, ...values);
+  const step = width / (dropHistory - 1);
+  const offset = width - (values.length - 1) * step;
+  const path = document.createElementNS(svgNS, "path");
+  path.setAttribute("d", values.map((v, i) =>
+    (i === 0 ? "M" : "L") + (offset + i * step).toFixed(1) + "," + (height - 1 - (v / max) * (height - 2)).toFixed(1)
+  ).join(" "));
+  svg.appendChild(path);
+
+  const title = document.createElementNS(svgNS, "title");
+  title.textContent = "current: " + (values[values.length - 1] || 0).toFixed(1) + " drops/s, max: " + max.toFixed(1) + " drops/s";
+  svg.appendChild(title);
+
+  return svg;
+}
+
+async function apiCall(path, options) {
+  const resp = await fetch(path, options);
+  if (!resp.ok) {
+    let msg = resp.status + " " + resp.statusText;
+    try {
+      const body = await resp.json();
+      if (body.error) {
+        msg = body.error;
+      }
+    } catch (e) {
+      // no JSON error payload, stick with the status
+    }
+    const err = new Error(msg);
+    err.status = resp.status;
+    throw err;
+  }
+  return resp.json();
+}
+
+async function updateStatus() {
+  let status;
+  try {
+    status = await apiCall("/status");
+  } catch (err) {
+    // global-query does not provide an interface status, hence the panel remains hidden
+    if (err.status === 404) {
+      return false;
+    }
+    document.getElementById("status-updated").textContent = "update failed: " + err.message;
+    return true;
+  }
+
+  const now = Date.now();
+  const ifaces = Object.keys(status.statuses || {}).sort();
+  if (lastStatus !== null) {
+    const elapsed = (now - lastStatus.time) / 1000;
+    for (const iface of ifaces) {
+      const prev = lastStatus.statuses[iface];
+      const cur = status.statuses[iface];
+      if (!prev || cur.dropped_total < prev.dropped_total) {
+        continue;
+      }
+      const rates = dropRates[iface] || (dropRates[iface] = []);
+      rates.push((cur.dropped_total - prev.dropped_total) / elapsed);
+      if (rates.length > dropHistory) {
+        rates.shift();
+      }
+    }
+  }
+  lastStatus = { time: now, statuses: status.statuses || {} };
+
+  const tbody = document.querySelector("#status-table tbody");
+  tbody.replaceChildren();
+  for (const iface of ifaces) {
+    const stats = status.statuses[iface];
+    const row = tbody.insertRow();
+    cell(row, iface);
+    cell(row, new Date(stats.started_at).toLocaleString());
+    cell(row, fmt.format(stats.received_total), true);
+    cell(row, fmt.format(stats.processed_total), true);
+    cell(row, fmt.format(stats.dropped_total), true);
+    cell(row, fmt.format(stats.filtered_total), true);
+    row.insertCell().appendChild(sparkline(dropRates[iface] || []));
+  }
+
+  document.getElementById("status").hidden = false;
+  document.getElementById("status-updated").textContent = "updated " + new Date(now).toLocaleTimeString() +
+    (status.last_writeout ? ", last writeout " + new Date(status.last_writeout).toLocaleTimeString() : "");
+  return true;
+}
+
+function queryArgs(form) {
+  const data = new FormData(form);
+  const args = { format: "json", sort_by: data.get("sort_by") };
+  for (const key of ["query", "ifaces", "query_hosts", "condition", "first", "last"]) {
+    const val = (data.get(key) || "").trim();
+    if (val !== "") {
+      args[key] = val;
+    }
+  }
+  const numResults = parseInt(data.get("num_results"), 10);
+  if (numResults > 0) {
+    args.num_results = numResults;
+  }
+  return args;
+}
+
+function attributeColumns(rows) {
+  const columns = [];
+  for (const group of ["labels", "attributes"]) {
+    for (const row of rows) {
+      for (const key of Object.keys(row[group] || {})) {
+        if (!columns.some((c) => c.group === group && c.key === key)) {
+          columns.push({ group: group, key: key });
+        }
+      }
+    }
+  }
+  return columns;
+}
+
+function renderResult(result, sortBy) {
+  const rows = result.rows || [];
+  const columns = attributeColumns(rows);
+  const counter = (row) => {
+    const c = row.counters || {};
+    return sortBy === "packets" ? (c.pr || 0) + (c.ps || 0) : (c.br || 0) + (c.bs || 0);
+  };
+
+  const summary = result.summary || {};
+  const totals = summary.totals || {};
+  document.getElementById("query-summary").textContent = rows.length + " of " + fmt.format(summary.hits ? summary.hits.total : rows.length) +
+    " flows, total " + bytes((totals.br || 0) + (totals.bs || 0)) + " / " + packets((totals.pr || 0) + (totals.ps || 0)) + " packets";
+
+  // table rendering
+  const table = document.getElementById("query-table");
+  const head = table.tHead;
+  head.replaceChildren();
+  const headRow = head.insertRow();
+  for (const col of columns) {
+    headRow.appendChild(document.createElement("th")).textContent = col.key;
+  }
+  for (const name of ["Bytes in", "Bytes out", "Packets in", "Packets out"]) {
+    const th = headRow.appendChild(document.createElement("th"));
+    th.textContent = name;
+    th.className = "num";
+  }
+
+  const tbody = table.tBodies[0];
+  tbody.replaceChildren();
+  for (const row of rows) {
+    const tr = tbody.insertRow();
+    for (const col of columns) {
+      const val = (row[col.group] || {})[col.key];
+      cell(tr, col.key === "timestamp" && val ? new Date(val).toLocaleString() : (val === undefined ? "" : String(val)));
+    }
+    const c = row.counters || {};
+    cell(tr, bytes(c.br || 0), true);
+    cell(tr, bytes(c.bs || 0), true);
+    cell(tr, packets(c.pr || 0), true);
+    cell(tr, packets(c.ps || 0), true);
+  }
+  table.hidden = rows.length === 0;
+
+  // chart rendering (horizontal bars relative to the largest row)
+  const chart = document.getElementById("query-chart");
+  chart.replaceChildren();
+  if (sortBy === "time") {
+    return;
+  }
+  const max = Math.max(1, ...rows.map(counter));
+  for (const row of rows) {
+    const bar = chart.appendChild(document.createElement("div"));
+    bar.className = "bar";
+    bar.appendChild(document.createElement("span")).textContent = columns
+      .filter((col) => col.group === "attributes")
+      .map((col) => (row.attributes || {})[col.key])
+      .filter((val) => val !== undefined)
+      .join(" / ");
+    bar.appendChild(document.createElement("div")).style.width = (60 * counter(row) / max) + "%";
+    bar.appendChild(document.createElement("span")).textContent = sortBy === "packets" ? packets(counter(row)) : bytes(counter(row));
+  }
+}
+
+async function runQuery(event) {
+  event.preventDefault();
+  const form = event.target;
+  const errorField = document.getElementById("query-error");
+  const button = form.querySelector("button");
+
+  const args = queryArgs(form);
+  errorField.hidden = true;
+  button.disabled = true;
+  try {
+    const result = await apiCall("/_query", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(args),
+    });
+    renderResult(result, args.sort_by);
+  } catch (err) {
+    errorField.textContent = "query failed: " + err.message;
+    errorField.hidden = false;
+  } finally {
+    button.disabled = false;
+  }
+}
+
+async function init() {
+  try {
+    const info = await apiCall("/-/info");
+    document.getElementById("service").textContent = info.name + " " + info.version;
+    document.title = info.name;
+  } catch (e) {
+    // the service info is purely cosmetic
+  }
+
+  document.getElementById("query-form").addEventListener("submit", runQuery);
+
+  if (await updateStatus()) {
+    setInterval(updateStatus, statusInterval);
+  } else {
+    // without interface status, the UI is served by global-query which requires the hosts to be queried
+    document.getElementById("hosts-field").hidden = false;
+    document.querySelector("#hosts-field input").required = true;
+  }
+}
+
+init();
@@ -0,0 +1,31 @@
+// Package ui provides a minimal, self-contained web UI (embedded into the binary) for small
+// deployments without a dedicated dashboarding solution. It provides the status of all interfaces
+// (including packet drops over time) and a simple query form, entirely backed by the existing API
+package ui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed static
+var static embed.FS
+
+// Register serves the web UI via the provided router (on api.UIRoute) and redirects requests
+// to the root path to it
+func Register(router gin.IRoutes) {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		// cannot happen, the directory is embedded at compile time
+		panic(err)
+	}
+
+	router.StaticFS(api.UIRoute, http.FS(files))
+	router.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, api.UIRoute+"/")
+	})
+}
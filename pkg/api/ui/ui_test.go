@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/api"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	Register(router)
+
+	var tests = []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{api.UIRoute + "/", http.StatusOK, "html"},
+		{api.UIRoute + "/app.js", http.StatusOK, "javascript"},
+		{api.UIRoute + "/style.css", http.StatusOK, "css"},
+		{api.UIRoute + "/missing.js", http.StatusNotFound, ""},
+		{"/", http.StatusFound, ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+			require.Equal(t, test.status, rec.Code)
+			if test.contentType != "" {
+				require.Contains(t, rec.Header().Get("Content-Type"), test.contentType)
+				require.NotZero(t, rec.Body.Len())
+			}
+		})
+	}
+}
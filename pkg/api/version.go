@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion denotes a version of the query API, determining the schema results are rendered in
+type APIVersion string
+
+const (
+	// APIVersionV1 renders results in the original schema (see results.Result)
+	APIVersionV1 APIVersion = "v1"
+	// APIVersionV2 renders results in the extended schema (see results.ResultV2)
+	APIVersionV2 APIVersion = "v2"
+
+	// DefaultAPIVersion is the version used if a client doesn't request a specific one
+	DefaultAPIVersion = APIVersionV1
+)
+
+// APIVersions lists all supported versions of the query API
+var APIVersions = []APIVersion{APIVersionV1, APIVersionV2}
+
+// APIVersionHeader denotes the response header stating the version of the query API a result was
+// rendered in
+const APIVersionHeader = "X-GOPROBE-API-VERSION"
+
+const (
+	versionedMediaTypePrefix = "application/vnd.goprobe."
+	versionedMediaTypeSuffix = "+json"
+
+	apiVersionKey = "api_version"
+)
+
+// ErrUnsupportedAPIVersion denotes that a client requested a version of the query API which isn't supported
+var ErrUnsupportedAPIVersion = errors.New("unsupported API version")
+
+// MediaType returns the media type a client can provide via the Accept header in order to request
+// results in this version (e.g. application/vnd.goprobe.v2+json)
+func (v APIVersion) MediaType() string {
+	return versionedMediaTypePrefix + string(v) + versionedMediaTypeSuffix
+}
+
+// Route returns the route prefixed by the version (e.g. /v2/_query), pinning all requests to it to
+// this version of the query API
+func (v APIVersion) Route(route string) string {
+	return "/" + string(v) + route
+}
+
+func (v APIVersion) isSupported() bool {
+	for _, version := range APIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// APIVersionMiddleware pins all requests handled by it to a version of the query API (as done for
+// the versioned routes, see APIVersion.Route()), taking precedence over the Accept header
+func APIVersionMiddleware(version APIVersion) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// NegotiateAPIVersion determines the version of the query API requested by a client: the version of
+// the route (if versioned), the version of the media type provided via the Accept header (if any)
+// or the default version otherwise
+func NegotiateAPIVersion(c *gin.Context) (APIVersion, error) {
+	if version, exists := c.Get(apiVersionKey); exists {
+		return version.(APIVersion), nil
+	}
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || !strings.HasPrefix(mediaType, versionedMediaTypePrefix) {
+			continue
+		}
+		version := APIVersion(strings.TrimSuffix(strings.TrimPrefix(mediaType, versionedMediaTypePrefix), versionedMediaTypeSuffix))
+		if !version.isSupported() {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedAPIVersion, mediaType)
+		}
+		return version, nil
+	}
+	return DefaultAPIVersion, nil
+}
+
+// Render returns the representation of the result in the schema of the version
+func (v APIVersion) Render(result *results.Result) any {
+	if v == APIVersionV2 {
+		return result.V2()
+	}
+	return result
+}
+
+// APICapabilities describes the versions of the query API supported by a service and the features
+// enabled in it, such that clients can determine how to interact with it
+type APICapabilities struct {
+	Versions       []APIVersion          `json:"versions"`           // Versions: the supported versions of the query API. Example: ["v1", "v2"]
+	DefaultVersion APIVersion            `json:"default_version"`    // DefaultVersion: the version used if a client doesn't request a specific one. Example: v1
+	MediaTypes     map[APIVersion]string `json:"media_types"`        // MediaTypes: the media types requesting a specific version via the Accept header. Example: {"v2": "application/vnd.goprobe.v2+json"}
+	Features       Features              `json:"features,omitempty"` // Features: enabled / disabled features, grouped by module
+}
+
+// APICapabilitiesHandler returns the handler describing the capabilities of the API of the service
+func APICapabilitiesHandler(features Features) gin.HandlerFunc {
+	capabilities := APICapabilities{
+		Versions:       APIVersions,
+		DefaultVersion: DefaultAPIVersion,
+		MediaTypes:     make(map[APIVersion]string, len(APIVersions)),
+		Features:       features,
+	}
+	for _, version := range APIVersions {
+		capabilities.MediaTypes[version] = version.MediaType()
+	}
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, capabilities)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := func(c *gin.Context) {
+		version, err := NegotiateAPIVersion(c)
+		if err != nil {
+			c.AbortWithStatus(http.StatusNotAcceptable)
+			return
+		}
+		c.String(http.StatusOK, string(version))
+	}
+
+	router := gin.New()
+	router.GET(QueryRoute, handler)
+	for _, version := range APIVersions {
+		router.GET(version.Route(QueryRoute), APIVersionMiddleware(version), handler)
+	}
+
+	for _, c := range []struct {
+		path     string
+		accept   string
+		expected string
+		code     int
+	}{
+		{"/_query", "", "v1", http.StatusOK},
+		{"/_query", "application/json", "v1", http.StatusOK},
+		{"/_query", "application/vnd.goprobe.v2+json", "v2", http.StatusOK},
+		{"/_query", "text/event-stream, application/vnd.goprobe.v2+json; q=0.9", "v2", http.StatusOK},
+		{"/_query", "application/vnd.goprobe.v1+json", "v1", http.StatusOK},
+		{"/_query", "application/vnd.goprobe.v3+json", "", http.StatusNotAcceptable},
+		{"/v1/_query", "", "v1", http.StatusOK},
+		{"/v2/_query", "", "v2", http.StatusOK},
+
+		// the version of the route takes precedence
+		{"/v1/_query", "application/vnd.goprobe.v2+json", "v1", http.StatusOK},
+		{"/v2/_query", "application/vnd.goprobe.v3+json", "v2", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		require.Equal(t, c.code, rec.Code, "%s (Accept: %s)", c.path, c.accept)
+		if c.code == http.StatusOK {
+			require.Equal(t, c.expected, rec.Body.String(), "%s (Accept: %s)", c.path, c.accept)
+		}
+	}
+}
+
+func TestAPICapabilitiesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET(APICapabilitiesRoute, APICapabilitiesHandler(Features{"api": {"metrics": true}}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, APICapabilitiesRoute, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var capabilities APICapabilities
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &capabilities))
+	require.Equal(t, APICapabilities{
+		Versions:       []APIVersion{APIVersionV1, APIVersionV2},
+		DefaultVersion: APIVersionV1,
+		MediaTypes: map[APIVersion]string{
+			APIVersionV1: "application/vnd.goprobe.v1+json",
+			APIVersionV2: "application/vnd.goprobe.v2+json",
+		},
+		Features: Features{"api": {"metrics": true}},
+	}, capabilities)
+}
@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/fako1024/slimcap/capture/pcap"
+)
+
+var (
+	// ErrBackfillNotSupported denotes that the writeout handler of the manager does not support backfilling
+	ErrBackfillNotSupported = errors.New("writeout handler does not support backfilling")
+
+	// ErrIngestInFuture denotes that the interval of ingested flows ends in the future (which would interfere
+	// with the regular writeouts)
+	ErrIngestInFuture = errors.New("invalid interval: end must not be in the future")
+)
+
+// Backfill re-writes / backfills the interval [from, to] of an interface. If no pcap file is provided,
+// all writeouts of the interface in the interval retained in the spill buffer of the writeout handler
+// are written. Otherwise, all packets contained in the pcap file (located on the host goProbe is running
+// on) are attributed to the interval and written as a single block with the timestamp of its end
+func (cm *Manager) Backfill(ctx context.Context, iface string, from, to time.Time, pcapPath string) ([]capturetypes.BackfillResult, error) {
+	backfiller, ok := cm.writeoutHandler.(writeout.Backfiller)
+	if !ok {
+		return nil, ErrBackfillNotSupported
+	}
+
+	if pcapPath == "" {
+		return backfiller.Backfill(ctx, iface, from, to)
+	}
+	if from.After(to) {
+		return nil, writeout.ErrInvalidInterval
+	}
+
+	f, err := os.Open(filepath.Clean(pcapPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pcap file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	flows, stats, err := ParsePcap(iface, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pcap file %s: %w", pcapPath, err)
+	}
+
+	res, err := backfiller.BackfillFlows(ctx, to, capturetypes.TaggedAggFlowMap{
+		Map:   flows,
+		Stats: stats,
+		Iface: iface,
+	}, gpfile.BackfillSourcePcap)
+	if err != nil {
+		return nil, err
+	}
+
+	return []capturetypes.BackfillResult{res}, nil
+}
+
+// Ingest writes externally generated flow aggregates of an interface, attributed to the interval [from, to],
+// as a single block with the timestamp of its end. Any block already present for the same timestamp is
+// replaced and the provenance of the block is recorded in the goDB metadata
+func (cm *Manager) Ingest(ctx context.Context, iface string, from, to time.Time, flows *hashmap.AggFlowMap) (capturetypes.BackfillResult, error) {
+	backfiller, ok := cm.writeoutHandler.(writeout.Backfiller)
+	if !ok {
+		return capturetypes.BackfillResult{}, ErrBackfillNotSupported
+	}
+	if err := validateIfaceName(iface); err != nil {
+		return capturetypes.BackfillResult{}, err
+	}
+	if from.After(to) {
+		return capturetypes.BackfillResult{}, writeout.ErrInvalidInterval
+	}
+	if to.After(cm.clock.Now()) {
+		return capturetypes.BackfillResult{}, ErrIngestInFuture
+	}
+
+	return backfiller.BackfillFlows(ctx, to, capturetypes.TaggedAggFlowMap{
+		Map:   flows,
+		Iface: iface,
+	}, gpfile.BackfillSourceIngest)
+}
+
+// ParsePcap reads all packets from a pcap file (optionally gzip compressed) and aggregates them into a
+// single flow map, as if they had been captured on an interface during a single rotation. Since packet
+// timestamps are not taken into account, no TCP handshake round trip times are estimated
+func ParsePcap(iface string, r io.Reader) (*hashmap.AggFlowMap, capturetypes.CaptureStats, error) {
+	var stats capturetypes.CaptureStats
+
+	src, err := pcap.NewSource(iface, r)
+	if err != nil {
+		return nil, stats, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	// Without a buffer, the IP layer is returned from the internal buffer of the source (valid until the
+	// next packet is read), which is sufficient since each packet is processed right away
+	flowLog := NewFlowLog()
+	for {
+		ipLayer, pktType, pktSize, err := src.NextIPPacket(nil)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, stats, err
+		}
+		stats.Received++
+
+		epHash, isIPv4, auxInfo, errno := ParsePacket(ipLayer)
+		errno = flowLog.Add(epHash, pktType, pktSize, isIPv4, auxInfo, errno)
+		stats.Processed++
+		if !isIPv4 && errno == capturetypes.ErrnoOK {
+			if fragID, isFirstFragment := ParseIPv6FragmentID(ipLayer); isFirstFragment {
+				flowLog.ObserveFragment(epHash, fragID)
+			}
+		}
+		if errno.ParsingFailed() {
+			stats.ParsingErrors[errno]++
+		}
+	}
+
+	flows, _, _ := flowLog.Rotate()
+	return flows, stats, nil
+}
@@ -4,17 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/els0r/goProbe/cmd/goProbe/config"
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/filter"
+	"github.com/els0r/goProbe/pkg/capture/hostaddrs"
+	"github.com/els0r/goProbe/pkg/capture/netns"
+	"github.com/els0r/goProbe/pkg/capture/nicprobe"
+	"github.com/els0r/goProbe/pkg/capture/tagging"
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
 	"github.com/els0r/telemetry/logging"
 	"github.com/fako1024/slimcap/capture"
 	"github.com/fako1024/slimcap/capture/afpacket/afring"
-	"github.com/fako1024/slimcap/link"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -29,8 +37,8 @@ var (
 	ErrLocalBufferOverflow = errors.New("local packet buffer overflow")
 
 	defaultSourceInitFn = func(c *Capture) (Source, error) {
-		return afring.NewSource(c.iface,
-			afring.CaptureLength(link.CaptureLengthMinimalIPv6Transport),
+		return afring.NewSource(c.device(),
+			afring.CaptureLength(captureLengthIPv6ExtHeaders),
 			afring.BufferSize(c.config.RingBuffer.BlockSize, c.config.RingBuffer.NumBlocks),
 			afring.Promiscuous(c.config.Promisc),
 		)
@@ -106,6 +114,9 @@ type Capture struct {
 
 	config config.CaptureConfig
 
+	// IP based allow / deny filter (if configured)
+	filter *filter.Filter
+
 	// stats from the last rotation or reset (needed for Status)
 	stats capturetypes.CaptureStats
 
@@ -120,25 +131,77 @@ type Capture struct {
 	captureHandle Source
 	sourceInitFn  sourceInitFn
 
+	// Flow source (replacing the capture handle if the eBPF capture driver is used)
+	flowSource       *flowSourceState
+	flowSourceInitFn flowSourceInitFn
+
 	// Error tracking (type / errno specific)
 	// parsingErrors ParsingErrTracker
 
+	// Dumps of packets that could not be parsed (if configured)
+	errDumper *errorDumper
+
+	// Path of the network namespace the interface resides in (if not the one of goProbe itself),
+	// resolved whenever the capture is (re-)initialized
+	netnsPath string
+
 	// WaitGroup tracking active processing
 	wgProc sync.WaitGroup
 
+	// CPU time consumed by the processing routine
+	cpu cpuTracker
+
+	// Idle standby state (if configured)
+	standby *standbyState
+
+	// Watchdog state, detecting a stalled processing routine (if configured)
+	watchdog *watchdogState
+
+	// Verification of the packet counters upon rotation (if enabled)
+	counterCheck *counterCheck
+
 	// startedAt tracks when the capture was started
 	startedAt time.Time
+
+	// Time source (inherited from the capture manager)
+	clock clock.Clock
+
+	// Capabilities of the NIC backing the interface, as probed upon initialization (if available)
+	capabilities *capturetypes.IfaceCapabilities
+
+	// Most recent status, serving on-demand status requests
+	statusCache statusCache
+
+	// Assessment of the startup self-benchmark performed with the configuration of the capture (if any)
+	benchAssessment atomic.Pointer[capturetypes.BenchAssessment]
 }
 
 // newCapture creates a new Capture associated with the given iface.
 func newCapture(iface string, config config.CaptureConfig) *Capture {
-	return &Capture{
-		iface:        iface,
-		config:       config,
-		capLock:      newCaptureLock(),
-		flowLog:      NewFlowLog(),
-		sourceInitFn: defaultSourceInitFn,
+	c := &Capture{
+		iface:            iface,
+		config:           config,
+		capLock:          newCaptureLock(),
+		flowLog:          NewFlowLog(),
+		sourceInitFn:     defaultSourceInitFn,
+		flowSourceInitFn: defaultFlowSourceInitFn,
+		clock:            clock.Real,
+	}
+	if config.Standby != nil {
+		c.standby = newStandbyState(config.Standby)
+	}
+	if config.Watchdog != nil {
+		c.watchdog = newWatchdogState(config.Watchdog)
+	}
+	if config.VerifyCounters {
+		c.counterCheck = new(counterCheck)
+	}
+	if config.Emergency != nil {
+		c.flowLog.emergency = newEmergencyMode(config.Emergency, func() time.Time {
+			return c.clock.Now()
+		})
 	}
+	return c
 }
 
 // SetSourceInitFn sets a custom function used to initialize a new capture
@@ -152,21 +215,150 @@ func (c *Capture) Iface() string {
 	return c.iface
 }
 
+// device returns the name of the interface the capture is attached to, which differs from
+// the name of the capture if an interface inside a network namespace is renamed via the config
+func (c *Capture) device() string {
+	if c.config.Netns != nil && c.config.Netns.Device != "" {
+		return c.config.Netns.Device
+	}
+	return c.iface
+}
+
+// inNetns executes fn within the network namespace of the interface (if any)
+func (c *Capture) inNetns(fn func() error) error {
+	if c.netnsPath == "" {
+		return fn()
+	}
+	return netns.Do(c.netnsPath, fn)
+}
+
+// probeCapabilities queries the capabilities of the NIC backing the interface (within its network
+// namespace) and derives the configuration hints for the capture from them
+func (c *Capture) probeCapabilities() (caps capturetypes.IfaceCapabilities, err error) {
+	if err = c.inNetns(func() (err error) {
+		caps, err = nicprobe.Probe(c.device())
+		return
+	}); err != nil {
+		return
+	}
+	caps.Hints = nicprobe.Hints(c.device(), caps, c.config)
+
+	return
+}
+
+// probeLink queries the properties of the link backing the interface (within its network namespace),
+// returning nil if they cannot be determined (e.g. for synthetic interfaces)
+func (c *Capture) probeLink() *types.LinkInfo {
+	var link types.LinkInfo
+	if err := c.inNetns(func() (err error) {
+		link, err = nicprobe.Link(c.device())
+		return
+	}); err != nil || link.IsZero() {
+		return nil
+	}
+	return &link
+}
+
 func (c *Capture) run() (err error) {
 
-	// Set up the packet source and capturing
-	c.captureHandle, err = c.sourceInitFn(c)
+	// Compile the capture filter (if any) prior to capturing the first packet
+	if c.config.Filter != nil {
+		c.filter, err = filter.New(c.config.Filter.Allow, c.config.Filter.Deny)
+		if err != nil {
+			return withReason(capturetypes.ErrorReasonInvalidConfig, fmt.Errorf("failed to initialize capture filter: %w", err))
+		}
+	}
+
+	// Compile the tagging rules applying to this interface (if any)
+	c.flowLog.tagger, err = tagging.New(c.iface, c.config.Tagging)
 	if err != nil {
-		return fmt.Errorf("failed to initialize capture: %w", err)
+		return withReason(capturetypes.ErrorReasonInvalidConfig, fmt.Errorf("failed to initialize tagging rules: %w", err))
+	}
+
+	// Determine the network namespace the interface resides in (if any). Since the namespace of
+	// a container changes upon its restart, it is resolved anew each time
+	if c.config.Netns != nil {
+		c.netnsPath, err = netns.Resolve(context.Background(), c.config.Netns)
+		if err != nil {
+			return withReason(capturetypes.ErrorReasonNetnsUnavailable, fmt.Errorf("failed to resolve network namespace: %w", err))
+		}
+	}
+
+	// Determine the addresses of the capturing host on this interface (if any)
+	if c.config.HostAddrs != nil {
+		if err = c.inNetns(func() (err error) {
+			c.flowLog.hostAddrs, err = hostaddrs.New(c.device(), c.config.HostAddrs.Prefixes, c.config.HostAddrs.Discover)
+			return
+		}); err != nil {
+			return fmt.Errorf("failed to initialize host addresses: %w", err)
+		}
+	}
+
+	// Flows are aggregated in-kernel when using the eBPF capture driver, hence
+	// there is no packet source to set up
+	if c.config.IsEBPF() {
+		return c.runFlowSource()
+	}
+
+	if err = c.initSource(); err != nil {
+		return
 	}
 
 	// make sure to store when the capture started
-	c.startedAt = time.Now()
+	c.startedAt = c.clock.Now()
+	if c.standby != nil {
+		c.standby.lastActivity = c.startedAt
+	}
 
 	return
 }
 
+// initSource sets up the packet source and capturing (the socket remains bound to the network
+// namespace it was created in)
+func (c *Capture) initSource() error {
+	if err := c.inNetns(func() (err error) {
+		c.captureHandle, err = c.sourceInitFn(c)
+		return
+	}); err != nil {
+		return fmt.Errorf("failed to initialize capture: %w", err)
+	}
+	return nil
+}
+
 func (c *Capture) close() error {
+	if c.flowSource != nil {
+		return c.closeFlowSource()
+	}
+
+	// Stop polling for activity (if in standby), in which case there is no capture source left to close
+	if c.standby != nil {
+		c.standby.Lock()
+		defer c.standby.Unlock()
+
+		c.standby.halt()
+		if c.standby.released {
+			promStandby.DeleteLabelValues(c.iface)
+			c.haltWatchdog()
+			return nil
+		}
+	}
+
+	// Stop the watchdog, in which case there is no capture source left to close if it is being
+	// reinitialized
+	if c.watchdog != nil {
+		c.watchdog.Lock()
+		defer c.watchdog.Unlock()
+
+		c.watchdog.halt()
+		promAutoRecoveries.DeleteLabelValues(c.iface)
+		if c.watchdog.recovering {
+			if c.captureHandle != nil {
+				return errCaptureWedged
+			}
+			return nil
+		}
+	}
+
 	if err := c.captureHandle.Close(); err != nil {
 		return err
 	}
@@ -182,15 +374,41 @@ func (c *Capture) close() error {
 	return nil
 }
 
-func (c *Capture) rotate(ctx context.Context) (agg *hashmap.AggFlowMap) {
+// rotate retires the flows of the capture by swapping in its standby flow map. It must be called
+// while the capture is locked, but only takes constant time (the retired flows are aggregated using
+// aggregate() once the capture has been unlocked), so the capture never has to buffer packets for
+// longer than it takes to swap the flow maps
+func (c *Capture) rotate() (retired map[string]*Flow) {
+	if c.counterCheck != nil {
+		c.counterCheck.rotate()
+	}
+	return c.flowLog.swap()
+}
+
+// rotateEmergency concludes the rotation interval of the emergency aggregation mode (if enabled).
+// Like rotate(), it must be called while the capture is locked
+func (c *Capture) rotateEmergency() emergencyRotation {
+	if c.flowLog.emergency == nil {
+		return emergencyRotation{}
+	}
+	return c.flowLog.emergency.rotate()
+}
+
+// aggregate extracts the flows retired by rotate() and prepares the standby flow map for the next
+// rotation. It is safe to call while the capture is processing packets, but must not be called
+// concurrently to rotate()
+func (c *Capture) aggregate(ctx context.Context, retired map[string]*Flow) (agg *hashmap.AggFlowMap, totals *types.Counters, rtt *capturetypes.HandshakeRTT) {
 
 	logger := logging.FromContext(ctx)
 
-	// write how many flows are currently in the map
-	nFlows := c.flowLog.Len()
+	// write how many flows have been retired
+	nFlows := len(retired)
 
-	var totals = &types.Counters{}
+	totals = &types.Counters{}
 	defer func() {
+		// the flows discarded upon the swap are no longer referenced anywhere and can be cleared
+		c.flowLog.recycle()
+
 		go func(iface string) {
 			// write volume metrics to prometheus
 			promNumFlows.WithLabelValues(c.iface).Set(float64(nFlows))
@@ -201,6 +419,15 @@ func (c *Capture) rotate(ctx context.Context) (agg *hashmap.AggFlowMap) {
 				promPackets.WithLabelValues(iface, "inbound").Add(float64(totals.PacketsRcvd))
 				promPackets.WithLabelValues(iface, "outbound").Add(float64(totals.PacketsSent))
 			}
+
+			// expose the handshake round trip times of this rotation (if any)
+			promHandshakeRTT.DeletePartialMatch(prometheus.Labels{"iface": iface})
+			if rtt != nil {
+				promHandshakes.WithLabelValues(iface).Add(float64(rtt.Samples))
+				promHandshakeRTT.WithLabelValues(iface, "min").Set(rtt.Min.Seconds())
+				promHandshakeRTT.WithLabelValues(iface, "median").Set(rtt.Median.Seconds())
+				promHandshakeRTT.WithLabelValues(iface, "max").Set(rtt.Max.Seconds())
+			}
 		}(c.iface)
 	}()
 
@@ -208,7 +435,7 @@ func (c *Capture) rotate(ctx context.Context) (agg *hashmap.AggFlowMap) {
 		logger.Debug("there are currently no flow records available")
 		return
 	}
-	agg, totals = c.flowLog.Rotate()
+	agg, totals, rtt = aggregateFlows(retired)
 
 	return
 }
@@ -233,12 +460,25 @@ func (c *Capture) flowMap(ctx context.Context) (agg *hashmap.AggFlowMap) {
 // a serious capture error
 func (c *Capture) process() <-chan error {
 
+	// Flow sources are drained during lock(), so there is no processing loop to run
+	if c.flowSource != nil {
+		return c.flowSource.errs
+	}
+
 	captureErrors := make(chan error, 64)
 
 	c.wgProc.Add(1)
 	go func() {
 
+		// Lock the processing routine to its OS thread, allowing to attribute the CPU time
+		// consumed by the thread to this capture
+		runtime.LockOSThread()
+		c.cpu.attach()
+
 		defer func() {
+			c.cpu.detach()
+			runtime.UnlockOSThread()
+
 			close(captureErrors)
 			c.wgProc.Done()
 		}()
@@ -261,6 +501,10 @@ func (c *Capture) process() <-chan error {
 				// Claim / assign the shared data from the memory pool for / to this buffer
 				localBuf.Assign(buf)
 
+				// Packets discarded by the capture filter while buffering are only accounted for once
+				// the buffer is drained, since the stats are read (and reset) concurrently during the lock
+				var filtered uint64
+
 				// Continue fetching packets and add them to the local buffer
 				for {
 					if len(c.capLock.done) > 0 {
@@ -270,6 +514,9 @@ func (c *Capture) process() <-chan error {
 
 					// Fetch the next packet form the wire
 					ipLayer, pktType, pktSize, err := c.captureHandle.NextIPPacketZeroCopy()
+					if c.watchdog != nil {
+						c.watchdog.beat()
+					}
 					if err != nil {
 
 						// If we receive an unblock event while capturing to buffer, continue
@@ -288,6 +535,13 @@ func (c *Capture) process() <-chan error {
 
 					// Parse the packet and extract relevant data for future addition to the flow log
 					epHash, isIPv4, auxInfo, errno := ParsePacket(ipLayer)
+					if errno.ParsingFailed() {
+						c.errDumper.dump(ipLayer, pktType, pktSize, errno)
+					}
+					if c.isFiltered(epHash, isIPv4, errno) {
+						filtered++
+						continue
+					}
 
 					// Try to append to local buffer. In case the buffer is full, stop buffering and
 					// wait for the unlock request
@@ -299,6 +553,7 @@ func (c *Capture) process() <-chan error {
 				}
 
 				// Drain buffer if not empty
+				c.stats.Filtered += filtered
 				if localBuf.N() > 0 {
 					for i := 0; i < localBuf.N(); i++ {
 						c.addToFlowLog(localBuf.Get(i))
@@ -335,6 +590,9 @@ func (c *Capture) capturePacket() error {
 
 	// Fetch the next packet form the wire
 	ipLayer, pktType, pktSize, err := c.captureHandle.NextIPPacketZeroCopy()
+	if c.watchdog != nil {
+		c.watchdog.beat()
+	}
 	if err != nil {
 
 		// NextPacket should return a ErrCaptureStopped in case the handle is closed or
@@ -344,15 +602,53 @@ func (c *Capture) capturePacket() error {
 
 	// Parse the packet, extract relevant data and add to the flow log
 	epHash, isIPv4, auxInfo, errno := ParsePacket(ipLayer)
+	if errno.ParsingFailed() {
+		c.errDumper.dump(ipLayer, pktType, pktSize, errno)
+	}
+	if c.isFiltered(epHash, isIPv4, errno) {
+		c.stats.Filtered++
+		return nil
+	}
 	c.addToFlowLog(epHash, pktType, pktSize, isIPv4, auxInfo, errno)
 
+	// Record the TTL / hop limit of the packet (if enabled). Packets buffered while the flow log is
+	// locked (during rotation) are not taken into account
+	if c.config.RecordTTL && errno == capturetypes.ErrnoOK {
+		c.flowLog.ObserveTTL(epHash, ParseTTL(ipLayer, isIPv4))
+	}
+
+	// Track the first fragments of fragmented IPv6 packets, allowing to attribute subsequent fragments to
+	// the same flow (packets buffered while the flow log is locked are not taken into account)
+	if !isIPv4 && errno == capturetypes.ErrnoOK {
+		if fragID, isFirstFragment := ParseIPv6FragmentID(ipLayer); isFirstFragment {
+			c.flowLog.ObserveFragment(epHash, fragID)
+		}
+	}
+
+	// Track TCP handshakes in order to estimate their round trip times. The (comparatively expensive)
+	// timestamp is only taken for SYN / SYN-ACK packets to avoid any overhead for all other packets
+	if errno == capturetypes.ErrnoOK && epHash[36] == capturetypes.TCP && (capturetypes.IsSYN(auxInfo) || capturetypes.IsSYNACK(auxInfo)) {
+		c.flowLog.ObserveHandshake(epHash, auxInfo, time.Now().UnixNano())
+	}
+
 	return nil
 }
 
-func (c *Capture) addToFlowLog(epHash capturetypes.EPHash, pktType byte, pktSize uint32, isIPv4 bool, auxInfo byte, errno capturetypes.ParsingErrno) {
+// isFiltered determines if a successfully parsed packet is discarded by the capture filter
+// (if any) and hence must not be added to the flow log
+func (c *Capture) isFiltered(epHash capturetypes.EPHash, isIPv4 bool, errno capturetypes.ParsingErrno) bool {
+	if c.filter == nil || errno != capturetypes.ErrnoOK {
+		return false
+	}
+	return !c.filter.Permits(epHash, isIPv4)
+}
 
+func (c *Capture) addToFlowLog(epHash capturetypes.EPHash, pktType byte, pktSize uint32, isIPv4 bool, auxInfo byte, errno capturetypes.ParsingErrno) {
 	// Parse / add the received data to the map of flows
 	errno = c.flowLog.Add(epHash, pktType, pktSize, isIPv4, auxInfo, errno)
+	if c.counterCheck != nil {
+		c.counterCheck.observe(errno)
+	}
 	c.stats.Processed++
 	if errno == capturetypes.ErrnoOK {
 		return
@@ -364,26 +660,46 @@ func (c *Capture) addToFlowLog(epHash capturetypes.EPHash, pktType byte, pktSize
 }
 
 func (c *Capture) status() (*capturetypes.CaptureStats, error) {
+	if c.flowSource != nil {
+		return c.statusFlowSource()
+	}
+	if c.standby != nil && c.standby.released {
+		return c.statusStandby(), nil
+	}
+	if c.watchdog != nil && c.watchdog.recovering {
+		return c.statusRecovering(), nil
+	}
 
 	stats, err := c.captureHandle.Stats()
 	if err != nil {
 		return nil, err
 	}
 
+	// Keep track of the most recent activity on the interface (in order to determine when it is idle)
+	if c.standby != nil && stats.PacketsReceived > 0 {
+		c.standby.lastActivity = c.clock.Now()
+	}
+
 	c.stats.ReceivedTotal += stats.PacketsReceived
 	c.stats.ProcessedTotal += c.stats.Processed
 	c.stats.DroppedTotal += stats.PacketsDropped
+	c.stats.FilteredTotal += c.stats.Filtered
+
+	cpuTime := c.cpu.sample()
+	c.stats.CPUTimeTotal += cpuTime
 
 	// add exposed metrics
 	// we do this every 5 minutes only in order not to interfere with the
 	// main packet processing loop. If this counter moves slowly (as in gets
 	// gets an update only every 5 minutes) it's not an issue to understand
 	// processed data volumes across longer time frames
-	go func(iface string, processed, dropped, errors uint64) {
+	go func(iface string, processed, dropped, filtered, errors uint64, cpuTime time.Duration) {
 		promPacketsProcessed.WithLabelValues(iface).Add(float64(processed))
 		promPacketsDropped.WithLabelValues(iface).Add(float64(dropped))
+		promPacketsFiltered.WithLabelValues(iface).Add(float64(filtered))
 		promCaptureErrors.WithLabelValues(iface).Add(float64(errors))
-	}(c.iface, c.stats.Processed, stats.PacketsDropped, uint64(c.stats.ParsingErrors.Sum()))
+		promCPUSeconds.WithLabelValues(iface).Add(cpuTime.Seconds())
+	}(c.iface, c.stats.Processed, stats.PacketsDropped, c.stats.Filtered, uint64(c.stats.ParsingErrors.Sum()), cpuTime)
 
 	res := capturetypes.CaptureStats{
 		StartedAt:      c.startedAt,
@@ -393,10 +709,16 @@ func (c *Capture) status() (*capturetypes.CaptureStats, error) {
 		ProcessedTotal: c.stats.ProcessedTotal,
 		Dropped:        stats.PacketsDropped,
 		DroppedTotal:   c.stats.DroppedTotal,
+		Filtered:       c.stats.Filtered,
+		FilteredTotal:  c.stats.FilteredTotal,
+		CPUTime:        cpuTime,
+		CPUTimeTotal:   c.stats.CPUTimeTotal,
 		ParsingErrors:  c.stats.ParsingErrors,
+		AutoRecovered:  c.autoRecovered(),
 	}
 
 	c.stats.Processed = 0
+	c.stats.Filtered = 0
 	c.stats.ParsingErrors.Reset()
 
 	return &res, nil
@@ -422,6 +744,27 @@ func (c *Capture) fetchStatusInBackground(ctx context.Context) (res chan *captur
 }
 
 func (c *Capture) lock() {
+	if c.flowSource != nil {
+		c.lockFlowSource()
+		return
+	}
+
+	// A capture in standby has no processing routine to synchronize with, hence holding the standby
+	// lock (preventing it from being released / resumed concurrently) suffices
+	if c.standby != nil {
+		c.standby.Lock()
+		if c.standby.released {
+			return
+		}
+	}
+
+	// The same applies to a capture whose capture source is being reinitialized by the watchdog
+	if c.watchdog != nil {
+		c.watchdog.Lock()
+		if c.watchdog.recovering {
+			return
+		}
+	}
 
 	// Fetch data from the pool for the local buffer. Tis will wait until it is actually
 	// available, allowing us to use a single buffer for all interfaces
@@ -442,6 +785,22 @@ func (c *Capture) lock() {
 }
 
 func (c *Capture) unlock() {
+	if c.flowSource != nil {
+		c.unlockFlowSource()
+		return
+	}
+	if c.standby != nil {
+		defer c.standby.Unlock()
+		if c.standby.released {
+			return
+		}
+	}
+	if c.watchdog != nil {
+		defer c.watchdog.Unlock()
+		if c.watchdog.recovering {
+			return
+		}
+	}
 
 	// Signal that the rotation is complete, releasing the processing routine
 	// Since the done channel has a depth of one an Unblock() event needs to be
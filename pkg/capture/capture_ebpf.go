@@ -0,0 +1,185 @@
+package capture
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/ebpf"
+)
+
+// FlowSource denotes a source of flows aggregated outside of goProbe's packet processing
+// loop, e.g. in-kernel by the eBPF capture driver (see package ebpf)
+type FlowSource interface {
+
+	// Drain removes all flows aggregated since the last call from the source and calls fn
+	// for each of them
+	Drain(fn func(capturetypes.FlowSummary)) (int, error)
+
+	// Stats returns the cumulative statistics of the source
+	Stats() (ebpf.Stats, error)
+
+	// Close closes the source
+	Close() error
+}
+
+// flowSourceInitFn denotes the function used to initialize a flow source,
+// providing the ability to override the default behavior, e.g. in mock tests
+type flowSourceInitFn func(*Capture) (FlowSource, error)
+
+var defaultFlowSourceInitFn = func(c *Capture) (FlowSource, error) {
+	pinPath := filepath.Join(config.DefaultEBPFPinRoot, c.iface)
+	if c.config.EBPF != nil && c.config.EBPF.PinPath != "" {
+		pinPath = c.config.EBPF.PinPath
+	}
+
+	src, err := ebpf.Open(c.iface, pinPath)
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// flowSourceState holds the state of a capture using a flow source instead of a packet source
+type flowSourceState struct {
+	source FlowSource
+
+	// statistics of the source as of the last call to status()
+	lastStats ebpf.Stats
+
+	// errors encountered while draining the source
+	errs chan error
+
+	// mu serializes draining the source (during lock()) and closing it
+	mu sync.Mutex
+}
+
+// SetFlowSourceInitFn sets a custom function used to initialize a new flow source
+func (c *Capture) SetFlowSourceInitFn(fn flowSourceInitFn) *Capture {
+	c.flowSourceInitFn = fn
+	return c
+}
+
+func (c *Capture) runFlowSource() error {
+	source, err := c.flowSourceInitFn(c)
+	if err != nil {
+		return fmt.Errorf("failed to initialize eBPF flow source: %w", err)
+	}
+
+	c.flowSource = &flowSourceState{
+		source: source,
+		errs:   make(chan error, 64),
+	}
+
+	// Discard any flows aggregated before the capture was started (e.g. during a restart
+	// of goProbe) and use the current statistics as baseline
+	if _, err := source.Drain(func(capturetypes.FlowSummary) {}); err != nil {
+		_ = source.Close()
+		return fmt.Errorf("failed to drain eBPF flow source: %w", err)
+	}
+	if c.flowSource.lastStats, err = source.Stats(); err != nil {
+		_ = source.Close()
+		return fmt.Errorf("failed to fetch eBPF flow source stats: %w", err)
+	}
+
+	// make sure to store when the capture started
+	c.startedAt = c.clock.Now()
+
+	return nil
+}
+
+func (c *Capture) closeFlowSource() error {
+	c.flowSource.mu.Lock()
+	defer c.flowSource.mu.Unlock()
+
+	if c.flowSource.errs == nil {
+		return nil
+	}
+	if err := c.flowSource.source.Close(); err != nil {
+		return err
+	}
+
+	// Closing the error channel signals the end of processing
+	close(c.flowSource.errs)
+	c.flowSource.errs = nil
+
+	return nil
+}
+
+// lockFlowSource drains all flows aggregated since the last call into the flow log. The
+// lock is held until unlockFlowSource() is called
+func (c *Capture) lockFlowSource() {
+	c.flowSource.mu.Lock()
+
+	if c.flowSource.errs == nil {
+		return
+	}
+
+	_, err := c.flowSource.source.Drain(func(summary capturetypes.FlowSummary) {
+		nPackets := summary.PacketsRcvd + summary.PacketsSent
+		if c.filter != nil && !c.filter.Permits(summary.EPHash, summary.IsIPv4) {
+			c.stats.Filtered += nPackets
+			return
+		}
+
+		c.flowLog.AddSummary(summary)
+		c.stats.Processed += nPackets
+	})
+	if err != nil {
+		select {
+		case c.flowSource.errs <- err:
+		default:
+		}
+	}
+}
+
+func (c *Capture) unlockFlowSource() {
+	c.flowSource.mu.Unlock()
+}
+
+// statusFlowSource is the equivalent of status() for flow sources. Since no packets are
+// parsed in userspace, there are no parsing errors to report
+func (c *Capture) statusFlowSource() (*capturetypes.CaptureStats, error) {
+
+	stats, err := c.flowSource.source.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	// All counters of the source are cumulative, hence the difference to the last call is
+	// reported. Packets ignored by the eBPF program (e.g. non-IP packets) were received,
+	// but not processed
+	received := (stats.Packets - c.flowSource.lastStats.Packets) + (stats.Ignored - c.flowSource.lastStats.Ignored)
+	dropped := stats.Dropped - c.flowSource.lastStats.Dropped
+	c.flowSource.lastStats = stats
+
+	c.stats.ReceivedTotal += received
+	c.stats.ProcessedTotal += c.stats.Processed
+	c.stats.DroppedTotal += dropped
+	c.stats.FilteredTotal += c.stats.Filtered
+
+	go func(iface string, processed, dropped, filtered uint64) {
+		promPacketsProcessed.WithLabelValues(iface).Add(float64(processed))
+		promPacketsDropped.WithLabelValues(iface).Add(float64(dropped))
+		promPacketsFiltered.WithLabelValues(iface).Add(float64(filtered))
+	}(c.iface, c.stats.Processed, dropped, c.stats.Filtered)
+
+	res := capturetypes.CaptureStats{
+		StartedAt:      c.startedAt,
+		Received:       received,
+		ReceivedTotal:  c.stats.ReceivedTotal,
+		Processed:      c.stats.Processed,
+		ProcessedTotal: c.stats.ProcessedTotal,
+		Dropped:        dropped,
+		DroppedTotal:   c.stats.DroppedTotal,
+		Filtered:       c.stats.Filtered,
+		FilteredTotal:  c.stats.FilteredTotal,
+	}
+
+	c.stats.Processed = 0
+	c.stats.Filtered = 0
+
+	return &res, nil
+}
@@ -0,0 +1,113 @@
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/ebpf"
+	"github.com/stretchr/testify/require"
+)
+
+type mockFlowSource struct {
+	flows  []capturetypes.FlowSummary
+	stats  ebpf.Stats
+	closed bool
+}
+
+func (m *mockFlowSource) Drain(fn func(capturetypes.FlowSummary)) (int, error) {
+	for _, flow := range m.flows {
+		fn(flow)
+	}
+	n := len(m.flows)
+	m.flows = nil
+	return n, nil
+}
+
+func (m *mockFlowSource) Stats() (ebpf.Stats, error) {
+	return m.stats, nil
+}
+
+func (m *mockFlowSource) Close() error {
+	m.closed = true
+	return nil
+}
+
+func newFlowSummary(sip, dip string, dport, sport uint16, proto byte, bytesRcvd, bytesSent, packetsRcvd, packetsSent uint64) capturetypes.FlowSummary {
+	summary := capturetypes.FlowSummary{
+		IsIPv4:      true,
+		BytesRcvd:   bytesRcvd,
+		BytesSent:   bytesSent,
+		PacketsRcvd: packetsRcvd,
+		PacketsSent: packetsSent,
+	}
+	sipRaw, dipRaw := netip.MustParseAddr(sip).As4(), netip.MustParseAddr(dip).As4()
+	copy(summary.EPHash[0:4], sipRaw[:])
+	copy(summary.EPHash[16:20], dipRaw[:])
+	binary.BigEndian.PutUint16(summary.EPHash[32:34], dport)
+	binary.BigEndian.PutUint16(summary.EPHash[34:36], sport)
+	summary.EPHash[36] = proto
+
+	return summary
+}
+
+func TestFlowSourceCapture(t *testing.T) {
+
+	src := &mockFlowSource{
+		// stale flows / stats from before the capture was started
+		flows: []capturetypes.FlowSummary{newFlowSummary("10.0.0.1", "10.0.0.2", 80, 0, capturetypes.TCP, 1, 1, 1, 1)},
+		stats: ebpf.Stats{Packets: 100, Ignored: 10},
+	}
+
+	cfg := config.CaptureConfig{
+		Driver: config.CaptureDriverEBPF,
+		Filter: &config.FilterConfig{
+			Deny: []string{"192.168.1.0/24"},
+		},
+	}
+	c := newCapture("eth0", cfg).SetFlowSourceInitFn(func(*Capture) (FlowSource, error) {
+		return src, nil
+	})
+	require.Nil(t, c.run())
+	require.Empty(t, src.flows, "stale flows should have been discarded")
+
+	errs := c.process()
+
+	// both directions of the flow, which must be merged into a single one
+	src.flows = []capturetypes.FlowSummary{
+		newFlowSummary("10.0.0.1", "10.0.0.2", 443, 0, capturetypes.TCP, 1000, 0, 10, 0),
+		newFlowSummary("10.0.0.2", "10.0.0.1", 0, 443, capturetypes.TCP, 0, 500, 0, 5),
+		newFlowSummary("192.168.1.1", "10.0.0.2", 53, 0, capturetypes.UDP, 100, 0, 2, 0),
+	}
+	src.stats = ebpf.Stats{Packets: 117, Dropped: 3, Ignored: 11}
+
+	c.lock()
+	flowMap := c.flowMap(context.Background())
+	stats, err := c.status()
+	c.unlock()
+	require.Nil(t, err)
+
+	require.NotNil(t, flowMap)
+	require.Equal(t, 1, flowMap.Len())
+	require.Equal(t, 1, c.flowLog.Len())
+
+	require.Equal(t, uint64(18), stats.Received)
+	require.Equal(t, uint64(15), stats.Processed)
+	require.Equal(t, uint64(2), stats.Filtered)
+	require.Equal(t, uint64(3), stats.Dropped)
+
+	c.lock()
+	retired := c.rotate()
+	c.unlock()
+	agg, _, _ := c.aggregate(context.Background(), retired)
+	require.NotNil(t, agg)
+
+	require.Nil(t, c.close())
+	require.True(t, src.closed)
+
+	_, ok := <-errs
+	require.False(t, ok, "error channel should be closed")
+}
@@ -11,7 +11,13 @@ import (
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
 	"github.com/els0r/goProbe/pkg/goDB"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
+	"github.com/els0r/goProbe/pkg/goprobe/statspush"
 	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
 	"github.com/els0r/telemetry/logging"
 )
@@ -26,12 +32,28 @@ type Manager struct {
 	writeoutHandler writeout.Handler
 	captures        *captures
 	sourceInitFn    sourceInitFn
+	cardinality     *cardinalityMonitor
+	ifaceErrors     *ifaceErrors
+	errorDumps      *errorDumps
+	rotations       *rotationHistory
+	alertTarget     *push.Target
+	sflow           *sflowCollector
+	flowExport      *flowExporter
+
+	// time source for rotations / writeouts (exchangeable for deterministic testing)
+	clock clock.Clock
 
 	lastAppliedConfig config.Ifaces
 
 	lastRotation time.Time
 	startedAt    time.Time
 
+	// number of rotations completed, identifying the generation of the blocks written last
+	generation uint64
+
+	// serializes writeouts (scheduled, on-demand and upon disabling interfaces)
+	writeoutMu sync.Mutex
+
 	skipWriteoutSchedule bool
 }
 
@@ -44,10 +66,22 @@ func InitManager(ctx context.Context, config *config.Config, opts ...ManagerOpti
 	if err != nil {
 		return nil, fmt.Errorf("failed to get encoder type from %s: %w", config.DB.EncoderType, err)
 	}
+	ifaceEncoders := make(map[string]migrate.Encoder, len(config.DB.Encoders))
+	for iface, enc := range config.DB.Encoders {
+		ifaceEncoderType, err := encoders.GetTypeByString(enc.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get encoder type of interface %s from %s: %w", iface, enc.Type, err)
+		}
+		ifaceEncoders[iface] = migrate.Encoder{Type: ifaceEncoderType, Level: enc.Level}
+	}
 	dbPermissions := goDB.DefaultPermissions
 	if config.DB.Permissions != 0 {
 		dbPermissions = config.DB.Permissions
 	}
+	syncPolicy, err := storage.ParseSyncPolicy(config.DB.SyncPolicy)
+	if err != nil {
+		return nil, err
+	}
 
 	// If a local buffer config exists, set the values accordingly (before initializing the manager)
 	if config.LocalBuffers != nil {
@@ -56,11 +90,82 @@ func InitManager(ctx context.Context, config *config.Config, opts ...ManagerOpti
 
 	// Initialize the DB writeout handler
 	writeoutHandler := writeout.NewGoDBHandler(config.DB.Path, encoderType).
+		WithIfaceEncoders(ifaceEncoders).
 		WithSyslogWriting(config.SyslogFlows).
-		WithPermissions(dbPermissions)
+		WithPermissions(dbPermissions).
+		WithSyncPolicy(syncPolicy).
+		WithHandshakeRTT(config.DB.HandshakeRTT).
+		WithSpillBuffer(config.DB.SpillBufferSize).
+		WithWriteCoalescing(config.DB.CoalesceMaxFlows).
+		WithQuotas(config.DB.Quota).
+		WithSpool(config.DB.Spool).
+		WithHostnames(config.DB.Hostnames)
+	if config.DB.Backlog != nil {
+		maxPendingAge := writeout.DefaultMaxPendingAge
+		if config.DB.Backlog.MaxPendingAge != 0 {
+			maxPendingAge = config.DB.Backlog.MaxPendingAge
+		}
+		writeoutHandler = writeoutHandler.WithBacklogLimits(config.DB.Backlog.MaxQueueDepth, maxPendingAge)
+	}
+
+	// Stream the rotated flows to an external consumer (if configured)
+	if writeoutHandler, err = writeoutHandler.WithStream(config.DB.Stream); err != nil {
+		return nil, fmt.Errorf("failed to initialize flow stream: %w", err)
+	}
+
+	// Push the statistics of each rotation to a statsd / graphite endpoint (if configured)
+	if config.StatsPush != nil {
+		pusher, err := statspush.New(*config.StatsPush)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize stats push: %w", err)
+		}
+		writeoutHandler = writeoutHandler.WithRotationStatsHandler(pusher)
+	}
+
+	// Deliver alerts to the configured target (if any)
+	if config.Alerting != nil && config.Alerting.Webhook != nil {
+		writeoutHandler = writeoutHandler.WithAlertTarget(config.Alerting.Webhook)
+		opts = append([]ManagerOption{WithAlertTarget(config.Alerting.Webhook)}, opts...)
+	}
+
+	// Dump the payloads of packets that could not be parsed (if configured)
+	if config.ErrorDumps != nil {
+		opts = append([]ManagerOption{WithErrorDumps(config.ErrorDumps)}, opts...)
+	}
+
+	// Retain the most recent rotations in memory (if configured)
+	if config.RotationHistory != nil {
+		opts = append([]ManagerOption{WithRotationHistory(config.RotationHistory)}, opts...)
+	}
+
+	// Ingest sFlow datagrams into the configured pseudo-interfaces (if configured)
+	if config.SFlow != nil {
+		opts = append([]ManagerOption{WithSFlow(config.SFlow)}, opts...)
+	}
+
+	// Export the flows of each rotation as NetFlow v9 / IPFIX records (if configured)
+	if config.FlowExport != nil {
+		opts = append([]ManagerOption{WithFlowExport(config.FlowExport)}, opts...)
+	}
 
 	// Initialize the CaptureManager
 	captureManager := NewManager(writeoutHandler, opts...)
+	writeoutHandler.WithClock(captureManager.clock)
+	if captureManager.errorDumps != nil {
+		go captureManager.errorDumps.run(ctx)
+	}
+	if captureManager.sflow != nil {
+		if err := captureManager.sflow.open(captureManager.clock.Now()); err != nil {
+			return nil, fmt.Errorf("failed to start sFlow collector: %w", err)
+		}
+		go captureManager.sflow.run(ctx)
+	}
+	if captureManager.flowExport != nil {
+		if err := captureManager.flowExport.open(); err != nil {
+			return nil, fmt.Errorf("failed to start flow export: %w", err)
+		}
+		go captureManager.flowExport.run(ctx)
+	}
 
 	// Update (i.e. start) all capture routines (implicitly by reloading all configurations) and schedule
 	// DB writeouts
@@ -70,10 +175,17 @@ func InitManager(ctx context.Context, config *config.Config, opts ...ManagerOpti
 	}
 
 	// this is the first time the capture manager is started and is important to report program runtime
-	captureManager.startedAt = time.Now()
+	captureManager.startedAt = captureManager.clock.Now()
 
 	if !captureManager.skipWriteoutSchedule {
 		captureManager.ScheduleWriteouts(ctx, time.Duration(goDB.DBWriteInterval)*time.Second)
+		go writeoutHandler.MonitorBacklog(ctx, writeout.DefaultBacklogCheckInterval)
+
+		// Re-compress historical data in the background (if configured)
+		if config.DB.Recompress != nil {
+			go writeoutHandler.RunRecompression(ctx, *config.DB.Recompress)
+		}
+		go writeoutHandler.RunStream(ctx)
 	}
 
 	return captureManager, nil
@@ -85,6 +197,9 @@ func NewManager(writeoutHandler writeout.Handler, opts ...ManagerOption) *Manage
 		captures:        newCaptures(),
 		writeoutHandler: writeoutHandler,
 		sourceInitFn:    defaultSourceInitFn,
+		cardinality:     newCardinalityMonitor(),
+		ifaceErrors:     newIfaceErrors(),
+		clock:           clock.Real,
 	}
 	for _, opt := range opts {
 		opt(captureManager)
@@ -121,30 +236,125 @@ func (cm *Manager) GetTimestamps() (startedAt, lastRotation time.Time) {
 	return
 }
 
+// WriteoutStats returns the current statistics of the writeout backlog. If the writeout handler
+// does not expose any statistics, false is returned
+func (cm *Manager) WriteoutStats() (capturetypes.WriteoutStats, bool) {
+	provider, ok := cm.writeoutHandler.(writeout.StatsProvider)
+	if !ok {
+		return capturetypes.WriteoutStats{}, false
+	}
+	return provider.WriteoutStats(), true
+}
+
+// QuotaStats returns the disk usage quota statistics of all (or a set of) interfaces with a quota. If
+// the writeout handler does not enforce any quotas, nil is returned
+func (cm *Manager) QuotaStats(ifaces ...string) map[string]capturetypes.QuotaStats {
+	provider, ok := cm.writeoutHandler.(writeout.QuotaStatsProvider)
+	if !ok {
+		return nil
+	}
+	return provider.QuotaStats(ifaces...)
+}
+
+// CardinalityStats returns the flow cardinality statistics of all (or a set of) interfaces with
+// a configured flow cardinality alarm
+func (cm *Manager) CardinalityStats(ifaces ...string) map[string]capturetypes.CardinalityStats {
+	return cm.cardinality.stats(ifaces...)
+}
+
+// IfaceErrors returns the error state of all (or a set of) interfaces which are configured, but not
+// capturing since their capture failed to initialize or was torn down due to a critical processing error
+func (cm *Manager) IfaceErrors(ifaces ...string) map[string]capturetypes.IfaceError {
+	return cm.ifaceErrors.get(ifaces...)
+}
+
+// Capabilities returns the capabilities of the NICs backing all (or a set of) interfaces, as probed
+// upon initialization of their captures
+func (cm *Manager) Capabilities(ifaces ...string) map[string]capturetypes.IfaceCapabilities {
+	res := make(map[string]capturetypes.IfaceCapabilities)
+	for _, iface := range cm.captures.Ifaces(ifaces...) {
+		if mc, exists := cm.captures.Get(iface); exists && mc.capabilities != nil {
+			res[iface] = *mc.capabilities
+		}
+	}
+	return res
+}
+
+// SetBenchAssessment records the assessment of the startup self-benchmark of an interface. It is discarded
+// once the capture of the interface is reconfigured (since it depends on the ring buffer parameters)
+func (cm *Manager) SetBenchAssessment(iface string, assessment capturetypes.BenchAssessment) {
+	if mc, exists := cm.captures.Get(iface); exists {
+		mc.benchAssessment.Store(&assessment)
+	}
+}
+
+// BenchAssessments returns the assessments of the startup self-benchmarks of all (or a set of) interfaces
+// (if performed)
+func (cm *Manager) BenchAssessments(ifaces ...string) map[string]capturetypes.BenchAssessment {
+	res := make(map[string]capturetypes.BenchAssessment)
+	for _, iface := range cm.captures.Ifaces(ifaces...) {
+		if mc, exists := cm.captures.Get(iface); exists {
+			if assessment := mc.benchAssessment.Load(); assessment != nil {
+				res[iface] = *assessment
+			}
+		}
+	}
+	return res
+}
+
+// ProbeCapabilities probes the capabilities of the NIC backing an interface on demand, deriving the
+// configuration hints from its current configuration (if capturing) or from the default one (allowing
+// to validate an interface prior to capturing on it)
+func (cm *Manager) ProbeCapabilities(iface string) (capturetypes.IfaceCapabilities, error) {
+	if err := validateIfaceName(iface); err != nil {
+		return capturetypes.IfaceCapabilities{}, err
+	}
+	if mc, exists := cm.captures.Get(iface); exists {
+		return mc.probeCapabilities()
+	}
+
+	return newCapture(iface, config.CaptureConfig{
+		RingBuffer: &config.RingBufferConfig{
+			BlockSize: config.DefaultRingBufferBlockSize,
+			NumBlocks: config.DefaultRingBufferNumBlocks,
+		},
+	}).probeCapabilities()
+}
+
+// ErrorDumps returns the metadata of all dumps of packets that could not be parsed retained for
+// an interface (oldest first)
+func (cm *Manager) ErrorDumps(iface string) ([]capturetypes.ErrorDump, error) {
+	return cm.errorDumps.list(iface)
+}
+
+// Rotations returns the (up to) last n rotations of each of the provided interfaces (or of all
+// interfaces if none are provided) retained in memory, oldest first. If n is zero, all retained
+// rotations are returned
+func (cm *Manager) Rotations(n int, ifaces ...string) ([]capturetypes.Rotation, error) {
+	return cm.rotations.last(n, ifaces...)
+}
+
+// ErrorDumpPayload returns the raw (truncated) IP layer of a dump of a packet that could not be parsed
+func (cm *Manager) ErrorDumpPayload(iface string, id uint64) ([]byte, error) {
+	return cm.errorDumps.payload(iface, id)
+}
+
 // ScheduleWriteouts creates a new goroutine that executes a DB writeout in defined time
-// intervals
+// intervals. Steps of the system time (e.g. by NTP) are detected and the schedule is realigned
+// with the interval boundaries if required
 func (cm *Manager) ScheduleWriteouts(ctx context.Context, interval time.Duration) {
 	go func() {
 		logger := logging.FromContext(ctx)
 
 		// wait until the next 5 minute interval of the hour is reached before starting the ticker
-		tNow := time.Now()
-
-		sleepUntil := tNow.Truncate(interval).Add(interval).Sub(tNow)
-		logger.Infof("waiting for %s to start capture rotation", sleepUntil.Round(time.Second))
-
-		timer := time.NewTimer(sleepUntil)
-		select {
-		case <-timer.C:
-			break
-		case <-ctx.Done():
+		ticker, ok := cm.startTicker(ctx, cm.boundaryTimer(ctx, interval), interval)
+		if !ok {
 			return
 		}
 
-		ticker := time.NewTicker(interval)
-
 		// immediately write out after the initial sleep has completed
-		t := time.Now()
+		var prev time.Time
+		t := cm.clock.Now()
 		for {
 			select {
 			case <-ctx.Done():
@@ -152,6 +362,20 @@ func (cm *Manager) ScheduleWriteouts(ctx context.Context, interval time.Duration
 				ticker.Stop()
 				return
 			default:
+
+				// if the system time was stepped off the interval boundaries, the schedule is restarted
+				// on the next one (after the current writeout)
+				var realign clock.Timer
+				if !prev.IsZero() {
+					if offset, misaligned := detectClockJump(prev, t, interval); offset != 0 || misaligned {
+						cm.handleClockJump(ctx, prev, t, offset, misaligned)
+						if misaligned {
+							ticker.Stop()
+							realign = cm.boundaryTimer(ctx, interval)
+						}
+					}
+				}
+
 				t0 := time.Now()
 				cm.performWriteout(ctx, t)
 				if elapsed := float64(time.Since(t0)); elapsed > allowedWriteoutDurationFraction*float64(interval) {
@@ -159,9 +383,25 @@ func (cm *Manager) ScheduleWriteouts(ctx context.Context, interval time.Duration
 						100*allowedWriteoutDurationFraction,
 						100.*elapsed/float64(interval))
 				}
+				prev = t
+
+				if realign != nil {
+					if ticker, ok = cm.startTicker(ctx, realign, interval); !ok {
+						logger.Info("stopping rotation handler")
+						return
+					}
+					t = cm.clock.Now()
+					continue
+				}
 
 				// wait for the the next ticker to complete
-				t = <-ticker.C
+				select {
+				case t = <-ticker.C():
+				case <-ctx.Done():
+					logger.Info("stopping rotation handler")
+					ticker.Stop()
+					return
+				}
 			}
 		}
 	}()
@@ -184,6 +424,37 @@ func WithSkipWriteoutSchedule(skip bool) ManagerOption {
 	}
 }
 
+// WithErrorDumps enables dumping the payloads of packets that could not be parsed
+func WithErrorDumps(cfg *config.ErrorDumpConfig) ManagerOption {
+	return func(cm *Manager) {
+		cm.errorDumps = newErrorDumps(cfg)
+	}
+}
+
+// WithRotationHistory enables retaining the most recent rotations of each interface in memory
+func WithRotationHistory(cfg *config.RotationHistoryConfig) ManagerOption {
+	return func(cm *Manager) {
+		cm.rotations = newRotationHistory(cfg)
+	}
+}
+
+// WithClock sets the time source driving the writeout schedule and all timestamps derived
+// from it (defaults to the system time). Mainly used to fast-forward rotations in tests
+func WithClock(c clock.Clock) ManagerOption {
+	return func(cm *Manager) {
+		cm.clock = c
+	}
+}
+
+// WithAlertTarget sets the target alerts (e.g. flow cardinality spikes or emergency aggregation mode
+// switches) are delivered to
+func WithAlertTarget(target *push.Target) ManagerOption {
+	return func(cm *Manager) {
+		cm.cardinality.alertTarget = target
+		cm.alertTarget = target
+	}
+}
+
 // Config returns the runtime config of the capture manager for all (or a set of) interfaces
 func (cm *Manager) Config(ifaces ...string) (ifaceConfigs config.Ifaces) {
 	cm.RLock()
@@ -231,18 +502,30 @@ func (cm *Manager) Status(ctx context.Context, ifaces ...string) (statusmap capt
 
 			runCtx := withIfaceContext(ctx, mc.iface)
 
+			// Serve the status from the cache if it is still recent enough (avoiding to lock the
+			// capture and to query its source)
+			if status, cached := mc.statusCache.get(mc.clock.Now()); cached {
+				statusmapMutex.Lock()
+				statusmap[mc.iface] = *status
+				statusmapMutex.Unlock()
+				return
+			}
+
 			// Lock the running capture and extract the status
 			mc.lock()
 
 			// Since the capture is locked we can safely extract the (capture) status
 			// from the individual interfaces (and unlock no matter what)
 			status, err := mc.status()
+			emergency := mc.flowLog.emergency.stats()
 			mc.unlock()
 
 			if err != nil {
 				logging.FromContext(runCtx).Errorf("failed to get capture stats: %v", err)
 				return
 			}
+			status.Emergency = emergency
+			mc.statusCache.set(status, mc.clock.Now())
 
 			statusmapMutex.Lock()
 			statusmap[mc.iface] = *status
@@ -320,7 +603,7 @@ func (cm *Manager) update(ctx context.Context, ifaces config.Ifaces, enable, dis
 
 	// execute a final writeout of all disabled interfaces in the list
 	if len(disable) > 0 {
-		cm.performWriteout(ctx, time.Now().Add(time.Second), disable.Names()...)
+		cm.performWriteout(ctx, cm.clock.Now().Add(time.Second), disable.Names()...)
 	}
 
 	// To avoid any interference the update() logic is protected as a whole
@@ -330,6 +613,8 @@ func (cm *Manager) update(ctx context.Context, ifaces config.Ifaces, enable, dis
 
 	// store the configuration so that changes can be communicated
 	cm.lastAppliedConfig = ifaces
+	cm.cardinality.prune(ifaces)
+	cm.ifaceErrors.prune(ifaces)
 
 	// Disable any interfaces present in the negative list
 	var rg RunGroup
@@ -369,17 +654,36 @@ func (cm *Manager) update(ctx context.Context, ifaces config.Ifaces, enable, dis
 			logger.Info("initializing capture / running packet processing")
 
 			newCap := newCapture(iface.Name, ifaces[iface.Name]).SetSourceInitFn(cm.sourceInitFn)
+			newCap.errDumper = cm.errorDumps.dumper(iface.Name)
+			newCap.clock = cm.clock
 			if err := newCap.run(); err != nil {
-				logger.Errorf("failed to start capture: %s", err)
+				ifaceErr := cm.ifaceErrors.record(iface.Name, capturetypes.ErrorClassInit, err, cm.clock.Now())
+				logger.With("reason", ifaceErr.Reason, "count", ifaceErr.Count).Errorf("failed to start capture: %s", err)
 				return
 			}
 			iface.Success = true
+			cm.ifaceErrors.clear(iface.Name)
+
+			// Probe the NIC for settings distorting flow accounting (not a prerequisite for capturing)
+			if caps, err := newCap.probeCapabilities(); err != nil {
+				logger.Debugf("failed to probe interface capabilities: %s", err)
+			} else {
+				newCap.capabilities = &caps
+				for _, hint := range caps.Hints {
+					logger.With("remediation", hint.Remediation).Warn(hint.Message)
+				}
+			}
 
 			// Start up processing and error handling / logging in the
 			// background
 			go cm.logErrors(runCtx, iface.Name,
 				newCap.process())
 
+			// Watch the processing routine for stalls (if configured)
+			if newCap.watchdog != nil {
+				go cm.watch(runCtx, newCap)
+			}
+
 			cm.captures.Set(iface.Name, newCap)
 		})
 	}
@@ -387,45 +691,69 @@ func (cm *Manager) update(ctx context.Context, ifaces config.Ifaces, enable, dis
 }
 
 // GetFlowMaps extracts a copy of all active flows and sends them on the provided channel (compatible with normal query
-// processing). This way, live data can be added to a query result
-func (cm *Manager) GetFlowMaps(ctx context.Context, filterFn goDB.FilterFn, writeoutChan chan<- hashmap.AggFlowMapWithMetadata, ifaces ...string) {
+// processing). This way, live data can be added to a query result. The returned cut identifies the last rotation
+// whose flows are no longer held in memory (and have hence been written to the DB)
+func (cm *Manager) GetFlowMaps(ctx context.Context, filterFn goDB.FilterFn, writeoutChan chan<- hashmap.AggFlowMapWithMetadata, ifaces ...string) (cut results.Cut) {
 
 	logger, t0 := logging.FromContext(ctx), time.Now()
 
+	// Wait for a rotation in progress to be written out before taking the snapshot. This way, the
+	// flows held in memory directly succeed the last block written to the DB, providing a consistent
+	// cut for queries combining both
+	cm.writeoutMu.Lock()
+	cm.RLock()
+	cut = results.Cut{
+		Generation: cm.generation,
+		Timestamp:  cm.lastRotation,
+	}
+	if cut.Timestamp.IsZero() {
+		cut.Timestamp = cm.startedAt
+	}
+	cm.RUnlock()
+
 	// Build list of interfaces to process (either from all interfaces or from explicit list)
 	// If none are provided / are available, return empty map
 	if ifaces = cm.captures.Ifaces(ifaces...); len(ifaces) == 0 {
+		cm.writeoutMu.Unlock()
 		return
 	}
 
+	flowMaps := make([]hashmap.AggFlowMapWithMetadata, 0, len(ifaces))
 	for _, iface := range ifaces {
 		mc, exists := cm.captures.Get(iface)
 		if exists {
 
 			runCtx := withIfaceContext(ctx, mc.iface)
 
-			// Lock the running capture and perform the rotation
+			// Lock the running capture and extract its flows
 			mc.lock()
 			flowMap := mc.flowMap(runCtx)
 			mc.unlock()
 
 			if flowMap != nil {
-				if filterFn != nil {
-					flowMap = filterFn(flowMap)
-				}
-				writeoutChan <- hashmap.AggFlowMapWithMetadata{
+				flowMaps = append(flowMaps, hashmap.AggFlowMapWithMetadata{
 					AggFlowMap: flowMap,
 					Interface:  iface,
-				}
+				})
 			}
 		}
 	}
+	cm.writeoutMu.Unlock()
+
+	for _, flowMap := range flowMaps {
+		if filterFn != nil {
+			flowMap.AggFlowMap = filterFn(flowMap.AggFlowMap)
+		}
+		writeoutChan <- flowMap
+	}
 
 	// log fetch duration
 	logger.With(
 		"elapsed", time.Since(t0).Round(time.Microsecond).String(),
 		"ifaces", ifaces,
 	).Debug("fetched flow maps")
+
+	return
 }
 
 // Close stops / closes all (or a set of) interfaces
@@ -452,14 +780,18 @@ func withIfaceContext(ctx context.Context, iface string) context.Context {
 	return logging.WithFields(ctx, slog.String("iface", iface))
 }
 
-func (cm *Manager) rotate(ctx context.Context, writeoutChan chan<- capturetypes.TaggedAggFlowMap, ifaces ...string) {
+func (cm *Manager) rotate(ctx context.Context, timestamp time.Time, writeoutChan chan<- capturetypes.TaggedAggFlowMap, ifaces ...string) (rotated []capturetypes.WriteoutResult) {
 
 	logger, t0 := logging.FromContext(ctx), time.Now()
 
+	// Rotate the pseudo-interfaces fed by the sFlow collector (if enabled), which are independent
+	// of any capture
+	rotated = cm.rotateSFlow(timestamp, writeoutChan, ifaces...)
+
 	// Build list of interfaces to process (either from all interfaces or from explicit list)
 	// If none are provided / are available, return empty map
 	if ifaces = cm.captures.Ifaces(ifaces...); len(ifaces) == 0 {
-		return
+		return rotated
 	}
 
 	// Iteratively rotate all interfaces. Since the rotation results are put on the writeoutChan for
@@ -478,18 +810,52 @@ func (cm *Manager) rotate(ctx context.Context, writeoutChan chan<- capturetypes.
 			// Extract capture stats in a separate goroutine to minimize rotation duration
 			statsRes := mc.fetchStatusInBackground(runCtx)
 
-			// Perform the rotation
-			rotateResult := mc.rotate(runCtx)
+			// Retire the active flows (which merely swaps the flow maps, so the capture remains
+			// locked only for as long as it takes to extract the stats)
+			retired := mc.rotate()
+			emergency := mc.rotateEmergency()
 
 			stats := <-statsRes
 			mc.unlock()
 			logger.With("elapsed", time.Since(lockStart).Round(time.Microsecond).String()).Debug("interface locked")
 
-			writeoutChan <- capturetypes.TaggedAggFlowMap{
+			// Aggregate the retired flows while the capture continues to process packets
+			rotateResult, totals, rtt := mc.aggregate(runCtx, retired)
+			stats.HandshakeRTT = rtt
+
+			// Record the link properties at the time of the rotation (allowing to interpret the
+			// data correctly after hardware changes)
+			stats.Link = mc.probeLink()
+
+			// Mark the rotation if its flows were (partially) collapsed and announce any mode switch
+			stats.Emergency = emergency.stats
+			mc.statusCache.set(stats, mc.clock.Now())
+			cm.announceEmergency(runCtx, mc.iface, mc.config.Emergency, timestamp, emergency)
+
+			// Verify that all packets processed during the rotation interval are accounted for (if enabled)
+			if mc.counterCheck != nil {
+				mc.counterCheck.verify(runCtx, mc.iface, totals)
+			}
+
+			cm.observeCardinality(runCtx, mc.iface, rotateResult)
+
+			taggedMap := capturetypes.TaggedAggFlowMap{
 				Map:   rotateResult,
 				Stats: *stats,
 				Iface: mc.iface,
 			}
+			cm.rotations.add(timestamp, taggedMap)
+			cm.exportFlows(runCtx, mc.iface, timestamp, rotateResult)
+			writeoutChan <- taggedMap
+
+			res := capturetypes.WriteoutResult{Iface: mc.iface}
+			if rotateResult != nil {
+				res.NumFlows = rotateResult.Len()
+			}
+			rotated = append(rotated, res)
+
+			// Release the capture into standby if the interface has been idle for long enough
+			cm.suspendIfIdle(runCtx, mc)
 		}
 	}
 
@@ -502,10 +868,33 @@ func (cm *Manager) rotate(ctx context.Context, writeoutChan chan<- capturetypes.
 		"elapsed", t1.Round(time.Microsecond).String(),
 		"ifaces", ifaces,
 	).Debug("rotated interfaces")
+
+	return rotated
+}
+
+// observeCardinality tracks the number of unique flows of a rotation for interfaces with a
+// configured flow cardinality alarm
+func (cm *Manager) observeCardinality(ctx context.Context, iface string, rotateResult *hashmap.AggFlowMap) {
+	cm.RLock()
+	cfg := cm.lastAppliedConfig[iface].Cardinality
+	cm.RUnlock()
+
+	if cfg == nil {
+		return
+	}
+
+	var flows int
+	if rotateResult != nil {
+		flows = rotateResult.Len()
+	}
+	cm.cardinality.observe(ctx, iface, cfg, cm.clock.Now(), flows)
 }
 
 func (cm *Manager) logErrors(ctx context.Context, iface string, errsChan <-chan error) {
 	logger := logging.FromContext(ctx)
+
+	// the most recent error is considered the cause if processing terminates prematurely
+	var lastErr error
 	for {
 		select {
 		case <-ctx.Done():
@@ -513,6 +902,24 @@ func (cm *Manager) logErrors(ctx context.Context, iface string, errsChan <-chan
 		case err, ok := <-errsChan:
 			if !ok {
 
+				// If the capture was released into standby, processing was stopped deliberately and the
+				// interface is polled for activity instead (until the capture has been reinitialized)
+				if mc, exists := cm.captures.Get(iface); exists && mc.isReleased() {
+					if errsChan = cm.awaitActivity(ctx, mc); errsChan != nil {
+						continue
+					}
+					return
+				}
+
+				// Similarly, if the capture was aborted by the watchdog due to stalled processing, its
+				// capture source is reinitialized
+				if mc, exists := cm.captures.Get(iface); exists && mc.isRecovering() {
+					if errsChan = cm.awaitRecovery(ctx, mc); errsChan != nil {
+						continue
+					}
+					return
+				}
+
 				// Ensure there is no conflict with calls to update() that might already be
 				// taking down this interface
 				cm.Lock()
@@ -521,7 +928,11 @@ func (cm *Manager) logErrors(ctx context.Context, iface string, errsChan <-chan
 				// If the error channel was closed prematurely, we have to assume there was
 				// a critical processing error and tear down the interface
 				if mc, exists := cm.captures.Get(iface); exists {
-					logger.Info("closing capture / stopping packet processing")
+					if lastErr == nil {
+						lastErr = errProcessingTerminated
+					}
+					ifaceErr := cm.ifaceErrors.record(iface, capturetypes.ErrorClassProcessing, lastErr, cm.clock.Now())
+					logger.With("reason", ifaceErr.Reason, "count", ifaceErr.Count).Info("closing capture / stopping packet processing")
 					if err := mc.close(); err != nil {
 						logger.Errorf("failed to close capture: %s", err)
 					}
@@ -530,20 +941,33 @@ func (cm *Manager) logErrors(ctx context.Context, iface string, errsChan <-chan
 				return
 			}
 			logger.Error(err)
+			lastErr = err
 		}
 	}
 }
 
-func (cm *Manager) performWriteout(ctx context.Context, timestamp time.Time, ifaces ...string) {
+func (cm *Manager) performWriteout(ctx context.Context, timestamp time.Time, ifaces ...string) (time.Time, []capturetypes.WriteoutResult) {
+	cm.writeoutMu.Lock()
+	defer cm.writeoutMu.Unlock()
+
+	// Blocks must be written in chronological order, which is not guaranteed if an on-demand
+	// writeout was performed between the scheduled one being triggered and it acquiring the lock
+	if lastRotation := cm.LastRotation(); !timestamp.After(lastRotation) {
+		timestamp = lastRotation.Add(time.Second)
+	}
+
 	writeoutChan := make(chan capturetypes.TaggedAggFlowMap, writeout.WriteoutsChanDepth)
 	doneChan := cm.writeoutHandler.HandleWriteout(ctx, timestamp, writeoutChan)
 
-	cm.rotate(ctx, writeoutChan, ifaces...)
+	rotated := cm.rotate(ctx, timestamp, writeoutChan, ifaces...)
 	close(writeoutChan)
 
 	<-doneChan
 
 	cm.Lock()
 	cm.lastRotation = timestamp
+	cm.generation++
 	cm.Unlock()
+
+	return timestamp, rotated
 }
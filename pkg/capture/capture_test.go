@@ -19,6 +19,7 @@ import (
 	"github.com/els0r/goProbe/cmd/goProbe/config"
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
 	"github.com/els0r/goProbe/pkg/goprobe/writeout"
 	"github.com/els0r/telemetry/logging"
 	"github.com/fako1024/slimcap/capture"
@@ -68,14 +69,21 @@ func TestConcurrentMethodAccess(t *testing.T) {
 
 	for _, i := range []int{1, 2, 3, 10} {
 		t.Run(fmt.Sprintf("%d ifaces", i), func(t *testing.T) {
-			testConcurrentMethodAccess(t, i, 1000)
+			testConcurrentMethodAccess(t, defaultMockIfaceConfig, i, 1000)
 		})
 	}
+
+	// all packets are discarded by the capture filter, including the ones buffered during rotations
+	filteredIfaceConfig := defaultMockIfaceConfig
+	filteredIfaceConfig.Filter = &config.FilterConfig{Deny: []string{"1.2.3.0/24"}}
+	t.Run("filtered", func(t *testing.T) {
+		testConcurrentMethodAccess(t, filteredIfaceConfig, 2, 1000)
+	})
 }
 
-func testConcurrentMethodAccess(t *testing.T, nIfaces, nIterations int) {
+func testConcurrentMethodAccess(t *testing.T, cfg config.CaptureConfig, nIfaces, nIterations int) {
 
-	captureManager, ifaceConfigs, testMockSrcs := setupInterfaces(t, defaultMockIfaceConfig, nIfaces)
+	captureManager, ifaceConfigs, testMockSrcs := setupInterfaces(t, cfg, nIfaces)
 
 	time.Sleep(time.Second)
 
@@ -99,7 +107,7 @@ func testConcurrentMethodAccess(t *testing.T, nIfaces, nIterations int) {
 		prng := rand.New(rand.NewSource(randSeed)) // #nosec G404
 		for i := 0; i < nIterations; i++ {
 			ifaceIdx := prng.Int63n(int64(nIfaces))
-			captureManager.rotate(ctx, writeoutChan, fmt.Sprintf("mock%00d", ifaceIdx))
+			captureManager.rotate(ctx, time.Now(), writeoutChan, fmt.Sprintf("mock%00d", ifaceIdx))
 			<-writeoutChan
 		}
 		wg.Done()
@@ -178,6 +186,33 @@ func TestHighTrafficDeadlock(t *testing.T) {
 	testDeadlockHighTraffic(t)
 }
 
+func TestScheduleWriteoutsMockClock(t *testing.T) {
+
+	interval := 5 * time.Minute
+	start := time.Unix(1704067200, 0).Add(90 * time.Second)
+	mockClock := clock.NewMock(start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writeouts := make(timestampRecorder)
+	captureManager := NewManager(writeouts, WithClock(mockClock))
+	captureManager.ScheduleWriteouts(ctx, interval)
+
+	// the first writeout is aligned to the next interval boundary
+	mockClock.BlockUntil(1)
+	mockClock.Advance(start.Truncate(interval).Add(interval).Sub(start))
+	expected := start.Truncate(interval).Add(interval)
+	require.Equal(t, expected, <-writeouts)
+
+	for i := 0; i < 12; i++ {
+		mockClock.BlockUntil(1)
+		mockClock.Advance(interval)
+		expected = expected.Add(interval)
+		require.Equal(t, expected, <-writeouts)
+	}
+}
+
 func TestMockPacketCapturePerformance(t *testing.T) {
 
 	if testing.Short() {
@@ -251,14 +286,14 @@ func BenchmarkRotation(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 
-			// Run best-case scenario (keep all flows)
-			aggMap, _ := benchData[i].transferAndAggregate()
-			require.EqualValues(b, nFlows, len(benchData[i].flowMap))
+			// Run best-case scenario (retain all flows)
+			aggMap, _, _ := benchData[i].transferAndAggregate()
+			require.EqualValues(b, nFlows, len(benchData[i].retired))
 			require.EqualValues(b, nFlows, aggMap.Len())
 
-			// Run worst-case scenario (keep no flows)
-			aggMap, _ = benchData[i].transferAndAggregate()
-			require.EqualValues(b, 0, len(benchData[i].flowMap))
+			// Run worst-case scenario (no flows continued)
+			aggMap, _, _ = benchData[i].transferAndAggregate()
+			require.EqualValues(b, 0, len(benchData[i].retired))
 			require.EqualValues(b, 0, aggMap.Len())
 		}
 	})
@@ -279,6 +314,89 @@ func BenchmarkRotation(b *testing.B) {
 
 }
 
+// BenchmarkRotationUnderLoad rotates a running capture on a busy (mock) link carrying many flows,
+// comparing the double-buffered rotation (merely swapping the flow maps while the capture is locked)
+// to aggregating the flows while the capture is locked. The local buffer is limited such that it
+// overflows (i.e. packets are dropped) if the capture remains locked for too long
+func BenchmarkRotationUnderLoad(b *testing.B) {
+
+	ctx := context.Background()
+
+	setLocalBuffers(1, 256*bufElementSize)
+	defer setLocalBuffers(config.DefaultLocalBufferNumBuffers, config.DefaultLocalBufferSizeLimit)
+
+	for _, bench := range []struct {
+		name            string
+		aggregateLocked bool
+	}{
+		{"aggregate_locked", true},
+		{"double_buffered", false},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+
+			pkt, err := genDummyPacket()
+			require.Nil(b, err)
+			ipLayer := pkt.IPLayer()
+
+			mockSrc, err := afring.NewMockSourceNoDrain("mock",
+				afring.CaptureLength(link.CaptureLengthMinimalIPv4Transport),
+			)
+			require.Nil(b, err)
+
+			// Fill the ring buffer with packets belonging to distinct flows
+			for i := uint32(0); mockSrc.CanAddPackets(); i++ {
+				*(*uint32)(unsafe.Pointer(&ipLayer[16])) = i // #nosec G103
+				require.Nil(b, mockSrc.AddPacket(pkt))
+			}
+			errChan, err := mockSrc.Run(time.Microsecond)
+			require.Nil(b, err)
+
+			mockC := newMockCapture(mockSrc)
+			captureErrors := mockC.process()
+
+			var overflows atomic.Int64
+			go func() {
+				for err := range captureErrors {
+					if errors.Is(err, ErrLocalBufferOverflow) {
+						overflows.Add(1)
+					}
+				}
+			}()
+
+			var locked time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+
+				// Allow the flow map to fill up between rotations
+				b.StopTimer()
+				time.Sleep(10 * time.Millisecond)
+				b.StartTimer()
+
+				lockStart := time.Now()
+				mockC.lock()
+				retired := mockC.rotate()
+				if bench.aggregateLocked {
+					mockC.aggregate(ctx, retired)
+				}
+				mockC.unlock()
+				locked += time.Since(lockStart)
+
+				if !bench.aggregateLocked {
+					mockC.aggregate(ctx, retired)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(locked.Microseconds())/float64(b.N), "locked-µs/op")
+			b.ReportMetric(float64(overflows.Load())/float64(b.N), "overflows/op")
+
+			mockSrc.Done()
+			require.Nil(b, <-errChan)
+			require.Nil(b, mockC.close())
+		})
+	}
+}
+
 func testDeadlockLowTraffic(t *testing.T, maxPkts int) {
 
 	ctx := context.Background()
@@ -308,8 +426,9 @@ func testDeadlockLowTraffic(t *testing.T, maxPkts int) {
 		t.Logf("starting roation loops after %v", time.Since(start))
 		for i := 0; i < 20; i++ {
 			mockC.lock()
-			mockC.rotate(ctx)
+			retired := mockC.rotate()
 			mockC.unlock()
+			mockC.aggregate(ctx, retired)
 			time.Sleep(10 * time.Millisecond)
 		}
 		t.Logf("roation loops done after %v", time.Since(start))
@@ -358,8 +477,9 @@ func testDeadlockHighTraffic(t *testing.T) {
 		t.Logf("starting roation loops after %v", time.Since(start))
 		for i := 0; i < 20; i++ {
 			mockC.lock()
-			mockC.rotate(ctx)
+			retired := mockC.rotate()
 			mockC.unlock()
+			mockC.aggregate(ctx, retired)
 			time.Sleep(10 * time.Millisecond)
 		}
 		mockSrc.Done()
@@ -388,6 +508,7 @@ func newMockCapture(src capture.SourceZeroCopy) *Capture {
 		capLock:       newCaptureLock(),
 		flowLog:       NewFlowLog(),
 		captureHandle: src,
+		clock:         clock.Real,
 	}
 }
 
@@ -97,6 +97,16 @@ const (
 	tcpFlagACK = 0x10
 )
 
+// IsSYN returns if the TCP flags denote the initial SYN of a handshake
+func IsSYN(tcpFlags byte) bool {
+	return tcpFlags&(tcpFlagSYN|tcpFlagACK) == tcpFlagSYN
+}
+
+// IsSYNACK returns if the TCP flags denote the SYN-ACK of a handshake
+func IsSYNACK(tcpFlags byte) bool {
+	return tcpFlags&(tcpFlagSYN|tcpFlagACK) == tcpFlagSYN|tcpFlagACK
+}
+
 func classifyTCP(epHash EPHash, tcpFlags byte) Direction {
 
 	// Use the TCP handshake to determine the direction
@@ -139,6 +149,44 @@ const (
 	icmpV4TimestampReply         = 0x0E
 )
 
+// Further ICMP message types only relevant for grouping (not for direction classification)
+const (
+	icmpV4InfoRequest        = 0x0F
+	icmpV4InfoReply          = 0x10
+	icmpV4AddressMaskRequest = 0x11
+	icmpV4AddressMaskReply   = 0x12
+
+	icmpV6NeighborSolicitation  = 0x87
+	icmpV6NeighborAdvertisement = 0x88
+)
+
+// ICMPGroupType returns the type by which an ICMP message is grouped into a flow: reply-type
+// messages are mapped to the type of their request (such that e.g. an echo request and its echo
+// reply belong to the same flow), all other types are returned as is
+func ICMPGroupType(protocol, icmpType byte) byte {
+	switch protocol {
+	case ICMP:
+		switch icmpType {
+		case icmpV4EchoReply:
+			return icmpV4EchoRrequest
+		case icmpV4TimestampReply:
+			return icmpV4TimestampRequest
+		case icmpV4InfoReply:
+			return icmpV4InfoRequest
+		case icmpV4AddressMaskReply:
+			return icmpV4AddressMaskRequest
+		}
+	case ICMPv6:
+		switch icmpType {
+		case icmpV6EchoReply:
+			return icmpV6EchoRrequest
+		case icmpV6NeighborAdvertisement:
+			return icmpV6NeighborSolicitation
+		}
+	}
+	return icmpType
+}
+
 func classifyICMPv4(icmpType byte) Direction {
 
 	// Check the ICMPv4 Type parameter
@@ -232,6 +280,38 @@ func classifyByPorts(epHash EPHash) Direction {
 	return DirectionRemains
 }
 
+// ClassifyHostDirection determines the direction of a TCP / UDP packet exchanged with the capturing
+// host itself (as denoted by srcIsHost, i.e. whether the host is the source or the destination of the
+// packet). Since ephemeral ports are only assigned by the host's kernel to outgoing connections, the
+// port of the host's endpoint definitively determines its role:
+//   - an ephemeral (or disregarded) port denotes the host acting as client
+//   - any other port denotes the host acting as server
+//
+// The TCP handshake (if observed) takes precedence and other protocols are not classified at all
+// (i.e. DirectionUnknown is returned)
+func ClassifyHostDirection(epHash EPHash, auxInfo byte, srcIsHost bool) Direction {
+	switch epHash[36] {
+	case TCP:
+		if auxInfo&tcpFlagSYN != 0 {
+			return classifyTCP(epHash, auxInfo)
+		}
+	case UDP:
+	default:
+		return DirectionUnknown
+	}
+
+	hostPort := uint16(epHash[32])<<8 | uint16(epHash[33])
+	if srcIsHost {
+		hostPort = uint16(epHash[34])<<8 | uint16(epHash[35])
+	}
+
+	// The host is the client if its port is ephemeral, in which case it must be the source
+	if isEphemeralPort(hostPort) == srcIsHost {
+		return DirectionRemains
+	}
+	return DirectionReverts
+}
+
 // Ephemeral ports as union of:
 // -> suggested by IANA / RFC6335 (49152–65535)
 // -> used by most Linux kernels (32768–60999)
@@ -260,3 +340,18 @@ func isBroadcastMulticast(destinationIP []byte, isIPv4 bool) bool {
 	// identifies an address as a unicast address.
 	return destinationIP[0] == 0xFF
 }
+
+// FlowSummary denotes a flow pre-aggregated by a flow source (e.g. in-kernel by an eBPF
+// program) instead of individual packets processed in userspace. The EPHash is oriented
+// according to the first packet observed for the flow, AuxInfo stores the auxiliary
+// information (TCP flags / ICMP type) of said packet
+type FlowSummary struct {
+	EPHash  EPHash
+	IsIPv4  bool
+	AuxInfo byte
+
+	BytesRcvd   uint64
+	BytesSent   uint64
+	PacketsRcvd uint64
+	PacketsSent uint64
+}
@@ -1,11 +1,17 @@
 package capturetypes
 
+import "time"
+
 // ParsingErrno denotes a non-critical packet parsing error / failure
 type ParsingErrno int8
 
 const (
 	// ErrnoOK : No Error
-	ErrnoOK ParsingErrno = iota - 2
+	ErrnoOK ParsingErrno = iota - 3
+
+	// ErrnoPacketFragment : non-first fragment of a fragmented IPv6 packet (lacking a transport
+	// layer), attributed to the flow of its first fragment (if observed) or ignored otherwise
+	ErrnoPacketFragment
 
 	// ErrnoPacketFragmentIgnore : packet fragment does not carry relevant information
 	// (will be skipped as non-error)
@@ -55,3 +61,21 @@ func (e *ParsingErrTracker) Reset() {
 		e[i] = 0
 	}
 }
+
+// ErrorDump denotes the metadata of a dumped payload of a packet that could not be parsed
+type ErrorDump struct {
+	// ID: denotes the (per-interface) sequence number of the dump. Example: 42
+	ID uint64 `json:"id"`
+	// Timestamp: denotes the time when the packet was captured. Example: "2021-01-01T00:03:17Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Errno: denotes the parsing error encountered. Example: 1
+	Errno ParsingErrno `json:"errno"`
+	// Error: denotes the parsing error encountered in human-readable form. Example: "packet truncated"
+	Error string `json:"error"`
+	// PktType: denotes the packet type (direction) as reported by the capture source. Example: 4
+	PktType byte `json:"pkt_type"`
+	// PktSize: denotes the total size of the packet on the wire. Example: 1514
+	PktSize uint32 `json:"pkt_size"`
+	// Size: denotes the number of bytes of the IP layer that were dumped. Example: 34
+	Size int `json:"size"`
+}
@@ -0,0 +1,58 @@
+package capturetypes
+
+import (
+	"time"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+)
+
+// Rotation denotes the flows of an interface retired during a single rotation
+type Rotation struct {
+	// Timestamp: denotes the timestamp of the rotation. Example: "2021-01-01T00:05:00Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Iface: denotes the interface the flows were captured on. Example: "eth0"
+	Iface string `json:"iface"`
+	// Stats: denotes the capture statistics of the interface for the rotation
+	Stats CaptureStats `json:"stats"`
+	// NumFlows: denotes the number of flows of the rotation. Example: 1024
+	NumFlows int `json:"num_flows"`
+	// Totals: stores the total traffic of all flows of the rotation
+	Totals types.Counters `json:"totals"`
+	// Flows: stores the flows of the rotation (unless only summaries are retained)
+	Flows []RotatedFlow `json:"flows,omitempty"`
+}
+
+// RotatedFlow denotes a single flow of a rotation (using the format of the flows of an ingest request)
+type RotatedFlow struct {
+	// Attributes: denotes the attributes of the flow
+	Attributes results.Attributes `json:"attributes"`
+	// Counters: stores the bytes / packets counters of the flow
+	Counters types.Counters `json:"counters"`
+}
+
+// RotatedFlows converts all flows of an aggregated flow map
+func RotatedFlows(m *hashmap.AggFlowMap) []RotatedFlow {
+	if m == nil {
+		return []RotatedFlow{}
+	}
+
+	flows := make([]RotatedFlow, 0, m.Len())
+	for it := m.Iter(); it.Next(); {
+		key := types.Key(it.Key())
+		flows = append(flows, RotatedFlow{
+			Attributes: results.Attributes{
+				SrcIP:   types.RawIPToAddr(key.GetSIP()),
+				DstIP:   types.RawIPToAddr(key.GetDIP()),
+				IPProto: key.GetProto(),
+				DstPort: types.PortToUint16(key.GetDport()),
+				Tag:     types.TagNameByID(key.GetTag()),
+				TTLMin:  key.GetTTLMin(),
+				TTLMax:  key.GetTTLMax(),
+			},
+			Counters: it.Val(),
+		})
+	}
+	return flows
+}
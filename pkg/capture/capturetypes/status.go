@@ -3,6 +3,7 @@ package capturetypes
 import (
 	"time"
 
+	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
 )
 
@@ -30,10 +31,261 @@ type CaptureStats struct {
 	ProcessedTotal uint64    `json:"processed_total"` // ProcessedTotal denotes the number of packets processed since the capture was started. Example: 70000
 	Dropped        uint64    `json:"dropped"`         // Dropped: denotes the number of packets dropped. Example: 3
 	DroppedTotal   uint64    `json:"dropped_total"`   // DroppedTotal: denotes the number of packets dropped since the capture was started. Example: 20
+	Filtered       uint64    `json:"filtered"`        // Filtered: denotes the number of packets discarded by the capture filter. Example: 5
+	FilteredTotal  uint64    `json:"filtered_total"`  // FilteredTotal: denotes the number of packets discarded by the capture filter since the capture was started. Example: 500
+
+	// CPUTime: denotes the CPU time consumed by the packet processing routine of the capture
+	// (in nanoseconds). Example: 1500000000
+	CPUTime time.Duration `json:"cpu_time_ns,omitempty"`
+	// CPUTimeTotal: denotes the CPU time consumed by the packet processing routine since the
+	// capture was started (in nanoseconds). Example: 90000000000
+	CPUTimeTotal time.Duration `json:"cpu_time_total_ns,omitempty"`
+
+	// Standby: indicates if the capture has been released due to inactivity (with the interface
+	// being polled for activity instead). Example: false
+	Standby bool `json:"standby,omitempty"`
+
+	// AutoRecovered: denotes the number of times the capture was reinitialized automatically after its
+	// packet processing stalled despite traffic on the interface (c.f. the capture watchdog). Example: 1
+	AutoRecovered uint64 `json:"auto_recovered,omitempty"`
+
+	// Age: denotes the age of the statistics if they were served from the cache instead of being
+	// retrieved from the capture (in nanoseconds). The statistics of idle interfaces are retained
+	// until the next rotation. Example: 42000000000
+	Age time.Duration `json:"age_ns,omitempty"`
 
 	// ParsingErrors: denotes all packet parsing errors / failures encountered
 	// Example: [23, 0]
 	ParsingErrors ParsingErrTracker `json:"parsing_errors,omitempty"`
+
+	// HandshakeRTT: denotes the TCP handshake round trip times observed during the rotation
+	// (only populated for rotations)
+	HandshakeRTT *HandshakeRTT `json:"handshake_rtt,omitempty"`
+
+	// Link: denotes the properties of the link backing the interface at the time of the rotation
+	// (only populated for rotations, if the interface could be probed)
+	Link *types.LinkInfo `json:"link,omitempty"`
+
+	// Emergency: denotes the emergency aggregation mode being in effect (or, for rotations, having
+	// been in effect at any point during the rotation interval)
+	Emergency *EmergencyStats `json:"emergency,omitempty"`
+}
+
+// EmergencyStats describes the emergency aggregation mode of an interface, during which the IP
+// addresses of all flows are collapsed to their prefixes
+type EmergencyStats struct {
+	// Since: denotes the time at which the mode was entered. Example: "2021-01-01T00:02:13Z"
+	Since time.Time `json:"since"`
+	// IPv4Prefix: denotes the length of the prefixes IPv4 addresses are collapsed to. Example: 24
+	IPv4Prefix int `json:"ipv4_prefix"`
+	// IPv6Prefix: denotes the length of the prefixes IPv6 addresses are collapsed to. Example: 64
+	IPv6Prefix int `json:"ipv6_prefix"`
+}
+
+// HandshakeRTT summarizes TCP handshake round trip time estimates, i.e. the time elapsed between
+// a SYN and the corresponding SYN-ACK as observed at the capture point
+type HandshakeRTT struct {
+	// Samples: denotes the number of handshakes the summary is based on. Example: 42
+	Samples int `json:"samples"`
+	// Min: denotes the minimum round trip time in nanoseconds. Example: 1200000
+	Min time.Duration `json:"min_ns"`
+	// Median: denotes the median round trip time in nanoseconds. Example: 15000000
+	Median time.Duration `json:"median_ns"`
+	// Max: denotes the maximum round trip time in nanoseconds. Example: 180000000
+	Max time.Duration `json:"max_ns"`
+}
+
+// IfaceCapabilities stores the properties of the NIC backing an interface that affect flow accounting,
+// as probed upon initialization of the capture (or on demand)
+type IfaceCapabilities struct {
+	// MTU: denotes the maximum transmission unit of the interface. Example: 1500
+	MTU int `json:"mtu"`
+	// Speed: denotes the link speed in Mbit/s (if reported by the driver). Example: 10000
+	Speed uint32 `json:"speed_mbps,omitempty"`
+	// Offloads: denotes the state of all offloads affecting flow accounting supported by the driver
+	// Example: {"gro": true, "lro": false, "rx_vlan": true}
+	Offloads map[string]bool `json:"offloads,omitempty"`
+	// Hints: stores the configuration hints derived from the capabilities (if any)
+	Hints []ConfigHint `json:"hints,omitempty"`
+}
+
+// BenchAssessment summarizes the self-benchmark of an interface performed on startup, predicting if its
+// ring buffer suffices for the link speed given the processing rate achieved with the configured ring
+// buffer parameters
+type BenchAssessment struct {
+	// Timestamp: denotes the time the benchmark was completed. Example: "2021-01-01T00:00:10Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Rate: denotes the achieved processing rate in packets per second. Example: 2500000
+	Rate uint64 `json:"rate_pps"`
+	// LineRate: denotes the worst-case packet rate at link speed (i.e. minimum-size frames) in packets
+	// per second. Zero if the link speed is unknown. Example: 1488095
+	LineRate uint64 `json:"line_rate_pps,omitempty"`
+	// Capacity: denotes the number of packets the ring buffer holds. Example: 32768
+	Capacity int `json:"capacity"`
+	// Headroom: denotes the duration a burst at line rate can be absorbed by the ring buffer before
+	// packets are dropped (in nanoseconds). Zero if the processing rate exceeds the line rate. Example: 5000000
+	Headroom time.Duration `json:"headroom_ns,omitempty"`
+	// Insufficient: indicates that the ring buffer is predicted insufficient for the link speed. Example: false
+	Insufficient bool `json:"insufficient,omitempty"`
+	// RecommendedNumBlocks: denotes the number of ring buffer blocks (of the configured size) predicted
+	// sufficient (only set if the current one is insufficient). Example: 64
+	RecommendedNumBlocks int `json:"recommended_num_blocks,omitempty"`
+}
+
+// ConfigHint describes an interface setting that distorts flow accounting (and how to remedy it)
+type ConfigHint struct {
+	// Message: describes the impact of the setting
+	// Example: "receive offloading (gro) merges inbound packets into super-packets, causing packet counts to be underestimated"
+	Message string `json:"message"`
+	// Remediation: denotes a suggested command / configuration change remedying the setting (if any)
+	// Example: "ethtool -K eth0 gro off"
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// ErrorReason denotes a machine-readable code of the reason an interface is in an error state
+type ErrorReason string
+
+const (
+	// ErrorReasonIfaceMissing denotes that the interface does not exist (anymore)
+	ErrorReasonIfaceMissing ErrorReason = "iface_missing"
+	// ErrorReasonIfaceDown denotes that the interface (or its link) is down
+	ErrorReasonIfaceDown ErrorReason = "iface_down"
+	// ErrorReasonPermissionDenied denotes that goProbe lacks the privileges required to capture on the interface
+	ErrorReasonPermissionDenied ErrorReason = "permission_denied"
+	// ErrorReasonResourcesExhausted denotes that the resources required for capturing (e.g. the memory of the
+	// ring buffer or file descriptors) could not be allocated
+	ErrorReasonResourcesExhausted ErrorReason = "resources_exhausted"
+	// ErrorReasonInvalidConfig denotes that the configuration of the interface (e.g. its capture filter or
+	// tagging rules) could not be applied
+	ErrorReasonInvalidConfig ErrorReason = "invalid_config"
+	// ErrorReasonNetnsUnavailable denotes that the network namespace of the interface could not be resolved
+	ErrorReasonNetnsUnavailable ErrorReason = "netns_unavailable"
+	// ErrorReasonUnknown denotes any other error
+	ErrorReasonUnknown ErrorReason = "unknown"
+)
+
+// Hint returns an actionable hint on how to remedy an error of the reason (if any)
+func (r ErrorReason) Hint() string {
+	switch r {
+	case ErrorReasonIfaceMissing:
+		return "interface missing: verify the interface name (e.g. via `ip link`) or remove it from the configuration"
+	case ErrorReasonIfaceDown:
+		return "interface down: bring the interface up (e.g. via `ip link set <iface> up`)"
+	case ErrorReasonPermissionDenied:
+		return "permission denied: need CAP_NET_RAW (and CAP_NET_ADMIN for promiscuous mode), e.g. via `setcap cap_net_raw,cap_net_admin+ep <goProbe binary>`"
+	case ErrorReasonResourcesExhausted:
+		return "resources exhausted: reduce the ring buffer size of the interface or raise the memory / file descriptor limits"
+	case ErrorReasonInvalidConfig:
+		return "invalid configuration: fix the capture filter / tagging rules of the interface and reload the configuration"
+	case ErrorReasonNetnsUnavailable:
+		return "network namespace unavailable: verify that the namespace (or the container owning it) exists"
+	}
+	return ""
+}
+
+// ErrorClass denotes the stage of a capture in which an error occurred
+type ErrorClass string
+
+const (
+	// ErrorClassInit denotes that the capture failed to initialize
+	ErrorClassInit ErrorClass = "init"
+	// ErrorClassProcessing denotes that packet processing aborted due to a critical error
+	ErrorClassProcessing ErrorClass = "processing"
+)
+
+// IfaceError describes the error state of an interface whose capture failed to initialize or was torn
+// down due to a critical processing error, i.e. which is configured but not capturing
+type IfaceError struct {
+	// Reason: denotes the machine-readable reason of the error
+	// Enum: [iface_missing, iface_down, permission_denied, resources_exhausted, invalid_config, netns_unavailable, unknown]
+	// Example: "permission_denied"
+	Reason ErrorReason `json:"reason"`
+	// Class: denotes the stage of the capture in which the error occurred
+	// Enum: [init, processing]. Example: "init"
+	Class ErrorClass `json:"class"`
+	// Message: denotes the most recent error. Example: "failed to initialize capture: operation not permitted"
+	Message string `json:"message"`
+	// Count: denotes the number of errors since the interface last captured successfully. Example: 3
+	Count int `json:"count"`
+	// Since: denotes the time of the first error since the interface last captured successfully
+	// Example: "2021-01-01T00:00:00Z"
+	Since time.Time `json:"since"`
+	// Timestamp: denotes the time of the most recent error. Example: "2021-01-01T00:10:00Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Hint: describes how to remedy the error (if known)
+	// Example: "permission denied: need CAP_NET_RAW (and CAP_NET_ADMIN for promiscuous mode), e.g. via `setcap cap_net_raw,cap_net_admin+ep <goProbe binary>`"
+	Hint string `json:"hint,omitempty"`
+}
+
+// WriteoutStats stores the statistics of the writeout handler, i.e. the backlog of rotated flow
+// maps that have not been written to all sinks yet
+type WriteoutStats struct {
+	// Status: denotes if the writeout backlog is within its configured bounds
+	// Enum: [ok, degraded]. Example: "ok"
+	Status types.Status `json:"status"`
+	// Message: describes why the writeout is degraded (if applicable)
+	// Example: "writeout queue depth 120 exceeds maximum of 100"
+	Message string `json:"message,omitempty"`
+	// QueueDepth: denotes the number of rotated flow maps pending writeout. Example: 2
+	QueueDepth int `json:"queue_depth"`
+	// OldestPending: denotes the rotation timestamp of the oldest pending writeout (if any)
+	// Example: "2021-01-01T00:05:00Z"
+	OldestPending time.Time `json:"oldest_pending,omitempty"`
+	// OldestPendingAge: denotes the age of the oldest pending writeout in nanoseconds. Example: 1500000000
+	OldestPendingAge time.Duration `json:"oldest_pending_age_ns,omitempty"`
+	// SinkLatencies: denotes the duration of the most recent write to each sink in nanoseconds
+	// Example: {"godb": 25000000, "syslog": 3000000}
+	SinkLatencies map[string]time.Duration `json:"sink_latencies_ns,omitempty"`
+	// SyncPolicy: denotes when data written to the goDB is committed to stable storage
+	// Enum: [always, per-rotation, os-default]. Example: "per-rotation"
+	SyncPolicy string `json:"sync_policy,omitempty"`
+}
+
+// IsDegraded returns if the writeout backlog exceeds its configured bounds
+func (w WriteoutStats) IsDegraded() bool {
+	return w.Status == types.StatusDegraded
+}
+
+// RotationStats stores the statistics of a single rotation / writeout of all interfaces
+type RotationStats struct {
+	// Timestamp: denotes the timestamp of the rotation. Example: "2021-01-01T00:05:00Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Ifaces: denotes the capture statistics of all rotated interfaces
+	Ifaces map[string]RotatedIface `json:"ifaces"`
+	// WriteoutDuration: denotes the time it took to write out all interfaces in nanoseconds. Example: 250000000
+	WriteoutDuration time.Duration `json:"writeout_duration_ns"`
+	// Writeout: denotes the statistics of the writeout backlog after the writeout completed
+	Writeout WriteoutStats `json:"writeout"`
+}
+
+// RotatedIface stores the statistics of an individual interface rotation
+type RotatedIface struct {
+	// Stats: denotes the capture statistics of the interface for the rotation
+	Stats CaptureStats `json:"stats"`
+	// NumFlows: denotes the number of flows written out for the interface. Example: 1024
+	NumFlows int `json:"num_flows"`
+}
+
+// BackfillResult stores the outcome of re-writing / backfilling a single block of an interface
+type BackfillResult struct {
+	// Timestamp: denotes the (writeout) timestamp of the backfilled block. Example: "2021-01-01T00:05:00Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Source: denotes where the flows of the backfilled block originate from
+	// Enum: [spill, pcap, ingest]. Example: "spill"
+	Source string `json:"source"`
+	// Replaced: denotes if an existing block was replaced (as opposed to added). Example: false
+	Replaced bool `json:"replaced"`
+	// NumFlows: denotes the number of flows written for the block. Example: 1024
+	NumFlows int `json:"num_flows"`
+}
+
+// WriteoutResult stores the outcome of an (out-of-cycle) writeout of an interface
+type WriteoutResult struct {
+	// Iface: denotes the interface that was written out. Example: "eth0"
+	Iface string `json:"iface"`
+	// NumFlows: denotes the number of flows handed to the writeout (flows that could not be
+	// written are retained in the writeout backlog). Example: 1024
+	NumFlows int `json:"num_flows"`
 }
 
 // AddStats is a convenience method to total capture stats. This is relevant in the scope of
@@ -57,3 +309,108 @@ func SubStats(a, b *CaptureStats) {
 	a.Received -= b.Received
 	a.Dropped -= b.Dropped
 }
+
+// CardinalityStats stores the flow cardinality statistics of an individual interface
+type CardinalityStats struct {
+	// Flows: denotes the number of unique flows in the most recent rotation. Example: 1200
+	Flows int `json:"flows"`
+	// Baseline: denotes the median number of unique flows across the recent rotations. Example: 1000
+	Baseline float64 `json:"baseline"`
+	// Alerting: denotes if the number of unique flows currently exceeds the configured multiple
+	// of the baseline. Example: false
+	Alerting bool `json:"alerting"`
+	// AlertingSince: denotes the time of the rotation that raised the current alert (if any)
+	// Example: "2021-01-01T00:05:00Z"
+	AlertingSince time.Time `json:"alerting_since,omitempty"`
+}
+
+// CardinalityAlertState denotes the state reported by a flow cardinality alert
+type CardinalityAlertState string
+
+const (
+	// CardinalitySpike is reported once the number of unique flows exceeds the configured
+	// multiple of the baseline
+	CardinalitySpike CardinalityAlertState = "spike"
+	// CardinalityRecovered is reported once the number of unique flows drops below the
+	// threshold again
+	CardinalityRecovered CardinalityAlertState = "recovered"
+)
+
+// CardinalityAlert is the payload delivered to the alerting targets upon a change of the flow
+// cardinality alarm state of an interface
+type CardinalityAlert struct {
+	Iface     string                `json:"iface"`     // Iface: the interface the alert refers to. Example: eth0
+	State     CardinalityAlertState `json:"state"`     // State: the alarm state. Example: spike
+	Timestamp time.Time             `json:"timestamp"` // Timestamp: the time of the rotation that triggered the alert. Example: "2021-01-01T00:05:00Z"
+	Flows     int                   `json:"flows"`     // Flows: the number of unique flows in the rotation. Example: 12000
+	Baseline  float64               `json:"baseline"`  // Baseline: the median number of unique flows across the recent rotations. Example: 1000
+	Factor    float64               `json:"factor"`    // Factor: the configured multiple of the baseline. Example: 3
+}
+
+// QuotaStats stores the disk usage quota statistics of an individual interface
+type QuotaStats struct {
+	// MaxSize: denotes the maximum disk usage (in bytes) of the interface. Example: 10737418240
+	MaxSize int64 `json:"max_size"`
+	// Used: denotes the disk usage (in bytes) of the interface as of its most recent writeout
+	// (after enforcement of the quota). Example: 5368709120
+	Used int64 `json:"used"`
+	// Utilization: denotes the fraction of the quota in use. Example: 0.5
+	Utilization float64 `json:"utilization"`
+	// Policy: denotes the action taken if the quota is exceeded. Example: drop_oldest
+	Policy string `json:"policy"`
+	// Exceeded: denotes if the quota was exceeded upon the most recent writeout. Example: false
+	Exceeded bool `json:"exceeded"`
+	// ExceededSince: denotes the time of the writeout that first exceeded the quota (if it
+	// is currently exceeded). Example: "2021-01-01T00:05:00Z"
+	ExceededSince time.Time `json:"exceeded_since,omitempty"`
+	// RemovedDirs: denotes the number of day directories removed to enforce the quota. Example: 3
+	RemovedDirs int `json:"removed_dirs,omitempty"`
+	// SkippedWriteouts: denotes the number of writeouts skipped due to the quota. Example: 0
+	SkippedWriteouts int `json:"skipped_writeouts,omitempty"`
+	// DownsampledWriteouts: denotes the number of writeouts downsampled due to the quota. Example: 0
+	DownsampledWriteouts int `json:"downsampled_writeouts,omitempty"`
+}
+
+// QuotaAlertState denotes the state reported by a disk usage quota alert
+type QuotaAlertState string
+
+const (
+	// QuotaExceeded is reported once the disk usage of an interface exceeds its quota
+	QuotaExceeded QuotaAlertState = "exceeded"
+	// QuotaRecovered is reported once the disk usage of an interface complies with its quota again
+	QuotaRecovered QuotaAlertState = "recovered"
+)
+
+// QuotaAlert is the payload delivered to the alerting targets upon a change of the disk usage
+// quota state of an interface
+type QuotaAlert struct {
+	Iface     string          `json:"iface"`     // Iface: the interface the alert refers to. Example: eth0
+	State     QuotaAlertState `json:"state"`     // State: the quota state. Example: exceeded
+	Timestamp time.Time       `json:"timestamp"` // Timestamp: the time of the writeout that triggered the alert. Example: "2021-01-01T00:05:00Z"
+	Used      int64           `json:"used"`      // Used: the disk usage (in bytes) of the interface. Example: 10737418241
+	MaxSize   int64           `json:"max_size"`  // MaxSize: the maximum disk usage (in bytes) of the interface. Example: 10737418240
+	Policy    string          `json:"policy"`    // Policy: the action taken while the quota is exceeded. Example: skip
+}
+
+// EmergencyAlertState denotes the state reported by an emergency aggregation mode alert
+type EmergencyAlertState string
+
+const (
+	// EmergencyEntered is reported once the number of concurrent flows reached the limit and
+	// flows are collapsed to their prefixes
+	EmergencyEntered EmergencyAlertState = "entered"
+	// EmergencyLeft is reported once the number of distinct flows subsided and flows are no
+	// longer collapsed
+	EmergencyLeft EmergencyAlertState = "left"
+)
+
+// EmergencyAlert is the payload delivered to the alerting targets upon a switch of the emergency
+// aggregation mode of an interface
+type EmergencyAlert struct {
+	Iface      string              `json:"iface"`       // Iface: the interface the alert refers to. Example: eth0
+	State      EmergencyAlertState `json:"state"`       // State: the mode switch. Example: entered
+	Timestamp  time.Time           `json:"timestamp"`   // Timestamp: the time of the mode switch. Example: "2021-01-01T00:02:13Z"
+	MaxFlows   int                 `json:"max_flows"`   // MaxFlows: the configured limit of concurrent flows. Example: 1000000
+	IPv4Prefix int                 `json:"ipv4_prefix"` // IPv4Prefix: the length of the prefixes IPv4 addresses are collapsed to. Example: 24
+	IPv6Prefix int                 `json:"ipv6_prefix"` // IPv6Prefix: the length of the prefixes IPv6 addresses are collapsed to. Example: 64
+}
@@ -0,0 +1,168 @@
+package capture
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/telemetry/logging"
+)
+
+// minBaselineRotations denotes the minimum number of rotations required before the flow
+// cardinality baseline is considered meaningful
+const minBaselineRotations = 3
+
+// cardinalityTracker tracks the number of unique flows per rotation of an individual interface
+type cardinalityTracker struct {
+	history []int
+	stats   capturetypes.CardinalityStats
+}
+
+// observe records the number of unique flows of a rotation and compares it against the baseline
+// computed from the preceding rotations. If the alarm state changes, an alert is returned
+func (t *cardinalityTracker) observe(cfg *config.CardinalityConfig, timestamp time.Time, flows int) *capturetypes.CardinalityAlert {
+	historyLen := cfg.History
+	if historyLen == 0 {
+		historyLen = config.DefaultCardinalityHistory
+	}
+
+	t.stats.Flows = flows
+
+	var exceeded bool
+	if len(t.history) >= minBaselineRotations {
+		t.stats.Baseline = median(t.history)
+		exceeded = flows >= cfg.MinFlows && float64(flows) > cfg.Factor*t.stats.Baseline
+	}
+
+	// the current rotation becomes part of the baseline in any case, so that a permanent
+	// change of the traffic pattern eventually becomes the new normal
+	t.history = append(t.history, flows)
+	if len(t.history) > historyLen {
+		t.history = t.history[len(t.history)-historyLen:]
+	}
+
+	if exceeded == t.stats.Alerting {
+		return nil
+	}
+
+	t.stats.Alerting = exceeded
+	alert := &capturetypes.CardinalityAlert{
+		State:     capturetypes.CardinalityRecovered,
+		Timestamp: timestamp,
+		Flows:     flows,
+		Baseline:  t.stats.Baseline,
+		Factor:    cfg.Factor,
+	}
+	t.stats.AlertingSince = time.Time{}
+	if exceeded {
+		alert.State = capturetypes.CardinalitySpike
+		t.stats.AlertingSince = timestamp
+	}
+	return alert
+}
+
+func median(values []int) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return float64(sorted[n/2])
+	}
+	return float64(sorted[n/2-1]+sorted[n/2]) / 2
+}
+
+// cardinalityMonitor tracks the flow cardinality of all interfaces with a configured alarm
+type cardinalityMonitor struct {
+	trackers    map[string]*cardinalityTracker
+	alertTarget *push.Target
+
+	sync.Mutex
+}
+
+func newCardinalityMonitor() *cardinalityMonitor {
+	return &cardinalityMonitor{
+		trackers: make(map[string]*cardinalityTracker),
+	}
+}
+
+// observe records the number of unique flows of a rotation of an interface and reports any
+// change of its alarm state
+func (m *cardinalityMonitor) observe(ctx context.Context, iface string, cfg *config.CardinalityConfig, timestamp time.Time, flows int) {
+	m.Lock()
+	tracker, exists := m.trackers[iface]
+	if !exists {
+		tracker = new(cardinalityTracker)
+		m.trackers[iface] = tracker
+	}
+	alert := tracker.observe(cfg, timestamp, flows)
+	promCardinalityBaseline.WithLabelValues(iface).Set(tracker.stats.Baseline)
+	m.Unlock()
+
+	if alert == nil {
+		return
+	}
+	alert.Iface = iface
+
+	logger := logging.FromContext(ctx).With(
+		"flows", alert.Flows,
+		"baseline", alert.Baseline,
+		"factor", alert.Factor,
+	)
+	if alert.State == capturetypes.CardinalitySpike {
+		promCardinalityAlerts.WithLabelValues(iface).Inc()
+		logger.Warn("number of unique flows exceeds baseline")
+	} else {
+		logger.Info("number of unique flows back to normal")
+	}
+
+	if m.alertTarget == nil {
+		return
+	}
+
+	// deliver the alert in the background to avoid delaying the rotation of other interfaces
+	go func() {
+		pusher, err := m.alertTarget.Pusher()
+		if err == nil {
+			err = pusher.PushJSON(context.WithoutCancel(ctx), alert)
+		}
+		if err != nil {
+			logger.Errorf("failed to send flow cardinality %s alert: %v", alert.State, err)
+		}
+	}()
+}
+
+// prune removes the trackers of all interfaces that are no longer present in the configuration
+// or don't have an alarm configured
+func (m *cardinalityMonitor) prune(ifaces config.Ifaces) {
+	m.Lock()
+	for iface := range m.trackers {
+		if cfg, exists := ifaces[iface]; !exists || cfg.Cardinality == nil {
+			delete(m.trackers, iface)
+			promCardinalityBaseline.DeleteLabelValues(iface)
+		}
+	}
+	m.Unlock()
+}
+
+// stats returns the flow cardinality statistics of all (or a set of) tracked interfaces
+func (m *cardinalityMonitor) stats(ifaces ...string) map[string]capturetypes.CardinalityStats {
+	m.Lock()
+	defer m.Unlock()
+
+	res := make(map[string]capturetypes.CardinalityStats)
+	for iface, tracker := range m.trackers {
+		if len(ifaces) > 0 && !slices.Contains(ifaces, iface) {
+			continue
+		}
+		res[iface] = tracker.stats
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityTracker(t *testing.T) {
+	cfg := &config.CardinalityConfig{
+		Factor:   3,
+		History:  4,
+		MinFlows: 100,
+	}
+
+	var (
+		tracker cardinalityTracker
+		ts      = time.Now()
+	)
+
+	// no alert is raised until the baseline is established
+	for _, flows := range []int{1000, 1200, 10000} {
+		require.Nil(t, tracker.observe(cfg, ts, flows))
+	}
+	require.False(t, tracker.stats.Alerting)
+
+	// the spike is detected against the median of the recent rotations
+	alert := tracker.observe(cfg, ts, 5000)
+	require.NotNil(t, alert)
+	require.Equal(t, capturetypes.CardinalitySpike, alert.State)
+	require.Equal(t, 1200., alert.Baseline)
+	require.True(t, tracker.stats.Alerting)
+	require.Equal(t, ts, tracker.stats.AlertingSince)
+
+	// an ongoing spike does not raise another alert
+	require.Nil(t, tracker.observe(cfg, ts, 20000))
+	require.Len(t, tracker.history, cfg.History)
+
+	alert = tracker.observe(cfg, ts, 1100)
+	require.NotNil(t, alert)
+	require.Equal(t, capturetypes.CardinalityRecovered, alert.State)
+	require.False(t, tracker.stats.Alerting)
+	require.True(t, tracker.stats.AlertingSince.IsZero())
+}
+
+func TestCardinalityTrackerMinFlows(t *testing.T) {
+	cfg := &config.CardinalityConfig{
+		Factor:   2,
+		MinFlows: 100,
+	}
+
+	var tracker cardinalityTracker
+	for _, flows := range []int{5, 5, 5, 50} {
+		require.Nil(t, tracker.observe(cfg, time.Now(), flows))
+	}
+	require.Equal(t, 5., tracker.stats.Baseline)
+	require.Equal(t, 50, tracker.stats.Flows)
+}
+
+func TestMedian(t *testing.T) {
+	require.Equal(t, 2., median([]int{3, 1, 2}))
+	require.Equal(t, 2.5, median([]int{4, 1, 3, 2}))
+}
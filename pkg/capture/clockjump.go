@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
+	"github.com/els0r/telemetry/logging"
+)
+
+// clockJumpThreshold denotes the minimum deviation of the system time from the writeout schedule
+// for it to be treated as a clock jump (as opposed to regular scheduling jitter)
+const clockJumpThreshold = 10 * time.Second
+
+// detectClockJump assesses if the system time was stepped (e.g. by NTP or manually) between the
+// scheduled writeouts at prev and t, returning the offset of the system time and if the writeouts
+// no longer occur on the boundaries of the writeout interval (requiring the schedule to be realigned)
+func detectClockJump(prev, t time.Time, interval time.Duration) (offset time.Duration, misaligned bool) {
+
+	// The schedule is driven by the monotonic clock, hence a step of the system time manifests
+	// as a difference between the elapsed wall clock time and the elapsed monotonic time (if
+	// readings of the latter are available, which is the case for the system clock)
+	offset = t.Round(0).Sub(prev.Round(0)) - t.Sub(prev)
+
+	// Writeouts going back in time must be reported regardless
+	if offset == 0 && !t.After(prev) {
+		offset = t.Sub(prev) - interval
+	}
+
+	// Any step of the system time that is not a multiple of the interval leaves the writeouts
+	// misaligned with the interval boundaries (which block lookups / time range queries rely on)
+	deviation := t.Sub(t.Round(interval))
+	if misaligned = deviation.Abs() > clockJumpThreshold; misaligned && offset == 0 {
+		offset = deviation
+	}
+
+	if offset.Abs() <= clockJumpThreshold {
+		offset = 0
+	}
+	return
+}
+
+// boundaryTimer creates a timer firing on the next boundary of the writeout interval
+func (cm *Manager) boundaryTimer(ctx context.Context, interval time.Duration) clock.Timer {
+	tNow := cm.clock.Now()
+
+	sleepUntil := tNow.Truncate(interval).Add(interval).Sub(tNow)
+	logging.FromContext(ctx).Infof("waiting for %s to start capture rotation", sleepUntil.Round(time.Second))
+
+	return cm.clock.NewTimer(sleepUntil)
+}
+
+// startTicker waits for a boundary timer to fire before starting the ticker driving the writeouts
+// (returning false if the context was cancelled in the meantime)
+func (cm *Manager) startTicker(ctx context.Context, timer clock.Timer, interval time.Duration) (clock.Ticker, bool) {
+	select {
+	case <-timer.C():
+		return cm.clock.NewTicker(interval), true
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, false
+	}
+}
+
+// handleClockJump reports a step of the system time observed between two scheduled writeouts
+func (cm *Manager) handleClockJump(ctx context.Context, prev, t time.Time, offset time.Duration, misaligned bool) {
+	logger := logging.FromContext(ctx).With(
+		"offset", offset.Round(time.Second).String(),
+		"previous_writeout", prev,
+		"writeout", t,
+	)
+
+	promClockJumps.Inc()
+	if !t.After(prev) {
+		logger.Warn("system clock jumped backwards, timestamping blocks after the last written one until the system time has caught up")
+		return
+	}
+	if misaligned {
+		logger.Warn("system clock jumped, realigning writeouts with the interval boundaries")
+		return
+	}
+	logger.Warn("system clock jumped")
+}
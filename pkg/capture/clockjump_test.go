@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
+	"github.com/stretchr/testify/require"
+)
+
+type timestampRecorder chan time.Time
+
+func (r timestampRecorder) HandleWriteout(_ context.Context, timestamp time.Time, writeoutChan <-chan capturetypes.TaggedAggFlowMap) <-chan struct{} {
+	doneChan := make(chan struct{})
+	go func() {
+		for range writeoutChan {
+		}
+		r <- timestamp
+		close(doneChan)
+	}()
+	return doneChan
+}
+
+func TestDetectClockJump(t *testing.T) {
+	interval := 5 * time.Minute
+	prev := time.Unix(1704067200, 0)
+
+	for _, c := range []struct {
+		name               string
+		t                  time.Time
+		expectedOffset     time.Duration
+		expectedMisaligned bool
+	}{
+		{"regular", prev.Add(interval), 0, false},
+		{"jitter", prev.Add(interval + 2*time.Second), 0, false},
+		{"dropped tick", prev.Add(2 * interval), 0, false},
+		{"forward", prev.Add(interval + 2*time.Minute), 2 * time.Minute, true},
+		{"backward", prev.Add(interval - time.Hour), -time.Hour, false},
+		{"backward misaligned", prev.Add(-3 * time.Minute), -3*time.Minute - interval, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			offset, misaligned := detectClockJump(prev, c.t, interval)
+			require.Equal(t, c.expectedOffset, offset)
+			require.Equal(t, c.expectedMisaligned, misaligned)
+		})
+	}
+}
+
+func TestScheduleWriteoutsClockJump(t *testing.T) {
+
+	interval := 5 * time.Minute
+	start := time.Unix(1704067200, 0)
+	mockClock := clock.NewMock(start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writeouts := make(timestampRecorder)
+	captureManager := NewManager(writeouts, WithClock(mockClock))
+	captureManager.ScheduleWriteouts(ctx, interval)
+
+	mockClock.BlockUntil(1)
+	mockClock.Advance(interval)
+	require.Equal(t, start.Add(interval), <-writeouts)
+
+	// a forward step of the system time triggers a writeout off the interval boundaries, followed by
+	// a realignment of the schedule
+	mockClock.BlockUntil(1)
+	mockClock.Jump(2 * time.Minute)
+	mockClock.Advance(interval)
+	require.Equal(t, start.Add(2*interval+2*time.Minute), <-writeouts)
+
+	mockClock.BlockUntil(1)
+	mockClock.Advance(3 * time.Minute)
+	require.Equal(t, start.Add(3*interval), <-writeouts)
+
+	mockClock.BlockUntil(1)
+	mockClock.Advance(interval)
+	require.Equal(t, start.Add(4*interval), <-writeouts)
+
+	// a backward step of the system time must not result in misordered block timestamps
+	mockClock.BlockUntil(1)
+	mockClock.Jump(-time.Hour)
+	mockClock.Advance(interval)
+	require.Equal(t, start.Add(4*interval+time.Second), <-writeouts)
+}
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"context"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+)
+
+// packetCounts denotes the number of packets handed to the flow log of a capture during a rotation
+// interval, broken down by how they were accounted for
+type packetCounts struct {
+	processed     uint64 // all packets handed to the flow log (i.e. not discarded by the capture filter)
+	ignored       uint64 // packet fragments not attributed to any flow
+	parsingErrors uint64 // packets that could not be parsed
+}
+
+// recorded returns the number of packets expected to be recorded in the flows of the rotation
+func (p packetCounts) recorded() uint64 {
+	return p.processed - p.ignored - p.parsingErrors
+}
+
+// counterCheck verifies the conservation of packet counters across rotations (if enabled): all packets
+// processed during a rotation interval must either be recorded in the rotated flows or be accounted
+// for as ignored / unparsable. Packets discarded by the capture filter or dropped by the kernel never
+// reach the flow log and hence are not part of the balance
+type counterCheck struct {
+	current packetCounts // packets handed to the flow log since the last rotation
+	retired packetCounts // packets handed to the flow log until the last rotation
+}
+
+// observe accounts for a packet handed to the flow log, given the outcome of adding it (called from
+// the processing routine only)
+func (cc *counterCheck) observe(errno capturetypes.ParsingErrno) {
+	cc.current.processed++
+	if errno.ParsingFailed() {
+		cc.current.parsingErrors++
+	} else if errno > capturetypes.ErrnoOK {
+		cc.current.ignored++
+	}
+}
+
+// rotate retires the packet counts of the current rotation interval. Like the rotation of the
+// flow log itself, it must be called while the capture is locked
+func (cc *counterCheck) rotate() {
+	cc.retired, cc.current = cc.current, packetCounts{}
+}
+
+// verify checks that the packets recorded in the retired flows (as summarized by totals) match
+// the packet counts of the last rotation, reporting any discrepancy
+func (cc *counterCheck) verify(ctx context.Context, iface string, totals *types.Counters) bool {
+	var recorded uint64
+	if totals != nil {
+		recorded = totals.PacketsRcvd + totals.PacketsSent
+	}
+
+	expected := cc.retired.recorded()
+	if recorded == expected {
+		return true
+	}
+
+	promCounterDiscrepancies.WithLabelValues(iface).Inc()
+
+	logger := logging.FromContext(ctx).With(
+		"processed", cc.retired.processed,
+		"ignored", cc.retired.ignored,
+		"parsing_errors", cc.retired.parsingErrors,
+		"expected", expected,
+		"recorded", recorded,
+	)
+	if recorded > expected {
+		logger.With("excess", recorded-expected).Error("packet counters not conserved: flows contain more packets than were processed (double counting or packets leaking from another rotation)")
+	} else {
+		logger.With("missing", expected-recorded).Error("packet counters not conserved: processed packets missing from the flows (lost during aggregation or leaking into another rotation)")
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package capture
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterCheck(t *testing.T) {
+	client, server := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	epHash := testTCPEPHash(client, server, 34567, 8080)
+
+	c := newCapture("eth0", config.CaptureConfig{VerifyCounters: true})
+	require.NotNil(t, c.counterCheck)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		c.addToFlowLog(epHash, capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+		c.addToFlowLog(epHash.Reverse(), capture.PacketThisHost, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	}
+	c.addToFlowLog(epHash, capture.PacketOutgoing, 64, true, 0, capturetypes.ErrnoPacketFragmentIgnore)
+	c.addToFlowLog(epHash, capture.PacketOutgoing, 64, true, 0, capturetypes.ErrnoPacketTruncated)
+
+	// all processed packets are accounted for
+	retired := c.rotate()
+	_, totals, _ := c.aggregate(ctx, retired)
+	require.Equal(t, packetCounts{processed: 8, ignored: 1, parsingErrors: 1}, c.counterCheck.retired)
+	require.True(t, c.counterCheck.verify(ctx, c.iface, totals))
+
+	// an empty rotation is balanced as well
+	retired = c.rotate()
+	_, totals, _ = c.aggregate(ctx, retired)
+	require.True(t, c.counterCheck.verify(ctx, c.iface, totals))
+
+	// packets missing from the flows and excess packets are both reported
+	c.addToFlowLog(epHash, capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	retired = c.rotate()
+	_, totals, _ = c.aggregate(ctx, retired)
+	require.False(t, c.counterCheck.verify(ctx, c.iface, &types.Counters{}))
+	require.False(t, c.counterCheck.verify(ctx, c.iface, &types.Counters{PacketsSent: totals.PacketsSent + 1}))
+	require.True(t, c.counterCheck.verify(ctx, c.iface, totals))
+
+	// captures without verification do not track any counts
+	require.Nil(t, newCapture("eth0", config.CaptureConfig{}).counterCheck)
+}
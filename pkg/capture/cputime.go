@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errThreadCPUTimeUnsupported denotes that the CPU time of individual threads cannot be determined
+var errThreadCPUTimeUnsupported = errors.New("per-thread CPU time not supported")
+
+// cpuTracker tracks the CPU time consumed by the processing routine of a capture. Since the routine
+// is locked to its OS thread, the CPU time of the thread can be attributed to the capture (without any
+// overhead in the packet processing loop itself)
+type cpuTracker struct {
+	tid     int
+	base    time.Duration // CPU time consumed by the thread before it was attached
+	last    time.Duration // CPU time consumed by the routine as of the previous sample
+	pending time.Duration // CPU time consumed by previously attached threads, not yet sampled
+
+	sync.Mutex
+}
+
+// attach registers the calling goroutine as processing routine. It must be locked to its OS thread
+// until detach() is called
+func (t *cpuTracker) attach() {
+	tid := currentThreadID()
+	base, err := threadCPUTime(tid)
+	if err != nil {
+		return
+	}
+
+	t.Lock()
+	t.tid, t.base, t.last = tid, base, 0
+	t.Unlock()
+}
+
+// detach unregisters the processing routine, retaining the CPU time consumed since the previous sample
+func (t *cpuTracker) detach() {
+	t.Lock()
+	t.pending += t.sampleThread()
+	t.tid = 0
+	t.Unlock()
+}
+
+// sample returns the CPU time consumed by the processing routine since the previous sample
+func (t *cpuTracker) sample() time.Duration {
+	t.Lock()
+	defer t.Unlock()
+
+	res := t.pending + t.sampleThread()
+	t.pending = 0
+	return res
+}
+
+func (t *cpuTracker) sampleThread() time.Duration {
+	if t.tid == 0 {
+		return 0
+	}
+	cur, err := threadCPUTime(t.tid)
+	if err != nil {
+		return 0
+	}
+	cur -= t.base
+
+	delta := cur - t.last
+	t.last = cur
+	if delta < 0 {
+		return 0
+	}
+	return delta
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package capture
+
+import "time"
+
+func currentThreadID() int {
+	return 0
+}
+
+func threadCPUTime(int) (time.Duration, error) {
+	return 0, errThreadCPUTimeUnsupported
+}
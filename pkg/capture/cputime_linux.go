@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package capture
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Clock flags for the CPU clock of an individual thread (c.f. MAKE_THREAD_CPUCLOCK in the kernel's
+// posix-timers.h), allowing to query it from any thread of the process
+const (
+	cpuClockSched     = 2
+	cpuClockPerThread = 4
+)
+
+func currentThreadID() int {
+	return unix.Gettid()
+}
+
+// threadCPUTime returns the CPU time consumed by a thread of the process
+func threadCPUTime(tid int) (time.Duration, error) {
+	clockID := int32(^tid)<<3 | cpuClockSched | cpuClockPerThread
+
+	var ts unix.Timespec
+	if err := unix.ClockGettime(clockID, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package capture
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCPUTracker(t *testing.T) {
+	var tracker cpuTracker
+	require.Zero(t, tracker.sample())
+
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		tracker.attach()
+		for start := time.Now(); time.Since(start) < 50*time.Millisecond; {
+		}
+		require.Greater(t, tracker.sample(), time.Duration(0))
+
+		for start := time.Now(); time.Since(start) < 20*time.Millisecond; {
+		}
+		tracker.detach()
+		close(done)
+	}()
+	<-done
+
+	// the CPU time consumed until detach() must be retained until the next sample
+	require.Greater(t, tracker.sample(), time.Duration(0))
+	require.Zero(t, tracker.sample())
+}
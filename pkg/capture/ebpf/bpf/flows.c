@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: GPL-2.0
+//
+// flows.c
+//
+// TC classifier aggregating packets per flow in-kernel for goProbe's eBPF capture driver
+// (capture_driver: ebpf). The program is attached to both the ingress (tc_ingress) and
+// egress (tc_egress) hooks of an interface. Flows are keyed by the same endpoint
+// information goProbe uses for its flow log (see ParsePacket() in pkg/capture/flow.go), the
+// map is drained periodically by goProbe (see pkg/capture/ebpf).
+//
+// The program never modifies or drops packets (it always returns TC_ACT_UNSPEC).
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/in.h>
+#include <linux/ip.h>
+#include <linux/ipv6.h>
+#include <linux/pkt_cls.h>
+#include <linux/tcp.h>
+#include <linux/udp.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+#define MAX_FLOWS 262144
+
+#define IPPROTO_ICMPV6_ 58
+#define IP_FRAG_OFFSET_MASK 0x1fff
+
+// Indices of the statistics counters (must match pkg/capture/ebpf/ebpf.go)
+enum {
+	STAT_PACKETS = 0,
+	STAT_MAP_FULL,
+	STAT_IGNORED,
+	NUM_STATS,
+};
+
+// struct flow_key mirrors goProbe's EPHash (37 bytes), followed by the IP version flag
+struct flow_key {
+	__u8 sip[16];
+	__u8 dip[16];
+	__u8 dport[2]; // network byte order
+	__u8 sport[2]; // network byte order
+	__u8 proto;
+	__u8 is_ipv4;
+	__u8 pad[2];
+};
+
+struct flow_value {
+	__u64 bytes_rcvd;
+	__u64 bytes_sent;
+	__u64 packets_rcvd;
+	__u64 packets_sent;
+	__u8 aux_info; // TCP flags / ICMP type of the first packet of the flow
+	__u8 pad[7];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_FLOWS);
+	__type(key, struct flow_key);
+	__type(value, struct flow_value);
+} goprobe_flows SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__uint(max_entries, NUM_STATS);
+	__type(key, __u32);
+	__type(value, __u64);
+} goprobe_stats SEC(".maps");
+
+static __always_inline void count(__u32 idx)
+{
+	__u64 *counter = bpf_map_lookup_elem(&goprobe_stats, &idx);
+	if (counter)
+		__sync_fetch_and_add(counter, 1);
+}
+
+// is_common_port mirrors isCommonPort() in pkg/capture/flow.go: for these ports the
+// ephemeral port of the other endpoint is not taken into account
+static __always_inline int is_common_port(__u8 *port, __u8 proto)
+{
+	if (port[0] > 1)
+		return 0;
+
+	if (proto == IPPROTO_TCP)
+		return (port[0] == 0 && (port[1] == 53 || port[1] == 80)) ||
+		       (port[0] == 1 && port[1] == 187);
+	if (proto == IPPROTO_UDP)
+		return (port[0] == 0 && port[1] == 53) || (port[0] == 1 && port[1] == 187);
+
+	return 0;
+}
+
+// icmp_group_type mirrors ICMPGroupType() in pkg/capture/capturetypes/packet.go: reply-type
+// messages are mapped to the type of their request
+static __always_inline __u8 icmp_group_type(__u8 proto, __u8 type)
+{
+	if (proto == IPPROTO_ICMP) {
+		switch (type) {
+		case 0: // echo reply
+			return 8;
+		case 14: // timestamp reply
+			return 13;
+		case 16: // information reply
+			return 15;
+		case 18: // address mask reply
+			return 17;
+		}
+	} else {
+		switch (type) {
+		case 129: // echo reply
+			return 128;
+		case 136: // neighbor advertisement
+			return 135;
+		}
+	}
+	return type;
+}
+
+// parse_transport extracts ports and auxiliary information from the transport layer
+// starting at offset l4
+static __always_inline int parse_transport(struct __sk_buff *skb, __u32 l4, struct flow_key *key,
+					   __u8 *aux_info)
+{
+	void *data = (void *)(long)skb->data;
+	void *data_end = (void *)(long)skb->data_end;
+
+	if (key->proto == IPPROTO_TCP || key->proto == IPPROTO_UDP) {
+		__u8 *ports = data + l4;
+		if ((void *)(ports + 4) > data_end)
+			return -1;
+
+		__u8 sport[2] = { ports[0], ports[1] };
+		__u8 dport[2] = { ports[2], ports[3] };
+
+		if (!is_common_port(dport, key->proto)) {
+			key->sport[0] = sport[0];
+			key->sport[1] = sport[1];
+		}
+		if (!is_common_port(sport, key->proto)) {
+			key->dport[0] = dport[0];
+			key->dport[1] = dport[1];
+		}
+
+		if (key->proto == IPPROTO_TCP) {
+			__u8 *flags = data + l4 + 13;
+			if ((void *)(flags + 1) > data_end)
+				return -1;
+			*aux_info = *flags;
+		}
+	} else if (key->proto == IPPROTO_ICMP || key->proto == IPPROTO_ICMPV6_) {
+		__u8 *type = data + l4;
+		if ((void *)(type + 2) > data_end)
+			return -1;
+		*aux_info = type[0];
+
+		// group ICMP messages by type / code (stored in both port fields, such that replies
+		// match the reverse flow of their request), mirroring setICMPTypeCode()
+		key->dport[0] = icmp_group_type(key->proto, type[0]);
+		key->dport[1] = type[1];
+		key->sport[0] = key->dport[0];
+		key->sport[1] = key->dport[1];
+	}
+
+	return 0;
+}
+
+static __always_inline int handle(struct __sk_buff *skb, int egress)
+{
+	void *data = (void *)(long)skb->data;
+	void *data_end = (void *)(long)skb->data_end;
+
+	struct flow_key key = {};
+	__u8 aux_info = 0;
+
+	struct ethhdr *eth = data;
+	if ((void *)(eth + 1) > data_end)
+		goto ignore;
+
+	__u32 l3 = sizeof(struct ethhdr);
+	if (eth->h_proto == bpf_htons(ETH_P_IP)) {
+		struct iphdr *ip = data + l3;
+		if ((void *)(ip + 1) > data_end)
+			goto ignore;
+
+		key.is_ipv4 = 1;
+		key.proto = ip->protocol;
+
+		// skip all but the first fragment (which carries the transport layer header)
+		if (key.proto != IPPROTO_ESP && (bpf_ntohs(ip->frag_off) & IP_FRAG_OFFSET_MASK))
+			goto ignore;
+
+		__builtin_memcpy(key.sip, &ip->saddr, 4);
+		__builtin_memcpy(key.dip, &ip->daddr, 4);
+
+		if (parse_transport(skb, l3 + ip->ihl * 4, &key, &aux_info) < 0)
+			goto ignore;
+	} else if (eth->h_proto == bpf_htons(ETH_P_IPV6)) {
+		struct ipv6hdr *ip6 = data + l3;
+		if ((void *)(ip6 + 1) > data_end)
+			goto ignore;
+
+		key.proto = ip6->nexthdr;
+		__builtin_memcpy(key.sip, &ip6->saddr, 16);
+		__builtin_memcpy(key.dip, &ip6->daddr, 16);
+
+		if (parse_transport(skb, l3 + sizeof(struct ipv6hdr), &key, &aux_info) < 0)
+			goto ignore;
+	} else {
+		goto ignore;
+	}
+
+	count(STAT_PACKETS);
+
+	struct flow_value *val = bpf_map_lookup_elem(&goprobe_flows, &key);
+	if (val) {
+		if (egress) {
+			__sync_fetch_and_add(&val->bytes_sent, skb->len);
+			__sync_fetch_and_add(&val->packets_sent, 1);
+		} else {
+			__sync_fetch_and_add(&val->bytes_rcvd, skb->len);
+			__sync_fetch_and_add(&val->packets_rcvd, 1);
+		}
+		return TC_ACT_UNSPEC;
+	}
+
+	struct flow_value new_val = { .aux_info = aux_info };
+	if (egress) {
+		new_val.bytes_sent = skb->len;
+		new_val.packets_sent = 1;
+	} else {
+		new_val.bytes_rcvd = skb->len;
+		new_val.packets_rcvd = 1;
+	}
+
+	// BPF_NOEXIST: if another CPU created the flow concurrently, add to it instead
+	if (bpf_map_update_elem(&goprobe_flows, &key, &new_val, BPF_NOEXIST) != 0) {
+		val = bpf_map_lookup_elem(&goprobe_flows, &key);
+		if (!val) {
+			count(STAT_MAP_FULL);
+			return TC_ACT_UNSPEC;
+		}
+		if (egress) {
+			__sync_fetch_and_add(&val->bytes_sent, skb->len);
+			__sync_fetch_and_add(&val->packets_sent, 1);
+		} else {
+			__sync_fetch_and_add(&val->bytes_rcvd, skb->len);
+			__sync_fetch_and_add(&val->packets_rcvd, 1);
+		}
+	}
+
+	return TC_ACT_UNSPEC;
+
+ignore:
+	count(STAT_IGNORED);
+	return TC_ACT_UNSPEC;
+}
+
+SEC("tc")
+int tc_ingress(struct __sk_buff *skb)
+{
+	return handle(skb, 0);
+}
+
+SEC("tc")
+int tc_egress(struct __sk_buff *skb)
+{
+	return handle(skb, 1);
+}
+
+char _license[] SEC("license") = "GPL";
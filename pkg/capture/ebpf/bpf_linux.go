@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpf(2) commands (see include/uapi/linux/bpf.h)
+const (
+	bpfMapLookupElem          = 1
+	bpfMapGetNextKey          = 4
+	bpfObjGet                 = 7
+	bpfMapLookupAndDeleteElem = 21
+)
+
+// bpfAttrObj mirrors the BPF_OBJ_* part of union bpf_attr
+type bpfAttrObj struct {
+	pathname uint64
+	fd       uint32
+	flags    uint32
+}
+
+// bpfAttrMapElem mirrors the BPF_MAP_*_ELEM part of union bpf_attr
+type bpfAttrMapElem struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64 // value or next_key
+	flags uint64
+}
+
+func bpf(cmd uintptr, attr unsafe.Pointer, size uintptr) (uintptr, error) {
+	r, _, errno := unix.Syscall(unix.SYS_BPF, cmd, uintptr(attr), size)
+	if errno != 0 {
+		return r, errno
+	}
+	return r, nil
+}
+
+func objGet(path string) (int, error) {
+	pathname, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	attr := bpfAttrObj{
+		pathname: uint64(uintptr(unsafe.Pointer(pathname))),
+	}
+	fd, err := bpf(bpfObjGet, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(pathname)
+	if err != nil {
+		return -1, err
+	}
+	return int(fd), nil
+}
+
+func closeFD(fd int) error {
+	return unix.Close(fd)
+}
+
+// nextKey writes the key following prevKey (or the first key if prevKey is nil) to next and
+// returns false if there are no more keys
+func nextKey(fd int, prevKey, next []byte) (bool, error) {
+	attr := bpfAttrMapElem{
+		mapFD: uint32(fd),
+		value: uint64(uintptr(unsafe.Pointer(&next[0]))),
+	}
+	if prevKey != nil {
+		attr.key = uint64(uintptr(unsafe.Pointer(&prevKey[0])))
+	}
+	_, err := bpf(bpfMapGetNextKey, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(prevKey)
+	runtime.KeepAlive(next)
+	if errors.Is(err, unix.ENOENT) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func lookup(fd int, key, value []byte) (bool, error) {
+	return elemOp(bpfMapLookupElem, fd, key, value)
+}
+
+func lookupAndDelete(fd int, key, value []byte) (bool, error) {
+	return elemOp(bpfMapLookupAndDeleteElem, fd, key, value)
+}
+
+func elemOp(cmd uintptr, fd int, key, value []byte) (bool, error) {
+	attr := bpfAttrMapElem{
+		mapFD: uint32(fd),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+		value: uint64(uintptr(unsafe.Pointer(&value[0]))),
+	}
+	_, err := bpf(cmd, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	if errors.Is(err, unix.ENOENT) {
+		return false, nil
+	}
+	return err == nil, err
+}
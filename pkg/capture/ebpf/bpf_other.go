@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package ebpf
+
+func objGet(_ string) (int, error) {
+	return -1, ErrNotSupported
+}
+
+func closeFD(_ int) error {
+	return ErrNotSupported
+}
+
+func nextKey(_ int, _, _ []byte) (bool, error) {
+	return false, ErrNotSupported
+}
+
+func lookup(_ int, _, _ []byte) (bool, error) {
+	return false, ErrNotSupported
+}
+
+func lookupAndDelete(_ int, _, _ []byte) (bool, error) {
+	return false, ErrNotSupported
+}
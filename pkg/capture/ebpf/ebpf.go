@@ -0,0 +1,196 @@
+// Package ebpf provides a flow source consuming flows aggregated in-kernel by goProbe's TC
+// eBPF program (see bpf/flows.c) instead of processing each packet in userspace.
+//
+// The program is attached to the ingress and egress hooks of an interface and aggregates
+// packets per flow in a hash map, which is pinned to the BPF file system (see
+// bpf/goprobe-ebpf-attach). The Source periodically drains this map, handing the flow
+// summaries to goProbe's FlowLog
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+const (
+	// FlowsMapName denotes the name of the pinned map holding the flows
+	FlowsMapName = "goprobe_flows"
+
+	// StatsMapName denotes the name of the pinned map holding the program statistics
+	StatsMapName = "goprobe_stats"
+
+	// keySize / valueSize denote the size of struct flow_key / struct flow_value (see bpf/flows.c)
+	keySize   = 40
+	valueSize = 40
+
+	statsKeySize   = 4
+	statsValueSize = 8
+)
+
+// Indices of the statistics counters (see bpf/flows.c)
+const (
+	statPackets = iota
+	statMapFull
+	statIgnored
+	numStats
+)
+
+var (
+	// ErrNotSupported is returned if eBPF flow sources are not supported on the platform
+	ErrNotSupported = errors.New("eBPF flow source is not supported on this platform")
+
+	errorSourceClosed = errors.New("eBPF flow source closed")
+)
+
+// Stats denotes the statistics of the eBPF program. All counters are cumulative since the
+// program was attached
+type Stats struct {
+	Packets uint64 // Packets: number of packets aggregated into the flow map
+	Dropped uint64 // Dropped: number of packets that could not be recorded since the flow map was full
+	Ignored uint64 // Ignored: number of packets ignored (non-IP, non-first fragments, truncated headers)
+}
+
+// Source drains flows from the pinned maps of the eBPF program attached to an interface
+type Source struct {
+	iface   string
+	pinPath string
+
+	flowsFD, statsFD int
+
+	// reusable buffers for map iteration / lookups
+	keys     []byte
+	keyBuf   []byte
+	valueBuf []byte
+}
+
+// Open opens the maps of the eBPF program attached to iface, which are expected to be
+// pinned in the pinPath directory
+func Open(iface, pinPath string) (*Source, error) {
+	s := &Source{
+		iface:    iface,
+		pinPath:  pinPath,
+		flowsFD:  -1,
+		statsFD:  -1,
+		keyBuf:   make([]byte, keySize),
+		valueBuf: make([]byte, valueSize),
+	}
+
+	var err error
+	if s.flowsFD, err = objGet(filepath.Join(pinPath, FlowsMapName)); err != nil {
+		return nil, fmt.Errorf("failed to open flow map of %s (is the eBPF program attached?): %w", iface, err)
+	}
+	if s.statsFD, err = objGet(filepath.Join(pinPath, StatsMapName)); err != nil {
+		_ = closeFD(s.flowsFD)
+		return nil, fmt.Errorf("failed to open stats map of %s: %w", iface, err)
+	}
+
+	return s, nil
+}
+
+// Iface returns the name of the interface the source is attached to
+func (s *Source) Iface() string {
+	return s.iface
+}
+
+// Drain removes all flows aggregated since the last call from the kernel and calls fn for
+// each of them. It returns the number of flows drained
+func (s *Source) Drain(fn func(capturetypes.FlowSummary)) (int, error) {
+	if s.flowsFD < 0 {
+		return 0, errorSourceClosed
+	}
+
+	// Collect all keys first, since deleting elements while iterating over a hash map
+	// restarts the iteration. Flows added in the meantime are picked up during the
+	// next call
+	s.keys = s.keys[:0]
+	var (
+		prevKey []byte
+		more    bool
+		err     error
+	)
+	for {
+		more, err = nextKey(s.flowsFD, prevKey, s.keyBuf)
+		if err != nil {
+			return 0, fmt.Errorf("failed to iterate flow map: %w", err)
+		}
+		if !more {
+			break
+		}
+		s.keys = append(s.keys, s.keyBuf...)
+		prevKey = s.keys[len(s.keys)-keySize:]
+	}
+
+	var n int
+	for i := 0; i < len(s.keys); i += keySize {
+		key := s.keys[i : i+keySize]
+		found, err := lookupAndDelete(s.flowsFD, key, s.valueBuf)
+		if err != nil {
+			return n, fmt.Errorf("failed to drain flow map: %w", err)
+		}
+		if !found {
+			continue
+		}
+		fn(decodeSummary(key, s.valueBuf))
+		n++
+	}
+
+	return n, nil
+}
+
+// Stats returns the (cumulative) statistics of the eBPF program
+func (s *Source) Stats() (Stats, error) {
+	if s.statsFD < 0 {
+		return Stats{}, errorSourceClosed
+	}
+
+	var counters [numStats]uint64
+	key, value := make([]byte, statsKeySize), make([]byte, statsValueSize)
+	for i := range counters {
+		binary.NativeEndian.PutUint32(key, uint32(i))
+		if _, err := lookup(s.statsFD, key, value); err != nil {
+			return Stats{}, fmt.Errorf("failed to read stats map: %w", err)
+		}
+		counters[i] = binary.NativeEndian.Uint64(value)
+	}
+
+	return Stats{
+		Packets: counters[statPackets],
+		Dropped: counters[statMapFull],
+		Ignored: counters[statIgnored],
+	}, nil
+}
+
+// Close closes the maps. The eBPF program itself remains attached to the interface
+func (s *Source) Close() error {
+	var errs []error
+	for _, fd := range []*int{&s.flowsFD, &s.statsFD} {
+		if *fd < 0 {
+			continue
+		}
+		if err := closeFD(*fd); err != nil {
+			errs = append(errs, err)
+		}
+		*fd = -1
+	}
+	return errors.Join(errs...)
+}
+
+// decodeSummary converts a struct flow_key / struct flow_value pair into a flow summary. The
+// first bytes of the key correspond to the layout of an EPHash
+func decodeSummary(key, value []byte) capturetypes.FlowSummary {
+	var summary capturetypes.FlowSummary
+	copy(summary.EPHash[:], key[:capturetypes.EPHashSize])
+	summary.IsIPv4 = key[capturetypes.EPHashSize] != 0
+
+	summary.BytesRcvd = binary.NativeEndian.Uint64(value[0:8])
+	summary.BytesSent = binary.NativeEndian.Uint64(value[8:16])
+	summary.PacketsRcvd = binary.NativeEndian.Uint64(value[16:24])
+	summary.PacketsSent = binary.NativeEndian.Uint64(value[24:32])
+	summary.AuxInfo = value[32]
+
+	return summary
+}
@@ -0,0 +1,42 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSummary(t *testing.T) {
+	key, value := make([]byte, keySize), make([]byte, valueSize)
+
+	// 10.0.0.1:12345 -> 10.0.0.2:443 (TCP)
+	copy(key[0:4], []byte{10, 0, 0, 1})
+	copy(key[16:20], []byte{10, 0, 0, 2})
+	copy(key[32:34], []byte{0x01, 0xbb})
+	copy(key[34:36], []byte{0x30, 0x39})
+	key[36] = capturetypes.TCP
+	key[37] = 1
+
+	binary.NativeEndian.PutUint64(value[0:8], 1000)
+	binary.NativeEndian.PutUint64(value[8:16], 500)
+	binary.NativeEndian.PutUint64(value[16:24], 10)
+	binary.NativeEndian.PutUint64(value[24:32], 5)
+	value[32] = 0x02 // SYN
+
+	summary := decodeSummary(key, value)
+
+	var expectedHash capturetypes.EPHash
+	copy(expectedHash[:], key[:capturetypes.EPHashSize])
+
+	require.Equal(t, capturetypes.FlowSummary{
+		EPHash:      expectedHash,
+		IsIPv4:      true,
+		AuxInfo:     0x02,
+		BytesRcvd:   1000,
+		BytesSent:   500,
+		PacketsRcvd: 10,
+		PacketsSent: 5,
+	}, summary)
+}
@@ -0,0 +1,224 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+)
+
+// emergencyMode bounds the number of concurrent flows of an interface (c.f. config.EmergencyConfig):
+// once the limit is reached, the IP addresses of all subsequently observed flows are collapsed to their
+// prefixes (retaining only the lower of their ports) until the number of distinct flows subsides. Since
+// collapsed flows cannot be matched to individual packets, neither TCP handshakes, TTLs nor IPv6
+// fragments are tracked for them. Like the flow log it is part of, it is NOT threadsafe
+type emergencyMode struct {
+	maxFlows               int
+	ipv4Prefix, ipv6Prefix int
+	ipv4Mask, ipv6Mask     net.IPMask
+
+	now func() time.Time
+
+	active        bool      // mode is currently in effect
+	activeAtStart bool      // mode was in effect at the start of the current rotation interval
+	entered       bool      // mode was entered during the current rotation interval
+	since         time.Time // time at which the mode was entered
+
+	// distinct (uncollapsed) flows observed during the current rotation interval while the mode is in
+	// effect, used to determine if the cardinality subsided. Since only the recovery threshold matters,
+	// tracking stops once it is reached (bounding the memory consumption)
+	observed map[string]struct{}
+	exceeded bool
+}
+
+// emergencyRotation summarizes the emergency aggregation mode of a rotation interval
+type emergencyRotation struct {
+	stats   *capturetypes.EmergencyStats // stats of the mode (if it was in effect during the interval)
+	entered bool                         // mode was entered during the interval
+	left    bool                         // mode was left upon the rotation
+}
+
+func newEmergencyMode(cfg *config.EmergencyConfig, now func() time.Time) *emergencyMode {
+	ipv4Prefix, ipv6Prefix := cfg.Prefixes()
+	return &emergencyMode{
+		maxFlows:   cfg.MaxFlows,
+		ipv4Prefix: ipv4Prefix,
+		ipv6Prefix: ipv6Prefix,
+		ipv4Mask:   net.CIDRMask(ipv4Prefix, 8*net.IPv4len),
+		ipv6Mask:   net.CIDRMask(ipv6Prefix, 8*net.IPv6len),
+		now:        now,
+	}
+}
+
+// recoveryThreshold denotes the number of distinct flows per rotation interval below which the
+// cardinality is considered to have subsided
+func (e *emergencyMode) recoveryThreshold() int {
+	return max(e.maxFlows/2, 1)
+}
+
+// limit returns the hash a packet / flow summary is recorded under given the current number of
+// flows, entering the mode if the limit is reached
+func (e *emergencyMode) limit(epHash capturetypes.EPHash, isIPv4 bool, nFlows int) capturetypes.EPHash {
+	if !e.active {
+		if nFlows < e.maxFlows {
+			return epHash
+		}
+		e.enter()
+	}
+
+	e.observe(epHash)
+	return e.collapse(epHash, isIPv4)
+}
+
+func (e *emergencyMode) enter() {
+	e.active, e.entered = true, true
+	e.since = e.now()
+	e.observed, e.exceeded = make(map[string]struct{}), false
+}
+
+// observe records a distinct (uncollapsed) flow, irrespective of its direction
+func (e *emergencyMode) observe(epHash capturetypes.EPHash) {
+	if e.exceeded {
+		return
+	}
+
+	key := epHash
+	if rev := epHash.Reverse(); bytes.Compare(rev[:], key[:]) < 0 {
+		key = rev
+	}
+	e.observed[string(key[:])] = struct{}{}
+
+	if len(e.observed) >= e.recoveryThreshold() {
+		e.observed, e.exceeded = nil, true
+	}
+}
+
+// collapse masks both IP addresses of a flow to the configured prefixes. For TCP / UDP, only the
+// lower of both ports is retained, which (in contrast to e.g. always dropping the source port)
+// ensures that both directions of a flow are collapsed identically
+func (e *emergencyMode) collapse(epHash capturetypes.EPHash, isIPv4 bool) capturetypes.EPHash {
+	if isIPv4 {
+		for i := 0; i < net.IPv4len; i++ {
+			epHash[i] &= e.ipv4Mask[i]
+			epHash[16+i] &= e.ipv4Mask[i]
+		}
+	} else {
+		for i := 0; i < net.IPv6len; i++ {
+			epHash[i] &= e.ipv6Mask[i]
+			epHash[16+i] &= e.ipv6Mask[i]
+		}
+	}
+
+	if proto := epHash[36]; proto != capturetypes.TCP && proto != capturetypes.UDP {
+		return epHash
+	}
+	dport, sport := epHash[32:34], epHash[34:36]
+	if isZeroPort(dport) || isZeroPort(sport) {
+		return epHash
+	}
+	if bytes.Compare(dport, sport) <= 0 {
+		sport[0], sport[1] = 0, 0
+	} else {
+		dport[0], dport[1] = 0, 0
+	}
+	return epHash
+}
+
+func isZeroPort(port []byte) bool {
+	return port[0] == 0 && port[1] == 0
+}
+
+// rotate concludes the current rotation interval. The mode is left if it was in effect during the
+// whole interval and the number of distinct flows stayed below the recovery threshold. Like the
+// rotation of the flow log itself, it must be called while the capture is locked
+func (e *emergencyMode) rotate() (res emergencyRotation) {
+	if !e.active && !e.activeAtStart {
+		return
+	}
+
+	res.stats = e.stats()
+	res.entered = e.entered
+	if e.active && e.activeAtStart && !e.exceeded {
+		e.active, res.left = false, true
+		e.since = time.Time{}
+	}
+
+	e.activeAtStart, e.entered = e.active, false
+	e.observed, e.exceeded = nil, false
+	if e.active {
+		e.observed = make(map[string]struct{})
+	}
+	return
+}
+
+// stats returns the stats of the mode (if in effect)
+func (e *emergencyMode) stats() *capturetypes.EmergencyStats {
+	if e == nil || (!e.active && !e.activeAtStart) {
+		return nil
+	}
+	return &capturetypes.EmergencyStats{
+		Since:      e.since,
+		IPv4Prefix: e.ipv4Prefix,
+		IPv6Prefix: e.ipv6Prefix,
+	}
+}
+
+// announceEmergency reports a switch of the emergency aggregation mode of an interface (if any)
+func (cm *Manager) announceEmergency(ctx context.Context, iface string, cfg *config.EmergencyConfig, timestamp time.Time, res emergencyRotation) {
+	if res.stats == nil {
+		return
+	}
+
+	logger := logging.FromContext(ctx).With(
+		"max_flows", cfg.MaxFlows,
+		"ipv4_prefix", res.stats.IPv4Prefix,
+		"ipv6_prefix", res.stats.IPv6Prefix,
+	)
+
+	var alerts []*capturetypes.EmergencyAlert
+	if res.entered {
+		promEmergencyEntered.WithLabelValues(iface).Inc()
+		logger.With("since", res.stats.Since).Warn("number of concurrent flows reached limit, entered emergency aggregation mode")
+		alerts = append(alerts, &capturetypes.EmergencyAlert{
+			State:     capturetypes.EmergencyEntered,
+			Timestamp: res.stats.Since,
+		})
+	}
+	if res.left {
+		logger.Info("number of distinct flows subsided, left emergency aggregation mode")
+		alerts = append(alerts, &capturetypes.EmergencyAlert{
+			State:     capturetypes.EmergencyLeft,
+			Timestamp: timestamp,
+		})
+	}
+	if res.left {
+		promEmergencyActive.WithLabelValues(iface).Set(0)
+	} else {
+		promEmergencyActive.WithLabelValues(iface).Set(1)
+	}
+
+	if cm.alertTarget == nil || len(alerts) == 0 {
+		return
+	}
+
+	// deliver the alerts in the background to avoid delaying the rotation of other interfaces
+	go func() {
+		pusher, err := cm.alertTarget.Pusher()
+		for _, alert := range alerts {
+			alert.Iface = iface
+			alert.MaxFlows = cfg.MaxFlows
+			alert.IPv4Prefix, alert.IPv6Prefix = res.stats.IPv4Prefix, res.stats.IPv6Prefix
+			if err == nil {
+				err = pusher.PushJSON(context.WithoutCancel(ctx), alert)
+			}
+			if err != nil {
+				logger.Errorf("failed to send emergency aggregation mode %s alert: %v", alert.State, err)
+				return
+			}
+		}
+	}()
+}
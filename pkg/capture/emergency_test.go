@@ -0,0 +1,70 @@
+package capture
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmergencyMode(t *testing.T) {
+	server := netip.MustParseAddr("192.168.1.1")
+	clientHash := func(i int) capturetypes.EPHash {
+		return testTCPEPHash(netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), server, uint16(30000+i), 443)
+	}
+
+	c := newCapture("eth0", config.CaptureConfig{Emergency: &config.EmergencyConfig{MaxFlows: 4}})
+	require.NotNil(t, c.flowLog.emergency)
+
+	// flows are recorded individually until the limit is reached
+	for i := 0; i < 4; i++ {
+		c.addToFlowLog(clientHash(i), capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	}
+	require.Equal(t, 4, c.flowLog.Len())
+	require.Nil(t, c.flowLog.emergency.stats())
+
+	// beyond the limit, both directions of all flows are collapsed to their prefixes and the lower port
+	for i := 4; i < 64; i++ {
+		c.addToFlowLog(clientHash(i), capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+		c.addToFlowLog(clientHash(i).Reverse(), capture.PacketThisHost, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	}
+	require.Equal(t, 5, c.flowLog.Len())
+	collapsed := testTCPEPHash(netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("192.168.1.0"), 0, 443)
+	flow, exists := c.flowLog.Flows()[string(collapsed[:])]
+	require.True(t, exists)
+	require.Equal(t, uint64(120), flow.packetsSent+flow.packetsRcvd)
+
+	// the mode is not left upon the rotation of the interval it was entered in
+	c.rotate()
+	res := c.rotateEmergency()
+	require.NotNil(t, res.stats)
+	require.Equal(t, 24, res.stats.IPv4Prefix)
+	require.Equal(t, 64, res.stats.IPv6Prefix)
+	require.True(t, res.entered)
+	require.False(t, res.left)
+
+	// a full interval with a high number of distinct flows retains the mode
+	for i := 0; i < 2; i++ {
+		c.addToFlowLog(clientHash(i), capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	}
+	c.rotate()
+	res = c.rotateEmergency()
+	require.NotNil(t, res.stats)
+	require.False(t, res.entered)
+	require.False(t, res.left)
+
+	// once the number of distinct flows subsided, the mode is left
+	c.addToFlowLog(clientHash(0), capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	c.rotate()
+	res = c.rotateEmergency()
+	require.NotNil(t, res.stats)
+	require.True(t, res.left)
+
+	c.addToFlowLog(clientHash(0), capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK)
+	require.Nil(t, c.flowLog.emergency.stats())
+	c.rotate()
+	require.Nil(t, c.rotateEmergency().stats)
+}
@@ -0,0 +1,262 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+	"github.com/fako1024/slimcap/capture"
+)
+
+const (
+	errorDumpIndexFile = "index.json"
+	errorDumpQueueSize = 64
+)
+
+var (
+	// ErrErrorDumpsDisabled signifies that error dumps are not configured
+	ErrErrorDumpsDisabled = errors.New("error dumps are disabled")
+
+	// ErrErrorDumpNotFound signifies that a requested error dump does not exist (anymore)
+	ErrErrorDumpNotFound = errors.New("error dump not found")
+
+	// ErrInvalidIfaceName signifies that an interface name is not valid (e.g. contains a path separator)
+	ErrInvalidIfaceName = errors.New("invalid interface name")
+)
+
+type errorDump struct {
+	iface   string
+	meta    capturetypes.ErrorDump
+	payload []byte
+}
+
+// errorDumps writes the (truncated) payloads of packets that could not be parsed to per-interface
+// directories, each of them containing the most recent dumps and an index describing them. Dumps
+// are written asynchronously in order to not interfere with packet processing
+type errorDumps struct {
+	path        string
+	maxDumps    int
+	maxSize     int
+	minInterval time.Duration
+
+	queue chan errorDump
+
+	// indices caches the index of each interface (loaded from disk upon first access)
+	indices map[string][]capturetypes.ErrorDump
+	sync.Mutex
+}
+
+func newErrorDumps(cfg *config.ErrorDumpConfig) *errorDumps {
+	e := &errorDumps{
+		path:        cfg.Path,
+		maxDumps:    cfg.MaxDumps,
+		maxSize:     cfg.MaxSize,
+		minInterval: cfg.MinInterval,
+		queue:       make(chan errorDump, errorDumpQueueSize),
+		indices:     make(map[string][]capturetypes.ErrorDump),
+	}
+	if e.maxDumps == 0 {
+		e.maxDumps = config.DefaultErrorDumpMaxDumps
+	}
+	if e.maxSize == 0 {
+		e.maxSize = config.DefaultErrorDumpMaxSize
+	}
+	if e.minInterval == 0 {
+		e.minInterval = config.DefaultErrorDumpMinInterval
+	}
+	return e
+}
+
+// dumper returns the (rate-limited) dumper for an individual capture. If error dumps are not
+// configured, nil is returned (which is safe to use)
+func (e *errorDumps) dumper(iface string) *errorDumper {
+	if e == nil {
+		return nil
+	}
+	return &errorDumper{
+		iface: iface,
+		dumps: e,
+	}
+}
+
+// run writes all queued dumps to disk until the context is cancelled
+func (e *errorDumps) run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dump := <-e.queue:
+			if err := e.write(dump); err != nil {
+				logger.With("iface", dump.iface).Errorf("failed to write error dump: %v", err)
+			}
+		}
+	}
+}
+
+func (e *errorDumps) write(dump errorDump) error {
+	e.Lock()
+	defer e.Unlock()
+
+	index, err := e.index(dump.iface)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(e.path, dump.iface)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	dump.meta.ID = 1
+	if len(index) > 0 {
+		dump.meta.ID = index[len(index)-1].ID + 1
+	}
+	if err := os.WriteFile(filepath.Join(dir, errorDumpFileName(dump.meta.ID)), dump.payload, 0600); err != nil {
+		return err
+	}
+	index = append(index, dump.meta)
+
+	// Rotate, i.e. remove the oldest dumps exceeding the limit
+	for len(index) > e.maxDumps {
+		if err := os.Remove(filepath.Join(dir, errorDumpFileName(index[0].ID))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		index = index[1:]
+	}
+	e.indices[dump.iface] = index
+
+	// Write the index to a temporary file first to never expose a partially written one
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(dir, errorDumpIndexFile+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dir, errorDumpIndexFile))
+}
+
+// index returns the index of an interface, loading it from disk if not yet cached (in order to continue
+// the sequence of dumps written before a restart). Must be called with the lock held
+func (e *errorDumps) index(iface string) ([]capturetypes.ErrorDump, error) {
+	if index, exists := e.indices[iface]; exists {
+		return index, nil
+	}
+
+	var index []capturetypes.ErrorDump
+	data, err := os.ReadFile(filepath.Join(e.path, iface, errorDumpIndexFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse error dump index: %w", err)
+	}
+	e.indices[iface] = index
+
+	return index, nil
+}
+
+// list returns the metadata of all dumps retained for an interface (oldest first)
+func (e *errorDumps) list(iface string) ([]capturetypes.ErrorDump, error) {
+	if e == nil {
+		return nil, ErrErrorDumpsDisabled
+	}
+	if err := validateIfaceName(iface); err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	index, err := e.index(iface)
+	if err != nil {
+		return nil, err
+	}
+	return append([]capturetypes.ErrorDump{}, index...), nil
+}
+
+// payload returns the raw (truncated) IP layer of a dump
+func (e *errorDumps) payload(iface string, id uint64) ([]byte, error) {
+	if e == nil {
+		return nil, ErrErrorDumpsDisabled
+	}
+	if err := validateIfaceName(iface); err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(e.path, iface, errorDumpFileName(id)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s/%d", ErrErrorDumpNotFound, iface, id)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// errorDumper samples the packets of an individual capture that could not be parsed. It is
+// used exclusively by the processing routine of its capture and hence not thread-safe
+type errorDumper struct {
+	iface    string
+	dumps    *errorDumps
+	lastDump time.Time
+}
+
+// dump queues the payload of a packet for being written to disk, unless the previous dump
+// happened less than the minimum interval ago or the queue is full
+func (d *errorDumper) dump(ipLayer capture.IPLayer, pktType capture.PacketType, pktSize uint32, errno capturetypes.ParsingErrno) {
+	if d == nil {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(d.lastDump) < d.dumps.minInterval {
+		return
+	}
+	d.lastDump = now
+
+	// The IP layer is only valid until the next packet is fetched, hence it must be copied
+	payload := make([]byte, min(len(ipLayer), d.dumps.maxSize))
+	copy(payload, ipLayer)
+
+	select {
+	case d.dumps.queue <- errorDump{
+		iface: d.iface,
+		meta: capturetypes.ErrorDump{
+			Timestamp: now,
+			Errno:     errno,
+			Error:     errno.String(),
+			PktType:   pktType,
+			PktSize:   pktSize,
+			Size:      len(payload),
+		},
+		payload: payload,
+	}:
+	default:
+	}
+}
+
+func errorDumpFileName(id uint64) string {
+	return fmt.Sprintf("%010d.bin", id)
+}
+
+func validateIfaceName(iface string) error {
+	if iface == "" || iface == "." || iface == ".." || filepath.Base(iface) != iface {
+		return fmt.Errorf("%w: %q", ErrInvalidIfaceName, iface)
+	}
+	return nil
+}
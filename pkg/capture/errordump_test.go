@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorDumps(t *testing.T) {
+	dir := t.TempDir()
+	dumps := newErrorDumps(&config.ErrorDumpConfig{
+		Path:        dir,
+		MaxDumps:    3,
+		MaxSize:     8,
+		MinInterval: time.Hour,
+	})
+
+	// dumps are rate-limited per interface
+	dumper := dumps.dumper("eth0")
+	payload := []byte{0x45, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	dumper.dump(payload, capture.PacketOutgoing, 1514, capturetypes.ErrnoPacketTruncated)
+	dumper.dump(payload, capture.PacketOutgoing, 1514, capturetypes.ErrnoPacketTruncated)
+	require.Len(t, dumps.queue, 1)
+	require.Nil(t, dumps.write(<-dumps.queue))
+
+	// only the most recent dumps are retained
+	for i := 0; i < 5; i++ {
+		dumper.lastDump = time.Time{}
+		dumper.dump(append([]byte{byte(i)}, payload...), capture.PacketThisHost, 64, capturetypes.ErrnoInvalidIPHeader)
+		require.Nil(t, dumps.write(<-dumps.queue))
+	}
+
+	index, err := dumps.list("eth0")
+	require.Nil(t, err)
+	require.Len(t, index, 3)
+	for i, dump := range index {
+		require.Equal(t, uint64(i+4), dump.ID)
+		require.Equal(t, capturetypes.ErrnoInvalidIPHeader, dump.Errno)
+		require.Equal(t, "invalid IP header", dump.Error)
+		require.Equal(t, 8, dump.Size)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "eth0"))
+	require.Nil(t, err)
+	require.Len(t, entries, 4)
+
+	data, err := dumps.payload("eth0", 6)
+	require.Nil(t, err)
+	require.Equal(t, append([]byte{4}, payload[:7]...), data)
+
+	_, err = dumps.payload("eth0", 1)
+	require.ErrorIs(t, err, ErrErrorDumpNotFound)
+	_, err = dumps.list("../eth0")
+	require.ErrorIs(t, err, ErrInvalidIfaceName)
+
+	// the sequence of dumps is continued from the index on disk
+	dumps = newErrorDumps(&config.ErrorDumpConfig{Path: dir, MaxDumps: 3})
+	require.Nil(t, dumps.write(errorDump{iface: "eth0", payload: payload}))
+	index, err = dumps.list("eth0")
+	require.Nil(t, err)
+	require.Equal(t, uint64(7), index[len(index)-1].ID)
+
+	// error dumps are optional
+	var disabled *errorDumps
+	disabled.dumper("eth0").dump(payload, capture.PacketOutgoing, 1514, capturetypes.ErrnoPacketTruncated)
+	_, err = disabled.list("eth0")
+	require.ErrorIs(t, err, ErrErrorDumpsDisabled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dumps.run(ctx)
+}
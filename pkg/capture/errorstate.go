@@ -0,0 +1,133 @@
+package capture
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+// errProcessingTerminated denotes that packet processing terminated prematurely without reporting an error
+var errProcessingTerminated = errors.New("packet processing terminated unexpectedly")
+
+// reasonError annotates an error with the reason it puts an interface into an error state, for errors
+// whose reason is determined by the stage they occur in rather than by their cause
+type reasonError struct {
+	reason capturetypes.ErrorReason
+	err    error
+}
+
+func withReason(reason capturetypes.ErrorReason, err error) error {
+	return &reasonError{reason: reason, err: err}
+}
+
+// Error implements the error interface
+func (e *reasonError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the annotated error
+func (e *reasonError) Unwrap() error {
+	return e.err
+}
+
+// errorReason determines the reason of an error of a capture. The underlying system call error (if any)
+// takes precedence over the reason the error was annotated with, since it is more specific
+func errorReason(err error) capturetypes.ErrorReason {
+	switch {
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return capturetypes.ErrorReasonPermissionDenied
+	case errors.Is(err, syscall.ENODEV), errors.Is(err, syscall.ENXIO),
+		strings.Contains(err.Error(), "no such network interface"):
+		return capturetypes.ErrorReasonIfaceMissing
+	case errors.Is(err, syscall.ENETDOWN):
+		return capturetypes.ErrorReasonIfaceDown
+	case errors.Is(err, syscall.ENOMEM), errors.Is(err, syscall.ENOBUFS), errors.Is(err, syscall.EMFILE):
+		return capturetypes.ErrorReasonResourcesExhausted
+	}
+
+	var re *reasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return capturetypes.ErrorReasonUnknown
+}
+
+// ifaceErrors tracks the error state of all interfaces which are configured, but not capturing due to
+// an error (until they capture successfully or are removed from the configuration)
+type ifaceErrors struct {
+	errs map[string]*capturetypes.IfaceError
+
+	sync.Mutex
+}
+
+func newIfaceErrors() *ifaceErrors {
+	return &ifaceErrors{
+		errs: make(map[string]*capturetypes.IfaceError),
+	}
+}
+
+// record puts an interface into an error state (or updates it), returning its state
+func (e *ifaceErrors) record(iface string, class capturetypes.ErrorClass, err error, timestamp time.Time) capturetypes.IfaceError {
+	e.Lock()
+	defer e.Unlock()
+
+	ifaceErr, exists := e.errs[iface]
+	if !exists {
+		ifaceErr = &capturetypes.IfaceError{Since: timestamp}
+		e.errs[iface] = ifaceErr
+	}
+
+	reason := errorReason(err)
+	ifaceErr.Reason = reason
+	ifaceErr.Class = class
+	ifaceErr.Message = err.Error()
+	ifaceErr.Count++
+	ifaceErr.Timestamp = timestamp
+	ifaceErr.Hint = reason.Hint()
+
+	promIfaceErrors.WithLabelValues(iface, string(reason)).Inc()
+
+	return *ifaceErr
+}
+
+// clear clears the error state of an interface (e.g. once it captures successfully)
+func (e *ifaceErrors) clear(iface string) {
+	e.Lock()
+	delete(e.errs, iface)
+	e.Unlock()
+}
+
+// prune clears the error state of all interfaces that are no longer present in the configuration
+func (e *ifaceErrors) prune(ifaces config.Ifaces) {
+	e.Lock()
+	for iface := range e.errs {
+		if _, exists := ifaces[iface]; !exists {
+			delete(e.errs, iface)
+		}
+	}
+	e.Unlock()
+}
+
+// get returns the error state of all (or a set of) interfaces in an error state
+func (e *ifaceErrors) get(ifaces ...string) map[string]capturetypes.IfaceError {
+	e.Lock()
+	defer e.Unlock()
+
+	res := make(map[string]capturetypes.IfaceError)
+	for iface, ifaceErr := range e.errs {
+		if len(ifaces) > 0 && !slices.Contains(ifaces, iface) {
+			continue
+		}
+		res[iface] = *ifaceErr
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,105 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReason(t *testing.T) {
+	var tests = []struct {
+		name     string
+		err      error
+		expected capturetypes.ErrorReason
+	}{
+		{"permission denied", fmt.Errorf("failed to initialize capture: %w", os.NewSyscallError("socket", syscall.EPERM)), capturetypes.ErrorReasonPermissionDenied},
+		{"access denied", fmt.Errorf("failed to initialize capture: %w", syscall.EACCES), capturetypes.ErrorReasonPermissionDenied},
+		{"missing interface (errno)", fmt.Errorf("failed to initialize capture: %w", syscall.ENODEV), capturetypes.ErrorReasonIfaceMissing},
+		{"missing interface (lookup)", errors.New("route ip+net: no such network interface"), capturetypes.ErrorReasonIfaceMissing},
+		{"interface down", fmt.Errorf("failed to initialize capture: %w", syscall.ENETDOWN), capturetypes.ErrorReasonIfaceDown},
+		{"out of memory", fmt.Errorf("failed to set up ring buffer: %w", syscall.ENOMEM), capturetypes.ErrorReasonResourcesExhausted},
+		{"invalid filter", withReason(capturetypes.ErrorReasonInvalidConfig, errors.New("failed to initialize capture filter")), capturetypes.ErrorReasonInvalidConfig},
+		{"netns permission denied", withReason(capturetypes.ErrorReasonNetnsUnavailable, fmt.Errorf("failed to resolve network namespace: %w", syscall.EPERM)), capturetypes.ErrorReasonPermissionDenied},
+		{"netns missing", withReason(capturetypes.ErrorReasonNetnsUnavailable, fmt.Errorf("failed to resolve network namespace: %w", os.ErrNotExist)), capturetypes.ErrorReasonNetnsUnavailable},
+		{"unknown", errors.New("something went wrong"), capturetypes.ErrorReasonUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, errorReason(test.err))
+		})
+	}
+}
+
+func TestIfaceErrors(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	errs := newIfaceErrors()
+	require.Nil(t, errs.get())
+
+	errs.record("eth0", capturetypes.ErrorClassInit, syscall.EPERM, start)
+	ifaceErr := errs.record("eth0", capturetypes.ErrorClassInit, syscall.EPERM, start.Add(time.Minute))
+	require.Equal(t, capturetypes.IfaceError{
+		Reason:    capturetypes.ErrorReasonPermissionDenied,
+		Class:     capturetypes.ErrorClassInit,
+		Message:   syscall.EPERM.Error(),
+		Count:     2,
+		Since:     start,
+		Timestamp: start.Add(time.Minute),
+		Hint:      capturetypes.ErrorReasonPermissionDenied.Hint(),
+	}, ifaceErr)
+
+	errs.record("eth1", capturetypes.ErrorClassProcessing, errProcessingTerminated, start)
+	require.Len(t, errs.get(), 2)
+	require.Len(t, errs.get("eth1", "eth2"), 1)
+	require.Equal(t, capturetypes.ErrorReasonUnknown, errs.get("eth1")["eth1"].Reason)
+
+	// errors are cleared once the interface captures successfully or is removed from the configuration
+	errs.clear("eth0")
+	require.Nil(t, errs.get("eth0"))
+	errs.prune(config.Ifaces{"eth0": config.CaptureConfig{}})
+	require.Nil(t, errs.get())
+}
+
+func TestIfaceErrorState(t *testing.T) {
+	captureManager := NewManager(
+		writeout.NewGoDBHandler(t.TempDir(), encoders.EncoderTypeLZ4),
+		WithSourceInitFn(func(_ *Capture) (Source, error) {
+			return nil, fmt.Errorf("failed to create socket: %w", syscall.EPERM)
+		}),
+	)
+	ifaceConfig := config.CaptureConfig{
+		RingBuffer: &config.RingBufferConfig{
+			BlockSize: config.DefaultRingBufferBlockSize,
+			NumBlocks: config.DefaultRingBufferNumBlocks,
+		},
+	}
+	ifaces := config.Ifaces{"eth0": ifaceConfig}
+
+	// repeated attempts to initialize the capture are counted
+	for i := 0; i < 2; i++ {
+		_, _, _, err := captureManager.Update(context.Background(), ifaces)
+		require.Nil(t, err)
+	}
+	ifaceErrs := captureManager.IfaceErrors()
+	require.Len(t, ifaceErrs, 1)
+	require.Equal(t, capturetypes.ErrorReasonPermissionDenied, ifaceErrs["eth0"].Reason)
+	require.Equal(t, capturetypes.ErrorClassInit, ifaceErrs["eth0"].Class)
+	require.Equal(t, 2, ifaceErrs["eth0"].Count)
+	require.Empty(t, captureManager.Status(context.Background()))
+
+	// the error state is cleared once the interface is removed from the configuration
+	_, _, _, err := captureManager.Update(context.Background(), config.Ifaces{"eth1": ifaceConfig})
+	require.Nil(t, err)
+	ifaceErrs = captureManager.IfaceErrors()
+	require.Len(t, ifaceErrs, 1)
+	require.Contains(t, ifaceErrs, "eth1")
+}
@@ -0,0 +1,100 @@
+// Package filter provides capture-side filtering of packets based on per-interface
+// allow / deny lists of IP prefixes (CIDRs). Prefixes are compiled into binary tries
+// in order to allow for fast evaluation in the capture hot path, i.e. before a packet
+// is added to the flow log.
+package filter
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+// Filter evaluates allow / deny lists of IP prefixes for both IPv4 and IPv6. It is
+// immutable once created and hence safe for concurrent use
+type Filter struct {
+	allowV4, allowV6 *trie
+	denyV4, denyV6   *trie
+
+	hasAllow bool
+}
+
+// New compiles a new Filter from lists of allowed and denied prefixes in CIDR notation
+// (e.g. 10.0.0.0/8 or 2001:db8::/32). Single IP addresses are treated as host prefixes
+func New(allow, deny []string) (*Filter, error) {
+	f := &Filter{
+		allowV4: newTrie(),
+		allowV6: newTrie(),
+		denyV4:  newTrie(),
+		denyV6:  newTrie(),
+	}
+
+	for _, cidr := range allow {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4() {
+			f.allowV4.insert(prefix)
+		} else {
+			f.allowV6.insert(prefix)
+		}
+	}
+	for _, cidr := range deny {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4() {
+			f.denyV4.insert(prefix)
+		} else {
+			f.denyV6.insert(prefix)
+		}
+	}
+	f.hasAllow = !f.allowV4.empty() || !f.allowV6.empty()
+
+	return f, nil
+}
+
+// ParsePrefix parses a prefix in CIDR notation (or a single IP address) and returns its
+// masked / canonical form
+func ParsePrefix(cidr string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		addr, addrErr := netip.ParseAddr(cidr)
+		if addrErr != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid prefix %q: %w", cidr, err)
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	// IPv4-mapped IPv6 addresses are treated as their IPv4 counterpart (which is
+	// also how they end up in the flow log)
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+
+	return prefix.Masked(), nil
+}
+
+// Permits returns if a packet with the given endpoints may be recorded. This is the case
+// if neither endpoint is covered by the deny list and, if an allow list is present, at
+// least one of the endpoints is covered by the allow list
+func (f *Filter) Permits(epHash capturetypes.EPHash, isIPv4 bool) bool {
+	if isIPv4 {
+		return f.permits(f.allowV4, f.denyV4, epHash[0:4], epHash[16:20])
+	}
+	return f.permits(f.allowV6, f.denyV6, epHash[0:16], epHash[16:32])
+}
+
+func (f *Filter) permits(allow, deny *trie, sip, dip []byte) bool {
+	if deny.contains(sip) || deny.contains(dip) {
+		return false
+	}
+	if !f.hasAllow {
+		return true
+	}
+
+	return allow.contains(sip) || allow.contains(dip)
+}
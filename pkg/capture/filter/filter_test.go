@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func newEPHash(sip, dip string) (epHash capturetypes.EPHash, isIPv4 bool) {
+	sipAddr, dipAddr := netip.MustParseAddr(sip), netip.MustParseAddr(dip)
+	if sipAddr.Is4() {
+		sipBytes, dipBytes := sipAddr.As4(), dipAddr.As4()
+		copy(epHash[0:4], sipBytes[:])
+		copy(epHash[16:20], dipBytes[:])
+		return epHash, true
+	}
+	sipBytes, dipBytes := sipAddr.As16(), dipAddr.As16()
+	copy(epHash[0:16], sipBytes[:])
+	copy(epHash[16:32], dipBytes[:])
+	return epHash, false
+}
+
+func TestFilter(t *testing.T) {
+	var tests = []struct {
+		name     string
+		allow    []string
+		deny     []string
+		sip, dip string
+		expected bool
+	}{
+		{"no lists", nil, nil, "10.0.0.1", "192.168.1.1", true},
+		{"deny source", nil, []string{"10.0.0.0/8"}, "10.0.0.1", "192.168.1.1", false},
+		{"deny destination", nil, []string{"192.168.0.0/16"}, "10.0.0.1", "192.168.1.1", false},
+		{"deny host", nil, []string{"192.168.1.1"}, "10.0.0.1", "192.168.1.1", false},
+		{"deny other", nil, []string{"172.16.0.0/12"}, "10.0.0.1", "192.168.1.1", true},
+		{"deny all", nil, []string{"0.0.0.0/0"}, "10.0.0.1", "192.168.1.1", false},
+		{"allow source", []string{"10.0.0.0/24"}, nil, "10.0.0.1", "192.168.1.1", true},
+		{"allow destination", []string{"192.168.1.0/24"}, nil, "10.0.0.1", "192.168.1.1", true},
+		{"allow other", []string{"172.16.0.0/12"}, nil, "10.0.0.1", "192.168.1.1", false},
+		{"allow other family", []string{"2001:db8::/32"}, nil, "10.0.0.1", "192.168.1.1", false},
+		{"deny precedence", []string{"10.0.0.0/8"}, []string{"10.0.0.0/24"}, "10.0.0.1", "192.168.1.1", false},
+		{"allow more specific", []string{"10.0.0.0/8", "10.1.0.0/16"}, nil, "10.1.2.3", "192.168.1.1", true},
+		{"allow less specific", []string{"10.1.0.0/16", "10.0.0.0/8"}, nil, "10.2.2.3", "192.168.1.1", true},
+		{"IPv6 deny", nil, []string{"2001:db8::/32"}, "2001:db8::1", "2001:db9::1", false},
+		{"IPv6 allow", []string{"2001:db9::/64"}, nil, "2001:db8::1", "2001:db9::1", true},
+		{"IPv6 allow other", []string{"2001:db9:1::/48"}, nil, "2001:db8::1", "2001:db9::1", false},
+		{"IPv4-mapped deny", nil, []string{"::ffff:10.0.0.0/104"}, "10.0.0.1", "192.168.1.1", false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			f, err := New(test.allow, test.deny)
+			require.Nil(t, err)
+
+			epHash, isIPv4 := newEPHash(test.sip, test.dip)
+			require.Equal(t, test.expected, f.Permits(epHash, isIPv4))
+
+			// the filter must not depend on the direction of the packet
+			require.Equal(t, test.expected, f.Permits(epHash.Reverse(), isIPv4))
+		})
+	}
+}
+
+func TestInvalidPrefix(t *testing.T) {
+	for _, cidr := range []string{"", "10.0.0.0/33", "10.0.0", "not an IP", "2001:db8::/129"} {
+		_, err := New([]string{cidr}, nil)
+		require.Error(t, err, cidr)
+		_, err = New(nil, []string{cidr})
+		require.Error(t, err, cidr)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	f, err := New([]string{"10.0.0.0/8", "172.16.0.0/12", "2001:db8::/32"}, []string{"10.0.1.0/24"})
+	require.Nil(b, err)
+
+	epHash, isIPv4 := newEPHash("192.168.1.1", "10.1.2.3")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = f.Permits(epHash, isIPv4)
+	}
+}
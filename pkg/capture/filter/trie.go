@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"net/netip"
+)
+
+// node denotes a single node of a (flattened) binary trie. Child nodes are referenced
+// by their index in the node slice of the trie, with zero denoting the absence of a
+// child (the root node can never be a child)
+type node struct {
+	children [2]uint32
+	terminal bool
+}
+
+// trie denotes a binary (radix 2) trie of IP prefixes, stored as a flat slice of nodes
+// in order to keep lookups in the capture hot path cache friendly and allocation free
+type trie struct {
+	nodes []node
+}
+
+func newTrie() *trie {
+	return &trie{
+		nodes: make([]node, 1),
+	}
+}
+
+// insert adds a prefix to the trie
+func (t *trie) insert(prefix netip.Prefix) {
+	addr := prefix.Addr().AsSlice()
+
+	cur := uint32(0)
+	for i := 0; i < prefix.Bits(); i++ {
+
+		// Any more specific prefix is already covered by a shorter one
+		if t.nodes[cur].terminal {
+			return
+		}
+
+		bit := (addr[i/8] >> (7 - i%8)) & 0x1
+		next := t.nodes[cur].children[bit]
+		if next == 0 {
+			t.nodes = append(t.nodes, node{})
+			next = uint32(len(t.nodes) - 1)
+			t.nodes[cur].children[bit] = next
+		}
+		cur = next
+	}
+
+	// Mark the node as terminal and drop all (now redundant) more specific prefixes
+	t.nodes[cur].terminal = true
+	t.nodes[cur].children = [2]uint32{}
+}
+
+// contains returns if the address (provided as raw bytes of length 4 or 16) is covered
+// by any of the prefixes in the trie
+func (t *trie) contains(addr []byte) bool {
+	cur := uint32(0)
+	for i := 0; i < len(addr)*8; i++ {
+		if t.nodes[cur].terminal {
+			return true
+		}
+		cur = t.nodes[cur].children[(addr[i/8]>>(7-i%8))&0x1]
+		if cur == 0 {
+			return false
+		}
+	}
+
+	return t.nodes[cur].terminal
+}
+
+// empty returns if the trie does not contain any prefixes
+func (t *trie) empty() bool {
+	return len(t.nodes) == 1 && !t.nodes[0].terminal
+}
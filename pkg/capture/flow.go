@@ -15,13 +15,16 @@ package capture
 import (
 	"fmt"
 	"io"
+	"maps"
 	"text/tabwriter"
 
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
+	"github.com/els0r/goProbe/pkg/capture/hostaddrs"
+	"github.com/els0r/goProbe/pkg/capture/tagging"
 	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 	"github.com/fako1024/slimcap/capture"
 	jsoniter "github.com/json-iterator/go"
 	"golang.org/x/net/ipv4"
@@ -34,14 +37,44 @@ const (
 )
 
 // FlowLog stores flows. It is NOT threadsafe.
+//
+// Flows are double-buffered: upon rotation, the active flow map is swapped with a pre-allocated
+// standby map and retired (see swap()). The retired flows are only ever read from that point on,
+// so they can be aggregated while new packets are added to the (now) active map. Flows continuing
+// across a rotation are carried over from the retired map as soon as they receive their next packet
 type FlowLog struct {
 	flowMap map[string]*Flow
+
+	// flows of the previous interval (read-only), consulted to carry over the direction of
+	// flows continuing across a rotation
+	retired map[string]*Flow
+
+	// empty (but pre-allocated) flow map swapped in upon the next rotation
+	standby map[string]*Flow
+
+	// standby map still holds the flows of an earlier rotation (i.e. it has not been cleared via
+	// recycle() yet), in which case it must not be swapped in
+	standbyStale bool
+
+	// tagging rules evaluated for each new flow (if any)
+	tagger *tagging.Tagger
+
+	// addresses of the capturing host used to classify the direction of flows involving it (if any)
+	hostAddrs *hostaddrs.Set
+
+	// flows of recently observed fragmented IPv6 packets (keyed by their fragment hash, c.f.
+	// ObserveFragment()), used to attribute non-first fragments to the flow of the first one
+	fragments map[string]capturetypes.EPHash
+
+	// limit of the number of concurrent flows, beyond which flows are collapsed (if configured)
+	emergency *emergencyMode
 }
 
 // NewFlowLog creates a new flow log for storing flows.
 func NewFlowLog() *FlowLog {
 	return &FlowLog{
 		flowMap: make(map[string]*Flow),
+		standby: make(map[string]*Flow),
 	}
 }
 
@@ -123,6 +156,7 @@ func ParsePacket(ipLayer capture.IPLayer) (epHash capturetypes.EPHash, isIPv4 bo
 			}
 		} else if protocol == capturetypes.ICMP {
 			auxInfo = ipLayer[ipv4.HeaderLen] // store ICMP type
+			setICMPTypeCode(&epHash, protocol, auxInfo, ipLayer[ipv4.HeaderLen+1])
 		}
 	} else if ipLayerType == ipLayerTypeV6 {
 
@@ -134,10 +168,31 @@ func ParsePacket(ipLayer capture.IPLayer) (epHash capturetypes.EPHash, isIPv4 bo
 		copy(epHash[0:16], ipLayer[8:24])
 		copy(epHash[16:32], ipLayer[24:40])
 
+		// Traverse any extension headers in order to find the actual transport layer
+		offset := ipv6.HeaderLen
+		if isIPv6ExtHeader(protocol) {
+			if protocol, offset, _, errno = walkIPv6ExtHeaders(ipLayer); errno != capturetypes.ErrnoOK {
+
+				// Non-first fragments lack a transport layer, so they are identified by the
+				// identification of the fragmented packet instead (stored in lieu of the ports)
+				// in order to be attributed to the flow of its first fragment
+				if errno == capturetypes.ErrnoPacketFragment {
+					copy(epHash[32:36], ipLayer[offset+4:offset+8])
+					epHash[36] = protocol
+				}
+				return
+			}
+
+			if (protocol == capturetypes.TCP || protocol == capturetypes.UDP || protocol == capturetypes.ICMPv6) && len(ipLayer) < offset+4 {
+				errno = capturetypes.ErrnoPacketTruncated
+				return
+			}
+		}
+
 		if protocol == capturetypes.TCP || protocol == capturetypes.UDP {
 
-			dport := ipLayer[ipv6.HeaderLen+2 : ipv6.HeaderLen+4]
-			sport := ipLayer[ipv6.HeaderLen : ipv6.HeaderLen+2]
+			dport := ipLayer[offset+2 : offset+4]
+			sport := ipLayer[offset : offset+2]
 
 			// If session based traffic is observed, the source port is taken
 			// into account. A major exception is traffic over port 53 as
@@ -151,14 +206,15 @@ func ParsePacket(ipLayer capture.IPLayer) (epHash capturetypes.EPHash, isIPv4 bo
 			}
 
 			if protocol == capturetypes.TCP {
-				if len(ipLayer) < ipv6.HeaderLen+13 {
+				if len(ipLayer) < offset+13 {
 					errno = capturetypes.ErrnoPacketTruncated
 					return
 				}
-				auxInfo = ipLayer[ipv6.HeaderLen+13] // store TCP flags
+				auxInfo = ipLayer[offset+13] // store TCP flags
 			}
 		} else if protocol == capturetypes.ICMPv6 {
-			auxInfo = ipLayer[ipv6.HeaderLen] // store ICMP type
+			auxInfo = ipLayer[offset] // store ICMP type
+			setICMPTypeCode(&epHash, protocol, auxInfo, ipLayer[offset+1])
 		}
 	} else {
 		errno = capturetypes.ErrnoInvalidIPHeader
@@ -171,42 +227,255 @@ func ParsePacket(ipLayer capture.IPLayer) (epHash capturetypes.EPHash, isIPv4 bo
 	return
 }
 
+// ParseTTL extracts the TTL (IPv4) / hop limit (IPv6) from the IP layer of a packet. It must only
+// be called for packets successfully parsed by ParsePacket()
+func ParseTTL(ipLayer capture.IPLayer, isIPv4 bool) byte {
+	if isIPv4 {
+		return ipLayer[8]
+	}
+	return ipLayer[7]
+}
+
+// setICMPTypeCode stores the (grouped) type and code of an ICMP message in the port fields of
+// the EPHash, such that ICMP traffic is aggregated by message type (e.g. echo, destination
+// unreachable) and the endpoints involved rather than merging all messages into a single
+// port-zero pseudo-flow. Both port fields are set in order for a reply to match the (reverse)
+// flow of its request
+func setICMPTypeCode(epHash *capturetypes.EPHash, protocol, icmpType, icmpCode byte) {
+	epHash[32], epHash[33] = capturetypes.ICMPGroupType(protocol, icmpType), icmpCode
+	epHash[34], epHash[35] = epHash[32], epHash[33]
+}
+
 // Add a packet to the flow log. If the packet belongs to a flow
 // already present in the log, the flow will be updated. Otherwise,
 // a new flow will be created.
+//
+// Non-first fragments of fragmented IPv6 packets are attributed to the flow of
+// their first fragment (if observed, c.f. ObserveFragment()). Packet fragments that
+// cannot be attributed to any flow are ignored (ErrnoPacketFragmentIgnore)
 func (f *FlowLog) Add(epHash capturetypes.EPHash, pktType byte, pktSize uint32, isIPv4 bool, auxInfo byte, errno capturetypes.ParsingErrno) capturetypes.ParsingErrno {
 
 	if errno > capturetypes.ErrnoOK {
-		if errno.ParsingFailed() {
-			return errno
+		if errno == capturetypes.ErrnoPacketFragment {
+			return f.addFragment(epHash, pktType, pktSize)
 		}
-		return capturetypes.ErrnoOK
+		return errno
+	}
+
+	// collapse the flow if the number of concurrent flows is limited (and the limit has been reached)
+	if f.emergency != nil {
+		epHash = f.emergency.limit(epHash, isIPv4, len(f.flowMap))
 	}
 
 	// update or assign the flow
 	if flowToUpdate, existsHash := f.flowMap[string(epHash[:])]; existsHash {
-		flowToUpdate.UpdateFlow(epHash, auxInfo, pktType, pktSize)
+		flowToUpdate.update(epHash, auxInfo, pktType, pktSize, f.hostAddrs)
 	} else {
 		epHashReverse := epHash.Reverse()
 		if flowToUpdate, existsReverseHash := f.flowMap[string(epHashReverse[:])]; existsReverseHash {
-			flowToUpdate.UpdateFlow(epHashReverse, auxInfo, pktType, pktSize)
+			flowToUpdate.update(epHashReverse, auxInfo, pktType, pktSize, f.hostAddrs)
+		} else if flowToUpdate, existsHash := f.carryOver(epHash); existsHash {
+			flowToUpdate.update(epHash, auxInfo, pktType, pktSize, f.hostAddrs)
+		} else if flowToUpdate, existsReverseHash := f.carryOver(epHashReverse); existsReverseHash {
+			flowToUpdate.update(epHashReverse, auxInfo, pktType, pktSize, f.hostAddrs)
 		} else {
-			f.flowMap[string(epHash[:])] = NewFlow(epHash, isIPv4, auxInfo, pktType, pktSize)
+			flow := newFlow(epHash, isIPv4, auxInfo, pktType, pktSize, f.hostAddrs)
+			flow.tag = f.tagger.Tag(epHash, isIPv4)
+			f.flowMap[string(epHash[:])] = flow
 		}
 	}
 
 	return capturetypes.ErrnoOK
 }
 
-// Rotate rotates the flow log. All flows are reset to no packets and traffic.
-// Moreover, any flows not worth keeping (according to Flow.IsWorthKeeping)
+// ObserveHandshake records the timestamp (unix nanoseconds) of a TCP handshake packet (SYN / SYN-ACK)
+// for the flow it belongs to in order to estimate the handshake round trip time. The packet is
+// expected to have been added to the flow log already
+func (f *FlowLog) ObserveHandshake(epHash capturetypes.EPHash, tcpFlags byte, timestamp int64) {
+
+	flow, exists := f.flowMap[string(epHash[:])]
+	if !exists {
+		epHashReverse := epHash.Reverse()
+		if flow, exists = f.flowMap[string(epHashReverse[:])]; !exists {
+			return
+		}
+	}
+
+	// Only allocate the tracker once a handshake is initiated (a SYN-ACK without preceding
+	// SYN cannot be used for an estimate anyway)
+	if flow.handshake == nil {
+		if !capturetypes.IsSYN(tcpFlags) {
+			return
+		}
+		flow.handshake = new(handshakeTracker)
+	}
+	flow.handshake.observe(tcpFlags, timestamp)
+}
+
+// ObserveTTL records the TTL (IPv4) / hop limit (IPv6) of a packet for the flow it belongs to, extending
+// the range of TTLs observed for the flow. The packet is expected to have been added to the flow log already
+func (f *FlowLog) ObserveTTL(epHash capturetypes.EPHash, ttl byte) {
+
+	flow, exists := f.flowMap[string(epHash[:])]
+	if !exists {
+		epHashReverse := epHash.Reverse()
+		if flow, exists = f.flowMap[string(epHashReverse[:])]; !exists {
+			return
+		}
+	}
+	flow.observeTTL(ttl)
+}
+
+// ObserveFragment records the identification of a fragmented IPv6 packet (as extracted from its first
+// fragment via ParseIPv6FragmentID()) for the flow it belongs to, such that subsequent fragments of the
+// packet are attributed to the same flow. The packet is expected to have been added to the flow log already
+func (f *FlowLog) ObserveFragment(epHash capturetypes.EPHash, fragID [ipv6FragmentIDLen]byte) {
+
+	flowHash := epHash
+	if _, exists := f.flowMap[string(flowHash[:])]; !exists {
+		flowHash = epHash.Reverse()
+		if _, exists = f.flowMap[string(flowHash[:])]; !exists {
+			return
+		}
+	}
+
+	// Fragments of a packet arrive in quick succession, so the table does not have to retain entries
+	// for long: instead of tracking their age, it is simply reset once it reaches its maximum size
+	if f.fragments == nil {
+		f.fragments = make(map[string]capturetypes.EPHash)
+	} else if len(f.fragments) >= maxIPv6Fragments {
+		clear(f.fragments)
+	}
+
+	fragHash := epHash
+	copy(fragHash[32:36], fragID[:])
+	f.fragments[string(fragHash[:])] = flowHash
+}
+
+// addFragment attributes a non-first fragment of a fragmented IPv6 packet (identified by its fragment
+// hash as determined by ParsePacket()) to the flow of its first fragment
+func (f *FlowLog) addFragment(fragHash capturetypes.EPHash, pktType byte, pktSize uint32) capturetypes.ParsingErrno {
+
+	epHash, exists := f.fragments[string(fragHash[:])]
+	if !exists {
+		return capturetypes.ErrnoPacketFragmentIgnore
+	}
+
+	// The flow may have been retired since its first fragment was observed (in which case it is
+	// continued, if possible)
+	flow, exists := f.flowMap[string(epHash[:])]
+	if !exists {
+		if flow, exists = f.carryOver(epHash); !exists {
+			return capturetypes.ErrnoPacketFragmentIgnore
+		}
+	}
+	flow.addPacket(pktType, pktSize)
+
+	return capturetypes.ErrnoOK
+}
+
+// AddSummary adds a flow summary (i.e. the counters of a flow aggregated outside of the
+// flow log, e.g. in-kernel by the eBPF capture driver) to the flow log. If the summary
+// belongs to a flow already present in the log, the flow will be updated. Otherwise, a
+// new flow will be created.
+func (f *FlowLog) AddSummary(summary capturetypes.FlowSummary) {
+
+	// collapse the flow if the number of concurrent flows is limited (and the limit has been reached)
+	if f.emergency != nil {
+		summary.EPHash = f.emergency.limit(summary.EPHash, summary.IsIPv4, len(f.flowMap))
+	}
+
+	// update or assign the flow
+	if flowToUpdate, existsHash := f.flowMap[string(summary.EPHash[:])]; existsHash {
+		flowToUpdate.updateFromSummary(summary.EPHash, summary, f.hostAddrs)
+	} else {
+		epHashReverse := summary.EPHash.Reverse()
+		if flowToUpdate, existsReverseHash := f.flowMap[string(epHashReverse[:])]; existsReverseHash {
+			flowToUpdate.updateFromSummary(epHashReverse, summary, f.hostAddrs)
+		} else if flowToUpdate, existsHash := f.carryOver(summary.EPHash); existsHash {
+			flowToUpdate.updateFromSummary(summary.EPHash, summary, f.hostAddrs)
+		} else if flowToUpdate, existsReverseHash := f.carryOver(epHashReverse); existsReverseHash {
+			flowToUpdate.updateFromSummary(epHashReverse, summary, f.hostAddrs)
+		} else {
+			res := Flow{
+				epHash: summary.EPHash,
+				isIPv4: summary.IsIPv4,
+				tag:    f.tagger.Tag(summary.EPHash, summary.IsIPv4),
+			}
+			res.updateDirection(summary.EPHash, summary.AuxInfo, f.hostAddrs)
+			res.addCounters(summary)
+			f.flowMap[string(summary.EPHash[:])] = &res
+		}
+	}
+}
+
+// Rotate rotates the flow log. All flows are retired (and reset for the next interval), flows
+// that cannot be continued (i.e. those whose direction could not be determined with confidence)
 // are discarded.
 //
-// Returns an AggFlowMap containing all flows since the last call to Rotate.
-func (f *FlowLog) Rotate() (agg *hashmap.AggFlowMap, totals *types.Counters) {
+// Returns an AggFlowMap containing all flows since the last call to Rotate, alongside
+// the TCP handshake round trip times observed in the meantime (if any).
+func (f *FlowLog) Rotate() (agg *hashmap.AggFlowMap, totals *types.Counters, rtt *capturetypes.HandshakeRTT) {
 	return f.transferAndAggregate()
 }
 
+// carryOver continues a flow of the previous interval (if present in the retired flow map and if
+// its direction was determined with high confidence) by adding it to the active flow map with reset
+// counters (and an empty range of observed TTLs), retaining its direction, tag and any pending
+// TCP handshake
+func (f *FlowLog) carryOver(epHash capturetypes.EPHash) (*Flow, bool) {
+	retiredFlow, exists := f.retired[string(epHash[:])]
+	if !exists || !retiredFlow.directionConfidenceHigh {
+		return nil, false
+	}
+	if retiredFlow.packetsRcvd == 0 && retiredFlow.packetsSent == 0 {
+		return nil, false
+	}
+
+	// The retired flow must not be modified (it may be aggregated concurrently), so a
+	// reset copy is added instead
+	flow := &Flow{
+		epHash:                  retiredFlow.epHash,
+		directionConfidenceHigh: true,
+		isIPv4:                  retiredFlow.isIPv4,
+		tag:                     retiredFlow.tag,
+	}
+	if retiredFlow.handshake != nil {
+		flow.handshake = retiredFlow.handshake.clone()
+		flow.handshake.reset()
+	}
+	f.flowMap[string(epHash[:])] = flow
+
+	return flow, true
+}
+
+// swap retires the active flow map and swaps in the standby one in constant time, making it the
+// only part of a rotation that requires exclusive access to the flow log. The flows retired during
+// the previous call are discarded. The returned flows must not be modified and can be aggregated
+// (using aggregateFlows()) concurrently to new packets being added to the flow log
+func (f *FlowLog) swap() (retired map[string]*Flow) {
+	if f.standby == nil || f.standbyStale {
+		f.standby = make(map[string]*Flow, len(f.flowMap))
+	}
+	f.flowMap, f.retired, f.standby = f.standby, f.flowMap, f.retired
+	f.standbyStale = f.standby != nil
+
+	return f.retired
+}
+
+// recycle clears the standby flow map (i.e. the flows discarded during the last call to swap())
+// for reuse upon the next rotation, allocating it if required. Clearing the map retains its allocated
+// memory, so it does not have to grow again while being populated. It must not be called concurrently
+// to swap()
+func (f *FlowLog) recycle() {
+	f.standbyStale = false
+	if f.standby == nil {
+		f.standby = make(map[string]*Flow, len(f.retired))
+		return
+	}
+	clear(f.standby)
+}
+
 // Aggregate extracts an AggFlowMap from the currently active flowMap. The flowMap
 // itself is not modified in the process.
 //
@@ -225,9 +494,15 @@ func (f *FlowLog) Aggregate() (agg *hashmap.AggFlowMap) {
 			// Populate key buffer according to source flow
 			if v.isIPv4 {
 				keyBufV4.PutAllV4(v.epHash[0:4], v.epHash[16:20], v.epHash[32:34], v.epHash[36])
+				keyBufV4.PutTag(v.tag)
+				keyBufV4.PutTTLMin(v.ttlMin)
+				keyBufV4.PutTTLMax(v.ttlMax)
 				agg.SetOrUpdate(keyBufV4, v.isIPv4, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
 			} else {
 				keyBufV6.PutAllV6(v.epHash[0:16], v.epHash[16:32], v.epHash[32:34], v.epHash[36])
+				keyBufV6.PutTag(v.tag)
+				keyBufV6.PutTTLMin(v.ttlMin)
+				keyBufV6.PutTTLMax(v.ttlMax)
 				agg.SetOrUpdate(keyBufV6, v.isIPv4, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
 			}
 		}
@@ -236,7 +511,16 @@ func (f *FlowLog) Aggregate() (agg *hashmap.AggFlowMap) {
 	return
 }
 
-func (f *FlowLog) transferAndAggregate() (agg *hashmap.AggFlowMap, totals *types.Counters) {
+func (f *FlowLog) transferAndAggregate() (agg *hashmap.AggFlowMap, totals *types.Counters, rtt *capturetypes.HandshakeRTT) {
+	agg, totals, rtt = aggregateFlows(f.swap())
+	f.recycle()
+
+	return
+}
+
+// aggregateFlows extracts an AggFlowMap from a (retired) flow map, alongside the totals and the
+// TCP handshake round trip times across all flows. The flows themselves are not modified
+func aggregateFlows(flows map[string]*Flow) (agg *hashmap.AggFlowMap, totals *types.Counters, rtt *capturetypes.HandshakeRTT) {
 
 	// Initialize aggregate flow map / result
 	agg = hashmap.NewAggFlowMap()
@@ -244,50 +528,58 @@ func (f *FlowLog) transferAndAggregate() (agg *hashmap.AggFlowMap, totals *types
 	// for recomputing the most up to date running sum of bytes and packets
 	totals = new(types.Counters)
 
+	// for summarizing the handshake round trip times across all flows
+	var rtts rttSamples
+
 	// Create reusable key conversion buffers
 	keyBufV4, keyBufV6 := types.NewEmptyV4Key(), types.NewEmptyV6Key()
 
-	for k, v := range f.flowMap {
-
-		// Check if the flow actually has any interesting information for us, otherwise
-		// delete it from the FlowMap
-		if v.packetsRcvd > 0 || v.packetsSent > 0 {
-			// update totals
-			totals.BytesRcvd += v.bytesRcvd
-			totals.BytesSent += v.bytesSent
-			totals.PacketsRcvd += v.packetsRcvd
-			totals.PacketsSent += v.packetsSent
+	for _, v := range flows {
 
-			// Populate key buffer according to source flow and update result
-			if v.isIPv4 {
-				keyBufV4.PutAllV4(v.epHash[0:4], v.epHash[16:20], v.epHash[32:34], v.epHash[36])
-				agg.SetOrUpdate(keyBufV4, true, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
-			} else {
-				keyBufV6.PutAllV6(v.epHash[0:16], v.epHash[16:32], v.epHash[32:34], v.epHash[36])
-				agg.SetOrUpdate(keyBufV6, false, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
-			}
-
-			// Check whether the flow should be retained / reset for the next interval
-			// or thrown away
-			if v.directionConfidenceHigh {
+		// Check if the flow actually has any interesting information for us
+		if v.packetsRcvd == 0 && v.packetsSent == 0 {
+			continue
+		}
 
-				// Reset the flow
-				v.Reset()
-			} else {
-				delete(f.flowMap, k)
-			}
+		// update totals
+		totals.BytesRcvd += v.bytesRcvd
+		totals.BytesSent += v.bytesSent
+		totals.PacketsRcvd += v.packetsRcvd
+		totals.PacketsSent += v.packetsSent
+
+		// Populate key buffer according to source flow and update result
+		if v.isIPv4 {
+			keyBufV4.PutAllV4(v.epHash[0:4], v.epHash[16:20], v.epHash[32:34], v.epHash[36])
+			keyBufV4.PutTag(v.tag)
+			keyBufV4.PutTTLMin(v.ttlMin)
+			keyBufV4.PutTTLMax(v.ttlMax)
+			agg.SetOrUpdate(keyBufV4, true, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
 		} else {
-			delete(f.flowMap, k)
+			keyBufV6.PutAllV6(v.epHash[0:16], v.epHash[16:32], v.epHash[32:34], v.epHash[36])
+			keyBufV6.PutTag(v.tag)
+			keyBufV6.PutTTLMin(v.ttlMin)
+			keyBufV6.PutTTLMax(v.ttlMax)
+			agg.SetOrUpdate(keyBufV6, false, v.bytesRcvd, v.bytesSent, v.packetsRcvd, v.packetsSent)
+		}
+		if v.handshake != nil {
+			rtts.merge(&v.handshake.rttSamples, maxRotationHandshakeSamples)
 		}
 	}
+	rtt = rtts.summary()
 
 	return
 }
 
 func (f *FlowLog) clone() (f2 *FlowLog) {
 	f2 = NewFlowLog()
+	f2.tagger = f.tagger
+	f2.hostAddrs = f.hostAddrs
+	f2.fragments = maps.Clone(f.fragments)
 	for k, v := range f.flowMap {
 		vCopy := *v
+		if v.handshake != nil {
+			vCopy.handshake = v.handshake.clone()
+		}
 		f2.flowMap[k] = &vCopy
 	}
 	return
@@ -304,6 +596,15 @@ type Flow struct {
 	packetsSent             uint64
 	directionConfidenceHigh bool
 	isIPv4                  bool
+
+	// tag assigned upon creation of the flow (if any)
+	tag byte
+
+	// range of TTLs / hop limits observed since the last reset (zero if not recorded)
+	ttlMin, ttlMax byte
+
+	// TCP handshake tracking (only allocated once a SYN has been observed)
+	handshake *handshakeTracker
 }
 
 // MarshalJSON implements the Marshaler interface for a flow
@@ -313,12 +614,16 @@ func (f *Flow) MarshalJSON() ([]byte, error) {
 
 // NewFlow creates a new flow based on the packet
 func NewFlow(epHash capturetypes.EPHash, isIPv4 bool, auxInfo byte, pktType capture.PacketType, pktTotalLen uint32) *Flow {
+	return newFlow(epHash, isIPv4, auxInfo, pktType, pktTotalLen, nil)
+}
+
+func newFlow(epHash capturetypes.EPHash, isIPv4 bool, auxInfo byte, pktType capture.PacketType, pktTotalLen uint32, hostAddrs *hostaddrs.Set) *Flow {
 
 	res := Flow{
 		epHash: epHash,
 		isIPv4: isIPv4,
 	}
-	res.updateDirection(epHash, auxInfo)
+	res.updateDirection(epHash, auxInfo, hostAddrs)
 
 	// set packet and byte counters with respect to its interface direction
 	if pktType != capture.PacketOutgoing {
@@ -334,8 +639,20 @@ func NewFlow(epHash capturetypes.EPHash, isIPv4 bool, auxInfo byte, pktType capt
 
 // UpdateFlow increments flow counters if the packet belongs to an existing flow
 func (f *Flow) UpdateFlow(epHash capturetypes.EPHash, auxInfo byte, pktType capture.PacketType, pktTotalLen uint32) {
+	f.update(epHash, auxInfo, pktType, pktTotalLen, nil)
+}
 
-	// increment packet and byte counters with respect to its interface direction
+func (f *Flow) update(epHash capturetypes.EPHash, auxInfo byte, pktType capture.PacketType, pktTotalLen uint32, hostAddrs *hostaddrs.Set) {
+	f.addPacket(pktType, pktTotalLen)
+
+	// try to update direction if necessary (as long as we're not confident enough)
+	if !f.directionConfidenceHigh {
+		f.updateDirection(epHash, auxInfo, hostAddrs)
+	}
+}
+
+// addPacket increments the packet and byte counters with respect to the interface direction of a packet
+func (f *Flow) addPacket(pktType capture.PacketType, pktTotalLen uint32) {
 	if pktType != capture.PacketOutgoing {
 		f.bytesRcvd += uint64(pktTotalLen)
 		f.packetsRcvd++
@@ -343,19 +660,61 @@ func (f *Flow) UpdateFlow(epHash capturetypes.EPHash, auxInfo byte, pktType capt
 		f.bytesSent += uint64(pktTotalLen)
 		f.packetsSent++
 	}
+}
+
+func (f *Flow) updateFromSummary(epHash capturetypes.EPHash, summary capturetypes.FlowSummary, hostAddrs *hostaddrs.Set) {
+	f.addCounters(summary)
 
 	// try to update direction if necessary (as long as we're not confident enough)
 	if !f.directionConfidenceHigh {
-		f.updateDirection(epHash, auxInfo)
+		f.updateDirection(epHash, summary.AuxInfo, hostAddrs)
 	}
 }
 
-// Reset resets all flow counters
+func (f *Flow) addCounters(summary capturetypes.FlowSummary) {
+	f.bytesRcvd += summary.BytesRcvd
+	f.bytesSent += summary.BytesSent
+	f.packetsRcvd += summary.PacketsRcvd
+	f.packetsSent += summary.PacketsSent
+}
+
+// observeTTL extends the range of TTLs / hop limits observed for the flow (a zero TTL is
+// ignored since it denotes that no TTL was recorded)
+func (f *Flow) observeTTL(ttl byte) {
+	if ttl == 0 {
+		return
+	}
+	if f.ttlMin == 0 || ttl < f.ttlMin {
+		f.ttlMin = ttl
+	}
+	if ttl > f.ttlMax {
+		f.ttlMax = ttl
+	}
+}
+
+// Reset resets all flow counters (and the range of observed TTLs)
 func (f *Flow) Reset() {
 	f.bytesRcvd = 0
 	f.bytesSent = 0
 	f.packetsRcvd = 0
 	f.packetsSent = 0
+	f.ttlMin = 0
+	f.ttlMax = 0
+
+	// Discard the handshake round trip times, but retain any pending SYN (since a handshake
+	// may well span a rotation)
+	if f.handshake != nil {
+		f.handshake.reset()
+	}
+}
+
+// HandshakeRTT returns the summary of the TCP handshake round trip times observed for the flow
+// since the last reset (or nil if there are none)
+func (f *Flow) HandshakeRTT() *capturetypes.HandshakeRTT {
+	if f.handshake == nil {
+		return nil
+	}
+	return f.handshake.summary()
 }
 
 // FlowInfo summarizes information about a given flow
@@ -363,6 +722,8 @@ type FlowInfo struct {
 	Idle                    bool                `json:"idle"`
 	DirectionConfidenceHigh bool                `json:"direction_confidence_high"`
 	Flow                    results.ExtendedRow `json:"flow"`
+
+	HandshakeRTT *capturetypes.HandshakeRTT `json:"handshake_rtt,omitempty"`
 }
 
 // FlowInfos is a list of FlowInfo objects
@@ -403,7 +764,7 @@ func (fs FlowInfos) TablePrint(w io.Writer) error {
 			fi.Flow.Attributes.SrcPort,
 			fi.Flow.Attributes.DstIP,
 			fi.Flow.Attributes.DstPort,
-			protocols.GetIPProto(int(fi.Flow.Attributes.IPProto)),
+			protocols.Format(fi.Flow.Attributes.IPProto, false),
 			fi.Flow.Counters.BytesRcvd,
 			fi.Flow.Counters.BytesSent,
 			fi.Flow.Counters.PacketsRcvd,
@@ -413,8 +774,15 @@ func (fs FlowInfos) TablePrint(w io.Writer) error {
 	return tw.Flush()
 }
 
-func (f *Flow) updateDirection(epHash capturetypes.EPHash, auxInfo byte) {
-	if direction := capturetypes.ClassifyPacketDirection(epHash, f.isIPv4, auxInfo); direction != capturetypes.DirectionUnknown {
+func (f *Flow) updateDirection(epHash capturetypes.EPHash, auxInfo byte, hostAddrs *hostaddrs.Set) {
+
+	// flows involving the capturing host itself can be classified definitively, overriding
+	// the default heuristics
+	direction := hostAddrs.Classify(epHash, f.isIPv4, auxInfo)
+	if direction == capturetypes.DirectionUnknown {
+		direction = capturetypes.ClassifyPacketDirection(epHash, f.isIPv4, auxInfo)
+	}
+	if direction != capturetypes.DirectionUnknown {
 		f.directionConfidenceHigh = direction.IsConfidenceHigh()
 
 		// switch fields if direction was opposite to the default direction
@@ -434,6 +802,9 @@ func (f *Flow) toExtendedRow() results.ExtendedRow {
 				DstIP:   types.RawIPToAddr(f.epHash[16:32]),
 				DstPort: types.PortToUint16(f.epHash[32:34]),
 				IPProto: f.epHash[36],
+				Tag:     types.TagNameByID(f.tag),
+				TTLMin:  f.ttlMin,
+				TTLMax:  f.ttlMax,
 			},
 		},
 		Counters: types.Counters{
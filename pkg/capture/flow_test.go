@@ -0,0 +1,98 @@
+package capture
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowLogCarryOver(t *testing.T) {
+	client, server := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	confident := testTCPEPHash(client, server, 34567, 8080)
+	unconfident := testTCPEPHash(client, server, 34568, 8081)
+
+	flowLog := NewFlowLog()
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(confident, capture.PacketOutgoing, 64, true, testFlagsSYN, capturetypes.ErrnoOK))
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(unconfident, capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK))
+	flowLog.flowMap[string(unconfident[:])].directionConfidenceHigh = false
+
+	retired := flowLog.swap()
+	require.Len(t, retired, 2)
+	require.Zero(t, flowLog.Len())
+
+	// packets of flows continuing across the rotation are added to the active flow map without
+	// modifying the retired flows (which may be aggregated concurrently)
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(confident.Reverse(), capture.PacketThisHost, 128, true, testFlagsACK, capturetypes.ErrnoOK))
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(unconfident.Reverse(), capture.PacketThisHost, 128, true, testFlagsACK, capturetypes.ErrnoOK))
+	require.Equal(t, 2, flowLog.Len())
+
+	carried, exists := flowLog.flowMap[string(confident[:])]
+	require.True(t, exists, "flow with high direction confidence should have been carried over")
+	require.Equal(t, retired[string(confident[:])].epHash, carried.epHash)
+	require.True(t, carried.directionConfidenceHigh)
+	require.Equal(t, uint64(1), carried.packetsRcvd)
+	require.Zero(t, carried.packetsSent)
+
+	_, exists = flowLog.flowMap[string(unconfident[:])]
+	require.False(t, exists, "flow with low direction confidence should not have been carried over")
+
+	agg, totals, _ := aggregateFlows(retired)
+	require.Equal(t, 2, agg.Len())
+	require.Equal(t, uint64(2), totals.PacketsSent)
+	require.Zero(t, totals.PacketsRcvd)
+
+	// upon the next rotation, the previously retired flows are discarded and their map is recycled
+	flowLog.recycle()
+	require.Empty(t, flowLog.standby)
+	retired = flowLog.swap()
+	require.Len(t, retired, 2)
+	require.Zero(t, flowLog.Len())
+
+	agg, totals, _ = aggregateFlows(retired)
+	require.Equal(t, 2, agg.Len())
+	require.Equal(t, uint64(2), totals.PacketsRcvd)
+
+	// rotating again without recycling the retired flows never swaps them back in
+	require.Empty(t, flowLog.swap())
+	require.Zero(t, flowLog.Len())
+}
+
+func TestFlowLogTTL(t *testing.T) {
+	client, server := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	epHash := testTCPEPHash(client, server, 34567, 8080)
+
+	ipLayerV4 := make(capture.IPLayer, 20)
+	ipLayerV4[0], ipLayerV4[8] = 0x45, 58
+	require.Equal(t, byte(58), ParseTTL(ipLayerV4, true))
+	ipLayerV6 := make(capture.IPLayer, 40)
+	ipLayerV6[0], ipLayerV6[7] = 0x60, 255
+	require.Equal(t, byte(255), ParseTTL(ipLayerV6, false))
+
+	flowLog := NewFlowLog()
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(epHash, capture.PacketOutgoing, 64, true, testFlagsSYN, capturetypes.ErrnoOK))
+	flowLog.ObserveTTL(epHash, 64)
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(epHash.Reverse(), capture.PacketThisHost, 64, true, testFlagsACK, capturetypes.ErrnoOK))
+	flowLog.ObserveTTL(epHash.Reverse(), 58)
+	flowLog.ObserveTTL(epHash.Reverse(), 0)
+
+	flow := flowLog.flowMap[string(epHash[:])]
+	require.Equal(t, byte(58), flow.ttlMin)
+	require.Equal(t, byte(64), flow.ttlMax)
+
+	agg, _, _ := flowLog.Rotate()
+	require.Equal(t, 1, agg.Len())
+	for i := agg.Iter(); i.Next(); {
+		require.Equal(t, byte(58), types.Key(i.Key()).GetTTLMin())
+		require.Equal(t, byte(64), types.Key(i.Key()).GetTTLMax())
+	}
+
+	// the range of observed TTLs starts afresh for flows continuing across the rotation
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(epHash, capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK))
+	flow = flowLog.flowMap[string(epHash[:])]
+	require.Zero(t, flow.ttlMin)
+	require.Zero(t, flow.ttlMax)
+}
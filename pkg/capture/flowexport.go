@@ -0,0 +1,171 @@
+package capture
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/netflow"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/els0r/telemetry/logging"
+)
+
+// flowExportQueueSize denotes the number of rotations that may be pending export. Since rotations
+// occur once per writeout interval, the queue is only ever filled if the collector is unreachable for
+// an extended period of time (in which case further rotations are dropped instead of piling up)
+const flowExportQueueSize = 64
+
+// flowExportJob denotes the flows of a rotation of an interface pending export
+type flowExportJob struct {
+	iface     string
+	timestamp time.Time
+	flows     *hashmap.AggFlowMap
+}
+
+// flowExporter exports the flows of each rotation of all interfaces enabling it as NetFlow v9 / IPFIX
+// records. Export happens asynchronously (the rotated flow maps are not modified after the rotation),
+// hence a slow / unreachable collector never delays rotations or writeouts
+type flowExporter struct {
+	cfg      *config.FlowExportConfig
+	exporter *netflow.Exporter
+	queue    chan flowExportJob
+
+	// end of the most recently exported interval of each interface
+	lastExport map[string]time.Time
+}
+
+func newFlowExporter(cfg *config.FlowExportConfig) *flowExporter {
+	return &flowExporter{
+		cfg:        cfg,
+		queue:      make(chan flowExportJob, flowExportQueueSize),
+		lastExport: make(map[string]time.Time),
+	}
+}
+
+// WithFlowExport enables the export of the flows of each rotation (of all interfaces enabling it) as
+// NetFlow v9 / IPFIX records to the configured collector
+func WithFlowExport(cfg *config.FlowExportConfig) ManagerOption {
+	return func(cm *Manager) {
+		cm.flowExport = newFlowExporter(cfg)
+	}
+}
+
+// open initializes the exporter (the connection to the collector is established upon the first export)
+func (f *flowExporter) open() (err error) {
+	opts := []netflow.Option{
+		netflow.WithObservationDomain(f.cfg.ObservationDomain),
+	}
+	if f.cfg.Protocol != "" {
+		opts = append(opts, netflow.WithProtocol(netflow.Protocol(f.cfg.Protocol)))
+	}
+	if f.cfg.Transport != "" {
+		opts = append(opts, netflow.WithTransport(netflow.Transport(f.cfg.Transport)))
+	}
+	if f.cfg.MaxMessageSize != 0 {
+		opts = append(opts, netflow.WithMaxMessageSize(f.cfg.MaxMessageSize))
+	}
+	if f.cfg.TemplateRefresh != 0 {
+		opts = append(opts, netflow.WithTemplateRefresh(f.cfg.TemplateRefresh))
+	}
+
+	f.exporter, err = netflow.NewExporter(f.cfg.Target, opts...)
+	return err
+}
+
+// enqueue schedules the flows of a rotation for export, dropping them if the queue is full
+func (f *flowExporter) enqueue(ctx context.Context, iface string, timestamp time.Time, flows *hashmap.AggFlowMap) {
+	select {
+	case f.queue <- flowExportJob{iface: iface, timestamp: timestamp, flows: flows}:
+	default:
+		promFlowExports.WithLabelValues(iface, "dropped").Inc()
+		logging.FromContext(ctx).Warn("flow export queue full, dropping rotation")
+	}
+}
+
+// run exports all queued rotations until the context is cancelled
+func (f *flowExporter) run(ctx context.Context) {
+	logger := logging.FromContext(ctx).With("target", f.cfg.Target)
+	logger.Info("starting flow export")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stopping flow export")
+			if err := f.exporter.Close(); err != nil {
+				logger.Errorf("failed to close connection to flow collector: %s", err)
+			}
+			return
+		case job := <-f.queue:
+			f.export(withIfaceContext(ctx, job.iface), job)
+		}
+	}
+}
+
+func (f *flowExporter) export(ctx context.Context, job flowExportJob) {
+	logger := logging.FromContext(ctx)
+
+	// the interval of the rotation starts with the previous one (or a writeout interval ago)
+	start, exists := f.lastExport[job.iface]
+	if !exists || !start.Before(job.timestamp) {
+		start = job.timestamp.Add(-time.Duration(goDB.DBWriteInterval) * time.Second)
+	}
+	f.lastExport[job.iface] = job.timestamp
+
+	// the index of the interface is reported as its SNMP index, allowing the collector to distinguish
+	// the interfaces (0 if it cannot be determined, e.g. for interfaces in other network namespaces)
+	var ifIndex uint32
+	if netIface, err := net.InterfaceByName(job.iface); err == nil {
+		ifIndex = uint32(netIface.Index)
+	}
+
+	flows := toNetFlows(job.flows)
+	n, err := f.exporter.Export(flows, ifIndex, start, job.timestamp)
+	if err != nil {
+		promFlowExports.WithLabelValues(job.iface, "failed").Inc()
+		logger.With("messages_sent", n).Errorf("failed to export flows: %s", err)
+		return
+	}
+	promFlowExports.WithLabelValues(job.iface, "exported").Inc()
+	logger.With("flows", len(flows), "messages", n).Debug("exported flows")
+}
+
+// exportFlows schedules the flows of a rotation for export (if enabled for the interface)
+func (cm *Manager) exportFlows(ctx context.Context, iface string, timestamp time.Time, flows *hashmap.AggFlowMap) {
+	if cm.flowExport == nil || flows == nil {
+		return
+	}
+
+	cm.RLock()
+	enabled := cm.lastAppliedConfig[iface].FlowExport
+	cm.RUnlock()
+
+	if enabled {
+		cm.flowExport.enqueue(ctx, iface, timestamp, flows)
+	}
+}
+
+// toNetFlows converts the flows of a rotation into flows to be exported
+func toNetFlows(flows *hashmap.AggFlowMap) []netflow.Flow {
+	res := make([]netflow.Flow, 0, flows.Len())
+	for it := flows.Iter(); it.Next(); {
+		key, val := types.Key(it.Key()), it.Val()
+
+		sip, _ := netip.AddrFromSlice(key.GetSIP())
+		dip, _ := netip.AddrFromSlice(key.GetDIP())
+		res = append(res, netflow.Flow{
+			SrcIP:       sip,
+			DstIP:       dip,
+			DstPort:     types.PortToUint16(key.GetDport()),
+			Proto:       key.GetProto(),
+			BytesRcvd:   val.BytesRcvd,
+			BytesSent:   val.BytesSent,
+			PacketsRcvd: val.PacketsRcvd,
+			PacketsSent: val.PacketsSent,
+		})
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package capture
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/netflow"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToNetFlows(t *testing.T) {
+	m := hashmap.NewAggFlowMap()
+	m.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 1, 1}, []byte{0x01, 0xbb}, 6),
+		types.Counters{BytesRcvd: 100, BytesSent: 10, PacketsRcvd: 2, PacketsSent: 1})
+	m.SecondaryMap.Set(types.NewV6KeyStatic(netip.MustParseAddr("2001:db8::1").As16(), netip.MustParseAddr("2001:db8::2").As16(), []byte{0, 53}, 17),
+		types.Counters{BytesRcvd: 200, PacketsRcvd: 4})
+
+	require.ElementsMatch(t, []netflow.Flow{
+		{
+			SrcIP:       netip.MustParseAddr("10.0.0.1"),
+			DstIP:       netip.MustParseAddr("10.0.1.1"),
+			DstPort:     443,
+			Proto:       6,
+			BytesRcvd:   100,
+			BytesSent:   10,
+			PacketsRcvd: 2,
+			PacketsSent: 1,
+		},
+		{
+			SrcIP:       netip.MustParseAddr("2001:db8::1"),
+			DstIP:       netip.MustParseAddr("2001:db8::2"),
+			DstPort:     53,
+			Proto:       17,
+			BytesRcvd:   200,
+			PacketsRcvd: 4,
+		},
+	}, toNetFlows(m))
+}
+
+func TestExportFlows(t *testing.T) {
+	cm := NewManager(nil, WithFlowExport(&config.FlowExportConfig{Target: "127.0.0.1:4739"}))
+	cm.lastAppliedConfig = config.Ifaces{
+		"eth0": config.CaptureConfig{FlowExport: true},
+		"eth1": config.CaptureConfig{},
+	}
+
+	ctx, ts := context.Background(), time.Now()
+
+	// only rotations of interfaces enabling the export are queued
+	cm.exportFlows(ctx, "eth0", ts, testRotation("eth0", 1).Map)
+	cm.exportFlows(ctx, "eth1", ts, testRotation("eth1", 1).Map)
+	cm.exportFlows(ctx, "eth0", ts, nil)
+	require.Len(t, cm.flowExport.queue, 1)
+
+	// rotations exceeding the capacity of the queue are dropped
+	for i := 0; i < 2*flowExportQueueSize; i++ {
+		cm.exportFlows(ctx, "eth0", ts, testRotation("eth0", 1).Map)
+	}
+	require.Len(t, cm.flowExport.queue, flowExportQueueSize)
+}
@@ -0,0 +1,38 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+// ErrIfaceNotCapturing denotes that an interface is not being captured on
+var ErrIfaceNotCapturing = errors.New("interface not capturing")
+
+// Flush triggers an immediate (out-of-cycle) rotation and writeout of all (or a set of) interfaces,
+// e.g. to have the freshest data available in the database before maintenance. It returns the
+// timestamp of the writeout and the number of flows written for each interface. The regular writeout
+// schedule is not affected
+func (cm *Manager) Flush(ctx context.Context, ifaces ...string) (time.Time, []capturetypes.WriteoutResult, error) {
+	var missing []string
+	for _, iface := range ifaces {
+		if _, exists := cm.captures.Get(iface); !exists {
+			missing = append(missing, iface)
+		}
+	}
+	if len(missing) > 0 {
+		return time.Time{}, nil, fmt.Errorf("%w: %s", ErrIfaceNotCapturing, strings.Join(missing, ","))
+	}
+
+	timestamp, res := cm.performWriteout(ctx, cm.clock.Now(), ifaces...)
+	slices.SortFunc(res, func(a, b capturetypes.WriteoutResult) int {
+		return strings.Compare(a.Iface, b.Iface)
+	})
+
+	return timestamp, res, nil
+}
@@ -0,0 +1,121 @@
+package capture
+
+import (
+	"slices"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+const (
+
+	// maxFlowHandshakeSamples denotes the maximum number of round trip times retained per flow
+	// and rotation for the computation of the median (minimum / maximum are tracked across all
+	// handshakes)
+	maxFlowHandshakeSamples = 32
+
+	// maxRotationHandshakeSamples denotes the maximum number of round trip times retained per
+	// interface and rotation for the computation of the median
+	maxRotationHandshakeSamples = 65536
+)
+
+// rttSamples tracks a (bounded) set of round trip times
+type rttSamples struct {
+	samples  []time.Duration
+	n        int
+	min, max time.Duration
+}
+
+// add records a round trip time, retaining at most limit samples for the computation of the median
+func (r *rttSamples) add(rtt time.Duration, limit int) {
+	if r.n == 0 || rtt < r.min {
+		r.min = rtt
+	}
+	if rtt > r.max {
+		r.max = rtt
+	}
+	r.n++
+
+	if len(r.samples) < limit {
+		r.samples = append(r.samples, rtt)
+	}
+}
+
+// merge adds all round trip times tracked by another set, retaining at most limit samples
+// for the computation of the median
+func (r *rttSamples) merge(r2 *rttSamples, limit int) {
+	if r2.n == 0 {
+		return
+	}
+
+	if r.n == 0 || r2.min < r.min {
+		r.min = r2.min
+	}
+	if r2.max > r.max {
+		r.max = r2.max
+	}
+	r.n += r2.n
+
+	if free := limit - len(r.samples); free > 0 {
+		r.samples = append(r.samples, r2.samples[:min(free, len(r2.samples))]...)
+	}
+}
+
+func (r *rttSamples) reset() {
+	r.samples = r.samples[:0]
+	r.n, r.min, r.max = 0, 0, 0
+}
+
+// summary returns the summary of all tracked round trip times (or nil if there are none)
+func (r *rttSamples) summary() *capturetypes.HandshakeRTT {
+	if r.n == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(r.samples)
+	slices.Sort(sorted)
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+
+	return &capturetypes.HandshakeRTT{
+		Samples: r.n,
+		Min:     r.min,
+		Median:  median,
+		Max:     r.max,
+	}
+}
+
+// handshakeTracker estimates the round trip times of the TCP handshakes of an individual flow
+type handshakeTracker struct {
+
+	// synSeen denotes the time (unix nanoseconds) of the most recent SYN pending a SYN-ACK
+	synSeen int64
+
+	rttSamples
+}
+
+// observe processes a handshake packet (SYN / SYN-ACK) of the flow
+func (h *handshakeTracker) observe(tcpFlags byte, timestamp int64) {
+
+	// A retransmitted SYN restarts the measurement
+	if capturetypes.IsSYN(tcpFlags) {
+		h.synSeen = timestamp
+		return
+	}
+
+	if capturetypes.IsSYNACK(tcpFlags) && h.synSeen != 0 {
+		if rtt := time.Duration(timestamp - h.synSeen); rtt > 0 {
+			h.add(rtt, maxFlowHandshakeSamples)
+		}
+		h.synSeen = 0
+	}
+}
+
+func (h *handshakeTracker) clone() *handshakeTracker {
+	res := *h
+	res.samples = slices.Clone(h.samples)
+	return &res
+}
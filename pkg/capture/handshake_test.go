@@ -0,0 +1,156 @@
+package capture
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/hostaddrs"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testFlagsSYN    = 0x02
+	testFlagsSYNACK = 0x12
+	testFlagsACK    = 0x10
+)
+
+func TestHandshakeTracker(t *testing.T) {
+	var tracker handshakeTracker
+
+	// a SYN-ACK without preceding SYN is ignored
+	tracker.observe(testFlagsSYNACK, 100)
+	require.Nil(t, tracker.summary())
+
+	// a retransmitted SYN restarts the measurement
+	tracker.observe(testFlagsSYN, 100)
+	tracker.observe(testFlagsSYN, 200)
+	tracker.observe(testFlagsACK, 250)
+	tracker.observe(testFlagsSYNACK, 300)
+
+	// duplicate SYN-ACKs are ignored
+	tracker.observe(testFlagsSYNACK, 400)
+
+	tracker.observe(testFlagsSYN, 1000)
+	tracker.observe(testFlagsSYNACK, 1400)
+	tracker.observe(testFlagsSYN, 2000)
+	tracker.observe(testFlagsSYNACK, 2200)
+
+	require.Equal(t, &capturetypes.HandshakeRTT{
+		Samples: 3,
+		Min:     100,
+		Median:  200,
+		Max:     400,
+	}, tracker.summary())
+
+	// pending SYNs are retained across a reset
+	tracker.observe(testFlagsSYN, 3000)
+	tracker.reset()
+	require.Nil(t, tracker.summary())
+	tracker.observe(testFlagsSYNACK, 3050)
+	require.Equal(t, &capturetypes.HandshakeRTT{
+		Samples: 1,
+		Min:     50,
+		Median:  50,
+		Max:     50,
+	}, tracker.summary())
+}
+
+func TestRTTSamplesLimit(t *testing.T) {
+	var samples, merged rttSamples
+	for i := 1; i <= 2*maxFlowHandshakeSamples; i++ {
+		samples.add(time.Duration(i), maxFlowHandshakeSamples)
+	}
+	require.Len(t, samples.samples, maxFlowHandshakeSamples)
+
+	merged.add(1000, 4)
+	merged.merge(&samples, 4)
+	require.Len(t, merged.samples, 4)
+	require.Equal(t, &capturetypes.HandshakeRTT{
+		Samples: 2*maxFlowHandshakeSamples + 1,
+		Min:     1,
+		Median:  2,
+		Max:     1000,
+	}, merged.summary())
+}
+
+func TestFlowLogHandshakeRTT(t *testing.T) {
+	client, server := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	syn := testTCPEPHash(client, server, 34567, 8080)
+	synAck := syn.Reverse()
+
+	flowLog := NewFlowLog()
+	for _, pkt := range []struct {
+		epHash    capturetypes.EPHash
+		flags     byte
+		timestamp int64
+	}{
+		{syn, testFlagsSYN, 1000},
+		{synAck, testFlagsSYNACK, 1500},
+		{syn, testFlagsACK, 1600},
+		{syn, testFlagsSYN, 5000},
+	} {
+		require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(pkt.epHash, capture.PacketOutgoing, 64, true, pkt.flags, capturetypes.ErrnoOK))
+		flowLog.ObserveHandshake(pkt.epHash, pkt.flags, pkt.timestamp)
+	}
+	require.Equal(t, 1, flowLog.Len())
+
+	_, _, rtt := flowLog.transferAndAggregate()
+	require.Equal(t, &capturetypes.HandshakeRTT{
+		Samples: 1,
+		Min:     500,
+		Median:  500,
+		Max:     500,
+	}, rtt)
+
+	// the handshake initiated in the previous rotation completes in the next one
+	require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(synAck, capture.PacketThisHost, 64, true, testFlagsSYNACK, capturetypes.ErrnoOK))
+	flowLog.ObserveHandshake(synAck, testFlagsSYNACK, 5300)
+	for _, flow := range flowLog.Flows() {
+		require.Equal(t, 300*time.Nanosecond, flow.HandshakeRTT().Median)
+	}
+
+	_, _, rtt = flowLog.transferAndAggregate()
+	require.Equal(t, 1, rtt.Samples)
+
+	_, _, rtt = flowLog.transferAndAggregate()
+	require.Nil(t, rtt)
+}
+
+func TestFlowLogHostAddrs(t *testing.T) {
+	host, remote := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+
+	// a mid-stream packet between two ephemeral ports would be classified as server -> client
+	// by the port heuristics (since the host's port is the lower one)
+	epHash := testTCPEPHash(host, remote, 40000, 50000)
+	require.Equal(t, capturetypes.DirectionReverts, capturetypes.ClassifyPacketDirection(epHash, true, testFlagsACK))
+
+	hostAddrs, err := hostaddrs.New("eth0", []string{host.String()}, false)
+	require.Nil(t, err)
+
+	for _, flowLog := range []*FlowLog{NewFlowLog(), {flowMap: make(map[string]*Flow), hostAddrs: hostAddrs}} {
+		require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(epHash, capture.PacketOutgoing, 64, true, testFlagsACK, capturetypes.ErrnoOK))
+		require.Equal(t, 1, flowLog.Len())
+
+		expected := epHash
+		if flowLog.hostAddrs == nil {
+			expected = epHash.Reverse()
+		}
+		for _, flow := range flowLog.clone().Flows() {
+			require.Equal(t, expected, flow.epHash)
+			require.True(t, flow.directionConfidenceHigh)
+		}
+	}
+}
+
+func testTCPEPHash(sip, dip netip.Addr, sport, dport uint16) (epHash capturetypes.EPHash) {
+	sip4, dip4 := sip.As4(), dip.As4()
+	copy(epHash[0:4], sip4[:])
+	copy(epHash[16:20], dip4[:])
+	epHash[32], epHash[33] = byte(dport>>8), byte(dport)
+	epHash[34], epHash[35] = byte(sport>>8), byte(sport)
+	epHash[36] = capturetypes.TCP
+	return
+}
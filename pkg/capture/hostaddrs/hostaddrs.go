@@ -0,0 +1,122 @@
+// Package hostaddrs provides the set of IP addresses / prefixes belonging to the capturing host
+// on an individual interface. Flows involving the host itself can then be classified definitively
+// since the role (client / server) of the host's endpoint is known, overriding the (port based)
+// direction heuristics applied to all other flows
+package hostaddrs
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/filter"
+)
+
+// Set stores the addresses / prefixes of the capturing host on an interface. It is immutable
+// once created and hence safe for concurrent use
+type Set struct {
+	v4, v6 []netip.Prefix
+}
+
+// New creates a new Set from a list of prefixes in CIDR notation (single IP addresses are treated
+// as host prefixes). If discover is set, all addresses currently assigned to the interface are added
+// (retrieved via netlink on Linux). If neither yields any address, nil is returned (which is a
+// valid Set that never classifies a flow)
+func New(iface string, prefixes []string, discover bool) (*Set, error) {
+	var s *Set
+	add := func(prefix netip.Prefix) {
+		if s == nil {
+			s = new(Set)
+		}
+		if prefix.Addr().Is4() {
+			s.v4 = append(s.v4, prefix)
+		} else {
+			s.v6 = append(s.v6, prefix)
+		}
+	}
+
+	for _, cidr := range prefixes {
+		prefix, err := filter.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		add(prefix)
+	}
+
+	if discover {
+		addrs, err := Discover(iface)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			add(netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	return s, nil
+}
+
+// Discover returns all IP addresses currently assigned to an interface. Only the addresses
+// themselves (not the subnets they are part of) are returned since other hosts on the same
+// subnet may be observed on the interface as well
+func Discover(iface string) ([]netip.Addr, error) {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover addresses of interface %s: %w", iface, err)
+	}
+	ifaceAddrs, err := link.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover addresses of interface %s: %w", iface, err)
+	}
+
+	addrs := make([]netip.Addr, 0, len(ifaceAddrs))
+	for _, ifaceAddr := range ifaceAddrs {
+		ipNet, ok := ifaceAddr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		addrs = append(addrs, addr.Unmap())
+	}
+
+	return addrs, nil
+}
+
+// Contains returns if the (raw) IP address, as stored in an EPHash, belongs to the host
+func (s *Set) Contains(ip []byte, isIPv4 bool) bool {
+	if s == nil {
+		return false
+	}
+	if isIPv4 {
+		return contains(s.v4, netip.AddrFrom4([4]byte(ip[0:4])))
+	}
+	return contains(s.v6, netip.AddrFrom16([16]byte(ip[0:16])))
+}
+
+// Classify determines the direction of a packet exchanged with the host itself. If neither or
+// both of its endpoints belong to the host, capturetypes.DirectionUnknown is returned and the
+// packet is left to the default heuristics
+func (s *Set) Classify(epHash capturetypes.EPHash, isIPv4 bool, auxInfo byte) capturetypes.Direction {
+	if s == nil {
+		return capturetypes.DirectionUnknown
+	}
+
+	srcIsHost, dstIsHost := s.Contains(epHash[0:16], isIPv4), s.Contains(epHash[16:32], isIPv4)
+	if srcIsHost == dstIsHost {
+		return capturetypes.DirectionUnknown
+	}
+	return capturetypes.ClassifyHostDirection(epHash, auxInfo, srcIsHost)
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package hostaddrs
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func newEPHash(sip, dip string, sport, dport uint16, proto byte) (epHash capturetypes.EPHash, isIPv4 bool) {
+	sipAddr, dipAddr := netip.MustParseAddr(sip), netip.MustParseAddr(dip)
+	if sipAddr.Is4() {
+		sipBytes, dipBytes := sipAddr.As4(), dipAddr.As4()
+		copy(epHash[0:4], sipBytes[:])
+		copy(epHash[16:20], dipBytes[:])
+		isIPv4 = true
+	} else {
+		sipBytes, dipBytes := sipAddr.As16(), dipAddr.As16()
+		copy(epHash[0:16], sipBytes[:])
+		copy(epHash[16:32], dipBytes[:])
+	}
+	binary.BigEndian.PutUint16(epHash[32:34], dport)
+	binary.BigEndian.PutUint16(epHash[34:36], sport)
+	epHash[36] = proto
+
+	return
+}
+
+var testPrefixes = []string{"192.0.2.10", "2001:db8::/64", "::ffff:198.51.100.1"}
+
+func TestClassify(t *testing.T) {
+	var tests = []struct {
+		name         string
+		sip, dip     string
+		sport, dport uint16
+		proto        byte
+		auxInfo      byte
+		expected     capturetypes.Direction
+	}{
+		{"host client", "192.0.2.10", "203.0.113.1", 40000, 443, capturetypes.TCP, 0x10, capturetypes.DirectionRemains},
+		{"host client response", "203.0.113.1", "192.0.2.10", 443, 40000, capturetypes.TCP, 0x10, capturetypes.DirectionReverts},
+		{"host server", "203.0.113.1", "192.0.2.10", 40000, 22, capturetypes.TCP, 0x10, capturetypes.DirectionRemains},
+		{"host server response", "192.0.2.10", "203.0.113.1", 22, 40000, capturetypes.TCP, 0x10, capturetypes.DirectionReverts},
+		{"host server ephemeral peer port", "203.0.113.1", "192.0.2.10", 5000, 8080, capturetypes.UDP, 0, capturetypes.DirectionRemains},
+		{"host server response ephemeral peer port", "192.0.2.10", "203.0.113.1", 8080, 5000, capturetypes.UDP, 0, capturetypes.DirectionReverts},
+		{"host client disregarded port", "192.0.2.10", "203.0.113.1", 0, 53, capturetypes.UDP, 0, capturetypes.DirectionRemains},
+		{"host client IPv6", "2001:db8::1", "2001:db9::1", 50000, 8443, capturetypes.TCP, 0x10, capturetypes.DirectionRemains},
+		{"host server IPv4-mapped", "198.51.100.1", "203.0.113.1", 443, 50000, capturetypes.TCP, 0x10, capturetypes.DirectionReverts},
+		{"SYN takes precedence", "192.0.2.10", "203.0.113.1", 40000, 443, capturetypes.TCP, 0x02, capturetypes.DirectionRemains},
+		{"SYN-ACK takes precedence", "192.0.2.10", "203.0.113.1", 40000, 443, capturetypes.TCP, 0x12, capturetypes.DirectionReverts},
+		{"ICMP", "192.0.2.10", "203.0.113.1", 0, 0, capturetypes.ICMP, 0x08, capturetypes.DirectionUnknown},
+		{"not involving host", "203.0.113.2", "203.0.113.1", 40000, 443, capturetypes.TCP, 0x10, capturetypes.DirectionUnknown},
+		{"both endpoints host", "2001:db8::1", "2001:db8::2", 40000, 443, capturetypes.TCP, 0x10, capturetypes.DirectionUnknown},
+	}
+
+	set, err := New("lo", testPrefixes, false)
+	require.Nil(t, err)
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			epHash, isIPv4 := newEPHash(test.sip, test.dip, test.sport, test.dport, test.proto)
+			direction := set.Classify(epHash, isIPv4, test.auxInfo)
+			require.Equal(t, test.expected, direction)
+			require.Equal(t, test.expected != capturetypes.DirectionUnknown, direction.IsConfidenceHigh())
+		})
+	}
+}
+
+func TestNilSet(t *testing.T) {
+	set, err := New("lo", nil, false)
+	require.Nil(t, err)
+	require.Nil(t, set)
+
+	epHash, isIPv4 := newEPHash("192.0.2.10", "203.0.113.1", 40000, 443, capturetypes.TCP)
+	require.False(t, set.Contains(epHash[0:16], isIPv4))
+	require.Equal(t, capturetypes.DirectionUnknown, set.Classify(epHash, isIPv4, 0x10))
+}
+
+func TestInvalidPrefix(t *testing.T) {
+	_, err := New("lo", []string{"192.0.2.300"}, false)
+	require.NotNil(t, err)
+}
+
+func TestDiscover(t *testing.T) {
+	_, err := New("doesnotexist0", nil, true)
+	require.NotNil(t, err)
+
+	set, err := New("lo", nil, true)
+	if err != nil {
+		t.Skipf("loopback interface not available: %s", err)
+	}
+	require.NotNil(t, set)
+
+	epHash, isIPv4 := newEPHash("127.0.0.1", "203.0.113.1", 40000, 443, capturetypes.TCP)
+	require.True(t, set.Contains(epHash[0:16], isIPv4))
+}
@@ -0,0 +1,102 @@
+package capture
+
+import (
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/fako1024/slimcap/link"
+	"golang.org/x/net/ipv6"
+)
+
+// IPv6 extension headers preceding the transport layer which are traversed during parsing
+const (
+	ipv6HopByHop    = 0x00 // Hop-by-Hop Options : 0
+	ipv6Routing     = 0x2B // Routing : 43
+	ipv6Fragment    = 0x2C // Fragment : 44
+	ipv6DestOptions = 0x3C // Destination Options : 60
+
+	ipv6ExtHeaderMinLen = 8 // all traversed extension headers span at least 8 bytes
+	ipv6FragmentIDLen   = 4 // length of the identification of a fragmented packet
+
+	// maxIPv6ExtHeaders limits the number of extension headers traversed per packet (RFC 8200 recommends
+	// each extension header to occur at most once, except for the Destination Options header)
+	maxIPv6ExtHeaders = 8
+
+	// maxIPv6Fragments limits the number of fragmented packets tracked by a flow log (c.f. FlowLog.ObserveFragment())
+	maxIPv6Fragments = 4096
+
+	// ipv6ExtHeadersCaptureLen denotes the additional capture length reserved for extension headers (e.g. a
+	// Hop-by-Hop Options, a Routing header with a single segment and a Fragment header). The transport layer of
+	// packets carrying longer chains is cut off by the capture, hence they are reported as truncated
+	ipv6ExtHeadersCaptureLen = 48
+)
+
+// captureLengthIPv6ExtHeaders extends the minimal capture length required for transport layer analysis by the
+// capture length reserved for IPv6 extension headers
+func captureLengthIPv6ExtHeaders(l *link.Link) int {
+	return link.CaptureLengthMinimalIPv6Transport(l) + ipv6ExtHeadersCaptureLen
+}
+
+func isIPv6ExtHeader(nextHeader byte) bool {
+	switch nextHeader {
+	case ipv6HopByHop, ipv6Routing, ipv6Fragment, ipv6DestOptions:
+		return true
+	}
+	return false
+}
+
+// walkIPv6ExtHeaders traverses the chain of IPv6 extension headers following the fixed header, returning the
+// actual (transport) protocol of the packet and the offset of its transport layer. If the packet is a fragment,
+// the offset of its Fragment header is returned as well (zero otherwise).
+//
+// Non-first fragments lack a transport layer (they are attributed to the flow of their first fragment instead,
+// c.f. FlowLog.Add()), in which case errno is set to ErrnoPacketFragment
+func walkIPv6ExtHeaders(ipLayer capture.IPLayer) (protocol byte, offset, fragOffset int, errno capturetypes.ParsingErrno) {
+	protocol, offset = ipLayer[6], ipv6.HeaderLen
+
+	for i := 0; isIPv6ExtHeader(protocol); i++ {
+		if i == maxIPv6ExtHeaders {
+			return protocol, offset, fragOffset, capturetypes.ErrnoInvalidIPHeader
+		}
+		if len(ipLayer) < offset+ipv6ExtHeaderMinLen {
+			return protocol, offset, fragOffset, capturetypes.ErrnoPacketTruncated
+		}
+
+		if protocol == ipv6Fragment {
+
+			// The Next Header field of a Fragment header denotes the protocol of the fragmented packet (in all
+			// of its fragments), the fragment offset is stored in the upper 13 bits of bytes 2-3
+			fragOffset = offset
+			if (uint16(ipLayer[offset+2])<<8|uint16(ipLayer[offset+3]))>>3 != 0 {
+				return ipLayer[offset], offset, fragOffset, capturetypes.ErrnoPacketFragment
+			}
+			protocol, offset = ipLayer[offset], offset+ipv6ExtHeaderMinLen
+			continue
+		}
+
+		// All other traversed extension headers denote their length in units of 8 bytes (not including
+		// the first 8 bytes)
+		protocol, offset = ipLayer[offset], offset+(int(ipLayer[offset+1])+1)*ipv6ExtHeaderMinLen
+	}
+
+	return protocol, offset, fragOffset, capturetypes.ErrnoOK
+}
+
+// ParseIPv6FragmentID extracts the identification of a fragmented IPv6 packet from its first fragment (i.e. the
+// fragment carrying the transport layer), allowing to attribute the subsequent fragments to the same flow
+// (c.f. FlowLog.ObserveFragment()). It must only be called for packets successfully parsed by ParsePacket()
+func ParseIPv6FragmentID(ipLayer capture.IPLayer) (fragID [ipv6FragmentIDLen]byte, isFirstFragment bool) {
+	if !isIPv6ExtHeader(ipLayer[6]) {
+		return
+	}
+
+	_, _, fragOffset, errno := walkIPv6ExtHeaders(ipLayer)
+
+	// Only packets with the More Fragments flag set are actually fragmented (as opposed to atomic
+	// fragments, c.f. RFC 6946)
+	if errno != capturetypes.ErrnoOK || fragOffset == 0 || ipLayer[fragOffset+3]&0x01 == 0 {
+		return
+	}
+	copy(fragID[:], ipLayer[fragOffset+4:fragOffset+8])
+
+	return fragID, true
+}
@@ -0,0 +1,159 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/fako1024/slimcap/capture"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/ipv6"
+)
+
+var testFragID = [ipv6FragmentIDLen]byte{0xde, 0xad, 0xbe, 0xef}
+
+type testExtHeader struct {
+	typ  byte
+	data []byte
+}
+
+func testOptionsHeader(typ byte, size int) testExtHeader {
+	data := make([]byte, size)
+	data[1] = byte(size/ipv6ExtHeaderMinLen - 1)
+	return testExtHeader{typ: typ, data: data}
+}
+
+func testFragmentHeader(offset uint16, moreFragments bool) testExtHeader {
+	data := make([]byte, ipv6ExtHeaderMinLen)
+	data[2], data[3] = byte(offset>>5), byte(offset<<3)
+	if moreFragments {
+		data[3] |= 0x01
+	}
+	copy(data[4:8], testFragID[:])
+	return testExtHeader{typ: ipv6Fragment, data: data}
+}
+
+// genIPv6ExtPacket generates a dummy IPv6 packet with the provided extension headers inserted between
+// the fixed header and the transport layer
+func (p testParams) genIPv6ExtPacket(pktType capture.PacketType, extHeaders ...testExtHeader) capture.Packet {
+	dummy := p.genDummyPacket(pktType)
+	plain := dummy.IPLayer()
+
+	data := append([]byte{}, plain[:ipv6.HeaderLen]...)
+	for i, extHeader := range extHeaders {
+		nextHeader := p.proto
+		if i < len(extHeaders)-1 {
+			nextHeader = extHeaders[i+1].typ
+		}
+		data = append(data, extHeader.data...)
+		data[len(data)-len(extHeader.data)] = nextHeader
+	}
+	if len(extHeaders) > 0 {
+		data[6] = extHeaders[0].typ
+	}
+	data = append(data, plain[ipv6.HeaderLen:]...)
+
+	return capture.NewIPPacket(nil, data, pktType, 128, 0)
+}
+
+func TestIPv6ExtHeaders(t *testing.T) {
+	params := testParams{sip: "2c04:4000::6ab", dip: "2c01:2000::3", sport: 37485, dport: 17500, proto: capturetypes.TCP}
+	tcpHash, _ := params.genEPHash()
+	udp := params
+	udp.proto = capturetypes.UDP
+	udpHash, _ := udp.genEPHash()
+	icmp := testParams{sip: "2c04:4000::6ab", dip: "2c01:2000::3", proto: capturetypes.ICMPv6, AuxInfo: 0x80}
+	icmpHash, _ := icmp.genEPHash()
+
+	// header claiming to extend beyond the end of the packet
+	testTruncatedHeader := testOptionsHeader(ipv6HopByHop, 8)
+	testTruncatedHeader.data[1] = 15
+
+	for _, c := range []struct {
+		name           string
+		pkt            capture.Packet
+		expectedHash   capturetypes.EPHash
+		expectedErrno  capturetypes.ParsingErrno
+		expectedFragID bool
+	}{
+		{"no extension headers", params.genIPv6ExtPacket(0), tcpHash, capturetypes.ErrnoOK, false},
+		{"hop-by-hop", params.genIPv6ExtPacket(0, testOptionsHeader(ipv6HopByHop, 8)), tcpHash, capturetypes.ErrnoOK, false},
+		{"hop-by-hop / routing / destination options", udp.genIPv6ExtPacket(0,
+			testOptionsHeader(ipv6HopByHop, 8),
+			testOptionsHeader(ipv6Routing, 24),
+			testOptionsHeader(ipv6DestOptions, 16),
+		), udpHash, capturetypes.ErrnoOK, false},
+		{"icmpv6 / destination options", icmp.genIPv6ExtPacket(0, testOptionsHeader(ipv6DestOptions, 8)), icmpHash, capturetypes.ErrnoOK, false},
+		{"first fragment", udp.genIPv6ExtPacket(0, testFragmentHeader(0, true)), udpHash, capturetypes.ErrnoOK, true},
+		{"atomic fragment", udp.genIPv6ExtPacket(0, testFragmentHeader(0, false)), udpHash, capturetypes.ErrnoOK, false},
+		{"truncated", params.genIPv6ExtPacket(0, testTruncatedHeader), capturetypes.EPHash{}, capturetypes.ErrnoPacketTruncated, false},
+		{"excessive chain", params.genIPv6ExtPacket(0,
+			testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8),
+			testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8),
+			testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8), testOptionsHeader(ipv6DestOptions, 8),
+		), capturetypes.EPHash{}, capturetypes.ErrnoInvalidIPHeader, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			epHash, isIPv4, _, errno := ParsePacket(c.pkt.IPLayer())
+			require.False(t, isIPv4)
+			require.Equal(t, c.expectedErrno, errno)
+			if errno != capturetypes.ErrnoOK {
+				return
+			}
+			require.Equal(t, c.expectedHash, epHash)
+
+			fragID, isFirstFragment := ParseIPv6FragmentID(c.pkt.IPLayer())
+			require.Equal(t, c.expectedFragID, isFirstFragment)
+			if isFirstFragment {
+				require.Equal(t, testFragID, fragID)
+			}
+		})
+	}
+
+	// TCP flags are extracted from behind the extension headers
+	pkt := params.genIPv6ExtPacket(0, testOptionsHeader(ipv6HopByHop, 8))
+	pkt.IPLayer()[ipv6.HeaderLen+8+13] = testFlagsSYN
+	_, _, auxInfo, errno := ParsePacket(pkt.IPLayer())
+	require.Equal(t, capturetypes.ErrnoOK, errno)
+	require.Equal(t, byte(testFlagsSYN), auxInfo)
+}
+
+func TestIPv6FragmentAttribution(t *testing.T) {
+	params := testParams{sip: "2c04:4000::6ab", dip: "2c01:2000::3", sport: 33561, dport: 53, proto: capturetypes.UDP}
+
+	flowLog := NewFlowLog()
+	addPacket := func(pkt capture.Packet) capturetypes.ParsingErrno {
+		epHash, isIPv4, auxInfo, errno := ParsePacket(pkt.IPLayer())
+		errno = flowLog.Add(epHash, pkt.Type(), 1280, isIPv4, auxInfo, errno)
+		if errno == capturetypes.ErrnoOK {
+			if fragID, isFirstFragment := ParseIPv6FragmentID(pkt.IPLayer()); isFirstFragment {
+				flowLog.ObserveFragment(epHash, fragID)
+			}
+		}
+		return errno
+	}
+
+	// fragments preceding their first fragment cannot be attributed to any flow
+	require.Equal(t, capturetypes.ErrnoPacketFragmentIgnore, addPacket(params.genIPv6ExtPacket(capture.PacketOutgoing, testFragmentHeader(1232/8, true))))
+
+	require.Equal(t, capturetypes.ErrnoOK, addPacket(params.genIPv6ExtPacket(capture.PacketOutgoing, testFragmentHeader(0, true))))
+	require.Equal(t, capturetypes.ErrnoOK, addPacket(params.genIPv6ExtPacket(capture.PacketOutgoing, testFragmentHeader(1232/8, true))))
+	require.Equal(t, capturetypes.ErrnoOK, addPacket(params.genIPv6ExtPacket(capture.PacketOutgoing, testFragmentHeader(2464/8, false))))
+
+	// fragments of a packet sent in the opposite direction are not attributed to the flow
+	reverse := testParams{sip: params.dip, dip: params.sip, sport: params.dport, dport: params.sport, proto: params.proto}
+	require.Equal(t, capturetypes.ErrnoPacketFragmentIgnore, addPacket(reverse.genIPv6ExtPacket(capture.PacketThisHost, testFragmentHeader(1232/8, false))))
+
+	require.Equal(t, 1, flowLog.Len())
+	for _, flow := range flowLog.Flows() {
+		require.Equal(t, uint64(3), flow.packetsSent)
+		require.Equal(t, uint64(3*1280), flow.bytesSent)
+		require.Zero(t, flow.packetsRcvd)
+	}
+
+	// fragments are attributed to their flow across rotations
+	_, totals, _ := flowLog.Rotate()
+	require.Equal(t, uint64(3), totals.PacketsSent)
+	require.Equal(t, capturetypes.ErrnoOK, addPacket(params.genIPv6ExtPacket(capture.PacketOutgoing, testFragmentHeader(3696/8, false))))
+	_, totals, _ = flowLog.Rotate()
+	require.Equal(t, uint64(1), totals.PacketsSent)
+}
@@ -52,6 +52,14 @@ var promPacketsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
 },
 	[]string{"iface"},
 )
+var promPacketsFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "packets_filtered_total",
+	Help:      "Number of packets discarded by the capture filter",
+},
+	[]string{"iface"},
+)
 var promCaptureErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Namespace: config.ServiceName,
 	Subsystem: captureSubsystem,
@@ -60,6 +68,89 @@ var promCaptureErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
 },
 	[]string{"iface"},
 )
+var promCPUSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "cpu_seconds_total",
+	Help:      "CPU time consumed by the packet processing routine of the capture",
+},
+	[]string{"iface"},
+)
+var promStandby = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "standby",
+	Help:      "Indicates if the capture has been released due to inactivity (1) or is active (0)",
+},
+	[]string{"iface"},
+)
+var promAutoRecoveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "auto_recoveries_total",
+	Help:      "Number of times the capture was reinitialized by the watchdog after its packet processing stalled",
+},
+	[]string{"iface"},
+)
+
+var promCardinalityBaseline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "flow_cardinality_baseline",
+	Help:      "Median number of unique flows per rotation across the recent rotations",
+},
+	[]string{"iface"},
+)
+var promCardinalityAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "flow_cardinality_alerts_total",
+	Help:      "Number of times the number of unique flows exceeded the configured multiple of the baseline",
+},
+	[]string{"iface"},
+)
+var promEmergencyActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "emergency_aggregation_active",
+	Help:      "Indicates if flows are collapsed to their prefixes due to the number of concurrent flows having reached the limit (as of the last rotation)",
+},
+	[]string{"iface"},
+)
+var promEmergencyEntered = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "emergency_aggregation_entered_total",
+	Help:      "Number of times the emergency aggregation mode was entered",
+},
+	[]string{"iface"},
+)
+
+var promCounterDiscrepancies = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "packet_counter_discrepancies_total",
+	Help:      "Number of rotations for which the packets recorded in the flows did not match the packets processed (if verification is enabled)",
+},
+	[]string{"iface"},
+)
+
+var promHandshakes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "tcp_handshakes_total",
+	Help:      "Number of TCP handshakes (SYN / SYN-ACK) used for round trip time estimation",
+},
+	[]string{"iface"},
+)
+var promHandshakeRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "tcp_handshake_rtt_seconds",
+	Help:      "Minimum / median / maximum TCP handshake round trip time estimate of the most recent rotation",
+},
+	[]string{"iface", "stat"},
+)
 
 var promInterfacesCapturing = prometheus.NewGauge(prometheus.GaugeOpts{
 	Namespace: config.ServiceName,
@@ -68,6 +159,38 @@ var promInterfacesCapturing = prometheus.NewGauge(prometheus.GaugeOpts{
 	Help:      "Number of interfaces that are actively capturing traffic",
 })
 
+var promSFlowDatagrams = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureManagerSubsystem,
+	Name:      "sflow_datagrams_total",
+	Help:      "Number of sFlow datagrams received (by decoding status)",
+},
+	[]string{"status"},
+)
+var promFlowExports = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "flow_exports_total",
+	Help:      "Number of rotations exported as NetFlow v9 / IPFIX records (by export status)",
+},
+	[]string{"iface", "status"},
+)
+var promIfaceErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureSubsystem,
+	Name:      "iface_errors_total",
+	Help:      "Number of errors putting an interface into an error state (by reason)",
+},
+	[]string{"iface", "reason"},
+)
+
+var promClockJumps = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: captureManagerSubsystem,
+	Name:      "clock_jumps_total",
+	Help:      "Number of steps of the system time detected between scheduled writeouts",
+})
+
 // not exposing the interface due to the high-cardinality nature of the histogram
 var promRotationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
 	Namespace: config.ServiceName,
@@ -82,12 +205,27 @@ func init() {
 	prometheus.MustRegister(
 		promPacketsProcessed,
 		promPacketsDropped,
+		promPacketsFiltered,
 		promBytes,
 		promPackets,
 		promNumFlows,
 		promCaptureErrors,
+		promCPUSeconds,
+		promStandby,
+		promAutoRecoveries,
+		promCardinalityBaseline,
+		promCardinalityAlerts,
+		promEmergencyActive,
+		promEmergencyEntered,
+		promCounterDiscrepancies,
+		promHandshakes,
+		promHandshakeRTT,
 		promInterfacesCapturing,
 		promRotationDuration,
+		promClockJumps,
+		promSFlowDatagrams,
+		promFlowExports,
+		promIfaceErrors,
 	)
 }
 
@@ -106,5 +244,17 @@ func ResetCountersTestingOnly() {
 	promPackets.Reset()
 	promNumFlows.Reset()
 	promPacketsDropped.Reset()
+	promPacketsFiltered.Reset()
 	promCaptureErrors.Reset()
+	promStandby.Reset()
+	promAutoRecoveries.Reset()
+	promCardinalityBaseline.Reset()
+	promCardinalityAlerts.Reset()
+	promEmergencyActive.Reset()
+	promEmergencyEntered.Reset()
+	promCounterDiscrepancies.Reset()
+	promHandshakes.Reset()
+	promHandshakeRTT.Reset()
+	promFlowExports.Reset()
+	promIfaceErrors.Reset()
 }
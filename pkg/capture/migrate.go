@@ -0,0 +1,25 @@
+package capture
+
+import (
+	"context"
+	"errors"
+
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+)
+
+// ErrMigrateNotSupported denotes that the writeout handler of the manager does not support migrations
+var ErrMigrateNotSupported = errors.New("writeout handler does not support migrations")
+
+// Migrate re-encodes the data in the storage of the writeout handler (of all interfaces or the provided
+// ones only) with the encoder currently configured for the respective interface, e.g. after the encoder
+// of an interface has been changed. In dry-run mode, the data that would be re-encoded is reported
+// without modifying it
+func (cm *Manager) Migrate(ctx context.Context, dryRun, force bool, ifaces ...string) (migrate.Results, error) {
+	migrator, ok := cm.writeoutHandler.(writeout.Migrator)
+	if !ok {
+		return nil, ErrMigrateNotSupported
+	}
+
+	return migrator.Migrate(ctx, ifaces, migrate.WithDryRun(dryRun), migrate.WithForce(force))
+}
@@ -0,0 +1,183 @@
+package netflow
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Transport denotes the transport protocol used to reach the collector
+type Transport string
+
+const (
+	// TransportUDP denotes export via UDP (one message per datagram)
+	TransportUDP Transport = "udp"
+
+	// TransportTCP denotes export via a TCP stream (IPFIX only, since NetFlow v9 messages do not state
+	// their length)
+	TransportTCP Transport = "tcp"
+)
+
+const (
+	// DefaultMaxMessageSize denotes the default maximum size of a message (fitting a UDP datagram into
+	// the common Ethernet MTU)
+	DefaultMaxMessageSize = 1400
+
+	// DefaultTemplateRefresh denotes the default interval in which the templates are resent via UDP
+	DefaultTemplateRefresh = 10 * time.Minute
+
+	// defaultTimeout denotes the timeout for establishing a connection / writing a message
+	defaultTimeout = 10 * time.Second
+)
+
+// ErrUnsupportedTransport denotes that a protocol cannot be exported via the requested transport
+var ErrUnsupportedTransport = errors.New("unsupported transport")
+
+// Exporter exports flows to a NetFlow v9 / IPFIX collector. Templates are sent ahead of the first
+// export of each connection and, if exporting via UDP, are refreshed periodically (since the collector
+// may have been restarted in the meantime). If a message cannot be sent, the connection is dropped and
+// reestablished upon the next export
+type Exporter struct {
+	target          string
+	protocol        Protocol
+	transport       Transport
+	domain          uint32
+	maxMessageSize  int
+	templateRefresh time.Duration
+	timeout         time.Duration
+
+	encoder       *Encoder
+	conn          net.Conn
+	lastTemplates time.Time
+
+	sync.Mutex
+}
+
+// Option denotes a functional option for an Exporter
+type Option func(*Exporter)
+
+// WithProtocol sets the export protocol (defaults to IPFIX)
+func WithProtocol(protocol Protocol) Option {
+	return func(e *Exporter) {
+		e.protocol = protocol
+	}
+}
+
+// WithTransport sets the transport protocol (defaults to UDP)
+func WithTransport(transport Transport) Option {
+	return func(e *Exporter) {
+		e.transport = transport
+	}
+}
+
+// WithObservationDomain sets the source ID (NetFlow v9) / observation domain ID (IPFIX) of all messages
+func WithObservationDomain(domain uint32) Option {
+	return func(e *Exporter) {
+		e.domain = domain
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a message
+func WithMaxMessageSize(size int) Option {
+	return func(e *Exporter) {
+		e.maxMessageSize = size
+	}
+}
+
+// WithTemplateRefresh sets the interval in which the templates are resent via UDP
+func WithTemplateRefresh(interval time.Duration) Option {
+	return func(e *Exporter) {
+		e.templateRefresh = interval
+	}
+}
+
+// NewExporter creates a new exporter for the collector at the given address (host:port). The connection
+// is established lazily upon the first export
+func NewExporter(target string, opts ...Option) (*Exporter, error) {
+	e := &Exporter{
+		target:          target,
+		protocol:        ProtocolIPFIX,
+		transport:       TransportUDP,
+		maxMessageSize:  DefaultMaxMessageSize,
+		templateRefresh: DefaultTemplateRefresh,
+		timeout:         defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	switch {
+	case e.transport == TransportUDP:
+	case e.transport == TransportTCP && e.protocol == ProtocolIPFIX:
+	default:
+		return nil, fmt.Errorf("%w %q for protocol %s", ErrUnsupportedTransport, e.transport, e.protocol)
+	}
+
+	var err error
+	if e.encoder, err = NewEncoder(e.protocol, e.domain, e.maxMessageSize, time.Now()); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Export exports the flows observed on an interface (identified by its index) during an interval,
+// returning the number of messages sent
+func (e *Exporter) Export(flows []Flow, ifIndex uint32, start, end time.Time) (int, error) {
+	if len(flows) == 0 {
+		return 0, nil
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	// (re-)establish the connection (if required). Templates are sent ahead of the first export of
+	// each connection
+	if e.conn == nil {
+		conn, err := net.DialTimeout(string(e.transport), e.target, e.timeout)
+		if err != nil {
+			return 0, fmt.Errorf("failed to connect to collector: %w", err)
+		}
+		e.conn, e.lastTemplates = conn, time.Time{}
+	}
+
+	now := time.Now()
+	withTemplates := e.lastTemplates.IsZero() ||
+		(e.transport == TransportUDP && now.Sub(e.lastTemplates) >= e.templateRefresh)
+
+	msgs := e.encoder.Encode(flows, ifIndex, start, end, now, withTemplates)
+	for i, msg := range msgs {
+		if err := e.conn.SetWriteDeadline(time.Now().Add(e.timeout)); err != nil {
+			e.closeConn()
+			return i, fmt.Errorf("failed to set write deadline: %w", err)
+		}
+		if _, err := e.conn.Write(msg); err != nil {
+			e.closeConn()
+			return i, fmt.Errorf("failed to send message to collector: %w", err)
+		}
+	}
+	if withTemplates {
+		e.lastTemplates = now
+	}
+
+	return len(msgs), nil
+}
+
+// Close closes the connection to the collector (if established)
+func (e *Exporter) Close() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+func (e *Exporter) closeConn() {
+	_ = e.conn.Close()
+	e.conn = nil
+}
@@ -0,0 +1,325 @@
+// Package netflow provides an exporter of flows as NetFlow v9 (c.f. RFC 3954) or IPFIX (c.f. RFC 7011)
+// records. Flows are exported via two fixed templates (one for IPv4, one for IPv6 flows), carrying the
+// attributes and counters of a goProbe flow along with the interval it was observed in
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Protocol denotes the export protocol
+type Protocol string
+
+const (
+	// ProtocolNetFlowV9 denotes the NetFlow version 9 protocol
+	ProtocolNetFlowV9 Protocol = "netflow_v9"
+
+	// ProtocolIPFIX denotes the IPFIX protocol
+	ProtocolIPFIX Protocol = "ipfix"
+)
+
+// Protocol versions as stated in the message headers
+const (
+	VersionNetFlowV9 = 9
+	VersionIPFIX     = 10
+)
+
+// Header lengths of the messages / sets
+const (
+	headerLenNetFlowV9 = 20
+	headerLenIPFIX     = 16
+	setHeaderLen       = 4
+)
+
+// Set IDs of the template sets
+const (
+	templateSetIDNetFlowV9 = 0
+	templateSetIDIPFIX     = 2
+)
+
+// Template IDs of the (fixed) templates
+const (
+	TemplateIDIPv4 = 256
+	TemplateIDIPv6 = 257
+)
+
+// Field types / information elements (the counters are numbered as the NetFlow v9 IN_* / OUT_* fields
+// in both protocols, denoting the traffic received / sent on the interface)
+const (
+	fieldInBytes       = 1
+	fieldInPkts        = 2
+	fieldProtocol      = 4
+	fieldIPv4SrcAddr   = 8
+	fieldInputSNMP     = 10
+	fieldL4DstPort     = 11
+	fieldIPv4DstAddr   = 12
+	fieldLastSwitched  = 21
+	fieldFirstSwitched = 22
+	fieldOutBytes      = 23
+	fieldOutPkts       = 24
+	fieldIPv6SrcAddr   = 27
+	fieldIPv6DstAddr   = 28
+	fieldFlowStartSecs = 150
+	fieldFlowEndSecs   = 151
+)
+
+// field denotes a field of a template
+type field struct {
+	id     uint16
+	length uint16
+}
+
+// template denotes a template, i.e. the fields of the records of a data set
+type template struct {
+	id     uint16
+	fields []field
+}
+
+func (t template) recordLen() (n int) {
+	for _, f := range t.fields {
+		n += int(f.length)
+	}
+	return
+}
+
+// Flow denotes a flow to be exported
+type Flow struct {
+	SrcIP   netip.Addr
+	DstIP   netip.Addr
+	DstPort uint16
+	Proto   uint8
+
+	BytesRcvd   uint64
+	BytesSent   uint64
+	PacketsRcvd uint64
+	PacketsSent uint64
+}
+
+// templates returns the templates of a protocol (for IPv4 and IPv6 flows)
+func templates(protocol Protocol) [2]template {
+	timeFields := []field{{fieldFlowStartSecs, 4}, {fieldFlowEndSecs, 4}}
+	if protocol == ProtocolNetFlowV9 {
+		timeFields = []field{{fieldFirstSwitched, 4}, {fieldLastSwitched, 4}}
+	}
+	commonFields := append([]field{
+		{fieldL4DstPort, 2},
+		{fieldProtocol, 1},
+		{fieldInputSNMP, 4},
+		{fieldInBytes, 8},
+		{fieldInPkts, 8},
+		{fieldOutBytes, 8},
+		{fieldOutPkts, 8},
+	}, timeFields...)
+
+	return [2]template{
+		{id: TemplateIDIPv4, fields: append([]field{{fieldIPv4SrcAddr, 4}, {fieldIPv4DstAddr, 4}}, commonFields...)},
+		{id: TemplateIDIPv6, fields: append([]field{{fieldIPv6SrcAddr, 16}, {fieldIPv6DstAddr, 16}}, commonFields...)},
+	}
+}
+
+// Encoder encodes flows as NetFlow v9 / IPFIX messages, keeping track of the sequence number of
+// the messages (NetFlow v9) / records (IPFIX) of its source / observation domain
+type Encoder struct {
+	protocol  Protocol
+	domain    uint32
+	maxSize   int
+	bootTime  time.Time
+	templates [2]template
+
+	sequence uint32
+}
+
+// NewEncoder creates a new encoder for messages of at most maxSize bytes of a source / observation domain.
+// The boot time denotes the reference of the system uptime stated in NetFlow v9 messages
+func NewEncoder(protocol Protocol, domain uint32, maxSize int, bootTime time.Time) (*Encoder, error) {
+	if protocol != ProtocolNetFlowV9 && protocol != ProtocolIPFIX {
+		return nil, fmt.Errorf("unsupported export protocol: %q", protocol)
+	}
+	e := &Encoder{
+		protocol:  protocol,
+		domain:    domain,
+		maxSize:   maxSize,
+		bootTime:  bootTime,
+		templates: templates(protocol),
+	}
+
+	// each message must at least fit the templates and a single record of each template
+	if minSize := e.headerLen() + e.templateSetLen() + setHeaderLen + e.templates[1].recordLen() + 3; maxSize < minSize || maxSize > 65535 {
+		return nil, fmt.Errorf("invalid maximum message size %d (must be between %d and 65535)", maxSize, minSize)
+	}
+	return e, nil
+}
+
+// Encode encodes the flows observed on an interface (identified by its index) during an interval into as
+// many messages as required, optionally preceded by the templates (which must be sent prior to any data
+// set and, if exporting via UDP, be refreshed periodically)
+func (e *Encoder) Encode(flows []Flow, ifIndex uint32, start, end, now time.Time, withTemplates bool) [][]byte {
+	m := &message{encoder: e, now: now}
+	m.reset()
+
+	if withTemplates {
+		m.appendTemplates()
+	}
+	for i, tmpl := range e.templates {
+		for _, flow := range flows {
+			if flow.SrcIP.Is4() != (i == 0) {
+				continue
+			}
+			m.appendRecord(tmpl, flow, ifIndex, start, end)
+		}
+	}
+	if m.numRecords > 0 {
+		m.finish()
+	}
+
+	return m.msgs
+}
+
+func (e *Encoder) headerLen() int {
+	if e.protocol == ProtocolNetFlowV9 {
+		return headerLenNetFlowV9
+	}
+	return headerLenIPFIX
+}
+
+func (e *Encoder) templateSetLen() int {
+	n := setHeaderLen
+	for _, tmpl := range e.templates {
+		n += 4 + 4*len(tmpl.fields)
+	}
+	return n
+}
+
+// uptime returns the system uptime (in milliseconds) at a point in time, as stated in NetFlow v9 messages
+func (e *Encoder) uptime(t time.Time) uint32 {
+	if t.Before(e.bootTime) {
+		return 0
+	}
+	return uint32(t.Sub(e.bootTime).Milliseconds())
+}
+
+// message denotes the message currently being encoded
+type message struct {
+	encoder *Encoder
+	now     time.Time
+	msgs    [][]byte
+
+	buf         []byte
+	setStart    int    // offset of the current set (-1 if none)
+	setID       uint16 // ID of the current set
+	numRecords  int    // number of records (including template records) of the message
+	dataRecords int    // number of data records of the message
+}
+
+func (m *message) reset() {
+	m.buf = make([]byte, m.encoder.headerLen(), m.encoder.maxSize)
+	m.setStart, m.numRecords, m.dataRecords = -1, 0, 0
+}
+
+// openSet starts a new set, provided there is enough space for it and a record of the given length
+// (including the maximum padding), otherwise a new message is started
+func (m *message) openSet(id uint16, recordLen int) {
+	if m.setStart >= 0 && m.setID == id && len(m.buf)+recordLen+3 <= m.encoder.maxSize {
+		return
+	}
+	m.closeSet()
+	if len(m.buf)+setHeaderLen+recordLen+3 > m.encoder.maxSize {
+		m.finish()
+	}
+	m.setStart, m.setID = len(m.buf), id
+	m.buf = binary.BigEndian.AppendUint16(m.buf, id)
+	m.buf = binary.BigEndian.AppendUint16(m.buf, 0) // length, set upon closing the set
+}
+
+// closeSet closes the current set (if any), padding it to a multiple of four bytes
+func (m *message) closeSet() {
+	if m.setStart < 0 {
+		return
+	}
+	for (len(m.buf)-m.setStart)%4 != 0 {
+		m.buf = append(m.buf, 0)
+	}
+	binary.BigEndian.PutUint16(m.buf[m.setStart+2:], uint16(len(m.buf)-m.setStart))
+	m.setStart = -1
+}
+
+func (m *message) appendTemplates() {
+	setID := uint16(templateSetIDIPFIX)
+	if m.encoder.protocol == ProtocolNetFlowV9 {
+		setID = templateSetIDNetFlowV9
+	}
+	m.openSet(setID, m.encoder.templateSetLen()-setHeaderLen)
+	for _, tmpl := range m.encoder.templates {
+		m.buf = binary.BigEndian.AppendUint16(m.buf, tmpl.id)
+		m.buf = binary.BigEndian.AppendUint16(m.buf, uint16(len(tmpl.fields)))
+		for _, f := range tmpl.fields {
+			m.buf = binary.BigEndian.AppendUint16(m.buf, f.id)
+			m.buf = binary.BigEndian.AppendUint16(m.buf, f.length)
+		}
+		m.numRecords++
+	}
+	m.closeSet()
+}
+
+func (m *message) appendRecord(tmpl template, flow Flow, ifIndex uint32, start, end time.Time) {
+	m.openSet(tmpl.id, tmpl.recordLen())
+
+	m.buf = append(m.buf, flow.SrcIP.AsSlice()...)
+	m.buf = append(m.buf, flow.DstIP.AsSlice()...)
+	m.buf = binary.BigEndian.AppendUint16(m.buf, flow.DstPort)
+	m.buf = append(m.buf, flow.Proto)
+	m.buf = binary.BigEndian.AppendUint32(m.buf, ifIndex)
+	m.buf = binary.BigEndian.AppendUint64(m.buf, flow.BytesRcvd)
+	m.buf = binary.BigEndian.AppendUint64(m.buf, flow.PacketsRcvd)
+	m.buf = binary.BigEndian.AppendUint64(m.buf, flow.BytesSent)
+	m.buf = binary.BigEndian.AppendUint64(m.buf, flow.PacketsSent)
+	if m.encoder.protocol == ProtocolNetFlowV9 {
+		m.buf = binary.BigEndian.AppendUint32(m.buf, m.encoder.uptime(start))
+		m.buf = binary.BigEndian.AppendUint32(m.buf, m.encoder.uptime(end))
+	} else {
+		m.buf = binary.BigEndian.AppendUint32(m.buf, uint32(start.Unix()))
+		m.buf = binary.BigEndian.AppendUint32(m.buf, uint32(end.Unix()))
+	}
+
+	m.numRecords++
+	m.dataRecords++
+}
+
+// finish completes the current message (populating its header) and starts a new one
+func (m *message) finish() {
+	m.closeSet()
+
+	e, now := m.encoder, m.now
+	binary.BigEndian.PutUint32(m.buf[headerSequenceOffset(e.protocol):], e.sequence)
+	if e.protocol == ProtocolNetFlowV9 {
+		binary.BigEndian.PutUint16(m.buf[0:], VersionNetFlowV9)
+		binary.BigEndian.PutUint16(m.buf[2:], uint16(m.numRecords))
+		binary.BigEndian.PutUint32(m.buf[4:], e.uptime(now))
+		binary.BigEndian.PutUint32(m.buf[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(m.buf[16:], e.domain)
+
+		// the sequence number of NetFlow v9 counts the messages
+		e.sequence++
+	} else {
+		binary.BigEndian.PutUint16(m.buf[0:], VersionIPFIX)
+		binary.BigEndian.PutUint16(m.buf[2:], uint16(len(m.buf)))
+		binary.BigEndian.PutUint32(m.buf[4:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(m.buf[12:], e.domain)
+
+		// the sequence number of IPFIX counts the data records
+		e.sequence += uint32(m.dataRecords)
+	}
+
+	m.msgs = append(m.msgs, m.buf)
+	m.reset()
+}
+
+func headerSequenceOffset(protocol Protocol) int {
+	if protocol == ProtocolNetFlowV9 {
+		return 12
+	}
+	return 8
+}
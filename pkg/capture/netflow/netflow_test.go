@@ -0,0 +1,301 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// set denotes a decoded set of a message
+type set struct {
+	id   uint16
+	data []byte
+}
+
+// decode decodes a message into its header and sets, validating its framing
+func decode(t *testing.T, protocol Protocol, msg []byte) (header []byte, sets []set) {
+	t.Helper()
+
+	headerLen := headerLenIPFIX
+	if protocol == ProtocolNetFlowV9 {
+		headerLen = headerLenNetFlowV9
+	} else {
+		require.EqualValues(t, len(msg), binary.BigEndian.Uint16(msg[2:]), "message length")
+	}
+	require.GreaterOrEqual(t, len(msg), headerLen)
+
+	header, msg = msg[:headerLen], msg[headerLen:]
+	for len(msg) > 0 {
+		require.GreaterOrEqual(t, len(msg), setHeaderLen)
+		length := int(binary.BigEndian.Uint16(msg[2:]))
+		require.Zero(t, length%4, "set not padded")
+		require.LessOrEqual(t, length, len(msg))
+		sets = append(sets, set{id: binary.BigEndian.Uint16(msg), data: msg[setHeaderLen:length]})
+		msg = msg[length:]
+	}
+	return
+}
+
+func testFlows(n int, ipv6 bool) []Flow {
+	flows := make([]Flow, n)
+	for i := range flows {
+		src, dst := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), netip.MustParseAddr("192.168.1.1")
+		if ipv6 {
+			src, dst = netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 14: byte(i >> 8), 15: byte(i)}), netip.MustParseAddr("2001:db8::1")
+		}
+		flows[i] = Flow{
+			SrcIP:       src,
+			DstIP:       dst,
+			DstPort:     443,
+			Proto:       6,
+			BytesRcvd:   uint64(1000 + i),
+			BytesSent:   uint64(2000 + i),
+			PacketsRcvd: uint64(10 + i),
+			PacketsSent: uint64(20 + i),
+		}
+	}
+	return flows
+}
+
+func TestEncodeTemplates(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolNetFlowV9, ProtocolIPFIX} {
+		t.Run(string(protocol), func(t *testing.T) {
+			enc, err := NewEncoder(protocol, 42, DefaultMaxMessageSize, time.Now())
+			require.Nil(t, err)
+
+			now := time.Unix(1700000000, 0)
+			msgs := enc.Encode(testFlows(1, false), 3, now.Add(-5*time.Minute), now, now, true)
+			require.Len(t, msgs, 1)
+
+			header, sets := decode(t, protocol, msgs[0])
+			require.Len(t, sets, 2)
+
+			expectedSetID, expectedTimeField := uint16(templateSetIDIPFIX), uint16(fieldFlowStartSecs)
+			if protocol == ProtocolNetFlowV9 {
+				expectedSetID, expectedTimeField = templateSetIDNetFlowV9, fieldFirstSwitched
+				require.EqualValues(t, VersionNetFlowV9, binary.BigEndian.Uint16(header))
+				require.EqualValues(t, 3, binary.BigEndian.Uint16(header[2:]), "record count")
+				require.EqualValues(t, 42, binary.BigEndian.Uint32(header[16:]), "source ID")
+			} else {
+				require.EqualValues(t, VersionIPFIX, binary.BigEndian.Uint16(header))
+				require.EqualValues(t, now.Unix(), binary.BigEndian.Uint32(header[4:]), "export time")
+				require.EqualValues(t, 42, binary.BigEndian.Uint32(header[12:]), "observation domain")
+			}
+
+			// template set, announcing both the IPv4 and the IPv6 template
+			require.Equal(t, expectedSetID, sets[0].id)
+			data := sets[0].data
+			for _, expected := range []struct {
+				id       uint16
+				srcField uint16
+				srcLen   uint16
+			}{
+				{TemplateIDIPv4, fieldIPv4SrcAddr, 4},
+				{TemplateIDIPv6, fieldIPv6SrcAddr, 16},
+			} {
+				require.Equal(t, expected.id, binary.BigEndian.Uint16(data))
+				numFields := int(binary.BigEndian.Uint16(data[2:]))
+				require.Equal(t, 11, numFields)
+				require.Equal(t, expected.srcField, binary.BigEndian.Uint16(data[4:]))
+				require.Equal(t, expected.srcLen, binary.BigEndian.Uint16(data[6:]))
+				require.Equal(t, expectedTimeField, binary.BigEndian.Uint16(data[4+4*(numFields-2):]))
+				data = data[4+4*numFields:]
+			}
+
+			// data set, holding the single IPv4 record
+			require.EqualValues(t, TemplateIDIPv4, sets[1].id)
+			record := sets[1].data
+			require.Equal(t, []byte{10, 0, 0, 0}, record[0:4])
+			require.Equal(t, []byte{192, 168, 1, 1}, record[4:8])
+			require.EqualValues(t, 443, binary.BigEndian.Uint16(record[8:]))
+			require.EqualValues(t, 6, record[10])
+			require.EqualValues(t, 3, binary.BigEndian.Uint32(record[11:]))
+			require.EqualValues(t, 1000, binary.BigEndian.Uint64(record[15:]))
+			require.EqualValues(t, 10, binary.BigEndian.Uint64(record[23:]))
+			require.EqualValues(t, 2000, binary.BigEndian.Uint64(record[31:]))
+			require.EqualValues(t, 20, binary.BigEndian.Uint64(record[39:]))
+			if protocol == ProtocolIPFIX {
+				require.EqualValues(t, now.Add(-5*time.Minute).Unix(), binary.BigEndian.Uint32(record[47:]))
+				require.EqualValues(t, now.Unix(), binary.BigEndian.Uint32(record[51:]))
+			}
+		})
+	}
+}
+
+func TestEncodeSplit(t *testing.T) {
+	var tests = []struct {
+		protocol Protocol
+		maxSize  int
+		nIPv4    int
+		nIPv6    int
+	}{
+		{ProtocolNetFlowV9, DefaultMaxMessageSize, 100, 0},
+		{ProtocolNetFlowV9, 512, 17, 33},
+		{ProtocolIPFIX, DefaultMaxMessageSize, 0, 100},
+		{ProtocolIPFIX, 512, 50, 50},
+		{ProtocolIPFIX, 65535, 1000, 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s_%d_%d_%d", test.protocol, test.maxSize, test.nIPv4, test.nIPv6), func(t *testing.T) {
+			enc, err := NewEncoder(test.protocol, 0, test.maxSize, time.Now())
+			require.Nil(t, err)
+
+			flows := append(testFlows(test.nIPv4, false), testFlows(test.nIPv6, true)...)
+
+			// encode twice to check the continuation of the sequence numbers
+			var expectedSequence uint32
+			for i := 0; i < 2; i++ {
+				now := time.Now()
+				msgs := enc.Encode(flows, 1, now.Add(-time.Minute), now, now, i == 0)
+				require.NotEmpty(t, msgs)
+
+				nRecords := map[uint16]int{}
+				for _, msg := range msgs {
+					require.LessOrEqual(t, len(msg), test.maxSize)
+					header, sets := decode(t, test.protocol, msg)
+					require.Equal(t, expectedSequence, binary.BigEndian.Uint32(header[headerSequenceOffset(test.protocol):]))
+
+					var nTemplateRecords, nDataRecords int
+					for _, s := range sets {
+						recordLen := map[uint16]int{TemplateIDIPv4: 55, TemplateIDIPv6: 79}[s.id]
+						if recordLen == 0 {
+							require.Zero(t, i, "unexpected template set")
+							nTemplateRecords += 2
+							continue
+						}
+						require.GreaterOrEqual(t, len(s.data), recordLen)
+						nRecords[s.id] += len(s.data) / recordLen
+						nDataRecords += len(s.data) / recordLen
+					}
+					if test.protocol == ProtocolNetFlowV9 {
+						require.EqualValues(t, nTemplateRecords+nDataRecords, binary.BigEndian.Uint16(header[2:]))
+						expectedSequence++
+					} else {
+						expectedSequence += uint32(nDataRecords)
+					}
+				}
+				require.Equal(t, test.nIPv4, nRecords[TemplateIDIPv4])
+				require.Equal(t, test.nIPv6, nRecords[TemplateIDIPv6])
+			}
+		})
+	}
+}
+
+func TestNewEncoderInvalid(t *testing.T) {
+	_, err := NewEncoder("netflow_v5", 0, DefaultMaxMessageSize, time.Now())
+	require.NotNil(t, err)
+
+	_, err = NewEncoder(ProtocolIPFIX, 0, 128, time.Now())
+	require.NotNil(t, err)
+
+	_, err = NewEncoder(ProtocolIPFIX, 0, 65536, time.Now())
+	require.NotNil(t, err)
+}
+
+func TestExporterUDP(t *testing.T) {
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer collector.Close()
+
+	exporter, err := NewExporter(collector.LocalAddr().String(),
+		WithProtocol(ProtocolNetFlowV9),
+		WithTemplateRefresh(time.Hour),
+	)
+	require.Nil(t, err)
+	defer exporter.Close()
+
+	receive := func() (header []byte, sets []set) {
+		buf := make([]byte, 65535)
+		require.Nil(t, collector.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := collector.ReadFrom(buf)
+		require.Nil(t, err)
+		return decode(t, ProtocolNetFlowV9, buf[:n])
+	}
+
+	now := time.Now()
+	for i, expectTemplates := range []bool{true, false} {
+		n, err := exporter.Export(testFlows(10, false), 1, now.Add(-time.Minute), now)
+		require.Nil(t, err)
+		require.Equal(t, 1, n)
+
+		header, sets := receive()
+		require.EqualValues(t, i, binary.BigEndian.Uint32(header[12:]))
+		require.Equal(t, expectTemplates, sets[0].id == templateSetIDNetFlowV9)
+	}
+
+	// nothing is sent if there are no flows
+	n, err := exporter.Export(nil, 1, now.Add(-time.Minute), now)
+	require.Nil(t, err)
+	require.Zero(t, n)
+}
+
+func TestExporterTCP(t *testing.T) {
+	collector, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer collector.Close()
+
+	received := make(chan []byte, 16)
+	go func() {
+		for {
+			conn, err := collector.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					header := make([]byte, headerLenIPFIX)
+					if _, err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					msg := make([]byte, binary.BigEndian.Uint16(header[2:]))
+					copy(msg, header)
+					if _, err := io.ReadFull(conn, msg[headerLenIPFIX:]); err != nil {
+						return
+					}
+					received <- msg
+				}
+			}(conn)
+		}
+	}()
+
+	exporter, err := NewExporter(collector.Addr().String(), WithTransport(TransportTCP), WithObservationDomain(7))
+	require.Nil(t, err)
+	defer exporter.Close()
+
+	now := time.Now()
+	for _, expectTemplates := range []bool{true, false, true} {
+		n, err := exporter.Export(testFlows(5, true), 1, now.Add(-time.Minute), now)
+		require.Nil(t, err)
+		require.Equal(t, 1, n)
+
+		select {
+		case msg := <-received:
+			header, sets := decode(t, ProtocolIPFIX, msg)
+			require.EqualValues(t, 7, binary.BigEndian.Uint32(header[12:]))
+			require.Equal(t, expectTemplates, sets[0].id == templateSetIDIPFIX)
+		case <-time.After(time.Second):
+			t.Fatal("no message received by collector")
+		}
+
+		// templates have to be resent upon reconnecting
+		if !expectTemplates {
+			require.Nil(t, exporter.Close())
+		}
+	}
+}
+
+func TestExporterUnsupportedTransport(t *testing.T) {
+	_, err := NewExporter("127.0.0.1:2055", WithProtocol(ProtocolNetFlowV9), WithTransport(TransportTCP))
+	require.ErrorIs(t, err, ErrUnsupportedTransport)
+
+	_, err = NewExporter("127.0.0.1:2055", WithTransport("sctp"))
+	require.ErrorIs(t, err, ErrUnsupportedTransport)
+}
@@ -0,0 +1,99 @@
+// Package netns provides the means to create capture sources for interfaces residing in other
+// network namespaces (e.g. of containers). Sockets remain bound to the network namespace they
+// were created in, hence it suffices to switch the namespace during their creation
+package netns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+)
+
+// lookupTimeout denotes the maximum duration of a container lookup via the runtime API
+const lookupTimeout = 10 * time.Second
+
+var (
+	// ErrNotSupported signifies that network namespaces are not supported on this platform
+	ErrNotSupported = errors.New("network namespaces are not supported on this platform")
+
+	// ErrContainerNotRunning signifies that a container exists, but is not running (and hence
+	// has no network namespace)
+	ErrContainerNotRunning = errors.New("container is not running")
+)
+
+// Resolve determines the path of the network namespace described by cfg, looking up the container
+// via its runtime API if required
+func Resolve(ctx context.Context, cfg *config.NetnsConfig) (string, error) {
+	if cfg.Path != "" {
+		return cfg.Path, nil
+	}
+
+	runtime := cfg.Runtime
+	if runtime == "" {
+		runtime = config.DefaultNetnsRuntime
+	}
+	pid, err := ContainerPID(ctx, runtime, cfg.Container)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/proc/%d/ns/net", pid), nil
+}
+
+// containerState denotes the (relevant part of the) state of a container as provided by the
+// Docker Engine API
+type containerState struct {
+	State struct {
+		Running bool `json:"Running"`
+		Pid     int  `json:"Pid"`
+	} `json:"State"`
+}
+
+// ContainerPID looks up the PID of the main process of a container via a Docker Engine compatible
+// runtime API, served on a unix socket (e.g. unix:///var/run/docker.sock)
+func ContainerPID(ctx context.Context, runtime, container string) (int, error) {
+	socket := strings.TrimPrefix(runtime, "unix://")
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	// the host is irrelevant since the connection is established via the unix socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://runtime/containers/"+url.PathEscape(container)+"/json", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up container %s via %s: %w", container, runtime, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to look up container %s via %s: %s", container, runtime, resp.Status)
+	}
+
+	var state containerState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return 0, fmt.Errorf("failed to parse state of container %s: %w", container, err)
+	}
+	if !state.State.Running || state.State.Pid <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrContainerNotRunning, container)
+	}
+
+	return state.State.Pid, nil
+}
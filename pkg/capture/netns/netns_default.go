@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package netns
+
+// Do executes fn within the network namespace located at path (not supported on this platform)
+func Do(_ string, _ func() error) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,48 @@
+//go:build linux
+// +build linux
+
+package netns
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Do executes fn within the network namespace located at path. Only the calling OS thread is
+// switched (and locked to the calling goroutine for the duration of fn), hence fn must not spawn
+// any goroutines relying on the namespace
+func Do(path string, fn func() error) error {
+	target, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace %s: %w", path, err)
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+
+	origin, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open current network namespace: %w", err)
+	}
+	defer origin.Close()
+
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to switch to network namespace %s: %w", path, err)
+	}
+
+	fnErr := fn()
+
+	// If switching back fails the thread is left locked, causing the runtime to terminate it
+	// once the goroutine exits instead of re-using it in the wrong namespace
+	if err := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("failed to switch back from network namespace %s: %w", path, err)
+	}
+	runtime.UnlockOSThread()
+
+	return fnErr
+}
@@ -0,0 +1,53 @@
+package netns
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "runtime.sock")
+	listener, err := net.Listen("unix", socket)
+	require.Nil(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/web/json":
+			_, _ = w.Write([]byte(`{"Id":"4f66ad9a0b2e","State":{"Running":true,"Pid":4242}}`))
+		case "/containers/stopped/json":
+			_, _ = w.Write([]byte(`{"Id":"8a1c3b2d4e5f","State":{"Running":false,"Pid":0}}`))
+		default:
+			http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+		}
+	}))
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	ctx := context.Background()
+	runtime := "unix://" + socket
+
+	path, err := Resolve(ctx, &config.NetnsConfig{Path: "/var/run/netns/blue"})
+	require.Nil(t, err)
+	require.Equal(t, "/var/run/netns/blue", path)
+
+	path, err = Resolve(ctx, &config.NetnsConfig{Container: "web", Runtime: runtime})
+	require.Nil(t, err)
+	require.Equal(t, "/proc/4242/ns/net", path)
+
+	_, err = Resolve(ctx, &config.NetnsConfig{Container: "stopped", Runtime: runtime})
+	require.ErrorIs(t, err, ErrContainerNotRunning)
+
+	_, err = Resolve(ctx, &config.NetnsConfig{Container: "missing", Runtime: runtime})
+	require.ErrorContains(t, err, "404")
+
+	_, err = Resolve(ctx, &config.NetnsConfig{Container: "web", Runtime: "unix://" + filepath.Join(t.TempDir(), "missing.sock")})
+	require.Error(t, err)
+}
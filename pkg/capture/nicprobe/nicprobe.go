@@ -0,0 +1,128 @@
+// Package nicprobe queries the properties of the NIC backing an interface that affect flow accounting
+// (offloads, MTU, link speed, driver) and derives configuration hints from them. Offloads like GRO / LRO merge
+// packets into super-packets before they reach the capture, hence the packet counts of the affected
+// flows are underestimated (while their byte counts remain accurate)
+package nicprobe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// Names of the offloads reported in the capabilities of an interface
+const (
+	OffloadGRO    = "gro"     // Generic receive offload
+	OffloadLRO    = "lro"     // Large receive offload
+	OffloadTSO    = "tso"     // TCP segmentation offload
+	OffloadGSO    = "gso"     // Generic segmentation offload
+	OffloadRxVLAN = "rx_vlan" // Stripping of VLAN tags from received packets
+)
+
+const (
+	// HighSpeedThreshold denotes the link speed (in Mbit/s) from which on the ring buffer size of a
+	// capture is checked against MinHighSpeedRingBufferSize
+	HighSpeedThreshold = 10000
+
+	// MinHighSpeedRingBufferSize denotes the minimum recommended ring buffer size (in bytes) of a
+	// capture on an interface with a link speed of at least HighSpeedThreshold
+	MinHighSpeedRingBufferSize = 16 * 1024 * 1024
+)
+
+var (
+	// ErrNotSupported signifies that probing interface capabilities is not supported on this platform
+	ErrNotSupported = errors.New("probing interface capabilities is not supported on this platform")
+
+	// ErrUnknownIface signifies that the interface to probe does not exist
+	ErrUnknownIface = errors.New("unknown interface")
+)
+
+// Probe queries the capabilities of an interface in the current network namespace. Offloads not
+// supported by the driver (and an unknown link speed) are omitted from the result
+func Probe(iface string) (capturetypes.IfaceCapabilities, error) {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return capturetypes.IfaceCapabilities{}, fmt.Errorf("%w %s: %w", ErrUnknownIface, iface, err)
+	}
+
+	caps, err := probe(iface)
+	if err != nil {
+		return capturetypes.IfaceCapabilities{}, err
+	}
+	caps.MTU = link.MTU
+
+	return caps, nil
+}
+
+// Link queries the properties of the link backing an interface in the current network namespace.
+// Properties not reported by the driver (or not available on this platform) are omitted from the result
+func Link(iface string) (types.LinkInfo, error) {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return types.LinkInfo{}, fmt.Errorf("%w %s: %w", ErrUnknownIface, iface, err)
+	}
+
+	info := types.LinkInfo{
+		MTU: link.MTU,
+		MAC: link.HardwareAddr.String(),
+	}
+	info.Speed, info.Driver = linkDetails(iface)
+
+	return info, nil
+}
+
+// Hints derives the configuration hints for a capture with the provided configuration from the
+// capabilities of the interface it is attached to (device denoting the name of the latter)
+func Hints(device string, caps capturetypes.IfaceCapabilities, cfg config.CaptureConfig) (hints []capturetypes.ConfigHint) {
+	if receive := enabled(caps.Offloads, OffloadGRO, OffloadLRO); len(receive) > 0 {
+		hints = append(hints, capturetypes.ConfigHint{
+			Message:     fmt.Sprintf("receive offloading (%s) merges inbound packets into super-packets, causing packet counts to be underestimated", strings.Join(receive, ", ")),
+			Remediation: remediation(device, receive),
+		})
+	}
+	if segmentation := enabled(caps.Offloads, OffloadTSO, OffloadGSO); len(segmentation) > 0 {
+		hints = append(hints, capturetypes.ConfigHint{
+			Message:     fmt.Sprintf("segmentation offloading (%s) passes outbound super-packets to the capture prior to segmentation, causing packet counts to be underestimated", strings.Join(segmentation, ", ")),
+			Remediation: remediation(device, segmentation),
+		})
+	}
+
+	if caps.Speed >= HighSpeedThreshold && !cfg.IsEBPF() && cfg.RingBuffer != nil {
+		if size := cfg.RingBuffer.BlockSize * cfg.RingBuffer.NumBlocks; size < MinHighSpeedRingBufferSize {
+			hints = append(hints, capturetypes.ConfigHint{
+				Message: fmt.Sprintf("ring buffer size of %d MiB is likely insufficient for a link speed of %d Mbit/s, causing packet drops during traffic bursts",
+					size/(1024*1024), caps.Speed),
+				Remediation: fmt.Sprintf("increase ring_buffer.num_blocks / ring_buffer.block_size to a total of at least %d MiB", MinHighSpeedRingBufferSize/(1024*1024)),
+			})
+		}
+	}
+
+	return
+}
+
+// enabled returns the subset of the provided offloads that are enabled
+func enabled(offloads map[string]bool, names ...string) (res []string) {
+	for _, name := range names {
+		if offloads[name] {
+			res = append(res, name)
+		}
+	}
+	return
+}
+
+func remediation(device string, offloads []string) string {
+	var b strings.Builder
+	b.WriteString("ethtool -K ")
+	b.WriteString(device)
+	for _, offload := range offloads {
+		b.WriteString(" ")
+		b.WriteString(offload)
+		b.WriteString(" off")
+	}
+	return b.String()
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package nicprobe
+
+import "github.com/els0r/goProbe/pkg/capture/capturetypes"
+
+func probe(_ string) (capturetypes.IfaceCapabilities, error) {
+	return capturetypes.IfaceCapabilities{}, ErrNotSupported
+}
+
+func linkDetails(_ string) (speed uint32, driver string) {
+	return 0, ""
+}
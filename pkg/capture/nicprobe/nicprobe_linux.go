@@ -0,0 +1,161 @@
+//go:build linux
+// +build linux
+
+package nicprobe
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"golang.org/x/sys/unix"
+)
+
+// Flags of the ETHTOOL_GFLAGS request (c.f. ETH_FLAG_* in linux/ethtool.h)
+const (
+	ethFlagRxVLAN = 1 << 8
+	ethFlagLRO    = 1 << 15
+)
+
+// speedUnknown denotes the link speed reported by drivers unable to determine it (SPEED_UNKNOWN)
+const speedUnknown = 0xffffffff
+
+// ifreq denotes the layout of struct ifreq carrying a pointer to an ethtool command
+type ifreq struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolValue denotes struct ethtool_value
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+// ethtoolCmd denotes the (legacy) struct ethtool_cmd, which is still supported by all drivers
+type ethtoolCmd struct {
+	cmd           uint32
+	supported     uint32
+	advertising   uint32
+	speed         uint16
+	duplex        uint8
+	port          uint8
+	phyAddress    uint8
+	transceiver   uint8
+	autoneg       uint8
+	mdioSupport   uint8
+	maxtxpkt      uint32
+	maxrxpkt      uint32
+	speedHi       uint16
+	ethTpMdix     uint8
+	ethTpMdixCtrl uint8
+	lpAdvertising uint32
+	reserved      [2]uint32
+}
+
+func probe(iface string) (capturetypes.IfaceCapabilities, error) {
+	if len(iface) >= unix.IFNAMSIZ {
+		return capturetypes.IfaceCapabilities{}, fmt.Errorf("invalid interface name %s", iface)
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return capturetypes.IfaceCapabilities{}, fmt.Errorf("failed to open socket for ethtool requests: %w", err)
+	}
+	defer unix.Close(fd)
+
+	caps := capturetypes.IfaceCapabilities{
+		Offloads: make(map[string]bool),
+	}
+
+	// Offloads which are not supported by the driver (or the kernel) are skipped
+	for _, offload := range []struct {
+		name string
+		cmd  uint32
+	}{
+		{OffloadGRO, unix.ETHTOOL_GGRO},
+		{OffloadTSO, unix.ETHTOOL_GTSO},
+		{OffloadGSO, unix.ETHTOOL_GGSO},
+	} {
+		value := ethtoolValue{cmd: offload.cmd}
+		if err := ethtool(fd, iface, unsafe.Pointer(&value)); err != nil { // #nosec G103
+			if isUnsupported(err) {
+				continue
+			}
+			return capturetypes.IfaceCapabilities{}, fmt.Errorf("failed to query %s state of interface %s: %w", offload.name, iface, err)
+		}
+		caps.Offloads[offload.name] = value.data != 0
+	}
+
+	flags := ethtoolValue{cmd: unix.ETHTOOL_GFLAGS}
+	if err := ethtool(fd, iface, unsafe.Pointer(&flags)); err == nil { // #nosec G103
+		caps.Offloads[OffloadLRO] = flags.data&ethFlagLRO != 0
+		caps.Offloads[OffloadRxVLAN] = flags.data&ethFlagRxVLAN != 0
+	} else if !isUnsupported(err) {
+		return capturetypes.IfaceCapabilities{}, fmt.Errorf("failed to query offload flags of interface %s: %w", iface, err)
+	}
+
+	if caps.Speed, err = linkSpeed(fd, iface); err != nil {
+		return capturetypes.IfaceCapabilities{}, err
+	}
+
+	if len(caps.Offloads) == 0 {
+		caps.Offloads = nil
+	}
+
+	return caps, nil
+}
+
+// linkSpeed queries the link speed (in Mbit/s) of an interface. The link speed is unknown (zero)
+// for virtual interfaces (or links that are down)
+func linkSpeed(fd int, iface string) (uint32, error) {
+	settings := ethtoolCmd{cmd: unix.ETHTOOL_GSET}
+	if err := ethtool(fd, iface, unsafe.Pointer(&settings)); err != nil { // #nosec G103
+		if isUnsupported(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to query link settings of interface %s: %w", iface, err)
+	}
+	if speed := uint32(settings.speedHi)<<16 | uint32(settings.speed); speed != speedUnknown && speed != 0xffff {
+		return speed, nil
+	}
+	return 0, nil
+}
+
+// linkDetails queries the link speed and the driver of an interface, omitting any property that
+// cannot be determined
+func linkDetails(iface string) (speed uint32, driver string) {
+	if len(iface) >= unix.IFNAMSIZ {
+		return 0, ""
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return 0, ""
+	}
+	defer unix.Close(fd)
+
+	speed, _ = linkSpeed(fd, iface)
+	drvInfo := unix.EthtoolDrvinfo{Cmd: unix.ETHTOOL_GDRVINFO}
+	if err := ethtool(fd, iface, unsafe.Pointer(&drvInfo)); err == nil { // #nosec G103
+		driver = unix.ByteSliceToString(drvInfo.Driver[:])
+	}
+
+	return speed, driver
+}
+
+func ethtool(fd int, iface string, data unsafe.Pointer) error {
+	var req ifreq
+	copy(req.name[:], iface)
+	req.data = data
+
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&req))); errno != 0 { // #nosec G103
+		return errno
+	}
+	return nil
+}
+
+func isUnsupported(err error) bool {
+	return errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL)
+}
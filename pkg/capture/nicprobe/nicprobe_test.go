@@ -0,0 +1,80 @@
+package nicprobe
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHints(t *testing.T) {
+	defaultCfg := config.CaptureConfig{
+		RingBuffer: &config.RingBufferConfig{
+			BlockSize: config.DefaultRingBufferBlockSize,
+			NumBlocks: config.DefaultRingBufferNumBlocks,
+		},
+	}
+
+	for _, c := range []struct {
+		name                 string
+		caps                 capturetypes.IfaceCapabilities
+		cfg                  config.CaptureConfig
+		expectedRemediations []string
+	}{
+		{"no offloads", capturetypes.IfaceCapabilities{MTU: 1500, Speed: 1000}, defaultCfg, nil},
+		{"disabled offloads", capturetypes.IfaceCapabilities{MTU: 1500, Offloads: map[string]bool{
+			OffloadGRO: false, OffloadLRO: false, OffloadTSO: false, OffloadRxVLAN: true,
+		}}, defaultCfg, nil},
+		{"receive offloads", capturetypes.IfaceCapabilities{MTU: 1500, Offloads: map[string]bool{
+			OffloadGRO: true, OffloadLRO: true, OffloadTSO: false,
+		}}, defaultCfg, []string{"ethtool -K eth0 gro off lro off"}},
+		{"all offloads", capturetypes.IfaceCapabilities{MTU: 9000, Offloads: map[string]bool{
+			OffloadGRO: true, OffloadLRO: false, OffloadTSO: true, OffloadGSO: true,
+		}}, defaultCfg, []string{"ethtool -K eth0 gro off", "ethtool -K eth0 tso off gso off"}},
+		{"high speed", capturetypes.IfaceCapabilities{MTU: 1500, Speed: 25000}, defaultCfg, []string{
+			"increase ring_buffer.num_blocks / ring_buffer.block_size to a total of at least 16 MiB",
+		}},
+		{"high speed large ring buffer", capturetypes.IfaceCapabilities{MTU: 1500, Speed: 25000}, config.CaptureConfig{
+			RingBuffer: &config.RingBufferConfig{
+				BlockSize: config.DefaultRingBufferBlockSize,
+				NumBlocks: 16,
+			},
+		}, nil},
+		{"high speed ebpf", capturetypes.IfaceCapabilities{MTU: 1500, Speed: 25000}, config.CaptureConfig{
+			Driver: config.CaptureDriverEBPF,
+		}, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var remediations []string
+			for _, hint := range Hints("eth0", c.caps, c.cfg) {
+				require.NotEmpty(t, hint.Message)
+				remediations = append(remediations, hint.Remediation)
+			}
+			require.Equal(t, c.expectedRemediations, remediations)
+		})
+	}
+}
+
+func TestProbeLoopback(t *testing.T) {
+	caps, err := Probe("lo")
+	if errors.Is(err, ErrNotSupported) {
+		t.Skip(err)
+	}
+	require.Nil(t, err)
+	require.Greater(t, caps.MTU, 0)
+
+	_, err = Probe("doesnotexist0")
+	require.ErrorIs(t, err, ErrUnknownIface)
+}
+
+func TestLinkLoopback(t *testing.T) {
+	link, err := Link("lo")
+	require.Nil(t, err)
+	require.Greater(t, link.MTU, 0)
+	require.Zero(t, link.Speed)
+
+	_, err = Link("doesnotexist0")
+	require.ErrorIs(t, err, ErrUnknownIface)
+}
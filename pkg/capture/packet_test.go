@@ -128,6 +128,38 @@ func TestClassification(t *testing.T) {
 	}
 }
 
+func TestICMPGrouping(t *testing.T) {
+	genICMP := func(sip, dip string, icmpType, icmpCode byte) capture.Packet {
+		p := testParams{sip: sip, dip: dip, proto: capturetypes.ICMP, AuxInfo: icmpType}
+		pkt := p.genDummyPacket(0)
+		pkt.IPLayer()[ipv4.HeaderLen+1] = icmpCode
+		return pkt
+	}
+
+	flowLog := NewFlowLog()
+	for _, pkt := range []capture.Packet{
+		genICMP("10.0.0.1", "10.0.0.2", 8, 0),   // echo request
+		genICMP("10.0.0.2", "10.0.0.1", 0, 0),   // echo reply
+		genICMP("10.0.0.1", "10.0.0.3", 8, 0),   // echo request to another host
+		genICMP("10.0.0.254", "10.0.0.1", 3, 3), // port unreachable
+		genICMP("10.0.0.254", "10.0.0.1", 3, 1), // host unreachable
+		genICMP("10.0.0.254", "10.0.0.1", 3, 3), // port unreachable
+	} {
+		epHash, isIPv4, auxInfo, errno := ParsePacket(pkt.IPLayer())
+		require.Equal(t, capturetypes.ErrnoOK, errno)
+		require.Equal(t, capturetypes.ErrnoOK, flowLog.Add(epHash, capture.PacketOutgoing, 64, isIPv4, auxInfo, errno))
+	}
+
+	// echo request and reply are merged into the same flow, the unreachable messages are
+	// grouped by code
+	require.Equal(t, 4, flowLog.Len())
+	dports := make(map[uint16]int)
+	for _, flow := range flowLog.Flows() {
+		dports[binary.BigEndian.Uint16(flow.epHash[32:34])]++
+	}
+	require.Equal(t, map[uint16]int{8 << 8: 2, 3<<8 | 3: 1, 3<<8 | 1: 1}, dports)
+}
+
 func BenchmarkPopulation(b *testing.B) {
 	for _, params := range testCases {
 		b.Run(params.String(), func(b *testing.B) {
@@ -187,8 +219,15 @@ func (p testParams) genEPHash() (res capturetypes.EPHash, IsIPv4 bool) {
 		copy(res[16:], tmpDst[:])
 	}
 
-	binary.BigEndian.PutUint16(res[32:34], p.dport)
-	binary.BigEndian.PutUint16(res[34:36], p.sport)
+	if p.proto == capturetypes.ICMP || p.proto == capturetypes.ICMPv6 {
+
+		// ICMP messages are grouped by (request) type and code, stored in both port fields
+		res[32] = capturetypes.ICMPGroupType(p.proto, p.AuxInfo)
+		res[34] = res[32]
+	} else {
+		binary.BigEndian.PutUint16(res[32:34], p.dport)
+		binary.BigEndian.PutUint16(res[34:36], p.sport)
+	}
 	res[36] = p.proto
 
 	return
@@ -205,6 +244,9 @@ func (p testParams) genDummyPacket(pktType capture.PacketType) capture.Packet {
 		copy(data[16:20], EPHash[16:20])
 		copy(data[ipv4.HeaderLen:ipv4.HeaderLen+2], EPHash[34:36])
 		copy(data[ipv4.HeaderLen+2:ipv4.HeaderLen+4], EPHash[32:34])
+		if p.proto == capturetypes.ICMP {
+			data[ipv4.HeaderLen] = p.AuxInfo
+		}
 
 	} else {
 		data[0] = (6 << 4)
@@ -213,6 +255,9 @@ func (p testParams) genDummyPacket(pktType capture.PacketType) capture.Packet {
 		copy(data[24:40], EPHash[16:32])
 		copy(data[ipv6.HeaderLen:ipv6.HeaderLen+2], EPHash[34:36])
 		copy(data[ipv6.HeaderLen+2:ipv6.HeaderLen+4], EPHash[32:34])
+		if p.proto == capturetypes.ICMPv6 {
+			data[ipv6.HeaderLen] = p.AuxInfo
+		}
 	}
 
 	return capture.NewIPPacket(nil, data, pktType, 128, 0)
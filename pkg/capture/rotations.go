@@ -0,0 +1,144 @@
+package capture
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+)
+
+// ErrRotationHistoryDisabled signifies that the rotation history is not configured
+var ErrRotationHistoryDisabled = errors.New("rotation history is disabled")
+
+type retainedRotation struct {
+	meta  capturetypes.Rotation
+	flows *hashmap.AggFlowMap
+	size  int64
+}
+
+// rotationHistory retains the most recent rotations of each interface in memory. Since the flow
+// map of each rotation is allocated anew (and not modified by the writeout), it is referenced
+// as is and only converted upon retrieval
+type rotationHistory struct {
+	retention   int
+	maxMemory   int64
+	summaryOnly bool
+
+	// rotations of all interfaces (in chronological order) and the memory occupied by their flows
+	rotations []retainedRotation
+	size      int64
+
+	sync.RWMutex
+}
+
+func newRotationHistory(cfg *config.RotationHistoryConfig) *rotationHistory {
+	r := &rotationHistory{
+		retention:   cfg.Retention,
+		maxMemory:   cfg.MaxMemory,
+		summaryOnly: cfg.SummaryOnly,
+	}
+	if r.retention == 0 {
+		r.retention = config.DefaultRotationHistoryRetention
+	}
+	if r.maxMemory == 0 {
+		r.maxMemory = config.DefaultRotationHistoryMaxMemory
+	}
+	return r
+}
+
+// add retains a rotation, discarding the oldest ones exceeding the retention / memory cap
+func (r *rotationHistory) add(timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) {
+	if r == nil {
+		return
+	}
+
+	rotation := retainedRotation{
+		meta: capturetypes.Rotation{
+			Timestamp: timestamp,
+			Iface:     taggedMap.Iface,
+			Stats:     taggedMap.Stats,
+		},
+	}
+	if taggedMap.Map != nil {
+		rotation.meta.NumFlows = taggedMap.Map.Len()
+		for it := taggedMap.Map.Iter(); it.Next(); {
+			rotation.meta.Totals = rotation.meta.Totals.Add(it.Val())
+		}
+		if !r.summaryOnly {
+			rotation.flows = taggedMap.Map
+			rotation.size = int64(taggedMap.Map.Size())
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.rotations = append(r.rotations, rotation)
+	r.size += rotation.size
+
+	// discard the oldest rotation of the interface if its retention is exceeded
+	var n int
+	for _, retained := range r.rotations {
+		if retained.meta.Iface == rotation.meta.Iface {
+			n++
+		}
+	}
+	if n > r.retention {
+		r.remove(slices.IndexFunc(r.rotations, func(retained retainedRotation) bool {
+			return retained.meta.Iface == rotation.meta.Iface
+		}))
+	}
+
+	// discard the oldest rotations (of any interface) until the memory cap is no longer exceeded
+	for r.size > r.maxMemory && len(r.rotations) > 0 {
+		r.remove(0)
+	}
+}
+
+func (r *rotationHistory) remove(i int) {
+	r.size -= r.rotations[i].size
+	r.rotations = slices.Delete(r.rotations, i, i+1)
+}
+
+// last returns the (up to) n most recent rotations of each of the provided interfaces (or of all
+// interfaces if none are provided) in chronological order. If n is zero, all retained rotations
+// are returned
+func (r *rotationHistory) last(n int, ifaces ...string) ([]capturetypes.Rotation, error) {
+	if r == nil {
+		return nil, ErrRotationHistoryDisabled
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	var (
+		selected []retainedRotation
+		perIface = make(map[string]int)
+	)
+	for i := len(r.rotations) - 1; i >= 0; i-- {
+		retained := r.rotations[i]
+		if len(ifaces) > 0 && !slices.Contains(ifaces, retained.meta.Iface) {
+			continue
+		}
+		if n > 0 && perIface[retained.meta.Iface] >= n {
+			continue
+		}
+		perIface[retained.meta.Iface]++
+		selected = append(selected, retained)
+	}
+	slices.Reverse(selected)
+
+	res := make([]capturetypes.Rotation, 0, len(selected))
+	for _, retained := range selected {
+		rotation := retained.meta
+		if retained.flows != nil {
+			rotation.Flows = capturetypes.RotatedFlows(retained.flows)
+		}
+		res = append(res, rotation)
+	}
+	return res, nil
+}
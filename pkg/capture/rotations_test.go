@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
+)
+
+func testRotation(iface string, nFlows int) capturetypes.TaggedAggFlowMap {
+	m := hashmap.NewAggFlowMap()
+	for i := 0; i < nFlows; i++ {
+		m.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, byte(i)}, [4]byte{10, 0, 1, 1}, []byte{0, 80}, 6),
+			types.Counters{BytesRcvd: 100, BytesSent: 10, PacketsRcvd: 2, PacketsSent: 1})
+	}
+	return capturetypes.TaggedAggFlowMap{
+		Map:   m,
+		Iface: iface,
+	}
+}
+
+func TestRotationHistory(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("disabled", func(t *testing.T) {
+		var r *rotationHistory
+		r.add(start, testRotation("eth0", 1))
+		_, err := r.last(1)
+		require.ErrorIs(t, err, ErrRotationHistoryDisabled)
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		r := newRotationHistory(&config.RotationHistoryConfig{Retention: 3})
+		for i := 0; i < 5; i++ {
+			ts := start.Add(time.Duration(i) * 5 * time.Minute)
+			r.add(ts, testRotation("eth0", i+1))
+			r.add(ts, testRotation("eth1", 1))
+		}
+
+		rotations, err := r.last(0, "eth0")
+		require.Nil(t, err)
+		require.Len(t, rotations, 3)
+		for i, rotation := range rotations {
+			require.Equal(t, start.Add(time.Duration(i+2)*5*time.Minute), rotation.Timestamp)
+			require.Equal(t, i+3, rotation.NumFlows)
+			require.Len(t, rotation.Flows, i+3)
+			require.Equal(t, uint64(100*(i+3)), rotation.Totals.BytesRcvd)
+		}
+
+		// the most recent rotations of all interfaces, in chronological order
+		rotations, err = r.last(2)
+		require.Nil(t, err)
+		require.Len(t, rotations, 4)
+		require.Equal(t, start.Add(15*time.Minute), rotations[0].Timestamp)
+		require.Equal(t, start.Add(20*time.Minute), rotations[3].Timestamp)
+	})
+
+	t.Run("memory cap", func(t *testing.T) {
+		size := int64(testRotation("eth0", 1).Map.Size())
+		r := newRotationHistory(&config.RotationHistoryConfig{MaxMemory: 2 * size})
+		for i := 0; i < 3; i++ {
+			r.add(start.Add(time.Duration(i)*5*time.Minute), testRotation("eth0", 1))
+		}
+
+		rotations, err := r.last(0)
+		require.Nil(t, err)
+		require.Len(t, rotations, 2)
+		require.Equal(t, start.Add(5*time.Minute), rotations[0].Timestamp)
+		require.Equal(t, 2*size, r.size)
+	})
+
+	t.Run("summary only", func(t *testing.T) {
+		r := newRotationHistory(&config.RotationHistoryConfig{SummaryOnly: true})
+		r.add(start, testRotation("eth0", 2))
+
+		rotations, err := r.last(1, "eth0")
+		require.Nil(t, err)
+		require.Len(t, rotations, 1)
+		require.Equal(t, 2, rotations[0].NumFlows)
+		require.Empty(t, rotations[0].Flows)
+		require.Zero(t, r.size)
+	})
+}
@@ -0,0 +1,249 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/sflow"
+	"github.com/els0r/telemetry/logging"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// sflowMaxDatagramSize denotes the size of the receive buffer of the sFlow collector (the maximum
+// size of a UDP datagram)
+const sflowMaxDatagramSize = 65535
+
+// sflowCollector receives sFlow datagrams exported by switches / routers and converts the sampled
+// packets of all flow samples into flows of the configured pseudo-interfaces. Their flow logs are
+// rotated and written to the database alongside the ones of the captured interfaces (c.f. rotate())
+type sflowCollector struct {
+	listen string
+	conn   net.PacketConn
+
+	ifaces map[string]*sflowIface
+
+	sync.Mutex
+}
+
+// sflowIface denotes a pseudo-interface fed by the sFlow collector. Its statistics count the sampled
+// packets (whereas the flow counters are scaled by the sampling rate)
+type sflowIface struct {
+	agent   netip.Addr
+	ifIndex uint32
+
+	flowLog   *FlowLog
+	startedAt time.Time
+	stats     capturetypes.CaptureStats
+}
+
+func newSFlowCollector(cfg *config.SFlowConfig) *sflowCollector {
+	s := &sflowCollector{
+		listen: cfg.Listen,
+		ifaces: make(map[string]*sflowIface, len(cfg.Ifaces)),
+	}
+	if s.listen == "" {
+		s.listen = config.DefaultSFlowListen
+	}
+	for iface, ifaceCfg := range cfg.Ifaces {
+		agent, _ := netip.ParseAddr(ifaceCfg.Agent) // validated as part of the configuration
+		s.ifaces[iface] = &sflowIface{
+			agent:   agent,
+			ifIndex: ifaceCfg.IfIndex,
+			flowLog: NewFlowLog(),
+		}
+	}
+	return s
+}
+
+// WithSFlow enables the sFlow collector, writing the flow samples received to the configured
+// pseudo-interfaces
+func WithSFlow(cfg *config.SFlowConfig) ManagerOption {
+	return func(cm *Manager) {
+		cm.sflow = newSFlowCollector(cfg)
+	}
+}
+
+// open starts listening for sFlow datagrams
+func (s *sflowCollector) open(t time.Time) (err error) {
+	if s.conn, err = net.ListenPacket("udp", s.listen); err != nil {
+		return err
+	}
+
+	s.Lock()
+	for _, si := range s.ifaces {
+		si.startedAt = t
+	}
+	s.Unlock()
+
+	return nil
+}
+
+// run receives and processes sFlow datagrams until the context is cancelled
+func (s *sflowCollector) run(ctx context.Context) {
+	logger := logging.FromContext(ctx).With("listen", s.conn.LocalAddr().String())
+	logger.Info("starting sFlow collector")
+
+	go func() {
+		<-ctx.Done()
+		_ = s.conn.Close()
+	}()
+
+	buf := make([]byte, sflowMaxDatagramSize)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				logger.Info("stopping sFlow collector")
+				return
+			}
+			logger.Errorf("failed to receive sFlow datagram: %s", err)
+			continue
+		}
+
+		dgram, err := sflow.Decode(buf[:n])
+		if err != nil {
+			promSFlowDatagrams.WithLabelValues("invalid").Inc()
+			logger.Debugf("failed to decode sFlow datagram: %s", err)
+			continue
+		}
+		promSFlowDatagrams.WithLabelValues("decoded").Inc()
+
+		s.add(dgram)
+	}
+}
+
+// add attributes all flow samples of a datagram to the pseudo-interfaces of its agent
+func (s *sflowCollector) add(dgram *sflow.Datagram) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, si := range s.ifaces {
+		if si.agent != dgram.Agent {
+			continue
+		}
+		for _, sample := range dgram.Samples {
+			si.add(sample)
+		}
+	}
+}
+
+// rotate rotates the flow logs of all (or a set of) pseudo-interfaces, returning their flows and
+// statistics of the interval since the previous rotation (ordered by interface name)
+func (s *sflowCollector) rotate(ifaces ...string) (res []capturetypes.TaggedAggFlowMap) {
+	s.Lock()
+	defer s.Unlock()
+
+	for iface, si := range s.ifaces {
+		if len(ifaces) > 0 && !slices.Contains(ifaces, iface) {
+			continue
+		}
+
+		agg, _, _ := si.flowLog.Rotate()
+		stats := si.stats
+		stats.StartedAt = si.startedAt
+		stats.ReceivedTotal += stats.Received
+		stats.ProcessedTotal += stats.Processed
+
+		si.stats = capturetypes.CaptureStats{
+			ReceivedTotal:  stats.ReceivedTotal,
+			ProcessedTotal: stats.ProcessedTotal,
+		}
+
+		res = append(res, capturetypes.TaggedAggFlowMap{
+			Map:   agg,
+			Stats: stats,
+			Iface: iface,
+		})
+	}
+	slices.SortFunc(res, func(a, b capturetypes.TaggedAggFlowMap) int {
+		return strings.Compare(a.Iface, b.Iface)
+	})
+
+	return res
+}
+
+// add adds the sampled packets of a flow sample (if matching the interface index of the pseudo-interface)
+// to its flow log, scaling their counters by the sampling rate. Packets are considered received unless
+// the pseudo-interface is restricted to an interface index matching the output interface of the sample
+func (si *sflowIface) add(sample sflow.FlowSample) {
+	rcvd := si.ifIndex == 0 || sample.Input == si.ifIndex
+	if !rcvd && sample.Output != si.ifIndex {
+		return
+	}
+
+	rate := uint64(max(sample.SamplingRate, 1))
+	for _, header := range sample.Headers {
+
+		// Non-IP packets (e.g. ARP) are skipped (as they would be by a capture)
+		ipLayer, totalLen := header.IPLayer()
+		if ipLayer == nil {
+			continue
+		}
+		si.stats.Received++
+
+		epHash, isIPv4, auxInfo, errno := parseSampledPacket(ipLayer)
+		if errno != capturetypes.ErrnoOK {
+			if errno.ParsingFailed() {
+				si.stats.ParsingErrors[errno]++
+			}
+			continue
+		}
+		si.stats.Processed++
+
+		summary := capturetypes.FlowSummary{
+			EPHash:  epHash,
+			IsIPv4:  isIPv4,
+			AuxInfo: auxInfo,
+		}
+		if rcvd {
+			summary.PacketsRcvd, summary.BytesRcvd = rate, uint64(totalLen)*rate
+		} else {
+			summary.PacketsSent, summary.BytesSent = rate, uint64(totalLen)*rate
+		}
+		si.flowLog.AddSummary(summary)
+	}
+}
+
+// parseSampledPacket is the equivalent of ParsePacket() for the IP layer of a packet sampled by an sFlow
+// agent, which (as opposed to a captured packet) isn't guaranteed to span the headers required for parsing
+func parseSampledPacket(ipLayer []byte) (epHash capturetypes.EPHash, isIPv4 bool, auxInfo byte, errno capturetypes.ParsingErrno) {
+	minLen := ipv4.HeaderLen + 4
+	if ipLayer[0]>>4 == ipLayerTypeV6 {
+		minLen = ipv6.HeaderLen + 4
+	}
+	if len(ipLayer) < minLen {
+		errno = capturetypes.ErrnoPacketTruncated
+		return
+	}
+
+	return ParsePacket(ipLayer)
+}
+
+// rotateSFlow rotates the pseudo-interfaces of the sFlow collector (if enabled), putting their flows on the
+// writeoutChan like the ones of any captured interface
+func (cm *Manager) rotateSFlow(timestamp time.Time, writeoutChan chan<- capturetypes.TaggedAggFlowMap, ifaces ...string) (rotated []capturetypes.WriteoutResult) {
+	if cm.sflow == nil {
+		return nil
+	}
+
+	for _, taggedMap := range cm.sflow.rotate(ifaces...) {
+		cm.rotations.add(timestamp, taggedMap)
+		writeoutChan <- taggedMap
+
+		res := capturetypes.WriteoutResult{Iface: taggedMap.Iface}
+		if taggedMap.Map != nil {
+			res.NumFlows = taggedMap.Map.Len()
+		}
+		rotated = append(rotated, res)
+	}
+	return rotated
+}
@@ -0,0 +1,291 @@
+// Package sflow provides a decoder for sFlow version 5 datagrams (c.f. https://sflow.org/sflow_version_5.txt).
+// Only the flow samples (and their raw packet header records) are extracted, all other samples / records
+// (e.g. counter samples) are skipped
+package sflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Version denotes the (only) supported version of the sFlow protocol
+const Version = 5
+
+// Sample / record formats (of the standard enterprise) extracted from a datagram
+const (
+	formatFlowSample         = 1
+	formatExpandedFlowSample = 3
+	formatRawPacketHeader    = 1
+)
+
+// Header protocols of a raw packet header record
+const (
+	HeaderProtocolEthernet = 1
+	HeaderProtocolIPv4     = 11
+	HeaderProtocolIPv6     = 12
+)
+
+// Address types of the agent address
+const (
+	addressTypeIPv4 = 1
+	addressTypeIPv6 = 2
+)
+
+// EtherTypes traversed when extracting the IP layer from an Ethernet header
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86DD
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88A8
+	etherHeaderLen = 14
+	vlanTagLen     = 4
+)
+
+// interfaceValueMask masks the value of an interface field of a compact flow sample (the upper two bits
+// denote its format)
+const interfaceValueMask = 0x3FFFFFFF
+
+var (
+	// ErrTruncated denotes that a datagram ended prematurely
+	ErrTruncated = errors.New("truncated sFlow datagram")
+
+	// ErrUnsupportedVersion denotes that a datagram uses a version other than sFlow v5
+	ErrUnsupportedVersion = errors.New("unsupported sFlow version")
+
+	// ErrInvalidAgentAddress denotes that the agent address of a datagram is of an unknown type
+	ErrInvalidAgentAddress = errors.New("invalid sFlow agent address")
+)
+
+// Datagram denotes a decoded sFlow datagram
+type Datagram struct {
+	Agent      netip.Addr // Agent: address of the agent that exported the datagram
+	SubAgentID uint32     // SubAgentID: identifies the sub-agent (in case an agent runs multiple ones)
+	Sequence   uint32     // Sequence: sequence number of the datagram (per sub-agent)
+	Uptime     uint32     // Uptime: time since the agent was started (in milliseconds)
+
+	Samples []FlowSample // Samples: all flow samples contained in the datagram
+}
+
+// FlowSample denotes a (compact or expanded) flow sample
+type FlowSample struct {
+	Sequence     uint32 // Sequence: sequence number of the sample (per data source)
+	SourceID     uint32 // SourceID: index of the data source (e.g. the ifIndex of the sampled interface)
+	SamplingRate uint32 // SamplingRate: on average, one out of SamplingRate packets is sampled
+	SamplePool   uint32 // SamplePool: total number of packets that could have been sampled
+	Drops        uint32 // Drops: number of samples dropped by the agent due to lack of resources
+
+	// Input / Output denote the ifIndex of the interface the packet was received / sent on (zero if
+	// unknown or not a single interface, e.g. for discarded or multicast packets)
+	Input, Output uint32
+
+	Headers []PacketHeader // Headers: all raw packet header records of the sample
+}
+
+// PacketHeader denotes a raw packet header record, i.e. the (truncated) header of a sampled packet
+type PacketHeader struct {
+	Protocol    uint32 // Protocol: protocol of the header (c.f. HeaderProtocolEthernet et al.)
+	FrameLength uint32 // FrameLength: original length of the sampled packet
+	Stripped    uint32 // Stripped: number of bytes removed from the packet before sampling (e.g. the FCS)
+	Header      []byte // Header: header bytes of the sampled packet (referencing the decoded datagram)
+}
+
+// IPLayer returns the IP layer of the sampled packet (which may be truncated) and the length of the packet as
+// stated in its IP header (matching the packet size tracked for captured packets, falling back to the length
+// of the original frame if it cannot be determined). If the header protocol is not supported or the header
+// doesn't carry an IP packet, nil is returned
+func (h PacketHeader) IPLayer() (ipLayer []byte, totalLen uint32) {
+	switch h.Protocol {
+	case HeaderProtocolIPv4, HeaderProtocolIPv6:
+		ipLayer = h.Header
+	case HeaderProtocolEthernet:
+		if len(h.Header) < etherHeaderLen {
+			return nil, 0
+		}
+
+		// Skip any (stacked) VLAN tags preceding the actual EtherType
+		offset := etherHeaderLen - 2
+		etherType := binary.BigEndian.Uint16(h.Header[offset:])
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			offset += vlanTagLen
+			if len(h.Header) < offset+2 {
+				return nil, 0
+			}
+			etherType = binary.BigEndian.Uint16(h.Header[offset:])
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return nil, 0
+		}
+		ipLayer = h.Header[offset+2:]
+	default:
+		return nil, 0
+	}
+
+	if len(ipLayer) == 0 {
+		return nil, 0
+	}
+
+	totalLen = h.FrameLength
+	if len(ipLayer) >= ipv4.HeaderLen && ipLayer[0]>>4 == 4 {
+		totalLen = uint32(binary.BigEndian.Uint16(ipLayer[2:4]))
+	} else if len(ipLayer) >= ipv6.HeaderLen && ipLayer[0]>>4 == 6 {
+
+		// Jumbograms (denoting a payload length of zero) fall back to the frame length
+		if payloadLen := binary.BigEndian.Uint16(ipLayer[4:6]); payloadLen > 0 {
+			totalLen = uint32(payloadLen) + ipv6.HeaderLen
+		}
+	}
+	return ipLayer, totalLen
+}
+
+// Decode decodes an sFlow datagram. Headers of sampled packets reference the provided data, hence it must not
+// be modified as long as the datagram is in use
+func Decode(data []byte) (*Datagram, error) {
+	r := reader{data: data}
+
+	if version := r.uint32(); r.err == nil && version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	var dgram Datagram
+	switch addrType := r.uint32(); addrType {
+	case addressTypeIPv4:
+		dgram.Agent = netip.AddrFrom4([4]byte(r.bytes(4)))
+	case addressTypeIPv6:
+		dgram.Agent = netip.AddrFrom16([16]byte(r.bytes(16)))
+	default:
+		if r.err == nil {
+			return nil, fmt.Errorf("%w: type %d", ErrInvalidAgentAddress, addrType)
+		}
+	}
+	dgram.SubAgentID = r.uint32()
+	dgram.Sequence = r.uint32()
+	dgram.Uptime = r.uint32()
+
+	nSamples := r.uint32()
+	for i := uint32(0); i < nSamples && r.err == nil; i++ {
+		format, sample := r.uint32(), r.opaque()
+		if r.err != nil {
+			break
+		}
+
+		var (
+			fs  FlowSample
+			err error
+		)
+		switch format {
+		case formatFlowSample:
+			fs, err = decodeFlowSample(sample, false)
+		case formatExpandedFlowSample:
+			fs, err = decodeFlowSample(sample, true)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dgram.Samples = append(dgram.Samples, fs)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &dgram, nil
+}
+
+func decodeFlowSample(data []byte, expanded bool) (fs FlowSample, err error) {
+	r := reader{data: data}
+
+	fs.Sequence = r.uint32()
+	if expanded {
+		_ = r.uint32() // source ID type
+		fs.SourceID = r.uint32()
+	} else {
+		fs.SourceID = r.uint32() & 0x00FFFFFF // lower 24 bits denote the index
+	}
+	fs.SamplingRate = r.uint32()
+	fs.SamplePool = r.uint32()
+	fs.Drops = r.uint32()
+	if expanded {
+		fs.Input = expandedInterface(r.uint32(), r.uint32())
+		fs.Output = expandedInterface(r.uint32(), r.uint32())
+	} else {
+		fs.Input, fs.Output = compactInterface(r.uint32()), compactInterface(r.uint32())
+	}
+
+	nRecords := r.uint32()
+	for i := uint32(0); i < nRecords && r.err == nil; i++ {
+		format, record := r.uint32(), r.opaque()
+		if r.err != nil || format != formatRawPacketHeader {
+			continue
+		}
+
+		rr := reader{data: record}
+		header := PacketHeader{
+			Protocol:    rr.uint32(),
+			FrameLength: rr.uint32(),
+			Stripped:    rr.uint32(),
+			Header:      rr.opaque(),
+		}
+		if rr.err != nil {
+			return fs, rr.err
+		}
+		fs.Headers = append(fs.Headers, header)
+	}
+
+	return fs, r.err
+}
+
+// compactInterface extracts the ifIndex from the interface field of a compact flow sample (which is only
+// set if the upper two bits, denoting the format, are zero)
+func compactInterface(value uint32) uint32 {
+	if value>>30 != 0 {
+		return 0
+	}
+	return value & interfaceValueMask
+}
+
+// expandedInterface extracts the ifIndex from the interface fields of an expanded flow sample
+func expandedInterface(format, value uint32) uint32 {
+	if format != 0 {
+		return 0
+	}
+	return value
+}
+
+// reader sequentially decodes XDR encoded data. Upon the first error (i.e. if the data is exhausted), all
+// subsequent reads return zero values and the error is retained
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.err = ErrTruncated
+		return make([]byte, max(n, 0))
+	}
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res
+}
+
+func (r *reader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.bytes(4))
+}
+
+// opaque reads variable-length opaque data (which is padded to a multiple of four bytes)
+func (r *reader) opaque() []byte {
+	n := r.uint32()
+	if r.err != nil || uint64(n) > uint64(len(r.data)) {
+		r.err = ErrTruncated
+		return nil
+	}
+	res := r.bytes(int(n))
+	_ = r.bytes(int((4 - n%4) % 4))
+	return res
+}
@@ -0,0 +1,185 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// xdr assembles XDR encoded test data
+type xdr []byte
+
+func (x xdr) uint32(vals ...uint32) xdr {
+	for _, val := range vals {
+		x = binary.BigEndian.AppendUint32(x, val)
+	}
+	return x
+}
+
+func (x xdr) opaque(data []byte) xdr {
+	x = x.uint32(uint32(len(data)))
+	x = append(x, data...)
+	return append(x, make([]byte, (4-len(data)%4)%4)...)
+}
+
+func testIPv4Packet(proto byte, length uint16) []byte {
+	pkt := make([]byte, 40)
+	pkt[0] = 0x45
+	binary.BigEndian.PutUint16(pkt[2:4], length)
+	pkt[9] = proto
+	copy(pkt[12:16], []byte{10, 0, 0, 1})
+	copy(pkt[16:20], []byte{10, 0, 0, 2})
+	binary.BigEndian.PutUint16(pkt[20:22], 43512)
+	binary.BigEndian.PutUint16(pkt[22:24], 443)
+	return pkt
+}
+
+func testEthernetFrame(etherTypes []uint16, payload []byte) []byte {
+	frame := make([]byte, 12)
+	for i, etherType := range etherTypes {
+		frame = binary.BigEndian.AppendUint16(frame, etherType)
+		if i < len(etherTypes)-1 {
+			frame = append(frame, 0x00, 0x0A) // VLAN TCI
+		}
+	}
+	return append(frame, payload...)
+}
+
+func testRawPacketHeader(protocol, frameLength uint32, header []byte) xdr {
+	record := xdr{}.uint32(protocol, frameLength, 4).opaque(header)
+	return xdr{}.uint32(formatRawPacketHeader).opaque(record)
+}
+
+func TestDecode(t *testing.T) {
+	ipv4Pkt := testIPv4Packet(6, 1500)
+	frame := testEthernetFrame([]uint16{etherTypeVLAN, etherTypeIPv4}, ipv4Pkt)
+
+	compactSample := xdr{}.uint32(
+		42,         // sequence
+		0x00000007, // source ID (type 0, index 7)
+		512,        // sampling rate
+		1024000,    // sample pool
+		3,          // drops
+		7,          // input
+		0x80000002, // output (multiple interfaces)
+		2,          // number of records
+	)
+	compactSample = compactSample.uint32(0x00001001).opaque([]byte{1, 2, 3, 4}) // non-standard enterprise (skipped)
+	compactSample = append(compactSample, testRawPacketHeader(HeaderProtocolEthernet, 1518, frame)...)
+
+	expandedSample := xdr{}.uint32(
+		43,    // sequence
+		0, 12, // source ID type / index
+		1000,  // sampling rate
+		2000,  // sample pool
+		0,     // drops
+		0, 12, // input format / value
+		1, 5, // output format / value (discarded)
+		1, // number of records
+	)
+	expandedSample = append(expandedSample, testRawPacketHeader(HeaderProtocolIPv4, 64, ipv4Pkt)...)
+
+	counterSample := xdr{}.uint32(1, 2, 3)
+
+	header := func(version, addrType uint32, addr []byte, nSamples uint32) xdr {
+		x := xdr{}.uint32(version, addrType)
+		x = append(x, addr...)
+		return x.uint32(1, 100, 360000, nSamples)
+	}
+
+	valid := header(Version, addressTypeIPv4, []byte{192, 168, 1, 1}, 3)
+	valid = valid.uint32(formatFlowSample).opaque(compactSample)
+	valid = valid.uint32(2).opaque(counterSample)
+	valid = valid.uint32(formatExpandedFlowSample).opaque(expandedSample)
+
+	t.Run("valid", func(t *testing.T) {
+		dgram, err := Decode(valid)
+		require.Nil(t, err)
+		require.Equal(t, netip.MustParseAddr("192.168.1.1"), dgram.Agent)
+		require.Equal(t, uint32(1), dgram.SubAgentID)
+		require.Equal(t, uint32(100), dgram.Sequence)
+		require.Equal(t, uint32(360000), dgram.Uptime)
+		require.Len(t, dgram.Samples, 2)
+
+		compact := dgram.Samples[0]
+		require.Equal(t, FlowSample{
+			Sequence:     42,
+			SourceID:     7,
+			SamplingRate: 512,
+			SamplePool:   1024000,
+			Drops:        3,
+			Input:        7,
+			Output:       0,
+			Headers: []PacketHeader{
+				{Protocol: HeaderProtocolEthernet, FrameLength: 1518, Stripped: 4, Header: frame},
+			},
+		}, compact)
+
+		ipLayer, totalLen := compact.Headers[0].IPLayer()
+		require.Equal(t, ipv4Pkt, ipLayer)
+		require.Equal(t, uint32(1500), totalLen)
+
+		expanded := dgram.Samples[1]
+		require.Equal(t, uint32(12), expanded.SourceID)
+		require.Equal(t, uint32(1000), expanded.SamplingRate)
+		require.Equal(t, uint32(12), expanded.Input)
+		require.Zero(t, expanded.Output)
+		require.Len(t, expanded.Headers, 1)
+	})
+
+	var tests = []struct {
+		name        string
+		data        []byte
+		expectedErr error
+	}{
+		{"empty", nil, ErrTruncated},
+		{"unsupported version", header(4, addressTypeIPv4, []byte{192, 168, 1, 1}, 0), ErrUnsupportedVersion},
+		{"invalid agent address", header(Version, 3, []byte{192, 168, 1, 1}, 0), ErrInvalidAgentAddress},
+		{"truncated header", valid[:20], ErrTruncated},
+		{"truncated sample", valid[:len(valid)-8], ErrTruncated},
+		{"excessive sample length", header(Version, addressTypeIPv6, make([]byte, 16), 1).uint32(formatFlowSample, 1<<31), ErrTruncated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(test.data)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestIPLayer(t *testing.T) {
+	ipv4Pkt := testIPv4Packet(17, 60)
+	ipv6Pkt := make([]byte, 48)
+	ipv6Pkt[0] = 0x60
+	binary.BigEndian.PutUint16(ipv6Pkt[4:6], 8)
+
+	var tests = []struct {
+		name             string
+		header           PacketHeader
+		expectedIPLayer  []byte
+		expectedTotalLen uint32
+	}{
+		{"ipv4", PacketHeader{Protocol: HeaderProtocolIPv4, FrameLength: 78, Header: ipv4Pkt}, ipv4Pkt, 60},
+		{"ipv6", PacketHeader{Protocol: HeaderProtocolIPv6, FrameLength: 66, Header: ipv6Pkt}, ipv6Pkt, 48},
+		{"ethernet", PacketHeader{Protocol: HeaderProtocolEthernet, FrameLength: 78,
+			Header: testEthernetFrame([]uint16{etherTypeIPv4}, ipv4Pkt)}, ipv4Pkt, 60},
+		{"ethernet with stacked vlan tags", PacketHeader{Protocol: HeaderProtocolEthernet, FrameLength: 74,
+			Header: testEthernetFrame([]uint16{etherTypeQinQ, etherTypeVLAN, etherTypeIPv6}, ipv6Pkt)}, ipv6Pkt, 48},
+		{"truncated ipv4", PacketHeader{Protocol: HeaderProtocolIPv4, FrameLength: 78, Header: ipv4Pkt[:10]}, ipv4Pkt[:10], 78},
+		{"ethernet without ip", PacketHeader{Protocol: HeaderProtocolEthernet, FrameLength: 64,
+			Header: testEthernetFrame([]uint16{0x0806}, make([]byte, 28))}, nil, 0},
+		{"truncated ethernet", PacketHeader{Protocol: HeaderProtocolEthernet, FrameLength: 64, Header: make([]byte, 10)}, nil, 0},
+		{"ethernet without payload", PacketHeader{Protocol: HeaderProtocolEthernet, FrameLength: 64,
+			Header: testEthernetFrame([]uint16{etherTypeIPv4}, nil)}, nil, 0},
+		{"unsupported protocol", PacketHeader{Protocol: 2, FrameLength: 64, Header: ipv4Pkt}, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ipLayer, totalLen := test.header.IPLayer()
+			require.Equal(t, test.expectedIPLayer, ipLayer)
+			require.Equal(t, test.expectedTotalLen, totalLen)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/sflow"
+	"github.com/stretchr/testify/require"
+)
+
+func testSFlowSample(input, output, rate uint32, ipLayers ...[]byte) sflow.FlowSample {
+	sample := sflow.FlowSample{
+		SamplingRate: rate,
+		Input:        input,
+		Output:       output,
+	}
+	for _, ipLayer := range ipLayers {
+		sample.Headers = append(sample.Headers, sflow.PacketHeader{
+			Protocol:    sflow.HeaderProtocolIPv4,
+			FrameLength: uint32(len(ipLayer)) + 18,
+			Header:      ipLayer,
+		})
+	}
+	return sample
+}
+
+func testSFlowIPv4Packet(sip, dip string, sport, dport uint16, length uint16) []byte {
+	pkt := make([]byte, 40)
+	pkt[0] = 0x45
+	binary.BigEndian.PutUint16(pkt[2:4], length)
+	pkt[9] = capturetypes.UDP
+	sipBytes, dipBytes := netip.MustParseAddr(sip).As4(), netip.MustParseAddr(dip).As4()
+	copy(pkt[12:16], sipBytes[:])
+	copy(pkt[16:20], dipBytes[:])
+	binary.BigEndian.PutUint16(pkt[20:22], sport)
+	binary.BigEndian.PutUint16(pkt[22:24], dport)
+	return pkt
+}
+
+func TestSFlowCollector(t *testing.T) {
+	s := newSFlowCollector(&config.SFlowConfig{
+		Ifaces: map[string]config.SFlowIfaceConfig{
+			"sw1":      {Agent: "10.0.0.1"},
+			"sw1-port": {Agent: "10.0.0.1", IfIndex: 12},
+			"sw2":      {Agent: "10.0.0.2"},
+		},
+	})
+	require.Equal(t, config.DefaultSFlowListen, s.listen)
+
+	pkt := testSFlowIPv4Packet("192.168.1.1", "192.168.1.2", 43512, 8080, 1000)
+	s.add(&sflow.Datagram{
+		Agent: netip.MustParseAddr("10.0.0.1"),
+		Samples: []sflow.FlowSample{
+			testSFlowSample(12, 3, 100, pkt, pkt),
+			testSFlowSample(3, 12, 0, pkt),
+			testSFlowSample(5, 3, 100, pkt, pkt[:22]),
+		},
+	})
+
+	rotated := s.rotate()
+	require.Len(t, rotated, 3)
+
+	var tests = []struct {
+		iface                 string
+		received, processed   uint64
+		bytesRcvd, bytesSent  uint64
+		pktsRcvd, pktsSent    uint64
+		expectedParsingErrors int
+	}{
+		// all samples of the agent are considered received
+		{"sw1", 5, 4, 301000, 0, 301, 0, 1},
+
+		// only samples of the interface index are considered (in the respective direction)
+		{"sw1-port", 3, 3, 200000, 1000, 200, 1, 0},
+
+		// samples of other agents are ignored
+		{"sw2", 0, 0, 0, 0, 0, 0, 0},
+	}
+	for i, test := range tests {
+		t.Run(test.iface, func(t *testing.T) {
+			require.Equal(t, test.iface, rotated[i].Iface)
+			require.Equal(t, test.received, rotated[i].Stats.Received)
+			require.Equal(t, test.processed, rotated[i].Stats.Processed)
+			require.Equal(t, test.expectedParsingErrors, rotated[i].Stats.ParsingErrors.Sum())
+
+			flows := capturetypes.RotatedFlows(rotated[i].Map)
+			if test.received == 0 {
+				require.Empty(t, flows)
+				return
+			}
+			require.Len(t, flows, 1)
+			require.Equal(t, test.bytesRcvd, flows[0].Counters.BytesRcvd)
+			require.Equal(t, test.bytesSent, flows[0].Counters.BytesSent)
+			require.Equal(t, test.pktsRcvd, flows[0].Counters.PacketsRcvd)
+			require.Equal(t, test.pktsSent, flows[0].Counters.PacketsSent)
+		})
+	}
+
+	// statistics are reset upon rotation (retaining the totals)
+	rotated = s.rotate("sw1")
+	require.Len(t, rotated, 1)
+	require.Zero(t, rotated[0].Stats.Received)
+	require.Equal(t, uint64(5), rotated[0].Stats.ReceivedTotal)
+	require.Equal(t, uint64(4), rotated[0].Stats.ProcessedTotal)
+}
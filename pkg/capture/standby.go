@@ -0,0 +1,248 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+)
+
+var (
+	// errLinkActivityUnsupported denotes that the activity of a link cannot be determined
+	errLinkActivityUnsupported = errors.New("link activity detection not supported")
+
+	// errCaptureClosed denotes that a capture in standby was closed in the meantime
+	errCaptureClosed = errors.New("capture closed")
+)
+
+// linkActivity denotes the packet counters and state of a link, any change of which is considered
+// to be activity on the interface
+type linkActivity struct {
+	rxPackets uint64
+	txPackets uint64
+	flags     net.Flags
+}
+
+// standbyState tracks the activity of a capture with a configured idle standby and whether its capture
+// source has been released. All fields are protected by the embedded mutex, which is held for the full
+// duration of a lock() / unlock() sequence
+type standbyState struct {
+	cfg *config.StandbyConfig
+
+	lastActivity time.Time    // time at which packets were last observed on the interface
+	released     bool         // indicates if the capture source has been released
+	link         linkActivity // activity of the link upon release, serving as baseline
+
+	stop   chan struct{} // closed once the capture is closed (stopping any polling for activity)
+	closed bool
+
+	sync.Mutex
+}
+
+func newStandbyState(cfg *config.StandbyConfig) *standbyState {
+	return &standbyState{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+	}
+}
+
+// idle determines if the capture is active and the interface has not received any packets for at
+// least the configured idle timeout
+func (s *standbyState) idle(t time.Time) bool {
+	return !s.released && !s.closed && t.Sub(s.lastActivity) >= s.cfg.IdleTimeout
+}
+
+// pollInterval returns the interval in which the interface is polled for activity while in standby
+func (s *standbyState) pollInterval() time.Duration {
+	if s.cfg.PollInterval > 0 {
+		return s.cfg.PollInterval
+	}
+	return config.DefaultStandbyPollInterval
+}
+
+// halt stops any polling for activity
+func (s *standbyState) halt() {
+	if !s.closed {
+		close(s.stop)
+		s.closed = true
+	}
+}
+
+// isReleased returns if the capture source has been released due to inactivity
+func (c *Capture) isReleased() bool {
+	if c.standby == nil {
+		return false
+	}
+
+	c.standby.Lock()
+	defer c.standby.Unlock()
+
+	return c.standby.released
+}
+
+// suspendIfIdle releases the capture source (and hence its ring buffer) if the interface has not received
+// any packets for at least the configured idle timeout, stopping the processing routine. It must not be
+// called while the capture is locked
+func (c *Capture) suspendIfIdle(t time.Time) (bool, error) {
+	if c.standby == nil {
+		return false, nil
+	}
+
+	c.standby.Lock()
+	defer c.standby.Unlock()
+
+	if !c.standby.idle(t) || c.isRecovering() {
+		return false, nil
+	}
+
+	// Determine the current activity of the link prior to releasing the capture source, serving as
+	// baseline for the detection of any subsequent activity
+	activity, err := c.linkActivity()
+	if err != nil {
+		return false, err
+	}
+
+	// The processing routine is only notified that it was stopped deliberately once it has concluded
+	// (since the mutex is held until then)
+	c.standby.released, c.standby.link = true, activity
+	if err := c.captureHandle.Close(); err != nil {
+		c.standby.released = false
+		return false, err
+	}
+	c.wgProc.Wait()
+	c.captureHandle = nil
+	c.statusCache.invalidate()
+
+	promStandby.WithLabelValues(c.iface).Set(1)
+
+	return true, nil
+}
+
+// detectActivity determines if there was any traffic / link activity on the interface since the capture
+// source was released
+func (c *Capture) detectActivity() (bool, error) {
+	c.standby.Lock()
+	baseline := c.standby.link
+	c.standby.Unlock()
+
+	activity, err := c.linkActivity()
+	if err != nil {
+		return false, err
+	}
+	return activity != baseline, nil
+}
+
+// resume reinitializes the capture source of a capture in standby and restarts packet processing,
+// returning the error channel of the processing routine (c.f. process())
+func (c *Capture) resume(t time.Time) (<-chan error, error) {
+	c.standby.Lock()
+	defer c.standby.Unlock()
+
+	if c.standby.closed {
+		return nil, errCaptureClosed
+	}
+	if err := c.initSource(); err != nil {
+		return nil, err
+	}
+	c.standby.released, c.standby.lastActivity = false, t
+	c.statusCache.invalidate()
+
+	// The progress of the processing routine has to be assessed anew (c.f. watchdogState.stalled())
+	if c.watchdog != nil {
+		c.watchdog.Lock()
+		c.watchdog.primed = false
+		c.watchdog.Unlock()
+	}
+
+	promStandby.WithLabelValues(c.iface).Set(0)
+
+	return c.process(), nil
+}
+
+// linkActivity determines the current activity of the link the capture is attached to
+func (c *Capture) linkActivity() (res linkActivity, err error) {
+	err = c.inNetns(func() (err error) {
+		res, err = readLinkActivity(c.device())
+		return
+	})
+	return
+}
+
+// statusStandby is the equivalent of status() for a capture in standby. Since the capture source has
+// been released, no packets were received since the previous status call
+func (c *Capture) statusStandby() *capturetypes.CaptureStats {
+	cpuTime := c.cpu.sample()
+	c.stats.CPUTimeTotal += cpuTime
+
+	return &capturetypes.CaptureStats{
+		StartedAt:      c.startedAt,
+		ReceivedTotal:  c.stats.ReceivedTotal,
+		ProcessedTotal: c.stats.ProcessedTotal,
+		DroppedTotal:   c.stats.DroppedTotal,
+		FilteredTotal:  c.stats.FilteredTotal,
+		CPUTime:        cpuTime,
+		CPUTimeTotal:   c.stats.CPUTimeTotal,
+		ParsingErrors:  c.stats.ParsingErrors,
+		Standby:        true,
+		AutoRecovered:  c.autoRecovered(),
+	}
+}
+
+// suspendIfIdle releases the capture of an interface that has been idle for at least its configured
+// idle timeout into standby. From then on, the interface is polled for activity by its error logging
+// routine (c.f. logErrors())
+func (cm *Manager) suspendIfIdle(ctx context.Context, mc *Capture) {
+	logger := logging.FromContext(ctx)
+
+	released, err := mc.suspendIfIdle(mc.clock.Now())
+	if err != nil {
+		logger.Errorf("failed to release idle capture into standby: %s", err)
+		return
+	}
+	if released {
+		logger.With("idle_timeout", mc.standby.cfg.IdleTimeout.String()).Info("interface idle, released capture into standby")
+	}
+}
+
+// awaitActivity polls the interface of a capture in standby for traffic / link activity, reinitializing
+// the capture once any is observed. It returns the error channel of the resumed processing routine or nil
+// if the capture was closed in the meantime
+func (cm *Manager) awaitActivity(ctx context.Context, mc *Capture) <-chan error {
+	logger := logging.FromContext(ctx)
+
+	ticker := mc.clock.NewTicker(mc.standby.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mc.standby.stop:
+			return nil
+		case <-ticker.C():
+			active, err := mc.detectActivity()
+			if err != nil {
+				logger.Warnf("failed to poll interface for activity: %s", err)
+				continue
+			}
+			if !active {
+				continue
+			}
+
+			errsChan, err := mc.resume(mc.clock.Now())
+			if err != nil {
+				if errors.Is(err, errCaptureClosed) {
+					return nil
+				}
+				logger.Errorf("failed to reinitialize capture from standby: %s", err)
+				continue
+			}
+
+			logger.Info("observed activity on interface, reinitialized capture from standby")
+			return errsChan
+		}
+	}
+}
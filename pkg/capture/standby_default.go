@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package capture
+
+func readLinkActivity(string) (linkActivity, error) {
+	return linkActivity{}, errLinkActivityUnsupported
+}
@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// netDevPath denotes the interface statistics of the network namespace of the calling thread (as
+// opposed to /proc/net/dev, which refers to the namespace of the process' main thread)
+const netDevPath = "/proc/thread-self/net/dev"
+
+// readLinkActivity determines the packet counters and state of a link (in the network namespace of
+// the calling thread)
+func readLinkActivity(device string) (res linkActivity, err error) {
+	link, err := net.InterfaceByName(device)
+	if err != nil {
+		return res, err
+	}
+	res.flags = link.Flags
+
+	f, err := os.Open(netDevPath)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+
+	res.rxPackets, res.txPackets, err = parseNetDev(f, device)
+	return
+}
+
+// parseNetDev extracts the received / transmitted packet counters of a device from the contents
+// of /proc/net/dev, formatted as
+//
+//	Inter-|   Receive                                                |  Transmit
+//	 face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+//	  eth0: 1234567    8910    0    0    0     0          0         0   765432    1098    0    0    0     0       0          0
+func parseNetDev(r io.Reader, device string) (rxPackets, txPackets uint64, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(name) != device {
+			continue
+		}
+
+		fields := strings.Fields(counters)
+		if len(fields) < 10 {
+			return 0, 0, fmt.Errorf("unexpected number of interface statistics for %s: %d", device, len(fields))
+		}
+		if rxPackets, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("failed to parse received packets of %s: %w", device, err)
+		}
+		if txPackets, err = strconv.ParseUint(fields[9], 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("failed to parse transmitted packets of %s: %w", device, err)
+		}
+		return
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	return 0, 0, fmt.Errorf("no interface statistics found for %s", device)
+}
@@ -0,0 +1,33 @@
+//go:build linux
+// +build linux
+
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  845612    9021    0    0    0     0          0         0   845612    9021    0    0    0     0       0          0
+  eth0: 1234567    8910    0    0    0     0          0         0   765432    1098    0    0    0     0       0          0
+ eth10:       0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	rx, tx, err := parseNetDev(strings.NewReader(testNetDev), "eth0")
+	require.Nil(t, err)
+	require.Equal(t, uint64(8910), rx)
+	require.Equal(t, uint64(1098), tx)
+
+	rx, tx, err = parseNetDev(strings.NewReader(testNetDev), "eth10")
+	require.Nil(t, err)
+	require.Zero(t, rx)
+	require.Zero(t, tx)
+
+	_, _, err = parseNetDev(strings.NewReader(testNetDev), "eth1")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandbyIdle(t *testing.T) {
+	start := time.Now()
+	state := newStandbyState(&config.StandbyConfig{IdleTimeout: 30 * time.Minute})
+	state.lastActivity = start
+	require.Equal(t, config.DefaultStandbyPollInterval, state.pollInterval())
+
+	require.False(t, state.idle(start.Add(5*time.Minute)))
+	require.True(t, state.idle(start.Add(30*time.Minute)))
+
+	// activity on the interface defers the standby
+	state.lastActivity = start.Add(10 * time.Minute)
+	require.False(t, state.idle(start.Add(30*time.Minute)))
+	require.True(t, state.idle(start.Add(40*time.Minute)))
+
+	// captures that are already released or closed are never considered idle
+	state.released = true
+	require.False(t, state.idle(start.Add(time.Hour)))
+	state.released = false
+	state.halt()
+	state.halt()
+	require.False(t, state.idle(start.Add(time.Hour)))
+	_, open := <-state.stop
+	require.False(t, open)
+}
+
+func TestStandbyStatus(t *testing.T) {
+	c := newCapture("eth0", config.CaptureConfig{
+		Standby: &config.StandbyConfig{IdleTimeout: time.Minute, PollInterval: time.Second},
+	})
+	require.Equal(t, time.Second, c.standby.pollInterval())
+	require.False(t, c.isReleased())
+
+	c.stats.ReceivedTotal, c.stats.ProcessedTotal = 100, 90
+	c.standby.released = true
+	require.True(t, c.isReleased())
+
+	// a released capture can be locked / queried without any capture source or processing routine
+	c.lock()
+	stats, err := c.status()
+	c.unlock()
+	require.Nil(t, err)
+	require.True(t, stats.Standby)
+	require.Zero(t, stats.Received)
+	require.Equal(t, uint64(100), stats.ReceivedTotal)
+	require.Equal(t, uint64(90), stats.ProcessedTotal)
+
+	require.Nil(t, c.close())
+	_, err = c.resume(time.Now())
+	require.ErrorIs(t, err, errCaptureClosed)
+}
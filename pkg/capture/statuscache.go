@@ -0,0 +1,59 @@
+package capture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+// statusMaxAgeActive denotes the maximum age of the cached status of an interface on which packets
+// were received upon its retrieval before on-demand status requests retrieve it anew
+const statusMaxAgeActive = time.Second
+
+// statusCache retains the most recent status of a capture, allowing on-demand status requests to be
+// served without locking the capture (and querying the capture source for its statistics). The age
+// up to which the cached status is served adapts to the activity of the interface: the status of an
+// active interface is retrieved anew once it is older than statusMaxAgeActive, whereas the status of
+// an idle interface (or one in standby) remains valid until it is refreshed by the next rotation
+type statusCache struct {
+	stats *capturetypes.CaptureStats
+	at    time.Time
+
+	sync.Mutex
+}
+
+// get returns a copy of the cached status (annotated with its age at t) if it is still valid
+func (s *statusCache) get(t time.Time) (*capturetypes.CaptureStats, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stats == nil {
+		return nil, false
+	}
+	age := t.Sub(s.at)
+	if s.stats.Received > 0 && !s.stats.Standby && age >= statusMaxAgeActive {
+		return nil, false
+	}
+
+	res := *s.stats
+	res.Age = max(age, 0)
+	return &res, true
+}
+
+// set caches a copy of a status retrieved at t (omitting all information only populated for rotations)
+func (s *statusCache) set(stats *capturetypes.CaptureStats, t time.Time) {
+	cached := *stats
+	cached.HandshakeRTT, cached.Link, cached.Age = nil, nil, 0
+
+	s.Lock()
+	s.stats, s.at = &cached, t
+	s.Unlock()
+}
+
+// invalidate discards the cached status (e.g. if the capture source was released or reinitialized)
+func (s *statusCache) invalidate() {
+	s.Lock()
+	s.stats = nil
+	s.Unlock()
+}
@@ -0,0 +1,52 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCache(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+
+	var tests = []struct {
+		name   string
+		stats  capturetypes.CaptureStats
+		age    time.Duration
+		cached bool
+	}{
+		{"active, recent", capturetypes.CaptureStats{Received: 10}, statusMaxAgeActive / 2, true},
+		{"active, outdated", capturetypes.CaptureStats{Received: 10}, statusMaxAgeActive, false},
+		{"idle", capturetypes.CaptureStats{ReceivedTotal: 10}, time.Hour, true},
+		{"standby", capturetypes.CaptureStats{Received: 10, Standby: true}, time.Hour, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cache statusCache
+
+			_, cached := cache.get(t0)
+			require.False(t, cached)
+
+			stats := test.stats
+			stats.HandshakeRTT = new(capturetypes.HandshakeRTT)
+			cache.set(&stats, t0)
+
+			res, cached := cache.get(t0.Add(test.age))
+			require.Equal(t, test.cached, cached)
+			if !cached {
+				return
+			}
+
+			expected := test.stats
+			expected.Age = test.age
+			require.Equal(t, &expected, res)
+
+			cache.invalidate()
+			_, cached = cache.get(t0.Add(test.age))
+			require.False(t, cached)
+		})
+	}
+}
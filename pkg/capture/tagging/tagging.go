@@ -0,0 +1,184 @@
+// Package tagging provides capture-side tagging of flows based on a list of rules matching
+// their endpoints (CIDRs), ports, IP protocol and interface. Tags are assigned once when a
+// flow is created and stored alongside it in goDB, allowing operators to encode business
+// context (e.g. "voip" or "backup") once instead of repeating the conditions in every query
+package tagging
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/capture/filter"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
+)
+
+var (
+	// ErrEmptyMatch is returned if a rule does not contain any match condition
+	ErrEmptyMatch = errors.New("tagging rule must contain at least one match condition")
+
+	// ErrInvalidProto is returned if a rule references an unknown IP protocol
+	ErrInvalidProto = errors.New("invalid IP protocol")
+)
+
+// Rule assigns a tag to all flows matching its conditions
+type Rule struct {
+	// Tag: denotes the tag assigned to matching flows
+	// Example: "voip"
+	Tag string `json:"tag" yaml:"tag"`
+
+	// Match: denotes the conditions a flow must fulfill in order to be tagged
+	Match Match `json:"match" yaml:"match"`
+}
+
+// Match stores the conditions of a tagging rule. All non-empty conditions must be fulfilled
+// for a flow to match, while it is sufficient for a flow to match any of the values of an
+// individual condition. Conditions are evaluated irrespective of the direction of the flow,
+// i.e. it suffices if either of its endpoints / ports matches
+type Match struct {
+	// Ifaces: list of interfaces the rule applies to (all interfaces if empty)
+	// Example: ["eth0", "eth1"]
+	Ifaces []string `json:"ifaces,omitempty" yaml:"ifaces,omitempty"`
+
+	// CIDRs: list of prefixes (CIDR notation) of which at least one must contain an
+	// endpoint of the flow
+	// Example: ["10.0.0.0/8", "2001:db8::/32"]
+	CIDRs []string `json:"cidrs,omitempty" yaml:"cidrs,omitempty"`
+
+	// Ports: list of ports of which at least one must be used by the flow
+	// Example: [5060, 5061]
+	Ports []uint16 `json:"ports,omitempty" yaml:"ports,omitempty"`
+
+	// Protos: list of IP protocols (by name or number) of which one must be used by the flow
+	// Example: ["udp"]
+	Protos []string `json:"protos,omitempty" yaml:"protos,omitempty"`
+}
+
+// Validate checks if the rule is well-formed
+func (r Rule) Validate() error {
+	if err := types.ValidateTag(r.Tag); err != nil {
+		return err
+	}
+	if r.Match.isEmpty() {
+		return fmt.Errorf("%w (tag `%s`)", ErrEmptyMatch, r.Tag)
+	}
+	for _, cidr := range r.Match.CIDRs {
+		if _, err := filter.ParsePrefix(cidr); err != nil {
+			return err
+		}
+	}
+	for _, proto := range r.Match.Protos {
+		if _, err := parseProto(proto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Equals compares r to rule and returns true if all fields are identical
+func (r Rule) Equals(rule Rule) bool {
+	return r.Tag == rule.Tag &&
+		slices.Equal(r.Match.Ifaces, rule.Match.Ifaces) &&
+		slices.Equal(r.Match.CIDRs, rule.Match.CIDRs) &&
+		slices.Equal(r.Match.Ports, rule.Match.Ports) &&
+		slices.Equal(r.Match.Protos, rule.Match.Protos)
+}
+
+// AppliesTo returns if the rule is evaluated for flows captured on the given interface
+func (r Rule) AppliesTo(iface string) bool {
+	return len(r.Match.Ifaces) == 0 || slices.Contains(r.Match.Ifaces, iface)
+}
+
+func (m Match) isEmpty() bool {
+	return len(m.Ifaces) == 0 && len(m.CIDRs) == 0 && len(m.Ports) == 0 && len(m.Protos) == 0
+}
+
+func parseProto(name string) (byte, error) {
+	id, err := protocols.Parse(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidProto, name)
+	}
+	return id, nil
+}
+
+// Tagger evaluates the tagging rules of an individual interface. It is immutable once created
+// and hence safe for concurrent use
+type Tagger struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	tag byte
+
+	cidrs  *filter.Filter
+	ports  []uint16
+	protos []byte
+}
+
+// New compiles a new Tagger from all rules applying to the given interface. If none of
+// the rules apply, nil is returned (which is a valid Tagger that never assigns a tag)
+func New(iface string, rules []Rule) (*Tagger, error) {
+	var t *Tagger
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if !rule.AppliesTo(iface) {
+			continue
+		}
+
+		tag, err := types.InternTag(rule.Tag)
+		if err != nil {
+			return nil, err
+		}
+		compiled := compiledRule{
+			tag:   tag,
+			ports: rule.Match.Ports,
+		}
+		if len(rule.Match.CIDRs) > 0 {
+			if compiled.cidrs, err = filter.New(rule.Match.CIDRs, nil); err != nil {
+				return nil, err
+			}
+		}
+		for _, proto := range rule.Match.Protos {
+			id, err := parseProto(proto)
+			if err != nil {
+				return nil, err
+			}
+			compiled.protos = append(compiled.protos, id)
+		}
+
+		if t == nil {
+			t = new(Tagger)
+		}
+		t.rules = append(t.rules, compiled)
+	}
+
+	return t, nil
+}
+
+// Tag returns the tag of the first rule matched by a flow with the given endpoints (or
+// types.UntaggedID if none of the rules match)
+func (t *Tagger) Tag(epHash capturetypes.EPHash, isIPv4 bool) byte {
+	if t == nil {
+		return types.UntaggedID
+	}
+
+	dport, sport := types.PortToUint16(epHash[32:34]), types.PortToUint16(epHash[34:36])
+	for _, rule := range t.rules {
+		if len(rule.protos) > 0 && !slices.Contains(rule.protos, epHash[36]) {
+			continue
+		}
+		if len(rule.ports) > 0 && !slices.Contains(rule.ports, dport) && !slices.Contains(rule.ports, sport) {
+			continue
+		}
+		if rule.cidrs != nil && !rule.cidrs.Permits(epHash, isIPv4) {
+			continue
+		}
+		return rule.tag
+	}
+
+	return types.UntaggedID
+}
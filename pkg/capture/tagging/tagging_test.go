@@ -0,0 +1,104 @@
+package tagging
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newEPHash(sip, dip string, sport, dport uint16, proto byte) (epHash capturetypes.EPHash, isIPv4 bool) {
+	sipAddr, dipAddr := netip.MustParseAddr(sip), netip.MustParseAddr(dip)
+	if sipAddr.Is4() {
+		sipBytes, dipBytes := sipAddr.As4(), dipAddr.As4()
+		copy(epHash[0:4], sipBytes[:])
+		copy(epHash[16:20], dipBytes[:])
+		isIPv4 = true
+	} else {
+		sipBytes, dipBytes := sipAddr.As16(), dipAddr.As16()
+		copy(epHash[0:16], sipBytes[:])
+		copy(epHash[16:32], dipBytes[:])
+	}
+	binary.BigEndian.PutUint16(epHash[32:34], dport)
+	binary.BigEndian.PutUint16(epHash[34:36], sport)
+	epHash[36] = proto
+
+	return
+}
+
+var testRules = []Rule{
+	{Tag: "voip", Match: Match{Ports: []uint16{5060, 5061}, Protos: []string{"udp", "TCP"}}},
+	{Tag: "backup", Match: Match{Ifaces: []string{"eth0"}, CIDRs: []string{"10.1.0.0/16", "2001:db8::/32"}}},
+	{Tag: "internet", Match: Match{Ifaces: []string{"eth1"}}},
+}
+
+func TestTagger(t *testing.T) {
+	var tests = []struct {
+		name         string
+		iface        string
+		sip, dip     string
+		sport, dport uint16
+		proto        byte
+		expected     string
+	}{
+		{"port match", "eth0", "10.0.0.1", "192.168.1.1", 40000, 5060, capturetypes.UDP, "voip"},
+		{"port proto mismatch", "eth0", "10.0.0.1", "192.168.1.1", 40000, 5060, capturetypes.ICMP, ""},
+		{"first match wins", "eth0", "10.1.0.1", "192.168.1.1", 40000, 5061, capturetypes.TCP, "voip"},
+		{"cidr match", "eth0", "10.1.0.1", "192.168.1.1", 40000, 22, capturetypes.TCP, "backup"},
+		{"cidr match IPv6", "eth0", "2001:db9::1", "2001:db8::1", 40000, 22, capturetypes.TCP, "backup"},
+		{"cidr other iface", "eth2", "10.1.0.1", "192.168.1.1", 40000, 22, capturetypes.TCP, ""},
+		{"iface match", "eth1", "10.1.0.1", "192.168.1.1", 40000, 22, capturetypes.TCP, "internet"},
+		{"no match", "eth0", "10.0.0.1", "192.168.1.1", 40000, 22, capturetypes.TCP, ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			tagger, err := New(test.iface, testRules)
+			require.Nil(t, err)
+
+			epHash, isIPv4 := newEPHash(test.sip, test.dip, test.sport, test.dport, test.proto)
+			require.Equal(t, test.expected, types.TagNameByID(tagger.Tag(epHash, isIPv4)))
+
+			// the tag must not depend on the direction of the flow
+			require.Equal(t, test.expected, types.TagNameByID(tagger.Tag(epHash.Reverse(), isIPv4)))
+		})
+	}
+}
+
+func TestNoApplicableRules(t *testing.T) {
+	tagger, err := New("eth0", []Rule{{Tag: "internet", Match: Match{Ifaces: []string{"eth1"}}}})
+	require.Nil(t, err)
+	require.Nil(t, tagger)
+
+	epHash, isIPv4 := newEPHash("10.0.0.1", "192.168.1.1", 40000, 22, capturetypes.TCP)
+	require.Equal(t, types.UntaggedID, tagger.Tag(epHash, isIPv4))
+}
+
+func TestInvalidRules(t *testing.T) {
+	var tests = []struct {
+		name     string
+		rule     Rule
+		expected error
+	}{
+		{"invalid tag", Rule{Tag: "VoIP", Match: Match{Ports: []uint16{5060}}}, types.ErrInvalidTag},
+		{"empty tag", Rule{Match: Match{Ports: []uint16{5060}}}, types.ErrInvalidTag},
+		{"empty match", Rule{Tag: "voip"}, ErrEmptyMatch},
+		{"invalid proto", Rule{Tag: "voip", Match: Match{Protos: []string{"sip"}}}, ErrInvalidProto},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.rule.Validate(), test.expected)
+			_, err := New("eth0", []Rule{test.rule})
+			require.ErrorIs(t, err, test.expected)
+		})
+	}
+
+	// invalid prefixes are reported by the filter package
+	require.Error(t, Rule{Tag: "backup", Match: Match{CIDRs: []string{"10.0.0.0/33"}}}.Validate())
+}
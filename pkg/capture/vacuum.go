@@ -0,0 +1,31 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+)
+
+// ErrVacuumNotSupported denotes that the writeout handler of the manager does not support vacuuming
+var ErrVacuumNotSupported = errors.New("writeout handler does not support vacuuming")
+
+// Vacuum reclaims disk space from the storage of the writeout handler: All data of interfaces no longer
+// part of the configuration that is older than minAge is removed and all remaining data is compacted.
+// In dry-run mode, the space that would be reclaimed is reported without modifying any data
+func (cm *Manager) Vacuum(ctx context.Context, dryRun bool, minAge time.Duration) (vacuum.Results, error) {
+	vacuumer, ok := cm.writeoutHandler.(writeout.Vacuumer)
+	if !ok {
+		return nil, ErrVacuumNotSupported
+	}
+
+	ifaceConfigs := cm.Config()
+	ifaces := make([]string, 0, len(ifaceConfigs))
+	for iface := range ifaceConfigs {
+		ifaces = append(ifaces, iface)
+	}
+
+	return vacuumer.Vacuum(ctx, ifaces, vacuum.WithDryRun(dryRun), vacuum.WithMinAge(minAge))
+}
@@ -0,0 +1,261 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+)
+
+// errCaptureWedged denotes that the processing routine of a stalled capture did not conclude after its
+// capture source was closed, hence the capture cannot be reinitialized
+var errCaptureWedged = errors.New("processing routine did not conclude after closing the capture source")
+
+// watchdogState tracks the progress of the processing routine of a capture with a configured watchdog and
+// whether its capture source is being reinitialized. Apart from the atomic counters, all fields are protected
+// by the embedded mutex, which is held for the full duration of a lock() / unlock() sequence
+type watchdogState struct {
+	cfg *config.WatchdogConfig
+
+	heartbeat atomic.Uint64 // incremented by the processing routine whenever the capture source returns
+	recovered atomic.Uint64 // number of times the capture source was reinitialized automatically
+
+	primed       bool         // indicates if a baseline of the heartbeat / link activity has been established
+	lastBeat     uint64       // heartbeat as of the previous check
+	lastProgress time.Time    // time at which the processing routine was last observed to progress (or the link to be idle)
+	link         linkActivity // activity of the link as of the previous check
+
+	recovering bool // indicates if the capture source has been closed in order to be reinitialized
+
+	stop   chan struct{} // closed once the capture is closed (stopping the watchdog)
+	closed bool
+
+	sync.Mutex
+}
+
+func newWatchdogState(cfg *config.WatchdogConfig) *watchdogState {
+	return &watchdogState{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+	}
+}
+
+// beat records progress of the processing routine
+func (w *watchdogState) beat() {
+	w.heartbeat.Add(1)
+}
+
+// stalled determines if the processing routine has not progressed for at least the configured timeout
+// although there was traffic on the link in the meantime
+func (w *watchdogState) stalled(t time.Time, activity linkActivity) bool {
+	beat := w.heartbeat.Load()
+	traffic := activity.rxPackets != w.link.rxPackets || activity.txPackets != w.link.txPackets
+	w.link = activity
+
+	if !w.primed || beat != w.lastBeat || !traffic {
+		w.primed, w.lastBeat, w.lastProgress = true, beat, t
+		return false
+	}
+	return t.Sub(w.lastProgress) >= w.cfg.Timeout
+}
+
+// halt stops the watchdog
+func (w *watchdogState) halt() {
+	if !w.closed {
+		close(w.stop)
+		w.closed = true
+	}
+}
+
+// haltWatchdog stops the watchdog of the capture (if any)
+func (c *Capture) haltWatchdog() {
+	if c.watchdog == nil {
+		return
+	}
+
+	c.watchdog.Lock()
+	c.watchdog.halt()
+	c.watchdog.Unlock()
+
+	promAutoRecoveries.DeleteLabelValues(c.iface)
+}
+
+// isRecovering returns if the capture source has been closed by the watchdog in order to be reinitialized
+func (c *Capture) isRecovering() bool {
+	if c.watchdog == nil {
+		return false
+	}
+
+	c.watchdog.Lock()
+	defer c.watchdog.Unlock()
+
+	return c.watchdog.recovering
+}
+
+// autoRecovered returns the number of times the capture source was reinitialized by the watchdog
+func (c *Capture) autoRecovered() uint64 {
+	if c.watchdog == nil {
+		return 0
+	}
+	return c.watchdog.recovered.Load()
+}
+
+// abortIfStalled closes the capture source if the processing routine has stalled (c.f. watchdogState.stalled()),
+// stopping the processing routine such that the capture can be reinitialized. It must not be called while the
+// capture is locked
+func (c *Capture) abortIfStalled(t time.Time) (bool, error) {
+
+	// Captures in standby have no processing routine that could stall (lock order as in lock())
+	if c.standby != nil {
+		c.standby.Lock()
+		defer c.standby.Unlock()
+
+		if c.standby.released {
+			return false, nil
+		}
+	}
+
+	c.watchdog.Lock()
+	defer c.watchdog.Unlock()
+
+	if c.watchdog.recovering || c.watchdog.closed {
+		return false, nil
+	}
+
+	activity, err := c.linkActivity()
+	if err != nil {
+		return false, err
+	}
+	if !c.watchdog.stalled(t, activity) {
+		return false, nil
+	}
+
+	// The processing routine is only notified that it was stopped deliberately once it has concluded
+	// (since the mutex is held until then)
+	c.watchdog.recovering = true
+	if err := c.captureHandle.Close(); err != nil {
+		c.watchdog.recovering = false
+		return false, err
+	}
+
+	concluded := make(chan struct{})
+	go func() {
+		c.wgProc.Wait()
+		close(concluded)
+	}()
+	select {
+	case <-concluded:
+	case <-time.After(c.watchdog.cfg.Timeout):
+
+		// The capture source is retained, since the processing routine may still access it (in which
+		// case the capture remains in recovery until it is closed)
+		return true, errCaptureWedged
+	}
+
+	c.captureHandle = nil
+	c.statusCache.invalidate()
+
+	return true, nil
+}
+
+// recoverSource reinitializes the capture source of a capture aborted by the watchdog and restarts packet
+// processing, returning the error channel of the processing routine (c.f. process())
+func (c *Capture) recoverSource() (<-chan error, error) {
+	c.watchdog.Lock()
+	defer c.watchdog.Unlock()
+
+	if c.watchdog.closed {
+		return nil, errCaptureClosed
+	}
+	if err := c.initSource(); err != nil {
+		return nil, err
+	}
+	c.watchdog.recovering, c.watchdog.primed = false, false
+	c.watchdog.recovered.Add(1)
+	c.statusCache.invalidate()
+
+	promAutoRecoveries.WithLabelValues(c.iface).Inc()
+
+	return c.process(), nil
+}
+
+// statusRecovering is the equivalent of status() for a capture whose capture source is being reinitialized
+func (c *Capture) statusRecovering() *capturetypes.CaptureStats {
+	cpuTime := c.cpu.sample()
+	c.stats.CPUTimeTotal += cpuTime
+
+	return &capturetypes.CaptureStats{
+		StartedAt:      c.startedAt,
+		ReceivedTotal:  c.stats.ReceivedTotal,
+		ProcessedTotal: c.stats.ProcessedTotal,
+		DroppedTotal:   c.stats.DroppedTotal,
+		FilteredTotal:  c.stats.FilteredTotal,
+		CPUTime:        cpuTime,
+		CPUTimeTotal:   c.stats.CPUTimeTotal,
+		ParsingErrors:  c.stats.ParsingErrors,
+		AutoRecovered:  c.autoRecovered(),
+	}
+}
+
+// watch periodically checks the progress of the processing routine of a capture with a configured watchdog,
+// closing its capture source if it stalled. The capture is then reinitialized by its error logging routine
+// (c.f. logErrors())
+func (cm *Manager) watch(ctx context.Context, mc *Capture) {
+	logger := logging.FromContext(ctx)
+
+	ticker := mc.clock.NewTicker(mc.watchdog.cfg.CheckIntervalOrDefault())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-mc.watchdog.stop:
+			return
+		case <-ticker.C():
+			aborted, err := mc.abortIfStalled(mc.clock.Now())
+			if err != nil {
+				logger.Errorf("failed to abort stalled capture: %s", err)
+				continue
+			}
+			if aborted {
+				logger.With("timeout", mc.watchdog.cfg.Timeout.String()).Warn("packet processing stalled despite traffic on interface, reinitializing capture")
+			}
+		}
+	}
+}
+
+// awaitRecovery reinitializes the capture source of a capture aborted by the watchdog (retrying in the check
+// interval of the watchdog upon failure). It returns the error channel of the resumed processing routine or
+// nil if the capture was closed in the meantime
+func (cm *Manager) awaitRecovery(ctx context.Context, mc *Capture) <-chan error {
+	logger := logging.FromContext(ctx)
+
+	ticker := mc.clock.NewTicker(mc.watchdog.cfg.CheckIntervalOrDefault())
+	defer ticker.Stop()
+
+	for {
+		errsChan, err := mc.recoverSource()
+		if err == nil {
+			logger.With("auto_recovered", mc.autoRecovered()).Info("reinitialized stalled capture")
+			return errsChan
+		}
+		if errors.Is(err, errCaptureClosed) {
+			return nil
+		}
+		logger.Errorf("failed to reinitialize stalled capture: %s", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-mc.watchdog.stop:
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
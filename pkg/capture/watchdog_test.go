@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogStalled(t *testing.T) {
+	start := time.Now()
+	state := newWatchdogState(&config.WatchdogConfig{Timeout: time.Minute})
+	require.Equal(t, config.DefaultWatchdogCheckInterval, state.cfg.CheckIntervalOrDefault())
+
+	// the first check only establishes the baseline
+	require.False(t, state.stalled(start, linkActivity{rxPackets: 100}))
+
+	// traffic without progress of the processing routine is tolerated up to the timeout
+	require.False(t, state.stalled(start.Add(30*time.Second), linkActivity{rxPackets: 200}))
+	require.True(t, state.stalled(start.Add(time.Minute), linkActivity{rxPackets: 300}))
+
+	// progress of the processing routine resets the timeout
+	state.beat()
+	require.False(t, state.stalled(start.Add(90*time.Second), linkActivity{rxPackets: 400}))
+	require.False(t, state.stalled(start.Add(2*time.Minute), linkActivity{rxPackets: 500}))
+	require.True(t, state.stalled(start.Add(150*time.Second), linkActivity{rxPackets: 500, txPackets: 1}))
+
+	// so does an idle link (since the processing routine legitimately waits for packets)
+	require.False(t, state.stalled(start.Add(10*time.Minute), linkActivity{rxPackets: 500, txPackets: 1}))
+	require.False(t, state.stalled(start.Add(10*time.Minute+30*time.Second), linkActivity{rxPackets: 600, txPackets: 1}))
+
+	state.halt()
+	state.halt()
+	_, open := <-state.stop
+	require.False(t, open)
+}
+
+func TestWatchdogStatus(t *testing.T) {
+	c := newCapture("eth0", config.CaptureConfig{
+		Watchdog: &config.WatchdogConfig{Timeout: time.Minute, CheckInterval: time.Second},
+	})
+	require.False(t, c.isRecovering())
+
+	c.stats.ReceivedTotal, c.stats.ProcessedTotal = 100, 90
+	c.watchdog.recovering = true
+	c.watchdog.recovered.Add(2)
+	require.True(t, c.isRecovering())
+
+	// a capture being reinitialized can be locked / queried without any capture source or processing routine
+	c.lock()
+	stats, err := c.status()
+	c.unlock()
+	require.Nil(t, err)
+	require.Zero(t, stats.Received)
+	require.Equal(t, uint64(100), stats.ReceivedTotal)
+	require.Equal(t, uint64(90), stats.ProcessedTotal)
+	require.Equal(t, uint64(2), stats.AutoRecovered)
+
+	// the watchdog doesn't interfere with a capture that is already being reinitialized
+	aborted, err := c.abortIfStalled(time.Now())
+	require.Nil(t, err)
+	require.False(t, aborted)
+
+	require.Nil(t, c.close())
+	_, err = c.recoverSource()
+	require.ErrorIs(t, err, errCaptureClosed)
+}
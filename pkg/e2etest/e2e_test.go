@@ -285,7 +285,7 @@ func testE2E(t *testing.T, valFilterDescriptor int, datasets ...[]byte) {
 		"-d", tempDir,
 		"-n", strconv.Itoa(100000),
 		"-s", "packets",
-		"sip,dip,dport,proto",
+		"sip,dip,dport,proto,iface,hostname,hostid",
 	}
 	dir := ""
 	switch valFilterDescriptor {
@@ -527,6 +527,14 @@ func newPcapSource(t testing.TB, name string, data []byte) (res *mockIface) {
 		require.Nil(t, err)
 		*res.src = *mockSrc
 
+		// first fragments of fragmented IPv6 packets (keyed by their fragment hash), used to attribute
+		// subsequent fragments to the same flow
+		type firstFragment struct {
+			hash    capturetypes.EPHash
+			auxInfo byte
+		}
+		fragments := make(map[capturetypes.EPHash]firstFragment)
+
 		pkt := mockSrc.NewPacket()
 		mockSrc.PacketAddCallbackFn(func(payload []byte, totalLen uint32, pktType, ipLayerOffset byte) {
 
@@ -535,16 +543,26 @@ func newPcapSource(t testing.TB, name string, data []byte) (res *mockIface) {
 
 			pkt = slimcap.NewIPPacket(pkt, payload, pktType, int(totalLen), ipLayerOffset)
 			hash, isIPv4, auxInfo, errno := capture.ParsePacket(pkt.IPLayer())
+			if errno == capturetypes.ErrnoPacketFragment {
+				errno = capturetypes.ErrnoPacketFragmentIgnore
+				if fragment, exists := fragments[hash]; exists {
+					hash, auxInfo, errno = fragment.hash, fragment.auxInfo, capturetypes.ErrnoOK
+				}
+			} else if errno == capturetypes.ErrnoOK && !isIPv4 {
+				if fragID, isFirstFragment := capture.ParseIPv6FragmentID(pkt.IPLayer()); isFirstFragment {
+					fragHash := hash
+					copy(fragHash[32:36], fragID[:])
+					fragments[fragHash] = firstFragment{hash: hash, auxInfo: auxInfo}
+				}
+			}
 			if errno > capturetypes.ErrnoOK {
 				res.tracking.nErr++
-				if errno != capturetypes.ErrnoPacketFragmentIgnore {
+				if errno.ParsingFailed() {
 					res.tracking.nErrTracked++
 				}
 				return
 			}
-			if errno != capturetypes.ErrnoPacketFragmentIgnore {
-				res.tracking.nProcessed++
-			}
+			res.tracking.nProcessed++
 
 			hashReverse := hash.Reverse()
 			if direction := capturetypes.ClassifyPacketDirection(hash, isIPv4, auxInfo); direction != capturetypes.DirectionUnknown {
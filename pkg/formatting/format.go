@@ -68,6 +68,23 @@ func Size(size uint64) string {
 	return fmt.Sprintf("%.2f %s", sizeF, units[count])
 }
 
+// SizeBinary prints out size in a human-readable format using binary (IEC)
+// prefixes (e.g. 10.00 MiB)
+func SizeBinary(size uint64) string {
+	count := 0
+	var sizeF = float64(size)
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB", "ZiB", "YiB"}
+
+	for size >= 1024 {
+		size /= 1024
+		sizeF /= 1024.0
+		count++
+	}
+
+	return fmt.Sprintf("%.2f %s", sizeF, units[count])
+}
+
 // Duration prints out d in a human-readable duration format
 func Duration(d time.Duration) string {
 	// enhance the classic duration Stringer to print out days
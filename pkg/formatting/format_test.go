@@ -49,6 +49,28 @@ func TestSize(t *testing.T) {
 	}
 }
 
+func TestSizeBinary(t *testing.T) {
+	var tests = []struct {
+		input    uint64
+		expected string
+	}{
+		{0, "0.00 B"},
+		{231, "231.00 B"},
+		{1024, "1.00 KiB"},
+		{2338231, "2.23 MiB"},
+		{28319384728, "26.37 GiB"},
+		{2832828383338231, "2.52 PiB"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.expected, func(t *testing.T) {
+			actual := SizeBinary(test.input)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
+
 func TestDuration(t *testing.T) {
 	var tests = []struct {
 		input    time.Duration
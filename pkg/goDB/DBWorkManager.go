@@ -15,9 +15,12 @@ package goDB
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"math/bits"
+	"net/netip"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -43,6 +46,10 @@ const (
 	// transmitting the resulting map to for further reduction / aggregtion
 	WorkBulkSize = 32
 
+	// blockQueueDepth denotes the number of decoded blocks (per CPU processing unit) that can be
+	// queued between the IO and CPU processing units
+	blockQueueDepth = 4
+
 	// defaultEncoderType denotes the default encoder / compressor
 	defaultEncoderType = encoders.EncoderTypeLZ4
 )
@@ -57,16 +64,45 @@ type DBWorkManager struct {
 	query              *Query
 	dbIfaceDir         string // path to interface directory in DB, e.g. /path/to/db/eth0
 	iface              string
+	fsys               storage.FS
 	workloadChan       chan DBWorkload
-	numProcessingUnits int
+	numProcessingUnits int // IO processing units (reading / decompressing blocks)
+	numCPUWorkers      int // CPU processing units (filtering / aggregating blocks)
 
 	tFirstCovered, tLastCovered int64
+	link                        *types.LinkInfo
+
+	idle   []gpfile.IdleMetadata
+	idleMu sync.Mutex
+
+	hostnames   map[netip.Addr]gpfile.Hostname
+	hostnamesMu sync.Mutex
+
+	tFirstRead, tLastRead int64 // time range of the blocks actually read (if any)
+	readMu                sync.Mutex
 
 	nWorkloads          uint64
 	nWorkloadsProcessed atomic.Uint64
+	nCorruptBlocks      atomic.Uint64
+	nInterpolatedBlocks atomic.Uint64
+
+	// progress tracking
+	nDirs           int
+	nDirsProcessed  atomic.Uint64
+	nBytesProcessed atomic.Uint64
+	nRowsAggregated atomic.Uint64
 }
 
-// NewDBWorkManager sets up a new work manager for executing queries
+// WorkProgress summarizes the progress of the workloads of a DBWorkManager
+type WorkProgress struct {
+	DirsTotal      int
+	DirsProcessed  int
+	BytesProcessed uint64
+	RowsAggregated uint64
+}
+
+// NewDBWorkManager sets up a new work manager for executing queries. By default, the number of CPU
+// processing units matches the number of IO processing units
 func NewDBWorkManager(query *Query, dbpath string, iface string, numProcessingUnits int) (*DBWorkManager, error) {
 
 	// Explicitly handle invalid number of processing units (to avoid deadlock)
@@ -78,21 +114,101 @@ func NewDBWorkManager(query *Query, dbpath string, iface string, numProcessingUn
 		query:              query,
 		dbIfaceDir:         filepath.Clean(filepath.Join(dbpath, iface)),
 		iface:              iface,
+		fsys:               storage.DefaultFS,
 		workloadChan:       make(chan DBWorkload, numProcessingUnits*64), // 64 is relatively arbitrary (but we're just sending quite basic objects)
 		numProcessingUnits: numProcessingUnits,
+		numCPUWorkers:      numProcessingUnits,
 	}, nil
 }
 
+// CPUWorkers overrides the number of processing units filtering / aggregating the blocks read by the
+// IO processing units. Non-positive values are ignored
+func (w *DBWorkManager) CPUWorkers(numCPUWorkers int) *DBWorkManager {
+	if numCPUWorkers > 0 {
+		w.numCPUWorkers = numCPUWorkers
+	}
+	return w
+}
+
+// FS overrides the default (on-disk) file system the DB is read from
+func (w *DBWorkManager) FS(fsys storage.FS) *DBWorkManager {
+	w.fsys = fsys
+	return w
+}
+
 // GetNumWorkers returns the number of workloads available to the outside world for loop bounds etc.
 func (w *DBWorkManager) GetNumWorkers() uint64 {
 	return w.nWorkloads
 }
 
+// NumCorruptBlocks returns the number of blocks skipped due to failed checksum validation
+func (w *DBWorkManager) NumCorruptBlocks() uint64 {
+	return w.nCorruptBlocks.Load()
+}
+
+// NumInterpolatedBlocks returns the number of blocks whose counters were scaled since they only partially
+// overlapped the time range of the query (c.f. Query.Interpolate())
+func (w *DBWorkManager) NumInterpolatedBlocks() uint64 {
+	return w.nInterpolatedBlocks.Load()
+}
+
+// Progress returns the progress of the workloads processed so far. It is safe to call while
+// the workloads are being processed
+func (w *DBWorkManager) Progress() WorkProgress {
+	return WorkProgress{
+		DirsTotal:      w.nDirs,
+		DirsProcessed:  int(w.nDirsProcessed.Load()),
+		BytesProcessed: w.nBytesProcessed.Load(),
+		RowsAggregated: w.nRowsAggregated.Load(),
+	}
+}
+
 // GetCoveredTimeInterval can be used to determine the time span actually covered by the query
 func (w *DBWorkManager) GetCoveredTimeInterval() (time.Time, time.Time) {
 	return time.Unix(w.tFirstCovered-DBWriteInterval, 0), time.Unix(w.tLastCovered, 0)
 }
 
+// GetReadTimeInterval returns the time span covered by the blocks actually read (as opposed to the time span
+// covered by the query) and whether any block was read at all. It is only populated once all worker read jobs
+// have been executed
+func (w *DBWorkManager) GetReadTimeInterval() (time.Time, time.Time, bool) {
+	w.readMu.Lock()
+	defer w.readMu.Unlock()
+
+	if w.tLastRead == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(w.tFirstRead-DBWriteInterval, 0), time.Unix(w.tLastRead, 0), true
+}
+
+// Link returns the properties of the link backing the interface at the end of the covered time
+// interval (if recorded)
+func (w *DBWorkManager) Link() *types.LinkInfo {
+	return w.link
+}
+
+// Idle returns the spans of idle rotations (i.e. rotations during which the interface was captured
+// without observing any traffic) within the covered time interval, ordered by time. It is only
+// populated once all worker read jobs have been executed
+func (w *DBWorkManager) Idle() []gpfile.IdleMetadata {
+	w.idleMu.Lock()
+	defer w.idleMu.Unlock()
+
+	sort.Slice(w.idle, func(i, j int) bool {
+		return w.idle[i].From < w.idle[j].From
+	})
+	return w.idle
+}
+
+// Hostnames returns the hostnames of the IP addresses (as resolved at capture time) in effect at the
+// end of the covered time interval. It is only populated once all worker read jobs have been executed
+func (w *DBWorkManager) Hostnames() map[netip.Addr]gpfile.Hostname {
+	w.hostnamesMu.Lock()
+	defer w.hostnamesMu.Unlock()
+
+	return w.hostnames
+}
+
 // CreateWorkerJobs sets up all workloads for query execution
 func (w *DBWorkManager) CreateWorkerJobs(tfirst int64, tlast int64) (nonempty bool, err error) {
 	// Make sure the channel is closed at the end of this function no matter what to
@@ -106,7 +222,7 @@ func (w *DBWorkManager) CreateWorkerJobs(tfirst int64, tlast int64) (nonempty bo
 	workloadBulk := make([]*gpfile.GPDir, 0, WorkBulkSize)
 
 	walkFunc := func(numDirs int, dayTimestamp int64) error {
-		curDir = gpfile.NewDir(w.dbIfaceDir, dayTimestamp, gpfile.ModeRead)
+		curDir = gpfile.NewDir(w.dbIfaceDir, dayTimestamp, gpfile.ModeRead, gpfile.WithFS(w.fsys), gpfile.WithMmap(w.query.mmap))
 
 		// For the first and last item, check out the GPDir metadata for the actual first and
 		// last block timestamp to cover (and adapt variables accordingly)
@@ -117,6 +233,11 @@ func (w *DBWorkManager) CreateWorkerJobs(tfirst int64, tlast int64) (nonempty bo
 				return fmt.Errorf("failed to open first GPDir %s to ascertain query block timing: %w", curDir.Path(), err)
 			}
 			dirFirst, _ := curDir.TimeRange()
+
+			// idle rotations preceding the first block are covered as well
+			if len(curDir.Idle) > 0 {
+				dirFirst = min(dirFirst, curDir.Idle[0].From)
+			}
 			if tfirst < dirFirst {
 				w.tFirstCovered = dirFirst
 			}
@@ -138,6 +259,7 @@ func (w *DBWorkManager) CreateWorkerJobs(tfirst int64, tlast int64) (nonempty bo
 	if err != nil {
 		return false, err
 	}
+	w.nDirs = numDirs
 
 	// Flush any remaining work
 	if len(workloadBulk) > 0 {
@@ -155,6 +277,14 @@ func (w *DBWorkManager) CreateWorkerJobs(tfirst int64, tlast int64) (nonempty bo
 			return false, fmt.Errorf("failed to open last GPDir %s to ascertain query block timing: %w", curDir.Path(), err)
 		}
 		_, dirLast := curDir.TimeRange()
+		if link, exists := curDir.LinkAt(min(tlast, dirLast)); exists {
+			w.link = &link
+		}
+
+		// idle rotations following the last block are covered as well
+		if n := len(curDir.Idle); n > 0 {
+			dirLast = max(dirLast, curDir.Idle[n-1].To)
+		}
 		if tlast > dirLast {
 			w.tLastCovered = dirLast
 		}
@@ -174,7 +304,7 @@ type dbWalkFunc func(numDirs int, dayTimestamp int64) error
 
 func (w *DBWorkManager) walkDB(tfirst, tlast int64, fn dbWalkFunc) (numDirs int, err error) {
 	// Get list of years in main directory (ordered by directory name, i.e. time)
-	yearList, err := os.ReadDir(w.dbIfaceDir)
+	yearList, err := w.fsys.ReadDir(w.dbIfaceDir)
 	if err != nil {
 		return numDirs, err
 	}
@@ -198,7 +328,7 @@ func (w *DBWorkManager) walkDB(tfirst, tlast int64, fn dbWalkFunc) (numDirs int,
 		}
 
 		// Get list of months in year directory (ordered by directory name, i.e. time)
-		monthList, err := os.ReadDir(filepath.Join(w.dbIfaceDir, year.Name()))
+		monthList, err := w.fsys.ReadDir(filepath.Join(w.dbIfaceDir, year.Name()))
 		if err != nil {
 			return numDirs, err
 		}
@@ -219,7 +349,7 @@ func (w *DBWorkManager) walkDB(tfirst, tlast int64, fn dbWalkFunc) (numDirs int,
 			}
 
 			// Get list of days in month directory (ordered by directory name, i.e. time)
-			dirList, err := os.ReadDir(filepath.Join(w.dbIfaceDir, year.Name(), month.Name()))
+			dirList, err := w.fsys.ReadDir(filepath.Join(w.dbIfaceDir, year.Name(), month.Name()))
 			if err != nil {
 				return numDirs, err
 			}
@@ -255,9 +385,9 @@ func (w *DBWorkManager) ReadMetadata(tfirst int64, tlast int64) (*InterfaceMetad
 	query := NewMetadataQuery()
 
 	// loop over directory list in order to create the timestamp pairs
-	var gpFileOptions []gpfile.Option
+	gpFileOptions := []gpfile.Option{gpfile.WithFS(w.fsys)}
 	if !query.lowMem {
-		memPool := concurrency.NewMemPool(len(query.columnIndices))
+		memPool := newReadMemPool(len(query.columnIndices))
 		gpFileOptions = append(gpFileOptions, gpfile.WithReadAll(memPool))
 		defer memPool.Clear()
 	}
@@ -352,6 +482,11 @@ func (w *DBWorkManager) ReadMetadata(tfirst int64, tlast int64) (*InterfaceMetad
 			w.tLastCovered = dirLast
 		}
 
+		// the link properties in effect for the last block covered apply to the time range
+		if link, exists := curDir.LinkAt(w.tLastCovered); exists {
+			aggMetadata.Link = &link
+		}
+
 		if err := curDir.Close(); err != nil {
 			return nil, fmt.Errorf("failed to close last GPDir %s after ascertaining query block timing: %w", curDir.Path(), err)
 		}
@@ -368,7 +503,7 @@ func (w *DBWorkManager) ReadMetadata(tfirst int64, tlast int64) (*InterfaceMetad
 	return aggMetadata, nil
 }
 
-// NOTE: contrary to it's bigger sister readBlocksAndEvaluate, the function assumes that the workDir is already open.
+// NOTE: contrary to it's bigger sister readBlocks, the function assumes that the workDir is already open.
 // This is owed to the nature of its calling function
 func (w *DBWorkManager) readMetadataAndEvaluate(workDir *gpfile.GPDir, blocks []storage.BlockAtTime, offset int, aggMetadata *InterfaceMetadata,
 	statsOpFunc func(*InterfaceMetadata, gpfile.Stats) gpfile.Stats,
@@ -406,7 +541,7 @@ func (w *DBWorkManager) readMetadataAndEvaluate(workDir *gpfile.GPDir, blocks []
 		stats.Traffic.NumV4Entries = workDir.NumIPv4EntriesAtIndex(ind)
 		stats.Traffic.NumV6Entries = workDir.NumIPv6EntriesAtIndex(ind)
 
-		numEntries := bitpack.Len(colBlocks[types.BytesRcvdColIdx])
+		numEntries := bitpack.Len(colBlocks[w.query.counterIndices[0]])
 		for _, colIdx := range w.query.columnIndices {
 			if colIdx.IsCounterCol() {
 				if bitpack.Len(colBlocks[colIdx]) != numEntries {
@@ -425,10 +560,10 @@ func (w *DBWorkManager) readMetadataAndEvaluate(workDir *gpfile.GPDir, blocks []
 			continue
 		}
 
-		bytesRcvdValues = bitpack.UnpackInto(colBlocks[types.BytesRcvdColIdx], bytesRcvdValues)
-		bytesSentValues = bitpack.UnpackInto(colBlocks[types.BytesSentColIdx], bytesSentValues)
-		pktsRcvdValues = bitpack.UnpackInto(colBlocks[types.PacketsRcvdColIdx], pktsRcvdValues)
-		pktsSentValues = bitpack.UnpackInto(colBlocks[types.PacketsSentColIdx], pktsSentValues)
+		bytesRcvdValues = w.unpackCounter(&colBlocks, types.BytesRcvdColIdx, bytesRcvdValues, numEntries)
+		bytesSentValues = w.unpackCounter(&colBlocks, types.BytesSentColIdx, bytesSentValues, numEntries)
+		pktsRcvdValues = w.unpackCounter(&colBlocks, types.PacketsRcvdColIdx, pktsRcvdValues, numEntries)
+		pktsSentValues = w.unpackCounter(&colBlocks, types.PacketsSentColIdx, pktsSentValues, numEntries)
 
 		for i := 0; i < numEntries; i++ {
 			stats.Counts = stats.Counts.Add(types.Counters{
@@ -447,8 +582,43 @@ func (w *DBWorkManager) readMetadataAndEvaluate(workDir *gpfile.GPDir, blocks []
 	return aggMetadata, nil
 }
 
-// main query processing
-func (w *DBWorkManager) grabAndProcessWorkload(ctx context.Context, wg *sync.WaitGroup, workloadChan <-chan DBWorkload, mapChan chan hashmap.AggFlowMapWithMetadata) {
+// decodedBlock stores the (decompressed) column data of a single block, read by an IO worker and
+// handed over to a CPU worker for evaluation
+type decodedBlock struct {
+	day          string // path of the day partition the block belongs to
+	timestamp    int64
+	numV4Entries int
+	numEntries   int
+	tagIDs       *[types.MaxTags + 1]byte
+	data         [types.ColIdxCount][]byte
+}
+
+// newReadMemPool returns the memory pool used by a reader to access the column files of the database,
+// drawing from the global pool of read buffers (if limited, c.f. SetReadBufferLimit)
+func newReadMemPool(nColumns int) concurrency.MemPoolGCable {
+	if pool := readBuffers.Load(); pool != nil {
+		return pool.Lease()
+	}
+	return concurrency.NewMemPool(nColumns)
+}
+
+// decodedBlockPool allows the column buffers of decoded blocks to be reused once they have been evaluated
+var decodedBlockPool = sync.Pool{
+	New: func() any {
+		return new(decodedBlock)
+	},
+}
+
+func releaseDecodedBlock(block *decodedBlock) {
+	for colIdx := range block.data {
+		block.data[colIdx] = block.data[colIdx][:0]
+	}
+	block.tagIDs = nil
+	decodedBlockPool.Put(block)
+}
+
+// IO stage: reading and decompression of blocks -----------------------------------------
+func (w *DBWorkManager) readWorkloads(ctx context.Context, wg *sync.WaitGroup, blockChan chan<- *decodedBlock, mapChan chan hashmap.AggFlowMapWithMetadata) {
 	go func() {
 		defer wg.Done()
 
@@ -458,81 +628,57 @@ func (w *DBWorkManager) grabAndProcessWorkload(ctx context.Context, wg *sync.Wai
 		if err != nil {
 			logger.Error(err)
 			mapChan <- hashmap.NilAggFlowMapWithMetadata
+			return
 		}
 
+		// Memory-mapped files are read directly, so no memory pool is required
 		var memPool concurrency.MemPoolGCable
-		if !w.query.lowMem {
-			memPool = concurrency.NewMemPool(len(w.query.columnIndices))
+		if !w.query.lowMem && !w.query.mmap {
+			memPool = newReadMemPool(len(w.query.columnIndices))
 		}
 		defer func() {
 			if memPool != nil {
 				memPool.Clear()
 			}
-			if cerr := enc.Close(); cerr != nil && err == nil {
-				err = cerr
+			if cerr := enc.Close(); cerr != nil {
+				logger.Error(cerr)
 			}
 		}()
 
-		for workload := range workloadChan {
-			resultMap := hashmap.NewAggFlowMapWithMetadata()
+		for workload := range w.workloadChan {
 			for _, workDir := range workload.workDirs {
-				select {
-				case <-ctx.Done():
 
-					// query was cancelled, exit
-					logger.Infof("query cancelled (workload %d / %d)...", w.nWorkloadsProcessed.Load(), w.nWorkloads)
-					return
-				default:
+				// check if a memory pool is available
+				if memPool != nil {
+					workDir.SetMemPool(memPool)
+				}
 
-					// check if a memory pool is available
-					if memPool != nil {
-						workDir.SetMemPool(memPool)
-					}
+				// if there is an error during one of the read jobs, throw a syslog message and terminate
+				if err := w.readBlocks(ctx, workDir, enc, blockChan); err != nil {
+					if ctx.Err() != nil {
 
-					// if there is an error during one of the read jobs, throw a syslog message and terminate
-					err := w.readBlocksAndEvaluate(workDir, enc, &resultMap)
-					if err != nil {
-						logger.Error(err)
-						mapChan <- hashmap.NilAggFlowMapWithMetadata
+						// query was cancelled, exit
+						logger.Infof("query cancelled (workload %d / %d)...", w.nWorkloadsProcessed.Load(), w.nWorkloads)
 						return
 					}
+					logger.Error(err)
+					mapChan <- hashmap.NilAggFlowMapWithMetadata
+					return
 				}
+				w.nDirsProcessed.Add(1)
 			}
 
-			// Workload is counted, but we only add it to the final result if we got any entries
+			// Workload is counted once all of its blocks have been handed over for evaluation
 			w.nWorkloadsProcessed.Add(1)
-			if resultMap.Len() > 0 {
-				mapChan <- resultMap
-			}
 		}
 	}()
 }
 
-// ExecuteWorkerReadJobs runs the query concurrently with multiple sprocessing units
-func (w *DBWorkManager) ExecuteWorkerReadJobs(ctx context.Context, mapChan chan hashmap.AggFlowMapWithMetadata) {
-
-	var wg = new(sync.WaitGroup)
-	wg.Add(w.numProcessingUnits)
-	for i := 0; i < w.numProcessingUnits; i++ {
-		// start worker up
-		w.grabAndProcessWorkload(ctx, wg, w.workloadChan, mapChan)
-	}
-
-	// check if all workers are done
-	wg.Wait()
-}
-
-// Block evaluation and aggregation -----------------------------------------------------
-// this is where the actual reading and aggregation magic happens
-func (w *DBWorkManager) readBlocksAndEvaluate(workDir *gpfile.GPDir, enc encoder.Encoder, resultMap *hashmap.AggFlowMapWithMetadata) (err error) {
+// readBlocks reads and decompresses all blocks of a directory within the covered time range and
+// hands them over to the CPU workers
+func (w *DBWorkManager) readBlocks(ctx context.Context, workDir *gpfile.GPDir, enc encoder.Encoder, blockChan chan<- *decodedBlock) (err error) {
 	logger := logging.Logger()
 
-	var (
-		v4Key, v4ComparisonValue                                         = types.NewEmptyV4Key().ExtendEmpty(), types.NewEmptyV4Key().ExtendEmpty()
-		v6Key, v6ComparisonValue                                         = types.NewEmptyV6Key().ExtendEmpty(), types.NewEmptyV6Key().ExtendEmpty()
-		bytesRcvdValues, bytesSentValues, pktsRcvdValues, pktsSentValues []uint64
-	)
-
 	// Open GPDir (reading metadata in the process)
 	if err := workDir.Open(gpfile.WithEncoder(enc)); err != nil {
 		return err
@@ -543,14 +689,53 @@ func (w *DBWorkManager) readBlocksAndEvaluate(workDir *gpfile.GPDir, enc encoder
 		}
 	}()
 
-	// Set map metadata (and cross-check consistency for consecutive workloads)
-	if resultMap.Interface == "" {
-		resultMap.Interface = w.iface
-	} else if resultMap.Interface != w.iface {
-		return fmt.Errorf("discovered invalid workload for mismatching interfaces, want `%s`, have `%s`", resultMap.Interface, w.iface)
+	// Collect the idle rotations of this directory within the covered time range
+	if idle := workDir.IdleWithin(w.tFirstCovered, w.tLastCovered); len(idle) > 0 {
+		w.idleMu.Lock()
+		w.idle = append(w.idle, idle...)
+		w.idleMu.Unlock()
 	}
 
-	// Process the workload, looping over all blocks in this directory
+	// Collect the hostnames of this directory in effect at the end of the covered time range, names
+	// recorded in later directories taking precedence
+	if hostnames := workDir.HostnamesAt(w.tLastCovered); len(hostnames) > 0 {
+		w.hostnamesMu.Lock()
+		if w.hostnames == nil {
+			w.hostnames = make(map[netip.Addr]gpfile.Hostname, len(hostnames))
+		}
+		for ip, hostname := range hostnames {
+			if existing, exists := w.hostnames[ip]; !exists || existing.Timestamp < hostname.Timestamp {
+				w.hostnames[ip] = hostname
+			}
+		}
+		w.hostnamesMu.Unlock()
+	}
+
+	// Translate the tag dictionary of this directory to the (process-wide) tag IDs
+	tagIDs := new([types.MaxTags + 1]byte)
+	if w.query.hasAttrTag || w.query.hasCondTag {
+		for i, tag := range workDir.Tags {
+			id, err := types.InternTag(tag)
+			if err != nil {
+				logger.With("day", workDir, "tag", tag).Warnf("Failed to resolve tag: %s", err)
+				continue
+			}
+			tagIDs[i+1] = id
+		}
+	}
+
+	// Loop over all blocks in this directory
+	var tFirstRead, tLastRead int64
+	defer func() {
+		if tLastRead != 0 {
+			w.readMu.Lock()
+			if w.tLastRead == 0 || tFirstRead < w.tFirstRead {
+				w.tFirstRead = tFirstRead
+			}
+			w.tLastRead = max(w.tLastRead, tLastRead)
+			w.readMu.Unlock()
+		}
+	}()
 	for b, block := range workDir.BlockMetadata[0].Blocks() {
 
 		// If this block is outside of the rannge, skip it (only happens at the very first
@@ -558,170 +743,394 @@ func (w *DBWorkManager) readBlocksAndEvaluate(workDir *gpfile.GPDir, enc encoder
 		if block.Timestamp < w.tFirstCovered || block.Timestamp > w.tLastCovered {
 			continue
 		}
+		if tLastRead == 0 {
+			tFirstRead = block.Timestamp
+		}
+		tLastRead = block.Timestamp
 
-		var (
-			blocks      [types.ColIdxCount][]byte
-			blockBroken bool
-		)
+		decoded := decodedBlockPool.Get().(*decodedBlock)
+		decoded.day = workDir.Path()
+		decoded.timestamp = block.Timestamp
+		decoded.tagIDs = tagIDs
 
-		// Read the blocks from their files
+		// Read the blocks from their files. Since the underlying buffers are reused by the
+		// GPDir for the next block, the data is copied
+		var blockBroken bool
 		for _, colIdx := range w.query.columnIndices {
-
-			// Read the block from the file
-			if blocks[colIdx], err = workDir.ReadBlockAtIndex(colIdx, b); err != nil {
+			data, err := workDir.ReadBlockAtIndex(colIdx, b)
+			if err != nil {
 				blockBroken = true
+				if errors.Is(err, gpfile.ErrChecksumMismatch) {
+					w.nCorruptBlocks.Add(1)
+				}
 				logger.With("day", workDir, "block", block.Timestamp, "column", types.ColumnFileNames[colIdx]).Warnf("Failed to read column: %s", err)
 				break
 			}
+			decoded.data[colIdx] = append(decoded.data[colIdx][:0], data...)
 		}
 
-		// Check whether all blocks have matching number of entries
-		numV4Entries := int(workDir.NumIPv4EntriesAtIndex(b))
-		numEntries := bitpack.Len(blocks[types.BytesRcvdColIdx])
+		// In case any error was observed during reading or sanity checks, skip this whole block
+		if blockBroken || !w.checkBlock(decoded, int(workDir.NumIPv4EntriesAtIndex(b)), b) {
+			releaseDecodedBlock(decoded)
+			continue
+		}
+		var nBytes int
 		for _, colIdx := range w.query.columnIndices {
-			l := len(blocks[colIdx])
-			if colIdx.IsCounterCol() {
-				if bitpack.Len(blocks[colIdx]) != numEntries {
-					blockBroken = true
-					logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in column file. Expected %d, found %d", numEntries, bitpack.Len(blocks[colIdx]))
-					break
+			nBytes += len(decoded.data[colIdx])
+		}
+		w.nBytesProcessed.Add(uint64(nBytes))
+
+		select {
+		case blockChan <- decoded:
+		case <-ctx.Done():
+			releaseDecodedBlock(decoded)
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// checkBlock verifies that all columns of a decoded block have a matching number of entries
+func (w *DBWorkManager) checkBlock(decoded *decodedBlock, numV4Entries, b int) bool {
+	logger := logging.Logger()
+
+	numEntries := bitpack.Len(decoded.data[w.query.counterIndices[0]])
+	for _, colIdx := range w.query.columnIndices {
+		l := len(decoded.data[colIdx])
+
+		// An empty tag / TTL block denotes that none of the flows is tagged / has recorded TTLs
+		if colIdx >= types.TagColIdx && l == 0 {
+			continue
+		}
+		if colIdx.IsCounterCol() {
+			if bitpack.Len(decoded.data[colIdx]) != numEntries {
+				logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in column file. Expected %d, found %d", numEntries, bitpack.Len(decoded.data[colIdx]))
+				return false
+			}
+		} else {
+			if types.ColumnSizeofs[colIdx] == types.IPSizeOf {
+				if l != (numEntries-numV4Entries)*types.IPv6Width+numV4Entries*types.IPv4Width {
+					logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in variable block size file. Expected file length %d, have %d", (numEntries-numV4Entries)*types.IPv6Width+numV4Entries*types.IPv4Width, l)
+					return false
 				}
 			} else {
-				if types.ColumnSizeofs[colIdx] == types.IPSizeOf {
-					if l != (numEntries-numV4Entries)*types.IPv6Width+numV4Entries*types.IPv4Width {
-						blockBroken = true
-						logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in variable block size file. Expected file length %d, have %d", (numEntries-numV4Entries)*types.IPv6Width+numV4Entries*types.IPv4Width, l)
-						break
-					}
-				} else {
-					if l/types.ColumnSizeofs[colIdx] != numEntries {
-						blockBroken = true
-						logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in column file. Expected %d, found %d", numEntries, l/types.ColumnSizeofs[colIdx])
-						break
-					}
-					if l%types.ColumnSizeofs[colIdx] != 0 {
-						blockBroken = true
-						logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warn("Entry size does not evenly divide block size in column file")
-						break
-					}
+				if l/types.ColumnSizeofs[colIdx] != numEntries {
+					logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warnf("Incorrect number of entries in column file. Expected %d, found %d", numEntries, l/types.ColumnSizeofs[colIdx])
+					return false
+				}
+				if l%types.ColumnSizeofs[colIdx] != 0 {
+					logger.With("block", b, "column", types.ColumnFileNames[colIdx]).Warn("Entry size does not evenly divide block size in column file")
+					return false
 				}
 			}
 		}
+	}
 
-		// In case any error was observed during above sanity checks, skip this whole block
-		if blockBroken {
-			continue
-		}
+	decoded.numV4Entries, decoded.numEntries = numV4Entries, numEntries
+	return true
+}
+
+// CPU stage: filtering and aggregation of blocks ----------------------------------------
+func (w *DBWorkManager) evaluateBlocks(ctx context.Context, wg *sync.WaitGroup, blockChan <-chan *decodedBlock, mapChan chan hashmap.AggFlowMapWithMetadata) {
+	go func() {
+		defer wg.Done()
 
-		// Initialize any (static) key extensions potentially present in the query
-		if w.query.hasAttrTime {
-			v4Key = types.NewEmptyV4Key().Extend(block.Timestamp)
-			v6Key = types.NewEmptyV6Key().Extend(block.Timestamp)
-			if w.query.Conditional == nil {
-				v4ComparisonValue = types.NewEmptyV4Key().Extend(block.Timestamp)
-				v6ComparisonValue = types.NewEmptyV6Key().Extend(block.Timestamp)
+		var (
+			eval      = w.newBlockEvaluator()
+			resultMap = hashmap.NewAggFlowMapWithMetadata()
+			day       string
+			nDays     int
+		)
+		resultMap.Interface = w.iface
+
+		// The partial result is only transmitted for further reduction / aggregation if it has any entries
+		flush := func() {
+			if resultMap.Len() > 0 {
+				mapChan <- resultMap
+				resultMap = hashmap.NewAggFlowMapWithMetadata()
+				resultMap.Interface = w.iface
 			}
+			nDays = 0
 		}
 
-		bytesRcvdValues = bitpack.UnpackInto(blocks[types.BytesRcvdColIdx], bytesRcvdValues)
-		bytesSentValues = bitpack.UnpackInto(blocks[types.BytesSentColIdx], bytesSentValues)
-		pktsRcvdValues = bitpack.UnpackInto(blocks[types.PacketsRcvdColIdx], pktsRcvdValues)
-		pktsSentValues = bitpack.UnpackInto(blocks[types.PacketsSentColIdx], pktsSentValues)
+		// Blocks are consumed until the IO workers are done, even if the query was cancelled (in
+		// which case they are discarded), so that no IO worker is left behind
+		for block := range blockChan {
+			if ctx.Err() != nil {
+				releaseDecodedBlock(block)
+				continue
+			}
 
-		sipBlocks := blocks[types.SIPColIdx]
-		dipBlocks := blocks[types.DIPColIdx]
-		dportBlocks := blocks[types.DportColIdx]
-		protoBlocks := blocks[types.ProtoColIdx]
+			// Transmit the partial result once it covers a bulk of day partitions
+			if block.day != day {
+				if nDays == WorkBulkSize {
+					flush()
+				}
+				day = block.day
+				nDays++
+			}
 
-		// Determine start / end of block perusal - If the query is limited to either IPv4 or IPv6, adjust
-		// accordingly to skip irrelevant data that wouldn't satisfy the condition anyway
-		key, comparisonValue := v4Key, v4ComparisonValue
-		startEntry, isIPv4, condIsIPv4 := 0, true, true
-		if w.query.ipVersion == types.IPVersionV6 {
-			startEntry = numV4Entries
-		} else if w.query.ipVersion == types.IPVersionV4 {
-			numEntries = numV4Entries
+			eval.evaluate(block, &resultMap)
+			releaseDecodedBlock(block)
 		}
-		for i := startEntry; i < numEntries; i++ {
 
-			// If / when reaching the v4/v6 mark, switch to the IPv6 key / submap
-			if i == numV4Entries {
+		if ctx.Err() == nil {
+			flush()
+		}
+	}()
+}
+
+// ExecuteWorkerReadJobs runs the query concurrently with separate pools of IO processing units (reading /
+// decompressing blocks) and CPU processing units (filtering / aggregating blocks), connected by a bounded
+// channel in order to keep both the disks and the cores busy
+func (w *DBWorkManager) ExecuteWorkerReadJobs(ctx context.Context, mapChan chan hashmap.AggFlowMapWithMetadata) {
+
+	blockChan := make(chan *decodedBlock, w.numCPUWorkers*blockQueueDepth)
+
+	var ioWG, cpuWG = new(sync.WaitGroup), new(sync.WaitGroup)
+	ioWG.Add(w.numProcessingUnits)
+	for i := 0; i < w.numProcessingUnits; i++ {
+		w.readWorkloads(ctx, ioWG, blockChan, mapChan)
+	}
+	cpuWG.Add(w.numCPUWorkers)
+	for i := 0; i < w.numCPUWorkers; i++ {
+		w.evaluateBlocks(ctx, cpuWG, blockChan, mapChan)
+	}
+
+	// once all blocks have been read, the CPU workers are signalled to finish up
+	ioWG.Wait()
+	close(blockChan)
+
+	// check if all workers are done
+	cpuWG.Wait()
+}
+
+// Block evaluation and aggregation -----------------------------------------------------
+// this is where the actual filtering and aggregation magic happens
+
+// blockEvaluator holds the (reusable) state of a CPU worker evaluating blocks
+type blockEvaluator struct {
+	w *DBWorkManager
+
+	v4Key, v4ComparisonValue                                         types.ExtendedKey
+	v6Key, v6ComparisonValue                                         types.ExtendedKey
+	bytesRcvdValues, bytesSentValues, pktsRcvdValues, pktsSentValues []uint64
+}
+
+func (w *DBWorkManager) newBlockEvaluator() *blockEvaluator {
+	return &blockEvaluator{
+		w:                 w,
+		v4Key:             types.NewEmptyV4Key().ExtendEmpty(),
+		v4ComparisonValue: types.NewEmptyV4Key().ExtendEmpty(),
+		v6Key:             types.NewEmptyV6Key().ExtendEmpty(),
+		v6ComparisonValue: types.NewEmptyV6Key().ExtendEmpty(),
+	}
+}
 
-				// Skip switching to secondary map if IPs are not part of the query attributes
-				if w.query.hasAttrSIP || w.query.hasAttrDIP {
-					key = v6Key
-					isIPv4 = false
+// evaluate filters the entries of a decoded block and aggregates them into the result map
+func (e *blockEvaluator) evaluate(block *decodedBlock, resultMap *hashmap.AggFlowMapWithMetadata) {
+	w := e.w
+
+	// Initialize any (static) key extensions potentially present in the query
+	if w.query.hasAttrTime {
+		e.v4Key = types.NewEmptyV4Key().Extend(block.timestamp)
+		e.v6Key = types.NewEmptyV6Key().Extend(block.timestamp)
+		if w.query.Conditional == nil {
+			e.v4ComparisonValue = types.NewEmptyV4Key().Extend(block.timestamp)
+			e.v6ComparisonValue = types.NewEmptyV6Key().Extend(block.timestamp)
+		}
+	}
+
+	blocks := &block.data
+	numV4Entries, numEntries := block.numV4Entries, block.numEntries
+
+	e.bytesRcvdValues = w.unpackCounter(blocks, types.BytesRcvdColIdx, e.bytesRcvdValues, numEntries)
+	e.bytesSentValues = w.unpackCounter(blocks, types.BytesSentColIdx, e.bytesSentValues, numEntries)
+	e.pktsRcvdValues = w.unpackCounter(blocks, types.PacketsRcvdColIdx, e.pktsRcvdValues, numEntries)
+	e.pktsSentValues = w.unpackCounter(blocks, types.PacketsSentColIdx, e.pktsSentValues, numEntries)
+	bytesRcvdValues, bytesSentValues, pktsRcvdValues, pktsSentValues := e.bytesRcvdValues, e.bytesSentValues, e.pktsRcvdValues, e.pktsSentValues
+
+	// If the block only partially overlaps the time range of an interpolating query, its counters are
+	// scaled by the fraction of the overlap
+	if w.query.interpolate {
+		if overlap := blockOverlap(block.timestamp, w.query.interpolateFirst, w.query.interpolateLast); overlap < DBWriteInterval {
+			for _, counter := range [...]struct {
+				colIdx types.ColumnIndex
+				values []uint64
+			}{
+				{types.BytesRcvdColIdx, bytesRcvdValues},
+				{types.BytesSentColIdx, bytesSentValues},
+				{types.PacketsRcvdColIdx, pktsRcvdValues},
+				{types.PacketsSentColIdx, pktsSentValues},
+			} {
+				if w.query.counters.Has(counter.colIdx) {
+					scaleCounters(counter.values, uint64(overlap), uint64(DBWriteInterval))
 				}
+			}
+			w.nInterpolatedBlocks.Add(1)
+		}
+	}
+
+	sipBlocks := blocks[types.SIPColIdx]
+	dipBlocks := blocks[types.DIPColIdx]
+	dportBlocks := blocks[types.DportColIdx]
+	protoBlocks := blocks[types.ProtoColIdx]
+	tagBlocks := blocks[types.TagColIdx]
+	ttlMinBlocks := blocks[types.TTLMinColIdx]
+	ttlMaxBlocks := blocks[types.TTLMaxColIdx]
+	tagIDs := block.tagIDs
+
+	// Determine start / end of block perusal - If the query is limited to either IPv4 or IPv6, adjust
+	// accordingly to skip irrelevant data that wouldn't satisfy the condition anyway
+	key, comparisonValue := e.v4Key, e.v4ComparisonValue
+	startEntry, isIPv4, condIsIPv4 := 0, true, true
+	var nAggregated uint64
+	if w.query.ipVersion == types.IPVersionV6 {
+		startEntry = numV4Entries
+	} else if w.query.ipVersion == types.IPVersionV4 {
+		numEntries = numV4Entries
+	}
+	for i := startEntry; i < numEntries; i++ {
+
+		// If / when reaching the v4/v6 mark, switch to the IPv6 key / submap
+		if i == numV4Entries {
+
+			// Skip switching to secondary map if IPs are not part of the query attributes
+			if w.query.hasAttrSIP || w.query.hasAttrDIP {
+				key = e.v6Key
+				isIPv4 = false
+			}
+
+			// But always switch the comparison value to allow for proper filtering
+			comparisonValue = e.v6ComparisonValue
+			condIsIPv4 = false
+		}
 
-				// But always switch the comparison value to allow for proper filtering
-				comparisonValue = v6ComparisonValue
-				condIsIPv4 = false
+		// Populate key for current entry
+		if w.query.hasAttrSIP {
+			if isIPv4 {
+				key.PutSIP(sipBlocks[i*4 : i*4+4])
+			} else {
+				key.PutSIP(sipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
+			}
+		}
+		if w.query.hasAttrDIP {
+			if isIPv4 {
+				key.PutDIPV4(dipBlocks[i*4 : i*4+4])
+			} else {
+				key.PutDIPV6(dipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
 			}
+		}
+		if w.query.hasAttrProto {
+			key.PutProtoV(protoBlocks[i], isIPv4)
+		}
+		if w.query.hasAttrDport {
+			key.PutDportV(dportBlocks[i*types.DportSizeof:i*types.DportSizeof+types.DportSizeof], isIPv4)
+		}
+		tag := types.UntaggedID
+		if len(tagBlocks) > 0 {
+			tag = tagIDs[tagBlocks[i]]
+		}
+		if w.query.hasAttrTag {
+			key.PutTagV(tag, isIPv4)
+		}
+		var ttlMin, ttlMax byte
+		if len(ttlMinBlocks) > 0 {
+			ttlMin = ttlMinBlocks[i]
+		}
+		if len(ttlMaxBlocks) > 0 {
+			ttlMax = ttlMaxBlocks[i]
+		}
+		if w.query.hasAttrTTLMin {
+			key.PutTTLMinV(ttlMin, isIPv4)
+		}
+		if w.query.hasAttrTTLMax {
+			key.PutTTLMaxV(ttlMax, isIPv4)
+		}
 
-			// Populate key for current entry
-			if w.query.hasAttrSIP {
-				if isIPv4 {
-					key.PutSIP(sipBlocks[i*4 : i*4+4])
+		// Check whether conditional is satisfied for current entry
+		var conditionalSatisfied = (w.query.Conditional == nil)
+		if !conditionalSatisfied {
+
+			// Populate comparison value for current entry
+			if w.query.hasCondSIP {
+				if condIsIPv4 {
+					comparisonValue.PutSIP(sipBlocks[i*4 : i*4+4])
 				} else {
-					key.PutSIP(sipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
+					comparisonValue.PutSIP(sipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
 				}
 			}
-			if w.query.hasAttrDIP {
-				if isIPv4 {
-					key.PutDIPV4(dipBlocks[i*4 : i*4+4])
+			if w.query.hasCondDIP {
+				if condIsIPv4 {
+					comparisonValue.PutDIPV4(dipBlocks[i*4 : i*4+4])
 				} else {
-					key.PutDIPV6(dipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
+					comparisonValue.PutDIPV6(dipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
 				}
 			}
-			if w.query.hasAttrProto {
-				key.PutProtoV(protoBlocks[i], isIPv4)
+			if w.query.hasCondProto {
+				comparisonValue.PutProtoV(protoBlocks[i], condIsIPv4)
+			}
+			if w.query.hasCondDport {
+				comparisonValue.PutDportV(dportBlocks[i*types.DportSizeof:i*types.DportSizeof+types.DportSizeof], condIsIPv4)
+			}
+			if w.query.hasCondTag {
+				comparisonValue.PutTagV(tag, condIsIPv4)
+			}
+			if w.query.hasCondTTLMin {
+				comparisonValue.PutTTLMinV(ttlMin, condIsIPv4)
 			}
-			if w.query.hasAttrDport {
-				key.PutDportV(dportBlocks[i*types.DportSizeof:i*types.DportSizeof+types.DportSizeof], isIPv4)
+			if w.query.hasCondTTLMax {
+				comparisonValue.PutTTLMaxV(ttlMax, condIsIPv4)
 			}
 
-			// Check whether conditional is satisfied for current entry
-			var conditionalSatisfied = (w.query.Conditional == nil)
-			if !conditionalSatisfied {
+			conditionalSatisfied = w.query.Conditional.Evaluate(comparisonValue.Key())
+		}
 
-				// Populate comparison value for current entry
-				if w.query.hasCondSIP {
-					if condIsIPv4 {
-						comparisonValue.PutSIP(sipBlocks[i*4 : i*4+4])
-					} else {
-						comparisonValue.PutSIP(sipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
-					}
-				}
-				if w.query.hasCondDIP {
-					if condIsIPv4 {
-						comparisonValue.PutDIPV4(dipBlocks[i*4 : i*4+4])
-					} else {
-						comparisonValue.PutDIPV6(dipBlocks[numV4Entries*4+(i-numV4Entries)*16 : numV4Entries*4+(i-numV4Entries)*16+16])
-					}
-				}
-				if w.query.hasCondProto {
-					comparisonValue.PutProtoV(protoBlocks[i], condIsIPv4)
-				}
-				if w.query.hasCondDport {
-					comparisonValue.PutDportV(dportBlocks[i*types.DportSizeof:i*types.DportSizeof+types.DportSizeof], condIsIPv4)
-				}
+		if conditionalSatisfied {
+			resultMap.SetOrUpdate(key,
+				isIPv4,
+				bytesRcvdValues[i],
+				bytesSentValues[i],
+				pktsRcvdValues[i],
+				pktsSentValues[i],
+			)
+			nAggregated++
+		}
+	}
+	w.nRowsAggregated.Add(nAggregated)
+}
 
-				conditionalSatisfied = w.query.Conditional.Evaluate(comparisonValue.Key())
-			}
+// unpackCounter unpacks the counter column colIdx of a block into buf. Counters not selected by the
+// query haven't been read from disk, hence an all-zero slice of the required length is returned
+func (w *DBWorkManager) unpackCounter(blocks *[types.ColIdxCount][]byte, colIdx types.ColumnIndex, buf []uint64, numEntries int) []uint64 {
+	if w.query.counters.Has(colIdx) {
+		return bitpack.UnpackInto(blocks[colIdx], buf)
+	}
 
-			if conditionalSatisfied {
-				resultMap.SetOrUpdate(key,
-					isIPv4,
-					bytesRcvdValues[i],
-					bytesSentValues[i],
-					pktsRcvdValues[i],
-					pktsSentValues[i],
-				)
-			}
-		}
+	// buf is exclusively used for this (unselected) counter and hence never written to
+	if cap(buf) < numEntries {
+		return make([]uint64, numEntries)
 	}
+	return buf[:numEntries]
+}
 
-	return nil
+// blockOverlap returns the number of seconds the block written at timestamp (covering the preceding
+// write out interval) overlaps the time range [first, last]
+func blockOverlap(timestamp, first, last int64) int64 {
+	return max(0, min(timestamp, last)-max(timestamp-DBWriteInterval, first))
+}
+
+// scaleCounters scales all counters by num / denom (rounded to the nearest integer), with num <= denom
+func scaleCounters(values []uint64, num, denom uint64) {
+	for i, v := range values {
+		hi, lo := bits.Mul64(v, num)
+		q, r := bits.Div64(hi, lo, denom)
+		if 2*r >= denom {
+			q++
+		}
+		values[i] = q
+	}
 }
 
 // Close releases all resources claimed by the DBWorkManager
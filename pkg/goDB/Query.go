@@ -32,6 +32,9 @@ type Query struct {
 	hasAttrTime, hasAttrIface                          bool
 	hasAttrSIP, hasAttrDIP, hasAttrDport, hasAttrProto bool
 	hasCondSIP, hasCondDIP, hasCondDport, hasCondProto bool
+	hasAttrTag, hasCondTag                             bool
+	hasAttrTTLMin, hasAttrTTLMax                       bool
+	hasCondTTLMin, hasCondTTLMax                       bool
 	ipVersion                                          types.IPVersion
 
 	// metadataOnly will determine if all relevant information to answer the query can be
@@ -49,12 +52,23 @@ type Query struct {
 	// would contain DipColIdx and DportColIdx
 	conditionalAttributeIndices []types.ColumnIndex
 	// Set containing the union of queryAttributeIndices, conditionalAttributeIndices, and
-	// {BytesSentColIdx, PacketsRcvdColIdx, PacketsSentColIdx, ColIdxCount}.
-	// The latter four elements are needed for every query since they contain the variables we aggregate.
+	// counterIndices.
 	columnIndices []types.ColumnIndex
+	// Set of indices of all counter columns aggregated by the query (all of them by default).
+	// Counter columns not contained in this set are not read at all
+	counterIndices []types.ColumnIndex
+	counters       types.CounterSelector
 
 	// Enables memory-saving mode
 	lowMem bool
+
+	// Enables memory-mapped access to the column files
+	mmap bool
+
+	// Enables scaling of the counters of blocks only partially overlapping the time range
+	// [interpolateFirst, interpolateLast] by the fraction of the overlap
+	interpolate                       bool
+	interpolateFirst, interpolateLast int64
 }
 
 // Computes a columnIndex from a column name. In principle we could merge
@@ -63,10 +77,13 @@ type Query struct {
 // the condition attributes.
 func queryAttributeNameToColumnIndex(name string) (colIdx types.ColumnIndex) {
 	colIdx, ok := map[string]types.ColumnIndex{
-		types.SIPName:   types.SIPColIdx,
-		types.DIPName:   types.DIPColIdx,
-		types.ProtoName: types.ProtoColIdx,
-		types.DportName: types.DportColIdx}[name]
+		types.SIPName:    types.SIPColIdx,
+		types.DIPName:    types.DIPColIdx,
+		types.ProtoName:  types.ProtoColIdx,
+		types.DportName:  types.DportColIdx,
+		types.TagName:    types.TagColIdx,
+		types.TTLMinName: types.TTLMinColIdx,
+		types.TTLMaxName: types.TTLMaxColIdx}[name]
 	if !ok {
 		panic("Unknown query attribute " + name)
 	}
@@ -77,30 +94,39 @@ func queryAttributeNameToColumnIndex(name string) (colIdx types.ColumnIndex) {
 // because snet and dnet are only allowed in conditionals.
 func conditionalAttributeNameToColumnIndex(name string) (colIdx types.ColumnIndex) {
 	colIdx, ok := map[string]types.ColumnIndex{
-		types.SIPName:   types.SIPColIdx,
-		"snet":          types.SIPColIdx,
-		types.DIPName:   types.DIPColIdx,
-		"dnet":          types.DIPColIdx,
-		types.ProtoName: types.ProtoColIdx,
-		types.DportName: types.DportColIdx}[name]
+		types.SIPName:    types.SIPColIdx,
+		"snet":           types.SIPColIdx,
+		types.DIPName:    types.DIPColIdx,
+		"dnet":           types.DIPColIdx,
+		types.ProtoName:  types.ProtoColIdx,
+		types.DportName:  types.DportColIdx,
+		types.TagName:    types.TagColIdx,
+		types.TTLMinName: types.TTLMinColIdx,
+		types.TTLMaxName: types.TTLMaxColIdx}[name]
 	if !ok {
 		panic("Unknown conditional attribute " + name)
 	}
 	return
 }
 
-var queryAttributeColumnFlagSetters = [types.ColIdxAttributeCount]func(q *Query){
+var queryAttributeColumnFlagSetters = [types.ColIdxCount]func(q *Query){
 	func(q *Query) { q.hasAttrSIP = true },
 	func(q *Query) { q.hasAttrDIP = true },
 	func(q *Query) { q.hasAttrProto = true },
 	func(q *Query) { q.hasAttrDport = true },
+	types.TagColIdx:    func(q *Query) { q.hasAttrTag = true },
+	types.TTLMinColIdx: func(q *Query) { q.hasAttrTTLMin = true },
+	types.TTLMaxColIdx: func(q *Query) { q.hasAttrTTLMax = true },
 }
 
-var queryConditionalColumnFlagSetters = [types.ColIdxAttributeCount]func(q *Query){
+var queryConditionalColumnFlagSetters = [types.ColIdxCount]func(q *Query){
 	func(q *Query) { q.hasCondSIP = true },
 	func(q *Query) { q.hasCondDIP = true },
 	func(q *Query) { q.hasCondProto = true },
 	func(q *Query) { q.hasCondDport = true },
+	types.TagColIdx:    func(q *Query) { q.hasCondTag = true },
+	types.TTLMinColIdx: func(q *Query) { q.hasCondTTLMin = true },
+	types.TTLMaxColIdx: func(q *Query) { q.hasCondTTLMax = true },
 }
 
 // NewMetadataQuery creates a metadata-only query
@@ -121,12 +147,9 @@ func NewQuery(attributes []types.Attribute, conditional node.Node, selector type
 	}
 
 	// Compute index sets
-	var isAttributeIndex [types.ColIdxAttributeCount]bool // temporary variable for computing set union
-
 	for _, attrib := range q.Attributes {
 		colIdx := queryAttributeNameToColumnIndex(attrib.Name())
 		q.queryAttributeIndices = append(q.queryAttributeIndices, colIdx)
-		isAttributeIndex[colIdx] = true
 		queryAttributeColumnFlagSetters[colIdx](q)
 	}
 
@@ -134,19 +157,45 @@ func NewQuery(attributes []types.Attribute, conditional node.Node, selector type
 		for attribName, ipVersion := range q.Conditional.Attributes() {
 			colIdx := conditionalAttributeNameToColumnIndex(attribName)
 			q.conditionalAttributeIndices = append(q.conditionalAttributeIndices, colIdx)
-			isAttributeIndex[colIdx] = true
 			queryConditionalColumnFlagSetters[colIdx](q)
 			q.ipVersion = q.ipVersion.Merge(ipVersion)
 		}
 	}
+	q.computeColumnIndices()
+
+	return q
+}
+
+// computeColumnIndices computes the set of all columns that have to be read by the query
+func (q *Query) computeColumnIndices() {
+	var isAttributeIndex [types.ColIdxCount]bool // temporary variable for computing set union
+	for _, colIdx := range q.queryAttributeIndices {
+		isAttributeIndex[colIdx] = true
+	}
+	for _, colIdx := range q.conditionalAttributeIndices {
+		isAttributeIndex[colIdx] = true
+	}
+
+	q.columnIndices = q.columnIndices[:0]
 	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxAttributeCount; colIdx++ {
 		if isAttributeIndex[colIdx] {
 			q.columnIndices = append(q.columnIndices, colIdx)
 		}
 	}
-	q.columnIndices = append(q.columnIndices,
-		types.BytesRcvdColIdx, types.BytesSentColIdx, types.PacketsRcvdColIdx, types.PacketsSentColIdx)
+	q.counterIndices = q.counters.ColumnIndices()
+	q.columnIndices = append(q.columnIndices, q.counterIndices...)
+	for colIdx := types.TagColIdx; colIdx < types.ColIdxCount; colIdx++ {
+		if isAttributeIndex[colIdx] {
+			q.columnIndices = append(q.columnIndices, colIdx)
+		}
+	}
+}
 
+// Counters limits the counters aggregated by the query. Counters that are not selected are
+// reported as zero, skipping their columns entirely when reading from the database
+func (q *Query) Counters(counters types.CounterSelector) *Query {
+	q.counters = counters
+	q.computeColumnIndices()
 	return q
 }
 
@@ -161,6 +210,21 @@ func (q *Query) IsLowMem() bool {
 	return q.lowMem
 }
 
+// Mmap enables reading the column files via memory mappings (with readahead hints) instead of
+// buffered reads, reducing the syscall overhead of large scans
+func (q *Query) Mmap(enable bool) *Query {
+	q.mmap = enable
+	return q
+}
+
+// Interpolate enables proportional interpolation of blocks cutting through the time range [first, last],
+// i.e. their counters are scaled by the fraction of the block's time span overlapping the time range
+func (q *Query) Interpolate(first, last int64) *Query {
+	q.interpolate = true
+	q.interpolateFirst, q.interpolateLast = first, last
+	return q
+}
+
 // AttributesToString is a convenience method for translating the query attributes
 // into a human-readable name
 func (q *Query) AttributesToString() []string {
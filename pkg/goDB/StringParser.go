@@ -16,10 +16,9 @@ package goDB
 import (
 	"fmt"
 	"strconv"
-	"strings"
 
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
 // ErrIPVersionMismatch signifies that there is an IPv4 / IPv6 mismatch
@@ -46,6 +45,12 @@ func NewStringKeyParser(kind string) StringKeyParser {
 		return &DportStringParser{}
 	case types.ProtoName:
 		return &ProtoStringParser{}
+	case types.TagName:
+		return &TagStringParser{}
+	case types.TTLMinName:
+		return &TTLMinStringParser{}
+	case types.TTLMaxName:
+		return &TTLMaxStringParser{}
 	case "time":
 		return &TimeStringParser{}
 	}
@@ -86,6 +91,15 @@ type DportStringParser struct{}
 // ProtoStringParser parses proto strings
 type ProtoStringParser struct{}
 
+// TagStringParser parses tag strings
+type TagStringParser struct{}
+
+// TTLMinStringParser parses minimum TTL strings
+type TTLMinStringParser struct{}
+
+// TTLMaxStringParser parses maximum TTL strings
+type TTLMaxStringParser struct{}
+
 // extra attributes
 
 // TimeStringParser parses time strings
@@ -155,21 +169,50 @@ func (d *DportStringParser) ParseKey(element string, key *types.ExtendedKey) err
 
 // ParseKey parses an IP protocol  string and writes it to the protocol key slice
 func (p *ProtoStringParser) ParseKey(element string, key *types.ExtendedKey) error {
-	var (
-		num  uint64
-		err  error
-		isIn bool
-	)
 
-	// first try to parse as number (e.g. 6 or 17)
-	if num, err = strconv.ParseUint(element, 10, 8); err != nil {
-		// then try to parse as string (e.g. TCP or UDP)
-		if num, isIn = protocols.GetIPProtoID(strings.ToLower(element)); !isIn {
-			return fmt.Errorf("could not parse 'protocol' attribute: %w", err)
-		}
+	// the protocol may be provided as number (e.g. 6 or 17) or as string (e.g. TCP or UDP)
+	proto, err := protocols.Parse(element)
+	if err != nil {
+		return fmt.Errorf("could not parse 'protocol' attribute: %w", err)
 	}
 
-	key.Key().PutProto(byte(num & 0xff))
+	key.Key().PutProto(proto)
+	return nil
+}
+
+// ParseKey parses a tag string and writes its ID to the tag key slice (an empty string
+// denotes an untagged flow)
+func (t *TagStringParser) ParseKey(element string, key *types.ExtendedKey) error {
+	if element == "" {
+		key.Key().PutTag(types.UntaggedID)
+		return nil
+	}
+
+	tag, err := types.InternTag(element)
+	if err != nil {
+		return fmt.Errorf("could not parse 'tag' attribute: %w", err)
+	}
+	key.Key().PutTag(tag)
+	return nil
+}
+
+// ParseKey parses a minimum TTL string and writes it to the minimum TTL key slice
+func (t *TTLMinStringParser) ParseKey(element string, key *types.ExtendedKey) error {
+	num, err := strconv.ParseUint(element, 10, 8)
+	if err != nil {
+		return fmt.Errorf("could not parse 'ttl_min' attribute: %w", err)
+	}
+	key.Key().PutTTLMin(uint8(num))
+	return nil
+}
+
+// ParseKey parses a maximum TTL string and writes it to the maximum TTL key slice
+func (t *TTLMaxStringParser) ParseKey(element string, key *types.ExtendedKey) error {
+	num, err := strconv.ParseUint(element, 10, 8)
+	if err != nil {
+		return fmt.Errorf("could not parse 'ttl_max' attribute: %w", err)
+	}
+	key.Key().PutTTLMax(uint8(num))
 	return nil
 }
 
@@ -0,0 +1,409 @@
+// Package backup creates consistent copies of a goDB while it is being written to (e.g. by a running
+// goProbe instance), instead of copying its files verbatim, which may capture a directory mid-write.
+//
+// Since the column files of a directory are only ever appended to and its metadata (referring to the
+// blocks stored in the column files) is replaced atomically once all blocks of a writeout have been
+// written, each directory is copied as follows:
+//
+//  1. The metadata is snapshotted and parsed.
+//  2. Of each column file, only the data referenced by the metadata is copied. The copy is verified to
+//     hold the data of all referenced blocks, matching their checksums (if available).
+//  3. The metadata is read again. If it changed while the column files were copied (e.g. due to a
+//     writeout or a compaction of the directory), the copy is retried.
+//  4. The metadata snapshot is written to the copy, making it visible to queries.
+//
+// Hence the backup can be queried like any goDB and is complete up to the last rotation committed
+// prior to copying the respective directory.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// DefaultMaxRetries denotes the default number of times the copy of a directory is retried if
+	// the directory changed while being copied
+	DefaultMaxRetries = 5
+
+	// DefaultRetryInterval denotes the default duration waited for prior to retrying the copy of a
+	// directory, allowing a concurrent writeout to complete
+	DefaultRetryInterval = time.Second
+
+	// defaultPermissions denotes the default permissions of the copied files
+	defaultPermissions fs.FileMode = 0644
+)
+
+var (
+	// ErrDestinationExists is thrown if the destination of a backup already contains any data
+	ErrDestinationExists = errors.New("backup destination is not empty")
+
+	// ErrInconsistent is thrown if a directory could not be copied consistently, i.e. it changed
+	// during all attempts or its column files do not hold the data referenced by its metadata
+	ErrInconsistent = errors.New("directory could not be copied consistently")
+
+	// errChanged signifies that the metadata of a directory changed while it was being copied
+	errChanged = errors.New("metadata changed during copy")
+)
+
+// IfaceResult summarizes the backup of a single interface
+type IfaceResult struct {
+	Iface   string    `json:"iface"`          // Iface: name of the interface
+	Dirs    int       `json:"dirs"`           // Dirs: number of (day) directories copied
+	Blocks  int       `json:"blocks"`         // Blocks: number of blocks copied
+	Bytes   int64     `json:"bytes"`          // Bytes: amount of data copied (in bytes)
+	Retries int       `json:"retries"`        // Retries: number of times the copy of a directory had to be retried
+	Last    time.Time `json:"last,omitempty"` // Last: timestamp of the last block copied (i.e. the last committed rotation)
+}
+
+// Results denotes the results of a backup run, ordered by interface
+type Results []IfaceResult
+
+// Bytes returns the total amount of data copied
+func (r Results) Bytes() (n int64) {
+	for _, res := range r {
+		n += res.Bytes
+	}
+	return
+}
+
+// Backup copies a goDB consistently while it is being written to
+type Backup struct {
+	dbPath        string
+	fsys          storage.FS
+	permissions   fs.FileMode
+	maxRetries    int
+	retryInterval time.Duration
+}
+
+// Option denotes a functional option for a Backup
+type Option func(*Backup)
+
+// WithFS sets the file system both the goDB and the backup reside on
+func WithFS(fsys storage.FS) Option {
+	return func(b *Backup) {
+		b.fsys = fsys
+	}
+}
+
+// WithPermissions sets the permissions of the copied files
+func WithPermissions(permissions fs.FileMode) Option {
+	return func(b *Backup) {
+		b.permissions = permissions
+	}
+}
+
+// WithRetries sets the number of times the copy of a directory is retried if it changed while being
+// copied, as well as the duration waited for prior to each retry
+func WithRetries(maxRetries int, interval time.Duration) Option {
+	return func(b *Backup) {
+		b.maxRetries = maxRetries
+		b.retryInterval = interval
+	}
+}
+
+// New instantiates a new Backup of the goDB located at dbPath
+func New(dbPath string, opts ...Option) *Backup {
+	b := &Backup{
+		dbPath:        dbPath,
+		fsys:          storage.DefaultFS,
+		permissions:   defaultPermissions,
+		maxRetries:    DefaultMaxRetries,
+		retryInterval: DefaultRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Run copies all interfaces of the goDB (or the provided ones only) to dstPath, which must either not
+// exist or be empty
+func (b *Backup) Run(ctx context.Context, dstPath string, ifaces ...string) (Results, error) {
+	if err := b.checkDestination(dstPath); err != nil {
+		return nil, err
+	}
+	if len(ifaces) == 0 {
+		var err error
+		if ifaces, err = info.GetInterfacesFS(b.fsys, b.dbPath); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make(Results, 0, len(ifaces))
+	for _, iface := range ifaces {
+		res, err := b.backupIface(ctx, dstPath, iface)
+		if err != nil {
+			return results, fmt.Errorf("failed to back up interface %s: %w", iface, err)
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// checkDestination ensures that the destination does not contain any data (creating it if required)
+func (b *Backup) checkDestination(dstPath string) error {
+	dirents, err := b.fsys.ReadDir(dstPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return b.fsys.MkdirAll(dstPath, dirPerm(b.permissions))
+		}
+		return err
+	}
+	if len(dirents) > 0 {
+		return fmt.Errorf("%w: %s", ErrDestinationExists, dstPath)
+	}
+	return nil
+}
+
+func (b *Backup) backupIface(ctx context.Context, dstPath, iface string) (IfaceResult, error) {
+	logger := logging.FromContext(ctx).With("iface", iface)
+	res := IfaceResult{Iface: iface}
+
+	srcIfacePath, dstIfacePath := filepath.Join(b.dbPath, iface), filepath.Join(dstPath, iface)
+	if _, err := b.fsys.Stat(srcIfacePath); err != nil {
+		return res, err
+	}
+
+	err := gpfile.WalkDirs(b.fsys, srcIfacePath, func(dayPath string, dayTimestamp int64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dirRes, retries, err := b.backupDir(ctx, srcIfacePath, dstIfacePath, dayTimestamp)
+		res.Retries += retries
+		if err != nil {
+			return fmt.Errorf("failed to back up directory %s: %w", dayPath, err)
+		}
+		if retries > 0 {
+			logger.With("path", dayPath, "retries", retries).Info("directory changed during backup, copied on retry")
+		}
+		if dirRes.blocks == 0 {
+			return nil
+		}
+
+		res.Dirs++
+		res.Blocks += dirRes.blocks
+		res.Bytes += dirRes.bytes
+		res.Last = time.Unix(dirRes.last, 0)
+
+		return nil
+	})
+
+	return res, err
+}
+
+// dirResult summarizes the copy of a directory
+type dirResult struct {
+	blocks int
+	bytes  int64
+	last   int64
+}
+
+// backupDir copies a day directory, retrying if it changed while being copied. It returns the number
+// of retries required. If the directory could not be copied, its (partial) copy is removed
+func (b *Backup) backupDir(ctx context.Context, srcIfacePath, dstIfacePath string, dayTimestamp int64) (res dirResult, retries int, err error) {
+	for {
+		res, err = b.copyDir(srcIfacePath, dstIfacePath, dayTimestamp)
+		if err == nil {
+			return res, retries, nil
+		}
+		if !isInconsistency(err) {
+			break
+		}
+		if retries >= b.maxRetries {
+			err = fmt.Errorf("%w after %d retries: %w", ErrInconsistent, retries, err)
+			break
+		}
+		retries++
+
+		if err = wait(ctx, b.retryInterval); err != nil {
+			break
+		}
+	}
+
+	return res, retries, errors.Join(err, removeDir(b.fsys, gpfile.GenPathForTimestamp(dstIfacePath, dayTimestamp)))
+}
+
+// copyDir performs a single attempt at copying a day directory, copying its metadata last
+func (b *Backup) copyDir(srcIfacePath, dstIfacePath string, dayTimestamp int64) (res dirResult, err error) {
+	src := gpfile.NewDir(srcIfacePath, dayTimestamp, gpfile.ModeRead, gpfile.WithFS(b.fsys))
+
+	// A directory without metadata does not hold any committed blocks (yet)
+	metadata, err := b.readFile(src.MetadataPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return res, nil
+		}
+		return res, err
+	}
+
+	if err := src.Open(); err != nil {
+		return res, err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	if res.blocks = src.NBlocks(); res.blocks > 0 {
+		_, res.last = src.TimeRange()
+	}
+
+	dstPath := gpfile.GenPathForTimestamp(dstIfacePath, dayTimestamp)
+	if err := b.fsys.MkdirAll(dstPath, dirPerm(b.permissions)); err != nil {
+		return res, err
+	}
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		header := src.BlockMetadata[colIdx]
+		if header == nil {
+			continue
+		}
+		size := gpfile.ReferencedSize(header)
+		if size == 0 {
+			continue
+		}
+
+		fileName := types.ColumnFileNames[colIdx] + gpfile.FileSuffix
+		if err := b.copyColumn(filepath.Join(src.Path(), fileName), filepath.Join(dstPath, fileName), size, header); err != nil {
+			return res, fmt.Errorf("failed to copy column %s: %w", types.ColumnFileNames[colIdx], err)
+		}
+		res.bytes += size
+	}
+
+	// Ensure that the copied column files match the metadata snapshot, i.e. that it didn't change
+	// while they were copied
+	current, err := b.readFile(src.MetadataPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return res, errChanged
+		}
+		return res, err
+	}
+	if !bytes.Equal(metadata, current) {
+		return res, errChanged
+	}
+
+	res.bytes += int64(len(metadata))
+	return res, b.writeFileAtomic(filepath.Join(dstPath, filepath.Base(src.MetadataPath())), metadata)
+}
+
+// copyColumn copies the first size bytes of a column file and verifies that the copy holds the data
+// of all blocks referenced by header
+func (b *Backup) copyColumn(srcPath, dstPath string, size int64, header *storage.BlockHeader) error {
+	src, err := b.fsys.OpenFile(srcPath, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: column file missing", gpfile.ErrBlockMissing)
+		}
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dst, err := b.fsys.OpenFile(dstPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, b.permissions)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(dst, src, size); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("%w: column file truncated", gpfile.ErrBlockMissing)
+		}
+		return errors.Join(err, dst.Close())
+	}
+	if err := gpfile.VerifyBlocks(dst, header); err != nil {
+		return errors.Join(err, dst.Close())
+	}
+
+	return dst.Close()
+}
+
+func (b *Backup) readFile(path string) ([]byte, error) {
+	f, err := b.fsys.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return io.ReadAll(f)
+}
+
+// writeFileAtomic writes a file via a temporary file, ensuring that it is only visible once complete
+func (b *Backup) writeFileAtomic(path string, data []byte) error {
+	tempFile, err := b.fsys.CreateTemp(filepath.Dir(path), ".tmp-backup-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		return errors.Join(err, b.fsys.Remove(tempFile.Name()))
+	}
+	if err := tempFile.Close(); err != nil {
+		return errors.Join(err, b.fsys.Remove(tempFile.Name()))
+	}
+	if err := b.fsys.Chmod(tempFile.Name(), b.permissions); err != nil {
+		return errors.Join(err, b.fsys.Remove(tempFile.Name()))
+	}
+
+	return b.fsys.Rename(tempFile.Name(), path)
+}
+
+// isInconsistency determines if an error signifies that a directory was copied inconsistently (most
+// likely due to a concurrent modification), warranting a retry
+func isInconsistency(err error) bool {
+	return errors.Is(err, errChanged) ||
+		errors.Is(err, gpfile.ErrBlockMissing) ||
+		errors.Is(err, gpfile.ErrChecksumMismatch)
+}
+
+// wait waits for the provided duration, unless the context is cancelled beforehand
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// removeDir removes a (copied) day directory and all files contained in it (if it exists)
+func removeDir(fsys storage.FS, path string) error {
+	dirents, err := fsys.ReadDir(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, dirent := range dirents {
+		if err := fsys.Remove(filepath.Join(path, dirent.Name())); err != nil {
+			return err
+		}
+	}
+	return fsys.Remove(path)
+}
+
+// dirPerm returns the permissions of directories containing files with the provided permissions
+func dirPerm(filePerm fs.FileMode) fs.FileMode {
+	return filePerm | (filePerm&0444)>>2
+}
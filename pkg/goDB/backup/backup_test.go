@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDBPath     = "/db"
+	testBackupPath = "/backup"
+)
+
+var testDay = time.Unix(1704067200, 0) // 2024-01-01
+
+func testData(colIdx types.ColumnIndex, n int) []byte {
+	return bytes.Repeat([]byte{byte(colIdx), byte(n)}, 512)
+}
+
+// writeTestBlock writes the n-th block of the test directory of an interface
+func writeTestBlock(t *testing.T, fsys storage.FS, iface string, n int) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testDBPath, iface), testDay.Unix(), gpfile.ModeWrite, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := range dbData {
+		dbData[colIdx] = testData(types.ColumnIndex(colIdx), n)
+	}
+	require.Nil(t, dir.WriteBlocks(testDay.Unix()+int64(n)*300, gpfile.TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData))
+	require.Nil(t, dir.Close())
+}
+
+func requireBackup(t *testing.T, fsys storage.FS, iface string, nBlocks int) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testBackupPath, iface), testDay.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	defer func() {
+		require.Nil(t, dir.Close())
+	}()
+
+	require.Equal(t, nBlocks, dir.NBlocks())
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		for i := 0; i < nBlocks; i++ {
+			data, err := dir.ReadBlockAtIndex(colIdx, i)
+			require.Nil(t, err)
+			require.Equal(t, testData(colIdx, i+1), data)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	fsys := godbtest.NewMemFS()
+	writeTestBlock(t, fsys, "eth0", 1)
+	writeTestBlock(t, fsys, "eth0", 2)
+	writeTestBlock(t, fsys, "eth1", 1)
+
+	// data not (yet) referenced by the metadata (e.g. of an ongoing writeout) is not copied
+	column := filepath.Join(gpfile.GenPathForTimestamp(filepath.Join(testDBPath, "eth1"), testDay.Unix()), types.ColumnFileNames[types.SIPColIdx]+gpfile.FileSuffix)
+	f, err := fsys.OpenFile(column, os.O_WRONLY, 0)
+	require.Nil(t, err)
+	_, err = f.Seek(0, io.SeekEnd)
+	require.Nil(t, err)
+	_, err = f.Write([]byte("partial"))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	res, err := New(testDBPath, WithFS(fsys)).Run(context.Background(), testBackupPath)
+	require.Nil(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, IfaceResult{Iface: "eth0", Dirs: 1, Blocks: 2, Bytes: res[0].Bytes, Last: testDay.Add(10 * time.Minute)}, res[0])
+	require.Equal(t, IfaceResult{Iface: "eth1", Dirs: 1, Blocks: 1, Bytes: res[1].Bytes, Last: testDay.Add(5 * time.Minute)}, res[1])
+	require.Equal(t, res[0].Bytes+res[1].Bytes, res.Bytes())
+
+	requireBackup(t, fsys, "eth0", 2)
+	requireBackup(t, fsys, "eth1", 1)
+
+	stat, err := fsys.Stat(strings.Replace(column, testDBPath, testBackupPath, 1))
+	require.Nil(t, err)
+	srcStat, err := fsys.Stat(column)
+	require.Nil(t, err)
+	require.Equal(t, srcStat.Size()-int64(len("partial")), stat.Size())
+
+	// existing data is never overwritten
+	_, err = New(testDBPath, WithFS(fsys)).Run(context.Background(), testBackupPath)
+	require.ErrorIs(t, err, ErrDestinationExists)
+}
+
+// changingFS emulates a writeout taking place while the first column file of a directory is copied
+type changingFS struct {
+	*godbtest.MemFS
+	onColumnOpen func()
+}
+
+func (c *changingFS) OpenFile(name string, flag int, perm fs.FileMode) (storage.File, error) {
+	if c.onColumnOpen != nil && strings.HasPrefix(name, testDBPath) && strings.HasSuffix(name, gpfile.FileSuffix) {
+		onColumnOpen := c.onColumnOpen
+		c.onColumnOpen = nil
+		onColumnOpen()
+	}
+	return c.MemFS.OpenFile(name, flag, perm)
+}
+
+func TestBackupRetry(t *testing.T) {
+	memFS := godbtest.NewMemFS()
+	writeTestBlock(t, memFS, "eth0", 1)
+
+	fsys := &changingFS{
+		MemFS: memFS,
+		onColumnOpen: func() {
+			writeTestBlock(t, memFS, "eth0", 2)
+		},
+	}
+
+	res, err := New(testDBPath, WithFS(fsys), WithRetries(1, 0)).Run(context.Background(), testBackupPath)
+	require.Nil(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, 1, res[0].Retries)
+	require.Equal(t, 2, res[0].Blocks)
+
+	requireBackup(t, fsys, "eth0", 2)
+}
+
+func TestBackupInconsistent(t *testing.T) {
+	fsys := godbtest.NewMemFS()
+	writeTestBlock(t, fsys, "eth0", 1)
+
+	// the column file lacks data referenced by the metadata
+	column := filepath.Join(gpfile.GenPathForTimestamp(filepath.Join(testDBPath, "eth0"), testDay.Unix()), types.ColumnFileNames[types.DportColIdx]+gpfile.FileSuffix)
+	f, err := fsys.OpenFile(column, os.O_WRONLY|os.O_TRUNC, 0)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	res, err := New(testDBPath, WithFS(fsys), WithRetries(2, 0)).Run(context.Background(), testBackupPath)
+	require.ErrorIs(t, err, ErrInconsistent)
+	require.ErrorIs(t, err, gpfile.ErrBlockMissing)
+	require.Empty(t, res)
+
+	// the partial copy of the directory is removed
+	_, err = fsys.Stat(gpfile.GenPathForTimestamp(filepath.Join(testBackupPath, "eth0"), testDay.Unix()))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
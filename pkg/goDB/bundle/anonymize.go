@@ -0,0 +1,78 @@
+package bundle
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
+
+const (
+	ipv4Len = 4
+	ipv6Len = 16
+
+	anonymizationKeyLen = 32
+)
+
+// anonymizer pseudonymizes IP addresses using a keyed hash (HMAC-SHA256) truncated to the length of
+// the address. Since the key is generated randomly per export (and is never persisted), the original
+// addresses cannot be recovered from a bundle, while the mapping is consistent across all flows of the
+// bundle (retaining the relation between flows of the same hosts) and the address family is retained
+type anonymizer struct {
+	mac hash.Hash
+	buf []byte
+}
+
+func newAnonymizer() (*anonymizer, error) {
+	key := make([]byte, anonymizationKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate anonymization key: %w", err)
+	}
+	return &anonymizer{
+		mac: hmac.New(sha256.New, key),
+		buf: make([]byte, 0, sha256.Size),
+	}, nil
+}
+
+// anonymizeColumn returns an anonymized copy of the data of an IP column (storing the addresses of
+// all IPv4 flows followed by the ones of all IPv6 flows)
+func (a *anonymizer) anonymizeColumn(data []byte, numV4, numV6 uint64) ([]byte, error) {
+	if expected := numV4*ipv4Len + numV6*ipv6Len; uint64(len(data)) != expected {
+		return nil, fmt.Errorf("unexpected length of IP column: %d (expected %d)", len(data), expected)
+	}
+
+	res := make([]byte, len(data))
+	v4Len := int(numV4) * ipv4Len
+	for i := 0; i < v4Len; i += ipv4Len {
+		a.anonymize(res[i:i+ipv4Len], data[i:i+ipv4Len])
+	}
+	for i := v4Len; i < len(data); i += ipv6Len {
+		a.anonymize(res[i:i+ipv6Len], data[i:i+ipv6Len])
+	}
+
+	return res, nil
+}
+
+// anonymize writes the pseudonym of addr to dst (of the same length). The unspecified address is
+// retained as is, since it carries no information about any host
+func (a *anonymizer) anonymize(dst, addr []byte) {
+	if isZero(addr) {
+		copy(dst, addr)
+		return
+	}
+
+	a.mac.Reset()
+	a.mac.Write(addr)
+	a.buf = a.mac.Sum(a.buf[:0])
+	copy(dst, a.buf)
+}
+
+func isZero(addr []byte) bool {
+	for _, b := range addr {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
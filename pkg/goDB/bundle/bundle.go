@@ -0,0 +1,245 @@
+// Package bundle exports a slice of a goDB (a time interval of a set of interfaces) to a self-contained
+// bundle and imports such bundles into another goDB, allowing to share reproducible datasets, e.g. as
+// part of a support case.
+//
+// A bundle is a zstd compressed tar archive containing a manifest (describing the origin and content of
+// the bundle) followed by the exported goDB directories (below DBDir, using the regular directory structure
+// of the goDB). Since all blocks are rewritten upon export, the IP addresses of all flows can optionally be
+// anonymized. Bundles can also be queried directly via goQuery (c.f. the archive package)
+package bundle
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+const (
+
+	// FormatVersion denotes the version of the bundle format written by this implementation
+	FormatVersion = 1
+
+	// ManifestName denotes the name of the manifest within a bundle
+	ManifestName = "manifest.json"
+
+	// DBDir denotes the directory containing the goDB within a bundle
+	DBDir = "godb"
+
+	// FileSuffix denotes the conventional suffix of a bundle
+	FileSuffix = ".tar.zst"
+
+	metadataFileName   = ".blockmeta"
+	defaultPermissions = fs.FileMode(0644)
+)
+
+var (
+	// ErrNoData denotes that the goDB does not contain any data for the requested interfaces / interval
+	ErrNoData = errors.New("no data found for the requested interfaces / interval")
+
+	// ErrNoManifest denotes that a bundle does not contain a manifest
+	ErrNoManifest = errors.New("bundle does not contain a manifest")
+
+	// ErrUnsupportedVersion denotes that a bundle was written using an unsupported bundle format or
+	// goDB metadata version
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+
+	// ErrInvalidMember denotes that a bundle contains a member not belonging to a goDB directory
+	ErrInvalidMember = errors.New("invalid bundle member")
+
+	// ErrDirExists denotes that a goDB directory contained in a bundle already exists in the target goDB
+	ErrDirExists = errors.New("directory already exists in target goDB")
+)
+
+// Manifest describes the origin and content of a bundle
+type Manifest struct {
+	// FormatVersion: version of the bundle format. Example: 1
+	FormatVersion int `json:"format_version"`
+	// SchemaVersion: version of the goDB metadata of all exported directories. Example: 3
+	SchemaVersion uint16 `json:"schema_version"`
+	// Created: time the bundle was created. Example: "2024-02-01T12:00:00Z"
+	Created time.Time `json:"created"`
+	// Version: version of goProbe the bundle was created with. Example: "v4.1.0-1234abcd"
+	Version string `json:"version"`
+	// Host: name of the host the bundle was created on (omitted for anonymized bundles). Example: "probe-1"
+	Host string `json:"host,omitempty"`
+	// Anonymized: whether the IP addresses of all flows were anonymized upon export. Example: true
+	Anonymized bool `json:"anonymized"`
+	// First: timestamp of the first block contained in the bundle. Example: "2024-01-01T00:05:00Z"
+	First time.Time `json:"first"`
+	// Last: timestamp of the last block contained in the bundle. Example: "2024-01-31T23:55:00Z"
+	Last time.Time `json:"last"`
+	// Ifaces: summary of the data exported per interface (ordered by interface)
+	Ifaces []IfaceManifest `json:"ifaces"`
+}
+
+// IfaceManifest summarizes the data of an interface contained in a bundle
+type IfaceManifest struct {
+	// Iface: name of the interface. Example: "eth0"
+	Iface string `json:"iface"`
+	// Dirs: number of (day) directories. Example: 31
+	Dirs int `json:"dirs"`
+	// Blocks: number of blocks. Example: 8928
+	Blocks int `json:"blocks"`
+	// Flows: number of flows stored in all blocks. Example: 1234567
+	Flows uint64 `json:"flows"`
+	// Counts: sum of all flow counters
+	Counts types.Counters `json:"counts"`
+}
+
+type options struct {
+	fsys        storage.FS
+	permissions fs.FileMode
+	tempDir     string
+
+	ifaces      []string
+	first, last int64
+	anonymize   bool
+
+	now func() time.Time
+}
+
+// Option denotes a functional option for an export / import
+type Option func(*options)
+
+// WithFS sets the file system the goDB resides on
+func WithFS(fsys storage.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// WithPermissions sets the permissions of all files written to the goDB upon import
+func WithPermissions(permissions fs.FileMode) Option {
+	return func(o *options) {
+		o.permissions = permissions
+	}
+}
+
+// WithTempDir sets the directory the exported goDB directories are staged in (export only)
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// WithIfaces restricts the export to a set of interfaces (default: all interfaces of the goDB)
+func WithIfaces(ifaces ...string) Option {
+	return func(o *options) {
+		o.ifaces = ifaces
+	}
+}
+
+// WithTimeRange restricts the export to all blocks within [first, last] (unix seconds)
+func WithTimeRange(first, last int64) Option {
+	return func(o *options) {
+		o.first, o.last = first, last
+	}
+}
+
+// WithAnonymization enables / disables the anonymization of all IP addresses upon export
+func WithAnonymization(b bool) Option {
+	return func(o *options) {
+		o.anonymize = b
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		fsys:        storage.DefaultFS,
+		permissions: defaultPermissions,
+		tempDir:     os.TempDir(),
+		last:        types.MaxTime.Unix(),
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// walkIface calls fn for all day directories of an interface. Entries not following the directory
+// structure of the goDB are ignored
+func walkIface(fsys storage.FS, ifacePath string, fn func(dayTimestamp int64) error) error {
+	years, err := subDirs(fsys, ifacePath)
+	if err != nil {
+		return err
+	}
+	for _, year := range years {
+		yearPath := filepath.Join(ifacePath, year)
+		months, err := subDirs(fsys, yearPath)
+		if err != nil {
+			return err
+		}
+		for _, month := range months {
+			days, err := subDirs(fsys, filepath.Join(yearPath, month))
+			if err != nil {
+				return err
+			}
+			for _, day := range days {
+				dayTimestamp, err := strconv.ParseInt(day, 10, 64)
+				if err != nil {
+					continue
+				}
+				if err := fn(dayTimestamp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// subDirs returns the names of all numeric subdirectories of a directory
+func subDirs(fsys storage.FS, path string) ([]string, error) {
+	dirents, err := fsys.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, dirent := range dirents {
+		if !dirent.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(dirent.Name()); err != nil {
+			continue
+		}
+		dirs = append(dirs, dirent.Name())
+	}
+	return dirs, nil
+}
+
+// removeAll removes a directory and all its contents from the file system
+func removeAll(fsys storage.FS, path string) error {
+	dirents, err := fsys.ReadDir(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, dirent := range dirents {
+		entryPath := filepath.Join(path, dirent.Name())
+		if dirent.IsDir() {
+			if err := removeAll(fsys, entryPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fsys.Remove(entryPath); err != nil {
+			return err
+		}
+	}
+	return fsys.Remove(path)
+}
+
+// dirPerm returns the permissions of directories containing files with the provided permissions
+func dirPerm(filePerm fs.FileMode) fs.FileMode {
+	return filePerm | (filePerm&0444)>>2
+}
@@ -0,0 +1,279 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/fako1024/gotools/bitpack"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDBPath     = "/db"
+	testImportPath = "/imported"
+)
+
+var (
+	testDay1 = time.Unix(1704067200, 0) // 2024-01-01
+	testDay2 = time.Unix(1704153600, 0) // 2024-01-02
+
+	testSIP = []byte{
+		10, 0, 0, 1,
+		0x2c, 0x04, 0x40, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x06, 0xab,
+	}
+	testDIP = []byte{
+		10, 0, 0, 2,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+)
+
+// writeTestDir writes a directory containing a block (holding an IPv4 and a tagged IPv6 flow) every
+// five minutes, starting at the provided day
+func writeTestDir(t *testing.T, fsys *godbtest.MemFS, iface string, day time.Time, nBlocks int) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testDBPath, iface), day.Unix(), gpfile.ModeWrite, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	tagIdx, err := dir.TagIndex("web")
+	require.Nil(t, err)
+
+	for i := 1; i <= nBlocks; i++ {
+		var dbData [types.ColIdxCount][]byte
+		dbData[types.SIPColIdx] = testSIP
+		dbData[types.DIPColIdx] = testDIP
+		dbData[types.ProtoColIdx] = []byte{6, 17}
+		dbData[types.DportColIdx] = []byte{0, 80, 0, 53}
+		dbData[types.BytesRcvdColIdx] = bitpack.Pack([]uint64{100, uint64(i)})
+		dbData[types.BytesSentColIdx] = bitpack.Pack([]uint64{200, 0})
+		dbData[types.PacketsRcvdColIdx] = bitpack.Pack([]uint64{1, 1})
+		dbData[types.PacketsSentColIdx] = bitpack.Pack([]uint64{2, 0})
+		dbData[types.TagColIdx] = []byte{0, tagIdx}
+
+		require.Nil(t, dir.WriteBlocks(day.Unix()+int64(i*300), gpfile.TrafficMetadata{NumV4Entries: 1, NumV6Entries: 1},
+			types.Counters{BytesRcvd: 100 + uint64(i), BytesSent: 200, PacketsRcvd: 2, PacketsSent: 2}, dbData))
+	}
+	require.Nil(t, dir.Close())
+}
+
+func newTestDB(t *testing.T) *godbtest.MemFS {
+	t.Helper()
+
+	fsys := godbtest.NewMemFS()
+	writeTestDir(t, fsys, "eth0", testDay1, 3)
+	writeTestDir(t, fsys, "eth0", testDay2, 2)
+	writeTestDir(t, fsys, "eth1", testDay1, 1)
+	return fsys
+}
+
+func readTestDir(t *testing.T, fsys *godbtest.MemFS, dbPath, iface string, day time.Time) (timestamps []int64, data [][types.ColIdxCount][]byte, dir *gpfile.GPDir) {
+	t.Helper()
+
+	gpDir := gpfile.NewDir(filepath.Join(dbPath, iface), day.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+	require.Nil(t, gpDir.Open())
+	defer func() {
+		require.Nil(t, gpDir.Close())
+	}()
+
+	for i, block := range gpDir.BlockMetadata[0].Blocks() {
+		var blockData [types.ColIdxCount][]byte
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+			if !hasColumn(gpDir.Features, colIdx) {
+				continue
+			}
+			colData, err := gpDir.ReadBlockAtIndex(colIdx, i)
+			require.Nil(t, err)
+			blockData[colIdx] = append([]byte{}, colData...)
+		}
+		timestamps = append(timestamps, block.Timestamp)
+		data = append(data, blockData)
+	}
+
+	// Retain a copy of the metadata (which is released upon Close())
+	dir = &gpfile.GPDir{Metadata: &gpfile.Metadata{
+		Tags:     append([]string{}, gpDir.Tags...),
+		Stats:    gpDir.Stats,
+		Features: gpDir.Features,
+	}}
+
+	return
+}
+
+func TestExportImport(t *testing.T) {
+	fsys := newTestDB(t)
+	ctx := context.Background()
+
+	buf := new(bytes.Buffer)
+	manifest, err := Export(ctx, testDBPath, buf, WithFS(fsys))
+	require.Nil(t, err)
+	require.Equal(t, FormatVersion, manifest.FormatVersion)
+	require.Equal(t, gpfile.MetadataVersion, manifest.SchemaVersion)
+	require.False(t, manifest.Anonymized)
+	require.Equal(t, testDay1.Unix()+300, manifest.First.Unix())
+	require.Equal(t, testDay2.Unix()+600, manifest.Last.Unix())
+	require.Equal(t, []IfaceManifest{
+		{Iface: "eth0", Dirs: 2, Blocks: 5, Flows: 10, Counts: types.Counters{BytesRcvd: 5*100 + 1 + 2 + 3 + 1 + 2, BytesSent: 5 * 200, PacketsRcvd: 10, PacketsSent: 10}},
+		{Iface: "eth1", Dirs: 1, Blocks: 1, Flows: 2, Counts: types.Counters{BytesRcvd: 101, BytesSent: 200, PacketsRcvd: 2, PacketsSent: 2}},
+	}, manifest.Ifaces)
+
+	// The staging directory has been removed
+	dirents, err := fsys.ReadDir("/tmp")
+	require.Nil(t, err)
+	require.Empty(t, dirents)
+
+	imported, err := Import(ctx, bytes.NewReader(buf.Bytes()), testImportPath, WithFS(fsys))
+	require.Nil(t, err)
+	require.Equal(t, manifest.Ifaces, imported.Ifaces)
+
+	for _, c := range []struct {
+		iface string
+		day   time.Time
+	}{
+		{"eth0", testDay1},
+		{"eth0", testDay2},
+		{"eth1", testDay1},
+	} {
+		timestamps, data, dir := readTestDir(t, fsys, testDBPath, c.iface, c.day)
+		importedTimestamps, importedData, importedDir := readTestDir(t, fsys, testImportPath, c.iface, c.day)
+		require.Equal(t, timestamps, importedTimestamps)
+		require.Equal(t, data, importedData)
+		require.Equal(t, dir.Tags, importedDir.Tags)
+		require.Equal(t, dir.Stats, importedDir.Stats)
+		require.Equal(t, dir.Features, importedDir.Features)
+	}
+
+	// Importing the same bundle again must not touch any existing directory
+	_, err = Import(ctx, bytes.NewReader(buf.Bytes()), testImportPath, WithFS(fsys))
+	require.ErrorIs(t, err, ErrDirExists)
+	_, data, _ := readTestDir(t, fsys, testImportPath, "eth0", testDay1)
+	require.Len(t, data, 3)
+}
+
+func TestExportSelection(t *testing.T) {
+	fsys := newTestDB(t)
+	ctx := context.Background()
+
+	buf := new(bytes.Buffer)
+	manifest, err := Export(ctx, testDBPath, buf, WithFS(fsys),
+		WithIfaces("eth0"),
+		WithTimeRange(testDay1.Unix()+600, testDay2.Unix()+300),
+	)
+	require.Nil(t, err)
+	require.Equal(t, testDay1.Unix()+600, manifest.First.Unix())
+	require.Equal(t, testDay2.Unix()+300, manifest.Last.Unix())
+	require.Len(t, manifest.Ifaces, 1)
+	require.Equal(t, IfaceManifest{
+		Iface: "eth0", Dirs: 2, Blocks: 3, Flows: 6,
+		Counts: types.Counters{BytesRcvd: 3*100 + 2 + 3 + 1, BytesSent: 3 * 200, PacketsRcvd: 6, PacketsSent: 6},
+	}, manifest.Ifaces[0])
+
+	_, err = Import(ctx, bytes.NewReader(buf.Bytes()), testImportPath, WithFS(fsys))
+	require.Nil(t, err)
+	timestamps, _, _ := readTestDir(t, fsys, testImportPath, "eth0", testDay1)
+	require.Equal(t, []int64{testDay1.Unix() + 600, testDay1.Unix() + 900}, timestamps)
+	timestamps, _, _ = readTestDir(t, fsys, testImportPath, "eth0", testDay2)
+	require.Equal(t, []int64{testDay2.Unix() + 300}, timestamps)
+	_, err = fsys.Stat(filepath.Join(testImportPath, "eth1"))
+	require.NotNil(t, err)
+
+	// Time ranges / interfaces without any data
+	_, err = Export(ctx, testDBPath, new(bytes.Buffer), WithFS(fsys), WithTimeRange(0, testDay1.Unix()))
+	require.ErrorIs(t, err, ErrNoData)
+	_, err = Export(ctx, testDBPath, new(bytes.Buffer), WithFS(fsys), WithIfaces("eth2"))
+	require.NotNil(t, err)
+}
+
+func TestExportAnonymized(t *testing.T) {
+	fsys := newTestDB(t)
+	ctx := context.Background()
+
+	buf := new(bytes.Buffer)
+	manifest, err := Export(ctx, testDBPath, buf, WithFS(fsys), WithIfaces("eth0"), WithAnonymization(true))
+	require.Nil(t, err)
+	require.True(t, manifest.Anonymized)
+	require.Empty(t, manifest.Host)
+
+	_, err = Import(ctx, bytes.NewReader(buf.Bytes()), testImportPath, WithFS(fsys))
+	require.Nil(t, err)
+
+	_, data, _ := readTestDir(t, fsys, testDBPath, "eth0", testDay1)
+	_, anonData, _ := readTestDir(t, fsys, testImportPath, "eth0", testDay1)
+	_, anonData2, _ := readTestDir(t, fsys, testImportPath, "eth0", testDay2)
+	require.Len(t, anonData, len(data))
+	for i := range data {
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+			if colIdx != types.SIPColIdx && colIdx != types.DIPColIdx {
+				require.Equal(t, data[i][colIdx], anonData[i][colIdx])
+				continue
+			}
+			require.Len(t, anonData[i][colIdx], len(data[i][colIdx]))
+		}
+
+		// Addresses are replaced consistently (across blocks and directories), the unspecified address is retained
+		require.NotEqual(t, testSIP[:4], anonData[i][types.SIPColIdx][:4])
+		require.NotEqual(t, testSIP[4:], anonData[i][types.SIPColIdx][4:])
+		require.NotEqual(t, testDIP[:4], anonData[i][types.DIPColIdx][:4])
+		require.Equal(t, testDIP[4:], anonData[i][types.DIPColIdx][4:])
+		require.Equal(t, anonData[0][types.SIPColIdx], anonData[i][types.SIPColIdx])
+		require.Equal(t, anonData[0][types.SIPColIdx], anonData2[0][types.SIPColIdx])
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	genBundle := func(members map[string]string) *bytes.Reader {
+		buf := new(bytes.Buffer)
+		zw, err := zstd.NewWriter(buf)
+		require.Nil(t, err)
+		tw := tar.NewWriter(zw)
+		for name, content := range members {
+			require.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(content))
+			require.Nil(t, err)
+		}
+		require.Nil(t, tw.Close())
+		require.Nil(t, zw.Close())
+		return bytes.NewReader(buf.Bytes())
+	}
+	validManifest := `{"format_version": 1, "schema_version": 3}`
+
+	for _, c := range []struct {
+		name     string
+		members  map[string]string
+		expected error
+	}{
+		{"no manifest", map[string]string{}, ErrNoManifest},
+		{"future format", map[string]string{ManifestName: `{"format_version": 2, "schema_version": 3}`}, ErrUnsupportedVersion},
+		{"future schema", map[string]string{ManifestName: `{"format_version": 1, "schema_version": 4}`}, ErrUnsupportedVersion},
+		{"path traversal", map[string]string{ManifestName: validManifest, "godb/../../etc/2024/01/1704067200/.blockmeta": ""}, ErrInvalidMember},
+		{"unexpected file", map[string]string{ManifestName: validManifest, "godb/eth0/2024/01/1704067200/evil.sh": ""}, ErrInvalidMember},
+		{"unaligned directory", map[string]string{ManifestName: validManifest, "godb/eth0/2024/01/1704067201/.blockmeta": ""}, ErrInvalidMember},
+		{"missing metadata", map[string]string{ManifestName: validManifest, "godb/eth0/2024/01/1704067200/sip.gpf": ""}, ErrInvalidMember},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			fsys := godbtest.NewMemFS()
+			_, err := Import(ctx, genBundle(c.members), testImportPath, WithFS(fsys))
+			require.ErrorIs(t, err, c.expected)
+			_, err = fsys.Stat(testImportPath)
+			require.NotNil(t, err)
+		})
+	}
+
+	// Directories failing validation are removed again
+	fsys := godbtest.NewMemFS()
+	_, err := Import(ctx, genBundle(map[string]string{
+		ManifestName: validManifest,
+		"godb/eth0/2024/01/1704067200/.blockmeta": "invalid",
+	}), testImportPath, WithFS(fsys))
+	require.NotNil(t, err)
+	_, err = fsys.Stat(testImportPath)
+	require.NotNil(t, err)
+}
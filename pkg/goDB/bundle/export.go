@@ -0,0 +1,344 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/version"
+	"github.com/els0r/telemetry/logging"
+	"github.com/fako1024/gotools/bitpack"
+	"github.com/klauspost/compress/zstd"
+)
+
+// exportedDir denotes a (day) directory staged for export
+type exportedDir struct {
+	iface        string
+	dayTimestamp int64
+}
+
+// Export writes all blocks of the goDB located at dbPath matching the selected interfaces and time range
+// to a bundle. All matching blocks are rewritten to a staging directory first (anonymizing their IP addresses
+// if requested), which is removed once the bundle has been written
+func Export(ctx context.Context, dbPath string, w io.Writer, opts ...Option) (*Manifest, error) {
+	o := newOptions(opts...)
+
+	ifaces := o.ifaces
+	if len(ifaces) == 0 {
+		var err error
+		if ifaces, err = info.GetInterfacesFS(o.fsys, dbPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var anon *anonymizer
+	if o.anonymize {
+		var err error
+		if anon, err = newAnonymizer(); err != nil {
+			return nil, err
+		}
+	}
+
+	created := o.now()
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		SchemaVersion: gpfile.MetadataVersion,
+		Created:       created.UTC(),
+		Version:       version.Short(),
+		Anonymized:    o.anonymize,
+	}
+	if !o.anonymize {
+		manifest.Host, _ = os.Hostname()
+	}
+
+	stagingPath := filepath.Join(o.tempDir, fmt.Sprintf("goprobe-export-%d", created.UnixNano()))
+	defer func() {
+		if err := removeAll(o.fsys, stagingPath); err != nil {
+			logging.FromContext(ctx).With("path", stagingPath, "error", err).Warn("failed to remove staging directory")
+		}
+	}()
+
+	var (
+		dirs        []exportedDir
+		first, last int64
+	)
+	for _, iface := range ifaces {
+		ifacePath := filepath.Join(dbPath, iface)
+		if _, err := o.fsys.Stat(ifacePath); err != nil {
+			return nil, fmt.Errorf("failed to access interface %s: %w", iface, err)
+		}
+
+		ifaceManifest := IfaceManifest{Iface: iface}
+		err := walkIface(o.fsys, ifacePath, func(dayTimestamp int64) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if dayTimestamp+gpfile.EpochDay <= o.first || dayTimestamp > o.last {
+				return nil
+			}
+
+			res, err := exportDir(ifacePath, filepath.Join(stagingPath, iface), dayTimestamp, anon, o)
+			if err != nil {
+				return fmt.Errorf("failed to export directory %s: %w", gpfile.GenPathForTimestamp(ifacePath, dayTimestamp), err)
+			}
+			if res.blocks == 0 {
+				return nil
+			}
+
+			ifaceManifest.Dirs++
+			ifaceManifest.Blocks += res.blocks
+			ifaceManifest.Flows += res.flows
+			ifaceManifest.Counts = ifaceManifest.Counts.Add(res.counts)
+			if first == 0 || res.first < first {
+				first = res.first
+			}
+			if res.last > last {
+				last = res.last
+			}
+			dirs = append(dirs, exportedDir{iface: iface, dayTimestamp: dayTimestamp})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ifaceManifest.Dirs > 0 {
+			manifest.Ifaces = append(manifest.Ifaces, ifaceManifest)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, ErrNoData
+	}
+	manifest.First, manifest.Last = time.Unix(first, 0).UTC(), time.Unix(last, 0).UTC()
+
+	if err := writeBundle(ctx, w, o.fsys, stagingPath, manifest, dirs); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// exportResult summarizes the blocks of an exported directory
+type exportResult struct {
+	blocks      int
+	flows       uint64
+	counts      types.Counters
+	first, last int64
+}
+
+// exportDir rewrites all blocks of a day directory within the selected time range to the staging directory
+// of the interface
+func exportDir(ifacePath, stagingIfacePath string, dayTimestamp int64, anon *anonymizer, o *options) (res exportResult, err error) {
+	src := gpfile.NewDir(ifacePath, dayTimestamp, gpfile.ModeRead, gpfile.WithFS(o.fsys))
+	if err := src.Open(); err != nil {
+		return res, err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	var blockIndices []int
+	for i, block := range src.BlockMetadata[0].Blocks() {
+		if block.Timestamp >= o.first && block.Timestamp <= o.last {
+			blockIndices = append(blockIndices, i)
+		}
+	}
+	if len(blockIndices) == 0 {
+		return res, nil
+	}
+
+	dst := gpfile.NewDir(stagingIfacePath, dayTimestamp, gpfile.ModeWrite, gpfile.WithFS(o.fsys))
+	if err := dst.Open(); err != nil {
+		return res, err
+	}
+
+	// The values of the tag column refer to the tag dictionary of the directory, hence it is retained as is
+	dst.Tags = append(dst.Tags, src.Tags...)
+
+	hasLatency := src.Features&gpfile.FeatureHandshakeRTT != 0 && len(src.BlockLatency) == src.NBlocks()
+	for _, i := range blockIndices {
+		timestamp := src.BlockMetadata[0].Blocks()[i].Timestamp
+		traffic := src.BlockTraffic[i]
+
+		var (
+			dbData [types.ColIdxCount][]byte
+			counts types.Counters
+		)
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+			if !hasColumn(src.Features, colIdx) {
+				continue
+			}
+			if dbData[colIdx], err = src.ReadBlockAtIndex(colIdx, i); err != nil {
+				return res, errors.Join(err, dst.Close())
+			}
+
+			switch {
+			case anon != nil && (colIdx == types.SIPColIdx || colIdx == types.DIPColIdx):
+				if dbData[colIdx], err = anon.anonymizeColumn(dbData[colIdx], traffic.NumV4Entries, traffic.NumV6Entries); err != nil {
+					return res, errors.Join(err, dst.Close())
+				}
+			case colIdx.IsCounterCol():
+				addCounter(&counts, colIdx, dbData[colIdx])
+			}
+		}
+
+		if hasLatency {
+			err = dst.WriteBlocksWithLatency(timestamp, traffic, src.BlockLatency[i], counts, dbData)
+		} else {
+			err = dst.WriteBlocks(timestamp, traffic, counts, dbData)
+		}
+		if err != nil {
+			return res, errors.Join(err, dst.Close())
+		}
+
+		if res.blocks == 0 {
+			res.first = timestamp
+		}
+		res.last = timestamp
+		res.blocks++
+		res.flows += traffic.NumFlows()
+		res.counts = res.counts.Add(counts)
+	}
+
+	// Retain the provenance of all exported backfilled blocks
+	for _, backfill := range src.Backfills {
+		if backfill.Timestamp >= res.first && backfill.Timestamp <= res.last {
+			dst.Backfills = append(dst.Backfills, backfill)
+			dst.Features |= gpfile.FeatureBackfill
+		}
+	}
+
+	return res, dst.Close()
+}
+
+// writeBundle writes the manifest and all staged directories to a zstd compressed tar archive
+func writeBundle(ctx context.Context, w io.Writer, fsys storage.FS, stagingPath string, manifest *Manifest, dirs []exportedDir) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	if err := writeMembers(ctx, tw, fsys, stagingPath, manifest, dirs); err != nil {
+		return errors.Join(err, zw.Close())
+	}
+	if err := tw.Close(); err != nil {
+		return errors.Join(err, zw.Close())
+	}
+	return zw.Close()
+}
+
+func writeMembers(ctx context.Context, tw *tar.Writer, fsys storage.FS, stagingPath string, manifest *Manifest, dirs []exportedDir) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     ManifestName,
+		Mode:     int64(defaultPermissions),
+		Size:     int64(len(manifestData)),
+		ModTime:  manifest.Created,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The metadata is written last, so that it is only extracted once all column files are available
+		dirPath := gpfile.GenPathForTimestamp(filepath.Join(stagingPath, dir.iface), dir.dayTimestamp)
+		memberPath := path.Join(DBDir, dir.iface, filepath.ToSlash(gpfile.GenPathForTimestamp("", dir.dayTimestamp)))
+		for colIdx := types.ColumnIndex(0); colIdx <= types.ColIdxCount; colIdx++ {
+			fileName := metadataFileName
+			if colIdx < types.ColIdxCount {
+				fileName = types.ColumnFileNames[colIdx] + gpfile.FileSuffix
+			}
+			if err := addFile(tw, fsys, filepath.Join(dirPath, fileName), path.Join(memberPath, fileName), manifest.Created); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addFile adds a staged file to the tar archive (skipping files that do not exist, e.g. optional columns)
+func addFile(tw *tar.Writer, fsys storage.FS, filePath, memberPath string, modTime time.Time) error {
+	stat, err := fsys.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	f, err := fsys.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     memberPath,
+		Mode:     int64(defaultPermissions),
+		Size:     stat.Size(),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+
+	return err
+}
+
+// addCounter adds the sum of all values of a (bit packed) counter column to the respective counter
+func addCounter(counts *types.Counters, colIdx types.ColumnIndex, data []byte) {
+	var sum uint64
+	for _, val := range bitpack.Unpack(data) {
+		sum += val
+	}
+	switch colIdx {
+	case types.BytesRcvdColIdx:
+		counts.BytesRcvd += sum
+	case types.BytesSentColIdx:
+		counts.BytesSent += sum
+	case types.PacketsRcvdColIdx:
+		counts.PacketsRcvd += sum
+	case types.PacketsSentColIdx:
+		counts.PacketsSent += sum
+	}
+}
+
+// hasColumn returns if a column is stored in a directory with the provided feature flags (the optional
+// columns only being stored if the respective feature is enabled)
+func hasColumn(features uint64, colIdx types.ColumnIndex) bool {
+	switch colIdx {
+	case types.TagColIdx:
+		return features&gpfile.FeatureTags != 0
+	case types.TTLMinColIdx, types.TTLMaxColIdx:
+		return features&gpfile.FeatureTTL != 0
+	}
+	return true
+}
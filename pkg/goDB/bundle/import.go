@@ -0,0 +1,270 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+// member denotes a file of a goDB directory contained in a bundle
+type member struct {
+	exportedDir
+	fileName string
+}
+
+// Import imports all goDB directories contained in a bundle into the goDB located at dbPath. The bundle is
+// read twice: First, the manifest and all members are validated and it is ensured that none of the contained
+// directories already exist in the goDB (in which case nothing is imported). Second, all directories are
+// extracted and validated by opening them. If an error occurs, all directories created so far are removed
+func Import(ctx context.Context, r io.ReadSeeker, dbPath string, opts ...Option) (*Manifest, error) {
+	o := newOptions(opts...)
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	manifest, dirs, err := scanBundle(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directory structure of the goDB depends on the local time zone, hence all paths are generated from
+	// the timestamps of the directories (instead of being taken from the bundle)
+	for _, dir := range dirs {
+		dirPath := gpfile.GenPathForTimestamp(filepath.Join(dbPath, dir.iface), dir.dayTimestamp)
+		if _, err := o.fsys.Stat(dirPath); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrDirExists, dirPath)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := zr.Reset(r); err != nil {
+		return nil, err
+	}
+
+	created, err := extractBundle(ctx, zr, dbPath, dirs, o)
+	if err != nil {
+		for i := len(created) - 1; i >= 0; i-- {
+			err = errors.Join(err, removeAll(o.fsys, created[i]))
+		}
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// scanBundle reads the manifest of a bundle and validates all of its members, returning the contained
+// goDB directories (ordered by interface and timestamp)
+func scanBundle(r io.Reader) (*Manifest, []exportedDir, error) {
+	var (
+		manifest *Manifest
+		metadata = make(map[exportedDir]bool)
+	)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		case hdr.Name == ManifestName:
+			manifest = new(Manifest)
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode manifest: %w", err)
+			}
+			if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+				return nil, nil, fmt.Errorf("%w (format version: %d)", ErrUnsupportedVersion, manifest.FormatVersion)
+			}
+			if manifest.SchemaVersion > gpfile.MetadataVersion {
+				return nil, nil, fmt.Errorf("%w (schema version: %d)", ErrUnsupportedVersion, manifest.SchemaVersion)
+			}
+			continue
+		}
+
+		m, err := parseMember(hdr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, exists := metadata[m.exportedDir]; !exists {
+			metadata[m.exportedDir] = false
+		}
+		if m.fileName == metadataFileName {
+			metadata[m.exportedDir] = true
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, ErrNoManifest
+	}
+
+	dirs := make([]exportedDir, 0, len(metadata))
+	for dir, hasMetadata := range metadata {
+		if !hasMetadata {
+			return nil, nil, fmt.Errorf("%w: directory %s/%d lacks metadata", ErrInvalidMember, dir.iface, dir.dayTimestamp)
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].iface != dirs[j].iface {
+			return dirs[i].iface < dirs[j].iface
+		}
+		return dirs[i].dayTimestamp < dirs[j].dayTimestamp
+	})
+
+	return manifest, dirs, nil
+}
+
+// parseMember validates a bundle member, which must be a regular file of a goDB directory, i.e.
+// <DBDir>/<iface>/<year>/<month>/<timestamp>/<file>
+func parseMember(hdr *tar.Header) (m member, err error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s (%s)", ErrInvalidMember, hdr.Name, reason)
+	}
+
+	if hdr.Typeflag != tar.TypeReg {
+		return m, invalid("not a regular file")
+	}
+	parts := strings.Split(hdr.Name, "/")
+	if len(parts) != 6 || path.Clean(hdr.Name) != hdr.Name || parts[0] != DBDir {
+		return m, invalid("unexpected path")
+	}
+
+	m.iface, m.fileName = parts[1], parts[5]
+	if m.iface == "." || m.iface == ".." || strings.ContainsRune(m.iface, '\\') {
+		return m, invalid("invalid interface")
+	}
+	if _, err := strconv.Atoi(parts[2]); err != nil {
+		return m, invalid("invalid year")
+	}
+	if _, err := strconv.Atoi(parts[3]); err != nil {
+		return m, invalid("invalid month")
+	}
+	if m.dayTimestamp, err = strconv.ParseInt(parts[4], 10, 64); err != nil || gpfile.DirTimestamp(m.dayTimestamp) != m.dayTimestamp {
+		return m, invalid("invalid directory timestamp")
+	}
+	if !isDirFile(m.fileName) {
+		return m, invalid("unexpected file")
+	}
+
+	return m, nil
+}
+
+// extractBundle extracts all members of a bundle to the goDB and validates all extracted directories,
+// returning the paths of all directories created (even if an error occurs)
+func extractBundle(ctx context.Context, r io.Reader, dbPath string, dirs []exportedDir, o *options) (created []string, err error) {
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return created, err
+		}
+
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return created, err
+		}
+		if hdr.Typeflag == tar.TypeDir || hdr.Name == ManifestName {
+			continue
+		}
+
+		// The bundle has been validated before, but might have changed in the meantime
+		m, err := parseMember(hdr)
+		if err != nil {
+			return created, err
+		}
+
+		dirPath := gpfile.GenPathForTimestamp(filepath.Join(dbPath, m.iface), m.dayTimestamp)
+		if created, err = mkdirAll(o, dirPath, created); err != nil {
+			return created, err
+		}
+		if err := extractFile(o, tr, filepath.Join(dirPath, m.fileName)); err != nil {
+			return created, err
+		}
+	}
+
+	for _, dir := range dirs {
+		gpDir := gpfile.NewDir(filepath.Join(dbPath, dir.iface), dir.dayTimestamp, gpfile.ModeRead, gpfile.WithFS(o.fsys))
+		if err := gpDir.Open(); err != nil {
+			return created, fmt.Errorf("failed to validate imported directory %s: %w", gpDir.Path(), err)
+		}
+		if err := gpDir.Close(); err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+// mkdirAll creates a directory (and all its parents), recording the topmost directory that did not
+// exist before
+func mkdirAll(o *options, dirPath string, created []string) ([]string, error) {
+	if _, err := o.fsys.Stat(dirPath); err == nil {
+		return created, nil
+	}
+
+	topmost := dirPath
+	for parent := filepath.Dir(topmost); parent != topmost; parent = filepath.Dir(topmost) {
+		if _, err := o.fsys.Stat(parent); err == nil {
+			break
+		}
+		topmost = parent
+	}
+	if err := o.fsys.MkdirAll(dirPath, dirPerm(o.permissions)); err != nil {
+		return created, err
+	}
+
+	return append(created, topmost), nil
+}
+
+func extractFile(o *options, r io.Reader, filePath string) error {
+	f, err := o.fsys.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, o.permissions)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	return f.Close()
+}
+
+// isDirFile returns if a file name denotes a file of a goDB directory
+func isDirFile(fileName string) bool {
+	if fileName == metadataFileName {
+		return true
+	}
+	for _, colName := range types.ColumnFileNames {
+		if fileName == colName+gpfile.FileSuffix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package conditions
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MacroPrefix denotes the prefix referencing a macro within a conditional (e.g. "$corp_nets")
+	MacroPrefix = "$"
+
+	// MacroDefinitionOperator separates the name of a macro from its conditional in a macro
+	// definition (e.g. "corp_nets := snet = 10.0.0.0/8 | snet = 172.16.0.0/12")
+	MacroDefinitionOperator = ":="
+)
+
+var (
+	// ErrInvalidMacro denotes that a macro definition is malformed
+	ErrInvalidMacro = errors.New("invalid condition macro")
+
+	// ErrUnknownMacro denotes that a conditional references a macro that is not defined
+	ErrUnknownMacro = errors.New("unknown condition macro")
+
+	// ErrMacroCycle denotes that the expansion of a macro references the macro itself
+	ErrMacroCycle = errors.New("cyclic condition macro")
+)
+
+var (
+	macroNameRegExp      = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	macroReferenceRegExp = regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+)
+
+// Macros stores named conditionals (keyed by their lower case name), which can be referenced
+// within other conditionals (and macros) via their name prefixed by MacroPrefix
+type Macros map[string]string
+
+// ParseMacros parses a set of macro definitions of the form "<name> := <conditional>" and
+// validates that all of them can be expanded, i.e. that they only reference defined macros and
+// are free of cycles. Macro names are case insensitive
+func ParseMacros(definitions ...string) (Macros, error) {
+	macros := make(Macros, len(definitions))
+	for _, definition := range definitions {
+		name, conditional, found := strings.Cut(definition, MacroDefinitionOperator)
+		if !found {
+			return nil, fmt.Errorf("%w: %q lacks %q", ErrInvalidMacro, definition, MacroDefinitionOperator)
+		}
+
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), MacroPrefix))
+		if !macroNameRegExp.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidMacro, name)
+		}
+		conditional = strings.TrimSpace(conditional)
+		if conditional == "" {
+			return nil, fmt.Errorf("%w: %s has an empty conditional", ErrInvalidMacro, name)
+		}
+		if _, exists := macros[name]; exists {
+			return nil, fmt.Errorf("%w: %s is defined more than once", ErrInvalidMacro, name)
+		}
+		macros[name] = conditional
+	}
+
+	for name := range macros {
+		if _, err := macros.Expand(MacroPrefix + name); err != nil {
+			return nil, err
+		}
+	}
+
+	return macros, nil
+}
+
+// Expand replaces all macro references within a conditional by the (recursively expanded) conditional
+// of the respective macro, enclosed in parentheses to retain its precedence
+func (m Macros) Expand(conditional string) (string, error) {
+	return m.expand(conditional, nil)
+}
+
+func (m Macros) expand(conditional string, stack []string) (string, error) {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, loc := range macroReferenceRegExp.FindAllStringSubmatchIndex(conditional, -1) {
+		name := strings.ToLower(conditional[loc[2]:loc[3]])
+		for i, parent := range stack {
+			if parent == name {
+				return "", fmt.Errorf("%w: %s -> %s", ErrMacroCycle, strings.Join(stack[i:], " -> "), name)
+			}
+		}
+		macro, exists := m[name]
+		if !exists {
+			return "", fmt.Errorf("%w: %s%s", ErrUnknownMacro, MacroPrefix, name)
+		}
+
+		expanded, err := m.expand(macro, append(stack, name))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(conditional[last:loc[0]])
+		b.WriteString("(")
+		b.WriteString(expanded)
+		b.WriteString(")")
+		last = loc[1]
+	}
+	b.WriteString(conditional[last:])
+
+	return b.String(), nil
+}
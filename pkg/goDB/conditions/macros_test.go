@@ -0,0 +1,65 @@
+package conditions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMacros(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		definitions []string
+		expectedErr error
+	}{
+		{"none", nil, nil},
+		{"single", []string{"corp_nets := snet = 10.0.0.0/8 | snet = 172.16.0.0/12"}, nil},
+		{"nested", []string{"web := dport = 443", "corp_web := $Corp_Nets & $web", "corp_nets := snet = 10.0.0.0/8"}, nil},
+		{"prefixed name", []string{"$web := dport = 443"}, nil},
+		{"missing operator", []string{"web = dport = 443"}, ErrInvalidMacro},
+		{"invalid name", []string{"web-ports := dport = 443"}, ErrInvalidMacro},
+		{"empty conditional", []string{"web := "}, ErrInvalidMacro},
+		{"duplicate", []string{"web := dport = 443", "WEB := dport = 80"}, ErrInvalidMacro},
+		{"unknown reference", []string{"corp_web := $corp_nets & dport = 443"}, ErrUnknownMacro},
+		{"self reference", []string{"web := dport = 443 | $web"}, ErrMacroCycle},
+		{"cycle", []string{"a := $b", "b := $c", "c := dport = 80 | $a"}, ErrMacroCycle},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseMacros(c.definitions...)
+			require.ErrorIs(t, err, c.expectedErr)
+		})
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	macros, err := ParseMacros(
+		"corp_nets := snet = 10.0.0.0/8 | snet = 172.16.0.0/12",
+		"corp_web := $corp_nets & dport = 443",
+	)
+	require.Nil(t, err)
+
+	for _, c := range []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"dport = 80", "dport = 80"},
+		{"$corp_nets", "(snet = 10.0.0.0/8 | snet = 172.16.0.0/12)"},
+		{"!$CORP_NETS & dport = 80", "!(snet = 10.0.0.0/8 | snet = 172.16.0.0/12) & dport = 80"},
+		{"$corp_web|$corp_nets", "((snet = 10.0.0.0/8 | snet = 172.16.0.0/12) & dport = 443)|(snet = 10.0.0.0/8 | snet = 172.16.0.0/12)"},
+		{"tag $= -prod", "tag $= -prod"},
+	} {
+		t.Run(c.input, func(t *testing.T) {
+			expanded, err := macros.Expand(c.input)
+			require.Nil(t, err)
+			require.Equal(t, c.expected, expanded)
+
+			// the expanded conditional must remain valid
+			_, err = Tokenize(SanitizeUserInput(expanded))
+			require.Nil(t, err)
+		})
+	}
+
+	_, err = macros.Expand("$unknown & dport = 80")
+	require.ErrorIs(t, err, ErrUnknownMacro)
+}
@@ -23,8 +23,8 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
 // Returns an identical version of the receiver instrumented
@@ -50,6 +50,18 @@ func generateCompareValue(condition *conditionNode) error {
 		err       error
 	)
 
+	// IP attributes may be checked against an IP address class instead of a specific address
+	if condition.attribute == types.SIPName || condition.attribute == types.DIPName {
+		if isClass, err := generateIPClassCompareValue(condition); isClass {
+			return err
+		}
+	}
+
+	// string matching operators are evaluated against the (dictionary encoded) label values
+	if isMatchComparator(condition.comparator) {
+		return generateMatchCompareValue(condition)
+	}
+
 	if value, netmask, ipVersion, err = conditionBytesAndNetmask(*condition); err != nil {
 		return err
 	}
@@ -278,6 +290,60 @@ func generateCompareValue(condition *conditionNode) error {
 		default:
 			return fmt.Errorf("comparator %q not allowed for attribute %q", condition.comparator, condition.attribute)
 		}
+	case types.TagName:
+		switch condition.comparator {
+		case "=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return currentValue.GetTag() == value[0]
+			}
+			return nil
+		case "!=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return currentValue.GetTag() != value[0]
+			}
+			return nil
+		default:
+			return fmt.Errorf("comparator %q not allowed for attribute %q", condition.comparator, condition.attribute)
+		}
+	case types.TTLMinName, types.TTLMaxName:
+		getTTL := types.Key.GetTTLMin
+		if condition.attribute == types.TTLMaxName {
+			getTTL = types.Key.GetTTLMax
+		}
+		switch condition.comparator {
+		case "=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) == value[0]
+			}
+			return nil
+		case "!=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) != value[0]
+			}
+			return nil
+		case "<":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) < value[0]
+			}
+			return nil
+		case ">":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) > value[0]
+			}
+			return nil
+		case "<=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) <= value[0]
+			}
+			return nil
+		case ">=":
+			condition.compareValue = func(currentValue types.Key) bool {
+				return getTTL(currentValue) >= value[0]
+			}
+			return nil
+		default:
+			return fmt.Errorf("comparator %q not allowed for attribute %q", condition.comparator, condition.attribute)
+		}
 	default:
 		return fmt.Errorf("unknown attribute %q", condition.attribute)
 	}
@@ -304,7 +370,6 @@ func conditionBytesAndNetmask(condition conditionNode) ([]byte, int, types.IPVer
 	var (
 		err       error
 		num       uint64
-		isIn      bool
 		netmask   int64
 		isIPv4    bool
 		ipVersion types.IPVersion
@@ -372,19 +437,38 @@ func conditionBytesAndNetmask(condition conditionNode) ([]byte, int, types.IPVer
 			}
 
 		case types.ProtoName:
-			if num, err = strconv.ParseUint(value, 10, 8); err != nil {
-				if num, isIn = protocols.GetIPProtoID(value); !isIn {
-					return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse protocol value: %w", err)
-				}
+			proto, err := protocols.Parse(value)
+			if err != nil {
+				return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse protocol value: %w", err)
 			}
 
-			condBytes = []byte{uint8(num & 0xff)}
+			condBytes = []byte{proto}
 		case types.DportName:
-			if num, err = strconv.ParseUint(value, 10, 16); err != nil {
+			// ICMP flows are grouped by message type / code, which may be provided as "<type>/<code>"
+			if strings.Contains(value, "/") {
+				typeCode, err := protocols.ParseICMP(value)
+				if err != nil {
+					return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse dport value: %w", err)
+				}
+				num = uint64(typeCode)
+			} else if num, err = strconv.ParseUint(value, 10, 16); err != nil {
 				return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse dport value: %w", err)
 			}
 
 			condBytes = []byte{uint8(num >> 8), uint8(num & 0xff)}
+		case types.TagName:
+			id, err := types.InternTag(value)
+			if err != nil {
+				return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse tag value: %w", err)
+			}
+
+			condBytes = []byte{id}
+		case types.TTLMinName, types.TTLMaxName:
+			if num, err = strconv.ParseUint(value, 10, 8); err != nil {
+				return nil, 0, types.IPVersionNone, fmt.Errorf("could not parse %s value: %w", attribute, err)
+			}
+
+			condBytes = []byte{uint8(num)}
 		default:
 			return nil, 0, types.IPVersionNone, fmt.Errorf("unknown attribute: %s", attribute)
 		}
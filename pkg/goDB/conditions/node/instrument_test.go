@@ -120,6 +120,10 @@ var conditionBytesAndNetmaskTests = []struct {
 	{conditionNode{attribute: "dport", comparator: "=", value: "80"}, []byte{0, 80}, 0, types.IPVersionNone, true},
 	{conditionNode{attribute: "dport", comparator: "=", value: "8080"}, []byte{0x1F, 0x90}, 0, types.IPVersionNone, true},
 	{conditionNode{attribute: "dport", comparator: "=", value: "65535"}, []byte{0xFF, 0xFF}, 0, types.IPVersionNone, true},
+	// ICMP type / code
+	{conditionNode{attribute: "dport", comparator: "=", value: "8/0"}, []byte{8, 0}, 0, types.IPVersionNone, true},
+	{conditionNode{attribute: "dport", comparator: "=", value: "3/13"}, []byte{3, 13}, 0, types.IPVersionNone, true},
+	{conditionNode{attribute: "dport", comparator: "=", value: "3/256"}, nil, 0, types.IPVersionNone, false},
 	// wrong attribute
 	{conditionNode{attribute: "sip", comparator: "=", value: "8080"}, nil, 0, types.IPVersionNone, false},
 	{conditionNode{attribute: "dip", comparator: "=", value: "8080"}, nil, 0, types.IPVersionNone, false},
@@ -153,3 +157,79 @@ func TestConditionBytesAndNetmask(t *testing.T) {
 		}
 	}
 }
+
+func TestTagCondition(t *testing.T) {
+	voip, err := types.InternTag("voip")
+	if err != nil {
+		t.Fatalf("failed to intern tag: %s", err)
+	}
+
+	voipKey := types.NewV4Key([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, []byte{0, 80}, 17)
+	voipKey.PutTag(voip)
+	untaggedKey := types.NewV6Key(make([]byte, 16), make([]byte, 16), []byte{0, 80}, 17)
+
+	var tests = []struct {
+		input    conditionNode
+		success  bool
+		voip     bool
+		untagged bool
+	}{
+		{conditionNode{attribute: types.TagName, comparator: "=", value: "voip"}, true, true, false},
+		{conditionNode{attribute: types.TagName, comparator: "!=", value: "voip"}, true, false, true},
+		{conditionNode{attribute: types.TagName, comparator: "=", value: "backup"}, true, false, false},
+		{conditionNode{attribute: types.TagName, comparator: "<", value: "voip"}, false, false, false},
+		{conditionNode{attribute: types.TagName, comparator: "=", value: "no tag!"}, false, false, false},
+	}
+	for _, test := range tests {
+		err := generateCompareValue(&test.input)
+		if !test.success {
+			if err == nil {
+				t.Fatalf("Expected to fail on input %v but it didn't", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpectedly failed on input %v. The error is: %s", test.input, err)
+		}
+		if test.input.compareValue(voipKey) != test.voip || test.input.compareValue(untaggedKey) != test.untagged {
+			t.Fatalf("Unexpected evaluation result for input %v", test.input)
+		}
+	}
+}
+
+func TestTTLCondition(t *testing.T) {
+	key := types.NewV4Key([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, []byte{0, 80}, 6)
+	key.PutTTLMin(58)
+	key.PutTTLMax(64)
+	unrecordedKey := types.NewV6Key(make([]byte, 16), make([]byte, 16), []byte{0, 80}, 6)
+
+	var tests = []struct {
+		input      conditionNode
+		success    bool
+		recorded   bool
+		unrecorded bool
+	}{
+		{conditionNode{attribute: types.TTLMinName, comparator: "=", value: "58"}, true, true, false},
+		{conditionNode{attribute: types.TTLMinName, comparator: "<", value: "64"}, true, true, true},
+		{conditionNode{attribute: types.TTLMaxName, comparator: ">=", value: "64"}, true, true, false},
+		{conditionNode{attribute: types.TTLMaxName, comparator: "!=", value: "0"}, true, true, false},
+		{conditionNode{attribute: types.TTLMaxName, comparator: "<=", value: "58"}, true, false, true},
+		{conditionNode{attribute: types.TTLMinName, comparator: "=", value: "256"}, false, false, false},
+		{conditionNode{attribute: types.TTLMaxName, comparator: "=", value: "high"}, false, false, false},
+	}
+	for _, test := range tests {
+		err := generateCompareValue(&test.input)
+		if !test.success {
+			if err == nil {
+				t.Fatalf("Expected to fail on input %v but it didn't", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpectedly failed on input %v. The error is: %s", test.input, err)
+		}
+		if test.input.compareValue(key) != test.recorded || test.input.compareValue(unrecordedKey) != test.unrecorded {
+			t.Fatalf("Unexpected evaluation result for input %v", test.input)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// ipClassFn determines if a raw IP address (4 bytes for IPv4, 16 bytes for IPv6) belongs
+// to an IP address class
+type ipClassFn func(ip []byte) bool
+
+// ipClasses maps the supported IP address classes (see types.IPClasses) to the functions
+// determining membership. All checks are performed directly on the relevant bytes of the
+// address instead of iterating over lists of prefixes
+var ipClasses = map[string]ipClassFn{
+	types.IPClassPrivate:          isPrivateIP,
+	types.IPClassLinkLocal:        isLinkLocalIP,
+	types.IPClassLinkLocalSugared: isLinkLocalIP,
+	types.IPClassBogon:            isBogonIP,
+	types.IPClassPublic:           isPublicIP,
+}
+
+func isIPClass(value string) bool {
+	_, isClass := ipClasses[value]
+	return isClass
+}
+
+// isPrivateIP checks for RFC1918 (10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16) and
+// unique local (fc00::/7) addresses
+func isPrivateIP(ip []byte) bool {
+	if len(ip) == types.IPv4Width {
+		return ip[0] == 10 ||
+			(ip[0] == 172 && ip[1]&0xf0 == 16) ||
+			(ip[0] == 192 && ip[1] == 168)
+	}
+	return ip[0]&0xfe == 0xfc
+}
+
+// isLinkLocalIP checks for link-local (169.254.0.0/16, fe80::/10) addresses
+func isLinkLocalIP(ip []byte) bool {
+	if len(ip) == types.IPv4Width {
+		return ip[0] == 169 && ip[1] == 254
+	}
+	return ip[0] == 0xfe && ip[1]&0xc0 == 0x80
+}
+
+// isBogonIP checks for addresses that are not globally routable. For IPv4 these are all
+// special-purpose ranges (RFC 6890), including private, shared (CGNAT), loopback,
+// link-local, documentation, benchmarking, multicast and reserved addresses. For IPv6,
+// anything outside of the global unicast range 2000::/3 (which includes ULA, link-local,
+// loopback and multicast), as well as documentation (2001:db8::/32) and the former 6bone
+// range (3ffe::/16) is considered a bogon
+func isBogonIP(ip []byte) bool {
+	if len(ip) == types.IPv4Width {
+		switch ip[0] {
+		case 0, 10, 127:
+			return true
+		case 100:
+			return ip[1]&0xc0 == 64 // 100.64.0.0/10
+		case 169:
+			return ip[1] == 254 // 169.254.0.0/16
+		case 172:
+			return ip[1]&0xf0 == 16 // 172.16.0.0/12
+		case 192:
+			return ip[1] == 168 || // 192.168.0.0/16
+				(ip[1] == 0 && (ip[2] == 0 || ip[2] == 2)) // 192.0.0.0/24, 192.0.2.0/24
+		case 198:
+			return ip[1]&0xfe == 18 || // 198.18.0.0/15
+				(ip[1] == 51 && ip[2] == 100) // 198.51.100.0/24
+		case 203:
+			return ip[1] == 0 && ip[2] == 113 // 203.0.113.0/24
+		}
+		return ip[0] >= 224 // 224.0.0.0/4, 240.0.0.0/4
+	}
+
+	if ip[0]&0xe0 != 0x20 {
+		return true
+	}
+	return (ip[0] == 0x20 && ip[1] == 0x01 && ip[2] == 0x0d && ip[3] == 0xb8) ||
+		(ip[0] == 0x3f && ip[1] == 0xfe)
+}
+
+// isPublicIP checks for globally routable addresses
+func isPublicIP(ip []byte) bool {
+	return !isBogonIP(ip)
+}
+
+// generateIPClassCompareValue instruments a condition checking an IP attribute against an
+// IP address class (e.g. "sip = private"). It returns false if the value of the condition
+// does not denote an IP address class
+func generateIPClassCompareValue(condition *conditionNode) (bool, error) {
+	matches, isClass := ipClasses[condition.value]
+	if !isClass {
+		return false, nil
+	}
+
+	getIP := types.Key.GetSIP
+	if condition.attribute == types.DIPName {
+		getIP = types.Key.GetDIP
+	}
+
+	// classes span both IPv4 and IPv6 addresses
+	condition.ipVersion = types.IPVersionBoth
+
+	switch condition.comparator {
+	case "=":
+		condition.compareValue = func(currentValue types.Key) bool {
+			return matches(getIP(currentValue))
+		}
+	case "!=":
+		condition.compareValue = func(currentValue types.Key) bool {
+			return !matches(getIP(currentValue))
+		}
+	default:
+		return true, fmt.Errorf("comparator %q not allowed for IP class %q", condition.comparator, condition.value)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package node
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/conditions"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPClasses(t *testing.T) {
+	var tests = []struct {
+		ip        string
+		private   bool
+		linkLocal bool
+		bogon     bool
+	}{
+		{"8.8.8.8", false, false, false},
+		{"10.1.2.3", true, false, true},
+		{"172.15.255.255", false, false, false},
+		{"172.16.0.1", true, false, true},
+		{"172.31.255.255", true, false, true},
+		{"172.32.0.1", false, false, false},
+		{"192.168.1.1", true, false, true},
+		{"192.169.1.1", false, false, false},
+		{"169.254.10.1", false, true, true},
+		{"100.64.0.1", false, false, true},
+		{"100.128.0.1", false, false, false},
+		{"127.0.0.1", false, false, true},
+		{"0.1.2.3", false, false, true},
+		{"192.0.2.1", false, false, true},
+		{"198.19.255.255", false, false, true},
+		{"198.51.100.7", false, false, true},
+		{"203.0.113.7", false, false, true},
+		{"224.0.0.251", false, false, true},
+		{"255.255.255.255", false, false, true},
+		{"2a00:1450:400a:803::200e", false, false, false},
+		{"fd00::1", true, false, true},
+		{"fc00::1", true, false, true},
+		{"fe80::1", false, true, true},
+		{"febf::1", false, true, true},
+		{"fec0::1", false, false, true},
+		{"::1", false, false, true},
+		{"ff02::1", false, false, true},
+		{"2001:db8::1", false, false, true},
+		{"3ffe::1", false, false, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.ip, func(t *testing.T) {
+			ip := netip.MustParseAddr(test.ip).AsSlice()
+
+			require.Equal(t, test.private, ipClasses[types.IPClassPrivate](ip), "private")
+			require.Equal(t, test.linkLocal, ipClasses[types.IPClassLinkLocal](ip), "link-local")
+			require.Equal(t, test.bogon, ipClasses[types.IPClassBogon](ip), "bogon")
+			require.Equal(t, !test.bogon, ipClasses[types.IPClassPublic](ip), "public")
+		})
+	}
+}
+
+func TestIPClassConditions(t *testing.T) {
+	newKey := func(sip, dip string) types.Key {
+		return types.NewKey(netip.MustParseAddr(sip).AsSlice(), netip.MustParseAddr(dip).AsSlice(), []byte{0, 80}, 6)
+	}
+
+	var tests = []struct {
+		condition string
+		key       types.Key
+		matches   bool
+	}{
+		{"sip is private", newKey("10.0.0.1", "8.8.8.8"), true},
+		{"sip is private", newKey("8.8.8.8", "10.0.0.1"), false},
+		{"dip is public", newKey("10.0.0.1", "8.8.8.8"), true},
+		{"dip is not public", newKey("10.0.0.1", "8.8.8.8"), false},
+		{"dip is bogon", newKey("fd00::1", "2001:db8::1"), true},
+		{"host is link-local", newKey("fe80::1", "2a00::1"), true},
+		{"sip is private & dip is public & dport = 80", newKey("192.168.1.1", "1.1.1.1"), true},
+		{"!(sip is private | dip is private)", newKey("192.168.1.1", "1.1.1.1"), false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.condition, func(t *testing.T) {
+			node, _, err := ParseAndInstrument(conditions.SanitizeUserInput(test.condition), time.Second)
+			require.Nil(t, err)
+
+			for attribute, ipVersion := range node.Attributes() {
+				if attribute == types.SIPName || attribute == types.DIPName {
+					require.Equal(t, types.IPVersionBoth, ipVersion)
+				}
+			}
+			require.Equal(t, test.matches, node.Evaluate(test.key))
+		})
+	}
+
+	_, _, err := ParseAndInstrument("sip < private", time.Second)
+	require.NotNil(t, err)
+}
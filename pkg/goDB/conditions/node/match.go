@@ -0,0 +1,127 @@
+package node
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// String matching operators (and their negations) supported for label attributes (e.g. tags)
+const (
+	prefixComparator    = "^="
+	notPrefixComparator = "!^="
+	suffixComparator    = "$="
+	notSuffixComparator = "!$="
+	globComparator      = "~="
+	notGlobComparator   = "!~="
+)
+
+// negatedMatchComparators maps each string matching operator to its negation
+var negatedMatchComparators = map[string]string{
+	prefixComparator:    notPrefixComparator,
+	notPrefixComparator: prefixComparator,
+	suffixComparator:    notSuffixComparator,
+	notSuffixComparator: suffixComparator,
+	globComparator:      notGlobComparator,
+	notGlobComparator:   globComparator,
+}
+
+func isMatchComparator(comparator string) bool {
+	_, exists := negatedMatchComparators[comparator]
+	return exists
+}
+
+// Label values are dictionary encoded (the key only stores the ID of e.g. a tag), hence the outcome
+// of a string match is determined once per ID instead of once per flow
+const (
+	matchUnknown uint32 = iota
+	matchTrue
+	matchFalse
+)
+
+// dictMatcher caches the outcome of a string match for all IDs of a dictionary encoded label. Since
+// new IDs may be assigned while a query is running (e.g. for tags only present in some of the queried
+// directories), the outcome for each ID is determined upon its first occurrence
+type dictMatcher struct {
+	lookup func(id byte) string
+	match  func(name string) bool
+
+	results [256]atomic.Uint32
+}
+
+// matches determines if the label with the given ID matches. Flows without a label (ID 0) never match
+func (m *dictMatcher) matches(id byte) bool {
+	switch m.results[id].Load() {
+	case matchTrue:
+		return true
+	case matchFalse:
+		return false
+	}
+
+	res := matchFalse
+	if id != types.UntaggedID && m.match(m.lookup(id)) {
+		res = matchTrue
+	}
+	m.results[id].Store(res)
+
+	return res == matchTrue
+}
+
+// Generates a closure for a condition using a string matching operator (e.g. "tag ^= voip").
+// Matching is case-insensitive (all conditions are converted to lower case upon sanitization)
+func generateMatchCompareValue(condition *conditionNode) error {
+	if condition.attribute != types.TagName {
+		return fmt.Errorf("comparator %q not allowed for attribute %q", condition.comparator, condition.attribute)
+	}
+
+	match, err := newStringMatch(condition.comparator, condition.value)
+	if err != nil {
+		return err
+	}
+	matcher := &dictMatcher{
+		lookup: types.TagNameByID,
+		match:  match,
+	}
+
+	switch condition.comparator {
+	case prefixComparator, suffixComparator, globComparator:
+		condition.compareValue = func(currentValue types.Key) bool {
+			return matcher.matches(currentValue.GetTag())
+		}
+	default:
+		condition.compareValue = func(currentValue types.Key) bool {
+			return !matcher.matches(currentValue.GetTag())
+		}
+	}
+
+	return nil
+}
+
+// newStringMatch compiles the (non-negated) match function for a string matching operator
+func newStringMatch(comparator, pattern string) (func(string) bool, error) {
+	pattern = strings.ToLower(pattern)
+
+	switch comparator {
+	case prefixComparator, notPrefixComparator:
+		return func(name string) bool {
+			return strings.HasPrefix(strings.ToLower(name), pattern)
+		}, nil
+	case suffixComparator, notSuffixComparator:
+		return func(name string) bool {
+			return strings.HasSuffix(strings.ToLower(name), pattern)
+		}, nil
+	case globComparator, notGlobComparator:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		return func(name string) bool {
+			matches, _ := path.Match(pattern, strings.ToLower(name))
+			return matches
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown comparator: %s", comparator)
+	}
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+func TestMatchCondition(t *testing.T) {
+	keys := make(map[string]types.Key)
+	for _, tag := range []string{"voip-prod", "voip-test", "backup-prod"} {
+		id, err := types.InternTag(tag)
+		if err != nil {
+			t.Fatalf("failed to intern tag: %s", err)
+		}
+		keys[tag] = types.NewV4Key([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, []byte{0, 80}, 17)
+		keys[tag].PutTag(id)
+	}
+	keys[""] = types.NewV6Key(make([]byte, 16), make([]byte, 16), []byte{0, 80}, 17)
+
+	var tests = []struct {
+		comparator string
+		value      string
+		success    bool
+		matches    []string
+	}{
+		{"^=", "voip", true, []string{"voip-prod", "voip-test"}},
+		{"!^=", "voip", true, []string{"backup-prod", ""}},
+		{"$=", "-prod", true, []string{"voip-prod", "backup-prod"}},
+		{"!$=", "-prod", true, []string{"voip-test", ""}},
+		{"~=", "*-prod", true, []string{"voip-prod", "backup-prod"}},
+		{"~=", "v?ip-*", true, []string{"voip-prod", "voip-test"}},
+		{"~=", "VoIP-[pt]*", true, []string{"voip-prod", "voip-test"}},
+		{"~=", "*", true, []string{"voip-prod", "voip-test", "backup-prod"}},
+		{"!~=", "*", true, []string{""}},
+		{"~=", "voip-[", false, nil},
+	}
+	for _, test := range tests {
+		condition := conditionNode{attribute: types.TagName, comparator: test.comparator, value: test.value}
+		err := generateCompareValue(&condition)
+		if !test.success {
+			if err == nil {
+				t.Fatalf("Expected to fail on input %v but it didn't", condition)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpectedly failed on input %v. The error is: %s", condition, err)
+		}
+
+		// evaluate twice to cover the cached outcome for each tag
+		for i := 0; i < 2; i++ {
+			var matches []string
+			for _, tag := range []string{"voip-prod", "voip-test", "backup-prod", ""} {
+				if condition.compareValue(keys[tag]) {
+					matches = append(matches, tag)
+				}
+			}
+			if len(matches) != len(test.matches) {
+				t.Fatalf("Unexpected matches for input %v: %v", condition, matches)
+			}
+			for j := range matches {
+				if matches[j] != test.matches[j] {
+					t.Fatalf("Unexpected matches for input %v: %v", condition, matches)
+				}
+			}
+		}
+	}
+
+	// string matching operators are only supported for label attributes
+	for _, attribute := range []string{types.SIPName, types.DportName, types.ProtoName} {
+		condition := conditionNode{attribute: attribute, comparator: "^=", value: "1"}
+		if err := generateCompareValue(&condition); err == nil {
+			t.Fatalf("Expected to fail on input %v but it didn't", condition)
+		}
+	}
+}
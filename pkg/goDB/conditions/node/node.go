@@ -282,6 +282,10 @@ func negationNormalForm(node Node) Node {
 			panic(fmt.Sprintf("Node unexpectly has type %T", node))
 		case conditionNode:
 			if negate {
+				if negated, isMatch := negatedMatchComparators[node.comparator]; isMatch {
+					node.comparator = negated
+					return node
+				}
 				switch node.comparator {
 				default:
 					panic(fmt.Sprintf("Unknown comparison operator %s", node.comparator))
@@ -37,6 +37,9 @@ var negationNormalFormTests = []struct {
 	{[]string{"!", "sip", "<=", "127.0.0.1"}, "sip > 127.0.0.1"},
 	{[]string{"!", "sip", "<", "127.0.0.1"}, "sip >= 127.0.0.1"},
 	{[]string{"!", "sip", ">", "127.0.0.1"}, "sip <= 127.0.0.1"},
+	{[]string{"!", "tag", "^=", "voip"}, "tag !^= voip"},
+	{[]string{"!", "tag", "!$=", "-prod"}, "tag $= -prod"},
+	{[]string{"!", "tag", "~=", "v*"}, "tag !~= v*"},
 	// Double negation
 	{[]string{"!", "(", "!", "sip", "!=", "127.0.0.1", ")"}, "sip != 127.0.0.1"},
 	// Logical connectives
@@ -75,7 +75,7 @@ func parseConditional(tokens []string) (conditionalNode Node, err error) {
 //	negation -> '!' primitive | primitive
 //	primitive -> '(' disjunction ')' | condition
 //	condition -> attribute comparator value
-//	comparator -> '=' | '!=' | '<' | '>' | '<=' | '>='
+//	comparator -> '=' | '!=' | '<' | '>' | '<=' | '>=' | '^=' | '!^=' | '$=' | '!$=' | '~=' | '!~='
 //
 // (Terminal symbols are written in single quotes)
 // (A rule part written with a star is meant to be repeated zero or more times)
@@ -302,7 +302,7 @@ func (p *parser) condition() (result Node) {
 // Corresponds to grammar rule "attribute"
 func (p *parser) attribute() (result string) {
 	attributes := []string{
-		types.DIPName, types.SIPName, "dnet", "snet", types.DportName, types.ProtoName, types.FilterKeywordDirection, // non-sugar
+		types.DIPName, types.SIPName, "dnet", "snet", types.DportName, types.ProtoName, types.TagName, types.TTLMinName, types.TTLMaxName, types.FilterKeywordDirection, // non-sugar
 		"dst", "src", "host", "net", "port", "protocol", "ipproto", types.FilterKeywordDirectionSugared, // sugar
 	}
 	for _, attrib := range attributes {
@@ -350,12 +350,32 @@ func (p *parser) comparator() (result string) {
 			return
 		}
 		result = ">"
+	} else if comparator, isMatch := p.acceptMatchComparator(); isMatch {
+		if !p.success() {
+			return
+		}
+		result = comparator
 	} else {
 		p.die("expected comparison operator")
 	}
 	return
 }
 
+// Accepts any of the string matching operators (prefix, suffix and glob matching as well as
+// their negations)
+func (p *parser) acceptMatchComparator() (string, bool) {
+	for _, comparator := range []string{
+		prefixComparator, notPrefixComparator,
+		suffixComparator, notSuffixComparator,
+		globComparator, notGlobComparator,
+	} {
+		if p.accept(comparator) {
+			return comparator, true
+		}
+	}
+	return "", false
+}
+
 // Corresponds to grammar rule "value"
 func (p *parser) value() (result string) {
 	result = p.advance()
@@ -38,8 +38,8 @@ func resolve(node Node, timeout time.Duration) (Node, error) {
 			return node, nil
 		}
 
-		// For IPs we are already done.
-		if net.ParseIP(node.value) != nil {
+		// For IPs and IP address classes we are already done.
+		if net.ParseIP(node.value) != nil || isIPClass(node.value) {
 			return node, nil
 		}
 
@@ -40,6 +40,27 @@ var grammarConversionMap = map[string][]string{
 	"<":  {"\\s+l\\s+", "\\s+\\-l\\s+", "\\s+lt\\s+", "\\s+\\-lt\\s+", "\\s+less\\s+"},
 }
 
+// orderedGrammarConversions holds conversions which have to be applied before (and in the given
+// order) the ones in grammarConversionMap, since their user grammar overlaps with other expressions
+// (e.g. "sip is not private" must not be converted to "sip = ! private")
+var orderedGrammarConversions = []struct {
+	condGrammarOp string
+	userGrammarOp *regexp.Regexp
+}{
+	{"!^=", regexp.MustCompile("\\s+not\\s+(prefix|startswith)\\s+")},
+	{"!$=", regexp.MustCompile("\\s+not\\s+(suffix|endswith)\\s+")},
+	{"!~=", regexp.MustCompile("\\s+not\\s+(like|glob)\\s+")},
+	{"^=", regexp.MustCompile("\\s+(prefix|startswith)\\s+")},
+	{"$=", regexp.MustCompile("\\s+(suffix|endswith)\\s+")},
+	{"~=", regexp.MustCompile("\\s+(like|glob)\\s+")},
+	{"!=", regexp.MustCompile("\\s+is\\s+not\\s+")},
+	{"=", regexp.MustCompile("\\s+is\\s+")},
+}
+
+// globPatternRegExp matches the glob patterns of a (sanitized) conditional, whose wildcards ("*")
+// must not be converted to logical operators
+var globPatternRegExp = regexp.MustCompile(`~=\s*[^\s!=<>|&()]+`)
+
 var (
 	regexAll                  *regexp.Regexp
 	regexGrammarConversionMap map[string][]*regexp.Regexp
@@ -79,19 +100,39 @@ func init() {
 func SanitizeUserInput(conditional string) (sanitized string) {
 	sanitized = string(regexAll.ReplaceAllFunc([]byte(conditional), bytes.ToLower))
 
+	for _, conversion := range orderedGrammarConversions {
+		sanitized = conversion.userGrammarOp.ReplaceAllString(sanitized, conversion.condGrammarOp)
+	}
+
+	// glob patterns are left untouched by all remaining conversions
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, loc := range globPatternRegExp.FindAllStringIndex(sanitized, -1) {
+		b.WriteString(convertGrammar(sanitized[last:loc[0]]))
+		b.WriteString(sanitized[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(convertGrammar(sanitized[last:]))
+
+	return b.String()
+}
+
+func convertGrammar(conditional string) string {
+
 	// range over map to convert the individual entries
 	for condGrammarOp, userGrammarOps := range regexGrammarConversionMap {
 		for _, userOpRegex := range userGrammarOps {
-			sanitized = userOpRegex.ReplaceAllString(sanitized, condGrammarOp)
+			conditional = userOpRegex.ReplaceAllString(conditional, condGrammarOp)
 		}
 	}
-
-	return sanitized
+	return conditional
 }
 
 func startsDelimiter(char byte) bool {
 	switch char {
-	case '!', '=', '<', '>', '|', '&', '(', ')', ' ', '\n', '\r', '\t':
+	case '!', '=', '<', '>', '|', '&', '(', ')', '^', '$', '~', ' ', '\n', '\r', '\t':
 		return true
 	default:
 		return false
@@ -102,13 +143,18 @@ func endsDelimiter(char byte) bool {
 	return char == '='
 }
 
+func isMatchOperator(char byte) bool {
+	return char == '^' || char == '$' || char == '~'
+}
+
 // delimiterSplitFunc is the SplitFunc for delimiter tokens. Since delimiter tokens
-// can never be longer than two characters it inspects at most two characters.
-// All delimiter tokens apart from "!=", "<=", and ">=" are only one character long.
-// For all tokens of length one that aren't prefixes of the aforementioned three
+// can never be longer than three characters it inspects at most three characters.
+// All delimiter tokens apart from "!=", "<=", ">=", the string matching operators
+// ("^=", "$=", "~=") and their negations ("!^=", "!$=", "!~=") are only one character long.
+// For all tokens of length one that aren't prefixes of the aforementioned
 // tokens simply looking at the first byte of the token is enough to tokenize it.
-// For the other six tokens ("<", ">", "!", "<=", ">=", "!=") we can simply look ahead
-// one character to determine what kind of token we are dealing with.
+// For the other tokens we can simply look ahead one (or, for the negated string matching
+// operators, two) characters to determine what kind of token we are dealing with.
 func delimiterSplitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if len(data) == 0 {
 		return
@@ -132,6 +178,19 @@ func delimiterSplitFunc(data []byte, atEOF bool) (advance int, token []byte, err
 		if len(data) < 2 {
 			return 0, nil, nil
 		}
+
+		// negated string matching operators ("!^=", "!$=", "!~=") are the only tokens
+		// consisting of three characters
+		if data[0] == '!' && isMatchOperator(data[1]) {
+			if len(data) < 3 {
+				return 0, nil, nil
+			}
+			if endsDelimiter(data[2]) {
+				advance = 3
+				token = data[0:3]
+				return
+			}
+		}
 		if endsDelimiter(data[1]) {
 			advance = 2
 			token = data[0:2]
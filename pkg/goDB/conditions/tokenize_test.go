@@ -46,6 +46,14 @@ var sanitizeUserInputTests = []struct {
 	{"not dport g 80", "!dport>80"},
 	{"dport<443& not{dport g 80}", "dport<443&!(dport>80)"},
 	{"dport<443& not[dport g 80]", "dport<443&!(dport>80)"},
+	{"sip is private and dip is not public", "sip=private&dip!=public"},
+	{"not sip is bogon", "!sip=bogon"},
+	// String matching operators, whose glob patterns retain their wildcards
+	{"tag prefix VoIP and tag not suffix -backup", "tag^=voip&tag!$=-backup"},
+	{"tag startswith voip or tag endswith prod", "tag^=voip|tag$=prod"},
+	{"tag like *-prod* and dport = 443", "tag~=*-prod*&dport = 443"},
+	{"tag not glob v?ip* or dport g 80*proto=tcp", "tag!~=v?ip*|dport>80&proto=tcp"},
+	{"tag ~= voip*&dport=80", "tag ~= voip*&dport=80"},
 }
 
 func TestSanitizeUserInput(t *testing.T) {
@@ -123,6 +131,13 @@ var delimiterSplitFuncTests = []splitFuncTest{
 	{[]byte("<="), false, 2, []byte("<=")},
 	{[]byte(">="), false, 2, []byte(">=")},
 	{[]byte("!=="), false, 2, []byte("!=")},
+	{[]byte("^=x"), false, 2, []byte("^=")},
+	{[]byte("$=x"), false, 2, []byte("$=")},
+	{[]byte("~=x"), false, 2, []byte("~=")},
+	{[]byte("!^"), false, 0, nil},
+	{[]byte("!^=x"), false, 3, []byte("!^=")},
+	{[]byte("!~=*"), false, 3, []byte("!~=")},
+	{[]byte("!^x"), false, 1, []byte("!")},
 	{[]byte("<"), true, 1, []byte("<")},
 	{[]byte(">"), true, 1, []byte(">")},
 	{[]byte("!"), true, 1, []byte("!")},
@@ -161,6 +176,8 @@ var TokenizeTests = []struct {
 	{"sip = 2a00:db0:7:c08:e4d:e9ff:fea4:88e9 & dip = 2a00::e4d:e9ff:fea4:88e9", []string{"sip", "=", "2a00:db0:7:c08:e4d:e9ff:fea4:88e9", "&", "dip", "=", "2a00::e4d:e9ff:fea4:88e9"}},
 	{"sip = 2a00:db0:7:c08:e4d:: & dip = 2a00::e4d:e9ff:fea4:88e9", []string{"sip", "=", "2a00:db0:7:c08:e4d::", "&", "dip", "=", "2a00::e4d:e9ff:fea4:88e9"}},
 	{"sip = example.com.  & dip =sub-domain.open.ch", []string{"sip", "=", "example.com.", "&", "dip", "=", "sub-domain.open.ch"}},
+	{"tag ^= voip & tag!$=-backup", []string{"tag", "^=", "voip", "&", "tag", "!$=", "-backup"}},
+	{"tag~=*-prod*|tag !~= v?ip", []string{"tag", "~=", "*-prod*", "|", "tag", "!~=", "v?ip"}},
 	// Tokenize also tokenizes incorrect conditionals. It's the parser's job to catch those.
 	{"dport =< 80", []string{"dport", "=", "<", "80"}},
 	{"dport << 80", []string{"dport", "<", "<", "80"}},
@@ -11,12 +11,17 @@
 package goDB
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"net/netip"
 	"path/filepath"
+	"slices"
 
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/encoder"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
 	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
@@ -26,6 +31,12 @@ import (
 // DefaultPermissions denotes the default permissions used during writeout
 const DefaultPermissions = fs.FileMode(0644)
 
+// HostnameLabeler provides the hostnames of (a subset of) the IP addresses observed in a flow map, as
+// resolved at writeout time. Implementations must not block on resolving them
+type HostnameLabeler interface {
+	Hostnames(flowmap *hashmap.AggFlowMap) map[netip.Addr]string
+}
+
 // DBWriter writes goProbe flows to goDB database files
 type DBWriter struct {
 	dbpath string
@@ -34,6 +45,14 @@ type DBWriter struct {
 	encoderType  encoders.Type
 	encoderLevel int
 	permissions  fs.FileMode
+	handshakeRTT bool
+	fsys         storage.FS
+	syncPolicy   storage.SyncPolicy
+	hostnames    HostnameLabeler
+
+	// idle rotations not yet recorded, since the daily directory they belong to has no blocks yet
+	pendingIdle    *gpfile.IdleMetadata
+	pendingIdleDir string
 }
 
 // NewDBWriter initializes a new DBWriter
@@ -43,6 +62,8 @@ func NewDBWriter(dbpath string, iface string, encoderType encoders.Type) (w *DBW
 		iface:       iface,
 		encoderType: encoderType,
 		permissions: DefaultPermissions,
+		fsys:        storage.DefaultFS,
+		syncPolicy:  storage.SyncOSDefault,
 	}
 }
 
@@ -58,31 +79,216 @@ func (w *DBWriter) EncoderLevel(level int) *DBWriter {
 	return w
 }
 
-// Write takes an aggregated flow map and its metadata and writes it to disk for a given timestamp
+// HandshakeRTT enables / disables storing the TCP handshake round trip time summaries of each block in the DB
+func (w *DBWriter) HandshakeRTT(enabled bool) *DBWriter {
+	w.handshakeRTT = enabled
+	return w
+}
+
+// FS overrides the default (on-disk) file system the DB is written to
+func (w *DBWriter) FS(fsys storage.FS) *DBWriter {
+	w.fsys = fsys
+	return w
+}
+
+// SyncPolicy overrides the default policy governing when written data is committed to stable storage
+// (c.f. storage.SyncPolicy). Under storage.SyncPerRotation, the caller is responsible for the sync barrier
+func (w *DBWriter) SyncPolicy(policy storage.SyncPolicy) *DBWriter {
+	w.syncPolicy = policy
+	return w
+}
+
+// Hostnames sets the labeler providing the hostnames of the observed IP addresses, which are stored
+// alongside the flows (allowing queries to show the hostnames as they were at capture time). A nil
+// labeler disables storing hostnames
+func (w *DBWriter) Hostnames(labeler HostnameLabeler) *DBWriter {
+	w.hostnames = labeler
+	return w
+}
+
+// Write takes an aggregated flow map and its metadata and writes it to disk for a given timestamp. If the
+// rotation is idle (i.e. neither flows nor drops were observed), no blocks are written (c.f. markIdle)
 func (w *DBWriter) Write(flowmap *hashmap.AggFlowMap, captureStats capturetypes.CaptureStats, timestamp int64) error {
+	if isIdle(flowmap, captureStats) {
+		return w.markIdle(timestamp)
+	}
+	return w.write(flowmap, captureStats, timestamp)
+}
+
+// WriteBatched takes an aggregated flow map and its metadata and writes it to disk for a given timestamp
+// as part of a WriteBatch. The data is only guaranteed to be on stable storage once the batch is committed
+func (w *DBWriter) WriteBatched(batch *WriteBatch, flowmap *hashmap.AggFlowMap, captureStats capturetypes.CaptureStats, timestamp int64) error {
+	var err error
+	if isIdle(flowmap, captureStats) {
+		err = w.markIdle(timestamp, gpfile.WithSyncPolicy(batch.syncPolicy))
+	} else {
+		err = w.write(flowmap, captureStats, timestamp, gpfile.WithEncoder(batch.encoder), gpfile.WithSyncPolicy(batch.syncPolicy))
+	}
+	if err != nil {
+		return err
+	}
+	batch.nWrites++
+
+	return nil
+}
+
+func (w *DBWriter) write(flowmap *hashmap.AggFlowMap, captureStats capturetypes.CaptureStats, timestamp int64, opts ...gpfile.Option) error {
 	var (
 		data   [types.ColIdxCount][]byte
 		update gpfile.Stats
 		err    error
 	)
 
-	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), timestamp, gpfile.ModeWrite, gpfile.WithPermissions(w.permissions), gpfile.WithEncoderTypeLevel(w.encoderType, w.encoderLevel))
+	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), timestamp, gpfile.ModeWrite, append(w.options(), opts...)...)
 	if err = dir.Open(); err != nil {
 		return fmt.Errorf("failed to create / open daily directory: %w", err)
 	}
+	w.flushIdle(dir)
 
-	data, update = dbData(flowmap)
-	if err := dir.WriteBlocks(timestamp, gpfile.TrafficMetadata{
-		NumV4Entries: update.Traffic.NumV4Entries,
-		NumV6Entries: update.Traffic.NumV6Entries,
-		NumDrops:     captureStats.Dropped,
-	}, update.Counts, data); err != nil {
+	if data, update, err = dbData(dir.Metadata, flowmap); err != nil {
+		return err
+	}
+	w.setHostnames(dir, timestamp, flowmap)
+	if err := w.writeBlocks(dir, timestamp, captureStats, update, data); err != nil {
 		return err
 	}
 
 	return dir.Close()
 }
 
+// setHostnames records the hostnames of the IP addresses observed in the flow map (if a labeler is set).
+// Since they merely annotate the flows, hostnames that cannot be recorded (e.g. because the dictionary
+// of the directory is exhausted) are skipped instead of failing the writeout
+func (w *DBWriter) setHostnames(dir *gpfile.GPDir, timestamp int64, flowmap *hashmap.AggFlowMap) {
+	if w.hostnames == nil {
+		return
+	}
+
+	hostnames := w.hostnames.Hostnames(flowmap)
+	ips := make([]netip.Addr, 0, len(hostnames))
+	for ip := range hostnames {
+		ips = append(ips, ip)
+	}
+	slices.SortFunc(ips, func(a, b netip.Addr) int {
+		return a.Compare(b)
+	})
+	for _, ip := range ips {
+		_ = dir.SetHostname(timestamp, ip, hostnames[ip])
+	}
+}
+
+// isIdle determines if a rotation observed neither any flows nor any dropped packets, in which case
+// writing (empty) blocks would merely bloat the DB
+func isIdle(flowmap *hashmap.AggFlowMap, captureStats capturetypes.CaptureStats) bool {
+	return (flowmap == nil || flowmap.Len() == 0) && captureStats.Dropped == 0
+}
+
+// markIdle records an idle rotation in the metadata of the daily directory without creating / touching
+// any of its column files, allowing queries to distinguish a lack of traffic from a lack of captured
+// data. If the directory has no blocks yet, the rotation is retained until the first block is written
+// to it (idle rotations of a day without any traffic at all are hence not recorded)
+func (w *DBWriter) markIdle(timestamp int64, opts ...gpfile.Option) error {
+	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), timestamp, gpfile.ModeWrite, append(w.options(), opts...)...)
+	if w.pendingIdle != nil && w.pendingIdleDir != dir.Path() {
+		w.pendingIdle = nil
+	}
+
+	if _, err := w.fsys.Stat(dir.MetadataPath()); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to access daily directory: %w", err)
+		}
+		if w.pendingIdle == nil {
+			w.pendingIdle, w.pendingIdleDir = &gpfile.IdleMetadata{From: timestamp}, dir.Path()
+		}
+		w.pendingIdle.To = timestamp
+		return nil
+	}
+
+	if err := dir.Open(); err != nil {
+		return fmt.Errorf("failed to open daily directory: %w", err)
+	}
+	w.flushIdle(dir)
+	dir.MarkIdle(timestamp, timestamp)
+
+	return dir.Close()
+}
+
+// flushIdle records the idle rotations retained for the provided (opened) daily directory, if any
+func (w *DBWriter) flushIdle(dir *gpfile.GPDir) {
+	if w.pendingIdle == nil {
+		return
+	}
+	if w.pendingIdleDir == dir.Path() {
+		dir.MarkIdle(w.pendingIdle.From, w.pendingIdle.To)
+	}
+	w.pendingIdle = nil
+}
+
+// WriteBatch coalesces the writeouts of several (usually mostly idle) interfaces into a single
+// transaction: All writes share a single encoder (and its buffers) instead of instantiating one per
+// column file, and all data is committed to stable storage by a single sync barrier upon Commit()
+// instead of syncing the files of each interface individually
+type WriteBatch struct {
+	dbpath     string
+	fsys       storage.FS
+	encoder    encoder.Encoder
+	syncPolicy storage.SyncPolicy
+
+	nWrites int
+}
+
+// NewWriteBatch initializes a new WriteBatch for the DB located at dbpath
+func NewWriteBatch(dbpath string, encoderType encoders.Type, encoderLevel int) (*WriteBatch, error) {
+	enc, err := encoder.New(encoderType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize shared encoder: %w", err)
+	}
+	if encoderLevel > 0 {
+		enc.SetLevel(encoderLevel)
+	}
+
+	return &WriteBatch{
+		dbpath:     dbpath,
+		fsys:       storage.DefaultFS,
+		encoder:    enc,
+		syncPolicy: storage.SyncOSDefault,
+	}, nil
+}
+
+// FS overrides the default (on-disk) file system the DB is written to
+func (b *WriteBatch) FS(fsys storage.FS) *WriteBatch {
+	b.fsys = fsys
+	return b
+}
+
+// SyncPolicy overrides the default policy governing when written data is committed to stable storage
+// (c.f. storage.SyncPolicy). Under storage.SyncPerRotation, the sync barrier is left to the caller
+func (b *WriteBatch) SyncPolicy(policy storage.SyncPolicy) *WriteBatch {
+	b.syncPolicy = policy
+	return b
+}
+
+// Len returns the number of writes performed as part of the batch
+func (b *WriteBatch) Len() int {
+	return b.nWrites
+}
+
+// Commit commits all writes performed as part of the batch to stable storage and releases
+// the shared resources. The batch must not be used afterwards
+func (b *WriteBatch) Commit() error {
+	var err error
+	if b.nWrites > 0 && b.syncPolicy != storage.SyncPerRotation {
+		if err = storage.SyncFS(b.fsys, b.dbpath); err != nil {
+			err = fmt.Errorf("failed to sync %d coalesced writes: %w", b.nWrites, err)
+		}
+	}
+	if cerr := b.encoder.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
 // BulkWorkload denotes a set of workloads / writes to perform during WriteBulk()
 type BulkWorkload struct {
 	FlowMap      *hashmap.AggFlowMap
@@ -97,18 +303,16 @@ func (w *DBWriter) WriteBulk(workloads []BulkWorkload, dirTimestamp int64) (err
 		update gpfile.Stats
 	)
 
-	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), dirTimestamp, gpfile.ModeWrite, gpfile.WithPermissions(w.permissions), gpfile.WithEncoderTypeLevel(w.encoderType, w.encoderLevel))
+	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), dirTimestamp, gpfile.ModeWrite, w.options()...)
 	if err = dir.Open(); err != nil {
 		return fmt.Errorf("failed to create / open daily directory: %w", err)
 	}
 
 	for _, workload := range workloads {
-		data, update = dbData(workload.FlowMap)
-		if err := dir.WriteBlocks(workload.Timestamp, gpfile.TrafficMetadata{
-			NumV4Entries: update.Traffic.NumV4Entries,
-			NumV6Entries: update.Traffic.NumV6Entries,
-			NumDrops:     workload.CaptureStats.Dropped,
-		}, update.Counts, data); err != nil {
+		if data, update, err = dbData(dir.Metadata, workload.FlowMap); err != nil {
+			return err
+		}
+		if err := w.writeBlocks(dir, workload.Timestamp, workload.CaptureStats, update, data); err != nil {
 			return err
 		}
 	}
@@ -116,9 +320,70 @@ func (w *DBWriter) WriteBulk(workloads []BulkWorkload, dirTimestamp int64) (err
 	return dir.Close()
 }
 
-func dbData(aggFlowMap *hashmap.AggFlowMap) ([types.ColIdxCount][]byte, gpfile.Stats) {
+// Backfill takes an aggregated flow map and its metadata and writes it to disk for a given timestamp,
+// replacing an existing block for the same timestamp or inserting it ahead of any more recent blocks
+// (c.f. gpfile.GPDir.BackfillBlocks). It returns if an existing block was replaced
+func (w *DBWriter) Backfill(flowmap *hashmap.AggFlowMap, captureStats capturetypes.CaptureStats, timestamp int64, source gpfile.BackfillSource) (replaced bool, err error) {
+	dir := gpfile.NewDir(filepath.Join(w.dbpath, w.iface), timestamp, gpfile.ModeWrite, w.options()...)
+	if err = dir.Open(); err != nil {
+		return false, fmt.Errorf("failed to create / open daily directory: %w", err)
+	}
+
+	data, update, err := dbData(dir.Metadata, flowmap)
+	if err != nil {
+		return false, err
+	}
+	if replaced, err = dir.BackfillBlocks(timestamp, source, gpfile.TrafficMetadata{
+		NumV4Entries: update.Traffic.NumV4Entries,
+		NumV6Entries: update.Traffic.NumV6Entries,
+		NumDrops:     captureStats.Dropped,
+	}, update.Counts, data); err != nil {
+		return false, err
+	}
+
+	return replaced, dir.Close()
+}
+
+// options returns the options all GPDirs are written with
+func (w *DBWriter) options() []gpfile.Option {
+	return []gpfile.Option{
+		gpfile.WithPermissions(w.permissions),
+		gpfile.WithEncoderTypeLevel(w.encoderType, w.encoderLevel),
+		gpfile.WithFS(w.fsys),
+		gpfile.WithSyncPolicy(w.syncPolicy),
+	}
+}
+
+func (w *DBWriter) writeBlocks(dir *gpfile.GPDir, timestamp int64, captureStats capturetypes.CaptureStats, update gpfile.Stats, data [types.ColIdxCount][]byte) error {
+	blockTraffic := gpfile.TrafficMetadata{
+		NumV4Entries: update.Traffic.NumV4Entries,
+		NumV6Entries: update.Traffic.NumV6Entries,
+		NumDrops:     captureStats.Dropped,
+	}
+	if captureStats.Link != nil {
+		dir.SetLink(timestamp, *captureStats.Link)
+	}
+	if emergency := captureStats.Emergency; emergency != nil {
+		dir.MarkEmergency(timestamp, uint8(emergency.IPv4Prefix), uint8(emergency.IPv6Prefix))
+	}
+	if !w.handshakeRTT {
+		return dir.WriteBlocks(timestamp, blockTraffic, update.Counts, data)
+	}
+
+	var blockLatency gpfile.LatencyMetadata
+	if rtt := captureStats.HandshakeRTT; rtt != nil {
+		blockLatency = gpfile.NewLatencyMetadata(rtt.Samples, rtt.Min, rtt.Median, rtt.Max)
+	}
+	return dir.WriteBlocksWithLatency(timestamp, blockTraffic, blockLatency, update.Counts, data)
+}
+
+// dbData extracts the column data from a flow map. Tagged flows are translated to the tag
+// dictionary of the metadata of the GPDir they are written to (the tag column is only populated
+// if any of the flows is tagged, the TTL columns only if TTLs were recorded for any of the flows)
+func dbData(metadata *gpfile.Metadata, aggFlowMap *hashmap.AggFlowMap) ([types.ColIdxCount][]byte, gpfile.Stats, error) {
 	var dbData [types.ColIdxCount][]byte
 	var summUpdate gpfile.Stats
+	var tagIndices [types.MaxTags + 1]byte
 
 	v4List, v6List := aggFlowMap.Flatten()
 	v4List = v4List.Sort()
@@ -139,6 +404,7 @@ func dbData(aggFlowMap *hashmap.AggFlowMap) ([types.ColIdxCount][]byte, gpfile.S
 		make([]uint64, 0, len(v4List)+len(v6List)),
 		make([]uint64, 0, len(v4List)+len(v6List)),
 		make([]uint64, 0, len(v4List)+len(v6List))
+	nFlows := 0
 	for _, list := range []hashmap.List{v4List, v6List} {
 		for _, flow := range list {
 
@@ -156,6 +422,32 @@ func dbData(aggFlowMap *hashmap.AggFlowMap) ([types.ColIdxCount][]byte, gpfile.S
 			dbData[types.ProtoColIdx] = append(dbData[types.ProtoColIdx], flow.GetProto())
 			dbData[types.SIPColIdx] = append(dbData[types.SIPColIdx], flow.GetSIP()...)
 			dbData[types.DIPColIdx] = append(dbData[types.DIPColIdx], flow.GetDIP()...)
+
+			// tags
+			if tag := flow.GetTag(); tag != types.UntaggedID || dbData[types.TagColIdx] != nil {
+				if dbData[types.TagColIdx] == nil {
+					dbData[types.TagColIdx] = make([]byte, nFlows, len(v4List)+len(v6List))
+				}
+				if tag != types.UntaggedID && tagIndices[tag] == 0 {
+					idx, err := metadata.TagIndex(types.TagNameByID(tag))
+					if err != nil {
+						return dbData, summUpdate, err
+					}
+					tagIndices[tag] = idx
+				}
+				dbData[types.TagColIdx] = append(dbData[types.TagColIdx], tagIndices[tag])
+			}
+
+			// TTL ranges (zero denoting that no TTL was recorded)
+			if ttlMin, ttlMax := flow.GetTTLMin(), flow.GetTTLMax(); ttlMin != 0 || ttlMax != 0 || dbData[types.TTLMinColIdx] != nil {
+				if dbData[types.TTLMinColIdx] == nil {
+					dbData[types.TTLMinColIdx] = make([]byte, nFlows, len(v4List)+len(v6List))
+					dbData[types.TTLMaxColIdx] = make([]byte, nFlows, len(v4List)+len(v6List))
+				}
+				dbData[types.TTLMinColIdx] = append(dbData[types.TTLMinColIdx], ttlMin)
+				dbData[types.TTLMaxColIdx] = append(dbData[types.TTLMaxColIdx], ttlMax)
+			}
+			nFlows++
 		}
 	}
 
@@ -168,5 +460,5 @@ func dbData(aggFlowMap *hashmap.AggFlowMap) ([types.ColIdxCount][]byte, gpfile.S
 	summUpdate.Traffic.NumV4Entries = uint64(len(v4List))
 	summUpdate.Traffic.NumV6Entries = uint64(len(v6List))
 
-	return dbData, summUpdate
+	return dbData, summUpdate, nil
 }
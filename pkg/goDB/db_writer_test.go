@@ -11,6 +11,7 @@ import (
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
 	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
 	"github.com/stretchr/testify/require"
 )
@@ -60,3 +61,88 @@ func TestPanicDuringWrite(t *testing.T) {
 	})
 
 }
+
+func TestWriteBatch(t *testing.T) {
+
+	// Setup a temporary directory for the test DB
+	tempDir, err := os.MkdirTemp(os.TempDir(), "dbwrite_batch_test")
+	require.Nil(t, err)
+	defer func() {
+		require.Nil(t, os.RemoveAll(tempDir))
+	}()
+
+	batch, err := NewWriteBatch(tempDir, encoders.EncoderTypeLZ4, 0)
+	require.Nil(t, err)
+
+	// Write several interfaces (and rotations) sharing the same batch / encoder
+	timestamp := time.Now().Unix()
+	ifaces := []string{"eth0", "eth1", "eth2"}
+	for _, iface := range ifaces {
+		w := NewDBWriter(tempDir, iface, encoders.EncoderTypeLZ4)
+		for i := int64(0); i < 2; i++ {
+			require.Nil(t, w.WriteBatched(batch, generateFlows(), capturetypes.CaptureStats{}, timestamp+i*DBWriteInterval))
+		}
+	}
+	require.Equal(t, 2*len(ifaces), batch.Len())
+	require.Nil(t, batch.Commit())
+
+	for _, iface := range ifaces {
+		dir := gpfile.NewDir(filepath.Join(tempDir, iface), timestamp, gpfile.ModeRead)
+		require.Nil(t, dir.Open())
+		require.Equal(t, 2, dir.NBlocks())
+		require.Equal(t, uint64(2*testNv4), dir.Metadata.Traffic.NumV4Entries)
+		require.Equal(t, uint64(2*testNv6), dir.Metadata.Traffic.NumV6Entries)
+
+		// All blocks must be readable using the decoder of the encoder type of the batch
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxAttributeCount; colIdx++ {
+			for blockIdx := 0; blockIdx < dir.NBlocks(); blockIdx++ {
+				_, err := dir.ReadBlockAtIndex(colIdx, blockIdx)
+				require.Nil(t, err)
+			}
+		}
+		require.Nil(t, dir.Close())
+	}
+
+	// Committing an empty batch is a no-op
+	batch, err = NewWriteBatch(tempDir, encoders.EncoderTypeLZ4, 0)
+	require.Nil(t, err)
+	require.Nil(t, batch.Commit())
+}
+
+func TestWriteIdle(t *testing.T) {
+
+	// Setup a temporary directory for the test DB
+	tempDir, err := os.MkdirTemp(os.TempDir(), "dbwrite_idle_test")
+	require.Nil(t, err)
+	defer func() {
+		require.Nil(t, os.RemoveAll(tempDir))
+	}()
+
+	timestamp := gpfile.DirTimestamp(time.Now().Unix()) + 3600
+	w := NewDBWriter(tempDir, "eth0", encoders.EncoderTypeLZ4)
+
+	// Idle rotations prior to the first block of the day don't create the directory
+	require.Nil(t, w.Write(nil, capturetypes.CaptureStats{}, timestamp))
+	require.Nil(t, w.Write(hashmap.NewAggFlowMap(), capturetypes.CaptureStats{}, timestamp+DBWriteInterval))
+	_, err = os.Stat(filepath.Join(tempDir, "eth0"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The first block records the idle rotations retained so far, consecutive ones are merged into spans
+	require.Nil(t, w.Write(generateFlows(), capturetypes.CaptureStats{}, timestamp+2*DBWriteInterval))
+	require.Nil(t, w.Write(nil, capturetypes.CaptureStats{}, timestamp+3*DBWriteInterval))
+	require.Nil(t, w.Write(nil, capturetypes.CaptureStats{}, timestamp+4*DBWriteInterval))
+
+	// Rotations with dropped packets are written even without any flows
+	require.Nil(t, w.Write(nil, capturetypes.CaptureStats{Dropped: 1}, timestamp+5*DBWriteInterval))
+	require.Nil(t, w.Write(nil, capturetypes.CaptureStats{}, timestamp+6*DBWriteInterval))
+
+	dir := gpfile.NewDir(filepath.Join(tempDir, "eth0"), timestamp, gpfile.ModeRead)
+	require.Nil(t, dir.Open())
+	require.Equal(t, 2, dir.NBlocks())
+	require.Equal(t, []gpfile.IdleMetadata{
+		{From: timestamp, To: timestamp + DBWriteInterval},
+		{From: timestamp + 3*DBWriteInterval, To: timestamp + 4*DBWriteInterval},
+		{From: timestamp + 6*DBWriteInterval, To: timestamp + 6*DBWriteInterval},
+	}, dir.Idle)
+	require.Nil(t, dir.Close())
+}
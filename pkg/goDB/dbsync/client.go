@@ -0,0 +1,482 @@
+package dbsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/telemetry/logging"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// metadataFileName denotes the name of the metadata file of a goDB directory (c.f. gpfile.GPDir)
+	metadataFileName = ".blockmeta"
+
+	// temporary files created while writing to / rewriting a goDB directory
+	tempFilePrefix    = ".tmp-"
+	rewriteFileSuffix = ".rewrite"
+
+	// maxConflictRetries denotes the number of times an upload is continued from the offset reported
+	// by the collector (e.g. in case of concurrent uploads or a lost response)
+	maxConflictRetries = 3
+)
+
+// Stats summarizes a sync run
+type Stats struct {
+	Files         int   // Files: number of changed files announced to the collector
+	Skipped       int   // Skipped: number of files already held by the collector
+	Uploaded      int   // Uploaded: number of files uploaded (completely or partially)
+	BytesUploaded int64 // BytesUploaded: amount of data uploaded
+}
+
+// Client synchronizes a local goDB to a collector (c.f. Server)
+type Client struct {
+	addr      string
+	dbPath    string
+	client    *http.Client
+	fsys      storage.FS
+	chunkSize int
+
+	// synced stores the size / modification time of all files as of their last successful sync
+	synced map[string]syncedFile
+
+	sync.Mutex
+}
+
+type syncedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// localFile denotes a changed file of the local database
+type localFile struct {
+	File
+
+	modTime time.Time
+	data    []byte // snapshot of the file's content (only used for metadata)
+}
+
+// Option denotes a functional option for a Client
+type Option func(*Client)
+
+// WithFS sets the file system the local database is read from
+func WithFS(fsys storage.FS) Option {
+	return func(c *Client) {
+		c.fsys = fsys
+	}
+}
+
+// WithChunkSize sets the size of the chunks files are uploaded in
+func WithChunkSize(size int) Option {
+	return func(c *Client) {
+		if size > 0 && size <= MaxChunkSize {
+			c.chunkSize = size
+		}
+	}
+}
+
+// NewClient instantiates a new Client synchronizing the goDB located at dbPath to the collector
+// reachable under addr (e.g. "https://collector.example.com:8147"), authenticating itself via
+// the provided TLS configuration
+func NewClient(addr, dbPath string, tlsConfig *tls.Config, opts ...Option) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	c := &Client{
+		addr:      strings.TrimSuffix(addr, "/"),
+		dbPath:    dbPath,
+		client:    &http.Client{Transport: transport},
+		fsys:      storage.DefaultFS,
+		chunkSize: DefaultChunkSize,
+		synced:    make(map[string]syncedFile),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run synchronizes the database periodically until the context is cancelled
+func (c *Client) Run(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx).With("target", c.addr)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := c.Sync(ctx)
+		if err != nil {
+			logger.Errorf("failed to synchronize database: %s", err)
+		} else {
+			logger.With("files", stats.Files, "skipped", stats.Skipped,
+				"uploaded", stats.Uploaded, "bytes", stats.BytesUploaded,
+			).Debug("synchronized database")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync performs a single sync run, uploading all files that changed since the last run. Files that
+// fail to upload are retried on the next run
+func (c *Client) Sync(ctx context.Context) (stats Stats, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	dirs, err := c.changedFiles(".")
+	if err != nil {
+		return stats, err
+	}
+
+	var errs []error
+	for _, files := range dirs {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := c.syncDir(ctx, files, &stats); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return stats, errors.Join(errs...)
+}
+
+// changedFiles recursively collects all files that changed since the last sync run, grouped
+// by directory. The metadata file of a directory is snapshotted before and sorted after all
+// other files of the directory
+func (c *Client) changedFiles(dir string) ([][]*localFile, error) {
+	entries, err := c.fsys.ReadDir(c.abs(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files, metadata []*localFile
+		dirs            [][]*localFile
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		relPath := path.Join(dir, name)
+
+		if entry.IsDir() {
+			sub, err := c.changedFiles(relPath)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, sub...)
+			continue
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, tempFilePrefix) || strings.HasSuffix(name, rewriteFileSuffix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if synced, exists := c.synced[relPath]; exists && synced.size == info.Size() && synced.modTime.Equal(info.ModTime()) {
+			continue
+		}
+
+		file := &localFile{
+			File:    File{Path: relPath, Size: info.Size()},
+			modTime: info.ModTime(),
+		}
+		if name == metadataFileName {
+			metadata = append(metadata, file)
+			continue
+		}
+		files = append(files, file)
+	}
+
+	// Snapshot the metadata first, guaranteeing that all blocks it refers to are contained in the
+	// column files read afterwards
+	for _, file := range metadata {
+		if file.data, err = c.readFile(file.Path); err != nil {
+			return nil, err
+		}
+		file.Size = int64(len(file.data))
+		file.Hash = hashBytes(file.data)
+	}
+	for _, file := range files {
+		if file.Hash, err = c.hashFile(file.Path, file.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(files)+len(metadata) > 0 {
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		})
+		dirs = append(dirs, append(files, metadata...))
+	}
+	return dirs, nil
+}
+
+func (c *Client) syncDir(ctx context.Context, files []*localFile, stats *Stats) error {
+	req := StatusRequest{Files: make([]File, 0, len(files))}
+	for _, file := range files {
+		req.Files = append(req.Files, file.File)
+	}
+	stats.Files += len(files)
+
+	var resp StatusResponse
+	if err := c.do(ctx, http.MethodPost, StatusRoute, nil, nil, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Files) != len(files) {
+		return fmt.Errorf("unexpected number of files in status response: %d (expected %d)", len(resp.Files), len(files))
+	}
+
+	for i, file := range files {
+		status := resp.Files[i]
+		if status.Path != file.Path {
+			return fmt.Errorf("unexpected file in status response: %s (expected %s)", status.Path, file.Path)
+		}
+
+		if status.State != StateComplete {
+			n, err := c.upload(ctx, file, status)
+			stats.BytesUploaded += n
+			if err != nil {
+				// Skip the remaining files of the directory in order to never upload its metadata
+				// without the data it refers to
+				return fmt.Errorf("failed to upload %s: %w", file.Path, err)
+			}
+			stats.Uploaded++
+		} else {
+			stats.Skipped++
+		}
+
+		c.synced[file.Path] = syncedFile{size: file.Size, modTime: file.modTime}
+	}
+	return nil
+}
+
+// upload uploads a file in chunks, starting from the data the collector already received (if any).
+// It returns the amount of data uploaded
+func (c *Client) upload(ctx context.Context, file *localFile, status FileStatus) (uploaded int64, err error) {
+	offset, base := status.Offset, ""
+	if status.State == StateMissing {
+		offset = 0
+
+		// If the collector holds a prefix of the file (i.e. the file grew since it was last synced),
+		// only the appended data needs to be uploaded
+		if status.Size > 0 && status.Size < file.Size {
+			prefixHash, err := c.prefixHash(file, status.Size)
+			if err != nil {
+				return 0, err
+			}
+			if prefixHash == status.Hash {
+				offset, base = status.Size, status.Hash
+			}
+		}
+	}
+
+	src, err := c.open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	chunk := make([]byte, c.chunkSize)
+	for conflicts := 0; ; {
+		n := int64(c.chunkSize)
+		if remaining := file.Size - offset; remaining < n {
+			n = remaining
+		}
+		if _, err := src.Seek(offset, io.SeekStart); err != nil {
+			return uploaded, err
+		}
+		if _, err := io.ReadFull(src, chunk[:n]); err != nil {
+			return uploaded, err
+		}
+
+		resp, err := c.uploadChunk(ctx, file.File, offset, base, chunk[:n])
+		if err != nil {
+			if !errors.Is(err, ErrOffsetMismatch) || conflicts >= maxConflictRetries {
+				return uploaded, err
+			}
+
+			// Continue from the data the collector actually received
+			conflicts++
+			offset, base = resp.Offset, ""
+			continue
+		}
+		uploaded += n
+
+		if resp.Complete {
+			return uploaded, nil
+		}
+		if resp.Offset <= offset {
+			return uploaded, fmt.Errorf("collector did not advance offset beyond %d", offset)
+		}
+		offset, base = resp.Offset, ""
+	}
+}
+
+func (c *Client) uploadChunk(ctx context.Context, file File, offset int64, base string, chunk []byte) (UploadResponse, error) {
+	params := url.Values{}
+	params.Set(paramPath, file.Path)
+	params.Set(paramSize, strconv.FormatInt(file.Size, 10))
+	params.Set(paramHash, file.Hash)
+	params.Set(paramOffset, strconv.FormatInt(offset, 10))
+	if base != "" {
+		params.Set(paramBase, base)
+	}
+	headers := http.Header{}
+	headers.Set(HeaderChunkHash, hashBytes(chunk))
+
+	var resp UploadResponse
+	err := c.do(ctx, http.MethodPut, UploadRoute, params, headers, chunk, &resp)
+	return resp, err
+}
+
+// do performs a request against the collector. A body of type []byte is sent as is, any other
+// body is encoded as JSON
+func (c *Client) do(ctx context.Context, method, route string, params url.Values, headers http.Header, body, res any) error {
+	var payload []byte
+	switch b := body.(type) {
+	case []byte:
+		payload = b
+	default:
+		var err error
+		if payload, err = jsoniter.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	reqURL := c.addr + route
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusConflict:
+		if err := jsoniter.Unmarshal(data, res); err != nil {
+			return fmt.Errorf("failed to decode response (status %d): %w", resp.StatusCode, err)
+		}
+		if resp.StatusCode == http.StatusConflict {
+			return ErrOffsetMismatch
+		}
+		return nil
+	case http.StatusUnprocessableEntity:
+		return ErrFileHashMismatch
+	}
+
+	var errRes UploadResponse
+	if err := jsoniter.Unmarshal(data, &errRes); err == nil && errRes.Error != "" {
+		return fmt.Errorf("%s (status %d)", errRes.Error, resp.StatusCode)
+	}
+	return fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+func (c *Client) prefixHash(file *localFile, size int64) (string, error) {
+	if file.data != nil {
+		return hashBytes(file.data[:size]), nil
+	}
+	return c.hashFile(file.Path, size)
+}
+
+// open returns a reader for the content of a file (or its snapshot)
+func (c *Client) open(file *localFile) (io.ReadSeekCloser, error) {
+	if file.data != nil {
+		return nopCloser{bytes.NewReader(file.data)}, nil
+	}
+	return c.fsys.OpenFile(c.abs(file.Path), os.O_RDONLY, 0)
+}
+
+// readFile reads the complete content of a file
+func (c *Client) readFile(relPath string) ([]byte, error) {
+	f, err := c.fsys.OpenFile(c.abs(relPath), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return io.ReadAll(f)
+}
+
+// hashFile computes the hash of the first size bytes of a file. Since column files are only ever
+// appended to (or replaced atomically), this yields a consistent hash even if the file is written
+// to concurrently
+func (c *Client) hashFile(relPath string, size int64) (string, error) {
+	f, err := c.fsys.OpenFile(c.abs(relPath), os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, f, size); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (c *Client) abs(relPath string) string {
+	return filepath.Join(c.dbPath, filepath.FromSlash(relPath))
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,302 @@
+package dbsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProbe    = "probe1"
+	testDir      = "eth0/2024/01/1704067200"
+	testColumn   = testDir + "/bytes_rcvd.gpf"
+	testMetadata = testDir + "/" + metadataFileName
+)
+
+type testPKI struct {
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+	pool  *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Nil(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &testPKI{ca: ca, caKey: key, pool: pool}
+}
+
+func (p *testPKI) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	require.Nil(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (p *testPKI) clientConfig(t *testing.T, cn string) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{p.issue(t, cn, x509.ExtKeyUsageClientAuth)},
+		RootCAs:      p.pool,
+	}
+}
+
+// newTestCollector starts a collector storing its data in a temporary directory
+func newTestCollector(t *testing.T, pki *testPKI) (*httptest.Server, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	ts := httptest.NewUnstartedServer(NewServer(root))
+	ts.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{pki.issue(t, "collector", x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    pki.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	return ts, root
+}
+
+func writeTestFile(t *testing.T, dir, path string, data []byte, appendData bool) {
+	t.Helper()
+
+	path = filepath.Join(dir, filepath.FromSlash(path))
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendData {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	require.Nil(t, err)
+	_, err = f.Write(data)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+}
+
+func requireSynced(t *testing.T, dbPath, root string, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		expected, err := os.ReadFile(filepath.Join(dbPath, path))
+		require.Nil(t, err)
+		actual, err := os.ReadFile(filepath.Join(root, testProbe, path))
+		require.Nil(t, err)
+		require.Equal(t, expected, actual, path)
+	}
+}
+
+func TestSync(t *testing.T) {
+	pki := newTestPKI(t)
+	ts, root := newTestCollector(t, pki)
+
+	dbPath := t.TempDir()
+	column := bytes.Repeat([]byte("a"), 1000)
+	writeTestFile(t, dbPath, testColumn, column, false)
+	writeTestFile(t, dbPath, testMetadata, []byte("metadata"), false)
+	writeTestFile(t, dbPath, testDir+"/.tmp-metadata-1234", []byte("tmp"), false)
+	writeTestFile(t, dbPath, testDir+"/bytes_sent.gpf.rewrite", []byte("tmp"), false)
+
+	ctx := context.Background()
+	client := NewClient(ts.URL, dbPath, pki.clientConfig(t, testProbe), WithChunkSize(64))
+
+	stats, err := client.Sync(ctx)
+	require.Nil(t, err)
+	require.Equal(t, Stats{Files: 2, Uploaded: 2, BytesUploaded: 1008}, stats)
+	requireSynced(t, dbPath, root, testColumn, testMetadata)
+
+	_, err = os.Stat(filepath.Join(root, testProbe, testDir, ".tmp-metadata-1234"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(root, testProbe, testDir, "bytes_sent.gpf.rewrite"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Run("unchanged", func(t *testing.T) {
+		stats, err := client.Sync(ctx)
+		require.Nil(t, err)
+		require.Equal(t, Stats{}, stats)
+	})
+
+	t.Run("dedup", func(t *testing.T) {
+		// a fresh client has to announce all files, but none of them is uploaded again
+		fresh := NewClient(ts.URL, dbPath, pki.clientConfig(t, testProbe))
+		stats, err := fresh.Sync(ctx)
+		require.Nil(t, err)
+		require.Equal(t, Stats{Files: 2, Skipped: 2}, stats)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		writeTestFile(t, dbPath, testColumn, bytes.Repeat([]byte("b"), 100), true)
+		writeTestFile(t, dbPath, testMetadata, []byte("metadata2"), false)
+
+		// only the appended data has to be uploaded
+		stats, err := client.Sync(ctx)
+		require.Nil(t, err)
+		require.Equal(t, Stats{Files: 2, Uploaded: 2, BytesUploaded: 101}, stats)
+		requireSynced(t, dbPath, root, testColumn, testMetadata)
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		writeTestFile(t, dbPath, testColumn, bytes.Repeat([]byte("c"), 500), false)
+
+		stats, err := client.Sync(ctx)
+		require.Nil(t, err)
+		require.Equal(t, Stats{Files: 1, Uploaded: 1, BytesUploaded: 500}, stats)
+		requireSynced(t, dbPath, root, testColumn, testMetadata)
+	})
+}
+
+func TestSyncResume(t *testing.T) {
+	pki := newTestPKI(t)
+	ts, root := newTestCollector(t, pki)
+
+	dbPath := t.TempDir()
+	column := bytes.Repeat([]byte("a"), 1000)
+	writeTestFile(t, dbPath, testColumn, column, false)
+
+	ctx := context.Background()
+	client := NewClient(ts.URL, dbPath, pki.clientConfig(t, testProbe), WithChunkSize(300))
+
+	// simulate an upload interrupted after the first chunk
+	file := File{Path: testColumn, Size: int64(len(column)), Hash: hashBytes(column)}
+	resp, err := client.uploadChunk(ctx, file, 0, "", column[:300])
+	require.Nil(t, err)
+	require.Equal(t, UploadResponse{Offset: 300}, resp)
+
+	// the file must not be visible on the collector until it is complete
+	_, err = os.Stat(filepath.Join(root, testProbe, testColumn))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// a chunk not continuing the received data is rejected
+	resp, err = client.uploadChunk(ctx, file, 600, "", column[600:900])
+	require.ErrorIs(t, err, ErrOffsetMismatch)
+	require.Equal(t, int64(300), resp.Offset)
+
+	stats, err := client.Sync(ctx)
+	require.Nil(t, err)
+	require.Equal(t, Stats{Files: 1, Uploaded: 1, BytesUploaded: 700}, stats)
+	requireSynced(t, dbPath, root, testColumn)
+}
+
+func TestUploadValidation(t *testing.T) {
+	pki := newTestPKI(t)
+	ts, root := newTestCollector(t, pki)
+
+	ctx := context.Background()
+	client := NewClient(ts.URL, t.TempDir(), pki.clientConfig(t, testProbe))
+
+	data := []byte("data")
+	file := File{Path: testColumn, Size: int64(len(data)), Hash: hashBytes(data)}
+
+	t.Run("invalid path", func(t *testing.T) {
+		for _, path := range []string{"", ".", "..", "../probe2/" + testColumn, "eth0/../../x", "/etc/passwd", `eth0\x`} {
+			invalid := file
+			invalid.Path = path
+			_, err := client.uploadChunk(ctx, invalid, 0, "", data)
+			require.ErrorContains(t, err, ErrInvalidPath.Error(), path)
+		}
+	})
+
+	t.Run("chunk hash mismatch", func(t *testing.T) {
+		headers := http.Header{}
+		headers.Set(HeaderChunkHash, hashBytes([]byte("other")))
+		err := client.do(ctx, http.MethodPut, UploadRoute+"?path="+testColumn+"&size=4&offset=0&hash="+file.Hash, nil, headers, data, &UploadResponse{})
+		require.ErrorContains(t, err, ErrChunkHashMismatch.Error())
+	})
+
+	t.Run("file hash mismatch", func(t *testing.T) {
+		invalid := file
+		invalid.Hash = hashBytes([]byte("other"))
+		_, err := client.uploadChunk(ctx, invalid, 0, "", data)
+		require.ErrorIs(t, err, ErrFileHashMismatch)
+
+		_, err = os.Stat(filepath.Join(root, testProbe, testColumn))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("chunk exceeds size", func(t *testing.T) {
+		invalid := file
+		invalid.Size = 2
+		_, err := client.uploadChunk(ctx, invalid, 0, "", data)
+		require.ErrorContains(t, err, errChunkExceeds.Error())
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	pki := newTestPKI(t)
+	ts, _ := newTestCollector(t, pki)
+
+	dbPath := t.TempDir()
+	writeTestFile(t, dbPath, testColumn, []byte("data"), false)
+
+	ctx := context.Background()
+
+	t.Run("no client certificate", func(t *testing.T) {
+		client := NewClient(ts.URL, dbPath, &tls.Config{MinVersion: tls.VersionTLS13, RootCAs: pki.pool})
+		_, err := client.Sync(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("untrusted client certificate", func(t *testing.T) {
+		other := newTestPKI(t)
+		cfg := other.clientConfig(t, testProbe)
+		cfg.RootCAs = pki.pool
+
+		client := NewClient(ts.URL, dbPath, cfg)
+		_, err := client.Sync(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid identity", func(t *testing.T) {
+		client := NewClient(ts.URL, dbPath, pki.clientConfig(t, ".staging"))
+		_, err := client.Sync(ctx)
+		require.ErrorContains(t, err, errInvalidProbeID.Error())
+	})
+}
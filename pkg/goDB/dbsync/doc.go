@@ -0,0 +1,25 @@
+/*
+Package dbsync implements the synchronization of goDB databases from (edge) probes to a central
+collector.
+
+All communication is performed via HTTPS using mutual TLS: The collector only accepts clients
+presenting a certificate signed by its configured CA and derives the identity of the probe from
+the common name of the certificate. The data of each probe is stored in a dedicated goDB below
+the collector's root directory (i.e. <root>/<probe>/<iface>/...), which can be queried directly.
+
+A sync run consists of two stages:
+
+ 1. The client announces the path, size and SHA-256 content hash of all files that changed since
+    the last successful run (StatusRoute). The collector responds with the state of each file:
+    Files it already holds with identical content are skipped (deduplication), files with an
+    interrupted upload are resumed from the offset received so far.
+ 2. All remaining files are uploaded in chunks (UploadRoute), each carrying the SHA-256 hash of
+    its content. Chunks are appended to a staging file that is only moved into place once the
+    hash of the complete file has been verified. If a file merely grew since it was last synced
+    (as is the case for the column files of the current day), only the appended data is uploaded.
+
+Since the metadata of a goDB directory refers to the blocks stored in its column files, it is read
+before and uploaded after all column files of the directory, ensuring that the collector never holds
+metadata referring to data it hasn't received yet.
+*/
+package dbsync
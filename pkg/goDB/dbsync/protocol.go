@@ -0,0 +1,117 @@
+package dbsync
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// StatusRoute is the route to query the state of a set of files on the collector
+	StatusRoute = "/sync/v1/status"
+
+	// UploadRoute is the route to upload a chunk of a file to the collector
+	UploadRoute = "/sync/v1/upload"
+
+	// HeaderChunkHash denotes the header carrying the (hex encoded) SHA-256 hash of an uploaded chunk
+	HeaderChunkHash = "X-Goprobe-Chunk-Sha256"
+
+	// DefaultChunkSize denotes the default size of the chunks a file is uploaded in
+	DefaultChunkSize = 4 * 1024 * 1024 // 4 MiB
+
+	// MaxChunkSize denotes the maximum size of a chunk accepted by the collector
+	MaxChunkSize = 64 * 1024 * 1024 // 64 MiB
+)
+
+// Upload query parameters
+const (
+	paramPath   = "path"
+	paramSize   = "size"
+	paramHash   = "hash"
+	paramOffset = "offset"
+	paramBase   = "base"
+)
+
+var (
+	// ErrInvalidPath denotes a file path that is absolute or escapes the database directory
+	ErrInvalidPath = errors.New("invalid file path")
+
+	// ErrChunkHashMismatch denotes that the content of a chunk does not match its hash
+	ErrChunkHashMismatch = errors.New("chunk hash mismatch")
+
+	// ErrFileHashMismatch denotes that the content of a completely uploaded file does not match its hash
+	ErrFileHashMismatch = errors.New("file hash mismatch")
+
+	// ErrOffsetMismatch denotes that a chunk does not continue the data received so far
+	ErrOffsetMismatch = errors.New("chunk offset does not match received data")
+)
+
+// FileState denotes the state of a file on the collector
+type FileState string
+
+const (
+	// StateComplete denotes that the collector holds the file with identical content
+	StateComplete FileState = "complete"
+
+	// StatePartial denotes that an upload of the file has been started, but not completed
+	StatePartial FileState = "partial"
+
+	// StateMissing denotes that the collector doesn't hold the file (or holds different content)
+	StateMissing FileState = "missing"
+)
+
+// File describes a file of a probe's database
+type File struct {
+	// Path: denotes the path of the file relative to the database directory. Example: "eth0/2024/01/1704067200/bytes_rcvd.gpf"
+	Path string `json:"path"`
+	// Size: denotes the size of the file in bytes. Example: 4096
+	Size int64 `json:"size"`
+	// Hash: denotes the hex encoded SHA-256 hash of the file's content
+	Hash string `json:"hash"`
+}
+
+// StatusRequest is the payload to query the state of a set of files on the collector
+type StatusRequest struct {
+	Files []File `json:"files"`
+}
+
+// FileStatus describes the state of a file on the collector
+type FileStatus struct {
+	// Path: denotes the path of the file relative to the database directory. Example: "eth0/2024/01/1704067200/bytes_rcvd.gpf"
+	Path string `json:"path"`
+	// State: denotes the state of the file. Example: "partial"
+	State FileState `json:"state"`
+	// Offset: denotes the amount of data received for an interrupted upload (if applicable). Example: 8388608
+	Offset int64 `json:"offset,omitempty"`
+	// Size: denotes the size of the (different) version of the file held by the collector (if any). Example: 2048
+	Size int64 `json:"size,omitempty"`
+	// Hash: denotes the hex encoded SHA-256 hash of the version of the file held by the collector (if any)
+	Hash string `json:"hash,omitempty"`
+}
+
+// StatusResponse is the response to a status request
+type StatusResponse struct {
+	Files []FileStatus `json:"files"`
+}
+
+// UploadResponse is the response to an uploaded chunk
+type UploadResponse struct {
+	// Offset: denotes the amount of data received for the file so far. Example: 8388608
+	Offset int64 `json:"offset"`
+	// Complete: denotes if the file has been received completely (and was moved into place). Example: false
+	Complete bool `json:"complete"`
+	// Error: stores the error message if the upload failed
+	Error string `json:"error,omitempty"`
+}
+
+// cleanPath validates a (slash separated) path relative to the database directory
+func cleanPath(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "/") || strings.Contains(path, "\\") {
+		return "", ErrInvalidPath
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidPath
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,452 @@
+package dbsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/telemetry/logging"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// stagingDir denotes the directory (below the root directory) holding incomplete uploads
+	stagingDir = ".staging"
+
+	maxStatusRequestSize = 32 * 1024 * 1024 // 32 MiB
+
+	filePermissions = fs.FileMode(0644)
+	dirPermissions  = fs.FileMode(0755)
+)
+
+var (
+	errUnauthenticated = errors.New("no verified client certificate presented")
+	errInvalidProbeID  = errors.New("invalid probe identity in client certificate")
+	errInvalidHash     = errors.New("invalid SHA-256 hash")
+	errChunkTooLarge   = fmt.Errorf("chunk exceeds maximum size of %d bytes", MaxChunkSize)
+	errChunkExceeds    = errors.New("chunk exceeds announced file size")
+)
+
+// Server receives the goDB databases synchronized by probes (c.f. Client). It is intended to be
+// served via TLS requiring client certificates (c.f. TLSConfig.ServerConfig()), since the identity
+// of each probe (and hence the location of its database) is derived from its certificate
+type Server struct {
+	root string
+	mux  *http.ServeMux
+
+	hashes     map[string]cachedHash
+	probeLocks map[string]*sync.Mutex
+
+	sync.Mutex
+}
+
+// cachedHash stores the content hash of a file held by the collector (valid as long as the file
+// isn't modified)
+type cachedHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// NewServer instantiates a new Server storing all databases below the provided root directory
+func NewServer(root string) *Server {
+	s := &Server{
+		root:       filepath.Clean(root),
+		mux:        http.NewServeMux(),
+		hashes:     make(map[string]cachedHash),
+		probeLocks: make(map[string]*sync.Mutex),
+	}
+	s.mux.HandleFunc(StatusRoute, s.handleStatus)
+	s.mux.HandleFunc(UploadRoute, s.handleUpload)
+
+	return s
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	probe, err := probeID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req StatusRequest
+	if err := jsoniter.NewDecoder(io.LimitReader(r.Body, maxStatusRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lock := s.probeLock(probe)
+	lock.Lock()
+	defer lock.Unlock()
+
+	resp := StatusResponse{
+		Files: make([]FileStatus, 0, len(req.Files)),
+	}
+	for _, file := range req.Files {
+		status, err := s.fileStatus(probe, file)
+		if err != nil {
+			writeError(w, statusCode(err), fmt.Errorf("%s: %w", file.Path, err))
+			return
+		}
+		resp.Files = append(resp.Files, status)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) fileStatus(probe string, file File) (FileStatus, error) {
+	path, err := cleanPath(file.Path)
+	if err != nil {
+		return FileStatus{}, err
+	}
+	if err := validateHash(file.Hash); err != nil {
+		return FileStatus{}, err
+	}
+
+	status := FileStatus{
+		Path:  file.Path,
+		State: StateMissing,
+	}
+
+	existing, err := s.hash(filepath.Join(s.root, probe, path))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return FileStatus{}, err
+	}
+	if err == nil {
+		if existing.hash == file.Hash && existing.size == file.Size {
+			status.State = StateComplete
+			return status, nil
+		}
+		status.Size, status.Hash = existing.size, existing.hash
+	}
+
+	stat, err := os.Stat(s.stagingPath(probe, path, file.Hash))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return FileStatus{}, err
+		}
+		return status, nil
+	}
+	status.State, status.Offset = StatePartial, stat.Size()
+
+	return status, nil
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	probe, err := probeID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	params := r.URL.Query()
+	path, err := cleanPath(params.Get(paramPath))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	size, err := strconv.ParseInt(params.Get(paramSize), 10, 64)
+	if err != nil || size < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid file size: %q", params.Get(paramSize)))
+		return
+	}
+	offset, err := strconv.ParseInt(params.Get(paramOffset), 10, 64)
+	if err != nil || offset < 0 || offset > size {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid offset: %q", params.Get(paramOffset)))
+		return
+	}
+	hash, base := params.Get(paramHash), params.Get(paramBase)
+	if err := validateHash(hash); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	chunkHash := r.Header.Get(HeaderChunkHash)
+	if err := validateHash(chunkHash); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%s: %w", HeaderChunkHash, err))
+		return
+	}
+
+	// Read (and verify) the chunk before touching any data on disk
+	chunk, err := io.ReadAll(io.LimitReader(r.Body, MaxChunkSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(chunk) > MaxChunkSize {
+		writeError(w, http.StatusRequestEntityTooLarge, errChunkTooLarge)
+		return
+	}
+	if sum := sha256.Sum256(chunk); hex.EncodeToString(sum[:]) != chunkHash {
+		writeError(w, http.StatusBadRequest, ErrChunkHashMismatch)
+		return
+	}
+	if offset+int64(len(chunk)) > size {
+		writeError(w, http.StatusBadRequest, errChunkExceeds)
+		return
+	}
+
+	lock := s.probeLock(probe)
+	lock.Lock()
+	defer lock.Unlock()
+
+	logger := logging.FromContext(r.Context()).With("probe", probe, "path", path)
+
+	target, staging := filepath.Join(s.root, probe, path), s.stagingPath(probe, path, hash)
+	received, err := s.prepareStaging(target, staging, offset, base)
+	if err != nil {
+		if errors.Is(err, ErrOffsetMismatch) {
+			writeJSON(w, http.StatusConflict, UploadResponse{Offset: received, Error: err.Error()})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := appendToFile(staging, chunk); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	received += int64(len(chunk))
+	if received < size {
+		writeJSON(w, http.StatusOK, UploadResponse{Offset: received})
+		return
+	}
+
+	// The file has been received completely, verify its content and move it into place
+	if err := s.complete(target, staging, size, hash); err != nil {
+		logger.Errorf("failed to complete upload: %s", err)
+		writeJSON(w, statusCode(err), UploadResponse{Error: err.Error()})
+		return
+	}
+	logger.With("size", size).Debug("received file")
+
+	writeJSON(w, http.StatusOK, UploadResponse{Offset: received, Complete: true})
+}
+
+// prepareStaging ensures that the staging file exists and that a chunk at the provided offset continues
+// the data received so far. If the upload continues the file currently held by the collector (as denoted
+// by its hash), the staging file is initialized with its content. It returns the amount of data received
+func (s *Server) prepareStaging(target, staging string, offset int64, base string) (int64, error) {
+	stat, err := os.Stat(staging)
+	if err == nil {
+		if stat.Size() != offset {
+			return stat.Size(), ErrOffsetMismatch
+		}
+		return offset, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(staging), dirPermissions); err != nil {
+		return 0, err
+	}
+	if offset == 0 {
+		return 0, os.WriteFile(staging, nil, filePermissions)
+	}
+	if base == "" {
+		return 0, ErrOffsetMismatch
+	}
+
+	existing, err := s.hash(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, ErrOffsetMismatch
+		}
+		return 0, err
+	}
+	if existing.hash != base || existing.size != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	return offset, copyFile(target, staging)
+}
+
+func (s *Server) complete(target, staging string, size int64, hash string) error {
+	received, err := hashFile(staging)
+	if err != nil {
+		return err
+	}
+	if received.size != size || received.hash != hash {
+		if rerr := os.Remove(staging); rerr != nil {
+			return errors.Join(ErrFileHashMismatch, rerr)
+		}
+		return ErrFileHashMismatch
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, target); err != nil {
+		return err
+	}
+
+	// Cache the hash of the file (in order to avoid having to recompute it on the next status request)
+	stat, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.hashes[target] = cachedHash{size: stat.Size(), modTime: stat.ModTime(), hash: hash}
+	s.Unlock()
+
+	return nil
+}
+
+// hash returns the content hash of a file held by the collector, recomputing it only if the file
+// was modified since the last computation
+func (s *Server) hash(path string) (cachedHash, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return cachedHash{}, err
+	}
+
+	s.Lock()
+	cached, exists := s.hashes[path]
+	s.Unlock()
+	if exists && cached.size == stat.Size() && cached.modTime.Equal(stat.ModTime()) {
+		return cached, nil
+	}
+
+	cached, err = hashFile(path)
+	if err != nil {
+		return cachedHash{}, err
+	}
+	cached.modTime = stat.ModTime()
+
+	s.Lock()
+	s.hashes[path] = cached
+	s.Unlock()
+
+	return cached, nil
+}
+
+func (s *Server) stagingPath(probe, path, hash string) string {
+	sum := sha256.Sum256([]byte(path + "\x00" + hash))
+	return filepath.Join(s.root, stagingDir, probe, hex.EncodeToString(sum[:]))
+}
+
+func (s *Server) probeLock(probe string) *sync.Mutex {
+	s.Lock()
+	defer s.Unlock()
+
+	lock, exists := s.probeLocks[probe]
+	if !exists {
+		lock = new(sync.Mutex)
+		s.probeLocks[probe] = lock
+	}
+	return lock
+}
+
+// probeID extracts the identity of the probe from the common name of its (verified) client certificate
+func probeID(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", errUnauthenticated
+	}
+
+	id := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\`) {
+		return "", errInvalidProbeID
+	}
+	return id, nil
+}
+
+func validateHash(hash string) error {
+	if len(hash) != 2*sha256.Size {
+		return errInvalidHash
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return errInvalidHash
+	}
+	return nil
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidPath), errors.Is(err, errInvalidHash):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrFileHashMismatch):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func hashFile(path string) (cachedHash, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return cachedHash{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, f)
+	if err != nil {
+		return cachedHash{}, err
+	}
+	return cachedHash{size: n, hash: hex.EncodeToString(hasher.Sum(nil))}, nil
+}
+
+func appendToFile(path string, data []byte) error {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_APPEND, filePermissions)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	return f.Close()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermissions)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = jsoniter.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, UploadResponse{Error: err.Error()})
+}
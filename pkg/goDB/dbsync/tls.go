@@ -0,0 +1,89 @@
+package dbsync
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	errNoCertificate = errors.New("certificate and key must be provided for mutual TLS")
+	errNoCA          = errors.New("CA certificate must be provided for mutual TLS")
+)
+
+// TLSConfig stores the certificates / keys used for mutual TLS authentication
+type TLSConfig struct {
+	// Cert: denotes the path to the PEM encoded certificate presented to the peer
+	// Example: "/etc/goprobe/tls/probe.crt"
+	Cert string `json:"cert" yaml:"cert"`
+	// Key: denotes the path to the PEM encoded private key of the certificate
+	// Example: "/etc/goprobe/tls/probe.key"
+	Key string `json:"key" yaml:"key"`
+	// CA: denotes the path to the PEM encoded CA certificate(s) used to verify the peer
+	// Example: "/etc/goprobe/tls/ca.crt"
+	CA string `json:"ca" yaml:"ca"`
+}
+
+// Validate checks if all certificates / keys are provided
+func (t *TLSConfig) Validate() error {
+	if t.Cert == "" || t.Key == "" {
+		return errNoCertificate
+	}
+	if t.CA == "" {
+		return errNoCA
+	}
+	return nil
+}
+
+// ClientConfig loads the certificates / keys and returns a TLS configuration suitable for a client
+func (t *TLSConfig) ClientConfig() (*tls.Config, error) {
+	cert, pool, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}, nil
+}
+
+// ServerConfig loads the certificates / keys and returns a TLS configuration suitable for a server,
+// requiring all clients to present a certificate signed by the CA
+func (t *TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, pool, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+func (t *TLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	if err := t.Validate(); err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(filepath.Clean(t.CA))
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return tls.Certificate{}, nil, fmt.Errorf("no valid CA certificate found in %s", t.CA)
+	}
+
+	return cert, pool, nil
+}
@@ -181,6 +181,29 @@ func TestCompressUncompress(t *testing.T) {
 	}
 }
 
+func TestCompressReusedBuffer(t *testing.T) {
+	in := []byte(testCases[0].uncompressed)
+
+	enc := New()
+	buf := bytes.NewBuffer(nil)
+	n, err := enc.Compress(in, bytes.Repeat([]byte{0xFF}, 4096), buf)
+	if err != nil {
+		t.Fatalf("failed to compress: %s", err)
+	}
+	if n != buf.Len() {
+		t.Fatalf("unexpected number of bytes written, want %d, have %d", buf.Len(), n)
+	}
+
+	out := make([]byte, len(in))
+	n, err = enc.Decompress(buf.Bytes(), out, bytes.NewBuffer(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to decompress: %s", err)
+	}
+	if string(out[:n]) != testCases[0].uncompressed {
+		t.Fatalf("mismatch detected after compression roundtrip, want `%s`, have `%s`", testCases[0].uncompressed, string(out[:n]))
+	}
+}
+
 func TestDecompress(t *testing.T) {
 	for tn, tc := range testCases {
 		for i := 0; i < len(tc.compressed); i++ {
@@ -4,18 +4,54 @@ import (
 	"fmt"
 	"runtime"
 
+	"github.com/els0r/goProbe/pkg/query"
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
 )
 
 type aggregateResult struct {
 	aggregatedMaps hashmap.NamedAggFlowMapWithMetadata
+	spill          *spiller
 	totals         types.Counters
 	err            error
 }
 
+// forEach calls fn on all aggregated maps. If partial aggregates were spilled to disk, they
+// are merged partition by partition, requiring fn to release each map once done with it
+func (a aggregateResult) forEach(fn func(iface string, aggMap *hashmap.AggFlowMapWithMetadata)) error {
+	if a.spill.spilled() {
+		return a.spill.merge(fn)
+	}
+	for iface, aggMap := range a.aggregatedMaps {
+		fn(iface, aggMap)
+	}
+	return nil
+}
+
+// clear releases all resources held by the aggregation result (including spill files, if any)
+func (a aggregateResult) clear() error {
+	a.aggregatedMaps.ClearFast()
+	return a.spill.close()
+}
+
 var numProcessingUnits = runtime.NumCPU()
 
+// ioWorkers returns the number of processing units reading / decompressing blocks for the statement
+func ioWorkers(stmt *query.Statement) int {
+	if stmt.IOWorkers > 0 {
+		return stmt.IOWorkers
+	}
+	return numProcessingUnits
+}
+
+// cpuWorkers returns the number of processing units filtering / aggregating blocks for the statement
+func cpuWorkers(stmt *query.Statement) int {
+	if stmt.CPUWorkers > 0 {
+		return stmt.CPUWorkers
+	}
+	return numProcessingUnits
+}
+
 type internalError int
 
 // enumeration of processing errors
@@ -40,8 +76,10 @@ func (i internalError) Error() string {
 // receive maps on mapChan until mapChan gets closed.
 // Then send aggregation result over resultChan.
 // If an error occurs, aggregate may return prematurely.
+// If a spiller is provided, partial aggregates are written to disk whenever
+// its memory budget is exceeded.
 // Closes resultChan on termination.
-func aggregate(mapChan <-chan hashmap.AggFlowMapWithMetadata, ifaces []string, isLowMem bool) chan aggregateResult {
+func aggregate(mapChan <-chan hashmap.AggFlowMapWithMetadata, ifaces []string, isLowMem bool, spill *spiller) chan aggregateResult {
 
 	// create channel that returns the final aggregate result
 	resultChan := make(chan aggregateResult, 1)
@@ -77,6 +115,39 @@ func aggregate(mapChan <-chan hashmap.AggFlowMapWithMetadata, ifaces []string, i
 			} else {
 				item.ClearFast()
 			}
+
+			// Spill the partial aggregates to disk if the memory budget is exceeded
+			if spill.exceeded(finalMaps) {
+				if err := spill.spill(finalMaps, isLowMem); err != nil {
+
+					// drain the map channel to allow all producers to terminate
+					for item := range mapChan {
+						item.ClearFast()
+					}
+					resultChan <- aggregateResult{
+						spill: spill,
+						err:   fmt.Errorf("failed to spill partial aggregates: %w", err),
+					}
+					return
+				}
+			}
+		}
+
+		// If partial aggregates were spilled before, the remaining ones are spilled as well
+		// so that all of them can be merged partition by partition
+		if spill.spilled() {
+			if err := spill.spill(finalMaps, isLowMem); err != nil {
+				resultChan <- aggregateResult{
+					spill: spill,
+					err:   fmt.Errorf("failed to spill partial aggregates: %w", err),
+				}
+				return
+			}
+			resultChan <- aggregateResult{
+				spill:  spill,
+				totals: totals,
+			}
+			return
 		}
 
 		// Push the final result
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+)
+
+// explain determines the execution plan of the statement across all its interfaces without
+// reading any flow data
+func (qr *QueryRunner) explain(stmt *query.Statement, hostname string) (*results.Plan, error) {
+	plan := &results.Plan{
+		Columns:    qr.query.Columns(),
+		Pushdowns:  qr.query.Pushdowns(),
+		Workers:    ioWorkers(stmt),
+		CPUWorkers: cpuWorkers(stmt),
+		LowMem:     stmt.LowMem,
+		Mmap:       stmt.Mmap || qr.mmap,
+		Live:       stmt.Live,
+		MaxAggMem:  uint64(stmt.MaxAggMem) * 1024 * 1024,
+	}
+
+	for _, iface := range stmt.Ifaces {
+		wm, err := goDB.NewDBWorkManager(qr.query, qr.dbPath, iface, plan.Workers)
+		if err != nil {
+			return nil, err
+		}
+		ifacePlan, err := wm.FS(qr.fsys).CPUWorkers(plan.CPUWorkers).Plan(stmt.First, stmt.Last)
+		if err != nil {
+			return nil, err
+		}
+		ifacePlan.Host = hostname
+		plan.Ifaces = append(plan.Ifaces, *ifacePlan)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/query"
+)
+
+// reportProgress periodically reports the combined progress of all work managers to fn until the
+// returned function is called, which reports the final progress and waits for the reporting to end
+func reportProgress(ctx context.Context, fn query.ProgressFn, workManagers map[string]*goDB.DBWorkManager, interval time.Duration) (stop func()) {
+	var (
+		start = time.Now()
+		done  = make(chan struct{})
+		wg    sync.WaitGroup
+	)
+
+	current := func() (progress query.Progress) {
+		for _, workManager := range workManagers {
+			wp := workManager.Progress()
+			progress.DirsTotal += wp.DirsTotal
+			progress.DirsScanned += wp.DirsProcessed
+			progress.BytesProcessed += wp.BytesProcessed
+			progress.RowsAggregated += wp.RowsAggregated
+		}
+		progress.Elapsed = time.Since(start)
+		progress.EstimateETA()
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn(current())
+			case <-ctx.Done():
+				return
+			case <-done:
+				fn(current())
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
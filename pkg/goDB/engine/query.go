@@ -4,19 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net/netip"
 	"os"
 	"regexp"
 	"runtime"
 	"runtime/debug"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/els0r/goProbe/pkg/capture"
 	"github.com/els0r/goProbe/pkg/goDB"
 	"github.com/els0r/goProbe/pkg/goDB/conditions/node"
 	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
 	"github.com/els0r/goProbe/pkg/query"
 	"github.com/els0r/goProbe/pkg/query/heap"
 	"github.com/els0r/goProbe/pkg/results"
@@ -28,29 +30,52 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// LiveDataSource provides the flows currently held in memory by a running capture (i.e. not yet
+// written to the DB). It is implemented by the capture manager and decoupled from it in order to
+// keep the query engine free of any (platform-specific) capture dependency
+type LiveDataSource interface {
+	GetFlowMaps(ctx context.Context, filterFn goDB.FilterFn, writeoutChan chan<- hashmap.AggFlowMapWithMetadata, ifaces ...string) results.Cut
+}
+
 // QueryRunner implements the Runner interface to execute queries
 // against the goDB flow database
 type QueryRunner struct {
-	query          *goDB.Query
-	captureManager *capture.Manager
-	dbPath         string
+	query    *goDB.Query
+	liveData LiveDataSource
+	dbPath   string
+	fsys     storage.FS
+	mmap     bool
 }
 
 // NewQueryRunner creates a new query runner
 func NewQueryRunner(dbPath string) *QueryRunner {
 	return &QueryRunner{
 		dbPath: dbPath,
+		fsys:   storage.DefaultFS,
 	}
 }
 
 // NewQueryRunnerWithLiveData creates a new query runner that acts on both DB and live data
-func NewQueryRunnerWithLiveData(dbPath string, captureManager *capture.Manager) *QueryRunner {
+func NewQueryRunnerWithLiveData(dbPath string, liveData LiveDataSource) *QueryRunner {
 	return &QueryRunner{
-		dbPath:         dbPath,
-		captureManager: captureManager,
+		dbPath:   dbPath,
+		liveData: liveData,
+		fsys:     storage.DefaultFS,
 	}
 }
 
+// WithFS sets a non-default file system (e.g. an in-memory one) the DB is read from
+func (qr *QueryRunner) WithFS(fsys storage.FS) *QueryRunner {
+	qr.fsys = fsys
+	return qr
+}
+
+// WithMmap enables memory-mapped access to the DB for all queries (regardless of the query arguments)
+func (qr *QueryRunner) WithMmap(enable bool) *QueryRunner {
+	qr.mmap = enable
+	return qr
+}
+
 // Run implements the query.Runner interface
 func (qr *QueryRunner) Run(ctx context.Context, args *query.Args) (res *results.Result, err error) {
 	var argsStr string
@@ -68,7 +93,7 @@ func (qr *QueryRunner) Run(ctx context.Context, args *query.Args) (res *results.
 	}
 
 	// get list of available interfaces in the local DB
-	stmt.Ifaces, err = parseIfaceList(qr.dbPath, args.Ifaces)
+	stmt.Ifaces, err = parseIfaceList(qr.fsys, qr.dbPath, args.Ifaces)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare query statement: %w", err)
 	}
@@ -104,10 +129,22 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 		return res, fmt.Errorf("conditions parsing error: %w", parseErr)
 	}
 
-	qr.query = goDB.NewQuery(queryAttributes, queryConditional, stmt.LabelSelector).LowMem(stmt.LowMem)
+	// the statistics per time bin require the traffic to be split by the time bins storing it
+	labelSelector := stmt.LabelSelector
+	if stmt.Stats {
+		labelSelector.Timestamp = true
+	}
+
+	qr.query = goDB.NewQuery(queryAttributes, queryConditional, labelSelector).
+		Counters(stmt.Counters).
+		LowMem(stmt.LowMem).
+		Mmap(stmt.Mmap || qr.mmap)
 	if qr.query == nil {
 		return res, errors.New("query is not executable")
 	}
+	if stmt.TimeAlign == query.TimeAlignInterpolate {
+		qr.query = qr.query.Interpolate(stmt.First, stmt.Last)
+	}
 
 	result.Query = results.Query{
 		Attributes: qr.query.AttributesToString(),
@@ -119,7 +156,12 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get system hostname: %w", err)
 	}
-	hostID := info.GetHostID(qr.dbPath)
+	// the fallback host ID is only persisted in case the DB resides on disk
+	hostIDFallbackPath := qr.dbPath
+	if _, onDisk := qr.fsys.(storage.OSFS); !onDisk {
+		hostIDFallbackPath = ""
+	}
+	hostID := info.GetHostID(hostIDFallbackPath)
 	result.Hostname = hostname
 
 	// assign the hostname to the list of hosts handled in this query. Here, the only one
@@ -127,6 +169,15 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 		result.HostsStatuses[hostname] = result.Status
 	}()
 
+	// if requested, only determine the execution plan instead of running the query
+	if stmt.Explain {
+		result.Plan, err = qr.explain(stmt, hostname)
+		if err != nil {
+			return res, err
+		}
+		return result, nil
+	}
+
 	// start ticker to check memory consumption every second
 	heapWatchCtx, cancelHeapWatch := context.WithCancel(ctx)
 	defer cancelHeapWatch()
@@ -136,9 +187,15 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 	queryCtx, cancelQuery := context.WithCancel(ctx)
 	defer cancelQuery()
 
+	// If a memory budget for the aggregation is set, partial aggregates exceeding it are spilled to disk
+	var spill *spiller
+	if stmt.MaxAggMem > 0 {
+		spill = newSpiller(uint64(stmt.MaxAggMem) * 1024 * 1024)
+	}
+
 	// Channel for handling of returned maps
 	mapChan := make(chan hashmap.AggFlowMapWithMetadata, 1024)
-	aggregateChan := aggregate(mapChan, stmt.Ifaces, stmt.LowMem)
+	aggregateChan := aggregate(mapChan, stmt.Ifaces, stmt.LowMem, spill)
 
 	go func() {
 		select {
@@ -154,7 +211,7 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 			agg := <-aggregateChan
 
 			// call the garbage collector
-			agg.aggregatedMaps.ClearFast()
+			_ = agg.clear()
 			runtime.GC()
 			debug.FreeOSMemory()
 
@@ -164,10 +221,25 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 		}
 	}()
 
+	// If a time alignment was requested, all blocks overlapping the time range are read (instead of only the
+	// ones written within it)
+	tFirst, tLast := stmt.First, stmt.Last
+	if stmt.TimeAlign != "" {
+		tFirst, tLast = overlappingBlocks(tFirst, tLast)
+	}
+
+	// If enabled, take a snapshot of the live data before reading from the DB, which is then only read up to
+	// the rotation preceding the snapshot. This way, a rotation completing while the query runs is either fully
+	// included from the DB or fully contained in the live data (instead of being missed or counted twice)
+	if cut := qr.runLiveQuery(queryCtx, mapChan, stmt); cut != nil {
+		result.Summary.Cut = cut
+		tLast = min(tLast, cut.Timestamp.Unix())
+	}
+
 	// create work managers
 	workManagers := map[string]*goDB.DBWorkManager{} // map interfaces to workManagers
 	for _, iface := range stmt.Ifaces {
-		wm, nonempty, err := createWorkManager(qr.dbPath, iface, stmt.First, stmt.Last, qr.query, numProcessingUnits)
+		wm, nonempty, err := createWorkManager(qr.fsys, qr.dbPath, iface, tFirst, tLast, qr.query, ioWorkers(stmt), cpuWorkers(stmt))
 		if err != nil {
 			return res, err
 		}
@@ -194,11 +266,44 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 		result.Summary.DataAvailable = true
 	}
 
+	// When interpolating, the traffic is attributed to the requested time range. In snap mode, the covered
+	// time period denotes the effective time range enclosing it (c.f. below)
+	if stmt.TimeAlign != "" {
+		result.Summary.Alignment = &results.Alignment{
+			Mode: stmt.TimeAlign,
+			Requested: results.TimeRange{
+				First: time.Unix(stmt.First, 0),
+				Last:  time.Unix(stmt.Last, 0),
+			},
+		}
+		if stmt.TimeAlign == query.TimeAlignInterpolate && len(workManagers) > 0 {
+			if tSpanFirst.Before(result.Summary.Alignment.Requested.First) {
+				tSpanFirst = result.Summary.Alignment.Requested.First
+			}
+			if tSpanLast.After(result.Summary.Alignment.Requested.Last) {
+				tSpanLast = result.Summary.Alignment.Requested.Last
+			}
+		}
+	}
+
 	result.Summary.First = tSpanFirst
 	result.Summary.Last = tSpanLast
 
-	// If enabled, run a live query in the background / parallel to the DB query and put the results on the same output channel
-	liveQueryWG := qr.runLiveQuery(queryCtx, mapChan, stmt)
+	// report the link properties of each interface (if recorded) to allow interpreting the traffic
+	for iface, workManager := range workManagers {
+		if link := workManager.Link(); link != nil {
+			if result.Summary.Links == nil {
+				result.Summary.Links = make(map[string]types.LinkInfo)
+			}
+			result.Summary.Links[iface] = *link
+		}
+	}
+
+	// report the progress of the query (if requested)
+	stopProgress := func() {}
+	if progressFn := query.ProgressFromContext(ctx); progressFn != nil {
+		stopProgress = reportProgress(queryCtx, progressFn, workManagers, query.DefaultProgressInterval)
+	}
 
 	// spawn reader processing units and make them work on the individual DB blocks
 	// processing by interface is sequential, e.g. for multi-interface queries
@@ -206,32 +311,84 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 		workManager.ExecuteWorkerReadJobs(queryCtx, mapChan)
 	}
 
-	// In case a live query is being performed in the background, ensure it is done
-	liveQueryWG.Wait()
+	stopProgress()
 
 	// We are done with all worker jobs, close the ouput / result channel
 	close(mapChan)
 
 	// wait for the job to complete, then call a garbage collection
 	agg := <-aggregateChan
-	for _, workManager := range workManagers {
+	var (
+		hostnames             map[netip.Addr]gpfile.Hostname
+		tSnapFirst, tSnapLast time.Time
+		snapped               bool
+	)
+	for iface, workManager := range workManagers {
+		result.Summary.CorruptBlocks += workManager.NumCorruptBlocks()
+		if result.Summary.Alignment != nil {
+			result.Summary.Alignment.InterpolatedBlocks += workManager.NumInterpolatedBlocks()
+		}
+		if stmt.TimeAlign == query.TimeAlignSnap {
+			if t0, t1, read := workManager.GetReadTimeInterval(); read {
+				if !snapped || t0.Before(tSnapFirst) {
+					tSnapFirst = t0
+				}
+				if !snapped || t1.After(tSnapLast) {
+					tSnapLast = t1
+				}
+				snapped = true
+			}
+		}
+
+		// gather the hostnames recorded at capture time, the most recently recorded one taking precedence
+		for ip, hostname := range workManager.Hostnames() {
+			if hostnames == nil {
+				hostnames = make(map[netip.Addr]gpfile.Hostname)
+			}
+			if existing, exists := hostnames[ip]; !exists || existing.Timestamp < hostname.Timestamp {
+				hostnames[ip] = hostname
+			}
+		}
+
+		// report the periods without any traffic, which are covered by the time range despite lacking data
+		for _, idle := range workManager.Idle() {
+			if result.Summary.Idle == nil {
+				result.Summary.Idle = make(map[string][]results.TimeRange)
+			}
+			result.Summary.Idle[iface] = append(result.Summary.Idle[iface], results.TimeRange{
+				First: time.Unix(idle.From-goDB.DBWriteInterval, 0),
+				Last:  time.Unix(idle.To, 0),
+			})
+		}
 		workManager.Close()
 		workManager = nil
 	}
 	runtime.GC()
 
+	// In snap mode, the time range covered by the blocks actually read denotes the effective time range
+	if snapped {
+		result.Summary.First, result.Summary.Last = tSnapFirst, tSnapLast
+	}
+
 	// first inspect if err is set due to problems not related to aggregation
 	if err != nil {
 		return res, err
 	}
 
+	// release the aggregation result (and remove its spill files, if any) once done
+	defer func() {
+		if cerr := agg.clear(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to remove spill files: %w", cerr)
+		}
+	}()
+
 	// check aggregation for errors
 	if agg.err != nil {
 		return res, agg.err
 	}
 
 	/// RESULTS PREPARATION ///
-	var sip, dip, dport, proto types.Attribute
+	var sip, dip, dport, proto, tag, ttlMin, ttlMax types.Attribute
 	for _, attribute := range qr.query.Attributes {
 		switch attribute.Name() {
 		case types.SIPName:
@@ -242,18 +399,24 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 			dport = attribute
 		case types.ProtoName:
 			proto = attribute
+		case types.TagName:
+			tag = attribute
+		case types.TTLMinName:
+			ttlMin = attribute
+		case types.TTLMaxName:
+			ttlMax = attribute
 		}
 	}
 
-	var rs = make(results.Rows, agg.aggregatedMaps.Len())
-	count := 0
+	// if partial aggregates were spilled, the number of rows is only known after merging them
+	var rs = make(results.Rows, 0, agg.aggregatedMaps.Len())
 
 	var metaIterOption hashmap.MetaIterOption
 	if valFilterNode != nil && valFilterNode.ValFilter != nil {
 		metaIterOption = hashmap.WithFilter(valFilterNode.ValFilter)
 	}
 	var totals hashmap.Val
-	for iface, aggMap := range agg.aggregatedMaps {
+	err = agg.forEach(func(iface string, aggMap *hashmap.AggFlowMapWithMetadata) {
 		var i = aggMap.Iter()
 		if metaIterOption != nil {
 			i = aggMap.Iter(metaIterOption)
@@ -263,32 +426,51 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 			key := types.ExtendedKey(i.Key())
 			val := i.Val()
 			totals = totals.Add(val)
+
+			// labels are only assigned if they are part of the query. Otherwise, the traffic is
+			// aggregated across them (see below)
+			var row results.Row
 			if ts, hasTS := key.AttrTime(); hasTS {
-				rs[count].Labels.Timestamp = time.Unix(ts, 0)
+				row.Labels.Timestamp = time.Unix(ts, 0)
+			}
+			if stmt.LabelSelector.Iface {
+				row.Labels.Iface = iface
 			}
-			rs[count].Labels.Iface = iface
 
 			// the host ID and hostname are statically assigned since a goDB is inherently limited to the
 			// system it runs on. The two parameters never change during query execution
-			rs[count].Labels.HostID = hostID
-			rs[count].Labels.Hostname = hostname
+			if stmt.LabelSelector.HostID {
+				row.Labels.HostID = hostID
+			}
+			if stmt.LabelSelector.Hostname {
+				row.Labels.Hostname = hostname
+			}
 
 			if sip != nil {
-				rs[count].Attributes.SrcIP = types.RawIPToAddr(key.Key().GetSIP())
+				row.Attributes.SrcIP = types.RawIPToAddr(key.Key().GetSIP())
 			}
 			if dip != nil {
-				rs[count].Attributes.DstIP = types.RawIPToAddr(key.Key().GetDIP())
+				row.Attributes.DstIP = types.RawIPToAddr(key.Key().GetDIP())
 			}
 			if proto != nil {
-				rs[count].Attributes.IPProto = key.Key().GetProto()
+				row.Attributes.IPProto = key.Key().GetProto()
 			}
 			if dport != nil {
-				rs[count].Attributes.DstPort = types.PortToUint16(key.Key().GetDport())
+				row.Attributes.DstPort = types.PortToUint16(key.Key().GetDport())
+			}
+			if tag != nil {
+				row.Attributes.Tag = types.TagNameByID(key.Key().GetTag())
+			}
+			if ttlMin != nil {
+				row.Attributes.TTLMin = key.Key().GetTTLMin()
+			}
+			if ttlMax != nil {
+				row.Attributes.TTLMax = key.Key().GetTTLMax()
 			}
 
 			// assign / update counters
-			rs[count].Counters = rs[count].Counters.Add(val)
-			count++
+			row.Counters = row.Counters.Add(val)
+			rs = append(rs, row)
 		}
 
 		// Now is a good time to release memory one last time for the final processing step
@@ -298,13 +480,53 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 			aggMap.ClearFast()
 		}
 		runtime.GC()
+	})
+	if err != nil {
+		return res, fmt.Errorf("failed to merge spilled partial aggregates: %w", err)
+	}
+	if agg.spill.spilled() {
+		spillStats := agg.spill.stats
+		result.Summary.Timings.Spill = &spillStats
+	}
+
+	// the flows are aggregated per interface, hence the rows of multiple interfaces have to be
+	// merged if the interface isn't part of the query
+	if !stmt.LabelSelector.Iface && len(stmt.Ifaces) > 1 {
+		rowMap := make(results.RowsMap, len(rs))
+		rowMap.MergeRows(rs)
+		rs = rowMap.ToRows()
+	}
+
+	// the statistics per time bin merge the rows of all time bins, hence the rows are reduced to the
+	// ones subsequently filtered / sorted
+	if stmt.Stats {
+		rs = rs.BinStats(result.Summary.First, result.Summary.Last, time.Duration(goDB.DBWriteInterval)*time.Second)
+	}
+
+	// predicates on the counters (e.g. traffic ratios) can only be evaluated on the aggregated rows.
+	// The totals are restricted to the remaining rows, consistent with the query condition
+	if having := stmt.Predicate(); having != nil {
+		rs, totals = rs.Filter(having)
 	}
 
-	// Ensure that potentially unused pre-allocated rows are dropped
-	rs = rs[:count]
+	// the traffic by role is joined prior to sorting / limiting the number of rows since the rows
+	// of a host may be spread across the entire result
+	if stmt.Roles {
+		rs = rs.JoinRoles()
+	}
+	count := len(rs)
 
 	result.Summary.Totals = totals
 
+	// counters saturate instead of overflowing, which must be surfaced since the volumes are no longer exact
+	result.Summary.Saturated = totals.IsSaturated() || rs.Saturated()
+
+	// if requested, draw a random sample of the rows instead of sorting / limiting all of them. Only
+	// the (small) sample is sorted subsequently
+	if stmt.Sample > 0 {
+		rs, result.Summary.Sample = rs.Sample(stmt.Sample, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+
 	// sort the results
 	results.By(stmt.SortBy, stmt.Direction, stmt.SortAscending).Sort(rs)
 
@@ -322,41 +544,62 @@ func (qr *QueryRunner) RunStatement(ctx context.Context, stmt *query.Statement)
 	}
 	result.Summary.Hits.Displayed = len(rs)
 	result.Rows = rs
+
+	// provide the hostnames of the displayed IP addresses as they were at capture time (if recorded)
+	if len(hostnames) > 0 && (sip != nil || dip != nil) {
+		result.AddHostnames(func(ip netip.Addr) (string, bool) {
+			hostname, exists := hostnames[ip]
+			return hostname.Name, exists
+		})
+	}
+
+	// represent all timestamps in the requested time zone (if any)
+	if loc := stmt.Location(); loc != nil {
+		result.In(loc)
+	}
+	if stmt.FlowHash {
+		result.AddFlowHashes()
+	}
+	if stmt.CommunityID {
+		result.AddCommunityIDs(stmt.CommunityIDSeed)
+	}
 	return result, nil
 }
 
-func (qr *QueryRunner) runLiveQuery(ctx context.Context, mapChan chan hashmap.AggFlowMapWithMetadata, stmt *query.Statement) (wg *sync.WaitGroup) {
-	wg = new(sync.WaitGroup)
-
-	if !stmt.Live {
-		return
+// runLiveQuery puts the live data (if requested) on the output channel and returns the cut between the
+// live data and the data to be read from the DB
+func (qr *QueryRunner) runLiveQuery(ctx context.Context, mapChan chan hashmap.AggFlowMapWithMetadata, stmt *query.Statement) *results.Cut {
+	if !stmt.Live || qr.liveData == nil {
+		return nil
 	}
 
-	wg.Add(1)
-	go func() {
-		qr.captureManager.GetFlowMaps(ctx, goDB.QueryFilter(qr.query), mapChan, stmt.Ifaces...)
-		wg.Done()
-	}()
+	cut := qr.liveData.GetFlowMaps(ctx, goDB.QueryFilter(qr.query), mapChan, stmt.Ifaces...)
+	return &cut
+}
 
-	return
+// overlappingBlocks returns the range of block timestamps of all blocks overlapping the time range
+// [first, last], each block covering the write out interval preceding its timestamp
+func overlappingBlocks(first, last int64) (int64, int64) {
+	return first + 1, last + goDB.DBWriteInterval - 1
 }
 
-func createWorkManager(dbPath string, iface string, tfirst, tlast int64, query *goDB.Query, numProcessingUnits int) (workManager *goDB.DBWorkManager, nonempty bool, err error) {
-	workManager, err = goDB.NewDBWorkManager(query, dbPath, iface, numProcessingUnits)
+func createWorkManager(fsys storage.FS, dbPath string, iface string, tfirst, tlast int64, query *goDB.Query, numIOWorkers, numCPUWorkers int) (workManager *goDB.DBWorkManager, nonempty bool, err error) {
+	workManager, err = goDB.NewDBWorkManager(query, dbPath, iface, numIOWorkers)
 	if err != nil {
 		return nil, false, fmt.Errorf("could not initialize query work manager for interface '%s': %w", iface, err)
 	}
+	workManager = workManager.FS(fsys).CPUWorkers(numCPUWorkers)
 	nonempty, err = workManager.CreateWorkerJobs(tfirst, tlast)
 	return
 }
 
-func parseIfaceList(dbPath string, ifaceList string) ([]string, error) {
+func parseIfaceList(fsys storage.FS, dbPath string, ifaceList string) ([]string, error) {
 	if ifaceList == "" {
 		return nil, errors.New("no interface(s) specified")
 	}
 
 	if types.IsAnySelector(ifaceList) {
-		ifaces, err := info.GetInterfaces(dbPath)
+		ifaces, err := info.GetInterfacesFS(fsys, dbPath)
 		if err != nil {
 			return nil, err
 		}
@@ -5,9 +5,14 @@ import (
 	"errors"
 	"io"
 	"testing"
+	"time"
 
+	"github.com/els0r/goProbe/pkg/goDB"
 	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -125,6 +130,212 @@ func TestSimpleQuery(t *testing.T) {
 	}
 }
 
+func TestCounterSelection(t *testing.T) {
+	opts := []query.Option{query.WithFirst("1456428000"), query.WithLast("1456473000"), query.WithSortBy("packets"), query.WithNumResults(query.MaxResults), query.WithFormat("json")}
+
+	reference, err := NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs("sip,dip", "eth1", opts...).AddOutputs(io.Discard))
+	require.Nil(t, err)
+	require.NotEmpty(t, reference.Rows)
+
+	var tests = []struct {
+		counters string
+		expected func(types.Counters) types.Counters
+	}{
+		{"packets", func(c types.Counters) types.Counters {
+			return types.Counters{PacketsRcvd: c.PacketsRcvd, PacketsSent: c.PacketsSent}
+		}},
+		{"packets_in", func(c types.Counters) types.Counters {
+			return types.Counters{PacketsRcvd: c.PacketsRcvd}
+		}},
+		{"bytes_in,pkts_sent", func(c types.Counters) types.Counters {
+			return types.Counters{BytesRcvd: c.BytesRcvd, PacketsSent: c.PacketsSent}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.counters, func(t *testing.T) {
+			a := query.NewArgs("sip,dip", "eth1", append(opts, query.WithCounters(test.counters))...).AddOutputs(io.Discard)
+
+			res, err := NewQueryRunner(TestDB).Run(context.Background(), a)
+			require.Nil(t, err)
+			require.Equal(t, test.expected(reference.Summary.Totals), res.Summary.Totals)
+
+			// unselected counters are zero, all others are identical to the ones of the full query
+			expected := make(map[results.Attributes]types.Counters)
+			for _, row := range reference.Rows {
+				expected[row.Attributes] = test.expected(row.Counters)
+			}
+			actual := make(map[results.Attributes]types.Counters)
+			for _, row := range res.Rows {
+				actual[row.Attributes] = row.Counters
+			}
+			for attr, counters := range actual {
+				require.Equal(t, expected[attr], counters, attr)
+			}
+		})
+	}
+}
+
+func TestLabelGroupBy(t *testing.T) {
+	opts := []query.Option{query.WithFirst("1456358400"), query.WithLast("1456473000"), query.WithNumResults(query.MaxResults), query.WithFormat("json")}
+
+	perIface, err := NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs("sip,iface", "eth0,eth1", opts...).AddOutputs(io.Discard))
+	require.Nil(t, err)
+	require.NotEmpty(t, perIface.Rows)
+
+	expected := make(map[results.Attributes]types.Counters)
+	for _, row := range perIface.Rows {
+		require.NotEmpty(t, row.Labels.Iface)
+		require.Empty(t, row.Labels.Hostname)
+		expected[row.Attributes] = expected[row.Attributes].Add(row.Counters)
+	}
+
+	// without the interface label, the traffic of both interfaces is aggregated
+	for _, queryType := range []string{"sip", "sip,hostname"} {
+		t.Run(queryType, func(t *testing.T) {
+			res, err := NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs(queryType, "eth0,eth1", opts...).AddOutputs(io.Discard))
+			require.Nil(t, err)
+			require.Equal(t, perIface.Summary.Totals, res.Summary.Totals)
+			require.Len(t, res.Rows, len(expected))
+
+			for _, row := range res.Rows {
+				require.Empty(t, row.Labels.Iface)
+				require.Equal(t, queryType == "sip", row.Labels.Hostname == "")
+				require.Equal(t, expected[row.Attributes], row.Counters, row.Attributes)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	opts := []query.Option{query.WithFirst("1456428000"), query.WithLast("1456473000"), query.WithNumResults(query.MaxResults), query.WithFormat("json"), query.WithExplain()}
+
+	res, err := NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs("sip,dip", "eth1", opts...).AddOutputs(io.Discard))
+	require.Nil(t, err)
+	require.Equal(t, types.StatusOK, res.Status.Code)
+	require.Empty(t, res.Rows)
+	require.NotNil(t, res.Plan)
+	require.Equal(t, []string{"sip", "dip", "bytes_rcvd", "bytes_sent", "pkts_rcvd", "pkts_sent"}, res.Plan.Columns)
+	require.Len(t, res.Plan.Ifaces, 1)
+
+	plan := res.Plan.Ifaces[0]
+	require.Equal(t, "eth1", plan.Iface)
+	require.NotEmpty(t, plan.Days)
+	require.Greater(t, plan.Blocks, 0)
+	require.Greater(t, plan.Entries, uint64(0))
+	require.Zero(t, plan.EntriesSkipped)
+	require.NotZero(t, plan.BytesRead)
+	require.NotZero(t, plan.MemAggregationMax)
+
+	// limiting the query to IPv4 skips all IPv6 entries of the same blocks
+	res, err = NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs("sip,dip", "eth1", append(opts, query.WithCondition("sip = 10.0.0.1"))...).AddOutputs(io.Discard))
+	require.Nil(t, err)
+	require.NotNil(t, res.Plan)
+	require.Len(t, res.Plan.Ifaces, 1)
+
+	planV4 := res.Plan.Ifaces[0]
+	require.Equal(t, plan.Blocks, planV4.Blocks)
+	require.Equal(t, plan.Entries, planV4.Entries+planV4.EntriesSkipped)
+	require.Contains(t, res.Plan.Pushdowns, "ip version: only IPv4 entries are evaluated")
+}
+
+type staticLiveData struct {
+	cut results.Cut
+}
+
+func (s staticLiveData) GetFlowMaps(_ context.Context, _ goDB.FilterFn, _ chan<- hashmap.AggFlowMapWithMetadata, _ ...string) results.Cut {
+	return s.cut
+}
+
+func TestLiveQueryCut(t *testing.T) {
+	cutTimestamp := time.Unix(1456463000, 0)
+
+	a := query.NewArgs("time", "eth1", query.WithFirst("1456358400"), query.WithNumResults(query.MaxResults), query.WithFormat("json")).AddOutputs(io.Discard)
+	a.Live = true
+
+	res, err := NewQueryRunnerWithLiveData(TestDB, staticLiveData{
+		cut: results.Cut{Generation: 42, Timestamp: cutTimestamp},
+	}).Run(context.Background(), a)
+	require.Nil(t, err)
+	require.Equal(t, types.StatusOK, res.Status.Code)
+	require.NotEmpty(t, res.Rows)
+
+	// blocks beyond the cut must not be read from the DB (since their flows are part of the live data)
+	require.Equal(t, &results.Cut{Generation: 42, Timestamp: cutTimestamp}, res.Summary.Cut)
+	require.False(t, res.Summary.Last.After(cutTimestamp))
+	for _, row := range res.Rows {
+		require.False(t, row.Labels.Timestamp.After(cutTimestamp), row.Labels.Timestamp)
+	}
+
+	// without live data, no cut is applied / reported
+	a.Live = false
+	a.Last = ""
+	res, err = NewQueryRunner(TestDB).Run(context.Background(), a)
+	require.Nil(t, err)
+	require.Nil(t, res.Summary.Cut)
+	require.True(t, res.Summary.Last.After(cutTimestamp))
+}
+
+func TestTimeAlign(t *testing.T) {
+
+	// the time range cuts through the blocks written at 1456461275 and 1456466675
+	var (
+		first, last = time.Unix(1456461175, 0), time.Unix(1456466575, 0)
+		opts        = []query.Option{
+			query.WithFirst("1456461175"), query.WithLast("1456466575"),
+			query.WithDirectionSum(), query.WithNumResults(query.MaxResults), query.WithFormat("json"),
+		}
+	)
+
+	run := func(opts ...query.Option) (*results.Result, map[int64]types.Counters) {
+		res, err := NewQueryRunner(TestDB).Run(context.Background(), query.NewArgs("time", "eth1", opts...).AddOutputs(io.Discard))
+		require.Nil(t, err)
+		require.Equal(t, types.StatusOK, res.Status.Code)
+
+		bins := make(map[int64]types.Counters)
+		for _, row := range res.Rows {
+			bins[row.Labels.Timestamp.Unix()] = row.Counters
+		}
+		return res, bins
+	}
+
+	// by default, only the blocks written within the time range are queried
+	res, bins := run(opts...)
+	require.Nil(t, res.Summary.Alignment)
+	require.Contains(t, bins, int64(1456461275))
+	require.NotContains(t, bins, int64(1456466675))
+
+	// snapping queries all blocks overlapping the time range, reporting the effective time range
+	snapped, snappedBins := run(append(opts, query.WithTimeAlign(query.TimeAlignSnap))...)
+	require.Equal(t, &results.Alignment{
+		Mode:      query.TimeAlignSnap,
+		Requested: results.TimeRange{First: first, Last: last},
+	}, snapped.Summary.Alignment)
+	require.Equal(t, time.Unix(1456461275-goDB.DBWriteInterval, 0), snapped.Summary.First)
+	require.Equal(t, time.Unix(1456466675, 0), snapped.Summary.Last)
+	require.Len(t, snappedBins, len(bins)+1)
+	require.Contains(t, snappedBins, int64(1456466675))
+
+	// interpolating scales the blocks cut by the time range, reporting the requested time range
+	interpolated, interpolatedBins := run(append(opts, query.WithTimeAlign(query.TimeAlignInterpolate))...)
+	require.Equal(t, query.TimeAlignInterpolate, interpolated.Summary.Alignment.Mode)
+	require.Equal(t, uint64(2), interpolated.Summary.Alignment.InterpolatedBlocks)
+	require.Equal(t, first, interpolated.Summary.First)
+	require.Equal(t, last, interpolated.Summary.Last)
+	require.Len(t, interpolatedBins, len(snappedBins))
+
+	for ts, counters := range snappedBins {
+		switch ts {
+		case 1456461275:
+			require.InEpsilon(t, float64(counters.SumBytes())/3, float64(interpolatedBins[ts].SumBytes()), 0.01)
+		case 1456466675:
+			require.InEpsilon(t, float64(counters.SumBytes())*2/3, float64(interpolatedBins[ts].SumBytes()), 0.01)
+		default:
+			require.Equal(t, counters, interpolatedBins[ts], ts)
+		}
+	}
+}
+
 func TestInterfaceValidation(t *testing.T) {
 
 	// create args
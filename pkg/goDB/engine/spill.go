@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/klauspost/compress/zstd"
+	"github.com/zeebo/xxh3"
+)
+
+// spillPartitions denotes the number of partitions (by hash of the flow key) partial aggregates
+// are spilled to. Since partitions are merged one at a time, the memory required for the final
+// merge is reduced to a fraction of the one required to aggregate all flows at once
+const spillPartitions = 16
+
+// spiller writes partial aggregates to temporary, compressed files whenever the aggregation
+// memory budget is exceeded and merges them again once all flows have been aggregated
+type spiller struct {
+	budget uint64
+	dir    string
+
+	ifaces map[string]struct{}
+	stats  results.SpillStats
+
+	buf []byte
+}
+
+// newSpiller instantiates a spiller for the given memory budget (in bytes). Its files are placed
+// in a temporary directory (below $TMPDIR), which is only created upon the first spill
+func newSpiller(budget uint64) *spiller {
+	return &spiller{
+		budget: budget,
+		ifaces: make(map[string]struct{}),
+	}
+}
+
+// exceeded returns if the memory allocated by the maps exceeds the budget
+func (s *spiller) exceeded(maps hashmap.NamedAggFlowMapWithMetadata) bool {
+	return s != nil && maps.Size() > s.budget
+}
+
+// spilled returns if any partial aggregates were written to disk
+func (s *spiller) spilled() bool {
+	return s != nil && s.stats.Spills > 0
+}
+
+// spill writes all entries of the maps to disk (appending to the files of previous spills) and
+// clears the maps
+func (s *spiller) spill(maps hashmap.NamedAggFlowMapWithMetadata, isLowMem bool) error {
+	if maps.Len() == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "goquery-spill-")
+		if err != nil {
+			return fmt.Errorf("failed to create spill directory: %w", err)
+		}
+		s.dir = dir
+	}
+
+	for iface, aggMap := range maps {
+		if aggMap.Len() == 0 {
+			continue
+		}
+		if err := s.spillMap(iface, aggMap.AggFlowMap); err != nil {
+			return err
+		}
+
+		// Replace the map by an empty one to release the memory
+		if isLowMem {
+			aggMap.Clear()
+		} else {
+			aggMap.ClearFast()
+		}
+		newMap := hashmap.NewAggFlowMapWithMetadata()
+		newMap.Interface = aggMap.Interface
+		maps[iface] = &newMap
+	}
+
+	// Determine the overall size of the spill files (which are appended to by each spill)
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spill directory: %w", err)
+	}
+	s.stats.Bytes = 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat spill file: %w", err)
+		}
+		s.stats.Bytes += uint64(info.Size())
+	}
+
+	s.stats.Spills++
+	s.stats.Duration += time.Since(start)
+
+	return nil
+}
+
+func (s *spiller) spillMap(iface string, aggMap *hashmap.AggFlowMap) (err error) {
+	s.ifaces[iface] = struct{}{}
+
+	var (
+		files    [spillPartitions]*os.File
+		encoders [spillPartitions]*zstd.Encoder
+	)
+	defer func() {
+		for i := 0; i < spillPartitions; i++ {
+			if encoders[i] != nil {
+				err = errors.Join(err, encoders[i].Close())
+			}
+			if files[i] != nil {
+				err = errors.Join(err, files[i].Close())
+			}
+		}
+	}()
+
+	// Each spill appends a new zstd frame to the file of the respective partition. Concatenated
+	// frames are decoded transparently when reading the file
+	for i := 0; i < spillPartitions; i++ {
+		files[i], err = os.OpenFile(s.path(iface, i), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %w", err)
+		}
+		encoders[i], err = zstd.NewWriter(files[i],
+			zstd.WithEncoderLevel(zstd.SpeedFastest),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create spill file encoder: %w", err)
+		}
+	}
+
+	for it := aggMap.Iter(); it.Next(); {
+		key, val := it.Key(), it.Val()
+
+		// Each entry is encoded as the length of the key, the key itself and its counters (varint encoded)
+		s.buf = append(s.buf[:0], byte(len(key)))
+		s.buf = append(s.buf, key...)
+		s.buf = binary.AppendUvarint(s.buf, val.BytesRcvd)
+		s.buf = binary.AppendUvarint(s.buf, val.BytesSent)
+		s.buf = binary.AppendUvarint(s.buf, val.PacketsRcvd)
+		s.buf = binary.AppendUvarint(s.buf, val.PacketsSent)
+		if _, err = encoders[xxh3.Hash(key)%spillPartitions].Write(s.buf); err != nil {
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+		s.stats.Entries++
+	}
+
+	return nil
+}
+
+// merge reads back all partitions, calling fn on the map obtained from merging all partial
+// aggregates of each partition. Only one partition is held in memory at any time, hence fn
+// is expected to release the map once done with it
+func (s *spiller) merge(fn func(iface string, aggMap *hashmap.AggFlowMapWithMetadata)) error {
+	start := time.Now()
+	defer func() {
+		s.stats.Duration += time.Since(start)
+	}()
+
+	ifaces := make([]string, 0, len(s.ifaces))
+	for iface := range s.ifaces {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	for _, iface := range ifaces {
+		for i := 0; i < spillPartitions; i++ {
+			aggMap, err := s.readPartition(iface, i)
+			if err != nil {
+				return err
+			}
+			fn(iface, &aggMap)
+		}
+	}
+
+	return nil
+}
+
+func (s *spiller) readPartition(iface string, partition int) (hashmap.AggFlowMapWithMetadata, error) {
+	aggMap := hashmap.NewAggFlowMapWithMetadata()
+	aggMap.Interface = iface
+
+	f, err := os.Open(s.path(iface, partition))
+	if err != nil {
+		return aggMap, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer f.Close()
+
+	dec, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	if err != nil {
+		return aggMap, fmt.Errorf("failed to create spill file decoder: %w", err)
+	}
+	defer dec.Close()
+
+	var (
+		r   = bufio.NewReader(dec)
+		key = make([]byte, 0, 256)
+		val [4]uint64
+	)
+	for {
+		keyLen, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return aggMap, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		key = key[:keyLen]
+		if _, err = io.ReadFull(r, key); err != nil {
+			return aggMap, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		for j := range val {
+			if val[j], err = binary.ReadUvarint(r); err != nil {
+				return aggMap, fmt.Errorf("failed to read spill file: %w", err)
+			}
+		}
+
+		// Since keys are partitioned by their hash, all partial aggregates of a flow reside in the
+		// same partition and can be merged right away
+		aggMap.SetOrUpdate(key, types.ExtendedKey(key).IsIPv4(), val[0], val[1], val[2], val[3])
+	}
+
+	return aggMap, nil
+}
+
+// close removes all spill files
+func (s *spiller) close() error {
+	if s == nil || s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func (s *spiller) path(iface string, partition int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%02d.zst", iface, partition))
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"os"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateSpill(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	ifaces := []string{"eth0", "eth1"}
+	genMaps := func() chan hashmap.AggFlowMapWithMetadata {
+		mapChan := make(chan hashmap.AggFlowMapWithMetadata, 64)
+		for i := 0; i < 32; i++ {
+			aggMap := hashmap.NewAggFlowMapWithMetadata()
+			aggMap.Interface = ifaces[i%len(ifaces)]
+
+			// consecutive maps partially overlap, requiring their entries to be merged
+			for j := i * 100; j < i*100+500; j++ {
+				v4Key := types.NewV4Key([]byte{10, 0, byte(j >> 8), byte(j)}, []byte{10, 0, 0, 1}, []byte{0, 80}, 6)
+				aggMap.SetOrUpdate(v4Key, true, uint64(j), 1, 2, 3)
+				v6Key := types.NewV6Key(make([]byte, 16), []byte{0x20, 1, 0xd, 0xb8, 14: byte(j >> 8), 15: byte(j)}, []byte{1, 187}, 17)
+				aggMap.SetOrUpdate(v6Key.Extend(int64(j)), false, 4, 5, uint64(j), 7)
+			}
+			mapChan <- aggMap
+		}
+		close(mapChan)
+		return mapChan
+	}
+
+	collect := func(agg aggregateResult) map[string]map[string]types.Counters {
+		require.Nil(t, agg.err)
+		flows := make(map[string]map[string]types.Counters)
+		require.Nil(t, agg.forEach(func(iface string, aggMap *hashmap.AggFlowMapWithMetadata) {
+			if flows[iface] == nil {
+				flows[iface] = make(map[string]types.Counters)
+			}
+			for i := aggMap.Iter(); i.Next(); {
+				_, exists := flows[iface][string(i.Key())]
+				require.False(t, exists, "duplicate flow after merge")
+				flows[iface][string(i.Key())] = i.Val()
+			}
+		}))
+		return flows
+	}
+
+	expected := collect(<-aggregate(genMaps(), ifaces, false, nil))
+	require.Len(t, expected, 2)
+
+	// a budget of a single byte forces a spill after each map
+	spill := newSpiller(1)
+	agg := <-aggregate(genMaps(), ifaces, false, spill)
+	require.True(t, spill.spilled())
+	require.Equal(t, expected, collect(agg))
+
+	require.Equal(t, 32, spill.stats.Spills)
+	require.NotZero(t, spill.stats.Entries)
+	require.NotZero(t, spill.stats.Bytes)
+	require.NotZero(t, spill.stats.Duration)
+
+	// all spill files are removed once the result is cleared
+	dir := spill.dir
+	require.DirExists(t, dir)
+	require.Nil(t, agg.clear())
+	_, err := os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,128 @@
+package goDB
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// valWidth denotes the (raw) size of the counters stored for each aggregated flow
+const valWidth = 4 * 8
+
+// Columns returns the names of all columns read by the query (projection)
+func (q *Query) Columns() []string {
+	cols := make([]string, len(q.columnIndices))
+	for i, colIdx := range q.columnIndices {
+		cols[i] = types.ColumnFileNames[colIdx]
+	}
+	return cols
+}
+
+// Pushdowns returns a human-readable description of all filters that are evaluated before reading
+// individual flow entries (or that avoid reading them altogether)
+func (q *Query) Pushdowns() []string {
+	pushdowns := []string{"time range: day partitions / blocks outside of the queried interval are skipped"}
+	switch q.ipVersion {
+	case types.IPVersionV4:
+		pushdowns = append(pushdowns, "ip version: only IPv4 entries are evaluated")
+	case types.IPVersionV6:
+		pushdowns = append(pushdowns, "ip version: only IPv6 entries are evaluated")
+	}
+	if !q.counters.IsAll() {
+		pushdowns = append(pushdowns, fmt.Sprintf("counters: only %s columns are read", q.counters))
+	}
+	return pushdowns
+}
+
+// Plan determines which day partitions and blocks would be read by the query for the given time
+// range (and at which cost) by inspecting the metadata of the day partitions only. Contrary to
+// CreateWorkerJobs, no workloads are created
+func (w *DBWorkManager) Plan(tfirst int64, tlast int64) (*results.IfacePlan, error) {
+	plan := &results.IfacePlan{Iface: w.iface}
+
+	keyWidthV4, keyWidthV6 := types.KeyWidthIPv4, types.KeyWidthIPv6
+	if w.query.hasAttrTime {
+		keyWidthV4 += types.TimestampWidth
+		keyWidthV6 += types.TimestampWidth
+	}
+
+	var memBuffersPerWorker, maxBlockBytes uint64
+	walkFunc := func(_ int, dayTimestamp int64) error {
+		dir := gpfile.NewDir(w.dbIfaceDir, dayTimestamp, gpfile.ModeRead, gpfile.WithFS(w.fsys))
+		if err := dir.Open(); err != nil {
+			return fmt.Errorf("failed to open GPDir %s to determine query plan: %w", dir.Path(), err)
+		}
+		defer dir.Close()
+
+		plan.Days = append(plan.Days, time.Unix(dayTimestamp, 0).UTC())
+
+		var dirBytesRead, maxBlockBytesDecoded uint64
+		for b, block := range dir.BlockMetadata[0].Blocks() {
+			if block.Timestamp < tfirst || block.Timestamp > tlast {
+				plan.BlocksSkipped++
+				continue
+			}
+			plan.Blocks++
+
+			numV4Entries, numV6Entries := dir.NumIPv4EntriesAtIndex(b), dir.NumIPv6EntriesAtIndex(b)
+			switch w.query.ipVersion {
+			case types.IPVersionV4:
+				plan.EntriesSkipped += numV6Entries
+				numV6Entries = 0
+			case types.IPVersionV6:
+				plan.EntriesSkipped += numV4Entries
+				numV4Entries = 0
+			}
+			plan.Entries += numV4Entries + numV6Entries
+			plan.MemAggregationMax += numV4Entries*uint64(keyWidthV4+valWidth) + numV6Entries*uint64(keyWidthV6+valWidth)
+
+			var blockBytesDecoded uint64
+			for _, colIdx := range w.query.columnIndices {
+				header := dir.BlockMetadata[colIdx]
+				if header == nil || b >= len(header.BlockList) {
+					continue
+				}
+				dirBytesRead += uint64(header.BlockList[b].Len)
+				blockBytesDecoded += uint64(header.BlockList[b].RawLen)
+			}
+			plan.BytesDecoded += blockBytesDecoded
+			if blockBytesDecoded > maxBlockBytesDecoded {
+				maxBlockBytesDecoded = blockBytesDecoded
+			}
+		}
+		plan.BytesRead += dirBytesRead
+
+		// Unless running in low-memory or mmap mode, the column files of a day partition are read
+		// into memory as a whole (in addition to the buffer for the decompressed block)
+		dirBuffers := maxBlockBytesDecoded
+		if !w.query.lowMem && !w.query.mmap {
+			dirBuffers += dirBytesRead
+		}
+		if dirBuffers > memBuffersPerWorker {
+			memBuffersPerWorker = dirBuffers
+		}
+		if maxBlockBytesDecoded > maxBlockBytes {
+			maxBlockBytes = maxBlockBytesDecoded
+		}
+		return nil
+	}
+	numDirs, err := w.walkDB(tfirst, tlast, walkFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Workloads = (numDirs + WorkBulkSize - 1) / WorkBulkSize
+	workers := w.numProcessingUnits
+	if plan.Workloads < workers {
+		workers = plan.Workloads
+	}
+	plan.MemBuffers = uint64(workers) * memBuffersPerWorker
+
+	// Decoded blocks are copied for the CPU processing units, both while being queued and evaluated
+	plan.MemBuffers += uint64(w.numCPUWorkers*(blockQueueDepth+1)) * maxBlockBytes
+
+	return plan, nil
+}
@@ -9,6 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/conditions/node"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
 	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/els0r/goProbe/pkg/types/hashmap"
@@ -26,6 +29,7 @@ type testCase struct {
 	iface                string
 	queryStart, queryEnd time.Time
 	numWorkers           int
+	numCPUWorkers        int
 	nExpectedWorkloads   uint64
 	nExpectedDays        int
 
@@ -128,6 +132,100 @@ func TestWorkload(t *testing.T) {
 			testWorkload(t, c, true)  // dry-run (to ascertain correct number of workloads / directories)
 			testWorkload(t, c, false) // actual processing
 		})
+		t.Run(fmt.Sprintf("%s_2io_8cpu_workers", c.name), func(t *testing.T) {
+			c.numWorkers, c.numCPUWorkers = 2, 8
+			testWorkload(t, c, false)
+		})
+	}
+}
+
+func TestTaggedFlows(t *testing.T) {
+
+	// Initialize temporary test directory
+	testPath, err := os.MkdirTemp("/tmp", "goDB")
+	require.Nil(t, err)
+	defer func(t *testing.T) {
+		require.Nil(t, os.RemoveAll(testPath))
+	}(t)
+
+	voip, err := types.InternTag("voip")
+	require.Nil(t, err)
+	backup, err := types.InternTag("backup")
+	require.Nil(t, err)
+
+	// Tag every second IPv4 flow and every third IPv6 flow
+	m := generateFlows()
+	tagged := hashmap.NewAggFlowMap()
+	for it := m.PrimaryMap.Iter(); it.Next(); {
+		key := types.Key(it.Key()).Clone()
+		if key.GetDport()[0]%2 == 0 {
+			key.PutTag(voip)
+		}
+		tagged.PrimaryMap.Set(key, it.Val())
+	}
+	for it := m.SecondaryMap.Iter(); it.Next(); {
+		key := types.Key(it.Key()).Clone()
+		if key.GetDport()[0]%3 == 0 {
+			key.PutTag(backup)
+		}
+		tagged.SecondaryMap.Set(key, it.Val())
+	}
+
+	// Write untagged flows on the previous day (i.e. without any tag column) in order to ascertain
+	// that directories written prior to tagging can still be queried
+	timestamp := time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC)
+	require.Nil(t, NewDBWriter(testPath, "eth0", encoders.EncoderTypeLZ4).Write(m, capturetypes.CaptureStats{}, timestamp.Add(-time.Hour).Unix()))
+	require.Nil(t, NewDBWriter(testPath, "eth0", encoders.EncoderTypeLZ4).Write(tagged, capturetypes.CaptureStats{}, timestamp.Unix()))
+
+	var tests = []struct {
+		name        string
+		conditional string
+		matches     func(tag string) bool
+	}{
+		{"all", "", func(string) bool { return true }},
+		{"tagged", "tag = voip", func(tag string) bool { return tag == "voip" }},
+		{"not tagged", "tag != backup", func(tag string) bool { return tag != "backup" }},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+
+			// Compute the expected number of packets per tag from the written flows
+			expected := make(map[string]uint64)
+			for _, flows := range []*hashmap.Map{tagged.PrimaryMap, tagged.SecondaryMap, m.PrimaryMap, m.SecondaryMap} {
+				for it := flows.Iter(); it.Next(); {
+					if tag := types.TagNameByID(types.Key(it.Key()).GetTag()); test.matches(tag) {
+						expected[tag] += it.Val().PacketsRcvd + it.Val().PacketsSent
+					}
+				}
+			}
+
+			var cond node.Node
+			if test.conditional != "" {
+				cond, _, err = node.ParseAndInstrument(test.conditional, time.Second)
+				require.Nil(t, err)
+			}
+			workMgr, err := NewDBWorkManager(NewQuery([]types.Attribute{types.TagAttribute{}}, cond, types.LabelSelector{}), testPath, "eth0", 1)
+			require.Nil(t, err)
+
+			nonempty, err := workMgr.CreateWorkerJobs(timestamp.Add(-2*time.Hour).Unix(), timestamp.Add(time.Hour).Unix())
+			require.Nil(t, err)
+			require.True(t, nonempty)
+
+			mapChan := make(chan hashmap.AggFlowMapWithMetadata, 2)
+			workMgr.ExecuteWorkerReadJobs(context.Background(), mapChan)
+			close(mapChan)
+
+			// Since no IP attributes are queried, flows are not separated by IP protocol version
+			actual := make(map[string]uint64)
+			for aggMap := range mapChan {
+				for it := aggMap.Iter(); it.Next(); {
+					actual[types.TagNameByID(types.Key(it.Key()).GetTag())] += it.Val().PacketsRcvd + it.Val().PacketsSent
+				}
+			}
+			require.Equal(t, expected, actual)
+		})
 	}
 }
 
@@ -138,7 +236,8 @@ func populateTestDir(t *testing.T, basePath, iface string, timestamp time.Time)
 	f := gpfile.NewDir(testPath, timestamp.Unix(), gpfile.ModeWrite)
 	require.Nil(t, f.Open())
 
-	data, update := dbData(generateFlows())
+	data, update, err := dbData(f.Metadata, generateFlows())
+	require.Nil(t, err)
 	require.Nil(t, f.WriteBlocks(timestamp.Unix()+300, gpfile.TrafficMetadata{
 		NumV4Entries: update.Traffic.NumV4Entries,
 		NumV6Entries: update.Traffic.NumV6Entries,
@@ -168,6 +267,58 @@ func generateFlows() *hashmap.AggFlowMap {
 	return &m
 }
 
+// runWorkload runs the query of a test case, returning its partial results
+func runWorkload(t *testing.T, c testCase) []hashmap.AggFlowMapWithMetadata {
+	workMgr, err := NewDBWorkManager(NewQuery([]types.Attribute{
+		types.SIPAttribute{},
+		types.DIPAttribute{},
+		types.DportAttribute{},
+		types.ProtoAttribute{}}, nil, types.LabelSelector{}), c.path, c.iface, c.numWorkers)
+	require.Nil(t, err)
+	workMgr = workMgr.CPUWorkers(c.numCPUWorkers)
+
+	nonempty, err := workMgr.CreateWorkerJobs(c.queryStart.Unix(), c.queryEnd.Unix())
+	require.Nil(t, err)
+	require.True(t, nonempty)
+
+	mapChan := make(chan hashmap.AggFlowMapWithMetadata, 1024)
+	workMgr.ExecuteWorkerReadJobs(context.Background(), mapChan)
+	close(mapChan)
+
+	results := make([]hashmap.AggFlowMapWithMetadata, 0, len(mapChan))
+	for aggMap := range mapChan {
+		results = append(results, aggMap)
+	}
+	return results
+}
+
+// mergeAggFlowMaps aggregates all partial results of a query into a single map
+func mergeAggFlowMaps(results []hashmap.AggFlowMapWithMetadata) *hashmap.AggFlowMap {
+	merged := hashmap.NewAggFlowMap()
+	for _, result := range results {
+		merged.Merge(*result.AggFlowMap)
+	}
+	return merged
+}
+
+// requireEqualAggFlowMaps asserts that two maps hold the same entries (with the same counters)
+func requireEqualAggFlowMaps(t *testing.T, expected, actual *hashmap.AggFlowMap) {
+	t.Helper()
+
+	require.Equal(t, expected.PrimaryMap.Len(), actual.PrimaryMap.Len())
+	require.Equal(t, expected.SecondaryMap.Len(), actual.SecondaryMap.Len())
+	for _, maps := range [][2]*hashmap.Map{
+		{expected.PrimaryMap, actual.PrimaryMap},
+		{expected.SecondaryMap, actual.SecondaryMap},
+	} {
+		for it := maps[0].Iter(); it.Next(); {
+			val, exists := maps[1].Get(it.Key())
+			require.True(t, exists)
+			require.Equal(t, it.Val(), val)
+		}
+	}
+}
+
 func testWorkload(t *testing.T, c testCase, dryRun bool) {
 
 	// Instantiate a new DBWorkManager
@@ -182,6 +333,7 @@ func testWorkload(t *testing.T, c testCase, dryRun bool) {
 		require.EqualError(t, err, c.expectedErr.Error())
 		return
 	}
+	workMgr = workMgr.CPUWorkers(c.numCPUWorkers)
 
 	// Create the workloads
 	nonempty, err := workMgr.CreateWorkerJobs(
@@ -215,12 +367,50 @@ func testWorkload(t *testing.T, c testCase, dryRun bool) {
 			workMgr.ExecuteWorkerReadJobs(context.Background(), mapChan)
 			close(mapChan)
 
-			// Perform sanity checks on aggregated data
-			require.Equal(t, int(c.nExpectedWorkloads), len(mapChan))
+			// Perform sanity checks on aggregated data. Each CPU worker transmits its partial result
+			// per bulk of day partitions, hence the number of results only matches the number of workloads
+			// if there is a single one. Irrespective of the split, the aggregate of all partial results
+			// must match the result of a single worker
+			if c.numWorkers == 1 && c.numCPUWorkers <= 1 {
+				require.Equal(t, int(c.nExpectedWorkloads), len(mapChan))
+			}
+			results := make([]hashmap.AggFlowMapWithMetadata, 0, len(mapChan))
 			for aggMap := range mapChan {
 				require.Equal(t, testNv4, aggMap.PrimaryMap.Len())
 				require.Equal(t, testNv6, aggMap.SecondaryMap.Len())
+				results = append(results, aggMap)
+			}
+			if c.numWorkers > 1 || c.numCPUWorkers > 1 {
+				c.numWorkers, c.numCPUWorkers = 1, 1
+				requireEqualAggFlowMaps(t, mergeAggFlowMaps(runWorkload(t, c)), mergeAggFlowMaps(results))
 			}
+
+			// All directories must have been accounted for in the progress
+			progress := workMgr.Progress()
+			require.Equal(t, c.nExpectedDays, progress.DirsTotal)
+			require.Equal(t, c.nExpectedDays, progress.DirsProcessed)
+			require.NotZero(t, progress.BytesProcessed)
+			require.NotZero(t, progress.RowsAggregated)
 		}
 	}
 }
+
+func TestInterpolation(t *testing.T) {
+	var tests = []struct {
+		timestamp, first, last int64
+		expectedOverlap        int64
+	}{
+		{1000, 500, 2000, DBWriteInterval},
+		{1000, 900, 2000, 100},
+		{1000, 500, 750, 50},
+		{1000, 750, 850, 100},
+		{1000, 1000, 2000, 0},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.expectedOverlap, blockOverlap(test.timestamp, test.first, test.last), test)
+	}
+
+	values := []uint64{0, 1, 2, 300, 1<<64 - 1}
+	scaleCounters(values, 100, 300)
+	require.Equal(t, []uint64{0, 0, 1, 100, (1<<64 - 1) / 3}, values)
+}
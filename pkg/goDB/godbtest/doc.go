@@ -0,0 +1,10 @@
+// Package godbtest provides utilities for testing against goDB without touching the filesystem,
+// most notably an in-memory implementation of the file system underlying the DB. It can be used
+// to run a full capture -> rotation -> query loop entirely in memory, e.g.
+//
+//	fsys := godbtest.NewMemFS()
+//	handler := writeout.NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(fsys)
+//	manager := capture.NewManager(handler)
+//	...
+//	runner := engine.NewQueryRunnerWithLiveData("/godb", manager).WithFS(fsys)
+package godbtest
@@ -0,0 +1,387 @@
+package godbtest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+)
+
+var errIsDir = errors.New("is a directory")
+
+// MemFS implements an in-memory file system suitable for use as goDB backend
+type MemFS struct {
+	nodes map[string]*memNode
+	nTemp uint64
+	sync.RWMutex
+}
+
+type memNode struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS instantiates a new (empty) in-memory file system
+func NewMemFS() *MemFS {
+	return &MemFS{
+		nodes: make(map[string]*memNode),
+	}
+}
+
+// OpenFile opens the named file (c.f. os.OpenFile)
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (storage.File, error) {
+	name = filepath.Clean(name)
+
+	m.Lock()
+	defer m.Unlock()
+
+	node, exists := m.nodes[name]
+	if !exists {
+		if flag&os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		if !m.isDir(filepath.Dir(name)) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		node = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[name] = node
+	} else if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+
+	if node.mode.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+	if flag&os.O_TRUNC != 0 {
+		node.data = node.data[:0]
+		node.modTime = time.Now()
+	}
+
+	return &memFile{
+		fsys: m,
+		name: name,
+		node: node,
+		flag: flag,
+	}, nil
+}
+
+// CreateTemp creates a new temporary file in directory dir (c.f. os.CreateTemp)
+func (m *MemFS) CreateTemp(dir, pattern string) (storage.File, error) {
+	m.Lock()
+	m.nTemp++
+	suffix := strconv.FormatUint(m.nTemp, 10)
+	m.Unlock()
+
+	name := pattern + suffix
+	if idx := strings.LastIndex(pattern, "*"); idx >= 0 {
+		name = pattern[:idx] + suffix + pattern[idx+1:]
+	}
+
+	return m.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+}
+
+// ReadDir reads the named directory, returning all its entries sorted by filename (c.f. os.ReadDir)
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	name = filepath.Clean(name)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	if !m.isDir(name) {
+		if _, exists := m.nodes[name]; exists {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []fs.DirEntry
+	for path, node := range m.nodes {
+		if path != name && filepath.Dir(path) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(node.info(path)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// Stat returns a FileInfo describing the named file (c.f. os.Stat)
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	name = filepath.Clean(name)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	if isRoot(name) {
+		return (&memNode{mode: fs.ModeDir | 0755}).info(name), nil
+	}
+	node, exists := m.nodes[name]
+	if !exists {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return node.info(name), nil
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents (c.f. os.MkdirAll)
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	path = filepath.Clean(path)
+
+	m.Lock()
+	defer m.Unlock()
+
+	return m.mkdirAll(path, perm)
+}
+
+// Remove removes the named file or (empty) directory (c.f. os.Remove)
+func (m *MemFS) Remove(name string) error {
+	name = filepath.Clean(name)
+
+	m.Lock()
+	defer m.Unlock()
+
+	node, exists := m.nodes[name]
+	if !exists {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.mode.IsDir() {
+		for path := range m.nodes {
+			if path != name && filepath.Dir(path) == name {
+				return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+
+	delete(m.nodes, name)
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath (c.f. os.Rename)
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+
+	m.Lock()
+	defer m.Unlock()
+
+	node, exists := m.nodes[oldpath]
+	if !exists {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if node.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errIsDir}
+	}
+	if target, exists := m.nodes[newpath]; exists && target.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errIsDir}
+	}
+	if !m.isDir(filepath.Dir(newpath)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+
+	delete(m.nodes, oldpath)
+	m.nodes[newpath] = node
+	return nil
+}
+
+// Chmod changes the mode of the named file to mode (c.f. os.Chmod)
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	name = filepath.Clean(name)
+
+	m.Lock()
+	defer m.Unlock()
+
+	node, exists := m.nodes[name]
+	if !exists {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	node.mode = (node.mode &^ fs.ModePerm) | mode.Perm()
+	return nil
+}
+
+// Size returns the total size of all files stored in the file system
+func (m *MemFS) Size() (size int64) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, node := range m.nodes {
+		size += int64(len(node.data))
+	}
+	return
+}
+
+func (m *MemFS) mkdirAll(path string, perm fs.FileMode) error {
+	if isRoot(path) {
+		return nil
+	}
+	if node, exists := m.nodes[path]; exists {
+		if !node.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: path, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(filepath.Dir(path), perm); err != nil {
+		return err
+	}
+
+	m.nodes[path] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) isDir(path string) bool {
+	if isRoot(path) {
+		return true
+	}
+	node, exists := m.nodes[path]
+	return exists && node.mode.IsDir()
+}
+
+func isRoot(path string) bool {
+	return path == "." || path == string(filepath.Separator)
+}
+
+func (n *memNode) info(path string) *memFileInfo {
+	return &memFileInfo{
+		name:    filepath.Base(path),
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memFileInfo) Name() string       { return i.name }
+func (i *memFileInfo) Size() int64        { return i.size }
+func (i *memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memFileInfo) ModTime() time.Time { return i.modTime }
+func (i *memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memFileInfo) Sys() any           { return nil }
+
+// memFile denotes an open handle to a file of a MemFS
+type memFile struct {
+	fsys   *MemFS
+	name   string
+	node   *memNode
+	flag   int
+	pos    int64
+	closed bool
+}
+
+// Name returns the name of the file
+func (f *memFile) Name() string {
+	return f.name
+}
+
+// Read reads up to len(p) bytes from the current position of the file
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrPermission}
+	}
+
+	f.fsys.RLock()
+	defer f.fsys.RUnlock()
+
+	if f.pos >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+// Write writes len(p) bytes at the current position of the file (extending it if required)
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+
+	f.fsys.Lock()
+	defer f.fsys.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.pos = int64(len(f.node.data))
+	}
+	if end := f.pos + int64(len(p)); end > int64(len(f.node.data)) {
+		size := len(f.node.data)
+		if end > int64(cap(f.node.data)) {
+			data := make([]byte, end, 2*end)
+			copy(data, f.node.data)
+			f.node.data = data
+		} else {
+			f.node.data = f.node.data[:end]
+			clear(f.node.data[size:])
+		}
+	}
+	n := copy(f.node.data[f.pos:], p)
+	f.pos += int64(n)
+	f.node.modTime = time.Now()
+	return n, nil
+}
+
+// Seek sets the position for the next Read or Write on the file
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		f.fsys.RLock()
+		pos = int64(len(f.node.data)) + offset
+		f.fsys.RUnlock()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if pos < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.pos = pos
+	return pos, nil
+}
+
+// Stat returns a FileInfo describing the file
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, fs.ErrClosed
+	}
+
+	f.fsys.RLock()
+	defer f.fsys.RUnlock()
+
+	return f.node.info(f.name), nil
+}
+
+// Close closes the file handle
+func (f *memFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
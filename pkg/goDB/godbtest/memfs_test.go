@@ -0,0 +1,126 @@
+package godbtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/engine"
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
+)
+
+const testDBPath = "/godb-in-memory"
+
+func TestMemFS(t *testing.T) {
+	fsys := NewMemFS()
+
+	_, err := fsys.OpenFile("/a/b/test.gpf", os.O_RDONLY, 0)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fsys.OpenFile("/a/b/test.gpf", os.O_CREATE|os.O_WRONLY, 0644)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.Nil(t, fsys.MkdirAll("/a/b", 0755))
+	f, err := fsys.OpenFile("/a/b/test.gpf", os.O_CREATE|os.O_WRONLY, 0644)
+	require.Nil(t, err)
+	_, err = f.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = f.Seek(8, io.SeekStart)
+	require.Nil(t, err)
+	_, err = f.Write([]byte("world"))
+	require.Nil(t, err)
+	_, err = f.Read(make([]byte, 1))
+	require.ErrorIs(t, err, fs.ErrPermission)
+	require.Nil(t, f.Close())
+	require.ErrorIs(t, f.Close(), fs.ErrClosed)
+
+	f, err = fsys.OpenFile("/a/b/test.gpf", os.O_RDONLY, 0)
+	require.Nil(t, err)
+	data, err := io.ReadAll(f)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello\x00\x00\x00world"), data)
+	require.Nil(t, f.Close())
+
+	tmp, err := fsys.CreateTemp("/a/b", ".tmp-*")
+	require.Nil(t, err)
+	require.Nil(t, tmp.Close())
+	require.Nil(t, fsys.Chmod(tmp.Name(), 0640))
+	require.Nil(t, fsys.Rename(tmp.Name(), "/a/b/.meta"))
+
+	entries, err := fsys.ReadDir("/a/b")
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ".meta", entries[0].Name())
+	require.Equal(t, "test.gpf", entries[1].Name())
+	stat, err := fsys.Stat("/a/b/.meta")
+	require.Nil(t, err)
+	require.Equal(t, fs.FileMode(0640), stat.Mode())
+
+	require.NotNil(t, fsys.Remove("/a/b"))
+	require.Nil(t, fsys.Remove("/a/b/.meta"))
+	require.Nil(t, fsys.Remove("/a/b/test.gpf"))
+	require.Nil(t, fsys.Remove("/a/b"))
+	_, err = fsys.Stat("/a/b")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.Zero(t, fsys.Size())
+}
+
+func TestWriteQuery(t *testing.T) {
+	fsys := NewMemFS()
+
+	tFirst := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	w := goDB.NewDBWriter(testDBPath, "eth0", encoders.EncoderTypeLZ4).FS(fsys)
+	for i := 0; i < 3; i++ {
+		require.Nil(t, w.Write(testFlows(), capturetypes.CaptureStats{}, tFirst.Add(time.Duration(i)*5*time.Minute).Unix()))
+	}
+	require.NotZero(t, fsys.Size())
+
+	// nothing must have been written to disk
+	_, err := os.Stat(testDBPath)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	ifaces, err := info.GetInterfacesFS(fsys, testDBPath)
+	require.Nil(t, err)
+	require.Equal(t, []string{"eth0"}, ifaces)
+
+	args := query.NewArgs("sip,dip", types.AnySelector,
+		query.WithFirst(strconv.FormatInt(tFirst.Add(-time.Hour).Unix(), 10)),
+		query.WithLast(strconv.FormatInt(tFirst.Add(time.Hour).Unix(), 10)),
+		query.WithNumResults(query.MaxResults),
+		query.WithFormat("json"),
+	)
+	res, err := engine.NewQueryRunner(testDBPath).WithFS(fsys).Run(context.Background(), args)
+	require.Nil(t, err)
+	require.Equal(t, types.StatusOK, res.Status.Code)
+	require.Len(t, res.Rows, 2)
+	require.Equal(t, types.Counters{
+		BytesRcvd:   3 * 300,
+		BytesSent:   3 * 30,
+		PacketsRcvd: 3 * 3,
+		PacketsSent: 3 * 2,
+	}, res.Summary.Totals)
+
+	// a DB that doesn't exist in memory must not be picked up from disk
+	_, err = engine.NewQueryRunner(testDBPath).WithFS(NewMemFS()).Run(context.Background(), args)
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func testFlows() *hashmap.AggFlowMap {
+	m := hashmap.NewAggFlowMap()
+	m.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, []byte{0, 80}, 6),
+		types.Counters{BytesRcvd: 200, BytesSent: 10, PacketsRcvd: 2, PacketsSent: 1})
+	m.SecondaryMap.Set(types.NewV6KeyStatic([16]byte{0x20, 0x01, 15: 1}, [16]byte{0x20, 0x01, 15: 2}, []byte{1, 187}, 17),
+		types.Counters{BytesRcvd: 100, BytesSent: 20, PacketsRcvd: 1, PacketsSent: 1})
+	return m
+}
@@ -1,13 +1,20 @@
 package info
 
 import (
-	"os"
 	"sort"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
 )
 
 // GetInterfaces returns a list of interfaces covered by this goDB
 func GetInterfaces(dbPath string) ([]string, error) {
-	dirents, err := os.ReadDir(dbPath)
+	return GetInterfacesFS(storage.DefaultFS, dbPath)
+}
+
+// GetInterfacesFS returns a list of interfaces covered by this goDB, residing on a specific
+// file system
+func GetInterfacesFS(fsys storage.FS, dbPath string) ([]string, error) {
+	dirents, err := fsys.ReadDir(dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -8,12 +8,15 @@ import (
 )
 
 // InterfaceMetadata describes the time range for which data is available, how many flows
-// were recorded and how much traffic was captured
+// were recorded and how much traffic was captured (as well as the properties of the link
+// backing the interface at the end of the time range, if recorded)
 type InterfaceMetadata struct {
 	Iface string `json:"iface"`
 	results.TimeRange
 
 	gpfile.Stats
+
+	Link *types.LinkInfo `json:"link,omitempty"`
 }
 
 // TableHeader constructs the table header for pretty printing metadata
@@ -22,7 +25,7 @@ func (i *InterfaceMetadata) TableHeader(detailed bool) (headerRows [][]string) {
 	fromTo := []string{"from", "to"}
 
 	if detailed {
-		r0 := []string{"", "packets", "packets", "bytes", "bytes", "# of", "# of", "", "", ""}
+		r0 := []string{"", "packets", "packets", "bytes", "bytes", "# of", "# of", "", "", "", ""}
 		r1 = append(r1, "in", "out", "in", "out", "IPv4 flows", "IPv6 flows", "drops")
 
 		headerRows = append(headerRows, r0)
@@ -31,6 +34,9 @@ func (i *InterfaceMetadata) TableHeader(detailed bool) (headerRows [][]string) {
 	}
 
 	r1 = append(r1, fromTo...)
+	if detailed {
+		r1 = append(r1, "link")
+	}
 
 	headerRows = append(headerRows, r1)
 	return headerRows
@@ -38,7 +44,7 @@ func (i *InterfaceMetadata) TableHeader(detailed bool) (headerRows [][]string) {
 
 // TableRow puts all attributes of the metadata into a row that can be used for table printing.
 // If detailed is false, the counts and metadata is summarized to their sum (e.g. IPv4 + IPv6 flows = NumFlows).
-// Drops are only printed in detail mode
+// Drops and the link properties are only printed in detail mode
 func (i *InterfaceMetadata) TableRow(detailed bool) []string {
 	str := []string{i.Iface}
 	fromTo := []string{i.First.Format(types.DefaultTimeOutputFormat), i.Last.Format(types.DefaultTimeOutputFormat)}
@@ -58,5 +64,12 @@ func (i *InterfaceMetadata) TableRow(detailed bool) []string {
 
 	}
 	str = append(str, fromTo...)
+	if detailed {
+		link := ""
+		if i.Link != nil {
+			link = i.Link.String()
+		}
+		str = append(str, link)
+	}
 	return str
 }
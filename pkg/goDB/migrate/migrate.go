@@ -0,0 +1,340 @@
+// Package migrate re-encodes the data stored in a goDB, e.g. after the encoder of an interface has been
+// changed in the configuration of the probe: Since the encoder is recorded per block, existing data
+// remains readable after such a change, but is only stored with the new encoder once migrated.
+//
+// Migrations can be restricted to historical data and throttled, allowing to re-compress long-term
+// archives with a denser encoder in the background (see WithMinAge, WithPause, WithIdle and WithProgress)
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/telemetry/logging"
+)
+
+// Encoder denotes the encoder (and its compression level) the data of an interface is stored with
+type Encoder struct {
+	Type  encoders.Type
+	Level int // Level: compression level (if supported by the encoder), zero denotes the default level
+}
+
+// ErrNegativeMinAge denotes that a negative minimum age was provided
+var ErrNegativeMinAge = errors.New("minimum age must not be negative")
+
+// errStopWalk stops walking the directories of an interface once the first directory that is too
+// recent to be migrated has been reached
+var errStopWalk = errors.New("stop walk")
+
+// EncoderFn returns the encoder the data of an interface is to be stored with
+type EncoderFn func(iface string) Encoder
+
+// IfaceResult summarizes the migration of a single interface
+type IfaceResult struct {
+	Iface        string `json:"iface"`         // Iface: name of the interface
+	Encoder      string `json:"encoder"`       // Encoder: name of the encoder the data is stored with
+	MigratedDirs int    `json:"migrated_dirs"` // MigratedDirs: number of (day) directories re-encoded
+	BytesBefore  int64  `json:"bytes_before"`  // BytesBefore: size of the re-encoded column files prior to the migration
+	BytesAfter   int64  `json:"bytes_after"`   // BytesAfter: size of the re-encoded column files after the migration (zero in dry-run mode)
+}
+
+// Results denotes the results of a migration run, ordered by interface
+type Results []IfaceResult
+
+// MigratedDirs returns the total number of directories re-encoded
+func (r Results) MigratedDirs() (n int) {
+	for _, res := range r {
+		n += res.MigratedDirs
+	}
+	return
+}
+
+// Migration re-encodes the data of a goDB
+type Migration struct {
+	dbPath      string
+	fsys        storage.FS
+	permissions fs.FileMode
+	locker      sync.Locker
+	force       bool
+	dryRun      bool
+
+	minAge       time.Duration
+	pause        time.Duration
+	idle         func() bool
+	progressPath string
+
+	now func() time.Time
+}
+
+// Option denotes a functional option for a Migration
+type Option func(*Migration)
+
+// WithFS sets the file system the goDB resides on
+func WithFS(fsys storage.FS) Option {
+	return func(m *Migration) {
+		m.fsys = fsys
+	}
+}
+
+// WithPermissions sets the permissions of rewritten column files
+func WithPermissions(permissions fs.FileMode) Option {
+	return func(m *Migration) {
+		m.permissions = permissions
+	}
+}
+
+// WithLocker sets a lock held while processing a directory, allowing to serialize access with
+// concurrent writeouts to the goDB
+func WithLocker(locker sync.Locker) Option {
+	return func(m *Migration) {
+		m.locker = locker
+	}
+}
+
+// WithForce enables / disables re-encoding all data, even if it is already stored with the target
+// encoder. Since the compression level is not recorded, this is required to apply a changed level
+func WithForce(b bool) Option {
+	return func(m *Migration) {
+		m.force = b
+	}
+}
+
+// WithDryRun enables / disables dry-run mode, in which all directories that would be re-encoded are
+// reported without modifying the goDB
+func WithDryRun(b bool) Option {
+	return func(m *Migration) {
+		m.dryRun = b
+	}
+}
+
+// WithMinAge restricts the migration to (day) directories whose data is older than minAge, leaving
+// recent data (which is still written to and most frequently queried) untouched
+func WithMinAge(minAge time.Duration) Option {
+	return func(m *Migration) {
+		m.minAge = minAge
+	}
+}
+
+// WithPause sets the duration to pause after re-encoding a directory, throttling the migration in
+// order to leave IO capacity to writeouts and queries
+func WithPause(pause time.Duration) Option {
+	return func(m *Migration) {
+		m.pause = pause
+	}
+}
+
+// WithIdle sets a function determining if the goDB is idle. Prior to processing a directory, the
+// migration waits (polling once per pause, or per second if no pause is set) until it returns true,
+// confining the migration to idle IO windows (e.g. in between writeouts)
+func WithIdle(idle func() bool) Option {
+	return func(m *Migration) {
+		m.idle = idle
+	}
+}
+
+// WithProgress persists the progress of the migration in a file at path (see Progress), allowing
+// subsequent / interrupted migrations to skip all directories that have already been re-encoded with
+// the same encoder. This is required to re-encode with a changed compression level only once, since
+// the level is not recorded per block (and hence implies WithForce)
+func WithProgress(path string) Option {
+	return func(m *Migration) {
+		m.progressPath = path
+	}
+}
+
+// New instantiates a new Migration for the goDB located at dbPath
+func New(dbPath string, opts ...Option) *Migration {
+	m := &Migration{
+		dbPath: dbPath,
+		fsys:   storage.DefaultFS,
+		locker: noopLocker{},
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Run re-encodes the data of all interfaces of the goDB (or of the provided ones only) with the
+// encoder returned for each interface by encoderOf
+func (m *Migration) Run(ctx context.Context, encoderOf EncoderFn, ifaces ...string) (Results, error) {
+	if m.minAge < 0 {
+		return nil, ErrNegativeMinAge
+	}
+	if len(ifaces) == 0 {
+		var err error
+		if ifaces, err = info.GetInterfacesFS(m.fsys, m.dbPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var progress Progress
+	if m.progressPath != "" {
+		var err error
+		if progress, err = ReadProgress(m.fsys, m.progressPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Any day directory ending before the cutoff is eligible for migration (all of them unless a
+	// minimum age is set)
+	cutoff := m.now().Add(-m.minAge).Unix()
+
+	results := make(Results, 0, len(ifaces))
+	for _, iface := range ifaces {
+		res, err := m.migrateIface(ctx, iface, encoderOf(iface), cutoff, progress)
+		if err != nil {
+			return results, fmt.Errorf("failed to migrate interface %s: %w", iface, err)
+		}
+		results = append(results, res)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Iface < results[j].Iface
+	})
+
+	return results, nil
+}
+
+func (m *Migration) migrateIface(ctx context.Context, iface string, enc Encoder, cutoff int64, progress Progress) (IfaceResult, error) {
+	logger := logging.FromContext(ctx).With("iface", iface, "encoder", enc.Type.String(), "dry_run", m.dryRun)
+	res := IfaceResult{
+		Iface:   iface,
+		Encoder: enc.Type.String(),
+	}
+
+	// Directories are walked in chronological order, hence all directories up to the one recorded
+	// have already been re-encoded (unless the encoder has changed since)
+	ifaceProgress, tracked := progress[iface]
+	if tracked && !ifaceProgress.matches(enc) {
+		tracked = false
+	}
+
+	ifacePath := filepath.Join(m.dbPath, iface)
+	err := gpfile.WalkDirs(m.fsys, ifacePath, func(dayPath string, dayTimestamp int64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if dayTimestamp+gpfile.EpochDay > cutoff {
+			return errStopWalk
+		}
+		if tracked && dayTimestamp <= ifaceProgress.Until {
+			return nil
+		}
+		if err := m.waitIdle(ctx); err != nil {
+			return err
+		}
+
+		before, after, err := m.migrateDir(ifacePath, dayTimestamp, enc)
+		if err != nil {
+			return err
+		}
+		if before > 0 {
+			logger.With("path", dayPath, "bytes_before", before, "bytes_after", after).Info("re-encoded directory")
+			res.MigratedDirs++
+			res.BytesBefore += before
+			res.BytesAfter += after
+		}
+
+		if progress != nil && !m.dryRun {
+			progress[iface] = newIfaceProgress(enc, dayTimestamp, m.now())
+			if err := progress.Write(m.fsys, m.progressPath, m.permissions); err != nil {
+				return fmt.Errorf("failed to persist progress: %w", err)
+			}
+		}
+		if before > 0 && !m.dryRun {
+			return wait(ctx, m.pause)
+		}
+		return nil
+	})
+	if errors.Is(err, errStopWalk) {
+		err = nil
+	}
+
+	return res, err
+}
+
+// waitIdle waits until the goDB is idle (if an idle function is set)
+func (m *Migration) waitIdle(ctx context.Context) error {
+	if m.idle == nil {
+		return nil
+	}
+	interval := m.pause
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for !m.idle() {
+		if err := wait(ctx, interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDir re-encodes a day directory (if required), returning the size of the re-encoded column
+// files before and after the migration
+func (m *Migration) migrateDir(ifacePath string, dayTimestamp int64, enc Encoder) (before, after int64, err error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+
+	opts := []gpfile.Option{gpfile.WithFS(m.fsys)}
+	if m.permissions != 0 {
+		opts = append(opts, gpfile.WithPermissions(m.permissions))
+	}
+
+	// Determine if the directory has to be migrated first to avoid touching any directory that is
+	// already stored with the target encoder
+	dir := gpfile.NewDir(ifacePath, dayTimestamp, gpfile.ModeRead, opts...)
+	if err := dir.Open(); err != nil {
+		return 0, 0, err
+	}
+	before, err = dir.ReencodeSize(enc.Type, m.force || m.progressPath != "")
+	if cerr := dir.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil || before == 0 || m.dryRun {
+		return before, 0, err
+	}
+
+	dir = gpfile.NewDir(ifacePath, dayTimestamp, gpfile.ModeWrite, opts...)
+	if err := dir.Open(); err != nil {
+		return 0, 0, err
+	}
+	before, after, err = dir.Reencode(enc.Type, enc.Level, m.force || m.progressPath != "")
+	if err != nil {
+		// Persist the metadata anyway, since any column re-encoded so far has already been replaced
+		return before, after, errors.Join(err, dir.Close())
+	}
+	return before, after, dir.Close()
+}
+
+// wait waits for the provided duration, unless the context is cancelled beforehand
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type noopLocker struct{}
+
+func (noopLocker) Lock()   {}
+func (noopLocker) Unlock() {}
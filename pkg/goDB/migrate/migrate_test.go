@@ -0,0 +1,168 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testDBPath = "/db"
+
+var testDay = time.Unix(1704067200, 0) // 2024-01-01
+
+func testData(colIdx int) []byte {
+	return bytes.Repeat([]byte{byte(colIdx)}, 1024)
+}
+
+func writeTestDir(t *testing.T, fsys *godbtest.MemFS, iface string, encoderType encoders.Type) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testDBPath, iface), testDay.Unix(), gpfile.ModeWrite, gpfile.WithFS(fsys), gpfile.WithEncoderTypeLevel(encoderType, 0))
+	require.Nil(t, dir.Open())
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := range dbData {
+		dbData[colIdx] = testData(colIdx)
+	}
+	require.Nil(t, dir.WriteBlocks(testDay.Unix()+300, gpfile.TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData))
+	require.Nil(t, dir.WriteBlocks(testDay.Unix()+600, gpfile.TrafficMetadata{}, types.Counters{}, [types.ColIdxCount][]byte{}))
+	require.Nil(t, dir.Close())
+}
+
+func requireEncoded(t *testing.T, fsys *godbtest.MemFS, iface string, encoderType encoders.Type) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testDBPath, iface), testDay.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	defer func() {
+		require.Nil(t, dir.Close())
+	}()
+
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		blocks := dir.BlockMetadata[colIdx].BlockList
+		require.Len(t, blocks, 2)
+		require.Equal(t, encoderType, blocks[0].EncoderType)
+		require.True(t, blocks[1].IsEmpty())
+
+		data, err := dir.ReadBlockAtIndex(colIdx, 0)
+		require.Nil(t, err)
+		require.Equal(t, testData(int(colIdx)), data)
+	}
+}
+
+func TestMigration(t *testing.T) {
+	encoderOf := func(iface string) Encoder {
+		if iface == "eth1" {
+			return Encoder{Type: encoders.EncoderTypeZSTD, Level: 19}
+		}
+		return Encoder{Type: encoders.EncoderTypeLZ4}
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		t.Run(map[bool]string{true: "dry-run", false: "migrate"}[dryRun], func(t *testing.T) {
+			fsys := godbtest.NewMemFS()
+			writeTestDir(t, fsys, "eth0", encoders.EncoderTypeLZ4)
+			writeTestDir(t, fsys, "eth1", encoders.EncoderTypeLZ4)
+
+			res, err := New(testDBPath, WithFS(fsys), WithDryRun(dryRun)).Run(context.Background(), encoderOf)
+			require.Nil(t, err)
+			require.Len(t, res, 2)
+
+			// eth0 is already stored with its encoder and must not be touched
+			require.Equal(t, IfaceResult{Iface: "eth0", Encoder: "lz4"}, res[0])
+			require.Equal(t, "eth1", res[1].Iface)
+			require.Equal(t, "zstd", res[1].Encoder)
+			require.Equal(t, 1, res[1].MigratedDirs)
+			require.NotZero(t, res[1].BytesBefore)
+
+			requireEncoded(t, fsys, "eth0", encoders.EncoderTypeLZ4)
+			if dryRun {
+				require.Zero(t, res[1].BytesAfter)
+				requireEncoded(t, fsys, "eth1", encoders.EncoderTypeLZ4)
+				return
+			}
+			require.NotZero(t, res[1].BytesAfter)
+			requireEncoded(t, fsys, "eth1", encoders.EncoderTypeZSTD)
+
+			// a subsequent run has nothing left to do, unless forced to
+			res, err = New(testDBPath, WithFS(fsys)).Run(context.Background(), encoderOf, "eth1")
+			require.Nil(t, err)
+			require.Zero(t, res.MigratedDirs())
+
+			res, err = New(testDBPath, WithFS(fsys), WithForce(true)).Run(context.Background(), encoderOf, "eth1")
+			require.Nil(t, err)
+			require.Equal(t, 1, res.MigratedDirs())
+			requireEncoded(t, fsys, "eth1", encoders.EncoderTypeZSTD)
+		})
+	}
+}
+
+func TestMigrationProgress(t *testing.T) {
+	encoderOf := func(string) Encoder {
+		return Encoder{Type: encoders.EncoderTypeZSTD, Level: 19}
+	}
+	progressPath := filepath.Join(testDBPath, ProgressFileName)
+
+	fsys := godbtest.NewMemFS()
+	writeTestDir(t, fsys, "eth0", encoders.EncoderTypeZSTD)
+
+	newMigration := func(now time.Time, opts ...Option) *Migration {
+		m := New(testDBPath, append([]Option{WithFS(fsys), WithMinAge(7 * 24 * time.Hour), WithProgress(progressPath)}, opts...)...)
+		m.now = func() time.Time { return now }
+		return m
+	}
+
+	// recent data is left untouched
+	res, err := newMigration(testDay.Add(24*time.Hour)).Run(context.Background(), encoderOf)
+	require.Nil(t, err)
+	require.Zero(t, res.MigratedDirs())
+
+	progress, err := ReadProgress(fsys, progressPath)
+	require.Nil(t, err)
+	require.Empty(t, progress)
+
+	// historical data is re-encoded once (even though it is already stored with the same encoder type,
+	// since the level isn't recorded)
+	now := testDay.Add(30 * 24 * time.Hour).UTC()
+	res, err = newMigration(now).Run(context.Background(), encoderOf)
+	require.Nil(t, err)
+	require.Equal(t, 1, res.MigratedDirs())
+	requireEncoded(t, fsys, "eth0", encoders.EncoderTypeZSTD)
+
+	progress, err = ReadProgress(fsys, progressPath)
+	require.Nil(t, err)
+	require.Equal(t, Progress{"eth0": newIfaceProgress(encoderOf("eth0"), testDay.Unix(), now)}, progress)
+
+	res, err = newMigration(now).Run(context.Background(), encoderOf)
+	require.Nil(t, err)
+	require.Zero(t, res.MigratedDirs())
+
+	// a changed encoder invalidates the progress
+	res, err = newMigration(now).Run(context.Background(), func(string) Encoder {
+		return Encoder{Type: encoders.EncoderTypeZSTD, Level: 9}
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1, res.MigratedDirs())
+
+	// the migration waits for the goDB to become idle
+	var polls int
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err = newMigration(now, WithPause(time.Millisecond), WithIdle(func() bool {
+		polls++
+		return polls > 3
+	})).Run(ctx, encoderOf)
+	require.Nil(t, err)
+	require.Equal(t, 1, res.MigratedDirs())
+	require.Equal(t, 4, polls)
+
+	_, err = New(testDBPath, WithFS(fsys), WithMinAge(-time.Hour)).Run(context.Background(), encoderOf)
+	require.ErrorIs(t, err, ErrNegativeMinAge)
+}
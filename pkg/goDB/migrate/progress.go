@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+)
+
+// ProgressFileName denotes the default name of the file (in the root of the goDB) the progress of a
+// background re-compression is persisted in
+const ProgressFileName = ".recompress.json"
+
+// defaultProgressPermissions denotes the permissions of the progress file if none are set
+const defaultProgressPermissions fs.FileMode = 0644
+
+// IfaceProgress denotes the progress of a migration of a single interface: All (day) directories up
+// to (and including) the one starting at Until have been re-encoded with the recorded encoder
+type IfaceProgress struct {
+	Encoder   string    `json:"encoder"`         // Encoder: name of the encoder the data has been re-encoded with
+	Level     int       `json:"level,omitempty"` // Level: compression level the data has been re-encoded with
+	Until     int64     `json:"until"`           // Until: timestamp of the most recent directory re-encoded
+	UpdatedAt time.Time `json:"updated_at"`      // UpdatedAt: time at which the progress was last updated
+}
+
+func newIfaceProgress(enc Encoder, until int64, now time.Time) IfaceProgress {
+	return IfaceProgress{
+		Encoder:   enc.Type.String(),
+		Level:     enc.Level,
+		Until:     until,
+		UpdatedAt: now,
+	}
+}
+
+// matches determines if the progress has been made with the provided encoder (otherwise all
+// directories have to be re-encoded anew)
+func (p IfaceProgress) matches(enc Encoder) bool {
+	encoderType, err := encoders.GetTypeByString(p.Encoder)
+	return err == nil && encoderType == enc.Type && p.Level == enc.Level
+}
+
+// Progress denotes the progress of a migration, keyed by interface
+type Progress map[string]IfaceProgress
+
+// ReadProgress reads the progress persisted at path. If there is no such file (yet), an empty
+// progress is returned
+func ReadProgress(fsys storage.FS, path string) (Progress, error) {
+	progress := make(Progress)
+
+	f, err := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return progress, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// Write persists the progress at path. The file is written via a temporary file, ensuring that an
+// interrupted write never leaves behind a truncated one
+func (p Progress) Write(fsys storage.FS, path string, permissions fs.FileMode) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if permissions == 0 {
+		permissions = defaultProgressPermissions
+	}
+
+	tempFile, err := fsys.CreateTemp(filepath.Dir(path), ".tmp-recompress-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		return errors.Join(err, fsys.Remove(tempFile.Name()))
+	}
+	if err := tempFile.Close(); err != nil {
+		return errors.Join(err, fsys.Remove(tempFile.Name()))
+	}
+	if err := fsys.Chmod(tempFile.Name(), permissions); err != nil {
+		return errors.Join(err, fsys.Remove(tempFile.Name()))
+	}
+
+	return fsys.Rename(tempFile.Name(), path)
+}
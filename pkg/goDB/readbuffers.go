@@ -0,0 +1,134 @@
+package goDB
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/fako1024/gotools/concurrency"
+)
+
+// readBuffers denotes the (optional) global pool the read buffers of all queries are drawn from
+var readBuffers atomic.Pointer[ReadBufferPool]
+
+// SetReadBufferLimit limits the total size (in bytes) of the buffers used to read the column files of
+// the database by all concurrently running queries of this process. Once the limit is reached, queries
+// wait for buffers to be returned by other queries instead of allocating additional memory. A limit of
+// zero (the default) disables the shared pool, using dedicated buffers per query instead
+func SetReadBufferLimit(limit int64) {
+	if limit <= 0 {
+		readBuffers.Store(nil)
+		return
+	}
+	readBuffers.Store(NewReadBufferPool(limit))
+}
+
+// ReadBufferPool provides read buffers to concurrent queries, limiting the total size of all buffers
+// in use. Buffers are handed out via leases (one per reader): a lease not holding any buffers waits
+// (in FIFO order) until the pool is below its limit, whereas a lease already holding buffers is never
+// blocked. Since all column files of a directory are held until the directory is closed, this ensures
+// that readers cannot block each other indefinitely, the limit being exceeded by at most the buffers
+// of one directory per reader
+type ReadBufferPool struct {
+	limit int64
+	inUse int64
+
+	// ticket / serving implement the FIFO order of waiting leases
+	ticket  uint64
+	serving uint64
+
+	free sync.Pool
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+// NewReadBufferPool creates a new pool of read buffers with a limit (in bytes)
+func NewReadBufferPool(limit int64) *ReadBufferPool {
+	p := &ReadBufferPool{limit: limit}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Lease creates a new lease, drawing buffers from the pool (to be used by a single reader)
+func (p *ReadBufferPool) Lease() concurrency.MemPoolGCable {
+	return &readBufferLease{pool: p}
+}
+
+// InUse returns the total size (in bytes) of all buffers currently in use
+func (p *ReadBufferPool) InUse() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.inUse
+}
+
+func (p *ReadBufferPool) acquire(size int64, wait bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if wait {
+		ticket := p.ticket
+		p.ticket++
+
+		// a buffer exceeding the limit by itself is granted once no other buffers are in use
+		for p.serving != ticket || (p.inUse > 0 && p.inUse+size > p.limit) {
+			p.cond.Wait()
+		}
+		p.serving++
+		p.cond.Broadcast()
+	}
+	p.inUse += size
+}
+
+func (p *ReadBufferPool) release(size int64) {
+	p.mu.Lock()
+	p.inUse -= size
+	p.mu.Unlock()
+
+	p.cond.Broadcast()
+}
+
+// readBufferLease implements concurrency.MemPoolGCable on top of a ReadBufferPool, tracking the buffers
+// held by a single reader
+type readBufferLease struct {
+	pool  *ReadBufferPool
+	inUse int64
+}
+
+// Get returns a buffer of (at least) the requested size, waiting for other queries to return buffers
+// to the pool if required
+func (l *readBufferLease) Get(size int) (elem []byte) {
+	l.pool.acquire(int64(size), l.inUse == 0)
+
+	if buf, ok := l.pool.free.Get().(*[]byte); ok && cap(*buf) >= size {
+		elem = (*buf)[:size]
+
+		// account for the actual capacity of the reused buffer
+		l.pool.acquire(int64(cap(elem)-size), false)
+	} else {
+		elem = make([]byte, size)
+	}
+	l.inUse += int64(cap(elem))
+
+	return elem
+}
+
+// Put returns a buffer to the pool
+func (l *readBufferLease) Put(elem []byte) {
+
+	// buffers returned after the lease has been cleared have already been released
+	if size := int64(cap(elem)); l.inUse >= size {
+		l.inUse -= size
+		l.pool.release(size)
+	}
+
+	l.pool.free.Put(&elem)
+}
+
+// Clear releases all buffers still held by the lease
+func (l *readBufferLease) Clear() {
+	if l.inUse > 0 {
+		l.pool.release(l.inUse)
+		l.inUse = 0
+	}
+}
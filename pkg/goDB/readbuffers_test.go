@@ -0,0 +1,100 @@
+package goDB
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBufferPool(t *testing.T) {
+	pool := NewReadBufferPool(1024)
+
+	// a lease holding buffers is never blocked, even if the limit is exceeded
+	first := pool.Lease()
+	buf := first.Get(768)
+	require.Len(t, buf, 768)
+	buf2 := first.Get(512)
+	require.EqualValues(t, 1280, pool.InUse())
+
+	// a new lease has to wait until enough buffers have been returned to the pool
+	granted := make(chan []byte)
+	second := pool.Lease()
+	go func() {
+		granted <- second.Get(512)
+	}()
+	select {
+	case <-granted:
+		t.Fatal("unexpected buffer exceeding the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Put(buf2)
+	select {
+	case <-granted:
+		t.Fatal("unexpected buffer exceeding the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Put(buf)
+	select {
+	case buf = <-granted:
+		require.Len(t, buf, 512)
+	case <-time.After(time.Second):
+		t.Fatal("buffer not granted after buffers were returned to the pool")
+	}
+	require.LessOrEqual(t, pool.InUse(), int64(1024))
+
+	second.Put(buf)
+	require.Zero(t, pool.InUse())
+}
+
+func TestReadBufferPoolOversized(t *testing.T) {
+	pool := NewReadBufferPool(1024)
+
+	// a buffer exceeding the limit by itself is granted if no other buffers are in use
+	lease := pool.Lease()
+	buf := lease.Get(4096)
+	require.Len(t, buf, 4096)
+	require.EqualValues(t, 4096, pool.InUse())
+
+	// buffers still held are released upon clearing the lease (and not released twice)
+	lease.Clear()
+	require.Zero(t, pool.InUse())
+	lease.Put(buf)
+	require.Zero(t, pool.InUse())
+}
+
+func TestReadBufferPoolFIFO(t *testing.T) {
+	pool := NewReadBufferPool(1024)
+
+	holder := pool.Lease()
+	buf := holder.Get(1024)
+
+	// waiting leases are served in order
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		lease := pool.Lease()
+		go func(i int) {
+			elem := lease.Get(1024)
+			order <- i
+			lease.Put(elem)
+		}(i)
+		require.Eventually(t, func() bool {
+			pool.mu.Lock()
+			defer pool.mu.Unlock()
+			return pool.ticket == uint64(i+2)
+		}, time.Second, time.Millisecond)
+	}
+
+	holder.Put(buf)
+	for i := 0; i < 3; i++ {
+		select {
+		case idx := <-order:
+			require.Equal(t, i, idx)
+		case <-time.After(time.Second):
+			t.Fatal("waiting lease not served")
+		}
+	}
+	require.Zero(t, pool.InUse())
+}
@@ -0,0 +1,412 @@
+// Package archive provides read-only access to goDB data contained in tar / zip archives (e.g. as
+// produced by backup tooling), allowing to query historical data without restoring the archive to disk.
+// Archive members are resolved lazily: Only the index (the headers of a tar archive / the central
+// directory of a zip archive) is read upon opening, member data is read when it is accessed
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrReadOnly denotes that a modification of an archive was attempted
+	ErrReadOnly = errors.New("archive is read-only")
+
+	// ErrUnsupportedFormat denotes that a file is neither a (gzip / zstd compressed) tar nor a zip archive
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+
+	// ErrNoDB denotes that an archive does not contain any goDB data
+	ErrNoDB = errors.New("archive does not contain any goDB data")
+)
+
+var (
+	magicZip  = []byte("PK\x03\x04")
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicTar  = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// FS provides read-only access to the members of an archive via the storage.FS interface. All members
+// are exposed as absolute paths relative to the root of the archive
+type FS struct {
+	root    string
+	entries map[string]*entry
+
+	closers []io.Closer
+}
+
+type entry struct {
+	name     string
+	size     int64
+	modTime  time.Time
+	isDir    bool
+	children []string
+
+	open func() (io.ReadSeeker, error)
+}
+
+// Open opens a tar (optionally gzip / zstd compressed) or zip archive. The format is determined
+// from the content of the file. Since compressed tar archives do not support random access, they are
+// decompressed into an (anonymous) temporary file first, which is removed upon Close()
+func Open(archivePath string) (*FS, error) {
+	f, err := os.Open(archivePath) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	a := &FS{
+		entries: map[string]*entry{
+			"/": {name: "/", isDir: true},
+		},
+		closers: []io.Closer{f},
+	}
+	if err := a.index(f); err != nil {
+		_ = a.Close()
+		return nil, fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+	return a, nil
+}
+
+func (a *FS) index(f *os.File) error {
+	header := make([]byte, tarMagicOffset+len(magicTar))
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	header = header[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, magicZip):
+		return a.indexZip(f)
+	case bytes.HasPrefix(header, magicGzip):
+		zr, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return a.indexCompressedTar(zr)
+	case bytes.HasPrefix(header, magicZstd):
+		zr, err := zstd.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return a.indexCompressedTar(zr)
+	case len(header) > tarMagicOffset && bytes.HasPrefix(header[tarMagicOffset:], magicTar):
+		return a.indexTar(f)
+	}
+	return ErrUnsupportedFormat
+}
+
+// indexCompressedTar decompresses a tar archive into a temporary file and indexes it
+func (a *FS) indexCompressedTar(r io.Reader) error {
+	tmp, err := os.CreateTemp("", "goquery-archive-*.tar")
+	if err != nil {
+		return err
+	}
+	a.closers = append(a.closers, tmp)
+
+	// The file is unlinked right away (remaining accessible until it is closed), ensuring it is cleaned
+	// up even if goQuery is terminated. If unsupported by the platform, it is removed upon Close()
+	if err := os.Remove(tmp.Name()); err != nil {
+		a.closers = append(a.closers, closerFunc(func() error {
+			return os.Remove(tmp.Name())
+		}))
+	}
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return a.indexTar(tmp)
+}
+
+func (a *FS) indexTar(f *os.File) error {
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			a.add(hdr.Name, 0, hdr.ModTime, true, nil)
+		case tar.TypeReg:
+			// Once the header has been read, the file is positioned at the start of the member data
+			// (the tar reader skips the data of all members via Seek)
+			offset, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			size := hdr.Size
+			a.add(hdr.Name, size, hdr.ModTime, false, func() (io.ReadSeeker, error) {
+				return io.NewSectionReader(f, offset, size), nil
+			})
+		}
+	}
+	return a.detectRoot()
+}
+
+func (a *FS) indexZip(f *os.File) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		zf := zf
+		if zf.FileInfo().IsDir() {
+			a.add(zf.Name, 0, zf.Modified, true, nil)
+			continue
+		}
+
+		size := int64(zf.UncompressedSize64)
+		a.add(zf.Name, size, zf.Modified, false, func() (io.ReadSeeker, error) {
+
+			// Stored members are accessed directly, compressed ones are decompressed upon access
+			if zf.Method == zip.Store {
+				offset, err := zf.DataOffset()
+				if err != nil {
+					return nil, err
+				}
+				return io.NewSectionReader(f, offset, size), nil
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+
+			data, err := io.ReadAll(rc)
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(data), nil
+		})
+	}
+	return a.detectRoot()
+}
+
+// add adds a member (and all its parent directories) to the index
+func (a *FS) add(name string, size int64, modTime time.Time, isDir bool, open func() (io.ReadSeeker, error)) {
+	name = path.Clean("/" + name)
+	if existing, exists := a.entries[name]; exists {
+		if !isDir {
+			existing.size, existing.modTime, existing.open = size, modTime, open
+		}
+		return
+	}
+	a.entries[name] = &entry{
+		name:    path.Base(name),
+		size:    size,
+		modTime: modTime,
+		isDir:   isDir,
+		open:    open,
+	}
+
+	// Register the member with its parent directory (creating it if it is not part of the archive)
+	parent := path.Dir(name)
+	if _, exists := a.entries[parent]; !exists {
+		a.add(parent, 0, modTime, true, nil)
+	}
+	a.entries[parent].children = append(a.entries[parent].children, name)
+}
+
+// detectRoot determines the directory of the goDB within the archive, i.e. the parent directory of
+// the interface directories (identified by the <iface>/<year>/<month>/<day> structure of the goDB)
+func (a *FS) detectRoot() error {
+	for name, e := range a.entries {
+		if !e.isDir {
+			continue
+		}
+		elems := strings.Split(strings.TrimPrefix(name, "/"), "/")
+		if len(elems) < 4 || !isNumeric(elems[len(elems)-1]) || !isNumeric(elems[len(elems)-2]) || !isNumeric(elems[len(elems)-3]) {
+			continue
+		}
+		root := "/" + path.Join(elems[:len(elems)-4]...)
+		if a.root == "" || len(root) < len(a.root) {
+			a.root = root
+		}
+	}
+	if a.root == "" {
+		return ErrNoDB
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// Root returns the path of the goDB within the archive
+func (a *FS) Root() string {
+	return a.root
+}
+
+// Close closes the archive (and removes any temporary file)
+func (a *FS) Close() error {
+	var errs []error
+	for _, closer := range a.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *FS) lookup(op, name string) (*entry, error) {
+	e, exists := a.entries[path.Clean("/"+name)]
+	if !exists {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// OpenFile opens the named member for reading (c.f. os.OpenFile). Any attempt to open a member for
+// writing fails
+func (a *FS) OpenFile(name string, flag int, _ fs.FileMode) (storage.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	e, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.isDir {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	rs, err := e.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{ReadSeeker: rs, name: name, entry: e}, nil
+}
+
+// ReadDir reads the named directory, returning all its entries sorted by filename (c.f. os.ReadDir)
+func (a *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := a.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.isDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	dirents := make([]fs.DirEntry, 0, len(e.children))
+	for _, child := range e.children {
+		dirents = append(dirents, fs.FileInfoToDirEntry(fileInfo{a.entries[child]}))
+	}
+	sort.Slice(dirents, func(i, j int) bool {
+		return dirents[i].Name() < dirents[j].Name()
+	})
+	return dirents, nil
+}
+
+// Stat returns a FileInfo describing the named member (c.f. os.Stat)
+func (a *FS) Stat(name string) (fs.FileInfo, error) {
+	e, err := a.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{e}, nil
+}
+
+// CreateTemp is not supported on an archive
+func (a *FS) CreateTemp(dir, _ string) (storage.File, error) {
+	return nil, &fs.PathError{Op: "createtemp", Path: dir, Err: ErrReadOnly}
+}
+
+// MkdirAll is not supported on an archive
+func (a *FS) MkdirAll(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// Remove is not supported on an archive
+func (a *FS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// Rename is not supported on an archive
+func (a *FS) Rename(oldpath, _ string) error {
+	return &fs.PathError{Op: "rename", Path: oldpath, Err: ErrReadOnly}
+}
+
+// Chmod is not supported on an archive
+func (a *FS) Chmod(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: ErrReadOnly}
+}
+
+type file struct {
+	io.ReadSeeker
+	name  string
+	entry *entry
+}
+
+func (f *file) Write([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return fileInfo{f.entry}, nil
+}
+
+type fileInfo struct {
+	*entry
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.isDir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error {
+	return c()
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testMembers = map[string]string{
+	"backup/db/eth0/2024/01/1704067200/.blockmeta":     "meta",
+	"backup/db/eth0/2024/01/1704067200/bytes_rcvd.gpf": "column data",
+	"backup/db/eth1/2024/01/1704153600/.blockmeta":     "meta eth1",
+	"backup/README": "not part of the goDB",
+}
+
+func writeTar(t *testing.T, w io.Writer) {
+	t.Helper()
+
+	tw := tar.NewWriter(w)
+	for name, content := range testMembers {
+		require.Nil(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tw.Close())
+}
+
+func createArchive(t *testing.T, format string) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "godb."+format)
+	f, err := os.Create(archivePath)
+	require.Nil(t, err)
+	defer f.Close()
+
+	switch format {
+	case "tar":
+		writeTar(t, f)
+	case "tar.gz":
+		zw := gzip.NewWriter(f)
+		writeTar(t, zw)
+		require.Nil(t, zw.Close())
+	case "zip":
+		zw := zip.NewWriter(f)
+		method := zip.Store
+		for name, content := range testMembers {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+			require.Nil(t, err)
+			_, err = w.Write([]byte(content))
+			require.Nil(t, err)
+
+			// alternate between stored and compressed members
+			if method == zip.Store {
+				method = zip.Deflate
+			} else {
+				method = zip.Store
+			}
+		}
+		require.Nil(t, zw.Close())
+	}
+	return archivePath
+}
+
+func TestArchive(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			a, err := Open(createArchive(t, format))
+			require.Nil(t, err)
+			defer a.Close()
+
+			require.Equal(t, "/backup/db", a.Root())
+
+			dirents, err := a.ReadDir(a.Root())
+			require.Nil(t, err)
+			require.Len(t, dirents, 2)
+			require.Equal(t, "eth0", dirents[0].Name())
+			require.True(t, dirents[0].IsDir())
+			require.Equal(t, "eth1", dirents[1].Name())
+
+			for name, content := range testMembers {
+				stat, err := a.Stat("/" + name)
+				require.Nil(t, err)
+				require.Equal(t, int64(len(content)), stat.Size())
+
+				f, err := a.OpenFile("/"+name, os.O_RDONLY, 0)
+				require.Nil(t, err)
+				data, err := io.ReadAll(f)
+				require.Nil(t, err)
+				require.Equal(t, content, string(data))
+
+				// members must support random access
+				_, err = f.Seek(1, io.SeekStart)
+				require.Nil(t, err)
+				data, err = io.ReadAll(f)
+				require.Nil(t, err)
+				require.Equal(t, content[1:], string(data))
+				require.Nil(t, f.Close())
+			}
+
+			_, err = a.Stat("/backup/db/eth2")
+			require.ErrorIs(t, err, fs.ErrNotExist)
+			_, err = a.OpenFile("/backup/README", os.O_RDWR, 0)
+			require.ErrorIs(t, err, ErrReadOnly)
+			require.ErrorIs(t, a.Remove("/backup/README"), ErrReadOnly)
+		})
+	}
+}
+
+func TestArchiveInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "godb.tar")
+	require.Nil(t, os.WriteFile(path, []byte("definitely not an archive"), 0600))
+	_, err := Open(path)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	// a valid archive without any goDB data
+	f, err := os.Create(path)
+	require.Nil(t, err)
+	tw := tar.NewWriter(f)
+	require.Nil(t, tw.WriteHeader(&tar.Header{Name: "README", Mode: 0644, Typeflag: tar.TypeReg}))
+	require.Nil(t, tw.Close())
+	require.Nil(t, f.Close())
+
+	_, err = Open(path)
+	require.ErrorIs(t, err, ErrNoDB)
+}
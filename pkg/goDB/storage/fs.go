@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// DefaultFS denotes the default file system used by goDB (i.e. the one of the operating system)
+var DefaultFS FS = OSFS{}
+
+// FS denotes the set of file system operations performed by goDB when reading / writing
+// data, allowing to replace the underlying (on-disk) file system, e.g. for testing purposes
+type FS interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// File denotes a file opened via an FS
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+
+	Name() string
+	Stat() (fs.FileInfo, error)
+}
+
+// OSFS implements FS using the file system of the operating system
+type OSFS struct{}
+
+// OpenFile opens the named file (c.f. os.OpenFile)
+func (OSFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// CreateTemp creates a new temporary file in directory dir (c.f. os.CreateTemp)
+func (OSFS) CreateTemp(dir, pattern string) (File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadDir reads the named directory, returning all its entries sorted by filename (c.f. os.ReadDir)
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Stat returns a FileInfo describing the named file (c.f. os.Stat)
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents (c.f. os.MkdirAll)
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Remove removes the named file or (empty) directory (c.f. os.Remove)
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Rename renames (moves) oldpath to newpath (c.f. os.Rename)
+func (OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Chmod changes the mode of the named file to mode (c.f. os.Chmod)
+func (OSFS) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Syncer denotes a file system that is able to commit all pending writes to stable storage
+// in a single operation (c.f. syncfs(2)), as opposed to syncing each file individually
+type Syncer interface {
+	SyncFS(path string) error
+}
+
+// SyncFS commits all pending writes to the file system the given path resides on to stable
+// storage. If the file system does not support syncing (e.g. an in-memory one), it is a no-op
+func SyncFS(fsys FS, path string) error {
+	if syncer, ok := fsys.(Syncer); ok {
+		return syncer.SyncFS(path)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package storage
+
+import "syscall"
+
+// SyncFS commits all pending writes to stable storage. Since there is no way to restrict
+// this to a single file system, all file systems are synced (c.f. sync(2))
+func (OSFS) SyncFS(string) error {
+	syscall.Sync()
+	return nil
+}
+
+// SyncDir commits the entries of the named directory to stable storage (c.f. fsync(2))
+func (OSFS) SyncDir(path string) error {
+	return syncDir(path)
+}
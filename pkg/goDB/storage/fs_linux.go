@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package storage
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// SyncFS commits all pending writes to the file system the named path resides on (c.f. syncfs(2))
+func (OSFS) SyncFS(path string) error {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return unix.Syncfs(int(f.Fd()))
+}
+
+// SyncDir commits the entries of the named directory to stable storage (c.f. fsync(2))
+func (OSFS) SyncDir(path string) error {
+	return syncDir(path)
+}
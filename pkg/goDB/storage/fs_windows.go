@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package storage
+
+// SyncFS commits all pending writes to stable storage. Windows provides no means to flush an entire
+// file system without administrative privileges, hence this is a no-op (the goDB is only read on
+// this platform)
+func (OSFS) SyncFS(string) error {
+	return nil
+}
+
+// SyncDir commits the entries of the named directory to stable storage. Directories cannot be synced
+// on Windows, hence this is a no-op
+func (OSFS) SyncDir(string) error {
+	return nil
+}
@@ -0,0 +1,237 @@
+package gpfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/fako1024/gotools/bitpack"
+)
+
+// rewriteSuffix denotes the suffix of the temporary column files created while rewriting a GPDir
+const rewriteSuffix = ".rewrite"
+
+// BackfillBlocks writes a set of blocks for a timestamp irrespective of the blocks already present
+// in the directory and records its provenance in the metadata: If a block for the timestamp exists,
+// it is replaced (rendering the operation idempotent), if the timestamp precedes the most recent
+// block, the block is inserted in chronological order. Since the blocks of each column file are
+// stored contiguously and in chronological order, both cases require a rewrite of all column files.
+// It returns if an existing block was replaced
+func (d *GPDir) BackfillBlocks(timestamp int64, source BackfillSource, blockTraffic TrafficMetadata, counters types.Counters, dbData [types.ColIdxCount][]byte) (replaced bool, err error) {
+
+	if !d.isOpen {
+		return false, ErrDirNotOpen
+	}
+	if d.accessMode != ModeWrite {
+		return false, errors.New("cannot backfill GPDir in read mode")
+	}
+
+	// Blocks following the most recent one can simply be appended
+	if nBlocks := d.NBlocks(); nBlocks == 0 || timestamp > d.BlockMetadata[0].BlockList[nBlocks-1].Timestamp {
+		if err := d.WriteBlocks(timestamp, blockTraffic, counters, dbData); err != nil {
+			return false, err
+		}
+		d.addBackfill(timestamp, source)
+		return false, nil
+	}
+
+	if replaced, err = d.rewriteWithBlocks(timestamp, blockTraffic, counters, dbData); err != nil {
+		return false, err
+	}
+	d.addBackfill(timestamp, source)
+
+	return replaced, nil
+}
+
+// addBackfill records the provenance of a backfilled block (enabling the respective feature of
+// the metadata, if required)
+func (d *GPDir) addBackfill(timestamp int64, source BackfillSource) {
+	d.Metadata.Features |= FeatureBackfill
+
+	backfill := BackfillMetadata{
+		Timestamp:    timestamp,
+		BackfilledAt: time.Now().Unix(),
+		Source:       source,
+	}
+
+	idx := sort.Search(len(d.Backfills), func(i int) bool {
+		return d.Backfills[i].Timestamp >= timestamp
+	})
+	if idx < len(d.Backfills) && d.Backfills[idx].Timestamp == timestamp {
+		d.Backfills[idx] = backfill
+		return
+	}
+	d.Backfills = append(d.Backfills[:idx], append([]BackfillMetadata{backfill}, d.Backfills[idx:]...)...)
+}
+
+// rewriteWithBlocks rewrites all column files, replacing the existing block for the timestamp or
+// inserting it in chronological order. The metadata is only updated once all column files have
+// been rewritten successfully
+func (d *GPDir) rewriteWithBlocks(timestamp int64, blockTraffic TrafficMetadata, counters types.Counters, dbData [types.ColIdxCount][]byte) (bool, error) {
+
+	blockList := d.BlockMetadata[0].BlockList
+	blockIdx, replace := d.BlockMetadata[0].BlockIndex(timestamp)
+	if !replace {
+		blockIdx = sort.Search(len(blockList), func(i int) bool {
+			return blockList[i].Timestamp > timestamp
+		})
+	}
+
+	// Close any column file that has already been accessed (all data is flushed after each block)
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		if d.gpFiles[colIdx] != nil {
+			if err := d.gpFiles[colIdx].Close(); err != nil {
+				return false, err
+			}
+			d.gpFiles[colIdx] = nil
+		}
+	}
+
+	// Write all columns to temporary files first, cleaning up in case anything goes wrong
+	var (
+		headers         [types.ColIdxCount]*storage.BlockHeader
+		replacedCounter types.Counters
+	)
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		header, replacedSum, err := d.rewriteColumn(colIdx, blockIdx, replace, timestamp, dbData[colIdx])
+		if err != nil {
+			for i := types.ColumnIndex(0); i <= colIdx; i++ {
+				_ = d.fsys.Remove(d.columnPath(i) + rewriteSuffix)
+			}
+			return false, fmt.Errorf("failed to rewrite column %s: %w", types.ColumnFileNames[colIdx], err)
+		}
+		headers[colIdx] = header
+
+		switch colIdx {
+		case types.BytesRcvdColIdx:
+			replacedCounter.BytesRcvd = replacedSum
+		case types.BytesSentColIdx:
+			replacedCounter.BytesSent = replacedSum
+		case types.PacketsRcvdColIdx:
+			replacedCounter.PacketsRcvd = replacedSum
+		case types.PacketsSentColIdx:
+			replacedCounter.PacketsSent = replacedSum
+		}
+	}
+
+	// Move the rewritten column files into place. A column that doesn't contain any data
+	// (anymore) has no file at all
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		path := d.columnPath(colIdx)
+		if _, err := d.fsys.Stat(path + rewriteSuffix); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return false, err
+			}
+			if err := d.fsys.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return false, err
+			}
+			continue
+		}
+		if err := d.fsys.Rename(path+rewriteSuffix, path); err != nil {
+			return false, err
+		}
+	}
+
+	// Update the metadata (replacing or inserting the block traffic / latency information)
+	d.BlockMetadata = headers
+	if len(dbData[types.TagColIdx]) > 0 {
+		d.Metadata.Features |= FeatureTags
+	}
+	if len(dbData[types.TTLMinColIdx]) > 0 {
+		d.Metadata.Features |= FeatureTTL
+	}
+	if replace {
+		d.Metadata.Traffic = d.Metadata.Traffic.Sub(d.BlockTraffic[blockIdx])
+		d.Metadata.Counts = d.Metadata.Counts.Sub(replacedCounter)
+		d.BlockTraffic[blockIdx] = blockTraffic
+		if d.Metadata.hasHandshakeRTT() {
+			d.BlockLatency[blockIdx] = LatencyMetadata{}
+		}
+	} else {
+		d.BlockTraffic = append(d.BlockTraffic[:blockIdx], append([]TrafficMetadata{blockTraffic}, d.BlockTraffic[blockIdx:]...)...)
+		if d.Metadata.hasHandshakeRTT() {
+			d.BlockLatency = append(d.BlockLatency[:blockIdx], append([]LatencyMetadata{{}}, d.BlockLatency[blockIdx:]...)...)
+		}
+	}
+	d.Metadata.Traffic = d.Metadata.Traffic.Add(blockTraffic)
+	d.Metadata.Counts = d.Metadata.Counts.Add(counters)
+
+	return replace, nil
+}
+
+// rewriteColumn copies all blocks of a column to a temporary file, replacing / inserting the block
+// for the timestamp at the provided index. For counter columns, the sum of the values of a replaced
+// block is returned
+func (d *GPDir) rewriteColumn(colIdx types.ColumnIndex, blockIdx int, replace bool, timestamp int64, blockData []byte) (*storage.BlockHeader, uint64, error) {
+	path := d.columnPath(colIdx)
+	if err := d.fsys.Remove(path + rewriteSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, err
+	}
+
+	src, err := New(path, d.BlockMetadata[colIdx], ModeRead, d.options...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	header := &storage.BlockHeader{
+		BlockList:    make([]storage.BlockAtTime, 0, len(d.BlockMetadata[colIdx].BlockList)+1),
+		HasChecksums: d.hasChecksums(),
+	}
+	dst, err := New(path+rewriteSuffix, header, ModeWrite, d.options...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var replacedSum uint64
+	for i, block := range d.BlockMetadata[colIdx].BlockList {
+		if i == blockIdx {
+			if err := dst.writeBlock(timestamp, blockData); err != nil {
+				return nil, 0, closeWithError(dst, err)
+			}
+		}
+
+		isReplaced := i == blockIdx && replace
+		if isReplaced && !colIdx.IsCounterCol() {
+			continue
+		}
+
+		data, err := src.ReadBlockAtIndex(i)
+		if err != nil {
+			return nil, 0, closeWithError(dst, err)
+		}
+		if isReplaced {
+			// An empty block (e.g. one without any flows) does not contribute to the sum
+			if len(data) == 0 {
+				continue
+			}
+			for _, val := range bitpack.UnpackInto(data, nil) {
+				replacedSum += val
+			}
+			continue
+		}
+		if err := dst.writeBlock(block.Timestamp, data); err != nil {
+			return nil, 0, closeWithError(dst, err)
+		}
+	}
+
+	return header, replacedSum, dst.Close()
+}
+
+func (d *GPDir) columnPath(colIdx types.ColumnIndex) string {
+	return filepath.Join(d.Path(), types.ColumnFileNames[colIdx]+FileSuffix)
+}
+
+func closeWithError(g *GPFile, err error) error {
+	if cerr := g.Close(); cerr != nil {
+		return errors.Join(err, cerr)
+	}
+	return err
+}
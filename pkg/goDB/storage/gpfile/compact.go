@@ -0,0 +1,135 @@
+package gpfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// UnreferencedBytes returns the number of bytes stored in the column files of the directory that
+// are not referenced by any block (e.g. data left behind by an interrupted writeout)
+func (d *GPDir) UnreferencedBytes() (int64, error) {
+	if !d.isOpen {
+		return 0, ErrDirNotOpen
+	}
+
+	var unreferenced int64
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		n, err := d.unreferencedColumnBytes(colIdx)
+		if err != nil {
+			return 0, err
+		}
+		unreferenced += n
+	}
+	return unreferenced, nil
+}
+
+// Compact rewrites all column files of the directory that contain data not referenced by any
+// block, copying the (still encoded) data of all blocks without re-encoding it. The metadata
+// reflecting the new block offsets is written on Close(). It returns the number of bytes reclaimed
+func (d *GPDir) Compact() (reclaimed int64, err error) {
+	if !d.isOpen {
+		return 0, ErrDirNotOpen
+	}
+	if d.accessMode != ModeWrite {
+		return 0, errors.New("cannot compact GPDir in read mode")
+	}
+
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		unreferenced, err := d.unreferencedColumnBytes(colIdx)
+		if err != nil {
+			return reclaimed, err
+		}
+		if unreferenced == 0 {
+			continue
+		}
+
+		// Close the column file if it has already been accessed (all data is flushed after each block)
+		if d.gpFiles[colIdx] != nil {
+			if err := d.gpFiles[colIdx].Close(); err != nil {
+				return reclaimed, err
+			}
+			d.gpFiles[colIdx] = nil
+		}
+
+		header, err := d.compactColumn(colIdx)
+		if err != nil {
+			_ = d.fsys.Remove(d.columnPath(colIdx) + rewriteSuffix)
+			return reclaimed, fmt.Errorf("failed to compact column %s: %w", types.ColumnFileNames[colIdx], err)
+		}
+		if err := d.fsys.Rename(d.columnPath(colIdx)+rewriteSuffix, d.columnPath(colIdx)); err != nil {
+			return reclaimed, err
+		}
+
+		d.BlockMetadata[colIdx] = header
+		reclaimed += unreferenced
+	}
+
+	return reclaimed, nil
+}
+
+// unreferencedColumnBytes returns the difference between the size of a column file and the
+// amount of data referenced by its blocks
+func (d *GPDir) unreferencedColumnBytes(colIdx types.ColumnIndex) (int64, error) {
+	stat, err := d.fsys.Stat(d.columnPath(colIdx))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var referenced int64
+	for _, block := range d.BlockMetadata[colIdx].BlockList {
+		referenced += int64(block.Len)
+	}
+	if unreferenced := stat.Size() - referenced; unreferenced > 0 {
+		return unreferenced, nil
+	}
+	return 0, nil
+}
+
+// compactColumn copies the data of all blocks of a column contiguously to a temporary file
+func (d *GPDir) compactColumn(colIdx types.ColumnIndex) (*storage.BlockHeader, error) {
+	path := d.columnPath(colIdx)
+
+	src, err := d.fsys.OpenFile(path, ModeRead, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dst, err := d.fsys.OpenFile(path+rewriteSuffix, ModeWrite|os.O_TRUNC, d.permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	oldHeader := d.BlockMetadata[colIdx]
+	header := &storage.BlockHeader{
+		BlockList:    make([]storage.BlockAtTime, 0, len(oldHeader.BlockList)),
+		HasChecksums: oldHeader.HasChecksums,
+	}
+	for _, block := range oldHeader.BlockList {
+		if block.Len > 0 {
+			if _, err := src.Seek(int64(block.Offset), io.SeekStart); err != nil {
+				return nil, errors.Join(err, dst.Close())
+			}
+			if _, err := io.CopyN(dst, src, int64(block.Len)); err != nil {
+				return nil, errors.Join(err, dst.Close())
+			}
+		}
+
+		block.Offset = header.CurrentOffset
+		header.AddBlock(block.Timestamp, block.Block)
+		header.CurrentOffset += uint64(block.Len)
+	}
+
+	return header, dst.Close()
+}
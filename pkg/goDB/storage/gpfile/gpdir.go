@@ -1,12 +1,15 @@
 package gpfile
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/fs"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -24,8 +27,61 @@ const (
 
 	metadataFileName = ".blockmeta"
 	maxUint32        = 1<<32 - 1 // 4294967295
+	maxUint16        = 1<<16 - 1 // 65535
+
+	// headerSize denotes the size of the metadata header prefix (magic, byte order, version
+	// and feature flags)
+	headerSize = 16
+
+	// legacyHeaderSize denotes the size of the metadata header prefix of legacy versions
+	// (version only, always big endian)
+	legacyHeaderSize = 8
+)
+
+// Byte order declarations stored in the metadata header
+const (
+	byteOrderBigEndian    byte = 1
+	byteOrderLittleEndian byte = 2
+)
+
+// Feature flags stored in the metadata header
+const (
+
+	// FeatureChecksums denotes that per-block checksums are stored
+	FeatureChecksums uint64 = 1 << iota
+
+	// FeatureHandshakeRTT denotes that per-block TCP handshake round trip time summaries are stored
+	FeatureHandshakeRTT
+
+	// FeatureBackfill denotes that the provenance of backfilled blocks is stored
+	FeatureBackfill
+
+	// FeatureTags denotes that the tag column and its dictionary are stored
+	FeatureTags
+
+	// FeatureTTL denotes that the columns of the TTL / hop limit ranges of the flows are stored
+	FeatureTTL
+
+	// FeatureLinks denotes that the properties of the link backing the interface are stored
+	FeatureLinks
+
+	// FeatureEmergency denotes that the blocks written in emergency aggregation mode are marked
+	FeatureEmergency
+
+	// FeatureIdle denotes that the rotations without any traffic (for which no blocks were written) are stored
+	FeatureIdle
+
+	// FeatureHostnames denotes that the hostnames of the observed IP addresses (as resolved at capture time) are stored
+	FeatureHostnames
+
+	// supportedFeatures denotes all feature flags known to this implementation
+	supportedFeatures = FeatureChecksums | FeatureHandshakeRTT | FeatureBackfill | FeatureTags | FeatureTTL | FeatureLinks | FeatureEmergency | FeatureIdle | FeatureHostnames
 )
 
+// headerMagic denotes the magic bytes identifying a GPDir metadata file. Since legacy
+// headers start with a big endian version number, they can never match it
+var headerMagic = [4]byte{'G', 'P', 'D', 'B'}
+
 var (
 
 	// Global memory pool used to minimize allocations
@@ -38,8 +94,26 @@ var (
 	// ErrInputSizeTooSmall is thrown if the input size for desiralization is too small to be valid
 	ErrInputSizeTooSmall = errors.New("input size too small to be a GPDir metadata header")
 
+	// ErrUnsupportedVersion is thrown if the metadata header version is unknown
+	ErrUnsupportedVersion = errors.New("unsupported GPDir metadata header version")
+
+	// ErrUnsupportedByteOrder is thrown if the byte order declared in the metadata header is unknown
+	ErrUnsupportedByteOrder = errors.New("unsupported GPDir metadata byte order")
+
+	// ErrUnsupportedFeatures is thrown if the metadata header declares unknown feature flags
+	ErrUnsupportedFeatures = errors.New("unsupported GPDir metadata features")
+
 	// ErrDirNotOpen denotes that a GPDir is not (yet) open or has been closed
 	ErrDirNotOpen = errors.New("GPDir not open, call Open() first")
+
+	// ErrTooManyTags is thrown if the tag dictionary of a GPDir exceeds the maximum number of tags
+	ErrTooManyTags = errors.New("exceeded maximum number of tags per GPDir")
+
+	// ErrTooManyHostnames is thrown if the hostname dictionary of a GPDir exceeds the maximum number of hostnames
+	ErrTooManyHostnames = errors.New("exceeded maximum number of hostnames per GPDir")
+
+	// ErrInvalidHostname is thrown if a hostname cannot be stored (i.e. it is empty or exceeds 255 bytes)
+	ErrInvalidHostname = errors.New("invalid hostname")
 )
 
 // TrafficMetadata denotes a serializable set of metadata information about traffic stats
@@ -49,6 +123,103 @@ type TrafficMetadata struct {
 	NumDrops     uint64 `json:"num_drops"`
 }
 
+// LatencyMetadata denotes a serializable summary of the TCP handshake round trip times observed
+// for a block (all durations in microseconds)
+type LatencyMetadata struct {
+	NumHandshakes uint32 `json:"num_handshakes"`
+	MinRTT        uint32 `json:"min_rtt_us"`
+	MedianRTT     uint32 `json:"median_rtt_us"`
+	MaxRTT        uint32 `json:"max_rtt_us"`
+}
+
+// NewLatencyMetadata creates a new LatencyMetadata summary, clamping all values to the encoding
+// width of 32-bit
+func NewLatencyMetadata(numHandshakes int, minRTT, medianRTT, maxRTT time.Duration) LatencyMetadata {
+	return LatencyMetadata{
+		NumHandshakes: uint32(min(uint64(numHandshakes), maxUint32)),
+		MinRTT:        uint32(min(uint64(minRTT.Microseconds()), maxUint32)),
+		MedianRTT:     uint32(min(uint64(medianRTT.Microseconds()), maxUint32)),
+		MaxRTT:        uint32(min(uint64(maxRTT.Microseconds()), maxUint32)),
+	}
+}
+
+// BackfillSource denotes the origin of the data of a backfilled block
+type BackfillSource uint8
+
+const (
+
+	// BackfillSourceUnknown denotes a backfill from an unknown source
+	BackfillSourceUnknown BackfillSource = iota
+
+	// BackfillSourceSpill denotes a backfill from data retained after a failed writeout
+	BackfillSourceSpill
+
+	// BackfillSourcePcap denotes a backfill from a pcap file
+	BackfillSourcePcap
+
+	// BackfillSourceIngest denotes a backfill from externally generated flow aggregates
+	BackfillSourceIngest
+)
+
+// String returns a human-readable representation of the backfill source
+func (s BackfillSource) String() string {
+	switch s {
+	case BackfillSourceSpill:
+		return "spill"
+	case BackfillSourcePcap:
+		return "pcap"
+	case BackfillSourceIngest:
+		return "ingest"
+	default:
+		return "unknown"
+	}
+}
+
+// BackfillMetadata denotes the (serializable) provenance of a backfilled block
+type BackfillMetadata struct {
+	Timestamp    int64          `json:"timestamp"`     // Timestamp of the backfilled block
+	BackfilledAt int64          `json:"backfilled_at"` // Time of the backfill (unix seconds)
+	Source       BackfillSource `json:"source"`        // Origin of the block data
+}
+
+// LinkMetadata denotes the (serializable) properties of the link backing the interface, recorded
+// whenever they change. They apply to the block at Timestamp and all subsequent blocks (until the
+// next change)
+type LinkMetadata struct {
+	Timestamp int64 `json:"timestamp"` // Timestamp of the first block the link properties apply to
+	types.LinkInfo
+}
+
+// EmergencyMetadata denotes a (serializable) block whose flows were (partially) collapsed to the
+// prefixes of their IP addresses due to the emergency aggregation mode
+type EmergencyMetadata struct {
+	Timestamp  int64 `json:"timestamp"`   // Timestamp of the affected block
+	IPv4Prefix uint8 `json:"ipv4_prefix"` // Length of the prefixes IPv4 addresses were collapsed to
+	IPv6Prefix uint8 `json:"ipv6_prefix"` // Length of the prefixes IPv6 addresses were collapsed to
+}
+
+// IdleMetadata denotes a (serializable) span of consecutive rotations during which the interface was
+// captured without observing any traffic, hence no blocks were written
+type IdleMetadata struct {
+	From int64 `json:"from"` // Timestamp of the first idle rotation
+	To   int64 `json:"to"`   // Timestamp of the last idle rotation
+}
+
+// HostnameMetadata denotes the (serializable) hostname of an IP address as resolved at capture time,
+// recorded whenever it changes. It applies to the block at Timestamp and all subsequent blocks (until
+// the next change)
+type HostnameMetadata struct {
+	Timestamp int64      `json:"timestamp"` // Timestamp of the first block the hostname applies to
+	IP        netip.Addr `json:"ip"`        // IP address the hostname was resolved for
+	Name      uint16     `json:"name"`      // Index of the hostname in the hostname dictionary
+}
+
+// Hostname denotes the hostname of an IP address in effect as of a timestamp
+type Hostname struct {
+	Timestamp int64  // Timestamp of the first block the hostname applies to
+	Name      string // Hostname as resolved at capture time
+}
+
 // Stats denotes statistics for a GPDir instance
 type Stats struct {
 	Counts  types.Counters  `json:"counts"`
@@ -94,9 +265,22 @@ func (s Stats) Sub(s2 Stats) Stats {
 type Metadata struct {
 	BlockMetadata [types.ColIdxCount]*storage.BlockHeader
 	BlockTraffic  []TrafficMetadata
+	BlockLatency  []LatencyMetadata   // only populated if FeatureHandshakeRTT is set
+	Backfills     []BackfillMetadata  // only populated if FeatureBackfill is set (ordered by block timestamp)
+	Tags          []string            // only populated if FeatureTags is set (tag column value n refers to Tags[n-1])
+	Links         []LinkMetadata      // only populated if FeatureLinks is set (ordered by timestamp)
+	Emergency     []EmergencyMetadata // only populated if FeatureEmergency is set (ordered by block timestamp)
+	Idle          []IdleMetadata      // only populated if FeatureIdle is set (ordered by timestamp)
+	Hostnames     []HostnameMetadata  // only populated if FeatureHostnames is set (ordered by timestamp)
+	HostnameDict  []string            // only populated if FeatureHostnames is set (HostnameMetadata.Name n refers to HostnameDict[n])
+
+	// lookup indices of the hostnames (lazily built upon the first recorded hostname)
+	hostnameIdx     map[netip.Addr]int
+	hostnameDictIdx map[string]uint16
 
 	Stats
-	Version uint64
+	Version  uint16
+	Features uint64
 }
 
 // newMetadata initializes a new Metadata set (internal / serialization use only)
@@ -104,16 +288,308 @@ func newMetadata() *Metadata {
 	m := Metadata{
 		BlockTraffic: make([]TrafficMetadata, 0),
 		Version:      headerVersion,
+		Features:     FeatureChecksums,
 	}
 	for i := 0; i < int(types.ColIdxCount); i++ {
 		m.BlockMetadata[i] = &storage.BlockHeader{
 			CurrentOffset: 0,
 			BlockList:     make([]storage.BlockAtTime, 0),
+			HasChecksums:  m.hasChecksums(),
 		}
 	}
 	return &m
 }
 
+// hasChecksums returns if per-block checksums are stored as part of the metadata
+func (m *Metadata) hasChecksums() bool {
+	return m.Features&FeatureChecksums != 0
+}
+
+// hasHandshakeRTT returns if per-block TCP handshake round trip time summaries are stored as
+// part of the metadata
+func (m *Metadata) hasHandshakeRTT() bool {
+	return m.Features&FeatureHandshakeRTT != 0
+}
+
+// hasBackfills returns if the provenance of backfilled blocks is stored as part of the metadata
+func (m *Metadata) hasBackfills() bool {
+	return m.Features&FeatureBackfill != 0
+}
+
+// hasTags returns if the tag column and its dictionary are stored as part of the metadata
+func (m *Metadata) hasTags() bool {
+	return m.Features&FeatureTags != 0
+}
+
+// hasTTL returns if the TTL / hop limit columns are stored as part of the metadata
+func (m *Metadata) hasTTL() bool {
+	return m.Features&FeatureTTL != 0
+}
+
+// hasEmergency returns if the blocks written in emergency aggregation mode are marked as part of
+// the metadata
+func (m *Metadata) hasEmergency() bool {
+	return m.Features&FeatureEmergency != 0
+}
+
+// hasIdle returns if the rotations without any traffic are stored as part of the metadata
+func (m *Metadata) hasIdle() bool {
+	return m.Features&FeatureIdle != 0
+}
+
+// hasHostnames returns if the hostnames of the observed IP addresses are stored as part of the metadata
+func (m *Metadata) hasHostnames() bool {
+	return m.Features&FeatureHostnames != 0
+}
+
+// hasLinks returns if the properties of the link backing the interface are stored as part of the
+// metadata
+func (m *Metadata) hasLinks() bool {
+	return m.Features&FeatureLinks != 0
+}
+
+// hasColumn returns if a column is stored as part of the metadata (the optional columns only
+// being stored if the respective feature is enabled)
+func (m *Metadata) hasColumn(colIdx types.ColumnIndex) bool {
+	switch colIdx {
+	case types.TagColIdx:
+		return m.hasTags()
+	case types.TTLMinColIdx, types.TTLMaxColIdx:
+		return m.hasTTL()
+	}
+	return true
+}
+
+// numColumns returns the number of columns stored as part of the metadata
+func (m *Metadata) numColumns() (n int) {
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		if m.hasColumn(colIdx) {
+			n++
+		}
+	}
+	return
+}
+
+// TagIndex returns the value representing a tag in the tag column of this GPDir, adding it to
+// the tag dictionary (and enabling the respective feature of the metadata) if required
+func (m *Metadata) TagIndex(name string) (byte, error) {
+	for i, tag := range m.Tags {
+		if tag == name {
+			return byte(i + 1), nil
+		}
+	}
+	if len(m.Tags) >= types.MaxTags {
+		return 0, ErrTooManyTags
+	}
+
+	m.Features |= FeatureTags
+	m.Tags = append(m.Tags, name)
+	return byte(len(m.Tags)), nil
+}
+
+// SetLink records the properties of the link backing the interface as of the block at the provided
+// timestamp (enabling the respective feature of the metadata, if required). Since the properties
+// rarely change, they are only recorded if they differ from the ones currently in effect
+func (m *Metadata) SetLink(timestamp int64, link types.LinkInfo) {
+	if current, exists := m.LinkAt(timestamp); exists && current == link {
+		return
+	}
+	m.Features |= FeatureLinks
+
+	idx := sort.Search(len(m.Links), func(i int) bool {
+		return m.Links[i].Timestamp >= timestamp
+	})
+	entry := LinkMetadata{Timestamp: timestamp, LinkInfo: link}
+	if idx < len(m.Links) && m.Links[idx].Timestamp == timestamp {
+		m.Links[idx] = entry
+		return
+	}
+	m.Links = append(m.Links[:idx], append([]LinkMetadata{entry}, m.Links[idx:]...)...)
+}
+
+// LinkAt returns the properties of the link backing the interface in effect for the block at the
+// provided timestamp (if any were recorded)
+func (m *Metadata) LinkAt(timestamp int64) (types.LinkInfo, bool) {
+	idx := sort.Search(len(m.Links), func(i int) bool {
+		return m.Links[i].Timestamp > timestamp
+	})
+	if idx == 0 {
+		return types.LinkInfo{}, false
+	}
+	return m.Links[idx-1].LinkInfo, true
+}
+
+// MarkEmergency marks the block at the provided timestamp as written in emergency aggregation mode
+// (enabling the respective feature of the metadata, if required)
+func (m *Metadata) MarkEmergency(timestamp int64, ipv4Prefix, ipv6Prefix uint8) {
+	m.Features |= FeatureEmergency
+
+	idx := sort.Search(len(m.Emergency), func(i int) bool {
+		return m.Emergency[i].Timestamp >= timestamp
+	})
+	entry := EmergencyMetadata{Timestamp: timestamp, IPv4Prefix: ipv4Prefix, IPv6Prefix: ipv6Prefix}
+	if idx < len(m.Emergency) && m.Emergency[idx].Timestamp == timestamp {
+		m.Emergency[idx] = entry
+		return
+	}
+	m.Emergency = append(m.Emergency[:idx], append([]EmergencyMetadata{entry}, m.Emergency[idx:]...)...)
+}
+
+// EmergencyAt returns if (and how) the block at the provided timestamp was written in emergency
+// aggregation mode
+func (m *Metadata) EmergencyAt(timestamp int64) (EmergencyMetadata, bool) {
+	idx := sort.Search(len(m.Emergency), func(i int) bool {
+		return m.Emergency[i].Timestamp >= timestamp
+	})
+	if idx < len(m.Emergency) && m.Emergency[idx].Timestamp == timestamp {
+		return m.Emergency[idx], true
+	}
+	return EmergencyMetadata{}, false
+}
+
+// MarkIdle records the rotations between the provided timestamps (inclusive) as idle, i.e. without any
+// traffic (enabling the respective feature of the metadata, if required). Unless a block was written in
+// the meantime, the most recent span of idle rotations is extended instead of starting a new one
+func (m *Metadata) MarkIdle(from, to int64) {
+	m.Features |= FeatureIdle
+
+	if n := len(m.Idle); n > 0 {
+		nBlocks := m.BlockMetadata[0].NBlocks()
+		if nBlocks == 0 || m.Idle[n-1].To > m.BlockMetadata[0].BlockList[nBlocks-1].Timestamp {
+			m.Idle[n-1].From = min(m.Idle[n-1].From, from)
+			m.Idle[n-1].To = max(m.Idle[n-1].To, to)
+			return
+		}
+	}
+	m.Idle = append(m.Idle, IdleMetadata{From: from, To: to})
+}
+
+// IdleWithin returns all spans of idle rotations overlapping the provided time range (inclusive),
+// clipped to it
+func (m *Metadata) IdleWithin(first, last int64) []IdleMetadata {
+	var spans []IdleMetadata
+	for _, span := range m.Idle {
+		if span.To < first || span.From > last {
+			continue
+		}
+		spans = append(spans, IdleMetadata{From: max(span.From, first), To: min(span.To, last)})
+	}
+	return spans
+}
+
+// SetHostname records the hostname of an IP address as of the block at the provided timestamp (enabling
+// the respective feature of the metadata, if required), which must not precede the timestamp of any
+// hostname recorded before. Since hostnames rarely change, they are only recorded if they differ from
+// the one currently in effect. Hostnames are dictionary-encoded, i.e. each distinct name is only stored once
+func (m *Metadata) SetHostname(timestamp int64, ip netip.Addr, name string) error {
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: `%s`", ErrInvalidHostname, name)
+	}
+
+	if m.hostnameIdx == nil {
+		m.hostnameIdx = make(map[netip.Addr]int, len(m.Hostnames))
+		for i, hostname := range m.Hostnames {
+			m.hostnameIdx[hostname.IP] = i
+		}
+		m.hostnameDictIdx = make(map[string]uint16, len(m.HostnameDict))
+		for i, name := range m.HostnameDict {
+			m.hostnameDictIdx[name] = uint16(i)
+		}
+	}
+	if idx, exists := m.hostnameIdx[ip]; exists && m.HostnameDict[m.Hostnames[idx].Name] == name {
+		return nil
+	}
+
+	nameIdx, exists := m.hostnameDictIdx[name]
+	if !exists {
+		if len(m.HostnameDict) >= maxUint16 {
+			return ErrTooManyHostnames
+		}
+		nameIdx = uint16(len(m.HostnameDict))
+		m.hostnameDictIdx[name] = nameIdx
+		m.HostnameDict = append(m.HostnameDict, name)
+	}
+	m.Features |= FeatureHostnames
+
+	m.hostnameIdx[ip] = len(m.Hostnames)
+	m.Hostnames = append(m.Hostnames, HostnameMetadata{Timestamp: timestamp, IP: ip, Name: nameIdx})
+	return nil
+}
+
+// HostnamesAt returns the hostnames of all IP addresses in effect for the block at the provided
+// timestamp (if any were recorded)
+func (m *Metadata) HostnamesAt(timestamp int64) map[netip.Addr]Hostname {
+	hostnames := make(map[netip.Addr]Hostname)
+	for _, hostname := range m.Hostnames {
+		if hostname.Timestamp > timestamp {
+			break
+		}
+		hostnames[hostname.IP] = Hostname{Timestamp: hostname.Timestamp, Name: m.HostnameDict[hostname.Name]}
+	}
+	return hostnames
+}
+
+// TagName returns the tag represented by a value of the tag column of this GPDir. For untagged
+// flows an empty string is returned
+func (m *Metadata) TagName(idx byte) string {
+	if idx == 0 || int(idx) > len(m.Tags) {
+		return ""
+	}
+	return m.Tags[idx-1]
+}
+
+// unmarshalHeader parses the header prefix of serialized metadata, supporting both the current
+// and the legacy layout, and returns the byte order of the remaining data and its start position
+func (m *Metadata) unmarshalHeader(data []byte) (binary.ByteOrder, int, error) {
+
+	// Legacy headers only consist of a big endian version number and imply all features
+	// introduced up to that version
+	if !bytes.Equal(data[0:4], headerMagic[:]) {
+		version := binary.BigEndian.Uint64(data[0:8])
+		if version >= headerVersionMagic {
+			return nil, 0, fmt.Errorf("%w (legacy version: %d)", ErrUnsupportedVersion, version)
+		}
+		m.Version, m.Features = uint16(version), 0
+		if version >= headerVersionChecksums {
+			m.Features |= FeatureChecksums
+		}
+		return binary.BigEndian, legacyHeaderSize, nil
+	}
+
+	var byteOrder binary.ByteOrder
+	switch data[4] {
+	case byteOrderBigEndian:
+		byteOrder = binary.BigEndian
+	case byteOrderLittleEndian:
+		byteOrder = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("%w (%d)", ErrUnsupportedByteOrder, data[4])
+	}
+
+	m.Version = byteOrder.Uint16(data[6:8])
+	if m.Version < headerVersionMagic || m.Version > headerVersion {
+		return nil, 0, fmt.Errorf("%w (version: %d)", ErrUnsupportedVersion, m.Version)
+	}
+	m.Features = byteOrder.Uint64(data[8:16])
+	if unknown := m.Features &^ supportedFeatures; unknown != 0 {
+		return nil, 0, fmt.Errorf("%w (flags: %#x)", ErrUnsupportedFeatures, unknown)
+	}
+
+	return byteOrder, headerSize, nil
+}
+
+// marshalHeader writes the header prefix of the metadata using the provided byte order
+func (m *Metadata) marshalHeader(data []byte, byteOrder binary.ByteOrder) {
+	copy(data[0:4], headerMagic[:])
+	data[4] = byteOrderBigEndian
+	if byteOrder == binary.LittleEndian {
+		data[4] = byteOrderLittleEndian
+	}
+	data[5] = 0 // reserved
+	byteOrder.PutUint16(data[6:8], headerVersion)
+	byteOrder.PutUint64(data[8:16], m.Features)
+}
+
 // GPDir denotes a timestamped goDB directory (usually a daily set of blocks)
 type GPDir struct {
 	gpFiles [types.ColIdxCount]*GPFile // Set of GPFile (lazy-load)
@@ -124,6 +600,9 @@ type GPDir struct {
 	metaPath    string      // Full path to GPDir metadata
 	accessMode  int         // Access mode (also forwarded to all GPFiles)
 	permissions os.FileMode // Permissions (also forwarded to all GPFiles)
+	fsys        storage.FS  // File system (also forwarded to all GPFiles)
+
+	syncPolicy storage.SyncPolicy // Policy governing when written data is committed to stable storage
 
 	isOpen bool
 	*Metadata
@@ -135,6 +614,7 @@ func NewDir(basePath string, timestamp int64, accessMode int, options ...Option)
 		basePath:    filepath.Clean(strings.TrimSuffix(basePath, "/")),
 		accessMode:  accessMode,
 		permissions: defaultPermissions,
+		fsys:        storage.DefaultFS,
 		options:     options,
 	}
 
@@ -162,7 +642,7 @@ func (d *GPDir) Open(options ...Option) error {
 	}
 
 	// Attempt to read the metadata from file
-	metadataFile, err := os.Open(d.MetadataPath())
+	metadataFile, err := d.fsys.OpenFile(d.MetadataPath(), os.O_RDONLY, 0)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 
@@ -221,6 +701,37 @@ func (d *GPDir) ReadBlockAtIndex(colIdx types.ColumnIndex, blockIdx int) ([]byte
 
 // WriteBlocks writes a set of blocks to the underlying GPFiles and updates the metadata
 func (d *GPDir) WriteBlocks(timestamp int64, blockTraffic TrafficMetadata, counters types.Counters, dbData [types.ColIdxCount][]byte) error {
+	if err := d.writeBlocks(timestamp, blockTraffic, counters, dbData); err != nil {
+		return err
+	}
+
+	// Keep the latency summaries aligned with the blocks if the directory stores them
+	if d.Metadata.hasHandshakeRTT() {
+		d.Metadata.BlockLatency = append(d.Metadata.BlockLatency, LatencyMetadata{})
+	}
+
+	return nil
+}
+
+// WriteBlocksWithLatency writes the data of all columns like WriteBlocks and additionally stores
+// a summary of the TCP handshake round trip times observed for the block (enabling the respective
+// feature of the metadata, if required)
+func (d *GPDir) WriteBlocksWithLatency(timestamp int64, blockTraffic TrafficMetadata, blockLatency LatencyMetadata, counters types.Counters, dbData [types.ColIdxCount][]byte) error {
+	if err := d.writeBlocks(timestamp, blockTraffic, counters, dbData); err != nil {
+		return err
+	}
+
+	// Blocks written before the feature was enabled don't have a latency summary
+	if !d.Metadata.hasHandshakeRTT() {
+		d.Metadata.Features |= FeatureHandshakeRTT
+		d.Metadata.BlockLatency = make([]LatencyMetadata, len(d.Metadata.BlockTraffic)-1, len(d.Metadata.BlockTraffic))
+	}
+	d.Metadata.BlockLatency = append(d.Metadata.BlockLatency, blockLatency)
+
+	return nil
+}
+
+func (d *GPDir) writeBlocks(timestamp int64, blockTraffic TrafficMetadata, counters types.Counters, dbData [types.ColIdxCount][]byte) error {
 	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
 
 		// Load column if required
@@ -235,6 +746,15 @@ func (d *GPDir) WriteBlocks(timestamp int64, blockTraffic TrafficMetadata, count
 		}
 	}
 
+	// Blocks written before any flow was tagged (or before TTLs were recorded) have empty
+	// blocks in the respective columns
+	if len(dbData[types.TagColIdx]) > 0 {
+		d.Metadata.Features |= FeatureTags
+	}
+	if len(dbData[types.TTLMinColIdx]) > 0 || len(dbData[types.TTLMaxColIdx]) > 0 {
+		d.Metadata.Features |= FeatureTTL
+	}
+
 	// Update global block info / counters
 	d.Metadata.BlockTraffic = append(d.Metadata.BlockTraffic, blockTraffic)
 	d.Metadata.Traffic = d.Metadata.Traffic.Add(blockTraffic)
@@ -269,35 +789,60 @@ func (d *GPDir) Unmarshal(r concurrency.ReadWriteSeekCloser) error {
 	}()
 
 	data := memFile.Data()
-	if len(data) < 16 {
+	if len(data) < headerSize {
 		return fmt.Errorf("%w (len: %d)", ErrInputSizeTooSmall, len(data))
 	}
 
 	d.Metadata = newMetadata()
 
-	d.Metadata.Version = binary.BigEndian.Uint64(data[0:8])                // Get header version
-	nBlocks := int(binary.BigEndian.Uint64(data[8:16]))                    // Get flat nummber of blocks
-	d.Metadata.Traffic.NumV4Entries = binary.BigEndian.Uint64(data[16:24]) // Get global number of IPv4 flows
-	d.Metadata.Traffic.NumV6Entries = binary.BigEndian.Uint64(data[24:32]) // Get global number of IPv6 flows
-	d.Metadata.Traffic.NumDrops = binary.BigEndian.Uint64(data[32:40])     // Get global number of dropped packets
-	d.Metadata.Counts.BytesRcvd = binary.BigEndian.Uint64(data[40:48])     // Get global Counters (BytesRcvd)
-	d.Metadata.Counts.BytesSent = binary.BigEndian.Uint64(data[48:56])     // Get global Counters (BytesSent)
-	d.Metadata.Counts.PacketsRcvd = binary.BigEndian.Uint64(data[56:64])   // Get global Counters (PacketsRcvd)
-	d.Metadata.Counts.PacketsSent = binary.BigEndian.Uint64(data[64:72])   // Get global Counters (PacketsSent)
-	pos := 72
+	// Get header information (version, feature flags and the byte order of all remaining data)
+	byteOrder, pos, err := d.Metadata.unmarshalHeader(data)
+	if err != nil {
+		return err
+	}
+
+	nBlocks := int(byteOrder.Uint64(data[pos : pos+8]))                       // Get flat nummber of blocks
+	d.Metadata.Traffic.NumV4Entries = byteOrder.Uint64(data[pos+8 : pos+16])  // Get global number of IPv4 flows
+	d.Metadata.Traffic.NumV6Entries = byteOrder.Uint64(data[pos+16 : pos+24]) // Get global number of IPv6 flows
+	d.Metadata.Traffic.NumDrops = byteOrder.Uint64(data[pos+24 : pos+32])     // Get global number of dropped packets
+	d.Metadata.Counts.BytesRcvd = byteOrder.Uint64(data[pos+32 : pos+40])     // Get global Counters (BytesRcvd)
+	d.Metadata.Counts.BytesSent = byteOrder.Uint64(data[pos+40 : pos+48])     // Get global Counters (BytesSent)
+	d.Metadata.Counts.PacketsRcvd = byteOrder.Uint64(data[pos+48 : pos+56])   // Get global Counters (PacketsRcvd)
+	d.Metadata.Counts.PacketsSent = byteOrder.Uint64(data[pos+56 : pos+64])   // Get global Counters (PacketsSent)
+	pos += 64
+
+	// Metadata without the respective feature flag does not carry per-block checksums
+	hasChecksums := d.Metadata.hasChecksums()
 
 	// Get block information
 	for i := 0; i < int(types.ColIdxCount); i++ {
-		d.BlockMetadata[i].CurrentOffset = binary.BigEndian.Uint64(data[pos : pos+8])
+
+		// Metadata without the respective feature flag does not carry the optional columns, in
+		// which case all their blocks are empty (e.g. all flows are untagged)
+		if !d.Metadata.hasColumn(types.ColumnIndex(i)) {
+			d.BlockMetadata[i].BlockList = make([]storage.BlockAtTime, nBlocks)
+			d.BlockMetadata[i].HasChecksums = hasChecksums
+			for j := 0; j < nBlocks; j++ {
+				d.BlockMetadata[i].BlockList[j].EncoderType = encoders.EncoderTypeNull
+			}
+			continue
+		}
+
+		d.BlockMetadata[i].CurrentOffset = byteOrder.Uint64(data[pos : pos+8])
 		d.BlockMetadata[i].BlockList = make([]storage.BlockAtTime, nBlocks)
+		d.BlockMetadata[i].HasChecksums = hasChecksums
 		pos += 8
 		curOffset := uint64(0)
 		for j := 0; j < nBlocks; j++ {
 			d.BlockMetadata[i].BlockList[j].Offset = curOffset
-			d.BlockMetadata[i].BlockList[j].Len = binary.BigEndian.Uint32(data[pos : pos+4])
-			d.BlockMetadata[i].BlockList[j].RawLen = binary.BigEndian.Uint32(data[pos+4 : pos+8])
+			d.BlockMetadata[i].BlockList[j].Len = byteOrder.Uint32(data[pos : pos+4])
+			d.BlockMetadata[i].BlockList[j].RawLen = byteOrder.Uint32(data[pos+4 : pos+8])
 			d.BlockMetadata[i].BlockList[j].EncoderType = encoders.Type(data[pos+8])
 			pos += 9
+			if hasChecksums {
+				d.BlockMetadata[i].BlockList[j].Checksum = byteOrder.Uint32(data[pos : pos+4])
+				pos += 4
+			}
 
 			curOffset += uint64(d.BlockMetadata[i].BlockList[j].Len)
 		}
@@ -305,13 +850,13 @@ func (d *GPDir) Unmarshal(r concurrency.ReadWriteSeekCloser) error {
 
 	// Get Metadata.NumIPV4Entries
 	d.BlockTraffic = make([]TrafficMetadata, nBlocks)
-	lastTimestamp := int64(binary.BigEndian.Uint64(data[pos : pos+8]))
+	lastTimestamp := int64(byteOrder.Uint64(data[pos : pos+8]))
 	pos += 8
 	for i := 0; i < nBlocks; i++ {
-		d.BlockTraffic[i].NumV4Entries = uint64(binary.BigEndian.Uint32(data[pos : pos+4]))
-		d.BlockTraffic[i].NumV6Entries = uint64(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
-		d.BlockTraffic[i].NumDrops = uint64(binary.BigEndian.Uint32(data[pos+8 : pos+12]))
-		thisTimestamp := lastTimestamp + int64(binary.BigEndian.Uint32(data[pos+12:pos+16]))
+		d.BlockTraffic[i].NumV4Entries = uint64(byteOrder.Uint32(data[pos : pos+4]))
+		d.BlockTraffic[i].NumV6Entries = uint64(byteOrder.Uint32(data[pos+4 : pos+8]))
+		d.BlockTraffic[i].NumDrops = uint64(byteOrder.Uint32(data[pos+8 : pos+12]))
+		thisTimestamp := lastTimestamp + int64(byteOrder.Uint32(data[pos+12:pos+16]))
 		for j := 0; j < int(types.ColIdxCount); j++ {
 			d.BlockMetadata[j].BlockList[i].Timestamp = thisTimestamp
 		}
@@ -319,15 +864,119 @@ func (d *GPDir) Unmarshal(r concurrency.ReadWriteSeekCloser) error {
 		pos += 16
 	}
 
+	// Get per-block handshake round trip time summaries (if present)
+	if d.Metadata.hasHandshakeRTT() {
+		d.BlockLatency = make([]LatencyMetadata, nBlocks)
+		for i := 0; i < nBlocks; i++ {
+			d.BlockLatency[i].NumHandshakes = byteOrder.Uint32(data[pos : pos+4])
+			d.BlockLatency[i].MinRTT = byteOrder.Uint32(data[pos+4 : pos+8])
+			d.BlockLatency[i].MedianRTT = byteOrder.Uint32(data[pos+8 : pos+12])
+			d.BlockLatency[i].MaxRTT = byteOrder.Uint32(data[pos+12 : pos+16])
+			pos += 16
+		}
+	}
+
+	// Get provenance of backfilled blocks (if present)
+	if d.Metadata.hasBackfills() && nBlocks > 0 {
+		nBackfills := int(byteOrder.Uint64(data[pos : pos+8]))
+		pos += 8
+		d.Backfills = make([]BackfillMetadata, nBackfills)
+		for i := 0; i < nBackfills; i++ {
+			d.Backfills[i].Timestamp = int64(byteOrder.Uint64(data[pos : pos+8]))
+			d.Backfills[i].BackfilledAt = int64(byteOrder.Uint64(data[pos+8 : pos+16]))
+			d.Backfills[i].Source = BackfillSource(data[pos+16])
+			pos += 17
+		}
+	}
+
+	// Get tag dictionary (if present)
+	if d.Metadata.hasTags() && nBlocks > 0 {
+		nTags := int(data[pos])
+		pos++
+		d.Tags = make([]string, nTags)
+		for i := 0; i < nTags; i++ {
+			tagLen := int(data[pos])
+			d.Tags[i] = string(data[pos+1 : pos+1+tagLen])
+			pos += 1 + tagLen
+		}
+	}
+
+	// Get link properties (if present)
+	if d.Metadata.hasLinks() && nBlocks > 0 {
+		nLinks := int(byteOrder.Uint16(data[pos : pos+2]))
+		pos += 2
+		d.Links = make([]LinkMetadata, nLinks)
+		for i := 0; i < nLinks; i++ {
+			d.Links[i].Timestamp = int64(byteOrder.Uint64(data[pos : pos+8]))
+			d.Links[i].MTU = int(byteOrder.Uint32(data[pos+8 : pos+12]))
+			d.Links[i].Speed = byteOrder.Uint32(data[pos+12 : pos+16])
+			pos += 16
+			d.Links[i].MAC, pos = unmarshalString(data, pos)
+			d.Links[i].Driver, pos = unmarshalString(data, pos)
+		}
+	}
+
+	// Get blocks written in emergency aggregation mode (if present)
+	if d.Metadata.hasEmergency() && nBlocks > 0 {
+		nEmergency := int(byteOrder.Uint64(data[pos : pos+8]))
+		pos += 8
+		d.Emergency = make([]EmergencyMetadata, nEmergency)
+		for i := 0; i < nEmergency; i++ {
+			d.Emergency[i].Timestamp = int64(byteOrder.Uint64(data[pos : pos+8]))
+			d.Emergency[i].IPv4Prefix = data[pos+8]
+			d.Emergency[i].IPv6Prefix = data[pos+9]
+			pos += 10
+		}
+	}
+
+	// Get spans of idle rotations (if present)
+	if d.Metadata.hasIdle() && nBlocks > 0 {
+		nIdle := int(byteOrder.Uint64(data[pos : pos+8]))
+		pos += 8
+		d.Idle = make([]IdleMetadata, nIdle)
+		for i := 0; i < nIdle; i++ {
+			d.Idle[i].From = int64(byteOrder.Uint64(data[pos : pos+8]))
+			d.Idle[i].To = int64(byteOrder.Uint64(data[pos+8 : pos+16]))
+			pos += 16
+		}
+	}
+
+	// Get hostname dictionary and hostnames (if present)
+	if d.Metadata.hasHostnames() && nBlocks > 0 {
+		nNames := int(byteOrder.Uint16(data[pos : pos+2]))
+		pos += 2
+		d.HostnameDict = make([]string, nNames)
+		for i := 0; i < nNames; i++ {
+			d.HostnameDict[i], pos = unmarshalString(data, pos)
+		}
+		nHostnames := int(byteOrder.Uint64(data[pos : pos+8]))
+		pos += 8
+		d.Hostnames = make([]HostnameMetadata, nHostnames)
+		for i := 0; i < nHostnames; i++ {
+			d.Hostnames[i].Timestamp = int64(byteOrder.Uint64(data[pos : pos+8]))
+			ipLen := int(data[pos+8])
+			d.Hostnames[i].IP, _ = netip.AddrFromSlice(data[pos+9 : pos+9+ipLen])
+			pos += 9 + ipLen
+			d.Hostnames[i].Name = byteOrder.Uint16(data[pos : pos+2])
+			pos += 2
+		}
+	}
+
 	return nil
 }
 
 // Marshal marshals and writes the metadata of the GPDir instance into serialized metadata set
 func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
+	return d.marshal(w, binary.BigEndian)
+}
+
+func (d *GPDir) marshal(w concurrency.ReadWriteSeekCloser, byteOrder binary.ByteOrder) error {
 
 	nBlocks := len(d.BlockTraffic)
-	size := 8 + // Overall number of blocks
-		8 + // Metadata.Version
+	hasChecksums := d.Metadata.hasChecksums()
+	nColumns := d.Metadata.numColumns()
+	size := headerSize + // Magic, byte order, Metadata.Version and Metadata.Features
+		8 + // Overall number of blocks
 		8 + // Metadata.NumV4Entries
 		8 + // Metadata.NumV6Entries
 		8 + // Metadata.NumDrops
@@ -337,10 +986,83 @@ func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
 		nBlocks*4 + // Metadata.GlobalBlockMetadata.NumV6Entries
 		nBlocks*4 + // Metadata.GlobalBlockMetadata.NumDrops
 		nBlocks*4 + // Metadata.BlockMetadata.BlockList.Timestamp (Delta)
-		int(types.ColIdxCount)*8 + // Metadata.BlockMetadata.CurrentOffset
-		nBlocks*int(types.ColIdxCount)*4 + // Metadata.BlockMetadata.BlockList.Len
-		nBlocks*int(types.ColIdxCount)*4 + // Metadata.BlockMetadata.BlockList.RawLen
-		nBlocks*int(types.ColIdxCount) // Metadata.BlockMetadata.BlockList.Block.EncoderType
+		nColumns*8 + // Metadata.BlockMetadata.CurrentOffset
+		nBlocks*nColumns*4 + // Metadata.BlockMetadata.BlockList.Len
+		nBlocks*nColumns*4 + // Metadata.BlockMetadata.BlockList.RawLen
+		nBlocks*nColumns // Metadata.BlockMetadata.BlockList.Block.EncoderType
+	if hasChecksums {
+		size += nBlocks * nColumns * 4 // Metadata.BlockMetadata.BlockList.Block.Checksum
+	}
+	hasHandshakeRTT := d.Metadata.hasHandshakeRTT()
+	if hasHandshakeRTT {
+		if len(d.BlockLatency) != nBlocks {
+			return fmt.Errorf("mismatched number of latency summaries, want %d, have %d", nBlocks, len(d.BlockLatency))
+		}
+		size += nBlocks * 4 * 4 // Metadata.BlockLatency
+	}
+	hasBackfills := d.Metadata.hasBackfills() && nBlocks > 0
+	if hasBackfills {
+		size += 8 + // Number of backfilled blocks
+			len(d.Backfills)*17 // Metadata.Backfills
+	}
+	hasTags := d.Metadata.hasTags() && nBlocks > 0
+	if hasTags {
+		if len(d.Tags) > types.MaxTags {
+			return ErrTooManyTags
+		}
+		size++ // Number of tags
+		for _, tag := range d.Tags {
+			if len(tag) > 255 {
+				return fmt.Errorf("%w: tag `%s` exceeds maximum length", types.ErrInvalidTag, tag)
+			}
+			size += 1 + len(tag) // Metadata.Tags
+		}
+	}
+
+	hasLinks := d.Metadata.hasLinks() && nBlocks > 0
+	if hasLinks {
+		if len(d.Links) > maxUint16 {
+			return fmt.Errorf("number of link property changes exceeds maximum of %d", maxUint16)
+		}
+		size += 2 // Number of link property changes
+		for _, link := range d.Links {
+			if len(link.MAC) > 255 || len(link.Driver) > 255 {
+				return fmt.Errorf("link properties at %d exceed maximum length", link.Timestamp)
+			}
+			size += 8 + 4 + 4 + // Metadata.Links.Timestamp / MTU / Speed
+				1 + len(link.MAC) + 1 + len(link.Driver) // Metadata.Links.MAC / Driver
+		}
+	}
+
+	hasEmergency := d.Metadata.hasEmergency() && nBlocks > 0
+	if hasEmergency {
+		size += 8 + // Number of blocks written in emergency aggregation mode
+			len(d.Emergency)*10 // Metadata.Emergency
+	}
+
+	hasIdle := d.Metadata.hasIdle() && nBlocks > 0
+	if hasIdle {
+		size += 8 + // Number of spans of idle rotations
+			len(d.Idle)*16 // Metadata.Idle
+	}
+
+	hasHostnames := d.Metadata.hasHostnames() && nBlocks > 0
+	if hasHostnames {
+		if len(d.HostnameDict) > maxUint16 {
+			return ErrTooManyHostnames
+		}
+		size += 2 // Number of hostnames in the dictionary
+		for _, name := range d.HostnameDict {
+			if len(name) > 255 {
+				return fmt.Errorf("%w: `%s`", ErrInvalidHostname, name)
+			}
+			size += 1 + len(name) // Metadata.HostnameDict
+		}
+		size += 8 // Number of recorded hostnames
+		for _, hostname := range d.Hostnames {
+			size += 8 + 1 + hostname.IP.BitLen()/8 + 2 // Metadata.Hostnames.Timestamp / IP / Name
+		}
+	}
 
 	// Note: Lengths and timestamp deltas are encoded as uint32s, allowing for a maximum block (!) size of
 	// 4 GiB (uncompressed / compressed).
@@ -351,22 +1073,25 @@ func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
 	data := metaDataMemPool.Get(size)
 	defer metaDataMemPool.Put(data)
 
-	binary.BigEndian.PutUint64(data[0:8], d.Metadata.Version)                // Store header version
-	binary.BigEndian.PutUint64(data[8:16], uint64(nBlocks))                  // Store flat nummber of blocks
-	binary.BigEndian.PutUint64(data[16:24], d.Metadata.Traffic.NumV4Entries) // Store global number of IPv4 flows
-	binary.BigEndian.PutUint64(data[24:32], d.Metadata.Traffic.NumV6Entries) // Store global number of IPv6 flows
-	binary.BigEndian.PutUint64(data[32:40], d.Metadata.Traffic.NumDrops)     // Store global number of dropped packets
-	binary.BigEndian.PutUint64(data[40:48], d.Metadata.Counts.BytesRcvd)     // Store global Counters (BytesRcvd)
-	binary.BigEndian.PutUint64(data[48:56], d.Metadata.Counts.BytesSent)     // Store global Counters (BytesSent)
-	binary.BigEndian.PutUint64(data[56:64], d.Metadata.Counts.PacketsRcvd)   // Store global Counters (PacketsRcvd)
-	binary.BigEndian.PutUint64(data[64:72], d.Metadata.Counts.PacketsSent)   // Store global Counters (PacketsSent)
-	pos := 72
+	d.Metadata.marshalHeader(data, byteOrder)                         // Store header (always using the current version)
+	byteOrder.PutUint64(data[16:24], uint64(nBlocks))                 // Store flat nummber of blocks
+	byteOrder.PutUint64(data[24:32], d.Metadata.Traffic.NumV4Entries) // Store global number of IPv4 flows
+	byteOrder.PutUint64(data[32:40], d.Metadata.Traffic.NumV6Entries) // Store global number of IPv6 flows
+	byteOrder.PutUint64(data[40:48], d.Metadata.Traffic.NumDrops)     // Store global number of dropped packets
+	byteOrder.PutUint64(data[48:56], d.Metadata.Counts.BytesRcvd)     // Store global Counters (BytesRcvd)
+	byteOrder.PutUint64(data[56:64], d.Metadata.Counts.BytesSent)     // Store global Counters (BytesSent)
+	byteOrder.PutUint64(data[64:72], d.Metadata.Counts.PacketsRcvd)   // Store global Counters (PacketsRcvd)
+	byteOrder.PutUint64(data[72:80], d.Metadata.Counts.PacketsSent)   // Store global Counters (PacketsSent)
+	pos := 80
 
 	if nBlocks > 0 {
 
 		// Store block information
 		for i := 0; i < int(types.ColIdxCount); i++ {
-			binary.BigEndian.PutUint64(data[pos:pos+8], d.BlockMetadata[i].CurrentOffset)
+			if !d.Metadata.hasColumn(types.ColumnIndex(i)) {
+				continue
+			}
+			byteOrder.PutUint64(data[pos:pos+8], d.BlockMetadata[i].CurrentOffset)
 			pos += 8
 			for _, block := range d.BlockMetadata[i].BlockList {
 
@@ -375,16 +1100,20 @@ func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
 					return ErrExceedsEncodingSize
 				}
 
-				binary.BigEndian.PutUint32(data[pos:pos+4], block.Len)
-				binary.BigEndian.PutUint32(data[pos+4:pos+8], block.RawLen)
+				byteOrder.PutUint32(data[pos:pos+4], block.Len)
+				byteOrder.PutUint32(data[pos+4:pos+8], block.RawLen)
 				data[pos+8] = byte(block.EncoderType)
 				pos += 9
+				if hasChecksums {
+					byteOrder.PutUint32(data[pos:pos+4], block.Checksum)
+					pos += 4
+				}
 			}
 		}
 
 		// Store Metadata.NumIPV4Entries
 		lastTimestamp := d.BlockMetadata[0].BlockList[0].Timestamp
-		binary.BigEndian.PutUint64(data[pos:pos+8], uint64(lastTimestamp))
+		byteOrder.PutUint64(data[pos:pos+8], uint64(lastTimestamp))
 		pos += 8
 		for i := 0; i < len(d.BlockTraffic); i++ {
 
@@ -396,13 +1125,102 @@ func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
 				return ErrExceedsEncodingSize
 			}
 
-			binary.BigEndian.PutUint32(data[pos:pos+4], uint32(d.BlockTraffic[i].NumV4Entries))
-			binary.BigEndian.PutUint32(data[pos+4:pos+8], uint32(d.BlockTraffic[i].NumV6Entries))
-			binary.BigEndian.PutUint32(data[pos+8:pos+12], uint32(d.BlockTraffic[i].NumDrops))
-			binary.BigEndian.PutUint32(data[pos+12:pos+16], uint32(d.BlockMetadata[0].BlockList[i].Timestamp-lastTimestamp))
+			byteOrder.PutUint32(data[pos:pos+4], uint32(d.BlockTraffic[i].NumV4Entries))
+			byteOrder.PutUint32(data[pos+4:pos+8], uint32(d.BlockTraffic[i].NumV6Entries))
+			byteOrder.PutUint32(data[pos+8:pos+12], uint32(d.BlockTraffic[i].NumDrops))
+			byteOrder.PutUint32(data[pos+12:pos+16], uint32(d.BlockMetadata[0].BlockList[i].Timestamp-lastTimestamp))
 			lastTimestamp = d.BlockMetadata[0].BlockList[i].Timestamp
 			pos += 16
 		}
+
+		// Store Metadata.BlockLatency
+		if hasHandshakeRTT {
+			for _, latency := range d.BlockLatency {
+				byteOrder.PutUint32(data[pos:pos+4], latency.NumHandshakes)
+				byteOrder.PutUint32(data[pos+4:pos+8], latency.MinRTT)
+				byteOrder.PutUint32(data[pos+8:pos+12], latency.MedianRTT)
+				byteOrder.PutUint32(data[pos+12:pos+16], latency.MaxRTT)
+				pos += 16
+			}
+		}
+
+		// Store Metadata.Backfills
+		if hasBackfills {
+			byteOrder.PutUint64(data[pos:pos+8], uint64(len(d.Backfills)))
+			pos += 8
+			for _, backfill := range d.Backfills {
+				byteOrder.PutUint64(data[pos:pos+8], uint64(backfill.Timestamp))
+				byteOrder.PutUint64(data[pos+8:pos+16], uint64(backfill.BackfilledAt))
+				data[pos+16] = byte(backfill.Source)
+				pos += 17
+			}
+		}
+
+		// Store Metadata.Tags
+		if hasTags {
+			data[pos] = byte(len(d.Tags))
+			pos++
+			for _, tag := range d.Tags {
+				data[pos] = byte(len(tag))
+				pos += 1 + copy(data[pos+1:], tag)
+			}
+		}
+
+		// Store Metadata.Links
+		if hasLinks {
+			byteOrder.PutUint16(data[pos:pos+2], uint16(len(d.Links)))
+			pos += 2
+			for _, link := range d.Links {
+				byteOrder.PutUint64(data[pos:pos+8], uint64(link.Timestamp))
+				byteOrder.PutUint32(data[pos+8:pos+12], uint32(link.MTU))
+				byteOrder.PutUint32(data[pos+12:pos+16], link.Speed)
+				pos += 16
+				pos = marshalString(data, pos, link.MAC)
+				pos = marshalString(data, pos, link.Driver)
+			}
+		}
+
+		// Store Metadata.Emergency
+		if hasEmergency {
+			byteOrder.PutUint64(data[pos:pos+8], uint64(len(d.Emergency)))
+			pos += 8
+			for _, emergency := range d.Emergency {
+				byteOrder.PutUint64(data[pos:pos+8], uint64(emergency.Timestamp))
+				data[pos+8] = emergency.IPv4Prefix
+				data[pos+9] = emergency.IPv6Prefix
+				pos += 10
+			}
+		}
+
+		// Store Metadata.Idle
+		if hasIdle {
+			byteOrder.PutUint64(data[pos:pos+8], uint64(len(d.Idle)))
+			pos += 8
+			for _, idle := range d.Idle {
+				byteOrder.PutUint64(data[pos:pos+8], uint64(idle.From))
+				byteOrder.PutUint64(data[pos+8:pos+16], uint64(idle.To))
+				pos += 16
+			}
+		}
+
+		// Store Metadata.HostnameDict / Metadata.Hostnames
+		if hasHostnames {
+			byteOrder.PutUint16(data[pos:pos+2], uint16(len(d.HostnameDict)))
+			pos += 2
+			for _, name := range d.HostnameDict {
+				pos = marshalString(data, pos, name)
+			}
+			byteOrder.PutUint64(data[pos:pos+8], uint64(len(d.Hostnames)))
+			pos += 8
+			for _, hostname := range d.Hostnames {
+				byteOrder.PutUint64(data[pos:pos+8], uint64(hostname.Timestamp))
+				ip := hostname.IP.AsSlice()
+				data[pos+8] = byte(len(ip))
+				pos += 9 + copy(data[pos+9:], ip)
+				byteOrder.PutUint16(data[pos:pos+2], hostname.Name)
+				pos += 2
+			}
+		}
 	}
 
 	n, err := w.Write(data)
@@ -417,6 +1235,20 @@ func (d *GPDir) Marshal(w concurrency.ReadWriteSeekCloser) error {
 	return nil
 }
 
+// marshalString writes a length-prefixed string (of at most 255 bytes) at the provided position and
+// returns the position following it
+func marshalString(data []byte, pos int, str string) int {
+	data[pos] = byte(len(str))
+	return pos + 1 + copy(data[pos+1:], str)
+}
+
+// unmarshalString reads a length-prefixed string at the provided position and returns it, along with
+// the position following it
+func unmarshalString(data []byte, pos int) (string, int) {
+	strLen := int(data[pos])
+	return string(data[pos+1 : pos+1+strLen]), pos + 1 + strLen
+}
+
 // Path returns the path of the GPDir (up to the timestamp)
 func (d *GPDir) Path() string {
 	return d.dirPath
@@ -452,6 +1284,16 @@ func (d *GPDir) Close() error {
 	// Ensure resources are marked for cleanup
 	defer func() {
 		d.Metadata.BlockTraffic = nil
+		d.Metadata.BlockLatency = nil
+		d.Metadata.Backfills = nil
+		d.Metadata.Tags = nil
+		d.Metadata.Links = nil
+		d.Metadata.Emergency = nil
+		d.Metadata.Idle = nil
+		d.Metadata.Hostnames = nil
+		d.Metadata.HostnameDict = nil
+		d.Metadata.hostnameIdx = nil
+		d.Metadata.hostnameDictIdx = nil
 		for i := 0; i < int(types.ColIdxCount); i++ {
 			d.Metadata.BlockMetadata[i].BlockList = nil
 			d.Metadata.BlockMetadata[i] = nil
@@ -490,20 +1332,52 @@ func (d *GPDir) Column(colIdx types.ColumnIndex) (*GPFile, error) {
 	return d.gpFiles[colIdx], nil
 }
 
-// createIfRequired created the underlying path structure (if missing)
+// createIfRequired created the underlying path structure (if missing). If required by the sync
+// policy, the entries of all newly created directories are committed to stable storage
 func (d *GPDir) createIfRequired() error {
-	return os.MkdirAll(d.dirPath, calculateDirPerm(d.permissions))
+	if !d.syncPolicy.SyncsDirs() {
+		return d.fsys.MkdirAll(d.dirPath, calculateDirPerm(d.permissions))
+	}
+
+	// Determine the closest existing ancestor, which receives the entry of the topmost created
+	// directory (and hence has to be synced as well)
+	ancestor := d.dirPath
+	for {
+		if _, err := d.fsys.Stat(ancestor); err == nil {
+			break
+		}
+		parent := filepath.Dir(ancestor)
+		if parent == ancestor {
+			break
+		}
+		ancestor = parent
+	}
+	if ancestor == d.dirPath {
+		return nil
+	}
+
+	if err := d.fsys.MkdirAll(d.dirPath, calculateDirPerm(d.permissions)); err != nil {
+		return err
+	}
+	for dir := d.dirPath; ; dir = filepath.Dir(dir) {
+		if err := storage.SyncDir(d.fsys, dir); err != nil {
+			return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+		}
+		if dir == ancestor {
+			return nil
+		}
+	}
 }
 
 func (d *GPDir) writeMetadataAtomic() error {
 
 	// Create a temporary file (in the destinantion directory to avoid moving accross the FS barrier)
-	tempFile, err := os.CreateTemp(d.dirPath, ".tmp-metadata-*")
+	tempFile, err := d.fsys.CreateTemp(d.dirPath, ".tmp-metadata-*")
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if cerr := os.Remove(tempFile.Name()); cerr != nil && err == nil {
+		if cerr := d.fsys.Remove(tempFile.Name()); cerr != nil && err == nil {
 			err = cerr
 		}
 	}()
@@ -512,23 +1386,43 @@ func (d *GPDir) writeMetadataAtomic() error {
 	if err = d.Marshal(tempFile); err != nil {
 		return err
 	}
+	if d.syncPolicy == storage.SyncAlways {
+		if err = storage.SyncFile(tempFile); err != nil {
+			_ = tempFile.Close()
+			return err
+		}
+	}
 	if err = tempFile.Close(); err != nil {
 		return err
 	}
 
 	// Set permissions / file mode
-	if err = os.Chmod(tempFile.Name(), d.permissions); err != nil {
+	if err = d.fsys.Chmod(tempFile.Name(), d.permissions); err != nil {
 		return err
 	}
 
-	// Move the temporary file
-	return os.Rename(tempFile.Name(), d.MetadataPath())
+	// Move the temporary file (committing the renamed entry if required)
+	if err = d.fsys.Rename(tempFile.Name(), d.MetadataPath()); err != nil {
+		return err
+	}
+	if d.syncPolicy == storage.SyncAlways {
+		return storage.SyncDir(d.fsys, d.dirPath)
+	}
+	return nil
 }
 
 func (d *GPDir) setPermissions(permissions fs.FileMode) {
 	d.permissions = permissions
 }
 
+func (d *GPDir) setFS(fsys storage.FS) {
+	d.fsys = fsys
+}
+
+func (d *GPDir) setSyncPolicy(policy storage.SyncPolicy) {
+	d.syncPolicy = policy
+}
+
 // GenPathForTimestamp provides a unified generator method that allows to construct the path to
 // the data on disk based on a base path and a timestamp
 func GenPathForTimestamp(basePath string, timestamp int64) string {
@@ -2,7 +2,11 @@ package gpfile
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -14,8 +18,16 @@ import (
 	"github.com/fako1024/gotools/concurrency"
 )
 
-// Global pool for reusable memory buffers
-var bufPool = concurrency.NewMemPoolNoLimit()
+var (
+	// Global pool for reusable memory buffers
+	bufPool = concurrency.NewMemPoolNoLimit()
+
+	// checksumTable denotes the CRC32 (Castagnoli) table used for block checksums
+	checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// ErrChecksumMismatch is thrown if the data of a block does not match its stored checksum
+	ErrChecksumMismatch = errors.New("block checksum mismatch")
+)
 
 const (
 	// FileSuffix denotes the suffix used for the raw data stored
@@ -32,7 +44,18 @@ const (
 	bufferPreallocSize = 8192
 
 	// headerVersion denotes the current header version
-	headerVersion = 1
+	headerVersion = headerVersionMagic
+
+	// MetadataVersion denotes the current version of the GPDir metadata, i.e. the most recent
+	// version that can be read by this implementation
+	MetadataVersion uint16 = headerVersion
+
+	// headerVersionChecksums denotes the first (legacy) header version storing per-block checksums
+	headerVersionChecksums = 2
+
+	// headerVersionMagic denotes the first header version carrying a magic prefix, an explicit
+	// byte order declaration and feature flags
+	headerVersionMagic = 3
 
 	// ModeRead denotes read access
 	ModeRead = os.O_RDONLY
@@ -70,11 +93,23 @@ type GPFile struct {
 	accessMode  int
 	permissions fs.FileMode
 
+	// fsys denotes the file system the GPF file resides on
+	fsys storage.FS
+
+	// syncPolicy denotes when written data is committed to stable storage
+	syncPolicy storage.SyncPolicy
+
 	// Reusable buffers for compression / decompression
 	uncompData, blockData []byte
 
+	// verifiedBlock provides the data of a block read in full (and verified against its checksum)
+	verifiedBlock bytes.Reader
+
 	// Memory pool (optional)
 	memPool concurrency.MemPoolGCable
+
+	// mmap denotes if the file is accessed via a memory mapping (read mode only)
+	mmap bool
 }
 
 // New returns a new GPFile object to read and write goProbe flow data
@@ -84,6 +119,7 @@ func New(filename string, header *storage.BlockHeader, accessMode int, options .
 		header:             header,
 		accessMode:         accessMode,
 		permissions:        defaultPermissions,
+		fsys:               storage.DefaultFS,
 		defaultEncoderType: defaultEncoderType,
 		freeEncoder:        true,
 	}
@@ -170,6 +206,13 @@ func (g *GPFile) ReadBlockAtIndex(idx int) ([]byte, error) {
 		g.uncompData = make([]byte, 0, 2*block.RawLen)
 	}
 	g.uncompData = g.uncompData[:block.RawLen]
+	src, err := g.blockReader(block.Block)
+	if err != nil {
+		// make sure that the next read seeks to the correct position, since the amount of
+		// data consumed from the file is unknown
+		g.lastSeekPos = -1
+		return nil, err
+	}
 	if block.EncoderType != encoders.EncoderTypeNull {
 
 		// Instantiate decoder / decompressor (if required)
@@ -185,14 +228,17 @@ func (g *GPFile) ReadBlockAtIndex(idx int) ([]byte, error) {
 			g.blockData = make([]byte, 0, 2*block.Len)
 		}
 		g.blockData = g.blockData[:block.Len]
-		nRead, err = g.defaultEncoder.Decompress(g.blockData, g.uncompData, g.file)
+		nRead, err = g.defaultEncoder.Decompress(g.blockData, g.uncompData, src)
 	} else {
 		// micro-optimization that saves the allocation of blockData for decompression
 		// in the Null decompression case, since it is essentially just a byte read
 		// and the src bytes aren't used
-		nRead, err = null.DefaultEncoder.Decompress(nil, g.uncompData, g.file)
+		nRead, err = null.DefaultEncoder.Decompress(nil, g.uncompData, src)
 	}
 	if err != nil {
+		// make sure that the next read seeks to the correct position, since the amount of
+		// data consumed from the file is unknown
+		g.lastSeekPos = -1
 		return nil, err
 	}
 	if uint32(nRead) != block.RawLen {
@@ -232,8 +278,10 @@ func (g *GPFile) writeBlock(timestamp int64, blockData []byte) error {
 		}
 	}
 
-	// Compress + write block data to file (append)
-	nWritten, err := g.defaultEncoder.Compress(blockData, g.blockData, g.fileWriteBuffer)
+	// Compress + write block data to file (append), computing the checksum of the
+	// written data on the fly
+	checksum := crc32.New(checksumTable)
+	nWritten, err := g.defaultEncoder.Compress(blockData, g.blockData, io.MultiWriter(g.fileWriteBuffer, checksum))
 	if err != nil {
 		return err
 	}
@@ -244,7 +292,8 @@ func (g *GPFile) writeBlock(timestamp int64, blockData []byte) error {
 	if nWritten > len(blockData) {
 		encType = encoders.EncoderTypeNull
 		g.fileWriteBuffer.Reset(g.file)
-		nWritten, err = null.DefaultEncoder.Compress(blockData, g.blockData, g.fileWriteBuffer)
+		checksum.Reset()
+		nWritten, err = null.DefaultEncoder.Compress(blockData, g.blockData, io.MultiWriter(g.fileWriteBuffer, checksum))
 		if err != nil {
 			return fmt.Errorf("failed to re-encode with %s encoder: %w", encType, err)
 		}
@@ -259,12 +308,37 @@ func (g *GPFile) writeBlock(timestamp int64, blockData []byte) error {
 		Len:         uint32(nWritten),
 		RawLen:      uint32(len(blockData)),
 		EncoderType: encType,
+		Checksum:    checksum.Sum32(),
 	})
 	g.header.CurrentOffset += uint64(nWritten)
 
 	return nil
 }
 
+// blockReader returns the reader to consume the (compressed) data of a block from. If the
+// header carries checksums, the whole block is read and verified before it is handed to the
+// decoder, since the underlying reader may return the data in several chunks
+func (g *GPFile) blockReader(block storage.Block) (io.Reader, error) {
+	if !g.header.HasChecksums {
+		return g.file, nil
+	}
+
+	if uint32(cap(g.blockData)) < block.Len {
+		g.blockData = make([]byte, 0, 2*block.Len)
+	}
+	g.blockData = g.blockData[:block.Len]
+	if _, err := io.ReadFull(g.file, g.blockData); err != nil {
+		return nil, err
+	}
+	if checksum := crc32.Checksum(g.blockData, checksumTable); checksum != block.Checksum {
+		return nil, fmt.Errorf("%w: want %08x, have %08x", ErrChecksumMismatch, block.Checksum, checksum)
+	}
+
+	// Decoders consume the block from the same buffer it was read into (which is a no-op copy)
+	g.verifiedBlock.Reset(g.blockData)
+	return &g.verifiedBlock, nil
+}
+
 // RawFile returns the raw underlying file as a concurrency.ReadWriteSeekCloser
 func (g *GPFile) RawFile() concurrency.ReadWriteSeekCloser {
 	return g.file
@@ -281,6 +355,12 @@ func (g *GPFile) Close() error {
 		}
 	}
 	if g.file != nil {
+		if g.accessMode == ModeWrite && g.syncPolicy == storage.SyncAlways {
+			if err := storage.SyncFile(g.file); err != nil {
+				_ = g.file.Close()
+				return fmt.Errorf("failed to sync file %s: %w", g.filename, err)
+			}
+		}
 		return g.file.Close()
 	}
 	return nil
@@ -288,7 +368,7 @@ func (g *GPFile) Close() error {
 
 // Delete removes the file and its metadata
 func (g *GPFile) delete() error {
-	return os.Remove(g.filename)
+	return g.fsys.Remove(g.filename)
 }
 
 // Filename exposes the location of the GPF file
@@ -309,7 +389,7 @@ func (g *GPFile) open() (err error) {
 	}
 
 	// Open file for append, create if not exists
-	if g.file, err = os.OpenFile(g.filename, g.accessMode, g.permissions); err != nil {
+	if g.file, err = g.fsys.OpenFile(g.filename, g.accessMode, g.permissions); err != nil {
 		return fmt.Errorf("failed to open file %s: %w", g.filename, err)
 	}
 	if g.accessMode == ModeWrite {
@@ -321,6 +401,14 @@ func (g *GPFile) open() (err error) {
 		}
 		g.fileWriteBuffer = bufio.NewWriter(g.file)
 	}
+	if g.accessMode == ModeRead && g.mmap {
+
+		// Fall back to regular reads if the file cannot be mapped (e.g. on a non-OS file system)
+		if mapped, merr := newMmapFile(g.file); merr == nil {
+			g.file = mapped
+			return
+		}
+	}
 	if g.accessMode == ModeRead && g.memPool != nil {
 		if g.file, err = concurrency.NewMemFile(g.file, g.memPool); err != nil {
 			return err
@@ -334,6 +422,18 @@ func (g *GPFile) setPermissions(permissions fs.FileMode) {
 	g.permissions = permissions
 }
 
+func (g *GPFile) setFS(fsys storage.FS) {
+	g.fsys = fsys
+}
+
+func (g *GPFile) setSyncPolicy(policy storage.SyncPolicy) {
+	g.syncPolicy = policy
+}
+
+func (g *GPFile) setMmap(enable bool) {
+	g.mmap = enable
+}
+
 func (g *GPFile) setMemPool(pool concurrency.MemPoolGCable) {
 	g.memPool = pool
 }
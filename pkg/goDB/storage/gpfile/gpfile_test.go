@@ -4,16 +4,24 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io/fs"
+	"math/rand"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/els0r/goProbe/pkg/goDB/encoder"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
 	"github.com/els0r/goProbe/pkg/goDB/storage"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/fako1024/gotools/bitpack"
+	"github.com/fako1024/gotools/concurrency"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
 )
@@ -217,6 +225,107 @@ func testRoundtrip(t *testing.T, encType encoders.Type) {
 	require.Nil(t, gpf.Close(), "failed to close test file")
 }
 
+func TestMmap(t *testing.T) {
+
+	m := newMetadata()
+	gpf, err := New(testFilePath, m.BlockMetadata[0], ModeWrite)
+	require.Nil(t, err, "failed to create new GPFile")
+	for i := 0; i < 100; i++ {
+		data := make([]byte, 8*i)
+		for j := 0; j < i; j++ {
+			binary.BigEndian.PutUint64(data[8*j:], uint64(i))
+		}
+		require.Nil(t, gpf.writeBlock(int64(i), data), "failed to write block")
+	}
+	require.Nil(t, gpf.Close(), "failed to close test file")
+	defer func(t *testing.T) {
+		require.Nil(t, gpf.delete())
+	}(t)
+
+	ref, err := New(testFilePath, m.BlockMetadata[0], ModeRead)
+	require.Nil(t, err, "failed to read GPFile")
+	mapped, err := New(testFilePath, m.BlockMetadata[0], ModeRead, WithMmap(true))
+	require.Nil(t, err, "failed to read GPFile")
+
+	// Read out of order to ensure that seeking works as expected
+	for _, i := range append(rand.Perm(100), 0, 1, 2) {
+		refData, err := ref.ReadBlockAtIndex(i)
+		require.Nilf(t, err, "failed to read block %d", i)
+		data, err := mapped.ReadBlockAtIndex(i)
+		require.Nilf(t, err, "failed to read block %d via mmap", i)
+		require.Equalf(t, refData, data, "unexpected data at block %d", i)
+	}
+	if runtime.GOOS == "linux" {
+		require.IsType(t, &mmapFile{}, mapped.RawFile())
+	}
+	require.Nil(t, ref.Close(), "failed to close test file")
+	require.Nil(t, mapped.Close(), "failed to close test file")
+
+	// Files that cannot be mapped must transparently fall back to regular reads
+	fsys := godbtest.NewMemFS()
+	raw, err := os.ReadFile(testFilePath)
+	require.Nil(t, err)
+	f, err := fsys.OpenFile("/test.gpf", os.O_CREATE|os.O_WRONLY, 0600)
+	require.Nil(t, err)
+	_, err = f.Write(raw)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	mapped, err = New("/test.gpf", m.BlockMetadata[0], ModeRead, WithFS(fsys), WithMmap(true))
+	require.Nil(t, err, "failed to read GPFile")
+	data, err := mapped.ReadBlockAtIndex(99)
+	require.Nil(t, err)
+	require.Equal(t, uint64(99), binary.BigEndian.Uint64(data))
+	_, isMapped := mapped.RawFile().(*mmapFile)
+	require.False(t, isMapped)
+	require.Nil(t, mapped.Close(), "failed to close test file")
+}
+
+func BenchmarkReadBlocks(b *testing.B) {
+
+	// A full day of blocks (at the default writeout interval) with some realistic data
+	m := newMetadata()
+	gpf, err := New(testFilePath, m.BlockMetadata[0], ModeWrite, WithEncoderTypeLevel(encoders.EncoderTypeNull, 0))
+	require.Nil(b, err, "failed to create new GPFile")
+	data := make([]byte, 64*1024)
+	for i := 0; i < 288; i++ {
+		binary.BigEndian.PutUint64(data, uint64(i))
+		require.Nil(b, gpf.writeBlock(int64(i), data), "failed to write block")
+	}
+	require.Nil(b, gpf.Close(), "failed to close test file")
+	defer func(b *testing.B) {
+		require.Nil(b, gpf.delete())
+	}(b)
+
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"buffered", nil},
+		{"read-all", []Option{WithReadAll(concurrency.NewMemPool(1))}},
+		{"mmap", []Option{WithMmap(true)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data) * 288))
+			for i := 0; i < b.N; i++ {
+				f, err := New(testFilePath, m.BlockMetadata[0], ModeRead, c.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < 288; j++ {
+					if _, err := f.ReadBlockAtIndex(j); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := f.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestInvalidMetadata(t *testing.T) {
 
 	require.Nil(t, os.RemoveAll("/tmp/test_db"))
@@ -258,6 +367,7 @@ func TestMetadataRoundTrip(t *testing.T) {
 			Len:         10001,
 			RawLen:      100,
 			EncoderType: 0,
+			Checksum:    0xdeadbeef,
 		})
 		testDir.BlockMetadata[i].AddBlock(1575245000, storage.Block{
 			Offset:      10001,
@@ -290,6 +400,13 @@ func TestMetadataRoundTrip(t *testing.T) {
 	for _, blockTraffic := range testDir.BlockTraffic {
 		testDir.Metadata.Traffic = testDir.Metadata.Traffic.Add(blockTraffic)
 	}
+	for _, tag := range []string{"voip", "backup"} {
+		_, err := testDir.Metadata.TagIndex(tag)
+		require.Nil(t, err)
+	}
+
+	// The TTL columns are only stored if TTLs were recorded (as is the case for the blocks above)
+	testDir.Metadata.Features |= FeatureTTL
 
 	// Need to jump through hoops here in order to create a real deep copy of the metadata
 	buf := bytes.NewBuffer(nil)
@@ -302,6 +419,7 @@ func TestMetadataRoundTrip(t *testing.T) {
 	require.Nil(t, testDir.Open(), "error opening test dir for reading")
 
 	require.Equal(t, testDir.Metadata.BlockTraffic, refMetadata.BlockTraffic, "mismatched global block metadata")
+	require.Equal(t, []string{"voip", "backup"}, testDir.Metadata.Tags, "mismatched tag dictionary")
 	for i := 0; i < int(types.ColIdxCount); i++ {
 		require.Equal(t, testDir.Metadata.BlockMetadata[i], refMetadata.BlockMetadata[i], "mismatched block metadata")
 	}
@@ -319,6 +437,754 @@ func TestMetadataRoundTrip(t *testing.T) {
 	require.Equal(t, sumDrops, int(testDir.Metadata.Traffic.NumDrops), "mismatched number of total packet drops vs. computed")
 }
 
+func TestChecksumValidation(t *testing.T) {
+	for _, encType := range testEncoders {
+		t.Run(encType.String(), func(t *testing.T) {
+			m := newMetadata()
+
+			enc, err := encoder.New(encType)
+			require.Nilf(t, err, "failed to create encoder of type %s", encType)
+
+			gpf, err := New(testFilePath, m.BlockMetadata[0], ModeWrite, WithEncoder(enc))
+			require.Nil(t, err, "failed to create new GPFile")
+			defer func(t *testing.T) {
+				require.Nil(t, gpf.delete())
+			}(t)
+
+			for i := 0; i < 3; i++ {
+				require.Nil(t, gpf.writeBlock(int64(i), bytes.Repeat([]byte{byte(i + 1)}, 1024)), "failed to write block")
+			}
+			require.Nil(t, gpf.Close(), "failed to close test file")
+
+			// corrupt the data of the second block on disk
+			data, err := os.ReadFile(testFilePath)
+			require.Nil(t, err)
+			corruptBlock := m.BlockMetadata[0].BlockList[1]
+			data[corruptBlock.Offset+uint64(corruptBlock.Len)/2] ^= 0xff
+			require.Nil(t, os.WriteFile(testFilePath, data, 0600))
+
+			gpf, err = New(testFilePath, m.BlockMetadata[0], ModeRead)
+			require.Nil(t, err, "failed to read GPFile")
+
+			for i := 0; i < 3; i++ {
+				blockData, err := gpf.ReadBlockAtIndex(i)
+				if i == 1 {
+					require.ErrorIs(t, err, ErrChecksumMismatch)
+					continue
+				}
+				require.Nilf(t, err, "failed to read block %d", i)
+				require.Equal(t, bytes.Repeat([]byte{byte(i + 1)}, 1024), blockData)
+			}
+			require.Nil(t, gpf.Close(), "failed to close test file")
+		})
+	}
+}
+
+// shortReadFS wraps the file system of the operating system, returning files that never read
+// more than half of the requested data at once (as e.g. network file systems might do)
+type shortReadFS struct {
+	storage.OSFS
+}
+
+func (s shortReadFS) OpenFile(name string, flag int, perm fs.FileMode) (storage.File, error) {
+	f, err := s.OSFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return shortReadFile{f}, nil
+}
+
+type shortReadFile struct {
+	storage.File
+}
+
+func (f shortReadFile) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:len(p)/2]
+	}
+	return f.File.Read(p)
+}
+
+func TestChecksumShortReads(t *testing.T) {
+	for _, encType := range testEncoders {
+		t.Run(encType.String(), func(t *testing.T) {
+			m := newMetadata()
+
+			gpf, err := New(testFilePath, m.BlockMetadata[0], ModeWrite, WithEncoderTypeLevel(encType, 0))
+			require.Nil(t, err, "failed to create new GPFile")
+			defer func(t *testing.T) {
+				require.Nil(t, gpf.delete())
+			}(t)
+
+			for i := 0; i < 3; i++ {
+				require.Nil(t, gpf.writeBlock(int64(i), bytes.Repeat([]byte{byte(i + 1)}, 1024)), "failed to write block")
+			}
+			require.Nil(t, gpf.Close(), "failed to close test file")
+
+			gpf, err = New(testFilePath, m.BlockMetadata[0], ModeRead, WithFS(shortReadFS{}))
+			require.Nil(t, err, "failed to read GPFile")
+			for i := 0; i < 3; i++ {
+				blockData, err := gpf.ReadBlockAtIndex(i)
+				require.Nilf(t, err, "failed to read block %d", i)
+				require.Equal(t, bytes.Repeat([]byte{byte(i + 1)}, 1024), blockData)
+			}
+			require.Nil(t, gpf.Close(), "failed to close test file")
+		})
+	}
+}
+
+func TestNoChecksumsForLegacyMetadata(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// emulate a directory written without the checksum feature
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	testDir.Metadata.Features &^= FeatureChecksums
+	for i := 0; i < int(types.ColIdxCount); i++ {
+		testDir.BlockMetadata[i].HasChecksums = false
+	}
+	require.Nil(t, writeDummyBlock(1, testDir, 1), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	for i := types.ColumnIndex(0); i < types.ColIdxCount; i++ {
+		require.False(t, testDir.BlockMetadata[i].HasChecksums)
+		require.Zero(t, testDir.BlockMetadata[i].Blocks()[0].Checksum)
+
+		data, err := testDir.ReadBlockAtIndex(i, 0)
+		require.Nil(t, err)
+		require.Equal(t, []byte{1}, data)
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestLegacyMetadataHeader(t *testing.T) {
+	for _, version := range []uint64{headerVersionChecksums - 1, headerVersionChecksums} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+
+			require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+			testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+			require.Nil(t, testDir.Open(), "error opening test dir for writing")
+			if version < headerVersionChecksums {
+				testDir.Metadata.Features &^= FeatureChecksums
+				for i := 0; i < int(types.ColIdxCount); i++ {
+					testDir.BlockMetadata[i].HasChecksums = false
+				}
+			}
+			require.Nil(t, writeDummyBlock(1, testDir, 1), "failed to write blocks")
+			require.Nil(t, testDir.Close(), "error writing test dir")
+
+			// convert the metadata to the legacy layout (plain big endian version instead of the header prefix)
+			data, err := os.ReadFile(testDir.MetadataPath())
+			require.Nil(t, err)
+			legacyData := binary.BigEndian.AppendUint64(nil, version)
+			legacyData = append(legacyData, data[headerSize:]...)
+			require.Nil(t, os.WriteFile(testDir.MetadataPath(), legacyData, 0600))
+
+			testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+			require.Nil(t, testDir.Open(), "error opening legacy test dir for reading")
+			require.Equal(t, uint16(version), testDir.Metadata.Version)
+			require.Equal(t, version >= headerVersionChecksums, testDir.hasChecksums())
+			require.Equal(t, uint64(1), testDir.Metadata.Traffic.NumV4Entries)
+
+			// legacy metadata never carries the tag column
+			for i := types.ColumnIndex(0); i < types.TagColIdx; i++ {
+				data, err := testDir.ReadBlockAtIndex(i, 0)
+				require.Nil(t, err)
+				require.Equal(t, []byte{1}, data)
+			}
+			require.Nil(t, testDir.Close(), "error closing test dir")
+
+			// appending to a legacy directory upgrades the header while retaining its features
+			testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+			require.Nil(t, testDir.Open(), "error opening legacy test dir for writing")
+			require.Nil(t, writeDummyBlock(2, testDir, 2), "failed to write blocks")
+			require.Nil(t, testDir.Close(), "error writing test dir")
+
+			data, err = os.ReadFile(testDir.MetadataPath())
+			require.Nil(t, err)
+			require.Equal(t, headerMagic[:], data[0:4])
+
+			testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+			require.Nil(t, testDir.Open(), "error opening upgraded test dir for reading")
+			require.Equal(t, uint16(headerVersion), testDir.Metadata.Version)
+			require.Equal(t, version >= headerVersionChecksums, testDir.hasChecksums())
+			require.Equal(t, 2, testDir.NBlocks())
+			require.Nil(t, testDir.Close(), "error closing test dir")
+		})
+	}
+}
+
+func TestMetadataByteOrder(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, writeDummyBlock(1, testDir, 1), "failed to write blocks")
+	require.Nil(t, writeDummyBlock(300, testDir, 2), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	refDir := NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, refDir.Open(), "error opening test dir for reading")
+
+	// rewrite the metadata in little endian byte order (as a foreign system might do)
+	metadataFile, err := os.Create(refDir.MetadataPath())
+	require.Nil(t, err)
+	require.Nil(t, refDir.marshal(metadataFile, binary.LittleEndian))
+	require.Nil(t, metadataFile.Close())
+
+	data, err := os.ReadFile(refDir.MetadataPath())
+	require.Nil(t, err)
+	require.Equal(t, byteOrderLittleEndian, data[4])
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening little endian test dir for reading")
+	require.Equal(t, refDir.Stats, testDir.Stats)
+	require.Equal(t, refDir.BlockTraffic, testDir.BlockTraffic)
+	for i := types.ColumnIndex(0); i < types.ColIdxCount; i++ {
+		require.Equal(t, refDir.BlockMetadata[i], testDir.BlockMetadata[i])
+
+		data, err := testDir.ReadBlockAtIndex(i, 1)
+		require.Nil(t, err)
+		require.Equal(t, []byte{2}, data)
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+	require.Nil(t, refDir.Close(), "error closing test dir")
+}
+
+func TestUnsupportedMetadataHeader(t *testing.T) {
+
+	var tests = []struct {
+		name   string
+		modify func(data []byte)
+		errExp error
+	}{
+		{"unknown byte order", func(data []byte) {
+			data[4] = 0xff
+		}, ErrUnsupportedByteOrder},
+		{"future version", func(data []byte) {
+			binary.BigEndian.PutUint16(data[6:8], headerVersion+1)
+		}, ErrUnsupportedVersion},
+		{"unknown features", func(data []byte) {
+			binary.BigEndian.PutUint64(data[8:16], FeatureChecksums|1<<63)
+		}, ErrUnsupportedFeatures},
+		{"unknown legacy version", func(data []byte) {
+			binary.BigEndian.PutUint64(data[0:8], headerVersionMagic)
+		}, ErrUnsupportedVersion},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+			testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+			require.Nil(t, testDir.Open(), "error opening test dir for writing")
+			require.Nil(t, writeDummyBlock(1, testDir, 1), "failed to write blocks")
+			require.Nil(t, testDir.Close(), "error writing test dir")
+
+			data, err := os.ReadFile(testDir.MetadataPath())
+			require.Nil(t, err)
+			test.modify(data)
+			require.Nil(t, os.WriteFile(testDir.MetadataPath(), data, 0600))
+
+			testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+			require.ErrorIs(t, testDir.Open(), test.errExp)
+		})
+	}
+}
+
+func TestMetadataHandshakeRTT(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Write a block before enabling the feature
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, writeDummyBlock(1, testDir, 1), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	latency := NewLatencyMetadata(12, 1500*time.Microsecond, 20*time.Millisecond, 2*time.Hour)
+	require.Equal(t, LatencyMetadata{
+		NumHandshakes: 12,
+		MinRTT:        1500,
+		MedianRTT:     20000,
+		MaxRTT:        maxUint32,
+	}, latency)
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.False(t, testDir.hasHandshakeRTT())
+	require.Nil(t, testDir.WriteBlocksWithLatency(2, TrafficMetadata{NumV4Entries: 1}, latency, types.Counters{}, dummyData(2)), "failed to write blocks")
+	require.Nil(t, writeDummyBlock(3, testDir, 3), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasHandshakeRTT())
+	require.True(t, testDir.hasChecksums())
+	require.Equal(t, []LatencyMetadata{{}, latency, {}}, testDir.BlockLatency)
+	require.Equal(t, 3, testDir.NBlocks())
+	for i := types.ColumnIndex(0); i < types.ColIdxCount; i++ {
+		data, err := testDir.ReadBlockAtIndex(i, 2)
+		require.Nil(t, err)
+		require.Equal(t, []byte{3}, data)
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataTags(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Write a block without any tagged flows (which must not enable the feature)
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := types.ColumnIndex(0); colIdx < types.TagColIdx; colIdx++ {
+		dbData[colIdx] = []byte{1}
+	}
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, testDir.WriteBlocks(1, TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.False(t, testDir.hasTags())
+	require.Equal(t, 1, testDir.BlockMetadata[types.TagColIdx].NBlocks())
+
+	// Tag the flows of the next block
+	voip, err := testDir.TagIndex("voip")
+	require.Nil(t, err)
+	backup, err := testDir.TagIndex("backup")
+	require.Nil(t, err)
+	idx, err := testDir.TagIndex("voip")
+	require.Nil(t, err)
+	require.Equal(t, voip, idx)
+	dbData[types.TagColIdx] = []byte{backup}
+	require.Nil(t, testDir.WriteBlocks(2, TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasTags())
+	require.Equal(t, []string{"voip", "backup"}, testDir.Tags)
+	require.Equal(t, 2, testDir.NBlocks())
+
+	data, err := testDir.ReadBlockAtIndex(types.TagColIdx, 0)
+	require.Nil(t, err)
+	require.Empty(t, data)
+	data, err = testDir.ReadBlockAtIndex(types.TagColIdx, 1)
+	require.Nil(t, err)
+	require.Equal(t, "backup", testDir.TagName(data[0]))
+	require.Empty(t, testDir.TagName(0))
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataTTL(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Write a block with tagged flows, but without any recorded TTLs (which must not enable the feature)
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := types.ColumnIndex(0); colIdx < types.TTLMinColIdx; colIdx++ {
+		dbData[colIdx] = []byte{1}
+	}
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	_, err := testDir.TagIndex("voip")
+	require.Nil(t, err)
+	require.Nil(t, testDir.WriteBlocks(1, TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.True(t, testDir.hasTags())
+	require.False(t, testDir.hasTTL())
+	require.Equal(t, int(types.TTLMinColIdx), testDir.numColumns())
+	require.Equal(t, 1, testDir.BlockMetadata[types.TTLMinColIdx].NBlocks())
+
+	// Record TTLs for the flows of the next block
+	dbData[types.TTLMinColIdx], dbData[types.TTLMaxColIdx] = []byte{58}, []byte{64}
+	require.Nil(t, testDir.WriteBlocks(2, TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasTags())
+	require.True(t, testDir.hasTTL())
+	require.Equal(t, int(types.ColIdxCount), testDir.numColumns())
+	require.Equal(t, 2, testDir.NBlocks())
+
+	for _, colIdx := range []types.ColumnIndex{types.TTLMinColIdx, types.TTLMaxColIdx} {
+		data, err := testDir.ReadBlockAtIndex(colIdx, 0)
+		require.Nil(t, err)
+		require.Empty(t, data)
+	}
+	data, err := testDir.ReadBlockAtIndex(types.TTLMinColIdx, 1)
+	require.Nil(t, err)
+	require.Equal(t, []byte{58}, data)
+	data, err = testDir.ReadBlockAtIndex(types.TTLMaxColIdx, 1)
+	require.Nil(t, err)
+	require.Equal(t, []byte{64}, data)
+	data, err = testDir.ReadBlockAtIndex(types.TagColIdx, 0)
+	require.Nil(t, err)
+	require.Equal(t, "voip", testDir.TagName(data[0]))
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataLinks(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	eth0 := types.LinkInfo{MTU: 1500, Speed: 1000, MAC: "00:1b:21:3a:4f:10", Driver: "igb"}
+	eth0Upgraded := types.LinkInfo{MTU: 9000, Speed: 10000, MAC: "00:1b:21:3a:4f:10", Driver: "ixgbe"}
+
+	// Write a block without link properties (which must not enable the feature)
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, writeCounterBlock(testDir, 300, 1), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	// Record the link properties for the subsequent blocks, unchanged ones must not be recorded again
+	for _, c := range []struct {
+		timestamp int64
+		link      types.LinkInfo
+	}{
+		{600, eth0},
+		{900, eth0},
+		{1200, eth0Upgraded},
+	} {
+		testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+		require.Nil(t, testDir.Open(), "error opening test dir for writing")
+		require.Equal(t, c.timestamp > 600, testDir.hasLinks())
+		testDir.SetLink(c.timestamp, c.link)
+		require.Nil(t, writeCounterBlock(testDir, c.timestamp, 1), "failed to write blocks")
+		require.Nil(t, testDir.Close(), "error writing test dir")
+	}
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasLinks())
+	require.Equal(t, []LinkMetadata{
+		{Timestamp: 600, LinkInfo: eth0},
+		{Timestamp: 1200, LinkInfo: eth0Upgraded},
+	}, testDir.Links)
+
+	for _, c := range []struct {
+		timestamp      int64
+		expectedLink   types.LinkInfo
+		expectedExists bool
+	}{
+		{300, types.LinkInfo{}, false},
+		{600, eth0, true},
+		{900, eth0, true},
+		{1199, eth0, true},
+		{1200, eth0Upgraded, true},
+		{1500, eth0Upgraded, true},
+	} {
+		link, exists := testDir.LinkAt(c.timestamp)
+		require.Equal(t, c.expectedExists, exists, "timestamp %d", c.timestamp)
+		require.Equal(t, c.expectedLink, link, "timestamp %d", c.timestamp)
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataEmergency(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Only the blocks written in emergency aggregation mode are marked
+	for _, ts := range []int64{300, 600, 900, 1200} {
+		testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+		require.Nil(t, testDir.Open(), "error opening test dir for writing")
+		if ts == 600 || ts == 900 {
+			testDir.MarkEmergency(ts, 24, 64)
+		}
+		require.Nil(t, writeCounterBlock(testDir, ts, 1), "failed to write blocks")
+		require.Nil(t, testDir.Close(), "error writing test dir")
+	}
+
+	testDir := NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasEmergency())
+	require.Equal(t, []EmergencyMetadata{
+		{Timestamp: 600, IPv4Prefix: 24, IPv6Prefix: 64},
+		{Timestamp: 900, IPv4Prefix: 24, IPv6Prefix: 64},
+	}, testDir.Emergency)
+
+	for _, ts := range []int64{300, 600, 900, 1200} {
+		emergency, exists := testDir.EmergencyAt(ts)
+		require.Equal(t, ts == 600 || ts == 900, exists, "timestamp %d", ts)
+		if exists {
+			require.Equal(t, ts, emergency.Timestamp)
+		}
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataIdle(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Consecutive idle rotations are merged into a single span unless interrupted by a block
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	testDir.MarkIdle(300, 300)
+	require.Nil(t, writeCounterBlock(testDir, 600, 1), "failed to write blocks")
+	for _, ts := range []int64{900, 1200, 1500} {
+		testDir.MarkIdle(ts, ts)
+	}
+	require.Nil(t, writeCounterBlock(testDir, 1800, 1), "failed to write blocks")
+	testDir.MarkIdle(2100, 2100)
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	// Idle rotations can be recorded without writing any blocks
+	testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	testDir.MarkIdle(2400, 2400)
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasIdle())
+	require.Equal(t, 2, testDir.NBlocks())
+	require.Equal(t, []IdleMetadata{
+		{From: 300, To: 300},
+		{From: 900, To: 1500},
+		{From: 2100, To: 2400},
+	}, testDir.Idle)
+	require.Equal(t, []IdleMetadata{
+		{From: 1000, To: 1500},
+		{From: 2100, To: 2200},
+	}, testDir.IdleWithin(1000, 2200))
+	require.Nil(t, testDir.IdleWithin(600, 600))
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestMetadataHostnames(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	var (
+		host1 = netip.MustParseAddr("10.0.0.1")
+		host2 = netip.MustParseAddr("10.0.0.2")
+		host3 = netip.MustParseAddr("fd00::1")
+	)
+
+	// Hostnames are only recorded if they change, each distinct name is stored once
+	for _, ts := range []int64{300, 600, 900} {
+		testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+		require.Nil(t, testDir.Open(), "error opening test dir for writing")
+		require.Nil(t, testDir.SetHostname(ts, host1, "db.example.com"))
+		if ts == 600 {
+			require.Nil(t, testDir.SetHostname(ts, host2, "web.example.com"))
+			require.Nil(t, testDir.SetHostname(ts, host3, "db.example.com"))
+		}
+		if ts == 900 {
+			require.Nil(t, testDir.SetHostname(ts, host2, "web-old.example.com"))
+		}
+		require.Nil(t, writeCounterBlock(testDir, ts, 1), "failed to write blocks")
+		require.Nil(t, testDir.Close(), "error writing test dir")
+	}
+
+	testDir := NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasHostnames())
+	require.Equal(t, []string{"db.example.com", "web.example.com", "web-old.example.com"}, testDir.HostnameDict)
+	require.Equal(t, []HostnameMetadata{
+		{Timestamp: 300, IP: host1, Name: 0},
+		{Timestamp: 600, IP: host2, Name: 1},
+		{Timestamp: 600, IP: host3, Name: 0},
+		{Timestamp: 900, IP: host2, Name: 2},
+	}, testDir.Hostnames)
+
+	require.Empty(t, testDir.HostnamesAt(0))
+	require.Equal(t, map[netip.Addr]Hostname{
+		host1: {Timestamp: 300, Name: "db.example.com"},
+		host2: {Timestamp: 600, Name: "web.example.com"},
+		host3: {Timestamp: 600, Name: "db.example.com"},
+	}, testDir.HostnamesAt(600))
+	require.Equal(t, Hostname{Timestamp: 900, Name: "web-old.example.com"}, testDir.HostnamesAt(900)[host2])
+
+	require.ErrorIs(t, testDir.SetHostname(1200, host1, ""), ErrInvalidHostname)
+	require.Nil(t, testDir.Close(), "error closing test dir")
+}
+
+func TestBackfillBlocks(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	// Write blocks with a gap (and a block without any data) and enable the latency feature
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, writeCounterBlock(testDir, 300, 1), "failed to write blocks")
+	require.Nil(t, testDir.WriteBlocksWithLatency(900, TrafficMetadata{}, LatencyMetadata{NumHandshakes: 1}, types.Counters{}, [types.ColIdxCount][]byte{}), "failed to write blocks")
+	require.Nil(t, writeCounterBlock(testDir, 1200, 3), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	for _, c := range []struct {
+		timestamp        int64
+		value            byte
+		source           BackfillSource
+		expectedReplaced bool
+	}{
+		{600, 2, BackfillSourceSpill, false},
+		{600, 2, BackfillSourceSpill, true},
+		{900, 4, BackfillSourcePcap, true},
+		{1500, 5, BackfillSourcePcap, false},
+		{0, 6, BackfillSourcePcap, false},
+		{300, 1, BackfillSourcePcap, true},
+	} {
+		testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+		require.Nil(t, testDir.Open(), "error opening test dir for writing")
+		dbData, traffic, counters := testCounterBlock(c.value)
+		replaced, err := testDir.BackfillBlocks(c.timestamp, c.source, traffic, counters, dbData)
+		require.Nil(t, err)
+		require.Equal(t, c.expectedReplaced, replaced, "timestamp %d", c.timestamp)
+		require.Nil(t, testDir.Close(), "error writing test dir")
+	}
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	require.True(t, testDir.hasBackfills())
+
+	// All blocks must be in chronological order and carry the expected data / metadata
+	expectedValues := []byte{6, 1, 2, 4, 3, 5}
+	require.Equal(t, len(expectedValues), testDir.NBlocks())
+	first, last := testDir.TimeRange()
+	require.Equal(t, int64(0), first)
+	require.Equal(t, int64(1500), last)
+	var expectedCounts types.Counters
+	for i, val := range expectedValues {
+		_, traffic, counters := testCounterBlock(val)
+		expectedCounts = expectedCounts.Add(counters)
+		require.Equal(t, traffic, testDir.BlockTraffic[i])
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+			data, err := testDir.ReadBlockAtIndex(colIdx, i)
+			require.Nil(t, err)
+			if colIdx.IsCounterCol() {
+				require.Equal(t, []uint64{uint64(val)}, bitpack.UnpackInto(data, nil))
+			} else {
+				require.Equal(t, []byte{val}, data)
+			}
+		}
+	}
+	require.Equal(t, expectedCounts, testDir.Counts)
+	require.Equal(t, uint64(1+2+4+3+5+6), testDir.Traffic.NumV4Entries)
+	require.Equal(t, []LatencyMetadata{{}, {}, {}, {}, {}, {}}, testDir.BlockLatency)
+
+	require.Len(t, testDir.Backfills, 5)
+	for i, expected := range []struct {
+		timestamp int64
+		source    BackfillSource
+	}{
+		{0, BackfillSourcePcap},
+		{300, BackfillSourcePcap},
+		{600, BackfillSourceSpill},
+		{900, BackfillSourcePcap},
+		{1500, BackfillSourcePcap},
+	} {
+		require.Equal(t, expected.timestamp, testDir.Backfills[i].Timestamp)
+		require.Equal(t, expected.source, testDir.Backfills[i].Source)
+		require.NotZero(t, testDir.Backfills[i].BackfilledAt)
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+
+	// No temporary files must be left behind
+	files, err := os.ReadDir(testDir.Path())
+	require.Nil(t, err)
+	for _, file := range files {
+		require.False(t, strings.HasSuffix(file.Name(), rewriteSuffix))
+	}
+}
+
+func TestCompact(t *testing.T) {
+
+	require.Nil(t, os.RemoveAll("/tmp/test_db"))
+
+	testDir := NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	require.Nil(t, writeCounterBlock(testDir, 300, 1), "failed to write blocks")
+	require.Nil(t, writeCounterBlock(testDir, 600, 2), "failed to write blocks")
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	// Simulate data left behind by an interrupted writeout (trailing data in all column files)
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		f, err := os.OpenFile(testDir.columnPath(colIdx), os.O_APPEND|os.O_WRONLY, 0600)
+		require.Nil(t, err)
+		_, err = f.Write([]byte{0xDE, 0xAD, 0xBE, 0xEF})
+		require.Nil(t, err)
+		require.Nil(t, f.Close())
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	unreferenced, err := testDir.UnreferencedBytes()
+	require.Nil(t, err)
+	require.Equal(t, int64(4*types.ColIdxCount), unreferenced)
+	_, err = testDir.Compact()
+	require.Error(t, err, "compaction must fail in read mode")
+	require.Nil(t, testDir.Close(), "error closing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeWrite)
+	require.Nil(t, testDir.Open(), "error opening test dir for writing")
+	reclaimed, err := testDir.Compact()
+	require.Nil(t, err)
+	require.Equal(t, unreferenced, reclaimed)
+	require.Nil(t, testDir.Close(), "error writing test dir")
+
+	testDir = NewDir("/tmp/test_db", 1000, ModeRead)
+	require.Nil(t, testDir.Open(), "error opening test dir for reading")
+	unreferenced, err = testDir.UnreferencedBytes()
+	require.Nil(t, err)
+	require.Zero(t, unreferenced)
+
+	require.Equal(t, 2, testDir.NBlocks())
+	for i, val := range []byte{1, 2} {
+		for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+			data, err := testDir.ReadBlockAtIndex(colIdx, i)
+			require.Nil(t, err)
+			if colIdx.IsCounterCol() {
+				require.Equal(t, []uint64{uint64(val)}, bitpack.UnpackInto(data, nil))
+			} else {
+				require.Equal(t, []byte{val}, data)
+			}
+		}
+	}
+	require.Nil(t, testDir.Close(), "error closing test dir")
+
+	// No temporary files must be left behind
+	files, err := os.ReadDir(testDir.Path())
+	require.Nil(t, err)
+	for _, file := range files {
+		require.False(t, strings.HasSuffix(file.Name(), rewriteSuffix))
+	}
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	data := []byte("abcdefgh")
+	header := &storage.BlockHeader{HasChecksums: true}
+	header.AddBlock(300, storage.Block{Offset: 0, Len: 4, RawLen: 4, Checksum: crc32.Checksum(data[:4], checksumTable)})
+	header.AddBlock(600, storage.Block{Offset: 4, Len: 4, RawLen: 4, Checksum: crc32.Checksum(data[4:], checksumTable)})
+	header.AddBlock(900, storage.Block{Offset: 8, EncoderType: encoders.EncoderTypeNull})
+
+	require.EqualValues(t, 8, ReferencedSize(header))
+	require.Nil(t, VerifyBlocks(bytes.NewReader(data), header))
+	require.ErrorIs(t, VerifyBlocks(bytes.NewReader(data[:6]), header), ErrBlockMissing)
+	require.ErrorIs(t, VerifyBlocks(bytes.NewReader([]byte("abcdXfgh")), header), ErrChecksumMismatch)
+
+	// without checksums, only the presence of the data can be verified
+	header.HasChecksums = false
+	require.Nil(t, VerifyBlocks(bytes.NewReader([]byte("abcdXfgh")), header))
+}
+
 func TestBrokenAccess(t *testing.T) {
 
 	require.Nil(t, os.RemoveAll("/tmp/test_db"))
@@ -422,6 +1288,29 @@ func (g *GPFile) validateBlocks(nExpected int) error {
 	return nil
 }
 
+func testCounterBlock(val byte) ([types.ColIdxCount][]byte, TrafficMetadata, types.Counters) {
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		dbData[colIdx] = []byte{val}
+		if colIdx.IsCounterCol() {
+			dbData[colIdx] = bitpack.Pack([]uint64{uint64(val)})
+		}
+	}
+	return dbData, TrafficMetadata{
+		NumV4Entries: uint64(val),
+	}, types.Counters{
+		BytesRcvd:   uint64(val),
+		BytesSent:   uint64(val),
+		PacketsRcvd: uint64(val),
+		PacketsSent: uint64(val),
+	}
+}
+
+func writeCounterBlock(dir *GPDir, timestamp int64, val byte) error {
+	dbData, traffic, counters := testCounterBlock(val)
+	return dir.WriteBlocks(timestamp, traffic, counters, dbData)
+}
+
 func writeDummyBlock(timestamp int64, dir *GPDir, dummyByte byte) error {
 	return dir.WriteBlocks(timestamp, TrafficMetadata{
 		NumV4Entries: uint64(dummyByte),
@@ -432,5 +1321,14 @@ func writeDummyBlock(timestamp int64, dir *GPDir, dummyByte byte) error {
 		BytesSent:   uint64(dummyByte),
 		PacketsRcvd: uint64(dummyByte),
 		PacketsSent: uint64(dummyByte),
-	}, [types.ColIdxCount][]byte{{dummyByte}, {dummyByte}, {dummyByte}, {dummyByte}, {dummyByte}, {dummyByte}, {dummyByte}, {dummyByte}})
+	}, dummyData(dummyByte))
+}
+
+// dummyData returns a block of data of all columns (including the optional ones, hence enabling
+// the respective features)
+func dummyData(dummyByte byte) (dbData [types.ColIdxCount][]byte) {
+	for colIdx := range dbData {
+		dbData[colIdx] = []byte{dummyByte}
+	}
+	return
 }
@@ -0,0 +1,104 @@
+package gpfile
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// errMmapUnsupported denotes that a file cannot be memory-mapped (e.g. because it does not reside on
+// the file system of the operating system), in which case regular (buffered) reads are used instead
+var errMmapUnsupported = errors.New("memory-mapped access not supported")
+
+// mappableFile denotes a file that can be memory-mapped (e.g. an *os.File)
+type mappableFile interface {
+	io.Closer
+	Fd() uintptr
+	Stat() (fs.FileInfo, error)
+}
+
+// mmapFile provides read access to a memory-mapped file via the concurrency.ReadWriteSeekCloser
+// interface, avoiding a syscall per block read
+type mmapFile struct {
+	data []byte
+	pos  int64
+
+	// file denotes the underlying file, which is closed along with the mapping
+	file mappableFile
+}
+
+// Read reads up to len(p) bytes from the current position of the mapping
+func (m *mmapFile) Read(p []byte) (int, error) {
+	if m.pos >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.pos:])
+	m.pos += int64(n)
+	return n, nil
+}
+
+// Write is not supported on a (read-only) memory-mapped file
+func (m *mmapFile) Write([]byte) (int, error) {
+	return 0, errors.New("cannot write to memory-mapped file")
+}
+
+// Seek sets the position for the next read
+func (m *mmapFile) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = m.pos + offset
+	case io.SeekEnd:
+		pos = int64(len(m.data)) + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	m.pos = pos
+	return pos, nil
+}
+
+// Stat returns a FileInfo describing the underlying file
+func (m *mmapFile) Stat() (fs.FileInfo, error) {
+	return m.file.Stat()
+}
+
+// Close removes the mapping and closes the underlying file
+func (m *mmapFile) Close() error {
+	var err error
+	if m.data != nil {
+		err = munmap(m.data)
+		m.data = nil
+	}
+	return errors.Join(err, m.file.Close())
+}
+
+// newMmapFile memory-maps the provided file (taking ownership of it upon success)
+func newMmapFile(f io.Closer) (*mmapFile, error) {
+	mf, ok := f.(mappableFile)
+	if !ok {
+		return nil, errMmapUnsupported
+	}
+	stat, err := mf.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty files cannot be mapped (and do not have to be read anyway)
+	if stat.Size() == 0 {
+		return nil, errMmapUnsupported
+	}
+
+	data, err := mmap(mf.Fd(), int(stat.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return &mmapFile{
+		data: data,
+		file: mf,
+	}, nil
+}
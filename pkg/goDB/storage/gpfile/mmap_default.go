@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package gpfile
+
+func mmap(uintptr, int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmap([]byte) error {
+	return nil
+}
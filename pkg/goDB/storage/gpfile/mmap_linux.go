@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package gpfile
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// mmap maps a file read-only into memory. Since column files are usually scanned front to back,
+// the kernel is advised to perform aggressive readahead on the mapping
+func mmap(fd uintptr, size int) ([]byte, error) {
+	data, err := unix.Mmap(int(fd), 0, size, unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	// Readahead hints are best effort only, hence errors are ignored
+	_ = unix.Madvise(data, unix.MADV_SEQUENTIAL)
+	_ = unix.Madvise(data, unix.MADV_WILLNEED)
+
+	return data, nil
+}
+
+func munmap(data []byte) error {
+	return unix.Munmap(data)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/els0r/goProbe/pkg/goDB/encoder"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
 	"github.com/fako1024/gotools/concurrency"
 )
 
@@ -14,6 +15,8 @@ type Option func(any)
 // optionSetterCommon denotes options that apply to both GPDir and GPFile
 type optionSetterCommon interface {
 	setPermissions(fs.FileMode)
+	setFS(storage.FS)
+	setSyncPolicy(storage.SyncPolicy)
 }
 
 // optionSetterFile denotes options that apply to GPFile only
@@ -22,6 +25,7 @@ type optionSetterFile interface {
 	setMemPool(concurrency.MemPoolGCable)
 	setEncoder(encoder.Encoder)
 	setEncoderTypeLevel(encoders.Type, int)
+	setMmap(bool)
 }
 
 // WithEncoder allows to set the compression implementation
@@ -54,6 +58,18 @@ func WithReadAll(pool concurrency.MemPoolGCable) Option {
 	}
 }
 
+// WithMmap enables / disables reading the underlying file via a memory mapping (with readahead
+// hints), avoiding a syscall per block read during large scans. It takes precedence over WithReadAll
+// and falls back to regular reads if the file cannot be mapped (e.g. on non-Linux systems or if a
+// non-default file system is used)
+func WithMmap(enable bool) Option {
+	return func(o any) {
+		if obj, ok := o.(optionSetterFile); ok {
+			obj.setMmap(enable)
+		}
+	}
+}
+
 // WithPermissions sets a non-default set of permissions / file mode for
 // the file
 func WithPermissions(permissions fs.FileMode) Option {
@@ -63,3 +79,25 @@ func WithPermissions(permissions fs.FileMode) Option {
 		}
 	}
 }
+
+// WithFS sets a non-default file system (e.g. an in-memory one) to read / write the
+// underlying files from / to
+func WithFS(fsys storage.FS) Option {
+	return func(o any) {
+		if obj, ok := o.(optionSetterCommon); ok {
+			obj.setFS(fsys)
+		}
+	}
+}
+
+// WithSyncPolicy sets the policy governing when written data is committed to stable storage (c.f.
+// storage.SyncPolicy). Under storage.SyncAlways, all written files and the directory entries of the
+// metadata are synced upon Close(), under storage.SyncPerRotation only newly created directories are
+// (leaving the data to the sync barrier of the caller)
+func WithSyncPolicy(policy storage.SyncPolicy) Option {
+	return func(o any) {
+		if obj, ok := o.(optionSetterCommon); ok {
+			obj.setSyncPolicy(policy)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gpfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// ReencodeSize returns the size of all column files of the directory that would be rewritten by
+// Reencode(), i.e. the ones containing blocks encoded with an encoder other than the provided one (or
+// all column files if force is set). Blocks stored without compression (because their data could not
+// be compressed) are only considered if the directory is to be stored uncompressed altogether
+func (d *GPDir) ReencodeSize(encoderType encoders.Type, force bool) (int64, error) {
+	if !d.isOpen {
+		return 0, ErrDirNotOpen
+	}
+
+	var size int64
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		if header := d.BlockMetadata[colIdx]; force || columnNeedsReencode(header, encoderType) {
+			size += int64(header.CurrentOffset)
+		}
+	}
+	return size, nil
+}
+
+func columnNeedsReencode(header *storage.BlockHeader, encoderType encoders.Type) bool {
+	for _, block := range header.BlockList {
+		if block.IsEmpty() || block.EncoderType == encoderType {
+			continue
+		}
+		if block.EncoderType == encoders.EncoderTypeNull && encoderType != encoders.EncoderTypeNull {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Reencode rewrites all column files of the directory containing blocks encoded with an encoder other
+// than the provided one (or all column files if force is set, e.g. to apply a different compression
+// level), decoding and re-encoding the data of each block. The metadata reflecting the new blocks is
+// written on Close(). It returns the size of the rewritten column files before and after re-encoding
+func (d *GPDir) Reencode(encoderType encoders.Type, encoderLevel int, force bool) (before, after int64, err error) {
+	if !d.isOpen {
+		return 0, 0, ErrDirNotOpen
+	}
+	if d.accessMode != ModeWrite {
+		return 0, 0, errors.New("cannot re-encode GPDir in read mode")
+	}
+
+	for colIdx := types.ColumnIndex(0); colIdx < types.ColIdxCount; colIdx++ {
+		oldHeader := d.BlockMetadata[colIdx]
+		// columns without any data (i.e. only empty blocks) are left untouched
+		if oldHeader.CurrentOffset == 0 || (!force && !columnNeedsReencode(oldHeader, encoderType)) {
+			continue
+		}
+
+		// Close the column file if it has already been accessed (all data is flushed after each block)
+		if d.gpFiles[colIdx] != nil {
+			if err := d.gpFiles[colIdx].Close(); err != nil {
+				return before, after, err
+			}
+			d.gpFiles[colIdx] = nil
+		}
+
+		header, err := d.reencodeColumn(colIdx, encoderType, encoderLevel)
+		if err != nil {
+			_ = d.fsys.Remove(d.columnPath(colIdx) + rewriteSuffix)
+			return before, after, fmt.Errorf("failed to re-encode column %s: %w", types.ColumnFileNames[colIdx], err)
+		}
+		if err := d.fsys.Rename(d.columnPath(colIdx)+rewriteSuffix, d.columnPath(colIdx)); err != nil {
+			return before, after, err
+		}
+
+		d.BlockMetadata[colIdx] = header
+		before += int64(oldHeader.CurrentOffset)
+		after += int64(header.CurrentOffset)
+	}
+
+	return before, after, nil
+}
+
+// reencodeColumn writes the (re-encoded) data of all blocks of a column to a temporary file
+func (d *GPDir) reencodeColumn(colIdx types.ColumnIndex, encoderType encoders.Type, encoderLevel int) (*storage.BlockHeader, error) {
+	path := d.columnPath(colIdx)
+
+	src, err := New(path, d.BlockMetadata[colIdx], ModeRead, WithFS(d.fsys))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	// The temporary file is written from scratch (GPFiles in write mode append to existing data)
+	if err := d.fsys.Remove(path + rewriteSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	header := &storage.BlockHeader{
+		BlockList:    make([]storage.BlockAtTime, 0, len(d.BlockMetadata[colIdx].BlockList)),
+		HasChecksums: d.BlockMetadata[colIdx].HasChecksums,
+	}
+	dst, err := New(path+rewriteSuffix, header, ModeWrite,
+		WithFS(d.fsys),
+		WithPermissions(d.permissions),
+		WithEncoderTypeLevel(encoderType, encoderLevel),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, block := range d.BlockMetadata[colIdx].BlockList {
+		data, err := src.ReadBlockAtIndex(i)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to read block %d: %w", block.Timestamp, err), dst.Close())
+		}
+		if err := dst.writeBlock(block.Timestamp, data); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to write block %d: %w", block.Timestamp, err), dst.Close())
+		}
+	}
+
+	return header, dst.Close()
+}
@@ -0,0 +1,59 @@
+package gpfile
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+)
+
+// ErrBlockMissing is thrown if a column file does not hold the (complete) data of a block referenced
+// by its header
+var ErrBlockMissing = errors.New("block data missing")
+
+// ReferencedSize returns the size of the prefix of a column file holding the data of all blocks
+// referenced by its header. Data beyond it is not referenced (e.g. left behind by an interrupted writeout)
+func ReferencedSize(header *storage.BlockHeader) int64 {
+	var size int64
+	for _, block := range header.BlockList {
+		if end := int64(block.Offset) + int64(block.Len); end > size {
+			size = end
+		}
+	}
+	return size
+}
+
+// VerifyBlocks verifies that the column file read via r holds the data of all blocks referenced by
+// header and, if the header carries checksums, that the data of each block matches its checksum
+func VerifyBlocks(r io.ReadSeeker, header *storage.BlockHeader) error {
+	var buf []byte
+	for _, block := range header.BlockList {
+		if block.Len == 0 {
+			continue
+		}
+
+		if _, err := r.Seek(int64(block.Offset), io.SeekStart); err != nil {
+			return err
+		}
+		if cap(buf) < int(block.Len) {
+			buf = make([]byte, block.Len)
+		}
+		buf = buf[:block.Len]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("%w: block at %d (offset %d, length %d)", ErrBlockMissing, block.Timestamp, block.Offset, block.Len)
+			}
+			return err
+		}
+
+		if !header.HasChecksums {
+			continue
+		}
+		if checksum := crc32.Checksum(buf, checksumTable); checksum != block.Checksum {
+			return fmt.Errorf("%w: block at %d: want %08x, have %08x", ErrChecksumMismatch, block.Timestamp, block.Checksum, checksum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package gpfile
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+)
+
+// WalkDirs calls fn for all (day) directories of an interface, in chronological order. Entries not
+// following the directory structure of the goDB (c.f. GenPathForTimestamp) are ignored
+func WalkDirs(fsys storage.FS, ifacePath string, fn func(dirPath string, dirTimestamp int64) error) error {
+	years, err := numericSubDirs(fsys, ifacePath)
+	if err != nil {
+		return err
+	}
+	for _, year := range years {
+		yearPath := filepath.Join(ifacePath, year)
+		months, err := numericSubDirs(fsys, yearPath)
+		if err != nil {
+			return err
+		}
+		for _, month := range months {
+			monthPath := filepath.Join(yearPath, month)
+			days, err := numericSubDirs(fsys, monthPath)
+			if err != nil {
+				return err
+			}
+			for _, day := range days {
+				dirTimestamp, err := strconv.ParseInt(day, 10, 64)
+				if err != nil {
+					continue
+				}
+				if err := fn(filepath.Join(monthPath, day), dirTimestamp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// numericSubDirs returns the names of all numeric subdirectories of a directory
+func numericSubDirs(fsys storage.FS, path string) ([]string, error) {
+	dirents, err := fsys.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, dirent := range dirents {
+		if !dirent.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(dirent.Name()); err != nil {
+			continue
+		}
+		dirs = append(dirs, dirent.Name())
+	}
+	return dirs, nil
+}
@@ -10,6 +10,7 @@ type Block struct {
 	Len         uint32
 	RawLen      uint32
 	EncoderType encoders.Type
+	Checksum    uint32
 }
 
 // IsEmpty checks if the block does not store any data
@@ -22,6 +23,9 @@ type BlockHeader struct {
 	BlockList     []BlockAtTime
 	CurrentOffset uint64
 
+	// HasChecksums denotes if the blocks carry a checksum of their (compressed) data
+	HasChecksums bool
+
 	blocks map[int64]int // Hidden from user / serialization (on-demand creation)
 }
 
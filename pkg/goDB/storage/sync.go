@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// SyncPolicy denotes when data written to goDB is committed to stable storage
+type SyncPolicy string
+
+const (
+	// SyncOSDefault leaves committing written data to the write-back of the operating system (apart
+	// from coalesced writeouts, which are committed by a single sync barrier)
+	SyncOSDefault SyncPolicy = "os-default"
+
+	// SyncPerRotation commits all data written during a rotation by a single sync barrier of the file
+	// system the DB resides on once all interfaces have been written
+	SyncPerRotation SyncPolicy = "per-rotation"
+
+	// SyncAlways commits every file (and the directory entries of its metadata) to stable storage as
+	// soon as it has been written
+	SyncAlways SyncPolicy = "always"
+)
+
+// ErrUnknownSyncPolicy denotes that a sync policy is not supported
+var ErrUnknownSyncPolicy = fmt.Errorf("unknown sync policy (must be one of %s, %s, %s)", SyncAlways, SyncPerRotation, SyncOSDefault)
+
+// ParseSyncPolicy parses a sync policy from its string representation (an empty one denoting the
+// default policy, i.e. SyncOSDefault)
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case "":
+		return SyncOSDefault, nil
+	case SyncOSDefault, SyncPerRotation, SyncAlways:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownSyncPolicy, s)
+}
+
+// SyncsDirs returns if newly created directories are committed to stable storage under the policy
+// (which is required for the data within them to be retrievable after a power loss)
+func (p SyncPolicy) SyncsDirs() bool {
+	return p == SyncPerRotation || p == SyncAlways
+}
+
+// fileSyncer denotes a file that can be committed to stable storage individually (c.f. fsync(2))
+type fileSyncer interface {
+	Sync() error
+}
+
+// DirSyncer denotes a file system that is able to commit a directory (i.e. its entries) to stable
+// storage
+type DirSyncer interface {
+	SyncDir(path string) error
+}
+
+// SyncFile commits all pending writes of a file to stable storage. If the file does not support
+// syncing (e.g. an in-memory one), it is a no-op
+func SyncFile(f any) error {
+	if syncer, ok := f.(fileSyncer); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// SyncDir commits the entries of a directory (e.g. files created / renamed within it) to stable
+// storage. If the file system does not support syncing directories, it is a no-op
+func SyncDir(fsys FS, path string) error {
+	if syncer, ok := fsys.(DirSyncer); ok {
+		return syncer.SyncDir(path)
+	}
+	return nil
+}
+
+// syncDir commits the entries of a directory of the file system of the operating system to stable storage
+func syncDir(path string) error {
+	d, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if err = d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
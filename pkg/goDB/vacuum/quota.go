@@ -0,0 +1,129 @@
+package vacuum
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/telemetry/logging"
+)
+
+type daySize struct {
+	path      string
+	timestamp int64
+	size      int64
+}
+
+// Usage returns the disk space occupied by the data of an interface. An interface without any data
+// in the goDB occupies no disk space
+func (v *Vacuum) Usage(iface string) (int64, error) {
+	days, err := v.daySizes(filepath.Join(v.dbPath, iface))
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, day := range days {
+		size += day.size
+	}
+	return size, nil
+}
+
+// Trim removes the oldest day directories of an interface until the disk space occupied by its data
+// no longer exceeds maxSize. Directories of days starting at or after keepFrom (e.g. the day currently
+// written to) are never removed, hence the interface may still exceed maxSize afterwards
+func (v *Vacuum) Trim(ctx context.Context, iface string, maxSize, keepFrom int64) (IfaceResult, error) {
+	logger := logging.FromContext(ctx).With("iface", iface, "dry_run", v.dryRun)
+	res := IfaceResult{
+		Iface:      iface,
+		Configured: true,
+	}
+
+	ifacePath := filepath.Join(v.dbPath, iface)
+	days, err := v.daySizes(ifacePath)
+	if err != nil {
+		return res, err
+	}
+	var size int64
+	for _, day := range days {
+		size += day.size
+	}
+
+	for _, day := range days {
+		if size <= maxSize || day.timestamp >= keepFrom {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		n, err := v.removeDir(day.path)
+		if err != nil {
+			return res, err
+		}
+		logger.With("path", day.path, "bytes", n).Info("removed directory exceeding quota")
+		res.RemovedDirs++
+		res.ReclaimedBytes += n
+		size -= n
+	}
+
+	if res.RemovedDirs > 0 && !v.dryRun {
+		if err := v.removeEmptyDirs(ifacePath); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// daySizes returns the size of all day directories of an interface, ordered by their timestamp
+func (v *Vacuum) daySizes(ifacePath string) ([]daySize, error) {
+	var days []daySize
+	err := gpfile.WalkDirs(v.fsys, ifacePath, func(dayPath string, dayTimestamp int64) error {
+		size, err := v.dirSize(dayPath)
+		if err != nil {
+			return err
+		}
+		days = append(days, daySize{
+			path:      dayPath,
+			timestamp: dayTimestamp,
+			size:      size,
+		})
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].timestamp < days[j].timestamp
+	})
+	return days, nil
+}
+
+// dirSize returns the total size of all files in a directory (non-recursively)
+func (v *Vacuum) dirSize(path string) (int64, error) {
+	dirents, err := v.fsys.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, dirent := range dirents {
+		if dirent.IsDir() {
+			continue
+		}
+		stat, err := v.fsys.Stat(filepath.Join(path, dirent.Name()))
+		if err != nil {
+			return size, err
+		}
+		size += stat.Size()
+	}
+	return size, nil
+}
@@ -0,0 +1,336 @@
+// Package vacuum reclaims disk space occupied by a goDB: It removes the directories of interfaces no
+// longer present in the configuration of the probe (once they are older than a safety window) and
+// compacts column files containing data not referenced by any block (e.g. left behind by interrupted
+// writeouts)
+package vacuum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/info"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/telemetry/logging"
+)
+
+// DefaultMinAge denotes the default safety window, i.e. the minimum age of the data of an unconfigured
+// interface before it is removed
+const DefaultMinAge = 7 * 24 * time.Hour
+
+// ErrNegativeMinAge denotes that a negative safety window was provided
+var ErrNegativeMinAge = errors.New("minimum age must not be negative")
+
+// IfaceResult summarizes the vacuum of a single interface
+type IfaceResult struct {
+	Iface          string `json:"iface"`           // Iface: name of the interface
+	Configured     bool   `json:"configured"`      // Configured: whether the interface is part of the configuration
+	RemovedDirs    int    `json:"removed_dirs"`    // RemovedDirs: number of (day) directories removed
+	CompactedDirs  int    `json:"compacted_dirs"`  // CompactedDirs: number of (day) directories compacted
+	ReclaimedBytes int64  `json:"reclaimed_bytes"` // ReclaimedBytes: amount of disk space reclaimed
+}
+
+// Results denotes the results of a vacuum run, ordered by interface
+type Results []IfaceResult
+
+// ReclaimedBytes returns the total amount of disk space reclaimed
+func (r Results) ReclaimedBytes() (n int64) {
+	for _, res := range r {
+		n += res.ReclaimedBytes
+	}
+	return
+}
+
+// Vacuum performs vacuum runs on a goDB
+type Vacuum struct {
+	dbPath      string
+	fsys        storage.FS
+	permissions fs.FileMode
+	locker      sync.Locker
+	minAge      time.Duration
+	dryRun      bool
+
+	now func() time.Time
+}
+
+// Option denotes a functional option for a Vacuum
+type Option func(*Vacuum)
+
+// WithFS sets the file system the goDB resides on
+func WithFS(fsys storage.FS) Option {
+	return func(v *Vacuum) {
+		v.fsys = fsys
+	}
+}
+
+// WithPermissions sets the permissions of rewritten column files
+func WithPermissions(permissions fs.FileMode) Option {
+	return func(v *Vacuum) {
+		v.permissions = permissions
+	}
+}
+
+// WithLocker sets a lock held while processing a directory, allowing to serialize access with
+// concurrent writeouts to the goDB
+func WithLocker(locker sync.Locker) Option {
+	return func(v *Vacuum) {
+		v.locker = locker
+	}
+}
+
+// WithMinAge sets the minimum age of the data of an unconfigured interface before it is removed
+func WithMinAge(minAge time.Duration) Option {
+	return func(v *Vacuum) {
+		v.minAge = minAge
+	}
+}
+
+// WithDryRun enables / disables dry-run mode, in which all space that would be reclaimed is reported
+// without modifying the goDB
+func WithDryRun(b bool) Option {
+	return func(v *Vacuum) {
+		v.dryRun = b
+	}
+}
+
+// New instantiates a new Vacuum for the goDB located at dbPath
+func New(dbPath string, opts ...Option) *Vacuum {
+	v := &Vacuum{
+		dbPath: dbPath,
+		fsys:   storage.DefaultFS,
+		locker: noopLocker{},
+		minAge: DefaultMinAge,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Run vacuums all interfaces of the goDB. All data of interfaces not contained in configuredIfaces
+// older than the minimum age is removed, all remaining directories are compacted
+func (v *Vacuum) Run(ctx context.Context, configuredIfaces []string) (Results, error) {
+	if v.minAge < 0 {
+		return nil, ErrNegativeMinAge
+	}
+
+	configured := make(map[string]struct{}, len(configuredIfaces))
+	for _, iface := range configuredIfaces {
+		configured[iface] = struct{}{}
+	}
+
+	ifaces, err := info.GetInterfacesFS(v.fsys, v.dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Any day directory ending before the cutoff is eligible for removal
+	cutoff := v.now().Add(-v.minAge).Unix()
+
+	results := make(Results, 0, len(ifaces))
+	for _, iface := range ifaces {
+		_, isConfigured := configured[iface]
+		res, err := v.vacuumIface(ctx, iface, isConfigured, cutoff)
+		if err != nil {
+			return results, fmt.Errorf("failed to vacuum interface %s: %w", iface, err)
+		}
+		results = append(results, res)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Iface < results[j].Iface
+	})
+
+	return results, nil
+}
+
+func (v *Vacuum) vacuumIface(ctx context.Context, iface string, isConfigured bool, cutoff int64) (IfaceResult, error) {
+	logger := logging.FromContext(ctx).With("iface", iface, "dry_run", v.dryRun)
+	res := IfaceResult{
+		Iface:      iface,
+		Configured: isConfigured,
+	}
+
+	ifacePath := filepath.Join(v.dbPath, iface)
+	err := gpfile.WalkDirs(v.fsys, ifacePath, func(dayPath string, dayTimestamp int64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !isConfigured && dayTimestamp+gpfile.EpochDay <= cutoff {
+			n, err := v.removeDir(dayPath)
+			if err != nil {
+				return err
+			}
+			logger.With("path", dayPath, "bytes", n).Info("removed directory of unconfigured interface")
+			res.RemovedDirs++
+			res.ReclaimedBytes += n
+			return nil
+		}
+
+		n, err := v.compactDir(ifacePath, dayTimestamp)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logger.With("path", dayPath, "bytes", n).Info("compacted directory")
+			res.CompactedDirs++
+			res.ReclaimedBytes += n
+		}
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+
+	// Clean up any directories (down to the interface itself) that have become empty
+	if res.RemovedDirs > 0 && !v.dryRun {
+		if err := v.removeEmptyDirs(ifacePath); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// subDirs returns the names of all numeric subdirectories of a directory
+func (v *Vacuum) subDirs(path string) ([]string, error) {
+	dirents, err := v.fsys.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, dirent := range dirents {
+		if !dirent.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(dirent.Name()); err != nil {
+			continue
+		}
+		dirs = append(dirs, dirent.Name())
+	}
+	return dirs, nil
+}
+
+// removeDir removes a day directory and all files therein, returning their total size
+func (v *Vacuum) removeDir(path string) (int64, error) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+
+	dirents, err := v.fsys.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, dirent := range dirents {
+		if dirent.IsDir() {
+			return size, fmt.Errorf("unexpected subdirectory %s in %s", dirent.Name(), path)
+		}
+		filePath := filepath.Join(path, dirent.Name())
+		stat, err := v.fsys.Stat(filePath)
+		if err != nil {
+			return size, err
+		}
+		if !v.dryRun {
+			if err := v.fsys.Remove(filePath); err != nil {
+				return size, err
+			}
+		}
+		size += stat.Size()
+	}
+	if v.dryRun {
+		return size, nil
+	}
+
+	return size, v.fsys.Remove(path)
+}
+
+// compactDir compacts a day directory (if it contains any unreferenced data), returning the number
+// of bytes reclaimed
+func (v *Vacuum) compactDir(ifacePath string, dayTimestamp int64) (int64, error) {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+
+	opts := []gpfile.Option{gpfile.WithFS(v.fsys)}
+	if v.permissions != 0 {
+		opts = append(opts, gpfile.WithPermissions(v.permissions))
+	}
+
+	// Determine the amount of unreferenced data first to avoid touching any directory that does not
+	// require compaction
+	dir := gpfile.NewDir(ifacePath, dayTimestamp, gpfile.ModeRead, opts...)
+	if err := dir.Open(); err != nil {
+		return 0, err
+	}
+	unreferenced, err := dir.UnreferencedBytes()
+	if cerr := dir.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil || unreferenced == 0 || v.dryRun {
+		return unreferenced, err
+	}
+
+	dir = gpfile.NewDir(ifacePath, dayTimestamp, gpfile.ModeWrite, opts...)
+	if err := dir.Open(); err != nil {
+		return 0, err
+	}
+	reclaimed, err := dir.Compact()
+	if err != nil {
+		// Persist the metadata anyway, since any column compacted so far has already been replaced
+		return reclaimed, errors.Join(err, dir.Close())
+	}
+	return reclaimed, dir.Close()
+}
+
+// removeEmptyDirs removes all empty month / year directories of an interface (and the interface
+// directory itself if it is empty)
+func (v *Vacuum) removeEmptyDirs(ifacePath string) error {
+	v.locker.Lock()
+	defer v.locker.Unlock()
+
+	years, err := v.subDirs(ifacePath)
+	if err != nil {
+		return err
+	}
+	for _, year := range years {
+		yearPath := filepath.Join(ifacePath, year)
+		months, err := v.subDirs(yearPath)
+		if err != nil {
+			return err
+		}
+		for _, month := range months {
+			if err := v.removeIfEmpty(filepath.Join(yearPath, month)); err != nil {
+				return err
+			}
+		}
+		if err := v.removeIfEmpty(yearPath); err != nil {
+			return err
+		}
+	}
+	return v.removeIfEmpty(ifacePath)
+}
+
+func (v *Vacuum) removeIfEmpty(path string) error {
+	dirents, err := v.fsys.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	if len(dirents) > 0 {
+		return nil
+	}
+	return v.fsys.Remove(path)
+}
+
+type noopLocker struct{}
+
+func (noopLocker) Lock()   {}
+func (noopLocker) Unlock() {}
@@ -0,0 +1,117 @@
+package vacuum
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testDBPath = "/db"
+
+var (
+	testNow    = time.Unix(1706745600, 0) // 2024-02-01
+	testOldDay = time.Unix(1704067200, 0) // 2024-01-01
+	testNewDay = testNow.Add(-24 * time.Hour)
+)
+
+func writeTestDir(t *testing.T, fsys *godbtest.MemFS, iface string, day time.Time, garbage int) {
+	t.Helper()
+
+	dir := gpfile.NewDir(filepath.Join(testDBPath, iface), day.Unix(), gpfile.ModeWrite, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	var dbData [types.ColIdxCount][]byte
+	for colIdx := range dbData {
+		dbData[colIdx] = []byte{1, 2, 3, 4}
+	}
+	require.Nil(t, dir.WriteBlocks(day.Unix()+300, gpfile.TrafficMetadata{NumV4Entries: 1}, types.Counters{}, dbData))
+	require.Nil(t, dir.Close())
+
+	// Append data not referenced by any block to the first column (e.g. from an interrupted writeout)
+	if garbage > 0 {
+		f, err := fsys.OpenFile(filepath.Join(dir.Path(), types.ColumnFileNames[0]+gpfile.FileSuffix), os.O_WRONLY|os.O_APPEND, 0)
+		require.Nil(t, err)
+		_, err = f.Write(make([]byte, garbage))
+		require.Nil(t, err)
+		require.Nil(t, f.Close())
+	}
+}
+
+func newTestDB(t *testing.T) *godbtest.MemFS {
+	t.Helper()
+
+	fsys := godbtest.NewMemFS()
+	writeTestDir(t, fsys, "eth0", testOldDay, 100)
+	writeTestDir(t, fsys, "eth0", testNewDay, 0)
+	writeTestDir(t, fsys, "eth1", testOldDay, 0)
+	writeTestDir(t, fsys, "eth1", testNewDay, 10)
+	writeTestDir(t, fsys, "eth2", testOldDay, 0)
+	return fsys
+}
+
+func TestVacuum(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry-run=%t", dryRun), func(t *testing.T) {
+			fsys := newTestDB(t)
+			sizeBefore := fsys.Size()
+
+			v := New(testDBPath, WithFS(fsys), WithMinAge(7*24*time.Hour), WithDryRun(dryRun))
+			v.now = func() time.Time { return testNow }
+
+			results, err := v.Run(context.Background(), []string{"eth0"})
+			require.Nil(t, err)
+			require.Len(t, results, 3)
+
+			require.Equal(t, IfaceResult{Iface: "eth0", Configured: true, CompactedDirs: 1, ReclaimedBytes: 100}, results[0])
+			require.Equal(t, "eth1", results[1].Iface)
+			require.False(t, results[1].Configured)
+			require.Equal(t, 1, results[1].RemovedDirs)
+			require.Equal(t, 1, results[1].CompactedDirs)
+			require.Equal(t, "eth2", results[2].Iface)
+			require.Equal(t, 1, results[2].RemovedDirs)
+			require.Zero(t, results[2].CompactedDirs)
+			require.Equal(t, results[1].ReclaimedBytes-10, results[2].ReclaimedBytes)
+
+			if dryRun {
+				require.Equal(t, sizeBefore, fsys.Size())
+				return
+			}
+			require.Equal(t, sizeBefore-results.ReclaimedBytes(), fsys.Size())
+
+			// Only the configured / recent data must be left
+			_, err = fsys.Stat(gpfile.GenPathForTimestamp(filepath.Join(testDBPath, "eth0"), testOldDay.Unix()))
+			require.Nil(t, err)
+			_, err = fsys.Stat(gpfile.GenPathForTimestamp(filepath.Join(testDBPath, "eth1"), testNewDay.Unix()))
+			require.Nil(t, err)
+			_, err = fsys.Stat(gpfile.GenPathForTimestamp(filepath.Join(testDBPath, "eth1"), testOldDay.Unix()))
+			require.ErrorIs(t, err, os.ErrNotExist)
+			_, err = fsys.Stat(filepath.Join(testDBPath, "eth2"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			// A subsequent run must not reclaim anything
+			results, err = v.Run(context.Background(), []string{"eth0"})
+			require.Nil(t, err)
+			require.Zero(t, results.ReclaimedBytes())
+
+			// Compacted data must still be readable
+			dir := gpfile.NewDir(filepath.Join(testDBPath, "eth0"), testOldDay.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+			require.Nil(t, dir.Open())
+			data, err := dir.ReadBlockAtIndex(0, 0)
+			require.Nil(t, err)
+			require.Equal(t, []byte{1, 2, 3, 4}, data)
+			require.Nil(t, dir.Close())
+		})
+	}
+}
+
+func TestNegativeMinAge(t *testing.T) {
+	_, err := New(testDBPath, WithFS(godbtest.NewMemFS()), WithMinAge(-time.Hour)).Run(context.Background(), nil)
+	require.ErrorIs(t, err, ErrNegativeMinAge)
+}
@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"math"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+)
+
+const (
+	// minFrameWireSize denotes the size of a minimum-size Ethernet frame on the wire (64 bytes plus
+	// preamble and inter-frame gap), determining the worst-case packet rate of a link
+	minFrameWireSize = 84
+
+	// MinBurstHeadroom denotes the minimum duration a burst at line rate must be absorbable by the ring
+	// buffer of an interface for it to be considered sufficient
+	MinBurstHeadroom = 100 * time.Millisecond
+)
+
+// LineRate returns the worst-case packet rate (i.e. for minimum-size frames) of a link of the given
+// speed (in Mbit/s)
+func LineRate(speed uint32) uint64 {
+	return uint64(speed) * 1e6 / (8 * minFrameWireSize)
+}
+
+// Assess predicts if the ring buffer used during a benchmark run suffices for a link of the given speed
+// (in Mbit/s, zero if unknown): if the achieved processing rate falls short of the line rate, the ring
+// buffer has to absorb the excess packets of a burst at line rate for at least MinBurstHeadroom
+func Assess(res *Result, speed uint32, numBlocks int) capturetypes.BenchAssessment {
+	assessment := capturetypes.BenchAssessment{
+		Timestamp: time.Now(),
+		Rate:      uint64(res.Rate()),
+		LineRate:  LineRate(speed),
+		Capacity:  res.Capacity,
+	}
+	if assessment.LineRate <= assessment.Rate {
+		return assessment
+	}
+
+	excess := float64(assessment.LineRate - assessment.Rate)
+	assessment.Headroom = time.Duration(float64(res.Capacity) / excess * float64(time.Second))
+	if assessment.Headroom >= MinBurstHeadroom {
+		return assessment
+	}
+
+	assessment.Insufficient = true
+	if res.Capacity > 0 && numBlocks > 0 {
+		packetsPerBlock := float64(res.Capacity) / float64(numBlocks)
+		assessment.RecommendedNumBlocks = int(math.Ceil(excess * MinBurstHeadroom.Seconds() / packetsPerBlock))
+	}
+	return assessment
+}
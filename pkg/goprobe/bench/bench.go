@@ -0,0 +1,194 @@
+// Package bench drives the full capture pipeline of goProbe (capture, flow aggregation, rotation and
+// writeout to a goDB) with synthetic traffic generated by a mock source at a requested load, reporting
+// the achieved throughput, drops and rotation latencies. It allows operators to size the hardware
+// of a probe prior to its deployment
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+)
+
+const (
+	// DefaultDuration denotes the default duration of a benchmark run
+	DefaultDuration = 60 * time.Second
+
+	// DefaultRotationInterval denotes the default interval between rotations / writeouts during a
+	// benchmark run (which is shorter than the regular writeout interval in order to obtain a
+	// representative number of rotations)
+	DefaultRotationInterval = 10 * time.Second
+
+	// DefaultFlows denotes the default number of distinct flows generated
+	DefaultFlows = 10000
+
+	// MaxFlows denotes the maximum number of distinct flows that can be generated (each flow is
+	// assigned a distinct source address within 10.0.0.0/8)
+	MaxFlows = 1 << 24
+)
+
+// ErrMockUnavailable denotes that the binary has been built without support for mock sources
+var ErrMockUnavailable = errors.New("benchmarking requires mock source support (binary was built with slimcap_nomock)")
+
+// Config denotes the parameters of a benchmark run
+type Config struct {
+	Rate             uint64        // Rate: packets per second to generate (zero denotes an unlimited rate)
+	Flows            int           // Flows: number of distinct flows (each comprising a packet in both directions)
+	Duration         time.Duration // Duration: duration of the run
+	RotationInterval time.Duration // RotationInterval: interval between rotations / writeouts
+	DBPath           string        // DBPath: path of the goDB written to
+
+	// RingBuffer: ring buffer parameters of the mock source (optional, by default the ring buffer is
+	// sized to hold all flows)
+	RingBuffer *config.RingBufferConfig
+}
+
+func (c Config) validate() error {
+	if c.Flows <= 0 || c.Flows > MaxFlows {
+		return fmt.Errorf("number of flows must be between 1 and %d", MaxFlows)
+	}
+	if c.Duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	if c.RotationInterval <= 0 {
+		return errors.New("rotation interval must be positive")
+	}
+	if c.DBPath == "" {
+		return errors.New("no database path provided")
+	}
+	if c.RingBuffer != nil && (c.RingBuffer.BlockSize <= 0 || c.RingBuffer.NumBlocks <= 0) {
+		return errors.New("ring buffer block size and number of blocks must be positive")
+	}
+	return nil
+}
+
+// Result summarizes a benchmark run
+type Result struct {
+	Duration    time.Duration   `json:"duration_ns"`  // Duration: actual duration of the run
+	TargetRate  uint64          `json:"target_rate"`  // TargetRate: requested packets per second (zero denotes an unlimited rate)
+	Flows       int             `json:"flows"`        // Flows: number of distinct flows generated
+	Processed   uint64          `json:"processed"`    // Processed: number of packets processed
+	Dropped     uint64          `json:"dropped"`      // Dropped: number of packets dropped
+	Rotations   []time.Duration `json:"rotations_ns"` // Rotations: duration of each rotation / writeout
+	MaxFlowsOut int             `json:"max_flows"`    // MaxFlowsOut: maximum number of flows written in a single rotation
+	Capacity    int             `json:"capacity"`     // Capacity: number of packets held by the ring buffer of the mock source
+}
+
+// Rate returns the achieved throughput in packets per second
+func (r Result) Rate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Processed) / r.Duration.Seconds()
+}
+
+// DropRatio returns the fraction of packets dropped
+func (r Result) DropRatio() float64 {
+	if total := r.Processed + r.Dropped; total > 0 {
+		return float64(r.Dropped) / float64(total)
+	}
+	return 0
+}
+
+// RotationLatencies returns the minimum, average and maximum duration of all rotations
+func (r Result) RotationLatencies() (min, avg, max time.Duration) {
+	if len(r.Rotations) == 0 {
+		return
+	}
+	var sum time.Duration
+	min = r.Rotations[0]
+	for _, d := range r.Rotations {
+		sum += d
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return min, sum / time.Duration(len(r.Rotations)), max
+}
+
+// Print writes a human-readable summary of the result to w
+func (r Result) Print(w io.Writer) error {
+	target := "unlimited"
+	if r.TargetRate > 0 {
+		target = FormatRate(float64(r.TargetRate))
+	}
+	minLat, avgLat, maxLat := r.RotationLatencies()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Duration:\t%v\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(tw, "Flows:\t%d\n", r.Flows)
+	fmt.Fprintf(tw, "Target rate:\t%s\n", target)
+	fmt.Fprintf(tw, "Achieved rate:\t%s\n", FormatRate(r.Rate()))
+	fmt.Fprintf(tw, "Processed:\t%d\n", r.Processed)
+	fmt.Fprintf(tw, "Dropped:\t%d (%.2f%%)\n", r.Dropped, 100*r.DropRatio())
+	fmt.Fprintf(tw, "Rotations:\t%d (max. %d flows)\n", len(r.Rotations), r.MaxFlowsOut)
+	fmt.Fprintf(tw, "Rotation latency:\tmin %v / avg %v / max %v\n",
+		minLat.Round(time.Microsecond), avgLat.Round(time.Microsecond), maxLat.Round(time.Microsecond))
+	return tw.Flush()
+}
+
+var siMultipliers = []struct {
+	suffix, prefix string
+	factor         float64
+}{
+	{"g", "G", 1e9},
+	{"m", "M", 1e6},
+	{"k", "k", 1e3},
+}
+
+// ParseRate parses a packet rate, optionally using an SI prefix and / or the unit "pps", e.g.
+// "1Mpps", "250k" or "5000"
+func ParseRate(s string) (uint64, error) {
+	v, err := parseSI(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "pps"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// ParseCount parses a count, optionally using an SI prefix, e.g. "100k"
+func ParseCount(s string) (int, error) {
+	v, err := parseSI(strings.ToLower(strings.TrimSpace(s)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %q: %w", s, err)
+	}
+	return int(v), nil
+}
+
+func parseSI(s string) (uint64, error) {
+	factor := 1.
+	for _, m := range siMultipliers {
+		if strings.HasSuffix(s, m.suffix) {
+			s, factor = strings.TrimSuffix(s, m.suffix), m.factor
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("not a number")
+	}
+	if v < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return uint64(v * factor), nil
+}
+
+// FormatRate formats a packet rate using an SI prefix
+func FormatRate(rate float64) string {
+	for _, m := range siMultipliers {
+		if rate >= m.factor {
+			return strconv.FormatFloat(rate/m.factor, 'f', 2, 64) + " " + m.prefix + "pps"
+		}
+	}
+	return strconv.FormatFloat(rate, 'f', 2, 64) + " pps"
+}
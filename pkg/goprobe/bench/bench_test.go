@@ -0,0 +1,104 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	for input, expected := range map[string]uint64{
+		"1Mpps":    1000000,
+		"1mpps":    1000000,
+		"250k":     250000,
+		"1.5Mpps":  1500000,
+		"5000":     5000,
+		"5000pps":  5000,
+		"2G":       2000000000,
+		" 10kpps ": 10000,
+		"0":        0,
+	} {
+		t.Run(input, func(t *testing.T) {
+			rate, err := ParseRate(input)
+			require.Nil(t, err)
+			require.Equal(t, expected, rate)
+		})
+	}
+
+	for _, input := range []string{"", "pps", "fast", "-1k", "1Tpps", "inf", "nan"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseRate(input)
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	count, err := ParseCount("100k")
+	require.Nil(t, err)
+	require.Equal(t, 100000, count)
+
+	_, err = ParseCount("100kpps")
+	require.NotNil(t, err)
+}
+
+func TestFormatRate(t *testing.T) {
+	require.Equal(t, "1.00 Mpps", FormatRate(1e6))
+	require.Equal(t, "999.50 kpps", FormatRate(999500))
+	require.Equal(t, "12.00 pps", FormatRate(12))
+}
+
+func TestResult(t *testing.T) {
+	res := Result{
+		Duration:  10 * time.Second,
+		Processed: 990,
+		Dropped:   10,
+		Rotations: []time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond},
+	}
+	require.Equal(t, 99., res.Rate())
+	require.Equal(t, 0.01, res.DropRatio())
+
+	minLat, avgLat, maxLat := res.RotationLatencies()
+	require.Equal(t, time.Millisecond, minLat)
+	require.Equal(t, 2*time.Millisecond, avgLat)
+	require.Equal(t, 3*time.Millisecond, maxLat)
+
+	require.Zero(t, Result{}.Rate())
+	require.Zero(t, Result{}.DropRatio())
+}
+
+func TestAssess(t *testing.T) {
+	var tests = []struct {
+		name               string
+		capacity           int
+		speed              uint32
+		expectedLineRate   uint64
+		expectedHeadroom   time.Duration
+		expectedRecBlocks  int
+		expectedSufficient bool
+	}{
+		{"unknown link speed", 32768, 0, 0, 0, 0, true},
+		{"processing exceeds line rate", 32768, 100, 148809, 0, 0, true},
+		{"sufficient headroom", 65536, 1000, 1488095, 134268 * time.Microsecond, 0, true},
+		{"insufficient headroom", 32768, 1000, 1488095, 67134 * time.Microsecond, 6, false},
+		{"insufficient headroom 10G", 32768, 10000, 14880952, 2360 * time.Microsecond, 170, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := &Result{
+				Duration:  time.Second,
+				Processed: 1000000,
+				Capacity:  test.capacity,
+			}
+			assessment := Assess(res, test.speed, 4)
+			require.EqualValues(t, 1000000, assessment.Rate)
+			require.Equal(t, test.expectedLineRate, assessment.LineRate)
+			require.Equal(t, test.capacity, assessment.Capacity)
+			require.Equal(t, test.expectedHeadroom, assessment.Headroom.Truncate(time.Microsecond))
+			require.Equal(t, !test.expectedSufficient, assessment.Insufficient)
+			require.Equal(t, test.expectedRecBlocks, assessment.RecommendedNumBlocks)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+//go:build !slimcap_nomock
+// +build !slimcap_nomock
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goprobe/writeout"
+	"github.com/els0r/telemetry/logging"
+	"github.com/fako1024/slimcap/capture/afpacket/afring"
+	"github.com/fako1024/slimcap/link"
+
+	slimcap "github.com/fako1024/slimcap/capture"
+)
+
+const (
+	// Iface denotes the name of the (synthetic) interface captured on during a benchmark run
+	Iface = "bench"
+
+	defaultBlockSize = 1024 * 1024
+
+	// estimated size of a single packet in the ring buffer (including the frame header), used to
+	// determine the number of blocks required to hold all flows
+	estFrameSize = 128
+)
+
+// Run performs a benchmark run, driving the capture pipeline with synthetic traffic according to
+// cfg until the configured duration has elapsed (or the context is cancelled)
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	logger := logging.FromContext(ctx)
+
+	blockSize, numBlocks := defaultBlockSize, 2*cfg.Flows*estFrameSize/defaultBlockSize
+	if numBlocks < 4 {
+		numBlocks = 4
+	}
+	if cfg.RingBuffer != nil {
+		blockSize, numBlocks = cfg.RingBuffer.BlockSize, cfg.RingBuffer.NumBlocks
+	}
+
+	res := &Result{
+		TargetRate: cfg.Rate,
+	}
+	writeoutHandler := writeout.NewGoDBHandler(cfg.DBPath, encoders.EncoderTypeLZ4).
+		WithPermissions(goDB.DefaultPermissions)
+	captureManager := capture.NewManager(writeoutHandler,
+		capture.WithSourceInitFn(newSourceInitFn(cfg, blockSize, numBlocks, res)),
+	)
+	if _, _, _, err := captureManager.Update(ctx, config.Ifaces{
+		Iface: config.CaptureConfig{
+			RingBuffer: &config.RingBufferConfig{
+				BlockSize: blockSize,
+				NumBlocks: numBlocks,
+			},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to start capture: %w", err)
+	}
+	if res.Flows < cfg.Flows {
+		logger.Warnf("ring buffer only holds %d of %d flows", res.Flows, cfg.Flows)
+	}
+	logger.With("flows", res.Flows, "rate", cfg.Rate, "duration", cfg.Duration).Info("started benchmark")
+
+	// Rotate / write out periodically, recording the duration of each rotation
+	t0 := time.Now()
+	timer := time.NewTimer(cfg.Duration)
+	defer timer.Stop()
+	ticker := time.NewTicker(cfg.RotationInterval)
+	defer ticker.Stop()
+
+	rotate := func() {
+		tRotate := time.Now()
+		_, rotated, err := captureManager.Flush(ctx, Iface)
+		if err != nil {
+			logger.Errorf("failed to rotate: %v", err)
+			return
+		}
+		res.Rotations = append(res.Rotations, time.Since(tRotate))
+		for _, r := range rotated {
+			if r.NumFlows > res.MaxFlowsOut {
+				res.MaxFlowsOut = r.NumFlows
+			}
+		}
+	}
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			rotate()
+		case <-timer.C:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	res.Duration = time.Since(t0)
+
+	// Rotate the flows captured since the last rotation and stop capturing
+	rotate()
+	stats := captureManager.Status(context.Background(), Iface)[Iface]
+	res.Processed, res.Dropped = stats.ProcessedTotal, stats.DroppedTotal
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.RotationInterval)
+	defer cancel()
+	captureManager.Close(shutdownCtx)
+
+	return res, nil
+}
+
+// newSourceInitFn returns a function initializing a mock source replaying a ring buffer filled with
+// packets of the configured number of flows (in both directions) at the configured rate. The number
+// of flows actually fitting into the ring buffer (and the number of packets it holds) is stored in res
+func newSourceInitFn(cfg Config, blockSize, numBlocks int, res *Result) func(c *capture.Capture) (capture.Source, error) {
+	return func(c *capture.Capture) (capture.Source, error) {
+		mockSrc, err := afring.NewMockSourceNoDrain(c.Iface(),
+			afring.CaptureLength(link.CaptureLengthMinimalIPv6Transport),
+			afring.Promiscuous(false),
+			afring.BufferSize(blockSize, numBlocks),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			nPackets int
+			dstIP    = net.ParseIP("192.168.0.1")
+		)
+		for i := 0; mockSrc.CanAddPackets(); i = (i + 1) % cfg.Flows {
+			srcIP := net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))
+			sport := uint16(1024 + i%64000)
+
+			p, err := slimcap.BuildPacket(srcIP, dstIP, sport, 443, 6, []byte{1, 2, 3, 4}, slimcap.PacketOutgoing, 128)
+			if err != nil {
+				return nil, err
+			}
+			if err := mockSrc.AddPacket(p); err != nil {
+				return nil, err
+			}
+			nPackets++
+
+			if !mockSrc.CanAddPackets() {
+				break
+			}
+			pRet, err := slimcap.BuildPacket(dstIP, srcIP, 443, sport, 6, []byte{1, 2, 3, 4}, slimcap.PacketThisHost, 1024)
+			if err != nil {
+				return nil, err
+			}
+			if err := mockSrc.AddPacket(pRet); err != nil {
+				return nil, err
+			}
+			nPackets++
+		}
+		res.Flows, res.Capacity = min(cfg.Flows, (nPackets+1)/2), nPackets
+
+		// Each block is released after the time it takes to "receive" its packets at the requested
+		// rate (or as fast as possible if the rate is unlimited)
+		releaseInterval := time.Microsecond
+		if cfg.Rate > 0 {
+			packetsPerBlock := uint64(nPackets / numBlocks)
+			releaseInterval = time.Duration(packetsPerBlock * uint64(time.Second) / cfg.Rate)
+		}
+		_, err = mockSrc.Run(releaseInterval)
+
+		return mockSrc, err
+	}
+}
@@ -0,0 +1,11 @@
+//go:build slimcap_nomock
+// +build slimcap_nomock
+
+package bench
+
+import "context"
+
+// Run performs a benchmark run. Since mock sources are not available in this build, it always fails
+func Run(_ context.Context, _ Config) (*Result, error) {
+	return nil, ErrMockUnavailable
+}
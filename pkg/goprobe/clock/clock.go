@@ -0,0 +1,76 @@
+// Package clock abstracts the time source of the capture and writeout logic, allowing tests (and
+// simulations) to fast-forward rotations deterministically instead of waiting for the wall clock
+package clock
+
+import "time"
+
+// Clock denotes a source of the current time, timers and tickers
+type Clock interface {
+
+	// Now returns the current time
+	Now() time.Time
+
+	// NewTimer creates a Timer that fires once after (at least) d has elapsed
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a Ticker that fires every d (dropping ticks for slow receivers)
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer denotes a single event, c.f. time.Timer
+type Timer interface {
+
+	// C returns the channel the time is delivered on once the timer fires
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, returning false if it already fired or was stopped
+	Stop() bool
+}
+
+// Ticker denotes a recurring event, c.f. time.Ticker
+type Ticker interface {
+
+	// C returns the channel the ticks are delivered on
+	C() <-chan time.Time
+
+	// Stop turns off the ticker (without closing its channel)
+	Stop()
+}
+
+// Real is the Clock based on the system time (i.e. the time package)
+var Real Clock = realClock{}
+
+// Since returns the time elapsed since t according to a Clock
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
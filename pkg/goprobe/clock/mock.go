@@ -0,0 +1,196 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Mock is a Clock that only advances when instructed to (via Advance / Set), firing all timers and
+// tickers that become due in chronological order. Like for time.Ticker, ticks are dropped if the
+// receiver has not yet consumed the previous one, hence fast-forwarding by more than one interval
+// at a time only delivers a single tick
+type Mock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*mockWaiter
+}
+
+// NewMock creates a new Mock clock, starting at the provided time
+func NewMock(start time.Time) *Mock {
+	m := &Mock{now: start}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the current (mocked) time
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// NewTimer creates a Timer that fires once the clock has been advanced by (at least) d
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	return mockTimer{m.addWaiter(d, 0)}
+}
+
+// NewTicker creates a Ticker that fires every time the clock has been advanced by d
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return mockTicker{m.addWaiter(d, d)}
+}
+
+// Advance moves the clock forward by d
+func (m *Mock) Advance(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the clock to t, firing all timers / tickers that are due until then. Moving the clock
+// backwards does not fire anything
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		w := m.nextDue(t)
+		if w == nil {
+			break
+		}
+		if w.deadline.After(m.now) {
+			m.now = w.deadline
+		}
+		w.fire(m.now)
+	}
+	m.now = t
+}
+
+// Jump steps the clock by d (forward or backward) without firing any timers / tickers, simulating
+// an adjustment of the system time (e.g. by NTP): pending timers and tickers still fire after the
+// originally requested duration has elapsed, but deliver the adjusted time
+func (m *Mock) Jump(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+	for _, w := range m.waiters {
+		w.deadline = w.deadline.Add(d)
+	}
+}
+
+// Waiters returns the number of active (i.e. neither fired nor stopped) timers and tickers
+func (m *Mock) Waiters() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.waiters)
+}
+
+// BlockUntil blocks until at least n timers / tickers are active, allowing to synchronize with
+// goroutines setting them up before advancing the clock
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.waiters) < n {
+		m.cond.Wait()
+	}
+}
+
+func (m *Mock) addWaiter(d, interval time.Duration) *mockWaiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := &mockWaiter{
+		clock:    m,
+		deadline: m.now.Add(d),
+		interval: interval,
+		c:        make(chan time.Time, 1),
+	}
+
+	// timers without any duration fire right away (like the ones of the time package)
+	if interval == 0 && d <= 0 {
+		w.c <- m.now
+		return w
+	}
+
+	m.waiters = append(m.waiters, w)
+	m.cond.Broadcast()
+
+	return w
+}
+
+// nextDue returns the active waiter with the earliest deadline not after t (if any)
+func (m *Mock) nextDue(t time.Time) *mockWaiter {
+	sort.SliceStable(m.waiters, func(i, j int) bool {
+		return m.waiters[i].deadline.Before(m.waiters[j].deadline)
+	})
+	if len(m.waiters) == 0 || m.waiters[0].deadline.After(t) {
+		return nil
+	}
+	return m.waiters[0]
+}
+
+func (m *Mock) removeWaiter(w *mockWaiter) bool {
+	for i, waiter := range m.waiters {
+		if waiter == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			m.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// mockWaiter denotes a pending event of both a Timer (interval == 0) and a Ticker (interval > 0)
+type mockWaiter struct {
+	clock    *Mock
+	deadline time.Time
+	interval time.Duration
+	c        chan time.Time
+}
+
+// fire delivers the current time (must be called with the lock of the clock held)
+func (w *mockWaiter) fire(now time.Time) {
+	select {
+	case w.c <- now:
+	default:
+	}
+
+	if w.interval > 0 {
+		w.deadline = w.deadline.Add(w.interval)
+		return
+	}
+	w.clock.removeWaiter(w)
+}
+
+func (w *mockWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *mockWaiter) stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	return w.clock.removeWaiter(w)
+}
+
+type mockTimer struct {
+	*mockWaiter
+}
+
+func (t mockTimer) Stop() bool {
+	return t.stop()
+}
+
+type mockTicker struct {
+	*mockWaiter
+}
+
+func (t mockTicker) Stop() {
+	t.stop()
+}
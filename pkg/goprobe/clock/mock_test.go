@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var testStart = time.Unix(1704067200, 0)
+
+func expectFired(t *testing.T, c <-chan time.Time, expected time.Time) {
+	t.Helper()
+
+	select {
+	case ts := <-c:
+		if !ts.Equal(expected) {
+			t.Fatalf("unexpected time delivered: %v (expected %v)", ts, expected)
+		}
+	default:
+		t.Fatalf("expected event at %v, got none", expected)
+	}
+}
+
+func expectPending(t *testing.T, c <-chan time.Time) {
+	t.Helper()
+
+	select {
+	case ts := <-c:
+		t.Fatalf("unexpected event at %v", ts)
+	default:
+	}
+}
+
+func TestMockTimer(t *testing.T) {
+	m := NewMock(testStart)
+
+	timer := m.NewTimer(time.Minute)
+	m.Advance(59 * time.Second)
+	expectPending(t, timer.C())
+
+	m.Advance(time.Second)
+	expectFired(t, timer.C(), testStart.Add(time.Minute))
+	if timer.Stop() {
+		t.Fatalf("stopping a fired timer must return false")
+	}
+
+	stopped := m.NewTimer(time.Minute)
+	if !stopped.Stop() {
+		t.Fatalf("stopping a pending timer must return true")
+	}
+	m.Advance(time.Hour)
+	expectPending(t, stopped.C())
+
+	expectFired(t, m.NewTimer(0).C(), testStart.Add(time.Hour+time.Minute))
+	if m.Waiters() != 0 {
+		t.Fatalf("unexpected number of waiters: %d", m.Waiters())
+	}
+}
+
+func TestMockTicker(t *testing.T) {
+	m := NewMock(testStart)
+
+	ticker := m.NewTicker(5 * time.Minute)
+	timer := m.NewTimer(7 * time.Minute)
+	for i := 1; i <= 3; i++ {
+		m.Advance(5 * time.Minute)
+		expectFired(t, ticker.C(), testStart.Add(time.Duration(i)*5*time.Minute))
+		if i == 2 {
+			expectFired(t, timer.C(), testStart.Add(7*time.Minute))
+		}
+	}
+
+	// ticks are dropped for slow receivers
+	m.Advance(time.Hour)
+	expectFired(t, ticker.C(), testStart.Add(20*time.Minute))
+	expectPending(t, ticker.C())
+	if now := m.Now(); !now.Equal(testStart.Add(75 * time.Minute)) {
+		t.Fatalf("unexpected time after advancing: %v", now)
+	}
+
+	ticker.Stop()
+	m.Advance(time.Hour)
+	expectPending(t, ticker.C())
+}
+
+func TestMockJump(t *testing.T) {
+	m := NewMock(testStart)
+
+	ticker := m.NewTicker(5 * time.Minute)
+	m.Jump(-time.Hour)
+	m.Advance(5*time.Minute - time.Second)
+	expectPending(t, ticker.C())
+
+	m.Advance(time.Second)
+	expectFired(t, ticker.C(), testStart.Add(5*time.Minute-time.Hour))
+
+	m.Jump(2 * time.Minute)
+	m.Advance(5 * time.Minute)
+	expectFired(t, ticker.C(), testStart.Add(12*time.Minute-time.Hour))
+}
+
+func TestMockBlockUntil(t *testing.T) {
+	m := NewMock(testStart)
+
+	done := make(chan time.Time)
+	go func() {
+		timer := m.NewTimer(time.Minute)
+		done <- <-timer.C()
+	}()
+
+	m.BlockUntil(1)
+	m.Advance(time.Minute)
+	if ts := <-done; !ts.Equal(testStart.Add(time.Minute)) {
+		t.Fatalf("unexpected time delivered: %v", ts)
+	}
+}
@@ -0,0 +1,299 @@
+// Package statspush delivers the capture and writeout statistics of each rotation to a statsd
+// or graphite endpoint, providing probe monitoring without a Prometheus setup
+package statspush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+)
+
+// Supported protocols
+const (
+	ProtocolStatsd   = "statsd"   // ProtocolStatsd: gauges sent via UDP using the statsd line protocol
+	ProtocolGraphite = "graphite" // ProtocolGraphite: metrics sent via TCP using the graphite plaintext protocol
+)
+
+const (
+	// DefaultPrefix denotes the default prefix template of all metric names
+	DefaultPrefix = "goprobe.{{.Hostname}}"
+
+	// DefaultTimeout denotes the default timeout for delivering the metrics of a single rotation
+	DefaultTimeout = 5 * time.Second
+
+	// maxDatagramSize limits the size of a single statsd datagram (to avoid fragmentation on
+	// common networks)
+	maxDatagramSize = 1432
+)
+
+var (
+	errorUnknownProtocol = errors.New("unknown stats push protocol (must be statsd or graphite)")
+	errorNoAddr          = errors.New("no stats push address provided")
+	errorNegativeTimeout = errors.New("stats push timeout must not be negative")
+)
+
+// Config stores the configuration of the endpoint statistics are pushed to
+type Config struct {
+	// Protocol: denotes the protocol used to deliver the metrics
+	// Enum: [statsd, graphite]. Example: statsd
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Addr: denotes the address (host:port) of the statsd / graphite endpoint
+	// Example: "localhost:8125"
+	Addr string `json:"addr" yaml:"addr"`
+
+	// Prefix: denotes the template used to render the prefix of all metric names. The hostname
+	// of the probe is available as {{.Hostname}}. Defaults to "goprobe.{{.Hostname}}"
+	// Example: "monitoring.probes.{{.Hostname}}"
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+
+	// Timeout: denotes the timeout for delivering the metrics of a single rotation. Defaults to 5s
+	// Example: 5s
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Validate checks that the configuration can be used to push statistics
+func (c *Config) Validate() error {
+	switch c.Protocol {
+	case ProtocolStatsd, ProtocolGraphite:
+	default:
+		return errorUnknownProtocol
+	}
+	if c.Addr == "" {
+		return errorNoAddr
+	}
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("invalid stats push address: %w", err)
+	}
+	if c.Timeout < 0 {
+		return errorNegativeTimeout
+	}
+	if _, err := c.renderPrefix("localhost"); err != nil {
+		return fmt.Errorf("invalid stats push prefix: %w", err)
+	}
+	return nil
+}
+
+// renderPrefix renders the metric name prefix for a host
+func (c *Config) renderPrefix(hostname string) (string, error) {
+	prefix := c.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	tmpl, err := template.New("prefix").Parse(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Hostname string }{sanitize(hostname)}); err != nil {
+		return "", err
+	}
+	return strings.Trim(buf.String(), "."), nil
+}
+
+// Metric denotes a single (named) value
+type Metric struct {
+	Name  string
+	Value float64
+}
+
+// Pusher delivers metrics to a statsd / graphite endpoint
+type Pusher struct {
+	protocol string
+	addr     string
+	prefix   string
+	timeout  time.Duration
+
+	dialer net.Dialer
+}
+
+// New creates a new pusher for the provided configuration, rendering the metric name prefix
+// for the local host
+func New(cfg Config) (*Pusher, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+	return newPusher(cfg, hostname)
+}
+
+func newPusher(cfg Config, hostname string) (*Pusher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	prefix, err := cfg.renderPrefix(hostname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render stats push prefix: %w", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Pusher{
+		protocol: cfg.Protocol,
+		addr:     cfg.Addr,
+		prefix:   prefix,
+		timeout:  timeout,
+	}, nil
+}
+
+// HandleRotationStats pushes the statistics of a rotation. Delivery failures are logged only,
+// since they must not impact the capture / writeout
+func (p *Pusher) HandleRotationStats(ctx context.Context, stats capturetypes.RotationStats) {
+	err := p.Push(ctx, stats.Timestamp, RotationMetrics(stats))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("failed to push rotation statistics: %v", err)
+	}
+}
+
+// Push delivers a set of metrics observed at timestamp t
+func (p *Pusher) Push(ctx context.Context, t time.Time, metrics []Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	network := "tcp"
+	if p.protocol == ProtocolStatsd {
+		network = "udp"
+	}
+	conn, err := p.dialer.DialContext(ctx, network, p.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	for _, payload := range p.encode(t, metrics) {
+		if _, err := conn.Write(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode serializes the metrics according to the protocol. For statsd, the metrics are split
+// into several datagrams if required
+func (p *Pusher) encode(t time.Time, metrics []Metric) (payloads [][]byte) {
+	var buf []byte
+	for _, metric := range metrics {
+		var line []byte
+		line = append(line, p.prefix...)
+		if len(p.prefix) > 0 {
+			line = append(line, '.')
+		}
+		line = append(line, metric.Name...)
+
+		switch p.protocol {
+		case ProtocolStatsd:
+			line = append(line, ':')
+			line = strconv.AppendFloat(line, metric.Value, 'f', -1, 64)
+			line = append(line, "|g\n"...)
+
+			if len(buf) > 0 && len(buf)+len(line) > maxDatagramSize {
+				payloads = append(payloads, buf)
+				buf = nil
+			}
+		case ProtocolGraphite:
+			line = append(line, ' ')
+			line = strconv.AppendFloat(line, metric.Value, 'f', -1, 64)
+			line = append(line, ' ')
+			line = strconv.AppendInt(line, t.Unix(), 10)
+			line = append(line, '\n')
+		}
+		buf = append(buf, line...)
+	}
+	return append(payloads, buf)
+}
+
+// RotationMetrics converts the statistics of a rotation into metrics, i.e.
+//
+//	capture.<iface>.{packets_received,packets_processed,packets_dropped,packets_filtered,parsing_errors,flows}
+//	capture.<iface>.{tcp_handshakes,tcp_handshake_rtt_{min,median,max}_seconds} (if available)
+//	writeout.{duration_seconds,queue_depth,oldest_pending_age_seconds,degraded}
+//	writeout.sink_latency_seconds.<sink>
+func RotationMetrics(stats capturetypes.RotationStats) (metrics []Metric) {
+	ifaces := make([]string, 0, len(stats.Ifaces))
+	for iface := range stats.Ifaces {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	for _, iface := range ifaces {
+		rotated := stats.Ifaces[iface]
+		prefix := "capture." + sanitize(iface) + "."
+
+		metrics = append(metrics,
+			Metric{prefix + "packets_received", float64(rotated.Stats.Received)},
+			Metric{prefix + "packets_processed", float64(rotated.Stats.Processed)},
+			Metric{prefix + "packets_dropped", float64(rotated.Stats.Dropped)},
+			Metric{prefix + "packets_filtered", float64(rotated.Stats.Filtered)},
+			Metric{prefix + "parsing_errors", float64(rotated.Stats.ParsingErrors.Sum())},
+			Metric{prefix + "flows", float64(rotated.NumFlows)},
+		)
+		if rtt := rotated.Stats.HandshakeRTT; rtt != nil {
+			metrics = append(metrics,
+				Metric{prefix + "tcp_handshakes", float64(rtt.Samples)},
+				Metric{prefix + "tcp_handshake_rtt_min_seconds", rtt.Min.Seconds()},
+				Metric{prefix + "tcp_handshake_rtt_median_seconds", rtt.Median.Seconds()},
+				Metric{prefix + "tcp_handshake_rtt_max_seconds", rtt.Max.Seconds()},
+			)
+		}
+	}
+
+	var degraded float64
+	if stats.Writeout.IsDegraded() {
+		degraded = 1
+	}
+	metrics = append(metrics,
+		Metric{"writeout.duration_seconds", stats.WriteoutDuration.Seconds()},
+		Metric{"writeout.queue_depth", float64(stats.Writeout.QueueDepth)},
+		Metric{"writeout.oldest_pending_age_seconds", stats.Writeout.OldestPendingAge.Seconds()},
+		Metric{"writeout.degraded", degraded},
+	)
+
+	sinks := make([]string, 0, len(stats.Writeout.SinkLatencies))
+	for sink := range stats.Writeout.SinkLatencies {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+	for _, sink := range sinks {
+		metrics = append(metrics, Metric{"writeout.sink_latency_seconds." + sanitize(sink), stats.Writeout.SinkLatencies[sink].Seconds()})
+	}
+
+	return metrics
+}
+
+// sanitize replaces all characters with special meaning in metric names (e.g. the dots in a
+// hostname or VLAN interface name) by underscores
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
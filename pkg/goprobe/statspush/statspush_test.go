@@ -0,0 +1,148 @@
+package statspush
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+var testStats = capturetypes.RotationStats{
+	Timestamp: time.Unix(1704067200, 0),
+	Ifaces: map[string]capturetypes.RotatedIface{
+		"eth0.100": {Stats: capturetypes.CaptureStats{Received: 100, Processed: 99, Dropped: 1, Filtered: 2}, NumFlows: 10},
+		"eth0": {Stats: capturetypes.CaptureStats{Received: 50, Processed: 50, HandshakeRTT: &capturetypes.HandshakeRTT{
+			Samples: 3, Min: 10 * time.Millisecond, Median: 20 * time.Millisecond, Max: 30 * time.Millisecond,
+		}}, NumFlows: 5},
+	},
+	WriteoutDuration: 250 * time.Millisecond,
+	Writeout: capturetypes.WriteoutStats{
+		Status:        types.StatusDegraded,
+		QueueDepth:    2,
+		SinkLatencies: map[string]time.Duration{"godb": 100 * time.Millisecond},
+	},
+}
+
+func TestRotationMetrics(t *testing.T) {
+	require.Equal(t, []Metric{
+		{"capture.eth0.packets_received", 50},
+		{"capture.eth0.packets_processed", 50},
+		{"capture.eth0.packets_dropped", 0},
+		{"capture.eth0.packets_filtered", 0},
+		{"capture.eth0.parsing_errors", 0},
+		{"capture.eth0.flows", 5},
+		{"capture.eth0.tcp_handshakes", 3},
+		{"capture.eth0.tcp_handshake_rtt_min_seconds", 0.01},
+		{"capture.eth0.tcp_handshake_rtt_median_seconds", 0.02},
+		{"capture.eth0.tcp_handshake_rtt_max_seconds", 0.03},
+		{"capture.eth0_100.packets_received", 100},
+		{"capture.eth0_100.packets_processed", 99},
+		{"capture.eth0_100.packets_dropped", 1},
+		{"capture.eth0_100.packets_filtered", 2},
+		{"capture.eth0_100.parsing_errors", 0},
+		{"capture.eth0_100.flows", 10},
+		{"writeout.duration_seconds", 0.25},
+		{"writeout.queue_depth", 2},
+		{"writeout.oldest_pending_age_seconds", 0},
+		{"writeout.degraded", 1},
+		{"writeout.sink_latency_seconds.godb", 0.1},
+	}, RotationMetrics(testStats))
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"statsd", Config{Protocol: ProtocolStatsd, Addr: "localhost:8125"}, true},
+		{"graphite with prefix", Config{Protocol: ProtocolGraphite, Addr: "localhost:2003", Prefix: "probes.{{.Hostname}}.gp"}, true},
+		{"unknown protocol", Config{Protocol: "influxdb", Addr: "localhost:8125"}, false},
+		{"no address", Config{Protocol: ProtocolStatsd}, false},
+		{"address without port", Config{Protocol: ProtocolStatsd, Addr: "localhost"}, false},
+		{"negative timeout", Config{Protocol: ProtocolStatsd, Addr: "localhost:8125", Timeout: -time.Second}, false},
+		{"invalid template", Config{Protocol: ProtocolStatsd, Addr: "localhost:8125", Prefix: "{{.Hostname"}, false},
+		{"unknown template field", Config{Protocol: ProtocolStatsd, Addr: "localhost:8125", Prefix: "{{.Iface}}"}, false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg.Validate()
+			if test.valid {
+				require.Nil(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPushStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+
+	pusher, err := newPusher(Config{Protocol: ProtocolStatsd, Addr: conn.LocalAddr().String()}, "probe.example.com")
+	require.Nil(t, err)
+
+	// enough metrics to require several datagrams
+	var metrics []Metric
+	for i := 0; i < 100; i++ {
+		metrics = append(metrics, Metric{"capture.eth0.packets_received", float64(i)})
+	}
+	require.Nil(t, pusher.Push(context.Background(), time.Now(), metrics))
+
+	var lines []string
+	buf := make([]byte, 65536)
+	for len(lines) < len(metrics) {
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.Nil(t, err)
+		require.LessOrEqual(t, n, maxDatagramSize)
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(buf[:n]), "\n"), "\n")...)
+	}
+	require.Len(t, lines, len(metrics))
+	require.Equal(t, "goprobe.probe_example_com.capture.eth0.packets_received:0|g", lines[0])
+	require.Equal(t, "goprobe.probe_example_com.capture.eth0.packets_received:99|g", lines[99])
+}
+
+func TestPushGraphite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}()
+
+	pusher, err := newPusher(Config{Protocol: ProtocolGraphite, Addr: listener.Addr().String(), Prefix: "probes.{{.Hostname}}"}, "probe1")
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	pusher.HandleRotationStats(ctx, testStats)
+
+	lines := <-received
+	require.Len(t, lines, len(RotationMetrics(testStats)))
+	require.Equal(t, "probes.probe1.capture.eth0.packets_received 50 1704067200", lines[0])
+	require.Equal(t, "probes.probe1.writeout.duration_seconds 0.25 1704067200", lines[16])
+}
@@ -0,0 +1,224 @@
+package writeout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// SinkGoDB denotes the (local) goDB writeout sink
+	SinkGoDB = "godb"
+
+	// SinkSyslog denotes the syslog flow writeout sink
+	SinkSyslog = "syslog"
+
+	// SinkSpool denotes the (local) JSON spool writeout sink
+	SinkSpool = "spool"
+
+	// SinkStream denotes the (external) gRPC flow stream writeout sink
+	SinkStream = "stream"
+)
+
+// DefaultMaxPendingAge denotes the default maximum age of the oldest pending writeout before the
+// writeout is reported as degraded. If it is exceeded, at least one rotation was missed
+var DefaultMaxPendingAge = 2 * time.Duration(goDB.DBWriteInterval) * time.Second
+
+// DefaultBacklogCheckInterval denotes the default interval in which the writeout backlog is
+// checked against its bounds
+const DefaultBacklogCheckInterval = 10 * time.Second
+
+// StatsProvider is implemented by writeout handlers that expose statistics about their backlog
+type StatsProvider interface {
+
+	// WriteoutStats returns the current statistics of the writeout backlog
+	WriteoutStats() capturetypes.WriteoutStats
+}
+
+// pendingWriteout tracks a single rotation whose flow maps are (partially) pending writeout
+type pendingWriteout struct {
+	timestamp time.Time
+	queue     <-chan capturetypes.TaggedAggFlowMap
+	inFlight  int
+}
+
+// backlog tracks all pending writeouts as well as the latency of the individual sinks
+type backlog struct {
+	pending       map[*pendingWriteout]struct{}
+	sinkLatencies map[string]time.Duration
+
+	maxQueueDepth int
+	maxPendingAge time.Duration
+	degraded      bool
+
+	sync.Mutex
+}
+
+func newBacklog() *backlog {
+	return &backlog{
+		pending:       make(map[*pendingWriteout]struct{}),
+		sinkLatencies: make(map[string]time.Duration),
+		maxPendingAge: DefaultMaxPendingAge,
+	}
+}
+
+// add registers a new rotation whose flow maps are provided via queue
+func (b *backlog) add(timestamp time.Time, queue <-chan capturetypes.TaggedAggFlowMap) *pendingWriteout {
+	p := &pendingWriteout{
+		timestamp: timestamp,
+		queue:     queue,
+	}
+
+	b.Lock()
+	b.pending[p] = struct{}{}
+	b.Unlock()
+
+	return p
+}
+
+// startWrite marks a flow map of the rotation as being written
+func (b *backlog) startWrite(p *pendingWriteout) {
+	b.Lock()
+	p.inFlight++
+	b.Unlock()
+}
+
+// endWrite marks a flow map of the rotation as written
+func (b *backlog) endWrite(p *pendingWriteout) {
+	b.Lock()
+	p.inFlight--
+	b.Unlock()
+}
+
+// done removes the rotation from the backlog
+func (b *backlog) done(p *pendingWriteout) {
+	b.Lock()
+	delete(b.pending, p)
+	b.Unlock()
+}
+
+// observeSink records the latency of a write to a sink
+func (b *backlog) observeSink(sink string, elapsed time.Duration) {
+	sinkLatency.WithLabelValues(sink).Observe(float64(elapsed) / float64(time.Second))
+
+	b.Lock()
+	b.sinkLatencies[sink] = elapsed
+	b.Unlock()
+}
+
+// stats computes the current backlog statistics and checks them against the configured bounds
+func (b *backlog) stats(now time.Time) (stats capturetypes.WriteoutStats) {
+	b.Lock()
+	defer b.Unlock()
+
+	for p := range b.pending {
+		stats.QueueDepth += len(p.queue) + p.inFlight
+		if stats.OldestPending.IsZero() || p.timestamp.Before(stats.OldestPending) {
+			stats.OldestPending = p.timestamp
+		}
+	}
+
+	// writeouts may be scheduled slightly ahead of time (e.g. when an interface is removed)
+	if !stats.OldestPending.IsZero() {
+		if age := now.Sub(stats.OldestPending); age > 0 {
+			stats.OldestPendingAge = age
+		}
+	}
+
+	if len(b.sinkLatencies) > 0 {
+		stats.SinkLatencies = make(map[string]time.Duration, len(b.sinkLatencies))
+		for sink, latency := range b.sinkLatencies {
+			stats.SinkLatencies[sink] = latency
+		}
+	}
+
+	stats.Status = types.StatusOK
+	switch {
+	case b.maxQueueDepth > 0 && stats.QueueDepth > b.maxQueueDepth:
+		stats.Status = types.StatusDegraded
+		stats.Message = fmt.Sprintf("writeout queue depth %d exceeds maximum of %d", stats.QueueDepth, b.maxQueueDepth)
+	case b.maxPendingAge > 0 && stats.OldestPendingAge > b.maxPendingAge:
+		stats.Status = types.StatusDegraded
+		stats.Message = fmt.Sprintf("oldest pending writeout age %s exceeds maximum of %s", stats.OldestPendingAge.Round(time.Second), b.maxPendingAge)
+	}
+
+	return stats
+}
+
+// check evaluates the backlog, updates the related metrics and returns the stats as well as
+// a flag indicating if the degradation state changed since the last check
+func (b *backlog) check(now time.Time) (stats capturetypes.WriteoutStats, changed bool) {
+	stats = b.stats(now)
+
+	writeoutQueueDepth.Set(float64(stats.QueueDepth))
+	writeoutOldestPendingAge.Set(float64(stats.OldestPendingAge) / float64(time.Second))
+
+	degraded := stats.IsDegraded()
+	if degraded {
+		writeoutDegraded.Set(1)
+	} else {
+		writeoutDegraded.Set(0)
+	}
+
+	b.Lock()
+	changed = degraded != b.degraded
+	b.degraded = degraded
+	b.Unlock()
+
+	return stats, changed
+}
+
+// WithBacklogLimits sets the bounds of the writeout backlog beyond which the writeout is
+// reported as degraded. A value of zero disables the respective bound
+func (h *GoDBHandler) WithBacklogLimits(maxQueueDepth int, maxPendingAge time.Duration) *GoDBHandler {
+	h.backlog.Lock()
+	h.backlog.maxQueueDepth = maxQueueDepth
+	h.backlog.maxPendingAge = maxPendingAge
+	h.backlog.Unlock()
+	return h
+}
+
+// WriteoutStats returns the current statistics of the writeout backlog
+func (h *GoDBHandler) WriteoutStats() capturetypes.WriteoutStats {
+	stats := h.backlog.stats(h.clock.Now())
+	stats.SyncPolicy = string(h.syncPolicy)
+	return stats
+}
+
+// MonitorBacklog periodically checks the writeout backlog against its bounds, updating the
+// related metrics and reporting any degradation (or recovery from it). Since a stalled writeout
+// does not trigger any further events, the check has to be performed independently of writeouts
+func (h *GoDBHandler) MonitorBacklog(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	ticker := h.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticker.C():
+			stats, changed := h.backlog.check(t)
+			if !changed {
+				continue
+			}
+
+			logger := logger.With(
+				"queue_depth", stats.QueueDepth,
+				"oldest_pending_age", stats.OldestPendingAge.Round(time.Second).String(),
+			)
+			if stats.IsDegraded() {
+				logger.Errorf("writeout degraded: %s", stats.Message)
+			} else {
+				logger.Info("writeout backlog recovered")
+			}
+		}
+	}
+}
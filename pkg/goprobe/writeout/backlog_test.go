@@ -0,0 +1,69 @@
+package writeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBacklogStats(t *testing.T) {
+	b := newBacklog()
+	b.maxQueueDepth = 2
+	b.maxPendingAge = time.Minute
+
+	now := time.Now()
+
+	stats, changed := b.check(now)
+	require.False(t, changed)
+	require.Equal(t, types.StatusOK, stats.Status)
+	require.Zero(t, stats.QueueDepth)
+	require.True(t, stats.OldestPending.IsZero())
+
+	// queue two flow maps for the first rotation, one of them being written
+	queue := make(chan capturetypes.TaggedAggFlowMap, 2)
+	queue <- capturetypes.TaggedAggFlowMap{Iface: "eth0"}
+	queue <- capturetypes.TaggedAggFlowMap{Iface: "eth1"}
+	first := b.add(now.Add(-30*time.Second), queue)
+	<-queue
+	b.startWrite(first)
+
+	stats, changed = b.check(now)
+	require.False(t, changed)
+	require.Equal(t, types.StatusOK, stats.Status)
+	require.Equal(t, 2, stats.QueueDepth)
+	require.Equal(t, 30*time.Second, stats.OldestPendingAge)
+
+	// a second rotation exceeds the maximum queue depth
+	second := b.add(now, make(chan capturetypes.TaggedAggFlowMap))
+	b.startWrite(second)
+
+	stats, changed = b.check(now)
+	require.True(t, changed)
+	require.Equal(t, types.StatusDegraded, stats.Status)
+	require.Equal(t, 3, stats.QueueDepth)
+	require.Equal(t, now.Add(-30*time.Second), stats.OldestPending)
+
+	// a stalled writeout exceeds the maximum pending age
+	b.endWrite(second)
+	b.done(second)
+
+	stats, changed = b.check(now.Add(time.Minute))
+	require.False(t, changed)
+	require.Equal(t, types.StatusDegraded, stats.Status)
+	require.Contains(t, stats.Message, "oldest pending writeout age")
+
+	// recovery once all writeouts have completed
+	<-queue
+	b.endWrite(first)
+	b.done(first)
+	b.observeSink(SinkGoDB, 25*time.Millisecond)
+
+	stats, changed = b.check(now.Add(time.Minute))
+	require.True(t, changed)
+	require.Equal(t, types.StatusOK, stats.Status)
+	require.Zero(t, stats.QueueDepth)
+	require.Equal(t, map[string]time.Duration{SinkGoDB: 25 * time.Millisecond}, stats.SinkLatencies)
+}
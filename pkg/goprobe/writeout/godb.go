@@ -2,6 +2,7 @@ package writeout
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"sync"
@@ -10,17 +11,43 @@ import (
 	"github.com/els0r/goProbe/pkg/capture/capturetypes"
 	"github.com/els0r/goProbe/pkg/goDB"
 	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+	"github.com/els0r/goProbe/pkg/goDB/storage"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/goprobe/clock"
+	"github.com/els0r/goProbe/pkg/query/push"
 	"github.com/els0r/telemetry/logging"
 )
 
 // GoDBHandler denotes a GoDB writeout handler
 type GoDBHandler struct {
-	encoderType encoders.Type
-	permissions fs.FileMode
+	encoderType   encoders.Type
+	ifaceEncoders map[string]migrate.Encoder
+	permissions   fs.FileMode
 
-	path        string
-	dbWriters   map[string]*goDB.DBWriter
-	logToSyslog bool
+	path         string
+	dbWriters    map[string]*goDB.DBWriter
+	logToSyslog  bool
+	handshakeRTT bool
+	fsys         storage.FS
+	syncPolicy   storage.SyncPolicy
+	clock        clock.Clock
+
+	backlog *backlog
+	spill   *spillBuffer
+
+	// coalesceMaxFlows denotes the maximum number of flows of an interface's rotated map for it to be
+	// written as part of the coalesced writeout of the rotation (zero disables write coalescing)
+	coalesceMaxFlows int
+
+	statsHandler RotationStatsHandler
+
+	quotas      *quotas
+	alertTarget *push.Target
+
+	spool     *spool
+	stream    *stream
+	hostnames *hostnameLabeler
 
 	sync.Mutex
 }
@@ -32,6 +59,11 @@ func NewGoDBHandler(path string, encoderType encoders.Type) *GoDBHandler {
 		dbWriters:   make(map[string]*goDB.DBWriter),
 		encoderType: encoderType,
 		permissions: goDB.DefaultPermissions,
+		fsys:        storage.DefaultFS,
+		syncPolicy:  storage.SyncOSDefault,
+		clock:       clock.Real,
+		backlog:     newBacklog(),
+		spill:       newSpillBuffer(0),
 	}
 }
 
@@ -47,10 +79,61 @@ func (h *GoDBHandler) WithPermissions(permissions fs.FileMode) *GoDBHandler {
 	return h
 }
 
+// WithHandshakeRTT enables / disables storing TCP handshake round trip time summaries in the GoDB
+func (h *GoDBHandler) WithHandshakeRTT(b bool) *GoDBHandler {
+	h.handshakeRTT = b
+	return h
+}
+
+// WithFS sets a non-default file system (e.g. an in-memory one) for the underlying GoDB
+func (h *GoDBHandler) WithFS(fsys storage.FS) *GoDBHandler {
+	h.fsys = fsys
+	return h
+}
+
+// WithSyncPolicy sets the policy governing when written data is committed to stable storage (c.f.
+// storage.SyncPolicy). Under storage.SyncPerRotation, a single sync barrier is performed once all
+// interfaces of a rotation have been written
+func (h *GoDBHandler) WithSyncPolicy(policy storage.SyncPolicy) *GoDBHandler {
+	h.syncPolicy = policy
+	return h
+}
+
+// WithClock sets the time source used to assess the age of pending writeouts (defaults to the
+// system time), which should match the one driving the writeouts
+func (h *GoDBHandler) WithClock(c clock.Clock) *GoDBHandler {
+	h.clock = c
+	return h
+}
+
+// WithSpillBuffer retains the flow maps of up to size failed writeouts per interface in memory, allowing
+// to backfill them later on (c.f. Backfill()). A size of zero disables the spill buffer
+func (h *GoDBHandler) WithSpillBuffer(size int) *GoDBHandler {
+	h.spill = newSpillBuffer(size)
+	return h
+}
+
+// WithWriteCoalescing batches the rotated maps of all interfaces with at most maxFlows flows into a
+// combined writeout transaction at the end of each rotation, sharing encoder buffers and committing
+// them to stable storage with a single sync barrier (instead of per-interface file churn). This reduces
+// the IOPS on hosts capturing on many mostly idle interfaces (e.g. SD-card based edge devices). A value
+// of zero disables write coalescing
+func (h *GoDBHandler) WithWriteCoalescing(maxFlows int) *GoDBHandler {
+	h.coalesceMaxFlows = maxFlows
+	return h
+}
+
+// WithRotationStatsHandler sets a handler that is notified about the statistics of each writeout
+func (h *GoDBHandler) WithRotationStatsHandler(handler RotationStatsHandler) *GoDBHandler {
+	h.statsHandler = handler
+	return h
+}
+
 // HandleWriteout provides access to writeouts to a GoDB via a channel
 func (h *GoDBHandler) HandleWriteout(ctx context.Context, timestamp time.Time, writeoutChan <-chan capturetypes.TaggedAggFlowMap) <-chan struct{} {
 
 	doneChan := make(chan struct{})
+	pending := h.backlog.add(timestamp, writeoutChan)
 	go func() {
 
 		logger := logging.FromContext(ctx)
@@ -67,10 +150,32 @@ func (h *GoDBHandler) HandleWriteout(ctx context.Context, timestamp time.Time, w
 		}
 
 		seenIfaces := make(map[string]struct{})
+		rotated := make(map[string]capturetypes.RotatedIface)
+		var coalesced []capturetypes.TaggedAggFlowMap
 		for taggedMap := range writeoutChan {
 			seenIfaces[taggedMap.Iface] = struct{}{}
-			h.handleIfaceWriteout(ctx, timestamp, taggedMap, syslogWriter)
+			rotated[taggedMap.Iface] = rotatedIface(taggedMap)
+			h.backlog.startWrite(pending)
+
+			// low-traffic interfaces are retained (and accounted for as in flight) until the end
+			// of the rotation and written as part of a single transaction
+			if h.coalesce(taggedMap) {
+				coalesced = append(coalesced, taggedMap)
+				continue
+			}
+			h.handleIfaceWriteout(ctx, timestamp, taggedMap, syslogWriter, nil)
+			h.backlog.endWrite(pending)
+		}
+		if len(coalesced) > 0 {
+			h.handleCoalescedWriteout(ctx, timestamp, coalesced, syslogWriter)
+			for range coalesced {
+				h.backlog.endWrite(pending)
+			}
+		}
+		if h.syncPolicy == storage.SyncPerRotation && len(seenIfaces) > 0 {
+			h.syncRotation(ctx)
 		}
+		h.backlog.done(pending)
 
 		// Clean up dead writers. We say that a writer is dead
 		// if it hasn't been used in the last few writeouts.
@@ -81,37 +186,118 @@ func (h *GoDBHandler) HandleWriteout(ctx context.Context, timestamp time.Time, w
 			}
 		}
 		h.Unlock()
+		h.quotas.prune(seenIfaces)
 
 		elapsed := time.Since(t0)
 		writeoutDuration.Observe(float64(elapsed) / float64(time.Second))
 
 		logger.With("elapsed", elapsed.Round(time.Millisecond).String()).Debug("completed writeout")
 		doneChan <- struct{}{}
+
+		// Notify the stats handler only after signaling completion to avoid delaying the next rotation
+		if h.statsHandler != nil {
+			h.statsHandler.HandleRotationStats(ctx, capturetypes.RotationStats{
+				Timestamp:        timestamp,
+				Ifaces:           rotated,
+				WriteoutDuration: elapsed,
+				Writeout:         h.WriteoutStats(),
+			})
+		}
 	}()
 
 	return doneChan
 }
 
-func (h *GoDBHandler) handleIfaceWriteout(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap, syslogWriter *goDB.SyslogDBWriter) {
+func rotatedIface(taggedMap capturetypes.TaggedAggFlowMap) capturetypes.RotatedIface {
+	res := capturetypes.RotatedIface{Stats: taggedMap.Stats}
+	if taggedMap.Map != nil {
+		res.NumFlows = taggedMap.Map.Len()
+	}
+	return res
+}
+
+// coalesce determines if the rotated map of an interface is written as part of the coalesced writeout
+// (which shares a single encoder, hence interfaces with a dedicated encoder are written individually)
+func (h *GoDBHandler) coalesce(taggedMap capturetypes.TaggedAggFlowMap) bool {
+	if _, hasEncoder := h.ifaceEncoders[taggedMap.Iface]; hasEncoder {
+		return false
+	}
+	return h.coalesceMaxFlows > 0 && taggedMap.Map != nil && taggedMap.Map.Len() <= h.coalesceMaxFlows
+}
+
+// handleCoalescedWriteout writes the rotated maps of several interfaces in a single transaction (c.f.
+// goDB.WriteBatch). If the transaction cannot be set up, the interfaces are written individually
+func (h *GoDBHandler) handleCoalescedWriteout(ctx context.Context, timestamp time.Time, taggedMaps []capturetypes.TaggedAggFlowMap, syslogWriter *goDB.SyslogDBWriter) {
+	logger := logging.FromContext(ctx).With("ifaces", len(taggedMaps))
+
+	batch, err := goDB.NewWriteBatch(h.path, h.encoderType, 0)
+	if err != nil {
+		logger.Errorf("failed to set up coalesced writeout, writing interfaces individually: %s", err)
+	} else {
+		batch = batch.FS(h.fsys).SyncPolicy(h.syncPolicy)
+	}
+	for _, taggedMap := range taggedMaps {
+		h.handleIfaceWriteout(ctx, timestamp, taggedMap, syslogWriter, batch)
+	}
+	if batch == nil {
+		return
+	}
+
+	t0 := time.Now()
+	if err := batch.Commit(); err != nil {
+		logger.Errorf("failed to commit coalesced writeout: %s", err)
+		return
+	}
+	coalescedWriteouts.Add(float64(batch.Len()))
+	logger.With("elapsed", time.Since(t0).Round(time.Millisecond).String()).Debug("committed coalesced writeout")
+}
+
+// handleIfaceWriteout writes the rotated map of an interface to all sinks. If batch is non-nil, the
+// map is written to the GoDB as part of the batch (which has to be committed by the caller)
+func (h *GoDBHandler) handleIfaceWriteout(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap, syslogWriter *goDB.SyslogDBWriter, batch *goDB.WriteBatch) {
 	ctx = logging.WithFields(ctx, slog.String("iface", taggedMap.Iface))
 	logger := logging.FromContext(ctx)
 
+	// Enforce the disk usage quota of the interface (if any) prior to acquiring the lock, since
+	// removing directories exceeding the quota locks the handler on its own
+	taggedMap, write := h.enforceQuota(ctx, timestamp, taggedMap)
+	if !write {
+		h.writeSyslog(ctx, timestamp, taggedMap, syslogWriter)
+		h.writeSpool(ctx, timestamp, taggedMap)
+		h.writeStream(timestamp, taggedMap)
+		return
+	}
+
 	// Ensure that there is a DBWriter for the given interface
 	h.Lock()
 	if _, exists := h.dbWriters[taggedMap.Iface]; !exists {
-		w := goDB.NewDBWriter(h.path,
-			taggedMap.Iface,
-			h.encoderType,
-		).Permissions(h.permissions)
-		h.dbWriters[taggedMap.Iface] = w
+		h.dbWriters[taggedMap.Iface] = h.newDBWriter(taggedMap.Iface)
 	}
 
-	// Write to database, update summary
-	err := h.dbWriters[taggedMap.Iface].Write(taggedMap.Map, taggedMap.Stats, timestamp.Unix())
+	// Write to database, update summary. If the writeout fails, the flows are retained
+	// in the spill buffer (if enabled) to allow for backfilling them later on
+	t0 := time.Now()
+	var err error
+	if batch != nil {
+		err = h.dbWriters[taggedMap.Iface].WriteBatched(batch, taggedMap.Map, taggedMap.Stats, timestamp.Unix())
+	} else {
+		err = h.dbWriters[taggedMap.Iface].Write(taggedMap.Map, taggedMap.Stats, timestamp.Unix())
+	}
 	if err != nil {
 		logger.Errorf("failed to perform writeout: %s", err)
+		h.spill.add(timestamp, taggedMap)
 	}
 	h.Unlock()
+	h.backlog.observeSink(SinkGoDB, time.Since(t0))
+
+	h.writeSyslog(ctx, timestamp, taggedMap, syslogWriter)
+	h.writeSpool(ctx, timestamp, taggedMap)
+	h.writeStream(timestamp, taggedMap)
+}
+
+// writeSyslog writes the rotated map of an interface to syslog (if enabled)
+func (h *GoDBHandler) writeSyslog(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap, syslogWriter *goDB.SyslogDBWriter) {
+	logger := logging.FromContext(ctx)
 
 	// write out flows to syslog if necessary
 	if h.logToSyslog {
@@ -119,12 +305,90 @@ func (h *GoDBHandler) handleIfaceWriteout(ctx context.Context, timestamp time.Ti
 			logger.Error("cannot write flows to <nil> syslog writer. Attempting reinitialization")
 
 			// try to reinitialize the writer
+			var err error
 			if syslogWriter, err = goDB.NewSyslogDBWriter(); err != nil {
 				logger.Errorf("failed to reinitialize syslog writer: %v", err)
 				return
 			}
 		}
 
+		t0 := time.Now()
 		syslogWriter.Write(taggedMap.Map, taggedMap.Iface, timestamp.Unix())
+		h.backlog.observeSink(SinkSyslog, time.Since(t0))
+	}
+}
+
+// Backfill re-writes all spilled writeouts of an interface with a timestamp in [from, to]. Each
+// writeout is removed from the spill buffer once it has been written successfully
+func (h *GoDBHandler) Backfill(ctx context.Context, iface string, from, to time.Time) ([]capturetypes.BackfillResult, error) {
+	if from.After(to) {
+		return nil, ErrInvalidInterval
+	}
+
+	spilled := h.spill.get(iface, from, to)
+	if len(spilled) == 0 {
+		return nil, ErrNoSpilledWriteouts
+	}
+
+	results := make([]capturetypes.BackfillResult, 0, len(spilled))
+	for _, entry := range spilled {
+		res, err := h.BackfillFlows(ctx, entry.timestamp, entry.taggedMap, gpfile.BackfillSourceSpill)
+		if err != nil {
+			return results, err
+		}
+		h.spill.remove(iface, entry.timestamp)
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// BackfillFlows writes the provided flows of an interface for a timestamp, replacing any block
+// already present for the same timestamp. Provenance information is recorded in the GoDB metadata
+func (h *GoDBHandler) BackfillFlows(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap, source gpfile.BackfillSource) (capturetypes.BackfillResult, error) {
+	logger := logging.FromContext(ctx).With("iface", taggedMap.Iface, "timestamp", timestamp.Unix(), "source", source.String())
+
+	// Serialize with regular writeouts, which may access the same directory
+	h.Lock()
+	defer h.Unlock()
+
+	replaced, err := h.newDBWriter(taggedMap.Iface).Backfill(taggedMap.Map, taggedMap.Stats, timestamp.Unix(), source)
+	if err != nil {
+		return capturetypes.BackfillResult{}, fmt.Errorf("failed to backfill %s at %s: %w", taggedMap.Iface, timestamp.Format(time.RFC3339), err)
+	}
+	if h.syncPolicy == storage.SyncPerRotation {
+		if err := storage.SyncFS(h.fsys, h.path); err != nil {
+			return capturetypes.BackfillResult{}, fmt.Errorf("failed to sync backfill of %s at %s: %w", taggedMap.Iface, timestamp.Format(time.RFC3339), err)
+		}
+	}
+	logger.With("replaced", replaced).Info("backfilled flows")
+
+	return capturetypes.BackfillResult{
+		Timestamp: timestamp,
+		Source:    source.String(),
+		Replaced:  replaced,
+		NumFlows:  taggedMap.Map.Len(),
+	}, nil
+}
+
+func (h *GoDBHandler) newDBWriter(iface string) *goDB.DBWriter {
+	enc := h.encoderOf(iface)
+	w := goDB.NewDBWriter(h.path,
+		iface,
+		enc.Type,
+	).EncoderLevel(enc.Level).Permissions(h.permissions).HandshakeRTT(h.handshakeRTT).FS(h.fsys).SyncPolicy(h.syncPolicy)
+	if h.hostnames != nil {
+		w = w.Hostnames(h.hostnames)
+	}
+	return w
+}
+
+// syncRotation commits all data written during a rotation to stable storage by a single sync barrier
+func (h *GoDBHandler) syncRotation(ctx context.Context) {
+	t0 := time.Now()
+	if err := storage.SyncFS(h.fsys, h.path); err != nil {
+		logging.FromContext(ctx).Errorf("failed to sync writeout: %s", err)
+		return
 	}
+	logging.FromContext(ctx).With("elapsed", time.Since(t0).Round(time.Millisecond).String()).Debug("synced writeout")
 }
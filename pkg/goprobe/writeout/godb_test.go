@@ -0,0 +1,51 @@
+package writeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCoalescing(t *testing.T) {
+	fsys := godbtest.NewMemFS()
+	h := NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(fsys).WithWriteCoalescing(2)
+
+	// eth0 / eth1 are within the coalescing threshold, eth2 exceeds it
+	busy := testTaggedMap("eth2")
+	busy.Map.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, 3}, [4]byte{10, 0, 0, 4}, []byte{0, 53}, 17),
+		types.Counters{BytesRcvd: 100, PacketsRcvd: 1})
+	require.False(t, h.coalesce(busy))
+	require.True(t, h.coalesce(testTaggedMap("eth0")))
+
+	timestamp := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		writeoutChan := make(chan capturetypes.TaggedAggFlowMap, 3)
+		writeoutChan <- testTaggedMap("eth0")
+		writeoutChan <- busy
+		writeoutChan <- testTaggedMap("eth1")
+		close(writeoutChan)
+
+		<-h.HandleWriteout(context.Background(), timestamp.Add(time.Duration(i)*5*time.Minute), writeoutChan)
+	}
+
+	// nothing must be pending after the rotations have completed
+	require.Zero(t, h.backlog.stats(time.Now()).QueueDepth)
+
+	for iface, nFlows := range map[string]uint64{"eth0": 2, "eth1": 2, "eth2": 3} {
+		dir := gpfile.NewDir("/godb/"+iface, timestamp.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+		require.Nil(t, dir.Open())
+		require.Equal(t, 2, dir.NBlocks(), iface)
+		require.Equal(t, 2*nFlows, dir.Metadata.Traffic.NumFlows(), iface)
+		require.Nil(t, dir.Close())
+	}
+
+	// a disabled write coalescing doesn't coalesce anything
+	require.False(t, NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).coalesce(testTaggedMap("eth0")))
+}
@@ -16,3 +16,11 @@ type Handler interface {
 	// HandleWriteout provides access to writeouts via a channel
 	HandleWriteout(ctx context.Context, timestamp time.Time, writeoutChan <-chan capturetypes.TaggedAggFlowMap) <-chan struct{}
 }
+
+// RotationStatsHandler is notified about the statistics of each completed writeout (e.g. in order
+// to forward them to an external monitoring system)
+type RotationStatsHandler interface {
+
+	// HandleRotationStats is called once all interfaces of a rotation have been written out
+	HandleRotationStats(ctx context.Context, stats capturetypes.RotationStats)
+}
@@ -0,0 +1,205 @@
+package writeout
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+)
+
+// HostnameResolver resolves the hostnames of IP addresses via reverse lookups (c.f. net.Resolver)
+type HostnameResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// hostnameLabeler provides the hostnames of the internal IP addresses observed in a flow map from a
+// bounded cache (c.f. goDB.HostnameLabeler). Addresses missing from the cache (or whose hostname has
+// expired) are resolved asynchronously, hence their hostnames are provided from a subsequent writeout on
+type hostnameLabeler struct {
+	resolver  HostnameResolver
+	networks  []netip.Prefix
+	cacheSize int
+	ttl       time.Duration
+	timeout   time.Duration
+	pending   chan struct{}
+	now       func() time.Time
+
+	cache    map[netip.Addr]hostnameEntry
+	inflight map[netip.Addr]struct{}
+	sync.Mutex
+}
+
+type hostnameEntry struct {
+	name    string // empty if the address has no hostname
+	expires time.Time
+}
+
+func newHostnameLabeler(cfg config.HostnamesConfig, resolver HostnameResolver) *hostnameLabeler {
+	if cfg.CacheSize == 0 {
+		cfg.CacheSize = config.DefaultHostnamesCacheSize
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = config.DefaultHostnamesTTL
+	}
+	if cfg.MaxPending == 0 {
+		cfg.MaxPending = config.DefaultHostnamesMaxPending
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = config.DefaultHostnamesTimeout
+	}
+
+	// the networks have been validated as part of the configuration
+	networks := make([]netip.Prefix, 0, len(cfg.Networks))
+	for _, network := range cfg.Networks {
+		if prefix, err := netip.ParsePrefix(network); err == nil {
+			networks = append(networks, prefix.Masked())
+		}
+	}
+
+	return &hostnameLabeler{
+		resolver:  resolver,
+		networks:  networks,
+		cacheSize: cfg.CacheSize,
+		ttl:       cfg.TTL,
+		timeout:   cfg.Timeout,
+		pending:   make(chan struct{}, cfg.MaxPending),
+		now:       time.Now,
+		cache:     make(map[netip.Addr]hostnameEntry),
+		inflight:  make(map[netip.Addr]struct{}),
+	}
+}
+
+// WithHostnames labels the internal IP addresses observed by all interfaces with their hostnames (as
+// resolved via reverse DNS lookups) at writeout, storing them alongside the flows. A nil configuration
+// disables the labeling
+func (h *GoDBHandler) WithHostnames(cfg *config.HostnamesConfig) *GoDBHandler {
+	h.hostnames = nil
+	if cfg != nil {
+		h.hostnames = newHostnameLabeler(*cfg, net.DefaultResolver)
+	}
+	return h
+}
+
+// Hostnames returns the cached hostnames of the internal IP addresses observed in the flow map and
+// triggers the resolution of the ones missing from the cache (or expired). Expired hostnames are
+// still provided until they have been resolved again
+func (l *hostnameLabeler) Hostnames(flowmap *hashmap.AggFlowMap) map[netip.Addr]string {
+	hostnames := make(map[netip.Addr]string)
+	if flowmap == nil {
+		return hostnames
+	}
+
+	now := l.now()
+	seen := make(map[netip.Addr]struct{})
+
+	l.Lock()
+	defer l.Unlock()
+
+	for it := flowmap.Iter(); it.Next(); {
+		key := types.Key(it.Key())
+		for _, ip := range [2]netip.Addr{types.RawIPToAddr(key.GetSIP()), types.RawIPToAddr(key.GetDIP())} {
+			if _, exists := seen[ip]; exists {
+				continue
+			}
+			seen[ip] = struct{}{}
+			if !l.isInternal(ip) {
+				continue
+			}
+
+			entry, exists := l.cache[ip]
+			if exists && entry.name != "" {
+				hostnames[ip] = entry.name
+			}
+			if !exists || now.After(entry.expires) {
+				l.resolve(ip)
+			}
+		}
+	}
+
+	return hostnames
+}
+
+// isInternal determines if the hostname of an IP address is to be resolved
+func (l *hostnameLabeler) isInternal(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	if len(l.networks) == 0 {
+		return ip.IsPrivate()
+	}
+	for _, network := range l.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve looks up the hostname of an IP address in the background (must be called with the lock
+// held). If the maximum number of concurrent lookups is reached, the lookup is skipped
+func (l *hostnameLabeler) resolve(ip netip.Addr) {
+	if _, exists := l.inflight[ip]; exists {
+		return
+	}
+	select {
+	case l.pending <- struct{}{}:
+	default:
+		return
+	}
+	l.inflight[ip] = struct{}{}
+
+	go func() {
+		defer func() {
+			<-l.pending
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		defer cancel()
+
+		names, err := l.resolver.LookupAddr(ctx, ip.String())
+		l.store(ip, names, err)
+	}()
+}
+
+// store caches the outcome of the lookup of an IP address. Unless the address is known not to have a
+// hostname, a failed lookup retains the previous hostname (if any)
+func (l *hostnameLabeler) store(ip netip.Addr, names []string, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.inflight, ip)
+
+	entry, exists := l.cache[ip]
+	entry.expires = l.now().Add(l.ttl)
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(names) > 0:
+		entry.name = strings.TrimSuffix(names[0], ".")
+	case err == nil, errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		entry.name = ""
+	}
+
+	// evict expired entries first, then arbitrary ones, if the cache is full
+	if !exists && len(l.cache) >= l.cacheSize {
+		now := l.now()
+		for cachedIP, cached := range l.cache {
+			if now.After(cached.expires) {
+				delete(l.cache, cachedIP)
+			}
+		}
+		for cachedIP := range l.cache {
+			if len(l.cache) < l.cacheSize {
+				break
+			}
+			delete(l.cache, cachedIP)
+		}
+	}
+	l.cache[ip] = entry
+}
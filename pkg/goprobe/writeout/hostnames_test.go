@@ -0,0 +1,98 @@
+package writeout
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/stretchr/testify/require"
+)
+
+type testResolver struct {
+	hostnames map[string]string
+	lookups   []string
+	sync.Mutex
+}
+
+func (r *testResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lookups = append(r.lookups, addr)
+	if name, exists := r.hostnames[addr]; exists {
+		return []string{name + "."}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func (r *testResolver) set(addr, name string) {
+	r.Lock()
+	defer r.Unlock()
+	r.hostnames[addr] = name
+}
+
+func (l *hostnameLabeler) idle() bool {
+	l.Lock()
+	defer l.Unlock()
+	return len(l.inflight) == 0
+}
+
+func TestHostnameLabeler(t *testing.T) {
+	var (
+		host1 = netip.MustParseAddr("10.0.0.1")
+		now   = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	resolver := &testResolver{hostnames: map[string]string{"10.0.0.1": "db.example.com"}}
+	labeler := newHostnameLabeler(config.HostnamesConfig{}, resolver)
+	labeler.now = func() time.Time { return now }
+
+	// hostnames are resolved in the background, hence they are only provided from the next writeout on.
+	// Only the private addresses are resolved, addresses without a hostname are cached as well
+	require.Empty(t, labeler.Hostnames(testTaggedMap("eth0").Map))
+	require.Eventually(t, labeler.idle, time.Second, time.Millisecond)
+	require.Equal(t, map[netip.Addr]string{host1: "db.example.com"}, labeler.Hostnames(testTaggedMap("eth0").Map))
+	require.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2"}, resolver.lookups)
+
+	// expired hostnames are provided until they have been resolved again
+	resolver.set("10.0.0.1", "db-new.example.com")
+	labeler.Lock()
+	now = now.Add(config.DefaultHostnamesTTL + time.Second)
+	labeler.Unlock()
+	require.Equal(t, map[netip.Addr]string{host1: "db.example.com"}, labeler.Hostnames(testTaggedMap("eth0").Map))
+	require.Eventually(t, labeler.idle, time.Second, time.Millisecond)
+	require.Equal(t, map[netip.Addr]string{host1: "db-new.example.com"}, labeler.Hostnames(testTaggedMap("eth0").Map))
+	require.Len(t, resolver.lookups, 4)
+
+	// the hostnames are stored alongside the flows
+	fsys := godbtest.NewMemFS()
+	h := NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(fsys)
+	h.hostnames = labeler
+	h.handleIfaceWriteout(context.Background(), now, testTaggedMap("eth0"), nil, nil)
+
+	dir := gpfile.NewDir("/godb/eth0", now.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+	require.Nil(t, dir.Open())
+	require.Equal(t, map[netip.Addr]gpfile.Hostname{
+		host1: {Timestamp: now.Unix(), Name: "db-new.example.com"},
+	}, dir.HostnamesAt(now.Unix()))
+	require.Nil(t, dir.Close())
+}
+
+func TestHostnameLabelerNetworks(t *testing.T) {
+	labeler := newHostnameLabeler(config.HostnamesConfig{Networks: []string{"10.0.0.2/32", "2001::/16"}}, &testResolver{})
+	for ip, expected := range map[string]bool{
+		"10.0.0.1":    false,
+		"10.0.0.2":    true,
+		"2001::1":     true,
+		"192.168.0.1": false,
+	} {
+		require.Equal(t, expected, labeler.isInternal(netip.MustParseAddr(ip)), ip)
+	}
+}
@@ -18,8 +18,103 @@ var writeoutDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
 	Buckets: []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 5, 10, 30, 60},
 })
 
+var sinkLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "sink_latency_seconds",
+	Help:      "Time to write the flow data of an individual interface to a writeout sink",
+	Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5},
+},
+	[]string{"sink"},
+)
+
+var writeoutQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "queue_depth",
+	Help:      "Number of rotated flow maps pending writeout",
+})
+
+var writeoutOldestPendingAge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "oldest_pending_age_seconds",
+	Help:      "Age of the oldest rotation pending writeout",
+})
+
+var writeoutDegraded = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "degraded",
+	Help:      "Indicates if the writeout backlog exceeds its configured bounds (1) or not (0)",
+})
+
+var spilledWriteouts = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "spilled_writeouts",
+	Help:      "Number of failed writeouts retained in the spill buffer for backfilling",
+})
+
+var coalescedWriteouts = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "coalesced_writeouts_total",
+	Help:      "Number of interface writeouts performed as part of a coalesced writeout transaction",
+})
+
+var quotaUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "quota_utilization_ratio",
+	Help:      "Fraction of the disk usage quota of an interface in use as of its most recent writeout",
+},
+	[]string{"iface"},
+)
+
+var quotaActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "quota_actions_total",
+	Help:      "Number of writeouts of an interface exceeding its disk usage quota, by the policy applied",
+},
+	[]string{"iface", "policy"},
+)
+
+var streamQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "stream_queue_depth",
+	Help:      "Number of batches of rotated flows pending transmission via the flow stream",
+})
+
+var streamDroppedBatches = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "stream_dropped_batches_total",
+	Help:      "Number of batches of rotated flows discarded since the queue of the flow stream was full",
+})
+
+var streamConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: config.ServiceName,
+	Subsystem: writeoutSubsystem,
+	Name:      "stream_connected",
+	Help:      "Indicates if the flow stream to the external consumer is established (1) or not (0)",
+})
+
 func init() {
 	prometheus.MustRegister(
 		writeoutDuration,
+		sinkLatency,
+		writeoutQueueDepth,
+		writeoutOldestPendingAge,
+		writeoutDegraded,
+		spilledWriteouts,
+		coalescedWriteouts,
+		quotaUtilization,
+		quotaActions,
+		streamQueueDepth,
+		streamDroppedBatches,
+		streamConnected,
 	)
 }
@@ -0,0 +1,44 @@
+package writeout
+
+import (
+	"context"
+
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+)
+
+// Migrator is implemented by writeout handlers that support re-encoding the data in their storage
+type Migrator interface {
+
+	// Migrate re-encodes the data of all (or a set of) interfaces with the encoder currently
+	// configured for the respective interface
+	Migrate(ctx context.Context, ifaces []string, opts ...migrate.Option) (migrate.Results, error)
+}
+
+// WithIfaceEncoders sets the encoders of individual interfaces, overriding the default encoder of the
+// handler (e.g. to write high-volume interfaces with a fast encoder and archival ones with a strong
+// compression)
+func (h *GoDBHandler) WithIfaceEncoders(ifaceEncoders map[string]migrate.Encoder) *GoDBHandler {
+	h.ifaceEncoders = ifaceEncoders
+	return h
+}
+
+// encoderOf returns the encoder the flows of an interface are written with
+func (h *GoDBHandler) encoderOf(iface string) migrate.Encoder {
+	if enc, exists := h.ifaceEncoders[iface]; exists {
+		return enc
+	}
+	return migrate.Encoder{Type: h.encoderType}
+}
+
+// Migrate re-encodes the data of all (or a set of) interfaces of the GoDB with the encoder currently
+// configured for the respective interface. Each directory is processed while holding the lock of the
+// handler, i.e. the migration is serialized with regular writeouts
+func (h *GoDBHandler) Migrate(ctx context.Context, ifaces []string, opts ...migrate.Option) (migrate.Results, error) {
+	opts = append([]migrate.Option{
+		migrate.WithFS(h.fsys),
+		migrate.WithPermissions(h.permissions),
+		migrate.WithLocker(h),
+	}, opts...)
+
+	return migrate.New(h.path, opts...).Run(ctx, h.encoderOf, ifaces...)
+}
@@ -0,0 +1,209 @@
+package writeout
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/els0r/telemetry/logging"
+)
+
+// QuotaStatsProvider is implemented by writeout handlers that enforce disk usage quotas
+type QuotaStatsProvider interface {
+
+	// QuotaStats returns the disk usage quota statistics of all (or a set of) interfaces
+	// with a quota
+	QuotaStats(ifaces ...string) map[string]capturetypes.QuotaStats
+}
+
+// quotas tracks the disk usage quota state of all interfaces written to the GoDB
+type quotas struct {
+	cfg *config.QuotaConfig
+
+	stats map[string]capturetypes.QuotaStats
+	sync.Mutex
+}
+
+func newQuotas(cfg *config.QuotaConfig) *quotas {
+	return &quotas{
+		cfg:   cfg,
+		stats: make(map[string]capturetypes.QuotaStats),
+	}
+}
+
+// WithQuotas enforces disk usage quotas on the interfaces written to the GoDB. The disk usage of an
+// interface is determined prior to each of its writeouts and the configured policy is applied if it
+// exceeds its quota. A nil configuration disables quotas
+func (h *GoDBHandler) WithQuotas(cfg *config.QuotaConfig) *GoDBHandler {
+	h.quotas = nil
+	if cfg != nil {
+		h.quotas = newQuotas(cfg)
+	}
+	return h
+}
+
+// WithAlertTarget sets the target alerts (e.g. exceeded disk usage quotas) are delivered to
+func (h *GoDBHandler) WithAlertTarget(target *push.Target) *GoDBHandler {
+	h.alertTarget = target
+	return h
+}
+
+// QuotaStats returns the disk usage quota statistics of all (or a set of) interfaces with a quota
+func (h *GoDBHandler) QuotaStats(ifaces ...string) map[string]capturetypes.QuotaStats {
+	if h.quotas == nil {
+		return nil
+	}
+
+	h.quotas.Lock()
+	defer h.quotas.Unlock()
+
+	res := make(map[string]capturetypes.QuotaStats)
+	for iface, stats := range h.quotas.stats {
+		if len(ifaces) > 0 && !slices.Contains(ifaces, iface) {
+			continue
+		}
+		res[iface] = stats
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// enforceQuota applies the quota policy to the rotated map of an interface prior to its writeout,
+// returning the (potentially downsampled) map and whether it should be written to the GoDB at all.
+// If the disk usage cannot be determined, the writeout is performed regardless
+func (h *GoDBHandler) enforceQuota(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) (capturetypes.TaggedAggFlowMap, bool) {
+	if h.quotas == nil || taggedMap.Map == nil {
+		return taggedMap, true
+	}
+	maxSize := h.quotas.cfg.MaxSizeOf(taggedMap.Iface)
+	if maxSize == 0 {
+		return taggedMap, true
+	}
+	policy := h.quotas.cfg.PolicyOrDefault()
+	logger := logging.FromContext(ctx).With("max_size", maxSize, "policy", policy)
+
+	v := vacuum.New(h.path,
+		vacuum.WithFS(h.fsys),
+		vacuum.WithPermissions(h.permissions),
+		vacuum.WithLocker(h),
+	)
+	used, err := v.Usage(taggedMap.Iface)
+	if err != nil {
+		logger.Errorf("failed to determine disk usage, not enforcing quota: %s", err)
+		return taggedMap, true
+	}
+
+	h.quotas.Lock()
+	stats := h.quotas.stats[taggedMap.Iface]
+	h.quotas.Unlock()
+
+	write := used <= maxSize || policy != config.QuotaPolicySkip
+	if used > maxSize {
+		logger = logger.With("used", used)
+		quotaActions.WithLabelValues(taggedMap.Iface, policy).Inc()
+
+		switch policy {
+		case config.QuotaPolicyDropOldest:
+			res, err := v.Trim(ctx, taggedMap.Iface, maxSize, gpfile.DirTimestamp(timestamp.Unix()))
+			if err != nil {
+				logger.Errorf("failed to remove oldest directories exceeding quota: %s", err)
+			}
+			used -= res.ReclaimedBytes
+			stats.RemovedDirs += res.RemovedDirs
+			if used > maxSize {
+				logger.Warn("disk usage quota still exceeded after removing all previous days")
+			}
+		case config.QuotaPolicySkip:
+			stats.SkippedWriteouts++
+			logger.Error("disk usage quota exceeded, skipping writeout")
+		case config.QuotaPolicyDownsample:
+			numFlows := taggedMap.Map.Len()
+			taggedMap.Map = downsample(taggedMap.Map)
+			stats.DownsampledWriteouts++
+			logger.With("flows", numFlows, "downsampled_flows", taggedMap.Map.Len()).Warn("disk usage quota exceeded, downsampling writeout")
+		}
+	}
+
+	stats.MaxSize = maxSize
+	stats.Policy = policy
+	stats.Used = used
+	stats.Utilization = float64(used) / float64(maxSize)
+	stats.Exceeded = used > maxSize
+	quotaUtilization.WithLabelValues(taggedMap.Iface).Set(stats.Utilization)
+
+	var alert *capturetypes.QuotaAlert
+	if stats.Exceeded && stats.ExceededSince.IsZero() {
+		stats.ExceededSince = timestamp
+		alert = &capturetypes.QuotaAlert{State: capturetypes.QuotaExceeded}
+	} else if !stats.Exceeded && !stats.ExceededSince.IsZero() {
+		stats.ExceededSince = time.Time{}
+		alert = &capturetypes.QuotaAlert{State: capturetypes.QuotaRecovered}
+		logger.Info("disk usage back within quota")
+	}
+
+	h.quotas.Lock()
+	h.quotas.stats[taggedMap.Iface] = stats
+	h.quotas.Unlock()
+
+	if alert != nil && h.alertTarget != nil {
+		alert.Iface = taggedMap.Iface
+		alert.Timestamp = timestamp
+		alert.Used = used
+		alert.MaxSize = maxSize
+		alert.Policy = policy
+
+		// deliver the alert in the background to avoid delaying the writeout of other interfaces
+		go func() {
+			pusher, err := h.alertTarget.Pusher()
+			if err == nil {
+				err = pusher.PushJSON(context.WithoutCancel(ctx), alert)
+			}
+			if err != nil {
+				logger.Errorf("failed to send disk usage quota %s alert: %v", alert.State, err)
+			}
+		}()
+	}
+
+	return taggedMap, write
+}
+
+// prune removes the quota state of all interfaces not part of the most recent writeout
+func (q *quotas) prune(seenIfaces map[string]struct{}) {
+	if q == nil {
+		return
+	}
+
+	q.Lock()
+	for iface := range q.stats {
+		if _, exists := seenIfaces[iface]; !exists {
+			delete(q.stats, iface)
+			quotaUtilization.DeleteLabelValues(iface)
+		}
+	}
+	q.Unlock()
+}
+
+// downsample aggregates the flows of a map across their destination ports, reducing the number of
+// flows (and hence the amount of data) written for a rotation
+func downsample(m *hashmap.AggFlowMap) *hashmap.AggFlowMap {
+	res := hashmap.NewAggFlowMap()
+	zeroPort := []byte{0, 0}
+	for it := m.Iter(); it.Next(); {
+		key := types.Key(it.Key()).Clone()
+		key.PutDport(zeroPort)
+
+		val := it.Val()
+		res.SetOrUpdate(key, key.IsIPv4(), val.BytesRcvd, val.BytesSent, val.PacketsRcvd, val.PacketsSent)
+	}
+	return res
+}
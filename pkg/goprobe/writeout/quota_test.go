@@ -0,0 +1,76 @@
+package writeout
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaPolicies(t *testing.T) {
+	day := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	nextDay := day.Add(24 * time.Hour)
+
+	t.Run(config.QuotaPolicySkip, func(t *testing.T) {
+		fsys := godbtest.NewMemFS()
+		h := NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(fsys).
+			WithQuotas(&config.QuotaConfig{MaxSize: 1, Policy: config.QuotaPolicySkip})
+
+		// the first writeout complies with the quota, all subsequent ones exceed it
+		for i := 0; i < 3; i++ {
+			h.handleIfaceWriteout(context.Background(), day.Add(time.Duration(i)*5*time.Minute), testTaggedMap("eth0"), nil, nil)
+		}
+
+		dir := gpfile.NewDir("/godb/eth0", day.Unix(), gpfile.ModeRead, gpfile.WithFS(fsys))
+		require.Nil(t, dir.Open())
+		require.Equal(t, 1, dir.NBlocks())
+		require.Nil(t, dir.Close())
+
+		stats := h.QuotaStats("eth0")["eth0"]
+		require.True(t, stats.Exceeded)
+		require.Equal(t, day.Add(5*time.Minute), stats.ExceededSince)
+		require.Equal(t, 2, stats.SkippedWriteouts)
+		require.Greater(t, stats.Used, stats.MaxSize)
+		require.Nil(t, h.QuotaStats("eth1"))
+	})
+
+	t.Run(config.QuotaPolicyDropOldest, func(t *testing.T) {
+		fsys := godbtest.NewMemFS()
+		h := NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(fsys).
+			WithQuotas(&config.QuotaConfig{Ifaces: map[string]int64{"eth0": 1}})
+
+		h.handleIfaceWriteout(context.Background(), day, testTaggedMap("eth0"), nil, nil)
+		h.handleIfaceWriteout(context.Background(), nextDay, testTaggedMap("eth0"), nil, nil)
+
+		// the previous day must have been removed, the current one is never removed
+		_, err := fsys.Stat(gpfile.GenPathForTimestamp("/godb/eth0", day.Unix()))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		_, err = fsys.Stat(gpfile.GenPathForTimestamp("/godb/eth0", nextDay.Unix()))
+		require.Nil(t, err)
+
+		stats := h.QuotaStats()["eth0"]
+		require.False(t, stats.Exceeded)
+		require.Equal(t, 1, stats.RemovedDirs)
+		require.Equal(t, config.QuotaPolicyDropOldest, stats.Policy)
+	})
+
+	t.Run(config.QuotaPolicyDownsample, func(t *testing.T) {
+		taggedMap := testTaggedMap("eth0")
+		taggedMap.Map.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, []byte{1, 187}, 6),
+			types.Counters{BytesRcvd: 100, PacketsRcvd: 1})
+
+		downsampled := downsample(taggedMap.Map)
+		require.Equal(t, 2, downsampled.Len())
+
+		val, exists := downsampled.PrimaryMap.Get(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, []byte{0, 0}, 6))
+		require.True(t, exists)
+		require.Equal(t, types.Counters{BytesRcvd: 300, BytesSent: 10, PacketsRcvd: 3, PacketsSent: 1}, val)
+	})
+}
@@ -0,0 +1,86 @@
+package writeout
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/migrate"
+	"github.com/els0r/telemetry/logging"
+)
+
+// RunRecompression periodically re-encodes the historical data of all interfaces of the GoDB with the
+// configured encoder (see config.RecompressConfig). Each directory is only processed while there are no
+// writeouts pending and while holding the lock of the handler, i.e. the re-compression is serialized with
+// regular writeouts. The progress is persisted in the GoDB, hence it continues where it left off across
+// restarts
+func (h *GoDBHandler) RunRecompression(ctx context.Context, cfg config.RecompressConfig) {
+	logger := logging.FromContext(ctx)
+
+	encoderType, err := encoders.GetTypeByString(cfg.Encoder.Type)
+	if err != nil {
+		logger.Errorf("failed to start background re-compression: %v", err)
+		return
+	}
+	enc := migrate.Encoder{Type: encoderType, Level: cfg.Encoder.Level}
+
+	minAge, interval, pause := cfg.MinAge, cfg.Interval, cfg.Pause
+	if minAge == 0 {
+		minAge = config.DefaultRecompressMinAge
+	}
+	if interval == 0 {
+		interval = config.DefaultRecompressInterval
+	}
+	if pause == 0 {
+		pause = config.DefaultRecompressPause
+	}
+
+	logger = logger.With("encoder", encoderType.String(), "level", enc.Level, "min_age", minAge.String())
+	logger.With("interval", interval.String()).Info("starting background re-compression")
+
+	m := migrate.New(h.path,
+		migrate.WithFS(h.fsys),
+		migrate.WithPermissions(h.permissions),
+		migrate.WithLocker(h),
+		migrate.WithMinAge(minAge),
+		migrate.WithPause(pause),
+		migrate.WithIdle(h.isIdle),
+		migrate.WithProgress(filepath.Join(h.path, migrate.ProgressFileName)),
+	)
+	encoderOf := func(string) migrate.Encoder {
+		return enc
+	}
+
+	ticker := h.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			res, err := m.Run(ctx, encoderOf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Errorf("background re-compression failed: %v", err)
+				continue
+			}
+			if n := res.MigratedDirs(); n > 0 {
+				var before, after int64
+				for _, ifaceRes := range res {
+					before += ifaceRes.BytesBefore
+					after += ifaceRes.BytesAfter
+				}
+				logger.With("dirs", n, "bytes_before", before, "bytes_after", after).Info("re-compressed historical data")
+			}
+		}
+	}
+}
+
+// isIdle determines if there are no writeouts pending
+func (h *GoDBHandler) isIdle() bool {
+	return h.backlog.stats(h.clock.Now()).QueueDepth == 0
+}
@@ -0,0 +1,126 @@
+package writeout
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+)
+
+var (
+	// ErrNoSpilledWriteouts denotes that there are no spilled writeouts for an interface in
+	// the requested interval
+	ErrNoSpilledWriteouts = errors.New("no spilled writeouts found for interval")
+
+	// ErrInvalidInterval denotes that the lower bound of an interval exceeds its upper bound
+	ErrInvalidInterval = errors.New("invalid interval: start must not be after end")
+)
+
+// Backfiller is implemented by writeout handlers that support re-writing / backfilling intervals
+// that could not be written out during regular operation (e.g. due to a full disk)
+type Backfiller interface {
+
+	// Backfill re-writes all spilled writeouts of an interface with a timestamp in [from, to]
+	Backfill(ctx context.Context, iface string, from, to time.Time) ([]capturetypes.BackfillResult, error)
+
+	// BackfillFlows writes the provided flows of an interface for a timestamp, replacing any
+	// block already present for the same timestamp
+	BackfillFlows(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap, source gpfile.BackfillSource) (capturetypes.BackfillResult, error)
+}
+
+// spilledWriteout denotes a flow map whose writeout failed
+type spilledWriteout struct {
+	timestamp time.Time
+	taggedMap capturetypes.TaggedAggFlowMap
+}
+
+// spillBuffer retains the flow maps of failed writeouts (bounded per interface) in order to allow
+// for backfilling them at a later point in time
+type spillBuffer struct {
+	entries map[string][]spilledWriteout
+	maxSize int
+
+	sync.Mutex
+}
+
+func newSpillBuffer(maxSize int) *spillBuffer {
+	return &spillBuffer{
+		entries: make(map[string][]spilledWriteout),
+		maxSize: maxSize,
+	}
+}
+
+// add retains the flow map of a failed writeout. If the buffer of the interface is full, the
+// oldest writeout is discarded
+func (s *spillBuffer) add(timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) {
+	if s.maxSize <= 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	entries := s.entries[taggedMap.Iface]
+
+	// a writeout for the same timestamp supersedes any previous one
+	idx := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].timestamp.Before(timestamp)
+	})
+	if idx < len(entries) && entries[idx].timestamp.Equal(timestamp) {
+		entries[idx].taggedMap = taggedMap
+		return
+	}
+	entries = append(entries[:idx], append([]spilledWriteout{{timestamp: timestamp, taggedMap: taggedMap}}, entries[idx:]...)...)
+	if len(entries) > s.maxSize {
+		entries = entries[len(entries)-s.maxSize:]
+	}
+	s.entries[taggedMap.Iface] = entries
+
+	spilledWriteouts.Set(float64(s.len()))
+}
+
+// get returns all spilled writeouts of an interface with a timestamp in [from, to]
+func (s *spillBuffer) get(iface string, from, to time.Time) (res []spilledWriteout) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, entry := range s.entries[iface] {
+		if entry.timestamp.Before(from) || entry.timestamp.After(to) {
+			continue
+		}
+		res = append(res, entry)
+	}
+	return
+}
+
+// remove discards the spilled writeout of an interface for a timestamp (if present)
+func (s *spillBuffer) remove(iface string, timestamp time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	entries := s.entries[iface]
+	for i, entry := range entries {
+		if entry.timestamp.Equal(timestamp) {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(s.entries, iface)
+	} else {
+		s.entries[iface] = entries
+	}
+
+	spilledWriteouts.Set(float64(s.len()))
+}
+
+func (s *spillBuffer) len() (n int) {
+	for _, entries := range s.entries {
+		n += len(entries)
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package writeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/goProbe/pkg/goDB/encoder/encoders"
+	"github.com/els0r/goProbe/pkg/goDB/godbtest"
+	"github.com/els0r/goProbe/pkg/goDB/storage/gpfile"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/hashmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	s := newSpillBuffer(2)
+
+	tFirst := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		s.add(tFirst.Add(time.Duration(i)*5*time.Minute), testTaggedMap("eth0"))
+	}
+	s.add(tFirst, testTaggedMap("eth1"))
+
+	// only the most recent writeouts per interface are retained
+	spilled := s.get("eth0", tFirst, tFirst.Add(time.Hour))
+	require.Len(t, spilled, 2)
+	require.Equal(t, tFirst.Add(5*time.Minute), spilled[0].timestamp)
+	require.Equal(t, tFirst.Add(10*time.Minute), spilled[1].timestamp)
+	require.Len(t, s.get("eth0", tFirst, tFirst.Add(5*time.Minute)), 1)
+	require.Len(t, s.get("eth1", tFirst, tFirst), 1)
+	require.Empty(t, s.get("eth2", tFirst, tFirst.Add(time.Hour)))
+
+	// a writeout for the same timestamp supersedes the retained one
+	s.add(tFirst.Add(5*time.Minute), testTaggedMap("eth0"))
+	require.Len(t, s.get("eth0", tFirst, tFirst.Add(time.Hour)), 2)
+
+	s.remove("eth0", tFirst.Add(5*time.Minute))
+	s.remove("eth1", tFirst)
+	require.Len(t, s.get("eth0", tFirst, tFirst.Add(time.Hour)), 1)
+	require.Empty(t, s.get("eth1", tFirst, tFirst.Add(time.Hour)))
+
+	// a disabled spill buffer doesn't retain anything
+	s = newSpillBuffer(0)
+	s.add(tFirst, testTaggedMap("eth0"))
+	require.Empty(t, s.get("eth0", tFirst, tFirst))
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+
+	h := NewGoDBHandler("/godb", encoders.EncoderTypeLZ4).WithFS(godbtest.NewMemFS()).WithSpillBuffer(4)
+
+	tFirst := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	h.spill.add(tFirst.Add(5*time.Minute), testTaggedMap("eth0"))
+	h.spill.add(tFirst.Add(10*time.Minute), testTaggedMap("eth0"))
+
+	_, err := h.Backfill(ctx, "eth0", tFirst.Add(time.Hour), tFirst)
+	require.ErrorIs(t, err, ErrInvalidInterval)
+	_, err = h.Backfill(ctx, "eth1", tFirst, tFirst.Add(time.Hour))
+	require.ErrorIs(t, err, ErrNoSpilledWriteouts)
+
+	// backfill all spilled writeouts (in reverse order to force an insertion)
+	res, err := h.Backfill(ctx, "eth0", tFirst.Add(10*time.Minute), tFirst.Add(time.Hour))
+	require.Nil(t, err)
+	require.Equal(t, []capturetypes.BackfillResult{
+		{Timestamp: tFirst.Add(10 * time.Minute), Source: "spill", NumFlows: 2},
+	}, res)
+	res, err = h.Backfill(ctx, "eth0", tFirst, tFirst.Add(time.Hour))
+	require.Nil(t, err)
+	require.Equal(t, []capturetypes.BackfillResult{
+		{Timestamp: tFirst.Add(5 * time.Minute), Source: "spill", NumFlows: 2},
+	}, res)
+
+	// successfully backfilled writeouts are removed from the spill buffer
+	_, err = h.Backfill(ctx, "eth0", tFirst, tFirst.Add(time.Hour))
+	require.ErrorIs(t, err, ErrNoSpilledWriteouts)
+
+	// backfilling a block that is already present replaces it
+	result, err := h.BackfillFlows(ctx, tFirst.Add(5*time.Minute), testTaggedMap("eth0"), gpfile.BackfillSourcePcap)
+	require.Nil(t, err)
+	require.Equal(t, capturetypes.BackfillResult{
+		Timestamp: tFirst.Add(5 * time.Minute),
+		Source:    "pcap",
+		Replaced:  true,
+		NumFlows:  2,
+	}, result)
+}
+
+func testTaggedMap(iface string) capturetypes.TaggedAggFlowMap {
+	m := hashmap.NewAggFlowMap()
+	m.PrimaryMap.Set(types.NewV4KeyStatic([4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, []byte{0, 80}, 6),
+		types.Counters{BytesRcvd: 200, BytesSent: 10, PacketsRcvd: 2, PacketsSent: 1})
+	m.SecondaryMap.Set(types.NewV6KeyStatic([16]byte{0x20, 0x01, 15: 1}, [16]byte{0x20, 0x01, 15: 2}, []byte{1, 187}, 17),
+		types.Counters{BytesRcvd: 100, BytesSent: 20, PacketsRcvd: 1, PacketsSent: 1})
+	return capturetypes.TaggedAggFlowMap{
+		Map:   m,
+		Iface: iface,
+	}
+}
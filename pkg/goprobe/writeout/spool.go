@@ -0,0 +1,178 @@
+package writeout
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// SpoolFileSuffix denotes the suffix of all snapshots written to the spool
+	SpoolFileSuffix = ".json"
+
+	// spoolTempPrefix denotes the prefix of snapshots being written (which are hidden from watchers
+	// of the spool until they are complete)
+	spoolTempPrefix = "."
+
+	spoolPermissions    = 0640
+	spoolDirPermissions = 0750
+)
+
+// SpoolSnapshot denotes the snapshot of the flows of an interface of a single rotation, written as
+// JSON to the spool
+type SpoolSnapshot struct {
+	// Timestamp: denotes the timestamp of the rotation. Example: "2021-01-01T00:05:00Z"
+	Timestamp time.Time `json:"timestamp"`
+	// Iface: denotes the interface the flows were captured on. Example: "eth0"
+	Iface string `json:"iface"`
+	// Stats: denotes the capture statistics of the interface for the rotation
+	Stats capturetypes.CaptureStats `json:"stats"`
+	// Flows: stores the flows of the rotation
+	Flows []capturetypes.RotatedFlow `json:"flows"`
+}
+
+// SpoolFileName returns the name of the snapshot of an interface for a rotation. Since the name is
+// prefixed with the (fixed width) unix timestamp of the rotation, snapshots sort chronologically
+func SpoolFileName(timestamp time.Time, iface string) string {
+	return fmt.Sprintf("%010d_%s%s", timestamp.Unix(), iface, SpoolFileSuffix)
+}
+
+// spool writes the rotated flows of each interface as JSON snapshot to a bounded local directory
+type spool struct {
+	cfg config.SpoolConfig
+
+	sync.Mutex
+}
+
+func newSpool(cfg config.SpoolConfig) *spool {
+	if cfg.MaxFiles == 0 {
+		cfg.MaxFiles = config.DefaultSpoolMaxFiles
+	}
+	return &spool{cfg: cfg}
+}
+
+// WithSpool additionally writes the rotated flows of each interface as JSON snapshot to a local spool
+// directory, consumable by third-party agents watching the filesystem. A nil configuration disables
+// the spool
+func (h *GoDBHandler) WithSpool(cfg *config.SpoolConfig) *GoDBHandler {
+	h.spool = nil
+	if cfg != nil {
+		h.spool = newSpool(*cfg)
+	}
+	return h
+}
+
+// writeSpool writes the rotated map of an interface to the spool (if enabled)
+func (h *GoDBHandler) writeSpool(ctx context.Context, timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) {
+	if h.spool == nil {
+		return
+	}
+
+	t0 := time.Now()
+	if err := h.spool.write(timestamp, taggedMap); err != nil {
+		logging.FromContext(ctx).Errorf("failed to write flows to spool: %s", err)
+		return
+	}
+	h.backlog.observeSink(SinkSpool, time.Since(t0))
+}
+
+// write atomically writes the snapshot of an interface and removes the oldest snapshots exceeding
+// the bounds of the spool
+func (s *spool) write(timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) error {
+	snapshot := SpoolSnapshot{
+		Timestamp: timestamp,
+		Iface:     taggedMap.Iface,
+		Stats:     taggedMap.Stats,
+		Flows:     capturetypes.RotatedFlows(taggedMap.Map),
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err := os.MkdirAll(s.cfg.Path, spoolDirPermissions); err != nil {
+		return err
+	}
+
+	name := SpoolFileName(timestamp, taggedMap.Iface)
+	tmpPath := filepath.Join(s.cfg.Path, spoolTempPrefix+name)
+	if err := writeSnapshot(tmpPath, &snapshot); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.cfg.Path, name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return s.prune()
+}
+
+func writeSnapshot(path string, snapshot *SpoolSnapshot) error {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, spoolPermissions)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(file)
+	if err := json.NewEncoder(buf).Encode(snapshot); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// prune removes the oldest snapshots until the spool complies with its bounds again
+func (s *spool) prune() error {
+	entries, err := os.ReadDir(s.cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	type snapshotFile struct {
+		name string
+		size int64
+	}
+	var (
+		files     []snapshotFile
+		totalSize int64
+	)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), spoolTempPrefix) || !strings.HasSuffix(entry.Name(), SpoolFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, snapshotFile{entry.Name(), info.Size()})
+		totalSize += info.Size()
+	}
+	slices.SortFunc(files, func(a, b snapshotFile) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	for len(files) > 0 && (len(files) > s.cfg.MaxFiles || (s.cfg.MaxSize > 0 && totalSize > s.cfg.MaxSize)) {
+		if err := os.Remove(filepath.Join(s.cfg.Path, files[0].name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		totalSize -= files[0].size
+		files = files[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package writeout
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "spool")
+	s := newSpool(config.SpoolConfig{Path: path, MaxFiles: 3})
+
+	for i := 0; i < 5; i++ {
+		require.Nil(t, s.write(start.Add(time.Duration(i)*5*time.Minute), testTaggedMap("eth0")))
+	}
+
+	// only the most recent snapshots are retained (and no temporary files are left behind)
+	entries, err := os.ReadDir(path)
+	require.Nil(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		require.Equal(t, SpoolFileName(start.Add(time.Duration(i+2)*5*time.Minute), "eth0"), entry.Name())
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, entries[2].Name()))
+	require.Nil(t, err)
+	var snapshot SpoolSnapshot
+	require.Nil(t, json.Unmarshal(data, &snapshot))
+	require.True(t, start.Add(20*time.Minute).Equal(snapshot.Timestamp))
+	require.Equal(t, "eth0", snapshot.Iface)
+	require.Len(t, snapshot.Flows, 2)
+
+	var bytesRcvd uint64
+	for _, flow := range snapshot.Flows {
+		bytesRcvd += flow.Counters.BytesRcvd
+		if flow.Attributes.SrcIP.Is4() {
+			require.Equal(t, "10.0.0.1", flow.Attributes.SrcIP.String())
+			require.Equal(t, uint16(80), flow.Attributes.DstPort)
+			require.Equal(t, uint8(6), flow.Attributes.IPProto)
+		}
+	}
+	require.Equal(t, uint64(300), bytesRcvd)
+
+	// enforce the size bound
+	s.cfg.MaxSize = int64(len(data))
+	require.Nil(t, s.write(start.Add(25*time.Minute), testTaggedMap("eth0")))
+	entries, err = os.ReadDir(path)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, SpoolFileName(start.Add(25*time.Minute), "eth0"), entries[0].Name())
+}
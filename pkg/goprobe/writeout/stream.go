@@ -0,0 +1,320 @@
+package writeout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/api/flowstream"
+	"github.com/els0r/goProbe/pkg/capture/capturetypes"
+	"github.com/els0r/telemetry/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// streamMinBackoff denotes the initial delay between attempts to reestablish the flow stream
+const streamMinBackoff = time.Second
+
+// errStreamClosed denotes that the consumer closed the flow stream
+var errStreamClosed = errors.New("stream closed by consumer")
+
+// stream streams the rotated flows of each interface as batches to an external gRPC consumer (c.f.
+// flowstream.FlowStreamServer). Batches are queued without ever blocking the writeout and transmitted
+// by a single routine (c.f. run()), which limits the number of unacknowledged batches and requeues them
+// whenever the stream breaks
+type stream struct {
+	cfg    config.StreamConfig
+	conn   *grpc.ClientConn
+	client flowstream.FlowStreamClient
+
+	session  string
+	hostname string
+	sequence uint64
+
+	queue    []*flowstream.FlowBatch // batches pending transmission (oldest first)
+	inFlight []*flowstream.FlowBatch // batches transmitted, but not yet acknowledged (oldest first)
+	notify   chan struct{}
+
+	minBackoff time.Duration
+
+	sync.Mutex
+}
+
+func newStream(cfg config.StreamConfig, opts ...grpc.DialOption) (*stream, error) {
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = config.DefaultStreamQueueSize
+	}
+	if cfg.MaxInFlight == 0 {
+		cfg.MaxInFlight = config.DefaultStreamMaxInFlight
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = config.DefaultStreamMaxBackoff
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		tlsConfig, err := cfg.TLS.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	// The connection is established lazily (and reestablished by the client connection itself)
+	conn, err := grpc.Dial(cfg.Target, append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	session := make([]byte, 16)
+	if _, err := rand.Read(session); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+
+	return &stream{
+		cfg:        cfg,
+		conn:       conn,
+		client:     flowstream.NewFlowStreamClient(conn),
+		session:    hex.EncodeToString(session),
+		hostname:   hostname,
+		notify:     make(chan struct{}, 1),
+		minBackoff: min(streamMinBackoff, cfg.MaxBackoff),
+	}, nil
+}
+
+// WithStream additionally streams the rotated flows of each interface to an external gRPC consumer
+// (c.f. RunStream()). A nil configuration disables the stream
+func (h *GoDBHandler) WithStream(cfg *config.StreamConfig) (*GoDBHandler, error) {
+	h.stream = nil
+	if cfg != nil {
+		s, err := newStream(*cfg)
+		if err != nil {
+			return h, err
+		}
+		h.stream = s
+	}
+	return h, nil
+}
+
+// RunStream transmits the rotated flows to the consumer of the flow stream (if enabled) until the
+// context is cancelled
+func (h *GoDBHandler) RunStream(ctx context.Context) {
+	if h.stream == nil {
+		return
+	}
+
+	logging.FromContext(ctx).With("target", h.stream.cfg.Target, "session", h.stream.session).Info("starting flow stream")
+	h.stream.run(ctx)
+}
+
+// writeStream queues the rotated map of an interface for transmission via the flow stream (if enabled)
+func (h *GoDBHandler) writeStream(timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) {
+	if h.stream == nil {
+		return
+	}
+
+	t0 := time.Now()
+	h.stream.enqueue(timestamp, taggedMap)
+	h.backlog.observeSink(SinkStream, time.Since(t0))
+}
+
+// enqueue converts the rotated map of an interface to a batch and queues it for transmission. If the
+// queue is full, the oldest batches are discarded
+func (s *stream) enqueue(timestamp time.Time, taggedMap capturetypes.TaggedAggFlowMap) {
+	batch := &flowstream.FlowBatch{
+		Session:   s.session,
+		Hostname:  s.hostname,
+		Iface:     taggedMap.Iface,
+		Timestamp: timestamp.Unix(),
+		Stats: &flowstream.CaptureStats{
+			Received:  taggedMap.Stats.Received,
+			Processed: taggedMap.Stats.Processed,
+			Dropped:   taggedMap.Stats.Dropped,
+			Filtered:  taggedMap.Stats.Filtered,
+		},
+		Flows: streamFlows(taggedMap),
+	}
+
+	s.Lock()
+	s.sequence++
+	batch.Sequence = s.sequence
+	s.queue = append(s.queue, batch)
+	s.trim()
+	s.Unlock()
+
+	s.wake()
+}
+
+func streamFlows(taggedMap capturetypes.TaggedAggFlowMap) []*flowstream.Flow {
+	rotated := capturetypes.RotatedFlows(taggedMap.Map)
+	flows := make([]*flowstream.Flow, 0, len(rotated))
+	for _, flow := range rotated {
+		flows = append(flows, &flowstream.Flow{
+			Sip:         flow.Attributes.SrcIP.AsSlice(),
+			Dip:         flow.Attributes.DstIP.AsSlice(),
+			Dport:       uint32(flow.Attributes.DstPort),
+			Proto:       uint32(flow.Attributes.IPProto),
+			Tag:         flow.Attributes.Tag,
+			TtlMin:      uint32(flow.Attributes.TTLMin),
+			TtlMax:      uint32(flow.Attributes.TTLMax),
+			BytesRcvd:   flow.Counters.BytesRcvd,
+			BytesSent:   flow.Counters.BytesSent,
+			PacketsRcvd: flow.Counters.PacketsRcvd,
+			PacketsSent: flow.Counters.PacketsSent,
+		})
+	}
+	return flows
+}
+
+// run maintains the stream to the consumer until the context is cancelled, reestablishing it (with an
+// exponential backoff) whenever it breaks
+func (s *stream) run(ctx context.Context) {
+	logger := logging.FromContext(ctx).With("target", s.cfg.Target)
+	defer func() {
+		_ = s.conn.Close()
+	}()
+
+	backoff := s.minBackoff
+	for {
+		acked, err := s.serve(ctx)
+		streamConnected.Set(0)
+		s.requeue()
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Only back off further if the consumer didn't process anything since the last attempt
+		if acked {
+			backoff = s.minBackoff
+		}
+		logger.With("retry_in", backoff.String()).Warnf("flow stream interrupted: %s", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, s.cfg.MaxBackoff)
+	}
+}
+
+// serve transmits the queued batches via a single stream until it breaks. It returns whether any
+// batch was acknowledged by the consumer in the meantime
+func (s *stream) serve(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := s.client.Stream(ctx)
+	if err != nil {
+		return false, err
+	}
+	streamConnected.Set(1)
+
+	var acked atomic.Bool
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			ack, err := client.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = errStreamClosed
+				}
+				errs <- err
+				return
+			}
+			s.ack(ack.GetSequence())
+			acked.Store(true)
+			s.wake()
+		}
+	}()
+
+	for {
+		// Sending blocks while the consumer doesn't keep up (as governed by the flow control of the
+		// transport), in which case batches pile up in the queue instead
+		for _, batch := range s.next() {
+			if err := client.Send(batch); err != nil {
+				return acked.Load(), err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return acked.Load(), ctx.Err()
+		case err := <-errs:
+			return acked.Load(), err
+		case <-s.notify:
+		}
+	}
+}
+
+// next moves as many batches from the queue to the batches in flight as permitted, returning them
+// for transmission
+func (s *stream) next() []*flowstream.FlowBatch {
+	s.Lock()
+	defer s.Unlock()
+
+	n := min(s.cfg.MaxInFlight-len(s.inFlight), len(s.queue))
+	if n <= 0 {
+		return nil
+	}
+	batches := slices.Clone(s.queue[:n])
+	s.inFlight = append(s.inFlight, batches...)
+	clear(s.queue[:n])
+	s.queue = s.queue[n:]
+	streamQueueDepth.Set(float64(len(s.queue)))
+
+	return batches
+}
+
+// ack removes all batches up to (and including) the provided sequence number from the batches in flight
+func (s *stream) ack(sequence uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	n := 0
+	for n < len(s.inFlight) && s.inFlight[n].Sequence <= sequence {
+		n++
+	}
+	clear(s.inFlight[:n])
+	s.inFlight = s.inFlight[n:]
+}
+
+// requeue moves all unacknowledged batches back to the front of the queue (retaining their order),
+// such that they are retransmitted once the stream has been reestablished
+func (s *stream) requeue() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.queue = append(s.inFlight, s.queue...)
+	s.inFlight = nil
+	s.trim()
+}
+
+// trim discards the oldest batches exceeding the size of the queue. It must be called with the lock held
+func (s *stream) trim() {
+	if excess := len(s.queue) - s.cfg.QueueSize; excess > 0 {
+		clear(s.queue[:excess])
+		s.queue = s.queue[excess:]
+		streamDroppedBatches.Add(float64(excess))
+	}
+	streamQueueDepth.Set(float64(len(s.queue)))
+}
+
+// wake notifies the transmitting routine about queued / acknowledged batches
+func (s *stream) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,178 @@
+package writeout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/cmd/goProbe/config"
+	"github.com/els0r/goProbe/pkg/api/flowstream"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testConsumer receives all batches of the flow stream, acknowledging each of them
+type testConsumer struct {
+	flowstream.UnimplementedFlowStreamServer
+
+	batches chan *flowstream.FlowBatch
+}
+
+func (c *testConsumer) Stream(srv flowstream.FlowStream_StreamServer) error {
+	for {
+		batch, err := srv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		c.batches <- batch
+		if err := srv.Send(&flowstream.Ack{Sequence: batch.Sequence}); err != nil {
+			return err
+		}
+	}
+}
+
+// testConsumerEndpoint allows to (re)start a consumer reachable via an in-memory connection
+type testConsumerEndpoint struct {
+	consumer *testConsumer
+	server   *grpc.Server
+	listener *bufconn.Listener
+
+	sync.Mutex
+}
+
+func (e *testConsumerEndpoint) start() {
+	e.Lock()
+	defer e.Unlock()
+
+	e.listener = bufconn.Listen(1024 * 1024)
+	e.server = grpc.NewServer()
+	flowstream.RegisterFlowStreamServer(e.server, e.consumer)
+	go func(server *grpc.Server, listener net.Listener) {
+		_ = server.Serve(listener)
+	}(e.server, e.listener)
+}
+
+func (e *testConsumerEndpoint) stop() {
+	e.Lock()
+	defer e.Unlock()
+
+	e.server.Stop()
+}
+
+func (e *testConsumerEndpoint) dial(ctx context.Context, _ string) (net.Conn, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.listener.DialContext(ctx)
+}
+
+func TestStreamQueue(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	s, err := newStream(config.StreamConfig{Target: "consumer", QueueSize: 3, MaxInFlight: 2})
+	require.Nil(t, err)
+	defer s.conn.Close()
+
+	sequences := func(batches []*flowstream.FlowBatch) (res []uint64) {
+		for _, batch := range batches {
+			res = append(res, batch.Sequence)
+		}
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		s.enqueue(start.Add(time.Duration(i)*5*time.Minute), testTaggedMap("eth0"))
+	}
+	require.Equal(t, []uint64{1, 2}, sequences(s.next()))
+	require.Empty(t, s.next())
+
+	// unacknowledged batches are retransmitted (in order) once the stream has been reestablished
+	s.ack(1)
+	s.requeue()
+	require.Empty(t, s.inFlight)
+	require.Equal(t, []uint64{2, 3}, sequences(s.queue))
+
+	// if the queue is full, the oldest batches are discarded
+	s.enqueue(start.Add(15*time.Minute), testTaggedMap("eth0"))
+	s.enqueue(start.Add(20*time.Minute), testTaggedMap("eth0"))
+	require.Equal(t, []uint64{3, 4, 5}, sequences(s.queue))
+}
+
+func TestStream(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	endpoint := &testConsumerEndpoint{
+		consumer: &testConsumer{batches: make(chan *flowstream.FlowBatch, 16)},
+	}
+	endpoint.start()
+
+	s, err := newStream(config.StreamConfig{Target: "consumer", MaxInFlight: 2}, grpc.WithContextDialer(endpoint.dial))
+	require.Nil(t, err)
+	s.minBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		endpoint.stop()
+	}()
+
+	receive := func() *flowstream.FlowBatch {
+		select {
+		case batch := <-endpoint.consumer.batches:
+			return batch
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for batch")
+		}
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		s.enqueue(start.Add(time.Duration(i)*5*time.Minute), testTaggedMap("eth0"))
+	}
+	for i := 0; i < 3; i++ {
+		batch := receive()
+		require.Equal(t, uint64(i+1), batch.Sequence)
+		require.Equal(t, s.session, batch.Session)
+		require.Equal(t, "eth0", batch.Iface)
+		require.Equal(t, start.Add(time.Duration(i)*5*time.Minute).Unix(), batch.Timestamp)
+		require.Len(t, batch.Flows, 2)
+
+		var bytesRcvd uint64
+		for _, flow := range batch.Flows {
+			bytesRcvd += flow.BytesRcvd
+			if addr, ok := netip.AddrFromSlice(flow.Sip); ok && addr.Is4() {
+				require.Equal(t, "10.0.0.1", addr.String())
+				require.Equal(t, uint32(80), flow.Dport)
+				require.Equal(t, uint32(6), flow.Proto)
+			}
+		}
+		require.Equal(t, uint64(300), bytesRcvd)
+	}
+	require.Eventually(t, func() bool {
+		s.Lock()
+		defer s.Unlock()
+		return len(s.inFlight) == 0 && len(s.queue) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// batches queued while the consumer is unavailable are transmitted once the stream is reestablished
+	endpoint.stop()
+	s.enqueue(start.Add(15*time.Minute), testTaggedMap("eth1"))
+	endpoint.start()
+
+	batch := receive()
+	require.Equal(t, uint64(4), batch.Sequence)
+	require.Equal(t, "eth1", batch.Iface)
+}
@@ -0,0 +1,28 @@
+package writeout
+
+import (
+	"context"
+
+	"github.com/els0r/goProbe/pkg/goDB/vacuum"
+)
+
+// Vacuumer is implemented by writeout handlers that support reclaiming disk space from their storage
+type Vacuumer interface {
+
+	// Vacuum removes the data of all interfaces not contained in configuredIfaces (older than
+	// a safety window) and compacts all remaining data
+	Vacuum(ctx context.Context, configuredIfaces []string, opts ...vacuum.Option) (vacuum.Results, error)
+}
+
+// Vacuum removes the data of all interfaces not contained in configuredIfaces (older than a safety
+// window) and compacts all remaining data of the GoDB. Each directory is processed while holding the
+// lock of the handler, i.e. the vacuum is serialized with regular writeouts
+func (h *GoDBHandler) Vacuum(ctx context.Context, configuredIfaces []string, opts ...vacuum.Option) (vacuum.Results, error) {
+	opts = append([]vacuum.Option{
+		vacuum.WithFS(h.fsys),
+		vacuum.WithPermissions(h.permissions),
+		vacuum.WithLocker(h),
+	}, opts...)
+
+	return vacuum.New(h.path, opts...).Run(ctx, configuredIfaces)
+}
@@ -0,0 +1,27 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/els0r/goProbe/pkg/query/asn"
+	"github.com/els0r/goProbe/pkg/results"
+)
+
+// printAnalysis evaluates the result according to the analysis mode of the statement and prints
+// the outcome (instead of the rows of the result)
+func (s *Statement) printAnalysis(result *results.Result) error {
+	switch s.Analysis {
+	case AnalysisASNMatrix:
+		db, err := asn.Load(s.ASNDB)
+		if err != nil {
+			return err
+		}
+		matrix := asn.NewMatrix(db, result)
+		if s.Format == "csv" {
+			return matrix.PrintCSV(s.Output)
+		}
+		return matrix.PrintJSON(s.Output)
+	default:
+		return fmt.Errorf("unknown analysis mode %s", s.Analysis)
+	}
+}
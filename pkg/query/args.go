@@ -56,7 +56,7 @@ func DefaultArgs() *Args {
 // Args bundles the command line/HTTP parameters required to prepare a query statement
 type Args struct {
 	// required
-	Query  string `json:"query" yaml:"query" form:"query"`    // Query: the query type. Example: sip,dip,dport,proto
+	Query  string `json:"query" yaml:"query" form:"query"`    // Query: the query type, i.e. the attributes and labels to group by. Example: sip,dip,dport,proto
 	Ifaces string `json:"ifaces" yaml:"ifaces" form:"ifaces"` // Ifaces: the interfaces to query. Example: eth0,eth1
 
 	QueryHosts string `json:"query_hosts,omitempty" yaml:"query_hosts,omitempty" form:"query_hosts,omitempty"` // QueryHosts: the hosts for which data is queried (comma-separated list). Example: hostA,hostB,hostC
@@ -67,21 +67,97 @@ type Args struct {
 	// data filtering
 	Condition string `json:"condition,omitempty" yaml:"condition,omitempty" form:"condition,omitempty"` // Condition: the condition to filter data by. Example: port=80 && proto=TCP
 
+	// Having: predicate on the counters of the aggregated rows, selecting rows by derived values such as the
+	// ratio between two counters (as opposed to the condition, which is evaluated on the individual flows).
+	// Comparisons of a counter (bytes_in, bytes_out, packets_in, packets_out, bytes, packets) or of
+	// ratio(<counter>, <counter>) with a number can be combined via "&" and "|". Example: ratio(bytes_in, bytes_out) > 100
+	Having string `json:"having,omitempty" yaml:"having,omitempty" form:"having,omitempty"`
+
 	// counter addition
 	In  bool `json:"in,omitempty" yaml:"in,omitempty" form:"in,omitempty"`     // In: only show incoming packets/bytes. Example: false
 	Out bool `json:"out,omitempty" yaml:"out,omitempty"  form:"out,omitempty"` // Out: only show outgoing packets/bytes. Example: false
 	Sum bool `json:"sum,omitempty" yaml:"sum,omitempty" form:"sum,omitempty"`  // Sum: show sum of incoming/outgoing packets/bytes. Example: false
 
+	// Counters: limits the counters aggregated by the query (comma-separated list). Counter columns that aren't
+	// selected are not read from disk at all. Enum: [bytes, packets, bytes_in, bytes_out, packets_in, packets_out]. Example: packets
+	Counters string `json:"counters,omitempty" yaml:"counters,omitempty" form:"counters,omitempty"`
+
 	// time selection
 	First string `json:"first,omitempty" yaml:"first,omitempty" form:"first,omitempty"` // First: the first timestamp to query. Example: 2020-08-12T09:47:00+0200
 	Last  string `json:"last,omitempty" yaml:"last,omitempty" form:"last,omitempty"`    // Last: the last timestamp to query. Example: -24h
 
+	// TimeAlign: handling of time ranges cutting through blocks (which cover one rotation, i.e. 5 minutes, each).
+	// snap expands the time range to the enclosing rotation boundaries, reporting the effective range in the
+	// summary. interpolate scales the counters of blocks only partially overlapping the time range by the fraction
+	// of the overlap (assuming the traffic to be evenly distributed across the block). By default, all blocks ending
+	// within the time range are queried. Enum: [snap, interpolate]. Example: snap
+	TimeAlign string `json:"time_align,omitempty" yaml:"time_align,omitempty" form:"time_align,omitempty"`
+
 	// formatting
-	Format        string `json:"format,omitempty" yaml:"format,omitempty" form:"format,omitempty"`                         // Format: the output format. Enum: [json, csv, table]. Example: json
+	Format        string `json:"format,omitempty" yaml:"format,omitempty" form:"format,omitempty"`                         // Format: the output format. Enum: [json, csv, table, pcapng, influxdb]. Example: json
 	SortBy        string `json:"sort_by,omitempty" yaml:"sort_by,omitempty" form:"sort_by,omitempty"`                      // SortBy: column to sort by. Enum: [packets, bytes]. Example: bytes
 	NumResults    uint64 `json:"num_results,omitempty" yaml:"num_results,omitempty" form:"num_results,omitempty"`          // NumResults: number of results to return/print. Example: 25
 	SortAscending bool   `json:"sort_ascending,omitempty" yaml:"sort_ascending,omitempty" form:"sort_ascending,omitempty"` // SortAscending: sort ascending instead of the default descending. Example: false
 
+	HumanReadable        bool `json:"human_readable,omitempty" yaml:"human_readable,omitempty" form:"human_readable,omitempty"`                      // HumanReadable: render byte/packet counters in human-readable units (e.g. KiB/MiB/GiB). Only permitted for the csv and txt formats. Example: false
+	DirectionPercentages bool `json:"direction_percentages,omitempty" yaml:"direction_percentages,omitempty" form:"direction_percentages,omitempty"` // DirectionPercentages: include percentage-of-total columns for each direction (received/sent). Only permitted for the csv and txt formats. Example: false
+	Numeric              bool `json:"numeric,omitempty" yaml:"numeric,omitempty" form:"numeric,omitempty"`                                           // Numeric: print IP protocols as numbers instead of their names (csv and table output). Example: false
+
+	// TimeZone: the time zone timestamps are printed in (IANA name, "UTC" or "Local"). JSON output keeps
+	// RFC3339 timestamps, carrying the offset of the time zone. Defaults to the local time zone. Example: Europe/Zurich
+	TimeZone string `json:"tz,omitempty" yaml:"tz,omitempty" form:"tz,omitempty"`
+
+	// TimeFormat: the format timestamps are printed in (csv and table output), either a named format or a layout
+	// in Go reference time notation (e.g. "02.01.2006 15:04"). Enum: [default, rfc3339, unix]. Example: rfc3339
+	TimeFormat string `json:"time_format,omitempty" yaml:"time_format,omitempty" form:"time_format,omitempty"`
+
+	// FlowHash: add the canonical flow hash (64-bit FNV-1a of the sip, dip, dport and proto attributes) to each row,
+	// allowing to join flows recorded by different probes or exported to other systems. Requires the query to contain
+	// all of these attributes. Example: false
+	FlowHash bool `json:"flow_hash,omitempty" yaml:"flow_hash,omitempty" form:"flow_hash,omitempty"`
+
+	// CommunityID: add the Community ID (version 1) of each flow, as used by e.g. Zeek and Suricata, allowing to join
+	// the flows with IDS alerts and other tooling relying on it. Since source ports aren't recorded, it is only set for
+	// flows of protocols without ports (e.g. ICMP, GRE or ESP) and left empty for TCP, UDP and SCTP flows. Requires the
+	// query to contain the sip, dip, dport and proto attributes. Example: false
+	CommunityID bool `json:"community_id,omitempty" yaml:"community_id,omitempty" form:"community_id,omitempty"`
+
+	// CommunityIDSeed: the seed of the Community ID, which has to match the one configured in the tools the flows are
+	// joined with. Example: 0
+	CommunityIDSeed uint16 `json:"community_id_seed,omitempty" yaml:"community_id_seed,omitempty" form:"community_id_seed,omitempty"`
+
+	// Roles: correlate the traffic of each host as source (sip) and as destination (dip), emitting a single row per
+	// host (and all other attributes) carrying its traffic in both roles, e.g. to compare the volume it fetched as
+	// client with the volume it served as server. Requires the query to contain both the sip and dip attributes and
+	// is limited to the json, csv and txt formats. Example: false
+	Roles bool `json:"roles,omitempty" yaml:"roles,omitempty" form:"roles,omitempty"`
+
+	// Stats: summarize the data volume of each row per time bin (5-minute interval) over the queried time range
+	// by its median, 95th percentile, maximum and standard deviation, emitting a single row per set of attributes
+	// instead of the raw bins (e.g. for capacity planning). Time bins without any traffic of a row count as zero.
+	// Cannot be combined with the time attribute and is limited to the json, csv and txt formats. Example: false
+	Stats bool `json:"stats,omitempty" yaml:"stats,omitempty" form:"stats,omitempty"`
+
+	// Analysis: evaluate the result of the query according to an analysis mode before printing it. asn-matrix
+	// aggregates the traffic between all pairs of source and destination ASNs (requiring the sip and dip attributes,
+	// an ASN database and the json or csv format). one-way only retains one-directional rows (i.e. without any
+	// packets in one of the directions), a common indicator of blocked responses, exfiltration or broken mirrors.
+	// Enum: [asn-matrix, one-way]. Example: asn-matrix
+	Analysis string `json:"analysis,omitempty" yaml:"analysis,omitempty" form:"analysis,omitempty"`
+
+	// ASNDB: path to the database mapping IP prefixes to ASNs used by the asn-matrix analysis, containing one
+	// prefix per line, followed by the ASN and (optionally) its name. Example: /etc/goprobe/asn.txt
+	ASNDB string `json:"asn_db,omitempty" yaml:"asn_db,omitempty" form:"asn_db,omitempty"`
+
+	// Sample: return a uniform random sample of (up to) this many of the matching rows instead of sorting and limiting
+	// them, along with the traffic of all matching rows extrapolated from the sample. Meant for exploratory looks
+	// at enormous results. 0 disables sampling. Example: 100
+	Sample int `json:"sample,omitempty" yaml:"sample,omitempty" form:"sample,omitempty"`
+
+	// Influx: the mapping of flows to InfluxDB line protocol (measurement, tags and fields) for the influxdb output format
+	// Note: Nested structures are not supported for form data
+	Influx *results.InfluxMapping `json:"influx,omitempty" yaml:"influx,omitempty"`
+
 	// do-and-exit arguments
 	List    bool `json:"list,omitempty" yaml:"list,omitempty" form:"list,omitempty"`          // List: only list interfaces and return. Example: false
 	Version bool `json:"version,omitempty" yaml:"version,omitempty" form:"version,omitempty"` // Version: only print version and return. Example: false
@@ -93,6 +169,18 @@ type Args struct {
 	// file system
 	MaxMemPct int  `json:"max_mem_pct,omitempty" yaml:"max_mem_pct,omitempty" form:"max_mem_pct,omitempty"` // MaxMemPct: maximum percentage of available host memory to use for query processing. Example: 80
 	LowMem    bool `json:"low_mem,omitempty" yaml:"low_mem,omitempty" form:"low_mem,omitempty"`             // LowMem: use less memory for query processing. Example: false
+	Mmap      bool `json:"mmap,omitempty" yaml:"mmap,omitempty" form:"mmap,omitempty"`                      // Mmap: read the database via memory-mapped IO (reducing the syscall overhead of large scans). Example: false
+
+	// MaxAggMem: memory budget (in MiB) for the aggregation of flows. If exceeded, partial aggregates are spilled to
+	// temporary, compressed files and merged at the end of the query instead of failing it. 0 disables the budget. Example: 512
+	MaxAggMem int `json:"max_agg_mem,omitempty" yaml:"max_agg_mem,omitempty" form:"max_agg_mem,omitempty"`
+
+	// IOWorkers: number of processing units reading / decompressing the blocks of an interface in parallel.
+	// 0 uses the number of available CPUs. Example: 4
+	IOWorkers int `json:"io_workers,omitempty" yaml:"io_workers,omitempty" form:"io_workers,omitempty"`
+	// CPUWorkers: number of processing units filtering / aggregating the blocks read by the IO processing units
+	// in parallel. 0 uses the number of available CPUs. Example: 8
+	CPUWorkers int `json:"cpu_workers,omitempty" yaml:"cpu_workers,omitempty" form:"cpu_workers,omitempty"`
 
 	// Caller stores who produced these args (caller). Example: goQuery. Example: goQuery. Example: goQuery. Example: goQuery
 	Caller string `json:"caller,omitempty" yaml:"caller,omitempty" form:"caller,omitempty"`
@@ -100,6 +188,21 @@ type Args struct {
 	// Live can be used to request live flow data (in addition to DB results). Example: false
 	Live bool `json:"live,omitempty" yaml:"live,omitempty" form:"live,omitempty"`
 
+	// Explain returns the execution plan of the query (day partitions / blocks read, column projections, filter
+	// pushdowns, parallelism and memory estimates) instead of running it. Example: false
+	Explain bool `json:"explain,omitempty" yaml:"explain,omitempty" form:"explain,omitempty"`
+
+	// Dedup enables detection of mirrored rows in distributed queries, i.e. the same flow observed by
+	// multiple hosts (e.g. both sides of a link) in inverse directions. Mirrored rows are either merged
+	// (counted once) or flagged. Enum: [merge, flag]. Example: merge
+	Dedup string `json:"dedup,omitempty" yaml:"dedup,omitempty" form:"dedup,omitempty"`
+
+	// Cache controls the use of the per-host sub-results cached by the global-query server (if enabled).
+	// Fresh sub-results are used instead of querying the respective host again, allowing to re-run a
+	// query after e.g. fixing an unreachable host without re-querying all others. Defaults to use.
+	// Enum: [use, refresh, off]. Example: refresh
+	Cache string `json:"cache,omitempty" yaml:"cache,omitempty" form:"cache,omitempty"`
+
 	// outputs is unexported
 	outputs []io.Writer
 }
@@ -302,6 +405,9 @@ func (a *Args) String() string {
 	if a.Condition != "" {
 		str += fmt.Sprintf(", condition: %s", a.Condition)
 	}
+	if a.Having != "" {
+		str += fmt.Sprintf(", having: %s", a.Having)
+	}
 	str += fmt.Sprintf(", limit: %d, from: %s, to: %s",
 		a.NumResults,
 		a.First,
@@ -343,12 +449,30 @@ const (
 	invalidFormatMsg               = "unknown format"
 	invalidSortByMsg               = "unknown format"
 	invalidTimeRangeMsg            = "invalid time range"
+	invalidTimeAlignMsg            = "unknown time alignment"
 	invalidDNSResolutionTimeoutMsg = "invalid resolution timeout"
 	invalidDNSResolutionRowsMsg    = "invalid number of rows"
 	invalidConditionMsg            = "invalid condition"
+	invalidHavingMsg               = "invalid predicate"
 	invalidMaxMemPctMsg            = "invalid max memory percentage"
+	invalidMaxAggMemMsg            = "invalid aggregation memory budget"
+	invalidWorkersMsg              = "invalid number of workers"
 	invalidRowLimitMsg             = "invalid row limit"
 	invalidLiveQueryMsg            = "query not possible"
+	invalidDedupMsg                = "unknown dedup mode"
+	invalidCacheMsg                = "unknown cache mode"
+	invalidCountersMsg             = "invalid counter selection"
+	invalidTimeZoneMsg             = "unknown time zone"
+	invalidTimeFormatMsg           = "invalid time format"
+	invalidHumanReadableMsg        = "human-readable units not possible"
+	invalidDirectionPctMsg         = "direction percentages not possible"
+	invalidInfluxMappingMsg        = "invalid influx mapping"
+	invalidFlowHashMsg             = "flow hash not possible"
+	invalidCommunityIDMsg          = "community ID not possible"
+	invalidRolesMsg                = "role analysis not possible"
+	invalidStatsMsg                = "statistics not possible"
+	invalidAnalysisMsg             = "analysis not possible"
+	invalidSampleMsg               = "sampling not possible"
 )
 
 // Prepare takes the query Arguments, validates them and creates an executable statement. Optionally, additional writers can be passed to route query results to different destinations.
@@ -360,9 +484,23 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 		DNSResolution: a.DNSResolution,
 		Condition:     a.Condition,
 		LowMem:        a.LowMem,
+		Mmap:          a.Mmap,
 		Caller:        a.Caller,
 		Live:          a.Live,
+		Explain:       a.Explain,
 		Output:        os.Stdout, // by default, we write results to the console
+
+		HumanReadable:        a.HumanReadable,
+		DirectionPercentages: a.DirectionPercentages,
+		Numeric:              a.Numeric,
+		FlowHash:             a.FlowHash,
+		CommunityID:          a.CommunityID,
+		CommunityIDSeed:      a.CommunityIDSeed,
+		Roles:                a.Roles,
+		Stats:                a.Stats,
+		Analysis:             a.Analysis,
+		ASNDB:                a.ASNDB,
+		Sample:               a.Sample,
 	}
 
 	// the query type is parsed here already in order to validate if the query contains
@@ -404,12 +542,8 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 
 	}
 
-	// insert iface attribute here in case multiple interfaces where specified and the
-	// interface column was not added as an attribute
-	if (len(s.Ifaces) > 1 || strings.Contains(a.Ifaces, types.AnySelector)) &&
-		!strings.Contains(a.Query, "iface") {
-		selector.Iface = true
-	}
+	// labels (e.g. the interface or host) are only part of the result if they are part of the
+	// query, the traffic is aggregated across them otherwise
 	s.LabelSelector = selector
 
 	// override sorting direction and number of entries for time based queries
@@ -429,6 +563,33 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 		)
 	}
 
+	// verify the time alignment (if any)
+	if a.TimeAlign != "" {
+		if _, verifies := permittedTimeAlignModes[a.TimeAlign]; !verifies {
+			return s, newArgsError(
+				"time_align",
+				invalidTimeAlignMsg,
+				types.NewUnsupportedError(a.TimeAlign, PermittedTimeAlignModes()),
+			)
+		}
+	}
+	s.TimeAlign = a.TimeAlign
+
+	// parse the counter selection. If the counters sorted by aren't selected, sort by the ones that are
+	s.Counters, err = types.ParseCounterSelector(a.Counters)
+	if err != nil {
+		return s, newArgsError(
+			"counters",
+			invalidCountersMsg,
+			err,
+		)
+	}
+	if s.SortBy == results.SortTraffic && !s.Counters.Bytes() {
+		s.SortBy = results.SortPackets
+	} else if s.SortBy == results.SortPackets && !s.Counters.Packets() {
+		s.SortBy = results.SortTraffic
+	}
+
 	switch {
 	case a.Sum:
 		s.Direction = types.DirectionSum
@@ -485,6 +646,34 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 	tokens, _ := conditions.Tokenize(s.Condition)
 	s.Condition = strings.Join(tokens, " ")
 
+	// parse the predicate on the aggregated rows. The one-way analysis merely selects the one-directional
+	// rows (which are printed as usual), hence it is a shortcut for the respective predicate
+	having := a.Having
+	if s.Analysis == AnalysisOneWay {
+		having = joinPredicates(having, oneWayPredicate)
+		s.Analysis = ""
+	}
+	if having != "" {
+		s.having, err = results.ParsePredicate(having)
+		if err != nil {
+			return s, newArgsError(
+				"having",
+				invalidHavingMsg,
+				err,
+			)
+		}
+		for _, colIdx := range s.having.Columns() {
+			if !s.Counters.Has(colIdx) {
+				return s, newArgsError(
+					"having",
+					invalidHavingMsg,
+					fmt.Errorf("counter %s is not selected", types.ColumnFileNames[colIdx]),
+				)
+			}
+		}
+		s.Having = s.having.String()
+	}
+
 	// check memory flag
 	if !(0 < a.MaxMemPct && a.MaxMemPct <= 100) {
 		return s, newArgsError(
@@ -495,6 +684,34 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 	}
 	s.MaxMemPct = a.MaxMemPct
 
+	// check aggregation memory budget
+	if a.MaxAggMem < 0 {
+		return s, newArgsError(
+			"max_agg_mem",
+			invalidMaxAggMemMsg,
+			types.NewMinBoundsError(strconv.Itoa(a.MaxAggMem), "0", true),
+		)
+	}
+	s.MaxAggMem = a.MaxAggMem
+
+	// check parallelism
+	if a.IOWorkers < 0 {
+		return s, newArgsError(
+			"io_workers",
+			invalidWorkersMsg,
+			types.NewMinBoundsError(strconv.Itoa(a.IOWorkers), "0", true),
+		)
+	}
+	s.IOWorkers = a.IOWorkers
+	if a.CPUWorkers < 0 {
+		return s, newArgsError(
+			"cpu_workers",
+			invalidWorkersMsg,
+			types.NewMinBoundsError(strconv.Itoa(a.CPUWorkers), "0", true),
+		)
+	}
+	s.CPUWorkers = a.CPUWorkers
+
 	// check limits flag
 	if a.NumResults <= 0 {
 		return s, newArgsError(
@@ -514,6 +731,152 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 		)
 	}
 
+	// verify dedup mode (if any)
+	if a.Dedup != "" {
+		if _, verifies := permittedDedupModes[a.Dedup]; !verifies {
+			return s, newArgsError(
+				"dedup",
+				invalidDedupMsg,
+				types.NewUnsupportedError(a.Dedup, PermittedDedupModes()),
+			)
+		}
+	}
+	s.Dedup = a.Dedup
+
+	// verify cache mode (if any)
+	if a.Cache != "" {
+		if _, verifies := permittedCacheModes[a.Cache]; !verifies {
+			return s, newArgsError(
+				"cache",
+				invalidCacheMsg,
+				types.NewUnsupportedError(a.Cache, PermittedCacheModes()),
+			)
+		}
+	}
+
+	// verify the time zone and format of printed timestamps (if any)
+	if a.TimeZone != "" {
+		s.location, err = time.LoadLocation(a.TimeZone)
+		if err != nil {
+			return s, newArgsError(
+				"tz",
+				invalidTimeZoneMsg,
+				err,
+			)
+		}
+		s.TimeZone = a.TimeZone
+	}
+	if a.TimeFormat != "" {
+		s.TimeFormat, err = ParseTimeFormat(a.TimeFormat)
+		if err != nil {
+			return s, newArgsError(
+				"time_format",
+				invalidTimeFormatMsg,
+				err,
+			)
+		}
+	}
+
+	// human-readable units and percentages are only rendered by the csv / table printer, the structured
+	// formats always carry the raw counters
+	if s.HumanReadable {
+		if err = validatePrinted(s.Format); err != nil {
+			return s, newArgsError(
+				"human_readable",
+				invalidHumanReadableMsg,
+				err,
+			)
+		}
+	}
+	if s.DirectionPercentages {
+		if err = validatePrinted(s.Format); err != nil {
+			return s, newArgsError(
+				"direction_percentages",
+				invalidDirectionPctMsg,
+				err,
+			)
+		}
+	}
+
+	// the flow hash is only meaningful if it covers the entire flow key
+	if s.FlowHash && !hasFlowKey(s.attributes) {
+		return s, newArgsError(
+			"flow_hash",
+			invalidFlowHashMsg,
+			fmt.Errorf("query must contain the %s, %s, %s and %s attributes", types.SIPName, types.DIPName, types.DportName, types.ProtoName),
+		)
+	}
+	if s.CommunityID && !hasFlowKey(s.attributes) {
+		return s, newArgsError(
+			"community_id",
+			invalidCommunityIDMsg,
+			fmt.Errorf("query must contain the %s, %s, %s and %s attributes", types.SIPName, types.DIPName, types.DportName, types.ProtoName),
+		)
+	}
+
+	// the traffic by role can only be determined if both endpoints of each flow are known
+	if s.Roles {
+		if err = validateRoles(s); err != nil {
+			return s, newArgsError(
+				"roles",
+				invalidRolesMsg,
+				err,
+			)
+		}
+	}
+
+	// the statistics are computed over the time bins, hence the rows mustn't be split by them already
+	if s.Stats {
+		if err = validateStats(s); err != nil {
+			return s, newArgsError(
+				"stats",
+				invalidStatsMsg,
+				err,
+			)
+		}
+	}
+
+	// analyses are evaluated on the entire result, hence the number of rows mustn't be limited
+	if s.Analysis != "" {
+		if err = validateAnalysis(s); err != nil {
+			return s, newArgsError(
+				"analysis",
+				invalidAnalysisMsg,
+				err,
+			)
+		}
+		s.NumResults = MaxResults
+	}
+
+	// the sample replaces sorting / limiting the rows, hence the number of rows mustn't be limited any further
+	if s.Sample < 0 {
+		return s, newArgsError(
+			"sample",
+			invalidSampleMsg,
+			types.NewMinBoundsError(strconv.Itoa(s.Sample), "0", true),
+		)
+	}
+	if s.Sample > 0 {
+		if s.Analysis != "" {
+			return s, newArgsError(
+				"sample",
+				invalidSampleMsg,
+				fmt.Errorf("the %s analysis requires the entire result", s.Analysis),
+			)
+		}
+		s.NumResults = MaxResults
+	}
+
+	// verify the mapping of flows to line protocol (if any)
+	if err = a.Influx.Validate(); err != nil {
+		return s, newArgsError(
+			"influx",
+			invalidInfluxMappingMsg,
+			err,
+		)
+	}
+	s.Influx = a.Influx
+
 	// fan-out query results in case multiple writers were supplied
 	writers = append(writers, a.outputs...)
 	if len(writers) > 0 {
@@ -522,3 +885,114 @@ func (a *Args) Prepare(writers ...io.Writer) (*Statement, error) {
 
 	return s, nil
 }
+
+// joinPredicates combines two predicates via a conjunction (if both are set)
+func joinPredicates(p1, p2 string) string {
+	if p1 == "" {
+		return p2
+	}
+	return fmt.Sprintf("(%s) & (%s)", p1, p2)
+}
+
+// validatePrinted checks if a format is rendered by the csv / table printer
+func validatePrinted(format string) error {
+	switch format {
+	case "csv", "txt":
+		return nil
+	}
+	return types.NewUnsupportedError(format, []string{"csv", "txt"})
+}
+
+func validateRoles(s *Statement) error {
+	var hasSIP, hasDIP bool
+	for _, attribute := range s.attributes {
+		switch attribute.Name() {
+		case types.SIPName:
+			hasSIP = true
+		case types.DIPName:
+			hasDIP = true
+		}
+	}
+	if !hasSIP || !hasDIP {
+		return fmt.Errorf("query must contain the %s and %s attributes", types.SIPName, types.DIPName)
+	}
+	switch s.Format {
+	case "json", "csv", "txt":
+	default:
+		return fmt.Errorf("format %s does not support the traffic by role", s.Format)
+	}
+	if s.FlowHash {
+		return errors.New("flow hash requires the traffic by flow")
+	}
+	if s.CommunityID {
+		return errors.New("community ID requires the traffic by flow")
+	}
+	if s.Dedup != "" {
+		return errors.New("mirrored rows cannot be detected on the traffic by host")
+	}
+	return nil
+}
+
+func validateStats(s *Statement) error {
+	if s.LabelSelector.Timestamp {
+		return fmt.Errorf("query must not contain the %s attribute", types.TimeName)
+	}
+	switch s.Format {
+	case "json", "csv", "txt":
+	default:
+		return fmt.Errorf("format %s does not support the statistics", s.Format)
+	}
+	if !s.Counters.Bytes() {
+		return errors.New("statistics require the byte counters")
+	}
+	if s.Roles {
+		return errors.New("statistics require the traffic by flow")
+	}
+	if s.Analysis != "" {
+		return fmt.Errorf("the %s analysis replaces the rows", s.Analysis)
+	}
+	return nil
+}
+
+func validateAnalysis(s *Statement) error {
+	if _, verifies := permittedAnalysisModes[s.Analysis]; !verifies {
+		return types.NewUnsupportedError(s.Analysis, PermittedAnalysisModes())
+	}
+
+	// the ASN matrix is the only analysis mode so far
+	var hasSIP, hasDIP bool
+	for _, attribute := range s.attributes {
+		switch attribute.Name() {
+		case types.SIPName:
+			hasSIP = true
+		case types.DIPName:
+			hasDIP = true
+		}
+	}
+	if !hasSIP || !hasDIP {
+		return fmt.Errorf("query must contain the %s and %s attributes", types.SIPName, types.DIPName)
+	}
+	switch s.Format {
+	case "json", "csv":
+	default:
+		return fmt.Errorf("format %s does not support the %s analysis", s.Format, s.Analysis)
+	}
+	if s.ASNDB == "" {
+		return errors.New("no ASN database provided")
+	}
+	if s.Roles {
+		return errors.New("role analysis requires the traffic by host")
+	}
+	return nil
+}
+
+func hasFlowKey(attributes []types.Attribute) bool {
+	var found int
+	for _, attribute := range attributes {
+		switch attribute.Name() {
+		case types.SIPName, types.DIPName, types.DportName, types.ProtoName:
+			found++
+		}
+	}
+	return found == 4
+}
@@ -200,6 +200,28 @@ func TestPrepareArgs(t *testing.T) {
 				Type:    fmt.Sprintf("%T", &types.RangeError{}),
 			},
 		},
+		{"negative aggregation memory budget",
+			&Args{
+				Query: "sip,time", Format: "json", First: "-7d",
+				MaxMemPct: 20, MaxAggMem: -1,
+			},
+			&ArgsError{
+				Field:   "max_agg_mem",
+				Message: invalidMaxAggMemMsg,
+				Type:    fmt.Sprintf("%T", &types.MinBoundsError{}),
+			},
+		},
+		{"negative number of CPU workers",
+			&Args{
+				Query: "sip,time", Format: "json", First: "-7d",
+				MaxMemPct: 20, CPUWorkers: -1,
+			},
+			&ArgsError{
+				Field:   "cpu_workers",
+				Message: invalidWorkersMsg,
+				Type:    fmt.Sprintf("%T", &types.MinBoundsError{}),
+			},
+		},
 		{"wrong number of results",
 			&Args{
 				Query: "sip,time", Format: "json", First: "-7d",
@@ -223,6 +245,266 @@ func TestPrepareArgs(t *testing.T) {
 				Type:    "*errors.errorString",
 			},
 		},
+		{"unknown dedup mode",
+			&Args{
+				Query: "sip,time", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Dedup: "drop",
+			},
+			&ArgsError{
+				Field:   "dedup",
+				Message: invalidDedupMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"unknown cache mode",
+			&Args{
+				Query: "sip,time", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Cache: "always",
+			},
+			&ArgsError{
+				Field:   "cache",
+				Message: invalidCacheMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"unknown time alignment",
+			&Args{
+				Query: "sip,time", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				TimeAlign: "round",
+			},
+			&ArgsError{
+				Field:   "time_align",
+				Message: invalidTimeAlignMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"invalid counters",
+			&Args{
+				Query: "sip,time", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Counters: "bytes,flows",
+			},
+			&ArgsError{
+				Field:   "counters",
+				Message: invalidCountersMsg,
+				Type:    fmt.Sprintf("%T", &types.ParseError{}),
+			},
+		},
+		{"unknown time zone",
+			&Args{
+				Query: "sip,time", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				TimeZone: "Mars/Olympus_Mons",
+			},
+			&ArgsError{
+				Field:   "tz",
+				Message: invalidTimeZoneMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"invalid time format",
+			&Args{
+				Query: "sip,time", Format: "csv", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				TimeFormat: "iso",
+			},
+			&ArgsError{
+				Field:   "time_format",
+				Message: invalidTimeFormatMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"flow hash without flow key",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				FlowHash: true,
+			},
+			&ArgsError{
+				Field:   "flow_hash",
+				Message: invalidFlowHashMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"community ID without flow key",
+			&Args{
+				Query: "sip,dip,dport", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				CommunityID: true,
+			},
+			&ArgsError{
+				Field:   "community_id",
+				Message: invalidCommunityIDMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"roles without dip",
+			&Args{
+				Query: "sip,dport", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Roles: true,
+			},
+			&ArgsError{
+				Field:   "roles",
+				Message: invalidRolesMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"roles with unsupported format",
+			&Args{
+				Query: "sip,dip", Format: "influxdb", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Roles: true,
+			},
+			&ArgsError{
+				Field:   "roles",
+				Message: invalidRolesMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"stats with time attribute",
+			&Args{
+				Query: "time,sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Stats: true,
+			},
+			&ArgsError{
+				Field:   "stats",
+				Message: invalidStatsMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"stats without byte counters",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d", Counters: "packets", SortBy: "packets",
+				MaxMemPct: 20, NumResults: 20,
+				Stats: true,
+			},
+			&ArgsError{
+				Field:   "stats",
+				Message: invalidStatsMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"unknown analysis mode",
+			&Args{
+				Query: "sip,dip", Format: "csv", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Analysis: "as-path", ASNDB: "/tmp/asn.txt",
+			},
+			&ArgsError{
+				Field:   "analysis",
+				Message: invalidAnalysisMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"asn matrix without ASN database",
+			&Args{
+				Query: "sip,dip", Format: "csv", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Analysis: AnalysisASNMatrix,
+			},
+			&ArgsError{
+				Field:   "analysis",
+				Message: invalidAnalysisMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"asn matrix with unsupported format",
+			&Args{
+				Query: "sip,dip", Format: "txt", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Analysis: AnalysisASNMatrix, ASNDB: "/tmp/asn.txt",
+			},
+			&ArgsError{
+				Field:   "analysis",
+				Message: invalidAnalysisMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"negative sample size",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Sample: -1,
+			},
+			&ArgsError{
+				Field:   "sample",
+				Message: invalidSampleMsg,
+				Type:    fmt.Sprintf("%T", &types.MinBoundsError{}),
+			},
+		},
+		{"sample with asn matrix",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Analysis: AnalysisASNMatrix, ASNDB: "/tmp/asn.txt", Sample: 100,
+			},
+			&ArgsError{
+				Field:   "sample",
+				Message: invalidSampleMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"invalid predicate",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Having: "ratio(bytes_in) > 100",
+			},
+			&ArgsError{
+				Field:   "having",
+				Message: invalidHavingMsg,
+				Type:    fmt.Sprintf("%T", &types.ParseError{}),
+			},
+		},
+		{"predicate on unselected counter",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Counters: "bytes", Analysis: AnalysisOneWay,
+			},
+			&ArgsError{
+				Field:   "having",
+				Message: invalidHavingMsg,
+				Type:    "*errors.errorString",
+			},
+		},
+		{"human-readable units with json format",
+			&Args{
+				Query: "sip,dip", Format: "json", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				HumanReadable: true,
+			},
+			&ArgsError{
+				Field:   "human_readable",
+				Message: invalidHumanReadableMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"direction percentages with influxdb format",
+			&Args{
+				Query: "sip,dip", Format: "influxdb", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				DirectionPercentages: true,
+			},
+			&ArgsError{
+				Field:   "direction_percentages",
+				Message: invalidDirectionPctMsg,
+				Type:    fmt.Sprintf("%T", &types.UnsupportedError{}),
+			},
+		},
+		{"human-readable units and direction percentages with csv format",
+			&Args{
+				Query: "sip,dip", Format: "csv", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				HumanReadable: true, DirectionPercentages: true,
+			},
+			nil,
+		},
 		{"valid query args",
 			&Args{
 				Query: "sip,time", Format: "json", Last: "-7d",
@@ -257,3 +539,35 @@ func TestPrepareArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestPrepareHaving(t *testing.T) {
+	var tests = []struct {
+		name     string
+		having   string
+		analysis string
+		expected string
+	}{
+		{"predicate", "ratio(bytes_in,bytes_out) > 100 and packets_out == 0", "", "ratio(bytes_in, bytes_out) > 100 & packets_out = 0"},
+		{"one-way analysis", "", AnalysisOneWay, "packets_in = 0 | packets_out = 0"},
+		{"one-way analysis with predicate", "bytes > 1000", AnalysisOneWay, "bytes > 1000 & (packets_in = 0 | packets_out = 0)"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			args := &Args{
+				Query: "sip,dip", Format: "txt", Last: "-7d",
+				MaxMemPct: 20, NumResults: 20,
+				Having: test.having, Analysis: test.analysis,
+			}
+			stmt, err := args.Prepare()
+			require.Nil(t, err)
+			require.Equal(t, test.expected, stmt.Having)
+			require.Equal(t, test.expected, stmt.Predicate().String())
+
+			// the rows selected by the one-way analysis are printed as usual (and not limited)
+			require.Empty(t, stmt.Analysis)
+			require.Equal(t, uint64(20), stmt.NumResults)
+		})
+	}
+}
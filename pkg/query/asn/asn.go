@@ -0,0 +1,132 @@
+// Package asn provides the mapping of IP addresses to autonomous systems (ASNs) used by goQuery's
+// analysis modes
+package asn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Unknown denotes the (reserved) ASN assigned to addresses not covered by the database
+const Unknown uint32 = 0
+
+// AS denotes an autonomous system
+type AS struct {
+	Number uint32 `json:"asn"`            // Number: the number of the autonomous system. Example: 13335
+	Name   string `json:"name,omitempty"` // Name: the name / description of the autonomous system. Example: CLOUDFLARENET
+}
+
+// String returns the canonical (AS-prefixed) notation of the autonomous system number
+func (a AS) String() string {
+	return "AS" + strconv.FormatUint(uint64(a.Number), 10)
+}
+
+// DB maps IP prefixes to the autonomous systems announcing them
+type DB struct {
+	prefixes map[netip.Prefix]AS
+
+	// prefix lengths present in the database, longest first (for the longest prefix match)
+	bits []int
+}
+
+// Load reads an ASN database from a file, see Parse for the format
+func Load(path string) (*DB, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	db, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ASN database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Parse reads an ASN database, consisting of one prefix per line, followed by the number (with
+// or without the "AS" prefix) and optionally the name of the autonomous system announcing it, e.g.
+//
+//	# prefix        asn      name
+//	1.1.1.0/24      13335    CLOUDFLARENET
+//	2606:4700::/32  AS13335  CLOUDFLARENET
+//
+// Empty lines and lines starting with '#' are ignored. Addresses covered by more than one prefix
+// are assigned to the autonomous system of the most specific one
+func Parse(r io.Reader) (*DB, error) {
+	db := &DB{
+		prefixes: make(map[netip.Prefix]AS),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected prefix and ASN, got %q", n, line)
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(fields[1]), "AS"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ASN %q", n, fields[1])
+		}
+		db.add(prefix, AS{
+			Number: uint32(number),
+			Name:   strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) add(prefix netip.Prefix, as AS) {
+	prefix = prefix.Masked()
+	if _, exists := db.prefixes[prefix]; !exists {
+		if bits := prefix.Bits(); !slices.Contains(db.bits, bits) {
+			db.bits = append(db.bits, bits)
+			slices.SortFunc(db.bits, func(a, b int) int { return b - a })
+		}
+	}
+	db.prefixes[prefix] = as
+}
+
+// Len returns the number of prefixes in the database
+func (db *DB) Len() int {
+	return len(db.prefixes)
+}
+
+// Lookup returns the autonomous system announcing the most specific prefix covering addr
+func (db *DB) Lookup(addr netip.Addr) (AS, bool) {
+	addr = addr.Unmap()
+	for _, bits := range db.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if as, exists := db.prefixes[prefix]; exists {
+			return as, true
+		}
+	}
+	return AS{Number: Unknown}, false
+}
@@ -0,0 +1,56 @@
+package asn
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDB = `
+# prefix        asn      name
+10.0.0.0/8      64512    PRIVATE-A
+10.1.0.0/16     AS64513  PRIVATE A SUBNET
+1.1.1.7/24      13335    CLOUDFLARENET
+2606:4700::/32  as13335  CLOUDFLARENET
+`
+
+func TestLookup(t *testing.T) {
+	db, err := Parse(strings.NewReader(testDB))
+	require.Nil(t, err)
+	require.Equal(t, 4, db.Len())
+
+	for _, c := range []struct {
+		addr     string
+		expected AS
+		found    bool
+	}{
+		{"10.2.3.4", AS{64512, "PRIVATE-A"}, true},
+		{"10.1.3.4", AS{64513, "PRIVATE A SUBNET"}, true},
+		{"::ffff:10.1.3.4", AS{64513, "PRIVATE A SUBNET"}, true},
+		{"1.1.1.1", AS{13335, "CLOUDFLARENET"}, true},
+		{"2606:4700:10::1", AS{13335, "CLOUDFLARENET"}, true},
+		{"192.168.1.1", AS{Number: Unknown}, false},
+		{"2001:db8::1", AS{Number: Unknown}, false},
+	} {
+		t.Run(c.addr, func(t *testing.T) {
+			as, found := db.Lookup(netip.MustParseAddr(c.addr))
+			require.Equal(t, c.found, found)
+			require.Equal(t, c.expected, as)
+		})
+	}
+	require.Equal(t, "AS13335", AS{Number: 13335}.String())
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"10.0.0.0/8",
+		"10.0.0.0/33 64512",
+		"10.0.0.0/8 ASX",
+		"10.0.0.0/8 4294967296",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,107 @@
+package asn
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// MatrixEntry stores the traffic between a source and a destination autonomous system
+type MatrixEntry struct {
+	Src      AS             `json:"src"`      // Src: the autonomous system of the source IPs (sip)
+	Dst      AS             `json:"dst"`      // Dst: the autonomous system of the destination IPs (dip)
+	Counters types.Counters `json:"counters"` // Counters: the traffic of all flows from Src to Dst
+}
+
+// Matrix denotes the traffic between all pairs of source and destination autonomous systems
+// observed over the time range of a result, e.g. for peering and transit cost analysis
+type Matrix struct {
+	results.TimeRange
+	Totals  types.Counters `json:"totals"`  // Totals: the total traffic covered by the matrix
+	Entries []MatrixEntry  `json:"entries"` // Entries: the traffic between each pair of autonomous systems, sorted by data volume (descending)
+}
+
+type matrixKey struct {
+	src, dst uint32
+}
+
+// NewMatrix aggregates the rows of a result by the autonomous systems of their source and
+// destination IPs. Rows must contain both the sip and dip attributes, addresses not covered
+// by the database are assigned to the Unknown ASN
+func NewMatrix(db *DB, result *results.Result) *Matrix {
+	var (
+		entries = make(map[matrixKey]*MatrixEntry)
+		totals  types.Counters
+	)
+	for _, row := range result.Rows {
+		src, _ := db.Lookup(row.Attributes.SrcIP)
+		dst, _ := db.Lookup(row.Attributes.DstIP)
+
+		key := matrixKey{src.Number, dst.Number}
+		entry, exists := entries[key]
+		if !exists {
+			entry = &MatrixEntry{Src: src, Dst: dst}
+			entries[key] = entry
+		}
+		entry.Counters = entry.Counters.Add(row.Counters)
+		totals = totals.Add(row.Counters)
+	}
+
+	m := &Matrix{
+		TimeRange: result.Summary.TimeRange,
+		Totals:    totals,
+		Entries:   make([]MatrixEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		m.Entries = append(m.Entries, *entry)
+	}
+	slices.SortFunc(m.Entries, func(a, b MatrixEntry) int {
+		if c := cmp.Compare(b.Counters.SumBytes(), a.Counters.SumBytes()); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Src.Number, b.Src.Number); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Dst.Number, b.Dst.Number)
+	})
+	return m
+}
+
+// PrintJSON writes the matrix in JSON format
+func (m *Matrix) PrintJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// PrintCSV writes the matrix in CSV format, one line per pair of autonomous systems
+func (m *Matrix) PrintCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"src_asn", "src_as_name", "dst_asn", "dst_as_name",
+		"packets received", "packets sent", "packets",
+		"data vol. received", "data vol. sent", "data vol.",
+	}); err != nil {
+		return err
+	}
+
+	format := func(n uint64) string {
+		return strconv.FormatUint(n, 10)
+	}
+	for _, entry := range m.Entries {
+		if err := writer.Write([]string{
+			format(uint64(entry.Src.Number)), entry.Src.Name,
+			format(uint64(entry.Dst.Number)), entry.Dst.Name,
+			format(entry.Counters.PacketsRcvd), format(entry.Counters.PacketsSent), format(entry.Counters.SumPackets()),
+			format(entry.Counters.BytesRcvd), format(entry.Counters.BytesSent), format(entry.Counters.SumBytes()),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
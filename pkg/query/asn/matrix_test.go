@@ -0,0 +1,56 @@
+package asn
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrix(t *testing.T) {
+	db, err := Parse(strings.NewReader(testDB))
+	require.Nil(t, err)
+
+	row := func(sip, dip string, bytesRcvd, bytesSent uint64) results.Row {
+		return results.Row{
+			Attributes: results.Attributes{
+				SrcIP: netip.MustParseAddr(sip),
+				DstIP: netip.MustParseAddr(dip),
+			},
+			Counters: types.Counters{BytesRcvd: bytesRcvd, BytesSent: bytesSent, PacketsRcvd: 1, PacketsSent: 1},
+		}
+	}
+	result := &results.Result{
+		Rows: results.Rows{
+			row("10.2.0.1", "1.1.1.1", 100, 10),
+			row("10.3.0.1", "1.1.1.2", 200, 20),
+			row("10.1.0.1", "2606:4700::1", 50, 5),
+			row("192.168.1.1", "10.1.0.1", 1000, 0),
+		},
+	}
+
+	m := NewMatrix(db, result)
+	require.Equal(t, []MatrixEntry{
+		{Src: AS{Number: Unknown}, Dst: AS{64513, "PRIVATE A SUBNET"}, Counters: types.Counters{BytesRcvd: 1000, PacketsRcvd: 1, PacketsSent: 1}},
+		{Src: AS{64512, "PRIVATE-A"}, Dst: AS{13335, "CLOUDFLARENET"}, Counters: types.Counters{BytesRcvd: 300, BytesSent: 30, PacketsRcvd: 2, PacketsSent: 2}},
+		{Src: AS{64513, "PRIVATE A SUBNET"}, Dst: AS{13335, "CLOUDFLARENET"}, Counters: types.Counters{BytesRcvd: 50, BytesSent: 5, PacketsRcvd: 1, PacketsSent: 1}},
+	}, m.Entries)
+	require.Equal(t, uint64(1385), m.Totals.SumBytes())
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, m.PrintCSV(buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, "64512,PRIVATE-A,13335,CLOUDFLARENET,2,2,4,300,30,330", lines[2])
+
+	buf.Reset()
+	require.Nil(t, m.PrintJSON(buf))
+	var decoded Matrix
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, m.Entries, decoded.Entries)
+}
@@ -0,0 +1,307 @@
+// Package audit records every executed query (who ran it, when, with which parameters, which hosts
+// were touched, how many rows were returned and how long it took) in a structured audit log. The
+// most recent entries are kept in memory so they can be queried (e.g. via an API endpoint), and all
+// entries can be forwarded to one or more sinks (file, syslog, webhook) for long-term retention
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// DefaultHistorySize denotes the default number of entries kept in memory
+	DefaultHistorySize = 1000
+
+	// DefaultTenantHeader denotes the default request header identifying the tenant running a query
+	DefaultTenantHeader = "X-GOPROBE-TENANT"
+
+	queueSize = 256
+)
+
+// ErrLogClosed is returned if an entry is recorded after the log has been closed
+var ErrLogClosed = errors.New("audit log closed")
+
+// Status denotes the outcome of an audited query
+type Status string
+
+const (
+	// StatusOK denotes a query that completed successfully
+	StatusOK Status = "ok"
+	// StatusFailed denotes a query that failed
+	StatusFailed Status = "failed"
+)
+
+// Identity describes who ran a query
+type Identity struct {
+	Tenant     string // Tenant: the tenant the query was run on behalf of
+	RemoteAddr string // RemoteAddr: the address the query originated from
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity of whoever runs a query
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity carried by the context (if any)
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Entry describes a single executed query
+type Entry struct {
+	Time       time.Time     `json:"time"`                  // Time: the time the query was started. Example: "2024-02-01T06:00:00Z"
+	Tenant     string        `json:"tenant,omitempty"`      // Tenant: the tenant the query was run on behalf of. Example: "soc"
+	Caller     string        `json:"caller,omitempty"`      // Caller: the caller provided in the query args. Example: "goQuery"
+	RemoteAddr string        `json:"remote_addr,omitempty"` // RemoteAddr: the address the query originated from. Example: "10.0.0.1"
+	Args       *query.Args   `json:"args"`                  // Args: the query arguments
+	Hosts      []string      `json:"hosts,omitempty"`       // Hosts: the hosts touched by the query. Example: ["hostA", "hostB"]
+	Rows       int           `json:"rows"`                  // Rows: the number of rows returned. Example: 25
+	Duration   time.Duration `json:"duration_ns"`           // Duration: the duration of the query in nanoseconds
+	Status     Status        `json:"status"`                // Status: the outcome of the query. Example: "ok"
+	Error      string        `json:"error,omitempty"`       // Error: the error that occurred while running the query
+}
+
+// Filter restricts the entries returned from the log. Zero values match all entries
+type Filter struct {
+	Tenant string    `json:"tenant,omitempty" form:"tenant"`                                       // Tenant: only return entries of this tenant. Example: "soc"
+	Caller string    `json:"caller,omitempty" form:"caller"`                                       // Caller: only return entries of this caller. Example: "goQuery"
+	Since  time.Time `json:"since,omitempty" form:"since" time_format:"2006-01-02T15:04:05Z07:00"` // Since: only return entries started at or after this time
+	Until  time.Time `json:"until,omitempty" form:"until" time_format:"2006-01-02T15:04:05Z07:00"` // Until: only return entries started before this time
+	Limit  int       `json:"limit,omitempty" form:"limit"`                                         // Limit: the maximum number of entries returned (most recent first). Example: 100
+}
+
+func (f *Filter) matches(e *Entry) bool {
+	if f.Tenant != "" && e.Tenant != f.Tenant {
+		return false
+	}
+	if f.Caller != "" && e.Caller != f.Caller {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Log keeps the most recent audit entries in memory and forwards all entries to its sinks
+type Log struct {
+	mu          sync.RWMutex
+	entries     []Entry
+	historySize int
+
+	sinkConfigs []SinkConfig
+	sinks       []sink
+
+	queue     chan Entry
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    bool
+}
+
+// Option configures the audit log
+type Option func(*Log)
+
+// WithHistorySize sets the number of entries kept in memory
+func WithHistorySize(n int) Option {
+	return func(l *Log) {
+		if n > 0 {
+			l.historySize = n
+		}
+	}
+}
+
+// WithSinks sets the sinks all entries are forwarded to
+func WithSinks(sinks ...SinkConfig) Option {
+	return func(l *Log) {
+		l.sinkConfigs = append(l.sinkConfigs, sinks...)
+	}
+}
+
+// New creates a new audit log. Entries are forwarded to the sinks in the background until the log
+// is closed
+func New(ctx context.Context, opts ...Option) (*Log, error) {
+	l := &Log{
+		historySize: DefaultHistorySize,
+		queue:       make(chan Entry, queueSize),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	for i, cfg := range l.sinkConfigs {
+		s, err := cfg.open()
+		if err != nil {
+			for _, opened := range l.sinks {
+				_ = opened.close()
+			}
+			return nil, fmt.Errorf("failed to open audit sink %d (%s): %w", i, cfg.String(), err)
+		}
+		l.sinks = append(l.sinks, s)
+	}
+
+	go l.forward(ctx)
+
+	return l, nil
+}
+
+// Record adds an entry to the log. If the sinks cannot keep up, Record blocks until the entry
+// has been queued, so no entry is lost
+func (l *Log) Record(entry Entry) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrLogClosed
+	}
+	l.entries = append([]Entry{entry}, l.entries...)
+	if len(l.entries) > l.historySize {
+		l.entries = l.entries[:l.historySize]
+	}
+	l.mu.Unlock()
+
+	if len(l.sinks) == 0 {
+		return nil
+	}
+
+	// the read lock guards against the queue being closed concurrently
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return ErrLogClosed
+	}
+	l.queue <- entry
+	return nil
+}
+
+// Entries returns all entries kept in memory matching the filter, most recent first
+func (l *Log) Entries(filter Filter) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]Entry, 0)
+	for i := range l.entries {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		if filter.matches(&l.entries[i]) {
+			entries = append(entries, l.entries[i])
+		}
+	}
+	return entries
+}
+
+// Close stops accepting new entries, waits for all queued entries to be forwarded and closes
+// all sinks
+func (l *Log) Close() (err error) {
+	l.closeOnce.Do(func() {
+		l.mu.Lock()
+		l.closed = true
+		close(l.queue)
+		l.mu.Unlock()
+
+		<-l.done
+		for _, s := range l.sinks {
+			err = errors.Join(err, s.close())
+		}
+	})
+	return err
+}
+
+func (l *Log) forward(ctx context.Context) {
+	defer close(l.done)
+
+	// queued entries are still forwarded while the log is closed during shutdown
+	ctx = context.WithoutCancel(ctx)
+
+	logger := logging.FromContext(ctx)
+	for entry := range l.queue {
+		for i, s := range l.sinks {
+			if err := s.write(ctx, &entry); err != nil {
+				logger.With("error", err, "sink", l.sinkConfigs[i].String()).Error("failed to forward audit entry")
+			}
+		}
+	}
+}
+
+// Runner wraps a query runner, recording every query run in an audit log
+type Runner struct {
+	runner query.Runner
+	log    *Log
+}
+
+// NewRunner creates a new auditing query runner
+func NewRunner(runner query.Runner, log *Log) *Runner {
+	return &Runner{
+		runner: runner,
+		log:    log,
+	}
+}
+
+// Run executes the query using the underlying runner and records it in the audit log. The
+// identity of whoever runs the query is taken from the context (see WithIdentity)
+func (r *Runner) Run(ctx context.Context, args *query.Args) (*results.Result, error) {
+	start := time.Now()
+	result, err := r.runner.Run(ctx, args)
+
+	identity, _ := IdentityFromContext(ctx)
+	entry := Entry{
+		Time:       start,
+		Tenant:     identity.Tenant,
+		RemoteAddr: identity.RemoteAddr,
+		Duration:   time.Since(start),
+		Status:     StatusOK,
+	}
+	if args != nil {
+		entry.Caller = args.Caller
+		argsCopy := *args
+		entry.Args = &argsCopy
+	}
+	queryErr := err
+	if result != nil {
+		entry.Hosts = hosts(result)
+		entry.Rows = len(result.Rows)
+		if queryErr == nil {
+			queryErr = result.Err()
+		}
+	}
+	if queryErr != nil {
+		entry.Status = StatusFailed
+		entry.Error = queryErr.Error()
+	}
+
+	if rerr := r.log.Record(entry); rerr != nil {
+		logging.FromContext(ctx).With("error", rerr).Error("failed to record audit entry")
+	}
+	return result, err
+}
+
+// hosts returns the (sorted) hosts touched by a query
+func hosts(result *results.Result) []string {
+	if len(result.HostsStatuses) == 0 {
+		if result.Hostname != "" {
+			return []string{result.Hostname}
+		}
+		return nil
+	}
+	hosts := make([]string, 0, len(result.HostsStatuses))
+	for host := range result.HostsStatuses {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
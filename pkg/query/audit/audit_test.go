@@ -0,0 +1,211 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRunner struct {
+	err error
+}
+
+func (m *mockRunner) Run(_ context.Context, _ *query.Args) (*results.Result, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &results.Result{
+		Status: results.Status{Code: types.StatusOK},
+		HostsStatuses: results.HostsStatuses{
+			"hostB": results.Status{Code: types.StatusOK},
+			"hostA": results.Status{Code: types.StatusEmpty},
+		},
+		Rows: results.Rows{
+			{Attributes: results.Attributes{DstPort: 443}},
+			{Attributes: results.Attributes{DstPort: 80}},
+		},
+	}, nil
+}
+
+func testArgs(caller string) *query.Args {
+	args := query.DefaultArgs()
+	args.Query = "dport"
+	args.Ifaces = "eth0"
+	args.Caller = caller
+	return args
+}
+
+func TestRunner(t *testing.T) {
+	log, err := New(context.Background(), WithHistorySize(2))
+	require.Nil(t, err)
+	defer func() {
+		require.Nil(t, log.Close())
+	}()
+
+	ctx := WithIdentity(context.Background(), Identity{Tenant: "soc", RemoteAddr: "10.0.0.1"})
+	_, err = NewRunner(&mockRunner{}, log).Run(ctx, testArgs("goQuery"))
+	require.Nil(t, err)
+
+	entries := log.Entries(Filter{})
+	require.Len(t, entries, 1)
+	require.Equal(t, "soc", entries[0].Tenant)
+	require.Equal(t, "10.0.0.1", entries[0].RemoteAddr)
+	require.Equal(t, "goQuery", entries[0].Caller)
+	require.Equal(t, "dport", entries[0].Args.Query)
+	require.Equal(t, []string{"hostA", "hostB"}, entries[0].Hosts)
+	require.Equal(t, 2, entries[0].Rows)
+	require.Equal(t, StatusOK, entries[0].Status)
+
+	// failed queries must be recorded as well, without altering the error returned
+	queryErr := errors.New("query failed")
+	_, err = NewRunner(&mockRunner{err: queryErr}, log).Run(context.Background(), testArgs("gpctl"))
+	require.ErrorIs(t, err, queryErr)
+
+	entries = log.Entries(Filter{})
+	require.Len(t, entries, 2)
+	require.Equal(t, StatusFailed, entries[0].Status)
+	require.Equal(t, queryErr.Error(), entries[0].Error)
+	require.Empty(t, entries[0].Tenant)
+
+	// the history is bounded
+	_, err = NewRunner(&mockRunner{}, log).Run(ctx, testArgs("goQuery"))
+	require.Nil(t, err)
+	entries = log.Entries(Filter{})
+	require.Len(t, entries, 2)
+	require.Equal(t, StatusOK, entries[0].Status)
+	require.Equal(t, StatusFailed, entries[1].Status)
+}
+
+func TestFilter(t *testing.T) {
+	log, err := New(context.Background())
+	require.Nil(t, err)
+	defer func() {
+		require.Nil(t, log.Close())
+	}()
+
+	start := time.Unix(1706745600, 0)
+	for i, tenant := range []string{"soc", "noc", "soc", "noc"} {
+		require.Nil(t, log.Record(Entry{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Tenant: tenant,
+			Caller: "goQuery",
+		}))
+	}
+
+	var tests = []struct {
+		name     string
+		filter   Filter
+		expected []int // minute offsets of the expected entries
+	}{
+		{"all", Filter{}, []int{3, 2, 1, 0}},
+		{"tenant", Filter{Tenant: "soc"}, []int{2, 0}},
+		{"caller", Filter{Caller: "gpctl"}, []int{}},
+		{"since", Filter{Since: start.Add(2 * time.Minute)}, []int{3, 2}},
+		{"until", Filter{Until: start.Add(2 * time.Minute)}, []int{1, 0}},
+		{"limit", Filter{Tenant: "noc", Limit: 1}, []int{3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := log.Entries(test.filter)
+			require.Len(t, entries, len(test.expected))
+			for i, offset := range test.expected {
+				require.Equal(t, start.Add(time.Duration(offset)*time.Minute), entries[i].Time)
+			}
+		})
+	}
+}
+
+func TestSinks(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Entry
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+
+		var entry Entry
+		require.Nil(t, jsoniter.Unmarshal(body, &entry))
+
+		mu.Lock()
+		received = append(received, entry)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "audit", "queries.log")
+	log, err := New(context.Background(), WithSinks(
+		SinkConfig{File: &FileSinkConfig{Path: path}},
+		SinkConfig{Webhook: &push.Target{URL: srv.URL}},
+	))
+	require.Nil(t, err)
+
+	for _, tenant := range []string{"soc", "noc"} {
+		require.Nil(t, log.Record(Entry{Tenant: tenant, Status: StatusOK}))
+	}
+
+	// closing the log flushes all queued entries
+	require.Nil(t, log.Close())
+	require.ErrorIs(t, log.Record(Entry{}), ErrLogClosed)
+
+	f, err := os.Open(path)
+	require.Nil(t, err)
+	defer f.Close()
+
+	var tenants []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		require.Nil(t, jsoniter.Unmarshal(scanner.Bytes(), &entry))
+		tenants = append(tenants, entry.Tenant)
+	}
+	require.Nil(t, scanner.Err())
+	require.Equal(t, []string{"soc", "noc"}, tenants)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	require.Equal(t, "soc", received[0].Tenant)
+}
+
+func TestSinkValidation(t *testing.T) {
+	var tests = []struct {
+		name  string
+		sink  SinkConfig
+		valid bool
+	}{
+		{"none", SinkConfig{}, false},
+		{"file", SinkConfig{File: &FileSinkConfig{Path: "/var/log/goprobe/audit.log"}}, true},
+		{"file without path", SinkConfig{File: &FileSinkConfig{}}, false},
+		{"local syslog", SinkConfig{Syslog: &SyslogSinkConfig{}}, true},
+		{"remote syslog", SinkConfig{Syslog: &SyslogSinkConfig{Network: "udp", Address: "localhost:514"}}, true},
+		{"syslog without address", SinkConfig{Syslog: &SyslogSinkConfig{Network: "udp"}}, false},
+		{"webhook", SinkConfig{Webhook: &push.Target{URL: "https://example.com/audit"}}, true},
+		{"multiple", SinkConfig{File: &FileSinkConfig{Path: "audit.log"}, Syslog: &SyslogSinkConfig{}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.sink.Validate()
+			if test.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
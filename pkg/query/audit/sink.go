@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/syslog"
+	"os"
+	"path/filepath"
+
+	"github.com/els0r/goProbe/pkg/query/push"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const defaultSyslogTag = "goprobe-audit"
+
+var (
+	errorNoSink          = errors.New("exactly one of file, syslog or webhook must be configured per sink")
+	errorNoPath          = errors.New("no file path provided")
+	errorNoSyslogAddress = errors.New("a syslog network requires an address (and vice versa)")
+)
+
+// SinkConfig defines where audit entries are forwarded to. Exactly one of the sink types must
+// be set
+type SinkConfig struct {
+	File    *FileSinkConfig   `json:"file,omitempty" yaml:"file,omitempty"`
+	Syslog  *SyslogSinkConfig `json:"syslog,omitempty" yaml:"syslog,omitempty"`
+	Webhook *push.Target      `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+}
+
+// FileSinkConfig appends entries to a file (one JSON object per line)
+type FileSinkConfig struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+// SyslogSinkConfig sends entries (as JSON) to a syslog daemon
+type SyslogSinkConfig struct {
+	// Network / Address denote the remote syslog daemon (e.g. udp / syslog.example.com:514). If
+	// both are empty, the local syslog daemon is used
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Tag denotes the syslog tag. Defaults to goprobe-audit
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+}
+
+// Validate checks that the sink is properly configured
+func (s *SinkConfig) Validate() error {
+	var n int
+	if s.File != nil {
+		n++
+		if s.File.Path == "" {
+			return errorNoPath
+		}
+	}
+	if s.Syslog != nil {
+		n++
+		if (s.Syslog.Network == "") != (s.Syslog.Address == "") {
+			return errorNoSyslogAddress
+		}
+	}
+	if s.Webhook != nil {
+		n++
+		if err := s.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	if n != 1 {
+		return errorNoSink
+	}
+	return nil
+}
+
+// String returns a short description of the sink
+func (s *SinkConfig) String() string {
+	switch {
+	case s.File != nil:
+		return "file:" + s.File.Path
+	case s.Syslog != nil:
+		if s.Syslog.Address == "" {
+			return "syslog:local"
+		}
+		return "syslog:" + s.Syslog.Network + "://" + s.Syslog.Address
+	case s.Webhook != nil:
+		return "webhook:" + s.Webhook.URL
+	}
+	return "none"
+}
+
+type sink interface {
+	write(ctx context.Context, entry *Entry) error
+	close() error
+}
+
+func (s *SinkConfig) open() (sink, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case s.File != nil:
+		return s.File.open()
+	case s.Syslog != nil:
+		return s.Syslog.open()
+	case s.Webhook != nil:
+		p, err := s.Webhook.Pusher()
+		if err != nil {
+			return nil, err
+		}
+		return &webhookSink{pusher: p}, nil
+	}
+	return nil, errorNoSink
+}
+
+type fileSink struct {
+	file *os.File
+}
+
+func (f *FileSinkConfig) open() (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Clean(f.Path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (f *fileSink) write(_ context.Context, entry *Entry) error {
+	line, err := jsoniter.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *fileSink) close() error {
+	return f.file.Close()
+}
+
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func (s *SyslogSinkConfig) open() (*syslogSink, error) {
+	tag := s.Tag
+	if tag == "" {
+		tag = defaultSyslogTag
+	}
+	writer, err := syslog.Dial(s.Network, s.Address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) write(_ context.Context, entry *Entry) error {
+	msg, err := jsoniter.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(msg))
+}
+
+func (s *syslogSink) close() error {
+	return s.writer.Close()
+}
+
+type webhookSink struct {
+	pusher *push.Pusher
+}
+
+func (w *webhookSink) write(ctx context.Context, entry *Entry) error {
+	return w.pusher.PushJSON(ctx, entry)
+}
+
+func (w *webhookSink) close() error {
+	return nil
+}
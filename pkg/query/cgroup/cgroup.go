@@ -0,0 +1,100 @@
+// Package cgroup provides the means to run queries confined to the CPU / memory limits of a (cgroup v2)
+// control group. Since the memory controller cannot be applied to individual threads, a query is run in
+// a dedicated worker process placed into the control group (c.f. Runner and Serve), preventing heavy
+// (e.g. reporting) queries from starving a co-located capture process
+package cgroup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MinCPUWeight denotes the minimum CPU weight of a control group
+	MinCPUWeight = 1
+	// MaxCPUWeight denotes the maximum CPU weight of a control group
+	MaxCPUWeight = 10000
+
+	// DefaultName denotes the name of the child group of queries not subject to any named limits
+	DefaultName = "default"
+)
+
+var (
+	// ErrNotSupported signifies that control groups are not supported on this platform
+	ErrNotSupported = errors.New("control groups are not supported on this platform")
+
+	// ErrInvalidCPUWeight signifies that a CPU weight is out of range
+	ErrInvalidCPUWeight = fmt.Errorf("CPU weight must be between %d and %d", MinCPUWeight, MaxCPUWeight)
+
+	// ErrInvalidMemoryMax signifies that a memory limit is negative
+	ErrInvalidMemoryMax = errors.New("memory limit must not be negative")
+
+	// ErrInvalidName signifies that the name of a child group is invalid
+	ErrInvalidName = errors.New("invalid control group name")
+)
+
+// Limits denotes the resource limits of a control group. Zero values retain the defaults of the kernel
+type Limits struct {
+	CPUWeight uint64 // CPUWeight: relative share of CPU time under contention (kernel default: 100)
+	MemoryMax int64  // MemoryMax: maximum memory usage in bytes (kernel default: unlimited)
+}
+
+// Validate checks if the limits are within the ranges supported by the kernel
+func (l Limits) Validate() error {
+	if l.CPUWeight != 0 && (l.CPUWeight < MinCPUWeight || l.CPUWeight > MaxCPUWeight) {
+		return fmt.Errorf("%w: %d", ErrInvalidCPUWeight, l.CPUWeight)
+	}
+	if l.MemoryMax < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidMemoryMax, l.MemoryMax)
+	}
+	return nil
+}
+
+// controllers returns the controllers required to enforce the limits
+func (l Limits) controllers() []string {
+	var controllers []string
+	if l.CPUWeight > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if l.MemoryMax > 0 {
+		controllers = append(controllers, "memory")
+	}
+	return controllers
+}
+
+// Group denotes a control group
+type Group struct {
+	path string
+
+	// effective memory limit, taking the limits of all parents created via Child into account
+	memoryMax int64
+}
+
+// Path returns the path of the group in the cgroup file system
+func (g *Group) Path() string {
+	return g.path
+}
+
+// MemoryMax returns the effective memory limit of the group (zero if unlimited or if the group is nil)
+func (g *Group) MemoryMax() int64 {
+	if g == nil {
+		return 0
+	}
+	return g.memoryMax
+}
+
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') ||
+		strings.HasPrefix(name, "cgroup.") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+func effectiveMemoryMax(parent, child int64) int64 {
+	if parent > 0 && (child == 0 || parent < child) {
+		return parent
+	}
+	return child
+}
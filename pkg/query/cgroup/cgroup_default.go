@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package cgroup
+
+import (
+	"os/exec"
+)
+
+// Create creates the control group located at path (not supported on this platform)
+func Create(_ string, _ Limits) (*Group, error) {
+	return nil, ErrNotSupported
+}
+
+// Child creates the child group name (not supported on this platform)
+func (g *Group) Child(_ string, _ Limits) (*Group, error) {
+	return nil, ErrNotSupported
+}
+
+func (g *Group) attach(_ *exec.Cmd) (func(), error) {
+	if g != nil {
+		return nil, ErrNotSupported
+	}
+	return func() {}, nil
+}
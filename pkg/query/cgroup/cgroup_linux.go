@@ -0,0 +1,104 @@
+//go:build linux
+// +build linux
+
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Create creates (if required) the control group located at path (within the cgroup v2 file system, e.g.
+// /sys/fs/cgroup/goprobe.slice/queries) and applies the limits to it. The group must either be delegated
+// to the running user or the process must be privileged
+func Create(path string, limits Limits) (*Group, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	// #nosec G301
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create control group %s: %w", path, err)
+	}
+	g := &Group{
+		path:      filepath.Clean(path),
+		memoryMax: limits.MemoryMax,
+	}
+	return g, g.apply(limits)
+}
+
+// Child creates (if required) the child group name, enabling the controllers required to enforce its
+// limits. Note that a group with children can no longer hold any processes itself
+func (g *Group) Child(name string, limits Limits) (*Group, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	if controllers := limits.controllers(); len(controllers) > 0 {
+		if err := g.write("cgroup.subtree_control", "+"+strings.Join(controllers, " +")); err != nil {
+			return nil, fmt.Errorf("failed to enable controllers %v: %w", controllers, err)
+		}
+	}
+
+	child := &Group{
+		path:      filepath.Join(g.path, name),
+		memoryMax: effectiveMemoryMax(g.memoryMax, limits.MemoryMax),
+	}
+	// #nosec G301
+	if err := os.Mkdir(child.path, 0755); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to create control group %s: %w", child.path, err)
+	}
+	return child, child.apply(limits)
+}
+
+func (g *Group) apply(limits Limits) error {
+	if limits.CPUWeight > 0 {
+		if err := g.write("cpu.weight", strconv.FormatUint(limits.CPUWeight, 10)); err != nil {
+			return fmt.Errorf("failed to set CPU weight: %w", err)
+		}
+	}
+	if limits.MemoryMax > 0 {
+		if err := g.write("memory.max", strconv.FormatInt(limits.MemoryMax, 10)); err != nil {
+			return fmt.Errorf("failed to set memory limit: %w", err)
+		}
+	}
+	return nil
+}
+
+func (g *Group) write(file, value string) error {
+	// #nosec G306
+	return os.WriteFile(filepath.Join(g.path, file), []byte(value), 0644)
+}
+
+// attach ensures that the process started by cmd is placed into the group right away (i.e. before
+// executing any code, c.f. CLONE_INTO_CGROUP) and is killed if the calling thread terminates. The
+// returned function must be called once the process was started
+func (g *Group) attach(cmd *exec.Cmd) (func(), error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if g == nil {
+		return func() {}, nil
+	}
+
+	fd, err := unix.Open(g.path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open control group %s: %w", g.path, err)
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+
+	return func() {
+		_ = unix.Close(fd)
+	}, nil
+}
@@ -0,0 +1,138 @@
+package cgroup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/stretchr/testify/require"
+)
+
+// workerEnv denotes the environment variable causing the test binary to act as query worker
+const workerEnv = "GOPROBE_TEST_QUERY_WORKER"
+
+type testRunner struct{}
+
+func (testRunner) Run(_ context.Context, args *query.Args) (*results.Result, error) {
+	switch args.Ifaces {
+	case "fail":
+		return nil, errors.New("interface fail not found")
+	case "crash":
+		os.Exit(3)
+	}
+	return &results.Result{
+		Hostname: args.Caller,
+		Summary: results.Summary{
+			Interfaces: []string{args.Ifaces},
+		},
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(workerEnv) != "" {
+		if err := Serve(context.Background(), testRunner{}, os.Stdin, os.Stdout); err != nil {
+			os.Stderr.WriteString(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLimitsValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		limits      Limits
+		expectedErr error
+	}{
+		{"defaults", Limits{}, nil},
+		{"valid", Limits{CPUWeight: 20, MemoryMax: 1 << 30}, nil},
+		{"max CPU weight", Limits{CPUWeight: MaxCPUWeight}, nil},
+		{"CPU weight too large", Limits{CPUWeight: MaxCPUWeight + 1}, ErrInvalidCPUWeight},
+		{"negative memory limit", Limits{MemoryMax: -1}, ErrInvalidMemoryMax},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.limits.Validate(), test.expectedErr)
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("control groups are only supported on linux")
+	}
+
+	// the cgroup file system is emulated by a plain directory, allowing to verify the files written
+	path := filepath.Join(t.TempDir(), "queries")
+	group, err := Create(path, Limits{CPUWeight: 20, MemoryMax: 1 << 30})
+	require.Nil(t, err)
+	require.Equal(t, path, group.Path())
+	require.EqualValues(t, 1<<30, group.MemoryMax())
+	requireFile(t, "20", path, "cpu.weight")
+	requireFile(t, "1073741824", path, "memory.max")
+
+	// the memory limit of the parent applies unless the one of the child is lower
+	def, err := group.Child(DefaultName, Limits{})
+	require.Nil(t, err)
+	require.EqualValues(t, 1<<30, def.MemoryMax())
+	require.NoFileExists(t, filepath.Join(path, "cgroup.subtree_control"))
+
+	reporting, err := group.Child("reporting", Limits{CPUWeight: 10, MemoryMax: 1 << 20})
+	require.Nil(t, err)
+	require.EqualValues(t, 1<<20, reporting.MemoryMax())
+	requireFile(t, "+cpu +memory", path, "cgroup.subtree_control")
+	requireFile(t, "10", path, "reporting", "cpu.weight")
+	requireFile(t, "1048576", path, "reporting", "memory.max")
+
+	for _, name := range []string{"", "..", "a/b", "cgroup.procs"} {
+		_, err = group.Child(name, Limits{})
+		require.ErrorIs(t, err, ErrInvalidName)
+	}
+	_, err = Create(path, Limits{MemoryMax: -1})
+	require.ErrorIs(t, err, ErrInvalidMemoryMax)
+}
+
+func requireFile(t *testing.T, expected string, elem ...string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(elem...))
+	require.Nil(t, err)
+	require.Equal(t, expected, string(data))
+}
+
+func TestRunner(t *testing.T) {
+	t.Setenv(workerEnv, "1")
+
+	executable, err := os.Executable()
+	require.Nil(t, err)
+
+	runner := NewRunner(nil, executable)
+	res, err := runner.Run(context.Background(), &query.Args{Ifaces: "eth0", Caller: "test"})
+	require.Nil(t, err)
+	require.Equal(t, "test", res.Hostname)
+	require.Equal(t, []string{"eth0"}, res.Summary.Interfaces)
+
+	_, err = runner.Run(context.Background(), &query.Args{Ifaces: "fail"})
+	require.EqualError(t, err, "interface fail not found")
+
+	_, err = runner.Run(context.Background(), &query.Args{Ifaces: "crash"})
+	require.ErrorContains(t, err, "exit status 3")
+
+	// live queries are delegated to the in-process runner (if any)
+	res, err = runner.WithInProcess(testRunner{}).Run(context.Background(), &query.Args{Ifaces: "eth1", Live: true})
+	require.Nil(t, err)
+	require.Equal(t, []string{"eth1"}, res.Summary.Interfaces)
+
+	if runtime.GOOS == "linux" {
+		runner = NewRunner(&Group{path: filepath.Join(t.TempDir(), "missing")}, executable)
+		_, err = runner.Run(context.Background(), &query.Args{Ifaces: "eth0"})
+		require.ErrorContains(t, err, "failed to open control group")
+	}
+}
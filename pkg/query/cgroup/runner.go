@@ -0,0 +1,150 @@
+package cgroup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxWorkerErrSize limits the amount of error output of a worker process reported back to the caller
+const maxWorkerErrSize = 4096
+
+// ErrWorkerKilled signifies that a worker process was killed before completing the query (most commonly
+// by the OOM killer upon exceeding the memory limit of its group)
+var ErrWorkerKilled = errors.New("query worker was killed (memory limit exceeded?)")
+
+// Runner runs queries in a worker process confined to a control group. The worker reads the query args
+// from its stdin and writes the result to its stdout, c.f. Serve
+type Runner struct {
+	group   *Group
+	command []string
+
+	inProcess query.Runner
+}
+
+// NewRunner creates a new runner executing command (the executable and its arguments) as worker process
+// for each query, placing it into group. If group is nil, the worker is not confined to any control group
+func NewRunner(group *Group, command ...string) *Runner {
+	return &Runner{
+		group:   group,
+		command: command,
+	}
+}
+
+// WithInProcess sets the runner executing queries requesting live flow data, which is only available to
+// the capturing process itself. If unset, such queries are run without live data
+func (r *Runner) WithInProcess(runner query.Runner) *Runner {
+	r.inProcess = runner
+	return r
+}
+
+// Run executes the query in a worker process and returns its result
+func (r *Runner) Run(ctx context.Context, args *query.Args) (*results.Result, error) {
+	if len(r.command) == 0 {
+		return nil, errors.New("no query worker command provided")
+	}
+	if args.Live {
+		if r.inProcess != nil {
+			return r.inProcess.Run(ctx, args)
+		}
+		argsCopy := *args
+		argsCopy.Live = false
+		args = &argsCopy
+	}
+
+	input, err := jsoniter.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query args: %w", err)
+	}
+
+	// #nosec G204
+	cmd := exec.CommandContext(ctx, r.command[0], r.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	stderr := &limitedBuffer{limit: maxWorkerErrSize}
+	cmd.Stderr = stderr
+
+	// let the garbage collector of the worker respect the memory limit of its group (keeping some
+	// headroom for non-heap memory), causing it to collect more aggressively instead of being killed
+	cmd.Env = os.Environ()
+	if memoryMax := r.group.MemoryMax(); memoryMax > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GOMEMLIMIT=%d", memoryMax/10*9))
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	release, err := r.group.attach(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start query worker: %w", err)
+	}
+
+	res := new(results.Result)
+	decodeErr := jsoniter.NewDecoder(stdout).Decode(res)
+	_, _ = io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && !exitErr.Exited() {
+			return nil, fmt.Errorf("%w: %v", ErrWorkerKilled, err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, fmt.Errorf("query worker failed: %w", err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse result of query worker: %w", decodeErr)
+	}
+	return res, nil
+}
+
+// Serve runs a single query in a worker process: the query args are read from r, the query is executed
+// using runner and its result is written to w. Errors are returned to the caller, which is expected to
+// report them on stderr and exit with a non-zero exit code
+func Serve(ctx context.Context, runner query.Runner, r io.Reader, w io.Writer) error {
+	args := query.DefaultArgs()
+	if err := jsoniter.NewDecoder(r).Decode(args); err != nil {
+		return fmt.Errorf("failed to parse query args: %w", err)
+	}
+
+	res, err := runner.Run(ctx, args)
+	if err != nil {
+		return err
+	}
+	return jsoniter.NewEncoder(w).Encode(res)
+}
+
+// limitedBuffer retains the first limit bytes written to it, discarding the remainder
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		b.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
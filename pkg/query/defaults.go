@@ -2,10 +2,12 @@ package query
 
 import (
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/els0r/goProbe/pkg/defaults"
 	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
 )
 
 // Defaults for query arguments
@@ -24,14 +26,76 @@ var (
 
 // PermittedFormats stores all supported output formats
 var permittedFormats = map[string]struct{}{
-	"txt":  {},
-	"json": {},
-	"csv":  {},
+	"txt":      {},
+	"json":     {},
+	"csv":      {},
+	"pcapng":   {},
+	"influxdb": {},
+}
+
+// Dedup modes for distributed queries
+const (
+	DedupMerge = "merge" // DedupMerge: mirrored rows are counted only once
+	DedupFlag  = "flag"  // DedupFlag: mirrored rows are kept, but flagged as such
+)
+
+var permittedDedupModes = map[string]struct{}{
+	DedupMerge: {},
+	DedupFlag:  {},
+}
+
+// Cache modes for distributed queries
+const (
+	CacheUse     = "use"     // CacheUse: cached per-host sub-results are used (if fresh) and results are cached
+	CacheRefresh = "refresh" // CacheRefresh: all hosts are queried and their results are cached
+	CacheOff     = "off"     // CacheOff: the cache is neither read nor populated
+)
+
+var permittedCacheModes = map[string]struct{}{
+	CacheUse:     {},
+	CacheRefresh: {},
+	CacheOff:     {},
+}
+
+// Time alignment modes (handling of time ranges which don't coincide with the rotation boundaries)
+const (
+	TimeAlignSnap        = "snap"        // TimeAlignSnap: the time range is expanded to the rotation boundaries enclosing it
+	TimeAlignInterpolate = "interpolate" // TimeAlignInterpolate: blocks cut by the time range are scaled by the fraction overlapping it
+)
+
+var permittedTimeAlignModes = map[string]struct{}{
+	TimeAlignSnap:        {},
+	TimeAlignInterpolate: {},
+}
+
+// Analysis modes (evaluated on the result of the query prior to printing it)
+const (
+	AnalysisASNMatrix = "asn-matrix" // AnalysisASNMatrix: traffic between all pairs of source and destination ASNs
+	AnalysisOneWay    = "one-way"    // AnalysisOneWay: one-directional rows only (shortcut for the respective predicate)
+)
+
+// oneWayPredicate selects all rows without any packets in one of the directions
+const oneWayPredicate = "packets_in = 0 | packets_out = 0"
+
+var permittedAnalysisModes = map[string]struct{}{
+	AnalysisASNMatrix: {},
+	AnalysisOneWay:    {},
+}
+
+// Named time formats for printing timestamps (alongside custom layouts)
+var namedTimeFormats = map[string]string{
+	"default": types.DefaultTimeOutputFormat,
+	"rfc3339": time.RFC3339,
+	"unix":    results.TimeFormatUnix,
 }
 
 var (
-	permittedFormatsSlice = []string{}
-	permittedSortBySlice  = []string{}
+	permittedFormatsSlice    = []string{}
+	permittedSortBySlice     = []string{}
+	permittedDedupModesSlice = []string{}
+	permittedCacheModesSlice = []string{}
+	permittedAnalysisSlice   = []string{}
+	permittedTimeAlignSlice  = []string{}
 )
 
 func init() {
@@ -44,6 +108,26 @@ func init() {
 		permittedSortBySlice = append(permittedSortBySlice, sortBy)
 	}
 	sort.StringSlice(permittedSortBySlice).Sort()
+
+	for mode := range permittedDedupModes {
+		permittedDedupModesSlice = append(permittedDedupModesSlice, mode)
+	}
+	sort.StringSlice(permittedDedupModesSlice).Sort()
+
+	for mode := range permittedCacheModes {
+		permittedCacheModesSlice = append(permittedCacheModesSlice, mode)
+	}
+	sort.StringSlice(permittedCacheModesSlice).Sort()
+
+	for mode := range permittedAnalysisModes {
+		permittedAnalysisSlice = append(permittedAnalysisSlice, mode)
+	}
+	sort.StringSlice(permittedAnalysisSlice).Sort()
+
+	for mode := range permittedTimeAlignModes {
+		permittedTimeAlignSlice = append(permittedTimeAlignSlice, mode)
+	}
+	sort.StringSlice(permittedTimeAlignSlice).Sort()
 }
 
 // PermittedFormats list which formats are supported
@@ -51,6 +135,38 @@ func PermittedFormats() []string {
 	return permittedFormatsSlice
 }
 
+// PermittedDedupModes lists which dedup modes are supported
+func PermittedDedupModes() []string {
+	return permittedDedupModesSlice
+}
+
+// PermittedCacheModes lists which cache modes are supported
+func PermittedCacheModes() []string {
+	return permittedCacheModesSlice
+}
+
+// PermittedTimeAlignModes lists which time alignment modes are supported
+func PermittedTimeAlignModes() []string {
+	return permittedTimeAlignSlice
+}
+
+// PermittedAnalysisModes lists which analysis modes are supported
+func PermittedAnalysisModes() []string {
+	return permittedAnalysisSlice
+}
+
+// ParseTimeFormat returns the layout of a named time format or validates a custom layout in
+// Go reference time notation (which must contain at least one element of the reference time)
+func ParseTimeFormat(format string) (string, error) {
+	if layout, isNamed := namedTimeFormats[strings.ToLower(format)]; isNamed {
+		return layout, nil
+	}
+	if time.Unix(0, 0).UTC().Format(format) == format {
+		return "", types.NewUnsupportedError(format, []string{"default", "rfc3339", "unix", "<Go time layout>"})
+	}
+	return format, nil
+}
+
 // PermittedSortBy sorts all permitted sorting orders
 var permittedSortBy = map[string]results.SortOrder{
 	"bytes":   results.SortTraffic,
@@ -17,12 +17,18 @@ func WithDirectionOut() Option { return func(a *Args) { a.Out = true } }
 // WithDirectionSum adds both directions
 func WithDirectionSum() Option { return func(a *Args) { a.Sum = true } }
 
+// WithCounters limits the counters aggregated by the query (e.g. "bytes_in,bytes_out")
+func WithCounters(counters string) Option { return func(a *Args) { a.Counters = counters } }
+
 // WithFirst sets the first timestamp to consider
 func WithFirst(f string) Option { return func(a *Args) { a.First = f } }
 
 // WithLast sets the last timestampt to consider
 func WithLast(l string) Option { return func(a *Args) { a.Last = l } }
 
+// WithTimeAlign sets how time ranges cutting through blocks are handled
+func WithTimeAlign(mode string) Option { return func(a *Args) { a.TimeAlign = mode } }
+
 // WithFormat sets the output format
 func WithFormat(f string) Option { return func(a *Args) { a.Format = f } }
 
@@ -35,6 +41,21 @@ func WithNumResults(n uint64) Option { return func(a *Args) { a.NumResults = n }
 // WithSortAscending sorts rows ascending
 func WithSortAscending() Option { return func(a *Args) { a.SortAscending = true } }
 
+// WithHumanReadable renders byte/packet counters in human-readable units
+func WithHumanReadable() Option { return func(a *Args) { a.HumanReadable = true } }
+
+// WithDirectionPercentages adds percentage columns for each direction
+func WithDirectionPercentages() Option { return func(a *Args) { a.DirectionPercentages = true } }
+
+// WithNumeric prints IP protocols as numbers instead of their names
+func WithNumeric() Option { return func(a *Args) { a.Numeric = true } }
+
+// WithTimeZone sets the time zone timestamps are printed in
+func WithTimeZone(tz string) Option { return func(a *Args) { a.TimeZone = tz } }
+
+// WithTimeFormat sets the format timestamps are printed in
+func WithTimeFormat(f string) Option { return func(a *Args) { a.TimeFormat = f } }
+
 // WithList sets the list parameter (only lists interfaces)
 func WithList() Option { return func(a *Args) { a.List = true } }
 
@@ -53,5 +74,46 @@ func WithResolveRows(r int) Option { return func(a *Args) { a.DNSResolution.MaxR
 // WithMaxMemPct is an advanced parameter to restrict system memory usage to a fixed percentage of the available memory during query processing
 func WithMaxMemPct(m int) Option { return func(a *Args) { a.MaxMemPct = m } }
 
+// WithMaxAggMem sets a memory budget (in MiB) for the aggregation of flows, spilling partial aggregates to disk if exceeded
+func WithMaxAggMem(m int) Option { return func(a *Args) { a.MaxAggMem = m } }
+
+// WithWorkers sets the number of processing units reading / decompressing (IO) and filtering / aggregating (CPU)
+// the blocks of an interface in parallel
+func WithWorkers(io, cpu int) Option {
+	return func(a *Args) {
+		a.IOWorkers, a.CPUWorkers = io, cpu
+	}
+}
+
 // WithCaller sets the name of the program/tool calling the query
 func WithCaller(c string) Option { return func(a *Args) { a.Caller = c } }
+
+// WithMmap reads the database via memory-mapped IO instead of buffered reads
+func WithMmap() Option { return func(a *Args) { a.Mmap = true } }
+
+// WithExplain sets the explain argument (returning the execution plan instead of running the query)
+func WithExplain() Option { return func(a *Args) { a.Explain = true } }
+
+// WithFlowHash sets the flow_hash argument (adding the canonical flow hash to each row)
+func WithFlowHash() Option { return func(a *Args) { a.FlowHash = true } }
+
+// WithCommunityID sets the community_id argument (adding the Community ID to each row) and its seed
+func WithCommunityID(seed uint16) Option {
+	return func(a *Args) { a.CommunityID, a.CommunityIDSeed = true, seed }
+}
+
+// WithRoles sets the roles argument (joining the traffic of each host as source and as destination)
+func WithRoles() Option { return func(a *Args) { a.Roles = true } }
+
+// WithASNMatrix sets the analysis argument to the ASN matrix (aggregating the traffic between
+// source and destination ASNs), mapping IPs to ASNs via the database at path
+func WithASNMatrix(path string) Option {
+	return func(a *Args) {
+		a.Analysis = AnalysisASNMatrix
+		a.ASNDB = path
+	}
+}
+
+// WithSample returns a uniform random sample of n of the matching rows (and the traffic extrapolated
+// from it) instead of sorting and limiting them
+func WithSample(n int) Option { return func(a *Args) { a.Sample = n } }
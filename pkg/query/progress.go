@@ -0,0 +1,109 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/formatting"
+)
+
+// DefaultProgressInterval denotes the interval in which the progress of a running query is reported
+const DefaultProgressInterval = 500 * time.Millisecond
+
+// Progress summarizes the progress of a running query
+type Progress struct {
+	// DirsScanned: number of (daily) directories scanned so far. Example: 12
+	DirsScanned int `json:"dirs_scanned"`
+	// DirsTotal: number of (daily) directories covered by the query. Example: 40
+	DirsTotal int `json:"dirs_total"`
+
+	// HostsDone: number of hosts that have returned a result (distributed queries only). Example: 3
+	HostsDone int `json:"hosts_done,omitempty"`
+	// HostsTotal: number of hosts queried (distributed queries only). Example: 10
+	HostsTotal int `json:"hosts_total,omitempty"`
+
+	// BytesProcessed: number of (uncompressed) bytes read from the database so far. Example: 1048576
+	BytesProcessed uint64 `json:"bytes_processed"`
+	// RowsAggregated: number of flows aggregated so far. Example: 34567
+	RowsAggregated uint64 `json:"rows_aggregated"`
+
+	// Elapsed: time elapsed since the start of the query. Example: 3000000000
+	Elapsed time.Duration `json:"elapsed"`
+	// ETA: estimated time until the query is completed (zero if it cannot be estimated yet). Example: 7000000000
+	ETA time.Duration `json:"eta,omitempty"`
+}
+
+// Fraction returns the fraction of the query that has been completed (based on the number of hosts
+// for distributed queries and on the number of directories otherwise)
+func (p Progress) Fraction() float64 {
+	if p.HostsTotal > 0 {
+		return float64(p.HostsDone) / float64(p.HostsTotal)
+	}
+	if p.DirsTotal > 0 {
+		return float64(p.DirsScanned) / float64(p.DirsTotal)
+	}
+	return 0
+}
+
+// EstimateETA extrapolates the time elapsed so far in order to estimate the time until the query
+// is completed
+func (p *Progress) EstimateETA() {
+	p.ETA = 0
+	if fraction := p.Fraction(); fraction > 0 && fraction < 1 {
+		p.ETA = time.Duration(float64(p.Elapsed) * (1 - fraction) / fraction).Round(time.Second)
+	}
+}
+
+// String returns a single-line, human readable summary of the progress
+func (p Progress) String() string {
+	var sb strings.Builder
+	if p.HostsTotal > 0 {
+		fmt.Fprintf(&sb, "%d/%d hosts", p.HostsDone, p.HostsTotal)
+	} else {
+		fmt.Fprintf(&sb, "%d/%d dirs", p.DirsScanned, p.DirsTotal)
+	}
+	fmt.Fprintf(&sb, " (%.0f%%), %s processed, %s rows, elapsed %s",
+		100*p.Fraction(),
+		formatting.Size(p.BytesProcessed),
+		strings.TrimSpace(formatting.Count(p.RowsAggregated)),
+		formatting.Duration(p.Elapsed.Round(time.Second)),
+	)
+	if p.ETA > 0 {
+		fmt.Fprintf(&sb, ", ETA %s", formatting.Duration(p.ETA))
+	}
+	return sb.String()
+}
+
+// ProgressFn denotes a function that is called periodically with the progress of a running query
+type ProgressFn func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context instructing query runners to report the progress of the query
+// to fn while it is running
+func WithProgress(ctx context.Context, fn ProgressFn) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the function the progress of a query is reported to (or nil
+// if no progress reporting was requested)
+func ProgressFromContext(ctx context.Context) ProgressFn {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFn)
+	return fn
+}
+
+// clearLine denotes the escape sequence returning to the start of the line and erasing it
+const clearLine = "\r\033[K"
+
+// NewProgressPrinter returns a ProgressFn rendering the progress on a single, continuously updated
+// line of w (e.g. os.Stderr), alongside a function clearing the line once the query is done
+func NewProgressPrinter(w io.Writer) (fn ProgressFn, done func()) {
+	return func(p Progress) {
+			fmt.Fprint(w, clearLine+"Progress: "+p.String())
+		}, func() {
+			fmt.Fprint(w, clearLine)
+		}
+}
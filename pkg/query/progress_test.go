@@ -0,0 +1,77 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressETA(t *testing.T) {
+	var tests = []struct {
+		name             string
+		progress         Progress
+		expectedFraction float64
+		expectedETA      time.Duration
+	}{
+		{"not started", Progress{DirsTotal: 40, Elapsed: time.Second}, 0, 0},
+		{"no dirs", Progress{Elapsed: time.Second}, 0, 0},
+		{"dirs", Progress{DirsScanned: 10, DirsTotal: 40, Elapsed: 3 * time.Second}, 0.25, 9 * time.Second},
+		{"dirs done", Progress{DirsScanned: 40, DirsTotal: 40, Elapsed: 3 * time.Second}, 1, 0},
+		{"hosts", Progress{DirsScanned: 1, DirsTotal: 2, HostsDone: 1, HostsTotal: 4, Elapsed: 2 * time.Second}, 0.25, 6 * time.Second},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.InDelta(t, test.expectedFraction, test.progress.Fraction(), 1e-9)
+
+			test.progress.EstimateETA()
+			require.Equal(t, test.expectedETA, test.progress.ETA)
+		})
+	}
+}
+
+func TestProgressString(t *testing.T) {
+	p := Progress{DirsScanned: 12, DirsTotal: 40, BytesProcessed: 2048, RowsAggregated: 1500, Elapsed: 3 * time.Second}
+	p.EstimateETA()
+	require.Equal(t, "12/40 dirs (30%), 2.00 kB processed, 1.50 k rows, elapsed 3s, ETA 7s", p.String())
+
+	p = Progress{HostsDone: 2, HostsTotal: 2, Elapsed: time.Second}
+	p.EstimateETA()
+	require.True(t, strings.HasPrefix(p.String(), "2/2 hosts (100%)"))
+	require.NotContains(t, p.String(), "ETA")
+}
+
+func TestProgressContext(t *testing.T) {
+	require.Nil(t, ProgressFromContext(context.Background()))
+
+	var reported []Progress
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		reported = append(reported, p)
+	})
+	fn := ProgressFromContext(ctx)
+	require.NotNil(t, fn)
+
+	fn(Progress{DirsScanned: 1, DirsTotal: 2})
+	require.Equal(t, []Progress{{DirsScanned: 1, DirsTotal: 2}}, reported)
+
+	// explicitly disabling the progress reporting for sub-queries
+	require.Nil(t, ProgressFromContext(WithProgress(ctx, nil)))
+}
+
+func TestProgressPrinter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	fn, done := NewProgressPrinter(buf)
+
+	fn(Progress{DirsScanned: 1, DirsTotal: 2})
+	fn(Progress{DirsScanned: 2, DirsTotal: 2})
+	done()
+
+	require.Equal(t, 3, strings.Count(buf.String(), clearLine))
+	require.Contains(t, buf.String(), "Progress: 2/2 dirs (100%)")
+	require.True(t, strings.HasSuffix(buf.String(), clearLine))
+}
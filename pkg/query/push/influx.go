@@ -0,0 +1,135 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	influxFormat = "influxdb"
+
+	influxV1WritePath = "/write"
+	influxV2WritePath = "/api/v2/write"
+)
+
+var errorTokenWithoutBucket = errors.New("influx token requires a bucket (InfluxDB v2 API)")
+
+// InfluxTarget defines an InfluxDB (or compatible, e.g. VictoriaMetrics) endpoint results are
+// written to in line protocol. If a bucket is configured, the InfluxDB v2 write API is used,
+// otherwise the v1 write API (which is also the one provided by VictoriaMetrics)
+type InfluxTarget struct {
+	URL string `json:"url" yaml:"url"` // URL: base URL of the endpoint. Example: http://localhost:8086
+
+	Bucket string `json:"bucket,omitempty" yaml:"bucket,omitempty"` // Bucket: the bucket to write to (v2 API). Example: traffic
+	Org    string `json:"org,omitempty" yaml:"org,omitempty"`       // Org: the organization owning the bucket (v2 API). Example: netops
+	Token  string `json:"token,omitempty" yaml:"token,omitempty"`   // Token: the API token (v2 API)
+
+	Database string `json:"database,omitempty" yaml:"database,omitempty"` // Database: the database to write to (v1 API). Example: traffic
+
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Retries *int              `json:"retries,omitempty" yaml:"retries,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Mapping overrides the mapping of flows to line protocol of the query (if any)
+	Mapping *results.InfluxMapping `json:"mapping,omitempty" yaml:"mapping,omitempty"`
+}
+
+// Validate checks that the target is properly configured
+func (t *InfluxTarget) Validate() error {
+	if t.Retries != nil && *t.Retries < 0 {
+		return errorNegativeRetry
+	}
+	if t.Token != "" && t.Bucket == "" {
+		return errorTokenWithoutBucket
+	}
+	if err := t.Mapping.Validate(); err != nil {
+		return err
+	}
+	return ValidateURL(t.URL)
+}
+
+// WriteURL returns the URL of the write API of the endpoint (requesting nanosecond precision)
+func (t *InfluxTarget) WriteURL() (string, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid push URL: %w", err)
+	}
+
+	params := u.Query()
+	params.Set("precision", "ns")
+	writePath := influxV1WritePath
+	if t.Bucket != "" {
+		writePath = influxV2WritePath
+		params.Set("bucket", t.Bucket)
+		if t.Org != "" {
+			params.Set("org", t.Org)
+		}
+	} else if t.Database != "" {
+		params.Set("db", t.Database)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + writePath
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+// Pusher creates a pusher delivering to the write API of the target
+func (t *InfluxTarget) Pusher() (*Pusher, error) {
+	writeURL, err := t.WriteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(t.Headers)+1)
+	for k, v := range t.Headers {
+		headers[k] = v
+	}
+	if t.Token != "" {
+		headers["Authorization"] = "Token " + t.Token
+	}
+
+	opts := []Option{
+		WithHeaders(headers),
+		WithTimeout(t.Timeout),
+	}
+	if t.Retries != nil {
+		opts = append(opts, WithRetries(*t.Retries))
+	}
+	return New(writeURL, opts...)
+}
+
+// Push writes the result to the target in line protocol, regardless of the output format of the
+// statement. Results without any flows are not written at all
+func (t *InfluxTarget) Push(ctx context.Context, stmt *query.Statement, result *results.Result) error {
+	if stmt == nil {
+		return errorNoStatement
+	}
+	p, err := t.Pusher()
+	if err != nil {
+		return err
+	}
+
+	influxStmt := *stmt
+	influxStmt.Format = influxFormat
+	if t.Mapping != nil {
+		influxStmt.Influx = t.Mapping
+	}
+
+	body, contentType, err := Render(ctx, &influxStmt, result)
+	if err != nil {
+		return fmt.Errorf("failed to render result: %w", err)
+	}
+	if len(body) == 0 {
+		logging.FromContext(ctx).With("url", t.URL).Debug("no flows to write")
+		return nil
+	}
+	return p.post(ctx, body, contentType)
+}
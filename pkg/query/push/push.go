@@ -0,0 +1,295 @@
+// Package push delivers completed query results to remote HTTP endpoints, e.g. the
+// webhooks of ticketing systems or dashboards
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// DefaultTimeout denotes the default timeout for a single push attempt
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRetries denotes the default number of retries if a push attempt fails
+	DefaultRetries = 3
+
+	contentTypeJSON = "application/json"
+	contentTypeCSV  = "text/csv; charset=utf-8"
+	contentTypeText = "text/plain; charset=utf-8"
+
+	initialBackOff = 1 * time.Second
+)
+
+var (
+	errorEmptyURL       = errors.New("no push URL provided")
+	errorInvalidScheme  = errors.New("push URL must use http or https scheme")
+	errorInvalidHeader  = errors.New("header must be of the form 'Key: Value'")
+	errorNegativeRetry  = errors.New("number of retries must not be negative")
+	errorNoResult       = errors.New("no result to push")
+	errorNoStatement    = errors.New("no query statement provided")
+	errorUnexpectedCode = errors.New("unexpected status code")
+)
+
+// Target defines the endpoint a result is pushed to
+type Target struct {
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Retries *int              `json:"retries,omitempty" yaml:"retries,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Validate checks that the target is properly configured
+func (t *Target) Validate() error {
+	if t.Retries != nil && *t.Retries < 0 {
+		return errorNegativeRetry
+	}
+	return ValidateURL(t.URL)
+}
+
+// Pusher creates a pusher delivering to the target
+func (t *Target) Pusher() (*Pusher, error) {
+	opts := []Option{
+		WithHeaders(t.Headers),
+		WithTimeout(t.Timeout),
+	}
+	if t.Retries != nil {
+		opts = append(opts, WithRetries(*t.Retries))
+	}
+	return New(t.URL, opts...)
+}
+
+// Pusher POSTs serialized query results to a configured endpoint
+type Pusher struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+
+	retries int
+	timeout time.Duration
+}
+
+// Option configures the pusher
+type Option func(*Pusher)
+
+// WithHeaders sets additional headers sent along with every push request (e.g. for
+// authentication / authorization against the endpoint)
+func WithHeaders(headers map[string]string) Option {
+	return func(p *Pusher) {
+		for k, v := range headers {
+			p.headers[k] = v
+		}
+	}
+}
+
+// WithRetries sets the number of retries performed (with exponential back-off) if a push
+// attempt fails
+func WithRetries(retries int) Option {
+	return func(p *Pusher) {
+		if retries >= 0 {
+			p.retries = retries
+		}
+	}
+}
+
+// WithTimeout sets the timeout for a single push attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Pusher) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+// WithClient sets the HTTP client used to perform the requests
+func WithClient(client *http.Client) Option {
+	return func(p *Pusher) {
+		if client != nil {
+			p.client = client
+		}
+	}
+}
+
+// New creates a new pusher delivering results to the provided URL
+func New(pushURL string, opts ...Option) (*Pusher, error) {
+	if err := ValidateURL(pushURL); err != nil {
+		return nil, err
+	}
+	p := &Pusher{
+		url:     pushURL,
+		client:  http.DefaultClient,
+		headers: make(map[string]string),
+		retries: DefaultRetries,
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// Push serializes the result in the output format of the statement and POSTs it to the
+// configured endpoint. Failed attempts (network errors, 5xx and 429 responses) are retried
+func (p *Pusher) Push(ctx context.Context, stmt *query.Statement, result *results.Result) error {
+	body, contentType, err := Render(ctx, stmt, result)
+	if err != nil {
+		return fmt.Errorf("failed to render result: %w", err)
+	}
+	return p.post(ctx, body, contentType)
+}
+
+// PushJSON serializes v as JSON and POSTs it to the configured endpoint, applying the same
+// retry behavior as Push
+func (p *Pusher) PushJSON(ctx context.Context, v any) error {
+	body, err := jsoniter.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to serialize payload: %w", err)
+	}
+	return p.post(ctx, body, contentTypeJSON)
+}
+
+// post sends the body to the endpoint, retrying with exponential back-off if required
+func (p *Pusher) post(ctx context.Context, body []byte, contentType string) (err error) {
+	logger := logging.FromContext(ctx).With("url", p.url, "content_type", contentType)
+
+	backOff := initialBackOff
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = p.send(ctx, body, contentType)
+		if err == nil {
+			logger.Debug("pushed payload")
+			return nil
+		}
+		if !retry || attempt >= p.retries {
+			break
+		}
+		logger.With("error", err, "attempt", attempt+1).Warnf("push failed, retrying in %s", backOff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backOff):
+		}
+		backOff *= 2
+	}
+	return fmt.Errorf("failed to push to %s: %w", p.url, err)
+}
+
+// send performs a single push attempt and reports whether it should be retried on failure
+func (p *Pusher) send(ctx context.Context, body []byte, contentType string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if 200 <= resp.StatusCode && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("%w: %d", errorUnexpectedCode, resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, err
+	}
+	return false, err
+}
+
+// Render serializes the result according to the output format of the statement and
+// returns the body alongside its content type
+func Render(ctx context.Context, stmt *query.Statement, result *results.Result) ([]byte, string, error) {
+	if stmt == nil {
+		return nil, "", errorNoStatement
+	}
+	if result == nil {
+		return nil, "", errorNoResult
+	}
+
+	if stmt.Format == "json" {
+		body, err := jsoniter.Marshal(result)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, contentTypeJSON, nil
+	}
+
+	contentType := contentTypeText
+	if stmt.Format == "csv" {
+		contentType = contentTypeCSV
+	}
+
+	buf := new(bytes.Buffer)
+	if result.Status.Code != types.StatusOK {
+
+		// the line protocol has no notion of a status, the result simply doesn't contain any points
+		if stmt.Format == influxFormat {
+			return nil, contentType, nil
+		}
+		fmt.Fprintf(buf, "Status %q: %s\n", result.Status.Code, result.Status.Message)
+		return buf.Bytes(), contentType, nil
+	}
+
+	// print into the buffer instead of the statement's original output
+	printStmt := *stmt
+	printStmt.Output = buf
+	if err := printStmt.Print(ctx, result); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// ValidateURL checks that the provided URL can be used as a push target
+func ValidateURL(pushURL string) error {
+	if pushURL == "" {
+		return errorEmptyURL
+	}
+	u, err := url.Parse(pushURL)
+	if err != nil {
+		return fmt.Errorf("invalid push URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errorInvalidScheme
+	}
+	return nil
+}
+
+// ParseHeaders converts a list of "Key: Value" strings into a header map
+func ParseHeaders(headers []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(headers))
+	for _, header := range headers {
+		key, value, found := strings.Cut(header, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("%w: %q", errorInvalidHeader, header)
+		}
+		parsed[key] = strings.TrimSpace(value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,213 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func testResult() *results.Result {
+	return &results.Result{
+		Hostname: "test-host",
+		Status:   results.Status{Code: types.StatusOK},
+		Rows: results.Rows{
+			{Attributes: results.Attributes{DstPort: 443}, Counters: types.Counters{BytesRcvd: 1024, PacketsRcvd: 1}},
+		},
+	}
+}
+
+func TestPushJSON(t *testing.T) {
+	var received results.Result
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, contentTypeJSON, r.Header.Get("Content-Type"))
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		require.Nil(t, jsoniter.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p, err := New(srv.URL, WithHeaders(map[string]string{"Authorization": "Bearer secret"}))
+	require.Nil(t, err)
+
+	result := testResult()
+	require.Nil(t, p.Push(context.Background(), &query.Statement{Format: "json"}, result))
+	require.Equal(t, result.Hostname, received.Hostname)
+	require.Equal(t, result.Rows, received.Rows)
+}
+
+func TestPushNonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+		require.Equal(t, contentTypeCSV, r.Header.Get("Content-Type"))
+		require.Equal(t, "Status \"empty\": no data\n", string(body))
+	}))
+	defer srv.Close()
+
+	p, err := New(srv.URL)
+	require.Nil(t, err)
+
+	result := &results.Result{Status: results.Status{Code: types.StatusEmpty, Message: "no data"}}
+	require.Nil(t, p.Push(context.Background(), &query.Statement{Format: "csv"}, result))
+}
+
+func TestPushRetries(t *testing.T) {
+	var tests = []struct {
+		name          string
+		codes         []int
+		retries       int
+		expectedCalls int32
+		shouldFail    bool
+	}{
+		{"success", []int{http.StatusOK}, 2, 1, false},
+		{"retry after server error", []int{http.StatusInternalServerError, http.StatusOK}, 2, 2, false},
+		{"retry after rate limit", []int{http.StatusTooManyRequests, http.StatusNoContent}, 2, 2, false},
+		{"no retry on client error", []int{http.StatusUnauthorized, http.StatusOK}, 2, 1, true},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway}, 1, 2, true},
+		{"retries disabled", []int{http.StatusBadGateway, http.StatusOK}, 0, 1, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				w.WriteHeader(test.codes[n-1])
+			}))
+			defer srv.Close()
+
+			p, err := New(srv.URL, WithRetries(test.retries), WithTimeout(time.Second))
+			require.Nil(t, err)
+
+			err = p.Push(context.Background(), &query.Statement{Format: "json"}, testResult())
+			if test.shouldFail {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, test.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    []string
+		expected map[string]string
+		valid    bool
+	}{
+		{"empty", nil, map[string]string{}, true},
+		{"single", []string{"Authorization: Bearer abc"}, map[string]string{"Authorization": "Bearer abc"}, true},
+		{"value with colon", []string{"X-Time:12:00"}, map[string]string{"X-Time": "12:00"}, true},
+		{"missing separator", []string{"Authorization"}, nil, false},
+		{"missing key", []string{": value"}, nil, false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			headers, err := ParseHeaders(test.input)
+			if !test.valid {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, test.expected, headers)
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	require.ErrorIs(t, ValidateURL(""), errorEmptyURL)
+	require.ErrorIs(t, ValidateURL("ftp://example.com"), errorInvalidScheme)
+	require.Nil(t, ValidateURL("https://example.com/hook"))
+}
+
+func TestInfluxTargetWriteURL(t *testing.T) {
+	var tests = []struct {
+		name     string
+		target   InfluxTarget
+		expected string
+	}{
+		{"v1", InfluxTarget{URL: "http://localhost:8428", Database: "traffic"}, "http://localhost:8428/write?db=traffic&precision=ns"},
+		{"v1 without database", InfluxTarget{URL: "http://localhost:8428/"}, "http://localhost:8428/write?precision=ns"},
+		{"v2", InfluxTarget{URL: "https://influx.example.com/", Bucket: "traffic", Org: "netops"}, "https://influx.example.com/api/v2/write?bucket=traffic&org=netops&precision=ns"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writeURL, err := test.target.WriteURL()
+			require.Nil(t, err)
+			require.Equal(t, test.expected, writeURL)
+		})
+	}
+}
+
+func TestInfluxTargetValidate(t *testing.T) {
+	negative := -1
+	var tests = []struct {
+		name   string
+		target InfluxTarget
+		err    error
+	}{
+		{"valid", InfluxTarget{URL: "http://localhost:8086", Bucket: "traffic", Token: "secret"}, nil},
+		{"token without bucket", InfluxTarget{URL: "http://localhost:8086", Token: "secret"}, errorTokenWithoutBucket},
+		{"negative retries", InfluxTarget{URL: "http://localhost:8086", Retries: &negative}, errorNegativeRetry},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.target.Validate(), test.err)
+		})
+	}
+}
+
+func TestInfluxTargetPush(t *testing.T) {
+	var requests int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, influxV2WritePath, r.URL.Path)
+		require.Equal(t, "traffic", r.URL.Query().Get("bucket"))
+		require.Equal(t, "Token secret", r.Header.Get("Authorization"))
+
+		b, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	stmt, err := query.NewArgs("dport", "eth0", query.WithFormat("json"), query.WithLast("-1h")).Prepare()
+	require.Nil(t, err)
+
+	target := &InfluxTarget{URL: srv.URL, Bucket: "traffic", Token: "secret", Mapping: &results.InfluxMapping{
+		Measurement: "flows",
+		Fields:      map[string]string{types.BytesRcvdName: "rx"},
+	}}
+	require.Nil(t, target.Validate())
+
+	result := testResult()
+	result.Rows[0].Labels.Timestamp = time.Unix(1704067200, 0)
+	require.Nil(t, target.Push(context.Background(), stmt, result))
+	require.Equal(t, 1, requests)
+	require.Equal(t, "flows,dport=443 rx=1024i 1704067200000000000\n", body)
+
+	// results without any flows are not written
+	require.Nil(t, target.Push(context.Background(), stmt, &results.Result{Status: results.Status{Code: types.StatusEmpty}}))
+	require.Equal(t, 1, requests)
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -23,6 +24,16 @@ func (s *Statement) Print(ctx context.Context, result *results.Result) error {
 	ctx, span := tracing.Start(ctx, "(*Statement).Print")
 	defer span.End()
 
+	// an explained query carries its execution plan instead of any rows
+	if result.Plan != nil {
+		return result.Plan.Print(s.Output)
+	}
+
+	// analyses replace the rows of the result by their outcome
+	if s.Analysis != "" {
+		return s.printAnalysis(result)
+	}
+
 	var sip, dip types.Attribute
 
 	var hasDNSattributes bool
@@ -32,7 +43,10 @@ func (s *Statement) Print(ctx context.Context, result *results.Result) error {
 			sip = attribute
 			hasDNSattributes = true
 		case "dip":
-			dip = attribute
+			// the host of rows joined by role is stored as source IP only
+			if !s.Roles {
+				dip = attribute
+			}
 			hasDNSattributes = true
 		}
 	}
@@ -40,20 +54,65 @@ func (s *Statement) Print(ctx context.Context, result *results.Result) error {
 	// Find map from ips to domains for reverse DNS
 	var ips2domains map[string]string
 	if s.DNSResolution.Enabled && hasDNSattributes {
+		// the hostnames recorded at capture time (if any) take precedence, hence only the remaining
+		// IP addresses are resolved
 		var ips []string
+		appendIP := func(ip netip.Addr) {
+			if _, captured := result.Hostnames[ip.String()]; !captured {
+				ips = append(ips, ip.String())
+			}
+		}
 		for i, l := 0, len(result.Rows); i < l && i < s.DNSResolution.MaxRows; i++ {
 			attr := result.Rows[i].Attributes
 			if sip != nil {
-				ips = append(ips, attr.SrcIP.String())
+				appendIP(attr.SrcIP)
 			}
 			if dip != nil {
-				ips = append(ips, attr.DstIP.String())
+				appendIP(attr.DstIP)
 			}
 		}
 
 		resolveStart := time.Now()
 		ips2domains = dns.TimedReverseLookup(ips, s.DNSResolution.Timeout)
 		result.Summary.Timings.ResolutionDuration = time.Since(resolveStart)
+		for ip, name := range result.Hostnames {
+			ips2domains[ip] = name
+		}
+	}
+
+	var printerOpts []results.PrinterOption
+	if s.HumanReadable {
+		printerOpts = append(printerOpts, results.WithHumanReadable())
+	}
+	if s.DirectionPercentages {
+		printerOpts = append(printerOpts, results.WithDirectionPercentages())
+	}
+	if s.Numeric {
+		printerOpts = append(printerOpts, results.WithNumeric())
+	}
+	if !s.Counters.IsAll() {
+		printerOpts = append(printerOpts, results.WithCounters(s.Counters))
+	}
+	if loc := s.Location(); loc != nil {
+		printerOpts = append(printerOpts, results.WithTimeLocation(loc))
+	}
+	if s.TimeFormat != "" {
+		printerOpts = append(printerOpts, results.WithTimeLayout(s.TimeFormat))
+	}
+	if s.FlowHash {
+		printerOpts = append(printerOpts, results.WithFlowHash())
+	}
+	if s.CommunityID {
+		printerOpts = append(printerOpts, results.WithCommunityID())
+	}
+	if s.Roles {
+		printerOpts = append(printerOpts, results.WithRoles())
+	}
+	if s.Stats {
+		printerOpts = append(printerOpts, results.WithStats())
+	}
+	if s.Influx != nil {
+		printerOpts = append(printerOpts, results.WithInfluxMapping(s.Influx))
 	}
 
 	// get the right printer
@@ -70,6 +129,7 @@ func (s *Statement) Print(ctx context.Context, result *results.Result) error {
 		s.DNSResolution.Timeout,
 		s.QueryType,
 		strings.Join(s.Ifaces, ","),
+		printerOpts...,
 	)
 	if err != nil {
 		return err
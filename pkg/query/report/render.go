@@ -0,0 +1,163 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"html/template"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var counterColumns = []string{"bytes_rcvd", "bytes_sent", "packets_rcvd", "packets_sent"}
+
+// Report denotes a generated summary report, holding the rows of its summary in tabular form
+type Report struct {
+	Name      string    `json:"name"`      // Name: the name of the report. Example: "top-talkers"
+	Kind      Kind      `json:"kind"`      // Kind: the summary provided by the report. Example: "top_talkers"
+	Day       string    `json:"day"`       // Day: the day covered by the report. Example: "2024-01-31"
+	First     time.Time `json:"first"`     // First: the start of the time range covered by the report
+	Last      time.Time `json:"last"`      // Last: the end of the time range covered by the report
+	Generated time.Time `json:"generated"` // Generated: the time the report was generated
+
+	Status        results.Status `json:"status"`                   // Status: the overall status of the underlying query
+	Totals        types.Counters `json:"totals"`                   // Totals: the total traffic volume covered by the report
+	HostsQueried  int            `json:"hosts_queried"`            // HostsQueried: the number of hosts queried. Example: 12
+	HostsFailed   int            `json:"hosts_failed"`             // HostsFailed: the number of hosts that could not be queried. Example: 1
+	CorruptBlocks uint64         `json:"corrupt_blocks,omitempty"` // CorruptBlocks: the number of blocks skipped due to failed checksum validation
+
+	Columns []string   `json:"columns"` // Columns: the names of the columns of the rows. Example: ["sip", "dip", "bytes_rcvd", "bytes_sent", "packets_rcvd", "packets_sent"]
+	Rows    [][]string `json:"rows"`    // Rows: the rows of the summary
+}
+
+// newReport summarizes the result of the query of a report
+func newReport(cfg *Config, day, first, last, generated time.Time, result *results.Result) *Report {
+	r := &Report{
+		Name:          cfg.Name,
+		Kind:          cfg.Kind,
+		Day:           day.Format(dayFormat),
+		First:         first,
+		Last:          last,
+		Generated:     generated,
+		Status:        result.Status,
+		Totals:        result.Summary.Totals,
+		HostsQueried:  len(result.HostsStatuses),
+		CorruptBlocks: result.Summary.CorruptBlocks,
+		Rows:          [][]string{},
+	}
+	for _, status := range result.HostsStatuses {
+		if status.Code == types.StatusError {
+			r.HostsFailed++
+		}
+	}
+
+	switch cfg.Kind {
+	case KindTopTalkers:
+		r.Columns = append([]string{types.SIPName, types.DIPName}, counterColumns...)
+		for _, row := range result.Rows {
+			r.Rows = append(r.Rows, append([]string{row.Attributes.SrcIP.String(), row.Attributes.DstIP.String()}, counters(row.Counters)...))
+		}
+	case KindIfaceTotals:
+		r.Columns = append([]string{types.HostnameName, types.IfaceName}, counterColumns...)
+		for _, row := range result.Rows {
+			r.Rows = append(r.Rows, append([]string{row.Labels.Hostname, row.Labels.Iface}, counters(row.Counters)...))
+		}
+		sort.SliceStable(r.Rows, func(i, j int) bool {
+			if r.Rows[i][0] != r.Rows[j][0] {
+				return r.Rows[i][0] < r.Rows[j][0]
+			}
+			return r.Rows[i][1] < r.Rows[j][1]
+		})
+	case KindErrors:
+		r.Columns = []string{types.HostnameName, "status", "message"}
+		for host, status := range result.HostsStatuses {
+			if status.Code == types.StatusOK {
+				continue
+			}
+			r.Rows = append(r.Rows, []string{host, string(status.Code), status.Message})
+		}
+		sort.Slice(r.Rows, func(i, j int) bool {
+			return r.Rows[i][0] < r.Rows[j][0]
+		})
+	}
+	return r
+}
+
+func counters(c types.Counters) []string {
+	return []string{
+		strconv.FormatUint(c.BytesRcvd, 10),
+		strconv.FormatUint(c.BytesSent, 10),
+		strconv.FormatUint(c.PacketsRcvd, 10),
+		strconv.FormatUint(c.PacketsSent, 10),
+	}
+}
+
+// Render renders the report in the requested format
+func (r *Report) Render(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return jsoniter.MarshalIndent(r, "", "  ")
+	case FormatCSV:
+		buf := new(bytes.Buffer)
+		w := csv.NewWriter(buf)
+		if err := w.Write(r.Columns); err != nil {
+			return nil, err
+		}
+		if err := w.WriteAll(r.Rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatHTML:
+		buf := new(bytes.Buffer)
+		if err := htmlTemplate.Execute(buf, r); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errorInvalidFormat
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - {{.Day}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.75em; text-align: left; }
+th { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h1>{{.Name}} ({{.Day}})</h1>
+<table>
+<tr><th>Kind</th><td>{{.Kind}}</td></tr>
+<tr><th>Time range</th><td>{{.First.Format "2006-01-02 15:04:05 MST"}} - {{.Last.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+<tr><th>Generated</th><td>{{.Generated.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+<tr><th>Status</th><td>{{.Status.Code}}{{with .Status.Message}}: {{.}}{{end}}</td></tr>
+<tr><th>Bytes (rcvd / sent)</th><td>{{.Totals.BytesRcvd}} / {{.Totals.BytesSent}}</td></tr>
+<tr><th>Packets (rcvd / sent)</th><td>{{.Totals.PacketsRcvd}} / {{.Totals.PacketsSent}}</td></tr>
+<tr><th>Hosts (queried / failed)</th><td>{{.HostsQueried}} / {{.HostsFailed}}</td></tr>
+{{- if .CorruptBlocks}}
+<tr><th>Corrupt blocks</th><td>{{.CorruptBlocks}}</td></tr>
+{{- end}}
+</table>
+<h2>Summary</h2>
+{{- if .Rows}}
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No entries.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,146 @@
+// Package report renders daily summary reports (top talkers, per-interface totals, query errors) of
+// the previous day to dated files in a report directory and removes them once they exceed the retention
+// period, providing small deployments with reporting out of the box (without an external scheduler)
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+const (
+	// DefaultLimit denotes the default number of rows of a top talkers report
+	DefaultLimit = 10
+
+	// dayFormat denotes the format of the day a report covers, as used in its file names
+	dayFormat = "2006-01-02"
+)
+
+// Kind denotes the kind of summary a report provides
+type Kind string
+
+const (
+	// KindTopTalkers denotes a report of the conversations (source / destination IP) with the
+	// largest traffic volume
+	KindTopTalkers Kind = "top_talkers"
+
+	// KindIfaceTotals denotes a report of the traffic volume of each interface (of each host)
+	KindIfaceTotals Kind = "iface_totals"
+
+	// KindErrors denotes a report of all hosts that could not be queried or had no data, alongside
+	// the number of corrupt blocks encountered
+	KindErrors Kind = "errors"
+)
+
+// Format denotes the format a report is rendered in
+type Format string
+
+const (
+	// FormatHTML renders a report as stand-alone HTML page
+	FormatHTML Format = "html"
+	// FormatCSV renders the rows of a report as CSV
+	FormatCSV Format = "csv"
+	// FormatJSON renders a report as JSON document
+	FormatJSON Format = "json"
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var (
+	errorInvalidName   = errors.New("report name must consist of alphanumeric characters, '.', '_' and '-' only")
+	errorInvalidKind   = errors.New("invalid report kind (must be top_talkers, iface_totals or errors)")
+	errorInvalidFormat = errors.New("invalid report format (must be html, csv or json)")
+	errorInvalidLimit  = errors.New("report limit must not be negative")
+)
+
+// Config defines a summary report
+type Config struct {
+	Name string `json:"name" yaml:"name"` // Name: unique name of the report, prefixing its file names. Example: "top-talkers"
+	Kind Kind   `json:"kind" yaml:"kind"` // Kind: the summary provided by the report. Enum: [top_talkers, iface_totals, errors]. Example: "top_talkers"
+
+	Ifaces     string `json:"ifaces,omitempty" yaml:"ifaces,omitempty"`           // Ifaces: the interfaces covered by the report. Defaults to any. Example: "eth0,eth1"
+	QueryHosts string `json:"query_hosts,omitempty" yaml:"query_hosts,omitempty"` // QueryHosts: the hosts covered by the report. Defaults to any. Example: "hostA,hostB"
+	Condition  string `json:"condition,omitempty" yaml:"condition,omitempty"`     // Condition: restricts the report to the flows matching the condition. Example: "dport=443"
+
+	// Limit: the number of rows of a top talkers report. Defaults to 10
+	Limit int `json:"limit,omitempty" yaml:"limit,omitempty"`
+
+	// Formats: the formats the report is rendered in (one file per format). Defaults to html
+	Formats []Format `json:"formats,omitempty" yaml:"formats,omitempty"`
+}
+
+// DefaultReports returns the reports generated if none are configured, i.e. one report of each kind
+// (rendered as HTML)
+func DefaultReports() []Config {
+	return []Config{
+		{Name: "top-talkers", Kind: KindTopTalkers},
+		{Name: "iface-totals", Kind: KindIfaceTotals},
+		{Name: "errors", Kind: KindErrors},
+	}
+}
+
+// Validate checks that the report can be generated
+func (c *Config) Validate() error {
+	if !nameRegexp.MatchString(c.Name) {
+		return fmt.Errorf("%w: %q", errorInvalidName, c.Name)
+	}
+	switch c.Kind {
+	case KindTopTalkers, KindIfaceTotals, KindErrors:
+	default:
+		return fmt.Errorf("%w: %q", errorInvalidKind, c.Kind)
+	}
+	for _, format := range c.Formats {
+		switch format {
+		case FormatHTML, FormatCSV, FormatJSON:
+		default:
+			return fmt.Errorf("%w: %q", errorInvalidFormat, format)
+		}
+	}
+	if c.Limit < 0 {
+		return errorInvalidLimit
+	}
+	if _, err := c.args(time.Now().AddDate(0, 0, -1), time.Now()).Prepare(); err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) formats() []Format {
+	if len(c.Formats) == 0 {
+		return []Format{FormatHTML}
+	}
+	return c.Formats
+}
+
+// args returns the query arguments of the report covering the interval [first, last]
+func (c *Config) args(first, last time.Time) *query.Args {
+	args := query.DefaultArgs()
+	args.Ifaces, args.QueryHosts, args.Condition = c.Ifaces, c.QueryHosts, c.Condition
+	if args.Ifaces == "" {
+		args.Ifaces = types.AnySelector
+	}
+	if args.QueryHosts == "" {
+		args.QueryHosts = types.AnySelector
+	}
+	args.First, args.Last = strconv.FormatInt(first.Unix(), 10), strconv.FormatInt(last.Unix(), 10)
+	args.Format = "json"
+	args.Caller = "report"
+
+	switch c.Kind {
+	case KindTopTalkers:
+		args.Query = types.TalkConvCompoundQuery
+		args.NumResults = uint64(c.Limit)
+		if args.NumResults == 0 {
+			args.NumResults = DefaultLimit
+		}
+	case KindIfaceTotals, KindErrors:
+		args.Query = types.HostnameName + "," + types.IfaceName
+	}
+	return args
+}
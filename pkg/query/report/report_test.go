@@ -0,0 +1,245 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRunner struct {
+	mu   sync.Mutex
+	args []query.Args
+	fail bool
+}
+
+func (m *mockRunner) Run(_ context.Context, args *query.Args) (*results.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.args = append(m.args, *args)
+	if m.fail {
+		return nil, errors.New("query failed")
+	}
+
+	res := &results.Result{
+		Status: results.Status{Code: types.StatusOK},
+		HostsStatuses: results.HostsStatuses{
+			"hostA": {Code: types.StatusOK},
+			"hostB": {Code: types.StatusError, Message: "connection refused"},
+			"hostC": {Code: types.StatusMissingData, Message: "no data"},
+		},
+		Summary: results.Summary{
+			Totals:        types.Counters{BytesRcvd: 3000, BytesSent: 300, PacketsRcvd: 30, PacketsSent: 3},
+			CorruptBlocks: 2,
+		},
+	}
+	if args.Query == types.TalkConvCompoundQuery {
+		res.Rows = results.Rows{
+			{
+				Attributes: results.Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2")},
+				Counters:   types.Counters{BytesRcvd: 2000, BytesSent: 200, PacketsRcvd: 20, PacketsSent: 2},
+			},
+		}
+	} else {
+		res.Rows = results.Rows{
+			{Labels: results.Labels{Hostname: "hostA", Iface: "eth1"}, Counters: types.Counters{BytesRcvd: 1000, PacketsRcvd: 10}},
+			{Labels: results.Labels{Hostname: "hostA", Iface: "eth0"}, Counters: types.Counters{BytesRcvd: 2000, BytesSent: 300, PacketsRcvd: 20, PacketsSent: 3}},
+		}
+	}
+	return res, nil
+}
+
+var allFormats = []Format{FormatHTML, FormatCSV, FormatJSON}
+
+func TestConfigValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		cfg         Config
+		expectedErr error
+	}{
+		{"valid", Config{Name: "top-talkers", Kind: KindTopTalkers, Limit: 25, Formats: allFormats}, nil},
+		{"no name", Config{Kind: KindTopTalkers}, errorInvalidName},
+		{"name with path", Config{Name: "../top", Kind: KindTopTalkers}, errorInvalidName},
+		{"invalid kind", Config{Name: "top", Kind: "bottom_talkers"}, errorInvalidKind},
+		{"invalid format", Config{Name: "top", Kind: KindTopTalkers, Formats: []Format{"pdf"}}, errorInvalidFormat},
+		{"negative limit", Config{Name: "top", Kind: KindTopTalkers, Limit: -1}, errorInvalidLimit},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.cfg.Validate(), test.expectedErr)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&mockRunner{}, "", DefaultReports())
+	require.ErrorIs(t, err, errorNoDir)
+
+	_, err = New(&mockRunner{}, t.TempDir(), nil)
+	require.ErrorIs(t, err, errorNoReports)
+
+	_, err = New(&mockRunner{}, t.TempDir(), append(DefaultReports(), DefaultReports()[0]))
+	require.ErrorIs(t, err, errorDuplicateName)
+
+	_, err = New(&mockRunner{}, t.TempDir(), DefaultReports(), WithTimeOfDay("25:00"))
+	require.ErrorIs(t, err, errorInvalidTimeDay)
+
+	_, err = New(&mockRunner{}, t.TempDir(), DefaultReports(), WithRetention(-1))
+	require.ErrorIs(t, err, errorInvalidRetain)
+}
+
+func TestGenerate(t *testing.T) {
+	dir, runner := t.TempDir(), &mockRunner{}
+	r, err := New(runner, dir, []Config{
+		{Name: "top", Kind: KindTopTalkers, Limit: 5, Formats: allFormats},
+		{Name: "ifaces", Kind: KindIfaceTotals, Formats: allFormats},
+		{Name: "errors", Kind: KindErrors, Formats: allFormats},
+	})
+	require.Nil(t, err)
+
+	day := time.Date(2024, time.March, 15, 13, 37, 0, 0, time.Local)
+	paths, err := r.Generate(context.Background(), day)
+	require.Nil(t, err)
+	require.Len(t, paths, 9)
+	require.True(t, r.exist(day))
+
+	// the queries cover the blocks of the day
+	require.Len(t, runner.args, 3)
+	first, last := time.Date(2024, time.March, 15, 0, 0, 1, 0, time.Local), time.Date(2024, time.March, 16, 0, 0, 0, 0, time.Local)
+	for _, args := range runner.args {
+		require.Equal(t, strconv.FormatInt(first.Unix(), 10), args.First)
+		require.Equal(t, strconv.FormatInt(last.Unix(), 10), args.Last)
+		require.Equal(t, types.AnySelector, args.Ifaces)
+		require.Equal(t, types.AnySelector, args.QueryHosts)
+	}
+	require.EqualValues(t, 5, runner.args[0].NumResults)
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.Nil(t, err)
+		return string(data)
+	}
+
+	require.Equal(t, "sip,dip,bytes_rcvd,bytes_sent,packets_rcvd,packets_sent\n10.0.0.1,10.0.0.2,2000,200,20,2\n", read("top_2024-03-15.csv"))
+	require.Equal(t, "hostname,iface,bytes_rcvd,bytes_sent,packets_rcvd,packets_sent\nhostA,eth0,2000,300,20,3\nhostA,eth1,1000,0,10,0\n", read("ifaces_2024-03-15.csv"))
+	require.Equal(t, "hostname,status,message\nhostB,error,connection refused\nhostC,missing_data,no data\n", read("errors_2024-03-15.csv"))
+
+	var report Report
+	require.Nil(t, jsoniter.UnmarshalFromString(read("errors_2024-03-15.json"), &report))
+	require.Equal(t, "2024-03-15", report.Day)
+	require.Equal(t, KindErrors, report.Kind)
+	require.Equal(t, 3, report.HostsQueried)
+	require.Equal(t, 1, report.HostsFailed)
+	require.EqualValues(t, 2, report.CorruptBlocks)
+	require.Len(t, report.Rows, 2)
+
+	html := read("top_2024-03-15.html")
+	require.True(t, strings.HasPrefix(html, "<!DOCTYPE html>"))
+	require.Contains(t, html, "<td>10.0.0.1</td><td>10.0.0.2</td>")
+
+	// failing queries are reported without writing any files
+	runner.fail = true
+	paths, err = r.Generate(context.Background(), day.AddDate(0, 0, 1))
+	require.NotNil(t, err)
+	require.Empty(t, paths)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(&mockRunner{}, dir, []Config{{Name: "top", Kind: KindTopTalkers, Formats: allFormats}}, WithRetention(2))
+	require.Nil(t, err)
+
+	for _, name := range []string{
+		"top_2024-03-10.html", "top_2024-03-12.csv", "top_2024-03-13.json", "top_2024-03-14.html",
+		"top_2024-03-01.txt", "other_2024-03-01.html", "notes.txt",
+	} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	n, err := r.Prune(time.Date(2024, time.March, 15, 0, 15, 0, 0, time.Local))
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+	require.ElementsMatch(t, []string{
+		"top_2024-03-13.json", "top_2024-03-14.html", "top_2024-03-01.txt", "other_2024-03-01.html", "notes.txt",
+	}, remaining)
+}
+
+func TestSchedule(t *testing.T) {
+	r, err := New(&mockRunner{}, t.TempDir(), DefaultReports(), WithTimeOfDay("00:15"))
+	require.Nil(t, err)
+
+	var tests = []struct {
+		now          time.Time
+		expectedNext time.Time
+	}{
+		{time.Date(2024, time.March, 15, 0, 0, 0, 0, time.Local), time.Date(2024, time.March, 15, 0, 15, 0, 0, time.Local)},
+		{time.Date(2024, time.March, 15, 0, 15, 0, 0, time.Local), time.Date(2024, time.March, 16, 0, 15, 0, 0, time.Local)},
+		{time.Date(2024, time.March, 15, 23, 59, 0, 0, time.Local), time.Date(2024, time.March, 16, 0, 15, 0, 0, time.Local)},
+		{time.Date(2024, time.December, 31, 12, 0, 0, 0, time.Local), time.Date(2025, time.January, 1, 0, 15, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		t.Run(test.now.String(), func(t *testing.T) {
+			next := r.next(test.now)
+			require.Equal(t, test.expectedNext, next)
+			require.Equal(t, startOfDay(test.expectedNext).AddDate(0, 0, -1), reportDay(next))
+		})
+	}
+}
+
+func TestRegisterCatchUp(t *testing.T) {
+	dir, runner := t.TempDir(), &mockRunner{}
+	r, err := New(runner, dir, DefaultReports(), WithTimeOfDay("06:30"))
+	require.Nil(t, err)
+	require.Equal(t, "30 6 * * *", r.spec)
+	r.now = func() time.Time {
+		return time.Date(2024, time.March, 15, 10, 0, 0, 0, time.Local)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := schedule.New(ctx, runner)
+
+	// the reports of the previous day are generated right away (if missing)
+	require.True(t, r.missing())
+	require.Nil(t, r.Register(s))
+	require.Eventually(t, func() bool {
+		status, err := s.Get(JobName)
+		return err == nil && status.LastRun != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, err := s.Get(JobName)
+	require.Nil(t, err)
+	require.Equal(t, schedule.RunOK, status.LastRun.Status)
+	require.Len(t, runner.args, 3)
+	require.True(t, r.exist(time.Date(2024, time.March, 14, 0, 0, 0, 0, time.Local)))
+
+	// ... but only once
+	require.False(t, r.missing())
+
+	// runs are skipped once the context is cancelled
+	cancel()
+	require.ErrorIs(t, r.run(ctx, r.now()), context.Canceled)
+	require.Len(t, runner.args, 3)
+	s.Wait()
+}
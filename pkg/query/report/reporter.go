@@ -0,0 +1,327 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/schedule"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// DefaultTimeOfDay denotes the default (local) time of day the reports of the previous day are
+	// generated at, leaving time for the last rotation of the day to be written by all hosts
+	DefaultTimeOfDay = "00:15"
+
+	// DefaultRetention denotes the default number of days reports are retained for
+	DefaultRetention = 30
+
+	// JobName denotes the name of the scheduler job generating the reports
+	JobName = "daily-reports"
+
+	timeOfDayFormat = "15:04"
+)
+
+var (
+	errorNoDir          = errors.New("no report directory provided")
+	errorNoReports      = errors.New("no reports provided")
+	errorDuplicateName  = errors.New("duplicate report name")
+	errorInvalidRetain  = errors.New("report retention must not be negative")
+	errorInvalidTimeDay = errors.New("time of day must be of the form HH:MM")
+)
+
+// Reporter generates the configured reports of the previous day once a day, writing each of them to
+// a dated file per format (<dir>/<name>_<YYYY-MM-DD>.<format>) and removing the files of reports
+// older than the retention period
+type Reporter struct {
+	runner  query.Runner
+	dir     string
+	reports []Config
+
+	timeOfDay string
+	offset    time.Duration // offset of the time of day from midnight
+	spec      string        // cron expression activating the reports at the time of day
+	retention int           // number of days, 0 retaining reports indefinitely
+
+	now func() time.Time
+}
+
+// Option configures the reporter
+type Option func(*Reporter)
+
+// WithTimeOfDay sets the (local) time of day (HH:MM) the reports are generated at
+func WithTimeOfDay(timeOfDay string) Option {
+	return func(r *Reporter) {
+		r.timeOfDay = timeOfDay
+	}
+}
+
+// WithRetention sets the number of days reports are retained for (0 retains them indefinitely)
+func WithRetention(days int) Option {
+	return func(r *Reporter) {
+		r.retention = days
+	}
+}
+
+// New creates a new reporter running the queries of the reports against the runner
+func New(runner query.Runner, dir string, reports []Config, opts ...Option) (*Reporter, error) {
+	r := &Reporter{
+		runner:    runner,
+		dir:       filepath.Clean(dir),
+		reports:   reports,
+		timeOfDay: DefaultTimeOfDay,
+		retention: DefaultRetention,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if dir == "" {
+		return nil, errorNoDir
+	}
+	if len(r.reports) == 0 {
+		return nil, errorNoReports
+	}
+	names := make(map[string]struct{}, len(r.reports))
+	for i := range r.reports {
+		if err := r.reports[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid report %q: %w", r.reports[i].Name, err)
+		}
+		if _, exists := names[r.reports[i].Name]; exists {
+			return nil, fmt.Errorf("%w: %s", errorDuplicateName, r.reports[i].Name)
+		}
+		names[r.reports[i].Name] = struct{}{}
+	}
+	if r.retention < 0 {
+		return nil, errorInvalidRetain
+	}
+	t, err := time.Parse(timeOfDayFormat, r.timeOfDay)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", errorInvalidTimeDay, r.timeOfDay)
+	}
+	r.offset = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	r.spec = fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour())
+
+	return r, nil
+}
+
+// Register schedules the daily generation of the reports as a job of the scheduler. If the reports of
+// the most recent day are missing (e.g. because the server wasn't running at the time), the job is
+// triggered right away
+func (r *Reporter) Register(s *schedule.Scheduler) error {
+	if err := s.RegisterFunc(JobName, r.spec, r.run); err != nil {
+		return err
+	}
+	if r.missing() {
+		return s.Trigger(JobName)
+	}
+	return nil
+}
+
+// Generate generates all reports covering the given (local) day, returning the paths of the files written
+func (r *Reporter) Generate(ctx context.Context, day time.Time) (paths []string, err error) {
+	first, last := startOfDay(day), startOfDay(day).AddDate(0, 0, 1)
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	var errs []error
+	for i := range r.reports {
+		cfg := &r.reports[i]
+
+		// blocks are stamped with the end of the interval they cover, hence the blocks of the day are the
+		// ones stamped after midnight up to (and including) the following midnight
+		result, err := r.runner.Run(ctx, cfg.args(first.Add(time.Second), last))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to run query of report %s: %w", cfg.Name, err))
+			continue
+		}
+		if result.Status.Code == types.StatusError && cfg.Kind != KindErrors {
+			errs = append(errs, fmt.Errorf("query of report %s returned error: %s", cfg.Name, result.Status.Message))
+			continue
+		}
+
+		report := newReport(cfg, first, first, last, r.now(), result)
+		for _, format := range cfg.formats() {
+			body, err := report.Render(format)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to render report %s as %s: %w", cfg.Name, format, err))
+				continue
+			}
+			path := r.path(cfg.Name, first, format)
+			if err := writeFile(path, body); err != nil {
+				errs = append(errs, fmt.Errorf("failed to write report %s: %w", cfg.Name, err))
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, errors.Join(errs...)
+}
+
+// Prune removes the files of all reports covering a day before the retention period (relative to the
+// given day), returning the number of files removed
+func (r *Reporter) Prune(day time.Time) (n int, err error) {
+	if r.retention == 0 {
+		return 0, nil
+	}
+	cutoff := startOfDay(day).AddDate(0, 0, -r.retention)
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		reportDay, ok := r.parseFileName(entry.Name())
+		if !ok || !reportDay.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(r.dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+////////////////////////////////////////////////////////////////////////
+
+// run generates the reports of the day preceding the most recent activation and prunes outdated ones
+func (r *Reporter) run(ctx context.Context, _ time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	day := reportDay(r.last(r.now()))
+	logger := logging.FromContext(ctx).With("day", day.Format(dayFormat), "dir", r.dir)
+
+	paths, err := r.Generate(ctx, day)
+	if err != nil {
+		err = fmt.Errorf("failed to generate reports: %w", err)
+	}
+	if len(paths) > 0 {
+		logger.With("files", len(paths)).Info("generated daily reports")
+	}
+
+	n, pruneErr := r.Prune(day)
+	if pruneErr != nil {
+		pruneErr = fmt.Errorf("failed to remove outdated reports: %w", pruneErr)
+	}
+	if n > 0 {
+		logger.With("files", n).Info("removed outdated reports")
+	}
+	return errors.Join(err, pruneErr)
+}
+
+// missing returns if the reports due at the most recent activation are missing
+func (r *Reporter) missing() bool {
+	return !r.exist(reportDay(r.last(r.now())))
+}
+
+// last returns the most recent activation at or before t
+func (r *Reporter) last(t time.Time) time.Time {
+	return r.next(t).AddDate(0, 0, -1)
+}
+
+// next returns the first activation strictly after t
+func (r *Reporter) next(t time.Time) time.Time {
+	next := startOfDay(t).Add(r.offset)
+	for !next.After(t) {
+		next = startOfDay(next.AddDate(0, 0, 1)).Add(r.offset)
+	}
+	return next
+}
+
+// exist returns if the files of all reports covering the given day exist
+func (r *Reporter) exist(day time.Time) bool {
+	for _, cfg := range r.reports {
+		for _, format := range cfg.formats() {
+			if _, err := os.Stat(r.path(cfg.Name, day, format)); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *Reporter) path(name string, day time.Time, format Format) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s_%s.%s", name, day.Format(dayFormat), format))
+}
+
+// parseFileName returns the day covered by a report file (if it belongs to one of the reports)
+func (r *Reporter) parseFileName(fileName string) (time.Time, bool) {
+	for _, cfg := range r.reports {
+		rest, found := strings.CutPrefix(fileName, cfg.Name+"_")
+		if !found {
+			continue
+		}
+		dayStr, format, found := strings.Cut(rest, ".")
+		if !found {
+			continue
+		}
+		switch Format(format) {
+		case FormatHTML, FormatCSV, FormatJSON:
+		default:
+			continue
+		}
+		day, err := time.ParseInLocation(dayFormat, dayStr, time.Local)
+		if err != nil {
+			continue
+		}
+		return day, true
+	}
+	return time.Time{}, false
+}
+
+// reportDay returns the day covered by the reports generated at an activation time (the previous day)
+func reportDay(activation time.Time) time.Time {
+	return startOfDay(startOfDay(activation).Add(-time.Hour))
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// writeFile writes a file atomically (via a temporary file in the same directory), so incomplete
+// reports are never exposed
+func writeFile(path string, body []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// #nosec G302
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,250 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears limits the search for the next activation time of a cron expression which
+// can never be satisfied (e.g. "0 0 30 2 *")
+const maxSearchYears = 5
+
+var (
+	errorEmptySpec      = errors.New("empty schedule specification")
+	errorInvalidNumFlds = errors.New("cron expression must consist of exactly five fields (minute hour day-of-month month day-of-week)")
+	errorInvalidEvery   = errors.New("@every requires a positive duration of at least one second")
+)
+
+// Spec determines the activation times of a scheduled job
+type Spec interface {
+
+	// Next returns the first activation time strictly after t. If there is none, the
+	// zero time is returned
+	Next(t time.Time) time.Time
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSpec parses a schedule specification. Supported are standard five-field cron
+// expressions (supporting *, lists, ranges and steps), the descriptors @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly as well as fixed intervals of the form
+// "@every <duration>" (e.g. "@every 15m")
+func ParseSpec(spec string) (Spec, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errorEmptySpec
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errorInvalidEvery, err)
+		}
+		if interval < time.Second {
+			return nil, errorInvalidEvery
+		}
+		return every(interval), nil
+	}
+	if expr, exists := descriptors[spec]; exists {
+		spec = expr
+	}
+
+	return parseCron(spec)
+}
+
+// every activates a job in fixed intervals
+type every time.Duration
+
+// Next implements the Spec interface
+func (e every) Next(t time.Time) time.Time {
+	interval := time.Duration(e)
+	return t.Truncate(time.Second).Add(interval)
+}
+
+// bitset stores the allowed values of a cron field
+type bitset uint64
+
+func (b bitset) has(i int) bool {
+	return b&(1<<uint(i)) > 0
+}
+
+// cron is a parsed five-field cron expression
+type cron struct {
+	minute, hour, dom, month, dow bitset
+
+	// domStar / dowStar track if the day fields were unrestricted, which determines
+	// how they are combined (see crontab(5))
+	domStar, dowStar bool
+}
+
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteBounds = fieldBounds{"minute", 0, 59}
+	hourBounds   = fieldBounds{"hour", 0, 23}
+	domBounds    = fieldBounds{"day-of-month", 1, 31}
+	monthBounds  = fieldBounds{"month", 1, 12}
+	dowBounds    = fieldBounds{"day-of-week", 0, 7}
+)
+
+func parseCron(expr string) (*cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errorInvalidNumFlds
+	}
+
+	var (
+		c   = new(cron)
+		err error
+	)
+	if c.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+
+	// Sunday may be specified as either 0 or 7
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	c.domStar, c.dowStar = fields[2] == "*", fields[4] == "*"
+
+	return c, nil
+}
+
+// parseField parses a comma-separated list of values, ranges (a-b) and steps (*/n, a-b/n)
+func parseField(field string, bounds fieldBounds) (bitset, error) {
+	var bits bitset
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, bounds.name)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = bounds.min, bounds.max
+		case strings.Contains(rng, "-"):
+			loStr, hiStr, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(loStr, bounds); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(hiStr, bounds); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rng, bounds.name)
+			}
+		default:
+			var err error
+			if lo, err = parseValue(rng, bounds); err != nil {
+				return 0, err
+			}
+			hi = lo
+
+			// a single value with step (e.g. 5/15) runs until the end of the range
+			if hasStep {
+				hi = bounds.max
+			}
+		}
+
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, bounds fieldBounds) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, bounds.name)
+	}
+	if v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d] in %s field", v, bounds.min, bounds.max, bounds.name)
+	}
+	return v, nil
+}
+
+// dayMatches checks the day-of-month and day-of-week fields. If both are restricted, a day
+// matches if either of them does
+func (c *cron) dayMatches(t time.Time) bool {
+	domMatch, dowMatch := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next implements the Spec interface
+func (c *cron) Next(t time.Time) time.Time {
+	// start at the next full minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + maxSearchYears
+
+	loc := t.Location()
+
+	// each time a field is advanced, all lower order fields are reset and the search restarts
+	// at the month field so that wrap-arounds are handled correctly
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for !c.month.has(int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for !c.hour.has(t.Hour()) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for !c.minute.has(t.Minute()) {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpec(t *testing.T) {
+	var tests = []struct {
+		spec  string
+		valid bool
+	}{
+		{"* * * * *", true},
+		{"*/15 0-6,22 * * 1-5", true},
+		{"5/10 * * * *", true},
+		{"0 0 1 1 7", true},
+		{"@daily", true},
+		{"@every 90s", true},
+		{"", false},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+		{"a * * * *", false},
+		{"@fortnightly", false},
+		{"@every 500ms", false},
+		{"@every soon", false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := ParseSpec(test.spec)
+			if test.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestSpecNext(t *testing.T) {
+	// Wednesday
+	ref := time.Date(2023, time.March, 15, 10, 17, 42, 0, time.UTC)
+
+	var tests = []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2023, time.March, 15, 10, 25, 0, 0, time.UTC)},
+		{"0 6 * * *", time.Date(2023, time.March, 16, 6, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2023, time.March, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)},
+
+		// day-of-month and day-of-week are combined if both are restricted
+		{"0 12 1 * 5", time.Date(2023, time.March, 17, 12, 0, 0, 0, time.UTC)},
+
+		{"@every 1h30m", ref.Truncate(time.Second).Add(90 * time.Minute)},
+
+		// impossible date
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.spec, func(t *testing.T) {
+			spec, err := ParseSpec(test.spec)
+			require.Nil(t, err)
+			require.Equal(t, test.expected, spec.Next(ref))
+		})
+	}
+}
@@ -0,0 +1,591 @@
+// Package schedule provides a scheduler for recurring queries. Each job runs a query according
+// to a cron specification and delivers its result to one or more sinks (file, webhook, email).
+// The scheduler keeps a bounded run history per job and can alert a webhook if a job fails
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
+)
+
+const (
+	// DefaultHistorySize denotes the default number of runs kept per job
+	DefaultHistorySize = 50
+
+	redactedValue = "<redacted>"
+)
+
+var (
+	// ErrJobExists is returned if a job with the same name is already registered
+	ErrJobExists = errors.New("job already exists")
+	// ErrJobNotFound is returned if no job with the provided name is registered
+	ErrJobNotFound = errors.New("job not found")
+	// ErrRunPending is returned if a job is triggered while a triggered run is still pending
+	ErrRunPending = errors.New("run already pending")
+	// ErrSinkNotPermitted is returned if a restricted job delivers to a sink it is not permitted to use
+	ErrSinkNotPermitted = errors.New("sink not permitted")
+
+	errorNoName       = errors.New("no job name provided")
+	errorNoQueryArgs  = errors.New("no query arguments provided")
+	errorNoSinks      = errors.New("at least one sink must be provided")
+	errorInvalidAlert = errors.New("alert threshold must not be negative")
+)
+
+// Job defines a recurring query
+type Job struct {
+	Name string `json:"name" yaml:"name"` // Name: unique identifier of the job. Example: "daily-top-talkers"
+	Spec string `json:"spec" yaml:"spec"` // Spec: cron expression, descriptor or interval determining when the job runs. Example: "0 6 * * 1-5"
+
+	// Args are the query arguments. Relative time specifications (e.g. first: -24h) are
+	// evaluated anew for every run
+	Args *query.Args `json:"query" yaml:"query"`
+
+	// Sinks define where the result of each run is delivered to
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"`
+
+	// OnFailure defines a webhook which is alerted once a job has failed AlertAfter
+	// consecutive times, as well as when it recovers
+	OnFailure *push.Target `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// AlertAfter denotes the number of consecutive failures after which an alert is sent.
+	// Defaults to 1
+	AlertAfter int `json:"alert_after,omitempty" yaml:"alert_after,omitempty"`
+
+	// task is run instead of a query (c.f. RegisterFunc)
+	task TaskFunc
+}
+
+// TaskFunc is a task run by a job instead of a query. It is provided with the start time of the run
+type TaskFunc func(ctx context.Context, started time.Time) error
+
+// Validate checks that the job can be scheduled
+func (j *Job) Validate() error {
+	if j.Name == "" {
+		return errorNoName
+	}
+	if _, err := ParseSpec(j.Spec); err != nil {
+		return err
+	}
+	if j.task != nil {
+		return j.validateAlert()
+	}
+	if j.Args == nil {
+		return errorNoQueryArgs
+	}
+	if _, err := j.Args.Prepare(); err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	if len(j.Sinks) == 0 {
+		return errorNoSinks
+	}
+	for i, sink := range j.Sinks {
+		if err := sink.Validate(); err != nil {
+			return fmt.Errorf("invalid sink %d: %w", i, err)
+		}
+	}
+	return j.validateAlert()
+}
+
+func (j *Job) validateAlert() error {
+	if j.OnFailure != nil {
+		if err := j.OnFailure.Validate(); err != nil {
+			return fmt.Errorf("invalid failure alert target: %w", err)
+		}
+	}
+	if j.AlertAfter < 0 {
+		return errorInvalidAlert
+	}
+	return nil
+}
+
+// redacted returns a copy of the job with all credentials (email passwords, tokens, header values)
+// masked, so it can be exposed e.g. via an API
+func (j Job) redacted() Job {
+	j.Sinks = append([]SinkConfig(nil), j.Sinks...)
+	for i, sink := range j.Sinks {
+		if sink.Webhook != nil {
+			j.Sinks[i].Webhook = redactTarget(sink.Webhook)
+		}
+		if sink.Influx != nil {
+			j.Sinks[i].Influx = redactInfluxTarget(sink.Influx)
+		}
+		if sink.Email != nil && sink.Email.Password != "" {
+			email := *sink.Email
+			email.Password = redactedValue
+			j.Sinks[i].Email = &email
+		}
+	}
+	if j.OnFailure != nil {
+		j.OnFailure = redactTarget(j.OnFailure)
+	}
+	return j
+}
+
+func redactTarget(target *push.Target) *push.Target {
+	redacted := *target
+	if len(target.Headers) > 0 {
+		redacted.Headers = make(map[string]string, len(target.Headers))
+		for k := range target.Headers {
+			redacted.Headers[k] = redactedValue
+		}
+	}
+	return &redacted
+}
+
+func redactInfluxTarget(target *push.InfluxTarget) *push.InfluxTarget {
+	redacted := *target
+	if target.Token != "" {
+		redacted.Token = redactedValue
+	}
+	if len(target.Headers) > 0 {
+		redacted.Headers = make(map[string]string, len(target.Headers))
+		for k := range target.Headers {
+			redacted.Headers[k] = redactedValue
+		}
+	}
+	return &redacted
+}
+
+// RunStatus denotes the outcome of a run
+type RunStatus string
+
+const (
+	// RunOK denotes a run that completed successfully
+	RunOK RunStatus = "ok"
+	// RunFailed denotes a run in which either the query or the delivery to a sink failed
+	RunFailed RunStatus = "failed"
+)
+
+// Run describes a single execution of a job
+type Run struct {
+	Started  time.Time     `json:"started"`         // Started: the time the run started. Example: "2023-01-01T06:00:00Z"
+	Duration time.Duration `json:"duration_ns"`     // Duration: the duration of the run in nanoseconds
+	Status   RunStatus     `json:"status"`          // Status: the outcome of the run. Example: "ok"
+	Error    string        `json:"error,omitempty"` // Error: the error(s) that occurred during the run
+	Hits     int           `json:"hits"`            // Hits: the total number of flow records matching the query
+}
+
+// JobStatus describes a registered job alongside its runtime state
+type JobStatus struct {
+	Job
+
+	NextRun             time.Time `json:"next_run,omitempty"`   // NextRun: the next scheduled activation of the job
+	ConsecutiveFailures int       `json:"consecutive_failures"` // ConsecutiveFailures: the number of consecutive failed runs
+	LastRun             *Run      `json:"last_run,omitempty"`   // LastRun: the most recent run
+	History             []Run     `json:"history,omitempty"`    // History: the recent runs, most recent first
+}
+
+// AlertState denotes the state reported by an alert
+type AlertState string
+
+const (
+	// AlertFailing is reported once a job reaches its failure threshold
+	AlertFailing AlertState = "failing"
+	// AlertRecovered is reported once a previously failing job succeeds again
+	AlertRecovered AlertState = "recovered"
+)
+
+// Alert is the payload POSTed to the failure alert target of a job
+type Alert struct {
+	Job                 string     `json:"job"`
+	State               AlertState `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Run                 Run        `json:"run"`
+}
+
+// Scheduler runs registered jobs according to their specification
+type Scheduler struct {
+	runner       query.Runner
+	historySize  int
+	fileDir      string
+	allowedHosts []string
+
+	ctx context.Context
+
+	mu   sync.RWMutex
+	jobs map[string]*jobState
+	wg   sync.WaitGroup
+}
+
+// Option configures the scheduler
+type Option func(*Scheduler)
+
+// WithHistorySize sets the number of runs kept per job
+func WithHistorySize(n int) Option {
+	return func(s *Scheduler) {
+		if n > 0 {
+			s.historySize = n
+		}
+	}
+}
+
+// WithFileDir sets the base directory of file sinks. Relative paths are resolved against it and absolute
+// paths must lie within it. Without a base directory, file sinks are rejected
+func WithFileDir(dir string) Option {
+	return func(s *Scheduler) {
+		if dir != "" {
+			s.fileDir = filepath.Clean(dir)
+		}
+	}
+}
+
+// WithAllowedHosts sets the hosts (host or host:port) restricted jobs may deliver results / alerts to
+func WithAllowedHosts(hosts []string) Option {
+	return func(s *Scheduler) {
+		s.allowedHosts = hosts
+	}
+}
+
+// New creates a new scheduler running queries against the runner. All jobs are stopped
+// once the context is cancelled
+func New(ctx context.Context, runner query.Runner, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		runner:      runner,
+		historySize: DefaultHistorySize,
+		ctx:         ctx,
+		jobs:        make(map[string]*jobState),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type jobState struct {
+	job  Job
+	spec Spec
+
+	cancel  context.CancelFunc
+	trigger chan struct{}
+
+	mu                  sync.Mutex
+	nextRun             time.Time
+	consecutiveFailures int
+	alerting            bool
+	history             []Run
+}
+
+func (st *jobState) status() JobStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := JobStatus{
+		Job:                 st.job.redacted(),
+		NextRun:             st.nextRun,
+		ConsecutiveFailures: st.consecutiveFailures,
+		History:             make([]Run, len(st.history)),
+	}
+	copy(status.History, st.history)
+	if len(status.History) > 0 {
+		status.LastRun = &status.History[0]
+	}
+	return status
+}
+
+// Register validates and schedules a job
+func (s *Scheduler) Register(job Job) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+	sinks, err := s.resolveFileSinks(job.Sinks)
+	if err != nil {
+		return err
+	}
+	job.Sinks = sinks
+
+	spec, err := ParseSpec(job.Spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	st := &jobState{
+		job:     job,
+		spec:    spec,
+		cancel:  cancel,
+		trigger: make(chan struct{}, 1),
+		nextRun: spec.Next(time.Now()),
+	}
+	s.jobs[job.Name] = st
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx, st)
+	}()
+
+	return nil
+}
+
+// RegisterRestricted validates and schedules a job defined by an untrusted party (e.g. via the API).
+// Such jobs may neither write files nor send emails and may only deliver results / alerts to the
+// allowed hosts
+func (s *Scheduler) RegisterRestricted(job Job) error {
+	for _, sink := range job.Sinks {
+		if sink.File != nil || sink.Email != nil {
+			return fmt.Errorf("%w: %s", ErrSinkNotPermitted, sink.String())
+		}
+		if sink.Webhook != nil && !s.isAllowedURL(sink.Webhook.URL) {
+			return fmt.Errorf("%w: %s", ErrSinkNotPermitted, sink.String())
+		}
+		if sink.Influx != nil && !s.isAllowedURL(sink.Influx.URL) {
+			return fmt.Errorf("%w: %s", ErrSinkNotPermitted, sink.String())
+		}
+	}
+	if job.OnFailure != nil && !s.isAllowedURL(job.OnFailure.URL) {
+		return fmt.Errorf("%w: failure alert target %s", ErrSinkNotPermitted, job.OnFailure.URL)
+	}
+	return s.Register(job)
+}
+
+// RegisterFunc schedules a job running a task instead of a query. Its runs are recorded and alerted
+// upon like the ones of any other job
+func (s *Scheduler) RegisterFunc(name, spec string, task TaskFunc) error {
+	return s.Register(Job{Name: name, Spec: spec, task: task})
+}
+
+// Remove stops and removes a job. Runs already in progress are cancelled
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, exists := s.jobs[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	st.cancel()
+	delete(s.jobs, name)
+
+	return nil
+}
+
+// Get returns the status of a single job
+func (s *Scheduler) Get(name string) (JobStatus, error) {
+	s.mu.RLock()
+	st, exists := s.jobs[name]
+	s.mu.RUnlock()
+
+	if !exists {
+		return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return st.status(), nil
+}
+
+// List returns the status of all jobs, sorted by name
+func (s *Scheduler) List() []JobStatus {
+	s.mu.RLock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, st := range s.jobs {
+		statuses = append(statuses, st.status())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Trigger runs a job immediately, independent of its schedule. The run is performed
+// asynchronously; its outcome is recorded in the job's history
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.RLock()
+	st, exists := s.jobs[name]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	select {
+	case st.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrRunPending
+	}
+}
+
+// Wait blocks until all jobs have terminated, i.e. until the scheduler's context is cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, st *jobState) {
+	logger := logging.FromContext(ctx).With("job", st.job.Name, "spec", st.job.Spec)
+	logger.Info("scheduled job")
+
+	for {
+		var (
+			timer  *time.Timer
+			timerC <-chan time.Time
+		)
+
+		next := st.spec.Next(time.Now())
+		if next.IsZero() {
+			logger.Warn("job has no future activation time, it will only run if triggered")
+		} else {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+
+		st.mu.Lock()
+		st.nextRun = next
+		st.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			logger.Info("stopping job")
+			return
+		case <-st.trigger:
+			logger.Info("job triggered manually")
+		case <-timerC:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		s.execute(ctx, st)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, st *jobState) {
+	logger := logging.FromContext(ctx).With("job", st.job.Name)
+
+	run := Run{Started: time.Now(), Status: RunOK}
+	hits, err := s.runJob(ctx, st.job, run.Started)
+	run.Duration = time.Since(run.Started)
+	run.Hits = hits
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		logger.Errorf("job run failed: %v", err)
+	} else {
+		logger.With("duration", run.Duration, "hits", hits).Info("job run completed")
+	}
+
+	st.mu.Lock()
+	st.history = append([]Run{run}, st.history...)
+	if len(st.history) > s.historySize {
+		st.history = st.history[:s.historySize]
+	}
+
+	var alert *Alert
+	threshold := st.job.AlertAfter
+	if threshold == 0 {
+		threshold = 1
+	}
+	if run.Status == RunFailed {
+		st.consecutiveFailures++
+		if st.consecutiveFailures == threshold {
+			st.alerting = true
+			alert = &Alert{Job: st.job.Name, State: AlertFailing, ConsecutiveFailures: st.consecutiveFailures, Run: run}
+		}
+	} else {
+		if st.alerting {
+			alert = &Alert{Job: st.job.Name, State: AlertRecovered, ConsecutiveFailures: st.consecutiveFailures, Run: run}
+		}
+		st.alerting = false
+		st.consecutiveFailures = 0
+	}
+	st.mu.Unlock()
+
+	if alert != nil && st.job.OnFailure != nil {
+		if err := sendAlert(ctx, st.job.OnFailure, alert); err != nil {
+			logger.Errorf("failed to send %s alert: %v", alert.State, err)
+		}
+	}
+}
+
+// runJob runs the query of the job and delivers its result to all sinks (or runs its task instead)
+func (s *Scheduler) runJob(ctx context.Context, job Job, started time.Time) (int, error) {
+	if job.task != nil {
+		return 0, job.task(ctx, started)
+	}
+
+	// work on a copy, since the runner may modify the arguments
+	queryArgs := *job.Args
+
+	stmt, err := queryArgs.Prepare()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare query: %w", err)
+	}
+	result, err := s.runner.Run(ctx, &queryArgs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run query: %w", err)
+	}
+	if result.Status.Code == types.StatusError {
+		return 0, fmt.Errorf("query returned error: %s", result.Status.Message)
+	}
+
+	var errs []error
+	for _, sink := range job.Sinks {
+		if err := sink.deliver(ctx, job.Name, started, stmt, result); err != nil {
+			errs = append(errs, fmt.Errorf("failed to deliver to %s: %w", sink.String(), err))
+		}
+	}
+	return result.Summary.Hits.Total, errors.Join(errs...)
+}
+
+// isAllowedURL returns if the URL targets one of the allowed hosts
+func (s *Scheduler) isAllowedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, host := range s.allowedHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFileSinks returns a copy of the sinks with the paths of all file sinks resolved against the
+// base directory
+func (s *Scheduler) resolveFileSinks(sinks []SinkConfig) ([]SinkConfig, error) {
+	resolved := append([]SinkConfig(nil), sinks...)
+	for i, sink := range resolved {
+		if sink.File == nil {
+			continue
+		}
+		if s.fileDir == "" {
+			return nil, errorNoFileDir
+		}
+		path, err := resolvePath(s.fileDir, sink.File.Path)
+		if err != nil {
+			return nil, err
+		}
+		file := *sink.File
+		file.Path = path
+		resolved[i].File = &file
+	}
+	return resolved, nil
+}
+
+func sendAlert(ctx context.Context, target *push.Target, alert *Alert) error {
+	p, err := target.Pusher()
+	if err != nil {
+		return err
+	}
+	return p.PushJSON(ctx, alert)
+}
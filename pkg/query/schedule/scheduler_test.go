@@ -0,0 +1,361 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/results"
+	"github.com/els0r/goProbe/pkg/types"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+// neverSpec is a valid spec which does not fire during tests, runs are triggered manually
+const neverSpec = "0 0 1 1 *"
+
+type mockRunner struct {
+	fail atomic.Bool
+}
+
+func (m *mockRunner) Run(_ context.Context, _ *query.Args) (*results.Result, error) {
+	if m.fail.Load() {
+		return nil, errors.New("query failed")
+	}
+	return &results.Result{
+		Status:  results.Status{Code: types.StatusOK},
+		Summary: results.Summary{Hits: results.Hits{Total: 1, Displayed: 1}},
+		Rows: results.Rows{
+			{Attributes: results.Attributes{DstPort: 443}, Counters: types.Counters{BytesRcvd: 1024, PacketsRcvd: 1}},
+		},
+	}, nil
+}
+
+func testArgs() *query.Args {
+	args := query.DefaultArgs()
+	args.Query = "dport"
+	args.Ifaces = "eth0"
+	args.Format = "json"
+	return args
+}
+
+// triggerAndWait triggers a job and polls its status until the run has been recorded
+func triggerAndWait(t *testing.T, s *Scheduler, name string) JobStatus {
+	t.Helper()
+
+	status, err := s.Get(name)
+	require.Nil(t, err)
+
+	var prev time.Time
+	if status.LastRun != nil {
+		prev = status.LastRun.Started
+	}
+	require.Nil(t, s.Trigger(name))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err = s.Get(name)
+		require.Nil(t, err)
+		if status.LastRun != nil && status.LastRun.Started != prev {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for run of job %s", name)
+	return JobStatus{}
+}
+
+func TestJobValidation(t *testing.T) {
+	fileSink := []SinkConfig{{File: &FileSinkConfig{Path: "/tmp/out.json"}}}
+
+	var tests = []struct {
+		name string
+		job  Job
+	}{
+		{"no name", Job{Spec: neverSpec, Args: testArgs(), Sinks: fileSink}},
+		{"invalid spec", Job{Name: "test", Spec: "* *", Args: testArgs(), Sinks: fileSink}},
+		{"no query", Job{Name: "test", Spec: neverSpec, Sinks: fileSink}},
+		{"no sinks", Job{Name: "test", Spec: neverSpec, Args: testArgs()}},
+		{"multiple sink types", Job{Name: "test", Spec: neverSpec, Args: testArgs(), Sinks: []SinkConfig{
+			{File: &FileSinkConfig{Path: "/tmp/out.json"}, Webhook: &push.Target{URL: "http://localhost"}},
+		}}},
+		{"invalid webhook", Job{Name: "test", Spec: neverSpec, Args: testArgs(), Sinks: []SinkConfig{
+			{Webhook: &push.Target{URL: "ftp://localhost"}},
+		}}},
+		{"email without recipients", Job{Name: "test", Spec: neverSpec, Args: testArgs(), Sinks: []SinkConfig{
+			{Email: &EmailSinkConfig{Server: "localhost:25", From: "goprobe@example.com"}},
+		}}},
+		{"email header injection", Job{Name: "test", Spec: neverSpec, Args: testArgs(), Sinks: []SinkConfig{
+			{Email: &EmailSinkConfig{Server: "localhost:25", From: "goprobe@example.com", To: []string{"a@example.com\r\nBcc: b@example.com"}}},
+		}}},
+		{"invalid alert target", Job{Name: "test", Spec: neverSpec, Args: testArgs(), Sinks: fileSink, OnFailure: &push.Target{}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.NotNil(t, test.job.Validate())
+		})
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	var tests = []struct {
+		path         string
+		expectedPath string
+		expectedErr  error
+	}{
+		{"out.json", "/var/reports/out.json", nil},
+		{"daily/{time}.json", "/var/reports/daily/{time}.json", nil},
+		{"/var/reports/daily/out.json", "/var/reports/daily/out.json", nil},
+		{"/var/reports/./out.json", "/var/reports/out.json", nil},
+		{"../out.json", "", errorPathOutside},
+		{"daily/../../out.json", "", errorPathOutside},
+		{"/var/reports/../out.json", "", errorPathOutside},
+		{"/var/reportsX/out.json", "", errorPathOutside},
+		{"/etc/passwd", "", errorPathOutside},
+		{"/var/reports", "", errorPathOutside},
+		{".", "", errorPathOutside},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.path, func(t *testing.T) {
+			path, err := resolvePath("/var/reports", test.path)
+			require.ErrorIs(t, err, test.expectedErr)
+			require.Equal(t, test.expectedPath, path)
+		})
+	}
+}
+
+func TestRegisterRestricted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(ctx, &mockRunner{}, WithFileDir(t.TempDir()), WithAllowedHosts([]string{"hooks.example.com", "influx.example.com:8086"}))
+
+	var tests = []struct {
+		name        string
+		sinks       []SinkConfig
+		onFailure   *push.Target
+		expectedErr error
+	}{
+		{"webhook", []SinkConfig{{Webhook: &push.Target{URL: "https://hooks.example.com/goprobe"}}}, nil, nil},
+		{"influx", []SinkConfig{{Influx: &push.InfluxTarget{URL: "http://influx.example.com:8086", Bucket: "traffic"}}}, &push.Target{URL: "https://HOOKS.example.com/alerts"}, nil},
+		{"file", []SinkConfig{{File: &FileSinkConfig{Path: "out.json"}}}, nil, ErrSinkNotPermitted},
+		{"email", []SinkConfig{{Email: &EmailSinkConfig{Server: "localhost:25", From: "goprobe@example.com", To: []string{"noc@example.com"}}}}, nil, ErrSinkNotPermitted},
+		{"webhook to other host", []SinkConfig{{Webhook: &push.Target{URL: "http://169.254.169.254/latest/meta-data"}}}, nil, ErrSinkNotPermitted},
+		{"influx on other port", []SinkConfig{{Influx: &push.InfluxTarget{URL: "http://influx.example.com:9999", Bucket: "traffic"}}}, nil, ErrSinkNotPermitted},
+		{"alert to other host", []SinkConfig{{Webhook: &push.Target{URL: "https://hooks.example.com/goprobe"}}}, &push.Target{URL: "http://localhost:8145/alerts"}, ErrSinkNotPermitted},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, s.RegisterRestricted(Job{
+				Name:      test.name,
+				Spec:      neverSpec,
+				Args:      testArgs(),
+				Sinks:     test.sinks,
+				OnFailure: test.onFailure,
+			}), test.expectedErr)
+		})
+	}
+
+	// file sinks of regular jobs are confined to the base directory
+	require.ErrorIs(t, s.Register(Job{
+		Name:  "outside",
+		Spec:  neverSpec,
+		Args:  testArgs(),
+		Sinks: []SinkConfig{{File: &FileSinkConfig{Path: "/etc/passwd"}}},
+	}), errorPathOutside)
+	require.ErrorIs(t, New(ctx, &mockRunner{}).Register(Job{
+		Name:  "no-dir",
+		Spec:  neverSpec,
+		Args:  testArgs(),
+		Sinks: []SinkConfig{{File: &FileSinkConfig{Path: "out.json"}}},
+	}), errorNoFileDir)
+
+	cancel()
+	s.Wait()
+}
+
+func TestJobRedaction(t *testing.T) {
+	job := Job{
+		Name: "test",
+		Sinks: []SinkConfig{
+			{Email: &EmailSinkConfig{Server: "localhost:25", Password: "secret"}},
+			{Webhook: &push.Target{URL: "http://localhost", Headers: map[string]string{"Authorization": "Bearer secret"}}},
+			{Influx: &push.InfluxTarget{URL: "http://localhost:8086", Bucket: "traffic", Token: "secret"}},
+		},
+		OnFailure: &push.Target{URL: "http://localhost", Headers: map[string]string{"Authorization": "Bearer secret"}},
+	}
+
+	redacted := job.redacted()
+	require.Equal(t, redactedValue, redacted.Sinks[0].Email.Password)
+	require.Equal(t, redactedValue, redacted.Sinks[1].Webhook.Headers["Authorization"])
+	require.Equal(t, redactedValue, redacted.Sinks[2].Influx.Token)
+	require.Equal(t, redactedValue, redacted.OnFailure.Headers["Authorization"])
+
+	// the original job must remain untouched
+	require.Equal(t, "secret", job.Sinks[0].Email.Password)
+	require.Equal(t, "Bearer secret", job.Sinks[1].Webhook.Headers["Authorization"])
+	require.Equal(t, "secret", job.Sinks[2].Influx.Token)
+	require.Equal(t, "Bearer secret", job.OnFailure.Headers["Authorization"])
+}
+
+func TestSchedulerFileSink(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	outDir := t.TempDir()
+
+	s := New(ctx, &mockRunner{}, WithHistorySize(2), WithFileDir(outDir))
+	require.Nil(t, s.Register(Job{
+		Name:  "file",
+		Spec:  neverSpec,
+		Args:  testArgs(),
+		Sinks: []SinkConfig{{File: &FileSinkConfig{Path: filepath.Join(outDir, "result.json")}}},
+	}))
+	require.ErrorIs(t, s.Register(Job{
+		Name:  "file",
+		Spec:  neverSpec,
+		Args:  testArgs(),
+		Sinks: []SinkConfig{{File: &FileSinkConfig{Path: filepath.Join(outDir, "result.json")}}},
+	}), ErrJobExists)
+
+	status, err := s.Get("file")
+	require.Nil(t, err)
+	require.False(t, status.NextRun.IsZero())
+	require.Nil(t, status.LastRun)
+
+	for i := 0; i < 3; i++ {
+		status = triggerAndWait(t, s, "file")
+	}
+	require.Len(t, status.History, 2)
+	require.Equal(t, RunOK, status.LastRun.Status)
+	require.Equal(t, 1, status.LastRun.Hits)
+
+	data, err := os.ReadFile(filepath.Join(outDir, "result.json"))
+	require.Nil(t, err)
+
+	var res results.Result
+	require.Nil(t, jsoniter.Unmarshal(data, &res))
+	require.Len(t, res.Rows, 1)
+
+	require.Len(t, s.List(), 1)
+	require.Nil(t, s.Remove("file"))
+	require.ErrorIs(t, s.Remove("file"), ErrJobNotFound)
+	require.ErrorIs(t, s.Trigger("file"), ErrJobNotFound)
+	require.Len(t, s.List(), 0)
+
+	cancel()
+	s.Wait()
+}
+
+func TestSchedulerFailureAlerts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		alerts []Alert
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		require.Nil(t, jsoniter.NewDecoder(r.Body).Decode(&alert))
+
+		mu.Lock()
+		alerts = append(alerts, alert)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	runner := &mockRunner{}
+	runner.fail.Store(true)
+
+	s := New(ctx, runner, WithFileDir(t.TempDir()))
+	require.Nil(t, s.Register(Job{
+		Name:       "alerting",
+		Spec:       neverSpec,
+		Args:       testArgs(),
+		Sinks:      []SinkConfig{{File: &FileSinkConfig{Path: "{time}.json"}}},
+		OnFailure:  &push.Target{URL: srv.URL},
+		AlertAfter: 2,
+	}))
+
+	// first failure does not reach the threshold, the second one does, the third one
+	// does not alert again
+	for i := 0; i < 3; i++ {
+		triggerAndWait(t, s, "alerting")
+	}
+
+	runner.fail.Store(false)
+	status := triggerAndWait(t, s, "alerting")
+
+	require.Equal(t, 0, status.ConsecutiveFailures)
+	require.Equal(t, RunOK, status.History[0].Status)
+	require.Equal(t, RunFailed, status.History[1].Status)
+	require.Equal(t, "failed to run query: query failed", status.History[1].Error)
+
+	// the recovery alert is sent after the run has been recorded
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(alerts)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, alerts, 2)
+	require.Equal(t, AlertFailing, alerts[0].State)
+	require.Equal(t, 2, alerts[0].ConsecutiveFailures)
+	require.Equal(t, AlertRecovered, alerts[1].State)
+	require.Equal(t, "alerting", alerts[1].Job)
+}
+
+func TestSchedulerFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	task := func(_ context.Context, _ time.Time) error {
+		if runs.Add(1) > 1 {
+			return errors.New("task failed")
+		}
+		return nil
+	}
+
+	s := New(ctx, &mockRunner{})
+	require.Nil(t, s.RegisterFunc("task", neverSpec, task))
+	require.ErrorIs(t, s.RegisterFunc("task", neverSpec, task), ErrJobExists)
+	require.ErrorIs(t, s.RegisterFunc("invalid", "", task), errorEmptySpec)
+
+	status := triggerAndWait(t, s, "task")
+	require.Equal(t, RunOK, status.LastRun.Status)
+	require.Nil(t, status.Args)
+
+	status = triggerAndWait(t, s, "task")
+	require.Equal(t, RunFailed, status.LastRun.Status)
+	require.Equal(t, "task failed", status.LastRun.Error)
+	require.Equal(t, 1, status.ConsecutiveFailures)
+	require.EqualValues(t, 2, runs.Load())
+
+	cancel()
+	s.Wait()
+}
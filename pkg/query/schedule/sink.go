@@ -0,0 +1,245 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/query"
+	"github.com/els0r/goProbe/pkg/query/push"
+	"github.com/els0r/goProbe/pkg/results"
+)
+
+const (
+	// TimePlaceholder is replaced by the start time of a run in the path of a file sink
+	TimePlaceholder = "{time}"
+
+	timePlaceholderFormat = "20060102T150405Z"
+
+	defaultSubject = "goProbe scheduled report"
+)
+
+var (
+	errorNoSink        = errors.New("exactly one of file, webhook, influx or email must be configured per sink")
+	errorNoPath        = errors.New("no file path provided")
+	errorNoFileDir     = errors.New("file sinks require a base directory")
+	errorPathOutside   = errors.New("file path must lie within the base directory")
+	errorNoMailServer  = errors.New("no mail server address provided")
+	errorNoSender      = errors.New("no sender address provided")
+	errorNoRecipients  = errors.New("no recipients provided")
+	errorInvalidHeader = errors.New("email addresses and subject must not contain line breaks")
+)
+
+// SinkConfig defines where the result of a scheduled query is delivered to. Exactly one of the
+// sink types must be set
+type SinkConfig struct {
+	File    *FileSinkConfig    `json:"file,omitempty" yaml:"file,omitempty"`
+	Webhook *push.Target       `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Influx  *push.InfluxTarget `json:"influx,omitempty" yaml:"influx,omitempty"`
+	Email   *EmailSinkConfig   `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// FileSinkConfig writes results to a file
+type FileSinkConfig struct {
+	// Path denotes the file to write to, relative to (or lying within) the base directory of
+	// the scheduler. If it contains the placeholder {time}, it is replaced by the (UTC) start
+	// time of the run, creating one file per run
+	Path string `json:"path" yaml:"path"`
+	// Append appends to the file instead of overwriting it
+	Append bool `json:"append,omitempty" yaml:"append,omitempty"`
+}
+
+// EmailSinkConfig sends results via email
+type EmailSinkConfig struct {
+	// Server is the address (host:port) of the SMTP server
+	Server string `json:"server" yaml:"server"`
+	// Username / Password are used for PLAIN authentication against the server. If no
+	// username is provided, no authentication is performed
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+
+	From    string   `json:"from" yaml:"from"`
+	To      []string `json:"to" yaml:"to"`
+	Subject string   `json:"subject,omitempty" yaml:"subject,omitempty"`
+}
+
+// Validate checks that the sink is properly configured
+func (s *SinkConfig) Validate() error {
+	var n int
+	if s.File != nil {
+		n++
+		if s.File.Path == "" {
+			return errorNoPath
+		}
+	}
+	if s.Webhook != nil {
+		n++
+		if err := s.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Influx != nil {
+		n++
+		if err := s.Influx.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Email != nil {
+		n++
+		if err := s.Email.validate(); err != nil {
+			return err
+		}
+	}
+	if n != 1 {
+		return errorNoSink
+	}
+	return nil
+}
+
+func (e *EmailSinkConfig) validate() error {
+	if e.Server == "" {
+		return errorNoMailServer
+	}
+	if _, _, err := net.SplitHostPort(e.Server); err != nil {
+		return fmt.Errorf("invalid mail server address: %w", err)
+	}
+	if e.From == "" {
+		return errorNoSender
+	}
+	if len(e.To) == 0 {
+		return errorNoRecipients
+	}
+	for _, field := range append([]string{e.From, e.Subject}, e.To...) {
+		if strings.ContainsAny(field, "\r\n") {
+			return errorInvalidHeader
+		}
+	}
+	return nil
+}
+
+// String returns a short description of the sink
+func (s *SinkConfig) String() string {
+	switch {
+	case s.File != nil:
+		return "file:" + s.File.Path
+	case s.Webhook != nil:
+		return "webhook:" + s.Webhook.URL
+	case s.Influx != nil:
+		return "influx:" + s.Influx.URL
+	case s.Email != nil:
+		return "email:" + strings.Join(s.Email.To, ",")
+	}
+	return "none"
+}
+
+// deliver renders the result and hands it to the sink
+func (s *SinkConfig) deliver(ctx context.Context, jobName string, started time.Time, stmt *query.Statement, result *results.Result) error {
+	switch {
+	case s.Webhook != nil:
+		p, err := s.Webhook.Pusher()
+		if err != nil {
+			return err
+		}
+		return p.Push(ctx, stmt, result)
+	case s.Influx != nil:
+		return s.Influx.Push(ctx, stmt, result)
+	case s.File != nil:
+		body, _, err := push.Render(ctx, stmt, result)
+		if err != nil {
+			return err
+		}
+		return s.File.write(started, body)
+	case s.Email != nil:
+		body, contentType, err := push.Render(ctx, stmt, result)
+		if err != nil {
+			return err
+		}
+		return s.Email.send(ctx, jobName, started, body, contentType)
+	}
+	return errorNoSink
+}
+
+// resolvePath resolves the path of a file sink against the base directory, rejecting paths referring
+// to a parent directory or lying outside of it
+func resolvePath(dir, path string) (string, error) {
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("%w: %s", errorPathOutside, path)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", errorPathOutside, path)
+	}
+	return path, nil
+}
+
+func (f *FileSinkConfig) write(started time.Time, body []byte) error {
+	path := strings.ReplaceAll(f.Path, TimePlaceholder, started.UTC().Format(timePlaceholderFormat))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if f.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(filepath.Clean(path), flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(body); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (e *EmailSinkConfig) send(ctx context.Context, jobName string, started time.Time, body []byte, contentType string) error {
+	subject := e.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+	subject = fmt.Sprintf("%s: %s (%s)", subject, jobName, started.UTC().Format(time.RFC3339))
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: %s\r\n\r\n", contentType)
+	msg.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, _ := net.SplitHostPort(e.Server)
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	// net/smtp does not support contexts, so the send is performed in the background and
+	// abandoned if the context is cancelled
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- smtp.SendMail(e.Server, auth, e.From, e.To, msg.Bytes())
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errChan:
+		return err
+	}
+}
@@ -22,13 +22,23 @@ type Statement struct {
 	attributes []types.Attribute `json:"-"`
 	Condition  string            `json:"condition,omitempty"`
 
+	// predicate on the counters of the aggregated rows (in canonical form)
+	Having string            `json:"having,omitempty"`
+	having results.Predicate `json:"-"`
+
 	// which direction is added
 	Direction types.Direction `json:"direction"`
 
+	// which counters are aggregated (all of them by default)
+	Counters types.CounterSelector `json:"counters,omitempty"`
+
 	// time selection
 	First int64 `json:"from"`
 	Last  int64 `json:"to"`
 
+	// handling of time ranges cutting through blocks
+	TimeAlign string `json:"time_align,omitempty"`
+
 	// formatting
 	Format        string            `json:"format"`
 	NumResults    uint64            `json:"limit"`
@@ -36,6 +46,36 @@ type Statement struct {
 	SortAscending bool              `json:"sort_ascending,omitempty"`
 	Output        io.Writer         `json:"-"`
 
+	// counter representation
+	HumanReadable        bool `json:"human_readable,omitempty"`
+	DirectionPercentages bool `json:"direction_percentages,omitempty"`
+	Numeric              bool `json:"numeric,omitempty"`
+	FlowHash             bool `json:"flow_hash,omitempty"`
+	CommunityID          bool `json:"community_id,omitempty"`
+	Roles                bool `json:"roles,omitempty"`
+	Stats                bool `json:"stats,omitempty"`
+
+	// seed of the Community ID (if added to the rows)
+	CommunityIDSeed uint16 `json:"community_id_seed,omitempty"`
+
+	// evaluation of the result prior to printing it
+	Analysis string `json:"analysis,omitempty"`
+	ASNDB    string `json:"asn_db,omitempty"`
+
+	// random sample of rows returned instead of the sorted / limited ones
+	Sample int `json:"sample,omitempty"`
+
+	// timestamp representation (the layout is resolved from named formats)
+	TimeZone   string         `json:"tz,omitempty"`
+	TimeFormat string         `json:"time_format,omitempty"`
+	location   *time.Location `json:"-"`
+
+	// representation of flows in line protocol (influxdb format)
+	Influx *results.InfluxMapping `json:"influx,omitempty"`
+
+	// handling of mirrored rows in distributed queries
+	Dedup string `json:"dedup,omitempty"`
+
 	// parameters for external calls
 	Caller string `json:"caller,omitempty"` // who called the query
 
@@ -44,10 +84,19 @@ type Statement struct {
 
 	// file system
 	MaxMemPct int  `json:"max_mem_pct,omitempty"`
+	MaxAggMem int  `json:"max_agg_mem,omitempty"` // in MiB, spilling partial aggregates to disk if exceeded
 	LowMem    bool `json:"low_mem,omitempty"`
+	Mmap      bool `json:"mmap,omitempty"`
+
+	// parallelism of the reading / decompression (IO) and filtering / aggregation (CPU) of blocks
+	IOWorkers  int `json:"io_workers,omitempty"`
+	CPUWorkers int `json:"cpu_workers,omitempty"`
 
 	// request live flow data (in addition to DB)
 	Live bool `json:"live,omitempty"`
+
+	// return the execution plan instead of running the query
+	Explain bool `json:"explain,omitempty"`
 }
 
 // String prints the executable statement in human-readable form
@@ -59,6 +108,9 @@ func (s *Statement) String() string {
 	if s.Condition != "" {
 		str += fmt.Sprintf(", condition: %s", s.Condition)
 	}
+	if s.Having != "" {
+		str += fmt.Sprintf(", having: %s", s.Having)
+	}
 	tFrom, tTo := time.Unix(s.First, 0), time.Unix(s.Last, 0)
 	str += fmt.Sprintf(", limit: %d, from: %s, to: %s",
 		s.NumResults,
@@ -72,6 +124,22 @@ func (s *Statement) String() string {
 	return str
 }
 
+// Location returns the time zone timestamps are printed in (or nil if the local time zone applies)
+func (s *Statement) Location() *time.Location {
+	if s.location == nil && s.TimeZone != "" {
+		s.location, _ = time.LoadLocation(s.TimeZone)
+	}
+	return s.location
+}
+
+// Predicate returns the predicate the aggregated rows are filtered by (or nil if there is none)
+func (s *Statement) Predicate() results.Predicate {
+	if s.having == nil && s.Having != "" {
+		s.having, _ = results.ParsePredicate(s.Having)
+	}
+	return s.having
+}
+
 func (s *Statement) Pretty() string {
 	ifaces := "any"
 	if len(s.Ifaces) > 0 {
@@ -87,6 +155,11 @@ func (s *Statement) Pretty() string {
 condition: %s
 		`, s.Condition)
 	}
+	if s.Having != "" {
+		str += fmt.Sprintf(`
+   having: %s
+`, s.Having)
+	}
 	tFrom, tTo := time.Unix(s.First, 0), time.Unix(s.Last, 0)
 	str += fmt.Sprintf(`
      from: %s
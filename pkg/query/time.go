@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/telemetry/logging"
 )
 
 // TimeFormats stores all supported tie formats
@@ -233,3 +234,27 @@ func ParseTimeArgument(timeString string) (int64, error) {
 
 	return 0, fmt.Errorf("unable to parse time format: %w", err)
 }
+
+// SetDefaultTimeRange handles the defaults for time arguments if they aren't set
+func SetDefaultTimeRange(args *Args) Args {
+	logger := logging.Logger()
+	if args.First == "" {
+		logger.Debug("setting default value for 'first'")
+
+		// protect against queries that are possibly too large and only go back a day if a time attribute
+		// is included. This is only done if first wasn't explicitly set. If it is, it must be assumed that
+		// the caller knows the possible extend of a "time" query
+		if strings.Contains(args.Query, types.TimeName) || strings.Contains(args.Query, types.RawCompoundQuery) {
+			logger.With("query", args.Query).Debug("time attribute detected, limiting time range to one day")
+			args.First = time.Now().AddDate(0, 0, -1).Format(time.ANSIC)
+		} else {
+			// by default, go back one month in time
+			args.First = time.Now().AddDate(0, -1, 0).Format(time.ANSIC)
+		}
+	}
+	if args.Last == "" {
+		logger.Debug("setting default value for 'last'")
+		args.Last = time.Now().Format(time.ANSIC)
+	}
+	return *args
+}
@@ -3,11 +3,39 @@ package query
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/els0r/goProbe/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestParseTimeFormat(t *testing.T) {
+	var tests = []struct {
+		format   string
+		expected string
+		valid    bool
+	}{
+		{"default", "2006-01-02 15:04:05", true},
+		{"RFC3339", time.RFC3339, true},
+		{"unix", "unix", true},
+		{"02.01.2006 15:04", "02.01.2006 15:04", true},
+		{"iso", "", false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.format, func(t *testing.T) {
+			layout, err := ParseTimeFormat(test.format)
+			if !test.valid {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, layout)
+		})
+	}
+}
+
 func TestParseTimestamp(t *testing.T) {
 	var tests = []string{
 		// special cases
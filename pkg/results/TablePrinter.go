@@ -16,13 +16,16 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/els0r/goProbe/pkg/formatting"
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
 	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
 // OutputColumn ranges over all possible output columns.
@@ -42,6 +45,11 @@ const (
 	OutcolDIP
 	OutcolDport
 	OutcolProto
+	OutcolTag
+	OutcolTTLMin
+	OutcolTTLMax
+	OutcolFlowHash
+	OutcolCommunityID
 	// counters
 	OutcolInPkts
 	OutcolInPktsPercent
@@ -61,6 +69,21 @@ const (
 	OutcolBothBytesRcvd
 	OutcolBothBytesSent
 	OutcolBothBytesPercent
+	OutcolBothPktsRcvdPercent
+	OutcolBothPktsSentPercent
+	OutcolBothBytesRcvdPercent
+	OutcolBothBytesSentPercent
+	// traffic by role (replacing sip / dip and the traffic by direction)
+	OutcolHost
+	OutcolRoleSrcPkts
+	OutcolRoleSrcBytes
+	OutcolRoleDstPkts
+	OutcolRoleDstBytes
+	// statistics of the data volume per time bin
+	OutcolStatsP50Bytes
+	OutcolStatsP95Bytes
+	OutcolStatsMaxBytes
+	OutcolStatsStdDevBytes
 	CountOutcol
 )
 
@@ -71,9 +94,15 @@ const (
 
 // columns returns the list of OutputColumns that (might) be printed.
 // timed indicates whether we're supposed to print timestamps. attributes lists
-// all attributes we have to print. d tells us which counters to print.
+// all attributes we have to print. flowHash adds the flow hash after the attributes,
+// communityID the Community ID after it.
+// roles replaces sip / dip by the host and prints its traffic by role instead of by direction.
+// stats adds the statistics of the data volume per time bin after the counters.
+// d tells us which counters to print. directionPct
+// adds percentage columns for each individual direction if both directions are printed.
+// Packet / byte columns are omitted if none of the respective counters were selected.
 // in this function (and some others) ORDER matters
-func columns(selector types.LabelSelector, attributes []types.Attribute, d types.Direction) (cols []OutputColumn) {
+func columns(selector types.LabelSelector, attributes []types.Attribute, flowHash, communityID, roles, stats bool, d types.Direction, directionPct bool, counters types.CounterSelector) (cols []OutputColumn) {
 	if selector.Timestamp {
 		cols = append(cols, OutcolTime)
 	}
@@ -91,15 +120,47 @@ func columns(selector types.LabelSelector, attributes []types.Attribute, d types
 	for _, attrib := range attributes {
 		switch attrib.Name() {
 		case types.SIPName:
+			if roles {
+				cols = append(cols, OutcolHost)
+				continue
+			}
 			cols = append(cols, OutcolSIP)
 		case types.DIPName:
+			if roles {
+				continue
+			}
 			cols = append(cols, OutcolDIP)
 		case types.ProtoName:
 			cols = append(cols, OutcolProto)
 		case types.DportName:
 			cols = append(cols, OutcolDport)
+		case types.TagName:
+			cols = append(cols, OutcolTag)
+		case types.TTLMinName:
+			cols = append(cols, OutcolTTLMin)
+		case types.TTLMaxName:
+			cols = append(cols, OutcolTTLMax)
 		}
 	}
+	if flowHash {
+		cols = append(cols, OutcolFlowHash)
+	}
+	if communityID {
+		cols = append(cols, OutcolCommunityID)
+	}
+
+	if roles {
+		cols = append(cols,
+			OutcolRoleSrcPkts,
+			OutcolRoleSrcBytes,
+			OutcolRoleDstPkts,
+			OutcolRoleDstBytes,
+			OutcolSumPkts,
+			OutcolSumPktsPercent,
+			OutcolSumBytes,
+			OutcolSumBytesPercent)
+		d = types.DirectionUnknown
+	}
 
 	switch d {
 	case types.DirectionIn:
@@ -115,6 +176,20 @@ func columns(selector types.LabelSelector, attributes []types.Attribute, d types
 			OutcolOutBytes,
 			OutcolOutBytesPercent)
 	case types.DirectionBoth:
+		if directionPct {
+			cols = append(cols,
+				OutcolBothPktsRcvd,
+				OutcolBothPktsRcvdPercent,
+				OutcolBothPktsSent,
+				OutcolBothPktsSentPercent,
+				OutcolBothPktsPercent,
+				OutcolBothBytesRcvd,
+				OutcolBothBytesRcvdPercent,
+				OutcolBothBytesSent,
+				OutcolBothBytesSentPercent,
+				OutcolBothBytesPercent)
+			break
+		}
 		cols = append(cols,
 			OutcolBothPktsRcvd,
 			OutcolBothPktsSent,
@@ -130,7 +205,52 @@ func columns(selector types.LabelSelector, attributes []types.Attribute, d types
 			OutcolSumBytesPercent)
 	}
 
-	return
+	if stats {
+		cols = append(cols,
+			OutcolStatsP50Bytes,
+			OutcolStatsP95Bytes,
+			OutcolStatsMaxBytes,
+			OutcolStatsStdDevBytes)
+	}
+
+	if counters.IsAll() {
+		return
+	}
+	selected := cols[:0]
+	for _, col := range cols {
+		if (isPacketsCol(col) && !counters.Packets()) || (isBytesCol(col) && !counters.Bytes()) {
+			continue
+		}
+		selected = append(selected, col)
+	}
+	return selected
+}
+
+func isPacketsCol(col OutputColumn) bool {
+	switch col {
+	case OutcolInPkts, OutcolInPktsPercent,
+		OutcolOutPkts, OutcolOutPktsPercent,
+		OutcolSumPkts, OutcolSumPktsPercent,
+		OutcolBothPktsRcvd, OutcolBothPktsSent, OutcolBothPktsPercent,
+		OutcolBothPktsRcvdPercent, OutcolBothPktsSentPercent,
+		OutcolRoleSrcPkts, OutcolRoleDstPkts:
+		return true
+	}
+	return false
+}
+
+func isBytesCol(col OutputColumn) bool {
+	switch col {
+	case OutcolInBytes, OutcolInBytesPercent,
+		OutcolOutBytes, OutcolOutBytesPercent,
+		OutcolSumBytes, OutcolSumBytesPercent,
+		OutcolBothBytesRcvd, OutcolBothBytesSent, OutcolBothBytesPercent,
+		OutcolBothBytesRcvdPercent, OutcolBothBytesSentPercent,
+		OutcolRoleSrcBytes, OutcolRoleDstBytes,
+		OutcolStatsP50Bytes, OutcolStatsP95Bytes, OutcolStatsMaxBytes, OutcolStatsStdDevBytes:
+		return true
+	}
+	return false
 }
 
 // Formatter provides methods for printing various types/units of values.
@@ -147,6 +267,48 @@ type Formatter interface {
 	String(string) string
 }
 
+// humanFormatter wraps a Formatter and renders data sizes and counts in
+// human-readable units (e.g. 10.00 MiB), leaving all other values untouched
+type humanFormatter struct {
+	Formatter
+}
+
+// Size prints out size using binary (IEC) prefixes
+func (h humanFormatter) Size(size uint64) string {
+	return h.String(formatting.SizeBinary(size))
+}
+
+// Count prints val in concise human-readable form
+func (h humanFormatter) Count(val uint64) string {
+	return h.String(formatting.Count(val))
+}
+
+// TimeFormatUnix denotes the time format printing timestamps as seconds since the epoch
+// (CSV output's default)
+const TimeFormatUnix = "unix"
+
+// timeFormatter wraps a Formatter and renders timestamps in a specific time zone and / or
+// layout, leaving all other values untouched
+type timeFormatter struct {
+	Formatter
+
+	location *time.Location
+	layout   string
+}
+
+// Time prints epoch in the configured layout, converted to the configured time zone (taking
+// into account the daylight saving time in effect at the time)
+func (t timeFormatter) Time(epoch int64) string {
+	if t.layout == TimeFormatUnix {
+		return t.String(strconv.FormatInt(epoch, 10))
+	}
+	ts := time.Unix(epoch, 0)
+	if t.location != nil {
+		ts = ts.In(t.location)
+	}
+	return t.String(ts.Format(t.layout))
+}
+
 func tryLookup(ips2domains map[string]string, ip string) string {
 	if dom, exists := ips2domains[ip]; exists {
 		return dom
@@ -157,8 +319,9 @@ func tryLookup(ips2domains map[string]string, ip string) string {
 // extract extracts the string that needs to be printed for the given OutputColumn.
 // The format argument is used to format the string appropriatly for the desired
 // output format. ips2domains is needed for reverse DNS lookups. totals is needed
-// for percentage calculations. e contains the actual data that is extracted.
-func extract(format Formatter, ips2domains map[string]string, totals types.Counters, row Row, col OutputColumn) string {
+// for percentage calculations. numeric disables the translation of IP protocol numbers
+// to names. e contains the actual data that is extracted.
+func extract(format Formatter, ips2domains map[string]string, totals types.Counters, numeric bool, row Row, col OutputColumn) string {
 	nz := func(u uint64) uint64 {
 		if u == 0 {
 			u = (1 << 64) - 1
@@ -181,25 +344,40 @@ func extract(format Formatter, ips2domains map[string]string, totals types.Count
 	case OutcolDIP:
 		return format.String(tryLookup(ips2domains, row.Attributes.DstIP.String()))
 	case OutcolDport:
+		if protocols.IsICMP(row.Attributes.IPProto) {
+			return format.String(protocols.FormatICMP(row.Attributes.IPProto, row.Attributes.DstPort, numeric))
+		}
 		return format.String(fmt.Sprintf("%d", row.Attributes.DstPort))
 	case OutcolProto:
-		return format.String(protocols.GetIPProto(int(row.Attributes.IPProto)))
+		return format.String(protocols.Format(row.Attributes.IPProto, numeric))
+	case OutcolTag:
+		return format.String(row.Attributes.Tag)
+	case OutcolTTLMin:
+		return format.String(fmt.Sprintf("%d", row.Attributes.TTLMin))
+	case OutcolTTLMax:
+		return format.String(fmt.Sprintf("%d", row.Attributes.TTLMax))
+	case OutcolFlowHash:
+		return format.String(FormatFlowHash(row.Attributes.Hash()))
+	case OutcolCommunityID:
+		return format.String(row.CommunityID)
+	case OutcolHost:
+		return format.String(tryLookup(ips2domains, row.Attributes.SrcIP.String()))
 
 	case OutcolInBytes, OutcolBothBytesRcvd:
 		return format.Size(row.Counters.BytesRcvd)
-	case OutcolInBytesPercent:
+	case OutcolInBytesPercent, OutcolBothBytesRcvdPercent:
 		return format.Float(float64(100*row.Counters.BytesRcvd) / float64(nz(totals.BytesRcvd)))
 	case OutcolInPkts, OutcolBothPktsRcvd:
 		return format.Count(row.Counters.PacketsRcvd)
-	case OutcolInPktsPercent:
+	case OutcolInPktsPercent, OutcolBothPktsRcvdPercent:
 		return format.Float(float64(100*row.Counters.PacketsRcvd) / float64(nz(totals.PacketsRcvd)))
 	case OutcolOutBytes, OutcolBothBytesSent:
 		return format.Size(row.Counters.BytesSent)
-	case OutcolOutBytesPercent:
+	case OutcolOutBytesPercent, OutcolBothBytesSentPercent:
 		return format.Float(float64(100*row.Counters.BytesSent) / float64(nz(totals.BytesSent)))
 	case OutcolOutPkts, OutcolBothPktsSent:
 		return format.Count(row.Counters.PacketsSent)
-	case OutcolOutPktsPercent:
+	case OutcolOutPktsPercent, OutcolBothPktsSentPercent:
 		return format.Float(float64(100*row.Counters.PacketsSent) / float64(nz(totals.PacketsSent)))
 	case OutcolSumBytes:
 		return format.Size(row.Counters.BytesRcvd + row.Counters.BytesSent)
@@ -209,6 +387,38 @@ func extract(format Formatter, ips2domains map[string]string, totals types.Count
 		return format.Count(row.Counters.SumPackets())
 	case OutcolSumPktsPercent, OutcolBothPktsPercent:
 		return format.Float(float64(100*(row.Counters.SumPackets())) / float64(nz(totals.SumPackets())))
+
+	case OutcolRoleSrcPkts, OutcolRoleSrcBytes, OutcolRoleDstPkts, OutcolRoleDstBytes:
+		var roles RoleCounters
+		if row.Roles != nil {
+			roles = *row.Roles
+		}
+		switch col {
+		case OutcolRoleSrcPkts:
+			return format.Count(roles.Source.SumPackets())
+		case OutcolRoleSrcBytes:
+			return format.Size(roles.Source.SumBytes())
+		case OutcolRoleDstPkts:
+			return format.Count(roles.Destination.SumPackets())
+		default:
+			return format.Size(roles.Destination.SumBytes())
+		}
+
+	case OutcolStatsP50Bytes, OutcolStatsP95Bytes, OutcolStatsMaxBytes, OutcolStatsStdDevBytes:
+		var stats BinStats
+		if row.Stats != nil {
+			stats = *row.Stats
+		}
+		switch col {
+		case OutcolStatsP50Bytes:
+			return format.Size(stats.P50)
+		case OutcolStatsP95Bytes:
+			return format.Size(stats.P95)
+		case OutcolStatsMaxBytes:
+			return format.Size(stats.Max)
+		default:
+			return format.Size(uint64(math.Round(stats.StdDev)))
+		}
 	default:
 		panic("unknown OutputColumn value")
 	}
@@ -296,9 +506,72 @@ type basePrinter struct {
 
 	ifaces string
 
+	// optional output settings
+	humanReadable bool
+	directionPct  bool
+	numeric       bool
+	counters      types.CounterSelector
+	timeLocation  *time.Location
+	timeLayout    string
+	influxMapping *InfluxMapping
+	flowHash      bool
+	communityID   bool
+	roles         bool
+	stats         bool
+
 	cols []OutputColumn
 }
 
+// PrinterOption allows to configure optional output settings of a TablePrinter
+type PrinterOption func(*basePrinter)
+
+// WithHumanReadable renders byte and packet counters in human-readable units
+// (e.g. KiB / MiB / GiB) regardless of the output format
+func WithHumanReadable() PrinterOption {
+	return func(b *basePrinter) {
+		b.humanReadable = true
+	}
+}
+
+// WithNumeric prints IP protocols as numbers instead of translating them to their
+// names (e.g. 17 instead of UDP)
+func WithNumeric() PrinterOption {
+	return func(b *basePrinter) {
+		b.numeric = true
+	}
+}
+
+// WithTimeLocation prints timestamps in the given time zone instead of the local one
+func WithTimeLocation(loc *time.Location) PrinterOption {
+	return func(b *basePrinter) {
+		b.timeLocation = loc
+	}
+}
+
+// WithTimeLayout prints timestamps using the given layout (in Go reference time notation,
+// or TimeFormatUnix) instead of the output format's default
+func WithTimeLayout(layout string) PrinterOption {
+	return func(b *basePrinter) {
+		b.timeLayout = layout
+	}
+}
+
+// WithDirectionPercentages adds percentage-of-total columns for each individual
+// direction (received / sent) if both directions are printed
+func WithDirectionPercentages() PrinterOption {
+	return func(b *basePrinter) {
+		b.directionPct = true
+	}
+}
+
+// WithCounters omits the packet / byte columns if none of the respective counters
+// were aggregated by the query
+func WithCounters(counters types.CounterSelector) PrinterOption {
+	return func(b *basePrinter) {
+		b.counters = counters
+	}
+}
+
 // newBasePrinter sets up the basic printing facilities
 func newBasePrinter(
 	output io.Writer,
@@ -309,14 +582,43 @@ func newBasePrinter(
 	ips2domains map[string]string,
 	totals types.Counters,
 	ifaces string,
+	opts ...PrinterOption,
 ) basePrinter {
-	result := basePrinter{output, sort, selector, direction, attributes, ips2domains, totals, ifaces,
-		columns(selector, attributes, direction),
+	result := basePrinter{
+		output:      output,
+		sort:        sort,
+		selector:    selector,
+		direction:   direction,
+		attributes:  attributes,
+		ips2domains: ips2domains,
+		totals:      totals,
+		ifaces:      ifaces,
+	}
+	for _, opt := range opts {
+		opt(&result)
 	}
+	result.cols = columns(selector, attributes, result.flowHash, result.communityID, result.roles, result.stats, direction, result.directionPct, result.counters)
 
 	return result
 }
 
+// formatter returns the Formatter to be used for the output format's native Formatter f,
+// taking into account the optional output settings. The default time layout of the output
+// format applies if the time zone is overridden without specifying a layout
+func (b *basePrinter) formatter(f Formatter, defaultTimeLayout string) Formatter {
+	if b.humanReadable {
+		f = humanFormatter{f}
+	}
+	if b.timeLayout != "" || b.timeLocation != nil {
+		layout := b.timeLayout
+		if layout == "" {
+			layout = defaultTimeLayout
+		}
+		f = timeFormatter{Formatter: f, location: b.timeLocation, layout: layout}
+	}
+	return f
+}
+
 // NewTablePrinter instantiates a new table printer
 func NewTablePrinter(output io.Writer, format string,
 	sort SortOrder,
@@ -328,8 +630,9 @@ func NewTablePrinter(output io.Writer, format string,
 	numFlows int,
 	resolveTimeout time.Duration,
 	_ string,
-	ifaces string) (TablePrinter, error) {
-	b := newBasePrinter(output, sort, labelSel, direction, attributes, ips2domains, totals, ifaces)
+	ifaces string,
+	opts ...PrinterOption) (TablePrinter, error) {
+	b := newBasePrinter(output, sort, labelSel, direction, attributes, ips2domains, totals, ifaces, opts...)
 
 	var printer TablePrinter
 	switch format {
@@ -337,6 +640,10 @@ func NewTablePrinter(output io.Writer, format string,
 		printer = NewTextTablePrinter(b, numFlows, resolveTimeout)
 	case "csv":
 		printer = NewCSVTablePrinter(b)
+	case "pcapng":
+		printer = NewPcapngTablePrinter(b)
+	case "influxdb":
+		printer = NewInfluxTablePrinter(b)
 	default:
 		return nil, fmt.Errorf("unknown output format %s", format)
 	}
@@ -380,6 +687,7 @@ func (CSVFormatter) String(s string) string {
 type CSVTablePrinter struct {
 	basePrinter
 	writer *csv.Writer
+	format Formatter
 	fields []string
 }
 
@@ -388,14 +696,19 @@ func NewCSVTablePrinter(b basePrinter) *CSVTablePrinter {
 	c := CSVTablePrinter{
 		b,
 		csv.NewWriter(b.output),
+		b.formatter(CSVFormatter{}, TimeFormatUnix),
 		make([]string, 0, len(b.cols)),
 	}
 
 	headers := append(types.AllColumns(), []string{
+		types.TagName, types.TTLMinName, types.TTLMaxName, FlowHashName, CommunityIDName,
 		packetsStr, "%", "data vol.", "%",
 		packetsStr, "%", "data vol.", "%",
 		packetsStr, "%", "data vol.", "%",
 		"packets received", "packets sent", "%", "data vol. received", "data vol. sent", "%",
+		"% received", "% sent", "% received", "% sent",
+		HostName, "packets as source", "data vol. as source", "packets as destination", "data vol. as destination",
+		"p50 data vol. per bin", "p95 data vol. per bin", "max data vol. per bin", "stddev data vol. per bin",
 	}...)
 
 	for _, col := range c.cols {
@@ -413,7 +726,7 @@ func NewCSVTablePrinter(b basePrinter) *CSVTablePrinter {
 func (c *CSVTablePrinter) AddRow(row Row) error {
 	c.fields = c.fields[:0]
 	for _, col := range c.cols {
-		c.fields = append(c.fields, extract(CSVFormatter{}, c.ips2domains, c.totals, row, col))
+		c.fields = append(c.fields, extract(c.format, c.ips2domains, c.totals, c.numeric, row, col))
 	}
 	return c.writer.Write(c.fields)
 }
@@ -438,7 +751,7 @@ func (c *CSVTablePrinter) Footer(_ *Result) error {
 	summaryEntries[OutcolBothBytesSent] = "Sent data volume (bytes)"
 	for _, col := range c.cols {
 		if summaryEntries[col] != "" {
-			if err := c.writer.Write([]string{summaryEntries[col], extractTotal(CSVFormatter{}, c.totals, col)}); err != nil {
+			if err := c.writer.Write([]string{summaryEntries[col], extractTotal(c.format, c.totals, col)}); err != nil {
 				return err
 			}
 		}
@@ -500,6 +813,7 @@ func (TextFormatter) String(s string) string {
 // TextTablePrinter pretty prints all flows
 type TextTablePrinter struct {
 	basePrinter
+	format         Formatter
 	writer         *tabwriter.Writer
 	footwriter     *tabwriter.Writer
 	numFlows       int
@@ -511,6 +825,7 @@ type TextTablePrinter struct {
 func NewTextTablePrinter(b basePrinter, numFlows int, resolveTimeout time.Duration) *TextTablePrinter {
 	var t = &TextTablePrinter{
 		b,
+		b.formatter(TextFormatter{}, types.DefaultTimeOutputFormat),
 		tabwriter.NewWriter(b.output, 0, 1, 2, ' ', tabwriter.AlignRight),
 		tabwriter.NewWriter(b.output, 0, 4, 1, ' ', 0),
 		numFlows,
@@ -529,12 +844,24 @@ func NewTextTablePrinter(b basePrinter, numFlows int, resolveTimeout time.Durati
 	header1[OutcolBothPktsSent] = packetsStr
 	header1[OutcolBothBytesRcvd] = bytesStr
 	header1[OutcolBothBytesSent] = bytesStr
+	header1[OutcolRoleSrcPkts] = packetsStr
+	header1[OutcolRoleSrcBytes] = bytesStr
+	header1[OutcolRoleDstPkts] = packetsStr
+	header1[OutcolRoleDstBytes] = bytesStr
+	header1[OutcolStatsP50Bytes] = bytesStr
+	header1[OutcolStatsP95Bytes] = bytesStr
+	header1[OutcolStatsMaxBytes] = bytesStr
+	header1[OutcolStatsStdDevBytes] = bytesStr
 
 	var header2 = append(types.AllColumns(), []string{
+		types.TagName, types.TTLMinName, types.TTLMaxName, FlowHashName, CommunityIDName,
 		"in", "%", "in", "%",
 		"out", "%", "out", "%",
 		"in+out", "%", "in+out", "%",
 		"in", "out", "%", "in", "out", "%",
+		"%", "%", "%", "%",
+		HostName, "as src", "as src", "as dst", "as dst",
+		"p50/bin", "p95/bin", "max/bin", "stddev/bin",
 	}...)
 
 	for _, col := range t.cols {
@@ -571,7 +898,7 @@ func addRows(ctx context.Context, p TablePrinter, rows Rows) error {
 // AddRow adds a flow entry to the table printer
 func (t *TextTablePrinter) AddRow(row Row) error {
 	for _, col := range t.cols {
-		fmt.Fprintf(t.writer, "%s\t", extract(TextFormatter{}, t.ips2domains, t.totals, row, col))
+		fmt.Fprintf(t.writer, "%s\t", extract(t.format, t.ips2domains, t.totals, t.numeric, row, col))
 	}
 	fmt.Fprintln(t.writer)
 	t.numPrinted++
@@ -609,7 +936,7 @@ func (t *TextTablePrinter) Footer(result *Result) error {
 	// Totals
 	for _, col := range t.cols {
 		if isTotal[col] {
-			fmt.Fprint(t.writer, extractTotal(TextFormatter{}, t.totals, col))
+			fmt.Fprint(t.writer, extractTotal(t.format, t.totals, col))
 		}
 		fmt.Fprint(t.writer, "\t")
 	}
@@ -624,10 +951,10 @@ func (t *TextTablePrinter) Footer(result *Result) error {
 		fmt.Fprint(t.writer, "Totals:\t")
 		for _, col := range t.cols[1:] {
 			if col == OutcolBothPktsSent {
-				fmt.Fprint(t.writer, TextFormatter{}.Count(t.totals.SumPackets()))
+				fmt.Fprint(t.writer, t.format.Count(t.totals.SumPackets()))
 			}
 			if col == OutcolBothBytesSent {
-				fmt.Fprint(t.writer, TextFormatter{}.Size(t.totals.SumBytes()))
+				fmt.Fprint(t.writer, t.format.Size(t.totals.SumBytes()))
 			}
 			fmt.Fprint(t.writer, "\t")
 		}
@@ -638,8 +965,8 @@ func (t *TextTablePrinter) Footer(result *Result) error {
 
 	// Summary
 	fmt.Fprintf(t.footwriter, "Timespan / Interface\t: [%s, %s] (%s) / %s\n",
-		result.Summary.First.Format(types.DefaultTimeOutputFormat),
-		result.Summary.Last.Format(types.DefaultTimeOutputFormat),
+		t.format.Time(result.Summary.First.Unix()),
+		t.format.Time(result.Summary.Last.Unix()),
 		formatting.Durationable(result.Summary.Last.Sub(result.Summary.First).Round(time.Minute)),
 		strings.Join(result.Summary.Interfaces, ","))
 	fmt.Fprintf(t.footwriter, "Sorted by\t: %s\n",
@@ -664,14 +991,101 @@ func (t *TextTablePrinter) Footer(result *Result) error {
 		hitsTotal = strings.TrimSpace(textFormatter.Count(uint64(result.Summary.Hits.Total)))
 	}
 
-	fmt.Fprintf(t.footwriter, "Query stats\t: displayed top %s hits out of %s in %s\n",
+	hitsKind := "top"
+	if result.Summary.Sample != nil {
+		hitsKind = "randomly sampled"
+	}
+	fmt.Fprintf(t.footwriter, "Query stats\t: displayed %s %s hits out of %s in %s\n",
+		hitsKind,
 		hitsDisplayed,
 		hitsTotal,
 		textFormatter.Duration(result.Summary.Timings.QueryDuration))
+	if sample := result.Summary.Sample; sample != nil {
+		fmt.Fprintf(t.footwriter, "Extrapolated\t: %s / %s packets (bytes ±%.1f%%, from %d of %s rows)\n",
+			strings.TrimSpace(textFormatter.Size(sample.Extrapolated.SumBytes())),
+			strings.TrimSpace(textFormatter.Count(sample.Extrapolated.SumPackets())),
+			100*sample.BytesRelErr,
+			sample.Size,
+			strings.TrimSpace(textFormatter.Count(uint64(sample.Population))))
+	}
+	if spill := result.Summary.Timings.Spill; spill != nil {
+		fmt.Fprintf(t.footwriter, "Spill stats\t: %d spills, %s entries (%s on disk) in %s\n",
+			spill.Spills,
+			strings.TrimSpace(textFormatter.Count(spill.Entries)),
+			textFormatter.Size(spill.Bytes),
+			textFormatter.Duration(spill.Duration))
+	}
+	if cut := result.Summary.Cut; cut != nil {
+		fmt.Fprintf(t.footwriter, "Live cut\t: rotation #%d at %s (later traffic from memory)\n",
+			cut.Generation,
+			t.format.Time(cut.Timestamp.Unix()))
+	}
+	if alignment := result.Summary.Alignment; alignment != nil {
+		switch {
+		case alignment.InterpolatedBlocks > 0:
+			fmt.Fprintf(t.footwriter, "Time alignment\t: %s (%d block(s) cut by the time range scaled proportionally)\n",
+				alignment.Mode,
+				alignment.InterpolatedBlocks)
+		case !alignment.Requested.First.Equal(result.Summary.First) || !alignment.Requested.Last.Equal(result.Summary.Last):
+			fmt.Fprintf(t.footwriter, "Time alignment\t: %s (requested [%s, %s])\n",
+				alignment.Mode,
+				t.format.Time(alignment.Requested.First.Unix()),
+				t.format.Time(alignment.Requested.Last.Unix()))
+		}
+	}
+	if len(result.Summary.Links) > 0 {
+		ifaces := make([]string, 0, len(result.Summary.Links))
+		for iface := range result.Summary.Links {
+			ifaces = append(ifaces, iface)
+		}
+		sort.Strings(ifaces)
+		for i, iface := range ifaces {
+			label := ""
+			if i == 0 {
+				label = "Links"
+			}
+			fmt.Fprintf(t.footwriter, "%s\t: %s: %s\n", label, iface, result.Summary.Links[iface])
+		}
+	}
+	if len(result.Summary.Idle) > 0 {
+		ifaces := make([]string, 0, len(result.Summary.Idle))
+		for iface := range result.Summary.Idle {
+			ifaces = append(ifaces, iface)
+		}
+		sort.Strings(ifaces)
+		for i, iface := range ifaces {
+			label := ""
+			if i == 0 {
+				label = "Idle"
+			}
+			var idle time.Duration
+			for _, span := range result.Summary.Idle[iface] {
+				idle += span.Last.Sub(span.First)
+			}
+			fmt.Fprintf(t.footwriter, "%s\t: %s: %s without traffic in %d period(s)\n", label, iface,
+				textFormatter.Duration(idle),
+				len(result.Summary.Idle[iface]))
+		}
+	}
 	if result.Query.Condition != "" {
 		fmt.Fprintf(t.footwriter, "Conditions:\t: %s\n",
 			result.Query.Condition)
 	}
+	if result.Summary.CorruptBlocks > 0 {
+		fmt.Fprintf(t.footwriter, "Corrupt blocks\t: %d (skipped, failed checksum validation)\n",
+			result.Summary.CorruptBlocks)
+	}
+	if result.Summary.MirroredRows > 0 {
+		fmt.Fprintf(t.footwriter, "Mirrored rows\t: %d (observed by multiple hosts in inverse directions)\n",
+			result.Summary.MirroredRows)
+	}
+	if cache := result.Summary.Cache; cache != nil && cache.Hits > 0 {
+		fmt.Fprintf(t.footwriter, "Cached hosts\t: %d of %d (results served from the query server's cache)\n",
+			cache.Hits, cache.Hits+cache.Misses)
+	}
+	if result.Summary.Saturated {
+		fmt.Fprint(t.footwriter, "Saturated\t: counters reached their maximum value, volumes are lower bounds\n")
+	}
 
 	return nil
 }
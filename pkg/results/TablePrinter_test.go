@@ -0,0 +1,200 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVTablePrinterOptions(t *testing.T) {
+	attributes, selector, err := types.ParseQueryType("dport")
+	require.Nil(t, err)
+
+	rows := Rows{
+		{Attributes: Attributes{DstPort: 443}, Counters: types.Counters{BytesRcvd: 3 * 1024 * 1024, BytesSent: 1024, PacketsRcvd: 3000, PacketsSent: 1000}},
+		{Attributes: Attributes{DstPort: 80}, Counters: types.Counters{BytesRcvd: 1024 * 1024, BytesSent: 3072, PacketsRcvd: 1000, PacketsSent: 3000}},
+	}
+	var totals types.Counters
+	for _, row := range rows {
+		totals = totals.Add(row.Counters)
+	}
+
+	var tests = []struct {
+		name     string
+		opts     []PrinterOption
+		expected [][]string
+	}{
+		{"default", nil,
+			[][]string{
+				{"dport", "packets received", "packets sent", "%", "data vol. received", "data vol. sent", "%"},
+				{"443", "3000", "1000", "50.00", "3145728", "1024", "74.95"},
+				{"80", "1000", "3000", "50.00", "1048576", "3072", "25.05"},
+			},
+		},
+		{"human readable", []PrinterOption{WithHumanReadable()},
+			[][]string{
+				{"dport", "packets received", "packets sent", "%", "data vol. received", "data vol. sent", "%"},
+				{"443", "3.00 k", "1.00 k", "50.00", "3.00 MiB", "1.00 KiB", "74.95"},
+				{"80", "1.00 k", "3.00 k", "50.00", "1.00 MiB", "3.00 KiB", "25.05"},
+			},
+		},
+		{"direction percentages", []PrinterOption{WithDirectionPercentages()},
+			[][]string{
+				{"dport", "packets received", "% received", "packets sent", "% sent", "%", "data vol. received", "% received", "data vol. sent", "% sent", "%"},
+				{"443", "3000", "75.00", "1000", "25.00", "50.00", "3145728", "75.00", "1024", "25.00", "74.95"},
+				{"80", "1000", "25.00", "3000", "75.00", "50.00", "1048576", "25.00", "3072", "75.00", "25.05"},
+			},
+		},
+		{"packets only", []PrinterOption{WithCounters(types.CounterSelector{PacketsRcvd: true, PacketsSent: true})},
+			[][]string{
+				{"dport", "packets received", "packets sent", "%"},
+				{"443", "3000", "1000", "50.00"},
+				{"80", "1000", "3000", "50.00"},
+			},
+		},
+		{"received bytes only", []PrinterOption{WithCounters(types.CounterSelector{BytesRcvd: true})},
+			[][]string{
+				{"dport", "data vol. received", "data vol. sent", "%"},
+				{"443", "3145728", "1024", "74.95"},
+				{"80", "1048576", "3072", "25.05"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+
+			printer, err := NewTablePrinter(buf, "csv", SortTraffic, selector, types.DirectionBoth,
+				attributes, nil, totals, len(rows), 0, "", "eth0", test.opts...,
+			)
+			require.Nil(t, err)
+			require.Nil(t, printer.AddRows(context.Background(), rows))
+			require.Nil(t, printer.Print(nil))
+
+			records, err := csv.NewReader(buf).ReadAll()
+			require.Nil(t, err)
+			require.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestCSVTablePrinterNumeric(t *testing.T) {
+	attributes, selector, err := types.ParseQueryType("proto")
+	require.Nil(t, err)
+
+	rows := Rows{
+		{Attributes: Attributes{IPProto: 6}, Counters: types.Counters{BytesRcvd: 2048, PacketsRcvd: 2}},
+		{Attributes: Attributes{IPProto: 17}, Counters: types.Counters{BytesRcvd: 1024, PacketsRcvd: 1}},
+		{Attributes: Attributes{IPProto: 200}, Counters: types.Counters{BytesRcvd: 512, PacketsRcvd: 1}},
+	}
+	var totals types.Counters
+	for _, row := range rows {
+		totals = totals.Add(row.Counters)
+	}
+
+	var tests = []struct {
+		name     string
+		opts     []PrinterOption
+		expected []string
+	}{
+		{"names", nil, []string{"TCP", "UDP", "200"}},
+		{"numeric", []PrinterOption{WithNumeric()}, []string{"6", "17", "200"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+
+			printer, err := NewTablePrinter(buf, "csv", SortTraffic, selector, types.DirectionIn,
+				attributes, nil, totals, len(rows), 0, "", "eth0", test.opts...,
+			)
+			require.Nil(t, err)
+			require.Nil(t, printer.AddRows(context.Background(), rows))
+			require.Nil(t, printer.Print(nil))
+
+			records, err := csv.NewReader(buf).ReadAll()
+			require.Nil(t, err)
+
+			var protos []string
+			for _, record := range records[1 : len(rows)+1] {
+				protos = append(protos, record[0])
+			}
+			require.Equal(t, test.expected, protos)
+		})
+	}
+}
+
+func TestTablePrinterTimeZone(t *testing.T) {
+	attributes, selector, err := types.ParseQueryType("time,dport")
+	require.Nil(t, err)
+
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.Nil(t, err)
+
+	// timestamps during winter and summer time, respectively
+	rows := Rows{
+		{Labels: Labels{Timestamp: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)}, Attributes: Attributes{DstPort: 443}, Counters: types.Counters{BytesRcvd: 2048, PacketsRcvd: 2}},
+		{Labels: Labels{Timestamp: time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)}, Attributes: Attributes{DstPort: 443}, Counters: types.Counters{BytesRcvd: 1024, PacketsRcvd: 1}},
+	}
+	var totals types.Counters
+	for _, row := range rows {
+		totals = totals.Add(row.Counters)
+	}
+
+	var tests = []struct {
+		name     string
+		format   string
+		opts     []PrinterOption
+		expected []string
+	}{
+		{"csv default", "csv", nil, []string{"1705320000", "1721044800"}},
+		{"csv time zone only", "csv", []PrinterOption{WithTimeLocation(zurich)}, []string{"1705320000", "1721044800"}},
+		{"csv time zone and layout", "csv", []PrinterOption{WithTimeLocation(zurich), WithTimeLayout(time.RFC3339)},
+			[]string{"2024-01-15T13:00:00+01:00", "2024-07-15T14:00:00+02:00"},
+		},
+		{"txt time zone", "txt", []PrinterOption{WithTimeLocation(time.UTC)}, []string{"2024-01-15 12:00:00", "2024-07-15 12:00:00"}},
+		{"txt time zone DST", "txt", []PrinterOption{WithTimeLocation(zurich)}, []string{"2024-01-15 13:00:00", "2024-07-15 14:00:00"}},
+		{"txt unix", "txt", []PrinterOption{WithTimeLayout(TimeFormatUnix)}, []string{"1705320000", "1721044800"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+
+			printer, err := NewTablePrinter(buf, test.format, SortTime, selector, types.DirectionIn,
+				attributes, nil, totals, len(rows), 0, "", "eth0", test.opts...,
+			)
+			require.Nil(t, err)
+			require.Nil(t, printer.AddRows(context.Background(), rows))
+
+			if test.format == "csv" {
+				require.Nil(t, printer.Print(nil))
+
+				records, err := csv.NewReader(buf).ReadAll()
+				require.Nil(t, err)
+				for i, expected := range test.expected {
+					require.Equal(t, expected, records[i+1][0])
+				}
+				return
+			}
+
+			// the summary's time range is printed in the same time zone / layout as the rows
+			result := &Result{Summary: Summary{TimeRange: TimeRange{First: rows[0].Labels.Timestamp, Last: rows[1].Labels.Timestamp}}}
+			require.Nil(t, printer.Footer(result))
+			require.Nil(t, printer.Print(result))
+			for _, expected := range test.expected {
+				require.Equal(t, 2, strings.Count(buf.String(), expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package results
+
+import (
+	"crypto/sha1" // #nosec G505
+	"encoding/base64"
+	"encoding/binary"
+	"net/netip"
+
+	"github.com/els0r/goProbe/pkg/types/protocols"
+)
+
+// CommunityIDName denotes the name of the community ID output column / field
+const CommunityIDName = "community_id"
+
+// communityIDVersion prefixes each community ID with the version of the specification
+const communityIDVersion = "1:"
+
+// IP protocol numbers of the protocols whose flows are identified by (source and destination) ports
+const (
+	protoTCP  = 6
+	protoUDP  = 17
+	protoSCTP = 132
+)
+
+// icmpCounterparts maps the ICMP / ICMPv6 message types which are part of a request / response pair
+// to the type of their counterpart (as defined by the Community ID specification). All other message
+// types are considered one-way
+var icmpCounterparts = map[uint8]map[uint8]uint8{
+	protocols.ICMP: {
+		8: 0, 0: 8, // echo
+		13: 14, 14: 13, // timestamp
+		15: 16, 16: 15, // info
+		10: 9, 9: 10, // router solicitation / advertisement
+		17: 18, 18: 17, // address mask
+	},
+	protocols.ICMPv6: {
+		128: 129, 129: 128, // echo
+		130: 131, 131: 130, // multicast listener query / report
+		133: 134, 134: 133, // router solicitation / advertisement
+		135: 136, 136: 135, // neighbor solicitation / advertisement
+		139: 140, 140: 139, // node information query / response
+		144: 145, 145: 144, // home agent address discovery
+	},
+}
+
+// CommunityID computes the Community ID (version 1, see https://github.com/corelight/community-id-spec)
+// of the flow described by the attributes, as used by e.g. Zeek and Suricata to identify flows. The seed
+// has to match the one configured in these tools (0 by default).
+//
+// Since the (ephemeral) source ports of TCP, UDP and SCTP flows aren't recorded, their Community ID cannot
+// be determined and an empty string is returned. The ID of an ICMP / ICMPv6 flow is derived from the message
+// type / code stored in its destination port, the one of a flow of any other protocol from its endpoints only.
+// Note that the ID of one-way ICMP messages (e.g. destination unreachable) depends on the orientation of the
+// row
+func (a Attributes) CommunityID(seed uint16) string {
+	if !a.SrcIP.IsValid() || !a.DstIP.IsValid() {
+		return ""
+	}
+	sip, dip := a.SrcIP.Unmap(), a.DstIP.Unmap()
+	if sip.Is4() != dip.Is4() {
+		return ""
+	}
+
+	switch a.IPProto {
+	case protoTCP, protoUDP, protoSCTP:
+		return ""
+	case protocols.ICMP, protocols.ICMPv6:
+		icmpType, icmpCode := uint8(a.DstPort>>8), uint8(a.DstPort)
+		if counterpart, exists := icmpCounterparts[a.IPProto][icmpType]; exists {
+			return communityID(seed, sip, dip, a.IPProto, uint16(icmpType), uint16(counterpart), true, false)
+		}
+		return communityID(seed, sip, dip, a.IPProto, uint16(icmpType), uint16(icmpCode), true, true)
+	}
+	return communityID(seed, sip, dip, a.IPProto, 0, 0, false, false)
+}
+
+// communityID computes the Community ID of a flow. Unless it is one-way, the endpoints are ordered such
+// that both directions of the flow are assigned the same ID
+func communityID(seed uint16, sip, dip netip.Addr, proto uint8, sport, dport uint16, hasPorts, oneWay bool) string {
+	if !oneWay && (dip.Less(sip) || (sip == dip && dport < sport)) {
+		sip, dip = dip, sip
+		sport, dport = dport, sport
+	}
+
+	buf := make([]byte, 0, 2+16+16+2+4)
+	buf = binary.BigEndian.AppendUint16(buf, seed)
+	buf = append(buf, sip.AsSlice()...)
+	buf = append(buf, dip.AsSlice()...)
+	buf = append(buf, proto, 0)
+	if hasPorts {
+		buf = binary.BigEndian.AppendUint16(buf, sport)
+		buf = binary.BigEndian.AppendUint16(buf, dport)
+	}
+
+	sum := sha1.Sum(buf) // #nosec G401
+	return communityIDVersion + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// AddCommunityIDs assigns the Community ID (see Attributes.CommunityID()) to all rows of the result
+func (r *Result) AddCommunityIDs(seed uint16) {
+	for i := range r.Rows {
+		r.Rows[i].CommunityID = r.Rows[i].Attributes.CommunityID(seed)
+	}
+}
+
+// WithCommunityID adds the Community ID as an output column. Since it depends on the seed, it is
+// taken from the rows (see Result.AddCommunityIDs())
+func WithCommunityID() PrinterOption {
+	return func(b *basePrinter) {
+		b.communityID = true
+	}
+}
@@ -0,0 +1,92 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityID(t *testing.T) {
+	var (
+		client = netip.MustParseAddr("128.232.110.120")
+		server = netip.MustParseAddr("66.35.250.204")
+	)
+
+	// reference value of the specification, identical for both directions of the flow
+	require.Equal(t, "1:LQU9qZlK+B5F3KDmev6m5PMibrg=", communityID(0, client, server, protoTCP, 34855, 80, true, false))
+	require.Equal(t, "1:LQU9qZlK+B5F3KDmev6m5PMibrg=", communityID(0, server, client, protoTCP, 80, 34855, true, false))
+	require.NotEqual(t, "1:LQU9qZlK+B5F3KDmev6m5PMibrg=", communityID(1, client, server, protoTCP, 34855, 80, true, false))
+
+	var tests = []struct {
+		name     string
+		attrs    Attributes
+		expected string
+	}{
+		{"ICMP echo",
+			Attributes{SrcIP: netip.MustParseAddr("192.168.0.89"), DstIP: netip.MustParseAddr("192.168.0.1"), DstPort: 8 << 8, IPProto: 1},
+			"1:X0snYXpgwiv9TZtqg64sgzUn6Dk=",
+		},
+		{"ICMP echo, reverse",
+			Attributes{SrcIP: netip.MustParseAddr("192.168.0.1"), DstIP: netip.MustParseAddr("192.168.0.89"), DstPort: 0 << 8, IPProto: 1},
+			"1:X0snYXpgwiv9TZtqg64sgzUn6Dk=",
+		},
+		{"ICMP echo, IPv4-mapped IPv6",
+			Attributes{SrcIP: netip.MustParseAddr("::ffff:192.168.0.89"), DstIP: netip.MustParseAddr("::ffff:192.168.0.1"), DstPort: 8 << 8, IPProto: 1},
+			"1:X0snYXpgwiv9TZtqg64sgzUn6Dk=",
+		},
+		{"TCP", Attributes{SrcIP: client, DstIP: server, DstPort: 80, IPProto: protoTCP}, ""},
+		{"UDP", Attributes{SrcIP: client, DstIP: server, DstPort: 53, IPProto: protoUDP}, ""},
+		{"mixed address families", Attributes{SrcIP: client, DstIP: netip.MustParseAddr("2001:db8::1"), IPProto: 47}, ""},
+		{"empty", Attributes{}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.attrs.CommunityID(0))
+		})
+	}
+
+	// one-way ICMP messages retain their orientation
+	unreachable := Attributes{SrcIP: server, DstIP: client, DstPort: 3<<8 | 3, IPProto: 1}
+	reverse := Attributes{SrcIP: client, DstIP: server, DstPort: 3<<8 | 3, IPProto: 1}
+	require.NotEqual(t, unreachable.CommunityID(0), reverse.CommunityID(0))
+
+	// flows of protocols without ports are identified by their endpoints only
+	gre := Attributes{SrcIP: client, DstIP: server, IPProto: 47}
+	greReverse := Attributes{SrcIP: server, DstIP: client, IPProto: 47}
+	require.Equal(t, gre.CommunityID(0), greReverse.CommunityID(0))
+	require.NotEmpty(t, gre.CommunityID(0))
+}
+
+func TestCommunityIDColumn(t *testing.T) {
+	rows := Rows{
+		{
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("192.168.0.89"), DstIP: netip.MustParseAddr("192.168.0.1"), DstPort: 8 << 8, IPProto: 1},
+			Counters:   types.Counters{BytesRcvd: 1, PacketsRcvd: 1},
+		},
+	}
+	result := &Result{Rows: rows}
+	result.AddCommunityIDs(0)
+	require.Equal(t, "1:X0snYXpgwiv9TZtqg64sgzUn6Dk=", result.Rows[0].CommunityID)
+
+	attributes, selector, err := types.ParseQueryType("sip,dip,dport,proto")
+	require.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	printer, err := NewTablePrinter(buf, "csv", SortTraffic, selector, types.DirectionSum,
+		attributes, nil, types.Counters{BytesRcvd: 1, PacketsRcvd: 1}, len(rows), 0, "", "eth0",
+		WithCommunityID(),
+	)
+	require.Nil(t, err)
+	require.Nil(t, printer.AddRows(context.Background(), result.Rows))
+	require.Nil(t, printer.Print(nil))
+
+	lines := strings.Split(buf.String(), "\n")
+	require.Equal(t, "sip,dip,dport,proto,community_id,packets,%,data vol.,%", lines[0])
+	require.Equal(t, "192.168.0.89,192.168.0.1,echo,ICMP,1:X0snYXpgwiv9TZtqg64sgzUn6Dk=,1,100.00,1,100.00", lines[1])
+}
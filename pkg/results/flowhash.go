@@ -0,0 +1,58 @@
+package results
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+)
+
+// FlowHashName denotes the name of the flow hash output column / field
+const FlowHashName = "flow_hash"
+
+// Hash computes the canonical flow hash of the attributes. It is the 64-bit FNV-1a hash of the
+// following (35 byte) representation of the flow key:
+//
+//	sip   16 bytes, IPv4 addresses in their IPv4-mapped IPv6 form (::ffff:a.b.c.d)
+//	dip   16 bytes, see sip
+//	dport  2 bytes, big endian
+//	proto  1 byte
+//
+// Attributes that weren't queried are represented by zero bytes. Since the hash only depends on the
+// flow key (and neither on the probe, the time of capture nor the goProbe version), flows recorded
+// by different probes or exported to other systems can be joined on it
+func (a Attributes) Hash() uint64 {
+	var key [16 + 16 + 2 + 1]byte
+	if a.SrcIP.IsValid() {
+		sip := a.SrcIP.As16()
+		copy(key[0:16], sip[:])
+	}
+	if a.DstIP.IsValid() {
+		dip := a.DstIP.As16()
+		copy(key[16:32], dip[:])
+	}
+	binary.BigEndian.PutUint16(key[32:34], a.DstPort)
+	key[34] = a.IPProto
+
+	h := fnv.New64a()
+	_, _ = h.Write(key[:])
+	return h.Sum64()
+}
+
+// FormatFlowHash formats a flow hash as a fixed-width (16 digit) hexadecimal string
+func FormatFlowHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// AddFlowHashes assigns the canonical flow hash (see Attributes.Hash()) to all rows of the result
+func (r *Result) AddFlowHashes() {
+	for i := range r.Rows {
+		r.Rows[i].FlowHash = FormatFlowHash(r.Rows[i].Attributes.Hash())
+	}
+}
+
+// WithFlowHash adds the canonical flow hash (see Attributes.Hash()) as an output column
+func WithFlowHash() PrinterOption {
+	return func(b *basePrinter) {
+		b.flowHash = true
+	}
+}
@@ -0,0 +1,61 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowHash(t *testing.T) {
+	var tests = []struct {
+		name     string
+		attrs    Attributes
+		expected string
+	}{
+		{"IPv4", Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), DstPort: 443, IPProto: 6}, "b38ae80d62c88452"},
+		{"IPv4-mapped IPv6", Attributes{SrcIP: netip.MustParseAddr("::ffff:10.0.0.1"), DstIP: netip.MustParseAddr("::ffff:10.0.0.2"), DstPort: 443, IPProto: 6}, "b38ae80d62c88452"},
+		{"IPv4, tagged", Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), DstPort: 443, IPProto: 6, Tag: "web"}, "b38ae80d62c88452"},
+		{"IPv6", Attributes{SrcIP: netip.MustParseAddr("2001:db8::1"), DstIP: netip.MustParseAddr("2001:db8::2"), DstPort: 53, IPProto: 17}, "5685cfb446a80656"},
+		{"empty", Attributes{}, "3aefc885e76ecb37"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, FormatFlowHash(test.attrs.Hash()))
+		})
+	}
+}
+
+func TestFlowHashColumn(t *testing.T) {
+	rows := Rows{
+		{
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), DstPort: 443, IPProto: 6},
+			Counters:   types.Counters{BytesRcvd: 1, PacketsRcvd: 1},
+		},
+	}
+
+	attributes, selector, err := types.ParseQueryType("sip,dip,dport,proto")
+	require.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	printer, err := NewTablePrinter(buf, "csv", SortTraffic, selector, types.DirectionSum,
+		attributes, nil, types.Counters{BytesRcvd: 1, PacketsRcvd: 1}, len(rows), 0, "", "eth0",
+		WithFlowHash(),
+	)
+	require.Nil(t, err)
+	require.Nil(t, printer.AddRows(context.Background(), rows))
+	require.Nil(t, printer.Print(nil))
+
+	lines := strings.Split(buf.String(), "\n")
+	require.Equal(t, "sip,dip,dport,proto,flow_hash,packets,%,data vol.,%", lines[0])
+	require.Equal(t, "10.0.0.1,10.0.0.2,443,TCP,b38ae80d62c88452,1,100.00,1,100.00", lines[1])
+
+	result := &Result{Rows: rows}
+	result.AddFlowHashes()
+	require.Equal(t, "b38ae80d62c88452", result.Rows[0].FlowHash)
+}
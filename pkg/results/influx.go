@@ -0,0 +1,326 @@
+package results
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
+)
+
+// DefaultInfluxMeasurement denotes the measurement flows are written to if none is configured
+const DefaultInfluxMeasurement = "goprobe_flows"
+
+var (
+	errorInfluxUnknownTag   = errors.New("unknown tag source")
+	errorInfluxUnknownField = errors.New("unknown field source")
+	errorInfluxEmptyKey     = errors.New("tag / field key must not be empty")
+)
+
+// influxTagSources lists all attributes / labels that can be mapped to tags (in output order)
+var influxTagSources = []string{
+	types.HostnameName, types.HostIDName, types.IfaceName,
+	types.SIPName, types.DIPName, types.DportName, types.ProtoName, types.TagName,
+	types.TTLMinName, types.TTLMaxName,
+}
+
+// influxFieldSources lists all counters that can be mapped to fields (in output order)
+var influxFieldSources = []string{
+	types.BytesRcvdName, types.BytesSentName, types.PktsRcvdName, types.PktsSentName,
+}
+
+// InfluxMapping defines how flows are represented in InfluxDB line protocol. Each flow is
+// written as one point of the measurement, carrying its attributes / labels as tags and its
+// counters as (integer) fields
+type InfluxMapping struct {
+	// Measurement: the measurement the flows are written to. Example: goprobe_flows
+	Measurement string `json:"measurement,omitempty" yaml:"measurement,omitempty"`
+
+	// Tags: maps attributes / labels to tag keys. If empty, all queried attributes / labels are written
+	// as tags using their names. Enum: [hostname, hostid, iface, sip, dip, dport, proto, tag]. Example: {"sip": "src", "dip": "dst"}
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// Fields: maps counters to field keys. If empty, all counters aggregated by the query are written as
+	// fields using their names. Enum: [bytes_rcvd, bytes_sent, pkts_rcvd, pkts_sent]. Example: {"bytes_rcvd": "rx_bytes"}
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+
+	// StaticTags: tags added to every point. Example: {"site": "zrh"}
+	StaticTags map[string]string `json:"static_tags,omitempty" yaml:"static_tags,omitempty"`
+}
+
+// Validate checks that the mapping only refers to known attributes / labels and counters
+func (m *InfluxMapping) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for source, key := range m.Tags {
+		if !contains(influxTagSources, source) {
+			return fmt.Errorf("%w: %s", errorInfluxUnknownTag, source)
+		}
+		if key == "" {
+			return errorInfluxEmptyKey
+		}
+	}
+	for source, key := range m.Fields {
+		if !contains(influxFieldSources, source) {
+			return fmt.Errorf("%w: %s", errorInfluxUnknownField, source)
+		}
+		if key == "" {
+			return errorInfluxEmptyKey
+		}
+	}
+	for key := range m.StaticTags {
+		if key == "" {
+			return errorInfluxEmptyKey
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// WithInfluxMapping sets the mapping of flows to InfluxDB line protocol (only applies to the influxdb
+// output format)
+func WithInfluxMapping(mapping *InfluxMapping) PrinterOption {
+	return func(b *basePrinter) {
+		b.influxMapping = mapping
+	}
+}
+
+type influxKey struct {
+	source, key string
+}
+
+// InfluxTablePrinter writes out all flows in InfluxDB line protocol (also understood by e.g.
+// VictoriaMetrics), using nanosecond precision timestamps
+type InfluxTablePrinter struct {
+	basePrinter
+	writer *bufio.Writer
+
+	measurement string
+	tags        []influxKey
+	staticTags  string
+	fields      []influxKey
+
+	rows Rows
+}
+
+// NewInfluxTablePrinter creates a new InfluxTablePrinter
+func NewInfluxTablePrinter(b basePrinter) *InfluxTablePrinter {
+	mapping := b.influxMapping
+	if mapping == nil {
+		mapping = &InfluxMapping{}
+	}
+
+	p := &InfluxTablePrinter{
+		basePrinter: b,
+		writer:      bufio.NewWriter(b.output),
+		measurement: escapeInflux(mapping.Measurement, ", "),
+	}
+	if p.measurement == "" {
+		p.measurement = DefaultInfluxMeasurement
+	}
+
+	// determine the attributes / labels present in the result
+	queried := map[string]bool{
+		types.HostnameName: b.selector.Hostname,
+		types.HostIDName:   b.selector.HostID,
+		types.IfaceName:    b.selector.Iface,
+	}
+	for _, attrib := range b.attributes {
+		queried[attrib.Name()] = true
+	}
+	for _, source := range influxTagSources {
+		if !queried[source] {
+			continue
+		}
+		key := source
+		if len(mapping.Tags) > 0 {
+			var mapped bool
+			if key, mapped = mapping.Tags[source]; !mapped {
+				continue
+			}
+		}
+		p.tags = append(p.tags, influxKey{source: source, key: escapeInflux(key, ",= ")})
+	}
+
+	staticKeys := make([]string, 0, len(mapping.StaticTags))
+	for key := range mapping.StaticTags {
+		staticKeys = append(staticKeys, key)
+	}
+	sort.Strings(staticKeys)
+	for _, key := range staticKeys {
+		if value := mapping.StaticTags[key]; value != "" {
+			p.staticTags += "," + escapeInflux(key, ",= ") + "=" + escapeInflux(value, ",= ")
+		}
+	}
+
+	counterCols := map[string]types.ColumnIndex{
+		types.BytesRcvdName: types.BytesRcvdColIdx,
+		types.BytesSentName: types.BytesSentColIdx,
+		types.PktsRcvdName:  types.PacketsRcvdColIdx,
+		types.PktsSentName:  types.PacketsSentColIdx,
+	}
+	for _, source := range influxFieldSources {
+		key := source
+		if len(mapping.Fields) > 0 {
+			var mapped bool
+			if key, mapped = mapping.Fields[source]; !mapped {
+				continue
+			}
+		} else if !b.counters.IsAll() && !b.counters.Has(counterCols[source]) {
+			continue
+		}
+		p.fields = append(p.fields, influxKey{source: source, key: escapeInflux(key, ",= ")})
+	}
+
+	return p
+}
+
+// AddRow adds a flow entry to the printer
+func (p *InfluxTablePrinter) AddRow(row Row) error {
+	p.rows = append(p.rows, row)
+	return nil
+}
+
+// AddRows adds several flow entries to the printer
+func (p *InfluxTablePrinter) AddRows(ctx context.Context, rows Rows) error {
+	return addRows(ctx, p, rows)
+}
+
+// Footer is a no-op for the InfluxTablePrinter
+func (p *InfluxTablePrinter) Footer(_ *Result) error {
+	return nil
+}
+
+// Print writes one point per flow. Flows without a timestamp (i.e. not queried by time) are
+// assigned the end of the queried time range
+func (p *InfluxTablePrinter) Print(result *Result) error {
+	var last time.Time
+	if result != nil {
+		last = result.Summary.Last
+	}
+
+	var line []byte
+	for _, row := range p.rows {
+		ts := row.Labels.Timestamp
+		if ts.IsZero() {
+			ts = last
+		}
+
+		line = p.appendLine(line[:0], row, ts)
+		if _, err := p.writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return p.writer.Flush()
+}
+
+func (p *InfluxTablePrinter) appendLine(line []byte, row Row, ts time.Time) []byte {
+	line = append(line, p.measurement...)
+	for _, tag := range p.tags {
+		value := p.tagValue(row, tag.source)
+
+		// empty tag values are not permitted by the line protocol
+		if value == "" {
+			continue
+		}
+		line = append(line, ',')
+		line = append(line, tag.key...)
+		line = append(line, '=')
+		line = append(line, escapeInflux(value, ",= ")...)
+	}
+	line = append(line, p.staticTags...)
+
+	for i, field := range p.fields {
+		if i == 0 {
+			line = append(line, ' ')
+		} else {
+			line = append(line, ',')
+		}
+		line = append(line, field.key...)
+		line = append(line, '=')
+		line = strconv.AppendUint(line, fieldValue(row.Counters, field.source), 10)
+		line = append(line, 'i')
+	}
+
+	if !ts.IsZero() {
+		line = append(line, ' ')
+		line = strconv.AppendInt(line, ts.UnixNano(), 10)
+	}
+	return append(line, '\n')
+}
+
+func (p *InfluxTablePrinter) tagValue(row Row, source string) string {
+	switch source {
+	case types.HostnameName:
+		return row.Labels.Hostname
+	case types.HostIDName:
+		return row.Labels.HostID
+	case types.IfaceName:
+		return row.Labels.Iface
+	case types.SIPName:
+		return row.Attributes.SrcIP.String()
+	case types.DIPName:
+		return row.Attributes.DstIP.String()
+	case types.DportName:
+		return strconv.FormatUint(uint64(row.Attributes.DstPort), 10)
+	case types.ProtoName:
+		return protocols.Format(row.Attributes.IPProto, p.numeric)
+	case types.TagName:
+		return row.Attributes.Tag
+	case types.TTLMinName:
+		return strconv.FormatUint(uint64(row.Attributes.TTLMin), 10)
+	case types.TTLMaxName:
+		return strconv.FormatUint(uint64(row.Attributes.TTLMax), 10)
+	}
+	return ""
+}
+
+func fieldValue(counters types.Counters, source string) uint64 {
+	switch source {
+	case types.BytesRcvdName:
+		return counters.BytesRcvd
+	case types.BytesSentName:
+		return counters.BytesSent
+	case types.PktsRcvdName:
+		return counters.PacketsRcvd
+	case types.PktsSentName:
+		return counters.PacketsSent
+	}
+	return 0
+}
+
+// escapeInflux escapes all special characters (and backslashes) in s. Line breaks cannot be
+// escaped in the line protocol and are replaced by spaces
+func escapeInflux(s string, special string) string {
+	if !strings.ContainsAny(s, special+"\\\n") {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r == '\n' {
+			r = ' '
+		}
+		if r == '\\' || strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
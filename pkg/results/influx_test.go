@@ -0,0 +1,95 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxTablePrinter(t *testing.T) {
+	ts := time.Unix(1704067200, 0)
+	last := ts.Add(time.Hour)
+
+	v4Row := Row{
+		Labels:     Labels{Timestamp: ts, Iface: "eth0", Hostname: "host A"},
+		Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), IPProto: ipProtoTCP, DstPort: 443, Tag: "web,prod"},
+		Counters:   types.Counters{BytesRcvd: 1000, BytesSent: 2000, PacketsRcvd: 10, PacketsSent: 20},
+	}
+	v6Row := Row{
+		Labels:     Labels{Iface: "eth1"},
+		Attributes: Attributes{SrcIP: netip.MustParseAddr("2001:db8::1"), DstIP: netip.MustParseAddr("2001:db8::2"), IPProto: ipProtoUDP, DstPort: 53},
+		Counters:   types.Counters{BytesRcvd: 100, PacketsRcvd: 1},
+	}
+
+	var tests = []struct {
+		name     string
+		query    string
+		rows     Rows
+		opts     []PrinterOption
+		expected []string
+	}{
+		{"default mapping", "time,iface,sip,dip,dport,proto", Rows{v4Row, v6Row}, nil, []string{
+			"goprobe_flows,iface=eth0,sip=10.0.0.1,dip=10.0.0.2,dport=443,proto=TCP bytes_rcvd=1000i,bytes_sent=2000i,pkts_rcvd=10i,pkts_sent=20i 1704067200000000000",
+			"goprobe_flows,iface=eth1,sip=2001:db8::1,dip=2001:db8::2,dport=53,proto=UDP bytes_rcvd=100i,bytes_sent=0i,pkts_rcvd=1i,pkts_sent=0i 1704070800000000000",
+		}},
+		{"escaping and empty values", "hostname,tag", Rows{v4Row, v6Row}, nil, []string{
+			`goprobe_flows,hostname=host\ A,tag=web\,prod bytes_rcvd=1000i,bytes_sent=2000i,pkts_rcvd=10i,pkts_sent=20i 1704067200000000000`,
+			`goprobe_flows bytes_rcvd=100i,bytes_sent=0i,pkts_rcvd=1i,pkts_sent=0i 1704070800000000000`,
+		}},
+		{"selected counters", "dport", Rows{v4Row}, []PrinterOption{WithCounters(types.CounterSelector{PacketsRcvd: true}), WithNumeric()}, []string{
+			"goprobe_flows,dport=443 pkts_rcvd=10i 1704067200000000000",
+		}},
+		{"custom mapping", "sip,dip,dport,proto", Rows{v4Row}, []PrinterOption{WithNumeric(), WithInfluxMapping(&InfluxMapping{
+			Measurement: "top talkers",
+			Tags:        map[string]string{types.SIPName: "src", types.ProtoName: "ip_proto"},
+			Fields:      map[string]string{types.BytesRcvdName: "rx", types.BytesSentName: "tx"},
+			StaticTags:  map[string]string{"site": "zrh", "env": "prod", "empty": ""},
+		})}, []string{
+			`top\ talkers,src=10.0.0.1,ip_proto=6,env=prod,site=zrh rx=1000i,tx=2000i 1704067200000000000`,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attributes, selector, err := types.ParseQueryType(test.query)
+			require.Nil(t, err)
+
+			buf := new(bytes.Buffer)
+			printer, err := NewTablePrinter(buf, "influxdb", SortTraffic, selector, types.DirectionBoth,
+				attributes, nil, types.Counters{}, len(test.rows), 0, "", "eth0,eth1", test.opts...,
+			)
+			require.Nil(t, err)
+			require.Nil(t, printer.AddRows(context.Background(), test.rows))
+			require.Nil(t, printer.Footer(nil))
+			require.Nil(t, printer.Print(&Result{Summary: Summary{TimeRange: TimeRange{Last: last}}}))
+
+			require.Equal(t, strings.Join(test.expected, "\n")+"\n", buf.String())
+		})
+	}
+}
+
+func TestInfluxMappingValidate(t *testing.T) {
+	var tests = []struct {
+		name    string
+		mapping *InfluxMapping
+		err     error
+	}{
+		{"nil", nil, nil},
+		{"valid", &InfluxMapping{Tags: map[string]string{types.SIPName: "src"}, Fields: map[string]string{types.PktsSentName: "tx"}}, nil},
+		{"unknown tag", &InfluxMapping{Tags: map[string]string{"snet": "src"}}, errorInfluxUnknownTag},
+		{"unknown field", &InfluxMapping{Fields: map[string]string{"bytes": "b"}}, errorInfluxUnknownField},
+		{"empty key", &InfluxMapping{StaticTags: map[string]string{"": "zrh"}}, errorInfluxEmptyKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.ErrorIs(t, test.mapping.Validate(), test.err)
+		})
+	}
+}
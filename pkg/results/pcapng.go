@@ -0,0 +1,325 @@
+package results
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/formatting"
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
+)
+
+// pcapng block types and options, see https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-01.html
+const (
+	pcapngBlockSHB uint32 = 0x0A0D0D0A
+	pcapngBlockIDB uint32 = 0x00000001
+	pcapngBlockEPB uint32 = 0x00000006
+
+	pcapngByteOrderMagic uint32 = 0x1A2B3C4D
+
+	pcapngOptEnd           uint16 = 0
+	pcapngOptComment       uint16 = 1
+	pcapngOptCustomBinary  uint16 = 2989
+	pcapngOptSHBUserAppl   uint16 = 4
+	pcapngOptIDBName       uint16 = 2
+	pcapngOptIDBDesciption uint16 = 3
+
+	// LINKTYPE_RAW: the marker packets start directly with an IPv4 / IPv6 header
+	pcapngLinkTypeRaw uint16 = 101
+)
+
+// PcapngPEN is the Private Enterprise Number identifying the custom option carrying the flow
+// counters of each marker packet. It defaults to the number reserved for documentation purposes
+// (RFC 5612)
+var PcapngPEN uint32 = 32473
+
+// PcapngCountersLen is the length of the custom option data following the PEN: the received /
+// sent bytes and packets of the flow, each encoded as 64 bit little endian integer
+const PcapngCountersLen = 4 * 8
+
+const (
+	// markerProtoUnknown is used as IP protocol of marker packets if the protocol was not part
+	// of the query ("use for experimentation and testing", RFC 3692)
+	markerProtoUnknown = 253
+
+	ipProtoTCP = 6
+	ipProtoUDP = 17
+
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	tcpHeaderLen  = 20
+	udpHeaderLen  = 8
+)
+
+// PcapngTablePrinter writes out all flows as synthetic pcapng records. Each flow is represented
+// by a single marker packet carrying its attributes in the packet headers and its counters in
+// the packet options, so that the flows can be correlated with real captures (e.g. in Wireshark)
+type PcapngTablePrinter struct {
+	basePrinter
+	writer *bufio.Writer
+
+	hasProto bool
+
+	rows       Rows
+	interfaces map[Labels]uint32
+}
+
+// NewPcapngTablePrinter creates a new PcapngTablePrinter
+func NewPcapngTablePrinter(b basePrinter) *PcapngTablePrinter {
+	p := &PcapngTablePrinter{
+		basePrinter: b,
+		writer:      bufio.NewWriter(b.output),
+		interfaces:  make(map[Labels]uint32),
+	}
+	for _, attrib := range b.attributes {
+		if attrib.Name() == types.ProtoName {
+			p.hasProto = true
+		}
+	}
+
+	return p
+}
+
+// AddRow adds a flow entry to the printer
+func (p *PcapngTablePrinter) AddRow(row Row) error {
+	p.rows = append(p.rows, row)
+	return nil
+}
+
+// AddRows adds several flow entries to the printer
+func (p *PcapngTablePrinter) AddRows(ctx context.Context, rows Rows) error {
+	return addRows(ctx, p, rows)
+}
+
+// Footer is a no-op for the PcapngTablePrinter
+func (p *PcapngTablePrinter) Footer(_ *Result) error {
+	return nil
+}
+
+// Print writes the section header and one marker packet per flow. Flows without a timestamp
+// (i.e. not queried by time) are placed at the start of the queried time range
+func (p *PcapngTablePrinter) Print(result *Result) error {
+	var opts []byte
+	opts = appendPcapngOption(opts, pcapngOptSHBUserAppl, []byte("goProbe"))
+	opts = appendPcapngOption(opts, pcapngOptEnd, nil)
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, pcapngByteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1) // major version
+	body = binary.LittleEndian.AppendUint16(body, 0) // minor version
+	body = binary.LittleEndian.AppendUint64(body, ^uint64(0))
+	p.writeBlock(pcapngBlockSHB, append(body, opts...))
+
+	var first time.Time
+	if result != nil {
+		first = result.Summary.First
+	}
+	for _, row := range p.rows {
+		ts := row.Labels.Timestamp
+		if ts.IsZero() {
+			ts = first
+		}
+		p.writePacket(p.iface(row.Labels), ts, row)
+	}
+
+	return p.writer.Flush()
+}
+
+// iface returns the ID of the interface description block for the interface (and host) the
+// flow was observed on, writing the block if it wasn't written before
+func (p *PcapngTablePrinter) iface(labels Labels) uint32 {
+	key := Labels{Iface: labels.Iface, Hostname: labels.Hostname, HostID: labels.HostID}
+	if id, exists := p.interfaces[key]; exists {
+		return id
+	}
+	id := uint32(len(p.interfaces))
+	p.interfaces[key] = id
+
+	name := labels.Iface
+	if name == "" {
+		name = p.ifaces
+	}
+
+	var opts []byte
+	if name != "" {
+		opts = appendPcapngOption(opts, pcapngOptIDBName, []byte(name))
+	}
+	if host := labels.Hostname; host != "" || labels.HostID != "" {
+		if labels.HostID != "" {
+			host = strings.TrimSpace(host + " (" + labels.HostID + ")")
+		}
+		opts = appendPcapngOption(opts, pcapngOptIDBDesciption, []byte(host))
+	}
+	opts = appendPcapngOption(opts, pcapngOptEnd, nil)
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint16(body, pcapngLinkTypeRaw)
+	body = binary.LittleEndian.AppendUint16(body, 0) // reserved
+	body = binary.LittleEndian.AppendUint32(body, 0) // no snap length limit
+	p.writeBlock(pcapngBlockIDB, append(body, opts...))
+
+	return id
+}
+
+func (p *PcapngTablePrinter) writePacket(ifaceID uint32, ts time.Time, row Row) {
+	packet := p.markerPacket(row.Attributes)
+
+	counters := make([]byte, 4, 4+PcapngCountersLen)
+	binary.LittleEndian.PutUint32(counters, PcapngPEN)
+	counters = binary.LittleEndian.AppendUint64(counters, row.Counters.BytesRcvd)
+	counters = binary.LittleEndian.AppendUint64(counters, row.Counters.BytesSent)
+	counters = binary.LittleEndian.AppendUint64(counters, row.Counters.PacketsRcvd)
+	counters = binary.LittleEndian.AppendUint64(counters, row.Counters.PacketsSent)
+
+	var opts []byte
+	opts = appendPcapngOption(opts, pcapngOptComment, []byte(p.comment(row)))
+	opts = appendPcapngOption(opts, pcapngOptCustomBinary, counters)
+	opts = appendPcapngOption(opts, pcapngOptEnd, nil)
+
+	micros := uint64(ts.UnixMicro())
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, ifaceID)
+	body = binary.LittleEndian.AppendUint32(body, uint32(micros>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(micros))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(packet))) // captured length
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(packet))) // original length
+	body = append(body, packet...)
+	body = append(body, make([]byte, pad4(len(packet)))...)
+	p.writeBlock(pcapngBlockEPB, append(body, opts...))
+}
+
+// markerPacket builds a raw IP packet representing the flow attributes. Attributes which were
+// not queried are left unspecified
+func (p *PcapngTablePrinter) markerPacket(attrs Attributes) []byte {
+	sip, dip := attrs.SrcIP, attrs.DstIP
+	is4 := true
+	if sip.IsValid() {
+		is4 = sip.Is4()
+	} else if dip.IsValid() {
+		is4 = dip.Is4()
+	}
+	unspecified := netip.IPv6Unspecified()
+	if is4 {
+		unspecified = netip.IPv4Unspecified()
+	}
+	if !sip.IsValid() {
+		sip = unspecified
+	}
+	if !dip.IsValid() {
+		dip = unspecified
+	}
+
+	proto := uint8(markerProtoUnknown)
+	if p.hasProto {
+		proto = attrs.IPProto
+	}
+
+	var l4 []byte
+	switch proto {
+	case ipProtoTCP:
+		l4 = make([]byte, tcpHeaderLen)
+		binary.BigEndian.PutUint16(l4[2:], attrs.DstPort)
+		l4[12] = (tcpHeaderLen / 4) << 4 // data offset
+	case ipProtoUDP:
+		l4 = make([]byte, udpHeaderLen)
+		binary.BigEndian.PutUint16(l4[2:], attrs.DstPort)
+		binary.BigEndian.PutUint16(l4[4:], udpHeaderLen)
+	}
+
+	var packet []byte
+	if is4 {
+		packet = make([]byte, ipv4HeaderLen, ipv4HeaderLen+len(l4))
+		packet[0] = 0x45 // version 4, header length of 5 words
+		binary.BigEndian.PutUint16(packet[2:], uint16(ipv4HeaderLen+len(l4)))
+		packet[8] = 64 // TTL
+		packet[9] = proto
+		src, dst := sip.As4(), dip.As4()
+		copy(packet[12:], src[:])
+		copy(packet[16:], dst[:])
+		binary.BigEndian.PutUint16(packet[10:], ipv4Checksum(packet))
+	} else {
+		packet = make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(l4))
+		packet[0] = 0x60 // version 6
+		binary.BigEndian.PutUint16(packet[4:], uint16(len(l4)))
+		packet[6] = proto
+		packet[7] = 64 // hop limit
+		src, dst := sip.As16(), dip.As16()
+		copy(packet[8:], src[:])
+		copy(packet[24:], dst[:])
+	}
+
+	return append(packet, l4...)
+}
+
+// comment returns a human-readable description of the flow and its counters
+func (p *PcapngTablePrinter) comment(row Row) string {
+	var sb strings.Builder
+	sb.WriteString("goProbe flow")
+
+	for _, attrib := range p.attributes {
+		switch attrib.Name() {
+		case types.SIPName:
+			fmt.Fprintf(&sb, " sip=%s", tryLookup(p.ips2domains, row.Attributes.SrcIP.String()))
+		case types.DIPName:
+			fmt.Fprintf(&sb, " dip=%s", tryLookup(p.ips2domains, row.Attributes.DstIP.String()))
+		case types.DportName:
+			fmt.Fprintf(&sb, " dport=%d", row.Attributes.DstPort)
+		case types.ProtoName:
+			fmt.Fprintf(&sb, " proto=%s", protocols.Format(row.Attributes.IPProto, p.numeric))
+		case types.TagName:
+			fmt.Fprintf(&sb, " tag=%s", row.Attributes.Tag)
+		case types.TTLMinName:
+			fmt.Fprintf(&sb, " ttl_min=%d", row.Attributes.TTLMin)
+		case types.TTLMaxName:
+			fmt.Fprintf(&sb, " ttl_max=%d", row.Attributes.TTLMax)
+		}
+	}
+
+	fmt.Fprintf(&sb, ": received %s packets / %s, sent %s packets / %s",
+		formatting.Count(row.Counters.PacketsRcvd), formatting.Size(row.Counters.BytesRcvd),
+		formatting.Count(row.Counters.PacketsSent), formatting.Size(row.Counters.BytesSent),
+	)
+
+	return sb.String()
+}
+
+// writeBlock writes a pcapng block. Write errors are deferred until the writer is flushed
+func (p *PcapngTablePrinter) writeBlock(blockType uint32, body []byte) {
+	var hdr [8]byte
+	totalLen := uint32(12 + len(body))
+	binary.LittleEndian.PutUint32(hdr[0:], blockType)
+	binary.LittleEndian.PutUint32(hdr[4:], totalLen)
+
+	_, _ = p.writer.Write(hdr[:])
+	_, _ = p.writer.Write(body)
+	_, _ = p.writer.Write(hdr[4:])
+}
+
+// appendPcapngOption appends an option (padded to 32 bits) to an option list
+func appendPcapngOption(opts []byte, code uint16, value []byte) []byte {
+	opts = binary.LittleEndian.AppendUint16(opts, code)
+	opts = binary.LittleEndian.AppendUint16(opts, uint16(len(value)))
+	opts = append(opts, value...)
+	return append(opts, make([]byte, pad4(len(value)))...)
+}
+
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+func ipv4Checksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
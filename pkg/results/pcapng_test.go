@@ -0,0 +1,151 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type pcapngBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+func readPcapngBlocks(t *testing.T, data []byte) (blocks []pcapngBlock) {
+	t.Helper()
+
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 12)
+		blockType := binary.LittleEndian.Uint32(data)
+		totalLen := int(binary.LittleEndian.Uint32(data[4:]))
+		require.Zero(t, totalLen%4, "block length not aligned")
+		require.LessOrEqual(t, totalLen, len(data))
+		require.Equal(t, uint32(totalLen), binary.LittleEndian.Uint32(data[totalLen-4:]))
+
+		blocks = append(blocks, pcapngBlock{blockType, data[8 : totalLen-4]})
+		data = data[totalLen:]
+	}
+	return
+}
+
+func readPcapngOptions(t *testing.T, opts []byte) map[uint16][]byte {
+	t.Helper()
+
+	res := make(map[uint16][]byte)
+	for {
+		require.GreaterOrEqual(t, len(opts), 4)
+		code, length := binary.LittleEndian.Uint16(opts), int(binary.LittleEndian.Uint16(opts[2:]))
+		if code == pcapngOptEnd {
+			return res
+		}
+		res[code] = opts[4 : 4+length]
+		opts = opts[4+length+pad4(length):]
+	}
+}
+
+func TestPcapngTablePrinter(t *testing.T) {
+	attributes, selector, err := types.ParseQueryType("time,iface,sip,dip,dport,proto")
+	require.Nil(t, err)
+
+	ts := time.Unix(1704067200, 0)
+	rows := Rows{
+		{
+			Labels:     Labels{Timestamp: ts, Iface: "eth0"},
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), IPProto: ipProtoTCP, DstPort: 443},
+			Counters:   types.Counters{BytesRcvd: 1000, BytesSent: 2000, PacketsRcvd: 10, PacketsSent: 20},
+		},
+		{
+			Labels:     Labels{Timestamp: ts.Add(5 * time.Minute), Iface: "eth1"},
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("2001:db8::1"), DstIP: netip.MustParseAddr("2001:db8::2"), IPProto: ipProtoUDP, DstPort: 53},
+			Counters:   types.Counters{BytesRcvd: 100, PacketsRcvd: 1},
+		},
+		{
+			Labels:     Labels{Timestamp: ts.Add(5 * time.Minute), Iface: "eth0"},
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.3"), IPProto: 1},
+			Counters:   types.Counters{BytesSent: 84, PacketsSent: 1},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	printer, err := NewTablePrinter(buf, "pcapng", SortTraffic, selector, types.DirectionBoth,
+		attributes, nil, types.Counters{}, len(rows), 0, "", "eth0,eth1",
+	)
+	require.Nil(t, err)
+	require.Nil(t, printer.AddRows(context.Background(), rows))
+	require.Nil(t, printer.Footer(nil))
+	require.Nil(t, printer.Print(nil))
+
+	blocks := readPcapngBlocks(t, buf.Bytes())
+	var blockTypes []uint32
+	for _, block := range blocks {
+		blockTypes = append(blockTypes, block.blockType)
+	}
+	require.Equal(t, []uint32{
+		pcapngBlockSHB,
+		pcapngBlockIDB, pcapngBlockEPB,
+		pcapngBlockIDB, pcapngBlockEPB,
+		pcapngBlockEPB,
+	}, blockTypes)
+
+	// section header
+	require.Equal(t, pcapngByteOrderMagic, binary.LittleEndian.Uint32(blocks[0].body))
+	require.Equal(t, "goProbe", string(readPcapngOptions(t, blocks[0].body[16:])[pcapngOptSHBUserAppl]))
+
+	// interfaces
+	for i, iface := range map[int]string{1: "eth0", 3: "eth1"} {
+		require.Equal(t, pcapngLinkTypeRaw, binary.LittleEndian.Uint16(blocks[i].body))
+		require.Equal(t, iface, string(readPcapngOptions(t, blocks[i].body[8:])[pcapngOptIDBName]))
+	}
+
+	var tests = []struct {
+		block   pcapngBlock
+		ifaceID uint32
+		row     Row
+		packet  []byte
+	}{
+		{blocks[2], 0, rows[0], []byte{
+			0x45, 0x00, 0x00, 0x28, 0x00, 0x00, 0x00, 0x00, 0x40, 0x06, 0x66, 0xce, 10, 0, 0, 1, 10, 0, 0, 2,
+			0x00, 0x00, 0x01, 0xbb, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}},
+		{blocks[4], 1, rows[1], append(append(append([]byte{
+			0x60, 0x00, 0x00, 0x00, 0x00, 0x08, 0x11, 0x40},
+			rows[1].Attributes.SrcIP.AsSlice()...),
+			rows[1].Attributes.DstIP.AsSlice()...),
+			0x00, 0x00, 0x00, 0x35, 0x00, 0x08, 0x00, 0x00,
+		)},
+		{blocks[5], 0, rows[2], []byte{
+			0x45, 0x00, 0x00, 0x14, 0x00, 0x00, 0x00, 0x00, 0x40, 0x01, 0x66, 0xe6, 10, 0, 0, 1, 10, 0, 0, 3,
+		}},
+	}
+
+	for _, test := range tests {
+		body := test.block.body
+		require.Equal(t, test.ifaceID, binary.LittleEndian.Uint32(body))
+
+		micros := uint64(binary.LittleEndian.Uint32(body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(body[8:]))
+		require.Equal(t, test.row.Labels.Timestamp.UnixMicro(), int64(micros))
+
+		capLen := int(binary.LittleEndian.Uint32(body[12:]))
+		require.Equal(t, uint32(capLen), binary.LittleEndian.Uint32(body[16:]))
+		require.Equal(t, test.packet, body[20:20+capLen])
+
+		opts := readPcapngOptions(t, body[20+capLen+pad4(capLen):])
+		require.Contains(t, string(opts[pcapngOptComment]), "sip="+test.row.Attributes.SrcIP.String())
+
+		custom := opts[pcapngOptCustomBinary]
+		require.Len(t, custom, 4+PcapngCountersLen)
+		require.Equal(t, PcapngPEN, binary.LittleEndian.Uint32(custom))
+		require.Equal(t, test.row.Counters, types.Counters{
+			BytesRcvd:   binary.LittleEndian.Uint64(custom[4:]),
+			BytesSent:   binary.LittleEndian.Uint64(custom[12:]),
+			PacketsRcvd: binary.LittleEndian.Uint64(custom[20:]),
+			PacketsSent: binary.LittleEndian.Uint64(custom[28:]),
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Plan describes how a query is executed, i.e. which parts of the database are read and how the
+// data is processed, without actually reading any flow data (see "explain" query argument)
+type Plan struct {
+	Columns    []string `json:"columns"`               // Columns: the columns read from disk (projection). Example: [sip dip bytes_rcvd bytes_sent]
+	Pushdowns  []string `json:"pushdowns,omitempty"`   // Pushdowns: the filters evaluated before / instead of reading flow entries. Example: ["ip version: IPv4 entries only"]
+	Workers    int      `json:"workers"`               // Workers: the number of processing units reading / decompressing the data of an interface in parallel. Example: 8
+	CPUWorkers int      `json:"cpu_workers"`           // CPUWorkers: the number of processing units filtering / aggregating the data of an interface in parallel. Example: 8
+	LowMem     bool     `json:"low_mem,omitempty"`     // LowMem: whether the query runs in memory-saving mode. Example: false
+	Mmap       bool     `json:"mmap,omitempty"`        // Mmap: whether the database is read via memory-mapped IO. Example: false
+	Live       bool     `json:"live,omitempty"`        // Live: whether live flow data is queried (in addition to the database). Example: false
+	MaxAggMem  uint64   `json:"max_agg_mem,omitempty"` // MaxAggMem: the memory budget for the aggregation in bytes (partial aggregates exceeding it are spilled to disk). Example: 536870912
+
+	Ifaces []IfacePlan `json:"ifaces"` // Ifaces: the execution plan of each interface
+}
+
+// IfacePlan describes the data of an interface read by a query
+type IfacePlan struct {
+	Host  string `json:"host,omitempty"` // Host: the host the interface resides on. Example: hostA
+	Iface string `json:"iface"`          // Iface: the interface. Example: eth0
+
+	Days []time.Time `json:"days,omitempty"` // Days: the day partitions read
+
+	Workloads     int `json:"workloads"`      // Workloads: the number of workloads (bulks of day partitions) distributed among the workers. Example: 1
+	Blocks        int `json:"blocks"`         // Blocks: the number of blocks read. Example: 288
+	BlocksSkipped int `json:"blocks_skipped"` // BlocksSkipped: the number of blocks of the day partitions that are skipped because they are outside of the time range. Example: 12
+
+	Entries        uint64 `json:"entries"`         // Entries: the number of flow entries evaluated. Example: 102400
+	EntriesSkipped uint64 `json:"entries_skipped"` // EntriesSkipped: the number of flow entries of the blocks read that are skipped without evaluation (e.g. due to the IP version). Example: 2048
+
+	BytesRead    uint64 `json:"bytes_read"`    // BytesRead: the amount of (compressed) data read from disk. Example: 1048576
+	BytesDecoded uint64 `json:"bytes_decoded"` // BytesDecoded: the amount of data after decompression. Example: 4194304
+
+	MemBuffers        uint64 `json:"mem_buffers"`         // MemBuffers: the estimated memory required for reading / decompressing the blocks. Example: 1048576
+	MemAggregationMax uint64 `json:"mem_aggregation_max"` // MemAggregationMax: the upper bound of the memory required for aggregation (if no flows could be aggregated). Example: 6553600
+}
+
+// Add merges the plan of another host into the plan. It is assumed that both plans originate
+// from the same query
+func (p *Plan) Add(p2 *Plan) *Plan {
+	if p2 == nil {
+		return p
+	}
+	if p == nil {
+		p = &Plan{
+			Columns:    p2.Columns,
+			Pushdowns:  p2.Pushdowns,
+			Workers:    p2.Workers,
+			CPUWorkers: p2.CPUWorkers,
+			LowMem:     p2.LowMem,
+			Mmap:       p2.Mmap,
+			Live:       p2.Live,
+			MaxAggMem:  p2.MaxAggMem,
+		}
+	}
+	p.Ifaces = append(p.Ifaces, p2.Ifaces...)
+	sort.SliceStable(p.Ifaces, func(i, j int) bool {
+		if p.Ifaces[i].Host != p.Ifaces[j].Host {
+			return p.Ifaces[i].Host < p.Ifaces[j].Host
+		}
+		return p.Ifaces[i].Iface < p.Ifaces[j].Iface
+	})
+	return p
+}
+
+// Total sums up the plans of all interfaces
+func (p *Plan) Total() (total IfacePlan) {
+	for _, ip := range p.Ifaces {
+		total.Workloads += ip.Workloads
+		total.Blocks += ip.Blocks
+		total.BlocksSkipped += ip.BlocksSkipped
+		total.Entries += ip.Entries
+		total.EntriesSkipped += ip.EntriesSkipped
+		total.BytesRead += ip.BytesRead
+		total.BytesDecoded += ip.BytesDecoded
+		total.MemBuffers += ip.MemBuffers
+		total.MemAggregationMax += ip.MemAggregationMax
+	}
+	return
+}
+
+// Print writes a human-readable representation of the plan to w
+func (p *Plan) Print(w io.Writer) error {
+	var format TextFormatter
+
+	fmt.Fprintf(w, "Columns:    %s\n", strings.Join(p.Columns, ", "))
+	pushdowns := "none"
+	if len(p.Pushdowns) > 0 {
+		pushdowns = strings.Join(p.Pushdowns, "\n            ")
+	}
+	fmt.Fprintf(w, "Pushdowns:  %s\n", pushdowns)
+
+	var modes []string
+	if p.LowMem {
+		modes = append(modes, "low-mem")
+	}
+	if p.Mmap {
+		modes = append(modes, "mmap")
+	}
+	if p.Live {
+		modes = append(modes, "live (flows currently tracked by goProbe are included)")
+	}
+	if p.MaxAggMem > 0 {
+		modes = append(modes, fmt.Sprintf("aggregation budget %s (spilling to disk if exceeded)", format.Size(p.MaxAggMem)))
+	}
+	workers := fmt.Sprintf("%d IO / %d CPU per interface (interfaces are processed sequentially)", p.Workers, p.CPUWorkers)
+	if len(modes) > 0 {
+		workers += ", " + strings.Join(modes, ", ")
+	}
+	fmt.Fprintf(w, "Workers:    %s\n\n", workers)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	sep := "\t"
+
+	header := []string{"iface", "days", "workloads", "blocks", "(skipped)", "entries", "(skipped)", "read", "decoded", "mem (buffers)", "mem (agg, max)"}
+	withHost := len(p.Ifaces) > 0 && p.Ifaces[0].Host != ""
+	if withHost {
+		header = append([]string{"host"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, sep)+sep)
+
+	printRow := func(host, iface, days string, ip IfacePlan) {
+		row := []string{
+			iface,
+			days,
+			fmt.Sprint(ip.Workloads),
+			fmt.Sprint(ip.Blocks),
+			fmt.Sprint(ip.BlocksSkipped),
+			fmt.Sprint(ip.Entries),
+			fmt.Sprint(ip.EntriesSkipped),
+			format.Size(ip.BytesRead),
+			format.Size(ip.BytesDecoded),
+			format.Size(ip.MemBuffers),
+			format.Size(ip.MemAggregationMax),
+		}
+		if withHost {
+			row = append([]string{host}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, sep)+sep)
+	}
+	for _, ip := range p.Ifaces {
+		printRow(ip.Host, ip.Iface, formatDays(ip.Days), ip)
+	}
+	if len(p.Ifaces) > 1 {
+		printRow("", "total", "", p.Total())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// the interfaces are processed sequentially, hence the peak memory is determined by the
+	// most demanding one
+	var memPeak uint64
+	for _, ip := range p.Ifaces {
+		memAgg := ip.MemAggregationMax
+		if p.MaxAggMem > 0 && memAgg > p.MaxAggMem {
+			memAgg = p.MaxAggMem
+		}
+		if mem := ip.MemBuffers + memAgg; mem > memPeak {
+			memPeak = mem
+		}
+	}
+	_, err := fmt.Fprintf(w, "\nEstimated peak memory: %s (upper bound)\n", format.Size(memPeak))
+	return err
+}
+
+func formatDays(days []time.Time) string {
+	switch len(days) {
+	case 0:
+		return "-"
+	case 1:
+		return days[0].Format(time.DateOnly)
+	}
+	return fmt.Sprintf("%s - %s (%d)", days[0].Format(time.DateOnly), days[len(days)-1].Format(time.DateOnly), len(days))
+}
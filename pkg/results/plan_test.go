@@ -0,0 +1,52 @@
+package results
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanAdd(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var plan *Plan
+	plan = plan.Add(nil)
+	require.Nil(t, plan)
+
+	plan = plan.Add(&Plan{
+		Columns: []string{"sip", "bytes_rcvd"},
+		Workers: 4,
+		Ifaces: []IfacePlan{
+			{Host: "hostB", Iface: "eth0", Days: []time.Time{day}, Workloads: 1, Blocks: 10, Entries: 100, BytesRead: 1000, MemBuffers: 10, MemAggregationMax: 20},
+		},
+	})
+	plan = plan.Add(&Plan{
+		Columns: []string{"sip", "bytes_rcvd"},
+		Workers: 8,
+		Ifaces: []IfacePlan{
+			{Host: "hostA", Iface: "eth1", Days: []time.Time{day, day.AddDate(0, 0, 1)}, Workloads: 1, Blocks: 20, BlocksSkipped: 2, Entries: 200, EntriesSkipped: 5, BytesRead: 2000, MemBuffers: 30, MemAggregationMax: 40},
+			{Host: "hostA", Iface: "eth0", Workloads: 0},
+		},
+	})
+
+	// the settings of the first plan are retained, the interfaces are ordered by host / interface
+	require.Equal(t, 4, plan.Workers)
+	require.Equal(t, []string{"sip", "bytes_rcvd"}, plan.Columns)
+	require.Len(t, plan.Ifaces, 3)
+	for i, expected := range [][2]string{{"hostA", "eth0"}, {"hostA", "eth1"}, {"hostB", "eth0"}} {
+		require.Equal(t, expected, [2]string{plan.Ifaces[i].Host, plan.Ifaces[i].Iface})
+	}
+
+	require.Equal(t, IfacePlan{
+		Workloads: 2, Blocks: 30, BlocksSkipped: 2, Entries: 300, EntriesSkipped: 5,
+		BytesRead: 3000, MemBuffers: 40, MemAggregationMax: 60,
+	}, plan.Total())
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, plan.Print(buf))
+	require.Contains(t, buf.String(), "2024-01-01 - 2024-01-02 (2)")
+	require.Contains(t, buf.String(), "total")
+	require.Contains(t, buf.String(), "Estimated peak memory")
+}
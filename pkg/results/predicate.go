@@ -0,0 +1,381 @@
+package results
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// Predicate denotes a condition on the counters of a row, evaluated after the flows have been
+// aggregated (as opposed to the query condition, which is evaluated on the individual flows).
+// It allows to select rows by derived values, e.g. "ratio(bytes_in, bytes_out) > 100" or
+// "packets_out = 0"
+type Predicate interface {
+	fmt.Stringer
+
+	// Match returns if the counters of a row fulfill the predicate
+	Match(c types.Counters) bool
+
+	// Columns returns the counter columns the predicate is evaluated on
+	Columns() []types.ColumnIndex
+}
+
+// Filter removes all rows not fulfilling the predicate (in place) and returns the remaining rows,
+// along with their total traffic
+func (r Rows) Filter(p Predicate) (Rows, types.Counters) {
+	var (
+		n      int
+		totals types.Counters
+	)
+	for _, row := range r {
+		if p.Match(row.Counters) {
+			r[n] = row
+			totals = totals.Add(row.Counters)
+			n++
+		}
+	}
+	return r[:n], totals
+}
+
+// counter denotes a counter (or the sum of both directions of a counter) of a row
+type counter struct {
+	name string
+	cols []types.ColumnIndex
+	val  func(types.Counters) uint64
+}
+
+var (
+	counterBytesIn    = counter{"bytes_in", []types.ColumnIndex{types.BytesRcvdColIdx}, func(c types.Counters) uint64 { return c.BytesRcvd }}
+	counterBytesOut   = counter{"bytes_out", []types.ColumnIndex{types.BytesSentColIdx}, func(c types.Counters) uint64 { return c.BytesSent }}
+	counterPacketsIn  = counter{"packets_in", []types.ColumnIndex{types.PacketsRcvdColIdx}, func(c types.Counters) uint64 { return c.PacketsRcvd }}
+	counterPacketsOut = counter{"packets_out", []types.ColumnIndex{types.PacketsSentColIdx}, func(c types.Counters) uint64 { return c.PacketsSent }}
+	counterBytes      = counter{types.CountersBytes, []types.ColumnIndex{types.BytesRcvdColIdx, types.BytesSentColIdx}, types.Counters.SumBytes}
+	counterPackets    = counter{types.CountersPackets, []types.ColumnIndex{types.PacketsRcvdColIdx, types.PacketsSentColIdx}, types.Counters.SumPackets}
+)
+
+// counters maps all names a counter can be referred to by (c.f. types.ParseCounterSelector) to it
+var counters = map[string]counter{
+	"bytes_in":            counterBytesIn,
+	types.BytesRcvdName:   counterBytesIn,
+	"bytes_out":           counterBytesOut,
+	types.BytesSentName:   counterBytesOut,
+	"packets_in":          counterPacketsIn,
+	"packets_rcvd":        counterPacketsIn,
+	"pkts_in":             counterPacketsIn,
+	types.PktsRcvdName:    counterPacketsIn,
+	"packets_out":         counterPacketsOut,
+	"packets_sent":        counterPacketsOut,
+	"pkts_out":            counterPacketsOut,
+	types.PktsSentName:    counterPacketsOut,
+	types.CountersBytes:   counterBytes,
+	types.CountersPackets: counterPackets,
+	"pkts":                counterPackets,
+}
+
+// operand denotes a value derived from the counters of a row
+type operand interface {
+	fmt.Stringer
+	value(c types.Counters) float64
+	columns() []types.ColumnIndex
+}
+
+func (c counter) value(cs types.Counters) float64 { return float64(c.val(cs)) }
+func (c counter) columns() []types.ColumnIndex    { return c.cols }
+func (c counter) String() string                  { return c.name }
+
+// ratio denotes the ratio between two counters. A ratio with a zero denominator is infinite (or
+// zero if the numerator is zero as well), i.e. one-directional traffic exceeds any ratio
+type ratio struct {
+	num, denom counter
+}
+
+func (r ratio) value(cs types.Counters) float64 {
+	num, denom := r.num.val(cs), r.denom.val(cs)
+	if denom == 0 {
+		if num == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return float64(num) / float64(denom)
+}
+
+func (r ratio) columns() []types.ColumnIndex {
+	return append(append([]types.ColumnIndex{}, r.num.cols...), r.denom.cols...)
+}
+
+func (r ratio) String() string {
+	return fmt.Sprintf("ratio(%s, %s)", r.num, r.denom)
+}
+
+type comparison struct {
+	operand    operand
+	comparator string
+	value      float64
+}
+
+func (c comparison) Match(cs types.Counters) bool {
+	val := c.operand.value(cs)
+	switch c.comparator {
+	case "=":
+		return val == c.value
+	case "!=":
+		return val != c.value
+	case "<":
+		return val < c.value
+	case "<=":
+		return val <= c.value
+	case ">":
+		return val > c.value
+	case ">=":
+		return val >= c.value
+	}
+	return false
+}
+
+func (c comparison) Columns() []types.ColumnIndex {
+	return c.operand.columns()
+}
+
+func (c comparison) String() string {
+	return fmt.Sprintf("%s %s %s", c.operand, c.comparator, strconv.FormatFloat(c.value, 'g', -1, 64))
+}
+
+type conjunction struct {
+	left, right Predicate
+}
+
+func (c conjunction) Match(cs types.Counters) bool {
+	return c.left.Match(cs) && c.right.Match(cs)
+}
+
+func (c conjunction) Columns() []types.ColumnIndex {
+	return append(c.left.Columns(), c.right.Columns()...)
+}
+
+func (c conjunction) String() string {
+	return fmt.Sprintf("%s & %s", parenthesize(c.left), parenthesize(c.right))
+}
+
+type disjunction struct {
+	left, right Predicate
+}
+
+func (d disjunction) Match(cs types.Counters) bool {
+	return d.left.Match(cs) || d.right.Match(cs)
+}
+
+func (d disjunction) Columns() []types.ColumnIndex {
+	return append(d.left.Columns(), d.right.Columns()...)
+}
+
+func (d disjunction) String() string {
+	return fmt.Sprintf("%s | %s", d.left, d.right)
+}
+
+// parenthesize retains the precedence of disjunctions nested in a conjunction
+func parenthesize(p Predicate) string {
+	if _, isDisjunction := p.(disjunction); isDisjunction {
+		return "(" + p.String() + ")"
+	}
+	return p.String()
+}
+
+// ParsePredicate parses a predicate on the counters of a row. Comparisons of a counter (bytes_in,
+// bytes_out, packets_in, packets_out or the sums bytes / packets) or of the ratio of two counters
+// (ratio(<counter>, <counter>)) with a number can be combined via "&" and "|" (and grouped via
+// parentheses), e.g. "ratio(bytes_in, bytes_out) > 100 | packets_out = 0"
+func ParsePredicate(predicate string) (Predicate, error) {
+	tokens, err := tokenizePredicate(predicate)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, types.NewParseError([]string{predicate}, 0, " ", "empty predicate")
+	}
+
+	p := &predicateParser{tokens: tokens}
+	pred, err := p.parseDisjunction()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(tokens) {
+		return nil, p.errorf("unexpected token")
+	}
+	return pred, nil
+}
+
+var predicateOperators = map[string]string{
+	"&&": "&", "and": "&",
+	"||": "|", "or": "|",
+	"==": "=",
+}
+
+// tokenizePredicate splits a predicate into identifiers, numbers and operators
+func tokenizePredicate(predicate string) (tokens []string, err error) {
+	runes := []rune(strings.ToLower(predicate))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' ||
+				((runes[i] == '+' || runes[i] == '-') && runes[i-1] == 'e')) {
+				i++
+			}
+		case strings.ContainsRune("(),", r):
+			i++
+		case strings.ContainsRune("&|=!<>", r):
+			for i < len(runes) && strings.ContainsRune("&|=!<>", runes[i]) {
+				i++
+			}
+		default:
+			return nil, types.NewParseError(append(tokens, string(runes[i:])), len(tokens), " ", "unexpected character %q", r)
+		}
+
+		token := string(runes[start:i])
+		if op, exists := predicateOperators[token]; exists {
+			token = op
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+type predicateParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *predicateParser) errorf(description string, args ...any) error {
+	return types.NewParseError(p.tokens, p.pos, " ", description, args...)
+}
+
+func (p *predicateParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *predicateParser) expect(token string) error {
+	if p.peek() != token {
+		return p.errorf("expected %q", token)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *predicateParser) parseDisjunction() (Predicate, error) {
+	left, err := p.parseConjunction()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "|" {
+		p.pos++
+		right, err := p.parseConjunction()
+		if err != nil {
+			return nil, err
+		}
+		left = disjunction{left, right}
+	}
+	return left, nil
+}
+
+func (p *predicateParser) parseConjunction() (Predicate, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&" {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = conjunction{left, right}
+	}
+	return left, nil
+}
+
+func (p *predicateParser) parseTerm() (Predicate, error) {
+	if p.peek() == "(" {
+		p.pos++
+		pred, err := p.parseDisjunction()
+		if err != nil {
+			return nil, err
+		}
+		return pred, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *predicateParser) parseComparison() (Predicate, error) {
+	op, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	comparator := p.peek()
+	switch comparator {
+	case "=", "!=", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		return nil, p.errorf("expected comparator (one of =, !=, <, <=, >, >=)")
+	}
+
+	value, err := strconv.ParseFloat(p.peek(), 64)
+	if err != nil || value < 0 || math.IsNaN(value) {
+		return nil, p.errorf("expected non-negative number")
+	}
+	p.pos++
+
+	return comparison{operand: op, comparator: comparator, value: value}, nil
+}
+
+func (p *predicateParser) parseOperand() (operand, error) {
+	if p.peek() == "ratio" {
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		num, err := p.parseCounter()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		denom, err := p.parseCounter()
+		if err != nil {
+			return nil, err
+		}
+		return ratio{num: num, denom: denom}, p.expect(")")
+	}
+	if _, exists := counters[p.peek()]; !exists {
+		return nil, p.errorf("expected %s or ratio(<counter>, <counter>)", expectedCounterMsg)
+	}
+	return p.parseCounter()
+}
+
+const expectedCounterMsg = "counter (one of bytes_in, bytes_out, packets_in, packets_out, bytes, packets)"
+
+func (p *predicateParser) parseCounter() (counter, error) {
+	c, exists := counters[p.peek()]
+	if !exists {
+		return counter{}, p.errorf("expected %s", expectedCounterMsg)
+	}
+	p.pos++
+	return c, nil
+}
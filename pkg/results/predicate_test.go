@@ -0,0 +1,109 @@
+package results
+
+import (
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePredicate(t *testing.T) {
+	var tests = []struct {
+		predicate string
+		canonical string
+		columns   []types.ColumnIndex
+	}{
+		{"packets_out = 0", "packets_out = 0", []types.ColumnIndex{types.PacketsSentColIdx}},
+		{"ratio(bytes_in,bytes_out)>100", "ratio(bytes_in, bytes_out) > 100", []types.ColumnIndex{types.BytesRcvdColIdx, types.BytesSentColIdx}},
+		{"RATIO(bytes_rcvd, bytes_sent) >= 0.5", "ratio(bytes_in, bytes_out) >= 0.5", []types.ColumnIndex{types.BytesRcvdColIdx, types.BytesSentColIdx}},
+		{"pkts_in == 0 or pkts_out == 0", "packets_in = 0 | packets_out = 0", []types.ColumnIndex{types.PacketsRcvdColIdx, types.PacketsSentColIdx}},
+		{"bytes > 1e6 && (packets_in = 0 || packets_out = 0)", "bytes > 1e+06 & (packets_in = 0 | packets_out = 0)",
+			[]types.ColumnIndex{types.BytesRcvdColIdx, types.BytesSentColIdx, types.PacketsRcvdColIdx, types.PacketsSentColIdx}},
+		{"packets < 10 & bytes != 0 | packets_in > 3", "packets < 10 & bytes != 0 | packets_in > 3",
+			[]types.ColumnIndex{types.PacketsRcvdColIdx, types.PacketsSentColIdx, types.BytesRcvdColIdx, types.BytesSentColIdx, types.PacketsRcvdColIdx}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.predicate, func(t *testing.T) {
+			p, err := ParsePredicate(test.predicate)
+			require.Nil(t, err)
+			require.Equal(t, test.canonical, p.String())
+			require.Equal(t, test.columns, p.Columns())
+
+			// the canonical form must be stable
+			p2, err := ParsePredicate(p.String())
+			require.Nil(t, err)
+			require.Equal(t, test.canonical, p2.String())
+		})
+	}
+}
+
+func TestParsePredicateErrors(t *testing.T) {
+	for _, predicate := range []string{
+		"",
+		"bytes",
+		"bytes >",
+		"bytes > -1",
+		"bytes > x",
+		"sip = 1.2.3.4",
+		"ratio(bytes_in) > 1",
+		"ratio(bytes_in, dport) > 1",
+		"(bytes > 1",
+		"bytes > 1 packets > 1",
+		"bytes ~ 1",
+		"bytes <> 1",
+	} {
+		t.Run(predicate, func(t *testing.T) {
+			_, err := ParsePredicate(predicate)
+			require.NotNil(t, err)
+
+			var parseErr *types.ParseError
+			require.ErrorAs(t, err, &parseErr)
+		})
+	}
+}
+
+func TestPredicateMatch(t *testing.T) {
+	var tests = []struct {
+		predicate string
+		counters  types.Counters
+		matches   bool
+	}{
+		{"ratio(bytes_in, bytes_out) > 100", types.Counters{BytesRcvd: 10001, BytesSent: 100}, true},
+		{"ratio(bytes_in, bytes_out) > 100", types.Counters{BytesRcvd: 10000, BytesSent: 100}, false},
+		{"ratio(bytes_in, bytes_out) > 100", types.Counters{BytesRcvd: 1}, true},
+		{"ratio(bytes_in, bytes_out) > 100", types.Counters{}, false},
+		{"ratio(bytes_in, bytes_out) < 0.01", types.Counters{BytesSent: 1}, true},
+		{"packets_out = 0", types.Counters{PacketsRcvd: 5}, true},
+		{"packets_out = 0", types.Counters{PacketsRcvd: 5, PacketsSent: 1}, false},
+		{"packets_in = 0 | packets_out = 0", types.Counters{PacketsSent: 1}, true},
+		{"packets >= 3 & bytes < 100", types.Counters{PacketsRcvd: 1, PacketsSent: 2, BytesRcvd: 99}, true},
+		{"packets >= 3 & bytes < 100", types.Counters{PacketsRcvd: 1, PacketsSent: 2, BytesRcvd: 99, BytesSent: 1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.predicate, func(t *testing.T) {
+			p, err := ParsePredicate(test.predicate)
+			require.Nil(t, err)
+			require.Equal(t, test.matches, p.Match(test.counters), test.counters.String())
+		})
+	}
+}
+
+func TestRowsFilter(t *testing.T) {
+	rows := Rows{
+		{Counters: types.Counters{PacketsRcvd: 1, BytesRcvd: 100}},
+		{Counters: types.Counters{PacketsRcvd: 1, PacketsSent: 1, BytesRcvd: 100, BytesSent: 100}},
+		{Counters: types.Counters{PacketsSent: 2, BytesSent: 50}},
+	}
+
+	p, err := ParsePredicate("packets_in = 0 | packets_out = 0")
+	require.Nil(t, err)
+
+	filtered, totals := rows.Filter(p)
+	require.Equal(t, Rows{
+		{Counters: types.Counters{PacketsRcvd: 1, BytesRcvd: 100}},
+		{Counters: types.Counters{PacketsSent: 2, BytesSent: 50}},
+	}, filtered)
+	require.Equal(t, types.Counters{PacketsRcvd: 1, PacketsSent: 2, BytesRcvd: 100, BytesSent: 50}, totals)
+}
@@ -33,6 +33,10 @@ type Result struct {
 	Query   Query   `json:"query"`   // Query: the kind of query that was run
 	Rows    Rows    `json:"rows"`    // Rows: the data rows returned
 
+	Plan *Plan `json:"plan,omitempty"` // Plan: the execution plan of the query (only set if the query was explained instead of run)
+
+	Hostnames map[string]string `json:"hostnames,omitempty"` // Hostnames: the hostnames of the IP addresses in the rows as resolved at capture time (if recorded). Example: {"10.0.0.1": "db.example.com"}
+
 	// err is the error encountered when fetching result
 	err error `json:"-"`
 }
@@ -60,6 +64,30 @@ func (r *Result) Err() error {
 	return r.err
 }
 
+// AddHostnames assigns the hostnames of the IP addresses contained in the rows of the result, as
+// provided by lookup
+func (r *Result) AddHostnames(lookup func(ip netip.Addr) (string, bool)) {
+	add := func(ip netip.Addr) {
+		if !ip.IsValid() {
+			return
+		}
+		key := ip.String()
+		if _, exists := r.Hostnames[key]; exists {
+			return
+		}
+		if name, exists := lookup(ip); exists {
+			if r.Hostnames == nil {
+				r.Hostnames = make(map[string]string)
+			}
+			r.Hostnames[key] = name
+		}
+	}
+	for _, row := range r.Rows {
+		add(row.Attributes.SrcIP)
+		add(row.Attributes.DstIP)
+	}
+}
+
 // Query stores the kind of query that was run
 type Query struct {
 	Attributes []string `json:"attributes"`          // Attributes: the attributes that were queried. Example: [sip dip dport proto]
@@ -79,10 +107,53 @@ type TimeRange struct {
 type Summary struct {
 	Interfaces []string `json:"interfaces"` // Interfaces: the interfaces that were queried
 	TimeRange
-	Totals        types.Counters `json:"totals"`         // Totals: the total traffic volume and packets observed over the queried range
-	Timings       Timings        `json:"timings"`        // Timings: query runtime fields
-	Hits          Hits           `json:"hits"`           // Hits: how many flow records were returned in total and how many are returned in Rows
-	DataAvailable bool           `json:"data_available"` // DataAvailable: Was there any data available on disk or from a live query at all
+	Totals        types.Counters `json:"totals"`                   // Totals: the total traffic volume and packets observed over the queried range
+	Timings       Timings        `json:"timings"`                  // Timings: query runtime fields
+	Hits          Hits           `json:"hits"`                     // Hits: how many flow records were returned in total and how many are returned in Rows
+	DataAvailable bool           `json:"data_available"`           // DataAvailable: Was there any data available on disk or from a live query at all
+	CorruptBlocks uint64         `json:"corrupt_blocks,omitempty"` // CorruptBlocks: the number of blocks skipped because they failed checksum validation
+	MirroredRows  int            `json:"mirrored_rows,omitempty"`  // MirroredRows: the number of rows observed by multiple hosts in inverse directions (merged or flagged, depending on the dedup mode)
+	Saturated     bool           `json:"saturated,omitempty"`      // Saturated: at least one counter (of a row or the totals) reached its maximum value during aggregation and stopped increasing instead of wrapping around
+	Cache         *CacheStats    `json:"cache,omitempty"`          // Cache: the use of cached per-host sub-results (only present for distributed queries with caching enabled)
+	Sample        *SampleSummary `json:"sample,omitempty"`         // Sample: describes the random sample formed by the rows and the traffic extrapolated from it (only present if sampling was requested)
+
+	// Links: the properties of the link backing each queried interface at the end of the covered time range (only present if recorded)
+	Links map[string]types.LinkInfo `json:"links,omitempty"`
+
+	// Idle: the periods within the covered time range during which each queried interface was captured without observing any traffic (only present if recorded)
+	Idle map[string][]TimeRange `json:"idle,omitempty"`
+
+	// Cut: the boundary between the data read from the DB and the live data held in memory (only present for live queries)
+	Cut *Cut `json:"cut,omitempty"`
+
+	// Alignment: the handling of the requested time range with respect to the rotation boundaries (only present if a time alignment was requested)
+	Alignment *Alignment `json:"alignment,omitempty"`
+}
+
+// Alignment describes how a requested time range not coinciding with the rotation boundaries (i.e. cutting
+// through blocks) was handled. In snap mode, the covered time range of the summary denotes the effective
+// range enclosing the requested one. In interpolate mode, it denotes the requested range (limited to the
+// available data) and the traffic of blocks cut by it is scaled proportionally
+type Alignment struct {
+	Mode               string    `json:"mode"`                          // Mode: the time alignment mode. Enum: [snap, interpolate]. Example: snap
+	Requested          TimeRange `json:"requested"`                     // Requested: the time range as requested by the query
+	InterpolatedBlocks uint64    `json:"interpolated_blocks,omitempty"` // InterpolatedBlocks: the number of blocks whose traffic was scaled since they only partially overlapped the requested range. Example: 2
+}
+
+// Cut describes the consistent boundary between the data read from the DB and the live data held in
+// memory by the capture, as used by a live query. All blocks up to (and including) the rotation marking
+// the cut are read from the DB while any later traffic stems from memory, so a rotation completing while
+// the query runs is neither missed nor counted twice
+type Cut struct {
+	Generation uint64    `json:"generation"` // Generation: the number of rotations completed by the capture at the time of the cut. Example: 288
+	Timestamp  time.Time `json:"timestamp"`  // Timestamp: the timestamp of the rotation marking the cut (or the capture start if no rotation was completed yet)
+}
+
+// CacheStats summarizes the use of cached per-host sub-results in a distributed query
+type CacheStats struct {
+	Hits     int                  `json:"hits"`                // Hits: the number of hosts whose sub-result was served from the cache. Example: 41
+	Misses   int                  `json:"misses"`              // Misses: the number of hosts that were queried. Example: 1
+	CachedAt map[string]time.Time `json:"cached_at,omitempty"` // CachedAt: the time at which the sub-result of each host served from the cache was obtained
 }
 
 // Status denotes the overall status of the result
@@ -96,6 +167,15 @@ type Timings struct {
 	QueryStart         time.Time     `json:"query_start"`          // QueryStart: the time when the query started
 	QueryDuration      time.Duration `json:"query_duration_ns"`    // QueryDuration: the time it took to run the query in nanoseconds
 	ResolutionDuration time.Duration `json:"resolution,omitempty"` // ResolutionDuration: the time it took to resolve all IPs in nanoseconds
+	Spill              *SpillStats   `json:"spill,omitempty"`      // Spill: statistics about partial aggregates spilled to disk (only present if the aggregation memory budget was exceeded)
+}
+
+// SpillStats summarizes the spilling of partial aggregates to disk during query processing
+type SpillStats struct {
+	Spills   int           `json:"spills"`      // Spills: how many times partial aggregates were written to disk. Example: 3
+	Entries  uint64        `json:"entries"`     // Entries: the number of (partially aggregated) flow entries written to disk. Example: 2500000
+	Bytes    uint64        `json:"bytes"`       // Bytes: the (compressed) size of all spill files in bytes. Example: 41943040
+	Duration time.Duration `json:"duration_ns"` // Duration: the time spent writing and merging spill files in nanoseconds. Example: 1203890000
 }
 
 // Hits stores how many flow records were returned in total and how many are
@@ -115,6 +195,26 @@ type Row struct {
 
 	// Counters for bytes/packets
 	Counters types.Counters `json:"counters"`
+
+	// Mirrored flags rows containing traffic observed by multiple hosts in inverse directions
+	// (only set for distributed queries using the "flag" dedup mode)
+	Mirrored bool `json:"mirrored,omitempty"`
+
+	// FlowHash is the canonical hash of the flow key in hexadecimal notation (only set if requested
+	// via the "flow_hash" query argument, see Attributes.Hash()). Example: "5f2a9c0d3b7e4a11"
+	FlowHash string `json:"flow_hash,omitempty"`
+
+	// CommunityID is the Community ID of the flow (only set if requested via the "community_id" query
+	// argument, see Attributes.CommunityID()). Example: "1:X0snYXpgwiv9TZtqg64sgzUn6Dk="
+	CommunityID string `json:"community_id,omitempty"`
+
+	// Roles stores the traffic of the host (stored as SrcIP) by the role it assumes in the respective
+	// flows (only set if requested via the "roles" query argument, see Rows.JoinRoles())
+	Roles *RoleCounters `json:"roles,omitempty"`
+
+	// Stats summarizes the data volume of the row per time bin over the queried time range (only set if
+	// requested via the "stats" query argument, see Rows.BinStats())
+	Stats *BinStats `json:"stats,omitempty"`
 }
 
 // Labels hold labels by which the goDB database is partitioned
@@ -127,10 +227,13 @@ type Labels struct {
 
 // Attributes are traffic attributes by which the goDB can be aggregated
 type Attributes struct {
-	SrcIP   netip.Addr `json:"sip,omitempty"`   // SrcIP: the source IP address
-	DstIP   netip.Addr `json:"dip,omitempty"`   // DstIP: the destination IP address
-	IPProto uint8      `json:"proto,omitempty"` // IPProto: the IP protocol number
-	DstPort uint16     `json:"dport,omitempty"` // DstPort: the destination port
+	SrcIP   netip.Addr `json:"sip,omitempty"`     // SrcIP: the source IP address
+	DstIP   netip.Addr `json:"dip,omitempty"`     // DstIP: the destination IP address
+	IPProto uint8      `json:"proto,omitempty"`   // IPProto: the IP protocol number
+	DstPort uint16     `json:"dport,omitempty"`   // DstPort: the destination port
+	Tag     string     `json:"tag,omitempty"`     // Tag: the tag assigned to the flow at capture time. Example: "voip"
+	TTLMin  uint8      `json:"ttl_min,omitempty"` // TTLMin: the minimum TTL / hop limit observed for the flow (if recorded). Example: 58
+	TTLMax  uint8      `json:"ttl_max,omitempty"` // TTLMax: the maximum TTL / hop limit observed for the flow (if recorded). Example: 64
 }
 
 // New instantiates a new result
@@ -153,10 +256,32 @@ func (r *Result) Start() {
 	r.HostsStatuses = make(HostsStatuses)
 }
 
+// In converts all timestamps of the result to the given time zone. The instants in time remain
+// unchanged, only their representation (e.g. the offset when serializing to JSON) is affected
+func (r *Result) In(loc *time.Location) {
+	r.Summary.First = r.Summary.First.In(loc)
+	r.Summary.Last = r.Summary.Last.In(loc)
+	r.Summary.Timings.QueryStart = r.Summary.Timings.QueryStart.In(loc)
+	if r.Summary.Alignment != nil {
+		r.Summary.Alignment.Requested.First = r.Summary.Alignment.Requested.First.In(loc)
+		r.Summary.Alignment.Requested.Last = r.Summary.Alignment.Requested.Last.In(loc)
+	}
+	if r.Summary.Cache != nil {
+		for host, cachedAt := range r.Summary.Cache.CachedAt {
+			r.Summary.Cache.CachedAt[host] = cachedAt.In(loc)
+		}
+	}
+	for i := range r.Rows {
+		if !r.Rows[i].Labels.Timestamp.IsZero() {
+			r.Rows[i].Labels.Timestamp = r.Rows[i].Labels.Timestamp.In(loc)
+		}
+	}
+}
+
 // End prepares the end of the result
 func (r *Result) End() {
 	r.Summary.Timings.QueryDuration = time.Since(r.Summary.Timings.QueryStart)
-	if len(r.Rows) == 0 {
+	if len(r.Rows) == 0 && r.Plan == nil {
 		if r.Summary.DataAvailable {
 			r.Status = Status{
 				Code:    types.StatusEmpty,
@@ -230,12 +355,33 @@ func (r *Row) String() string {
 	return fmt.Sprintf("%s; %s; %s", r.Labels.String(), r.Attributes.String(), r.Counters.String())
 }
 
-// Less returns wether the row r sorts before r2
+// Less returns wether the row r sorts before r2. Together with Labels.Less() and Attributes.Less()
+// it defines a total order on all rows: Rows with identical attributes and labels are ordered by
+// their counters
 func (r *Row) Less(r2 *Row) bool {
-	if r.Attributes == r2.Attributes {
-		return r.Labels.Less(r2.Labels)
+	if r.Attributes != r2.Attributes {
+		return r.Attributes.Less(r2.Attributes)
+	}
+	if r.Labels.Less(r2.Labels) {
+		return true
+	}
+	if r2.Labels.Less(r.Labels) {
+		return false
+	}
+	return lessCounters(r.Counters, r2.Counters)
+}
+
+func lessCounters(c, c2 types.Counters) bool {
+	if c.BytesRcvd != c2.BytesRcvd {
+		return c.BytesRcvd < c2.BytesRcvd
+	}
+	if c.BytesSent != c2.BytesSent {
+		return c.BytesSent < c2.BytesSent
 	}
-	return r.Attributes.Less(r2.Attributes)
+	if c.PacketsRcvd != c2.PacketsRcvd {
+		return c.PacketsRcvd < c2.PacketsRcvd
+	}
+	return c.PacketsSent < c2.PacketsSent
 }
 
 // ExtendedRow is a human-readable, aggregatable representation of goProbe's active
@@ -285,17 +431,20 @@ func (l Labels) String() string {
 
 // Less returns wether the set of labels l sorts before l2
 func (l Labels) Less(l2 Labels) bool {
-	if l.Timestamp != l2.Timestamp {
+	if !l.Timestamp.Equal(l2.Timestamp) {
 		return l.Timestamp.Before(l2.Timestamp)
 	}
 
-	// Since sorting is about human-readable information this ignores the hostID, assuming
-	// that for sorting identical hostnames imply the same host
+	// Since sorting is about human-readable information the hostname takes precedence over
+	// the hostID, which is only used to break ties (e.g. if two hosts share the same hostname)
 	if l.Hostname != l2.Hostname {
 		return l.Hostname < l2.Hostname
 	}
+	if l.Iface != l2.Iface {
+		return l.Iface < l2.Iface
+	}
 
-	return l.Iface < l2.Iface
+	return l.HostID < l2.HostID
 }
 
 // ExtendedAttributes includes the source port. It is meant to be used if (and only if)
@@ -314,9 +463,15 @@ func (a Attributes) MarshalJSON() ([]byte, error) {
 		DstIP   *netip.Addr `json:"dip,omitempty"`
 		IPProto uint8       `json:"proto,omitempty"`
 		DstPort uint16      `json:"dport,omitempty"`
+		Tag     string      `json:"tag,omitempty"`
+		TTLMin  uint8       `json:"ttl_min,omitempty"`
+		TTLMax  uint8       `json:"ttl_max,omitempty"`
 	}{
 		IPProto: a.IPProto,
 		DstPort: a.DstPort,
+		Tag:     a.Tag,
+		TTLMin:  a.TTLMin,
+		TTLMax:  a.TTLMax,
 	}
 	if a.SrcIP.IsValid() {
 		aux.SrcIP = &a.SrcIP
@@ -348,12 +503,31 @@ func (a Attributes) Less(a2 Attributes) bool {
 	if a.IPProto != a2.IPProto {
 		return a.IPProto < a2.IPProto
 	}
-	return a.DstPort < a2.DstPort
+	if a.DstPort != a2.DstPort {
+		return a.DstPort < a2.DstPort
+	}
+	if a.Tag != a2.Tag {
+		return a.Tag < a2.Tag
+	}
+	if a.TTLMin != a2.TTLMin {
+		return a.TTLMin < a2.TTLMin
+	}
+	return a.TTLMax < a2.TTLMax
 }
 
 // Rows is a list of results
 type Rows []Row
 
+// Saturated returns if the counters of any row are saturated (see types.Counters.IsSaturated)
+func (r Rows) Saturated() bool {
+	for _, row := range r {
+		if row.Counters.IsSaturated() {
+			return true
+		}
+	}
+	return false
+}
+
 // MergeableAttributes bundles all fields of a Result by which aggregation/merging is possible
 type MergeableAttributes struct {
 	Labels
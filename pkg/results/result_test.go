@@ -3,10 +3,12 @@ package results
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/els0r/goProbe/pkg/types"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMerge(t *testing.T) {
@@ -32,3 +34,27 @@ func TestMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestResultIn(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.Nil(t, err)
+
+	ts := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	result := &Result{
+		Summary: Summary{TimeRange: TimeRange{First: ts, Last: ts.Add(time.Hour)}},
+		Rows:    Rows{{Labels: Labels{Timestamp: ts}}, {Labels: Labels{Iface: "eth0"}}},
+	}
+	result.In(zurich)
+
+	require.True(t, result.Summary.First.Equal(ts))
+	require.True(t, result.Rows[0].Labels.Timestamp.Equal(ts))
+	require.True(t, result.Rows[1].Labels.Timestamp.IsZero())
+
+	b, err := jsoniter.Marshal(result.Rows[0].Labels)
+	require.Nil(t, err)
+	require.Contains(t, string(b), `"timestamp":"2024-07-15T14:00:00+02:00"`)
+
+	b, err = jsoniter.Marshal(result.Rows[1].Labels)
+	require.Nil(t, err)
+	require.NotContains(t, string(b), "timestamp")
+}
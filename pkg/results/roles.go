@@ -0,0 +1,88 @@
+package results
+
+import (
+	"net/netip"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// HostName denotes the name of the host output column (replacing sip / dip if the traffic
+// of each host is joined by role)
+const HostName = "host"
+
+// RoleCounters stores the traffic of a host by the role it assumes in the respective flows
+type RoleCounters struct {
+	Source      types.Counters `json:"source"`      // Source: the traffic of all flows in which the host is the source (sip), i.e. acts as client
+	Destination types.Counters `json:"destination"` // Destination: the traffic of all flows in which the host is the destination (dip), i.e. acts as server
+}
+
+// JoinRoles correlates the traffic of each host as source (sip) and as destination (dip), merging
+// the rows into a single one per host (and all other attributes / labels). The host is stored as
+// source IP of the resulting rows, the traffic by role in Roles and the total of both in Counters.
+// Rows must contain both the sip and dip attributes
+func (r Rows) JoinRoles() Rows {
+	joined := make(RolesMap, len(r))
+	add := func(row Row, host netip.Addr, isSource bool) {
+		key := MergeableAttributes{Labels: row.Labels, Attributes: row.Attributes}
+		key.SrcIP, key.DstIP = host, netip.Addr{}
+
+		roles := joined[key]
+		if isSource {
+			roles.Source = roles.Source.Add(row.Counters)
+		} else {
+			roles.Destination = roles.Destination.Add(row.Counters)
+		}
+		joined[key] = roles
+	}
+	for _, row := range r {
+		add(row, row.Attributes.SrcIP, true)
+		add(row, row.Attributes.DstIP, false)
+	}
+
+	res := make(Rows, 0, len(joined))
+	for key, roles := range joined {
+		roles := roles
+		res = append(res, Row{
+			Labels:     key.Labels,
+			Attributes: key.Attributes,
+			Counters:   roles.Source.Add(roles.Destination),
+			Roles:      &roles,
+		})
+	}
+	return res
+}
+
+// RolesMap is an aggregated representation of the traffic by role of a Rows list, allowing
+// to retain it when merging rows via a RowsMap (which only covers the total counters)
+type RolesMap map[MergeableAttributes]RoleCounters
+
+// MergeRows aggregates the traffic by role of all rows (if present), modifying rm in the process
+func (rm RolesMap) MergeRows(r Rows) {
+	for _, row := range r {
+		if row.Roles == nil {
+			continue
+		}
+		key := MergeableAttributes{row.Labels, row.Attributes}
+		roles := rm[key]
+		roles.Source = roles.Source.Add(row.Roles.Source)
+		roles.Destination = roles.Destination.Add(row.Roles.Destination)
+		rm[key] = roles
+	}
+}
+
+// Assign sets the traffic by role of all rows contained in rm
+func (rm RolesMap) Assign(r Rows) {
+	for i, row := range r {
+		if roles, exists := rm[MergeableAttributes{row.Labels, row.Attributes}]; exists {
+			r[i].Roles = &roles
+		}
+	}
+}
+
+// WithRoles prints the traffic of each host by role (see Rows.JoinRoles()) instead of the
+// sip / dip attributes and the traffic by direction
+func WithRoles() PrinterOption {
+	return func(b *basePrinter) {
+		b.roles = true
+	}
+}
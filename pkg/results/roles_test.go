@@ -0,0 +1,75 @@
+package results
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinRoles(t *testing.T) {
+	var (
+		client  = netip.MustParseAddr("10.0.0.1")
+		server  = netip.MustParseAddr("10.0.0.2")
+		server2 = netip.MustParseAddr("2001:db8::1")
+	)
+
+	rows := Rows{
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: client, DstIP: server, IPProto: 6}, Counters: types.Counters{BytesRcvd: 1000, BytesSent: 100, PacketsRcvd: 10, PacketsSent: 5}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: client, DstIP: server2, IPProto: 6}, Counters: types.Counters{BytesRcvd: 2000, PacketsRcvd: 20}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: server, DstIP: server2, IPProto: 6}, Counters: types.Counters{BytesSent: 300, PacketsSent: 3}},
+
+		// different protocol, hence a separate row for each host
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: client, DstIP: server, IPProto: 17}, Counters: types.Counters{BytesSent: 50, PacketsSent: 1}},
+	}
+
+	joined := rows.JoinRoles()
+	By(SortTraffic, types.DirectionSum, false).Sort(joined)
+
+	// ties are broken by the host (descending order)
+	expected := Rows{
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: client, IPProto: 6},
+			Counters: types.Counters{BytesRcvd: 3000, BytesSent: 100, PacketsRcvd: 30, PacketsSent: 5},
+			Roles: &RoleCounters{
+				Source: types.Counters{BytesRcvd: 3000, BytesSent: 100, PacketsRcvd: 30, PacketsSent: 5},
+			}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: server2, IPProto: 6},
+			Counters: types.Counters{BytesRcvd: 2000, BytesSent: 300, PacketsRcvd: 20, PacketsSent: 3},
+			Roles: &RoleCounters{
+				Destination: types.Counters{BytesRcvd: 2000, BytesSent: 300, PacketsRcvd: 20, PacketsSent: 3},
+			}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: server, IPProto: 6},
+			Counters: types.Counters{BytesRcvd: 1000, BytesSent: 400, PacketsRcvd: 10, PacketsSent: 8},
+			Roles: &RoleCounters{
+				Source:      types.Counters{BytesSent: 300, PacketsSent: 3},
+				Destination: types.Counters{BytesRcvd: 1000, BytesSent: 100, PacketsRcvd: 10, PacketsSent: 5},
+			}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: server, IPProto: 17},
+			Counters: types.Counters{BytesSent: 50, PacketsSent: 1},
+			Roles: &RoleCounters{
+				Destination: types.Counters{BytesSent: 50, PacketsSent: 1},
+			}},
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: client, IPProto: 17},
+			Counters: types.Counters{BytesSent: 50, PacketsSent: 1},
+			Roles: &RoleCounters{
+				Source: types.Counters{BytesSent: 50, PacketsSent: 1},
+			}},
+	}
+	require.Equal(t, expected, joined)
+
+	// the traffic by role is retained when merging the rows of several hosts
+	rowsMap, rolesMap := make(RowsMap), make(RolesMap)
+	for i := 0; i < 2; i++ {
+		rowsMap.MergeRows(joined)
+		rolesMap.MergeRows(joined)
+	}
+	merged := rowsMap.ToRowsSorted(By(SortTraffic, types.DirectionSum, false))
+	rolesMap.Assign(merged)
+	require.Len(t, merged, len(expected))
+	for i, row := range merged {
+		require.Equal(t, expected[i].Counters.Add(expected[i].Counters), row.Counters)
+		require.Equal(t, expected[i].Roles.Source.Add(expected[i].Roles.Source), row.Roles.Source)
+		require.Equal(t, expected[i].Roles.Destination.Add(expected[i].Roles.Destination), row.Roles.Destination)
+	}
+}
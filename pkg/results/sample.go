@@ -0,0 +1,109 @@
+package results
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/els0r/goProbe/pkg/types"
+)
+
+// SampleSummary describes a uniform random sample of the rows matching a query and the traffic of
+// all matching rows extrapolated from it (see "sample" query argument)
+type SampleSummary struct {
+	Size         int            `json:"size"`          // Size: the number of rows in the sample. Example: 100
+	Population   int            `json:"population"`    // Population: the number of rows the sample was drawn from. Example: 2500000
+	Mean         types.Counters `json:"mean"`          // Mean: the mean traffic of a row in the sample
+	Extrapolated types.Counters `json:"extrapolated"`  // Extrapolated: the traffic of all rows, extrapolated from the sample
+	BytesRelErr  float64        `json:"bytes_rel_err"` // BytesRelErr: the relative standard error of the extrapolated number of bytes (both directions). Example: 0.042
+}
+
+// Sample draws a uniform random sample of (up to) n rows without replacement and summarizes it.
+// Contrary to sorting, the sample is drawn in linear time. The rows are reordered in the process,
+// the sample is returned as a sub-slice of r
+func (r Rows) Sample(n int, rng *rand.Rand) (Rows, *SampleSummary) {
+	if n > len(r) {
+		n = len(r)
+	}
+
+	// partial Fisher-Yates shuffle, moving the sampled rows to the front
+	for i := 0; i < n; i++ {
+		j := i + rng.Intn(len(r)-i)
+		r[i], r[j] = r[j], r[i]
+	}
+	sample := r[:n]
+
+	return sample, sample.summarize(len(r))
+}
+
+// summarize extrapolates the traffic of a population of the given size from the sample
+func (r Rows) summarize(population int) *SampleSummary {
+	s := &SampleSummary{
+		Size:       len(r),
+		Population: population,
+	}
+	if len(r) == 0 {
+		return s
+	}
+
+	var sum types.Counters
+	for _, row := range r {
+		sum = sum.Add(row.Counters)
+	}
+	s.Mean = scaleCounters(sum, 1/float64(len(r)))
+	s.Extrapolated = scaleCounters(sum, float64(population)/float64(len(r)))
+
+	// standard error of the extrapolated total (including the finite population correction)
+	if len(r) > 1 && population > 1 && sum.SumBytes() > 0 {
+		mean := float64(sum.SumBytes()) / float64(len(r))
+		var sqDiff float64
+		for _, row := range r {
+			d := float64(row.Counters.SumBytes()) - mean
+			sqDiff += d * d
+		}
+		variance := sqDiff / float64(len(r)-1)
+		fpc := float64(population-len(r)) / float64(population-1)
+		stdErr := float64(population) * math.Sqrt(variance/float64(len(r))*fpc)
+		s.BytesRelErr = stdErr / (mean * float64(population))
+	}
+
+	return s
+}
+
+// Add merges the sample summary of another (disjoint) population, e.g. from another host, treating
+// both samples as strata of a stratified sample
+func (s *SampleSummary) Add(s2 *SampleSummary) *SampleSummary {
+	if s2 == nil {
+		return s
+	}
+	if s == nil {
+		s2 := *s2
+		return &s2
+	}
+
+	// the standard errors of the strata are combined in absolute terms
+	stdErr := math.Hypot(
+		s.BytesRelErr*float64(s.Extrapolated.SumBytes()),
+		s2.BytesRelErr*float64(s2.Extrapolated.SumBytes()),
+	)
+
+	s.Size += s2.Size
+	s.Population += s2.Population
+	s.Extrapolated = s.Extrapolated.Add(s2.Extrapolated)
+	s.Mean, s.BytesRelErr = types.Counters{}, 0
+	if s.Population > 0 {
+		s.Mean = scaleCounters(s.Extrapolated, 1/float64(s.Population))
+	}
+	if total := s.Extrapolated.SumBytes(); total > 0 {
+		s.BytesRelErr = stdErr / float64(total)
+	}
+	return s
+}
+
+func scaleCounters(c types.Counters, factor float64) types.Counters {
+	return types.Counters{
+		BytesRcvd:   uint64(math.Round(float64(c.BytesRcvd) * factor)),
+		BytesSent:   uint64(math.Round(float64(c.BytesSent) * factor)),
+		PacketsRcvd: uint64(math.Round(float64(c.PacketsRcvd) * factor)),
+		PacketsSent: uint64(math.Round(float64(c.PacketsSent) * factor)),
+	}
+}
@@ -0,0 +1,67 @@
+package results
+
+import (
+	"math"
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	rows := make(Rows, 1000)
+	for i := range rows {
+		rows[i] = Row{
+			Attributes: Attributes{SrcIP: netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})},
+			Counters:   types.Counters{BytesRcvd: 100, BytesSent: 50, PacketsRcvd: 2, PacketsSent: 1},
+		}
+	}
+
+	sample, summary := rows.Sample(10, rand.New(rand.NewSource(1)))
+	require.Len(t, sample, 10)
+
+	seen := make(map[netip.Addr]struct{})
+	for _, row := range sample {
+		seen[row.Attributes.SrcIP] = struct{}{}
+	}
+	require.Len(t, seen, 10, "rows must be sampled without replacement")
+
+	// all rows carry the same traffic, hence the extrapolation is exact
+	require.Equal(t, &SampleSummary{
+		Size:         10,
+		Population:   1000,
+		Mean:         types.Counters{BytesRcvd: 100, BytesSent: 50, PacketsRcvd: 2, PacketsSent: 1},
+		Extrapolated: types.Counters{BytesRcvd: 100000, BytesSent: 50000, PacketsRcvd: 2000, PacketsSent: 1000},
+	}, summary)
+
+	// a sample exceeding the number of rows covers all of them
+	sample, summary = rows[:5].Sample(10, rand.New(rand.NewSource(1)))
+	require.Len(t, sample, 5)
+	require.Equal(t, 5, summary.Population)
+	require.Equal(t, uint64(750), summary.Extrapolated.SumBytes())
+	require.Zero(t, summary.BytesRelErr)
+}
+
+func TestSampleSummaryAdd(t *testing.T) {
+	var s *SampleSummary
+	s = s.Add(&SampleSummary{
+		Size:         10,
+		Population:   100,
+		Extrapolated: types.Counters{BytesRcvd: 3000, BytesSent: 1000},
+		BytesRelErr:  0.3,
+	})
+	s = s.Add(&SampleSummary{
+		Size:         10,
+		Population:   300,
+		Extrapolated: types.Counters{BytesRcvd: 2000},
+		BytesRelErr:  0.6,
+	})
+
+	require.Equal(t, 20, s.Size)
+	require.Equal(t, 400, s.Population)
+	require.Equal(t, types.Counters{BytesRcvd: 5000, BytesSent: 1000}, s.Extrapolated)
+	require.Equal(t, types.Counters{BytesRcvd: 13, BytesSent: 3}, s.Mean)
+	require.InDelta(t, 0.2*math.Sqrt2, s.BytesRelErr, 1e-9)
+}
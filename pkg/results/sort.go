@@ -91,7 +91,10 @@ func (s *entrySorter) Less(i, j int) bool {
 	return s.less(&s.entries[i], &s.entries[j])
 }
 
-// By is part of the sort.Interface
+// By is part of the sort.Interface. Ties of the requested sort criterion are broken by Row.Less(),
+// which defines a total order on all rows. Hence, the sorted output does not depend on the order
+// of the input rows (e.g. as a result of randomized map iteration), which in turn guarantees that
+// identical queries yield identical outputs
 func By(sort SortOrder, direction types.Direction, ascending bool) by {
 	switch sort {
 	case SortPackets:
@@ -0,0 +1,63 @@
+package results
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortTotalOrder(t *testing.T) {
+	ts := time.Unix(1704110400, 0)
+	counters := types.Counters{BytesRcvd: 100, BytesSent: 100, PacketsRcvd: 1, PacketsSent: 1}
+
+	// all rows share the same counter totals, forcing the tie-breaks to be evaluated
+	rows := Rows{
+		{Labels: Labels{Timestamp: ts, Iface: "eth0", Hostname: "host", HostID: "a"}, Counters: counters},
+		{Labels: Labels{Timestamp: ts, Iface: "eth0", Hostname: "host", HostID: "b"}, Counters: counters},
+		{Labels: Labels{Timestamp: ts.UTC(), Iface: "eth1", Hostname: "host", HostID: "a"}, Counters: counters},
+		{Labels: Labels{Timestamp: ts.Add(time.Minute), Iface: "eth0", Hostname: "host", HostID: "a"}, Counters: counters},
+		{Labels: Labels{Timestamp: ts, Iface: "eth0", Hostname: "host2", HostID: "a"}, Counters: counters},
+		{Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), IPProto: 6}, Counters: counters},
+		{Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), IPProto: 17}, Counters: counters},
+		{Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstPort: 80}, Counters: counters},
+		{Attributes: Attributes{DstIP: netip.MustParseAddr("2001:db8::1")}, Counters: counters},
+		{Counters: types.Counters{BytesRcvd: 150, BytesSent: 50, PacketsRcvd: 1, PacketsSent: 1}},
+		{Counters: types.Counters{BytesRcvd: 50, BytesSent: 150, PacketsRcvd: 1, PacketsSent: 1}},
+		{Counters: types.Counters{BytesRcvd: 100, BytesSent: 100, PacketsRcvd: 2}},
+	}
+
+	for _, sortBy := range []SortOrder{SortPackets, SortTraffic, SortTime} {
+		for _, direction := range []types.Direction{types.DirectionBoth, types.DirectionSum, types.DirectionIn, types.DirectionOut} {
+			for _, ascending := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s_%s_%t", sortBy, direction, ascending), func(t *testing.T) {
+					order := By(sortBy, direction, ascending)
+
+					expected := make(Rows, len(rows))
+					copy(expected, rows)
+					order.Sort(expected)
+
+					// no two distinct rows must compare as equal
+					for i := 1; i < len(expected); i++ {
+						require.True(t, order(&expected[i-1], &expected[i]), "rows %d / %d not strictly ordered", i-1, i)
+					}
+
+					rng := rand.New(rand.NewSource(1))
+					for i := 0; i < 100; i++ {
+						shuffled := make(Rows, len(rows))
+						copy(shuffled, rows)
+						rng.Shuffle(len(shuffled), func(i, j int) {
+							shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+						})
+						order.Sort(shuffled)
+						require.Equal(t, expected, shuffled)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package results
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// BinStats summarizes the data volume of a row per time bin (i.e. per DB write interval) over the
+// queried time range, e.g. to assess the peak / typical load of a flow for capacity planning
+type BinStats struct {
+	Bins   int     `json:"bins"`         // Bins: the number of time bins covered by the time range (including the ones without any traffic of the row). Example: 288
+	P50    uint64  `json:"p50_bytes"`    // P50: the median data volume per time bin in bytes. Example: 1048576
+	P95    uint64  `json:"p95_bytes"`    // P95: the 95th percentile of the data volume per time bin in bytes. Example: 8388608
+	Max    uint64  `json:"max_bytes"`    // Max: the maximum data volume per time bin in bytes. Example: 16777216
+	StdDev float64 `json:"stddev_bytes"` // StdDev: the (population) standard deviation of the data volume per time bin in bytes. Example: 2097152.5
+}
+
+// NewBinStats computes the statistics of the data volumes of the time bins in which traffic was
+// observed. Bins exceeding their number count as time bins without any traffic (contributing a
+// volume of zero). volumes is sorted in the process
+func NewBinStats(volumes []uint64, bins int) *BinStats {
+	if bins < len(volumes) {
+		bins = len(volumes)
+	}
+	stats := &BinStats{Bins: bins}
+	if bins == 0 {
+		return stats
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i] < volumes[j]
+	})
+
+	// the percentiles are determined via the nearest rank among all bins, the empty ones preceding
+	// the ones carrying traffic
+	nEmpty := bins - len(volumes)
+	percentile := func(p float64) uint64 {
+		rank := int(math.Ceil(p*float64(bins))) - 1
+		if rank < nEmpty {
+			return 0
+		}
+		return volumes[rank-nEmpty]
+	}
+	stats.P50, stats.P95 = percentile(0.5), percentile(0.95)
+	if len(volumes) > 0 {
+		stats.Max = volumes[len(volumes)-1]
+	}
+
+	var sum float64
+	for _, volume := range volumes {
+		sum += float64(volume)
+	}
+	mean := sum / float64(bins)
+	variance := float64(nEmpty) * mean * mean
+	for _, volume := range volumes {
+		variance += (float64(volume) - mean) * (float64(volume) - mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(bins))
+
+	return stats
+}
+
+// BinStats merges the rows across their timestamps (i.e. the time bins storing them), emitting a single
+// row per set of attributes and remaining labels which carries the statistics of its data volume per time
+// bin in Stats. The time range [first, last] is divided into bins of the given interval, all of which
+// are taken into account (regardless of whether the row carries any traffic in them)
+func (r Rows) BinStats(first, last time.Time, interval time.Duration) Rows {
+	type binned struct {
+		Row
+		volumes map[time.Time]uint64
+	}
+
+	grouped := make(map[MergeableAttributes]*binned, len(r))
+	for _, row := range r {
+		key := MergeableAttributes{Labels: row.Labels, Attributes: row.Attributes}
+		key.Timestamp = time.Time{}
+
+		group, exists := grouped[key]
+		if !exists {
+			group = &binned{
+				Row:     Row{Labels: key.Labels, Attributes: key.Attributes},
+				volumes: make(map[time.Time]uint64),
+			}
+			grouped[key] = group
+		}
+		group.Counters = group.Counters.Add(row.Counters)
+		group.Mirrored = group.Mirrored || row.Mirrored
+		group.volumes[row.Labels.Timestamp] += row.Counters.SumBytes()
+	}
+
+	var bins int
+	if interval > 0 && last.After(first) {
+		bins = int((last.Sub(first) + interval - 1) / interval)
+	}
+
+	res := make(Rows, 0, len(grouped))
+	for _, group := range grouped {
+		volumes := make([]uint64, 0, len(group.volumes))
+		for _, volume := range group.volumes {
+			volumes = append(volumes, volume)
+		}
+		group.Stats = NewBinStats(volumes, bins)
+		res = append(res, group.Row)
+	}
+	return res
+}
+
+// WithStats prints the statistics of the data volume per time bin of each row (see Rows.BinStats())
+// after its counters
+func WithStats() PrinterOption {
+	return func(b *basePrinter) {
+		b.stats = true
+	}
+}
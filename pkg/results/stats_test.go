@@ -0,0 +1,97 @@
+package results
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBinStats(t *testing.T) {
+	var tests = []struct {
+		name     string
+		volumes  []uint64
+		bins     int
+		expected BinStats
+	}{
+		{"no bins", nil, 0, BinStats{}},
+		{"single bin", []uint64{100}, 1, BinStats{Bins: 1, P50: 100, P95: 100, Max: 100}},
+		{"all bins", []uint64{400, 100, 300, 200}, 4, BinStats{Bins: 4, P50: 200, P95: 400, Max: 400, StdDev: 111.80339887498948}},
+		{"empty bins", []uint64{100, 100}, 4, BinStats{Bins: 4, P50: 0, P95: 100, Max: 100, StdDev: 50}},
+		{"bins exceeded", []uint64{100, 300}, 1, BinStats{Bins: 2, P50: 100, P95: 300, Max: 300, StdDev: 100}},
+		{"percentiles",
+			[]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 40,
+			BinStats{Bins: 40, P50: 0, P95: 18, Max: 20, StdDev: 6.6473679001541655},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, *NewBinStats(test.volumes, test.bins))
+		})
+	}
+}
+
+func TestRowsBinStats(t *testing.T) {
+	var (
+		host1 = netip.MustParseAddr("10.0.0.1")
+		host2 = netip.MustParseAddr("10.0.0.2")
+		first = time.Unix(1704067200, 0)
+		bin   = func(n int) time.Time { return first.Add(time.Duration(n) * 5 * time.Minute) }
+	)
+
+	rows := Rows{
+		{Labels: Labels{Timestamp: bin(1), Iface: "eth0"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesRcvd: 100, BytesSent: 100, PacketsRcvd: 1}},
+		{Labels: Labels{Timestamp: bin(2), Iface: "eth0"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesRcvd: 600, PacketsRcvd: 2}},
+		{Labels: Labels{Timestamp: bin(3), Iface: "eth0"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesSent: 300, PacketsSent: 3}},
+		{Labels: Labels{Timestamp: bin(4), Iface: "eth0"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesRcvd: 400, PacketsRcvd: 4}},
+
+		// different interface, hence a separate row
+		{Labels: Labels{Timestamp: bin(2), Iface: "eth1"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesRcvd: 1000, PacketsRcvd: 10}, Mirrored: true},
+
+		// rows of the same bin (e.g. of multiple hosts) are merged
+		{Labels: Labels{Timestamp: bin(2), Iface: "eth1"}, Attributes: Attributes{SrcIP: host1, DstIP: host2}, Counters: types.Counters{BytesRcvd: 1000, PacketsRcvd: 10}},
+	}
+
+	binned := rows.BinStats(first, bin(4), 5*time.Minute)
+	By(SortTraffic, types.DirectionSum, false).Sort(binned)
+
+	expected := Rows{
+		{Labels: Labels{Iface: "eth0"}, Attributes: Attributes{SrcIP: host1, DstIP: host2},
+			Counters: types.Counters{BytesRcvd: 1100, BytesSent: 400, PacketsRcvd: 7, PacketsSent: 3},
+			Stats:    &BinStats{Bins: 4, P50: 300, P95: 600, Max: 600, StdDev: 147.9019945774904},
+		},
+		{Labels: Labels{Iface: "eth1"}, Attributes: Attributes{SrcIP: host1, DstIP: host2},
+			Counters: types.Counters{BytesRcvd: 2000, PacketsRcvd: 20},
+			Mirrored: true,
+			Stats:    &BinStats{Bins: 4, P50: 0, P95: 2000, Max: 2000, StdDev: 866.0254037844386},
+		},
+	}
+	By(SortTraffic, types.DirectionSum, false).Sort(expected)
+	require.Equal(t, expected, binned)
+}
+
+func TestStatsColumns(t *testing.T) {
+	attributes, selector, err := types.ParseQueryType("sip")
+	require.Nil(t, err)
+
+	row := Row{
+		Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1")},
+		Counters:   types.Counters{BytesRcvd: 1000, PacketsRcvd: 10},
+		Stats:      &BinStats{Bins: 4, P50: 100, P95: 800, Max: 900, StdDev: 349.6},
+	}
+
+	buf := &bytes.Buffer{}
+	printer, err := NewTablePrinter(buf, "csv", SortTraffic, selector, types.DirectionSum, attributes, nil, row.Counters, 1, 0, "", "eth0", WithStats())
+	require.Nil(t, err)
+	require.Nil(t, printer.AddRow(row))
+	require.Nil(t, printer.Print(nil))
+
+	lines := strings.Split(buf.String(), "\n")
+	require.Equal(t, "sip,packets,%,data vol.,%,p50 data vol. per bin,p95 data vol. per bin,max data vol. per bin,stddev data vol. per bin", lines[0])
+	require.Equal(t, "10.0.0.1,10,100.00,1000,100.00,100,800,900,350", lines[1])
+}
@@ -0,0 +1,145 @@
+package results
+
+import (
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/els0r/goProbe/pkg/types/protocols"
+)
+
+// ResultV2 is the representation of a result in the v2 schema of the query API. In contrast to the
+// (default) v1 schema, the counters of each row are spelled out and extended by their totals and their
+// share of the traffic of the entire result, and the rows are labeled with the names of their protocol
+// and of their IP addresses (instead of providing the latter in a separate map)
+type ResultV2 struct {
+	Hostname string `json:"hostname,omitempty"` // Hostname: from which the result originated
+
+	Status        Status        `json:"status"`         // Status: the overall status of the result
+	HostsStatuses HostsStatuses `json:"hosts_statuses"` // HostsStatuses: the status of all hosts queried
+
+	Summary SummaryV2 `json:"summary"` // Summary: the total traffic volume and packets observed over the queried range and the interfaces that were queried
+	Query   Query     `json:"query"`   // Query: the kind of query that was run
+	Rows    []RowV2   `json:"rows"`    // Rows: the data rows returned
+
+	Plan *Plan `json:"plan,omitempty"` // Plan: the execution plan of the query (only set if the query was explained instead of run)
+}
+
+// SummaryV2 is the representation of the summary of a result in the v2 schema, carrying the totals
+// in the extended form of the counters
+type SummaryV2 struct {
+	Summary
+	Totals CountersV2 `json:"totals"` // Totals: the total traffic volume and packets observed over the queried range
+}
+
+// RowV2 is the representation of a row in the v2 schema
+type RowV2 struct {
+	Labels      LabelsV2      `json:"labels"`                 // Labels: the labels of the row, extended by the names of its protocol and IP addresses
+	Attributes  Attributes    `json:"attributes"`             // Attributes: the attributes the row was grouped by
+	Counters    CountersV2    `json:"counters"`               // Counters: the traffic of the row
+	Mirrored    bool          `json:"mirrored,omitempty"`     // Mirrored: flags rows containing traffic observed by multiple hosts in inverse directions
+	FlowHash    string        `json:"flow_hash,omitempty"`    // FlowHash: the canonical hash of the flow key (if requested)
+	CommunityID string        `json:"community_id,omitempty"` // CommunityID: the Community ID of the flow (if requested)
+	Roles       *RoleCounters `json:"roles,omitempty"`        // Roles: the traffic of the host by the role it assumes (if requested)
+	Stats       *BinStats     `json:"stats,omitempty"`        // Stats: the statistics of the data volume per time bin (if requested)
+}
+
+// LabelsV2 is the representation of the labels of a row in the v2 schema
+type LabelsV2 struct {
+	Timestamp *time.Time `json:"timestamp,omitempty"` // Timestamp: the timestamp of the 5-minute interval storing the flow record
+	Iface     string     `json:"iface,omitempty"`     // Iface: the interface on which the flow was observed
+	Hostname  string     `json:"hostname,omitempty"`  // Hostname: the hostname of the host on which the flow was observed
+	HostID    string     `json:"host_id,omitempty"`   // HostID: the host id of the host on which the flow was observed
+
+	Proto       string `json:"proto,omitempty"`        // Proto: the name of the IP protocol. Example: "TCP"
+	ICMP        string `json:"icmp,omitempty"`         // ICMP: the name of the ICMP message type / code (ICMP / ICMPv6 flows only). Example: "unreachable/port"
+	SrcHostname string `json:"sip_hostname,omitempty"` // SrcHostname: the hostname of the source IP address as resolved at capture time (if recorded). Example: "db.example.com"
+	DstHostname string `json:"dip_hostname,omitempty"` // DstHostname: the hostname of the destination IP address as resolved at capture time (if recorded). Example: "web.example.com"
+}
+
+// CountersV2 is the representation of the counters of a row in the v2 schema
+type CountersV2 struct {
+	BytesRcvd    uint64 `json:"bytes_rcvd"`    // BytesRcvd: bytes received. Example: 1024
+	BytesSent    uint64 `json:"bytes_sent"`    // BytesSent: bytes sent. Example: 2048
+	BytesTotal   uint64 `json:"bytes_total"`   // BytesTotal: bytes received and sent. Example: 3072
+	PacketsRcvd  uint64 `json:"packets_rcvd"`  // PacketsRcvd: packets received. Example: 8
+	PacketsSent  uint64 `json:"packets_sent"`  // PacketsSent: packets sent. Example: 16
+	PacketsTotal uint64 `json:"packets_total"` // PacketsTotal: packets received and sent. Example: 24
+
+	BytesShare   float64 `json:"bytes_share"`   // BytesShare: the share of the total data volume of the result (between 0 and 1). Example: 0.25
+	PacketsShare float64 `json:"packets_share"` // PacketsShare: the share of the total packets of the result (between 0 and 1). Example: 0.125
+}
+
+// NewCountersV2 converts counters to their v2 representation, relating them to the totals of the result
+func NewCountersV2(c, totals types.Counters) CountersV2 {
+	share := func(val, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(val) / float64(total)
+	}
+	return CountersV2{
+		BytesRcvd:    c.BytesRcvd,
+		BytesSent:    c.BytesSent,
+		BytesTotal:   c.SumBytes(),
+		PacketsRcvd:  c.PacketsRcvd,
+		PacketsSent:  c.PacketsSent,
+		PacketsTotal: c.SumPackets(),
+		BytesShare:   share(c.SumBytes(), totals.SumBytes()),
+		PacketsShare: share(c.SumPackets(), totals.SumPackets()),
+	}
+}
+
+// V2 converts the result to its representation in the v2 schema
+func (r *Result) V2() *ResultV2 {
+	res := &ResultV2{
+		Hostname:      r.Hostname,
+		Status:        r.Status,
+		HostsStatuses: r.HostsStatuses,
+		Summary: SummaryV2{
+			Summary: r.Summary,
+			Totals:  NewCountersV2(r.Summary.Totals, r.Summary.Totals),
+		},
+		Query: r.Query,
+		Rows:  make([]RowV2, 0, len(r.Rows)),
+		Plan:  r.Plan,
+	}
+	for _, row := range r.Rows {
+		res.Rows = append(res.Rows, row.v2(r.Summary.Totals, r.Hostnames))
+	}
+	return res
+}
+
+func (r Row) v2(totals types.Counters, hostnames map[string]string) RowV2 {
+	labels := LabelsV2{
+		Iface:    r.Labels.Iface,
+		Hostname: r.Labels.Hostname,
+		HostID:   r.Labels.HostID,
+	}
+	if !r.Labels.Timestamp.IsZero() {
+		ts := r.Labels.Timestamp
+		labels.Timestamp = &ts
+	}
+	if r.Attributes.IPProto != 0 {
+		labels.Proto = protocols.Format(r.Attributes.IPProto, false)
+		if protocols.IsICMP(r.Attributes.IPProto) {
+			labels.ICMP = protocols.FormatICMP(r.Attributes.IPProto, r.Attributes.DstPort, false)
+		}
+	}
+	if r.Attributes.SrcIP.IsValid() {
+		labels.SrcHostname = hostnames[r.Attributes.SrcIP.String()]
+	}
+	if r.Attributes.DstIP.IsValid() {
+		labels.DstHostname = hostnames[r.Attributes.DstIP.String()]
+	}
+
+	return RowV2{
+		Labels:      labels,
+		Attributes:  r.Attributes,
+		Counters:    NewCountersV2(r.Counters, totals),
+		Mirrored:    r.Mirrored,
+		FlowHash:    r.FlowHash,
+		CommunityID: r.CommunityID,
+		Roles:       r.Roles,
+		Stats:       r.Stats,
+	}
+}
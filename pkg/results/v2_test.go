@@ -0,0 +1,73 @@
+package results
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultV2(t *testing.T) {
+	ts := time.Unix(1704067200, 0).UTC()
+
+	result := New()
+	result.Summary.Totals = types.Counters{BytesRcvd: 300, BytesSent: 100, PacketsRcvd: 3, PacketsSent: 1}
+	result.Hostnames = map[string]string{"10.0.0.1": "db.example.com"}
+	result.Rows = Rows{
+		{
+			Labels:     Labels{Timestamp: ts, Iface: "eth0", Hostname: "probe-1"},
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.1"), DstIP: netip.MustParseAddr("10.0.0.2"), DstPort: 443, IPProto: 6},
+			Counters:   types.Counters{BytesRcvd: 200, BytesSent: 100, PacketsRcvd: 2, PacketsSent: 1},
+		},
+		{
+			Labels:     Labels{Iface: "eth0"},
+			Attributes: Attributes{SrcIP: netip.MustParseAddr("10.0.0.2"), DstIP: netip.MustParseAddr("10.0.0.3"), DstPort: 3<<8 | 3, IPProto: 1},
+			Counters:   types.Counters{BytesRcvd: 100, PacketsRcvd: 1},
+		},
+	}
+
+	v2 := result.V2()
+	require.Equal(t, CountersV2{
+		BytesRcvd: 300, BytesSent: 100, BytesTotal: 400,
+		PacketsRcvd: 3, PacketsSent: 1, PacketsTotal: 4,
+		BytesShare: 1, PacketsShare: 1,
+	}, v2.Summary.Totals)
+	require.Len(t, v2.Rows, 2)
+
+	require.Equal(t, LabelsV2{Timestamp: &ts, Iface: "eth0", Hostname: "probe-1", Proto: "TCP", SrcHostname: "db.example.com"}, v2.Rows[0].Labels)
+	require.Equal(t, CountersV2{
+		BytesRcvd: 200, BytesSent: 100, BytesTotal: 300,
+		PacketsRcvd: 2, PacketsSent: 1, PacketsTotal: 3,
+		BytesShare: 0.75, PacketsShare: 0.75,
+	}, v2.Rows[0].Counters)
+
+	require.Nil(t, v2.Rows[1].Labels.Timestamp)
+	require.Equal(t, "ICMP", v2.Rows[1].Labels.Proto)
+	require.Equal(t, "unreachable/port", v2.Rows[1].Labels.ICMP)
+
+	// the totals of the summary are rendered in their extended form
+	b, err := json.Marshal(v2)
+	require.Nil(t, err)
+	var decoded struct {
+		Summary struct {
+			Totals    map[string]any `json:"totals"`
+			TimeFirst time.Time      `json:"time_first"`
+		} `json:"summary"`
+	}
+	require.Nil(t, json.Unmarshal(b, &decoded))
+	require.EqualValues(t, 400, decoded.Summary.Totals["bytes_total"])
+}
+
+func TestResultV2NoTraffic(t *testing.T) {
+	result := New()
+	result.Rows = Rows{{Attributes: Attributes{IPProto: 17}}}
+
+	v2 := result.V2()
+	require.Equal(t, CountersV2{}, v2.Summary.Totals)
+	require.Equal(t, CountersV2{}, v2.Rows[0].Counters)
+	require.Equal(t, "UDP", v2.Rows[0].Labels.Proto)
+	require.Empty(t, v2.Rows[0].Labels.ICMP)
+}
@@ -17,7 +17,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
 // ColumnIndex denotes a static index for one of the supported DB columns
@@ -39,6 +39,11 @@ const (
 	BytesSentColIdx, _
 	PacketsRcvdColIdx, _
 	PacketsSentColIdx, _
+
+	// ... and finally the optional columns (only stored if in use)
+	TagColIdx, _
+	TTLMinColIdx, _
+	TTLMaxColIdx, _
 	ColIdxCount, _
 )
 
@@ -48,6 +53,8 @@ const (
 	DIPSizeof   int = IPSizeOf
 	ProtoSizeof int = 1
 	DportSizeof int = 2
+	TagSizeof   int = 1
+	TTLSizeof   int = 1
 )
 
 // Below enumerate the data type names used across goProbe
@@ -57,10 +64,13 @@ const (
 	HostIDName   = "hostid"
 	IfaceName    = "iface"
 
-	SIPName   = "sip"
-	DIPName   = "dip"
-	DportName = "dport"
-	ProtoName = "proto"
+	SIPName    = "sip"
+	DIPName    = "dip"
+	DportName  = "dport"
+	ProtoName  = "proto"
+	TagName    = "tag"
+	TTLMinName = "ttl_min"
+	TTLMaxName = "ttl_max"
 
 	BytesRcvdName = "bytes_rcvd"
 	BytesSentName = "bytes_sent"
@@ -77,12 +87,118 @@ func (c ColumnIndex) IsCounterCol() bool {
 // ColumnSizeofs returns the data sizes for each column
 var ColumnSizeofs = [ColIdxCount]int{
 	SIPSizeof, DIPSizeof, ProtoSizeof, DportSizeof,
+	TagColIdx: TagSizeof, TTLSizeof, TTLSizeof,
 }
 
 // ColumnFileNames returns the name / title for each column
 var ColumnFileNames = [ColIdxCount]string{
 	SIPName, DIPName, ProtoName, DportName,
 	BytesRcvdName, BytesSentName, PktsRcvdName, PktsSentName,
+	TagName, TTLMinName, TTLMaxName,
+}
+
+// CounterSelector defines which counters are aggregated (and hence which counter columns are
+// read) by a query. The zero value selects all counters
+type CounterSelector struct {
+	BytesRcvd   bool `json:"bytes_rcvd,omitempty"`
+	BytesSent   bool `json:"bytes_sent,omitempty"`
+	PacketsRcvd bool `json:"packets_rcvd,omitempty"`
+	PacketsSent bool `json:"packets_sent,omitempty"`
+}
+
+// Counter selection shorthands (in addition to the counter column names)
+const (
+	CountersAll     = "all"
+	CountersBytes   = "bytes"
+	CountersPackets = "packets"
+)
+
+// IsAll returns if all counters are selected
+func (c CounterSelector) IsAll() bool {
+	return c == CounterSelector{} || c == CounterSelector{BytesRcvd: true, BytesSent: true, PacketsRcvd: true, PacketsSent: true}
+}
+
+// Bytes returns if any of the byte counters is selected
+func (c CounterSelector) Bytes() bool {
+	return c.IsAll() || c.BytesRcvd || c.BytesSent
+}
+
+// Packets returns if any of the packet counters is selected
+func (c CounterSelector) Packets() bool {
+	return c.IsAll() || c.PacketsRcvd || c.PacketsSent
+}
+
+// Has returns if the counter stored in column colIdx is selected
+func (c CounterSelector) Has(colIdx ColumnIndex) bool {
+	if c.IsAll() {
+		return colIdx.IsCounterCol()
+	}
+	switch colIdx {
+	case BytesRcvdColIdx:
+		return c.BytesRcvd
+	case BytesSentColIdx:
+		return c.BytesSent
+	case PacketsRcvdColIdx:
+		return c.PacketsRcvd
+	case PacketsSentColIdx:
+		return c.PacketsSent
+	}
+	return false
+}
+
+// ColumnIndices returns the indices of all selected counter columns
+func (c CounterSelector) ColumnIndices() (cols []ColumnIndex) {
+	for colIdx := BytesRcvdColIdx; colIdx <= PacketsSentColIdx; colIdx++ {
+		if c.Has(colIdx) {
+			cols = append(cols, colIdx)
+		}
+	}
+	return
+}
+
+// String returns the (canonical) comma separated list of selected counters
+func (c CounterSelector) String() string {
+	if c.IsAll() {
+		return CountersAll
+	}
+	var names []string
+	for _, colIdx := range c.ColumnIndices() {
+		names = append(names, ColumnFileNames[colIdx])
+	}
+	return strings.Join(names, AttrSep)
+}
+
+// ParseCounterSelector parses a comma separated list of counters (e.g. "bytes_in,bytes_out").
+// Each counter can be referred to by its column name or by its direction (e.g. "pkts_rcvd" or
+// "packets_in"). The shorthands "bytes" and "packets" select both directions of the respective
+// counter. An empty string selects all counters
+func ParseCounterSelector(counters string) (sel CounterSelector, err error) {
+	if counters == "" {
+		return sel, nil
+	}
+
+	tokens := strings.Split(counters, AttrSep)
+	for pos, token := range tokens {
+		switch strings.TrimSpace(token) {
+		case CountersAll:
+			return CounterSelector{}, nil
+		case CountersBytes:
+			sel.BytesRcvd, sel.BytesSent = true, true
+		case CountersPackets, "pkts":
+			sel.PacketsRcvd, sel.PacketsSent = true, true
+		case BytesRcvdName, "bytes_in":
+			sel.BytesRcvd = true
+		case BytesSentName, "bytes_out":
+			sel.BytesSent = true
+		case PktsRcvdName, "packets_rcvd", "packets_in", "pkts_in":
+			sel.PacketsRcvd = true
+		case PktsSentName, "packets_sent", "packets_out", "pkts_out":
+			sel.PacketsSent = true
+		default:
+			return CounterSelector{}, NewParseError(tokens, pos, AttrSep, "unknown counter")
+		}
+	}
+	return sel, nil
 }
 
 // Column denotes a generic column and enforces the existence of certain methods
@@ -159,7 +275,7 @@ type ProtoAttribute struct {
 
 // String returns the string representation of the IP protocol  attribute
 func (p ProtoAttribute) String() string {
-	return protocols.GetIPProto(int(p.data))
+	return protocols.Format(p.data, false)
 }
 
 // Width returns the amount of bytes the IP protocol attribute takes up on disk
@@ -216,6 +332,78 @@ func (DportAttribute) Name() string {
 
 func (DportAttribute) attributeMarker() {}
 
+// TagAttribute implements the flow tag attribute (assigned by the tagging rules at capture time)
+type TagAttribute struct {
+	data byte
+}
+
+// Width returns the amount of bytes the tag attribute takes up on disk
+func (TagAttribute) Width() Width {
+	return TagWidth
+}
+
+// String returns the string representation of the tag attribute
+func (t TagAttribute) String() string {
+	return TagNameByID(t.data)
+}
+
+// Resolvable returns if the tag attribute is resolvable
+func (TagAttribute) Resolvable() bool {
+	return false
+}
+
+// Name returns the tag attribute name
+func (TagAttribute) Name() string {
+	return TagName
+}
+
+func (TagAttribute) attributeMarker() {}
+
+type ttlAttribute struct {
+	data uint8
+}
+
+// Width returns the amount of bytes the TTL attribute takes up on disk
+func (ttlAttribute) Width() Width {
+	return TTLWidth
+}
+
+// String returns the string representation of the TTL attribute
+func (t ttlAttribute) String() string {
+	return fmt.Sprint(t.data)
+}
+
+// Resolvable returns if the TTL attribute is resolvable
+func (ttlAttribute) Resolvable() bool {
+	return false
+}
+
+// TTLMinAttribute implements the attribute denoting the minimum TTL (IPv4) / hop limit (IPv6)
+// observed for a flow (zero if not recorded)
+type TTLMinAttribute struct {
+	ttlAttribute
+}
+
+// Name returns the minimum TTL attribute name
+func (TTLMinAttribute) Name() string {
+	return TTLMinName
+}
+
+func (TTLMinAttribute) attributeMarker() {}
+
+// TTLMaxAttribute implements the attribute denoting the maximum TTL (IPv4) / hop limit (IPv6)
+// observed for a flow (zero if not recorded)
+type TTLMaxAttribute struct {
+	ttlAttribute
+}
+
+// Name returns the maximum TTL attribute name
+func (TTLMaxAttribute) Name() string {
+	return TTLMaxName
+}
+
+func (TTLMaxAttribute) attributeMarker() {}
+
 var errorUnknownAttribute = errors.New("unknown attribute")
 
 // NewAttribute returns an attribute for the given name. If no such attribute
@@ -231,6 +419,12 @@ func NewAttribute(name string) (Attribute, error) {
 		return ProtoAttribute{}, nil
 	case DportName, "port":
 		return DportAttribute{}, nil
+	case TagName:
+		return TagAttribute{}, nil
+	case TTLMinName:
+		return TTLMinAttribute{}, nil
+	case TTLMaxName:
+		return TTLMaxAttribute{}, nil
 	default:
 		return nil, errorUnknownAttribute
 	}
@@ -306,6 +500,8 @@ func SanitizeQueryType(query string) string {
 // attribute list.) The time attribute is present for the query type
 // 'raw', or if it is explicitly mentioned in a list of attribute
 // names.
+// Labels (time, iface, hostname and hostid) can be freely combined with the
+// attributes. They are returned via the selector instead of the attribute list.
 func ParseQueryType(queryType string) (attributes []Attribute, selector LabelSelector, err error) {
 	attributeNames := Tokenize(queryType)
 	attributeSet := make(map[string]struct{})
@@ -318,13 +514,13 @@ func ParseQueryType(queryType string) (attributes []Attribute, selector LabelSel
 		case TimeName:
 			selector.Timestamp = true
 			continue
-		case IfaceName:
+		case IfaceName, "interface":
 			selector.Iface = true
 			continue
-		case HostnameName:
+		case HostnameName, "host":
 			selector.Hostname = true
 			continue
-		case HostIDName:
+		case HostIDName, "host_id":
 			selector.HostID = true
 			continue
 		}
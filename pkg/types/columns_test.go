@@ -101,6 +101,8 @@ var parseQueryTypeTests = []struct {
 	{"talk_src,dip", []Attribute{SIPAttribute{}, DIPAttribute{}}, false, false},
 	{"talk_src,src", []Attribute{SIPAttribute{}}, false, false},
 	{"raw", []Attribute{SIPAttribute{}, DIPAttribute{}, DportAttribute{}, ProtoAttribute{}}, true, true},
+	{"hostname,tag,interface,dport", []Attribute{TagAttribute{}, DportAttribute{}}, false, true},
+	{"host,host_id,sip", []Attribute{SIPAttribute{}}, false, false},
 }
 
 func TestParseQueryType(t *testing.T) {
@@ -116,6 +118,60 @@ func TestParseQueryType(t *testing.T) {
 	}
 }
 
+func TestParseQueryTypeLabels(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected LabelSelector
+	}{
+		{"sip,dip", LabelSelector{}},
+		{"hostname,sip", LabelSelector{Hostname: true}},
+		{"host,host_id,tag", LabelSelector{Hostname: true, HostID: true}},
+		{"dport,interface,hostid,time", LabelSelector{Timestamp: true, Iface: true, HostID: true}},
+		{"raw", LabelSelector{Timestamp: true, Iface: true, Hostname: true, HostID: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, selector, err := ParseQueryType(test.input)
+			require.Nil(t, err)
+			require.Equal(t, test.expected, selector)
+		})
+	}
+}
+
+func TestParseCounterSelector(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected CounterSelector
+		cols     []ColumnIndex
+	}{
+		{"", CounterSelector{}, []ColumnIndex{BytesRcvdColIdx, BytesSentColIdx, PacketsRcvdColIdx, PacketsSentColIdx}},
+		{"all", CounterSelector{}, []ColumnIndex{BytesRcvdColIdx, BytesSentColIdx, PacketsRcvdColIdx, PacketsSentColIdx}},
+		{"packets", CounterSelector{PacketsRcvd: true, PacketsSent: true}, []ColumnIndex{PacketsRcvdColIdx, PacketsSentColIdx}},
+		{"bytes_in,bytes_out", CounterSelector{BytesRcvd: true, BytesSent: true}, []ColumnIndex{BytesRcvdColIdx, BytesSentColIdx}},
+		{"pkts_sent, bytes_rcvd", CounterSelector{BytesRcvd: true, PacketsSent: true}, []ColumnIndex{BytesRcvdColIdx, PacketsSentColIdx}},
+		{"bytes,packets_in,packets_out", CounterSelector{BytesRcvd: true, BytesSent: true, PacketsRcvd: true, PacketsSent: true},
+			[]ColumnIndex{BytesRcvdColIdx, BytesSentColIdx, PacketsRcvdColIdx, PacketsSentColIdx}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.input, func(t *testing.T) {
+			sel, err := ParseCounterSelector(test.input)
+			require.Nil(t, err)
+			require.Equal(t, test.expected, sel)
+			require.Equal(t, test.cols, sel.ColumnIndices())
+
+			// the canonical representation must parse to the same selection
+			reparsed, err := ParseCounterSelector(sel.String())
+			require.Nil(t, err)
+			require.Equal(t, sel.ColumnIndices(), reparsed.ColumnIndices())
+		})
+	}
+
+	_, err := ParseCounterSelector("packets,flows")
+	require.Equal(t, NewParseError([]string{"packets", "flows"}, 1, ",", "unknown counter"), err)
+}
+
 func TestParseQueryError(t *testing.T) {
 	var tests = []struct {
 		name        string
@@ -83,6 +83,14 @@ func (n NamedAggFlowMapWithMetadata) Len() (l int) {
 	return
 }
 
+// Size returns the (approximate) amount of memory in bytes allocated by all maps
+func (n NamedAggFlowMapWithMetadata) Size() (s uint64) {
+	for _, v := range n {
+		s += v.Size()
+	}
+	return
+}
+
 // Clear frees as many resources as possible by making them eligible for GC
 func (n NamedAggFlowMapWithMetadata) Clear() {
 	for k, v := range n {
@@ -128,6 +136,11 @@ func (a AggFlowMap) Len() int {
 	return a.PrimaryMap.count + a.SecondaryMap.count
 }
 
+// Size returns the (approximate) amount of memory in bytes allocated by both underlying maps
+func (a AggFlowMap) Size() uint64 {
+	return a.PrimaryMap.Size() + a.SecondaryMap.Size()
+}
+
 // Iter provides a map Iter to allow traversal of both underlying maps (IPv4 and IPv6)
 func (a AggFlowMap) Iter(opts ...MetaIterOption) *MetaIter {
 	iter := &MetaIter{
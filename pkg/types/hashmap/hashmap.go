@@ -3,9 +3,11 @@
 package hashmap
 
 import (
-	"github.com/zeebo/xxh3"
+	"sync/atomic"
+	"unsafe"
 
-	_ "unsafe" // required to allow linking to runtime.fastrand64
+	"github.com/els0r/goProbe/pkg/types"
+	"github.com/zeebo/xxh3"
 )
 
 // Use the same PRNG as the native map implementation in map.go by linking it
@@ -102,6 +104,20 @@ func (m *Map) Len() int {
 	return m.count
 }
 
+// Size returns the (approximate) amount of memory in bytes allocated by the map, i.e.
+// its buckets (including overflow buckets and buckets pending evacuation during growth)
+// and the storage for its keys
+func (m *Map) Size() uint64 {
+	if m == nil {
+		return 0
+	}
+	nBuckets := uint64(cap(m.buckets)) + uint64(m.nOverflow)
+	if m.oldBuckets != nil {
+		nBuckets += uint64(cap(*m.oldBuckets))
+	}
+	return nBuckets*uint64(unsafe.Sizeof(bucket{})) + uint64(cap(m.keyData))
+}
+
 // Get returns the valent associated with key and true if that key exists
 func (m *Map) Get(key Key) (Val, bool) {
 	var res Val
@@ -235,10 +251,12 @@ bucketloop:
 				continue
 			}
 
-			b.vals[i].BytesRcvd += eA
-			b.vals[i].BytesSent += eB
-			b.vals[i].PacketsRcvd += eC
-			b.vals[i].PacketsSent += eD
+			// counters saturate instead of wrapping around (which would silently yield a
+			// tiny value for a huge amount of traffic)
+			b.vals[i].BytesRcvd = types.AddSaturating(b.vals[i].BytesRcvd, eA)
+			b.vals[i].BytesSent = types.AddSaturating(b.vals[i].BytesSent, eB)
+			b.vals[i].PacketsRcvd = types.AddSaturating(b.vals[i].PacketsRcvd, eC)
+			b.vals[i].PacketsSent = types.AddSaturating(b.vals[i].PacketsSent, eD)
 			goto done
 		}
 		ovf := b.overflow
@@ -640,7 +658,23 @@ func isEmpty(x uint8) bool {
 	return x <= emptyOne
 }
 
+// fixedSeed denotes the seed used for all maps instantiated (if non-zero, c.f. SetSeed())
+var fixedSeed atomic.Uint64
+
+// SetSeed sets a fixed seed used for hashing the keys of all maps instantiated subsequently.
+// Since the iteration order of a map only depends on its seed and the sequence of operations
+// performed on it, this renders the iteration order reproducible across runs. A seed of zero
+// restores the default behavior (a random seed per map, which should be preferred for any
+// long-running process handling untrusted input)
+func SetSeed(seed uint64) {
+	fixedSeed.Store(seed)
+}
+
 func generateSeed() uint64 {
+	if seed := fixedSeed.Load(); seed != 0 {
+		return seed
+	}
+
 	var s uint64
 	for {
 		s = runtimeFastrand64()
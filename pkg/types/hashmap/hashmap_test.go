@@ -3,7 +3,9 @@ package hashmap
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"testing"
+	"unsafe"
 
 	"github.com/els0r/goProbe/pkg/types"
 	jsoniter "github.com/json-iterator/go"
@@ -20,6 +22,31 @@ func TestSeedRandomness(t *testing.T) {
 	}
 }
 
+func TestFixedSeed(t *testing.T) {
+	SetSeed(42)
+	defer SetSeed(0)
+
+	iterKeys := func() (keys []string) {
+		testMap := New()
+		for i := 0; i < 1000; i++ {
+			testMap.Set([]byte(fmt.Sprintf("key%d", i)), types.Counters{PacketsRcvd: uint64(i)})
+		}
+		for it := testMap.Iter(); it.Next(); {
+			keys = append(keys, string(it.Key()))
+		}
+		return
+	}
+
+	keys := iterKeys()
+	require.Len(t, keys, 1000)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, keys, iterKeys())
+	}
+
+	SetSeed(0)
+	require.NotEqual(t, uint64(42), New().seed)
+}
+
 func TestSimpleHashMapOperations(t *testing.T) {
 
 	testMap := New()
@@ -80,6 +107,27 @@ func TestHashMapSetOrUpdate(t *testing.T) {
 	require.Equal(t, count, testMap.Len())
 }
 
+func TestHashMapSetOrUpdateSaturation(t *testing.T) {
+
+	testMap := New()
+	testMap.Set([]byte("a"), types.Counters{BytesRcvd: math.MaxUint64 - 10, BytesSent: 5, PacketsRcvd: math.MaxUint64, PacketsSent: 1})
+
+	// counters must saturate instead of wrapping around (while the others continue to be updated)
+	testMap.SetOrUpdate([]byte("a"), 11, 5, 1, 1)
+	val, exists := testMap.Get([]byte("a"))
+	require.True(t, exists)
+	require.Equal(t, types.Counters{BytesRcvd: math.MaxUint64, BytesSent: 10, PacketsRcvd: math.MaxUint64, PacketsSent: 2}, val)
+	require.True(t, val.IsSaturated())
+
+	// merging applies the same semantics
+	testMap2 := New()
+	testMap2.Set([]byte("a"), types.Counters{BytesSent: math.MaxUint64})
+	testMap.Merge(testMap2)
+	val, exists = testMap.Get([]byte("a"))
+	require.True(t, exists)
+	require.Equal(t, types.Counters{BytesRcvd: math.MaxUint64, BytesSent: math.MaxUint64, PacketsRcvd: math.MaxUint64, PacketsSent: 2}, val)
+}
+
 func TestHashMapIteratorConsistency(t *testing.T) {
 	testMap := New()
 	for i := 0; i < 1000; i++ {
@@ -188,6 +236,25 @@ func TestLinearHashMapOperations(t *testing.T) {
 	}
 }
 
+func TestSize(t *testing.T) {
+
+	var nilMap *Map
+	require.Zero(t, nilMap.Size())
+
+	testMap := New()
+	lastSize := testMap.Size()
+	require.NotZero(t, lastSize)
+
+	// the allocated memory covers (at least) the buckets and the key data of all entries
+	for i := 0; i < 100000; i++ {
+		temp := make([]byte, 8)
+		binary.BigEndian.PutUint64(temp, uint64(i))
+		testMap.Set(temp, types.Counters{BytesRcvd: uint64(i)})
+	}
+	require.Greater(t, testMap.Size(), lastSize+100000*8)
+	require.Greater(t, testMap.Size(), uint64(100000/bucketCnt)*uint64(unsafe.Sizeof(bucket{})))
+}
+
 func TestMerge(t *testing.T) {
 
 	testMap, testMap2 := New(), New(100000)
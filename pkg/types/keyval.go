@@ -16,11 +16,14 @@ package types
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
+	"math/bits"
 
-	"github.com/els0r/goProbe/pkg/goDB/protocols"
+	"github.com/els0r/goProbe/pkg/types/protocols"
 )
 
-// Key stores the 5-tuple which defines a goProbe flow
+// Key stores the 5-tuple which defines a goProbe flow (plus the tag assigned to it and the
+// range of observed TTLs / hop limits, if recorded)
 type Key []byte
 
 // NewEmptyV4Key creates / allocates an emty key for IPV4
@@ -171,6 +174,48 @@ func (k Key) PutProtoV4(proto byte) {
 	k[protoPosIPv4] = proto
 }
 
+// PutTag stores a tag ID in the key
+func (k Key) PutTag(tag byte) {
+	k.PutTagV(tag, k.IsIPv4())
+}
+
+// PutTagV stores a tag ID in the key (depending on the IP protocol version)
+func (k Key) PutTagV(tag byte, isIPv4 bool) {
+	if isIPv4 {
+		k[tagPosIPv4] = tag
+	} else {
+		k[tagPosIPv6] = tag
+	}
+}
+
+// PutTTLMin stores the minimum observed TTL / hop limit in the key
+func (k Key) PutTTLMin(ttl byte) {
+	k.PutTTLMinV(ttl, k.IsIPv4())
+}
+
+// PutTTLMinV stores the minimum observed TTL / hop limit in the key (depending on the IP protocol version)
+func (k Key) PutTTLMinV(ttl byte, isIPv4 bool) {
+	if isIPv4 {
+		k[ttlMinPosIPv4] = ttl
+	} else {
+		k[ttlMinPosIPv6] = ttl
+	}
+}
+
+// PutTTLMax stores the maximum observed TTL / hop limit in the key
+func (k Key) PutTTLMax(ttl byte) {
+	k.PutTTLMaxV(ttl, k.IsIPv4())
+}
+
+// PutTTLMaxV stores the maximum observed TTL / hop limit in the key (depending on the IP protocol version)
+func (k Key) PutTTLMaxV(ttl byte, isIPv4 bool) {
+	if isIPv4 {
+		k[ttlMaxPosIPv4] = ttl
+	} else {
+		k[ttlMaxPosIPv6] = ttl
+	}
+}
+
 // PutDIPV4 stores a destination IP in the key (assuming it is an IPv4 key)
 func (k Key) PutDIPV4(dip []byte) {
 	copy(k[dipPosIPv4:dipPosIPv4+IPv4Width], dip)
@@ -207,6 +252,30 @@ func (k Key) GetProto() byte {
 	return k[protoPosIPv6]
 }
 
+// GetTag retrieves the tag ID from the key
+func (k Key) GetTag() byte {
+	if k.IsIPv4() {
+		return k[tagPosIPv4]
+	}
+	return k[tagPosIPv6]
+}
+
+// GetTTLMin retrieves the minimum observed TTL / hop limit from the key
+func (k Key) GetTTLMin() byte {
+	if k.IsIPv4() {
+		return k[ttlMinPosIPv4]
+	}
+	return k[ttlMinPosIPv6]
+}
+
+// GetTTLMax retrieves the maximum observed TTL / hop limit from the key
+func (k Key) GetTTLMax() byte {
+	if k.IsIPv4() {
+		return k[ttlMaxPosIPv4]
+	}
+	return k[ttlMaxPosIPv6]
+}
+
 // GetSIP retrieves the source IP from the key
 func (k Key) GetSIP() []byte {
 	if k.IsIPv4() {
@@ -301,6 +370,48 @@ func (e ExtendedKey) PutProto(proto byte) {
 	e.PutProtoV(proto, e.IsIPv4())
 }
 
+// PutTag stores a tag ID in the key
+func (e ExtendedKey) PutTag(tag byte) {
+	e.PutTagV(tag, e.IsIPv4())
+}
+
+// PutTagV stores a tag ID in the key (depending on the IP protocol version)
+func (e ExtendedKey) PutTagV(tag byte, isIPv4 bool) {
+	if isIPv4 {
+		e[tagPosIPv4] = tag
+	} else {
+		e[tagPosIPv6] = tag
+	}
+}
+
+// PutTTLMin stores the minimum observed TTL / hop limit in the key
+func (e ExtendedKey) PutTTLMin(ttl byte) {
+	e.PutTTLMinV(ttl, e.IsIPv4())
+}
+
+// PutTTLMinV stores the minimum observed TTL / hop limit in the key (depending on the IP protocol version)
+func (e ExtendedKey) PutTTLMinV(ttl byte, isIPv4 bool) {
+	if isIPv4 {
+		e[ttlMinPosIPv4] = ttl
+	} else {
+		e[ttlMinPosIPv6] = ttl
+	}
+}
+
+// PutTTLMax stores the maximum observed TTL / hop limit in the key
+func (e ExtendedKey) PutTTLMax(ttl byte) {
+	e.PutTTLMaxV(ttl, e.IsIPv4())
+}
+
+// PutTTLMaxV stores the maximum observed TTL / hop limit in the key (depending on the IP protocol version)
+func (e ExtendedKey) PutTTLMaxV(ttl byte, isIPv4 bool) {
+	if isIPv4 {
+		e[ttlMaxPosIPv4] = ttl
+	} else {
+		e[ttlMaxPosIPv6] = ttl
+	}
+}
+
 // PutDIPV stores a destination IP in the key (depending on the IP protocol version)
 func (e ExtendedKey) PutDIPV(dip []byte, isIPv4 bool) {
 	if isIPv4 {
@@ -374,6 +485,30 @@ func (e ExtendedKey) GetProto() byte {
 	return e[protoPosIPv6]
 }
 
+// GetTag retrieves the tag ID from the key
+func (e ExtendedKey) GetTag() byte {
+	if e.IsIPv4() {
+		return e[tagPosIPv4]
+	}
+	return e[tagPosIPv6]
+}
+
+// GetTTLMin retrieves the minimum observed TTL / hop limit from the key
+func (e ExtendedKey) GetTTLMin() byte {
+	if e.IsIPv4() {
+		return e[ttlMinPosIPv4]
+	}
+	return e[ttlMinPosIPv6]
+}
+
+// GetTTLMax retrieves the maximum observed TTL / hop limit from the key
+func (e ExtendedKey) GetTTLMax() byte {
+	if e.IsIPv4() {
+		return e[ttlMaxPosIPv4]
+	}
+	return e[ttlMaxPosIPv6]
+}
+
 // GetSIP retrieves the source IP from the key
 func (e ExtendedKey) GetSIP() []byte {
 	if e.IsIPv4() {
@@ -405,7 +540,7 @@ func (k Key) String() string {
 		RawIPToString(k.GetSIP()),
 		RawIPToString(k.GetDIP()),
 		int(PortToUint16(k.GetDport())),
-		protocols.GetIPProto(int(k.GetProto())),
+		protocols.Format(k.GetProto(), false),
 	)
 }
 
@@ -427,34 +562,73 @@ func (c Counters) String() string {
 	)
 }
 
-// SumPackets sums the packet received and sent directions
+// SumPackets sums the packet received and sent directions (saturating at the maximum value)
 func (c Counters) SumPackets() uint64 {
-	return c.PacketsRcvd + c.PacketsSent
+	return AddSaturating(c.PacketsRcvd, c.PacketsSent)
 }
 
-// SumBytes sums the bytes received and sent directions
+// SumBytes sums the bytes received and sent directions (saturating at the maximum value)
 func (c Counters) SumBytes() uint64 {
-	return c.BytesRcvd + c.BytesSent
+	return AddSaturating(c.BytesRcvd, c.BytesSent)
 }
 
-// Add adds the values from a different counter and returns the result
+// Add adds the values from a different counter and returns the result. Counters saturate at
+// their maximum value instead of wrapping around
 func (c Counters) Add(c2 Counters) Counters {
-	c.BytesRcvd += c2.BytesRcvd
-	c.BytesSent += c2.BytesSent
-	c.PacketsRcvd += c2.PacketsRcvd
-	c.PacketsSent += c2.PacketsSent
+	c, _ = c.AddChecked(c2)
 	return c
 }
 
-// Sub subtracts the values from a different counter and returns the result
+// AddChecked adds the values from a different counter and returns the result, along with a flag
+// indicating if any of the counters saturated (i.e. the addition would have overflowed)
+func (c Counters) AddChecked(c2 Counters) (Counters, bool) {
+	var carryA, carryB, carryC, carryD uint64
+	c.BytesRcvd, carryA = bits.Add64(c.BytesRcvd, c2.BytesRcvd, 0)
+	c.BytesSent, carryB = bits.Add64(c.BytesSent, c2.BytesSent, 0)
+	c.PacketsRcvd, carryC = bits.Add64(c.PacketsRcvd, c2.PacketsRcvd, 0)
+	c.PacketsSent, carryD = bits.Add64(c.PacketsSent, c2.PacketsSent, 0)
+
+	// a carry of 1 turns into a mask with all bits set, saturating the respective counter
+	c.BytesRcvd |= -carryA
+	c.BytesSent |= -carryB
+	c.PacketsRcvd |= -carryC
+	c.PacketsSent |= -carryD
+
+	return c, carryA|carryB|carryC|carryD != 0
+}
+
+// Sub subtracts the values from a different counter and returns the result. Counters saturate at
+// zero instead of wrapping around. Saturated counters remain saturated since their actual value
+// is unknown
 func (c Counters) Sub(c2 Counters) Counters {
-	c.BytesRcvd -= c2.BytesRcvd
-	c.BytesSent -= c2.BytesSent
-	c.PacketsRcvd -= c2.PacketsRcvd
-	c.PacketsSent -= c2.PacketsSent
+	c.BytesRcvd = subSaturating(c.BytesRcvd, c2.BytesRcvd)
+	c.BytesSent = subSaturating(c.BytesSent, c2.BytesSent)
+	c.PacketsRcvd = subSaturating(c.PacketsRcvd, c2.PacketsRcvd)
+	c.PacketsSent = subSaturating(c.PacketsSent, c2.PacketsSent)
 	return c
 }
 
+// IsSaturated returns if any of the counters reached its maximum value, i.e. if it potentially
+// stopped increasing because of an overflow
+func (c Counters) IsSaturated() bool {
+	return c.BytesRcvd == math.MaxUint64 || c.BytesSent == math.MaxUint64 ||
+		c.PacketsRcvd == math.MaxUint64 || c.PacketsSent == math.MaxUint64
+}
+
+// AddSaturating adds two counter values, saturating at the maximum value instead of wrapping around
+func AddSaturating(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	return sum | -carry
+}
+
+func subSaturating(a, b uint64) uint64 {
+	if a == math.MaxUint64 {
+		return a
+	}
+	diff, borrow := bits.Sub64(a, b, 0)
+	return diff &^ -borrow
+}
+
 // IsOnlyInbound returns if a set of counters represents traffic that is only inbound
 func (c Counters) IsOnlyInbound() bool {
 	return c.PacketsRcvd > 0 && c.PacketsSent == 0
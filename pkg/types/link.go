@@ -0,0 +1,45 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LinkInfo denotes the properties of the link backing an interface that are required to interpret
+// the traffic captured on it (e.g. to compute the utilization against the correct link speed)
+type LinkInfo struct {
+	MTU    int    `json:"mtu,omitempty"`        // MTU: denotes the maximum transmission unit of the interface. Example: 1500
+	Speed  uint32 `json:"speed_mbps,omitempty"` // Speed: denotes the link speed in Mbit/s (if reported by the driver). Example: 10000
+	MAC    string `json:"mac,omitempty"`        // MAC: denotes the hardware address of the interface (if any). Example: "00:1b:21:3a:4f:10"
+	Driver string `json:"driver,omitempty"`     // Driver: denotes the name of the driver of the interface (if reported). Example: "ixgbe"
+}
+
+// IsZero returns if none of the link properties are known
+func (l LinkInfo) IsZero() bool {
+	return l == LinkInfo{}
+}
+
+// String returns a human-readable summary of the link properties
+func (l LinkInfo) String() string {
+	var parts []string
+	if l.Speed > 0 {
+		if l.Speed >= 1000 && l.Speed%1000 == 0 {
+			parts = append(parts, fmt.Sprintf("%d Gbit/s", l.Speed/1000))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d Mbit/s", l.Speed))
+		}
+	}
+	if l.MTU > 0 {
+		parts = append(parts, fmt.Sprintf("MTU %d", l.MTU))
+	}
+	if l.Driver != "" {
+		parts = append(parts, l.Driver)
+	}
+	if l.MAC != "" {
+		parts = append(parts, l.MAC)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
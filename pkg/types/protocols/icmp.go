@@ -0,0 +1,116 @@
+package protocols
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IP protocol numbers of ICMP and ICMPv6
+const (
+	ICMP   uint8 = 1
+	ICMPv6 uint8 = 58
+)
+
+// ErrInvalidICMPTypeCode is returned if an ICMP type / code cannot be parsed
+var ErrInvalidICMPTypeCode = errors.New("invalid ICMP type / code")
+
+// ICMP message flows are grouped by message type and code instead of ports. Both are stored
+// in the destination port (type in the upper byte, code in the lower byte), where reply-type
+// messages are grouped under the type of their request (e.g. echo reply under echo request)
+
+var icmpTypes = map[uint8]string{
+	3:  "unreachable",
+	4:  "source-quench",
+	5:  "redirect",
+	8:  "echo",
+	9:  "router-adv",
+	10: "router-sol",
+	11: "time-exceeded",
+	12: "param-problem",
+	13: "timestamp",
+	15: "info",
+	17: "mask",
+}
+
+var icmpUnreachableCodes = map[uint8]string{
+	0:  "net",
+	1:  "host",
+	2:  "proto",
+	3:  "port",
+	4:  "frag-needed",
+	5:  "src-route-failed",
+	9:  "net-prohibited",
+	10: "host-prohibited",
+	13: "admin-prohibited",
+}
+
+var icmpv6Types = map[uint8]string{
+	1:   "unreachable",
+	2:   "too-big",
+	3:   "time-exceeded",
+	4:   "param-problem",
+	128: "echo",
+	130: "mld-query",
+	131: "mld-report",
+	133: "router-sol",
+	134: "router-adv",
+	135: "neighbor",
+	137: "redirect",
+	143: "mldv2-report",
+}
+
+var icmpv6UnreachableCodes = map[uint8]string{
+	0: "no-route",
+	1: "admin-prohibited",
+	3: "addr",
+	4: "port",
+}
+
+// IsICMP returns if the IP protocol is ICMP or ICMPv6 (i.e. its flows are grouped by
+// message type / code instead of destination port)
+func IsICMP(proto uint8) bool {
+	return proto == ICMP || proto == ICMPv6
+}
+
+// FormatICMP returns the friendly representation of the ICMP type / code stored in the destination
+// port of an ICMP / ICMPv6 flow (e.g. "echo" or "unreachable/port"). If numeric is set or the
+// type has no known name, type and code are returned as numbers (e.g. "3/3")
+func FormatICMP(proto uint8, dport uint16, numeric bool) string {
+	icmpType, icmpCode := uint8(dport>>8), uint8(dport)
+	if !numeric {
+		types, codes := icmpTypes, icmpUnreachableCodes
+		if proto == ICMPv6 {
+			types, codes = icmpv6Types, icmpv6UnreachableCodes
+		}
+		if name, exists := types[icmpType]; exists {
+			if icmpCode == 0 {
+				return name
+			}
+			if codeName, exists := codes[icmpCode]; exists && name == "unreachable" {
+				return name + "/" + codeName
+			}
+			return name + "/" + strconv.Itoa(int(icmpCode))
+		}
+	}
+	return strconv.Itoa(int(icmpType)) + "/" + strconv.Itoa(int(icmpCode))
+}
+
+// ParseICMP parses an ICMP type / code provided as "<type>/<code>" (e.g. "3/3") and returns
+// its representation in the destination port of an ICMP flow
+func ParseICMP(typeCode string) (uint16, error) {
+	typeStr, codeStr, found := strings.Cut(typeCode, "/")
+	if !found {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidICMPTypeCode, typeCode)
+	}
+	icmpType, err := strconv.ParseUint(typeStr, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidICMPTypeCode, typeCode)
+	}
+	icmpCode, err := strconv.ParseUint(codeStr, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidICMPTypeCode, typeCode)
+	}
+	return uint16(icmpType)<<8 | uint16(icmpCode), nil
+}
@@ -0,0 +1,69 @@
+/*
+Package protocols provides lookup functionality for IP protocol IDs and their names (which are
+in some cases OS specific). It is shared by all components parsing or printing IP protocols
+(conditions, tagging rules, query output and flow listings) so names and numbers can be used
+interchangeably throughout goProbe
+*/
+package protocols
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run protocols_generator.go
+
+// ErrUnknownIPProto is returned if an IP protocol can neither be parsed as number nor be found by name
+var ErrUnknownIPProto = errors.New("unknown IP protocol")
+
+// aliases stores commonly used protocol names which are not present in the protocol
+// list of all operating systems
+var aliases = map[string]uint8{
+	"esp":       50,
+	"ipsec-esp": 50,
+	"ah":        51,
+	"ipsec-ah":  51,
+	"icmp6":     58,
+	"icmpv6":    58,
+	"ipv6-icmp": 58,
+}
+
+// GetIPProto returns the friendly name for a given protocol id
+func GetIPProto(id int) string {
+	return IPProtocols[id]
+}
+
+// GetIPProtoID returns the numeric value for a given IP protocol
+func GetIPProtoID(name string) (uint64, bool) {
+	ret, ok := IPProtocolIDs[name]
+	return uint64(ret), ok
+}
+
+// Parse returns the IP protocol number for a given protocol, which may either be provided
+// as number (e.g. "17") or by its (case-insensitive) name (e.g. "udp" or "UDP")
+func Parse(proto string) (uint8, error) {
+	if num, err := strconv.ParseUint(proto, 10, 8); err == nil {
+		return uint8(num), nil
+	}
+	name := strings.ToLower(proto)
+	if id, ok := GetIPProtoID(name); ok {
+		return uint8(id), nil
+	}
+	if id, ok := aliases[name]; ok {
+		return id, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownIPProto, proto)
+}
+
+// Format returns the friendly name for a given protocol id. If numeric is set or the id has no
+// known name, the number itself is returned
+func Format(id uint8, numeric bool) string {
+	if !numeric {
+		if name := GetIPProto(int(id)); name != "" {
+			return name
+		}
+	}
+	return strconv.Itoa(int(id))
+}
@@ -2,12 +2,15 @@
 // +build ignore
 
 // The make_protocols program is run by go generate to compile a OS specific
-// list of IP protocols for forward and reverse lookup
+// list of IP protocols for forward and reverse lookup. For platforms without an
+// /etc/protocols file (e.g. Windows), the list can be generated on another host
+// by providing the target platform via -goos
 package main
 
 import (
 	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"go/format"
 	"os"
@@ -23,13 +26,16 @@ const (
 
 func main() {
 
+	goos := flag.String("goos", runtime.GOOS, "target platform to generate the list of IP protocols for")
+	flag.Parse()
+
 	protocols, err := readProtocols()
 	if err != nil {
 		fmt.Printf("failed to read IP protocols: %s\n", err)
 		os.Exit(1)
 	}
 
-	if err := generateOutput(protocols); err != nil {
+	if err := generateOutput(protocols, *goos); err != nil {
 		fmt.Printf("failed to generate code: %s\n", err)
 		os.Exit(1)
 	}
@@ -91,7 +97,7 @@ func readProtocols() (protoList []proto, err error) {
 	return
 }
 
-func generateOutput(protoList []proto) error {
+func generateOutput(protoList []proto, goos string) error {
 
 	buffer := bytes.NewBuffer(nil)
 
@@ -99,7 +105,7 @@ func generateOutput(protoList []proto) error {
 // +build %s
 
 // Code generated by protocols_generator.go - DO NOT EDIT.
-`, runtime.GOOS, runtime.GOOS)
+`, goos, goos)
 
 	fmt.Fprintln(buffer, `package protocols
 
@@ -141,5 +147,5 @@ var IPProtocolIDs = map[string]int{`)
 		return err
 	}
 
-	return os.WriteFile("protocols_"+runtime.GOOS+".go", fmtContent, 0600)
+	return os.WriteFile("protocols_"+goos+".go", fmtContent, 0600)
 }
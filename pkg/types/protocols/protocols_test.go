@@ -0,0 +1,93 @@
+package protocols
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected uint8
+		err      error
+	}{
+		{"6", 6, nil},
+		{"17", 17, nil},
+		{"0", 0, nil},
+		{"255", 255, nil},
+		{"tcp", 6, nil},
+		{"UDP", 17, nil},
+		{"Icmp", 1, nil},
+		{"gre", 47, nil},
+		{"esp", 50, nil},
+		{"icmpv6", 58, nil},
+		{"256", 0, ErrUnknownIPProto},
+		{"-1", 0, ErrUnknownIPProto},
+		{"sip", 0, ErrUnknownIPProto},
+		{"", 0, ErrUnknownIPProto},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.input, func(t *testing.T) {
+			proto, err := Parse(test.input)
+			require.ErrorIs(t, err, test.err)
+			require.Equal(t, test.expected, proto)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	require.Equal(t, "TCP", Format(6, false))
+	require.Equal(t, "6", Format(6, true))
+	require.Equal(t, "UDP", Format(17, false))
+	require.Equal(t, "17", Format(17, true))
+
+	// unassigned protocol numbers are printed as number in any case
+	require.Equal(t, "200", Format(200, false))
+
+	// names must be parsed back to the same protocol number
+	for _, id := range []uint8{1, 6, 17, 47, 50, 58} {
+		proto, err := Parse(Format(id, false))
+		require.Nil(t, err)
+		require.Equal(t, id, proto)
+	}
+}
+
+func TestICMP(t *testing.T) {
+	var tests = []struct {
+		proto    uint8
+		dport    uint16
+		expected string
+		numeric  string
+	}{
+		{ICMP, 8 << 8, "echo", "8/0"},
+		{ICMP, 3<<8 | 3, "unreachable/port", "3/3"},
+		{ICMP, 11<<8 | 1, "time-exceeded/1", "11/1"},
+		{ICMP, 42<<8 | 7, "42/7", "42/7"},
+		{ICMPv6, 128 << 8, "echo", "128/0"},
+		{ICMPv6, 1<<8 | 4, "unreachable/port", "1/4"},
+		{ICMPv6, 135 << 8, "neighbor", "135/0"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.expected, func(t *testing.T) {
+			require.True(t, IsICMP(test.proto))
+			require.Equal(t, test.expected, FormatICMP(test.proto, test.dport, false))
+			require.Equal(t, test.numeric, FormatICMP(test.proto, test.dport, true))
+
+			// the numeric representation must be parsed back to the same type / code
+			dport, err := ParseICMP(test.numeric)
+			require.Nil(t, err)
+			require.Equal(t, test.dport, dport)
+		})
+	}
+
+	for _, input := range []string{"8", "256/0", "8/256", "echo/0", "/", ""} {
+		_, err := ParseICMP(input)
+		require.ErrorIs(t, err, ErrInvalidICMPTypeCode, input)
+	}
+	require.False(t, IsICMP(6))
+}
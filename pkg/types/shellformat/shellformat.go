@@ -3,8 +3,6 @@ package shellformat
 import (
 	"fmt"
 	"os"
-
-	"golang.org/x/sys/unix"
 )
 
 // EscapeSeq denotes a simple formatting modifier / escape sequence for shell output
@@ -84,8 +82,3 @@ func (f Format) genEscapeSeq() (seq string) {
 	}
 	return
 }
-
-func isTerminal(fd uintptr) bool {
-	_, err := unix.IoctlGetTermios(int(fd), unix.TCGETS)
-	return err == nil
-}
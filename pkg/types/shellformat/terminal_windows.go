@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package shellformat
+
+import "golang.org/x/sys/windows"
+
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// UntaggedID denotes the tag ID of a flow that has not been assigned a tag
+const UntaggedID byte = 0
+
+// MaxTags denotes the maximum number of distinct tags that can be used
+const MaxTags = 255
+
+var (
+	// ErrInvalidTag is returned if a tag name does not conform to the permitted format
+	ErrInvalidTag = errors.New("invalid tag name")
+
+	// ErrTooManyTags is returned if more than MaxTags distinct tags are used
+	ErrTooManyTags = fmt.Errorf("exceeded maximum number of distinct tags (%d)", MaxTags)
+)
+
+// tagNameRegExp restricts tag names to lower case since conditions are case-insensitive
+var tagNameRegExp = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// tagRegistry maps tag names to the (process-wide) IDs stored in flow keys and vice versa. IDs
+// are only stable within a process, hence they are never persisted as such (c.f. the tag
+// dictionary stored alongside the tag column in goDB)
+type tagRegistry struct {
+	sync.RWMutex
+
+	ids   map[string]byte
+	names []string
+}
+
+var tags = &tagRegistry{
+	ids:   make(map[string]byte),
+	names: []string{""},
+}
+
+// ValidateTag checks if a tag name conforms to the permitted format (lower case alphanumeric
+// characters, dots, dashes and underscores, up to 64 characters)
+func ValidateTag(name string) error {
+	if !tagNameRegExp.MatchString(name) {
+		return fmt.Errorf("%w: `%s`", ErrInvalidTag, name)
+	}
+	return nil
+}
+
+// InternTag returns the ID of a tag, assigning a new one if the tag has not been seen before
+func InternTag(name string) (byte, error) {
+	tags.RLock()
+	id, exists := tags.ids[name]
+	tags.RUnlock()
+	if exists {
+		return id, nil
+	}
+
+	if err := ValidateTag(name); err != nil {
+		return UntaggedID, err
+	}
+
+	tags.Lock()
+	defer tags.Unlock()
+
+	// Check again in case the tag was added concurrently
+	if id, exists = tags.ids[name]; exists {
+		return id, nil
+	}
+	if len(tags.names) > MaxTags {
+		return UntaggedID, ErrTooManyTags
+	}
+
+	id = byte(len(tags.names))
+	tags.ids[name] = id
+	tags.names = append(tags.names, name)
+
+	return id, nil
+}
+
+// LookupTag returns the ID of a tag (if it has been assigned one)
+func LookupTag(name string) (byte, bool) {
+	tags.RLock()
+	defer tags.RUnlock()
+
+	id, exists := tags.ids[name]
+	return id, exists
+}
+
+// TagNameByID returns the name of the tag with the given ID. For untagged flows (or unknown IDs)
+// an empty string is returned
+func TagNameByID(id byte) string {
+	tags.RLock()
+	defer tags.RUnlock()
+
+	if int(id) >= len(tags.names) {
+		return ""
+	}
+	return tags.names[id]
+}
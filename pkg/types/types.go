@@ -54,6 +54,7 @@ type Status string
 // Definition of some common status results
 const (
 	StatusError       Status = "error"
+	StatusDegraded    Status = "degraded"
 	StatusEmpty       Status = "empty"
 	StatusMissingData Status = "missing_data"
 	StatusOK          Status = "ok"
@@ -79,21 +80,29 @@ const (
 	IPv4Width  Width = 4
 	DPortWidth Width = 2
 	ProtoWidth Width = 1
+	TagWidth   Width = 1
+	TTLWidth   Width = 1
 
 	TimestampWidth Width = 8
 )
 
 // Basic constants used to simplify column width calculations
 const (
-	sipPos       = 0
-	dipPosIPv4   = IPv4Width
-	dipPosIPv6   = IPv6Width
-	dportPosIPv4 = sipDipIPv4Width
-	dportPosIPv6 = sipDipIPv6Width
-	protoPosIPv4 = dportPosIPv4 + DPortWidth
-	protoPosIPv6 = dportPosIPv6 + DPortWidth
-
-	nonIPKeysWidth  = DPortWidth + ProtoWidth
+	sipPos        = 0
+	dipPosIPv4    = IPv4Width
+	dipPosIPv6    = IPv6Width
+	dportPosIPv4  = sipDipIPv4Width
+	dportPosIPv6  = sipDipIPv6Width
+	protoPosIPv4  = dportPosIPv4 + DPortWidth
+	protoPosIPv6  = dportPosIPv6 + DPortWidth
+	tagPosIPv4    = protoPosIPv4 + ProtoWidth
+	tagPosIPv6    = protoPosIPv6 + ProtoWidth
+	ttlMinPosIPv4 = tagPosIPv4 + TagWidth
+	ttlMinPosIPv6 = tagPosIPv6 + TagWidth
+	ttlMaxPosIPv4 = ttlMinPosIPv4 + TTLWidth
+	ttlMaxPosIPv6 = ttlMinPosIPv6 + TTLWidth
+
+	nonIPKeysWidth  = DPortWidth + ProtoWidth + TagWidth + 2*TTLWidth
 	sipDipIPv4Width = 2 * IPv4Width
 	sipDipIPv6Width = 2 * IPv6Width
 
@@ -127,6 +136,22 @@ var DirectionFilters = []string{FilterTypeDirectionIn, FilterTypeDirectionInSuga
 	FilterTypeDirectionOut, FilterTypeDirectionOutSugared, FilterTypeDirectionUni, FilterTypeDirectionUniSugared,
 	FilterTypeDirectionBi, FilterTypeDirectionBiSugared}
 
+// IP address classes which can be used as value for IP attributes in conditions (e.g. "sip is private")
+const (
+	// RFC1918 / unique local (ULA) addresses
+	IPClassPrivate = "private"
+	// link-local addresses
+	IPClassLinkLocal        = "linklocal"
+	IPClassLinkLocalSugared = "link-local"
+	// addresses that are not globally routable (private, link-local, loopback, documentation, multicast, ...)
+	IPClassBogon = "bogon"
+	// globally routable addresses (i.e. no bogon)
+	IPClassPublic = "public"
+)
+
+// IPClasses lists all supported IP address classes
+var IPClasses = []string{IPClassPrivate, IPClassPublic, IPClassBogon, IPClassLinkLocal, IPClassLinkLocalSugared}
+
 // AnySelector denotes any / all (interfaces, hosts, ...)
 const AnySelector = "any"
 
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -56,3 +57,58 @@ func TestIPAddressStringParsing(t *testing.T) {
 		require.Equal(t, test.expectedErr, err)
 	}
 }
+
+func TestCountersSaturation(t *testing.T) {
+	var tests = []struct {
+		name      string
+		c, c2     Counters
+		sum       Counters
+		saturated bool
+	}{
+		{"regular",
+			Counters{BytesRcvd: 1, BytesSent: 2, PacketsRcvd: 3, PacketsSent: 4},
+			Counters{BytesRcvd: 10, BytesSent: 20, PacketsRcvd: 30, PacketsSent: 40},
+			Counters{BytesRcvd: 11, BytesSent: 22, PacketsRcvd: 33, PacketsSent: 44},
+			false,
+		},
+		{"exactly max",
+			Counters{BytesRcvd: math.MaxUint64 - 1},
+			Counters{BytesRcvd: 1},
+			Counters{BytesRcvd: math.MaxUint64},
+			false,
+		},
+		{"overflow",
+			Counters{BytesRcvd: math.MaxUint64 - 1, PacketsSent: 1},
+			Counters{BytesRcvd: 2, PacketsSent: 1},
+			Counters{BytesRcvd: math.MaxUint64, PacketsSent: 2},
+			true,
+		},
+		{"overflow all",
+			Counters{BytesRcvd: math.MaxUint64, BytesSent: math.MaxUint64, PacketsRcvd: math.MaxUint64, PacketsSent: math.MaxUint64},
+			Counters{BytesRcvd: math.MaxUint64, BytesSent: 1, PacketsRcvd: 2, PacketsSent: 3},
+			Counters{BytesRcvd: math.MaxUint64, BytesSent: math.MaxUint64, PacketsRcvd: math.MaxUint64, PacketsSent: math.MaxUint64},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			sum, saturated := test.c.AddChecked(test.c2)
+			require.Equal(t, test.sum, sum)
+			require.Equal(t, test.saturated, saturated)
+			require.Equal(t, test.sum, test.c.Add(test.c2))
+		})
+	}
+
+	// sums across directions saturate as well
+	c := Counters{BytesRcvd: math.MaxUint64 - 1, BytesSent: 2, PacketsRcvd: 1, PacketsSent: 2}
+	require.Equal(t, uint64(math.MaxUint64), c.SumBytes())
+	require.Equal(t, uint64(3), c.SumPackets())
+	require.False(t, c.IsSaturated())
+	require.True(t, c.Add(c).IsSaturated())
+
+	// subtraction saturates at zero, saturated counters remain saturated
+	require.Equal(t, Counters{BytesSent: 1}, Counters{BytesRcvd: 1, BytesSent: 3}.Sub(Counters{BytesRcvd: 2, BytesSent: 2}))
+	require.Equal(t, Counters{BytesRcvd: math.MaxUint64}, Counters{BytesRcvd: math.MaxUint64}.Sub(Counters{BytesRcvd: 5}))
+}
@@ -3,6 +3,7 @@ package apiclient
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/els0r/goProbe/cmd/global-query/pkg/distributed"
 	"github.com/els0r/goProbe/cmd/global-query/pkg/hosts"
+	baseclient "github.com/els0r/goProbe/pkg/api/client"
+	"github.com/els0r/goProbe/pkg/api/client/pinning"
 	"github.com/els0r/goProbe/pkg/api/goprobe/client"
 	"github.com/els0r/goProbe/pkg/query"
 	"github.com/els0r/goProbe/pkg/results"
@@ -26,8 +29,12 @@ const (
 )
 
 func init() {
-	plugins.RegisterQuerier(Name, func(_ context.Context, cfgPath string) (distributed.Querier, error) {
-		return New(cfgPath)
+	plugins.RegisterQuerier(Name, func(ctx context.Context, cfgPath string) (distributed.Querier, error) {
+		a, err := New(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		return a.SetPins(pinning.FromContext(ctx)), nil
 	})
 }
 
@@ -36,6 +43,9 @@ type APIClientQuerier struct {
 	apiEndpoints map[string]*client.Config `json:"endpoints" yaml:"endpoints"`
 
 	maxConcurrent int `json:"max_concurrent" yaml:"max_concurrent"`
+
+	// pins stores the pinned certificate fingerprints of the endpoints (if pinning is enabled)
+	pins *pinning.Store
 }
 
 // one CPU can handle more than one client call at a time
@@ -75,14 +85,28 @@ func (a *APIClientQuerier) SetMaxConcurrent(num int) *APIClientQuerier {
 	return a
 }
 
+// SetPins enables trust-on-first-use pinning of the certificates presented by the endpoints (if
+// contacted via TLS), verifying them against the fingerprints in the pin store instead of a CA
+func (a *APIClientQuerier) SetPins(pins *pinning.Store) *APIClientQuerier {
+	a.pins = pins
+	return a
+}
+
 // createQueryWorkload prepares and executes the workload required to perform the query
 func (a *APIClientQuerier) createQueryWorkload(_ context.Context, host string, args *query.Args) (*queryWorkload, error) {
 	qw := &queryWorkload{
 		Host: host,
 		Args: args,
 	}
-	// create the api client runner by looking up the endpoint config for the given host
+	// create the api client runner by looking up the endpoint config for the given host. Hosts without
+	// a configuration (e.g. discovered via DNS SRV records) are contacted directly if they denote an
+	// address (host:port)
 	cfg, exists := a.apiEndpoints[host]
+	if !exists {
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			cfg, exists = &client.Config{Addr: host}, true
+		}
+	}
 	if !exists {
 		err := fmt.Errorf("couldn't find endpoint configuration for host")
 
@@ -90,7 +114,11 @@ func (a *APIClientQuerier) createQueryWorkload(_ context.Context, host string, a
 		// result
 		qw.Runner = distributed.NewErrorRunner(err)
 	} else {
-		qw.Runner = client.NewFromConfig(cfg)
+		var opts []baseclient.Option
+		if a.pins != nil {
+			opts = append(opts, baseclient.WithTLSConfig(a.pins.ClientTLSConfig(cfg.Addr)))
+		}
+		qw.Runner = client.NewFromConfig(cfg, opts...)
 	}
 
 	return qw, nil